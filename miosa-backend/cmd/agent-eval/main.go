@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/ai_providers"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/analysis"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/architect"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/communication"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/deployment"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/development"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/monitoring"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/recommender"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/strategy"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/eval"
+)
+
+func main() {
+	settings := config.NewLoader(flag.CommandLine)
+	var (
+		suiteFile   = flag.String("suite", "", "Suite JSON file (defaults to the built-in golden suite)")
+		model       = flag.String("model", "", "Model to run every agent against (defaults to each agent's own model)")
+		provider    = flag.String("provider", "groq", "Provider name reported in results")
+		baseURL     = flag.String("base-url", "", "OpenAI-compatible API base URL (defaults to Groq)")
+		only        = flag.String("agents", "", "Comma-separated agent types to evaluate (defaults to all)")
+		timeout     = flag.Duration("timeout", 3*time.Minute, "Timeout per case")
+		minPassRate = flag.Float64("min-pass-rate", 0, "Exit non-zero when the pass rate is below this (0-1)")
+		jsonOut     = flag.Bool("json", false, "Print the report as JSON")
+		compression = flag.String("compression", "on", "Prompt compression: on, off, or compare to run the suite both ways and report its effect on pass rate and tokens")
+		apiKey      = settings.Secret("groq-api-key", "Groq API key", "GROQ_API_KEY")
+	)
+	settings.Require("groq-api-key")
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if settings.PrintRequested() {
+		settings.Print(os.Stdout)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	var opts []groq.Opts
+	if *baseURL != "" {
+		opts = append(opts, groq.WithBaseURL(*baseURL))
+	}
+	groqClient, err := groq.NewClient(*apiKey, opts...)
+	if err != nil {
+		log.Fatal("Failed to create client:", err)
+	}
+
+	agents.DefaultLLMGuard.SetProvider(*provider)
+	agents.DefaultLLMGuard.SetModel(*model)
+
+	suite := eval.DefaultSuite()
+	if *suiteFile != "" {
+		if suite, err = eval.LoadSuite(*suiteFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *only != "" {
+		suite = filterSuite(suite, strings.Split(*only, ","))
+	}
+
+	registry := map[agents.AgentType]agents.Agent{
+		agents.AnalysisAgent:      analysis.New(groqClient),
+		agents.ArchitectAgent:     architect.New(groqClient),
+		agents.DevelopmentAgent:   development.New(groqClient),
+		agents.QualityAgent:       quality.New(groqClient),
+		agents.DeploymentAgent:    deployment.New(groqClient),
+		agents.MonitoringAgent:    monitoring.New(groqClient),
+		agents.StrategyAgent:      strategy.New(groqClient),
+		agents.CommunicationAgent: communication.New(groqClient),
+		agents.RecommenderAgent:   recommender.New(groqClient),
+		agents.AIProvidersAgent:   ai_providers.New(groqClient),
+	}
+	runner := eval.NewRunner(func(t agents.AgentType) (agents.Agent, error) {
+		agent, ok := registry[t]
+		if !ok {
+			return nil, fmt.Errorf("agent %s not available", t)
+		}
+		return agent, nil
+	}, *timeout)
+
+	// Compare runs the suite without compression first as the baseline
+	var baseline *eval.Report
+	switch *compression {
+	case "off":
+	case "on":
+		agents.DefaultLLMGuard.SetCompressor(agents.NewPromptCompressor())
+	case "compare":
+		baseline = runner.Run(context.Background(), suite)
+		agents.DefaultLLMGuard.SetCompressor(agents.NewPromptCompressor())
+	default:
+		log.Fatalf("Invalid -compression %q, expected on, off or compare", *compression)
+	}
+
+	report := runner.Run(context.Background(), suite)
+	report.Provider = *provider
+	report.Model = *model
+
+	if baseline != nil {
+		comparison := eval.Compare(baseline, report)
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(map[string]interface{}{"baseline": baseline, "compressed": report, "comparison": comparison})
+		} else {
+			printReport(report)
+			printComparison(comparison)
+		}
+		if report.PassRate < *minPassRate {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(report)
+	}
+
+	if report.PassRate < *minPassRate {
+		os.Exit(1)
+	}
+}
+
+func filterSuite(suite *eval.Suite, types []string) *eval.Suite {
+	keep := make(map[agents.AgentType]bool, len(types))
+	for _, t := range types {
+		keep[agents.AgentType(strings.TrimSpace(t))] = true
+	}
+	filtered := &eval.Suite{Name: suite.Name}
+	for _, c := range suite.Cases {
+		if keep[c.Agent] {
+			filtered.Cases = append(filtered.Cases, c)
+		}
+	}
+	return filtered
+}
+
+func printReport(report *eval.Report) {
+	model := report.Model
+	if model == "" {
+		model = "per-agent defaults"
+	}
+	fmt.Printf("Suite %s on %s (%s)\n\n", report.Suite, report.Provider, model)
+
+	for _, res := range report.Results {
+		status := "PASS"
+		if !res.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("  %s  %-32s %6dms\n", status, res.Name, res.DurationMS)
+		if res.Error != "" {
+			fmt.Printf("        error: %s\n", res.Error)
+		}
+		for _, f := range res.Failures {
+			fmt.Printf("        - %s\n", f)
+		}
+	}
+
+	types := make([]string, 0, len(report.ByAgent))
+	for t := range report.ByAgent {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+
+	fmt.Println("\nBy agent:")
+	for _, t := range types {
+		stats := report.ByAgent[agents.AgentType(t)]
+		fmt.Printf("  %-16s %d/%d (%.0f%%)\n", t, stats.Passed, stats.Total, stats.Rate*100)
+	}
+	fmt.Printf("\nPass rate: %d/%d (%.0f%%) in %s\n", report.Passed, report.Total,
+		report.PassRate*100, time.Duration(report.DurationMS)*time.Millisecond)
+}
+
+func printComparison(c *eval.Comparison) {
+	fmt.Printf("\nPrompt compression: pass rate %.0f%% -> %.0f%% (%+.0f points), %d prompt tokens saved\n",
+		c.BaselinePassRate*100, c.CandidatePassRate*100, c.PassRateDelta*100, c.PromptTokensSaved)
+	for _, name := range c.Regressions {
+		fmt.Printf("  regressed: %s\n", name)
+	}
+	for _, name := range c.Fixes {
+		fmt.Printf("  fixed:     %s\n", name)
+	}
+}
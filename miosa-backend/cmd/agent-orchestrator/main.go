@@ -17,79 +17,24 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"go.uber.org/zap"
 )
 
-// AgentType represents different agent specializations
-type AgentType string
-
-const (
-	OrchestratorAgent AgentType = "orchestrator"
-	AnalysisAgent     AgentType = "analysis"
-	ArchitectAgent    AgentType = "architect"
-	DevelopmentAgent  AgentType = "development"
-	TestingAgent      AgentType = "testing"
-	QualityAgent      AgentType = "quality"
-	DeploymentAgent   AgentType = "deployment"
-	MonitoringAgent   AgentType = "monitoring"
-)
-
-// Task represents a task for an agent
-type Task struct {
-	ID          uuid.UUID              `json:"id"`
-	Type        string                 `json:"type"`
-	Description string                 `json:"description"`
-	Input       string                 `json:"input"`
-	Parameters  map[string]interface{} `json:"parameters"`
-	Priority    int                    `json:"priority"`
-	Context     *TaskContext           `json:"context"`
-}
-
-// TaskContext provides context for task execution
-type TaskContext struct {
-	ProjectID   uuid.UUID              `json:"project_id"`
-	Phase       string                 `json:"phase"`
-	Memory      map[string]interface{} `json:"memory"`
-	History     []Message              `json:"history"`
-	IDEEndpoint string                 `json:"ide_endpoint"`
-}
-
-// Message represents a conversation message
-type Message struct {
-	Role    string    `json:"role"`
-	Content string    `json:"content"`
-	Agent   AgentType `json:"agent"`
-	Time    time.Time `json:"time"`
-}
-
-// Result represents agent execution result
-type Result struct {
-	Success     bool                   `json:"success"`
-	Output      string                 `json:"output"`
-	Files       []GeneratedFile        `json:"files,omitempty"`
-	Data        map[string]interface{} `json:"data"`
-	NextAgent   AgentType              `json:"next_agent,omitempty"`
-	Confidence  float64                `json:"confidence"`
-	ExecutionMS int64                  `json:"execution_ms"`
-	Error       string                 `json:"error,omitempty"`
-}
-
-// GeneratedFile represents a file created by an agent
-type GeneratedFile struct {
-	Path    string `json:"path"`
-	Content string `json:"content"`
-	Type    string `json:"type"`
-}
-
-// Agent interface
-type Agent interface {
-	GetType() AgentType
-	Execute(ctx context.Context, task Task) (*Result, error)
-	GetCapabilities() []string
-}
+// TestingAgent is where the development agent hands off. No agent of this
+// type is registered here, so the workflow ends after development.
+const TestingAgent agents.AgentType = "testing"
 
 // BaseAgent provides common functionality
 type BaseAgent struct {
-	Type        AgentType
+	Type        agents.AgentType
 	Name        string
 	Description string
 	APIKey      string
@@ -99,13 +44,14 @@ type BaseAgent struct {
 // IDEClient handles IDE server communication
 type IDEClient struct {
 	BaseURL string
+	Audit   *audit.Log
 }
 
 // SaveFile saves content to IDE
 func (c *IDEClient) SaveFile(path string, content string) error {
 	payload := map[string]string{
 		"path":    path,
-		"content": content,
+		"content": redact.Clean(content),
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -128,6 +74,13 @@ func (c *IDEClient) SaveFile(path string, content string) error {
 		return fmt.Errorf("failed to save file: %s", string(body))
 	}
 
+	c.Audit.Record(context.Background(), audit.Event{
+		Actor:       "ide-client",
+		ActorType:   audit.ActorSystem,
+		Action:      audit.ActionFileWrite,
+		Resource:    path,
+		RequestHash: audit.HashRequest(payload["content"]),
+	})
 	return nil
 }
 
@@ -203,15 +156,19 @@ type AnalysisAgentImpl struct {
 	llm *LLMClient
 }
 
-func (a *AnalysisAgentImpl) GetType() AgentType {
-	return AnalysisAgent
+func (a *AnalysisAgentImpl) GetType() agents.AgentType {
+	return agents.AnalysisAgent
+}
+
+func (a *AnalysisAgentImpl) GetDescription() string {
+	return "Analyzes requirements and produces specifications"
 }
 
-func (a *AnalysisAgentImpl) GetCapabilities() []string {
-	return []string{"requirements_analysis", "specification_creation", "risk_assessment"}
+func (a *AnalysisAgentImpl) GetCapabilities() []agents.Capability {
+	return agents.CapabilitiesFromNames([]string{"requirements_analysis", "specification_creation", "risk_assessment"})
 }
 
-func (a *AnalysisAgentImpl) Execute(ctx context.Context, task Task) (*Result, error) {
+func (a *AnalysisAgentImpl) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
 	start := time.Now()
 
 	systemPrompt := `You are an Analysis Agent specializing in software requirements analysis.
@@ -224,11 +181,11 @@ Your role is to:
 
 Format your response as a structured markdown document with clear sections.`
 
-	response, err := a.llm.CallLLM(ctx, systemPrompt, task.Description)
+	response, err := a.llm.CallLLM(ctx, systemPrompt, task.Input)
 	if err != nil {
-		return &Result{
+		return &agents.Result{
 			Success:     false,
-			Error:       err.Error(),
+			Error:       err,
 			ExecutionMS: time.Since(start).Milliseconds(),
 		}, nil
 	}
@@ -242,12 +199,12 @@ Format your response as a structured markdown document with clear sections.`
 		log.Printf("Failed to save analysis: %v", err)
 	}
 
-	return &Result{
+	return &agents.Result{
 		Success:     true,
 		Output:      response,
-		Files:       []GeneratedFile{{Path: fileName, Content: response, Type: "markdown"}},
+		Files:       []agents.GeneratedFile{{Path: fileName, Content: response, Type: "markdown"}},
 		Confidence:  0.85,
-		NextAgent:   ArchitectAgent,
+		NextAgent:   agents.ArchitectAgent,
 		ExecutionMS: time.Since(start).Milliseconds(),
 	}, nil
 }
@@ -258,15 +215,19 @@ type ArchitectAgentImpl struct {
 	llm *LLMClient
 }
 
-func (a *ArchitectAgentImpl) GetType() AgentType {
-	return ArchitectAgent
+func (a *ArchitectAgentImpl) GetType() agents.AgentType {
+	return agents.ArchitectAgent
 }
 
-func (a *ArchitectAgentImpl) GetCapabilities() []string {
-	return []string{"system_design", "data_modeling", "api_design"}
+func (a *ArchitectAgentImpl) GetDescription() string {
+	return "Designs data models, interfaces and system architecture"
 }
 
-func (a *ArchitectAgentImpl) Execute(ctx context.Context, task Task) (*Result, error) {
+func (a *ArchitectAgentImpl) GetCapabilities() []agents.Capability {
+	return agents.CapabilitiesFromNames([]string{"system_design", "data_modeling", "api_design"})
+}
+
+func (a *ArchitectAgentImpl) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
 	start := time.Now()
 
 	systemPrompt := `You are an Architecture Agent specializing in system design.
@@ -279,11 +240,11 @@ Your role is to:
 Generate complete, working Go code with proper struct definitions, interfaces, and JSON tags.
 Include comments explaining design decisions.`
 
-	response, err := a.llm.CallLLM(ctx, systemPrompt, task.Description)
+	response, err := a.llm.CallLLM(ctx, systemPrompt, task.Input)
 	if err != nil {
-		return &Result{
+		return &agents.Result{
 			Success:     false,
-			Error:       err.Error(),
+			Error:       err,
 			ExecutionMS: time.Since(start).Milliseconds(),
 		}, nil
 	}
@@ -300,12 +261,12 @@ Include comments explaining design decisions.`
 		log.Printf("Failed to save model: %v", err)
 	}
 
-	return &Result{
+	return &agents.Result{
 		Success:     true,
 		Output:      response,
-		Files:       []GeneratedFile{{Path: fileName, Content: code, Type: "go"}},
+		Files:       []agents.GeneratedFile{{Path: fileName, Content: code, Type: "go"}},
 		Confidence:  0.88,
-		NextAgent:   DevelopmentAgent,
+		NextAgent:   agents.DevelopmentAgent,
 		ExecutionMS: time.Since(start).Milliseconds(),
 	}, nil
 }
@@ -316,15 +277,19 @@ type DevelopmentAgentImpl struct {
 	llm *LLMClient
 }
 
-func (a *DevelopmentAgentImpl) GetType() AgentType {
-	return DevelopmentAgent
+func (a *DevelopmentAgentImpl) GetType() agents.AgentType {
+	return agents.DevelopmentAgent
+}
+
+func (a *DevelopmentAgentImpl) GetDescription() string {
+	return "Implements API handlers and business logic"
 }
 
-func (a *DevelopmentAgentImpl) GetCapabilities() []string {
-	return []string{"api_implementation", "business_logic", "integration"}
+func (a *DevelopmentAgentImpl) GetCapabilities() []agents.Capability {
+	return agents.CapabilitiesFromNames([]string{"api_implementation", "business_logic", "integration"})
 }
 
-func (a *DevelopmentAgentImpl) Execute(ctx context.Context, task Task) (*Result, error) {
+func (a *DevelopmentAgentImpl) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
 	start := time.Now()
 
 	systemPrompt := `You are a Development Agent specializing in Go implementation.
@@ -337,11 +302,11 @@ Your role is to:
 
 Generate production-ready Go code with all necessary imports.`
 
-	response, err := a.llm.CallLLM(ctx, systemPrompt, task.Description)
+	response, err := a.llm.CallLLM(ctx, systemPrompt, task.Input)
 	if err != nil {
-		return &Result{
+		return &agents.Result{
 			Success:     false,
-			Error:       err.Error(),
+			Error:       err,
 			ExecutionMS: time.Since(start).Milliseconds(),
 		}, nil
 	}
@@ -358,10 +323,10 @@ Generate production-ready Go code with all necessary imports.`
 		log.Printf("Failed to save handler: %v", err)
 	}
 
-	return &Result{
+	return &agents.Result{
 		Success:     true,
 		Output:      response,
-		Files:       []GeneratedFile{{Path: fileName, Content: code, Type: "go"}},
+		Files:       []agents.GeneratedFile{{Path: fileName, Content: code, Type: "go"}},
 		Confidence:  0.90,
 		NextAgent:   TestingAgent,
 		ExecutionMS: time.Since(start).Milliseconds(),
@@ -370,41 +335,44 @@ Generate production-ready Go code with all necessary imports.`
 
 // Orchestrator coordinates agent execution
 type Orchestrator struct {
-	agents      map[AgentType]Agent
+	agents      map[agents.AgentType]agents.Agent
 	ideClient   *IDEClient
-	taskHistory []Task
+	audit       *audit.Log
+	auth        *middleware.AuthMiddleware
+	taskHistory []agents.Task
 	mu          sync.RWMutex
 }
 
 // NewOrchestrator creates a new orchestrator
 func NewOrchestrator(apiKey string, ideEndpoint string) *Orchestrator {
-	ideClient := &IDEClient{BaseURL: ideEndpoint}
+	auditLog := audit.New(audit.NewMemoryStore(), nil)
+	ideClient := &IDEClient{BaseURL: ideEndpoint, Audit: auditLog}
 	llmClient := &LLMClient{APIKey: apiKey}
 
-	agents := make(map[AgentType]Agent)
+	registry := make(map[agents.AgentType]agents.Agent)
 
 	// Initialize agents
-	agents[AnalysisAgent] = &AnalysisAgentImpl{
+	registry[agents.AnalysisAgent] = &AnalysisAgentImpl{
 		BaseAgent: BaseAgent{
-			Type:      AnalysisAgent,
+			Type:      agents.AnalysisAgent,
 			Name:      "Analysis Agent",
 			IDEClient: ideClient,
 		},
 		llm: llmClient,
 	}
 
-	agents[ArchitectAgent] = &ArchitectAgentImpl{
+	registry[agents.ArchitectAgent] = &ArchitectAgentImpl{
 		BaseAgent: BaseAgent{
-			Type:      ArchitectAgent,
+			Type:      agents.ArchitectAgent,
 			Name:      "Architecture Agent",
 			IDEClient: ideClient,
 		},
 		llm: llmClient,
 	}
 
-	agents[DevelopmentAgent] = &DevelopmentAgentImpl{
+	registry[agents.DevelopmentAgent] = &DevelopmentAgentImpl{
 		BaseAgent: BaseAgent{
-			Type:      DevelopmentAgent,
+			Type:      agents.DevelopmentAgent,
 			Name:      "Development Agent",
 			IDEClient: ideClient,
 		},
@@ -412,15 +380,28 @@ func NewOrchestrator(apiKey string, ideEndpoint string) *Orchestrator {
 	}
 
 	return &Orchestrator{
-		agents:    agents,
+		agents:    registry,
 		ideClient: ideClient,
+		audit:     auditLog,
 	}
 }
 
 // ExecuteTask orchestrates task execution across agents
-func (o *Orchestrator) triggerE2BWorkflow(workspacePath string) {
+func (o *Orchestrator) triggerE2BWorkflow(ctx context.Context, workflowID uuid.UUID, workspacePath string) {
 	log.Printf("Triggering E2B workflow for path: %s", workspacePath)
 
+	status := audit.StatusFailure
+	defer func() {
+		o.audit.Record(ctx, audit.Event{
+			WorkflowID: workflowID,
+			Actor:      "agent-orchestrator",
+			ActorType:  audit.ActorSystem,
+			Action:     audit.ActionDeploymentTrigger,
+			Resource:   workspacePath,
+			Status:     status,
+		})
+	}()
+
 	payload := map[string]string{"path": workspacePath}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
@@ -442,29 +423,29 @@ func (o *Orchestrator) triggerE2BWorkflow(workspacePath string) {
 		return
 	}
 
+	status = audit.StatusSuccess
 	log.Println("Successfully triggered E2B workflow.")
 }
 
 // ExecuteTask orchestrates task execution across agents
 func (o *Orchestrator) ExecuteTask(ctx context.Context, description string) (*WorkflowResult, error) {
-	workflowID := uuid.New()
-	results := make([]*Result, 0)
+	return o.ExecuteRequest(ctx, uuid.New(), &orchestrate.Request{Description: description})
+}
+
+// ExecuteRequest orchestrates a validated orchestrate request under the
+// given workflow ID
+func (o *Orchestrator) ExecuteRequest(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request) (*WorkflowResult, error) {
+	results := make([]*agents.Result, 0)
 
 	// Create initial task
-	task := Task{
-		ID:          workflowID,
-		Type:        "implementation",
-		Description: description,
-		Context: &TaskContext{
-			ProjectID:   workflowID,
-			Phase:       "analysis",
-			Memory:      make(map[string]interface{}),
-			IDEEndpoint: o.ideClient.BaseURL,
-		},
-	}
+	task := req.Task(workflowID)
+	task.Context.WorkspaceID = workflowID
+	task.Context.RequestID = requestid.FromContext(ctx)
+	task.Context.Phase = "analysis"
+	task.Context.Metadata = map[string]string{"ide_endpoint": o.ideClient.BaseURL}
 
 	// Start with analysis agent
-	currentAgent := AnalysisAgent
+	currentAgent := agents.AnalysisAgent
 
 	for i := 0; i < 5; i++ { // Max 5 agent hops
 		agent, exists := o.agents[currentAgent]
@@ -474,18 +455,18 @@ func (o *Orchestrator) ExecuteTask(ctx context.Context, description string) (*Wo
 
 		log.Printf("> Executing %s agent...", currentAgent)
 
-		result, err := agent.Execute(ctx, task)
+		result, err := agents.ExecuteTracked(ctx, agent, task)
 		if err != nil {
 			return nil, fmt.Errorf("agent %s failed: %w", currentAgent, err)
 		}
 
+		if n := redact.Result(result); n > 0 {
+			log.Printf("> Redacted %d secrets from %s output", n, currentAgent)
+		}
 		results = append(results, result)
 
-		// Update task context with result
-		if task.Context.Memory == nil {
-			task.Context.Memory = make(map[string]interface{})
-		}
-		task.Context.Memory[string(currentAgent)] = result.Output
+		// Record the step; later prompts see it summarized
+		task.Context.Record(currentAgent, result)
 
 		// Move to next agent if specified
 		if result.NextAgent == "" {
@@ -503,7 +484,7 @@ func (o *Orchestrator) ExecuteTask(ctx context.Context, description string) (*Wo
 		log.Printf("Error getting current directory: %v", err)
 	} else {
 		workspaceDir := filepath.Join(wsPath, "agent-workspace")
-		o.triggerE2BWorkflow(workspaceDir)
+		o.triggerE2BWorkflow(ctx, workflowID, workspaceDir)
 	}
 
 	return &WorkflowResult{
@@ -515,9 +496,9 @@ func (o *Orchestrator) ExecuteTask(ctx context.Context, description string) (*Wo
 
 // WorkflowResult represents complete workflow execution
 type WorkflowResult struct {
-	WorkflowID uuid.UUID `json:"workflow_id"`
-	Results    []*Result `json:"results"`
-	Success    bool      `json:"success"`
+	WorkflowID uuid.UUID        `json:"workflow_id"`
+	Results    []*agents.Result `json:"results"`
+	Success    bool             `json:"success"`
 }
 
 // extractCode extracts code blocks from markdown
@@ -545,38 +526,64 @@ func extractCode(text string) string {
 type Server struct {
 	orchestrator *Orchestrator
 	router       *mux.Router
+	batches      *orchestrate.Scheduler
 }
 
 // NewServer creates a new API server
-func NewServer(orchestrator *Orchestrator) *Server {
+func NewServer(orchestrator *Orchestrator, batchWorkers int) *Server {
 	s := &Server{
 		orchestrator: orchestrator,
 		router:       mux.NewRouter(),
 	}
+	s.batches = orchestrate.NewScheduler(func(ctx context.Context, job orchestrate.Job) (interface{}, error) {
+		return s.execute(ctx, job.WorkflowID, job.Request, job.Actor, job.ActorType, "/api/orchestrate/batch")
+	}, batchWorkers)
 	s.setupRoutes()
 	return s
 }
 
 func (s *Server) setupRoutes() {
+	s.router.Use(requestid.Handler)
 	s.router.HandleFunc("/api/orchestrate", s.handleOrchestrate).Methods("POST")
+	s.router.HandleFunc("/api/orchestrate/batch", s.batches.SubmitHandler()).Methods("POST")
+	s.router.HandleFunc("/api/orchestrate/batch/{id}", s.batches.StatusHandler()).Methods("GET")
+	s.router.HandleFunc("/api/orchestrate/batch/{id}/items/{index}", s.batches.ItemHandler()).Methods("GET")
 	s.router.HandleFunc("/api/agents", s.handleListAgents).Methods("GET")
+	s.router.HandleFunc("/api/audit", s.orchestrator.auth.RequireHTTP(middleware.PermAuditRead, audit.QueryHandler(s.orchestrator.audit, middleware.TenantOf))).Methods("GET")
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
 }
 
 func (s *Server) handleOrchestrate(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Description string `json:"description"`
+	req, err := orchestrate.Decode(w, r)
+	if err != nil {
+		orchestrate.WriteError(w, err)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	workflowID := uuid.New()
+	actor, actorType := audit.ActorFromRequest(r)
+	run := func(ctx context.Context) (*WorkflowResult, error) {
+		return s.execute(ctx, workflowID, req, actor, actorType, "/api/orchestrate")
+	}
+
+	if req.Async {
+		// Results are delivered to the IDE workspace; the audit log records
+		// the outcome
+		go run(requestid.Detach(r.Context()))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"workflow_id": workflowID.String(),
+			"status":      "accepted",
+		})
 		return
 	}
 
-	ctx := context.Background()
-	result, err := s.orchestrator.ExecuteTask(ctx, req.Description)
+	result, err := run(requestid.Detach(r.Context()))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Workflow %s failed [%s] (request %s): %v", workflowID, apierror.CategoryOf(err), requestid.FromContext(r.Context()), err)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -584,18 +591,38 @@ func (s *Server) handleOrchestrate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// execute runs one request and records it in the audit log
+func (s *Server) execute(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request, actor, actorType, resource string) (*WorkflowResult, error) {
+	result, err := s.orchestrator.ExecuteRequest(ctx, workflowID, req)
+
+	event := audit.Event{
+		Actor:       actor,
+		ActorType:   actorType,
+		Action:      audit.ActionOrchestrate,
+		Resource:    resource,
+		WorkflowID:  workflowID,
+		RequestHash: audit.HashRequest(req),
+		Status:      audit.StatusSuccess,
+	}
+	if err != nil {
+		event.Status = audit.StatusFailure
+	}
+	s.orchestrator.audit.Record(ctx, event)
+	return result, err
+}
+
 func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	agents := []map[string]interface{}{}
+	list := []map[string]interface{}{}
 
 	for agentType, agent := range s.orchestrator.agents {
-		agents = append(agents, map[string]interface{}{
+		list = append(list, map[string]interface{}{
 			"type":         agentType,
-			"capabilities": agent.GetCapabilities(),
+			"capabilities": agents.CapabilityNames(agent.GetCapabilities()),
 		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(agents)
+	json.NewEncoder(w).Encode(list)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -604,22 +631,35 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	settings := config.NewLoader(flag.CommandLine)
 	var (
-		port   = flag.String("port", "8090", "Server port")
-		ideURL = flag.String("ide", "http://localhost:8085", "IDE server URL")
+		port         = flag.String("port", "8090", "Server port")
+		ideURL       = flag.String("ide", "http://localhost:8085", "IDE server URL")
+		batchWorkers = flag.Int("batch-workers", 4, "Workers running batch orchestration items")
+		apiKey       = settings.Secret("groq-api-key", "Groq API key", "GROQ_API_KEY")
+		jwtSecret    = settings.Secret("jwt-secret", "Secret verifying JWTs issued by the API gateway; empty refuses every call to the audit route", "JWT_SECRET")
 	)
-	flag.Parse()
-
-	apiKey := os.Getenv("GROQ_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GROQ_API_KEY environment variable is required")
+	settings.Require("groq-api-key")
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if settings.PrintRequested() {
+		settings.Print(os.Stdout)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		log.Fatal(err)
 	}
 
 	// Create orchestrator
-	orchestrator := NewOrchestrator(apiKey, *ideURL)
+	orchestrator := NewOrchestrator(*apiKey, *ideURL)
+	if *jwtSecret != "" {
+		orchestrator.auth = middleware.NewAuthMiddleware(&config.AuthConfig{JWTSecret: *jwtSecret}, nil, nil, zap.NewNop())
+	}
 
 	// Create and start server
-	server := NewServer(orchestrator)
+	server := NewServer(orchestrator, *batchWorkers)
+	server.batches.Start(context.Background())
 
 	log.Printf("[ORCHESTRATOR] Starting on port %s", *port)
 	log.Printf("[IDE] Endpoint: %s", *ideURL)
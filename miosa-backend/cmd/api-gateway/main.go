@@ -0,0 +1,667 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/ai_providers"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/analysis"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/architect"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/communication"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/deployment"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/development"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/monitoring"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/recommender"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/strategy"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	dbpkg "github.com/sormind/OSA/miosa-backend/internal/db"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+	"github.com/sormind/OSA/miosa-backend/internal/plugins"
+	"github.com/sormind/OSA/miosa-backend/internal/redisconn"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"github.com/sormind/OSA/miosa-backend/internal/services/collaboration"
+	"github.com/sormind/OSA/miosa-backend/internal/services/consultation"
+	"github.com/sormind/OSA/miosa-backend/internal/services/gateway"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	Port         string
+	GroqKey      string
+	FastModel    string
+	DeepModel    string
+	DBUrl        string
+	RedisUrl     string
+	JWTSecret    string
+	Environment  string
+	E2BKey       string
+	RenderKey    string
+	DrainTimeout time.Duration
+	DrainState   string
+	ProbeEvery   time.Duration
+	AutoMigrate  bool
+	Plugins      []config.PluginConfig
+}
+
+type ChatRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+type AnalyzeRequest struct {
+	Content string `json:"content" binding:"required"`
+	Type    string `json:"type"` // business, technical, product
+}
+
+type ConsultationRequest struct {
+	Topic   string `json:"topic" binding:"required"`
+	Context string `json:"context"`
+	Phase   string `json:"phase"` // initial, exploration, deep-dive
+}
+
+type Response struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Model   string      `json:"model,omitempty"`
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func boolToEmoji(b bool) string {
+	if b {
+		return "✅"
+	}
+	return "⚠️"
+}
+
+// devJWTSecret signs tokens in development when JWT_SECRET is unset
+const devJWTSecret = "dev-secret-change-this"
+
+func loadConfig() *Config {
+	_ = godotenv.Load()
+
+	config := &Config{
+		Port:        getEnv("PORT", "8080"),
+		GroqKey:     os.Getenv("GROQ_API_KEY"),
+		FastModel:   getEnv("FAST_MODEL", "llama-3.1-8b-instant"),
+		DeepModel:   getEnv("DEEP_MODEL", "moonshotai/kimi-k2-instruct"),
+		DBUrl:       os.Getenv("DATABASE_URL"),
+		RedisUrl:    os.Getenv("REDIS_URL"),
+		JWTSecret:   os.Getenv("JWT_SECRET"),
+		Environment: os.Getenv("ENVIRONMENT"),
+		E2BKey:      os.Getenv("E2B_API_KEY"),
+		RenderKey:   os.Getenv("RENDER_API_KEY"),
+		DrainState:  getEnv("DRAIN_STATE_PATH", "data/drain-state.json"),
+		Plugins:     config.LoadPlugins(),
+	}
+	config.AutoMigrate = getEnv("AUTO_MIGRATE", "false") == "true"
+
+	// Anyone can forge tokens signed with the well-known development secret,
+	// so it is only used when development mode is asked for explicitly
+	if config.JWTSecret == "" || config.JWTSecret == devJWTSecret {
+		if config.Environment != "development" {
+			log.Fatal("JWT_SECRET must be set to a non-default value unless ENVIRONMENT=development")
+		}
+		log.Println("⚠️  JWT_SECRET not set; using the development secret")
+		config.JWTSecret = devJWTSecret
+	}
+
+	config.DrainTimeout = 60 * time.Second
+	if v := os.Getenv("DRAIN_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.DrainTimeout = d
+		} else {
+			log.Printf("Invalid DRAIN_TIMEOUT %q, using %s", v, config.DrainTimeout)
+		}
+	}
+
+	config.ProbeEvery = gateway.DefaultProbeInterval
+	if v := os.Getenv("PROVIDER_PROBE_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.ProbeEvery = d
+		} else {
+			log.Printf("Invalid PROVIDER_PROBE_INTERVAL %q, using %s", v, config.ProbeEvery)
+		}
+	}
+
+	// Log what's configured
+	log.Println("🔧 Configuration Status:")
+	log.Printf("  %v Groq API: %v", boolToEmoji(config.GroqKey != ""), config.GroqKey != "")
+	log.Printf("  %v Database: %v", boolToEmoji(config.DBUrl != ""), config.DBUrl != "")
+	log.Printf("  %v Redis: %v", boolToEmoji(config.RedisUrl != ""), config.RedisUrl != "")
+	log.Printf("  %v E2B: %v", boolToEmoji(config.E2BKey != ""), config.E2BKey != "")
+	log.Printf("  %v Render: %v", boolToEmoji(config.RenderKey != ""), config.RenderKey != "")
+	log.Printf("  %v Agent plugins: %d", boolToEmoji(len(config.Plugins) > 0), len(config.Plugins))
+
+	return config
+}
+
+func callGroq(client *groq.Client, model string, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := client.ChatCompletion(ctx, groq.ChatCompletionRequest{
+		Model: groq.ChatModel(model),
+		Messages: []groq.ChatCompletionMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(response.Choices) > 0 {
+		return response.Choices[0].Message.Content, nil
+	}
+
+	return "", fmt.Errorf("no response from model")
+}
+
+func main() {
+	// "api-gateway migrate <command>" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	log.Println("🚀 Starting MIOSA API Gateway with Full Integration")
+
+	// Load configuration
+	cfg := loadConfig()
+
+	// Initialize logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+
+	// Initialize database connection (optional - will work without it)
+	var db *sql.DB
+	if cfg.DBUrl != "" {
+		db, err = sql.Open("postgres", cfg.DBUrl)
+		if err != nil {
+			logger.Warn("Database connection failed, continuing without DB", zap.Error(err))
+		} else {
+			db.SetMaxOpenConns(25)
+			db.SetMaxIdleConns(10)
+			db.SetConnMaxLifetime(5 * time.Minute)
+			if err := db.Ping(); err != nil {
+				logger.Warn("Database ping failed", zap.Error(err))
+				db = nil
+			} else {
+				logger.Info("✅ Connected to PostgreSQL")
+				defer db.Close()
+
+				if cfg.AutoMigrate {
+					if err := dbpkg.Migrate(db, os.Getenv("MIGRATIONS_PATH")); err != nil {
+						logger.Fatal("Database migration failed", zap.Error(err))
+					}
+					logger.Info("✅ Database migrations applied")
+				}
+			}
+		}
+	}
+
+	// Initialize Redis connection (optional - will work without it).
+	// REDIS_URL may name a standalone server, a Sentinel master or a Cluster.
+	var redisClient redis.UniversalClient
+	if cfg.RedisUrl != "" {
+		client, err := redisconn.New(cfg.RedisUrl)
+		if err != nil {
+			logger.Warn("Redis URL parse failed", zap.Error(err))
+		} else {
+			redisClient = client
+			ctx := context.Background()
+			if err := redisClient.Ping(ctx).Err(); err != nil {
+				logger.Warn("Redis connection failed", zap.Error(err))
+				redisClient = nil
+			} else {
+				logger.Info("✅ Connected to Redis", zap.String("mode", redisconn.Mode(cfg.RedisUrl)))
+			}
+		}
+	}
+
+	// Initialize Groq client
+	var groqClient *groq.Client
+	if cfg.GroqKey != "" && cfg.GroqKey != "gsk_YOUR_ACTUAL_KEY_HERE" {
+		groqClient, err = groq.NewClient(cfg.GroqKey)
+		if err != nil {
+			logger.Error("Failed to create Groq client", zap.Error(err))
+		} else {
+			logger.Info("✅ Groq client initialized")
+		}
+	} else {
+		logger.Warn("GROQ_API_KEY not configured - API features limited")
+	}
+
+	// Initialize agent orchestrator
+	var orchestrator *agents.Orchestrator
+	pluginManager := plugins.NewManager(logger)
+	defer pluginManager.Close()
+	if groqClient != nil {
+		// Register all agents from their packages
+		agents.Register(communication.New(groqClient))
+		agents.Register(analysis.New(groqClient))
+		agents.Register(development.New(groqClient))
+		agents.Register(quality.New(groqClient))
+		agents.Register(deployment.New(groqClient))
+		agents.Register(architect.New(groqClient))
+		agents.Register(monitoring.New(groqClient))
+		agents.Register(strategy.New(groqClient))
+
+		// Register new agents with Redis support
+		recommenderAgent := recommender.New(groqClient)
+		if redisClient != nil {
+			recommenderAgent.SetRedis(redisClient)
+			recommenderAgent.SetLogger(logger)
+		}
+		agents.Register(recommenderAgent)
+
+		aiProvidersAgent := ai_providers.New(groqClient)
+		if redisClient != nil {
+			aiProvidersAgent.SetRedis(redisClient)
+			aiProvidersAgent.SetLogger(logger)
+		}
+		agents.Register(aiProvidersAgent)
+
+		// Out-of-process agents declared in AGENT_PLUGINS
+		if len(cfg.Plugins) > 0 {
+			loaded := pluginManager.LoadAll(context.Background(), cfg.Plugins)
+			logger.Info("Loaded agent plugins", zap.Int("loaded", loaded), zap.Int("configured", len(cfg.Plugins)))
+		}
+
+		orchestrator = agents.NewOrchestrator(groqClient, logger, nil)
+		logger.Info("✅ Agent orchestrator initialized with all agents")
+	}
+
+	// Setup Gin with production settings
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+
+	// Recovery middleware (must be first)
+	r.Use(gin.Recovery())
+
+	// Request IDs come next so every later middleware and log sees them
+	r.Use(requestid.Gin())
+
+	// Initialize our middleware chain
+
+	// 1. Security middleware
+	securityConfig := middleware.DefaultSecurityConfig()
+	securityMiddleware := middleware.NewSecurityMiddleware(logger, securityConfig)
+	r.Use(securityMiddleware.Handle())
+
+	// 2. Logging middleware
+	loggingConfig := &middleware.LoggingConfig{
+		SkipPaths:       []string{"/health", "/metrics"},
+		SlowRequestTime: 2 * time.Second,
+		Level:           "info",
+		Environment:     "production",
+	}
+	loggingMiddleware, err := middleware.NewLoggingMiddleware(loggingConfig)
+	if err != nil {
+		logger.Error("Failed to create logging middleware", zap.Error(err))
+	} else {
+		r.Use(loggingMiddleware.Handle())
+	}
+
+	// 3. Auth middleware (skip for public endpoints). Redis is optional and
+	// only needed for token revocation.
+	var apiKeyHandlers *gateway.APIKeyHandlers
+	// manageKeys admits admins and callers holding keys:manage
+	var manageKeys gin.HandlerFunc
+	// require gates a route on an RBAC permission. Without a database there
+	// is no caller identity to check, so gated routes fail closed.
+	require := func(middleware.Permission) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication is not configured"})
+		}
+	}
+	// chatWrite admits developers and callers holding chat:write
+	chatWrite := require(middleware.PermOrchestrateExecute)
+	if db != nil {
+		authConfig := &config.AuthConfig{
+			JWTSecret: cfg.JWTSecret,
+		}
+		authMiddleware := middleware.NewAuthMiddleware(authConfig, db, redisClient, logger)
+		apiKeyHandlers = gateway.NewAPIKeyHandlers(authMiddleware.APIKeys(), logger)
+		rbac := middleware.NewRBACMiddleware(logger)
+		require = rbac.Require
+		manageKeys = rbac.RequireOrScope(middleware.PermTenantsManage, middleware.ScopeKeysManage)
+		chatWrite = rbac.RequireOrScope(middleware.PermOrchestrateExecute, middleware.ScopeChatWrite)
+		// Apply selectively to protected routes
+		r.Use(func(c *gin.Context) {
+			// Skip auth for public endpoints
+			publicPaths := []string{"/health", "/api/capabilities", "/api/auth/login", "/api/auth/register"}
+			for _, path := range publicPaths {
+				if c.Request.URL.Path == path {
+					c.Next()
+					return
+				}
+			}
+			authMiddleware.Handle()(c)
+		})
+	}
+
+	// 4. Rate limiting middleware
+	if redisClient != nil {
+		rateLimitConfig := middleware.DefaultRateLimitConfig()
+		rateLimitMiddleware := middleware.NewRateLimitMiddleware(redisClient, logger, rateLimitConfig)
+		r.Use(rateLimitMiddleware.Handle())
+	}
+
+	// CORS configuration
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Initialize gateway handlers
+	handlers := gateway.NewHandlers(orchestrator, groqClient, logger)
+
+	// Audit log (append-only orchestration_audit table when a DB is available)
+	var auditLog *audit.Log
+	if db != nil {
+		auditLog = audit.New(audit.NewPostgresStore(db), logger)
+	} else {
+		auditLog = audit.New(audit.NewMemoryStore(), logger)
+	}
+	handlers.SetAuditLog(auditLog)
+
+	// Marketplace agents registered at runtime, restored from their manifests
+	var manifestStore plugins.ManifestStore
+	if db != nil {
+		manifestStore = plugins.NewPostgresManifestStore(db)
+	} else {
+		manifestStore = plugins.NewFileManifestStore(getEnv("AGENT_MANIFESTS_PATH", "data/agent-manifests.json"))
+	}
+	if restored, err := pluginManager.Restore(context.Background(), manifestStore); err != nil {
+		logger.Warn("Failed to restore marketplace agents", zap.Error(err))
+	} else if restored > 0 {
+		logger.Info("Restored marketplace agents", zap.Int("count", restored))
+	}
+	agentRegistryHandlers := gateway.NewAgentRegistryHandlers(pluginManager, manifestStore, auditLog, logger)
+
+	// Tenant stack profiles live in tenants.settings when a database is available
+	if db != nil {
+		agents.DefaultStackProfiles = agents.NewPostgresStackProfileStore(db)
+	}
+	stackProfileHandlers := gateway.NewStackProfileHandlers(agents.DefaultStackProfiles, logger)
+
+	// Drainer tracks in-flight workflows so shutdown can finish or persist them
+	drainer := agents.NewDrainer(agents.NewFileDrainStore(cfg.DrainState), logger)
+	handlers.SetDrainer(drainer)
+
+	// Orchestrations are deferred while the model provider is down and
+	// resumed once a probe of its model list succeeds
+	var probe func(ctx context.Context) error
+	if groqClient != nil {
+		catalog := agents.NewModelCatalog(agents.DefaultModelsURL, cfg.GroqKey)
+		probe = func(ctx context.Context) error {
+			_, err := catalog.Sync(ctx)
+			return err
+		}
+	}
+	providerMonitor := gateway.NewProviderMonitor("groq", probe, cfg.ProbeEvery, logger)
+	handlers.SetDegradation(providerMonitor, gateway.NewDeferredQueue(gateway.MaxDeferred, logger))
+	go providerMonitor.Run(context.Background())
+
+	// Chat sessions persist in Postgres when available, otherwise Redis
+	var chatStore gateway.ChatSessionStore
+	switch {
+	case db != nil:
+		chatStore = gateway.NewPostgresChatSessionStore(db)
+	case redisClient != nil:
+		chatStore = gateway.NewRedisChatSessionStore(redisClient, 30*24*time.Hour, 500)
+	default:
+		chatStore = gateway.NewMemoryChatSessionStore()
+	}
+	chatSessionHandlers := gateway.NewChatSessionHandlers(groqClient, chatStore, logger)
+
+	streamConfig := gateway.DefaultChatStreamConfig()
+	streamConfig.ChatModel = cfg.FastModel
+	streamConfig.ConsultationModel = cfg.DeepModel
+	chatStreamHandler := gateway.NewChatStreamHandler(groqClient, streamConfig, logger)
+
+	// Consultations keep their phase history in Postgres when available
+	var consultationStore consultation.Store = consultation.NewMemoryStore()
+	if db != nil {
+		consultationStore = consultation.NewPostgresStore(db)
+	}
+	var consultationCompleter consultation.Completer
+	if groqClient != nil {
+		consultationCompleter = groqClient
+	}
+	consultationHandlers := consultation.NewHandlers(consultationCompleter, consultationStore, cfg.DeepModel, logger)
+
+	// Initialize collaboration handlers (only if Redis is available)
+	var collabHandlers *collaboration.Handlers
+	if redisClient != nil {
+		collabHandlers = collaboration.NewHandlers(orchestrator, redisClient, logger)
+		collabHandlers.SetAuditLog(auditLog)
+		collabHandlers.SetDrainer(drainer)
+		if db != nil {
+			// Postgres is the source of truth for learned patterns; Redis caches them
+			store := collaboration.NewCachedPatternStore(collaboration.NewPostgresPatternStore(db), redisClient, collaboration.DefaultPatternCacheTTL)
+			if err := collabHandlers.SetPatternStore(context.Background(), store); err != nil {
+				logger.Warn("Failed to load collaboration patterns", zap.Error(err))
+			}
+		}
+		collabHandlers.StartPatternPruning(context.Background(), time.Hour)
+		if v := os.Getenv("COLLABORATION_EXPLORATION"); v != "" {
+			if strategy, err := collaboration.ParseExplorationStrategy(v); err != nil {
+				logger.Warn("Ignoring invalid COLLABORATION_EXPLORATION", zap.Error(err))
+			} else {
+				policy := collaboration.DefaultExplorationPolicy
+				policy.Strategy = strategy
+				if eps, err := strconv.ParseFloat(getEnv("COLLABORATION_EPSILON", "0.1"), 64); err == nil {
+					policy.Epsilon = eps
+				}
+				collabHandlers.SetExplorationPolicy(policy)
+			}
+		}
+		if seed := os.Getenv("COLLABORATION_PATTERN_SEED"); seed != "" {
+			if err := collabHandlers.SeedPatterns(context.Background(), seed); err != nil {
+				logger.Warn("Failed to seed collaboration patterns", zap.Error(err))
+			}
+		}
+	}
+
+	// Health check
+	r.GET("/health", handlers.HealthCheck)
+
+	handlers.SetFeatures(map[string]bool{
+		"auth":          db != nil,
+		"rate_limiting": redisClient != nil,
+		"collaboration": collabHandlers != nil,
+		"audit":         true,
+	})
+
+	// Streaming chat and consultation over WebSocket
+	r.GET("/ws/chat", chatWrite, chatStreamHandler.Handle)
+
+	// API routes
+	api := r.Group("/api")
+	{
+		// Main agent execution endpoint
+		api.POST("/agents/execute", require(middleware.PermOrchestrateExecute), handlers.ExecuteAgent)
+
+		// What works right now, and orchestrations deferred while the model provider is down
+		api.GET("/capabilities", handlers.Capabilities)
+		api.GET("/deferred/:id", require(middleware.PermWorkflowsRead), handlers.GetDeferred)
+
+		// Type-specific generation pipelines
+		api.POST("/generate", require(middleware.PermOrchestrateExecute), handlers.Generate)
+
+		// Legacy chat endpoint for backward compatibility
+		api.POST("/chat", handlers.Chat)
+
+		// Conversational chat sessions
+		api.POST("/chat/sessions", chatWrite, chatSessionHandlers.CreateSession)
+		api.GET("/chat/sessions", require(middleware.PermWorkflowsRead), chatSessionHandlers.ListSessions)
+		api.GET("/chat/sessions/:id", require(middleware.PermWorkflowsRead), chatSessionHandlers.GetSession)
+		api.POST("/chat/sessions/:id/messages", chatWrite, chatSessionHandlers.SendMessage)
+		api.DELETE("/chat/sessions/:id", chatWrite, chatSessionHandlers.DeleteSession)
+
+		// Phased consultations
+		api.POST("/consultation/sessions", require(middleware.PermOrchestrateExecute), consultationHandlers.StartSession)
+		api.GET("/consultation/sessions", require(middleware.PermWorkflowsRead), consultationHandlers.ListSessions)
+		api.GET("/consultation/sessions/:id", require(middleware.PermWorkflowsRead), consultationHandlers.GetSession)
+		api.POST("/consultation/sessions/:id/messages", require(middleware.PermOrchestrateExecute), consultationHandlers.SendMessage)
+		api.POST("/consultation/sessions/:id/phase", require(middleware.PermOrchestrateExecute), consultationHandlers.Transition)
+
+		// Collaboration endpoints (only if handlers available)
+		if collabHandlers != nil {
+			api.POST("/collaboration/execute", require(middleware.PermOrchestrateExecute), collabHandlers.ExecuteCollaborativeTask)
+			api.POST("/collaborations", require(middleware.PermOrchestrateExecute), collabHandlers.CreateCollaboration)
+			api.GET("/collaborations", require(middleware.PermWorkflowsRead), collabHandlers.ListCollaborations)
+			api.GET("/collaborations/:id", require(middleware.PermWorkflowsRead), collabHandlers.GetCollaboration)
+			api.GET("/collaborations/:id/tasks/:task_id", require(middleware.PermWorkflowsRead), collabHandlers.GetCollaborationTask)
+			api.POST("/collaborations/:id/tasks/:task_id/feedback", require(middleware.PermOrchestrateExecute), collabHandlers.SubmitFeedback)
+			api.GET("/collaboration/patterns/export", require(middleware.PermImprovementsApprove), collabHandlers.ExportPatterns)
+			api.POST("/collaboration/patterns/import", require(middleware.PermImprovementsApprove), collabHandlers.ImportPatterns)
+			api.POST("/collaboration/patterns/feedback", require(middleware.PermOrchestrateExecute), collabHandlers.SubmitPatternFeedback)
+		}
+
+		// Marketplace agent registration
+		api.POST("/agents/register", require(middleware.PermAgentsManage), agentRegistryHandlers.RegisterAgent)
+		api.GET("/agents/registered", require(middleware.PermAgentsManage), agentRegistryHandlers.ListAgents)
+		api.DELETE("/agents/:type", require(middleware.PermAgentsManage), agentRegistryHandlers.DeregisterAgent)
+
+		// House stack and conventions injected into generation prompts
+		api.GET("/stack-profile", require(middleware.PermWorkflowsRead), stackProfileHandlers.GetProfile)
+		api.PUT("/stack-profile", require(middleware.PermTenantsManage), stackProfileHandlers.SetProfile)
+
+		// Compliance review of orchestration actions
+		api.GET("/audit", require(middleware.PermAuditRead), handlers.QueryAudit)
+
+		// API key management (only if auth is enabled)
+		if apiKeyHandlers != nil {
+			api.POST("/keys", manageKeys, apiKeyHandlers.CreateKey)
+			api.GET("/keys", manageKeys, apiKeyHandlers.ListKeys)
+			api.DELETE("/keys/:id", manageKeys, apiKeyHandlers.RevokeKey)
+		}
+
+		// Additional endpoints can be added here as needed
+		// All complex logic should go through the agent system
+	}
+
+	// Setup graceful shutdown
+	srv := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+
+	// Resume workflows that were still running when the last instance drained
+	if orchestrator != nil {
+		resumed, err := drainer.Resume(context.Background(), func(ctx context.Context, work agents.InFlight) error {
+			result, err := orchestrator.Execute(ctx, work.Task)
+			status := audit.StatusSuccess
+			if err != nil || !result.Success {
+				status = audit.StatusFailure
+			}
+			auditLog.Record(ctx, audit.FromTaskContext(audit.Event{
+				WorkflowID: work.Task.ID,
+				Action:     audit.ActionOrchestrate,
+				Resource:   work.Source,
+				Status:     status,
+				Metadata:   map[string]string{"resumed": "true"},
+			}, work.Task.Context))
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed to resume drained workflows", zap.Error(err))
+		} else if resumed > 0 {
+			logger.Info("Resumed drained workflows", zap.Int("count", resumed))
+		}
+	}
+
+	// Start server in goroutine
+	go func() {
+		logger.Info("🚀 MIOSA API Gateway starting",
+			zap.String("port", cfg.Port),
+			zap.Bool("database", db != nil),
+			zap.Bool("redis", redisClient != nil),
+			zap.Bool("groq", groqClient != nil))
+
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start server", zap.Error(err))
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info("Shutting down server...")
+
+	// Stop accepting orchestrations and wait for running ones; anything still
+	// running after DrainTimeout is persisted and resumed on next start
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	if err := drainer.Drain(drainCtx); err != nil {
+		logger.Warn("Drain incomplete", zap.Error(err))
+	}
+	drainCancel()
+
+	// Graceful shutdown with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Fatal("Server forced to shutdown", zap.Error(err))
+	}
+
+	logger.Info("Server exited properly")
+}
+
+// runMigrate applies a migrate subcommand against DATABASE_URL
+func runMigrate(args []string) {
+	_ = godotenv.Load()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := dbpkg.RunMigrateCommand(db, os.Getenv("MIGRATIONS_PATH"), args, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
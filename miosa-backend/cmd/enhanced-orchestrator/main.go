@@ -3,14 +3,19 @@ package main
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,27 +23,88 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"io"
+	"github.com/redis/go-redis/v9"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/ai_providers"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/analysis"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/architect"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/communication"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/deployment"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/development"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/monitoring"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/recommender"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/strategy"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/catalog"
+	"github.com/sormind/OSA/miosa-backend/internal/claude"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/diagram"
+	"github.com/sormind/OSA/miosa-backend/internal/digest"
+	"github.com/sormind/OSA/miosa-backend/internal/flags"
+	"github.com/sormind/OSA/miosa-backend/internal/githubapp"
+	"github.com/sormind/OSA/miosa-backend/internal/graph"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+	"github.com/sormind/OSA/miosa-backend/internal/langdetect"
+	"github.com/sormind/OSA/miosa-backend/internal/knowledge"
+	"github.com/sormind/OSA/miosa-backend/internal/liveconfig"
+	"github.com/sormind/OSA/miosa-backend/internal/logctx"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+	"github.com/sormind/OSA/miosa-backend/internal/moderation"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
+	"github.com/sormind/OSA/miosa-backend/internal/redisconn"
+	"github.com/sormind/OSA/miosa-backend/internal/reporting"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"github.com/sormind/OSA/miosa-backend/internal/schedule"
+	"github.com/sormind/OSA/miosa-backend/internal/services/ide"
+	"github.com/sormind/OSA/miosa-backend/internal/slack"
+	"github.com/sormind/OSA/miosa-backend/internal/transcript"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
 	"github.com/conneroisu/groq-go"
+	_ "github.com/lib/pq"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
+// DefaultWorkflowTTL is how long finished workflows are kept in memory
+const DefaultWorkflowTTL = 24 * time.Hour
+
 // EnhancedOrchestrator manages agents with proper file generation
 type EnhancedOrchestrator struct {
-	registry     map[agents.AgentType]agents.Agent
-	groqClient   *groq.Client
-	logger       *zap.Logger
-	workspaceDir string
-	mu           sync.RWMutex
+	registry      map[agents.AgentType]agents.Agent
+	groqClient    *groq.Client
+	logger        *zap.Logger
+	workspaceDir  string
+	grafana       *monitoring.GrafanaClient
+	grafanaDir    string
+	loadTest      *quality.LoadTestConfig
+	e2eTarget     string
+	signer        integrity.Signer
+	blobs         *workspace.BlobStore
+	models        *agents.ModelCatalog
+	testFixRounds int
+	frontendFixes int
+	spillAt       int
+	c4            bool
+	patchMode     bool
+	flags         *flags.Service
+	digests       *digest.Log
+	transcripts   bool
+	workflowTTL   time.Duration
+	workflows     map[uuid.UUID]*WorkflowResult
+	clarifying    map[uuid.UUID]*pendingClarification
+	knowledge     *knowledge.Base
+	audit         *audit.Log
+	checkpoints   agents.CheckpointStore
+	graphs        graph.Store
+	schedules     schedule.Store
+	artifacts     *agents.OutputRegistry
+	live          *liveconfig.Store
+	moderator     *moderation.Moderator
+	auth          *middleware.AuthMiddleware
+	mu            sync.RWMutex
 }
 
 // CodeFile represents a parsed code file
@@ -54,8 +120,9 @@ func NewEnhancedOrchestrator(apiKey, workspaceDir string) (*EnhancedOrchestrator
 	if err != nil {
 		return nil, err
 	}
+	logger = redact.Logger(logger)
 
-	groqClient, err := groq.NewClient(apiKey)
+	groqClient, err := agents.NewLLMClient(apiKey, "")
 	if err != nil {
 		return nil, err
 	}
@@ -65,12 +132,171 @@ func NewEnhancedOrchestrator(apiKey, workspaceDir string) (*EnhancedOrchestrator
 		groqClient:   groqClient,
 		logger:       logger,
 		workspaceDir: workspaceDir,
+		spillAt:      agents.DefaultSpillThreshold,
+		workflowTTL:  DefaultWorkflowTTL,
+		workflows:    make(map[uuid.UUID]*WorkflowResult),
+		clarifying:   make(map[uuid.UUID]*pendingClarification),
+		knowledge:    knowledge.New(nil, nil),
+		audit:        audit.New(audit.NewMemoryStore(), logger),
+		checkpoints:  agents.NewFileCheckpointStore(filepath.Join(workspaceDir, ".checkpoints")),
+		graphs:       graph.NewMemoryStore(),
+		schedules:    schedule.NewMemoryStore(),
+		artifacts:    agents.NewOutputRegistry(),
+		live:         liveconfig.NewStore(agents.DefaultLLMGuard, logger),
+		flags:        flags.NewService(flags.NewMemoryStore(), flags.DefaultTTL, logger),
+		digests:      digest.NewLog(),
 	}
 
 	o.registerAllAgents()
 	return o, nil
 }
 
+// SetAuth authenticates callers of the admin, audit and per-workflow
+// routes; without it those routes refuse every request
+func (o *EnhancedOrchestrator) SetAuth(auth *middleware.AuthMiddleware) {
+	o.auth = auth
+}
+
+// SetGrafana enables provisioning of generated dashboards into the given
+// Grafana folder
+func (o *EnhancedOrchestrator) SetGrafana(client *monitoring.GrafanaClient, folder string) {
+	o.grafana = client
+	o.grafanaDir = folder
+}
+
+// SetTestFixRounds sets how many times the development agent is asked to
+// fix failing generated tests when quality.DefaultSandbox runs them
+func (o *EnhancedOrchestrator) SetTestFixRounds(n int) {
+	o.testFixRounds = n
+}
+
+// SetFrontendFixRounds sets how many times the development agent is asked
+// to fix generated React apps that fail to build, type check or lint when
+// quality.DefaultSandbox builds them
+func (o *EnhancedOrchestrator) SetFrontendFixRounds(n int) {
+	o.frontendFixes = n
+}
+
+// SetSpillThreshold sets the output size, in bytes, beyond which agent
+// outputs are kept in the workspace and returned by reference; zero or
+// less returns every output inline
+func (o *EnhancedOrchestrator) SetSpillThreshold(n int) {
+	o.spillAt = n
+}
+
+// SetWorkflowTTL sets how long finished workflows, and workflows awaiting
+// clarification, are kept in memory; zero or less keeps them until restart
+func (o *EnhancedOrchestrator) SetWorkflowTTL(ttl time.Duration) {
+	o.mu.Lock()
+	o.workflowTTL = ttl
+	o.mu.Unlock()
+}
+
+// SetC4Diagrams also writes C4-PlantUML architecture diagrams alongside
+// the Mermaid ones
+func (o *EnhancedOrchestrator) SetC4Diagrams(enabled bool) {
+	o.c4 = enabled
+}
+
+// SetGraphStore sets where project knowledge graphs are kept
+func (o *EnhancedOrchestrator) SetGraphStore(store graph.Store) {
+	o.graphs = store
+}
+
+// SetScheduleStore sets where scheduled workflows and their runs are kept
+func (o *EnhancedOrchestrator) SetScheduleStore(store schedule.Store) {
+	o.schedules = store
+}
+
+// SetE2ETarget sets the smoke-test deployment the generated Playwright
+// suite runs against when quality.DefaultSandbox is set; empty only writes
+// the suite
+func (o *EnhancedOrchestrator) SetE2ETarget(baseURL string) {
+	o.e2eTarget = baseURL
+}
+
+// SetSigner enables artifact signing: after each workflow, the checksums of
+// the files it generated are signed with signer and kept in its workspace
+func (o *EnhancedOrchestrator) SetSigner(signer integrity.Signer) {
+	o.signer = signer
+}
+
+// SetBlobDedupe stores the last generated content of files once per
+// checksum across workspaces, instead of a copy per workspace
+func (o *EnhancedOrchestrator) SetBlobDedupe(enabled bool) {
+	o.blobs = nil
+	if enabled {
+		o.blobs = workspace.NewBlobStore(filepath.Join(o.workspaceDir, workspace.BlobDir))
+	}
+	workspace.DefaultBlobs = o.blobs
+}
+
+// SetModelCatalog syncs the models the provider serves, warns about the
+// configured ones it no longer does, and keeps validating every LLM call
+// against it, resyncing every interval
+func (o *EnhancedOrchestrator) SetModelCatalog(ctx context.Context, catalog *agents.ModelCatalog, interval time.Duration) {
+	o.models = catalog
+	syncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	_, err := catalog.Sync(syncCtx)
+	cancel()
+	if err != nil {
+		o.logger.Warn("Failed to sync model catalog; models are not validated until it syncs", zap.Error(err))
+	}
+	for _, check := range catalog.Check(agents.ConfiguredModels(agents.DefaultLLMGuard, o.registry)) {
+		if !check.Available {
+			o.logger.Warn("Configured model is not available",
+				zap.String("model", check.Model),
+				zap.String("suggestion", check.Suggestion))
+		}
+	}
+	agents.DefaultLLMGuard.SetCatalog(catalog)
+	go catalog.Watch(ctx, interval, o.logger)
+}
+
+// SetPatchMode has the development agent fix failing tests by editing the
+// project's files rather than generating them again
+func (o *EnhancedOrchestrator) SetPatchMode(enabled bool) {
+	o.patchMode = enabled
+}
+
+// SetFlags replaces the feature flags deciding which agent behaviors each
+// workflow gets
+func (o *EnhancedOrchestrator) SetFlags(service *flags.Service) {
+	o.flags = service
+}
+
+// SetModerator screens every request with m before any agent runs
+func (o *EnhancedOrchestrator) SetModerator(m *moderation.Moderator) {
+	o.moderator = m
+}
+
+// SetTranscripts records the redacted prompt and response of every LLM
+// call a workflow makes, for export at /api/workflow/{id}/transcript
+func (o *EnhancedOrchestrator) SetTranscripts(enabled bool) {
+	o.transcripts = enabled
+}
+
+// withTranscript returns ctx recording the workflow's LLM calls into its
+// transcript, or ctx itself when transcripts are disabled
+func (o *EnhancedOrchestrator) withTranscript(ctx context.Context, workflowID uuid.UUID) context.Context {
+	if !o.transcripts {
+		return ctx
+	}
+	recorder, err := transcript.NewRecorder(o.projectDir(workflowID), workflowID)
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to open transcript", zap.Error(err))
+		return ctx
+	}
+	return agents.WithTranscript(ctx, recorder)
+}
+
+// SetLoadTest enables the performance stage: a k6 script is written into
+// each project and, when quality.DefaultLoadTester is set, run against the
+// configured deployment
+func (o *EnhancedOrchestrator) SetLoadTest(config quality.LoadTestConfig) {
+	o.loadTest = &config
+}
+
 func (o *EnhancedOrchestrator) registerAllAgents() {
 	// Create enhanced development agent that generates multiple files
 	o.registry[agents.DevelopmentAgent] = &EnhancedDevelopmentAgent{
@@ -94,6 +320,13 @@ func (o *EnhancedOrchestrator) registerAllAgents() {
 	o.registry[agents.RecommenderAgent] = recommender.New(o.groqClient)
 	o.registry[agents.AIProvidersAgent] = ai_providers.New(o.groqClient)
 
+	// Agents contribute handlers for the artifacts in their output
+	for _, agent := range o.registry {
+		if err := o.artifacts.RegisterAgent(agent); err != nil {
+			o.logger.Warn("Failed to register output handlers", zap.Error(err))
+		}
+	}
+
 	o.logger.Info("Registered enhanced agents", zap.Int("count", len(o.registry)))
 }
 
@@ -107,6 +340,11 @@ func (a *EnhancedDevelopmentAgent) GetType() agents.AgentType {
 	return agents.DevelopmentAgent
 }
 
+// Model returns the model the agent calls
+func (a *EnhancedDevelopmentAgent) Model() string {
+	return a.config.Model
+}
+
 func (a *EnhancedDevelopmentAgent) GetDescription() string {
 	return "Generates complete application code with multiple files"
 }
@@ -118,7 +356,12 @@ func (a *EnhancedDevelopmentAgent) GetCapabilities() []agents.Capability {
 	}
 }
 
+// Execute generates the application's files or, given the current files in
+// task.Parameters[agents.PatchFilesParam], edits to them
 func (a *EnhancedDevelopmentAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	if files := agents.PatchFiles(task); len(files) > 0 {
+		return agents.RequestPatches(ctx, a.groqClient, agents.DevelopmentAgent, a.config, task, files)
+	}
 	startTime := time.Now()
 
 	// Generate structured application code
@@ -144,10 +387,17 @@ Generate the following files:
 10. README.md with setup instructions
 
 Make it a complete, runnable application.`, task.Input)
+	// Build what the architect declared, and put shared code where it said
+	if spec := agents.TaskArchitecture(task); spec != nil {
+		prompt += "\n\n" + spec.Prompt()
+	}
+	if plan := agents.TaskMonorepo(task); plan != nil {
+		prompt += "\n\n" + plan.Prompt()
+	}
 
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, agents.DevelopmentAgent, groq.ChatCompletionRequest{
 		Model: groq.ChatModel(a.config.Model),
-		Messages: []groq.ChatCompletionMessage{
+		Messages: agents.WithStackProfile(ctx, task, []groq.ChatCompletionMessage{
 			{
 				Role:    "system",
 				Content: "You are an expert developer. Generate complete, production-ready applications with multiple files.",
@@ -156,7 +406,7 @@ Make it a complete, runnable application.`, task.Input)
 				Role:    "user",
 				Content: prompt,
 			},
-		},
+		}),
 		MaxTokens:   a.config.MaxTokens,
 		Temperature: float32(a.config.Temperature),
 		TopP:        float32(a.config.TopP),
@@ -181,387 +431,2674 @@ Make it a complete, runnable application.`, task.Input)
 	}, nil
 }
 
+// workflowSequence is the agent execution order
+var workflowSequence = []agents.AgentType{
+	agents.StrategyAgent,
+	agents.AnalysisAgent,
+	agents.ArchitectAgent,
+	agents.DevelopmentAgent, // This will generate actual code files
+	agents.QualityAgent,
+	agents.MonitoringAgent,
+	agents.DeploymentAgent,
+	agents.RecommenderAgent,
+}
+
 // ExecuteWorkflow runs complete multi-agent workflow with enhanced file generation
 func (o *EnhancedOrchestrator) ExecuteWorkflow(ctx context.Context, description string) (*WorkflowResult, error) {
-	workflowID := uuid.New()
-	results := make([]AgentResult, 0)
+	return o.ExecuteRequest(ctx, uuid.New(), &orchestrate.Request{Description: description})
+}
 
-	task := agents.Task{
-		ID:    workflowID,
-		Type:  "implementation",
-		Input: description,
-		Context: &agents.TaskContext{
-			Phase:  "initialization",
-			Memory: make(map[string]interface{}),
-		},
+// ExecuteRequest runs a workflow for a validated orchestrate request under
+// the given workflow ID
+func (o *EnhancedOrchestrator) ExecuteRequest(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request) (*WorkflowResult, error) {
+	// The tenant's stack profile decides the language and stack left open
+	if profile := agents.StackProfileFor(ctx, req.TenantID); profile != nil {
+		applied := *req
+		applied.ApplyProfile(profile)
+		req = &applied
+	}
+	task := req.Task(workflowID)
+	if req.Clarify {
+		if paused := o.askClarification(ctx, task); paused != nil {
+			return paused, nil
+		}
+	}
+	return o.runWorkflow(ctx, task, 0, nil)
+}
+
+// StatusAwaitingClarification is the status of a workflow paused for answers
+// to questions about its description
+const StatusAwaitingClarification = "awaiting_clarification"
+
+// pendingClarification is a workflow paused until its questions are answered
+type pendingClarification struct {
+	task          agents.Task
+	clarification *agents.Clarification
+}
+
+// askClarification has the analysis agent review the task before anything
+// runs. If it has questions the workflow is recorded as awaiting their
+// answers and returned; otherwise, or if asking fails, it returns nil and
+// the workflow proceeds.
+func (o *EnhancedOrchestrator) askClarification(ctx context.Context, task agents.Task) *WorkflowResult {
+	clarifier, ok := o.registry[agents.AnalysisAgent].(agents.Clarifier)
+	if !ok {
+		return nil
+	}
+	tenant := ""
+	if task.Context.TenantID != uuid.Nil {
+		tenant = task.Context.TenantID.String()
+	}
+	ctx = logctx.Workflow(logctx.NewContext(ctx, o.logger), task.ID.String(), tenant)
+	ctx = o.withTranscript(ctx, task.ID)
+	questions, err := clarifier.Clarify(ctx, task)
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to clarify request, proceeding without", zap.Error(err))
+		return nil
+	}
+	if len(questions) == 0 {
+		return nil
 	}
 
-	// Execute agents
-	agentSequence := []agents.AgentType{
-		agents.StrategyAgent,
-		agents.AnalysisAgent,
-		agents.ArchitectAgent,
-		agents.DevelopmentAgent, // This will generate actual code files
-		agents.QualityAgent,
-		agents.MonitoringAgent,
-		agents.DeploymentAgent,
-		agents.RecommenderAgent,
+	now := time.Now()
+	clarification := &agents.Clarification{Questions: questions, AskedAt: now}
+	workflow := &WorkflowResult{
+		WorkflowID:    task.ID,
+		Status:        StatusAwaitingClarification,
+		Timestamp:     now,
+		Clarification: clarification,
 	}
+	o.mu.Lock()
+	o.clarifying[task.ID] = &pendingClarification{task: task, clarification: clarification}
+	o.storeWorkflow(workflow)
+	o.mu.Unlock()
+
+	logctx.From(ctx).Info("Workflow awaiting clarification", zap.Int("questions", len(questions)))
+	orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventAwaitingClarification, WorkflowID: task.ID})
+	return workflow
+}
 
-	for _, agentType := range agentSequence {
-		agent, exists := o.registry[agentType]
-		if !exists {
-			continue
+// AnswerClarification merges the answers to a paused workflow's questions
+// into its task, returning the run of the workflow from the start. The
+// answers are checked, and the pause ended, before it returns.
+func (o *EnhancedOrchestrator) AnswerClarification(workflowID uuid.UUID, answers map[string]string) (func(ctx context.Context) (*WorkflowResult, error), error) {
+	o.mu.Lock()
+	pending, ok := o.clarifying[workflowID]
+	if !ok {
+		o.mu.Unlock()
+		return nil, apierror.New(apierror.CategoryNotFound, "workflow is not awaiting clarification")
+	}
+	clarification := *pending.clarification
+	if err := clarification.Answer(answers, time.Now()); err != nil {
+		o.mu.Unlock()
+		return nil, apierror.Wrap(apierror.CategoryValidation, err.Error(), err)
+	}
+	// The workflow is running again, so it is unknown until it finishes
+	delete(o.clarifying, workflowID)
+	delete(o.workflows, workflowID)
+	o.mu.Unlock()
+
+	task := pending.task
+	clarification.Apply(&task)
+	return func(ctx context.Context) (*WorkflowResult, error) {
+		workflow, err := o.runWorkflow(ctx, task, 0, nil)
+		if workflow != nil {
+			workflow.Clarification = &clarification
 		}
+		return workflow, err
+	}, nil
+}
 
-		o.logger.Info("Executing agent", zap.String("type", string(agentType)))
-		task.Context.Phase = string(agentType)
+// ResumeWorkflow restarts a workflow from the given agent, or from its first
+// failed step when from is empty, reusing checkpointed results of earlier
+// steps. Files written by earlier steps are left in place.
+func (o *EnhancedOrchestrator) ResumeWorkflow(ctx context.Context, workflowID uuid.UUID, from agents.AgentType) (*WorkflowResult, error) {
+	checkpoints, err := o.checkpoints.Load(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	start, err := agents.ResumeIndex(workflowSequence, checkpoints, from)
+	if err != nil {
+		return nil, err
+	}
 
-		result, err := agent.Execute(ctx, task)
-		if err != nil {
-			o.logger.Error("Agent failed", zap.Error(err))
-			continue
+	task := resumeTask(workflowID, checkpoints[0].Task)
+	prior := make([]*agents.Checkpoint, 0, start)
+	for _, cp := range checkpoints {
+		if cp.Step < start && cp.Succeeded() {
+			prior = append(prior, cp)
+			task.Context.Record(cp.Agent, cp.Result)
 		}
+	}
 
-		// Enhanced saving that parses and creates actual code files
-		if err := o.saveEnhancedOutput(agentType, workflowID, result); err != nil {
-			o.logger.Error("Failed to save output", zap.Error(err))
-		}
+	o.logger.Info("Resuming workflow",
+		zap.String("workflow_id", workflowID.String()),
+		zap.Int("start_step", start),
+		zap.Int("reused_steps", len(prior)))
 
-		results = append(results, AgentResult{
-			Agent:       agentType,
-			Success:     result.Success,
-			Output:      result.Output,
-			Confidence:  result.Confidence,
-			ExecutionMS: result.ExecutionMS,
-		})
+	return o.runWorkflow(ctx, task, start, prior)
+}
 
-		if task.Context.Memory == nil {
-			task.Context.Memory = make(map[string]interface{})
+// resumeTask rebuilds the task a workflow started with from its first
+// checkpoint: its input and parameters, and who it runs for. Step results
+// are not carried over; the caller records the ones it reuses.
+func resumeTask(workflowID uuid.UUID, base agents.Task) agents.Task {
+	task := agents.Task{
+		ID:         workflowID,
+		Type:       base.Type,
+		Input:      base.Input,
+		Parameters: make(map[string]interface{}, len(base.Parameters)),
+		Priority:   base.Priority,
+		Timeout:    base.Timeout,
+		Context: &agents.TaskContext{
+			Phase:    "resume",
+			Memory:   make(map[string]interface{}),
+			Metadata: make(map[string]string),
+		},
+	}
+	for k, v := range base.Parameters {
+		task.Parameters[k] = v
+	}
+	if base.Context != nil {
+		task.Context.UserID = base.Context.UserID
+		task.Context.TenantID = base.Context.TenantID
+		task.Context.WorkspaceID = base.Context.WorkspaceID
+		task.Context.SessionID = base.Context.SessionID
+		task.Context.ConsultationID = base.Context.ConsultationID
+		for k, v := range base.Context.Metadata {
+			task.Context.Metadata[k] = v
 		}
-		task.Context.Memory[string(agentType)] = result.Output
 	}
+	return task
+}
 
-	projectDir := filepath.Join(o.workspaceDir, workflowID.String()[:8])
-	o.triggerE2BWorkflow(projectDir)
+// runWorkflow executes workflowSequence from index start, checkpointing each
+// step. prior holds results reused from an earlier run.
+func (o *EnhancedOrchestrator) runWorkflow(ctx context.Context, task agents.Task, start int, prior []*agents.Checkpoint) (*WorkflowResult, error) {
+	workflowID := task.ID
+	run := uuid.New().String()
+	task.Context.RequestID = requestid.FromContext(ctx)
+	results := make([]AgentResult, 0, len(workflowSequence))
+
+	// Retention applies the tenant's policy to the project directory
+	owner := workspace.Owner{WorkflowID: workflowID.String()}
+	if task.Context.TenantID != uuid.Nil {
+		owner.TenantID = task.Context.TenantID.String()
+	}
+	// Every line logged for the workflow carries its IDs
+	ctx = logctx.Workflow(logctx.NewContext(ctx, o.logger), workflowID.String(), owner.TenantID)
+	ctx = o.withTranscript(ctx, workflowID)
+	if err := workspace.RecordOwner(o.projectDir(workflowID), owner); err != nil {
+		logctx.From(ctx).Warn("Failed to record workspace owner", zap.Error(err))
+	}
+	report := reporting.NewWorkflowReport(workflowID)
+	task = o.applyFlags(ctx, task, owner.TenantID, report)
+
+	for _, cp := range prior {
+		report.Add(cp.Agent, cp.Result)
+		reused := o.agentResult(ctx, workflowID, cp.Agent, cp.Result)
+		reused.Reused = true
+		results = append(results, reused)
+		step := results[len(results)-1].step()
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepCompleted, WorkflowID: workflowID, Step: cp.Step, Agent: cp.Agent, Result: &step})
+	}
 
-	return &WorkflowResult{
-		WorkflowID: workflowID,
-		Results:    results,
-		Success:    true,
-		Timestamp:  time.Now(),
-	}, nil
-}
+	for next := start; next < len(workflowSequence); {
+		steps := o.executeSteps(ctx, task, next, o.live.Group(workflowSequence, next))
+		next += len(steps)
 
-// saveEnhancedOutput parses output and saves as appropriate file types
-func (o *EnhancedOrchestrator) saveEnhancedOutput(agentType agents.AgentType, workflowID uuid.UUID, result *agents.Result) error {
-	projectDir := filepath.Join(o.workspaceDir, workflowID.String()[:8])
+		// Steps that ran together are recorded in sequence order
+		for _, s := range steps {
+			if s.skipped {
+				continue
+			}
+			step, agentType, result := s.step, s.agentType, s.result
+			if s.err != nil {
+				logctx.From(s.ctx).Error("Agent failed", apierror.Fields(s.err)...)
+				o.checkpoint(s.ctx, step, agentType, s.task, nil, s.err)
+				orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepFailed, WorkflowID: workflowID, Step: step, Agent: agentType, Error: s.err.Error()})
+				continue
+			}
 
-	switch agentType {
-	case agents.DevelopmentAgent:
-		// Parse and save multiple code files
-		files := o.parseCodeFiles(result.Output)
-		for _, file := range files {
-			filePath := filepath.Join(projectDir, file.Path)
-			dir := filepath.Dir(filePath)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return err
+			// Strip secrets before anything is written or returned
+			redactions := redact.Result(result)
+			o.checkpoint(s.ctx, step, agentType, s.task, result, nil)
+
+			// Enhanced saving that parses and creates actual code files
+			prov := stepProvenance(workflowID, run, step, agentType, result)
+			if err := o.saveEnhancedOutput(s.ctx, agentType, workflowID, prov, result); err != nil {
+				logctx.From(s.ctx).Error("Failed to save output", zap.Error(err))
 			}
-			if err := os.WriteFile(filePath, []byte(file.Content), 0644); err != nil {
-				return err
+
+			report.Add(agentType, result)
+			completedResult := o.agentResult(s.ctx, workflowID, agentType, result)
+			completedResult.Redactions = redactions
+			results = append(results, completedResult)
+			completed := results[len(results)-1].step()
+			orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepCompleted, WorkflowID: workflowID, Step: step, Agent: agentType, Result: &completed})
+
+			// Record the step; later prompts see it summarized, and what
+			// it designed or generated in the project graph
+			task.Context.Record(agentType, result)
+			if g := o.updateGraph(s.ctx, task, agentType, result); g != nil {
+				o.writeDiagrams(s.ctx, workflowID, prov, g)
 			}
-			o.logger.Info("Created code file", zap.String("path", filePath))
-		}
-
-	case agents.DeploymentAgent:
-		// Save as Docker and Kubernetes files
-		deployDir := filepath.Join(projectDir, "deployment")
-		os.MkdirAll(deployDir, 0755)
-		
-		// Extract Docker content
-		if dockerContent := o.extractSection(result.Output, "Dockerfile"); dockerContent != "" {
-			dockerPath := filepath.Join(deployDir, "Dockerfile")
-			os.WriteFile(dockerPath, []byte(dockerContent), 0644)
-		}
-		
-		// Extract K8s manifests
-		if k8sContent := o.extractSection(result.Output, "kubernetes"); k8sContent != "" {
-			k8sPath := filepath.Join(deployDir, "k8s-deployment.yaml")
-			os.WriteFile(k8sPath, []byte(k8sContent), 0644)
-		}
-
-		// Extract docker-compose
-		if composeContent := o.extractSection(result.Output, "docker-compose"); composeContent != "" {
-			composePath := filepath.Join(deployDir, "docker-compose.yml")
-			os.WriteFile(composePath, []byte(composeContent), 0644)
-		}
-
-	case agents.MonitoringAgent:
-		// Save monitoring configs
-		monitorDir := filepath.Join(projectDir, "monitoring")
-		os.MkdirAll(monitorDir, 0755)
-		
-		// Prometheus config
-		if promContent := o.extractSection(result.Output, "prometheus"); promContent != "" {
-			promPath := filepath.Join(monitorDir, "prometheus.yml")
-			os.WriteFile(promPath, []byte(promContent), 0644)
-		}
-		
-		// Grafana dashboards
-		if grafanaContent := o.extractSection(result.Output, "grafana"); grafanaContent != "" {
-			grafanaPath := filepath.Join(monitorDir, "grafana-dashboard.json")
-			os.WriteFile(grafanaPath, []byte(grafanaContent), 0644)
-		}
-
-	case agents.QualityAgent:
-		// Save test files
-		testDir := filepath.Join(projectDir, "tests")
-		os.MkdirAll(testDir, 0755)
-		
-		// Extract test code
-		if testContent := o.extractCodeBlocks(result.Output); len(testContent) > 0 {
-			for i, test := range testContent {
-				testPath := filepath.Join(testDir, fmt.Sprintf("test_%d.js", i+1))
-				os.WriteFile(testPath, []byte(test), 0644)
+			if spec, ok := result.Data[agents.ArchitectureKey].(*agents.ArchitectureSpec); ok {
+				o.writeArchitecture(s.ctx, workflowID, prov, spec)
 			}
 		}
+	}
 
-	default:
-		// Save documentation for other agents
-		docDir := filepath.Join(projectDir, "docs")
-		os.MkdirAll(docDir, 0755)
-		docPath := filepath.Join(docDir, fmt.Sprintf("%s.md", agentType))
-		os.WriteFile(docPath, []byte(result.Output), 0644)
+	projectDir := o.projectDir(workflowID)
+	frontend := o.runFrontendBuild(ctx, task, run, projectDir, report)
+	tests := o.runTests(ctx, task, run, projectDir, report)
+
+	// Project-wide files are derived after the last step and on every run
+	final := len(workflowSequence)
+	security := o.writeSecurity(ctx, workflowID, projectDir, task, stepProvenance(workflowID, run, final, agents.DevelopmentAgent, nil))
+	env := o.writeEnvManifest(ctx, workflowID, projectDir, stepProvenance(workflowID, run, final, agents.DeploymentAgent, nil))
+	routes := o.writeOpenAPI(ctx, workflowID, projectDir, stepProvenance(workflowID, run, final, agents.QualityAgent, nil))
+	monorepo := o.checkMonorepo(ctx, workflowID, projectDir, task, stepProvenance(workflowID, run, final, agents.ArchitectAgent, nil))
+	seed := o.writeSeed(ctx, workflowID, projectDir, task, stepProvenance(workflowID, run, final, agents.DeploymentAgent, nil))
+	terraform := o.writeTerraform(ctx, workflowID, projectDir, stepProvenance(workflowID, run, final, agents.DeploymentAgent, nil))
+	ci := o.writeCI(ctx, workflowID, projectDir, stepProvenance(workflowID, run, final, agents.DeploymentAgent, nil))
+	conflicts := o.conflicts(projectDir)
+	o.triggerE2BWorkflow(ctx, workflowID, projectDir)
+	report.LoadTest = o.runLoadTest(ctx, workflowID, projectDir, stepProvenance(workflowID, run, final, agents.QualityAgent, nil))
+	report.E2E = o.runE2E(ctx, workflowID, projectDir, stepProvenance(workflowID, run, final, agents.QualityAgent, nil))
+	o.signArtifacts(ctx, workflowID, projectDir)
+	if recorder, ok := agents.TranscriptFromContext(ctx).(*transcript.Recorder); ok && recorder.Err() != nil {
+		logctx.From(ctx).Error("Transcript is incomplete", zap.Error(recorder.Err()))
 	}
 
-	return nil
+	workflow := &WorkflowResult{
+		WorkflowID: workflowID,
+		Results:    results,
+		Success:    true,
+		Timestamp:  time.Now(),
+		Report:     report,
+		Env:        env,
+		Routes:     routes,
+		Monorepo:   monorepo,
+		Seed:       seed,
+		Terraform:  terraform,
+		CI:         ci,
+		Security:   security,
+		Conflicts:  conflicts,
+		Frontend:   frontend,
+		Tests:      tests,
+
+		Architecture: agents.TaskArchitecture(task),
+	}
+
+	o.mu.Lock()
+	o.storeWorkflow(workflow)
+	o.mu.Unlock()
+	o.digests.Record(digestRun(workflow, owner.TenantID))
+
+	return workflow, nil
 }
 
-// parseCodeFiles extracts multiple files from structured output
-func (o *EnhancedOrchestrator) parseCodeFiles(content string) []CodeFile {
-	var files []CodeFile
-	
-	// Pattern to match file blocks
-	filePattern := regexp.MustCompile(`=== FILE: (.+?) ===\n([\s\S]*?)(?:=== END FILE ===|$)`)
-	matches := filePattern.FindAllStringSubmatch(content, -1)
-	
-	for _, match := range matches {
-		if len(match) >= 3 {
-			files = append(files, CodeFile{
-				Path:    strings.TrimSpace(match[1]),
-				Content: strings.TrimSpace(match[2]),
-			})
+// digestRun summarizes a completed workflow for the tenant's digests. It
+// succeeded when every step did and the generated tests and frontend pass.
+func digestRun(w *WorkflowResult, tenantID string) digest.Run {
+	run := digest.Run{WorkflowID: w.WorkflowID.String(), TenantID: tenantID, Success: w.Success, CompletedAt: w.Timestamp}
+	for _, r := range w.Results {
+		run.Success = run.Success && r.Success
+	}
+	if w.Report != nil {
+		run.CostUSD = w.Report.TotalCostUSD
+	}
+	if w.Tests != nil && !w.Tests.Success {
+		run.Success = false
+		run.Issues = append(run.Issues, digest.Issue{Source: "tests", Kind: "failing_tests"})
+	}
+	if w.Frontend != nil {
+		run.Success = run.Success && w.Frontend.Success
+		for _, app := range w.Frontend.Apps {
+			for _, e := range app.Errors {
+				kind := e.Code
+				if kind == "" {
+					kind = e.Step + "_error"
+				}
+				run.Issues = append(run.Issues, digest.Issue{Source: "frontend", Kind: kind})
+			}
 		}
 	}
-	
-	// If no structured format, try to extract code blocks
-	if len(files) == 0 {
-		codeBlocks := o.extractCodeBlocks(content)
-		for i, block := range codeBlocks {
-			ext := o.detectLanguage(block)
-			files = append(files, CodeFile{
-				Path:    fmt.Sprintf("file_%d.%s", i+1, ext),
-				Content: block,
-			})
+	if w.Security != nil {
+		for _, f := range w.Security.Findings {
+			kind := f.Rule
+			if kind == "" {
+				kind = f.Title
+			}
+			run.Issues = append(run.Issues, digest.Issue{Source: "security", Kind: kind})
 		}
 	}
-	
-	return files
-}
-
-// extractCodeBlocks finds code blocks in markdown
-func (o *EnhancedOrchestrator) extractCodeBlocks(content string) []string {
-	var blocks []string
-	codePattern := regexp.MustCompile("```[a-z]*\n([\\s\\S]*?)```")
-	matches := codePattern.FindAllStringSubmatch(content, -1)
-	for _, match := range matches {
-		if len(match) >= 2 {
-			blocks = append(blocks, strings.TrimSpace(match[1]))
+	if w.Monorepo != nil {
+		for _, issue := range w.Monorepo.Issues {
+			run.Issues = append(run.Issues, digest.Issue{Source: "monorepo", Kind: issue.Kind})
 		}
 	}
-	return blocks
+	if w.CI != nil && len(w.CI.Issues) > 0 {
+		run.Issues = append(run.Issues, digest.Issue{Source: "ci", Kind: "invalid_workflow"})
+	}
+	if w.Routes != nil && len(w.Routes.Undocumented) > 0 {
+		run.Issues = append(run.Issues, digest.Issue{Source: "routes", Kind: "undocumented_endpoints"})
+	}
+	return run
 }
 
-// extractSection extracts specific sections from content
-func (o *EnhancedOrchestrator) extractSection(content, section string) string {
-	lower := strings.ToLower(content)
-	start := strings.Index(lower, strings.ToLower(section))
-	if start == -1 {
-		return ""
-	}
-	
-	// Find the content after the section header
-	subContent := content[start:]
-	lines := strings.Split(subContent, "\n")
-	
-	var result []string
-	inSection := false
-	for _, line := range lines {
-		if strings.Contains(strings.ToLower(line), strings.ToLower(section)) {
-			inSection = true
+// appliedImprovements lists the live config changes since from, which is
+// how the self-improvement engine's changes reach the orchestrator
+func (o *EnhancedOrchestrator) appliedImprovements(from time.Time) []digest.Improvement {
+	var improvements []digest.Improvement
+	for _, snap := range o.live.History() {
+		if snap.Kind == liveconfig.KindInitial || snap.CreatedAt.Before(from) {
 			continue
 		}
-		if inSection {
-			if strings.HasPrefix(line, "#") && !strings.Contains(line, section) {
-				break // Next section started
-			}
-			result = append(result, line)
+		summary := fmt.Sprintf("%s (config version %d)", snap.Kind, snap.Version)
+		if snap.Kind == liveconfig.KindRollback {
+			summary = fmt.Sprintf("rolled back to config version %d", snap.RolledBackTo)
 		}
+		improvements = append(improvements, digest.Improvement{Kind: snap.Kind, Summary: summary, AppliedAt: snap.CreatedAt})
 	}
-	
-	return strings.Join(result, "\n")
+	return improvements
 }
 
-// detectLanguage detects programming language from code
-func (o *EnhancedOrchestrator) triggerE2BWorkflow(projectPath string) {
-	e2bServerURL := "http://localhost:3001" // The Node.js server
-	o.logger.Info("Triggering E2B workflow", zap.String("path", projectPath))
+// renderDigest has the communication agent write a digest's summary
+func (o *EnhancedOrchestrator) renderDigest(ctx context.Context, d *digest.Digest) (string, error) {
+	communicator, ok := o.registry[agents.CommunicationAgent]
+	if !ok {
+		return "", fmt.Errorf("no communication agent is registered")
+	}
+	result, err := agents.ExecuteTracked(ctx, communicator, agents.Task{
+		ID:      uuid.New(),
+		Type:    "digest",
+		Input:   d.Prompt(),
+		Context: &agents.TaskContext{Phase: "digest"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result == nil || !result.Success {
+		return "", fmt.Errorf("communication agent did not render the digest")
+	}
+	return result.Output, nil
+}
 
-	payload := map[string]string{"path": projectPath}
-	jsonPayload, err := json.Marshal(payload)
+// updateGraph adds the services, endpoints, tables and dependencies a step
+// designed or generated to the project's knowledge graph, returning the
+// graph saved or nil if the step adds nothing
+func (o *EnhancedOrchestrator) updateGraph(ctx context.Context, task agents.Task, agentType agents.AgentType, result *agents.Result) *graph.Graph {
+	var add func(g *graph.Graph)
+	switch agentType {
+	case agents.AnalysisAgent, agents.ArchitectAgent:
+		add = func(g *graph.Graph) { g.AddDocument(string(agentType), result.Output) }
+	case agents.DevelopmentAgent, agents.DeploymentAgent:
+		files := o.projectFiles(o.projectDir(task.ID), nil)
+		add = func(g *graph.Graph) { g.AddFiles(files) }
+	default:
+		return nil
+	}
+
+	g, err := o.graphs.Load(ctx, task.ID)
+	if errors.Is(err, graph.ErrNotFound) {
+		g, err = graph.New(task.ID), nil
+	}
 	if err != nil {
-		o.logger.Error("Error creating JSON payload for E2B server", zap.Error(err))
-		return
+		logctx.From(ctx).Warn("Failed to load knowledge graph", zap.Error(err))
+		return nil
 	}
+	g.TenantID = task.Context.TenantID
+	add(g)
+	g.UpdatedAt = time.Now()
+	if err := o.graphs.Save(ctx, g); err != nil {
+		logctx.From(ctx).Warn("Failed to save knowledge graph", zap.Error(err))
+		return nil
+	}
+	return g
+}
 
-	resp, err := http.Post(e2bServerURL, "application/json", bytes.NewBuffer(jsonPayload))
+// writeDiagrams draws the project graph into docs/architecture.mmd, with
+// its SVG rendering for previews and, if enabled, a C4-PlantUML version.
+// The Mermaid source is parsed back before writing, so an invalid diagram
+// is logged rather than written.
+func (o *EnhancedOrchestrator) writeDiagrams(ctx context.Context, workflowID uuid.UUID, prov workspace.Provenance, g *graph.Graph) {
+	chart := diagram.FromGraph(g)
+	if chart == nil {
+		return
+	}
+	src := chart.String()
+	parsed, err := diagram.ParseMermaid(src)
 	if err != nil {
-		o.logger.Error("Error calling E2B server", zap.Error(err))
+		logctx.From(ctx).Warn("Generated an invalid architecture diagram", zap.Error(err))
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		o.logger.Error("E2B server returned non-OK status", zap.String("status", resp.Status), zap.String("body", string(body)))
+	docDir := filepath.Join(o.projectDir(workflowID), "docs")
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		logctx.From(ctx).Warn("Failed to write architecture diagrams", zap.Error(err))
 		return
 	}
-
-	o.logger.Info("Successfully triggered E2B workflow.")
-}
-
-// detectLanguage detects programming language from code
-func (o *EnhancedOrchestrator) detectLanguage(code string) string {
-	if strings.Contains(code, "package main") || strings.Contains(code, "func ") {
-		return "go"
+	files := map[string]string{
+		"architecture.mmd": src,
+		"architecture.svg": string(parsed.SVG()),
 	}
-	if strings.Contains(code, "const ") || strings.Contains(code, "function ") || strings.Contains(code, "=>") {
-		return "js"
+	if o.c4 {
+		files["architecture.puml"] = diagram.C4(g)
 	}
-	if strings.Contains(code, "def ") || strings.Contains(code, "import ") {
-		return "py"
+	for name, content := range files {
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(docDir, name), content); err != nil {
+			logctx.From(ctx).Warn("Failed to write architecture diagram", zap.String("file", name), zap.Error(err))
+		}
 	}
-	if strings.Contains(code, "FROM ") || strings.Contains(code, "RUN ") {
-		return "dockerfile"
+}
+
+// writeArchitecture keeps the architect's spec in docs/architecture.json,
+// next to its prose, for tools and later runs to read
+func (o *EnhancedOrchestrator) writeArchitecture(ctx context.Context, workflowID uuid.UUID, prov workspace.Provenance, spec *agents.ArchitectureSpec) {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to encode architecture spec", zap.Error(err))
+		return
 	}
-	if strings.Contains(code, "apiVersion:") || strings.Contains(code, "kind:") {
-		return "yaml"
+	docDir := filepath.Join(o.projectDir(workflowID), "docs")
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		logctx.From(ctx).Warn("Failed to write architecture spec", zap.Error(err))
+		return
 	}
-	if strings.Contains(code, "{") && strings.Contains(code, "}") {
-		return "json"
+	if err := o.writeFile(ctx, workflowID, prov, filepath.Join(docDir, "architecture.json"), string(data)+"\n"); err != nil {
+		logctx.From(ctx).Warn("Failed to write architecture spec", zap.Error(err))
 	}
-	return "txt"
 }
 
-// WorkflowResult represents complete workflow execution
-type WorkflowResult struct {
-	WorkflowID uuid.UUID     `json:"workflow_id"`
-	Results    []AgentResult `json:"results"`
-	Success    bool          `json:"success"`
-	Timestamp  time.Time     `json:"timestamp"`
+// graphPrompt renders the knowledge graph of the task's project for agent
+// prompts, or returns "" before any step added to it
+func (o *EnhancedOrchestrator) graphPrompt(ctx context.Context, task agents.Task) string {
+	g, err := o.graphs.Load(ctx, task.ID)
+	if err != nil {
+		if !errors.Is(err, graph.ErrNotFound) {
+			logctx.From(ctx).Warn("Failed to load knowledge graph", zap.Error(err))
+		}
+		return ""
+	}
+	return g.Prompt()
 }
 
-// AgentResult represents individual agent result
-type AgentResult struct {
-	Agent       agents.AgentType `json:"agent"`
-	Success     bool            `json:"success"`
-	Output      string          `json:"output"`
-	Confidence  float64         `json:"confidence"`
-	ExecutionMS int64           `json:"execution_ms"`
-}
+// maxReuseHints bounds the files listed by reuseHint
+const maxReuseHints = 20
 
-// API Server
-type Server struct {
-	orchestrator *EnhancedOrchestrator
-	router       *mux.Router
+// reuseHint lists the files of the task's project identical to ones
+// generated before, so agents keep proven boilerplate instead of rewriting
+// it. It returns "" when there are none.
+func (o *EnhancedOrchestrator) reuseHint(ctx context.Context, task agents.Task) string {
+	if o.blobs == nil {
+		return ""
+	}
+	ws, err := workspace.Open(o.projectDir(task.ID))
+	if err != nil {
+		return ""
+	}
+	var lines []string
+	for _, f := range ws.Files() {
+		if !f.Shared || len(lines) == maxReuseHints {
+			continue
+		}
+		if blob, err := o.blobs.Stat(f.Checksum); err == nil && blob.Refs > 1 {
+			lines = append(lines, fmt.Sprintf("- %s (identical to %d other generated files)", f.Path, blob.Refs-1))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "# Previously generated files\nThese files match output generated before; keep them as they are unless the task needs a change.\n" + strings.Join(lines, "\n")
 }
 
-func NewServer(orchestrator *EnhancedOrchestrator) *Server {
-	s := &Server{
-		orchestrator: orchestrator,
-		router:       mux.NewRouter(),
+// projectContext is the knowledge graph of the task's project followed by
+// its previously generated files
+func (o *EnhancedOrchestrator) projectContext(ctx context.Context, task agents.Task) string {
+	var sections []string
+	for _, section := range []string{o.graphPrompt(ctx, task), o.reuseHint(ctx, task)} {
+		if section != "" {
+			sections = append(sections, section)
+		}
 	}
-	s.setupRoutes()
-	return s
+	return strings.Join(sections, "\n\n")
 }
 
-func (s *Server) setupRoutes() {
-	s.router.HandleFunc("/api/orchestrate", s.handleOrchestrate).Methods("POST")
-	s.router.HandleFunc("/api/agents", s.handleListAgents).Methods("GET")
-	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
+// outputs returns the spiller keeping a workflow's large outputs in its
+// workspace, so retention archives and purges them with the project
+func (o *EnhancedOrchestrator) outputs(workflowID uuid.UUID) *agents.Spiller {
+	store := agents.NewFileArtifactStore(filepath.Join(o.projectDir(workflowID), workspace.StateDir, "outputs"))
+	return agents.NewSpiller(agents.NewArtifactRegistry(store), o.spillAt)
 }
 
-func (s *Server) handleOrchestrate(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Description string `json:"description"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	ctx := context.Background()
-	result, err := s.orchestrator.ExecuteWorkflow(ctx, req.Description)
+// agentResult converts a step's result for the workflow. An output over the
+// spill threshold is stored and replaced by a reference, keeping workflows
+// held in memory, API responses and events bounded.
+func (o *EnhancedOrchestrator) agentResult(ctx context.Context, workflowID uuid.UUID, agentType agents.AgentType, result *agents.Result) AgentResult {
+	output, ref, err := o.outputs(workflowID).Spill(ctx, agentType, workflowID, result.Output)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		logctx.From(ctx).Warn("Failed to spill agent output, returning it inline", zap.Int("bytes", len(result.Output)), zap.Error(err))
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
-}
-
-func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	agents := make([]map[string]interface{}, 0)
-	
-	for agentType, agent := range s.orchestrator.registry {
-		agents = append(agents, map[string]interface{}{
-			"type":        agentType,
-			"description": agent.GetDescription(),
-			"capabilities": agent.GetCapabilities(),
-		})
+	return AgentResult{
+		Agent:       agentType,
+		Success:     result.Success,
+		Output:      output,
+		OutputRef:   ref,
+		Confidence:  result.Confidence,
+		ExecutionMS: result.ExecutionMS,
+		Data:        result.Data,
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(agents)
+// stepRun is the outcome of one workflow step
+type stepRun struct {
+	step      int
+	agentType agents.AgentType
+	task      agents.Task
+	result    *agents.Result
+	err       error
+	skipped   bool            // No agent is registered for the step
+	ctx       context.Context // Logs the step, agent and model that answered
 }
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+// executeSteps runs the n steps of workflowSequence from start, concurrently
+// when the live config groups them. Grouped steps get their own copy of the
+// task context, so they see the same prior steps and not each other.
+func (o *EnhancedOrchestrator) executeSteps(ctx context.Context, task agents.Task, start, n int) []stepRun {
+	steps := make([]stepRun, n)
+	var wg sync.WaitGroup
+	for i := range steps {
+		s := &steps[i]
+		s.step = start + i
+		s.agentType = workflowSequence[s.step]
+		route := o.live.Route(task.Type, s.agentType)
+		agent, exists := o.registry[route]
+		if !exists {
+			s.skipped = true
+			continue
+		}
+
+		s.task = task
+		if n > 1 {
+			taskContext := *task.Context
+			s.task.Context = &taskContext
+		}
+		s.task.Context.Phase = string(s.agentType)
+		s.ctx = logctx.Step(ctx, s.step, string(s.agentType))
+		enrichment := agents.DefaultContextEnricher.Enrich(ctx, route, &s.task)
+
+		logctx.From(s.ctx).Info("Executing agent",
+			zap.String("type", string(s.agentType)),
+			zap.String("routed_to", string(route)),
+			zap.Int("parallel", n),
+			zap.Duration("enrichment", enrichment))
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepStarted, WorkflowID: task.ID, Step: s.step, Agent: s.agentType})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.result, s.err = agents.ExecuteTracked(s.ctx, agent, s.task)
+			if s.result != nil && s.result.Model != "" {
+				s.ctx = logctx.Model(s.ctx, s.result.Model)
+			}
+			if s.result != nil && s.task.Context.Enrichment != nil {
+				if s.result.Data == nil {
+					s.result.Data = make(map[string]interface{})
+				}
+				s.result.Data[reporting.EnrichmentKey] = enrichment.Milliseconds()
+			}
+		}()
+	}
+	wg.Wait()
+	return steps
+}
+
+// checkpoint persists the task an agent received and what it produced so
+// the workflow can later resume from this step
+func (o *EnhancedOrchestrator) checkpoint(ctx context.Context, step int, agentType agents.AgentType, task agents.Task, result *agents.Result, err error) {
+	cp := &agents.Checkpoint{
+		WorkflowID: task.ID,
+		Step:       step,
+		Agent:      agentType,
+		Task:       task,
+		Result:     result,
+	}
+	if err != nil {
+		cp.Error = err.Error()
+	}
+	if err := o.checkpoints.Save(ctx, cp); err != nil {
+		logctx.From(ctx).Warn("Failed to save checkpoint", zap.Error(err))
+	}
+}
+
+// storeWorkflow records a workflow, evicting those, and the clarifications
+// they await, that are older than the workflow TTL. Evicted workflows can
+// still be resumed from their checkpoints. The caller holds o.mu.
+func (o *EnhancedOrchestrator) storeWorkflow(workflow *WorkflowResult) {
+	if o.workflowTTL > 0 {
+		cutoff := time.Now().Add(-o.workflowTTL)
+		for id, w := range o.workflows {
+			if w.Timestamp.Before(cutoff) {
+				delete(o.workflows, id)
+				delete(o.clarifying, id)
+			}
+		}
+	}
+	o.workflows[workflow.WorkflowID] = workflow
+}
+
+// GetWorkflow returns a previously executed workflow
+func (o *EnhancedOrchestrator) GetWorkflow(id uuid.UUID) (*WorkflowResult, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	workflow, ok := o.workflows[id]
+	return workflow, ok
+}
+
+// ideWriteScope is what an IDE session on the workflow may write: its whole
+// project once the workflow has finished, and nothing while agents may
+// still be writing to it or it is unknown to this instance
+func (o *EnhancedOrchestrator) ideWriteScope(id uuid.UUID) []string {
+	workflow, ok := o.GetWorkflow(id)
+	if !ok || workflow.Status == StatusAwaitingClarification {
+		return nil
+	}
+	return []string{ide.WriteAll}
+}
+
+// saveEnhancedOutput saves the files an agent's output holds: the
+// development agent's code, the artifacts the registered output handlers
+// sniff, and documentation for the other agents
+func (o *EnhancedOrchestrator) saveEnhancedOutput(ctx context.Context, agentType agents.AgentType, workflowID uuid.UUID, prov workspace.Provenance, result *agents.Result) error {
+	projectDir := o.projectDir(workflowID)
+
+	// The development agent names the files it writes
+	if agentType == agents.DevelopmentAgent {
+		if patches, ok := result.Data[agents.PatchesKey].([]agents.FilePatch); ok {
+			return o.applyPatches(ctx, workflowID, prov, patches)
+		}
+		for _, file := range o.parseCodeFiles(result.Output) {
+			filePath := filepath.Join(projectDir, file.Path)
+			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+				return err
+			}
+			if err := o.writeFile(ctx, workflowID, prov, filePath, file.Content); err != nil {
+				return err
+			}
+			logctx.Sampled(ctx).Info("Created code file", zap.String("path", filePath))
+		}
+	}
+
+	// Save the artifacts the registered handlers find in the output
+	for _, file := range o.artifacts.Files(agentType, result.Output) {
+		filePath := filepath.Join(projectDir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := o.writeFile(ctx, workflowID, prov, filePath, file.Content); err != nil {
+			return err
+		}
+		logctx.Sampled(ctx).Info("Created artifact", zap.String("kind", file.Kind), zap.String("path", filePath))
+		if file.Kind == monitoring.OutputGrafanaDashboard {
+			o.provisionDashboard(ctx, workflowID, file.Content, result)
+		}
+	}
+	if agentType == agents.DevelopmentAgent || o.artifacts.Handles(agentType) {
+		return nil
+	}
+
+	// Save documentation for agents without handlers of their own
+	docDir := filepath.Join(projectDir, "docs")
+	os.MkdirAll(docDir, 0755)
+	docPath := filepath.Join(docDir, fmt.Sprintf("%s.md", agentType))
+	o.writeFile(ctx, workflowID, prov, docPath, result.Output)
+
+	// Make generated documentation searchable for later workflows
+	if err := o.knowledge.Index(ctx, &knowledge.Document{
+		ProjectID: workflowID.String(),
+		Agent:     agentType,
+		Path:      docPath,
+		Content:   result.Output,
+	}); err != nil {
+		logctx.From(ctx).Warn("Failed to index documentation", zap.Error(err))
+	}
+
+	return nil
+}
+
+// projectDir is the directory a workflow generates its project into
+func (o *EnhancedOrchestrator) projectDir(workflowID uuid.UUID) string {
+	return filepath.Join(o.workspaceDir, workflowID.String()[:8])
+}
+
+// projectFiles reads the files generated into projectDir, skipping the
+// paths in skip. It returns nil when nothing has been generated.
+func (o *EnhancedOrchestrator) projectFiles(projectDir string, skip map[string]bool) []agents.GeneratedFile {
+	if _, err := os.Stat(projectDir); err != nil {
+		return nil
+	}
+
+	var files []agents.GeneratedFile
+	err := filepath.WalkDir(projectDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Workspace state is not part of the project
+			if path == filepath.Join(projectDir, workspace.StateDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil || skip[filepath.ToSlash(rel)] {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, agents.GeneratedFile{Path: filepath.ToSlash(rel), Content: string(content)})
+		return nil
+	})
+	if err != nil {
+		o.logger.Warn("Failed to read generated project", zap.String("dir", projectDir), zap.Error(err))
+		return nil
+	}
+	return files
+}
+
+// writeSecurity adds the security middleware requested in the task's
+// security profile to the project's web gateway, then lints the result with
+// the Security quality rules
+func (o *EnhancedOrchestrator) writeSecurity(ctx context.Context, workflowID uuid.UUID, projectDir string, task agents.Task, prov workspace.Provenance) *development.SecurityReport {
+	profile, ok := task.Parameters["security"].(orchestrate.Security)
+	if !ok {
+		return nil
+	}
+	files := o.projectFiles(projectDir, nil)
+	if files == nil {
+		return nil
+	}
+
+	scaffold, err := development.ScaffoldSecurity(files, &development.SecurityConfig{
+		Headers:       profile.Headers,
+		CSP:           profile.CSP,
+		HSTSMaxAge:    profile.HSTSMaxAge,
+		CSRF:          profile.CSRF,
+		SecureCookies: profile.SecureCookies,
+	})
+	if errors.Is(err, development.ErrNoGateway) {
+		return nil
+	}
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to generate security middleware", zap.Error(err))
+		return nil
+	}
+
+	report := &development.SecurityReport{Gateway: scaffold.Gateway}
+	for _, f := range scaffold.Files {
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, f.Path), f.Content); err != nil {
+			logctx.From(ctx).Warn("Failed to write security middleware", zap.String("file", f.Path), zap.Error(err))
+			return nil
+		}
+		report.Files = append(report.Files, f.Path)
+	}
+
+	var code []quality.CodeFile
+	for _, f := range o.projectFiles(projectDir, nil) {
+		code = append(code, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	report.Findings = quality.LintWebSecurity(code)
+	return report
+}
+
+// writeEnvManifest scans the generated project for the environment
+// variables it reads and writes .env.example plus Kubernetes Secret and
+// ConfigMap templates alongside it
+func (o *EnhancedOrchestrator) writeEnvManifest(ctx context.Context, workflowID uuid.UUID, projectDir string, prov workspace.Provenance) *deployment.EnvReport {
+	generated := map[string]bool{}
+	for _, f := range (&deployment.EnvManifest{}).Files() {
+		generated[f.Path] = true
+	}
+	files := o.projectFiles(projectDir, generated)
+	if files == nil {
+		return nil
+	}
+
+	manifest := deployment.BuildEnvManifest(filepath.Base(projectDir), files)
+	for _, f := range manifest.Files() {
+		path := filepath.Join(projectDir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			logctx.From(ctx).Warn("Failed to write environment manifest", zap.Error(err))
+			return nil
+		}
+		if err := o.writeFile(ctx, workflowID, prov, path, f.Content); err != nil {
+			logctx.From(ctx).Warn("Failed to write environment manifest", zap.Error(err))
+			return nil
+		}
+	}
+
+	if len(manifest.Report.Undefined) > 0 {
+		logctx.From(ctx).Warn("Generated code reads undefined environment variables",
+			zap.String("workflow_id", workflowID.String()),
+			zap.Strings("undefined", manifest.Report.Undefined))
+	}
+	return &manifest.Report
+}
+
+// writeOpenAPI derives openapi.yaml from the routes the generated backend
+// registers and returns how well the project's own spec, if any, covers them
+func (o *EnhancedOrchestrator) writeOpenAPI(ctx context.Context, workflowID uuid.UUID, projectDir string, prov workspace.Provenance) *quality.RouteCoverage {
+	generated := o.projectFiles(projectDir, map[string]bool{"openapi.yaml": true})
+	files := make([]quality.CodeFile, 0, len(generated))
+	for _, f := range generated {
+		files = append(files, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	routes := quality.DetectRoutes(files)
+	if len(routes) == 0 {
+		return nil
+	}
+	coverage := quality.CheckRouteCoverage(files, routes)
+
+	doc := quality.BuildOpenAPI(filepath.Base(projectDir), "0.1.0", routes)
+	if err := quality.ValidateOpenAPI(doc); err != nil {
+		logctx.From(ctx).Warn("Derived OpenAPI spec is invalid", zap.Error(err))
+		return coverage
+	}
+	content, err := quality.MarshalOpenAPI(doc)
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to render OpenAPI spec", zap.Error(err))
+		return coverage
+	}
+	if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, "openapi.yaml"), string(content)); err != nil {
+		logctx.From(ctx).Warn("Failed to write OpenAPI spec", zap.Error(err))
+	}
+	return coverage
+}
+
+// checkMonorepo writes the workspace files tying a monorepo's services and
+// shared packages together, then checks the cross-references between them
+// against the architect's plan
+func (o *EnhancedOrchestrator) checkMonorepo(ctx context.Context, workflowID uuid.UUID, projectDir string, task agents.Task, prov workspace.Provenance) *quality.MonorepoReport {
+	plan := agents.TaskMonorepo(task)
+	if plan == nil {
+		return nil
+	}
+	for _, f := range plan.WorkspaceFiles() {
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, f.Path), f.Content); err != nil {
+			logctx.From(ctx).Warn("Failed to write monorepo workspace", zap.String("file", f.Path), zap.Error(err))
+			return nil
+		}
+	}
+
+	var code []quality.CodeFile
+	for _, f := range o.projectFiles(projectDir, nil) {
+		code = append(code, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	report := quality.CheckMonorepo(plan, code)
+	if !report.Valid() {
+		logctx.From(ctx).Warn("Monorepo cross-references do not resolve",
+			zap.String("workflow_id", workflowID.String()),
+			zap.Int("issues", len(report.Issues)))
+	}
+	return report
+}
+
+// writeSeed generates demo data for the project's SQL schema and adds a
+// seed service to each docker-compose file that runs Postgres. It returns
+// the number of rows seeded per table.
+func (o *EnhancedOrchestrator) writeSeed(ctx context.Context, workflowID uuid.UUID, projectDir string, task agents.Task, prov workspace.Provenance) map[string]int {
+	generated := map[string]bool{}
+	for _, f := range (&deployment.SeedData{}).Files() {
+		generated[f.Path] = true
+	}
+	files := o.projectFiles(projectDir, generated)
+	if files == nil {
+		return nil
+	}
+
+	config := deployment.DefaultSeedConfig()
+	if seed, ok := task.Parameters["seed"].(orchestrate.Seed); ok {
+		if seed.Rows > 0 {
+			config.Rows = seed.Rows
+		}
+		if seed.RandSeed != 0 {
+			config.RandSeed = seed.RandSeed
+		}
+		config.TableRows = seed.TableRows
+	}
+	data, err := deployment.GenerateSeed(files, config)
+	if errors.Is(err, deployment.ErrNoSchema) {
+		return nil
+	}
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to generate seed data", zap.Error(err))
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(projectDir, "seed"), 0755); err != nil {
+		logctx.From(ctx).Warn("Failed to write seed data", zap.Error(err))
+		return nil
+	}
+	for _, f := range data.Files() {
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, f.Path), f.Content); err != nil {
+			logctx.From(ctx).Warn("Failed to write seed data", zap.Error(err))
+			return nil
+		}
+	}
+
+	for _, f := range files {
+		base := filepath.Base(f.Path)
+		if !strings.HasPrefix(base, "docker-compose") && !strings.HasPrefix(base, "compose.") {
+			continue
+		}
+		root, err := filepath.Rel(filepath.Dir(filepath.Join(projectDir, f.Path)), projectDir)
+		if err != nil {
+			continue
+		}
+		compose, err := deployment.WireSeedCompose([]byte(f.Content), filepath.ToSlash(root))
+		if errors.Is(err, deployment.ErrNoPostgresService) {
+			continue
+		}
+		if err != nil {
+			logctx.From(ctx).Warn("Failed to add seed service", zap.String("file", f.Path), zap.Error(err))
+			continue
+		}
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, f.Path), string(compose)); err != nil {
+			logctx.From(ctx).Warn("Failed to add seed service", zap.String("file", f.Path), zap.Error(err))
+		}
+	}
+
+	counts := make(map[string]int, len(data.Tables))
+	for _, table := range data.Tables {
+		counts[table] = len(data.Rows[table])
+	}
+	return counts
+}
+
+// writeTerraform provisions the project's docker-compose services under
+// terraform/, unless the project brings its own Terraform, and validates
+// the module when a terraform binary is configured
+func (o *EnhancedOrchestrator) writeTerraform(ctx context.Context, workflowID uuid.UUID, projectDir string, prov workspace.Provenance) *deployment.TerraformReport {
+	generated := map[string]bool{}
+	for _, name := range deployment.TerraformFiles {
+		generated[deployment.TerraformDir+"/"+name] = true
+	}
+	files := o.projectFiles(projectDir, generated)
+	if files == nil {
+		return nil
+	}
+
+	var module []agents.GeneratedFile
+	var compose *agents.GeneratedFile
+	for i, f := range files {
+		base := filepath.Base(f.Path)
+		switch {
+		case strings.HasPrefix(f.Path, deployment.TerraformDir+"/") && strings.HasSuffix(f.Path, ".tf"):
+			module = append(module, f)
+		case compose == nil && (strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose.")):
+			compose = &files[i]
+		}
+	}
+	if len(module) == 0 {
+		if compose == nil {
+			return nil
+		}
+		model, err := deployment.InfraFromCompose(filepath.Base(projectDir), []byte(compose.Content))
+		if errors.Is(err, deployment.ErrNoServices) {
+			return nil
+		}
+		if err != nil {
+			logctx.From(ctx).Warn("Failed to derive infrastructure", zap.String("file", compose.Path), zap.Error(err))
+			return nil
+		}
+		module = deployment.GenerateTerraform(model)
+		if err := os.MkdirAll(filepath.Join(projectDir, deployment.TerraformDir), 0755); err != nil {
+			logctx.From(ctx).Warn("Failed to write terraform", zap.Error(err))
+			return nil
+		}
+		for _, f := range module {
+			if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, f.Path), f.Content); err != nil {
+				logctx.From(ctx).Warn("Failed to write terraform", zap.Error(err))
+				return nil
+			}
+		}
+	}
+
+	validator := deployment.DefaultTerraformValidator
+	if validator == nil {
+		return nil
+	}
+	for i := range module {
+		module[i].Path = strings.TrimPrefix(module[i].Path, deployment.TerraformDir+"/")
+	}
+	report, err := validator.Validate(ctx, module)
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to validate terraform", zap.Error(err))
+		return nil
+	}
+	if report.Errors() > 0 || !report.Formatted {
+		logctx.From(ctx).Warn("Terraform did not validate",
+			zap.String("workflow_id", workflowID.String()),
+			zap.Bool("formatted", report.Formatted),
+			zap.Int("errors", report.Errors()))
+	}
+	return report
+}
+
+// writeCI writes a GitHub Actions workflow building, linting and testing
+// the project's stacks and building its images, unless the deployment agent
+// wrote one that validates against the generated tree
+func (o *EnhancedOrchestrator) writeCI(ctx context.Context, workflowID uuid.UUID, projectDir string, prov workspace.Provenance) *deployment.CIReport {
+	files := o.projectFiles(projectDir, nil)
+	if files == nil {
+		return nil
+	}
+	report, workflow, err := deployment.BuildCI(files)
+	if errors.Is(err, deployment.ErrNoStack) {
+		return nil
+	}
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to generate CI workflow", zap.Error(err))
+		return nil
+	}
+	if len(report.Replaced) > 0 {
+		logctx.From(ctx).Info("Replacing CI workflow that does not match the project", zap.Int("issues", len(report.Replaced)))
+	}
+	if workflow != nil {
+		path := filepath.Join(projectDir, filepath.FromSlash(workflow.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			logctx.From(ctx).Warn("Failed to write CI workflow", zap.Error(err))
+			return nil
+		}
+		if err := o.writeFile(ctx, workflowID, prov, path, workflow.Content); err != nil {
+			logctx.From(ctx).Warn("Failed to write CI workflow", zap.Error(err))
+			return nil
+		}
+	}
+	if len(report.Issues) > 0 {
+		logctx.From(ctx).Warn("CI workflow references files the project lacks",
+			zap.String("workflow_id", workflowID.String()),
+			zap.Int("issues", len(report.Issues)))
+	}
+	return report
+}
+
+// runLoadTest writes a k6 script for the project's GET endpoints to
+// quality.LoadTestScript and runs it against the smoke-test deployment when
+// a load tester is configured
+func (o *EnhancedOrchestrator) runLoadTest(ctx context.Context, workflowID uuid.UUID, projectDir string, prov workspace.Provenance) *quality.LoadReport {
+	if o.loadTest == nil {
+		return nil
+	}
+	generated := o.projectFiles(projectDir, map[string]bool{quality.LoadTestScript: true})
+	files := make([]quality.CodeFile, 0, len(generated))
+	for _, f := range generated {
+		files = append(files, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	plan, err := quality.NewLoadPlan(files, *o.loadTest)
+	if errors.Is(err, quality.ErrNoLoadEndpoints) {
+		return nil
+	}
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to plan load test", zap.Error(err))
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(projectDir, filepath.Dir(quality.LoadTestScript)), 0755); err != nil {
+		logctx.From(ctx).Warn("Failed to write load test", zap.Error(err))
+		return nil
+	}
+	if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, quality.LoadTestScript), plan.Script); err != nil {
+		logctx.From(ctx).Warn("Failed to write load test", zap.Error(err))
+		return nil
+	}
+
+	tester := quality.DefaultLoadTester
+	if tester == nil || plan.Config.BaseURL == "" {
+		return nil
+	}
+	report, err := tester.Run(ctx, plan)
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to run load test", zap.Error(err))
+		return nil
+	}
+	if !report.Passed {
+		logctx.From(ctx).Warn("Load test missed its thresholds",
+			zap.String("workflow_id", workflowID.String()),
+			zap.String("target", report.Target),
+			zap.String("error", report.Error))
+	}
+	return report
+}
+
+// e2eArtifactsDir keeps the screenshots, videos and traces of failed
+// end-to-end tests, relative to the project
+const e2eArtifactsDir = workspace.StateDir + "/e2e"
+
+// runE2E writes a Playwright suite of the project's user flows and runs it
+// in quality.DefaultSandbox against the smoke-test deployment. Artifacts of
+// failed tests are kept in e2eArtifactsDir.
+func (o *EnhancedOrchestrator) runE2E(ctx context.Context, workflowID uuid.UUID, projectDir string, prov workspace.Provenance) *quality.E2EReport {
+	generated := o.projectFiles(projectDir, map[string]bool{quality.E2ESpec: true, quality.E2EConfig: true})
+	if generated == nil {
+		return nil
+	}
+	files := make([]quality.CodeFile, 0, len(generated))
+	for _, f := range generated {
+		files = append(files, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	plan, err := quality.NewE2EPlan(files, o.e2eTarget)
+	if errors.Is(err, quality.ErrNoUserFlows) {
+		return nil
+	}
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to plan end-to-end tests", zap.Error(err))
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(projectDir, filepath.Dir(quality.E2ESpec)), 0755); err != nil {
+		logctx.From(ctx).Warn("Failed to write end-to-end tests", zap.Error(err))
+		return nil
+	}
+	for path, content := range map[string]string{quality.E2ESpec: plan.Spec, quality.E2EConfig: plan.Config} {
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, path), content); err != nil {
+			logctx.From(ctx).Warn("Failed to write end-to-end tests", zap.Error(err))
+			return nil
+		}
+	}
+
+	sandbox := quality.DefaultSandbox
+	if sandbox == nil || plan.BaseURL == "" {
+		return nil
+	}
+	report := quality.RunE2E(ctx, sandbox, files, plan)
+	for _, a := range report.Artifacts {
+		if !filepath.IsLocal(a.Path) {
+			continue
+		}
+		path := filepath.Join(projectDir, e2eArtifactsDir, filepath.FromSlash(a.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = os.WriteFile(path, a.Content, 0644)
+		}
+		if err != nil {
+			logctx.From(ctx).Warn("Failed to store end-to-end artifact", zap.String("path", a.Path), zap.Error(err))
+		}
+	}
+	if !report.Passed {
+		logctx.From(ctx).Warn("End-to-end tests failed",
+			zap.String("workflow_id", workflowID.String()),
+			zap.String("target", report.Target),
+			zap.String("error", report.Error))
+	}
+	return report
+}
+
+// signArtifacts signs the checksums of the files the workflow generated,
+// once every stage has written its files
+func (o *EnhancedOrchestrator) signArtifacts(ctx context.Context, workflowID uuid.UUID, projectDir string) {
+	if o.signer == nil {
+		return
+	}
+	ws, err := workspace.Open(projectDir)
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to sign generated files", zap.Error(err))
+		return
+	}
+	var paths []string
+	for _, f := range ws.Files() {
+		if f.Provenance.WorkflowID == workflowID.String() {
+			paths = append(paths, f.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	m, err := integrity.Sign(projectDir, workflowID, paths, o.signer)
+	if err == nil {
+		err = integrity.Save(projectDir, m)
+	}
+	if err != nil {
+		logctx.From(ctx).Warn("Failed to sign generated files", zap.Error(err))
+		return
+	}
+	logctx.From(ctx).Info("Signed generated files",
+		zap.String("workflow_id", workflowID.String()),
+		zap.Int("files", len(m.Files)),
+		zap.String("key_id", m.KeyID))
+}
+
+// runTests runs the generated test suites in quality.DefaultSandbox. When
+// tests fail, the failures are given to the development agent, its fix is
+// saved over the project and the suites run again, up to testFixRounds
+// times. In patch mode the agent edits the failing files instead of
+// rewriting them.
+func (o *EnhancedOrchestrator) runTests(ctx context.Context, task agents.Task, run, projectDir string, report *reporting.WorkflowReport) *quality.TestReport {
+	sandbox := quality.DefaultSandbox
+	if sandbox == nil {
+		return nil
+	}
+
+	for round := 0; ; round++ {
+		generated := o.projectFiles(projectDir, nil)
+		tests := quality.RunTests(ctx, sandbox, codeFiles(generated))
+		if tests == nil {
+			return nil
+		}
+		tests.FixRounds = round
+		logctx.From(ctx).Info("Ran generated tests",
+			zap.Int("passed", tests.Passed),
+			zap.Int("failed", tests.Failed),
+			zap.Int("round", round))
+		if tests.Success || round >= o.testFixRounds || ctx.Err() != nil {
+			return tests
+		}
+		if !o.repair(ctx, task, run, generated, tests.Feedback(), report) {
+			return tests
+		}
+	}
+}
+
+// runFrontendBuild installs, builds, type checks and lints the generated
+// React apps in quality.DefaultSandbox. Like the test stage, errors go back
+// to the development agent, up to frontendFixes times.
+func (o *EnhancedOrchestrator) runFrontendBuild(ctx context.Context, task agents.Task, run, projectDir string, report *reporting.WorkflowReport) *quality.FrontendReport {
+	sandbox := quality.DefaultSandbox
+	if sandbox == nil {
+		return nil
+	}
+
+	for round := 0; ; round++ {
+		generated := o.projectFiles(projectDir, nil)
+		build := quality.RunFrontendBuild(ctx, sandbox, codeFiles(generated))
+		if build == nil {
+			return nil
+		}
+		build.FixRounds = round
+		logctx.From(ctx).Info("Built generated frontend",
+			zap.Int("apps", len(build.Apps)),
+			zap.Int("errors", build.Errors),
+			zap.Int("round", round))
+		if build.Success || round >= o.frontendFixes || ctx.Err() != nil {
+			return build
+		}
+		if !o.repair(ctx, task, run, generated, build.Feedback(), report) {
+			return build
+		}
+	}
+}
+
+// repair has the development agent revise the project given feedback on
+// what is broken, saving the revision over the generated files. It reports
+// whether a revision was saved.
+func (o *EnhancedOrchestrator) repair(ctx context.Context, task agents.Task, run string, generated []agents.GeneratedFile, feedback string, report *reporting.WorkflowReport) bool {
+	developer, ok := o.registry[o.live.Route(task.Type, agents.DevelopmentAgent)]
+	if !ok {
+		return false
+	}
+	step := 0
+	for i, agentType := range workflowSequence {
+		if agentType == agents.DevelopmentAgent {
+			step = i
+		}
+	}
+
+	fix := task
+	fix.Input = task.Input + "\n\n" + feedback
+	fix.Context.Phase = string(agents.DevelopmentAgent)
+	if agents.FeatureEnabled(task, agents.FeaturePatchMode) {
+		// The fix edits the files in place rather than rewriting them
+		fix.Parameters = make(map[string]interface{}, len(task.Parameters)+1)
+		for k, v := range task.Parameters {
+			fix.Parameters[k] = v
+		}
+		fix.Parameters[agents.PatchFilesParam] = patchTargets(generated, feedback)
+	}
+	result, err := agents.ExecuteTracked(ctx, developer, fix)
+	if err != nil || result == nil || !result.Success {
+		logctx.From(ctx).Warn("Development agent failed to fix the project", zap.Error(err))
+		return false
+	}
+	redact.Result(result)
+	prov := stepProvenance(task.ID, run, step, agents.DevelopmentAgent, result)
+	if err := o.saveEnhancedOutput(ctx, agents.DevelopmentAgent, task.ID, prov, result); err != nil {
+		logctx.From(ctx).Error("Failed to save fix", zap.Error(err))
+		return false
+	}
+	report.Add(agents.DevelopmentAgent, result)
+	task.Context.Record(agents.DevelopmentAgent, result)
+	return true
+}
+
+// applyFlags decides the feature flags for the workflow and records the
+// decisions in its report. A flag named after a feature governs it: the
+// feature is requested from the agents only while the flag is on. Patch
+// mode is requested when -patch-mode is set and no flag turns it off.
+func (o *EnhancedOrchestrator) applyFlags(ctx context.Context, task agents.Task, tenantID string, report *reporting.WorkflowReport) agents.Task {
+	features := agents.RequestedFeatures(task)
+	if o.patchMode && !agents.FeatureEnabled(task, agents.FeaturePatchMode) {
+		features = append(features, agents.FeaturePatchMode)
+	}
+	if o.flags != nil {
+		report.Flags = o.flags.Evaluate(ctx, tenantID, task.ID.String())
+		for _, d := range report.Flags {
+			kept := features[:0:0]
+			for _, f := range features {
+				if f != agents.Feature(d.Flag) {
+					kept = append(kept, f)
+				}
+			}
+			if d.Enabled {
+				kept = append(kept, agents.Feature(d.Flag))
+			}
+			features = kept
+		}
+		if len(report.Flags) > 0 {
+			logctx.From(ctx).Info("Evaluated feature flags", zap.Any("flags", report.Flags))
+		}
+	}
+
+	params := make(map[string]interface{}, len(task.Parameters)+1)
+	for k, v := range task.Parameters {
+		params[k] = v
+	}
+	params[agents.FeatureParam] = features
+	task.Parameters = params
+	return task
+}
+
+// codeFiles converts generated files for the quality checks
+func codeFiles(generated []agents.GeneratedFile) []quality.CodeFile {
+	files := make([]quality.CodeFile, 0, len(generated))
+	for _, f := range generated {
+		files = append(files, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	return files
+}
+
+// applyPatches writes a patch-mode result's edits into the project. Every
+// patch is checked against the files as they are on disk before any file is
+// written, so either all of them apply or none do.
+func (o *EnhancedOrchestrator) applyPatches(ctx context.Context, workflowID uuid.UUID, prov workspace.Provenance, patches []agents.FilePatch) error {
+	projectDir := o.projectDir(workflowID)
+	current := make(map[string]string, len(patches))
+	for _, p := range patches {
+		if !filepath.IsLocal(filepath.FromSlash(p.Path)) {
+			return fmt.Errorf("patched path %s is outside the project", p.Path)
+		}
+		content, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(p.Path)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s to patch it: %w", p.Path, err)
+		}
+		current[p.Path] = string(content)
+	}
+	patched, err := agents.ApplyPatches(current, patches)
+	if err != nil {
+		return err
+	}
+	for _, p := range patches {
+		if err := o.writeFile(ctx, workflowID, prov, filepath.Join(projectDir, filepath.FromSlash(p.Path)), patched[p.Path]); err != nil {
+			return err
+		}
+		logctx.Sampled(ctx).Info("Patched code file", zap.String("path", p.Path), zap.Int("edits", len(p.Edits)))
+	}
+	return nil
+}
+
+// patchTargets picks the files a test fix edits in patch mode: those the
+// failures name, or every file when they name none
+func patchTargets(files []agents.GeneratedFile, feedback string) []agents.GeneratedFile {
+	var named []agents.GeneratedFile
+	for _, f := range files {
+		if strings.Contains(feedback, f.Path) || strings.Contains(feedback, filepath.Base(f.Path)) {
+			named = append(named, f)
+		}
+	}
+	if len(named) == 0 {
+		return files
+	}
+	return named
+}
+
+// stepProvenance identifies a step's output. result is nil for files derived
+// from the whole project.
+func stepProvenance(workflowID uuid.UUID, run string, step int, agentType agents.AgentType, result *agents.Result) workspace.Provenance {
+	prov := workspace.Provenance{
+		WorkflowID: workflowID.String(),
+		Run:        run,
+		Step:       step,
+		Agent:      string(agentType),
+	}
+	if result != nil {
+		prov.Model = result.Model
+		prov.PromptVersion = result.PromptVersion
+	}
+	return prov
+}
+
+// writeFile writes a generated file into the workflow's project and records
+// it in the audit log. Files edited since they were generated are kept and
+// the new content is proposed under .miosa/proposed/ instead; files owned by
+// steps a resumed run did not repeat are left alone.
+func (o *EnhancedOrchestrator) writeFile(ctx context.Context, workflowID uuid.UUID, prov workspace.Provenance, path, content string) error {
+	if prov.RequestID == "" {
+		prov.RequestID = requestid.FromContext(ctx)
+	}
+	projectDir := o.projectDir(workflowID)
+	rel, err := filepath.Rel(projectDir, path)
+	if err != nil {
+		return err
+	}
+	ws, err := workspace.Open(projectDir)
+	if err != nil {
+		return err
+	}
+	res, err := ws.Write(rel, content, prov)
+	if err != nil {
+		return err
+	}
+	if err := ws.Save(); err != nil {
+		return err
+	}
+
+	switch res.Outcome {
+	case workspace.Kept:
+		logctx.From(ctx).Info("Kept file owned by an earlier step", zap.String("file", rel))
+		return nil
+	case workspace.Conflict:
+		logctx.From(ctx).Warn("Generated file conflicts with local edits",
+			zap.String("file", rel),
+			zap.String("proposed", res.Conflict.Proposed),
+			zap.Int("conflicting_hunks", res.Conflict.Hunks))
+	}
+	o.audit.Record(ctx, audit.Event{
+		WorkflowID:  workflowID,
+		Actor:       prov.Agent,
+		ActorType:   audit.ActorSystem,
+		Action:      audit.ActionFileWrite,
+		Resource:    res.Path,
+		RequestHash: audit.HashRequest(content),
+		Metadata:    map[string]string{"outcome": string(res.Outcome)},
+	})
+	return nil
+}
+
+// conflicts returns the generated files held back to protect local edits
+func (o *EnhancedOrchestrator) conflicts(projectDir string) []workspace.FileConflict {
+	ws, err := workspace.Open(projectDir)
+	if err != nil {
+		o.logger.Warn("Failed to read workspace state", zap.Error(err))
+		return nil
+	}
+	return ws.Conflicts()
+}
+
+// provisionDashboard pushes a generated dashboard to Grafana when configured
+// and records its URL in the result data. The dashboard belongs to the
+// workflow and its tenant.
+func (o *EnhancedOrchestrator) provisionDashboard(ctx context.Context, workflowID uuid.UUID, content string, result *agents.Result) {
+	if o.grafana == nil {
+		return
+	}
+
+	if _, err := monitoring.ValidateDashboard([]byte(content)); err != nil {
+		logctx.From(ctx).Warn("Generated Grafana dashboard is invalid", zap.Error(err))
+		return
+	}
+
+	folderUID, err := o.grafana.EnsureFolder(ctx, o.grafanaDir)
+	if err != nil {
+		logctx.From(ctx).Error("Failed to ensure Grafana folder", zap.Error(err))
+		return
+	}
+
+	owner := monitoring.DashboardOwner{WorkflowID: workflowID.String()}
+	if recorded, err := workspace.ReadOwner(o.projectDir(workflowID)); err == nil {
+		owner.TenantID = recorded.TenantID
+	}
+	dashboard, err := o.grafana.UpsertDashboard(ctx, []byte(content), folderUID, owner)
+	if err != nil {
+		logctx.From(ctx).Error("Failed to provision Grafana dashboard", zap.Error(err))
+		return
+	}
+
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	urls, _ := result.Data["grafana_dashboards"].([]string)
+	result.Data["grafana_dashboards"] = append(urls, dashboard.URL)
+	logctx.From(ctx).Info("Provisioned Grafana dashboard",
+		zap.String("uid", dashboard.UID),
+		zap.String("url", dashboard.URL))
+}
+
+// parseCodeFiles extracts multiple files from structured output
+func (o *EnhancedOrchestrator) parseCodeFiles(content string) []CodeFile {
+	var files []CodeFile
+	
+	// Pattern to match file blocks
+	filePattern := regexp.MustCompile(`=== FILE: (.+?) ===\n([\s\S]*?)(?:=== END FILE ===|$)`)
+	matches := filePattern.FindAllStringSubmatch(content, -1)
+	
+	for _, match := range matches {
+		if len(match) >= 3 {
+			files = append(files, CodeFile{
+				Path:    strings.TrimSpace(match[1]),
+				Content: strings.TrimSpace(match[2]),
+			})
+		}
+	}
+	
+	// If no structured format, try to extract code blocks
+	if len(files) == 0 {
+		codeBlocks := o.extractCodeBlocks(content)
+		for i, block := range codeBlocks {
+			lang := langdetect.Classify("", block)
+			files = append(files, CodeFile{
+				Path:    fmt.Sprintf("file_%d%s", i+1, lang.Extension),
+				Content: block,
+			})
+		}
+	}
+	
+	return files
+}
+
+// extractCodeBlocks finds code blocks in markdown
+func (o *EnhancedOrchestrator) extractCodeBlocks(content string) []string {
+	var blocks []string
+	codePattern := regexp.MustCompile("```[a-z]*\n([\\s\\S]*?)```")
+	matches := codePattern.FindAllStringSubmatch(content, -1)
+	for _, match := range matches {
+		if len(match) >= 2 {
+			blocks = append(blocks, strings.TrimSpace(match[1]))
+		}
+	}
+	return blocks
+}
+
+// triggerE2BWorkflow asks the E2B server to deploy the project
+func (o *EnhancedOrchestrator) triggerE2BWorkflow(ctx context.Context, workflowID uuid.UUID, projectPath string) {
+	e2bServerURL := "http://localhost:3001" // The Node.js server
+	logctx.From(ctx).Info("Triggering E2B workflow", zap.String("path", projectPath))
+
+	status := audit.StatusFailure
+	defer func() {
+		o.audit.Record(ctx, audit.Event{
+			WorkflowID: workflowID,
+			Actor:      "enhanced-orchestrator",
+			ActorType:  audit.ActorSystem,
+			Action:     audit.ActionDeploymentTrigger,
+			Resource:   e2bServerURL,
+			Status:     status,
+		})
+	}()
+
+	payload := map[string]string{"path": projectPath}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		logctx.From(ctx).Error("Error creating JSON payload for E2B server", zap.Error(err))
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e2bServerURL, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		logctx.From(ctx).Error("Error creating E2B request", zap.Error(err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	requestid.Set(ctx, req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logctx.From(ctx).Error("Error calling E2B server", apierror.Fields(apierror.Wrap(apierror.CategorySandboxFailure, "sandbox unreachable", err))...)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		err := apierror.Newf(apierror.CategorySandboxFailure, "sandbox returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		logctx.From(ctx).Error("E2B server returned non-OK status", apierror.Fields(err)...)
+		return
+	}
+
+	status = audit.StatusSuccess
+	logctx.From(ctx).Info("Successfully triggered E2B workflow.")
+}
+
+// WorkflowResult represents complete workflow execution
+type WorkflowResult struct {
+	WorkflowID uuid.UUID                 `json:"workflow_id"`
+	Results    []AgentResult             `json:"results"`
+	Success    bool                      `json:"success"`
+	Status     string                    `json:"status,omitempty"` // StatusAwaitingClarification while paused
+	Timestamp  time.Time                 `json:"timestamp"`
+	Report     *reporting.WorkflowReport `json:"report,omitempty"`
+	Env        *deployment.EnvReport     `json:"env,omitempty"`
+	Routes     *quality.RouteCoverage    `json:"routes,omitempty"`
+	Monorepo   *quality.MonorepoReport   `json:"monorepo,omitempty"`
+	Seed       map[string]int              `json:"seed,omitempty"` // Seeded rows per table
+	Terraform  *deployment.TerraformReport `json:"terraform,omitempty"`
+	CI         *deployment.CIReport        `json:"ci,omitempty"`
+	Security   *development.SecurityReport `json:"security,omitempty"`
+	Conflicts  []workspace.FileConflict    `json:"conflicts,omitempty"`
+	Frontend   *quality.FrontendReport     `json:"frontend,omitempty"`
+	Tests      *quality.TestReport         `json:"tests,omitempty"`
+
+	Architecture  *agents.ArchitectureSpec `json:"architecture,omitempty"` // The spec later steps built from
+	Clarification *agents.Clarification `json:"clarification,omitempty"` // Questions asked before the workflow ran
+}
+
+// AgentResult represents individual agent result
+type AgentResult struct {
+	Agent       agents.AgentType       `json:"agent"`
+	Success     bool                   `json:"success"`
+	Output      string                 `json:"output"`
+	Confidence  float64                `json:"confidence"`
+	ExecutionMS int64                  `json:"execution_ms"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Redactions  int                    `json:"redactions,omitempty"`
+	Reused      bool                   `json:"reused,omitempty"`
+	OutputRef   *agents.OutputRef      `json:"output_ref,omitempty"` // Set, and Output empty, when the output was spilled
+}
+
+// step converts the result for the gRPC API
+func (r AgentResult) step() orchestrate.StepResult {
+	return orchestrate.StepResult{
+		Agent:       r.Agent,
+		Success:     r.Success,
+		Output:      r.Output,
+		Confidence:  r.Confidence,
+		ExecutionMS: r.ExecutionMS,
+		Reused:      r.Reused,
+		Data:        r.Data,
+		OutputRef:   r.OutputRef,
+	}
+}
+
+// workflow converts the result for the gRPC API
+func (r *WorkflowResult) workflow() *orchestrate.Workflow {
+	w := &orchestrate.Workflow{ID: r.WorkflowID, Success: r.Success, Timestamp: r.Timestamp}
+	for _, res := range r.Results {
+		w.Steps = append(w.Steps, res.step())
+	}
+	return w
+}
+
+// API Server
+type Server struct {
+	orchestrator *EnhancedOrchestrator
+	router       *mux.Router
+	batches      *orchestrate.Scheduler
+	schedules    *schedule.Scheduler
+	catalog      *catalog.Cache
+}
+
+func NewServer(orchestrator *EnhancedOrchestrator, batchWorkers int) *Server {
+	s := &Server{
+		orchestrator: orchestrator,
+		router:       mux.NewRouter(),
+	}
+	commands := claude.NewCommandRegistry()
+	s.catalog = catalog.NewCache(func() *catalog.Catalog {
+		return catalog.Build(orchestrator.registry, commands)
+	})
+	s.batches = orchestrate.NewScheduler(func(ctx context.Context, job orchestrate.Job) (interface{}, error) {
+		if tenant, err := uuid.Parse(job.Tenant); err == nil {
+			job.Request.TenantID = tenant
+		}
+		return s.execute(ctx, job.WorkflowID, job.Request, job.Actor, job.ActorType, "/api/orchestrate/batch")
+	}, batchWorkers)
+	s.schedules = schedule.NewScheduler(orchestrator.schedules, s.runSchedule, orchestrator.logger)
+	s.setupRoutes()
+	return s
+}
+
+func (s *Server) setupRoutes() {
+	s.router.Use(requestid.Handler)
+	s.router.HandleFunc("/api/orchestrate", s.handleOrchestrate).Methods("POST")
+	s.router.HandleFunc("/api/orchestrate/batch", s.batches.SubmitHandler()).Methods("POST")
+	s.router.HandleFunc("/api/orchestrate/batch/{id}", s.batches.StatusHandler()).Methods("GET")
+	s.router.HandleFunc("/api/orchestrate/batch/{id}/items/{index}", s.batches.ItemHandler()).Methods("GET")
+	s.router.HandleFunc("/api/agents", s.catalog.AgentsHandler()).Methods("GET")
+	s.router.HandleFunc("/api/commands", s.catalog.CommandsHandler()).Methods("GET")
+	s.router.HandleFunc("/api/catalog", s.catalog.Handler()).Methods("GET")
+	s.router.HandleFunc("/api/quality/packs", quality.PacksHandler()).Methods("GET")
+	s.router.HandleFunc("/api/pipelines/params", orchestrate.ParamsHandler()).Methods("GET")
+	// Registered before /api/workflow/{id}, which would take "schedules" as an ID
+	s.router.HandleFunc("/api/workflow/schedules", schedule.ListHandler(s.schedules)).Methods("GET")
+	s.router.HandleFunc("/api/workflow/schedules", schedule.CreateHandler(s.schedules, s.orchestrator.audit)).Methods("POST")
+	s.router.HandleFunc("/api/workflow/schedules/{id}", schedule.GetHandler(s.schedules)).Methods("GET")
+	s.router.HandleFunc("/api/workflow/schedules/{id}", schedule.UpdateHandler(s.schedules, s.orchestrator.audit)).Methods("PUT")
+	s.router.HandleFunc("/api/workflow/schedules/{id}", schedule.DeleteHandler(s.schedules, s.orchestrator.audit)).Methods("DELETE")
+	s.router.HandleFunc("/api/workflow/schedules/{id}/runs", schedule.RunsHandler(s.schedules)).Methods("GET")
+	s.router.HandleFunc("/api/workflow/schedules/{id}/runs", schedule.TriggerHandler(s.schedules, s.orchestrator.audit)).Methods("POST")
+	s.router.HandleFunc("/api/workflow/{id}", s.handleGetWorkflow).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/report", s.handleWorkflowReport).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/outputs/{agent}", s.handleAgentOutput).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/graph", graph.Handler(s.orchestrator.graphs)).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/e2e/{path:.*}", s.handleE2EArtifact).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/resume", s.handleResumeWorkflow).Methods("POST")
+	s.router.HandleFunc("/api/workflow/{id}/clarification", s.handleClarification).Methods("POST")
+	s.router.HandleFunc("/api/workflow/{id}/provenance", workspace.ProvenanceHandler(s.orchestrator.projectDir)).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/archive", workspace.ArchiveHandler(s.orchestrator.projectDir)).Methods("GET")
+	s.router.HandleFunc("/api/knowledge/search", knowledge.SearchHandler(s.orchestrator.knowledge)).Methods("GET")
+	s.router.HandleFunc("/api/audit", s.orchestrator.auth.RequireHTTP(middleware.PermAuditRead, audit.QueryHandler(s.orchestrator.audit, middleware.TenantOf))).Methods("GET")
+	s.router.HandleFunc("/api/config/live", s.admin(liveconfig.Handler(s.orchestrator.live))).Methods("GET")
+	s.router.HandleFunc("/api/config/live/rollback", s.admin(liveconfig.RollbackHandler(s.orchestrator.live))).Methods("POST")
+	s.router.HandleFunc("/api/admin/flags", s.admin(flags.Handler(s.orchestrator.flags))).Methods("GET")
+	s.router.HandleFunc("/api/admin/flags/{name}", s.admin(flags.PutHandler(s.orchestrator.flags, s.orchestrator.audit))).Methods("PUT")
+	s.router.HandleFunc("/api/admin/flags/{name}", s.admin(flags.DeleteHandler(s.orchestrator.flags, s.orchestrator.audit))).Methods("DELETE")
+	if s.orchestrator.moderator != nil {
+		s.router.HandleFunc(moderation.DefaultAppealPath+"{id}", moderation.AppealHandler(s.orchestrator.moderator, s.orchestrator.audit)).Methods("POST")
+	}
+	s.router.HandleFunc("/api/llm/health", s.handleLLMHealth).Methods("GET")
+	s.router.HandleFunc("/api/llm/models", s.handleLLMModels).Methods("GET")
+	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
+}
+
+// admin restricts a route to admins, whose changes apply to every tenant
+func (s *Server) admin(next http.HandlerFunc) http.HandlerFunc {
+	return s.orchestrator.auth.RequireHTTP(middleware.PermTenantsManage, next)
+}
+
+// ownWorkflow restricts a workflow route to callers whose role holds p and
+// whose tenant the {id} workflow ran for. Other tenants' workflows are
+// reported as not found.
+func (s *Server) ownWorkflow(p middleware.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return s.orchestrator.auth.RequireHTTP(p, func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+			return
+		}
+		owner, err := workspace.ReadOwner(s.orchestrator.projectDir(id))
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CategoryInternal, "failed to read workflow owner", err))
+			return
+		}
+		tenantID := middleware.TenantOf(r)
+		if tenantID == uuid.Nil || owner.TenantID != tenantID.String() {
+			apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "workflow not found"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) handleOrchestrate(w http.ResponseWriter, r *http.Request) {
+	req, err := orchestrate.Decode(w, r)
+	if err != nil {
+		orchestrate.WriteError(w, err)
+		return
+	}
+
+	if tenant, err := uuid.Parse(orchestrate.TenantFromRequest(r)); err == nil {
+		req.TenantID = tenant
+	}
+
+	workflowID := uuid.New()
+	actor, actorType := audit.ActorFromRequest(r)
+	if err := s.moderate(r.Context(), workflowID, req, actor, actorType, "/api/orchestrate"); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	run := func(ctx context.Context) (*WorkflowResult, error) {
+		return s.run(ctx, workflowID, req, actor, actorType, "/api/orchestrate")
+	}
+
+	if req.Async {
+		go run(requestid.Detach(r.Context()))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"workflow_id": workflowID.String(),
+			"status":      "accepted",
+			"status_url":  "/api/workflow/" + workflowID.String(),
+		})
+		return
+	}
+
+	result, err := run(requestid.Detach(r.Context()))
+	if err != nil {
+		s.orchestrator.logger.Error("Workflow failed", apierror.Fields(err, zap.String("workflow_id", workflowID.String()), requestid.Field(r.Context()))...)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Status == StatusAwaitingClarification {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// execute screens one request, then runs it
+func (s *Server) execute(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request, actor, actorType, resource string) (*WorkflowResult, error) {
+	if err := s.moderate(ctx, workflowID, req, actor, actorType, resource); err != nil {
+		return nil, err
+	}
+	return s.run(ctx, workflowID, req, actor, actorType, resource)
+}
+
+// moderate screens a request's description against the content policy,
+// recording the decision in the audit log, and returns the rejection sent
+// to clients when it is refused
+func (s *Server) moderate(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request, actor, actorType, resource string) error {
+	m := s.orchestrator.moderator
+	if m == nil {
+		return nil
+	}
+	d := m.Check(ctx, req.Description)
+	moderation.Record(ctx, s.orchestrator.audit, d, audit.Event{
+		TenantID:    req.TenantID,
+		WorkflowID:  workflowID,
+		Actor:       actor,
+		ActorType:   actorType,
+		Resource:    resource,
+		RequestHash: audit.HashRequest(req),
+	})
+	if d.ClassifierError != "" {
+		logctx.From(ctx).Warn("Moderation classifier failed; screened with rules only", zap.String("error", d.ClassifierError))
+	}
+	if !d.Allowed {
+		logctx.From(ctx).Info("Request rejected by content policy",
+			zap.String("workflow_id", workflowID.String()),
+			zap.String("category", d.Category),
+			zap.String("source", d.Source),
+			zap.String("decision_id", d.ID.String()))
+	}
+	return m.Err(d)
+}
+
+// run runs one screened request and records it in the audit log
+func (s *Server) run(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request, actor, actorType, resource string) (*WorkflowResult, error) {
+	result, err := s.orchestrator.ExecuteRequest(ctx, workflowID, req)
+
+	event := audit.Event{
+		Actor:       actor,
+		ActorType:   actorType,
+		Action:      audit.ActionOrchestrate,
+		Resource:    resource,
+		WorkflowID:  workflowID,
+		RequestHash: audit.HashRequest(req),
+		Status:      audit.StatusSuccess,
+	}
+	if err != nil {
+		event.Status = audit.StatusFailure
+	}
+	s.orchestrator.audit.Record(ctx, event)
+	return result, err
+}
+
+// runSchedule runs one fire of a scheduled workflow for its tenant. A
+// workflow that completes without succeeding fails the run.
+func (s *Server) runSchedule(ctx context.Context, sched *schedule.Schedule, workflowID uuid.UUID) error {
+	req := sched.Request
+	if tenant, err := uuid.Parse(sched.Tenant); err == nil {
+		req.TenantID = tenant
+	}
+	resource := "/api/workflow/schedules/" + sched.ID.String()
+	result, err := s.execute(ctx, workflowID, &req, "schedule:"+sched.ID.String(), audit.ActorSystem, resource)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("workflow %s did not succeed", workflowID)
+	}
+	return nil
+}
+
+// rpcBackend runs workflows for the gRPC API and the GitHub integration,
+// auditing them under the given actor and resource
+type rpcBackend struct {
+	s         *Server
+	actor     string
+	actorType string
+	resource  string
+}
+
+func (b rpcBackend) Orchestrate(ctx context.Context, req *orchestrate.Request) (*orchestrate.Workflow, error) {
+	result, err := b.s.execute(ctx, uuid.New(), req, b.actor, b.actorType, b.resource)
+	if err != nil {
+		return nil, err
+	}
+	return result.workflow(), nil
+}
+
+func (b rpcBackend) Workflow(id uuid.UUID) (*orchestrate.Workflow, bool) {
+	result, ok := b.s.orchestrator.GetWorkflow(id)
+	if !ok {
+		return nil, false
+	}
+	return result.workflow(), true
+}
+
+func (b rpcBackend) Agents() []orchestrate.AgentInfo {
+	infos := make([]orchestrate.AgentInfo, 0, len(b.s.orchestrator.registry))
+	for agentType, agent := range b.s.orchestrator.registry {
+		infos = append(infos, orchestrate.AgentInfo{
+			Type:         agentType,
+			Description:  agent.GetDescription(),
+			Capabilities: agent.GetCapabilities(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+func (s *Server) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := s.lookupWorkflow(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workflow)
+}
+
+func (s *Server) handleWorkflowReport(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := s.lookupWorkflow(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=workflow_%s_report.csv", workflow.WorkflowID.String()[:8]))
+		if err := workflow.Report.WriteCSV(w); err != nil {
+			apierror.Write(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workflow.Report)
+}
+
+// handleResumeWorkflow serves POST /api/workflow/{id}/resume?from=<agent>
+func (s *Server) handleResumeWorkflow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return
+	}
+	from := agents.AgentType(r.URL.Query().Get("from"))
+
+	ctx := requestid.Detach(r.Context())
+	result, err := s.orchestrator.ResumeWorkflow(ctx, id, from)
+
+	actor, actorType := audit.ActorFromRequest(r)
+	event := audit.Event{
+		WorkflowID: id,
+		Actor:      actor,
+		ActorType:  actorType,
+		Action:     audit.ActionOrchestrate,
+		Resource:   r.URL.Path,
+		Status:     audit.StatusSuccess,
+		Metadata:   map[string]string{"resume_from": string(from)},
+	}
+	if err != nil {
+		event.Status = audit.StatusFailure
+	}
+	s.orchestrator.audit.Record(ctx, event)
+
+	if err != nil {
+		if apierror.CategoryOf(err) == apierror.CategoryInternal {
+			// Anything else is a bad resume point
+			err = apierror.Wrap(apierror.CategoryValidation, err.Error(), err)
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// clarificationRequest is the body of POST /api/workflow/{id}/clarification
+type clarificationRequest struct {
+	Answers map[string]string `json:"answers"` // By question ID; unanswered questions are left to the agents
+	Async   bool              `json:"async,omitempty"`
+}
+
+// handleClarification serves POST /api/workflow/{id}/clarification, running
+// a workflow paused for clarification with the answers merged into its task
+func (s *Server) handleClarification(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return
+	}
+	var req clarificationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, orchestrate.MaxBodyBytes)).Decode(&req); err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CategoryValidation, "invalid JSON body", err))
+		return
+	}
+
+	resume, err := s.orchestrator.AnswerClarification(id, req.Answers)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	actor, actorType := audit.ActorFromRequest(r)
+	run := func(ctx context.Context) (*WorkflowResult, error) {
+		result, err := resume(ctx)
+		event := audit.Event{
+			WorkflowID: id,
+			Actor:      actor,
+			ActorType:  actorType,
+			Action:     audit.ActionOrchestrate,
+			Resource:   r.URL.Path,
+			Status:     audit.StatusSuccess,
+		}
+		if err != nil {
+			event.Status = audit.StatusFailure
+		}
+		s.orchestrator.audit.Record(ctx, event)
+		return result, err
+	}
+
+	if req.Async {
+		go run(requestid.Detach(r.Context()))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"workflow_id": id.String(),
+			"status":      "accepted",
+			"status_url":  "/api/workflow/" + id.String(),
+		})
+		return
+	}
+
+	result, err := run(requestid.Detach(r.Context()))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleTranscript serves GET /api/workflow/{id}/transcript, the workflow's
+// LLM calls as JSONL signed with the artifact signing key, to callers of the
+// workflow's tenant. Every export is audited.
+func (s *Server) handleTranscript(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return
+	}
+	records, err := transcript.Load(s.orchestrator.projectDir(id), id)
+	if errors.Is(err, transcript.ErrNoTranscript) {
+		apierror.Write(w, r, apierror.Wrap(apierror.CategoryNotFound, "no transcript for workflow", err))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CategoryInternal, "failed to read transcript", err))
+		return
+	}
+	var buf bytes.Buffer
+	if err := transcript.Export(&buf, id, records, s.orchestrator.signer); err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CategoryInternal, "failed to export transcript", err))
+		return
+	}
+
+	actor, actorType := audit.ActorFromRequest(r)
+	event := audit.Event{
+		WorkflowID: id,
+		Actor:      actor,
+		ActorType:  actorType,
+		Action:     audit.ActionTranscriptExport,
+		Resource:   r.URL.Path,
+		Status:     audit.StatusSuccess,
+		Metadata:   map[string]string{"entries": fmt.Sprint(len(records))},
+	}
+	if principal, ok := middleware.PrincipalFromRequest(r); ok {
+		event = audit.FromTaskContext(event, principal.TaskContext())
+	}
+	s.orchestrator.audit.Record(r.Context(), event)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"transcript-%s.jsonl\"", id))
+	w.Write(buf.Bytes())
+}
+
+// lookupWorkflow resolves the {id} route variable, writing an error response
+// if the workflow is unknown
+func (s *Server) lookupWorkflow(w http.ResponseWriter, r *http.Request) (*WorkflowResult, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return nil, false
+	}
+
+	workflow, ok := s.orchestrator.GetWorkflow(id)
+	if !ok {
+		apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "workflow not found"))
+		return nil, false
+	}
+	return workflow, true
+}
+
+// handleAgentOutput serves an agent's full output, loading it from the
+// workspace when it was spilled
+func (s *Server) handleAgentOutput(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := s.lookupWorkflow(w, r)
+	if !ok {
+		return
+	}
+
+	agentType := agents.AgentType(mux.Vars(r)["agent"])
+	for _, res := range workflow.Results {
+		if res.Agent != agentType {
+			continue
+		}
+		output := res.Output
+		if res.OutputRef != nil {
+			var err error
+			output, err = s.orchestrator.outputs(workflow.WorkflowID).Load(r.Context(), res.OutputRef)
+			if errors.Is(err, agents.ErrArtifactNotFound) || errors.Is(err, os.ErrNotExist) {
+				apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "output no longer stored"))
+				return
+			}
+			if err != nil {
+				apierror.Write(w, r, apierror.Wrap(apierror.CategoryInternal, "failed to load output", err))
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, output)
+		return
+	}
+	apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "agent did not run in this workflow"))
+}
+
+// handleE2EArtifact serves a screenshot, video or trace of a failed
+// end-to-end test, by the path listed in the workflow report
+func (s *Server) handleE2EArtifact(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return
+	}
+	rel := mux.Vars(r)["path"]
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid artifact path"))
+		return
+	}
+	path := filepath.Join(s.orchestrator.projectDir(id), e2eArtifactsDir, filepath.FromSlash(rel))
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "artifact not found"))
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// handleLLMHealth reports the health of each LLM provider, the failover
+// chain of each agent and the prompt tokens compression saved
+func (s *Server) handleLLMHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"providers":   agents.DefaultLLMGuard.Health(),
+		"chains":      agents.DefaultLLMGuard.Chains(),
+		"compression": agents.DefaultLLMGuard.Compression(),
+	})
+}
+
+// handleLLMModels lists the models the provider serves and validates the
+// configured ones, suggesting replacements for those it no longer does
+func (s *Server) handleLLMModels(w http.ResponseWriter, r *http.Request) {
+	catalog := s.orchestrator.models
+	if catalog == nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryUnavailable, "model catalog sync is disabled"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"synced_at":  catalog.Synced(),
+		"models":     catalog.Models(),
+		"configured": catalog.Check(agents.ConfiguredModels(agents.DefaultLLMGuard, s.orchestrator.registry)),
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
+// configureFailover registers the alternate providers in providers, given
+// as name=baseURL pairs, and the per-agent chains in chains, given as
+// agent=target|target pairs, with DefaultLLMGuard
+func configureFailover(chains, providers string) error {
+	for _, pair := range strings.Split(providers, ",") {
+		name, baseURL, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		key := os.Getenv(strings.ToUpper(name) + "_API_KEY")
+		if key == "" {
+			return fmt.Errorf("failover provider %s needs %s_API_KEY", name, strings.ToUpper(name))
+		}
+		client, err := agents.NewLLMClient(key, baseURL)
+		if err != nil {
+			return err
+		}
+		agents.DefaultLLMGuard.AddProvider(name, client)
+		log.Printf("[FAILOVER] Provider %s at %s", name, baseURL)
+	}
+	for _, pair := range strings.Split(chains, ",") {
+		agent, targets, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		agents.DefaultLLMGuard.SetChain(agents.AgentType(agent), strings.Split(targets, "|")...)
+		log.Printf("[FAILOVER] %s fails over to %s", agent, targets)
+	}
+	return nil
+}
+
+// setupDigests sends the digests configured in configFile, checking every
+// interval whether one is due, and serves their preview to admins. A nil sender
+// leaves its channel unavailable; webhooks are signed with webhookSecret.
+func (s *Server) setupDigests(configFile string, interval time.Duration, slackSender *digest.SlackSender, email *digest.EmailSender, webhookSecret string) error {
+	if configFile == "" {
+		return nil
+	}
+	cfg, err := digest.LoadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	scheduler := digest.NewScheduler(cfg, s.orchestrator.digests, s.orchestrator.logger, time.Now())
+	scheduler.Render = s.orchestrator.renderDigest
+	scheduler.Improvements = s.orchestrator.appliedImprovements
+	scheduler.Slack, scheduler.Email = slackSender, email
+	scheduler.Webhook = digest.NewWebhookSender(webhookSecret)
+	s.router.HandleFunc("/api/admin/digests/preview", s.admin(digest.PreviewHandler(scheduler))).Methods("GET")
+	go scheduler.Run(context.Background(), interval)
+	log.Printf("[DIGEST] Sending digests from %s at %02d:00 UTC", configFile, cfg.Hour)
+	return nil
+}
+
+// setupRetention serves the workspace admin endpoints and, given a policy
+// file, enforces it every interval. The endpoints purge on request even
+// without policies.
+func (s *Server) setupRetention(root, policyFile, archiveTo, archiveToken string, interval time.Duration) error {
+	var archiver workspace.Archiver
+	if archiveTo != "" {
+		archiver = workspace.NewArchiver(archiveTo, archiveToken)
+	}
+	reaper := workspace.NewReaper(root, workspace.Policies{}, archiver)
+	s.router.HandleFunc("/api/admin/workspaces", s.admin(workspace.WorkspacesHandler(reaper))).Methods("GET")
+	s.router.HandleFunc("/api/admin/workspaces/purge", s.admin(workspace.PurgeHandler(reaper))).Methods("POST")
+	if s.orchestrator.blobs != nil {
+		s.router.HandleFunc("/api/admin/blobs", s.admin(workspace.BlobsHandler(s.orchestrator.blobs))).Methods("GET")
+	}
+	if policyFile == "" {
+		return nil
+	}
+	policies, err := workspace.LoadPolicies(policyFile)
+	if err != nil {
+		return err
+	}
+	reaper.SetPolicies(policies)
+	go reaper.Run(context.Background(), interval, s.orchestrator.logger)
+	log.Printf("[RETENTION] Enforcing %s every %s (archive: %q)", policyFile, interval, archiveTo)
+	return nil
+}
+
 func main() {
+	settings := config.NewLoader(flag.CommandLine)
 	var (
-		port      = flag.String("port", "8092", "Server port")
-		workspace = flag.String("workspace", "/Users/ososerious/OSA/agent-workspace", "Workspace directory")
+		port          = flag.String("port", "8092", "Server port")
+		workspace     = flag.String("workspace", "/Users/ososerious/OSA/agent-workspace", "Workspace directory")
+		grafanaURL    = flag.String("grafana-url", "", "Grafana base URL for dashboard provisioning")
+		grafanaFolder = flag.String("grafana-folder", "MIOSA", "Grafana folder for provisioned dashboards")
+		batchWorkers  = flag.Int("batch-workers", 4, "Workers running batch orchestration items")
+		batchQuotas   = flag.String("batch-quotas", "", "YAML file of per-tenant batch weights, concurrency limits and bursts; empty shares workers equally")
+		grpcPort      = flag.String("grpc-port", "9092", "gRPC server port; empty disables the gRPC API")
+		githubAppID   = flag.Int64("github-app-id", 0, "GitHub App ID; 0 disables the GitHub integration")
+		githubKey     = flag.String("github-private-key", "", "Path to the GitHub App private key")
+		githubAPI     = flag.String("github-api-url", "", "GitHub API base URL (defaults to api.github.com)")
+		githubLabel   = flag.String("github-label", githubapp.DefaultLabel, "Issue label that starts a workflow")
+		terraformBin  = flag.String("terraform", "", "terraform or tofu binary validating generated infrastructure (defaults to either on PATH)")
+		terraformPlan = flag.Bool("terraform-plan", false, "Also run terraform plan with the credentials in the environment")
+		loadTarget    = flag.String("loadtest-target", "", "Base URL of the smoke-test deployment to load test; empty only writes the k6 script")
+		e2eTarget     = flag.String("e2e-target", "", "Base URL of the deployment end-to-end tests run against (defaults to -loadtest-target); empty only writes the Playwright suite")
+		k6Bin         = flag.String("k6", "", "k6 binary running load tests (defaults to k6 on PATH)")
+		loadVUs       = flag.Int("loadtest-vus", 10, "Concurrent virtual users per load test")
+		loadDuration  = flag.Duration("loadtest-duration", 30*time.Second, "Duration of each load test")
+		testSandbox   = flag.String("test-sandbox", "", "E2B server URL running generated test suites, e.g. http://localhost:3001; empty skips the test stage")
+		testFixRounds = flag.Int("test-fix-rounds", 2, "Times the development agent is asked to fix failing generated tests")
+		frontendFixes = flag.Int("frontend-fix-rounds", 2, "Times the development agent is asked to fix generated React apps that fail npm run build, tsc or ESLint")
+		patchMode     = flag.Bool("patch-mode", true, "Fix failing tests with line edits to the existing files instead of regenerating them")
+		failover      = flag.String("agent-failover", "", "Failover chains per agent, e.g. development=llama-3.1-8b-instant|openai:gpt-4o-mini,quality=...")
+		altProviders  = flag.String("failover-providers", "", "Alternate OpenAI-compatible providers chains may name, e.g. openai=https://api.openai.com/v1; keys come from <NAME>_API_KEY")
+		retention     = flag.String("retention-policy", "", "YAML file of per-tenant workspace TTLs and size quotas; empty keeps workspaces until purged")
+		retentionTo   = flag.String("retention-archive", "", "Directory or object store URL workspaces are archived to before deletion; empty deletes without archiving")
+		retentionTick = flag.Duration("retention-interval", time.Hour, "How often workspace retention policies are enforced")
+		modelsURL     = flag.String("models-url", agents.DefaultModelsURL, "Provider endpoint listing the available models")
+		modelSync     = flag.Duration("model-sync-interval", time.Hour, "How often the model catalog is synced; configured models missing from it are replaced by the nearest available one. 0 disables validation")
+		transcripts   = flag.Bool("transcripts", false, "Record the redacted prompt and response of every LLM call for signed export; needs -signing-key")
+		dedupe        = flag.Bool("blob-dedupe", true, "Store identical generated content once across workspaces, with reference counting")
+		c4Diagrams    = flag.Bool("diagram-c4", false, "Also write C4-PlantUML architecture diagrams next to the Mermaid ones")
+		spillAt       = flag.Int("output-spill-threshold", agents.DefaultSpillThreshold, "Agent output size in bytes beyond which outputs are stored in the workspace and returned by reference; 0 returns them inline")
+		loadP95       = flag.Float64("loadtest-p95", quality.DefaultLoadThresholds.P95MS, "Highest acceptable p95 latency per endpoint in milliseconds")
+		loadMinRPS    = flag.Float64("loadtest-min-rps", 0, "Lowest acceptable throughput per endpoint in requests per second")
+		loadErrorRate = flag.Float64("loadtest-max-error-rate", quality.DefaultLoadThresholds.MaxErrorRate, "Highest acceptable share of failed requests per endpoint")
+		digestConfig  = flag.String("digest-config", "", "YAML file of per-tenant daily and weekly digest subscriptions (Slack, webhook, email); empty sends none")
+		digestTick    = flag.Duration("digest-interval", 5*time.Minute, "How often the digest schedule is checked")
+		scheduleTick  = flag.Duration("schedule-interval", 30*time.Second, "How often scheduled workflows are checked for due runs; 0 only runs them when triggered. Set it on one orchestrator instance per database")
+		smtpAddr      = flag.String("smtp-addr", "", "SMTP server host:port mailing digests; empty disables email delivery")
+		smtpFrom      = flag.String("smtp-from", "", "Sender address of digest emails")
+		smtpUser      = flag.String("smtp-username", "", "SMTP username; empty sends without authentication")
+		compressAt    = flag.Int("prompt-compress-tokens", agents.DefaultCompressMinTokens, "Prompt size in tokens beyond which earlier steps' outputs are compressed to their key facts before LLM calls; 0 disables compression")
+		sectionTokens = flag.Int("prompt-section-tokens", agents.DefaultSectionTokens, "Token budget of each section of a compressed prompt")
+		workflowTTL   = flag.Duration("workflow-ttl", DefaultWorkflowTTL, "How long finished workflows are kept in memory for the status and report endpoints; 0 keeps them until restart")
+		ideTTL        = flag.Duration("ide-session-ttl", ide.DefaultSessionTTL, "How long IDE session tokens last unless requested for less")
+		moderate      = flag.Bool("moderation", true, "Screen orchestrate descriptions for prohibited content (malware, credential harvesting) before any agent runs")
+		moderationCfg = flag.String("moderation-config", "", "YAML file of extra moderation rules and categories, classifier confidence and appeal link; empty uses the built-in rules")
+		moderationLLM = flag.String("moderation-model", moderation.DefaultClassifierModel, "Model classifying descriptions the moderation rules let through; empty screens with rules only")
+
+		apiKey        = settings.Secret("groq-api-key", "Groq API key", "GROQ_API_KEY")
+		grafanaKey    = settings.Secret("grafana-api-key", "Grafana API key", "GRAFANA_API_KEY")
+		githubSecret  = settings.Secret("github-webhook-secret", "GitHub App webhook secret", "GITHUB_WEBHOOK_SECRET")
+		slackSecret   = settings.Secret("slack-signing-secret", "Slack signing secret", "SLACK_SIGNING_SECRET")
+		slackToken    = settings.Secret("slack-bot-token", "Slack bot token", "SLACK_BOT_TOKEN")
+		archiveToken  = settings.Secret("retention-archive-token", "Bearer token for the workspace archive object store", "RETENTION_ARCHIVE_TOKEN")
+		signingKey    = settings.Secret("signing-key", "Key signing the checksums of generated files: ed25519:<base64 seed> or an HMAC secret; empty disables signing", "ARTIFACT_SIGNING_KEY")
+		databaseURL   = settings.Secret("database-url", "Postgres URL storing project knowledge graphs; empty keeps them in memory", "DATABASE_URL")
+		smtpPassword  = settings.Secret("smtp-password", "SMTP password", "SMTP_PASSWORD")
+		digestSecret  = settings.Secret("digest-webhook-secret", "Key signing digest webhook bodies in "+digest.SignatureHeader+"; empty sends them unsigned", "DIGEST_WEBHOOK_SECRET")
+		ideSecret     = settings.Secret("ide-token-secret", "Secret signing IDE session tokens, shared with the IDE server; empty disables issuing them", "IDE_TOKEN_SECRET")
+		jwtSecret     = settings.Secret("jwt-secret", "Secret verifying JWTs issued by the API gateway, which API keys are checked alongside when -database-url is set; empty refuses every call to admin and audit routes", "JWT_SECRET")
+		redisURL      = settings.Secret("redis-url", "Redis URL whose "+liveconfig.Channel+" channel hot-reloads routing, parallelism, models and timeouts; redis+sentinel:// and redis+cluster:// URLs list every seed address", "REDIS_URL")
 	)
-	flag.Parse()
+	settings.Env("grafana-url", "GRAFANA_URL")
+	settings.Env("github-private-key", "GITHUB_APP_PRIVATE_KEY_PATH")
+	settings.Env("github-api-url", "GITHUB_API_URL")
+	settings.Env("loadtest-target", "LOADTEST_TARGET")
+	settings.Env("test-sandbox", "E2B_SERVER_URL")
+	settings.Env("frontend-fix-rounds", "FRONTEND_FIX_ROUNDS")
+	settings.Env("e2e-target", "E2E_TARGET")
+	settings.Env("retention-policy", "RETENTION_POLICY_FILE")
+	settings.Env("batch-quotas", "BATCH_QUOTAS_FILE")
+	settings.Env("agent-failover", "AGENT_FAILOVER")
+	settings.Env("failover-providers", "LLM_FAILOVER_PROVIDERS")
+	settings.Env("retention-archive", "RETENTION_ARCHIVE_URL")
+	settings.Env("output-spill-threshold", "OUTPUT_SPILL_THRESHOLD")
+	settings.Env("workflow-ttl", "WORKFLOW_TTL")
+	settings.Env("diagram-c4", "DIAGRAM_C4")
+	settings.Env("blob-dedupe", "BLOB_DEDUPE")
+	settings.Env("transcripts", "LLM_TRANSCRIPTS")
+	settings.Env("patch-mode", "DEV_PATCH_MODE")
+	settings.Env("models-url", "GROQ_MODELS_URL")
+	settings.Env("model-sync-interval", "MODEL_SYNC_INTERVAL")
+	settings.Env("digest-config", "DIGEST_CONFIG_FILE")
+	settings.Env("schedule-interval", "SCHEDULE_INTERVAL")
+	settings.Env("smtp-addr", "SMTP_ADDR")
+	settings.Env("smtp-from", "SMTP_FROM")
+	settings.Env("smtp-username", "SMTP_USERNAME")
+	settings.Env("ide-session-ttl", "IDE_SESSION_TTL")
+	settings.Env("moderation", "MODERATION_ENABLED")
+	settings.Env("moderation-config", "MODERATION_CONFIG_FILE")
+	settings.Env("moderation-model", "MODERATION_MODEL")
+	settings.Require("groq-api-key")
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if settings.PrintRequested() {
+		settings.Print(os.Stdout)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Validate generated Terraform in a scratch directory
+	if *terraformBin == "" {
+		for _, name := range []string{"terraform", "tofu"} {
+			if bin, err := exec.LookPath(name); err == nil {
+				*terraformBin = bin
+				break
+			}
+		}
+	}
+	if *terraformBin != "" {
+		deployment.DefaultTerraformValidator = deployment.NewCLITerraformValidator(*terraformBin, *terraformPlan)
+		log.Printf("[TERRAFORM] Validating generated infrastructure with %s (plan: %v)", *terraformBin, *terraformPlan)
+	}
+
+	// Load test the smoke-test deployment
+	if *k6Bin == "" {
+		if bin, err := exec.LookPath("k6"); err == nil {
+			*k6Bin = bin
+		}
+	}
+	if *k6Bin != "" && *loadTarget != "" {
+		quality.DefaultLoadTester = quality.NewK6LoadTester(*k6Bin)
+		log.Printf("[LOADTEST] Load testing %s with %s", *loadTarget, *k6Bin)
+	}
+
+	// Run generated tests in the E2B sandbox
+	if *testSandbox != "" {
+		quality.DefaultSandbox = quality.NewE2BSandbox(*testSandbox)
+		log.Printf("[TESTS] Running generated tests through %s (fix rounds: %d)", *testSandbox, *testFixRounds)
+	}
 
-	apiKey := os.Getenv("GROQ_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GROQ_API_KEY environment variable is required")
+	// Fail over between models and providers when calls fail
+	if err := configureFailover(*failover, *altProviders); err != nil {
+		log.Fatal(err)
+	}
+	if *compressAt > 0 {
+		compressor := agents.NewPromptCompressor()
+		compressor.MinTokens = *compressAt
+		compressor.SectionTokens = *sectionTokens
+		agents.DefaultLLMGuard.SetCompressor(compressor)
 	}
 
 	// Create enhanced orchestrator
-	orchestrator, err := NewEnhancedOrchestrator(apiKey, *workspace)
+	orchestrator, err := NewEnhancedOrchestrator(*apiKey, *workspace)
 	if err != nil {
 		log.Fatal("Failed to create orchestrator:", err)
 	}
+	orchestrator.SetTestFixRounds(*testFixRounds)
+	orchestrator.SetFrontendFixRounds(*frontendFixes)
+	orchestrator.SetPatchMode(*patchMode)
+	orchestrator.SetSpillThreshold(*spillAt)
+	orchestrator.SetWorkflowTTL(*workflowTTL)
+	orchestrator.SetC4Diagrams(*c4Diagrams)
+	if *e2eTarget == "" {
+		*e2eTarget = *loadTarget
+	}
+	orchestrator.SetE2ETarget(*e2eTarget)
+	if *signingKey != "" {
+		signer, err := integrity.ParseSigner(*signingKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		orchestrator.SetSigner(signer)
+		log.Printf("[INTEGRITY] Signing generated files with %s key %s", signer.Algorithm(), signer.KeyID())
+	}
+	orchestrator.SetBlobDedupe(*dedupe)
+	if *transcripts {
+		if orchestrator.signer == nil {
+			log.Fatal("transcripts need a signing key to export them")
+		}
+		orchestrator.SetTranscripts(true)
+		log.Printf("[TRANSCRIPTS] Recording LLM calls for export")
+	}
+	if *moderate {
+		var cfg moderation.Config
+		if *moderationCfg != "" {
+			if cfg, err = moderation.LoadConfig(*moderationCfg); err != nil {
+				log.Fatal(err)
+			}
+		}
+		var classifier moderation.Classifier
+		if *moderationLLM != "" {
+			classifier = moderation.NewLLMClassifier(orchestrator.groqClient, *moderationLLM)
+		}
+		moderator, err := moderation.New(cfg, classifier)
+		if err != nil {
+			log.Fatal(err)
+		}
+		orchestrator.SetModerator(moderator)
+		log.Printf("[MODERATION] Screening requests (classifier: %q)", *moderationLLM)
+	}
+	if *modelSync > 0 {
+		orchestrator.SetModelCatalog(context.Background(), agents.NewModelCatalog(*modelsURL, *apiKey), *modelSync)
+	}
+	agents.DefaultContextEnricher.SetProjectGraph(orchestrator.projectContext)
+	var db *sql.DB
+	if *databaseURL != "" {
+		var err error
+		db, err = sql.Open("postgres", *databaseURL)
+		if err != nil {
+			log.Fatal("Invalid database URL:", err)
+		}
+		orchestrator.SetGraphStore(graph.NewPostgresStore(db))
+		log.Printf("[GRAPH] Storing project knowledge graphs in Postgres")
+		orchestrator.SetScheduleStore(schedule.NewPostgresStore(db))
+		log.Printf("[SCHEDULE] Storing scheduled workflows in Postgres")
+	}
+	orchestrator.SetLoadTest(quality.LoadTestConfig{
+		BaseURL:    *loadTarget,
+		VUs:        *loadVUs,
+		Duration:   *loadDuration,
+		Thresholds: quality.LoadThresholds{P95MS: *loadP95, MinRPS: *loadMinRPS, MaxErrorRate: *loadErrorRate},
+	})
+
+	var redisClient redis.UniversalClient
+	if *redisURL != "" {
+		client, err := redisconn.New(*redisURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		redisClient = client
+		go func() {
+			if err := orchestrator.live.Subscribe(context.Background(), client); err != nil {
+				log.Printf("[LIVECONFIG] Stopped applying config updates: %v", err)
+			}
+		}()
+		go liveconfig.WatchEnrichment(context.Background(), client, agents.DefaultContextEnricher, liveconfig.EnrichmentPollInterval, orchestrator.logger)
+		log.Printf("[LIVECONFIG] Applying config updates from %s", liveconfig.Channel)
+		orchestrator.SetFlags(flags.NewService(flags.NewRedisStore(client, orchestrator.logger), flags.DefaultTTL, orchestrator.logger))
+		log.Printf("[FLAGS] Reading feature flags from %s", flags.FlagsKey)
+	}
+
+	if *grafanaURL != "" {
+		orchestrator.SetGrafana(monitoring.NewGrafanaClient(*grafanaURL, *grafanaKey), *grafanaFolder)
+		log.Printf("[GRAFANA] Provisioning dashboards to %s", *grafanaURL)
+	}
+
+	if *jwtSecret != "" {
+		orchestrator.SetAuth(middleware.NewAuthMiddleware(&config.AuthConfig{JWTSecret: *jwtSecret}, db, redisClient, orchestrator.logger))
+	} else {
+		log.Printf("[AUTH] No -jwt-secret; admin and audit routes refuse every request")
+	}
 
 	// Create server
-	server := NewServer(orchestrator)
+	server := NewServer(orchestrator, *batchWorkers)
+	if *batchQuotas != "" {
+		quotas, err := orchestrate.LoadQuotas(*batchQuotas)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.batches.SetQuotas(quotas)
+	}
+	server.batches.Start(context.Background())
+
+	if err := server.schedules.Load(context.Background(), time.Now()); err != nil {
+		log.Fatal("Failed to load scheduled workflows:", err)
+	}
+	if *scheduleTick > 0 {
+		go server.schedules.Run(context.Background(), *scheduleTick)
+		log.Printf("[SCHEDULE] Checking scheduled workflows every %s", *scheduleTick)
+	}
+
+	if err := server.setupRetention(*workspace, *retention, *retentionTo, *archiveToken, *retentionTick); err != nil {
+		log.Fatal(err)
+	}
+
+	var digestSlack *digest.SlackSender
+	if *slackToken != "" {
+		digestSlack = &digest.SlackSender{Client: slack.NewClient(*slackToken, "")}
+	}
+	var digestEmail *digest.EmailSender
+	if *smtpAddr != "" {
+		digestEmail = &digest.EmailSender{Addr: *smtpAddr, From: *smtpFrom, Username: *smtpUser, Password: *smtpPassword}
+	}
+	if err := server.setupDigests(*digestConfig, *digestTick, digestSlack, digestEmail, *digestSecret); err != nil {
+		log.Fatal(err)
+	}
+
+	if *grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+*grpcPort)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC:", err)
+		}
+		grpcServer := grpc.NewServer()
+		orchestrate.RegisterOrchestratorServer(grpcServer, rpcBackend{s: server, actor: "grpc", actorType: audit.ActorAnonymous, resource: orchestrate.ServiceName})
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatal(err)
+			}
+		}()
+		log.Printf("[GRPC] Serving %s on port %s", orchestrate.ServiceName, *grpcPort)
+	}
+
+	if orchestrator.signer != nil {
+		server.router.HandleFunc("/api/integrity/key", integrity.KeyHandler(orchestrator.signer)).Methods("GET")
+		server.router.HandleFunc("/api/workflow/{id}/integrity", integrity.ManifestHandler(orchestrator.projectDir)).Methods("GET")
+		server.router.HandleFunc("/api/workflow/{id}/integrity/verify", integrity.VerifyHandler(orchestrator.projectDir, orchestrator.signer)).Methods("GET")
+		server.router.HandleFunc("/api/workflow/{id}/transcript", server.ownWorkflow(middleware.PermWorkflowsRead, server.handleTranscript)).Methods("GET")
+	}
+
+	if *ideSecret != "" {
+		issuer := ide.NewTokenIssuer(*ideSecret, *ideTTL)
+		server.router.HandleFunc("/api/workflow/{id}/ide-session", server.ownWorkflow(middleware.PermOrchestrateExecute, ide.IssueHandler(issuer, orchestrator.workspaceDir, orchestrator.projectDir, orchestrator.ideWriteScope))).Methods("POST")
+		log.Printf("[IDE] Issuing session tokens valid for %s", *ideTTL)
+	}
+
+	if *githubAppID != 0 {
+		if *githubSecret == "" {
+			log.Fatal("github-webhook-secret is required for the GitHub integration")
+		}
+		pem, err := os.ReadFile(*githubKey)
+		if err != nil {
+			log.Fatal("Failed to read GitHub App private key:", err)
+		}
+		key, err := githubapp.ParsePrivateKey(pem)
+		if err != nil {
+			log.Fatal(err)
+		}
+		github := githubapp.NewService(
+			githubapp.Config{WebhookSecret: *githubSecret, Label: *githubLabel},
+			githubapp.NewClient(*githubAppID, key, *githubAPI),
+			rpcBackend{s: server, actor: "github", actorType: audit.ActorSystem, resource: "/api/github/webhook"},
+			orchestrator.projectDir, orchestrator.audit, orchestrator.logger)
+		server.router.HandleFunc("/api/github/webhook", github.WebhookHandler()).Methods("POST")
+		log.Printf("[GITHUB] Opening pull requests for issues labeled %q", *githubLabel)
+	}
+
+	if *slackSecret != "" && *slackToken != "" {
+		// Registry commands resolve agents from the global registry
+		for _, agent := range orchestrator.registry {
+			agents.Register(agent)
+		}
+		slackService := slack.NewService(*slackSecret, slack.NewClient(*slackToken, ""),
+			claude.NewCommandExecutor(uuid.New(), uuid.Nil, uuid.Nil),
+			rpcBackend{s: server, actor: "slack", actorType: audit.ActorSystem, resource: "/api/slack/commands"},
+			orchestrator.logger)
+		server.router.HandleFunc("/api/slack/commands", slackService.CommandHandler()).Methods("POST")
+		log.Printf("[SLACK] Serving slash commands")
+	}
 
 	log.Printf("[ENHANCED ORCHESTRATOR] Starting on port %s", *port)
 	log.Printf("[WORKSPACE] %s", *workspace)
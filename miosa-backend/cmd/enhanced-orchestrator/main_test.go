@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+	"github.com/sormind/OSA/miosa-backend/internal/services/ide"
+	"github.com/sormind/OSA/miosa-backend/internal/transcript"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+const testJWTSecret = "test-secret"
+
+func bearer(t *testing.T, tenantID uuid.UUID, role string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+		UserID:   uuid.New(),
+		TenantID: tenantID,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+// newTestServer serves a test orchestrator, authenticating callers with
+// testJWTSecret when withAuth is set
+func newTestServer(t *testing.T, withAuth bool) *Server {
+	o := newTestOrchestrator(t)
+	if withAuth {
+		o.SetAuth(middleware.NewAuthMiddleware(&config.AuthConfig{JWTSecret: testJWTSecret}, nil, nil, o.logger))
+	}
+	o.SetBlobDedupe(true)
+	t.Cleanup(func() { o.SetBlobDedupe(false) })
+	s := NewServer(o, 1)
+	require.NoError(t, s.setupRetention(o.workspaceDir, "", "", "", time.Hour))
+	digests := filepath.Join(t.TempDir(), "digests.yaml")
+	require.NoError(t, os.WriteFile(digests, []byte("hour: 9\n"), 0o644))
+	require.NoError(t, s.setupDigests(digests, time.Hour, nil, nil, ""))
+	return s
+}
+
+func serve(s *Server, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func newTestOrchestrator(t *testing.T) *EnhancedOrchestrator {
+	o, err := NewEnhancedOrchestrator("test-key", t.TempDir())
+	require.NoError(t, err)
+	return o
+}
+
+func TestStoreWorkflowEvictsExpiredWorkflows(t *testing.T) {
+	o := newTestOrchestrator(t)
+	o.SetWorkflowTTL(time.Hour)
+
+	stale := &WorkflowResult{WorkflowID: uuid.New(), Timestamp: time.Now().Add(-2 * time.Hour)}
+	paused := &WorkflowResult{WorkflowID: uuid.New(), Status: StatusAwaitingClarification, Timestamp: time.Now().Add(-2 * time.Hour)}
+	fresh := &WorkflowResult{WorkflowID: uuid.New(), Timestamp: time.Now()}
+
+	o.mu.Lock()
+	o.workflows[stale.WorkflowID] = stale
+	o.workflows[paused.WorkflowID] = paused
+	o.clarifying[paused.WorkflowID] = &pendingClarification{}
+	o.storeWorkflow(fresh)
+	o.mu.Unlock()
+
+	_, ok := o.GetWorkflow(stale.WorkflowID)
+	assert.False(t, ok, "finished workflow past the TTL is evicted")
+	_, ok = o.GetWorkflow(paused.WorkflowID)
+	assert.False(t, ok, "paused workflow past the TTL is evicted")
+	assert.NotContains(t, o.clarifying, paused.WorkflowID)
+	_, ok = o.GetWorkflow(fresh.WorkflowID)
+	assert.True(t, ok)
+}
+
+func TestStoreWorkflowKeepsEverythingWithoutTTL(t *testing.T) {
+	o := newTestOrchestrator(t)
+	o.SetWorkflowTTL(0)
+
+	old := &WorkflowResult{WorkflowID: uuid.New(), Timestamp: time.Now().Add(-30 * 24 * time.Hour)}
+	o.mu.Lock()
+	o.workflows[old.WorkflowID] = old
+	o.storeWorkflow(&WorkflowResult{WorkflowID: uuid.New(), Timestamp: time.Now()})
+	o.mu.Unlock()
+
+	_, ok := o.GetWorkflow(old.WorkflowID)
+	assert.True(t, ok)
+}
+
+func TestResumeTaskKeepsParametersAndContext(t *testing.T) {
+	o := newTestOrchestrator(t)
+	ctx := context.Background()
+	workflowID := uuid.New()
+	tenantID, userID := uuid.New(), uuid.New()
+
+	o.checkpoint(ctx, 0, agents.AnalysisAgent, agents.Task{
+		ID:    workflowID,
+		Type:  "orchestrate",
+		Input: "Build a todo app",
+		Parameters: map[string]interface{}{
+			"stack":       "go",
+			"quality_min": 7.5,
+		},
+		Context: &agents.TaskContext{
+			TenantID: tenantID,
+			UserID:   userID,
+			Metadata: map[string]string{"source": "github"},
+			History:  []agents.Message{{Role: "user", Content: "earlier"}},
+		},
+	}, &agents.Result{Success: true, Output: "analysis"}, nil)
+
+	// Resume from what the checkpoint store persisted, not the original task
+	checkpoints, err := o.checkpoints.Load(ctx, workflowID)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 1)
+
+	task := resumeTask(workflowID, checkpoints[0].Task)
+	assert.Equal(t, workflowID, task.ID)
+	assert.Equal(t, "Build a todo app", task.Input)
+	assert.Equal(t, "go", task.Parameters["stack"])
+	assert.Equal(t, 7.5, task.Parameters["quality_min"])
+	assert.Equal(t, tenantID, task.Context.TenantID)
+	assert.Equal(t, userID, task.Context.UserID)
+	assert.Equal(t, "github", task.Context.Metadata["source"])
+	assert.Equal(t, "resume", task.Context.Phase)
+	assert.Empty(t, task.Context.History, "step results are replayed by the caller")
+
+	// The resumed task owns its parameters
+	task.Parameters["files"] = []string{"main.go"}
+	assert.NotContains(t, checkpoints[0].Task.Parameters, "files")
+}
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/config/live"},
+		{http.MethodPost, "/api/config/live/rollback"},
+		{http.MethodGet, "/api/admin/workspaces"},
+		{http.MethodPost, "/api/admin/workspaces/purge"},
+		{http.MethodGet, "/api/admin/blobs"},
+		{http.MethodGet, "/api/admin/flags"},
+		{http.MethodPut, "/api/admin/flags/patch_mode"},
+		{http.MethodDelete, "/api/admin/flags/patch_mode"},
+		{http.MethodGet, "/api/admin/digests/preview"},
+	}
+
+	unconfigured := newTestServer(t, false)
+	s := newTestServer(t, true)
+	tenantID := uuid.New()
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			assert.Equal(t, http.StatusServiceUnavailable, serve(unconfigured, route.method, route.path, bearer(t, tenantID, "admin")).Code)
+			assert.Equal(t, http.StatusUnauthorized, serve(s, route.method, route.path, "").Code)
+			assert.Equal(t, http.StatusForbidden, serve(s, route.method, route.path, bearer(t, tenantID, "operator")).Code)
+			w := serve(s, route.method, route.path, bearer(t, tenantID, "admin"))
+			assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusServiceUnavailable}, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestTranscriptRequiresOwningTenant(t *testing.T) {
+	s := newTestServer(t, true)
+	s.orchestrator.SetSigner(integrity.NewHMACSigner([]byte("signing-key")))
+	s.router.HandleFunc("/api/workflow/{id}/transcript", s.ownWorkflow(middleware.PermWorkflowsRead, s.handleTranscript)).Methods("GET")
+
+	tenantID, id := uuid.New(), uuid.New()
+	root := s.orchestrator.projectDir(id)
+	require.NoError(t, workspace.RecordOwner(root, workspace.Owner{WorkflowID: id.String(), TenantID: tenantID.String()}))
+	recorder, err := transcript.NewRecorder(root, id)
+	require.NoError(t, err)
+	recorder.Record(agents.TranscriptEntry{Response: "package main", At: time.Now()})
+	require.NoError(t, recorder.Err())
+
+	path := "/api/workflow/" + id.String() + "/transcript"
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodGet, path, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodGet, path, bearer(t, uuid.New(), "admin")).Code, "another tenant's workflow")
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodGet, "/api/workflow/"+uuid.New().String()+"/transcript", bearer(t, tenantID, "viewer")).Code)
+	w := serve(s, http.MethodGet, path, bearer(t, tenantID, "viewer"))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "package main")
+}
+
+func TestIDESessionRequiresOwningTenant(t *testing.T) {
+	s := newTestServer(t, true)
+	issuer := ide.NewTokenIssuer("ide-secret", time.Hour)
+	s.router.HandleFunc("/api/workflow/{id}/ide-session", s.ownWorkflow(middleware.PermOrchestrateExecute, ide.IssueHandler(issuer, s.orchestrator.workspaceDir, s.orchestrator.projectDir, s.orchestrator.ideWriteScope))).Methods("POST")
+
+	tenantID, id := uuid.New(), uuid.New()
+	require.NoError(t, workspace.RecordOwner(s.orchestrator.projectDir(id), workspace.Owner{WorkflowID: id.String(), TenantID: tenantID.String()}))
+
+	path := "/api/workflow/" + id.String() + "/ide-session"
+	assert.Equal(t, http.StatusUnauthorized, serve(s, http.MethodPost, path, "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(s, http.MethodPost, path, bearer(t, tenantID, "viewer")).Code)
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodPost, path, bearer(t, uuid.New(), "admin")).Code, "another tenant's workflow")
+	w := serve(s, http.MethodPost, path, bearer(t, tenantID, "developer"))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"token"`)
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -25,16 +28,40 @@ import (
 	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/recommender"
 	"github.com/sormind/OSA/miosa-backend/internal/agents/strategy"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/catalog"
+	"github.com/sormind/OSA/miosa-backend/internal/claude"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/ingest"
+	"github.com/sormind/OSA/miosa-backend/internal/knowledge"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
+	"github.com/sormind/OSA/miosa-backend/internal/reporting"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
 	"github.com/conneroisu/groq-go"
+	_ "github.com/lib/pq"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
+// DefaultWorkflowTTL is how long finished workflows are kept in memory
+const DefaultWorkflowTTL = 24 * time.Hour
+
 // FullOrchestrator manages ALL agents
 type FullOrchestrator struct {
 	registry    map[agents.AgentType]agents.Agent
 	groqClient  *groq.Client
 	logger      *zap.Logger
 	workspaceDir string
+	workflowTTL time.Duration
+	workflows   map[uuid.UUID]*WorkflowResult
+	knowledge   *knowledge.Base
+	audit       *audit.Log
+	checkpoints agents.CheckpointStore
+	auth        *middleware.AuthMiddleware
 	mu          sync.RWMutex
 }
 
@@ -45,6 +72,7 @@ func NewFullOrchestrator(apiKey, workspaceDir string) (*FullOrchestrator, error)
 	if err != nil {
 		return nil, err
 	}
+	logger = redact.Logger(logger)
 
 	// Initialize Groq client
 	groqClient, err := groq.NewClient(apiKey)
@@ -58,6 +86,11 @@ func NewFullOrchestrator(apiKey, workspaceDir string) (*FullOrchestrator, error)
 		groqClient:   groqClient,
 		logger:       logger,
 		workspaceDir: workspaceDir,
+		workflowTTL:  DefaultWorkflowTTL,
+		workflows:    make(map[uuid.UUID]*WorkflowResult),
+		knowledge:    knowledge.New(nil, nil),
+		audit:        audit.New(audit.NewMemoryStore(), logger),
+		checkpoints:  agents.NewFileCheckpointStore(filepath.Join(workspaceDir, ".checkpoints")),
 	}
 
 	// Register ALL agents
@@ -82,87 +115,296 @@ func (o *FullOrchestrator) registerAllAgents() {
 	o.logger.Info("Registered all agents", zap.Int("count", len(o.registry)))
 }
 
+// resolve returns a registered agent
+func (o *FullOrchestrator) resolve(agentType agents.AgentType) (agents.Agent, error) {
+	agent, ok := o.registry[agentType]
+	if !ok {
+		return nil, fmt.Errorf("agent %s not registered", agentType)
+	}
+	return agent, nil
+}
+
+// workflowSequence is the agent execution order for a comprehensive solution
+var workflowSequence = []agents.AgentType{
+	agents.StrategyAgent,    // Strategic planning
+	agents.AnalysisAgent,    // Requirements analysis
+	agents.ArchitectAgent,   // System architecture
+	agents.DevelopmentAgent, // Implementation
+	agents.QualityAgent,     // Quality assurance
+	agents.MonitoringAgent,  // Monitoring setup
+	agents.DeploymentAgent,  // Deployment config
+	agents.RecommenderAgent, // Recommendations
+}
+
 // ExecuteWorkflow runs complete multi-agent workflow
 func (o *FullOrchestrator) ExecuteWorkflow(ctx context.Context, description string) (*WorkflowResult, error) {
-	workflowID := uuid.New()
-	results := make([]AgentResult, 0)
+	return o.ExecuteRequest(ctx, uuid.New(), &orchestrate.Request{Description: description})
+}
+
+// ExecuteRequest runs a workflow for a validated orchestrate request under
+// the given workflow ID
+func (o *FullOrchestrator) ExecuteRequest(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request) (*WorkflowResult, error) {
+	return o.runWorkflow(ctx, req.Task(workflowID), 0, nil)
+}
+
+// ResumeWorkflow restarts a workflow from the given agent, or from its first
+// failed step when from is empty, reusing the checkpointed results of every
+// earlier step instead of running those agents again
+func (o *FullOrchestrator) ResumeWorkflow(ctx context.Context, workflowID uuid.UUID, from agents.AgentType) (*WorkflowResult, error) {
+	checkpoints, err := o.checkpoints.Load(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	start, err := agents.ResumeIndex(workflowSequence, checkpoints, from)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild the task from the first checkpoint and replay earlier outputs
+	// into memory exactly as the original run did
+	task := resumeTask(workflowID, checkpoints[0].Task)
+	prior := make([]*agents.Checkpoint, 0, start)
+	for _, cp := range checkpoints {
+		if cp.Step < start && cp.Succeeded() {
+			prior = append(prior, cp)
+			task.Context.Record(cp.Agent, cp.Result)
+			shareFiles(&task, cp.Result)
+		}
+	}
+
+	o.logger.Info("Resuming workflow",
+		zap.String("workflow_id", workflowID.String()),
+		zap.Int("start_step", start),
+		zap.Int("reused_steps", len(prior)))
 
-	// Create base task
+	return o.runWorkflow(ctx, task, start, prior)
+}
+
+// resumeTask rebuilds the task a workflow started with from its first
+// checkpoint: its input and parameters, and who it runs for. Shared files
+// are left out; the caller shares them again from the reused results.
+func resumeTask(workflowID uuid.UUID, base agents.Task) agents.Task {
 	task := agents.Task{
-		ID:    workflowID,
-		Type:  "implementation",
-		Input: description,
+		ID:         workflowID,
+		Type:       base.Type,
+		Input:      base.Input,
+		Parameters: make(map[string]interface{}, len(base.Parameters)),
+		Priority:   base.Priority,
+		Timeout:    base.Timeout,
 		Context: &agents.TaskContext{
-			Phase: "initialization",
-			Memory: make(map[string]interface{}),
+			Phase:    "resume",
+			Memory:   make(map[string]interface{}),
+			Metadata: make(map[string]string),
 		},
 	}
+	for k, v := range base.Parameters {
+		if k != "files" {
+			task.Parameters[k] = v
+		}
+	}
+	if base.Context != nil {
+		task.Context.UserID = base.Context.UserID
+		task.Context.TenantID = base.Context.TenantID
+		task.Context.WorkspaceID = base.Context.WorkspaceID
+		task.Context.SessionID = base.Context.SessionID
+		task.Context.ConsultationID = base.Context.ConsultationID
+		for k, v := range base.Context.Metadata {
+			task.Context.Metadata[k] = v
+		}
+	}
+	return task
+}
 
-	// Define agent execution order for comprehensive solution
-	agentSequence := []agents.AgentType{
-		agents.StrategyAgent,      // Strategic planning
-		agents.AnalysisAgent,      // Requirements analysis  
-		agents.ArchitectAgent,     // System architecture
-		agents.DevelopmentAgent,   // Implementation
-		agents.QualityAgent,       // Quality assurance
-		agents.MonitoringAgent,    // Monitoring setup
-		agents.DeploymentAgent,    // Deployment config
-		agents.RecommenderAgent,   // Recommendations
+// runWorkflow executes workflowSequence from index start, checkpointing each
+// step. prior holds results reused from an earlier run.
+func (o *FullOrchestrator) runWorkflow(ctx context.Context, task agents.Task, start int, prior []*agents.Checkpoint) (*WorkflowResult, error) {
+	workflowID := task.ID
+	run := uuid.New().String()
+	task.Context.RequestID = requestid.FromContext(ctx)
+	results := make([]AgentResult, 0, len(workflowSequence))
+	report := reporting.NewWorkflowReport(workflowID)
+
+	for _, cp := range prior {
+		report.Add(cp.Agent, cp.Result)
+		results = append(results, AgentResult{
+			Agent:       cp.Agent,
+			Success:     cp.Result.Success,
+			Output:      cp.Result.Output,
+			Confidence:  cp.Result.Confidence,
+			ExecutionMS: cp.Result.ExecutionMS,
+			Reused:      true,
+		})
+		reused := results[len(results)-1].step()
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepCompleted, WorkflowID: workflowID, Step: cp.Step, Agent: cp.Agent, Result: &reused})
 	}
 
 	// Execute agents in sequence
-	for _, agentType := range agentSequence {
+	for step := start; step < len(workflowSequence); step++ {
+		agentType := workflowSequence[step]
 		agent, exists := o.registry[agentType]
 		if !exists {
 			o.logger.Warn("Agent not found", zap.String("type", string(agentType)))
 			continue
 		}
 
-		o.logger.Info("Executing agent", zap.String("type", string(agentType)))
+		o.logger.Info("Executing agent", zap.String("type", string(agentType)), requestid.Field(ctx))
 
 		// Update task context
 		task.Context.Phase = string(agentType)
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepStarted, WorkflowID: workflowID, Step: step, Agent: agentType})
 
 		// Execute agent
-		result, err := agent.Execute(ctx, task)
+		result, err := agents.ExecuteTracked(ctx, agent, task)
 		if err != nil {
-			o.logger.Error("Agent failed", 
-				zap.String("type", string(agentType)),
-				zap.Error(err))
+			o.logger.Error("Agent failed",
+				apierror.Fields(err, zap.String("type", string(agentType)), requestid.Field(ctx))...)
+			o.checkpoint(ctx, step, agentType, task, nil, err)
+			orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepFailed, WorkflowID: workflowID, Step: step, Agent: agentType, Error: err.Error()})
 			continue
 		}
 
+		// Strip secrets before anything is written or returned
+		redactions := redact.Result(result)
+		o.checkpoint(ctx, step, agentType, task, result, nil)
+
+		if agentType == agents.DeploymentAgent {
+			o.audit.Record(ctx, audit.Event{
+				WorkflowID: workflowID,
+				Actor:      "full-orchestrator",
+				ActorType:  audit.ActorSystem,
+				Action:     audit.ActionDeploymentTrigger,
+				Resource:   string(agentType),
+				Status:     successStatus(result.Success),
+			})
+		}
+
 		// Save agent output
-		if err := o.saveAgentOutput(agentType, workflowID, result); err != nil {
+		prov := workspace.Provenance{
+			WorkflowID:    workflowID.String(),
+			Run:           run,
+			Step:          step,
+			Agent:         string(agentType),
+			Model:         result.Model,
+			PromptVersion: result.PromptVersion,
+			RequestID:     requestid.FromContext(ctx),
+		}
+		if err := o.saveAgentOutput(ctx, agentType, workflowID, prov, result); err != nil {
 			o.logger.Error("Failed to save output", zap.Error(err))
 		}
 
 		// Record result
+		report.Add(agentType, result)
 		results = append(results, AgentResult{
 			Agent:       agentType,
 			Success:     result.Success,
 			Output:      result.Output,
 			Confidence:  result.Confidence,
 			ExecutionMS: result.ExecutionMS,
+			Redactions:  redactions,
 		})
+		completed := results[len(results)-1].step()
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepCompleted, WorkflowID: workflowID, Step: step, Agent: agentType, Result: &completed})
 
-		// Update task memory with result
-		if task.Context.Memory == nil {
-			task.Context.Memory = make(map[string]interface{})
-		}
-		task.Context.Memory[string(agentType)] = result.Output
+		// Record the step; later prompts see it summarized
+		task.Context.Record(agentType, result)
+		shareFiles(&task, result)
 	}
 
-	return &WorkflowResult{
+	workflow := &WorkflowResult{
 		WorkflowID: workflowID,
 		Results:    results,
 		Success:    true,
 		Timestamp:  time.Now(),
-	}, nil
+		Report:     report,
+	}
+
+	o.mu.Lock()
+	o.storeWorkflow(workflow)
+	o.mu.Unlock()
+
+	return workflow, nil
+}
+
+// shareFiles appends an agent's generated files to task.Parameters["files"]
+// so later agents, such as the quality gate, can inspect them
+func shareFiles(task *agents.Task, result *agents.Result) {
+	if len(result.Files) == 0 {
+		return
+	}
+	if task.Parameters == nil {
+		task.Parameters = make(map[string]interface{})
+	}
+	files, _ := task.Parameters["files"].([]agents.GeneratedFile)
+	task.Parameters["files"] = append(files, result.Files...)
+}
+
+// checkpoint persists the task an agent received and what it produced so
+// the workflow can later resume from this step
+func (o *FullOrchestrator) checkpoint(ctx context.Context, step int, agentType agents.AgentType, task agents.Task, result *agents.Result, err error) {
+	cp := &agents.Checkpoint{
+		WorkflowID: task.ID,
+		Step:       step,
+		Agent:      agentType,
+		Task:       task,
+		Result:     result,
+	}
+	if err != nil {
+		cp.Error = err.Error()
+	}
+	if err := o.checkpoints.Save(ctx, cp); err != nil {
+		o.logger.Warn("Failed to save checkpoint",
+			zap.String("workflow_id", task.ID.String()),
+			zap.String("agent", string(agentType)),
+			zap.Error(err))
+	}
+}
+
+// successStatus maps an agent's success flag to an audit status
+func successStatus(ok bool) string {
+	if ok {
+		return audit.StatusSuccess
+	}
+	return audit.StatusFailure
+}
+
+// SetAuth authenticates callers of the audit route; without it the route
+// refuses every request
+func (o *FullOrchestrator) SetAuth(auth *middleware.AuthMiddleware) {
+	o.auth = auth
+}
+
+// SetWorkflowTTL sets how long finished workflows are kept in memory;
+// zero or less keeps them until restart
+func (o *FullOrchestrator) SetWorkflowTTL(ttl time.Duration) {
+	o.mu.Lock()
+	o.workflowTTL = ttl
+	o.mu.Unlock()
+}
+
+// storeWorkflow records a workflow, evicting those older than the
+// workflow TTL. The caller holds o.mu.
+func (o *FullOrchestrator) storeWorkflow(workflow *WorkflowResult) {
+	if o.workflowTTL > 0 {
+		cutoff := time.Now().Add(-o.workflowTTL)
+		for id, w := range o.workflows {
+			if w.Timestamp.Before(cutoff) {
+				delete(o.workflows, id)
+			}
+		}
+	}
+	o.workflows[workflow.WorkflowID] = workflow
+}
+
+// GetWorkflow returns a previously executed workflow
+func (o *FullOrchestrator) GetWorkflow(id uuid.UUID) (*WorkflowResult, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	workflow, ok := o.workflows[id]
+	return workflow, ok
 }
 
 // saveAgentOutput saves agent output to appropriate directory
-func (o *FullOrchestrator) saveAgentOutput(agentType agents.AgentType, workflowID uuid.UUID, result *agents.Result) error {
+func (o *FullOrchestrator) saveAgentOutput(ctx context.Context, agentType agents.AgentType, workflowID uuid.UUID, prov workspace.Provenance, result *agents.Result) error {
 	// Determine output directory based on agent type
 	var outputDir string
 	var fileName string
@@ -207,23 +449,53 @@ func (o *FullOrchestrator) saveAgentOutput(agentType agents.AgentType, workflowI
 		extension = ".txt"
 	}
 
-	// Create directory if it doesn't exist
-	fullDir := filepath.Join(o.workspaceDir, outputDir)
-	if err := os.MkdirAll(fullDir, 0755); err != nil {
+	// Write file, keeping any edits made since a previous run generated it
+	ws, err := workspace.Open(o.workspaceDir)
+	if err != nil {
 		return err
 	}
-
-	// Write file
-	filePath := filepath.Join(fullDir, fileName+extension)
-	return os.WriteFile(filePath, []byte(result.Output), 0644)
+	res, err := ws.Write(filepath.Join(outputDir, fileName+extension), result.Output, prov)
+	if err != nil {
+		return err
+	}
+	if err := ws.Save(); err != nil {
+		return err
+	}
+	filePath := res.Path
+	if res.Outcome == workspace.Conflict {
+		o.logger.Warn("Generated file conflicts with local edits",
+			zap.String("file", res.Conflict.Path),
+			zap.String("proposed", res.Conflict.Proposed))
+	}
+	o.audit.Record(ctx, audit.Event{
+		WorkflowID:  workflowID,
+		Actor:       string(agentType),
+		ActorType:   audit.ActorSystem,
+		Action:      audit.ActionFileWrite,
+		Resource:    filePath,
+		RequestHash: audit.HashRequest(result.Output),
+		Metadata:    map[string]string{"outcome": string(res.Outcome)},
+	})
+
+	// Make generated documentation searchable for later workflows
+	if extension == ".md" {
+		return o.knowledge.Index(ctx, &knowledge.Document{
+			ProjectID: workflowID.String(),
+			Agent:     agentType,
+			Path:      filePath,
+			Content:   result.Output,
+		})
+	}
+	return nil
 }
 
 // WorkflowResult represents complete workflow execution
 type WorkflowResult struct {
-	WorkflowID uuid.UUID     `json:"workflow_id"`
-	Results    []AgentResult `json:"results"`
-	Success    bool          `json:"success"`
-	Timestamp  time.Time     `json:"timestamp"`
+	WorkflowID uuid.UUID                 `json:"workflow_id"`
+	Results    []AgentResult             `json:"results"`
+	Success    bool                      `json:"success"`
+	Timestamp  time.Time                 `json:"timestamp"`
+	Report     *reporting.WorkflowReport `json:"report,omitempty"`
 }
 
 // AgentResult represents individual agent result
@@ -233,44 +505,105 @@ type AgentResult struct {
 	Output      string          `json:"output"`
 	Confidence  float64         `json:"confidence"`
 	ExecutionMS int64           `json:"execution_ms"`
+	Redactions  int             `json:"redactions,omitempty"`
+	Reused      bool            `json:"reused,omitempty"`
+}
+
+// step converts the result for the gRPC API
+func (r AgentResult) step() orchestrate.StepResult {
+	return orchestrate.StepResult{
+		Agent:       r.Agent,
+		Success:     r.Success,
+		Output:      r.Output,
+		Confidence:  r.Confidence,
+		ExecutionMS: r.ExecutionMS,
+		Reused:      r.Reused,
+	}
+}
+
+// workflow converts the result for the gRPC API
+func (r *WorkflowResult) workflow() *orchestrate.Workflow {
+	w := &orchestrate.Workflow{ID: r.WorkflowID, Success: r.Success, Timestamp: r.Timestamp}
+	for _, res := range r.Results {
+		w.Steps = append(w.Steps, res.step())
+	}
+	return w
 }
 
 // API Server
 type Server struct {
 	orchestrator *FullOrchestrator
 	router       *mux.Router
+	batches      *orchestrate.Scheduler
+	catalog      *catalog.Cache
 }
 
-func NewServer(orchestrator *FullOrchestrator) *Server {
+func NewServer(orchestrator *FullOrchestrator, batchWorkers int) *Server {
 	s := &Server{
 		orchestrator: orchestrator,
 		router:       mux.NewRouter(),
 	}
+	commands := claude.NewCommandRegistry()
+	s.catalog = catalog.NewCache(func() *catalog.Catalog {
+		return catalog.Build(orchestrator.registry, commands)
+	})
+	s.batches = orchestrate.NewScheduler(func(ctx context.Context, job orchestrate.Job) (interface{}, error) {
+		return s.execute(ctx, job.WorkflowID, job.Request, job.Actor, job.ActorType, "/api/orchestrate/batch")
+	}, batchWorkers)
 	s.setupRoutes()
 	return s
 }
 
 func (s *Server) setupRoutes() {
+	s.router.Use(requestid.Handler)
 	s.router.HandleFunc("/api/orchestrate", s.handleOrchestrate).Methods("POST")
-	s.router.HandleFunc("/api/agents", s.handleListAgents).Methods("GET")
+	s.router.HandleFunc("/api/orchestrate/batch", s.batches.SubmitHandler()).Methods("POST")
+	s.router.HandleFunc("/api/orchestrate/batch/{id}", s.batches.StatusHandler()).Methods("GET")
+	s.router.HandleFunc("/api/orchestrate/batch/{id}/items/{index}", s.batches.ItemHandler()).Methods("GET")
+	s.router.HandleFunc("/api/agents", s.catalog.AgentsHandler()).Methods("GET")
+	s.router.HandleFunc("/api/commands", s.catalog.CommandsHandler()).Methods("GET")
+	s.router.HandleFunc("/api/catalog", s.catalog.Handler()).Methods("GET")
 	s.router.HandleFunc("/api/workflow/{id}", s.handleGetWorkflow).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/report", s.handleWorkflowReport).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/resume", s.handleResumeWorkflow).Methods("POST")
+	s.router.HandleFunc("/api/workflow/{id}/provenance", workspace.ProvenanceHandler(func(uuid.UUID) string { return s.orchestrator.workspaceDir })).Methods("GET")
+	s.router.HandleFunc("/api/workflow/{id}/archive", workspace.ArchiveHandler(func(uuid.UUID) string { return s.orchestrator.workspaceDir })).Methods("GET")
+	s.router.HandleFunc("/api/ingest", ingest.Handler(ingest.NewPipeline(s.orchestrator.resolve), s.orchestrator.workspaceDir, s.orchestrator.audit)).Methods("POST")
+	s.router.HandleFunc("/api/knowledge/search", knowledge.SearchHandler(s.orchestrator.knowledge)).Methods("GET")
+	s.router.HandleFunc("/api/audit", s.orchestrator.auth.RequireHTTP(middleware.PermAuditRead, audit.QueryHandler(s.orchestrator.audit, middleware.TenantOf))).Methods("GET")
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
 }
 
 func (s *Server) handleOrchestrate(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Description string `json:"description"`
+	req, err := orchestrate.Decode(w, r)
+	if err != nil {
+		orchestrate.WriteError(w, err)
+		return
+	}
+
+	workflowID := uuid.New()
+	actor, actorType := audit.ActorFromRequest(r)
+	run := func(ctx context.Context) (*WorkflowResult, error) {
+		return s.execute(ctx, workflowID, req, actor, actorType, "/api/orchestrate")
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if req.Async {
+		go run(requestid.Detach(r.Context()))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"workflow_id": workflowID.String(),
+			"status":      "accepted",
+			"status_url":  "/api/workflow/" + workflowID.String(),
+		})
 		return
 	}
 
-	ctx := context.Background()
-	result, err := s.orchestrator.ExecuteWorkflow(ctx, req.Description)
+	result, err := run(requestid.Detach(r.Context()))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.orchestrator.logger.Error("Workflow failed", apierror.Fields(err, zap.String("workflow_id", workflowID.String()), requestid.Field(r.Context()))...)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -278,24 +611,145 @@ func (s *Server) handleOrchestrate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	agents := make([]map[string]interface{}, 0)
-	
-	for agentType, agent := range s.orchestrator.registry {
-		agents = append(agents, map[string]interface{}{
-			"type":        agentType,
-			"description": agent.GetDescription(),
-			"capabilities": agent.GetCapabilities(),
+// execute runs one request and records it in the audit log
+func (s *Server) execute(ctx context.Context, workflowID uuid.UUID, req *orchestrate.Request, actor, actorType, resource string) (*WorkflowResult, error) {
+	result, err := s.orchestrator.ExecuteRequest(ctx, workflowID, req)
+
+	event := audit.Event{
+		Actor:       actor,
+		ActorType:   actorType,
+		Action:      audit.ActionOrchestrate,
+		Resource:    resource,
+		WorkflowID:  workflowID,
+		RequestHash: audit.HashRequest(req),
+		Status:      audit.StatusSuccess,
+	}
+	if err != nil {
+		event.Status = audit.StatusFailure
+	}
+	s.orchestrator.audit.Record(ctx, event)
+	return result, err
+}
+
+// rpcBackend serves the orchestrator over gRPC
+type rpcBackend struct {
+	s *Server
+}
+
+func (b rpcBackend) Orchestrate(ctx context.Context, req *orchestrate.Request) (*orchestrate.Workflow, error) {
+	result, err := b.s.execute(ctx, uuid.New(), req, "grpc", audit.ActorAnonymous, orchestrate.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return result.workflow(), nil
+}
+
+func (b rpcBackend) Workflow(id uuid.UUID) (*orchestrate.Workflow, bool) {
+	result, ok := b.s.orchestrator.GetWorkflow(id)
+	if !ok {
+		return nil, false
+	}
+	return result.workflow(), true
+}
+
+func (b rpcBackend) Agents() []orchestrate.AgentInfo {
+	infos := make([]orchestrate.AgentInfo, 0, len(b.s.orchestrator.registry))
+	for agentType, agent := range b.s.orchestrator.registry {
+		infos = append(infos, orchestrate.AgentInfo{
+			Type:         agentType,
+			Description:  agent.GetDescription(),
+			Capabilities: agent.GetCapabilities(),
 		})
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+func (s *Server) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := s.lookupWorkflow(w, r)
+	if !ok {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(agents)
+	json.NewEncoder(w).Encode(workflow)
 }
 
-func (s *Server) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement workflow retrieval
-	w.WriteHeader(http.StatusNotImplemented)
+func (s *Server) handleWorkflowReport(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := s.lookupWorkflow(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=workflow_%s_report.csv", workflow.WorkflowID.String()[:8]))
+		if err := workflow.Report.WriteCSV(w); err != nil {
+			apierror.Write(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workflow.Report)
+}
+
+// handleResumeWorkflow serves POST /api/workflow/{id}/resume?from=<agent>
+func (s *Server) handleResumeWorkflow(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return
+	}
+	from := agents.AgentType(r.URL.Query().Get("from"))
+
+	ctx := requestid.Detach(r.Context())
+	result, err := s.orchestrator.ResumeWorkflow(ctx, id, from)
+
+	actor, actorType := audit.ActorFromRequest(r)
+	event := audit.Event{
+		WorkflowID: id,
+		Actor:      actor,
+		ActorType:  actorType,
+		Action:     audit.ActionOrchestrate,
+		Resource:   r.URL.Path,
+		Status:     audit.StatusSuccess,
+		Metadata:   map[string]string{"resume_from": string(from)},
+	}
+	if err != nil {
+		event.Status = audit.StatusFailure
+	}
+	s.orchestrator.audit.Record(ctx, event)
+
+	if err != nil {
+		if apierror.CategoryOf(err) == apierror.CategoryInternal {
+			// Anything else is a bad resume point
+			err = apierror.Wrap(apierror.CategoryValidation, err.Error(), err)
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// lookupWorkflow resolves the {id} route variable, writing an error response
+// if the workflow is unknown
+func (s *Server) lookupWorkflow(w http.ResponseWriter, r *http.Request) (*WorkflowResult, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid workflow id"))
+		return nil, false
+	}
+
+	workflow, ok := s.orchestrator.GetWorkflow(id)
+	if !ok {
+		apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "workflow not found"))
+		return nil, false
+	}
+	return workflow, true
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -304,22 +758,51 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	settings := config.NewLoader(flag.CommandLine)
 	var (
-		port      = flag.String("port", "8091", "Server port")
-		workspace = flag.String("workspace", "/Users/ososerious/OSA/agent-workspace", "Workspace directory")
+		port         = flag.String("port", "8091", "Server port")
+		workspace    = flag.String("workspace", "/Users/ososerious/OSA/agent-workspace", "Workspace directory")
+		batchWorkers = flag.Int("batch-workers", 4, "Workers running batch orchestration items")
+		batchQuotas  = flag.String("batch-quotas", "", "YAML file of per-tenant batch weights, concurrency limits and bursts; empty shares workers equally")
+		grpcPort     = flag.String("grpc-port", "9091", "gRPC server port; empty disables the gRPC API")
+		workflowTTL  = flag.Duration("workflow-ttl", DefaultWorkflowTTL, "How long finished workflows are kept in memory; 0 keeps them until restart")
+		apiKey       = settings.Secret("groq-api-key", "Groq API key", "GROQ_API_KEY")
+		jwtSecret    = settings.Secret("jwt-secret", "Secret verifying JWTs issued by the API gateway; empty refuses every call to the audit route", "JWT_SECRET")
+		sandboxDSN   = settings.Secret("sandbox-database-url", "Postgres URL of the database generated migrations are verified against", "SANDBOX_DATABASE_URL")
 	)
-	flag.Parse()
+	settings.Env("batch-quotas", "BATCH_QUOTAS_FILE")
+	settings.Env("workflow-ttl", "WORKFLOW_TTL")
+	settings.Require("groq-api-key")
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if settings.PrintRequested() {
+		settings.Print(os.Stdout)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		log.Fatal(err)
+	}
 
-	apiKey := os.Getenv("GROQ_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GROQ_API_KEY environment variable is required")
+	// Apply generated migrations to a sandbox database during quality checks
+	if *sandboxDSN != "" {
+		sandbox, err := sql.Open("postgres", *sandboxDSN)
+		if err != nil {
+			log.Fatal("Failed to open sandbox database:", err)
+		}
+		defer sandbox.Close()
+		quality.DefaultMigrationVerifier = quality.NewPostgresMigrationVerifier(sandbox)
 	}
 
 	// Create orchestrator with ALL agents
-	orchestrator, err := NewFullOrchestrator(apiKey, *workspace)
+	orchestrator, err := NewFullOrchestrator(*apiKey, *workspace)
 	if err != nil {
 		log.Fatal("Failed to create orchestrator:", err)
 	}
+	orchestrator.SetWorkflowTTL(*workflowTTL)
+	if *jwtSecret != "" {
+		orchestrator.SetAuth(middleware.NewAuthMiddleware(&config.AuthConfig{JWTSecret: *jwtSecret}, nil, nil, orchestrator.logger))
+	}
 
 	// Create directories
 	dirs := []string{
@@ -332,7 +815,30 @@ func main() {
 	}
 
 	// Create and start server
-	server := NewServer(orchestrator)
+	server := NewServer(orchestrator, *batchWorkers)
+	if *batchQuotas != "" {
+		quotas, err := orchestrate.LoadQuotas(*batchQuotas)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.batches.SetQuotas(quotas)
+	}
+	server.batches.Start(context.Background())
+
+	if *grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+*grpcPort)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC:", err)
+		}
+		grpcServer := grpc.NewServer()
+		orchestrate.RegisterOrchestratorServer(grpcServer, rpcBackend{s: server})
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatal(err)
+			}
+		}()
+		log.Printf("[GRPC] Serving %s on port %s", orchestrate.ServiceName, *grpcPort)
+	}
 
 	log.Printf("[FULL ORCHESTRATOR] Starting with ALL %d agents on port %s", 
 		len(orchestrator.registry), *port)
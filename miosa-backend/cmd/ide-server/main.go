@@ -3,17 +3,27 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 	"path/filepath"
 
+	"github.com/sormind/OSA/miosa-backend/internal/config"
 	"github.com/sormind/OSA/miosa-backend/internal/services/ide"
 )
 
 func main() {
+	settings := config.NewLoader(flag.CommandLine)
 	var (
-		port     = flag.String("port", "8080", "Port to run the IDE server on")
-		rootPath = flag.String("root", ".", "Root directory to serve files from")
+		port        = flag.String("port", "8080", "Port to run the IDE server on")
+		rootPath    = flag.String("root", ".", "Root directory to serve files from")
+		insecure    = flag.Bool("insecure", false, "Serve without session tokens, letting anyone read and write under root; for local development only")
+		tokenSecret = settings.Secret("token-secret", "Secret verifying the session tokens the orchestrator issues", "IDE_TOKEN_SECRET")
 	)
-	flag.Parse()
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatalf("Invalid settings: %v", err)
+	}
+	if *tokenSecret == "" && !*insecure {
+		log.Fatalf("IDE_TOKEN_SECRET is required; pass -insecure to serve without authentication")
+	}
 
 	// Convert to absolute path
 	absPath, err := filepath.Abs(*rootPath)
@@ -22,6 +32,11 @@ func main() {
 	}
 
 	server := ide.NewServer(absPath, *port)
+	if *tokenSecret != "" {
+		server.IDEService.Auth = ide.NewAuthenticator(*tokenSecret)
+	} else {
+		log.Printf("WARNING: authentication disabled, any client may write under %s", absPath)
+	}
 	
 	log.Printf("Starting OSA IDE Server...")
 	log.Printf("Root directory: %s", absPath)
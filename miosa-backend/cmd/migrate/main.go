@@ -0,0 +1,48 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/db"
+)
+
+func main() {
+	settings := config.NewLoader(flag.CommandLine)
+	var (
+		databaseURL = settings.Secret("database", "Postgres connection URL", "DATABASE_URL")
+		path        = flag.String("path", "", "Migrations directory (defaults to the embedded migrations)")
+	)
+	settings.Env("path", "MIGRATIONS_PATH")
+	settings.Require("database")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, db.MigrateUsage)
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		flag.PrintDefaults()
+	}
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if settings.PrintRequested() {
+		settings.Print(os.Stdout)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := sql.Open("postgres", *databaseURL)
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+	defer conn.Close()
+
+	if err := db.RunMigrateCommand(conn, *path, flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
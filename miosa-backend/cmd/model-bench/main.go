@@ -0,0 +1,238 @@
+// Command model-bench runs the golden agent suite on several
+// providers/models and compares their latency, token cost, compile success
+// and code assurance scores, to guide the model routing policy.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/ai_providers"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/analysis"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/architect"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/communication"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/deployment"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/development"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/monitoring"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/recommender"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/strategy"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/eval"
+)
+
+// primaryProvider names the Groq client targets without a provider use
+const primaryProvider = "groq"
+
+func main() {
+	settings := config.NewLoader(flag.CommandLine)
+	var (
+		targetList = flag.String("targets", "llama-3.1-8b-instant,llama-3.3-70b-versatile", "Comma-separated models to compare, as provider:model or a bare Groq model")
+		providers  = flag.String("providers", "", "Other OpenAI-compatible providers targets may name, e.g. openai=https://api.openai.com/v1; keys come from <NAME>_API_KEY")
+		suiteFile  = flag.String("suite", "", "Suite JSON file (defaults to the built-in golden suite)")
+		only       = flag.String("agents", "", "Comma-separated agent types to benchmark (defaults to all)")
+		timeout    = flag.Duration("timeout", 3*time.Minute, "Timeout per case")
+		assurance  = flag.Bool("assurance", true, "Score generated files with the static code assurance checks")
+		jsonOut    = flag.Bool("json", false, "Print the comparison as JSON")
+		apiKey     = settings.Secret("groq-api-key", "Groq API key", "GROQ_API_KEY")
+	)
+	settings.Require("groq-api-key")
+	if err := settings.Load(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if settings.PrintRequested() {
+		settings.Print(os.Stdout)
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	clients, err := providerClients(*apiKey, *providers)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	suite := eval.DefaultSuite()
+	if *suiteFile != "" {
+		if suite, err = eval.LoadSuite(*suiteFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *only != "" {
+		suite = filterSuite(suite, strings.Split(*only, ","))
+	}
+
+	var targets []string
+	var reports []*eval.Report
+	for _, s := range strings.Split(*targetList, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		target := agents.ParseTarget(s)
+		provider := target.Provider
+		if provider == "" {
+			provider = primaryProvider
+		}
+		client, ok := clients[provider]
+		if !ok {
+			log.Fatalf("Target %s names unknown provider %s; add it to -providers", target, provider)
+		}
+
+		log.Printf("[BENCH] Running %d cases on %s", len(suite.Cases), target)
+		agents.DefaultLLMGuard.SetProvider(provider)
+		agents.DefaultLLMGuard.SetModel(target.Model)
+		runner := eval.NewRunner(resolver(client), *timeout)
+		runner.SetAssurance(*assurance)
+		report := runner.Run(context.Background(), suite)
+		report.Provider, report.Model = provider, target.Model
+
+		targets = append(targets, target.String())
+		reports = append(reports, report)
+	}
+	if len(reports) == 0 {
+		log.Fatal("No targets to benchmark")
+	}
+
+	bench := eval.NewBenchmark(targets, reports)
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(bench)
+		return
+	}
+	printBenchmark(bench)
+}
+
+// providerClients creates a client for Groq and each named provider
+func providerClients(apiKey, providers string) (map[string]*groq.Client, error) {
+	primary, err := agents.NewLLMClient(apiKey, "")
+	if err != nil {
+		return nil, err
+	}
+	clients := map[string]*groq.Client{primaryProvider: primary}
+	for _, pair := range strings.Split(providers, ",") {
+		name, baseURL, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		key := os.Getenv(strings.ToUpper(name) + "_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("provider %s needs %s_API_KEY", name, strings.ToUpper(name))
+		}
+		client, err := agents.NewLLMClient(key, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		clients[name] = client
+	}
+	return clients, nil
+}
+
+// resolver returns the agents built on client
+func resolver(client *groq.Client) eval.Resolver {
+	registry := map[agents.AgentType]agents.Agent{
+		agents.AnalysisAgent:      analysis.New(client),
+		agents.ArchitectAgent:     architect.New(client),
+		agents.DevelopmentAgent:   development.New(client),
+		agents.QualityAgent:       quality.New(client),
+		agents.DeploymentAgent:    deployment.New(client),
+		agents.MonitoringAgent:    monitoring.New(client),
+		agents.StrategyAgent:      strategy.New(client),
+		agents.CommunicationAgent: communication.New(client),
+		agents.RecommenderAgent:   recommender.New(client),
+		agents.AIProvidersAgent:   ai_providers.New(client),
+	}
+	return func(t agents.AgentType) (agents.Agent, error) {
+		agent, ok := registry[t]
+		if !ok {
+			return nil, fmt.Errorf("agent %s not available", t)
+		}
+		return agent, nil
+	}
+}
+
+func filterSuite(suite *eval.Suite, types []string) *eval.Suite {
+	keep := make(map[agents.AgentType]bool, len(types))
+	for _, t := range types {
+		keep[agents.AgentType(strings.TrimSpace(t))] = true
+	}
+	filtered := &eval.Suite{Name: suite.Name}
+	for _, c := range suite.Cases {
+		if keep[c.Agent] {
+			filtered.Cases = append(filtered.Cases, c)
+		}
+	}
+	return filtered
+}
+
+func printBenchmark(b *eval.Benchmark) {
+	fmt.Printf("Suite %s on %d targets\n\n", b.Suite, len(b.Targets))
+	fmt.Printf("  %-40s %6s %8s %8s %10s %10s %8s %9s\n", "TARGET", "PASS", "P50", "P95", "TOKENS", "COST", "COMPILE", "ASSURANCE")
+	for _, tb := range b.Targets {
+		s := tb.Stats
+		fmt.Printf("  %-40s %5.0f%% %6dms %6dms %10d %9.4f$ %7s %9s\n", tb.Target,
+			s.PassRate*100, s.LatencyP50MS, s.LatencyP95MS, s.PromptTokens+s.CompletionTokens, s.CostUSD,
+			compileRate(s), assuranceScore(s))
+	}
+
+	types := make([]string, 0)
+	seen := map[agents.AgentType]bool{}
+	for _, tb := range b.Targets {
+		for t := range tb.ByAgent {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, string(t))
+			}
+		}
+	}
+	sort.Strings(types)
+
+	fmt.Println("\nBy agent:")
+	for _, t := range types {
+		fmt.Printf("  %s\n", t)
+		for _, tb := range b.Targets {
+			s := tb.ByAgent[agents.AgentType(t)]
+			if s == nil {
+				continue
+			}
+			fmt.Printf("    %-38s %d/%d %6dms %9.4f$ compile %s assurance %s\n", tb.Target,
+				s.Passed, s.Cases, s.LatencyP50MS, s.CostUSD, compileRate(*s), assuranceScore(*s))
+		}
+	}
+
+	fmt.Println("\nRecommended routing, best first:")
+	for _, rec := range b.Recommendations {
+		if len(rec.Chain) == 0 {
+			fmt.Printf("  %-16s no target passed\n", rec.Agent)
+			continue
+		}
+		fmt.Printf("  %-16s %s\n", rec.Agent, strings.Join(rec.Chain, " > "))
+	}
+	if chains := b.FailoverChains(); chains != "" {
+		fmt.Printf("\n  -agent-failover %q\n", chains)
+	}
+}
+
+func compileRate(s eval.TargetStats) string {
+	if s.CodeCases == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", s.CompileRate*100)
+}
+
+func assuranceScore(s eval.TargetStats) string {
+	if s.AssuredCases == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f", s.AssuranceScore)
+}
@@ -234,16 +234,22 @@ func generateDatabaseSchemas() map[string]string {
 }
 
 func generateInfrastructure() map[string]string {
-	return map[string]string{
+	files := map[string]string{
 		"docker-compose.yml":           generateDockerComposeFile(),
 		"Dockerfile.backend":           generateBackendDockerfile(),
 		"Dockerfile.frontend":          generateFrontendDockerfile(),
 		"kubernetes/deployment.yaml":   generateK8sDeploymentFile(),
 		"kubernetes/service.yaml":      generateK8sServiceFile(),
 		"kubernetes/ingress.yaml":      generateK8sIngressFile(),
-		"terraform/main.tf":            generateTerraformMain(),
 		"scripts/deploy.sh":            generateDeployScript(),
 	}
+	// Terraform is derived from the compose services
+	if model, err := deployment.InfraFromCompose("ecommerce", []byte(files["docker-compose.yml"])); err == nil {
+		for _, f := range deployment.GenerateTerraform(model) {
+			files[f.Path] = f.Content
+		}
+	}
+	return files
 }
 
 // Sample service generators
@@ -675,10 +681,6 @@ func generateK8sIngressFile() string {
 	return `# Kubernetes ingress configuration`
 }
 
-func generateTerraformMain() string {
-	return `# Terraform infrastructure as code`
-}
-
 func generateDeployScript() string {
 	return `#!/bin/bash
 # Deployment script`
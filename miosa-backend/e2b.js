@@ -1,5 +1,8 @@
 import 'dotenv/config';
 import express from 'express';
+import fs from 'fs/promises';
+import os from 'os';
+import path from 'path';
 import { Sandbox } from '@e2b/code-interpreter';
 import { timestampSlug, shortId } from './lib/util.js';
 import { collectFiles } from './lib/collect.js';
@@ -475,19 +478,130 @@ if service_id:
 app.post('/', async (req, res) => {
   const { path } = req.body;
 
+  // The orchestrator sends the API request's ID so sandbox logs can be
+  // matched to the workflow that triggered them
+  const requestId = req.get('X-Request-ID');
+  if (requestId) {
+    res.set('X-Request-ID', requestId);
+  }
+
   if (!path) {
     return res.status(400).send({ error: 'Missing path in request body' });
   }
 
+  console.log(`[${requestId || '-'}] Deploying ${path}`);
   try {
     await run(path);
+    console.log(`[${requestId || '-'}] Deployment finished`);
     res.status(200).send({ message: 'Process completed successfully' });
   } catch (error) {
-    console.error(error);
+    console.error(`[${requestId || '-'}]`, error);
     res.status(500).send({ error: 'An error occurred' });
   }
 });
 
+// Runs one command against uploaded files in a fresh sandbox and returns
+// what it printed. A failing command is still a 200: the orchestrator reads
+// test failures from the output. Files the command leaves under `collect`
+// are returned base64-encoded, up to MAX_COLLECT_BYTES in total.
+const MAX_COLLECT_BYTES = 25 * 1024 * 1024
+
+async function collectFiles(sandbox, root, dir) {
+  const collected = []
+  let total = 0
+  const walk = async (path) => {
+    const entries = await sandbox.files.list(path).catch(() => [])
+    for (const entry of entries) {
+      if (entry.type === 'dir') {
+        await walk(entry.path)
+        continue
+      }
+      const data = await sandbox.files.read(entry.path, { format: 'bytes' })
+      if (total + data.length > MAX_COLLECT_BYTES) continue
+      total += data.length
+      collected.push({ path: entry.path.slice(root.length + 1), content: Buffer.from(data).toString('base64') })
+    }
+  }
+  await walk(`${root}/${dir}`)
+  return collected
+}
+
+// A `cache` of {key, dir} keeps dir, relative to the project, on this
+// server between runs: it is restored before the command when the key has
+// been saved, and saved after it otherwise. Callers key it by what the
+// directory is built from, e.g. node_modules by the lockfile's hash.
+const CACHE_DIR = process.env.SANDBOX_CACHE_DIR || path.join(os.tmpdir(), 'miosa-sandbox-cache')
+const CACHE_KEY = /^[A-Za-z0-9._-]{1,128}$/
+
+function validCache(cache) {
+  return cache && CACHE_KEY.test(cache.key || '') && typeof cache.dir === 'string' &&
+    /^[A-Za-z0-9._\/-]+$/.test(cache.dir) && !path.isAbsolute(cache.dir) && !cache.dir.split('/').includes('..')
+}
+
+async function restoreCache(sandbox, root, cache) {
+  const data = await fs.readFile(path.join(CACHE_DIR, `${cache.key}.tgz`)).catch(() => null)
+  if (!data) return false
+  await sandbox.files.write('/tmp/cache.tgz', data.buffer.slice(data.byteOffset, data.byteOffset + data.length))
+  const result = await sandbox.commands.run(`tar xzf /tmp/cache.tgz -C ${root}`).catch(err => err)
+  return result.exitCode === 0
+}
+
+async function saveCache(sandbox, root, cache) {
+  const result = await sandbox.commands.run(`[ -d '${cache.dir}' ] && tar czf /tmp/cache.tgz -C ${root} '${cache.dir}'`).catch(err => err)
+  if (result.exitCode !== 0) return
+  const data = await sandbox.files.read('/tmp/cache.tgz', { format: 'bytes' })
+  await fs.mkdir(CACHE_DIR, { recursive: true })
+  const file = path.join(CACHE_DIR, `${cache.key}.tgz`)
+  await fs.writeFile(`${file}.tmp`, data)
+  await fs.rename(`${file}.tmp`, file)
+}
+
+app.post('/exec', async (req, res) => {
+  const { files, command, timeoutMs, collect, cache } = req.body;
+  const requestId = req.get('X-Request-ID');
+  if (requestId) {
+    res.set('X-Request-ID', requestId);
+  }
+
+  if (!command || !Array.isArray(files)) {
+    return res.status(400).send({ error: 'Missing files or command in request body' });
+  }
+  if (cache && !validCache(cache)) {
+    return res.status(400).send({ error: 'cache needs a key of letters, digits, ".", "_" or "-" and a relative dir' });
+  }
+
+  const SANDBOX_APP_DIR = '/tmp/app'
+  let sandbox
+  try {
+    assertEnv('E2B_API_KEY');
+    sandbox = await Sandbox.create()
+    await sandbox.files.write(files.map(f => ({ path: `${SANDBOX_APP_DIR}/${f.path}`, data: f.content })))
+    const restored = cache ? await restoreCache(sandbox, SANDBOX_APP_DIR, cache) : false
+
+    console.log(`[${requestId || '-'}] Running in ${sandbox.id}: ${command}`);
+    let result
+    try {
+      result = await sandbox.commands.run(command, { cwd: SANDBOX_APP_DIR, timeoutMs: timeoutMs || 300000 })
+    } catch (cmdErr) {
+      // Non-zero exits are thrown with the command's output attached
+      if (cmdErr?.exitCode === undefined) throw cmdErr
+      result = cmdErr
+    }
+    if (cache && !restored) {
+      await saveCache(sandbox, SANDBOX_APP_DIR, cache).catch(err => console.warn(`[${requestId || '-'}] Failed to save cache ${cache.key}:`, err?.message))
+    }
+    const collected = collect ? await collectFiles(sandbox, SANDBOX_APP_DIR, collect) : []
+    res.status(200).send({ exitCode: result.exitCode, stdout: result.stdout || '', stderr: result.stderr || '', files: collected });
+  } catch (error) {
+    console.error(`[${requestId || '-'}]`, error);
+    res.status(500).send({ error: error?.message || 'An error occurred' });
+  } finally {
+    if (sandbox) {
+      await sandbox.kill().catch(() => {})
+    }
+  }
+});
+
 const PORT = process.env.PORT || 3001;
 app.listen(PORT, () => {
   console.log(`Server listening on port ${PORT}`);
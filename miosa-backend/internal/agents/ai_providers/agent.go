@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
 	"go.uber.org/zap"
 )
 
@@ -19,7 +20,7 @@ import (
 type AIProvidersAgent struct {
 	id          uuid.UUID
 	groqClient  *groq.Client
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
 	logger      *zap.Logger
 	modelStats  map[string]*ModelStats
 	cache       *ResponseCache
@@ -41,7 +42,7 @@ type ModelStats struct {
 
 // ResponseCache implements intelligent caching
 type ResponseCache struct {
-	client     *redis.Client
+	client     redis.UniversalClient
 	ttl        time.Duration
 	hitRate    float64
 	totalHits  int
@@ -149,7 +150,7 @@ func New(groqClient *groq.Client) *AIProvidersAgent {
 }
 
 // SetRedis adds Redis client for caching
-func (a *AIProvidersAgent) SetRedis(client *redis.Client) {
+func (a *AIProvidersAgent) SetRedis(client redis.UniversalClient) {
 	a.redisClient = client
 	a.cache = &ResponseCache{
 		client: client,
@@ -328,7 +329,7 @@ You excel at complex reasoning, coding, and tool use. Be precise and efficient.`
 		}, messages...)
 	}
 	
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
 		Model:       groq.ChatModel(model.ID),
 		Messages:    messages,
 		Temperature: float32(model.Temperature),
@@ -406,6 +407,7 @@ func (a *AIProvidersAgent) cacheResult(ctx context.Context, task agents.Task, re
 	}
 	
 	cacheKey := a.generateCacheKey(task)
+	redact.Result(result)
 	data, _ := json.Marshal(result)
 	
 	a.redisClient.Set(ctx, cacheKey, data, a.cache.ttl)
@@ -8,6 +8,7 @@ import (
 
 	"github.com/conneroisu/groq-go"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/logctx"
 	"go.uber.org/zap"
 )
 
@@ -43,6 +44,11 @@ func (a *AnalysisAgent) GetDescription() string {
 	return "Analyzes requirements, breaks down problems, and provides insights"
 }
 
+// Model returns the model the agent calls
+func (a *AnalysisAgent) Model() string {
+	return a.config.Model
+}
+
 // GetCapabilities returns the agent's capabilities
 func (a *AnalysisAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
@@ -63,7 +69,7 @@ func (a *AnalysisAgent) Execute(ctx context.Context, task agents.Task) (*agents.
 	// Use tools to analyze requirements
 	requirementsAnalysis, err := tools.AnalyzeRequirements(ctx, task.Input)
 	if err != nil {
-		a.logger.Warn("Tool analysis failed, falling back to LLM", zap.Error(err))
+		logctx.FromOr(ctx, a.logger).Warn("Tool analysis failed, falling back to LLM", zap.Error(err))
 	}
 	
 	// Build analysis prompt with tool results
@@ -84,9 +90,9 @@ Provide a comprehensive analysis including:
 Be specific and actionable.`, task.Input, requirementsAnalysis)
 
 	// Get analysis from LLM
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
 		Model: groq.ChatModel(a.config.Model),
-		Messages: []groq.ChatCompletionMessage{
+		Messages: agents.WithFewShot(ctx, a.GetType(), task, agents.WithEnrichment(task, []groq.ChatCompletionMessage{
 			{
 				Role:    "system",
 				Content: "You are an expert systems analyst specializing in breaking down complex requirements.",
@@ -95,7 +101,7 @@ Be specific and actionable.`, task.Input, requirementsAnalysis)
 				Role:    "user",
 				Content: prompt,
 			},
-		},
+		})),
 		MaxTokens:   a.config.MaxTokens,
 		Temperature: float32(a.config.Temperature),
 		TopP:        float32(a.config.TopP),
@@ -135,9 +141,11 @@ Be specific and actionable.`, task.Input, requirementsAnalysis)
 		ExecutionMS: time.Since(startTime).Milliseconds(),
 		Data: map[string]interface{}{
 			"model":      a.config.Model,
+			"usage":      response.Usage,
 			"word_count": len(strings.Fields(content)),
 		},
 	}
+	result.AddUsage(response)
 	
 	// Record execution for self-improvement
 	agents.RecordExecution(a.GetType(), result)
@@ -154,6 +162,47 @@ Be specific and actionable.`, task.Input, requirementsAnalysis)
 	return result, nil
 }
 
+// Clarify asks about what the request leaves open when it is too ambiguous
+// to build from, returning no questions when it can proceed as is
+func (a *AnalysisAgent) Clarify(ctx context.Context, task agents.Task) ([]agents.Question, error) {
+	prompt := fmt.Sprintf(`Review this software request before anything is designed or built:
+
+Request: %s
+
+Decide whether it is ambiguous enough that building it would mean guessing
+at decisions the requester should make, such as who the users are, the core
+entities and workflows, integrations, scale, or hard constraints. Do not ask
+about anything the request already answers or that has a sensible default.
+
+Respond with JSON only:
+{"questions": [{"id": "q1", "question": "...", "why": "what the answer changes", "options": ["...", "..."]}]}
+
+Ask at most %d targeted questions. Return {"questions": []} if the request is clear enough.`, task.Input, agents.MaxQuestions)
+
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
+		Model: groq.ChatModel(a.config.Model),
+		Messages: []groq.ChatCompletionMessage{
+			{
+				Role:    "system",
+				Content: "You are an expert systems analyst who clarifies requirements before work starts.",
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		MaxTokens:   1000,
+		Temperature: float32(a.config.Temperature),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clarification failed: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response from model")
+	}
+	return agents.ParseQuestions(response.Choices[0].Message.Content)
+}
+
 // calculateConfidence assesses the quality of the analysis
 func (a *AnalysisAgent) calculateConfidence(content string) float64 {
 	confidence := 5.0 // Base confidence
@@ -34,22 +34,100 @@ func (a *ArchitectAgent) GetDescription() string {
 	return "Designs system architecture and technical solutions"
 }
 
+// Model returns the model the agent calls
+func (a *ArchitectAgent) Model() string {
+	return a.config.Model
+}
+
 func (a *ArchitectAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
-		{Name: "system_design", Description: "Design system architecture", Required: true},
+		{Name: "system_design", Description: "Design system architecture", Required: true, Features: []agents.Feature{agents.FeatureManifest}},
 		{Name: "tech_stack", Description: "Select technology stack", Required: true},
 	}
 }
 
 func (a *ArchitectAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
 	startTime := time.Now()
+	output := fmt.Sprintf("Architecture design for: %s", task.Input)
+	// The design commits to the tenant's house stack for later agents
+	if profile := agents.TaskStackProfile(ctx, task); profile != nil {
+		output += "\n\n" + profile.Prompt()
+	}
 	result := &agents.Result{
 		Success:     true,
-		Output:      fmt.Sprintf("Architecture design for: %s", task.Input),
+		Output:      output,
 		Confidence:  9.0,
 		ExecutionMS: time.Since(startTime).Milliseconds(),
 		NextAgent:   agents.DevelopmentAgent,
+		Data:        make(map[string]interface{}),
+	}
+	// Later steps build from the spec rather than the prose
+	if spec := a.designSpec(ctx, task, result); spec != nil {
+		result.Output += "\n\n" + spec.Markdown()
+		result.Data[agents.ArchitectureKey] = spec
 	}
+	if plan := agents.TaskMonorepo(task); plan != nil {
+		plan = a.designMonorepo(ctx, task, plan, result)
+		result.Output += "\n\n" + plan.Prompt()
+		result.Data[agents.MonorepoKey] = plan
+	}
+	result.ExecutionMS = time.Since(startTime).Milliseconds()
 	agents.RecordExecution(a.GetType(), result)
 	return result, nil
 }
+
+// designMonorepo declares the shared packages of a monorepo plan, falling
+// back to a single package every service imports
+func (a *ArchitectAgent) designMonorepo(ctx context.Context, task agents.Task, plan *agents.MonorepoPlan, result *agents.Result) *agents.MonorepoPlan {
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
+		Model: groq.ChatModel(a.config.Model),
+		Messages: []groq.ChatCompletionMessage{
+			{Role: groq.RoleSystem, Content: "You are a software architect splitting code between services and shared packages."},
+			{Role: groq.RoleUser, Content: plan.DesignPrompt(task.Input)},
+		},
+		MaxTokens:   a.config.MaxTokens,
+		Temperature: float32(a.config.Temperature),
+		TopP:        float32(a.config.TopP),
+	})
+	if err != nil || len(response.Choices) == 0 {
+		return plan.WithSharedDefault()
+	}
+	result.AddUsage(response)
+	designed, err := plan.Designed(response.Choices[0].Message.Content)
+	if err != nil {
+		return plan.WithSharedDefault()
+	}
+	return designed
+}
+
+// designSpec asks for the machine-readable architecture, returning the
+// validation error once so the model can correct it. It returns nil when
+// no valid spec comes back; the prose still stands.
+func (a *ArchitectAgent) designSpec(ctx context.Context, task agents.Task, result *agents.Result) *agents.ArchitectureSpec {
+	messages := []groq.ChatCompletionMessage{
+		{Role: groq.RoleSystem, Content: "You are a software architect declaring a system's services, APIs, datastores and queues."},
+		{Role: groq.RoleUser, Content: agents.ArchitecturePrompt(task.Input)},
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
+			Model:       groq.ChatModel(a.config.Model),
+			Messages:    messages,
+			MaxTokens:   a.config.MaxTokens,
+			Temperature: float32(a.config.Temperature),
+			TopP:        float32(a.config.TopP),
+		})
+		if err != nil || len(response.Choices) == 0 {
+			return nil
+		}
+		result.AddUsage(response)
+		content := response.Choices[0].Message.Content
+		spec, err := agents.ParseArchitectureSpec(content)
+		if err == nil {
+			return spec
+		}
+		messages = append(messages,
+			groq.ChatCompletionMessage{Role: groq.RoleAssistant, Content: content},
+			groq.ChatCompletionMessage{Role: groq.RoleUser, Content: fmt.Sprintf("That spec is invalid: %v. Respond with the corrected JSON only.", err)})
+	}
+	return nil
+}
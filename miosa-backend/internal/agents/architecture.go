@@ -0,0 +1,241 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ArchitectureKey is the Result.Data and TaskContext.Memory key of the
+// *ArchitectureSpec the architect returned
+const ArchitectureKey = "architecture"
+
+// Relationship kinds, by what the service at From does with To
+const (
+	RelationCalls     = "calls"     // Another service's API
+	RelationReads     = "reads"     // A datastore
+	RelationWrites    = "writes"    // A datastore
+	RelationPublishes = "publishes" // To a queue
+	RelationConsumes  = "consumes"  // From a queue
+)
+
+// APISpec is one endpoint a service exposes
+type APISpec struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Description string `json:"description,omitempty"`
+}
+
+// ServiceSpec is a deployable service
+type ServiceSpec struct {
+	Name    string    `json:"name"`
+	Purpose string    `json:"purpose,omitempty"`
+	Runtime string    `json:"runtime,omitempty"` // Language or framework, e.g. "go" or "nextjs"
+	APIs    []APISpec `json:"apis,omitempty"`
+}
+
+// DatastoreSpec is a database, cache or object store
+type DatastoreSpec struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"` // e.g. postgres, redis, s3
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// QueueSpec is a message queue or topic
+type QueueSpec struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"` // e.g. kafka, rabbitmq, sqs
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// Relationship is a dependency of a service on another component
+type Relationship struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+}
+
+// ArchitectureSpec is the machine-readable design the architect returns
+// with its prose. Later steps build and deploy from it rather than
+// re-reading the prose, so every step agrees on the same components.
+type ArchitectureSpec struct {
+	Summary       string          `json:"summary,omitempty"`
+	Services      []ServiceSpec   `json:"services"`
+	Datastores    []DatastoreSpec `json:"datastores,omitempty"`
+	Queues        []QueueSpec     `json:"queues,omitempty"`
+	Relationships []Relationship  `json:"relationships,omitempty"`
+}
+
+// apiMethods are the HTTP methods an API may use
+var apiMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+
+// relationTargets is the component a relationship of each kind points at
+var relationTargets = map[string]string{
+	RelationCalls:     "service",
+	RelationReads:     "datastore",
+	RelationWrites:    "datastore",
+	RelationPublishes: "queue",
+	RelationConsumes:  "queue",
+}
+
+// Validate checks that the spec has a service, that component names are
+// valid and unique, and that APIs and relationships are well formed
+func (s *ArchitectureSpec) Validate() error {
+	if len(s.Services) == 0 {
+		return fmt.Errorf("architecture declares no services")
+	}
+	kinds := make(map[string]string)
+	declare := func(kind, name string) error {
+		if !ValidPackageName(name) {
+			return fmt.Errorf("invalid %s name %q", kind, name)
+		}
+		if _, ok := kinds[name]; ok {
+			return fmt.Errorf("%s %q is declared twice", kind, name)
+		}
+		kinds[name] = kind
+		return nil
+	}
+
+	for _, svc := range s.Services {
+		if err := declare("service", svc.Name); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, api := range svc.APIs {
+			if !apiMethods[api.Method] {
+				return fmt.Errorf("service %s: invalid API method %q", svc.Name, api.Method)
+			}
+			if !strings.HasPrefix(api.Path, "/") {
+				return fmt.Errorf("service %s: API path %q must start with /", svc.Name, api.Path)
+			}
+			route := api.Method + " " + api.Path
+			if seen[route] {
+				return fmt.Errorf("service %s: API %s is declared twice", svc.Name, route)
+			}
+			seen[route] = true
+		}
+	}
+	for _, ds := range s.Datastores {
+		if err := declare("datastore", ds.Name); err != nil {
+			return err
+		}
+		if ds.Kind == "" {
+			return fmt.Errorf("datastore %s has no kind", ds.Name)
+		}
+	}
+	for _, q := range s.Queues {
+		if err := declare("queue", q.Name); err != nil {
+			return err
+		}
+		if q.Kind == "" {
+			return fmt.Errorf("queue %s has no kind", q.Name)
+		}
+	}
+
+	for _, r := range s.Relationships {
+		target, ok := relationTargets[r.Kind]
+		switch {
+		case !ok:
+			return fmt.Errorf("relationship %s -> %s: unknown kind %q", r.From, r.To, r.Kind)
+		case kinds[r.From] != "service":
+			return fmt.Errorf("relationship %s -> %s: %q is not a service", r.From, r.To, r.From)
+		case kinds[r.To] != target:
+			return fmt.Errorf("relationship %s %s %s: %q is not a %s", r.From, r.Kind, r.To, r.To, target)
+		case r.From == r.To:
+			return fmt.Errorf("service %s calls itself", r.From)
+		}
+	}
+	return nil
+}
+
+// ParseArchitectureSpec reads and validates the architect's JSON response
+func ParseArchitectureSpec(response string) (*ArchitectureSpec, error) {
+	var spec ArchitectureSpec
+	if err := json.Unmarshal([]byte(stripJSONFences(response)), &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse architecture spec: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// ArchitecturePrompt asks the architect for the spec of input
+func ArchitecturePrompt(input string) string {
+	return fmt.Sprintf(`Design the architecture for:
+
+%s
+
+Declare every service with the HTTP APIs it exposes, every datastore and queue, and how services use them.
+Names are lowercase with dashes and unique across services, datastores and queues. Relationship kinds are "calls" (service to service), "reads" and "writes" (service to datastore), and "publishes" and "consumes" (service to queue).
+Respond with only JSON: {"summary": "...", "services": [{"name": "api", "purpose": "...", "runtime": "go", "apis": [{"method": "GET", "path": "/items", "description": "..."}]}], "datastores": [{"name": "db", "kind": "postgres", "purpose": "..."}], "queues": [{"name": "events", "kind": "kafka", "purpose": "..."}], "relationships": [{"from": "api", "to": "db", "kind": "writes"}]}`, input)
+}
+
+// Markdown renders the spec for people to read
+func (s *ArchitectureSpec) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("## Architecture spec")
+	if s.Summary != "" {
+		sb.WriteString("\n\n" + s.Summary)
+	}
+	sb.WriteString("\n\n### Services")
+	for _, svc := range s.Services {
+		fmt.Fprintf(&sb, "\n- **%s**", svc.Name)
+		if svc.Runtime != "" {
+			fmt.Fprintf(&sb, " (%s)", svc.Runtime)
+		}
+		if svc.Purpose != "" {
+			sb.WriteString(": " + svc.Purpose)
+		}
+		for _, api := range svc.APIs {
+			fmt.Fprintf(&sb, "\n  - `%s %s`", api.Method, api.Path)
+			if api.Description != "" {
+				sb.WriteString(" " + api.Description)
+			}
+		}
+	}
+	if len(s.Datastores) > 0 {
+		sb.WriteString("\n\n### Datastores")
+		for _, ds := range s.Datastores {
+			fmt.Fprintf(&sb, "\n- **%s** (%s)", ds.Name, ds.Kind)
+			if ds.Purpose != "" {
+				sb.WriteString(": " + ds.Purpose)
+			}
+		}
+	}
+	if len(s.Queues) > 0 {
+		sb.WriteString("\n\n### Queues")
+		for _, q := range s.Queues {
+			fmt.Fprintf(&sb, "\n- **%s** (%s)", q.Name, q.Kind)
+			if q.Purpose != "" {
+				sb.WriteString(": " + q.Purpose)
+			}
+		}
+	}
+	if len(s.Relationships) > 0 {
+		sb.WriteString("\n\n### Relationships")
+		for _, r := range s.Relationships {
+			fmt.Fprintf(&sb, "\n- %s %s %s", r.From, r.Kind, r.To)
+			if r.Description != "" {
+				sb.WriteString(": " + r.Description)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Prompt states the spec for the agents building and deploying it
+func (s *ArchitectureSpec) Prompt() string {
+	return s.Markdown() + "\n\nFollow this spec exactly: implement and deploy these services, APIs, datastores and queues with these names, and no others."
+}
+
+// TaskArchitecture returns the spec the architect declared for the task,
+// nil before it has or when it returned none
+func TaskArchitecture(task Task) *ArchitectureSpec {
+	if task.Context == nil {
+		return nil
+	}
+	spec, _ := task.Context.Memory[ArchitectureKey].(*ArchitectureSpec)
+	return spec
+}
@@ -0,0 +1,62 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const shopSpec = `{
+	"summary": "An API in front of orders",
+	"services": [
+		{"name": "api", "runtime": "go", "apis": [{"method": "POST", "path": "/orders", "description": "place an order"}]},
+		{"name": "worker"}
+	],
+	"datastores": [{"name": "db", "kind": "postgres"}],
+	"queues": [{"name": "orders-placed", "kind": "kafka"}],
+	"relationships": [
+		{"from": "api", "to": "db", "kind": "writes"},
+		{"from": "api", "to": "orders-placed", "kind": "publishes"},
+		{"from": "worker", "to": "orders-placed", "kind": "consumes"}
+	]
+}`
+
+func TestParseArchitectureSpec(t *testing.T) {
+	spec, err := ParseArchitectureSpec("```json\n" + shopSpec + "\n```")
+	require.NoError(t, err)
+	require.Len(t, spec.Services, 2)
+	assert.Equal(t, "postgres", spec.Datastores[0].Kind)
+
+	md := spec.Markdown()
+	assert.Contains(t, md, "- **api** (go)")
+	assert.Contains(t, md, "  - `POST /orders` place an order")
+	assert.Contains(t, md, "- worker consumes orders-placed")
+	assert.Contains(t, spec.Prompt(), "Follow this spec exactly")
+
+	for response, msg := range map[string]string{
+		`{"services": []}`:                "architecture declares no services",
+		`{"services": [{"name": "API"}]}`: `invalid service name "API"`,
+		`{"services": [{"name": "api"}], "datastores": [{"name": "api", "kind": "redis"}]}`:                              `datastore "api" is declared twice`,
+		`{"services": [{"name": "api", "apis": [{"method": "FETCH", "path": "/x"}]}]}`:                                   `invalid API method "FETCH"`,
+		`{"services": [{"name": "api", "apis": [{"method": "GET", "path": "x"}]}]}`:                                      `API path "x" must start with /`,
+		`{"services": [{"name": "api"}], "datastores": [{"name": "db"}]}`:                                                "datastore db has no kind",
+		`{"services": [{"name": "api"}], "relationships": [{"from": "api", "to": "db", "kind": "writes"}]}`:              `"db" is not a datastore`,
+		`{"services": [{"name": "api"}], "relationships": [{"from": "api", "to": "api", "kind": "calls"}]}`:              "service api calls itself",
+		`{"services": [{"name": "api"}, {"name": "web"}], "relationships": [{"from": "api", "to": "web", "kind": "x"}]}`: `unknown kind "x"`,
+		`not json`: "failed to parse architecture spec",
+	} {
+		_, err := ParseArchitectureSpec(response)
+		assert.ErrorContains(t, err, msg, response)
+	}
+}
+
+func TestTaskArchitecture_FromRecordedResult(t *testing.T) {
+	spec, err := ParseArchitectureSpec(shopSpec)
+	require.NoError(t, err)
+
+	task := Task{Context: &TaskContext{}}
+	assert.Nil(t, TaskArchitecture(task))
+	task.Context.Record(ArchitectAgent, &Result{Success: true, Output: "design", Data: map[string]interface{}{ArchitectureKey: spec}})
+	assert.Same(t, spec, TaskArchitecture(task))
+}
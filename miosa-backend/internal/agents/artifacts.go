@@ -0,0 +1,259 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArtifactScheme prefixes artifact references, e.g.
+// artifact://architecture/6f1c...#data-model
+const ArtifactScheme = "artifact://"
+
+var artifactRefPattern = regexp.MustCompile(`artifact://([a-z0-9_\-]+)/([0-9a-f\-]{36})(?:#([a-z0-9\-]+))?`)
+
+// ErrArtifactNotFound is returned for references to unknown artifacts
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// Artifact is a document an agent produced, stored once and passed between
+// agents by reference
+type Artifact struct {
+	ID          uuid.UUID   `json:"id"`
+	Kind        string      `json:"kind"` // e.g. "architecture", "analysis"
+	Agent       AgentType   `json:"agent"`
+	WorkflowID  uuid.UUID   `json:"workflow_id,omitempty"`
+	Content     string      `json:"content,omitempty"`
+	Location    string      `json:"location,omitempty"` // external storage, when Content is not inline
+	DerivedFrom []uuid.UUID `json:"derived_from,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Ref returns the artifact's reference
+func (a *Artifact) Ref() string {
+	return fmt.Sprintf("%s%s/%s", ArtifactScheme, a.Kind, a.ID)
+}
+
+// Section returns the markdown section whose heading slugifies to slug
+func (a *Artifact) Section(slug string) (string, bool) {
+	for _, s := range splitSections(a.Content) {
+		if s.slug == slug {
+			return s.body, true
+		}
+	}
+	return "", false
+}
+
+// Outline lists the artifact's section references, so a prompt can point
+// at the parts worth resolving instead of carrying the whole document
+func (a *Artifact) Outline() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s artifact from %s: %s]", a.Kind, a.Agent, a.Ref())
+	for _, s := range splitSections(a.Content) {
+		if s.slug != "" {
+			fmt.Fprintf(&sb, "\n- %s: %s#%s", s.heading, a.Ref(), s.slug)
+		}
+	}
+	return sb.String()
+}
+
+type artifactSection struct {
+	heading string
+	slug    string
+	body    string
+}
+
+// splitSections splits markdown content at its headings. Text before the
+// first heading is a section with an empty slug.
+func splitSections(content string) []artifactSection {
+	var sections []artifactSection
+	current := artifactSection{}
+	var body []string
+	flush := func() {
+		current.body = strings.TrimSpace(strings.Join(body, "\n"))
+		if current.slug != "" || current.body != "" {
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "#") {
+			flush()
+			heading := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			current = artifactSection{heading: heading, slug: slugify(heading)}
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+func slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+			dash = false
+		case sb.Len() > 0 && !dash:
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
+
+// ArtifactStore persists artifacts
+type ArtifactStore interface {
+	Put(ctx context.Context, artifact *Artifact) error
+	Get(ctx context.Context, id uuid.UUID) (*Artifact, error)
+	// Derived returns artifacts that list id in DerivedFrom
+	Derived(ctx context.Context, id uuid.UUID) ([]*Artifact, error)
+}
+
+// MemoryArtifactStore keeps artifacts in memory
+type MemoryArtifactStore struct {
+	artifacts map[uuid.UUID]*Artifact
+	mu        sync.RWMutex
+}
+
+// NewMemoryArtifactStore creates an empty store
+func NewMemoryArtifactStore() *MemoryArtifactStore {
+	return &MemoryArtifactStore{artifacts: make(map[uuid.UUID]*Artifact)}
+}
+
+// Put stores the artifact
+func (s *MemoryArtifactStore) Put(ctx context.Context, artifact *Artifact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifacts[artifact.ID] = artifact
+	return nil
+}
+
+// Get returns the artifact with id
+func (s *MemoryArtifactStore) Get(ctx context.Context, id uuid.UUID) (*Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.artifacts[id]
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+	return a, nil
+}
+
+// Derived returns artifacts derived from id
+func (s *MemoryArtifactStore) Derived(ctx context.Context, id uuid.UUID) ([]*Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Artifact
+	for _, a := range s.artifacts {
+		for _, src := range a.DerivedFrom {
+			if src == id {
+				out = append(out, a)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// ArtifactRegistry publishes agent outputs as artifacts and resolves the
+// references to them found in prompts
+type ArtifactRegistry struct {
+	store ArtifactStore
+}
+
+// NewArtifactRegistry creates a registry backed by store
+func NewArtifactRegistry(store ArtifactStore) *ArtifactRegistry {
+	return &ArtifactRegistry{store: store}
+}
+
+// Publish stores content as a new artifact. derivedFrom names the artifacts
+// whose references were resolved into the prompt that produced it.
+func (r *ArtifactRegistry) Publish(ctx context.Context, agent AgentType, kind string, workflowID uuid.UUID, content string, derivedFrom []uuid.UUID) (*Artifact, error) {
+	kind = slugify(kind)
+	if kind == "" {
+		kind = slugify(string(agent))
+	}
+	artifact := &Artifact{
+		ID:          uuid.New(),
+		Kind:        strings.ReplaceAll(kind, "-", "_"),
+		Agent:       agent,
+		WorkflowID:  workflowID,
+		Content:     content,
+		DerivedFrom: derivedFrom,
+		CreatedAt:   time.Now(),
+	}
+	if err := r.store.Put(ctx, artifact); err != nil {
+		return nil, fmt.Errorf("failed to store artifact: %w", err)
+	}
+	return artifact, nil
+}
+
+// Get returns the artifact a reference points to
+func (r *ArtifactRegistry) Get(ctx context.Context, ref string) (*Artifact, error) {
+	m := artifactRefPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return nil, fmt.Errorf("invalid artifact reference %q", ref)
+	}
+	id, err := uuid.Parse(m[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact reference %q: %w", ref, err)
+	}
+	return r.store.Get(ctx, id)
+}
+
+// Lineage returns the artifacts derived from the one ref points to
+func (r *ArtifactRegistry) Lineage(ctx context.Context, ref string) ([]*Artifact, error) {
+	a, err := r.Get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return r.store.Derived(ctx, a.ID)
+}
+
+// Resolve expands the artifact references in text: a reference to a section
+// becomes that section, a bare reference becomes the artifact's outline.
+// Unknown references are left as they are. It returns the expanded text and
+// the IDs of the artifacts used, for provenance.
+func (r *ArtifactRegistry) Resolve(ctx context.Context, text string) (string, []uuid.UUID) {
+	var used []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	resolved := artifactRefPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		m := artifactRefPattern.FindStringSubmatch(ref)
+		artifact, err := r.Get(ctx, ref)
+		if err != nil {
+			return ref
+		}
+		expansion := artifact.Outline()
+		if m[3] != "" {
+			section, ok := artifact.Section(m[3])
+			if !ok {
+				return ref
+			}
+			expansion = fmt.Sprintf("[%s]\n%s\n[end %s]", ref, section, ref)
+		}
+		if !seen[artifact.ID] {
+			seen[artifact.ID] = true
+			used = append(used, artifact.ID)
+		}
+		return expansion
+	})
+	return resolved, used
+}
+
+// SetArtifactRegistry replaces the registry chain outputs are published to
+func (o *Orchestrator) SetArtifactRegistry(registry *ArtifactRegistry) {
+	o.artifacts = registry
+}
+
+// Artifacts returns the registry chain outputs are published to
+func (o *Orchestrator) Artifacts() *ArtifactRegistry {
+	return o.artifacts
+}
@@ -0,0 +1,76 @@
+package agents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const architectureDoc = `Overview of the chat service.
+
+## Data Model
+Messages are stored in Postgres, partitioned by room.
+
+## API Design
+REST for history, WebSockets for delivery.`
+
+func TestArtifactRegistry_ResolveSectionsAndOutlines(t *testing.T) {
+	ctx := context.Background()
+	registry := NewArtifactRegistry(NewMemoryArtifactStore())
+
+	artifact, err := registry.Publish(ctx, ArchitectAgent, "architecture", uuid.New(), architectureDoc, nil)
+	require.NoError(t, err)
+	assert.Regexp(t, `^artifact://architecture/[0-9a-f-]{36}$`, artifact.Ref())
+
+	resolved, used := registry.Resolve(ctx, "Implement the schema from "+artifact.Ref()+"#data-model please")
+	assert.Contains(t, resolved, "partitioned by room")
+	assert.NotContains(t, resolved, "WebSockets", "only the referenced section is inlined")
+	assert.Equal(t, []uuid.UUID{artifact.ID}, used)
+
+	resolved, _ = registry.Resolve(ctx, "See "+artifact.Ref())
+	assert.Contains(t, resolved, "- Data Model: "+artifact.Ref()+"#data-model")
+	assert.Contains(t, resolved, "- API Design: "+artifact.Ref()+"#api-design")
+	assert.NotContains(t, resolved, "partitioned by room")
+
+	unknown := "artifact://architecture/" + uuid.New().String()
+	resolved, used = registry.Resolve(ctx, unknown+" and "+artifact.Ref()+"#missing-section")
+	assert.Equal(t, unknown+" and "+artifact.Ref()+"#missing-section", resolved)
+	assert.Empty(t, used)
+}
+
+func TestArtifactRegistry_Lineage(t *testing.T) {
+	ctx := context.Background()
+	registry := NewArtifactRegistry(NewMemoryArtifactStore())
+
+	design, err := registry.Publish(ctx, ArchitectAgent, "architecture", uuid.Nil, architectureDoc, nil)
+	require.NoError(t, err)
+	code, err := registry.Publish(ctx, DevelopmentAgent, "", uuid.Nil, "package chat", []uuid.UUID{design.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "development", code.Kind)
+
+	derived, err := registry.Lineage(ctx, design.Ref())
+	require.NoError(t, err)
+	require.Len(t, derived, 1)
+	assert.Equal(t, code.ID, derived[0].ID)
+
+	_, err = registry.Get(ctx, "artifact://architecture/"+uuid.New().String())
+	assert.ErrorIs(t, err, ErrArtifactNotFound)
+	_, err = registry.Get(ctx, "not a reference")
+	assert.Error(t, err)
+}
+
+func TestContextBuilder_ReferencesArtifactsOfSummarizedSteps(t *testing.T) {
+	tc := &TaskContext{}
+	long := "Lead sentence. " + architectureDoc
+	for len(long) < 2000 {
+		long += "\n\nMore detail about the design."
+	}
+	tc.Record(ArchitectAgent, &Result{Success: true, Output: long, Data: map[string]interface{}{"artifact": "artifact://architecture/ref"}})
+	tc.Record(DevelopmentAgent, &Result{Success: true, Output: "code"})
+
+	assert.Equal(t, "artifact://architecture/ref", tc.Entries[0].Artifact)
+	assert.Contains(t, NewContextBuilder(400).Build(tc), "Full output: artifact://architecture/ref")
+}
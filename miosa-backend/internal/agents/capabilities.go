@@ -0,0 +1,197 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CapabilitiesParam is the task parameter listing capabilities the handling
+// agent must provide, e.g. ["multi_file_generation", "api_design"]
+const CapabilitiesParam = "required_capabilities"
+
+// RequiredCapabilities reads the capability list from task parameters. It
+// accepts the typed slice as well as the forms produced by JSON decoding.
+func RequiredCapabilities(task Task) []string {
+	raw, ok := task.Parameters[CapabilitiesParam]
+	if !ok {
+		return nil
+	}
+	var names []string
+	switch v := raw.(type) {
+	case []string:
+		names = v
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, fmt.Sprint(item))
+		}
+	case string:
+		names = strings.Split(v, ",")
+	}
+
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		if n = normalizeCapability(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func normalizeCapability(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CostProvider is implemented by agents that declare a relative cost per
+// execution; agents without one count as 1
+type CostProvider interface {
+	CostWeight() float64
+}
+
+func costWeight(agent Agent) float64 {
+	if c, ok := agent.(CostProvider); ok && c.CostWeight() > 0 {
+		return c.CostWeight()
+	}
+	return 1
+}
+
+// AgentMatch is a candidate agent for a set of required capabilities
+type AgentMatch struct {
+	Agent    Agent
+	Type     AgentType
+	Matched  []string
+	Missing  []string
+	Coverage float64 // fraction of required capabilities provided
+	Score    float64 // 0-1, coverage weighted with past reliability
+}
+
+// CapabilityIndex maps capability names to the agents that declare them
+type CapabilityIndex struct {
+	agents map[AgentType]Agent
+	byName map[string][]AgentType
+}
+
+// NewCapabilityIndex indexes the capabilities of the given agents. The
+// orchestrator itself is never a routing target and is skipped.
+func NewCapabilityIndex(registered map[AgentType]Agent) *CapabilityIndex {
+	idx := &CapabilityIndex{
+		agents: make(map[AgentType]Agent, len(registered)),
+		byName: make(map[string][]AgentType),
+	}
+	for t, agent := range registered {
+		if t == OrchestratorAgent || agent == nil {
+			continue
+		}
+		idx.agents[t] = agent
+		seen := make(map[string]bool)
+		for _, c := range agent.GetCapabilities() {
+			name := normalizeCapability(c.Name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			idx.byName[name] = append(idx.byName[name], t)
+		}
+	}
+	for name := range idx.byName {
+		sort.Slice(idx.byName[name], func(i, j int) bool { return idx.byName[name][i] < idx.byName[name][j] })
+	}
+	return idx
+}
+
+// IndexCapabilities builds an index over the currently registered agents
+func IndexCapabilities() *CapabilityIndex {
+	return NewCapabilityIndex(GetAll())
+}
+
+// Providers returns the agent types declaring a capability
+func (idx *CapabilityIndex) Providers(capability string) []AgentType {
+	return append([]AgentType(nil), idx.byName[normalizeCapability(capability)]...)
+}
+
+// Capabilities returns every indexed capability name, sorted
+func (idx *CapabilityIndex) Capabilities() []string {
+	names := make([]string, 0, len(idx.byName))
+	for name := range idx.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rank scores every agent providing at least one required capability, best
+// first. Coverage dominates the score; agents with a better execution
+// record win among equally capable ones, then preferred, then the cheaper.
+func (idx *CapabilityIndex) Rank(required []string, preferred AgentType) []AgentMatch {
+	if len(required) == 0 {
+		return nil
+	}
+	wanted := make([]string, 0, len(required))
+	seen := make(map[string]bool)
+	for _, r := range required {
+		if r = normalizeCapability(r); r != "" && !seen[r] {
+			seen[r] = true
+			wanted = append(wanted, r)
+		}
+	}
+
+	provides := make(map[AgentType]map[string]bool)
+	for _, name := range wanted {
+		for _, t := range idx.byName[name] {
+			if provides[t] == nil {
+				provides[t] = make(map[string]bool)
+			}
+			provides[t][name] = true
+		}
+	}
+
+	matches := make([]AgentMatch, 0, len(provides))
+	for t, caps := range provides {
+		m := AgentMatch{Agent: idx.agents[t], Type: t}
+		for _, name := range wanted {
+			if caps[name] {
+				m.Matched = append(m.Matched, name)
+			} else {
+				m.Missing = append(m.Missing, name)
+			}
+		}
+		m.Coverage = float64(len(m.Matched)) / float64(len(wanted))
+		m.Score = 0.8*m.Coverage + 0.2*reliability(t)
+		matches = append(matches, m)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if (a.Type == preferred) != (b.Type == preferred) {
+			return a.Type == preferred
+		}
+		if ca, cb := costWeight(a.Agent), costWeight(b.Agent); ca != cb {
+			return ca < cb
+		}
+		return a.Type < b.Type
+	})
+	return matches
+}
+
+// Best returns the highest-ranked agent for the required capabilities
+func (idx *CapabilityIndex) Best(required []string, preferred AgentType) (*AgentMatch, error) {
+	matches := idx.Rank(required, preferred)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no registered agent provides any of %v", required)
+	}
+	return &matches[0], nil
+}
+
+// reliability blends an agent's success rate and average confidence; agents
+// without history get a neutral 0.5
+func reliability(t AgentType) float64 {
+	eval, err := GetEvaluation(t)
+	if err != nil || eval.TotalExecutions == 0 {
+		return 0.5
+	}
+	success := float64(eval.SuccessfulExecutions) / float64(eval.TotalExecutions)
+	return 0.7*success + 0.3*eval.AverageConfidence
+}
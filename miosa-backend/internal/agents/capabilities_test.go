@@ -0,0 +1,91 @@
+package agents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type capAgent struct {
+	agentType AgentType
+	caps      []string
+}
+
+func (a *capAgent) GetType() AgentType     { return a.agentType }
+func (a *capAgent) GetDescription() string { return string(a.agentType) }
+func (a *capAgent) GetCapabilities() []Capability {
+	caps := make([]Capability, len(a.caps))
+	for i, c := range a.caps {
+		caps[i] = Capability{Name: c}
+	}
+	return caps
+}
+func (a *capAgent) Execute(ctx context.Context, task Task) (*Result, error) {
+	return &Result{Success: true, Output: string(a.agentType)}, nil
+}
+
+func TestRequiredCapabilities(t *testing.T) {
+	task := Task{Parameters: map[string]interface{}{
+		CapabilitiesParam: []interface{}{"API_Design", " multi_file_generation ", ""},
+	}}
+	assert.Equal(t, []string{"api_design", "multi_file_generation"}, RequiredCapabilities(task))
+	assert.Equal(t, []string{"a", "b"}, RequiredCapabilities(Task{Parameters: map[string]interface{}{CapabilitiesParam: "a, b"}}))
+	assert.Nil(t, RequiredCapabilities(Task{}))
+}
+
+func TestCapabilityIndex_Rank(t *testing.T) {
+	idx := NewCapabilityIndex(map[AgentType]Agent{
+		OrchestratorAgent:  &capAgent{OrchestratorAgent, []string{"api_design"}},
+		DevelopmentAgent:   &capAgent{DevelopmentAgent, []string{"multi_file_generation", "code_generation"}},
+		ArchitectAgent:     &capAgent{ArchitectAgent, []string{"api_design", "system_design"}},
+		"plugin_fullstack": &capAgent{"plugin_fullstack", []string{"api_design", "multi_file_generation"}},
+	})
+
+	assert.Equal(t, []AgentType{ArchitectAgent, "plugin_fullstack"}, idx.Providers("API_DESIGN"))
+	assert.Contains(t, idx.Capabilities(), "system_design")
+
+	matches := idx.Rank([]string{"multi_file_generation", "api_design"}, "")
+	require.Len(t, matches, 3)
+	assert.Equal(t, AgentType("plugin_fullstack"), matches[0].Type)
+	assert.Equal(t, 1.0, matches[0].Coverage)
+	assert.Equal(t, ArchitectAgent, matches[1].Type)
+	assert.Equal(t, []string{"multi_file_generation"}, matches[1].Missing)
+
+	// Preferred agent wins ties between equally capable agents
+	best, err := idx.Best([]string{"api_design"}, "plugin_fullstack")
+	require.NoError(t, err)
+	assert.Equal(t, AgentType("plugin_fullstack"), best.Type)
+
+	_, err = idx.Best([]string{"quantum_computing"}, "")
+	assert.Error(t, err)
+}
+
+func TestOrchestrator_AgentForStep(t *testing.T) {
+	Clear()
+	t.Cleanup(Clear)
+	require.NoError(t, Register(&capAgent{DevelopmentAgent, []string{"code_generation"}}))
+	require.NoError(t, Register(&capAgent{"plugin_fullstack", []string{"code_generation", "multi_file_generation"}}))
+
+	o := NewOrchestrator(nil, zap.NewNop(), nil)
+
+	agent, err := o.agentForStep(DevelopmentAgent, Task{})
+	require.NoError(t, err)
+	assert.Equal(t, DevelopmentAgent, agent.GetType())
+
+	task := Task{Parameters: map[string]interface{}{CapabilitiesParam: []string{"code_generation"}}}
+	agent, err = o.agentForStep(DevelopmentAgent, task)
+	require.NoError(t, err)
+	assert.Equal(t, DevelopmentAgent, agent.GetType(), "planned agent is kept when it is capable")
+
+	task.Parameters[CapabilitiesParam] = []string{"multi_file_generation"}
+	agent, err = o.agentForStep(DevelopmentAgent, task)
+	require.NoError(t, err)
+	assert.Equal(t, AgentType("plugin_fullstack"), agent.GetType())
+
+	routing, err := o.routeByCapability([]string{"multi_file_generation", "code_generation"})
+	require.NoError(t, err)
+	assert.Equal(t, "plugin_fullstack", routing.Agent)
+}
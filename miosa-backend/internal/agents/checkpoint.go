@@ -0,0 +1,132 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoCheckpoints is returned when a workflow has nothing to resume from
+var ErrNoCheckpoints = errors.New("no checkpoints for workflow")
+
+// Checkpoint captures one step of a sequential workflow: the task as the
+// agent received it (including context and memory) and what it produced.
+type Checkpoint struct {
+	WorkflowID uuid.UUID `json:"workflow_id"`
+	Step       int       `json:"step"`
+	Agent      AgentType `json:"agent"`
+	Task       Task      `json:"task"`
+	Result     *Result   `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Succeeded reports whether the step produced a usable result
+func (c *Checkpoint) Succeeded() bool {
+	return c.Error == "" && c.Result != nil
+}
+
+// CheckpointStore persists workflow checkpoints
+type CheckpointStore interface {
+	Save(ctx context.Context, cp *Checkpoint) error
+	// Load returns the latest checkpoint of each step, ordered by step
+	Load(ctx context.Context, workflowID uuid.UUID) ([]*Checkpoint, error)
+}
+
+// ResumeIndex returns the position in sequence to restart from. With from
+// set, that agent is restarted and every earlier step must have succeeded.
+// Otherwise the first step without a successful checkpoint is chosen.
+func ResumeIndex(sequence []AgentType, checkpoints []*Checkpoint, from AgentType) (int, error) {
+	if len(checkpoints) == 0 {
+		return 0, ErrNoCheckpoints
+	}
+
+	done := make(map[AgentType]bool, len(checkpoints))
+	for _, cp := range checkpoints {
+		done[cp.Agent] = cp.Succeeded()
+	}
+
+	if from == "" {
+		for i, agent := range sequence {
+			if !done[agent] {
+				return i, nil
+			}
+		}
+		return len(sequence), nil
+	}
+
+	for i, agent := range sequence {
+		if agent == from {
+			return i, nil
+		}
+		if !done[agent] {
+			return 0, fmt.Errorf("cannot resume from %s: earlier step %s has no successful checkpoint", from, agent)
+		}
+	}
+	return 0, fmt.Errorf("agent %s is not part of this workflow", from)
+}
+
+// FileCheckpointStore keeps checkpoints as JSON under dir/<workflow id>/
+type FileCheckpointStore struct {
+	dir string
+}
+
+// NewFileCheckpointStore creates a store rooted at dir
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{dir: dir}
+}
+
+// Save writes the checkpoint, replacing any earlier one for the same step
+func (s *FileCheckpointStore) Save(ctx context.Context, cp *Checkpoint) error {
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = time.Now()
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	dir := filepath.Join(s.dir, cp.WorkflowID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%02d_%s.json", cp.Step, cp.Agent))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads all checkpoints of a workflow
+func (s *FileCheckpointStore) Load(ctx context.Context, workflowID uuid.UUID) ([]*Checkpoint, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, workflowID.String(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]*Checkpoint, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(file), err)
+		}
+		checkpoints = append(checkpoints, &cp)
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Step < checkpoints[j].Step
+	})
+	return checkpoints, nil
+}
@@ -0,0 +1,70 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpointStore_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileCheckpointStore(t.TempDir())
+	workflowID := uuid.New()
+
+	task := Task{
+		ID:      workflowID,
+		Input:   "build an API",
+		Context: &TaskContext{Memory: map[string]interface{}{"strategy": "plan"}},
+	}
+	require.NoError(t, store.Save(ctx, &Checkpoint{
+		WorkflowID: workflowID, Step: 1, Agent: AnalysisAgent, Task: task,
+		Error: "timeout",
+	}))
+	require.NoError(t, store.Save(ctx, &Checkpoint{
+		WorkflowID: workflowID, Step: 0, Agent: StrategyAgent, Task: task,
+		Result: &Result{Success: true, Output: "plan"},
+	}))
+	// A retry overwrites the failed step
+	require.NoError(t, store.Save(ctx, &Checkpoint{
+		WorkflowID: workflowID, Step: 1, Agent: AnalysisAgent, Task: task,
+		Result: &Result{Success: false, Output: "partial", Error: errors.New("low confidence")},
+	}))
+
+	checkpoints, err := store.Load(ctx, workflowID)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, StrategyAgent, checkpoints[0].Agent)
+	assert.Equal(t, "plan", checkpoints[0].Task.Context.Memory["strategy"])
+	assert.True(t, checkpoints[1].Succeeded())
+	assert.EqualError(t, checkpoints[1].Result.Error, "low confidence")
+}
+
+func TestResumeIndex(t *testing.T) {
+	sequence := []AgentType{StrategyAgent, AnalysisAgent, DevelopmentAgent, QualityAgent}
+	checkpoints := []*Checkpoint{
+		{Step: 0, Agent: StrategyAgent, Result: &Result{Success: true}},
+		{Step: 1, Agent: AnalysisAgent, Result: &Result{Success: true}},
+		{Step: 2, Agent: DevelopmentAgent, Error: "rate limited"},
+	}
+
+	idx, err := ResumeIndex(sequence, checkpoints, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, idx, "defaults to the failed step")
+
+	idx, err = ResumeIndex(sequence, checkpoints, AnalysisAgent)
+	require.NoError(t, err)
+	assert.Equal(t, 1, idx)
+
+	_, err = ResumeIndex(sequence, checkpoints, QualityAgent)
+	assert.Error(t, err, "development never succeeded")
+
+	_, err = ResumeIndex(sequence, checkpoints, DeploymentAgent)
+	assert.Error(t, err)
+
+	_, err = ResumeIndex(sequence, nil, "")
+	assert.ErrorIs(t, err, ErrNoCheckpoints)
+}
@@ -0,0 +1,146 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Limits on the questions asked before a workflow
+const (
+	MaxQuestions      = 5
+	MaxAnswerLength   = 2000
+	ClarificationsKey = "clarifications" // TaskContext.Memory key holding the answered questions
+)
+
+// noAnswer stands in for questions left unanswered
+const noAnswer = "No preference; use your judgment"
+
+// Question is something the description leaves open that the caller is
+// asked before the workflow runs
+type Question struct {
+	ID       string   `json:"id"`
+	Question string   `json:"question"`
+	Why      string   `json:"why,omitempty"`     // What the answer changes
+	Options  []string `json:"options,omitempty"` // Suggested answers; any answer is accepted
+}
+
+// Clarifier is implemented by agents that can ask about an ambiguous task
+// before the workflow starts. Clarify returns no questions when the task is
+// clear enough to proceed.
+type Clarifier interface {
+	Clarify(ctx context.Context, task Task) ([]Question, error)
+}
+
+// Clarification is the questions asked about a task and, once submitted,
+// the caller's answers keyed by question ID
+type Clarification struct {
+	Questions  []Question        `json:"questions"`
+	Answers    map[string]string `json:"answers,omitempty"`
+	AskedAt    time.Time         `json:"asked_at"`
+	AnsweredAt *time.Time        `json:"answered_at,omitempty"`
+}
+
+// ParseQuestions reads the questions an LLM returned as JSON, either an
+// array or an object with a questions field, possibly fenced. Questions
+// without text are dropped, missing IDs are numbered and at most
+// MaxQuestions are kept.
+func ParseQuestions(content string) ([]Question, error) {
+	content = stripJSONFences(content)
+	var questions []Question
+	if strings.HasPrefix(content, "[") {
+		if err := json.Unmarshal([]byte(content), &questions); err != nil {
+			return nil, fmt.Errorf("failed to parse questions: %w", err)
+		}
+	} else {
+		var w struct {
+			Questions []Question `json:"questions"`
+		}
+		if err := json.Unmarshal([]byte(content), &w); err != nil {
+			return nil, fmt.Errorf("failed to parse questions: %w", err)
+		}
+		questions = w.Questions
+	}
+
+	kept := make([]Question, 0, len(questions))
+	seen := make(map[string]bool)
+	for _, q := range questions {
+		q.Question = strings.TrimSpace(q.Question)
+		if q.Question == "" {
+			continue
+		}
+		q.ID = strings.TrimSpace(q.ID)
+		if q.ID == "" || seen[q.ID] {
+			q.ID = fmt.Sprintf("q%d", len(kept)+1)
+		}
+		seen[q.ID] = true
+		kept = append(kept, q)
+		if len(kept) == MaxQuestions {
+			break
+		}
+	}
+	return kept, nil
+}
+
+// Answer records the caller's answers, rejecting answers to questions that
+// were not asked. Questions left unanswered are answered with no preference.
+func (c *Clarification) Answer(answers map[string]string, now time.Time) error {
+	asked := make(map[string]bool, len(c.Questions))
+	for _, q := range c.Questions {
+		asked[q.ID] = true
+	}
+	var unknown []string
+	for id, answer := range answers {
+		if !asked[id] {
+			unknown = append(unknown, id)
+		}
+		if len(answer) > MaxAnswerLength {
+			return fmt.Errorf("answer to %s must be at most %d characters", id, MaxAnswerLength)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("no question asked with id %s", strings.Join(unknown, ", "))
+	}
+
+	c.Answers = make(map[string]string, len(c.Questions))
+	for _, q := range c.Questions {
+		answer := strings.TrimSpace(answers[q.ID])
+		if answer == "" {
+			answer = noAnswer
+		}
+		c.Answers[q.ID] = answer
+	}
+	c.AnsweredAt = &now
+	return nil
+}
+
+// Apply merges the answers into the task: the input gains a clarifications
+// section every agent reads, and the context memory keeps them by question
+func (c *Clarification) Apply(task *Task) {
+	if len(c.Answers) == 0 {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(task.Input)
+	b.WriteString("\n\nClarifications:")
+	for _, q := range c.Questions {
+		fmt.Fprintf(&b, "\n- Q: %s\n  A: %s", q.Question, c.Answers[q.ID])
+	}
+	task.Input = b.String()
+
+	if task.Context == nil {
+		task.Context = &TaskContext{}
+	}
+	if task.Context.Memory == nil {
+		task.Context.Memory = make(map[string]interface{})
+	}
+	answered := make(map[string]string, len(c.Questions))
+	for _, q := range c.Questions {
+		answered[q.Question] = c.Answers[q.ID]
+	}
+	task.Context.Memory[ClarificationsKey] = answered
+}
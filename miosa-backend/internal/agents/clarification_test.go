@@ -0,0 +1,61 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuestions(t *testing.T) {
+	questions, err := ParseQuestions("```json\n" + `{"questions": [
+		{"id": "users", "question": "Who signs in?", "why": "Decides the auth model", "options": ["staff", "customers"]},
+		{"id": "users", "question": " Which payment provider? "},
+		{"question": ""},
+		{"question": "How many orders a day?"}
+	]}` + "\n```")
+	require.NoError(t, err)
+	assert.Equal(t, []Question{
+		{ID: "users", Question: "Who signs in?", Why: "Decides the auth model", Options: []string{"staff", "customers"}},
+		{ID: "q2", Question: "Which payment provider?"},
+		{ID: "q3", Question: "How many orders a day?"},
+	}, questions)
+
+	questions, err = ParseQuestions(`[{"question": "a"}, {"question": "b"}, {"question": "c"}, {"question": "d"}, {"question": "e"}, {"question": "f"}]`)
+	require.NoError(t, err)
+	assert.Len(t, questions, MaxQuestions)
+
+	questions, err = ParseQuestions(`{"questions": []}`)
+	require.NoError(t, err)
+	assert.Empty(t, questions)
+
+	_, err = ParseQuestions("The request is clear.")
+	assert.Error(t, err)
+}
+
+func TestClarification_Answer(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &Clarification{Questions: []Question{
+		{ID: "users", Question: "Who signs in?"},
+		{ID: "payments", Question: "Which payment provider?"},
+	}}
+
+	assert.EqualError(t, c.Answer(map[string]string{"users": "staff", "scale": "10k"}, now), "no question asked with id scale")
+	assert.Nil(t, c.Answers)
+
+	require.NoError(t, c.Answer(map[string]string{"users": " staff only "}, now))
+	assert.Equal(t, map[string]string{"users": "staff only", "payments": noAnswer}, c.Answers)
+	assert.Equal(t, &now, c.AnsweredAt)
+
+	task := Task{Input: "Build a shop"}
+	c.Apply(&task)
+	assert.Equal(t, "Build a shop\n\nClarifications:\n- Q: Who signs in?\n  A: staff only\n- Q: Which payment provider?\n  A: "+noAnswer, task.Input)
+	assert.Equal(t, map[string]string{"Who signs in?": "staff only", "Which payment provider?": noAnswer}, task.Context.Memory[ClarificationsKey])
+
+	// Unanswered clarifications leave the task alone
+	task = Task{Input: "Build a shop"}
+	(&Clarification{Questions: c.Questions}).Apply(&task)
+	assert.Equal(t, "Build a shop", task.Input)
+	assert.Nil(t, task.Context)
+}
@@ -39,6 +39,11 @@ func (a *CommunicationAgent) GetDescription() string {
 	return "Handles user interactions, chat responses, and UI/UX communications"
 }
 
+// Model returns the model the agent calls
+func (a *CommunicationAgent) Model() string {
+	return a.config.Model
+}
+
 // GetCapabilities returns the agent's capabilities
 func (a *CommunicationAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
@@ -64,7 +69,7 @@ func (a *CommunicationAgent) Execute(ctx context.Context, task agents.Task) (*ag
 	})
 	
 	// Get response from LLM
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
 		Model:       groq.ChatModel(a.config.Model),
 		Messages:    messages,
 		MaxTokens:   a.config.MaxTokens,
@@ -93,7 +98,7 @@ func (a *CommunicationAgent) Execute(ctx context.Context, task agents.Task) (*ag
 	// Analyze response for next steps
 	nextStep, suggestions := a.analyzeResponse(content, task)
 	
-	return &agents.Result{
+	result := &agents.Result{
 		Success:     true,
 		Output:      content,
 		NextStep:    nextStep,
@@ -105,7 +110,9 @@ func (a *CommunicationAgent) Execute(ctx context.Context, task agents.Task) (*ag
 			"tokens_used": response.Usage.TotalTokens,
 		},
 		ExecutionMS: time.Since(startTime).Milliseconds(),
-	}, nil
+	}
+	result.AddUsage(response)
+	return result, nil
 }
 
 // buildConversationContext builds the conversation context from task history
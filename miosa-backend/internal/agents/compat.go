@@ -0,0 +1,166 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// LegacyAgent is the pre-registry agent shape used by the standalone
+// orchestrator binaries: capabilities are plain strings and failures are
+// reported through LegacyResult.Error.
+//
+// Deprecated: implement Agent instead and wrap remaining legacy
+// implementations with FromLegacy. LegacyAgent will be removed once no
+// binary under cmd/ declares its own agent types.
+type LegacyAgent interface {
+	GetType() AgentType
+	GetCapabilities() []string
+	Execute(ctx context.Context, task Task) (*LegacyResult, error)
+}
+
+// LegacyResult is the result shape returned by LegacyAgent.
+//
+// Deprecated: use Result. Convert with ToResult and FromResult.
+type LegacyResult struct {
+	Success     bool                   `json:"success"`
+	Output      string                 `json:"output"`
+	Files       []GeneratedFile        `json:"files,omitempty"`
+	Data        map[string]interface{} `json:"data"`
+	NextAgent   AgentType              `json:"next_agent,omitempty"`
+	Confidence  float64                `json:"confidence"`
+	ExecutionMS int64                  `json:"execution_ms"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// ToResult converts a legacy result into the canonical Result.
+//
+// Deprecated: only needed while LegacyResult exists; return Result directly.
+func (r *LegacyResult) ToResult() *Result {
+	if r == nil {
+		return nil
+	}
+	result := &Result{
+		Success:     r.Success,
+		Output:      r.Output,
+		Files:       r.Files,
+		Data:        r.Data,
+		NextAgent:   r.NextAgent,
+		Confidence:  r.Confidence,
+		ExecutionMS: r.ExecutionMS,
+	}
+	if r.Error != "" {
+		result.Error = errors.New(r.Error)
+	}
+	return result
+}
+
+// FromResult converts a canonical Result into the legacy shape.
+//
+// Deprecated: only for consumers still reading LegacyResult; use Result.
+func FromResult(r *Result) *LegacyResult {
+	if r == nil {
+		return nil
+	}
+	return &LegacyResult{
+		Success:     r.Success,
+		Output:      r.Output,
+		Files:       r.Files,
+		Data:        r.Data,
+		NextAgent:   r.NextAgent,
+		Confidence:  r.Confidence,
+		ExecutionMS: r.ExecutionMS,
+		Error:       r.ErrorMessage(),
+	}
+}
+
+// ErrorMessage returns the execution error as a string, or "" if none
+func (r *Result) ErrorMessage() string {
+	if r == nil || r.Error == nil {
+		return ""
+	}
+	return r.Error.Error()
+}
+
+// MarshalJSON encodes Error as its message so that API consumers receive
+// the same string field the legacy result exposed.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type plain Result
+	return json.Marshal(struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{
+		plain: plain(r),
+		Error: r.ErrorMessage(),
+	})
+}
+
+// UnmarshalJSON restores Error from its encoded message so results survive
+// a round trip through caches and queues
+func (r *Result) UnmarshalJSON(data []byte) error {
+	type plain Result
+	var decoded struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = Result(decoded.plain)
+	if decoded.Error != "" {
+		r.Error = errors.New(decoded.Error)
+	}
+	return nil
+}
+
+// CapabilityNames flattens capabilities to their names
+func CapabilityNames(caps []Capability) []string {
+	names := make([]string, 0, len(caps))
+	for _, c := range caps {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// CapabilitiesFromNames builds capabilities from plain names
+func CapabilitiesFromNames(names []string) []Capability {
+	caps := make([]Capability, 0, len(names))
+	for _, name := range names {
+		caps = append(caps, Capability{Name: name, Version: "1.0.0"})
+	}
+	return caps
+}
+
+// legacyAdapter exposes a LegacyAgent through the canonical Agent interface
+type legacyAdapter struct {
+	legacy      LegacyAgent
+	description string
+}
+
+// FromLegacy wraps a LegacyAgent so it can be registered and orchestrated
+// like any other Agent.
+//
+// Deprecated: migration shim for LegacyAgent; port the agent to Agent.
+func FromLegacy(legacy LegacyAgent, description string) Agent {
+	return &legacyAdapter{legacy: legacy, description: description}
+}
+
+func (a *legacyAdapter) GetType() AgentType {
+	return a.legacy.GetType()
+}
+
+func (a *legacyAdapter) GetCapabilities() []Capability {
+	return CapabilitiesFromNames(a.legacy.GetCapabilities())
+}
+
+func (a *legacyAdapter) GetDescription() string {
+	return a.description
+}
+
+func (a *legacyAdapter) Execute(ctx context.Context, task Task) (*Result, error) {
+	result, err := a.legacy.Execute(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return result.ToResult(), nil
+}
@@ -0,0 +1,110 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult_JSONRoundTrip(t *testing.T) {
+	original := &Result{
+		Success:     false,
+		Output:      "partial",
+		Files:       []GeneratedFile{{Path: "main.go", Content: "package main", Type: "go"}},
+		Data:        map[string]interface{}{"attempts": float64(2)},
+		NextAgent:   QualityAgent,
+		Confidence:  0.4,
+		ExecutionMS: 120,
+		Error:       errors.New("model overloaded"),
+	}
+
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "model overloaded", fields["error"], "error is encoded as its message")
+
+	var decoded Result
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "model overloaded", decoded.ErrorMessage())
+	decoded.Error, original.Error = nil, nil
+	assert.Equal(t, *original, decoded)
+}
+
+func TestResult_JSONWithoutError(t *testing.T) {
+	data, err := json.Marshal(Result{Success: true, Output: "ok"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"error"`)
+
+	var decoded Result
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Nil(t, decoded.Error)
+	assert.Equal(t, "", decoded.ErrorMessage())
+	assert.Equal(t, "", (*Result)(nil).ErrorMessage())
+}
+
+func TestCapabilityNamesRoundTrip(t *testing.T) {
+	names := []string{"system_design", "api_design"}
+	caps := CapabilitiesFromNames(names)
+	require.Len(t, caps, 2)
+	assert.Equal(t, "1.0.0", caps[0].Version)
+	assert.Equal(t, names, CapabilityNames(caps))
+}
+
+// legacyEcho is a LegacyAgent that echoes the task input
+type legacyEcho struct {
+	err error
+}
+
+func (a *legacyEcho) GetType() AgentType        { return DevelopmentAgent }
+func (a *legacyEcho) GetCapabilities() []string { return []string{"code_generation"} }
+
+func (a *legacyEcho) Execute(ctx context.Context, task Task) (*LegacyResult, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &LegacyResult{Success: false, Output: task.Input, NextAgent: QualityAgent, Error: "lint failed"}, nil
+}
+
+func TestLegacyResult_RoundTrip(t *testing.T) {
+	legacy := &LegacyResult{
+		Success:     true,
+		Output:      "done",
+		Files:       []GeneratedFile{{Path: "main.go", Content: "package main", Type: "go"}},
+		Data:        map[string]interface{}{"lines": 1},
+		NextAgent:   QualityAgent,
+		Confidence:  0.9,
+		ExecutionMS: 42,
+		Error:       "partial failure",
+	}
+
+	result := legacy.ToResult()
+	require.Error(t, result.Error)
+	assert.Equal(t, "partial failure", result.Error.Error())
+	assert.Equal(t, legacy.Files, result.Files)
+	assert.Equal(t, legacy, FromResult(result))
+
+	assert.Nil(t, (*LegacyResult)(nil).ToResult())
+	assert.Nil(t, FromResult(nil))
+	assert.Nil(t, (&LegacyResult{Success: true}).ToResult().Error)
+}
+
+func TestFromLegacy(t *testing.T) {
+	agent := FromLegacy(&legacyEcho{}, "legacy development agent")
+	assert.Equal(t, DevelopmentAgent, agent.GetType())
+	assert.Equal(t, "legacy development agent", agent.GetDescription())
+	assert.Equal(t, []string{"code_generation"}, CapabilityNames(agent.GetCapabilities()))
+
+	result, err := agent.Execute(context.Background(), Task{Input: "build it"})
+	require.NoError(t, err)
+	assert.Equal(t, "build it", result.Output)
+	assert.Equal(t, QualityAgent, result.NextAgent)
+	assert.Equal(t, "lint failed", result.ErrorMessage())
+
+	_, err = FromLegacy(&legacyEcho{err: errors.New("down")}, "").Execute(context.Background(), Task{})
+	assert.EqualError(t, err, "down")
+}
@@ -0,0 +1,279 @@
+package agents
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/conneroisu/groq-go"
+)
+
+// Prompt compression defaults
+const (
+	DefaultCompressMinTokens = 1000 // Shorter messages are sent as they are
+	DefaultSectionTokens     = 400
+)
+
+// PromptCompressor shortens long user messages before they are sent, so
+// later agents do not pay for every earlier output in full. A message is
+// split into sections at its markdown headings; a section over its token
+// budget keeps its heading and key facts — numbers, identifiers, paths,
+// requirements and errors — then the first sentence of each paragraph,
+// in their original order. The text before the first heading and the last
+// paragraph, where prompts put their instructions, are always kept.
+type PromptCompressor struct {
+	MinTokens     int            // Messages at or below this are left alone
+	SectionTokens int            // Budget of a section without its own
+	Budgets       map[string]int // Budgets by heading, matched case-insensitively as a prefix
+
+	mu    sync.Mutex
+	stats map[AgentType]*CompressionStats
+}
+
+// CompressionStats counts the tokens compression saved for an agent
+type CompressionStats struct {
+	Calls            int `json:"calls"`
+	Compressed       int `json:"compressed"` // Calls with a message compressed
+	OriginalTokens   int `json:"original_tokens"`
+	CompressedTokens int `json:"compressed_tokens"`
+	SavedTokens      int `json:"saved_tokens"`
+}
+
+// NewPromptCompressor creates a compressor with the default budgets and a
+// larger one for the history of earlier steps
+func NewPromptCompressor() *PromptCompressor {
+	return &PromptCompressor{
+		MinTokens:     DefaultCompressMinTokens,
+		SectionTokens: DefaultSectionTokens,
+		Budgets:       map[string]int{"previous work": 1200},
+		stats:         make(map[AgentType]*CompressionStats),
+	}
+}
+
+// Compress returns the messages with long user messages compressed, and
+// the prompt tokens that saved. The messages passed in are not modified.
+func (c *PromptCompressor) Compress(agent AgentType, messages []groq.ChatCompletionMessage) ([]groq.ChatCompletionMessage, int) {
+	out := make([]groq.ChatCompletionMessage, len(messages))
+	copy(out, messages)
+	original, compressed := 0, 0
+	for i, m := range out {
+		tokens := estimateTokens(m.Content)
+		original += tokens
+		if m.Role == groq.RoleUser && tokens > c.MinTokens {
+			out[i].Content = c.compressText(m.Content)
+		}
+		compressed += estimateTokens(out[i].Content)
+	}
+	saved := original - compressed
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats == nil {
+		c.stats = make(map[AgentType]*CompressionStats)
+	}
+	s, ok := c.stats[agent]
+	if !ok {
+		s = &CompressionStats{}
+		c.stats[agent] = s
+	}
+	s.Calls++
+	s.OriginalTokens += original
+	s.CompressedTokens += compressed
+	if saved > 0 {
+		s.Compressed++
+		s.SavedTokens += saved
+	}
+	return out, saved
+}
+
+// Stats returns the tokens saved so far, by agent
+func (c *PromptCompressor) Stats() map[AgentType]CompressionStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[AgentType]CompressionStats, len(c.stats))
+	for agent, s := range c.stats {
+		out[agent] = *s
+	}
+	return out
+}
+
+// budget returns the token budget of the section under heading
+func (c *PromptCompressor) budget(heading string) int {
+	name := strings.ToLower(strings.TrimSpace(strings.TrimLeft(heading, "#")))
+	for prefix, n := range c.Budgets {
+		if strings.HasPrefix(name, strings.ToLower(prefix)) {
+			return n
+		}
+	}
+	if c.SectionTokens > 0 {
+		return c.SectionTokens
+	}
+	return DefaultSectionTokens
+}
+
+// compressText compresses each section of text to its budget
+func (c *PromptCompressor) compressText(text string) string {
+	// The last paragraph is held back so it is never cut
+	body, last := text, ""
+	if i := strings.LastIndex(strings.TrimRight(text, "\n"), "\n\n"); i >= 0 {
+		body, last = text[:i], text[i:]
+	}
+
+	sections := splitPromptSections(body)
+	var sb strings.Builder
+	for i, s := range sections {
+		if i == 0 && s.heading == "" {
+			sb.WriteString(s.body)
+			continue
+		}
+		sb.WriteString(s.heading)
+		sb.WriteString(compressSection(s.body, c.budget(s.heading)))
+	}
+	sb.WriteString(last)
+	return sb.String()
+}
+
+// promptSection is a heading line and the text up to the next one
+type promptSection struct {
+	heading string // Includes its newline; empty before the first heading
+	body    string
+}
+
+// splitPromptSections splits text at markdown headings outside code fences. A
+// "Previous work:" label starts a section too.
+func splitPromptSections(text string) []promptSection {
+	sections := []promptSection{{}}
+	inFence := false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && (strings.HasPrefix(trimmed, "#") || trimmed == "Previous work:") {
+			sections = append(sections, promptSection{heading: line})
+			continue
+		}
+		sections[len(sections)-1].body += line
+	}
+	return sections
+}
+
+// keyFact matches lines worth keeping whatever their position: numbers,
+// code identifiers and paths, requirements and errors
+var keyFact = regexp.MustCompile("[0-9]|`|[a-z][a-z0-9]*[A-Z]\\w*|\\w/\\w|\\.(go|js|ts|tsx|py|sql|json|yaml|yml)\\b|(?i:\\b(must|should|required?|never|always|error|fail(ed|s|ure)?|todo|decided?)\\b)")
+
+// promptUnit is a line, sentence or code block a section is cut into
+type promptUnit struct {
+	text  string
+	score int // 2 for key facts, 1 for the first of a paragraph
+	index int
+}
+
+// compressSection keeps the highest scoring units of body within budget,
+// in their original order, noting how much was left out
+func compressSection(body string, budget int) string {
+	original := estimateTokens(body)
+	if original <= budget {
+		return body
+	}
+
+	units := sectionUnits(body)
+	ranked := make([]promptUnit, len(units))
+	copy(ranked, units)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	keep := make(map[int]bool)
+	used := 0
+	for _, u := range ranked {
+		if u.score == 0 {
+			break
+		}
+		if n := estimateTokens(u.text) + 1; used+n <= budget {
+			keep[u.index] = true
+			used += n
+		}
+	}
+
+	var lines []string
+	for _, u := range units {
+		if keep[u.index] {
+			lines = append(lines, u.text)
+		}
+	}
+	kept := strings.Join(lines, "\n")
+	return fmt.Sprintf("%s\n(compressed from about %d tokens)\n\n", kept, original)
+}
+
+// sectionUnits cuts a section body into code blocks, lines and, for long
+// lines, sentences
+func sectionUnits(body string) []promptUnit {
+	var units []promptUnit
+	add := func(text string, score int) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		if keyFact.MatchString(text) {
+			score = 2
+		}
+		units = append(units, promptUnit{text: text, score: score, index: len(units)})
+	}
+
+	var fence []string
+	firstOfParagraph := true
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != nil:
+			fence = append(fence, line)
+			if strings.HasPrefix(trimmed, "```") {
+				add(strings.Join(fence, "\n"), 1)
+				fence = nil
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```"):
+			fence = []string{line}
+			continue
+		case trimmed == "":
+			firstOfParagraph = true
+			continue
+		}
+
+		score := 0
+		if firstOfParagraph {
+			score = 1
+		}
+		firstOfParagraph = false
+		if estimateTokens(line) <= 60 {
+			add(line, score)
+			continue
+		}
+		for i, sentence := range splitSentences(line) {
+			if i > 0 {
+				score = 0
+			}
+			add(sentence, score)
+		}
+	}
+	if fence != nil {
+		add(strings.Join(fence, "\n"), 1)
+	}
+	return units
+}
+
+// splitSentences splits text after sentence-ending punctuation
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text)-1; i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && text[i+1] == ' ' {
+			sentences = append(sentences, strings.TrimSpace(text[start:i+1]))
+			start = i + 2
+		}
+	}
+	if rest := strings.TrimSpace(text[start:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
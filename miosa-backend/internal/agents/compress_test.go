@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptCompressor_KeepsKeyFactsWithinBudget(t *testing.T) {
+	filler := strings.Repeat("The team discussed the general approach at some length and agreed on it. ", 20)
+	var sb strings.Builder
+	sb.WriteString("Previous work:\n## Step 1: analysis\n")
+	for i := 0; i < 10; i++ {
+		sb.WriteString("Overview paragraph opening sentence.\n" + filler + "\n\n")
+	}
+	sb.WriteString("The API must return 404 for unknown orders.\n\n")
+	sb.WriteString("Now, build the orders service")
+	prompt := sb.String()
+
+	c := NewPromptCompressor()
+	c.MinTokens = 100
+	c.Budgets = map[string]int{"step 1": 50}
+	messages := []groq.ChatCompletionMessage{
+		{Role: groq.RoleSystem, Content: filler},
+		{Role: groq.RoleUser, Content: prompt},
+	}
+	out, saved := c.Compress(DevelopmentAgent, messages)
+
+	require.Len(t, out, 2)
+	assert.Equal(t, prompt, messages[1].Content, "the request's messages are left as they were")
+	assert.Equal(t, filler, out[0].Content, "system prompts are not compressed")
+	compressed := out[1].Content
+	assert.Contains(t, compressed, "## Step 1: analysis")
+	assert.Contains(t, compressed, "must return 404 for unknown orders")
+	assert.True(t, strings.HasSuffix(compressed, "Now, build the orders service"), "the instruction is kept")
+	assert.Contains(t, compressed, "(compressed from about")
+	assert.Less(t, estimateTokens(compressed), 200)
+	assert.Equal(t, estimateTokens(prompt)-estimateTokens(compressed), saved)
+
+	stats := c.Stats()[DevelopmentAgent]
+	assert.Equal(t, 1, stats.Compressed)
+	assert.Equal(t, saved, stats.SavedTokens)
+}
+
+func TestPromptCompressor_LeavesShortPrompts(t *testing.T) {
+	c := NewPromptCompressor()
+	messages := []groq.ChatCompletionMessage{{Role: groq.RoleUser, Content: "## Notes\nShort."}}
+	out, saved := c.Compress(AnalysisAgent, messages)
+	assert.Equal(t, messages, out)
+	assert.Zero(t, saved)
+	assert.Equal(t, CompressionStats{Calls: 1, OriginalTokens: 4, CompressedTokens: 4}, c.Stats()[AnalysisAgent])
+}
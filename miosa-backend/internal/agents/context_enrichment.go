@@ -0,0 +1,218 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+)
+
+// ContextBuilderConfigKey is the Redis key the self-improvement engine
+// stores the enrichment config under
+const ContextBuilderConfigKey = "context_builder_config"
+
+// Context sources an EnrichmentConfig can enable
+const (
+	ContextFullHistory     = "full_history"     // Every earlier step in full instead of summarized
+	ContextRelatedTasks    = "related_tasks"    // Best past results of the agent for similar tasks
+	ContextUserPreferences = "user_preferences" // The tenant's stack profile
+)
+
+// Limits on what enrichment adds to a prompt
+const (
+	FullHistoryTokens   = 6000
+	DefaultRelatedTasks = 3
+)
+
+// EnrichmentConfig selects the context added to each task before an agent
+// runs. The JSON form is what the self-improvement engine stores.
+type EnrichmentConfig struct {
+	AdditionalContext []string `json:"additional_context"`
+	RelatedLimit      int      `json:"related_limit,omitempty"` // 0 uses DefaultRelatedTasks
+}
+
+// Has reports whether the config enables source
+func (c EnrichmentConfig) Has(source string) bool {
+	for _, s := range c.AdditionalContext {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// RelatedTask is a past result shown to an agent for reference
+type RelatedTask struct {
+	TaskType string  `json:"task_type"`
+	Input    string  `json:"input"`
+	Summary  string  `json:"summary"`
+	Score    float64 `json:"score"`
+}
+
+// ContextEnrichment is the context added to one agent call
+type ContextEnrichment struct {
+	Agent       AgentType     `json:"agent"`
+	FullHistory string        `json:"full_history,omitempty"`
+	Related     []RelatedTask `json:"related,omitempty"`
+	Preferences string        `json:"preferences,omitempty"`
+	Graph       string        `json:"graph,omitempty"` // The project knowledge graph, rendered
+	LatencyMS   int64         `json:"latency_ms"`
+}
+
+// Prompt renders the enrichment for a system prompt, or "" if it is empty
+func (e *ContextEnrichment) Prompt() string {
+	if e == nil {
+		return ""
+	}
+	var sections []string
+	if e.FullHistory != "" {
+		sections = append(sections, "# Work so far\n"+e.FullHistory)
+	}
+	if len(e.Related) > 0 {
+		var sb strings.Builder
+		sb.WriteString("# Related past tasks")
+		for _, r := range e.Related {
+			fmt.Fprintf(&sb, "\n\n## %s\n%s\n\nResult: %s", r.TaskType, r.Input, r.Summary)
+		}
+		sections = append(sections, sb.String())
+	}
+	if e.Preferences != "" {
+		sections = append(sections, e.Preferences)
+	}
+	if e.Graph != "" {
+		sections = append(sections, e.Graph)
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// ProjectGraph renders the knowledge graph of the task's project for a
+// prompt, or returns "" if it has none
+type ProjectGraph func(ctx context.Context, task Task) string
+
+// ContextEnricher adds the context sources enabled by its config to tasks
+// before they reach an agent, and the project graph when one is set. With
+// the zero config and no graph it adds nothing.
+type ContextEnricher struct {
+	examples ExampleStore
+	config   EnrichmentConfig
+	graph    ProjectGraph
+	mu       sync.RWMutex
+}
+
+// NewContextEnricher creates an enricher drawing related tasks from
+// examples. Preferences come from DefaultStackProfiles.
+func NewContextEnricher(examples ExampleStore) *ContextEnricher {
+	return &ContextEnricher{examples: examples}
+}
+
+// DefaultContextEnricher is used by the orchestrator before each agent call
+var DefaultContextEnricher = NewContextEnricher(DefaultExamples.store)
+
+// Config returns the config in effect
+func (e *ContextEnricher) Config() EnrichmentConfig {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return EnrichmentConfig{
+		AdditionalContext: append([]string(nil), e.config.AdditionalContext...),
+		RelatedLimit:      e.config.RelatedLimit,
+	}
+}
+
+// SetConfig replaces the config used by later calls to Enrich
+func (e *ContextEnricher) SetConfig(config EnrichmentConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.config = config
+}
+
+// SetProjectGraph sets the source of the project graph added to every
+// task, whatever the config; nil stops adding it
+func (e *ContextEnricher) SetProjectGraph(graph ProjectGraph) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.graph = graph
+}
+
+func (e *ContextEnricher) projectGraph() ProjectGraph {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.graph
+}
+
+// Enrich sets task.Context.Enrichment for agent and returns the time it
+// took. It replaces rather than edits any earlier enrichment, so tasks
+// sharing a context with another step see their own.
+func (e *ContextEnricher) Enrich(ctx context.Context, agent AgentType, task *Task) time.Duration {
+	if e == nil || task.Context == nil {
+		return 0
+	}
+	config := e.Config()
+	graph := e.projectGraph()
+	task.Context.Enrichment = nil
+	if len(config.AdditionalContext) == 0 && graph == nil {
+		return 0
+	}
+
+	start := time.Now()
+	enrichment := &ContextEnrichment{Agent: agent}
+	if config.Has(ContextFullHistory) {
+		full := &ContextBuilder{TokenBudget: FullHistoryTokens, RecentFull: len(task.Context.Entries)}
+		enrichment.FullHistory = full.Build(task.Context)
+	}
+	if config.Has(ContextRelatedTasks) && e.examples != nil {
+		limit := config.RelatedLimit
+		if limit <= 0 {
+			limit = DefaultRelatedTasks
+		}
+		// One extra in case the task itself is among them
+		found, err := e.examples.Find(ctx, agent, task.Type, limit+1)
+		if err == nil {
+			for _, ex := range found {
+				if ex.Input == task.Input || len(enrichment.Related) == limit {
+					continue
+				}
+				enrichment.Related = append(enrichment.Related, RelatedTask{
+					TaskType: ex.TaskType,
+					Input:    Summarize(ex.Input, DefaultSummaryTokens),
+					Summary:  Summarize(ex.Output, DefaultSummaryTokens),
+					Score:    ex.Score,
+				})
+			}
+		}
+	}
+	if config.Has(ContextUserPreferences) {
+		if profile := TaskStackProfile(ctx, *task); !profile.IsZero() {
+			enrichment.Preferences = profile.Prompt()
+		}
+	}
+	if graph != nil {
+		enrichment.Graph = graph(ctx, *task)
+	}
+
+	elapsed := time.Since(start)
+	enrichment.LatencyMS = elapsed.Milliseconds()
+	task.Context.Enrichment = enrichment
+	return elapsed
+}
+
+// WithEnrichment appends the task's enrichment to the first system
+// message, adding one if messages has none
+func WithEnrichment(task Task, messages []groq.ChatCompletionMessage) []groq.ChatCompletionMessage {
+	if task.Context == nil {
+		return messages
+	}
+	prompt := task.Context.Enrichment.Prompt()
+	if prompt == "" {
+		return messages
+	}
+
+	out := append([]groq.ChatCompletionMessage(nil), messages...)
+	if len(out) > 0 && out[0].Role == "system" {
+		out[0].Content += "\n\n" + prompt
+		return out
+	}
+	return append([]groq.ChatCompletionMessage{{Role: "system", Content: prompt}}, out...)
+}
@@ -0,0 +1,61 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextEnricher_Enrich(t *testing.T) {
+	ctx := context.Background()
+	profiles := NewMemoryStackProfileStore()
+	defer func(prev StackProfileStore) { DefaultStackProfiles = prev }(DefaultStackProfiles)
+	DefaultStackProfiles = profiles
+	tenant := uuid.New()
+	require.NoError(t, profiles.SetProfile(ctx, tenant, houseStyle))
+
+	examples := NewMemoryExampleStore(5)
+	for _, input := range []string{"Build a todo API", "Build a blog API"} {
+		require.NoError(t, examples.Add(ctx, &Example{Agent: AnalysisAgent, TaskType: "implementation", Input: input, Output: "Use REST.", Score: 9.5}))
+	}
+
+	tc := &TaskContext{TenantID: tenant}
+	tc.Record(StrategyAgent, &Result{Success: true, Output: "# Plan\n\n" + strings.Repeat("Ship it first. ", 100)})
+	tc.Record(ArchitectAgent, &Result{Success: true, Output: "Monolith."})
+	task := Task{Type: "implementation", Input: "Build a todo API", Context: tc}
+
+	enricher := NewContextEnricher(examples)
+	enricher.Enrich(ctx, AnalysisAgent, &task)
+	assert.Nil(t, tc.Enrichment, "nothing is added without a config")
+
+	enricher.SetConfig(EnrichmentConfig{AdditionalContext: []string{ContextFullHistory, ContextRelatedTasks, ContextUserPreferences}})
+	enricher.Enrich(ctx, AnalysisAgent, &task)
+	require.NotNil(t, tc.Enrichment)
+	assert.Equal(t, AnalysisAgent, tc.Enrichment.Agent)
+	assert.Contains(t, tc.Enrichment.FullHistory, strings.Repeat("Ship it first. ", 50), "earlier steps are not summarized")
+	require.Len(t, tc.Enrichment.Related, 1, "the task itself is not related")
+	assert.Equal(t, "Build a blog API", tc.Enrichment.Related[0].Input)
+	assert.Equal(t, houseStyle.Prompt(), tc.Enrichment.Preferences)
+
+	messages := []groq.ChatCompletionMessage{{Role: "system", Content: "You analyze."}, {Role: "user", Content: "Go"}}
+	got := WithEnrichment(task, WithStackProfile(ctx, task, messages))
+	assert.Equal(t, 1, strings.Count(got[0].Content, houseStyle.Prompt()), "the profile is added once")
+	assert.Contains(t, got[0].Content, "# Related past tasks")
+	assert.Equal(t, "You analyze.", messages[0].Content)
+
+	enricher.SetConfig(EnrichmentConfig{})
+	enricher.Enrich(ctx, AnalysisAgent, &task)
+	assert.Nil(t, tc.Enrichment)
+	assert.Equal(t, messages, WithEnrichment(task, messages))
+
+	// The project graph is added whatever the config
+	enricher.SetProjectGraph(func(ctx context.Context, task Task) string { return "# Project knowledge graph\n- orders" })
+	enricher.Enrich(ctx, DevelopmentAgent, &task)
+	require.NotNil(t, tc.Enrichment)
+	assert.Contains(t, WithEnrichment(task, messages)[0].Content, "- orders")
+}
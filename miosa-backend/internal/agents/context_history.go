@@ -0,0 +1,179 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContextEntry is one agent's contribution to a workflow. The full output is
+// kept for the record; prompts use the summary once the entry is no longer
+// recent.
+type ContextEntry struct {
+	Agent      AgentType `json:"agent"`
+	Step       int       `json:"step"`
+	Success    bool      `json:"success"`
+	Confidence float64   `json:"confidence"`
+	Output     string    `json:"output"`
+	Summary    string    `json:"summary"`
+	Artifact   string    `json:"artifact,omitempty"` // reference to the full output
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DefaultSummaryTokens bounds the summary stored with each entry
+const DefaultSummaryTokens = 150
+
+// Record appends the result as a structured entry and stores its summary
+// in Memory under the agent type, so Memory no longer grows with raw output
+func (tc *TaskContext) Record(agent AgentType, result *Result) {
+	if tc == nil || result == nil {
+		return
+	}
+	entry := ContextEntry{
+		Agent:      agent,
+		Step:       len(tc.Entries) + 1,
+		Success:    result.Success,
+		Confidence: result.Confidence,
+		Output:     result.Output,
+		Summary:    Summarize(result.Output, DefaultSummaryTokens),
+		CreatedAt:  time.Now(),
+	}
+	if ref, ok := result.Data["artifact"].(string); ok {
+		entry.Artifact = ref
+	}
+	tc.Entries = append(tc.Entries, entry)
+
+	if tc.Memory == nil {
+		tc.Memory = make(map[string]interface{})
+	}
+	tc.Memory[string(agent)] = entry.Summary
+	// A declared monorepo layout or architecture binds the steps after it
+	if plan, ok := result.Data[MonorepoKey].(*MonorepoPlan); ok {
+		tc.Memory[MonorepoKey] = plan
+	}
+	if spec, ok := result.Data[ArchitectureKey].(*ArchitectureSpec); ok {
+		tc.Memory[ArchitectureKey] = spec
+	}
+}
+
+// Summarize shortens text to about maxTokens without calling a model. It
+// keeps markdown headings and the first sentence of each paragraph, which
+// is where agent outputs put their conclusions, then truncates.
+func Summarize(text string, maxTokens int) string {
+	text = strings.TrimSpace(text)
+	if estimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	var parts []string
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" || strings.HasPrefix(para, "```") {
+			continue
+		}
+		if strings.HasPrefix(para, "#") {
+			heading, rest, _ := strings.Cut(para, "\n")
+			parts = append(parts, heading)
+			para = strings.TrimSpace(rest)
+			if para == "" {
+				continue
+			}
+		}
+		parts = append(parts, firstSentence(para))
+	}
+	return truncateTokens(strings.Join(parts, "\n"), maxTokens)
+}
+
+// firstSentence returns text up to the first sentence end or line break
+func firstSentence(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	for i := 0; i < len(text)-1; i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && text[i+1] == ' ' {
+			return text[:i+1]
+		}
+	}
+	return text
+}
+
+// truncateTokens cuts text to about maxTokens, preferring a word boundary
+func truncateTokens(text string, maxTokens int) string {
+	limit := maxTokens * 4
+	if len(text) <= limit {
+		return text
+	}
+	if limit <= 3 {
+		return ""
+	}
+	cut := text[:limit-3]
+	if i := strings.LastIndexAny(cut, " \n"); i > limit/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + "..."
+}
+
+// ContextBuilder renders a task's history for a downstream agent prompt
+// within a token budget: the most recent entries in full, older ones as
+// summaries with a reference to their artifact, and a note for anything
+// that no longer fits.
+type ContextBuilder struct {
+	TokenBudget int
+	RecentFull  int // entries, newest first, rendered with their full output
+}
+
+// NewContextBuilder creates a builder keeping the latest entry in full
+func NewContextBuilder(tokenBudget int) *ContextBuilder {
+	return &ContextBuilder{TokenBudget: tokenBudget, RecentFull: 1}
+}
+
+// DefaultContextBuilder is used by the orchestrator's chains
+var DefaultContextBuilder = NewContextBuilder(1500)
+
+// Build returns the rendered history, oldest first, or "" if there is none
+func (b *ContextBuilder) Build(tc *TaskContext) string {
+	if tc == nil || len(tc.Entries) == 0 {
+		return ""
+	}
+
+	remaining := b.TokenBudget
+	sections := make([]string, 0, len(tc.Entries))
+	omitted := 0
+	for i := len(tc.Entries) - 1; i >= 0; i-- {
+		e := tc.Entries[i]
+		header := fmt.Sprintf("## Step %d: %s", e.Step, e.Agent)
+		if !e.Success {
+			header += " (failed)"
+		}
+		remaining -= estimateTokens(header) + 1
+		if remaining <= 0 {
+			omitted = i + 1
+			break
+		}
+
+		body := e.Summary
+		if e.Artifact != "" && e.Summary != e.Output {
+			body += "\nFull output: " + e.Artifact
+		}
+		if len(sections) < b.RecentFull && estimateTokens(e.Output) <= remaining {
+			body = e.Output
+		}
+		if estimateTokens(body) > remaining {
+			body = truncateTokens(body, remaining)
+		}
+		remaining -= estimateTokens(body)
+		sections = append(sections, header+"\n"+body)
+	}
+
+	var sb strings.Builder
+	if omitted > 0 {
+		fmt.Fprintf(&sb, "(%d earlier steps omitted)\n\n", omitted)
+	}
+	for i := len(sections) - 1; i >= 0; i-- {
+		sb.WriteString(sections[i])
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+	}
+	return sb.String()
+}
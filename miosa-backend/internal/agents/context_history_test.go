@@ -0,0 +1,77 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize_KeepsHeadingsAndLeadSentences(t *testing.T) {
+	output := "# Architecture\nThe service is split into three parts. Each part has its own store.\n\n" +
+		strings.Repeat("Filler sentence that goes on. ", 40) + "\n\n" +
+		"```go\nfunc main() {}\n```\n\n" +
+		"## Risks\nThe queue is a single point of failure. It needs a replica."
+
+	summary := Summarize(output, 60)
+	assert.Contains(t, summary, "# Architecture")
+	assert.Contains(t, summary, "The service is split into three parts.")
+	assert.Contains(t, summary, "## Risks")
+	assert.Contains(t, summary, "The queue is a single point of failure.")
+	assert.NotContains(t, summary, "own store")
+	assert.NotContains(t, summary, "func main")
+	assert.LessOrEqual(t, estimateTokens(summary), 60)
+
+	assert.Equal(t, "short output", Summarize("  short output ", 60))
+}
+
+func TestTaskContext_RecordStoresEntriesAndSummaries(t *testing.T) {
+	tc := &TaskContext{}
+	long := strings.Repeat("word ", 1000)
+	tc.Record(AnalysisAgent, &Result{Success: true, Output: long, Confidence: 0.8})
+	tc.Record(ArchitectAgent, &Result{Success: false, Output: "design"})
+
+	require.Len(t, tc.Entries, 2)
+	assert.Equal(t, 1, tc.Entries[0].Step)
+	assert.Equal(t, long, tc.Entries[0].Output)
+	assert.Equal(t, 2, tc.Entries[1].Step)
+	assert.False(t, tc.Entries[1].Success)
+
+	memory := tc.Memory[string(AnalysisAgent)].(string)
+	assert.LessOrEqual(t, estimateTokens(memory), DefaultSummaryTokens)
+	assert.Equal(t, "design", tc.Memory[string(ArchitectAgent)])
+}
+
+func TestContextBuilder_RecentFullOlderSummarized(t *testing.T) {
+	tc := &TaskContext{}
+	tc.Record(AnalysisAgent, &Result{Success: true, Output: "Analysis lead. " + strings.Repeat("detail ", 200)})
+	tc.Record(ArchitectAgent, &Result{Success: true, Output: "Architecture lead. " + strings.Repeat("detail ", 200)})
+	tc.Record(DevelopmentAgent, &Result{Success: true, Output: "latest output in full"})
+
+	built := NewContextBuilder(400).Build(tc)
+	assert.LessOrEqual(t, estimateTokens(built), 420)
+
+	analysis := strings.Index(built, "## Step 1: analysis")
+	architect := strings.Index(built, "## Step 2: architect")
+	development := strings.Index(built, "## Step 3: development")
+	require.True(t, analysis >= 0 && architect > analysis && development > architect, built)
+	assert.Contains(t, built, "latest output in full")
+	assert.NotContains(t, built, strings.Repeat("detail ", 200), "older steps are summarized")
+}
+
+func TestContextBuilder_DropsOldestWhenOverBudget(t *testing.T) {
+	tc := &TaskContext{}
+	for i := 0; i < 10; i++ {
+		tc.Record(AnalysisAgent, &Result{Success: true, Output: strings.Repeat("x", 400)})
+	}
+
+	built := NewContextBuilder(200).Build(tc)
+	assert.True(t, strings.HasPrefix(built, "("), built)
+	assert.Contains(t, built, "earlier steps omitted")
+	assert.Contains(t, built, "## Step 10: analysis")
+	assert.NotContains(t, built, "## Step 1: analysis\n")
+	assert.LessOrEqual(t, estimateTokens(built), 220)
+
+	assert.Empty(t, NewContextBuilder(200).Build(&TaskContext{}))
+}
@@ -3,6 +3,7 @@ package deployment
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/conneroisu/groq-go"
@@ -34,6 +35,11 @@ func (a *DeploymentAgent) GetDescription() string {
 	return "Handles deployment to various cloud platforms"
 }
 
+// Model returns the model the agent calls
+func (a *DeploymentAgent) Model() string {
+	return a.config.Model
+}
+
 func (a *DeploymentAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
 		{Name: "deploy", Description: "Deploy applications", Required: true},
@@ -43,9 +49,14 @@ func (a *DeploymentAgent) GetCapabilities() []agents.Capability {
 
 func (a *DeploymentAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
 	startTime := time.Now()
+	output := fmt.Sprintf("Deployment configuration for: %s", task.Input)
+	// Deploy the components the architect declared, by their names
+	if spec := agents.TaskArchitecture(task); spec != nil {
+		output += "\n\n" + deploymentPlan(spec)
+	}
 	result := &agents.Result{
 		Success:     true,
-		Output:      fmt.Sprintf("Deployment configuration for: %s", task.Input),
+		Output:      output,
 		Confidence:  8.0,
 		ExecutionMS: time.Since(startTime).Milliseconds(),
 		NextAgent:   agents.MonitoringAgent,
@@ -53,3 +64,32 @@ func (a *DeploymentAgent) Execute(ctx context.Context, task agents.Task) (*agent
 	agents.RecordExecution(a.GetType(), result)
 	return result, nil
 }
+
+// deploymentPlan lists what deploying the architecture takes: a container
+// per service and the backing datastores and queues it names
+func deploymentPlan(spec *agents.ArchitectureSpec) string {
+	var sb strings.Builder
+	sb.WriteString("## Deployment plan")
+	for _, svc := range spec.Services {
+		fmt.Fprintf(&sb, "\n- Service %s: container image %s", svc.Name, svc.Name)
+		if len(svc.APIs) > 0 {
+			fmt.Fprintf(&sb, ", serving %d API route(s)", len(svc.APIs))
+		}
+		var deps []string
+		for _, r := range spec.Relationships {
+			if r.From == svc.Name {
+				deps = append(deps, r.To)
+			}
+		}
+		if len(deps) > 0 {
+			fmt.Fprintf(&sb, ", depends on %s", strings.Join(deps, ", "))
+		}
+	}
+	for _, ds := range spec.Datastores {
+		fmt.Fprintf(&sb, "\n- Datastore %s: %s", ds.Name, ds.Kind)
+	}
+	for _, q := range spec.Queues {
+		fmt.Fprintf(&sb, "\n- Queue %s: %s", q.Name, q.Kind)
+	}
+	return sb.String()
+}
@@ -0,0 +1,687 @@
+package deployment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// CIWorkflowPath is where a project's GitHub Actions workflow is written
+const CIWorkflowPath = ".github/workflows/ci.yml"
+
+// Stacks a CI workflow builds
+const (
+	StackGo     = "go"
+	StackNode   = "node"
+	StackPython = "python"
+)
+
+// Toolchain versions used when a project pins none
+const (
+	DefaultNodeVersion   = "20"
+	DefaultPythonVersion = "3.12"
+)
+
+// ErrNoStack is returned when a project has nothing CI could build
+var ErrNoStack = errors.New("no buildable stack in project")
+
+// CIStack is one buildable part of a project, found by its manifest
+type CIStack struct {
+	Kind           string   `json:"kind"`
+	Dir            string   `json:"dir"`                       // "." for the project root
+	Version        string   `json:"version,omitempty"`         // Toolchain version the project pins
+	Manifest       string   `json:"manifest,omitempty"`        // What Python dependencies install from
+	PackageManager string   `json:"package_manager,omitempty"` // npm, yarn or pnpm
+	Scripts        []string `json:"scripts,omitempty"`         // package.json scripts CI runs
+	Tests          bool     `json:"tests"`                     // Whether test files were found
+}
+
+// CIImage is a container image CI builds, and pushes from the default branch
+type CIImage struct {
+	Name       string `json:"name"`
+	Dockerfile string `json:"dockerfile"`
+	Context    string `json:"context"`
+}
+
+// CIIssue is a problem found validating a workflow
+type CIIssue struct {
+	Job     string `json:"job,omitempty"`
+	Step    string `json:"step,omitempty"`
+	Message string `json:"message"`
+}
+
+// CIReport is the outcome of the CI stage
+type CIReport struct {
+	Path      string    `json:"path"`
+	Generated bool      `json:"generated"` // False when the deployment agent's workflow was kept
+	Stacks    []CIStack `json:"stacks,omitempty"`
+	Images    []CIImage `json:"images,omitempty"`
+	Issues    []CIIssue `json:"issues,omitempty"`   // In the written workflow
+	Replaced  []CIIssue `json:"replaced,omitempty"` // In the agent's workflow, which was replaced
+}
+
+// ciSkipDirs are never searched for manifests
+var ciSkipDirs = map[string]bool{"node_modules": true, "vendor": true, ".git": true, "dist": true, "build": true, ".venv": true, "venv": true}
+
+var (
+	goTestFile     = regexp.MustCompile(`_test\.go$`)
+	jestTestFile   = regexp.MustCompile(`\.(test|spec)\.[jt]sx?$|(^|/)tests/test_[^/]*\.js$`)
+	pytestFile     = regexp.MustCompile(`(^|/)(test_[^/]*|[^/]*_test)\.py$`)
+	versionNumber  = regexp.MustCompile(`\d+(\.\d+)?`)
+	goDirective    = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+)`)
+	requiresPython = regexp.MustCompile(`(?m)^requires-python\s*=\s*["'][^"'\d]*(\d+\.\d+)`)
+	pyprojectBuild = regexp.MustCompile(`(?m)^\[(project|build-system)\]`)
+)
+
+// BuildCI returns the project's CI report and, when it must be written,
+// the workflow. A workflow already at CIWorkflowPath, as the deployment
+// agent writes one, is kept when it validates and replaced otherwise.
+func BuildCI(files []agents.GeneratedFile) (*CIReport, *agents.GeneratedFile, error) {
+	stacks, images := DetectCI(files)
+	report := &CIReport{Path: CIWorkflowPath, Stacks: stacks, Images: images}
+
+	for _, f := range files {
+		if f.Path != CIWorkflowPath {
+			continue
+		}
+		issues := ValidateCI([]byte(f.Content), files)
+		if len(issues) == 0 {
+			return report, nil, nil
+		}
+		report.Replaced = issues
+	}
+
+	if len(stacks) == 0 && len(images) == 0 {
+		return nil, nil, ErrNoStack
+	}
+	workflow := agents.GeneratedFile{Path: CIWorkflowPath, Content: renderCI(stacks, images), Type: "yaml"}
+	report.Generated = true
+	report.Issues = ValidateCI([]byte(workflow.Content), files)
+	return report, &workflow, nil
+}
+
+// DetectCI finds the stacks CI builds, by their go.mod, package.json,
+// requirements.txt or pyproject.toml, and the Dockerfiles it builds images
+// from. Packages of an npm, yarn or pnpm workspace are built by its root.
+func DetectCI(files []agents.GeneratedFile) ([]CIStack, []CIImage) {
+	byPath := make(map[string]string, len(files))
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if !ciSkipped(f.Path) {
+			byPath[f.Path] = f.Content
+			paths = append(paths, f.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	var workspaces []string
+	for _, p := range paths {
+		if path.Base(p) != "package.json" {
+			continue
+		}
+		var pkg packageJSON
+		dir := path.Dir(p)
+		if json.Unmarshal([]byte(byPath[p]), &pkg) == nil && len(pkg.Workspaces) > 0 || hasFile(byPath, path.Join(dir, "pnpm-workspace.yaml")) {
+			workspaces = append(workspaces, dir)
+		}
+	}
+
+	var stacks []CIStack
+	seen := map[string]bool{}
+	for _, p := range paths {
+		dir, base := path.Dir(p), path.Base(p)
+		var stack *CIStack
+		switch base {
+		case "go.mod":
+			stack = &CIStack{Kind: StackGo, Dir: dir}
+			if m := goDirective.FindStringSubmatch(byPath[p]); m != nil {
+				stack.Version = m[1]
+			}
+		case "package.json":
+			if underWorkspace(dir, workspaces) {
+				continue
+			}
+			var pkg packageJSON
+			if err := json.Unmarshal([]byte(byPath[p]), &pkg); err != nil {
+				continue
+			}
+			stack = &CIStack{Kind: StackNode, Dir: dir, Version: nodeVersion(pkg.Engines.Node), PackageManager: packageManager(byPath, dir)}
+			for _, script := range []string{"lint", "build", "test"} {
+				if _, ok := pkg.Scripts[script]; ok {
+					stack.Scripts = append(stack.Scripts, script)
+				}
+			}
+		case "requirements.txt", "pyproject.toml":
+			stack = &CIStack{Kind: StackPython, Dir: dir, Version: pythonVersion(byPath, dir)}
+			switch {
+			case hasFile(byPath, path.Join(dir, "requirements.txt")):
+				stack.Manifest = "requirements.txt"
+			case pyprojectBuild.MatchString(byPath[path.Join(dir, "pyproject.toml")]):
+				stack.Manifest = "pyproject.toml"
+			}
+		default:
+			continue
+		}
+		if key := stack.Kind + " " + stack.Dir; !seen[key] {
+			seen[key] = true
+			stack.Tests = hasTests(paths, *stack)
+			stacks = append(stacks, *stack)
+		}
+	}
+
+	var images []CIImage
+	names := map[string]int{}
+	for _, p := range paths {
+		base := path.Base(p)
+		if base != "Dockerfile" && !strings.HasPrefix(base, "Dockerfile.") {
+			continue
+		}
+		image := CIImage{Dockerfile: p, Context: dockerContext(path.Dir(p), stacks)}
+		switch {
+		case strings.HasPrefix(base, "Dockerfile."):
+			image.Name = strings.TrimPrefix(base, "Dockerfile.")
+		case image.Context != ".":
+			image.Name = path.Base(image.Context)
+		default:
+			image.Name = "app"
+		}
+		image.Name = k8sName(image.Name)
+		if names[image.Name]++; names[image.Name] > 1 {
+			image.Name = fmt.Sprintf("%s-%d", image.Name, names[image.Name])
+		}
+		images = append(images, image)
+	}
+	return stacks, images
+}
+
+type packageJSON struct {
+	Scripts    map[string]string `json:"scripts"`
+	Workspaces json.RawMessage   `json:"workspaces"`
+	Engines    struct {
+		Node string `json:"node"`
+	} `json:"engines"`
+}
+
+func ciSkipped(p string) bool {
+	for _, part := range strings.Split(path.Dir(p), "/") {
+		if ciSkipDirs[part] {
+			return true
+		}
+	}
+	return false
+}
+
+// underWorkspace reports whether dir is a package inside one of the
+// workspace roots
+func underWorkspace(dir string, roots []string) bool {
+	for _, root := range roots {
+		if dir != root && (root == "." || strings.HasPrefix(dir, root+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTests reports whether the stack's directory holds test files its test
+// runner picks up
+func hasTests(paths []string, s CIStack) bool {
+	pattern := map[string]*regexp.Regexp{StackGo: goTestFile, StackNode: jestTestFile, StackPython: pytestFile}[s.Kind]
+	for _, p := range paths {
+		if (s.Dir == "." || strings.HasPrefix(p, s.Dir+"/")) && pattern.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeVersion(engines string) string {
+	if v := versionNumber.FindString(engines); v != "" {
+		return strings.Split(v, ".")[0]
+	}
+	return DefaultNodeVersion
+}
+
+func packageManager(byPath map[string]string, dir string) string {
+	switch {
+	case hasFile(byPath, path.Join(dir, "pnpm-lock.yaml")):
+		return "pnpm"
+	case hasFile(byPath, path.Join(dir, "yarn.lock")):
+		return "yarn"
+	}
+	return "npm"
+}
+
+func pythonVersion(byPath map[string]string, dir string) string {
+	if m := requiresPython.FindStringSubmatch(byPath[path.Join(dir, "pyproject.toml")]); m != nil {
+		return m[1]
+	}
+	if v := versionNumber.FindString(byPath[path.Join(dir, ".python-version")]); strings.Contains(v, ".") {
+		return v
+	}
+	return DefaultPythonVersion
+}
+
+func hasFile(byPath map[string]string, p string) bool {
+	_, ok := byPath[p]
+	return ok
+}
+
+// dockerContext is the build context of a Dockerfile in dir: the nearest
+// stack at or above dir, since Dockerfiles kept under deployment/ copy the
+// project they sit beside, or the project root
+func dockerContext(dir string, stacks []CIStack) string {
+	best := "."
+	for _, s := range stacks {
+		if s.Dir != "." && strings.HasPrefix(dir+"/", s.Dir+"/") && len(s.Dir) > len(best) {
+			best = s.Dir
+		}
+	}
+	return best
+}
+
+// renderCI renders a workflow with a job per stack, building, linting and
+// testing it, and a job building every image once they pass
+func renderCI(stacks []CIStack, images []CIImage) string {
+	var sb strings.Builder
+	sb.WriteString("name: CI\n\n")
+	sb.WriteString("on:\n  push:\n  pull_request:\n\n")
+	sb.WriteString("permissions:\n  contents: read\n")
+	if len(images) > 0 {
+		sb.WriteString("  packages: write\n")
+	}
+	sb.WriteString("\njobs:\n")
+
+	counts := map[string]int{}
+	for _, s := range stacks {
+		counts[s.Kind]++
+	}
+	var jobs []string
+	for _, s := range stacks {
+		id := s.Kind
+		if counts[s.Kind] > 1 && s.Dir != "." {
+			id = s.Kind + "-" + k8sName(s.Dir)
+		}
+		jobs = append(jobs, id)
+		fmt.Fprintf(&sb, "  %s:\n    runs-on: ubuntu-latest\n", id)
+		if s.Dir != "." {
+			fmt.Fprintf(&sb, "    defaults:\n      run:\n        working-directory: %q\n", s.Dir)
+		}
+		sb.WriteString("    steps:\n      - uses: actions/checkout@v4\n")
+		for _, step := range stackSteps(s) {
+			sb.WriteString(step)
+		}
+	}
+
+	if len(images) > 0 {
+		sb.WriteString("  docker:\n    runs-on: ubuntu-latest\n")
+		if len(jobs) > 0 {
+			fmt.Fprintf(&sb, "    needs: [%s]\n", strings.Join(jobs, ", "))
+		}
+		sb.WriteString("    env:\n      PUSH: ${{ github.event_name == 'push' && github.ref_name == github.event.repository.default_branch }}\n")
+		sb.WriteString("    steps:\n      - uses: actions/checkout@v4\n      - uses: docker/setup-buildx-action@v3\n")
+		sb.WriteString("      - name: Log in to GitHub Container Registry\n        if: env.PUSH == 'true'\n        uses: docker/login-action@v3\n" +
+			"        with:\n          registry: ghcr.io\n          username: ${{ github.actor }}\n          password: ${{ secrets.GITHUB_TOKEN }}\n")
+		// Image names must be lowercase; the owner need not be
+		sb.WriteString("      - name: Image owner\n        run: echo \"OWNER=${GITHUB_REPOSITORY_OWNER,,}\" >> \"$GITHUB_ENV\"\n")
+		for _, image := range images {
+			fmt.Fprintf(&sb, "      - name: Build %s\n        uses: docker/build-push-action@v6\n        with:\n", image.Name)
+			fmt.Fprintf(&sb, "          context: %q\n          file: %q\n", image.Context, image.Dockerfile)
+			sb.WriteString("          push: ${{ env.PUSH == 'true' }}\n")
+			fmt.Fprintf(&sb, "          tags: ghcr.io/${{ env.OWNER }}/%s:${{ github.sha }}\n", image.Name)
+		}
+	}
+	return sb.String()
+}
+
+// stackSteps are the steps after checkout that set up, build, lint and test
+// a stack
+func stackSteps(s CIStack) []string {
+	run := func(name, command string) string {
+		return fmt.Sprintf("      - name: %s\n        run: %s\n", name, command)
+	}
+	switch s.Kind {
+	case StackGo:
+		return []string{
+			fmt.Sprintf("      - uses: actions/setup-go@v5\n        with:\n          go-version-file: %q\n", path.Join(s.Dir, "go.mod")),
+			run("Build", "go build ./..."),
+			run("Lint", `test -z "$(gofmt -l .)" && go vet ./...`),
+			run("Test", "go test ./..."),
+		}
+
+	case StackNode:
+		steps := []string{fmt.Sprintf("      - uses: actions/setup-node@v4\n        with:\n          node-version: %q\n", s.Version)}
+		pm := s.PackageManager
+		switch pm {
+		case "pnpm":
+			steps = append(steps, run("Enable pnpm", "corepack enable"), run("Install", "pnpm install --frozen-lockfile"))
+		case "yarn":
+			steps = append(steps, run("Install", "yarn install --frozen-lockfile"))
+		default:
+			steps = append(steps, run("Install", "npm install"))
+		}
+		tested := false
+		for _, script := range s.Scripts {
+			command := pm + " run " + script
+			if script == "test" {
+				command, tested = pm+" test", true
+			}
+			steps = append(steps, run(strings.ToUpper(script[:1])+script[1:], command))
+		}
+		// Generated tests without a test script are run the way the test
+		// stage runs them
+		if !tested && s.Tests {
+			steps = append(steps, run("Test", "npx --yes jest --ci --testMatch '**/?(*.)+(spec|test).[jt]s?(x)' '**/tests/test_*.js'"))
+		}
+		return steps
+
+	case StackPython:
+		steps := []string{fmt.Sprintf("      - uses: actions/setup-python@v5\n        with:\n          python-version: %q\n", s.Version)}
+		switch s.Manifest {
+		case "requirements.txt":
+			steps = append(steps, run("Install", "python -m pip install -r requirements.txt"))
+		case "pyproject.toml":
+			steps = append(steps, run("Install", "python -m pip install ."))
+		}
+		steps = append(steps,
+			run("Build", "python -m compileall -q ."),
+			run("Lint", "python -m pip install ruff && ruff check ."))
+		if s.Tests {
+			steps = append(steps, run("Test", "python -m pip install pytest && python -m pytest"))
+		}
+		return steps
+	}
+	return nil
+}
+
+// ValidateCI parses a GitHub Actions workflow and checks that its jobs are
+// well formed and that the scripts, package scripts, Makefile targets and
+// files its steps reference exist among files
+func ValidateCI(workflow []byte, files []agents.GeneratedFile) []CIIssue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(workflow, &doc); err != nil {
+		return []CIIssue{{Message: fmt.Sprintf("invalid YAML: %v", err)}}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return []CIIssue{{Message: "workflow is not a mapping"}}
+	}
+	root := doc.Content[0]
+	project := newCIProject(files)
+
+	var issues []CIIssue
+	if mappingValue(root, "on") == nil && mappingValue(root, "true") == nil {
+		issues = append(issues, CIIssue{Message: "workflow has no triggers"})
+	}
+	jobs := mappingValue(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode || len(jobs.Content) == 0 {
+		return append(issues, CIIssue{Message: "workflow has no jobs"})
+	}
+
+	ids := map[string]bool{}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		ids[jobs.Content[i].Value] = true
+	}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i].Value, jobs.Content[i+1]
+		issues = append(issues, validateJob(id, job, ids, project)...)
+	}
+	return issues
+}
+
+func validateJob(id string, job *yaml.Node, ids map[string]bool, project *ciProject) []CIIssue {
+	var issues []CIIssue
+	add := func(step, format string, args ...interface{}) {
+		issues = append(issues, CIIssue{Job: id, Step: step, Message: fmt.Sprintf(format, args...)})
+	}
+	if job.Kind != yaml.MappingNode {
+		add("", "job is not a mapping")
+		return issues
+	}
+	if needs := mappingValue(job, "needs"); needs != nil {
+		for _, need := range scalars(needs) {
+			if !ids[need] {
+				add("", "needs unknown job %q", need)
+			}
+		}
+	}
+	// Reusable workflow calls have no steps of their own
+	if mappingValue(job, "uses") != nil {
+		return issues
+	}
+	if mappingValue(job, "runs-on") == nil {
+		add("", "has no runs-on")
+	}
+	steps := mappingValue(job, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+		add("", "has no steps")
+		return issues
+	}
+
+	jobDir := "."
+	if wd := mappingValue(mappingValue(mappingValue(job, "defaults"), "run"), "working-directory"); wd != nil {
+		jobDir = path.Clean(wd.Value)
+	}
+	if !isExpression(jobDir) && !project.hasDir(jobDir) {
+		add("", "working directory %s does not exist", jobDir)
+	}
+
+	for n, step := range steps.Content {
+		name := fmt.Sprintf("step %d", n+1)
+		if v := mappingValue(step, "name"); v != nil {
+			name = v.Value
+		}
+		uses, run := mappingValue(step, "uses"), mappingValue(step, "run")
+		switch {
+		case uses == nil && run == nil:
+			add(name, "has neither uses nor run")
+		case uses != nil && run != nil:
+			add(name, "has both uses and run")
+		case uses != nil:
+			for _, msg := range project.checkAction(uses.Value, mappingValue(step, "with")) {
+				add(name, "%s", msg)
+			}
+		default:
+			dir := jobDir
+			if wd := mappingValue(step, "working-directory"); wd != nil {
+				dir = path.Clean(wd.Value)
+			}
+			for _, msg := range project.checkRun(run.Value, dir) {
+				add(name, "%s", msg)
+			}
+		}
+	}
+	return issues
+}
+
+func scalars(n *yaml.Node) []string {
+	if n.Kind == yaml.ScalarNode {
+		return []string{n.Value}
+	}
+	var values []string
+	for _, item := range n.Content {
+		values = append(values, item.Value)
+	}
+	return values
+}
+
+func isExpression(s string) bool {
+	return strings.Contains(s, "${{")
+}
+
+// ciProject answers what a workflow's steps can find in the checkout
+type ciProject struct {
+	files map[string]string
+	dirs  map[string]bool
+}
+
+func newCIProject(files []agents.GeneratedFile) *ciProject {
+	p := &ciProject{files: make(map[string]string, len(files)), dirs: map[string]bool{".": true}}
+	for _, f := range files {
+		p.files[f.Path] = f.Content
+		for dir := path.Dir(f.Path); dir != "."; dir = path.Dir(dir) {
+			p.dirs[dir] = true
+		}
+	}
+	return p
+}
+
+func (p *ciProject) hasFile(name string) bool {
+	_, ok := p.files[path.Clean(name)]
+	return ok
+}
+
+func (p *ciProject) hasDir(dir string) bool {
+	return p.dirs[path.Clean(dir)]
+}
+
+// checkAction checks the paths an action step is given
+func (p *ciProject) checkAction(uses string, with *yaml.Node) []string {
+	var problems []string
+	input := func(key string) string {
+		if v := mappingValue(with, key); v != nil && !isExpression(v.Value) {
+			return v.Value
+		}
+		return ""
+	}
+	action := strings.Split(uses, "@")[0]
+	switch {
+	case strings.HasPrefix(uses, "./"):
+		if !p.hasFile(path.Join(uses, "action.yml")) && !p.hasFile(path.Join(uses, "action.yaml")) {
+			problems = append(problems, fmt.Sprintf("local action %s has no action.yml", uses))
+		}
+	case action == "docker/build-push-action":
+		if dir := input("context"); dir != "" && !p.hasDir(dir) {
+			problems = append(problems, fmt.Sprintf("build context %s does not exist", dir))
+		}
+		if file := input("file"); file != "" && !p.hasFile(file) {
+			problems = append(problems, fmt.Sprintf("Dockerfile %s does not exist", file))
+		}
+	}
+	for _, key := range []string{"go-version-file", "node-version-file", "python-version-file", "cache-dependency-path"} {
+		if file := input(key); file != "" && !strings.ContainsAny(file, "*\n") && !p.hasFile(file) {
+			problems = append(problems, fmt.Sprintf("%s %s does not exist", key, file))
+		}
+	}
+	return problems
+}
+
+var commandSeparator = regexp.MustCompile(`\s*(?:&&|\|\||;|\n)\s*`)
+
+// checkRun checks each command of a run step, run from dir
+func (p *ciProject) checkRun(script, dir string) []string {
+	if !isExpression(dir) && !p.hasDir(dir) {
+		return []string{fmt.Sprintf("working directory %s does not exist", dir)}
+	}
+	var problems []string
+	for _, command := range commandSeparator.Split(script, -1) {
+		args := strings.Fields(command)
+		if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+			continue
+		}
+		if problem := p.checkCommand(args, dir); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+func (p *ciProject) checkCommand(args []string, dir string) string {
+	arg := func(i int) string {
+		if i < len(args) && !strings.HasPrefix(args[i], "-") && !isExpression(args[i]) {
+			return args[i]
+		}
+		return ""
+	}
+	switch name := args[0]; {
+	case strings.HasPrefix(name, "./"):
+		if !p.hasFile(path.Join(dir, name)) {
+			return fmt.Sprintf("script %s does not exist", path.Join(dir, name))
+		}
+	case name == "bash" || name == "sh" || name == "python" || name == "python3" || name == "node":
+		if script := arg(1); script != "" && strings.Contains(script, ".") && !p.hasFile(path.Join(dir, script)) {
+			return fmt.Sprintf("script %s does not exist", path.Join(dir, script))
+		}
+		if (name == "python" || name == "python3") && arg(1) == "" && len(args) > 3 && args[1] == "-m" && args[2] == "pip" {
+			return p.checkPip(args[3:], dir)
+		}
+	case name == "pip" || name == "pip3":
+		return p.checkPip(args[1:], dir)
+	case name == "go":
+		for d := dir; ; d = path.Dir(d) {
+			if p.hasFile(path.Join(d, "go.mod")) {
+				return ""
+			}
+			if d == "." {
+				return fmt.Sprintf("go runs in %s, which has no go.mod", dir)
+			}
+		}
+	case name == "make":
+		makefile, ok := p.files[path.Join(dir, "Makefile")]
+		if !ok {
+			return fmt.Sprintf("%s has no Makefile", dir)
+		}
+		if target := arg(1); target != "" && !strings.Contains(target, "=") &&
+			!regexp.MustCompile(`(?m)^`+regexp.QuoteMeta(target)+`\s*:`).MatchString(makefile) {
+			return fmt.Sprintf("Makefile has no %s target", target)
+		}
+	case name == "npm" || name == "yarn" || name == "pnpm":
+		return p.checkPackageScript(name, args[1:], dir)
+	}
+	return ""
+}
+
+func (p *ciProject) checkPip(args []string, dir string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-r" && !isExpression(args[i+1]) && !p.hasFile(path.Join(dir, args[i+1])) {
+			return fmt.Sprintf("requirements file %s does not exist", path.Join(dir, args[i+1]))
+		}
+	}
+	return ""
+}
+
+// checkPackageScript checks a package manager command against the
+// package.json in dir
+func (p *ciProject) checkPackageScript(pm string, args []string, dir string) string {
+	manifest, ok := p.files[path.Join(dir, "package.json")]
+	if !ok {
+		return fmt.Sprintf("%s runs in %s, which has no package.json", pm, dir)
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	script := ""
+	switch args[0] {
+	case "ci":
+		if pm == "npm" && !p.hasFile(path.Join(dir, "package-lock.json")) && !p.hasFile(path.Join(dir, "npm-shrinkwrap.json")) {
+			return "npm ci needs a package-lock.json in " + dir
+		}
+	case "test", "t":
+		script = "test"
+	case "run", "run-script":
+		if len(args) > 1 {
+			script = args[1]
+		}
+	case "lint", "build":
+		if pm == "yarn" {
+			script = args[0]
+		}
+	}
+	if script == "" {
+		return ""
+	}
+	var pkg packageJSON
+	if err := json.Unmarshal([]byte(manifest), &pkg); err != nil {
+		return fmt.Sprintf("%s is not valid JSON", path.Join(dir, "package.json"))
+	}
+	if _, ok := pkg.Scripts[script]; !ok {
+		return fmt.Sprintf("%s has no %q script", path.Join(dir, "package.json"), script)
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+var ciProjectFiles = []agents.GeneratedFile{
+	{Path: "api/go.mod", Content: "module example.com/api\n\ngo 1.23\n"},
+	{Path: "api/main.go", Content: "package main\n"},
+	{Path: "api/main_test.go", Content: "package main\n"},
+	{Path: "api/Dockerfile", Content: "FROM golang:1.23\n"},
+	{Path: "web/package.json", Content: `{"scripts": {"build": "vite build", "lint": "eslint ."}, "engines": {"node": ">=18"}}`},
+	{Path: "web/yarn.lock", Content: ""},
+	{Path: "web/src/app.test.js", Content: "test('renders', () => {})\n"},
+	{Path: "worker/requirements.txt", Content: "redis\n"},
+	{Path: "worker/pyproject.toml", Content: "[tool.ruff]\nrequires-python = \">=3.11\"\n"},
+	{Path: "worker/test_jobs.py", Content: "def test_jobs():\n    pass\n"},
+	{Path: "deployment/Dockerfile.worker", Content: "FROM python:3.11\n"},
+	{Path: "web/node_modules/left-pad/package.json", Content: `{}`},
+}
+
+func TestDetectCI(t *testing.T) {
+	stacks, images := DetectCI(ciProjectFiles)
+	assert.Equal(t, []CIStack{
+		{Kind: StackGo, Dir: "api", Version: "1.23", Tests: true},
+		{Kind: StackNode, Dir: "web", Version: "18", PackageManager: "yarn", Scripts: []string{"lint", "build"}, Tests: true},
+		{Kind: StackPython, Dir: "worker", Version: "3.11", Manifest: "requirements.txt", Tests: true},
+	}, stacks)
+	assert.Equal(t, []CIImage{
+		{Name: "api", Dockerfile: "api/Dockerfile", Context: "api"},
+		{Name: "worker", Dockerfile: "deployment/Dockerfile.worker", Context: "."},
+	}, images)
+}
+
+func TestDetectCI_WorkspacePackagesBuildFromTheRoot(t *testing.T) {
+	stacks, _ := DetectCI([]agents.GeneratedFile{
+		{Path: "apps/web/package.json", Content: `{"scripts": {"test": "vitest"}}`},
+		{Path: "package.json", Content: `{"workspaces": ["apps/*"], "scripts": {"test": "turbo test"}}`},
+		{Path: "package-lock.json", Content: "{}"},
+	})
+	assert.Equal(t, []CIStack{{Kind: StackNode, Dir: ".", Version: DefaultNodeVersion, PackageManager: "npm", Scripts: []string{"test"}}}, stacks)
+}
+
+func TestBuildCI_GeneratesAValidWorkflow(t *testing.T) {
+	report, workflow, err := BuildCI(ciProjectFiles)
+	require.NoError(t, err)
+	require.NotNil(t, workflow)
+	assert.Equal(t, CIWorkflowPath, workflow.Path)
+	assert.True(t, report.Generated)
+	assert.Empty(t, report.Issues, workflow.Content)
+
+	var doc struct {
+		On   map[string]interface{} `yaml:"on"`
+		Jobs map[string]struct {
+			Needs    []string `yaml:"needs"`
+			Defaults struct {
+				Run struct {
+					WorkingDirectory string `yaml:"working-directory"`
+				} `yaml:"run"`
+			} `yaml:"defaults"`
+			Steps []struct {
+				Uses string            `yaml:"uses"`
+				Run  string            `yaml:"run"`
+				With map[string]string `yaml:"with"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(workflow.Content), &doc))
+	assert.Contains(t, doc.On, "push")
+	assert.Contains(t, doc.On, "pull_request")
+	require.Len(t, doc.Jobs, 4)
+	assert.Equal(t, []string{"go", "node", "python"}, doc.Jobs["docker"].Needs)
+	assert.Equal(t, "web", doc.Jobs["node"].Defaults.Run.WorkingDirectory)
+
+	var runs []string
+	for _, step := range doc.Jobs["node"].Steps {
+		runs = append(runs, step.Run)
+	}
+	assert.Contains(t, runs, "yarn install --frozen-lockfile")
+	assert.Contains(t, runs, "yarn run lint")
+	assert.NotContains(t, runs, "yarn test", "the package has no test script")
+	assert.Equal(t, "api/go.mod", doc.Jobs["go"].Steps[1].With["go-version-file"])
+	assert.Equal(t, "deployment/Dockerfile.worker", doc.Jobs["docker"].Steps[len(doc.Jobs["docker"].Steps)-1].With["file"])
+}
+
+func TestBuildCI_KeepsAValidAgentWorkflow(t *testing.T) {
+	files := append([]agents.GeneratedFile{{Path: CIWorkflowPath, Content: `on: [push]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: go test ./...
+        working-directory: api
+`}}, ciProjectFiles...)
+
+	report, workflow, err := BuildCI(files)
+	require.NoError(t, err)
+	assert.Nil(t, workflow)
+	assert.False(t, report.Generated)
+	assert.Empty(t, report.Replaced)
+}
+
+func TestBuildCI_ReplacesAnInvalidAgentWorkflow(t *testing.T) {
+	files := append([]agents.GeneratedFile{{Path: CIWorkflowPath, Content: "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: ./scripts/test.sh\n"}}, ciProjectFiles...)
+
+	report, workflow, err := BuildCI(files)
+	require.NoError(t, err)
+	require.NotNil(t, workflow)
+	assert.True(t, report.Generated)
+	assert.Equal(t, []CIIssue{{Job: "test", Step: "step 1", Message: "script scripts/test.sh does not exist"}}, report.Replaced)
+}
+
+func TestBuildCI_NoStack(t *testing.T) {
+	_, _, err := BuildCI([]agents.GeneratedFile{{Path: "README.md", Content: "# Notes"}})
+	assert.ErrorIs(t, err, ErrNoStack)
+}
+
+func TestValidateCI(t *testing.T) {
+	files := []agents.GeneratedFile{
+		{Path: "package.json", Content: `{"scripts": {"test": "jest"}}`},
+		{Path: "Makefile", Content: "build:\n\tgo build ./...\n"},
+		{Path: "scripts/deploy.sh", Content: "#!/bin/sh\n"},
+	}
+	workflow := `name: CI
+on:
+  push:
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    needs: lint
+    steps:
+      - uses: actions/checkout@v4
+      - run: npm ci && npm test
+      - run: npm run lint
+      - run: make build && make release
+      - run: ./scripts/deploy.sh; bash scripts/missing.sh
+      - run: pip install -r requirements.txt
+      - run: go build ./...
+      - name: Image
+        uses: docker/build-push-action@v6
+        with:
+          context: services/api
+          file: ${{ matrix.dockerfile }}
+      - name: Nothing
+        with:
+          x: y
+  docs:
+    uses: ./.github/workflows/docs.yml
+`
+	var messages []string
+	for _, issue := range ValidateCI([]byte(workflow), files) {
+		messages = append(messages, issue.Job+"/"+issue.Step+": "+issue.Message)
+	}
+	assert.Equal(t, []string{
+		`build/: needs unknown job "lint"`,
+		"build/step 2: npm ci needs a package-lock.json in .",
+		`build/step 3: package.json has no "lint" script`,
+		"build/step 4: Makefile has no release target",
+		"build/step 5: script scripts/missing.sh does not exist",
+		"build/step 6: requirements file requirements.txt does not exist",
+		"build/step 7: go runs in ., which has no go.mod",
+		"build/Image: build context services/api does not exist",
+		"build/Nothing: has neither uses nor run",
+	}, messages)
+
+	assert.Equal(t, []CIIssue{{Message: "workflow has no jobs"}}, ValidateCI([]byte("on: push\n"), files))
+	assert.Equal(t, "workflow has no triggers", ValidateCI([]byte("jobs: {}\n"), files)[0].Message)
+	assert.Len(t, ValidateCI([]byte("jobs: [\n"), files), 1)
+}
@@ -0,0 +1,297 @@
+package deployment
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// EnvReference is a place generated code reads an environment variable
+type EnvReference struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// EnvVar is an environment variable used or defined by a generated project
+type EnvVar struct {
+	Name       string         `json:"name"`
+	Secret     bool           `json:"secret"`
+	Default    string         `json:"default,omitempty"`
+	References []EnvReference `json:"references,omitempty"`
+	DefinedIn  []string       `json:"defined_in,omitempty"`
+}
+
+// EnvReport lists variables code reads that nothing defines, and variables
+// defined for the deployment that no code reads
+type EnvReport struct {
+	Undefined []string `json:"undefined"`
+	Unused    []string `json:"unused"`
+}
+
+// EnvManifest is the canonical environment of a generated project
+type EnvManifest struct {
+	Vars       []EnvVar  `json:"vars"`
+	EnvExample string    `json:"env_example"`
+	Secret     string    `json:"secret"`
+	ConfigMap  string    `json:"config_map"`
+	Report     EnvReport `json:"report"`
+}
+
+// Patterns for reading a variable in code. The first group is the name and
+// the optional second group a literal default.
+var envUsagePatterns = []*regexp.Regexp{
+	// Go
+	regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\("([A-Za-z_][A-Za-z0-9_]*)"\)()`),
+	regexp.MustCompile(`(?:^|[^.\w])get[eE]nv(?:OrDefault)?\("([A-Za-z_][A-Za-z0-9_]*)",\s*"([^"]*)"\)`),
+	// JavaScript and TypeScript
+	regexp.MustCompile(`process\.env\.([A-Za-z_][A-Za-z0-9_]*)(?:\s*(?:\|\||\?\?)\s*['"]([^'"]*)['"])?`),
+	regexp.MustCompile(`process\.env\[['"]([A-Za-z_][A-Za-z0-9_]*)['"]\](?:\s*(?:\|\||\?\?)\s*['"]([^'"]*)['"])?`),
+	regexp.MustCompile(`import\.meta\.env\.([A-Za-z_][A-Za-z0-9_]*)()`),
+	// Python
+	regexp.MustCompile(`os\.environ\[['"]([A-Za-z_][A-Za-z0-9_]*)['"]\]()`),
+	regexp.MustCompile(`os\.(?:environ\.get|getenv)\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"](?:\s*,\s*['"]([^'"]*)['"])?`),
+	// Ruby
+	regexp.MustCompile(`ENV(?:\.fetch\(|\[)['"]([A-Za-z_][A-Za-z0-9_]*)['"](?:\s*,\s*['"]([^'"]*)['"])?`),
+}
+
+var (
+	dotenvLine     = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	dockerfileEnv  = regexp.MustCompile(`^\s*ENV\s+([A-Za-z_][A-Za-z0-9_]*)(?:\s*=\s*|\s+)(.*)$`)
+	composeEnvItem = regexp.MustCompile(`^\s*-\s*['"]?([A-Za-z_][A-Za-z0-9_]*)=([^'"]*)['"]?\s*$`)
+	composeEnvKey  = regexp.MustCompile(`^\s+([A-Za-z_][A-Za-z0-9_]*):\s*['"]?([^'"]*)['"]?\s*$`)
+	k8sEnvName     = regexp.MustCompile(`^\s*-\s*name:\s*['"]?([A-Z_][A-Z0-9_]*)['"]?\s*$`)
+)
+
+// secretMarkers flag variable names whose values are credentials
+var secretMarkers = []string{
+	"SECRET", "PASSWORD", "PASSWD", "TOKEN", "API_KEY", "APIKEY", "PRIVATE_KEY",
+	"ACCESS_KEY", "CREDENTIAL", "DATABASE_URL", "DSN",
+}
+
+// IsSecretEnv reports whether a variable's value should be kept in a
+// Secret rather than a ConfigMap
+func IsSecretEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range secretMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanEnv collects the variables generated files read and define. Code
+// files contribute references; .env files, Dockerfiles, docker-compose
+// and Kubernetes manifests contribute definitions.
+func ScanEnv(files []agents.GeneratedFile) []EnvVar {
+	vars := make(map[string]*EnvVar)
+	get := func(name string) *EnvVar {
+		v, ok := vars[name]
+		if !ok {
+			v = &EnvVar{Name: name, Secret: IsSecretEnv(name)}
+			vars[name] = v
+		}
+		return v
+	}
+	define := func(name, value, file string) {
+		v := get(name)
+		if len(v.DefinedIn) == 0 || v.DefinedIn[len(v.DefinedIn)-1] != file {
+			v.DefinedIn = append(v.DefinedIn, file)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if v.Default == "" && !v.Secret && !strings.Contains(value, "${") {
+			v.Default = value
+		}
+	}
+
+	for _, f := range files {
+		switch kind := envFileKind(f.Path); kind {
+		case "dotenv", "dockerfile", "compose", "k8s":
+			scanEnvDefinitions(f, kind, define)
+		default:
+			for i, line := range strings.Split(f.Content, "\n") {
+				for _, pattern := range envUsagePatterns {
+					for _, m := range pattern.FindAllStringSubmatch(line, -1) {
+						v := get(m[1])
+						v.References = append(v.References, EnvReference{Path: f.Path, Line: i + 1})
+						if v.Default == "" && !v.Secret && len(m) > 2 {
+							v.Default = m[2]
+						}
+					}
+				}
+			}
+		}
+	}
+
+	out := make([]EnvVar, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// envFileKind classifies a file by where variables are defined in it
+func envFileKind(p string) string {
+	base := strings.ToLower(path.Base(p))
+	switch {
+	case base == ".env" || strings.HasPrefix(base, ".env."):
+		return "dotenv"
+	case base == "dockerfile" || strings.HasSuffix(base, ".dockerfile"):
+		return "dockerfile"
+	case strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose."):
+		return "compose"
+	case strings.HasSuffix(base, ".yaml") || strings.HasSuffix(base, ".yml"):
+		return "k8s"
+	}
+	return "code"
+}
+
+func scanEnvDefinitions(f agents.GeneratedFile, kind string, define func(name, value, file string)) {
+	inEnvironment := false
+	for _, line := range strings.Split(f.Content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		switch kind {
+		case "dotenv":
+			if m := dotenvLine.FindStringSubmatch(line); m != nil {
+				define(m[1], m[2], f.Path)
+			}
+		case "dockerfile":
+			if m := dockerfileEnv.FindStringSubmatch(line); m != nil {
+				define(m[1], m[2], f.Path)
+			}
+		case "compose":
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case trimmed == "environment:":
+				inEnvironment = true
+				continue
+			case inEnvironment && !strings.HasPrefix(trimmed, "-") && strings.HasSuffix(trimmed, ":"):
+				inEnvironment = false
+			}
+			if !inEnvironment {
+				continue
+			}
+			if m := composeEnvItem.FindStringSubmatch(line); m != nil {
+				define(m[1], m[2], f.Path)
+			} else if m := composeEnvKey.FindStringSubmatch(line); m != nil {
+				define(m[1], m[2], f.Path)
+			}
+		case "k8s":
+			if m := k8sEnvName.FindStringSubmatch(line); m != nil {
+				define(m[1], "", f.Path)
+			}
+		}
+	}
+}
+
+// BuildEnvManifest scans files and renders the canonical .env.example,
+// Kubernetes Secret and ConfigMap for project, plus the validation report.
+// Secret values are never copied from the scanned files.
+func BuildEnvManifest(project string, files []agents.GeneratedFile) *EnvManifest {
+	vars := ScanEnv(files)
+	m := &EnvManifest{
+		Vars:   vars,
+		Report: EnvReport{Undefined: []string{}, Unused: []string{}},
+	}
+	for _, v := range vars {
+		switch {
+		case len(v.References) > 0 && len(v.DefinedIn) == 0:
+			m.Report.Undefined = append(m.Report.Undefined, v.Name)
+		case len(v.References) == 0 && len(v.DefinedIn) > 0:
+			m.Report.Unused = append(m.Report.Unused, v.Name)
+		}
+	}
+
+	name := k8sName(project)
+	m.EnvExample = renderEnvExample(vars)
+	m.Secret = renderK8sEnv("Secret", name+"-secrets", "stringData", vars, true)
+	m.ConfigMap = renderK8sEnv("ConfigMap", name+"-config", "data", vars, false)
+	return m
+}
+
+// Files returns the manifest as files to write into the project
+func (m *EnvManifest) Files() []agents.GeneratedFile {
+	return []agents.GeneratedFile{
+		{Path: ".env.example", Content: m.EnvExample, Type: "env"},
+		{Path: "deployment/k8s-secret.yaml", Content: m.Secret, Type: "yaml"},
+		{Path: "deployment/k8s-configmap.yaml", Content: m.ConfigMap, Type: "yaml"},
+	}
+}
+
+func renderEnvExample(vars []EnvVar) string {
+	var sb strings.Builder
+	sb.WriteString("# Generated from the environment variables the project reads.\n")
+	sb.WriteString("# Copy to .env and fill in the secrets.\n")
+	for _, section := range []struct {
+		title  string
+		secret bool
+	}{{"Configuration", false}, {"Secrets", true}} {
+		first := true
+		for _, v := range vars {
+			if v.Secret != section.secret || len(v.References) == 0 {
+				continue
+			}
+			if first {
+				fmt.Fprintf(&sb, "\n# %s\n", section.title)
+				first = false
+			}
+			ref := v.References[0]
+			fmt.Fprintf(&sb, "# Used in %s:%d\n", ref.Path, ref.Line)
+			value := v.Default
+			if v.Secret {
+				value = ""
+			}
+			fmt.Fprintf(&sb, "%s=%s\n", v.Name, value)
+		}
+	}
+	return sb.String()
+}
+
+func renderK8sEnv(kind, name, field string, vars []EnvVar, secret bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "apiVersion: v1\nkind: %s\nmetadata:\n  name: %s\n", kind, name)
+	if secret {
+		sb.WriteString("type: Opaque\n")
+	}
+
+	var entries []EnvVar
+	for _, v := range vars {
+		if v.Secret == secret && len(v.References) > 0 {
+			entries = append(entries, v)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(&sb, "%s: {}\n", field)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "%s:\n", field)
+	for _, v := range entries {
+		value := v.Default
+		if secret {
+			value = "REPLACE_ME"
+		}
+		fmt.Fprintf(&sb, "  %s: %q\n", v.Name, value)
+	}
+	return sb.String()
+}
+
+var k8sNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// k8sName turns a project name into a valid Kubernetes resource name
+func k8sName(project string) string {
+	name := strings.Trim(k8sNameInvalid.ReplaceAllString(strings.ToLower(project), "-"), "-")
+	if name == "" {
+		name = "app"
+	}
+	if len(name) > 40 {
+		name = strings.TrimRight(name[:40], "-")
+	}
+	return name
+}
@@ -0,0 +1,68 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+var envProject = []agents.GeneratedFile{
+	{Path: "main.go", Content: "port := getEnv(\"PORT\", \"8080\")\ndsn := os.Getenv(\"DATABASE_URL\")\n"},
+	{Path: "web/api.js", Content: "const base = process.env.API_BASE_URL || 'http://localhost:8080'\nconst key = process.env['STRIPE_API_KEY']\n"},
+	{Path: "worker/app.py", Content: "level = os.getenv(\"LOG_LEVEL\", \"info\")\n"},
+	{Path: ".env", Content: "PORT=8080\nDATABASE_URL=postgres://admin:hunter2@db/app\nLEGACY_FLAG=true\n"},
+	{Path: "deployment/k8s-deployment.yaml", Content: "env:\n  - name: LOG_LEVEL\n    value: debug\n"},
+}
+
+func TestScanEnv(t *testing.T) {
+	vars := ScanEnv(envProject)
+
+	byName := make(map[string]EnvVar)
+	for _, v := range vars {
+		byName[v.Name] = v
+	}
+	require.Len(t, byName, 6)
+
+	assert.Equal(t, "8080", byName["PORT"].Default)
+	assert.Equal(t, []string{".env"}, byName["PORT"].DefinedIn)
+	assert.Equal(t, []EnvReference{{Path: "main.go", Line: 1}}, byName["PORT"].References)
+	assert.Equal(t, "http://localhost:8080", byName["API_BASE_URL"].Default)
+	assert.Equal(t, "info", byName["LOG_LEVEL"].Default)
+	assert.Len(t, byName["LOG_LEVEL"].References, 1, "os.getenv matches only the Python pattern")
+
+	assert.True(t, byName["DATABASE_URL"].Secret)
+	assert.True(t, byName["STRIPE_API_KEY"].Secret)
+	assert.Empty(t, byName["DATABASE_URL"].Default, "secret values are never kept")
+}
+
+func TestBuildEnvManifest(t *testing.T) {
+	m := BuildEnvManifest("My Shop!", envProject)
+
+	assert.Equal(t, []string{"API_BASE_URL", "STRIPE_API_KEY"}, m.Report.Undefined)
+	assert.Equal(t, []string{"LEGACY_FLAG"}, m.Report.Unused)
+
+	assert.Contains(t, m.EnvExample, "PORT=8080\n")
+	assert.Contains(t, m.EnvExample, "DATABASE_URL=\n")
+	assert.NotContains(t, m.EnvExample, "LEGACY_FLAG")
+	assert.NotContains(t, m.EnvExample+m.Secret+m.ConfigMap, "hunter2")
+
+	assert.Contains(t, m.Secret, "kind: Secret\nmetadata:\n  name: my-shop-secrets\n")
+	assert.Contains(t, m.Secret, "  STRIPE_API_KEY: \"REPLACE_ME\"\n")
+	assert.NotContains(t, m.Secret, "PORT")
+	assert.Contains(t, m.ConfigMap, "  LOG_LEVEL: \"info\"\n")
+	assert.NotContains(t, m.ConfigMap, "DATABASE_URL")
+
+	files := m.Files()
+	require.Len(t, files, 3)
+	assert.Equal(t, ".env.example", files[0].Path)
+}
+
+func TestBuildEnvManifest_Empty(t *testing.T) {
+	m := BuildEnvManifest("", nil)
+	assert.Empty(t, m.Report.Undefined)
+	assert.Contains(t, m.Secret, "name: app-secrets")
+	assert.Contains(t, m.ConfigMap, "data: {}\n")
+}
@@ -0,0 +1,74 @@
+package deployment
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// Artifact kinds the deployment agent's output is sniffed for
+const (
+	OutputDockerfile     = "dockerfile"
+	OutputDockerCompose  = "docker-compose"
+	OutputKubernetes     = "kubernetes"
+	OutputGitHubWorkflow = "github-workflow"
+)
+
+var (
+	dockerInstruction = regexp.MustCompile(`(?i)^(FROM|ARG)\s+\S`)
+	yamlServices      = regexp.MustCompile(`(?m)^services:\s*$`)
+	yamlAPIVersion    = regexp.MustCompile(`(?m)^apiVersion:\s*\S`)
+	yamlKind          = regexp.MustCompile(`(?m)^kind:\s*\S`)
+	yamlOn            = regexp.MustCompile(`(?m)^["']?on["']?:`)
+	yamlJobs          = regexp.MustCompile(`(?m)^jobs:\s*$`)
+)
+
+// OutputHandlers saves Dockerfiles, Compose files, Kubernetes manifests and
+// GitHub Actions workflows found in the agent's output
+func (a *DeploymentAgent) OutputHandlers() []agents.OutputHandler {
+	return []agents.OutputHandler{
+		{
+			Kind:   OutputDockerfile,
+			Detect: isDockerfile,
+			Path:   agents.FixedPath("deployment/Dockerfile"),
+		},
+		{
+			Kind: OutputDockerCompose,
+			Detect: func(b agents.OutputBlock) bool {
+				return yamlServices.MatchString(b.Content) && !yamlJobs.MatchString(b.Content)
+			},
+			Path: agents.FixedPath("deployment/docker-compose.yml"),
+		},
+		{
+			Kind: OutputKubernetes,
+			Detect: func(b agents.OutputBlock) bool {
+				return yamlAPIVersion.MatchString(b.Content) && yamlKind.MatchString(b.Content)
+			},
+			Path: agents.FixedPath("deployment/k8s-deployment.yaml"),
+		},
+		{
+			Kind: OutputGitHubWorkflow,
+			Detect: func(b agents.OutputBlock) bool {
+				return yamlOn.MatchString(b.Content) && yamlJobs.MatchString(b.Content)
+			},
+			Path: agents.FixedPath(CIWorkflowPath),
+		},
+	}
+}
+
+// isDockerfile reports whether the block is fenced as a Dockerfile or its
+// first instruction is FROM, or an ARG ahead of it
+func isDockerfile(b agents.OutputBlock) bool {
+	if b.Lang == "dockerfile" || b.Lang == "docker" {
+		return true
+	}
+	for _, line := range strings.Split(b.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return dockerInstruction.MatchString(line)
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+const deploymentOutput = "## Dockerfile\n\n```\n# syntax=docker/dockerfile:1\nARG GO_VERSION=1.23\nFROM golang:${GO_VERSION}\n```\n\n" +
+	"## Local stack\n\n```yaml\nversion: \"3.9\"\nservices:\n  api:\n    build: .\n```\n\n" +
+	"## Kubernetes\n\n```yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n```\n\n" +
+	"## CI\n\n```yaml\nname: ci\non:\n  push:\njobs:\n  build:\n    runs-on: ubuntu-latest\n    services:\n      postgres:\n        image: postgres:16\n```\n\n" +
+	"## Notes\n\n```bash\nkubectl apply -f deployment/\n```\n"
+
+func TestDeploymentAgent_OutputHandlers(t *testing.T) {
+	registry := agents.NewOutputRegistry()
+	require.NoError(t, registry.RegisterAgent(New(nil)))
+
+	var paths []string
+	for _, f := range registry.Files(agents.DeploymentAgent, deploymentOutput) {
+		paths = append(paths, f.Kind+" "+f.Path)
+	}
+	assert.Equal(t, []string{
+		"dockerfile deployment/Dockerfile",
+		"docker-compose deployment/docker-compose.yml",
+		"kubernetes deployment/k8s-deployment.yaml",
+		"github-workflow .github/workflows/ci.yml",
+	}, paths, "the shell snippet is not an artifact")
+}
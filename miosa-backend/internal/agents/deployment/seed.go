@@ -0,0 +1,549 @@
+package deployment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/sqlschema"
+)
+
+// MaxSeedRows caps the rows generated for one table
+const MaxSeedRows = 1000
+
+// ErrNoSchema is returned when a project declares no tables to seed
+var ErrNoSchema = errors.New("no CREATE TABLE statements found")
+
+// ErrNoPostgresService is returned when a compose file has no Postgres
+// service to seed
+var ErrNoPostgresService = errors.New("no postgres service in compose file")
+
+// SeedConfig controls generated seed data
+type SeedConfig struct {
+	Rows      int            `json:"rows"`       // Rows per table
+	TableRows map[string]int `json:"table_rows"` // Per-table overrides of Rows
+	RandSeed  int64          `json:"seed"`       // The same seed always produces the same data
+}
+
+// DefaultSeedConfig returns the default seed configuration
+func DefaultSeedConfig() *SeedConfig {
+	return &SeedConfig{Rows: 10, RandSeed: 1}
+}
+
+// rows returns the row count for table
+func (c *SeedConfig) rows(table string) int {
+	n := c.Rows
+	if override, ok := c.TableRows[table]; ok {
+		n = override
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n > MaxSeedRows {
+		n = MaxSeedRows
+	}
+	return n
+}
+
+// SeedData is generated data for every table of a schema
+type SeedData struct {
+	Tables     []string                            `json:"tables"` // Insertion order, referenced tables first
+	Rows       map[string][]map[string]interface{} `json:"rows"`
+	Migrations []string                            `json:"migrations"` // Schema files applied before seeding
+	SQL        string                              `json:"-"`
+	Script     string                              `json:"-"`
+}
+
+// GenerateSeed reads the project's SQL schema and generates rows for each
+// table. Foreign keys point at generated rows of the referenced table, so
+// the data loads in order without violating constraints.
+func GenerateSeed(files []agents.GeneratedFile, config *SeedConfig) (*SeedData, error) {
+	if config == nil {
+		config = DefaultSeedConfig()
+	}
+
+	schema := sqlschema.New()
+	data := &SeedData{Rows: make(map[string][]map[string]interface{})}
+	for _, f := range schemaFiles(files) {
+		schema.AddFile(f.Path, f.Content)
+		data.Migrations = append(data.Migrations, f.Path)
+	}
+	if len(schema.Tables) == 0 {
+		return nil, ErrNoSchema
+	}
+
+	order, err := seedOrder(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(config.RandSeed))
+	generated := make(map[string][]map[string]interface{})
+	for _, t := range order {
+		rows := make([]map[string]interface{}, config.rows(t.Name))
+		for i := range rows {
+			row := make(map[string]interface{})
+			for _, c := range t.Columns {
+				if c.Computed {
+					continue
+				}
+				row[c.Name] = seedValue(rng, schema, generated, t, c, i, rows[:i])
+			}
+			rows[i] = row
+		}
+		generated[t.Name] = rows
+		data.Tables = append(data.Tables, t.Name)
+		data.Rows[t.Name] = rows
+	}
+
+	data.SQL = renderSeedSQL(schema, data)
+	data.Script = renderSeedScript(order[0].Name, data.Migrations)
+	return data, nil
+}
+
+// schemaFiles returns the project's up migrations and schema scripts in
+// the order they apply
+func schemaFiles(files []agents.GeneratedFile) []agents.GeneratedFile {
+	var out []agents.GeneratedFile
+	for _, f := range files {
+		p := strings.ToLower(f.Path)
+		if !strings.HasSuffix(p, ".sql") || strings.HasSuffix(p, ".down.sql") || strings.HasSuffix(p, "_down.sql") || strings.HasPrefix(p, "seed/") {
+			continue
+		}
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// seedOrder sorts tables so each comes after the tables it references.
+// Self-references are allowed; other cycles must be broken by a nullable key.
+func seedOrder(schema *sqlschema.Schema) ([]*sqlschema.Table, error) {
+	var order []*sqlschema.Table
+	placed := make(map[string]bool)
+	for len(order) < len(schema.Tables) {
+		progress := false
+		for _, t := range schema.Tables {
+			if placed[t.Name] || !seedReady(t, schema, placed, true) {
+				continue
+			}
+			order = append(order, t)
+			placed[t.Name] = true
+			progress = true
+		}
+		if progress {
+			continue
+		}
+
+		// Every remaining table waits on another; take the first whose
+		// unmet references are all nullable and leave those NULL
+		var stuck []string
+		for _, t := range schema.Tables {
+			if placed[t.Name] {
+				continue
+			}
+			if seedReady(t, schema, placed, false) {
+				order = append(order, t)
+				placed[t.Name] = true
+				progress = true
+				break
+			}
+			stuck = append(stuck, t.Name)
+		}
+		if !progress {
+			return nil, fmt.Errorf("tables %s reference each other through required foreign keys", strings.Join(stuck, ", "))
+		}
+	}
+	return order, nil
+}
+
+// seedReady reports whether the tables t references are already placed.
+// With strict false, unplaced references on nullable columns are allowed.
+func seedReady(t *sqlschema.Table, schema *sqlschema.Schema, placed map[string]bool, strict bool) bool {
+	for _, c := range t.Columns {
+		if c.References == nil || c.References.Table == t.Name || placed[c.References.Table] || schema.Table(c.References.Table) == nil {
+			continue
+		}
+		if strict || c.NotNull {
+			return false
+		}
+	}
+	return true
+}
+
+var seedEpoch = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// seedValue generates column c of row i. earlier holds the table's rows
+// generated so far, for self-references.
+func seedValue(rng *rand.Rand, schema *sqlschema.Schema, generated map[string][]map[string]interface{}, t *sqlschema.Table, c *sqlschema.Column, i int, earlier []map[string]interface{}) interface{} {
+	if ref := c.References; ref != nil {
+		target := schema.Table(ref.Table)
+		candidates := generated[ref.Table]
+		if ref.Table == t.Name {
+			candidates = earlier
+		}
+		if target == nil || len(candidates) == 0 {
+			return nil
+		}
+		col := ref.Column
+		if col == "" && len(target.PrimaryKey) > 0 {
+			col = target.PrimaryKey[0]
+		}
+		return candidates[rng.Intn(len(candidates))][col]
+	}
+
+	n := i + 1
+	name := c.Name
+	typ := c.Type
+	if labels, ok := schema.Enums[typ]; ok && len(labels) > 0 {
+		return labels[rng.Intn(len(labels))]
+	}
+	switch {
+	case strings.HasSuffix(typ, "[]"):
+		return "{}"
+	case typ == "uuid":
+		return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s.%s.%d", t.Name, name, n))).String()
+	case isIntType(typ):
+		switch {
+		case c.PrimaryKey || c.Unique:
+			return n
+		case name == "age" || strings.HasSuffix(name, "_age"):
+			return 18 + rng.Intn(60)
+		case strings.Contains(name, "count"), strings.Contains(name, "quantity"), strings.Contains(name, "stock"):
+			return 1 + rng.Intn(100)
+		default:
+			return 1 + rng.Intn(1000)
+		}
+	case strings.HasPrefix(typ, "numeric"), strings.HasPrefix(typ, "decimal"), strings.HasPrefix(typ, "real"),
+		strings.HasPrefix(typ, "double"), strings.HasPrefix(typ, "float"), typ == "money":
+		if strings.Contains(name, "rate") || strings.Contains(name, "ratio") {
+			return float64(rng.Intn(100)) / 100
+		}
+		return float64(100+rng.Intn(50000)) / 100
+	case typ == "boolean" || typ == "bool":
+		return rng.Intn(4) != 0
+	case strings.HasPrefix(typ, "timestamp"):
+		return seedEpoch.Add(time.Duration(rng.Intn(365*24)) * time.Hour).Format("2006-01-02 15:04:05Z07:00")
+	case typ == "date":
+		return seedEpoch.AddDate(0, 0, rng.Intn(365)).Format("2006-01-02")
+	case strings.HasPrefix(typ, "time"):
+		return fmt.Sprintf("%02d:%02d:00", 8+rng.Intn(10), rng.Intn(4)*15)
+	case typ == "interval":
+		return fmt.Sprintf("%d days", 1+rng.Intn(30))
+	case typ == "json" || typ == "jsonb":
+		return fmt.Sprintf(`{"seed": %d}`, n)
+	case typ == "inet" || typ == "cidr":
+		return fmt.Sprintf("10.0.%d.%d", n/250, n%250+1)
+	}
+	return truncateSeed(seedText(rng, name, n, c.Unique || c.PrimaryKey), typ)
+}
+
+// isIntType reports whether typ is an integer or serial type
+func isIntType(typ string) bool {
+	switch typ {
+	case "int", "integer", "bigint", "smallint", "int2", "int4", "int8", "serial", "bigserial", "smallserial", "serial4", "serial8":
+		return true
+	}
+	return false
+}
+
+var (
+	firstNames = []string{"Ada", "Grace", "Alan", "Linus", "Margaret", "Ken", "Barbara", "Dennis", "Frances", "Edsger"}
+	lastNames  = []string{"Lovelace", "Hopper", "Turing", "Torvalds", "Hamilton", "Thompson", "Liskov", "Ritchie", "Allen", "Dijkstra"}
+	cities     = []string{"Lisbon", "Toronto", "Nairobi", "Osaka", "Berlin", "Austin", "Melbourne", "Bogotá"}
+	countries  = []string{"PT", "CA", "KE", "JP", "DE", "US", "AU", "CO"}
+	statuses   = []string{"active", "pending", "active", "completed", "cancelled"}
+	words      = []string{"quick", "reliable", "modern", "simple", "secure", "scalable", "friendly", "smart", "bright", "steady"}
+	nouns      = []string{"dashboard", "report", "order", "widget", "project", "invoice", "service", "workspace", "ticket", "plan"}
+)
+
+// seedText generates a realistic value for a text column from its name
+func seedText(rng *rand.Rand, name string, n int, unique bool) string {
+	first := firstNames[rng.Intn(len(firstNames))]
+	last := lastNames[rng.Intn(len(lastNames))]
+	value := ""
+	switch {
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), n)
+	case strings.Contains(name, "password"), strings.Contains(name, "hash"), strings.Contains(name, "token"), strings.Contains(name, "secret"):
+		return fmt.Sprintf("seed-%s-%d", strings.ReplaceAll(name, "_", "-"), n)
+	case name == "first_name" || name == "firstname":
+		value = first
+	case name == "last_name" || name == "lastname" || name == "surname":
+		value = last
+	case strings.Contains(name, "username") || name == "login" || name == "handle":
+		return fmt.Sprintf("%s%d", strings.ToLower(first), n)
+	case name == "name" || strings.HasSuffix(name, "_name") || name == "full_name":
+		value = first + " " + last
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1-555-%04d", n)
+	case strings.Contains(name, "url") || strings.Contains(name, "website") || strings.Contains(name, "avatar") || strings.Contains(name, "image"):
+		return fmt.Sprintf("https://example.com/%s/%d", strings.ReplaceAll(name, "_", "-"), n)
+	case strings.Contains(name, "address") || strings.Contains(name, "street"):
+		value = fmt.Sprintf("%d %s Street", 10+rng.Intn(990), last)
+	case strings.Contains(name, "city"):
+		value = cities[rng.Intn(len(cities))]
+	case strings.Contains(name, "country"):
+		value = countries[rng.Intn(len(countries))]
+	case strings.Contains(name, "zip") || strings.Contains(name, "postal"):
+		value = fmt.Sprintf("%05d", 10000+rng.Intn(89999))
+	case strings.Contains(name, "status") || strings.Contains(name, "state"):
+		value = statuses[rng.Intn(len(statuses))]
+	case strings.Contains(name, "role"):
+		value = []string{"admin", "member", "member", "viewer"}[rng.Intn(4)]
+	case strings.Contains(name, "slug"):
+		return fmt.Sprintf("%s-%s-%d", words[rng.Intn(len(words))], nouns[rng.Intn(len(nouns))], n)
+	case strings.Contains(name, "title") || strings.Contains(name, "subject"):
+		word := words[rng.Intn(len(words))]
+		value = strings.ToUpper(word[:1]) + word[1:] + " " + nouns[rng.Intn(len(nouns))]
+	case strings.Contains(name, "description") || strings.Contains(name, "body") || strings.Contains(name, "content") ||
+		strings.Contains(name, "bio") || strings.Contains(name, "notes") || strings.Contains(name, "comment"):
+		value = fmt.Sprintf("A %s %s for %s %s.", words[rng.Intn(len(words))], nouns[rng.Intn(len(nouns))], first, last)
+	case strings.Contains(name, "currency"):
+		value = []string{"USD", "EUR", "GBP"}[rng.Intn(3)]
+	case strings.Contains(name, "color") || strings.Contains(name, "colour"):
+		value = fmt.Sprintf("#%06x", rng.Intn(0xffffff))
+	default:
+		value = fmt.Sprintf("%s %d", strings.ReplaceAll(name, "_", " "), n)
+		unique = false
+	}
+	if unique {
+		value = fmt.Sprintf("%s %d", value, n)
+	}
+	return value
+}
+
+var typeLength = regexp.MustCompile(`^(?:varchar|character varying|char|character)\((\d+)\)$`)
+
+// truncateSeed cuts text to the column's declared length
+func truncateSeed(value, typ string) string {
+	if m := typeLength.FindStringSubmatch(typ); m != nil {
+		if max, err := strconv.Atoi(m[1]); err == nil && len(value) > max {
+			return value[:max]
+		}
+	}
+	return value
+}
+
+// renderSeedSQL writes the rows as INSERT statements in one transaction.
+// Conflicting rows are skipped so the script can be re-run.
+func renderSeedSQL(schema *sqlschema.Schema, data *SeedData) string {
+	var sb strings.Builder
+	sb.WriteString("-- Seed data generated from the project schema. Re-running skips rows that\n")
+	sb.WriteString("-- already exist.\nBEGIN;\n")
+	for _, name := range data.Tables {
+		rows := data.Rows[name]
+		if len(rows) == 0 {
+			continue
+		}
+		t := schema.Table(name)
+		var cols []string
+		identity := false
+		for _, c := range t.Columns {
+			if c.Computed {
+				continue
+			}
+			cols = append(cols, c.Name)
+			identity = identity || c.Identity
+		}
+
+		fmt.Fprintf(&sb, "\nINSERT INTO %s (%s)", quoteIdent(name), joinIdents(cols))
+		if identity {
+			sb.WriteString(" OVERRIDING SYSTEM VALUE")
+		}
+		sb.WriteString(" VALUES\n")
+		for i, row := range rows {
+			values := make([]string, len(cols))
+			for j, col := range cols {
+				values[j] = sqlLiteral(row[col])
+			}
+			sep := ","
+			if i == len(rows)-1 {
+				sep = "\nON CONFLICT DO NOTHING;"
+			}
+			fmt.Fprintf(&sb, "  (%s)%s\n", strings.Join(values, ", "), sep)
+		}
+
+		// Explicit keys leave sequences behind; move them past the seed rows
+		for _, c := range t.Columns {
+			if c.PrimaryKey && (strings.Contains(c.Type, "serial") || c.Identity) {
+				fmt.Fprintf(&sb, "SELECT setval(pg_get_serial_sequence('%s', '%s'), (SELECT MAX(%s) FROM %s));\n",
+					name, c.Name, quoteIdent(c.Name), quoteIdent(name))
+			}
+		}
+	}
+	sb.WriteString("\nCOMMIT;\n")
+	return sb.String()
+}
+
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func quoteIdent(name string) string {
+	if plainIdent.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func joinIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdent(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	}
+}
+
+// renderSeedScript writes the compose seed service's entrypoint. It applies
+// the schema when the first table is missing, then loads seed/seed.sql.
+func renderSeedScript(probe string, migrations []string) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n# Loads demo data into the compose Postgres service.\nset -e\n\n")
+	sb.WriteString("until pg_isready -q; do sleep 1; done\n\n")
+	fmt.Fprintf(&sb, "if [ -z \"$(psql -tAc \"SELECT to_regclass('%s')\")\" ]; then\n", probe)
+	for _, m := range migrations {
+		fmt.Fprintf(&sb, "  psql -v ON_ERROR_STOP=1 -f '/project/%s'\n", m)
+	}
+	sb.WriteString("fi\n\npsql -v ON_ERROR_STOP=1 -f /project/seed/seed.sql\n")
+	return sb.String()
+}
+
+// Files returns the seed SQL, JSON fixtures and loader script to write
+// into the project
+func (d *SeedData) Files() []agents.GeneratedFile {
+	fixtures, _ := json.MarshalIndent(d.Rows, "", "  ")
+	return []agents.GeneratedFile{
+		{Path: "seed/seed.sql", Content: d.SQL, Type: "sql"},
+		{Path: "seed/fixtures.json", Content: string(fixtures) + "\n", Type: "json"},
+		{Path: "seed/seed.sh", Content: d.Script, Type: "shell"},
+	}
+}
+
+// WireSeedCompose adds a one-shot "seed" service to a docker-compose file
+// that loads the seed data into its Postgres service. projectRoot is the
+// project directory relative to the compose file.
+func WireSeedCompose(compose []byte, projectRoot string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(compose, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, ErrNoPostgresService
+	}
+	services := mappingValue(doc.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, ErrNoPostgresService
+	}
+
+	var dbName string
+	var db *yaml.Node
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		image := mappingValue(services.Content[i+1], "image")
+		if image != nil && strings.Contains(image.Value, "postgres") && services.Content[i].Value != "seed" {
+			dbName, db = services.Content[i].Value, services.Content[i+1]
+			break
+		}
+	}
+	if db == nil {
+		return nil, ErrNoPostgresService
+	}
+
+	env := composeEnvironment(mappingValue(db, "environment"))
+	user := env["POSTGRES_USER"]
+	if user == "" {
+		user = "postgres"
+	}
+	database := env["POSTGRES_DB"]
+	if database == "" {
+		database = user
+	}
+	service := map[string]interface{}{
+		"image":       mappingValue(db, "image").Value,
+		"depends_on":  []string{dbName},
+		"volumes":     []string{path.Clean(projectRoot) + ":/project:ro"},
+		"entrypoint":  []string{"sh", "/project/seed/seed.sh"},
+		"restart":     "no",
+		"environment": map[string]string{"PGHOST": dbName, "PGUSER": user, "PGPASSWORD": env["POSTGRES_PASSWORD"], "PGDATABASE": database},
+	}
+	var node yaml.Node
+	if err := node.Encode(service); err != nil {
+		return nil, fmt.Errorf("failed to encode seed service: %w", err)
+	}
+
+	if existing := mappingValue(services, "seed"); existing != nil {
+		*existing = node
+	} else {
+		services.Content = append(services.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "seed"}, &node)
+	}
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal compose file: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// composeEnvironment reads a service's environment in either its mapping
+// or KEY=value list form
+func composeEnvironment(n *yaml.Node) map[string]string {
+	env := make(map[string]string)
+	if n == nil {
+		return env
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			env[n.Content[i].Value] = n.Content[i+1].Value
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if k, v, ok := strings.Cut(item.Value, "="); ok {
+				env[k] = v
+			}
+		}
+	}
+	return env
+}
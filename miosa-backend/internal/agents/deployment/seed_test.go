@@ -0,0 +1,134 @@
+package deployment
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+var seedSchema = []agents.GeneratedFile{
+	{Path: "migrations/002_orders.up.sql", Content: `CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id),
+    parent_id INT REFERENCES orders,
+    total NUMERIC(10, 2) NOT NULL,
+    status order_status NOT NULL,
+    code VARCHAR(8) UNIQUE
+);`},
+	{Path: "migrations/001_users.up.sql", Content: `CREATE TYPE order_status AS ENUM ('pending', 'shipped');
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`},
+	{Path: "migrations/001_users.down.sql", Content: "DROP TABLE users;"},
+}
+
+func TestGenerateSeed(t *testing.T) {
+	data, err := GenerateSeed(seedSchema, &SeedConfig{Rows: 5, TableRows: map[string]int{"orders": 8}, RandSeed: 7})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"users", "orders"}, data.Tables, "referenced tables load first")
+	assert.Equal(t, []string{"migrations/001_users.up.sql", "migrations/002_orders.up.sql"}, data.Migrations)
+	require.Len(t, data.Rows["users"], 5)
+	require.Len(t, data.Rows["orders"], 8)
+
+	userIDs := map[interface{}]bool{}
+	emails := map[interface{}]bool{}
+	for _, u := range data.Rows["users"] {
+		userIDs[u["id"]] = true
+		emails[u["email"]] = true
+		assert.Contains(t, u["email"], "@example.com")
+	}
+	assert.Len(t, emails, 5, "unique columns get distinct values")
+
+	assert.Nil(t, data.Rows["orders"][0]["parent_id"], "the first row has no earlier row to reference")
+	for i, o := range data.Rows["orders"] {
+		assert.True(t, userIDs[o["user_id"]], "order %d references a seeded user", i)
+		assert.Contains(t, []string{"pending", "shipped"}, o["status"])
+		assert.LessOrEqual(t, len(o["code"].(string)), 8)
+		if parent := o["parent_id"]; parent != nil {
+			assert.Less(t, parent.(int), i+1)
+		}
+	}
+
+	assert.Contains(t, data.SQL, "INSERT INTO users (id, email, name, created_at) VALUES\n")
+	assert.Contains(t, data.SQL, "ON CONFLICT DO NOTHING;")
+	assert.Contains(t, data.SQL, "SELECT setval(pg_get_serial_sequence('orders', 'id'), (SELECT MAX(id) FROM orders));")
+	assert.Contains(t, data.Script, "to_regclass('users')")
+	assert.Contains(t, data.Script, "-f '/project/migrations/001_users.up.sql'")
+
+	again, err := GenerateSeed(seedSchema, &SeedConfig{Rows: 5, TableRows: map[string]int{"orders": 8}, RandSeed: 7})
+	require.NoError(t, err)
+	assert.Equal(t, data.SQL, again.SQL, "the same seed produces the same data")
+
+	files := data.Files()
+	require.Len(t, files, 3)
+	var fixtures map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(files[1].Content), &fixtures))
+	assert.Len(t, fixtures["orders"], 8)
+}
+
+func TestGenerateSeed_Errors(t *testing.T) {
+	_, err := GenerateSeed([]agents.GeneratedFile{{Path: "main.go", Content: "package main"}}, nil)
+	assert.ErrorIs(t, err, ErrNoSchema)
+
+	_, err = GenerateSeed([]agents.GeneratedFile{{Path: "schema.sql", Content: `
+CREATE TABLE a (id INT PRIMARY KEY, b_id INT NOT NULL REFERENCES b);
+CREATE TABLE b (id INT PRIMARY KEY, a_id INT NOT NULL REFERENCES a);`}}, nil)
+	assert.ErrorContains(t, err, "reference each other")
+
+	data, err := GenerateSeed([]agents.GeneratedFile{{Path: "schema.sql", Content: `
+CREATE TABLE a (id INT PRIMARY KEY, b_id INT REFERENCES b);
+CREATE TABLE b (id INT PRIMARY KEY, a_id INT NOT NULL REFERENCES a);`}}, nil)
+	require.NoError(t, err, "a nullable key breaks the cycle")
+	assert.Equal(t, []string{"a", "b"}, data.Tables)
+	assert.Nil(t, data.Rows["a"][0]["b_id"])
+}
+
+func TestWireSeedCompose(t *testing.T) {
+	compose := []byte(`services:
+  api:
+    build: ..
+  db:
+    image: postgres:16-alpine
+    environment:
+      - POSTGRES_USER=shop
+      - POSTGRES_PASSWORD=shop_password
+`)
+	out, err := WireSeedCompose(compose, "..")
+	require.NoError(t, err)
+
+	var parsed struct {
+		Services map[string]yaml.Node `yaml:"services"`
+	}
+	require.NoError(t, yaml.Unmarshal(out, &parsed))
+	var seed struct {
+		Image       string            `yaml:"image"`
+		DependsOn   []string          `yaml:"depends_on"`
+		Volumes     []string          `yaml:"volumes"`
+		Restart     string            `yaml:"restart"`
+		Environment map[string]string `yaml:"environment"`
+	}
+	node := parsed.Services["seed"]
+	require.NoError(t, node.Decode(&seed))
+	assert.Equal(t, "postgres:16-alpine", seed.Image)
+	assert.Equal(t, []string{"db"}, seed.DependsOn)
+	assert.Equal(t, []string{"..:/project:ro"}, seed.Volumes)
+	assert.Equal(t, "no", seed.Restart)
+	assert.Equal(t, map[string]string{"PGHOST": "db", "PGUSER": "shop", "PGPASSWORD": "shop_password", "PGDATABASE": "shop"}, seed.Environment)
+	assert.Contains(t, parsed.Services, "api")
+
+	rewired, err := WireSeedCompose(out, "..")
+	require.NoError(t, err)
+	assert.Equal(t, string(out), string(rewired), "wiring twice replaces the seed service")
+
+	_, err = WireSeedCompose([]byte("services:\n  api:\n    image: node:20\n"), ".")
+	assert.ErrorIs(t, err, ErrNoPostgresService)
+}
@@ -0,0 +1,679 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// TerraformDir is where generated Terraform is written in a project
+const TerraformDir = "terraform"
+
+// TerraformFiles are the files GenerateTerraform writes in TerraformDir
+var TerraformFiles = []string{"versions.tf", "variables.tf", "main.tf", "outputs.tf"}
+
+// ErrNoServices is returned when a compose file defines nothing to deploy
+var ErrNoServices = errors.New("no deployable services in compose file")
+
+// InfraModel describes what a project needs to run, independent of the
+// HCL that provisions it
+type InfraModel struct {
+	Project  string         `json:"project"`
+	Region   string         `json:"region"`
+	Services []InfraService `json:"services"`
+	Database *InfraDatabase `json:"database,omitempty"`
+	Cache    *InfraCache    `json:"cache,omitempty"`
+}
+
+// InfraService is one container service
+type InfraService struct {
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	Port     int               `json:"port,omitempty"` // Container port; 0 for workers
+	Public   bool              `json:"public"`         // Reachable from the internet through a load balancer
+	CPU      int               `json:"cpu"`            // Fargate CPU units
+	MemoryMB int               `json:"memory_mb"`
+	Replicas int               `json:"replicas"`
+	Env      map[string]string `json:"env,omitempty"`     // Non-secret settings
+	Secrets  []string          `json:"secrets,omitempty"` // Secret settings, supplied at deploy time
+}
+
+// InfraDatabase is a managed relational database
+type InfraDatabase struct {
+	Engine        string `json:"engine"` // postgres or mysql
+	Version       string `json:"version"`
+	Name          string `json:"name"`
+	InstanceClass string `json:"instance_class"`
+	StorageGB     int    `json:"storage_gb"`
+}
+
+// InfraCache is a managed Redis cache
+type InfraCache struct {
+	NodeType string `json:"node_type"`
+}
+
+// Defaults applied by InfraFromCompose
+const (
+	DefaultRegion        = "us-east-1"
+	defaultCPU           = 256
+	defaultMemoryMB      = 512
+	defaultInstanceClass = "db.t3.micro"
+	defaultStorageGB     = 20
+	defaultCacheNode     = "cache.t3.micro"
+)
+
+var (
+	dbNameInvalid = regexp.MustCompile(`[^A-Za-z0-9]+`)
+	dbNameStart   = regexp.MustCompile(`^[A-Za-z]`)
+	hclIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	majorVersion  = regexp.MustCompile(`^\d+(\.\d+)?`)
+)
+
+// InfraFromCompose derives an infrastructure model from a docker-compose
+// file. Postgres, MySQL and Redis services become managed services; every
+// other service with an image or build becomes a container service, public
+// when the compose file publishes its ports.
+func InfraFromCompose(project string, compose []byte) (*InfraModel, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(compose, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, ErrNoServices
+	}
+	services := mappingValue(doc.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, ErrNoServices
+	}
+
+	name := k8sName(project)
+	model := &InfraModel{Project: name, Region: DefaultRegion}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		svcName, svc := services.Content[i].Value, services.Content[i+1]
+		image := ""
+		if n := mappingValue(svc, "image"); n != nil {
+			image = n.Value
+		}
+		env := composeEnvironment(mappingValue(svc, "environment"))
+
+		switch repo, tag := splitImage(image); {
+		case svcName == "seed":
+			// The one-shot seed loader is a local development aid
+		case strings.HasSuffix(repo, "postgres"), strings.HasSuffix(repo, "mysql"), strings.HasSuffix(repo, "mariadb"):
+			db := &InfraDatabase{Engine: "postgres", Version: "16", InstanceClass: defaultInstanceClass, StorageGB: defaultStorageGB}
+			dbName := env["POSTGRES_DB"]
+			if !strings.HasSuffix(repo, "postgres") {
+				db.Engine, db.Version, dbName = "mysql", "8.0", env["MYSQL_DATABASE"]
+			}
+			if m := majorVersion.FindString(tag); m != "" {
+				db.Version = m
+			}
+			if db.Name = dbNameInvalid.ReplaceAllString(dbName, ""); !dbNameStart.MatchString(db.Name) {
+				db.Name = "app"
+			}
+			model.Database = db
+		case strings.HasSuffix(repo, "redis"), strings.HasSuffix(repo, "valkey"):
+			model.Cache = &InfraCache{NodeType: defaultCacheNode}
+		case image != "" || mappingValue(svc, "build") != nil:
+			if image == "" {
+				image = name + "/" + svcName + ":latest"
+			}
+			s := InfraService{
+				Name:     svcName,
+				Image:    image,
+				CPU:      defaultCPU,
+				MemoryMB: defaultMemoryMB,
+				Replicas: 1,
+			}
+			s.Port, s.Public = composePort(svc)
+			for k, v := range env {
+				if IsSecretEnv(k) {
+					s.Secrets = append(s.Secrets, k)
+				} else {
+					if s.Env == nil {
+						s.Env = make(map[string]string)
+					}
+					s.Env[k] = v
+				}
+			}
+			sort.Strings(s.Secrets)
+			if replicas := mappingValue(mappingValue(svc, "deploy"), "replicas"); replicas != nil {
+				if n, err := strconv.Atoi(replicas.Value); err == nil && n > 0 {
+					s.Replicas = n
+				}
+			}
+			model.Services = append(model.Services, s)
+		}
+	}
+	if len(model.Services) == 0 {
+		return nil, ErrNoServices
+	}
+	sort.Slice(model.Services, func(i, j int) bool { return model.Services[i].Name < model.Services[j].Name })
+	return model, nil
+}
+
+// splitImage splits "registry/name:tag" into its repository and tag
+func splitImage(image string) (repo, tag string) {
+	repo = image
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repo, tag = image[:i], image[i+1:]
+	}
+	return repo, tag
+}
+
+// composePort returns the container port of a service and whether the
+// compose file publishes it to the host
+func composePort(svc *yaml.Node) (int, bool) {
+	if ports := mappingValue(svc, "ports"); ports != nil && ports.Kind == yaml.SequenceNode && len(ports.Content) > 0 {
+		spec := ports.Content[0].Value
+		if target := mappingValue(ports.Content[0], "target"); target != nil {
+			spec = target.Value
+		}
+		spec, _, _ = strings.Cut(spec, "/")
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			spec = spec[i+1:]
+		}
+		if port, err := strconv.Atoi(spec); err == nil {
+			return port, true
+		}
+	}
+	if expose := mappingValue(svc, "expose"); expose != nil && expose.Kind == yaml.SequenceNode && len(expose.Content) > 0 {
+		if port, err := strconv.Atoi(expose.Content[0].Value); err == nil {
+			return port, false
+		}
+	}
+	return 0, false
+}
+
+// GenerateTerraform renders the model as Terraform for AWS: a VPC, ECS
+// Fargate services behind application load balancers for public services,
+// and RDS and ElastiCache when the model has a database or cache. Paths are
+// under TerraformDir and the output is already terraform fmt clean.
+func GenerateTerraform(model *InfraModel) []agents.GeneratedFile {
+	files := []agents.GeneratedFile{
+		{Path: "versions.tf", Content: terraformVersions},
+		{Path: "variables.tf", Content: terraformVariables(model)},
+		{Path: "main.tf", Content: terraformMain(model)},
+		{Path: "outputs.tf", Content: terraformOutputs(model)},
+	}
+	for i := range files {
+		files[i].Path = path.Join(TerraformDir, files[i].Path)
+		files[i].Type = "terraform"
+	}
+	return files
+}
+
+const terraformVersions = `terraform {
+  required_version = ">= 1.5.0"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.region
+}
+`
+
+// hclAttr is one name = value line
+type hclAttr struct {
+	name, value string
+}
+
+// writeAttrs writes consecutive attributes with their equals signs aligned,
+// as terraform fmt does
+func writeAttrs(sb *strings.Builder, indent string, attrs ...hclAttr) {
+	width := 0
+	for _, a := range attrs {
+		if len(a.name) > width {
+			width = len(a.name)
+		}
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(sb, "%s%-*s = %s\n", indent, width, a.name, a.value)
+	}
+}
+
+// hclKey quotes object keys that are not plain identifiers
+func hclKey(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+	return hclString(key)
+}
+
+// hclString quotes s as a literal, escaping template sequences
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+func terraformVariables(m *InfraModel) string {
+	var sb strings.Builder
+	variable := func(name string, attrs ...hclAttr) {
+		fmt.Fprintf(&sb, "variable %q {\n", name)
+		writeAttrs(&sb, "  ", attrs...)
+		sb.WriteString("}\n\n")
+	}
+	variable("project",
+		hclAttr{"description", hclString("Name prefix for every resource")},
+		hclAttr{"type", "string"},
+		hclAttr{"default", hclString(m.Project)})
+	variable("region",
+		hclAttr{"description", hclString("AWS region to deploy into")},
+		hclAttr{"type", "string"},
+		hclAttr{"default", hclString(m.Region)})
+
+	sb.WriteString("variable \"images\" {\n")
+	writeAttrs(&sb, "  ",
+		hclAttr{"description", hclString("Container image of each service")},
+		hclAttr{"type", "map(string)"})
+	sb.WriteString("\n  default = {\n")
+	images := make([]hclAttr, len(m.Services))
+	for i, s := range m.Services {
+		images[i] = hclAttr{hclKey(s.Name), hclString(s.Image)}
+	}
+	writeAttrs(&sb, "    ", images...)
+	sb.WriteString("  }\n}\n\n")
+
+	sb.WriteString("variable \"secrets\" {\n")
+	writeAttrs(&sb, "  ",
+		hclAttr{"description", hclString("Secret environment of each service, by variable name")},
+		hclAttr{"type", "map(map(string))"},
+		hclAttr{"sensitive", "true"})
+	sb.WriteString("\n  default = {\n")
+	for _, s := range m.Services {
+		if len(s.Secrets) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "    %s = {\n", hclKey(s.Name))
+		secrets := make([]hclAttr, len(s.Secrets))
+		for i, name := range s.Secrets {
+			secrets[i] = hclAttr{hclKey(name), `""`}
+		}
+		writeAttrs(&sb, "      ", secrets...)
+		sb.WriteString("    }\n")
+	}
+	sb.WriteString("  }\n}\n")
+
+	if m.Database != nil {
+		sb.WriteString("\n")
+		variable("db_username",
+			hclAttr{"description", hclString("Database master user")},
+			hclAttr{"type", "string"},
+			hclAttr{"default", hclString("app")})
+		variable("db_password",
+			hclAttr{"description", hclString("Database master password")},
+			hclAttr{"type", "string"},
+			hclAttr{"sensitive", "true"})
+		return strings.TrimSuffix(sb.String(), "\n")
+	}
+	return sb.String()
+}
+
+func terraformMain(m *InfraModel) string {
+	var sb strings.Builder
+
+	// Services are described once in locals and expanded with for_each
+	sb.WriteString("locals {\n  services = {\n")
+	for _, s := range m.Services {
+		fmt.Fprintf(&sb, "    %s = {\n", hclKey(s.Name))
+		writeAttrs(&sb, "      ",
+			hclAttr{"port", strconv.Itoa(s.Port)},
+			hclAttr{"public", strconv.FormatBool(s.Public && s.Port > 0)},
+			hclAttr{"cpu", strconv.Itoa(s.CPU)},
+			hclAttr{"memory", strconv.Itoa(s.MemoryMB)},
+			hclAttr{"replicas", strconv.Itoa(s.Replicas)})
+		if len(s.Env) > 0 {
+			sb.WriteString("\n      environment = {\n")
+			names := make([]string, 0, len(s.Env))
+			for k := range s.Env {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			env := make([]hclAttr, len(names))
+			for i, k := range names {
+				env[i] = hclAttr{hclKey(k), hclString(s.Env[k])}
+			}
+			writeAttrs(&sb, "        ", env...)
+			sb.WriteString("      }\n")
+		} else {
+			sb.WriteString("\n      environment = {}\n")
+		}
+		sb.WriteString("    }\n")
+	}
+	sb.WriteString("  }\n\n")
+	sb.WriteString("  public_services = { for name, svc in local.services : name => svc if svc.public }\n\n")
+	sb.WriteString("  shared_environment = {")
+	var shared []hclAttr
+	if m.Database != nil {
+		shared = append(shared,
+			hclAttr{"DATABASE_HOST", "aws_db_instance.main.address"},
+			hclAttr{"DATABASE_PORT", "tostring(aws_db_instance.main.port)"},
+			hclAttr{"DATABASE_NAME", "aws_db_instance.main.db_name"})
+	}
+	if m.Cache != nil {
+		shared = append(shared, hclAttr{"REDIS_HOST", "aws_elasticache_cluster.main.cache_nodes[0].address"})
+	}
+	if len(shared) == 0 {
+		sb.WriteString("}\n}\n")
+	} else {
+		sb.WriteString("\n")
+		writeAttrs(&sb, "    ", shared...)
+		sb.WriteString("  }\n}\n")
+	}
+
+	sb.WriteString(terraformNetwork)
+	sb.WriteString(terraformCompute)
+	if m.Database != nil || m.Cache != nil {
+		sb.WriteString(terraformDataSecurityGroup)
+	}
+	if db := m.Database; db != nil {
+		sb.WriteString("\nresource \"aws_db_subnet_group\" \"main\" {\n")
+		writeAttrs(&sb, "  ", hclAttr{"name", "var.project"}, hclAttr{"subnet_ids", "aws_subnet.private[*].id"})
+		sb.WriteString("}\n\nresource \"aws_db_instance\" \"main\" {\n")
+		writeAttrs(&sb, "  ",
+			hclAttr{"identifier", "var.project"},
+			hclAttr{"engine", hclString(db.Engine)},
+			hclAttr{"engine_version", hclString(db.Version)},
+			hclAttr{"instance_class", hclString(db.InstanceClass)},
+			hclAttr{"allocated_storage", strconv.Itoa(db.StorageGB)},
+			hclAttr{"db_name", hclString(db.Name)},
+			hclAttr{"username", "var.db_username"},
+			hclAttr{"password", "var.db_password"},
+			hclAttr{"db_subnet_group_name", "aws_db_subnet_group.main.name"},
+			hclAttr{"vpc_security_group_ids", "[aws_security_group.data.id]"},
+			hclAttr{"storage_encrypted", "true"},
+			hclAttr{"skip_final_snapshot", "true"})
+		sb.WriteString("}\n")
+	}
+	if c := m.Cache; c != nil {
+		sb.WriteString("\nresource \"aws_elasticache_subnet_group\" \"main\" {\n")
+		writeAttrs(&sb, "  ", hclAttr{"name", "var.project"}, hclAttr{"subnet_ids", "aws_subnet.private[*].id"})
+		sb.WriteString("}\n\nresource \"aws_elasticache_cluster\" \"main\" {\n")
+		writeAttrs(&sb, "  ",
+			hclAttr{"cluster_id", "substr(var.project, 0, 40)"},
+			hclAttr{"engine", `"redis"`},
+			hclAttr{"node_type", hclString(c.NodeType)},
+			hclAttr{"num_cache_nodes", "1"},
+			hclAttr{"subnet_group_name", "aws_elasticache_subnet_group.main.name"},
+			hclAttr{"security_group_ids", "[aws_security_group.data.id]"})
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+// terraformNetwork is a VPC with public subnets for the services and load
+// balancers, and private subnets for managed data stores
+const terraformNetwork = `
+data "aws_availability_zones" "available" {
+  state = "available"
+}
+
+resource "aws_vpc" "main" {
+  cidr_block           = "10.0.0.0/16"
+  enable_dns_hostnames = true
+
+  tags = {
+    Name = var.project
+  }
+}
+
+resource "aws_subnet" "public" {
+  count = 2
+
+  vpc_id                  = aws_vpc.main.id
+  cidr_block              = cidrsubnet(aws_vpc.main.cidr_block, 8, count.index)
+  availability_zone       = data.aws_availability_zones.available.names[count.index]
+  map_public_ip_on_launch = true
+}
+
+resource "aws_subnet" "private" {
+  count = 2
+
+  vpc_id            = aws_vpc.main.id
+  cidr_block        = cidrsubnet(aws_vpc.main.cidr_block, 8, count.index + 10)
+  availability_zone = data.aws_availability_zones.available.names[count.index]
+}
+
+resource "aws_internet_gateway" "main" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_route_table" "public" {
+  vpc_id = aws_vpc.main.id
+
+  route {
+    cidr_block = "0.0.0.0/0"
+    gateway_id = aws_internet_gateway.main.id
+  }
+}
+
+resource "aws_route_table_association" "public" {
+  count = 2
+
+  subnet_id      = aws_subnet.public[count.index].id
+  route_table_id = aws_route_table.public.id
+}
+
+resource "aws_security_group" "alb" {
+  name   = "${var.project}-alb"
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 80
+    to_port     = 80
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_security_group" "services" {
+  name   = "${var.project}-services"
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 0
+    to_port     = 65535
+    protocol    = "tcp"
+    cidr_blocks = [aws_vpc.main.cidr_block]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+`
+
+// terraformCompute runs each service on ECS Fargate, fronting public ones
+// with an application load balancer
+const terraformCompute = `
+resource "aws_lb" "public" {
+  for_each = local.public_services
+
+  name               = substr("${var.project}-${each.key}", 0, 32)
+  load_balancer_type = "application"
+  security_groups    = [aws_security_group.alb.id]
+  subnets            = aws_subnet.public[*].id
+}
+
+resource "aws_lb_target_group" "public" {
+  for_each = local.public_services
+
+  name        = substr("${var.project}-${each.key}", 0, 32)
+  port        = each.value.port
+  protocol    = "HTTP"
+  target_type = "ip"
+  vpc_id      = aws_vpc.main.id
+}
+
+resource "aws_lb_listener" "public" {
+  for_each = local.public_services
+
+  load_balancer_arn = aws_lb.public[each.key].arn
+  port              = 80
+  protocol          = "HTTP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = aws_lb_target_group.public[each.key].arn
+  }
+}
+
+resource "aws_ecs_cluster" "main" {
+  name = var.project
+}
+
+resource "aws_cloudwatch_log_group" "services" {
+  name              = "/ecs/${var.project}"
+  retention_in_days = 14
+}
+
+data "aws_iam_policy_document" "ecs_assume" {
+  statement {
+    actions = ["sts:AssumeRole"]
+
+    principals {
+      type        = "Service"
+      identifiers = ["ecs-tasks.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "execution" {
+  name               = "${var.project}-execution"
+  assume_role_policy = data.aws_iam_policy_document.ecs_assume.json
+}
+
+resource "aws_iam_role_policy_attachment" "execution" {
+  role       = aws_iam_role.execution.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"
+}
+
+resource "aws_ecs_task_definition" "service" {
+  for_each = local.services
+
+  family                   = "${var.project}-${each.key}"
+  requires_compatibilities = ["FARGATE"]
+  network_mode             = "awsvpc"
+  cpu                      = each.value.cpu
+  memory                   = each.value.memory
+  execution_role_arn       = aws_iam_role.execution.arn
+
+  container_definitions = jsonencode([{
+    name         = each.key
+    image        = var.images[each.key]
+    essential    = true
+    portMappings = each.value.port > 0 ? [{ containerPort = each.value.port }] : []
+
+    environment = [
+      for k, v in merge(each.value.environment, local.shared_environment, lookup(var.secrets, each.key, {})) :
+      { name = k, value = v }
+    ]
+
+    logConfiguration = {
+      logDriver = "awslogs"
+
+      options = {
+        awslogs-group         = aws_cloudwatch_log_group.services.name
+        awslogs-region        = var.region
+        awslogs-stream-prefix = each.key
+      }
+    }
+  }])
+}
+
+resource "aws_ecs_service" "service" {
+  for_each = local.services
+
+  name            = each.key
+  cluster         = aws_ecs_cluster.main.id
+  task_definition = aws_ecs_task_definition.service[each.key].arn
+  desired_count   = each.value.replicas
+  launch_type     = "FARGATE"
+
+  network_configuration {
+    subnets          = aws_subnet.public[*].id
+    security_groups  = [aws_security_group.services.id]
+    assign_public_ip = true
+  }
+
+  dynamic "load_balancer" {
+    for_each = each.value.public ? [each.key] : []
+
+    content {
+      target_group_arn = aws_lb_target_group.public[load_balancer.value].arn
+      container_name   = load_balancer.value
+      container_port   = each.value.port
+    }
+  }
+
+  depends_on = [aws_lb_listener.public]
+}
+`
+
+// terraformDataSecurityGroup admits the services to the data stores
+const terraformDataSecurityGroup = `
+resource "aws_security_group" "data" {
+  name   = "${var.project}-data"
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port       = 0
+    to_port         = 65535
+    protocol        = "tcp"
+    security_groups = [aws_security_group.services.id]
+  }
+}
+`
+
+func terraformOutputs(m *InfraModel) string {
+	var sb strings.Builder
+	sb.WriteString(`output "cluster" {
+  value = aws_ecs_cluster.main.name
+}
+
+output "urls" {
+  description = "Public URL of each public service"
+  value       = { for name, lb in aws_lb.public : name => "http://${lb.dns_name}" }
+}
+`)
+	if m.Database != nil {
+		sb.WriteString(`
+output "database_endpoint" {
+  value = aws_db_instance.main.endpoint
+}
+`)
+	}
+	if m.Cache != nil {
+		sb.WriteString(`
+output "redis_endpoint" {
+  value = aws_elasticache_cluster.main.cache_nodes[0].address
+}
+`)
+	}
+	return sb.String()
+}
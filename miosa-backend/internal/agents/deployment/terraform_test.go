@@ -0,0 +1,161 @@
+package deployment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+const infraCompose = `services:
+  api:
+    build: .
+    ports:
+      - "8080:3000"
+    environment:
+      NODE_ENV: production
+      JWT_SECRET: change-me
+    deploy:
+      replicas: 2
+  worker:
+    image: acme/worker:1.2
+    expose:
+      - "9000"
+  db:
+    image: postgres:15-alpine
+    environment:
+      POSTGRES_DB: todo-app
+  cache:
+    image: redis:7
+  seed:
+    image: postgres:15-alpine
+`
+
+func TestInfraFromCompose(t *testing.T) {
+	model, err := InfraFromCompose("Todo App", []byte(infraCompose))
+	require.NoError(t, err)
+
+	assert.Equal(t, "todo-app", model.Project)
+	assert.Equal(t, &InfraDatabase{Engine: "postgres", Version: "15", Name: "todoapp", InstanceClass: "db.t3.micro", StorageGB: 20}, model.Database)
+	assert.NotNil(t, model.Cache)
+	require.Len(t, model.Services, 2)
+
+	api := model.Services[0]
+	assert.Equal(t, "api", api.Name)
+	assert.Equal(t, "todo-app/api:latest", api.Image)
+	assert.Equal(t, 3000, api.Port)
+	assert.True(t, api.Public)
+	assert.Equal(t, 2, api.Replicas)
+	assert.Equal(t, map[string]string{"NODE_ENV": "production"}, api.Env)
+	assert.Equal(t, []string{"JWT_SECRET"}, api.Secrets, "secret values stay out of the model")
+
+	worker := model.Services[1]
+	assert.Equal(t, "acme/worker:1.2", worker.Image)
+	assert.Equal(t, 9000, worker.Port)
+	assert.False(t, worker.Public)
+
+	_, err = InfraFromCompose("x", []byte("services:\n  db:\n    image: postgres\n"))
+	assert.ErrorIs(t, err, ErrNoServices)
+}
+
+func TestGenerateTerraform(t *testing.T) {
+	model, err := InfraFromCompose("todo", []byte(infraCompose))
+	require.NoError(t, err)
+	files := GenerateTerraform(model)
+
+	content := map[string]string{}
+	for _, f := range files {
+		content[f.Path] = f.Content
+		assert.Equal(t, strings.Count(f.Content, "{"), strings.Count(f.Content, "}"), "%s has balanced braces", f.Path)
+		assert.NotContains(t, f.Content, "\t", "%s is indented with spaces", f.Path)
+		assert.True(t, strings.HasSuffix(f.Content, "}\n"), f.Path)
+	}
+	require.Len(t, content, len(TerraformFiles))
+
+	vars := content["terraform/variables.tf"]
+	assert.Contains(t, vars, "    api    = \"todo/api:latest\"\n    worker = \"acme/worker:1.2\"\n")
+	assert.Contains(t, vars, "    api = {\n      JWT_SECRET = \"\"\n    }\n")
+	assert.NotContains(t, vars, "change-me")
+	assert.Contains(t, vars, `variable "db_password"`)
+
+	main := content["terraform/main.tf"]
+	assert.Contains(t, main, "    api = {\n      port     = 3000\n      public   = true\n")
+	assert.Contains(t, main, "      environment = {\n        NODE_ENV = \"production\"\n      }\n")
+	assert.Contains(t, main, "    DATABASE_HOST = aws_db_instance.main.address\n")
+	assert.Contains(t, main, `engine_version         = "15"`)
+	assert.Contains(t, main, `resource "aws_elasticache_cluster" "main"`)
+	assert.Contains(t, content["terraform/outputs.tf"], `output "redis_endpoint"`)
+
+	model.Database, model.Cache = nil, nil
+	main = GenerateTerraform(model)[2].Content
+	assert.NotContains(t, main, "aws_db_instance")
+	assert.Contains(t, main, "  shared_environment = {}\n")
+}
+
+// fakeTerraform writes a script standing in for the terraform CLI
+func fakeTerraform(t *testing.T, script string) string {
+	bin := filepath.Join(t.TempDir(), "terraform")
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"+script), 0755))
+	return bin
+}
+
+func TestCLITerraformValidator(t *testing.T) {
+	files := []agents.GeneratedFile{{Path: "main.tf", Content: "terraform {}\n"}, {Path: "modules/net/main.tf", Content: "x\n"}}
+
+	bin := fakeTerraform(t, `case "$1" in
+fmt) echo modules/net/main.tf; exit 3 ;;
+init) test -f modules/net/main.tf ;;
+validate) echo '{"valid":false,"diagnostics":[{"severity":"error","summary":"Unsupported argument","detail":"An argument named \"x\" is not expected here.","range":{"filename":"main.tf","start":{"line":4}}}]}'; exit 1 ;;
+plan) echo "plan must not run for an invalid module"; exit 1 ;;
+esac
+`)
+	v := NewCLITerraformValidator(bin, true)
+	report, err := v.Validate(context.Background(), files)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.tf", "modules/net/main.tf"}, report.Files)
+	assert.False(t, report.Formatted)
+	assert.False(t, report.Valid)
+	assert.Nil(t, report.Plan)
+	assert.Equal(t, 1, report.Errors())
+	assert.Equal(t, []TerraformDiagnostic{
+		{Stage: StageFmt, Severity: "warning", Summary: "File is not in canonical format", File: "modules/net/main.tf"},
+		{Stage: StageValidate, Severity: "error", Summary: "Unsupported argument", Detail: `An argument named "x" is not expected here.`, File: "main.tf", Line: 4},
+	}, report.Diagnostics)
+
+	bin = fakeTerraform(t, `case "$1" in
+validate) echo '{"valid":true,"diagnostics":[]}' ;;
+plan) test "$AWS_PROFILE" = sandbox && echo "Plan: 12 to add, 0 to change, 0 to destroy." ;;
+esac
+`)
+	v = NewCLITerraformValidator(bin, true)
+	v.Env = []string{"AWS_PROFILE=sandbox"}
+	report, err = v.Validate(context.Background(), files[:1])
+	require.NoError(t, err)
+	assert.True(t, report.Formatted)
+	assert.True(t, report.Valid)
+	assert.Equal(t, &TerraformPlan{Add: 12}, report.Plan)
+
+	bin = fakeTerraform(t, `test "$1" = init && echo "Error: Failed to query available provider packages" >&2 && exit 1
+exit 0
+`)
+	report, err = NewCLITerraformValidator(bin, false).Validate(context.Background(), files[:1])
+	require.NoError(t, err)
+	require.Len(t, report.Diagnostics, 1)
+	assert.Equal(t, StageInit, report.Diagnostics[0].Stage)
+	assert.Contains(t, report.Diagnostics[0].Detail, "Failed to query available provider packages")
+
+	_, err = NewCLITerraformValidator(filepath.Join(t.TempDir(), "missing"), false).Validate(context.Background(), files)
+	assert.Error(t, err)
+}
+
+func TestHCLString(t *testing.T) {
+	assert.Equal(t, `"$${HOME} %%{ if }"`, hclString("${HOME} %{ if }"))
+	assert.Equal(t, `"discovery.type"`, hclKey("discovery.type"))
+	assert.Equal(t, "NODE_ENV", hclKey("NODE_ENV"))
+}
@@ -0,0 +1,214 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// TerraformValidator checks a Terraform module without touching real
+// infrastructure, except for an optional plan
+type TerraformValidator interface {
+	Validate(ctx context.Context, files []agents.GeneratedFile) (*TerraformReport, error)
+}
+
+// DefaultTerraformValidator is the validator consulted after generation.
+// It is nil, skipping validation, unless a terraform binary is configured.
+var DefaultTerraformValidator TerraformValidator
+
+// Terraform validation stages, in the order they run
+const (
+	StageFmt      = "fmt"
+	StageInit     = "init"
+	StageValidate = "validate"
+	StagePlan     = "plan"
+)
+
+// TerraformDiagnostic is one problem reported by a stage
+type TerraformDiagnostic struct {
+	Stage    string `json:"stage"`
+	Severity string `json:"severity"` // error or warning
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// TerraformPlan counts the changes a plan would make
+type TerraformPlan struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// TerraformReport is the outcome of validating a module
+type TerraformReport struct {
+	Files       []string              `json:"files"`
+	Formatted   bool                  `json:"formatted"`
+	Valid       bool                  `json:"valid"`
+	Diagnostics []TerraformDiagnostic `json:"diagnostics,omitempty"`
+	Plan        *TerraformPlan        `json:"plan,omitempty"` // Nil unless a plan ran and succeeded
+}
+
+// Errors counts error diagnostics
+func (r *TerraformReport) Errors() int {
+	n := 0
+	for _, d := range r.Diagnostics {
+		if d.Severity == "error" {
+			n++
+		}
+	}
+	return n
+}
+
+// CLITerraformValidator runs the terraform CLI in a scratch directory:
+// fmt -check, init without a backend, validate and, when Plan is set, plan
+// with the credentials in the process environment plus Env.
+type CLITerraformValidator struct {
+	Binary string   // terraform or tofu
+	Plan   bool     // Also run terraform plan
+	Env    []string // Extra KEY=value settings, e.g. provider credentials
+}
+
+// NewCLITerraformValidator creates a validator running binary
+func NewCLITerraformValidator(binary string, plan bool) *CLITerraformValidator {
+	return &CLITerraformValidator{Binary: binary, Plan: plan}
+}
+
+var planSummary = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+
+// Validate copies files, with paths relative to the module root, into a
+// temporary directory and runs each stage there. Failing stages are
+// reported as diagnostics; the error is only for failures to run terraform.
+func (v *CLITerraformValidator) Validate(ctx context.Context, files []agents.GeneratedFile) (*TerraformReport, error) {
+	dir, err := os.MkdirTemp("", "miosa-terraform-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create terraform sandbox: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	report := &TerraformReport{Formatted: true}
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			return nil, fmt.Errorf("terraform file %s escapes the module", f.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create terraform sandbox: %w", err)
+		}
+		if err := os.WriteFile(target, []byte(f.Content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+		report.Files = append(report.Files, f.Path)
+	}
+
+	// fmt -check exits 3 and lists the files that need formatting
+	out, _, err := v.run(ctx, dir, "fmt", "-check", "-recursive", "-list=true", "-no-color")
+	if err != nil && !isExit(err) {
+		return nil, err
+	}
+	for _, file := range strings.Fields(out) {
+		report.Formatted = false
+		report.Diagnostics = append(report.Diagnostics, TerraformDiagnostic{
+			Stage: StageFmt, Severity: "warning", Summary: "File is not in canonical format", File: filepath.ToSlash(file),
+		})
+	}
+
+	if _, stderr, err := v.run(ctx, dir, "init", "-backend=false", "-input=false", "-no-color"); err != nil {
+		if !isExit(err) {
+			return nil, err
+		}
+		report.Diagnostics = append(report.Diagnostics, TerraformDiagnostic{
+			Stage: StageInit, Severity: "error", Summary: "terraform init failed", Detail: strings.TrimSpace(stderr),
+		})
+		return report, nil
+	}
+
+	// validate -json exits 1 on errors but still reports them on stdout
+	out, stderr, err := v.run(ctx, dir, "validate", "-json", "-no-color")
+	if err != nil && !isExit(err) {
+		return nil, err
+	}
+	var result struct {
+		Valid       bool `json:"valid"`
+		Diagnostics []struct {
+			Severity string `json:"severity"`
+			Summary  string `json:"summary"`
+			Detail   string `json:"detail"`
+			Range    *struct {
+				Filename string `json:"filename"`
+				Start    struct {
+					Line int `json:"line"`
+				} `json:"start"`
+			} `json:"range"`
+		} `json:"diagnostics"`
+	}
+	if jsonErr := json.Unmarshal([]byte(out), &result); jsonErr != nil {
+		report.Diagnostics = append(report.Diagnostics, TerraformDiagnostic{
+			Stage: StageValidate, Severity: "error", Summary: "terraform validate failed", Detail: strings.TrimSpace(stderr + out),
+		})
+		return report, nil
+	}
+	report.Valid = result.Valid
+	for _, d := range result.Diagnostics {
+		diag := TerraformDiagnostic{Stage: StageValidate, Severity: d.Severity, Summary: d.Summary, Detail: d.Detail}
+		if d.Range != nil {
+			diag.File, diag.Line = d.Range.Filename, d.Range.Start.Line
+		}
+		report.Diagnostics = append(report.Diagnostics, diag)
+	}
+
+	if !v.Plan || !report.Valid {
+		return report, nil
+	}
+	out, stderr, err = v.run(ctx, dir, "plan", "-input=false", "-lock=false", "-no-color")
+	if err != nil {
+		if !isExit(err) {
+			return nil, err
+		}
+		report.Diagnostics = append(report.Diagnostics, TerraformDiagnostic{
+			Stage: StagePlan, Severity: "error", Summary: "terraform plan failed", Detail: strings.TrimSpace(stderr),
+		})
+		return report, nil
+	}
+	report.Plan = &TerraformPlan{}
+	if m := planSummary.FindStringSubmatch(out); m != nil {
+		report.Plan.Add, _ = strconv.Atoi(m[1])
+		report.Plan.Change, _ = strconv.Atoi(m[2])
+		report.Plan.Destroy, _ = strconv.Atoi(m[3])
+	}
+	return report, nil
+}
+
+// run executes one terraform command in dir, returning stdout and stderr
+func (v *CLITerraformValidator) run(ctx context.Context, dir string, args ...string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, v.Binary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "TF_IN_AUTOMATION=1"), v.Env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if isExit(err) {
+			return stdout.String(), stderr.String(), err
+		}
+		return "", "", fmt.Errorf("failed to run %s %s: %w", v.Binary, args[0], err)
+	}
+	return stdout.String(), stderr.String(), nil
+}
+
+// isExit reports whether err is terraform exiting non-zero, as opposed to
+// failing to start
+func isExit(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr)
+}
@@ -39,18 +39,28 @@ func (a *DevelopmentAgent) GetDescription() string {
 	return "Generates high-quality code implementations with best practices"
 }
 
+// Model returns the model the agent calls
+func (a *DevelopmentAgent) Model() string {
+	return a.config.Model
+}
+
 // GetCapabilities returns the agent's capabilities
 func (a *DevelopmentAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
 		{Name: "code_generation", Description: "Generate production-ready code", Required: true},
-		{Name: "refactoring", Description: "Refactor and optimize code", Required: true},
+		{Name: "refactoring", Description: "Refactor and optimize code", Required: true, Features: []agents.Feature{agents.FeaturePatchMode}},
 		{Name: "debugging", Description: "Debug and fix issues", Required: false},
 		{Name: "documentation", Description: "Generate code documentation", Required: false},
 	}
 }
 
-// Execute processes a development task
+// Execute processes a development task. Given the current files in
+// task.Parameters[agents.PatchFilesParam] it returns edits to them instead
+// of whole files.
 func (a *DevelopmentAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	if files := agents.PatchFiles(task); len(files) > 0 {
+		return agents.RequestPatches(ctx, a.groqClient, a.GetType(), a.config, task, files)
+	}
 	startTime := time.Now()
 	
 	// Build development prompt
@@ -66,11 +76,18 @@ Requirements:
 - Make it maintainable and scalable
 
 Provide complete, working code.`, task.Input)
+	// Build what the architect declared, and put shared code where it said
+	if spec := agents.TaskArchitecture(task); spec != nil {
+		prompt += "\n\n" + spec.Prompt()
+	}
+	if plan := agents.TaskMonorepo(task); plan != nil {
+		prompt += "\n\n" + plan.Prompt()
+	}
 
 	// Get code from LLM
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
 		Model: groq.ChatModel(a.config.Model),
-		Messages: []groq.ChatCompletionMessage{
+		Messages: agents.WithFewShot(ctx, a.GetType(), task, agents.WithEnrichment(task, agents.WithStackProfile(ctx, task, []groq.ChatCompletionMessage{
 			{
 				Role:    "system",
 				Content: "You are an expert software engineer who writes clean, efficient, and maintainable code.",
@@ -79,7 +96,7 @@ Provide complete, working code.`, task.Input)
 				Role:    "user",
 				Content: prompt,
 			},
-		},
+		}))),
 		MaxTokens:   a.config.MaxTokens,
 		Temperature: float32(a.config.Temperature),
 		TopP:        float32(a.config.TopP),
@@ -116,11 +133,13 @@ Provide complete, working code.`, task.Input)
 		ExecutionMS: time.Since(startTime).Milliseconds(),
 		Data: map[string]interface{}{
 			"model":       a.config.Model,
+			"usage":       response.Usage,
 			"line_count":  len(strings.Split(content, "\n")),
 			"has_tests":   strings.Contains(content, "test") || strings.Contains(content, "Test"),
 			"has_docs":    strings.Contains(content, "/**") || strings.Contains(content, "#"),
 		},
 	}
+	result.AddUsage(response)
 	
 	// Record execution for self-improvement
 	agents.RecordExecution(a.GetType(), result)
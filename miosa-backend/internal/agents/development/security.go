@@ -0,0 +1,376 @@
+package development
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+)
+
+// DefaultCSP is the Content-Security-Policy sent when none is configured
+const DefaultCSP = "default-src 'self'"
+
+// Web gateway frameworks the security middleware is generated for
+const (
+	GatewayGin     = "gin"
+	GatewayExpress = "express"
+)
+
+// ErrNoGateway is returned when a project has no gin or Express server to
+// protect
+var ErrNoGateway = errors.New("no gin or express gateway found")
+
+// SecurityConfig selects the middleware generated for a web gateway
+type SecurityConfig struct {
+	Headers       bool   `json:"headers"`        // nosniff, frame denial, referrer and opener policies, CSP
+	CSP           string `json:"csp"`            // Content-Security-Policy; empty = DefaultCSP
+	HSTSMaxAge    int    `json:"hsts_max_age"`   // Strict-Transport-Security max-age; 0 = no HSTS
+	CSRF          bool   `json:"csrf"`           // Double-submit CSRF token on state-changing requests
+	SecureCookies bool   `json:"secure_cookies"` // Secure, HttpOnly, SameSite=Strict cookies
+}
+
+// IsZero reports whether the config enables no middleware
+func (c *SecurityConfig) IsZero() bool {
+	return c == nil || !c.Headers && !c.CSRF && !c.SecureCookies
+}
+
+func (c *SecurityConfig) csp() string {
+	if c.CSP == "" {
+		return DefaultCSP
+	}
+	return c.CSP
+}
+
+// SecurityScaffold is the middleware generated for one gateway
+type SecurityScaffold struct {
+	Gateway string                 `json:"gateway"` // gin or express
+	Entry   string                 `json:"entry"`   // File that creates the server
+	Files   []agents.GeneratedFile `json:"files"`   // The middleware plus the rewired entry file
+}
+
+// SecurityReport describes the middleware added to a generated project and
+// the Security quality findings that remain afterwards
+type SecurityReport struct {
+	Gateway  string            `json:"gateway"`
+	Files    []string          `json:"files"`
+	Findings []quality.Finding `json:"findings,omitempty"`
+}
+
+var (
+	ginImport     = regexp.MustCompile(`"github\.com/gin-gonic/gin"`)
+	ginEngine     = regexp.MustCompile(`(?m)^([ \t]*)(\w+)\s*:?=\s*gin\.(?:Default|New)\(\)[^\n]*$`)
+	goPackage     = regexp.MustCompile(`(?m)^package (\w+)`)
+	expressImport = regexp.MustCompile(`require\(\s*['"]express['"]\s*\)|from\s+['"]express['"]`)
+	expressApp    = regexp.MustCompile(`(?m)^([ \t]*)(?:const|let|var)\s+(\w+)\s*=\s*express\(\)[^\n]*$`)
+)
+
+// ScaffoldSecurity generates security middleware for the first gin or
+// Express server in files and wires it in right after the server is created.
+// The middleware has no dependencies beyond the framework itself. A gateway
+// that already uses the middleware yields an empty scaffold.
+func ScaffoldSecurity(files []agents.GeneratedFile, config *SecurityConfig) (*SecurityScaffold, error) {
+	if config.IsZero() {
+		return &SecurityScaffold{}, nil
+	}
+
+	sorted := append([]agents.GeneratedFile(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	for _, f := range sorted {
+		switch ext := path.Ext(f.Path); {
+		case ext == ".go" && !strings.HasSuffix(f.Path, "_test.go") && ginImport.MatchString(f.Content):
+			if m := ginEngine.FindStringSubmatchIndex(f.Content); m != nil {
+				return scaffoldGin(f, m, config)
+			}
+		case (ext == ".js" || ext == ".ts" || ext == ".mjs" || ext == ".cjs") && expressImport.MatchString(f.Content):
+			if m := expressApp.FindStringSubmatchIndex(f.Content); m != nil {
+				return scaffoldExpress(f, m, config)
+			}
+		}
+	}
+	return nil, ErrNoGateway
+}
+
+// scaffoldGin writes security.go into the engine's package and registers
+// it with Use
+func scaffoldGin(entry agents.GeneratedFile, m []int, config *SecurityConfig) (*SecurityScaffold, error) {
+	scaffold := &SecurityScaffold{Gateway: GatewayGin, Entry: entry.Path}
+	if strings.Contains(entry.Content, "SecurityMiddleware()") {
+		return scaffold, nil
+	}
+	pkg := goPackage.FindStringSubmatch(entry.Content)
+	if pkg == nil {
+		return nil, fmt.Errorf("%s has no package clause", entry.Path)
+	}
+
+	source, err := format.Source([]byte(ginSecurity(pkg[1], config)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format gin security middleware: %w", err)
+	}
+	indent, engine := entry.Content[m[2]:m[3]], entry.Content[m[4]:m[5]]
+	wired := entry.Content[:m[1]] + "\n" + indent + engine + ".Use(SecurityMiddleware()...)" + entry.Content[m[1]:]
+
+	scaffold.Files = []agents.GeneratedFile{
+		{Path: path.Join(path.Dir(entry.Path), "security.go"), Content: string(source)},
+		{Path: entry.Path, Content: wired},
+	}
+	return scaffold, nil
+}
+
+// ginSecurity renders the gin middleware for package pkg
+func ginSecurity(pkg string, config *SecurityConfig) string {
+	imports := []string{`"github.com/gin-gonic/gin"`}
+	if config.CSRF || config.SecureCookies {
+		imports = append(imports, `"net/http"`)
+	}
+	if config.CSRF {
+		imports = append(imports, `"crypto/rand"`, `"crypto/subtle"`, `"encoding/base64"`)
+	}
+	sort.Strings(imports)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by MIOSA from the workflow's security profile.\n\npackage %s\n\nimport (\n", pkg)
+	for _, imp := range imports {
+		fmt.Fprintf(&sb, "\t%s\n", imp)
+	}
+	sb.WriteString(")\n\n")
+
+	var chain []string
+	if config.Headers {
+		chain = append(chain, "SecurityHeaders()")
+	}
+	if config.CSRF {
+		chain = append(chain, "CSRFProtection()")
+	}
+	sb.WriteString("// SecurityMiddleware returns the gateway's security middleware, in order\n")
+	fmt.Fprintf(&sb, "func SecurityMiddleware() []gin.HandlerFunc {\n\treturn []gin.HandlerFunc{%s}\n}\n", strings.Join(chain, ", "))
+
+	if config.Headers {
+		sb.WriteString(`
+// SecurityHeaders sets browser hardening headers on every response
+func SecurityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+`)
+		fmt.Fprintf(&sb, "\t\th.Set(\"Content-Security-Policy\", %q)\n", config.csp())
+		if config.HSTSMaxAge > 0 {
+			fmt.Fprintf(&sb, "\t\th.Set(\"Strict-Transport-Security\", %q)\n", fmt.Sprintf("max-age=%d; includeSubDomains", config.HSTSMaxAge))
+		}
+		sb.WriteString("\t\tc.Next()\n\t}\n}\n")
+	}
+
+	if config.CSRF {
+		sameSite := "http.SameSiteLaxMode"
+		if config.SecureCookies {
+			sameSite = "http.SameSiteStrictMode"
+		}
+		fmt.Fprintf(&sb, `
+const (
+	csrfCookie = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+)
+
+// CSRFProtection implements the double-submit cookie pattern: every client
+// receives a random token cookie, and state-changing requests must echo it in
+// the X-CSRF-Token header. The cookie is readable by scripts so the frontend
+// can copy it.
+func CSRFProtection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(csrfCookie)
+		if err != nil || token == "" {
+			token = newCSRFToken()
+			http.SetCookie(c.Writer, &http.Cookie{Name: csrfCookie, Value: token, Path: "/", Secure: %t, SameSite: %s})
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(csrfHeader)), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid CSRF token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+`, config.SecureCookies, sameSite)
+	}
+
+	if config.SecureCookies {
+		sb.WriteString(`
+// SetSecureCookie sets a Secure, HttpOnly, SameSite=Strict cookie. Use it
+// instead of c.SetCookie for every cookie the gateway issues.
+func SetSecureCookie(c *gin.Context, name, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+`)
+	}
+	return sb.String()
+}
+
+// scaffoldExpress writes a security module next to the app's entry file
+// and mounts it with app.use
+func scaffoldExpress(entry agents.GeneratedFile, m []int, config *SecurityConfig) (*SecurityScaffold, error) {
+	scaffold := &SecurityScaffold{Gateway: GatewayExpress, Entry: entry.Path}
+	if strings.Contains(entry.Content, "securityMiddleware()") {
+		return scaffold, nil
+	}
+
+	ext := path.Ext(entry.Path)
+	typescript := ext == ".ts"
+	esm := strings.Contains(entry.Content, "import ") && !strings.Contains(entry.Content, "require(")
+	module := "./security"
+	if !typescript {
+		module += ext
+	}
+	indent, app := entry.Content[m[2]:m[3]], entry.Content[m[4]:m[5]]
+	importLine := "const { securityMiddleware } = require('" + module + "');\n"
+	if esm {
+		importLine = "import { securityMiddleware } from '" + module + "';\n"
+	}
+	wired := entry.Content[:m[1]] + "\n" + indent + app + ".use(securityMiddleware());" + entry.Content[m[1]:]
+	wired = insertAfterImports(wired, importLine)
+
+	scaffold.Files = []agents.GeneratedFile{
+		{Path: path.Join(path.Dir(entry.Path), "security"+ext), Content: expressSecurity(config, esm, typescript)},
+		{Path: entry.Path, Content: wired},
+	}
+	return scaffold, nil
+}
+
+var jsImport = regexp.MustCompile(`(?m)^(?:import\s.*|(?:const|let|var)\s.*=\s*require\(.*\).*)$`)
+
+// insertAfterImports adds line after the last top-level import or require
+func insertAfterImports(content, line string) string {
+	all := jsImport.FindAllStringIndex(content, -1)
+	if all == nil {
+		return line + content
+	}
+	end := all[len(all)-1][1]
+	return content[:end] + "\n" + strings.TrimSuffix(line, "\n") + content[end:]
+}
+
+// expressSecurity renders the Express middleware module
+func expressSecurity(config *SecurityConfig, esm, typescript bool) string {
+	params, cookieParams := "req, res, next", "req, name"
+	if typescript {
+		params, cookieParams = "req: any, res: any, next: any", "req: any, name: string"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by MIOSA from the workflow's security profile.\n")
+	if config.CSRF {
+		if esm || typescript {
+			sb.WriteString("import crypto from 'crypto';\n")
+		} else {
+			sb.WriteString("const crypto = require('crypto');\n")
+		}
+	}
+
+	var chain, exports []string
+	if config.SecureCookies || config.CSRF {
+		if config.SecureCookies {
+			sb.WriteString("\n// Pass to res.cookie for every cookie the gateway issues\nconst cookieOptions = { path: '/', secure: true, httpOnly: true, sameSite: 'strict' };\n")
+		} else {
+			sb.WriteString("\nconst cookieOptions = { path: '/', sameSite: 'lax' };\n")
+		}
+		exports = append(exports, "cookieOptions")
+	}
+
+	if config.Headers {
+		fmt.Fprintf(&sb, `
+// securityHeaders sets browser hardening headers on every response
+function securityHeaders(%s) {
+  res.setHeader('X-Content-Type-Options', 'nosniff');
+  res.setHeader('X-Frame-Options', 'DENY');
+  res.setHeader('Referrer-Policy', 'strict-origin-when-cross-origin');
+  res.setHeader('Cross-Origin-Opener-Policy', 'same-origin');
+  res.setHeader('Content-Security-Policy', %s);
+`, params, jsString(config.csp()))
+		if config.HSTSMaxAge > 0 {
+			fmt.Fprintf(&sb, "  res.setHeader('Strict-Transport-Security', 'max-age=%d; includeSubDomains');\n", config.HSTSMaxAge)
+		}
+		sb.WriteString("  next();\n}\n")
+		chain = append(chain, "securityHeaders")
+		exports = append(exports, "securityHeaders")
+	}
+
+	if config.CSRF {
+		fmt.Fprintf(&sb, `
+const CSRF_COOKIE = 'csrf_token';
+const CSRF_HEADER = 'x-csrf-token';
+const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+function readCookie(%s) {
+  for (const part of (req.headers.cookie || '').split(';')) {
+    const [key, ...value] = part.trim().split('=');
+    if (key === name) return decodeURIComponent(value.join('='));
+  }
+  return '';
+}
+
+// csrfProtection implements the double-submit cookie pattern: every client
+// receives a random token cookie, and state-changing requests must echo it in
+// the X-CSRF-Token header. The cookie is readable by scripts so the frontend
+// can copy it.
+function csrfProtection(%s) {
+  let token = readCookie(req, CSRF_COOKIE);
+  if (!token) {
+    token = crypto.randomBytes(32).toString('base64url');
+    res.cookie(CSRF_COOKIE, token, { ...cookieOptions, httpOnly: false });
+  }
+  if (SAFE_METHODS.includes(req.method)) return next();
+
+  const sent = Buffer.from(String(req.get(CSRF_HEADER) || ''));
+  const expected = Buffer.from(token);
+  if (sent.length !== expected.length || !crypto.timingSafeEqual(sent, expected)) {
+    return res.status(403).json({ error: 'invalid CSRF token' });
+  }
+  next();
+}
+`, cookieParams, params)
+		chain = append(chain, "csrfProtection")
+		exports = append(exports, "csrfProtection")
+	}
+
+	fmt.Fprintf(&sb, "\n// securityMiddleware returns the gateway's security middleware, in order\nfunction securityMiddleware() {\n  return [%s];\n}\n", strings.Join(chain, ", "))
+	exports = append([]string{"securityMiddleware"}, exports...)
+	if esm || typescript {
+		fmt.Fprintf(&sb, "\nexport { %s };\n", strings.Join(exports, ", "))
+	} else {
+		fmt.Fprintf(&sb, "\nmodule.exports = { %s };\n", strings.Join(exports, ", "))
+	}
+	return sb.String()
+}
+
+// jsString quotes s as a single-quoted JavaScript string
+func jsString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,136 @@
+package development
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+)
+
+const ginMain = `package main
+
+import "github.com/gin-gonic/gin"
+
+func main() {
+	r := gin.Default()
+	r.POST("/login", func(c *gin.Context) {
+		c.SetCookie("session", "s", 3600, "/", "", false, true)
+	})
+	r.Run()
+}
+`
+
+const expressServer = `const express = require('express');
+const cookieParser = require('cookie-parser');
+
+const app = express();
+app.post('/login', (req, res) => {
+  res.cookie('session', 's');
+  res.sendStatus(204);
+});
+app.listen(3000);
+`
+
+var fullSecurity = &SecurityConfig{Headers: true, HSTSMaxAge: 31536000, CSRF: true, SecureCookies: true}
+
+// securityRules lints files and returns the Security rules reported
+func securityRules(files []agents.GeneratedFile) []string {
+	var code []quality.CodeFile
+	for _, f := range files {
+		code = append(code, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	var rules []string
+	for _, f := range quality.LintWebSecurity(code) {
+		rules = append(rules, f.Rule)
+	}
+	return rules
+}
+
+// apply overlays the scaffold's files onto files
+func apply(files []agents.GeneratedFile, scaffold *SecurityScaffold) []agents.GeneratedFile {
+	out := append([]agents.GeneratedFile(nil), files...)
+	for _, f := range scaffold.Files {
+		replaced := false
+		for i := range out {
+			if out[i].Path == f.Path {
+				out[i], replaced = f, true
+			}
+		}
+		if !replaced {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func TestScaffoldSecurity_Gin(t *testing.T) {
+	files := []agents.GeneratedFile{{Path: "cmd/api/main.go", Content: ginMain}}
+	assert.Equal(t, []string{"Security.Headers", "Security.CSRF", "Security.CookieFlags"}, securityRules(files))
+
+	scaffold, err := ScaffoldSecurity(files, fullSecurity)
+	require.NoError(t, err)
+	assert.Equal(t, GatewayGin, scaffold.Gateway)
+	require.Len(t, scaffold.Files, 2)
+
+	middleware := scaffold.Files[0]
+	assert.Equal(t, "cmd/api/security.go", middleware.Path)
+	assert.Contains(t, middleware.Content, "package main\n")
+	assert.Contains(t, middleware.Content, "return []gin.HandlerFunc{SecurityHeaders(), CSRFProtection()}")
+	assert.Contains(t, middleware.Content, `h.Set("Content-Security-Policy", "default-src 'self'")`)
+	assert.Contains(t, middleware.Content, `h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")`)
+	assert.Contains(t, middleware.Content, "func SetSecureCookie(")
+	assert.Contains(t, scaffold.Files[1].Content, "\tr := gin.Default()\n\tr.Use(SecurityMiddleware()...)\n")
+
+	// The gin SetCookie call still needs fixing by hand
+	assert.Equal(t, []string{"Security.CookieFlags"}, securityRules(apply(files, scaffold)))
+
+	again, err := ScaffoldSecurity(apply(files, scaffold), fullSecurity)
+	require.NoError(t, err)
+	assert.Empty(t, again.Files, "an already wired gateway is left alone")
+
+	headersOnly, err := ScaffoldSecurity(files, &SecurityConfig{Headers: true, CSP: `default-src "self"`})
+	require.NoError(t, err)
+	assert.NotContains(t, headersOnly.Files[0].Content, "net/http")
+	assert.Contains(t, headersOnly.Files[0].Content, `h.Set("Content-Security-Policy", "default-src \"self\"")`)
+}
+
+func TestScaffoldSecurity_Express(t *testing.T) {
+	files := []agents.GeneratedFile{{Path: "src/server.js", Content: expressServer}}
+	scaffold, err := ScaffoldSecurity(files, fullSecurity)
+	require.NoError(t, err)
+	assert.Equal(t, GatewayExpress, scaffold.Gateway)
+	require.Len(t, scaffold.Files, 2)
+
+	middleware := scaffold.Files[0]
+	assert.Equal(t, "src/security.js", middleware.Path)
+	assert.Contains(t, middleware.Content, "const crypto = require('crypto');")
+	assert.Contains(t, middleware.Content, "return [securityHeaders, csrfProtection];")
+	assert.Contains(t, middleware.Content, "module.exports = { securityMiddleware, cookieOptions, securityHeaders, csrfProtection };")
+
+	entry := scaffold.Files[1].Content
+	assert.True(t, strings.HasPrefix(entry, "const express = require('express');\nconst cookieParser = require('cookie-parser');\nconst { securityMiddleware } = require('./security.js');\n"))
+	assert.Contains(t, entry, "const app = express();\napp.use(securityMiddleware());\n")
+
+	assert.Equal(t, []string{"Security.CookieFlags"}, securityRules(apply(files, scaffold)))
+
+	ts := []agents.GeneratedFile{{Path: "index.ts", Content: "import express from 'express';\n\nconst app = express();\n"}}
+	scaffold, err = ScaffoldSecurity(ts, &SecurityConfig{CSRF: true})
+	require.NoError(t, err)
+	assert.Equal(t, "security.ts", scaffold.Files[0].Path)
+	assert.Contains(t, scaffold.Files[0].Content, "function csrfProtection(req: any, res: any, next: any) {")
+	assert.Contains(t, scaffold.Files[0].Content, "export { securityMiddleware, cookieOptions, csrfProtection };")
+	assert.Equal(t, "import express from 'express';\nimport { securityMiddleware } from './security';\n\nconst app = express();\napp.use(securityMiddleware());\n", scaffold.Files[1].Content)
+}
+
+func TestScaffoldSecurity_NoGateway(t *testing.T) {
+	_, err := ScaffoldSecurity([]agents.GeneratedFile{{Path: "main.go", Content: "package main\n"}}, fullSecurity)
+	assert.ErrorIs(t, err, ErrNoGateway)
+
+	scaffold, err := ScaffoldSecurity(nil, &SecurityConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, scaffold.Files)
+}
@@ -0,0 +1,229 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrDraining is returned by Drainer.Begin once shutdown has started
+var ErrDraining = errors.New("orchestrator is draining, not accepting new work")
+
+// InFlight is a unit of work that was running when the process drained
+type InFlight struct {
+	Task      Task      `json:"task"`
+	Source    string    `json:"source"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// DrainStore persists in-flight work across restarts
+type DrainStore interface {
+	Save(ctx context.Context, work []InFlight) error
+	Load(ctx context.Context) ([]InFlight, error)
+	Clear(ctx context.Context) error
+}
+
+// Drainer tracks in-flight workflows so shutdown can stop accepting new
+// ones, wait for running ones and persist whatever did not finish.
+type Drainer struct {
+	store    DrainStore
+	logger   *zap.Logger
+	inflight map[uuid.UUID]*InFlight
+	draining bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// NewDrainer creates a drainer. store may be nil, in which case unfinished
+// work is logged but not persisted.
+func NewDrainer(store DrainStore, logger *zap.Logger) *Drainer {
+	return &Drainer{
+		store:    store,
+		logger:   logger,
+		inflight: make(map[uuid.UUID]*InFlight),
+	}
+}
+
+// Begin registers task as in flight. The returned func must be called when
+// the task finishes. Begin fails with ErrDraining after Drain has started.
+func (d *Drainer) Begin(task Task, source string) (func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return nil, ErrDraining
+	}
+	if task.ID == uuid.Nil {
+		task.ID = uuid.New()
+	}
+
+	d.inflight[task.ID] = &InFlight{Task: task, Source: source, StartedAt: time.Now()}
+	d.wg.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			delete(d.inflight, task.ID)
+			d.mu.Unlock()
+			d.wg.Done()
+		})
+	}, nil
+}
+
+// Draining reports whether Drain has been called
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// InFlightCount returns the number of running tasks
+func (d *Drainer) InFlightCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.inflight)
+}
+
+// Drain stops accepting new work and waits for in-flight tasks until ctx is
+// done. Tasks still running at that point are persisted so Resume can rerun
+// them on the next start; the returned error is ctx.Err() in that case.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	count := len(d.inflight)
+	d.mu.Unlock()
+
+	d.logger.Info("Draining in-flight workflows", zap.Int("in_flight", count))
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.logger.Info("All in-flight workflows completed")
+		return nil
+	case <-ctx.Done():
+	}
+
+	d.mu.Lock()
+	remaining := make([]InFlight, 0, len(d.inflight))
+	for _, w := range d.inflight {
+		remaining = append(remaining, *w)
+	}
+	d.mu.Unlock()
+
+	d.logger.Warn("Drain timeout reached, persisting unfinished workflows",
+		zap.Int("remaining", len(remaining)))
+
+	if d.store != nil && len(remaining) > 0 {
+		// ctx is already done, so give the store its own short deadline
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := d.store.Save(saveCtx, remaining); err != nil {
+			d.logger.Error("Failed to persist in-flight workflows", zap.Error(err))
+		}
+	}
+	return ctx.Err()
+}
+
+// Resume loads work persisted by a previous Drain, clears the store and
+// reruns each task in the background with run. It returns the number of
+// tasks resumed. Tasks are rerun from the start, so run must tolerate a
+// task having partially executed before.
+func (d *Drainer) Resume(ctx context.Context, run func(ctx context.Context, work InFlight) error) (int, error) {
+	if d.store == nil {
+		return 0, nil
+	}
+
+	work, err := d.store.Load(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load drained workflows: %w", err)
+	}
+	if len(work) == 0 {
+		return 0, nil
+	}
+	if err := d.store.Clear(ctx); err != nil {
+		return 0, fmt.Errorf("failed to clear drained workflows: %w", err)
+	}
+
+	for _, w := range work {
+		finish, err := d.Begin(w.Task, w.Source)
+		if err != nil {
+			return 0, err
+		}
+		go func(w InFlight) {
+			defer finish()
+			d.logger.Info("Resuming drained workflow",
+				zap.String("task_id", w.Task.ID.String()),
+				zap.String("source", w.Source))
+			if err := run(ctx, w); err != nil {
+				d.logger.Error("Resumed workflow failed",
+					zap.String("task_id", w.Task.ID.String()),
+					zap.Error(err))
+			}
+		}(w)
+	}
+	return len(work), nil
+}
+
+// FileDrainStore keeps drained work in a JSON file
+type FileDrainStore struct {
+	path string
+}
+
+// NewFileDrainStore creates a store writing to path
+func NewFileDrainStore(path string) *FileDrainStore {
+	return &FileDrainStore{path: path}
+}
+
+// Save writes work atomically via a temp file and rename
+func (s *FileDrainStore) Save(ctx context.Context, work []InFlight) error {
+	data, err := json.MarshalIndent(work, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Load reads persisted work; a missing file means nothing to resume
+func (s *FileDrainStore) Load(ctx context.Context) ([]InFlight, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var work []InFlight
+	if err := json.Unmarshal(data, &work); err != nil {
+		return nil, err
+	}
+	return work, nil
+}
+
+// Clear removes the persisted file
+func (s *FileDrainStore) Clear(ctx context.Context) error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
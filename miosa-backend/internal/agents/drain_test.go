@@ -0,0 +1,68 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDrainer_WaitsForInFlight(t *testing.T) {
+	d := NewDrainer(nil, zap.NewNop())
+
+	finish, err := d.Begin(Task{ID: uuid.New()}, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 1, d.InFlightCount())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		finish()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, d.Drain(ctx))
+	assert.Equal(t, 0, d.InFlightCount())
+
+	_, err = d.Begin(Task{}, "test")
+	assert.ErrorIs(t, err, ErrDraining)
+}
+
+func TestDrainer_PersistsAndResumes(t *testing.T) {
+	store := NewFileDrainStore(filepath.Join(t.TempDir(), "drain.json"))
+	d := NewDrainer(store, zap.NewNop())
+
+	taskID := uuid.New()
+	_, err := d.Begin(Task{ID: taskID, Input: "build a todo app"}, "agents/execute")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Drain(ctx), context.DeadlineExceeded)
+
+	// A fresh process picks up the persisted task
+	restarted := NewDrainer(store, zap.NewNop())
+	var ran atomic.Value
+	n, err := restarted.Resume(context.Background(), func(ctx context.Context, w InFlight) error {
+		ran.Store(w)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Eventually(t, func() bool { return restarted.InFlightCount() == 0 }, time.Second, 5*time.Millisecond)
+	w := ran.Load().(InFlight)
+	assert.Equal(t, taskID, w.Task.ID)
+	assert.Equal(t, "build a todo app", w.Task.Input)
+
+	// The store is cleared so work is not resumed twice
+	work, err := store.Load(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, work)
+}
@@ -0,0 +1,226 @@
+package agents
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+)
+
+// Example is a past high-scoring agent result reused as a few-shot example
+type Example struct {
+	ID        uuid.UUID `json:"id"`
+	Agent     AgentType `json:"agent"`
+	TaskType  string    `json:"task_type"`
+	Input     string    `json:"input"`
+	Output    string    `json:"output"`
+	Score     float64   `json:"score"` // 0-10 scale
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// tokens roughly estimates the prompt tokens the example costs
+func (e *Example) tokens() int {
+	return estimateTokens(e.Input) + estimateTokens(e.Output)
+}
+
+// estimateTokens approximates token count at ~4 characters per token
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// ExampleStore persists few-shot examples
+type ExampleStore interface {
+	Add(ctx context.Context, example *Example) error
+	// Find returns examples for the agent and task type, best score first
+	Find(ctx context.Context, agent AgentType, taskType string, limit int) ([]*Example, error)
+}
+
+// MemoryExampleStore keeps the best examples per agent and task type in memory
+type MemoryExampleStore struct {
+	perKey   int
+	examples map[string][]*Example
+	mu       sync.RWMutex
+}
+
+// NewMemoryExampleStore creates a store retaining up to perKey examples for
+// each agent and task type
+func NewMemoryExampleStore(perKey int) *MemoryExampleStore {
+	if perKey <= 0 {
+		perKey = 20
+	}
+	return &MemoryExampleStore{
+		perKey:   perKey,
+		examples: make(map[string][]*Example),
+	}
+}
+
+func exampleKey(agent AgentType, taskType string) string {
+	return string(agent) + "/" + taskType
+}
+
+// Add stores the example, evicting the lowest-scoring one when full
+func (s *MemoryExampleStore) Add(ctx context.Context, example *Example) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := exampleKey(example.Agent, example.TaskType)
+	list := append(s.examples[key], example)
+	sortExamples(list)
+	if len(list) > s.perKey {
+		list = list[:s.perKey]
+	}
+	s.examples[key] = list
+	return nil
+}
+
+// Find returns examples for the task type, falling back to the agent's other
+// task types when there are not enough
+func (s *MemoryExampleStore) Find(ctx context.Context, agent AgentType, taskType string, limit int) ([]*Example, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := append([]*Example(nil), s.examples[exampleKey(agent, taskType)]...)
+	if len(found) < limit {
+		var others []*Example
+		for key, list := range s.examples {
+			if key == exampleKey(agent, taskType) || len(list) == 0 || list[0].Agent != agent {
+				continue
+			}
+			others = append(others, list...)
+		}
+		sortExamples(others)
+		found = append(found, others...)
+	}
+
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// sortExamples orders by score, newest first on ties
+func sortExamples(list []*Example) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+}
+
+// ExampleLibrary records high-confidence results and turns them into
+// few-shot prompt messages within a token budget
+type ExampleLibrary struct {
+	store       ExampleStore
+	minScore    float64
+	tokenBudget int
+	maxExamples int
+}
+
+// NewExampleLibrary creates a library keeping results scoring above minScore
+// and injecting at most tokenBudget tokens of examples per prompt
+func NewExampleLibrary(store ExampleStore, minScore float64, tokenBudget int) *ExampleLibrary {
+	return &ExampleLibrary{
+		store:       store,
+		minScore:    minScore,
+		tokenBudget: tokenBudget,
+		maxExamples: 3,
+	}
+}
+
+// DefaultExamples is used by RecordExample and WithFewShot
+var DefaultExamples = NewExampleLibrary(NewMemoryExampleStore(20), 9.0, 2000)
+
+// Record stores the result as an example if it succeeded and scored above
+// the library's threshold. It reports whether the result was kept.
+func (l *ExampleLibrary) Record(ctx context.Context, agent AgentType, task Task, result *Result, score float64) (bool, error) {
+	if l == nil || result == nil || !result.Success || score <= l.minScore || result.Output == "" {
+		return false, nil
+	}
+
+	example := &Example{
+		ID:        uuid.New(),
+		Agent:     agent,
+		TaskType:  task.Type,
+		Input:     task.Input,
+		Output:    result.Output,
+		Score:     score,
+		CreatedAt: time.Now(),
+	}
+	// A single example larger than the whole budget could never be injected
+	if example.tokens() > l.tokenBudget {
+		return false, nil
+	}
+	if err := l.store.Add(ctx, example); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Select returns the best examples for the task that fit in the budget
+func (l *ExampleLibrary) Select(ctx context.Context, agent AgentType, task Task) []*Example {
+	if l == nil {
+		return nil
+	}
+
+	candidates, err := l.store.Find(ctx, agent, task.Type, l.maxExamples*3)
+	if err != nil {
+		return nil
+	}
+
+	var selected []*Example
+	remaining := l.tokenBudget
+	for _, e := range candidates {
+		if len(selected) == l.maxExamples {
+			break
+		}
+		// Never show the model its own task as an example
+		if e.Input == task.Input {
+			continue
+		}
+		cost := e.tokens()
+		if cost > remaining {
+			continue
+		}
+		remaining -= cost
+		selected = append(selected, e)
+	}
+	return selected
+}
+
+// WithFewShot inserts example user/assistant turns after the leading system
+// messages
+func (l *ExampleLibrary) WithFewShot(ctx context.Context, agent AgentType, task Task, messages []groq.ChatCompletionMessage) []groq.ChatCompletionMessage {
+	examples := l.Select(ctx, agent, task)
+	if len(examples) == 0 {
+		return messages
+	}
+
+	split := 0
+	for split < len(messages) && messages[split].Role == "system" {
+		split++
+	}
+
+	out := make([]groq.ChatCompletionMessage, 0, len(messages)+len(examples)*2)
+	out = append(out, messages[:split]...)
+	for _, e := range examples {
+		out = append(out,
+			groq.ChatCompletionMessage{Role: "user", Content: e.Input},
+			groq.ChatCompletionMessage{Role: "assistant", Content: e.Output},
+		)
+	}
+	return append(out, messages[split:]...)
+}
+
+// RecordExample stores a result in DefaultExamples
+func RecordExample(ctx context.Context, agent AgentType, task Task, result *Result, score float64) (bool, error) {
+	return DefaultExamples.Record(ctx, agent, task, result, score)
+}
+
+// WithFewShot injects examples from DefaultExamples into messages
+func WithFewShot(ctx context.Context, agent AgentType, task Task, messages []groq.ChatCompletionMessage) []groq.ChatCompletionMessage {
+	return DefaultExamples.WithFewShot(ctx, agent, task, messages)
+}
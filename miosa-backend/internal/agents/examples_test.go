@@ -0,0 +1,57 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExampleLibrary_RecordsOnlyHighScores(t *testing.T) {
+	ctx := context.Background()
+	lib := NewExampleLibrary(NewMemoryExampleStore(5), 9.0, 1000)
+	task := Task{Type: "architecture", Input: "design a chat service"}
+
+	kept, err := lib.Record(ctx, ArchitectAgent, task, &Result{Success: true, Output: "doc"}, 8.5)
+	require.NoError(t, err)
+	assert.False(t, kept)
+
+	kept, err = lib.Record(ctx, ArchitectAgent, task, &Result{Success: false, Output: "doc"}, 9.5)
+	require.NoError(t, err)
+	assert.False(t, kept)
+
+	kept, err = lib.Record(ctx, ArchitectAgent, task, &Result{Success: true, Output: "doc"}, 9.5)
+	require.NoError(t, err)
+	assert.True(t, kept)
+}
+
+func TestExampleLibrary_WithFewShotRespectsBudget(t *testing.T) {
+	ctx := context.Background()
+	// Budget fits two ~40 token examples but not three
+	lib := NewExampleLibrary(NewMemoryExampleStore(5), 9.0, 100)
+
+	for i, score := range []float64{9.2, 9.8, 9.5} {
+		task := Task{Type: "architecture", Input: strings.Repeat("i", 40) + string(rune('a'+i))}
+		_, err := lib.Record(ctx, ArchitectAgent, task, &Result{Success: true, Output: strings.Repeat("o", 120)}, score)
+		require.NoError(t, err)
+	}
+
+	messages := lib.WithFewShot(ctx, ArchitectAgent, Task{Type: "architecture", Input: "design a blog"},
+		[]groq.ChatCompletionMessage{
+			{Role: "system", Content: "You are an architect."},
+			{Role: "user", Content: "design a blog"},
+		})
+
+	require.Len(t, messages, 6)
+	assert.EqualValues(t, "system", messages[0].Role)
+	assert.True(t, strings.HasSuffix(messages[1].Content, "b"), "highest score first")
+	assert.EqualValues(t, "assistant", messages[2].Role)
+	assert.Equal(t, "design a blog", messages[5].Content)
+
+	// Other agents get nothing
+	plain := lib.WithFewShot(ctx, DevelopmentAgent, Task{Type: "architecture"}, messages[:1])
+	assert.Len(t, plain, 1)
+}
@@ -0,0 +1,261 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/conneroisu/groq-go/pkg/groqerr"
+)
+
+// Failover reasons, from the error that made the guard move on
+const (
+	FailoverRateLimited  = "rate_limited" // HTTP 429
+	FailoverServerError  = "server_error" // HTTP 5xx
+	FailoverTimeout      = "timeout"      // The agent's LLM timeout passed
+	FailoverError        = "error"        // Any other failure
+	FailoverCircuitOpen  = "circuit_open" // The model's breaker was open
+	FailoverCooldown     = "cooldown"     // The provider was cooling down
+	FailoverUnconfigured = "unconfigured" // No client for the provider
+)
+
+// Target is one step of a failover chain: a model on a provider. An empty
+// provider is the guard's primary provider.
+type Target struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model"`
+}
+
+// ParseTarget parses "provider:model", or a bare model on the primary
+// provider. Model names may themselves contain slashes.
+func ParseTarget(s string) Target {
+	if provider, model, ok := strings.Cut(strings.TrimSpace(s), ":"); ok {
+		return Target{Provider: provider, Model: model}
+	}
+	return Target{Model: strings.TrimSpace(s)}
+}
+
+// String formats the target as ParseTarget reads it
+func (t Target) String() string {
+	if t.Provider == "" {
+		return t.Model
+	}
+	return t.Provider + ":" + t.Model
+}
+
+// FailoverEvent records the guard moving from one target to the next
+type FailoverEvent struct {
+	Agent  AgentType `json:"agent"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// AddFailover records a failover made during the execution
+func (u *Usage) AddFailover(event FailoverEvent) {
+	if u.parent != nil {
+		u.parent.AddFailover(event)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Failovers = append(u.Failovers, event)
+}
+
+// failoverReason classifies an LLM call error
+func failoverReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return FailoverTimeout
+	}
+	status := 0
+	var apiErr *groqerr.APIError
+	var reqErr *groqerr.ErrRequest
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch {
+	case status == http.StatusTooManyRequests:
+		return FailoverRateLimited
+	case status >= 500:
+		return FailoverServerError
+	default:
+		return FailoverError
+	}
+}
+
+// ProviderHealth is the observed health of one provider. A provider whose
+// calls fail with server errors or timeouts the breaker threshold times in
+// a row cools down: its targets are skipped until CooldownUntil. Rate
+// limits and request errors are the model's, not the provider's.
+type ProviderHealth struct {
+	Provider            string    `json:"provider"`
+	Healthy             bool      `json:"healthy"`
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	RateLimited         int       `json:"rate_limited"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastFailureAt       time.Time `json:"last_failure_at,omitempty"`
+	CooldownUntil       time.Time `json:"cooldown_until,omitempty"`
+}
+
+// providerHealth tracks ProviderHealth for one provider
+type providerHealth struct {
+	mu     sync.Mutex
+	health ProviderHealth
+}
+
+func (p *providerHealth) coolingDown(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return now.Before(p.health.CooldownUntil)
+}
+
+func (p *providerHealth) success() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.health.Successes++
+	p.health.ConsecutiveFailures = 0
+	p.health.CooldownUntil = time.Time{}
+}
+
+func (p *providerHealth) failure(reason string, err error, threshold int, cooldown time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.health.Failures++
+	p.health.LastError = err.Error()
+	p.health.LastFailureAt = now
+	switch reason {
+	case FailoverRateLimited:
+		p.health.RateLimited++
+		return
+	case FailoverServerError, FailoverTimeout:
+		p.health.ConsecutiveFailures++
+	default:
+		return
+	}
+	if p.health.ConsecutiveFailures >= threshold {
+		p.health.CooldownUntil = now.Add(cooldown)
+	}
+}
+
+func (p *providerHealth) snapshot(now time.Time) ProviderHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.health
+	h.Healthy = !now.Before(h.CooldownUntil)
+	return h
+}
+
+// NewLLMClient creates a client for an OpenAI-compatible endpoint, the
+// provider's default when baseURL is empty. groq-go retries 500 and 503
+// responses without delay until the context ends, so they are reported as
+// 502 instead: the guard then fails over rather than spending the agent's
+// whole timeout on a provider that is down.
+func NewLLMClient(apiKey, baseURL string) (*groq.Client, error) {
+	opts := []groq.Opts{groq.WithClient(&http.Client{Transport: noRetryTransport{http.DefaultTransport}})}
+	if baseURL != "" {
+		opts = append(opts, groq.WithBaseURL(baseURL))
+	}
+	return groq.NewClient(apiKey, opts...)
+}
+
+// noRetryTransport rewrites the statuses groq-go retries in a loop
+type noRetryTransport struct {
+	next http.RoundTripper
+}
+
+func (t noRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && (resp.StatusCode == http.StatusInternalServerError || resp.StatusCode == http.StatusServiceUnavailable) {
+		resp.StatusCode = http.StatusBadGateway
+	}
+	return resp, err
+}
+
+// AddProvider registers a client for an alternate provider that failover
+// chains can name, e.g. an OpenAI-compatible endpoint
+func (g *LLMGuard) AddProvider(name string, client *groq.Client) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.providers[name] = client
+}
+
+// SetChain sets the targets tried, in order, when an agent's requested
+// model fails, in place of the model's fallbacks. No targets clears it.
+func (g *LLMGuard) SetChain(agent AgentType, chain ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(chain) == 0 {
+		delete(g.chains, agent)
+		return
+	}
+	targets := make([]Target, 0, len(chain))
+	for _, s := range chain {
+		targets = append(targets, ParseTarget(s))
+	}
+	g.chains[agent] = targets
+}
+
+// Chains returns a copy of the per-agent failover chains
+func (g *LLMGuard) Chains() map[AgentType][]Target {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	chains := make(map[AgentType][]Target, len(g.chains))
+	for agent, chain := range g.chains {
+		chains[agent] = append([]Target(nil), chain...)
+	}
+	return chains
+}
+
+// Health returns the health of every provider called so far, by name
+func (g *LLMGuard) Health() []ProviderHealth {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	now := time.Now()
+	health := make([]ProviderHealth, 0, len(g.health))
+	for _, p := range g.health {
+		health = append(health, p.snapshot(now))
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Provider < health[j].Provider })
+	return health
+}
+
+func (g *LLMGuard) providerHealth(provider string) *providerHealth {
+	g.mu.RLock()
+	p, ok := g.health[provider]
+	g.mu.RUnlock()
+	if ok {
+		return p
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, ok := g.health[provider]; ok {
+		return p
+	}
+	p = &providerHealth{health: ProviderHealth{Provider: provider}}
+	g.health[provider] = p
+	return p
+}
+
+// client returns the client for a provider, or nil when it is unknown.
+// The primary provider uses the caller's client.
+func (g *LLMGuard) client(provider string, primary *groq.Client) *groq.Client {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if provider == g.provider {
+		return primary
+	}
+	return g.providers[provider]
+}
@@ -0,0 +1,154 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/conneroisu/groq-go/pkg/groqerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusGroq answers with status for every model in statuses and succeeds
+// for the rest
+func statusGroq(t *testing.T, calls *int32, statuses map[string]int) *groq.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if status, ok := statuses[req.Model]; ok {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":"status %d"}}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": "ok"}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewLLMClient("test-key", server.URL)
+	require.NoError(t, err)
+	return client
+}
+
+func TestParseTarget(t *testing.T) {
+	assert.Equal(t, Target{Model: "moonshotai/kimi-k2-instruct"}, ParseTarget("moonshotai/kimi-k2-instruct"))
+	assert.Equal(t, Target{Provider: "openai", Model: "gpt-4o-mini"}, ParseTarget(" openai:gpt-4o-mini "))
+	assert.Equal(t, "openai:gpt-4o-mini", ParseTarget("openai:gpt-4o-mini").String())
+}
+
+func TestFailoverReason(t *testing.T) {
+	assert.Equal(t, FailoverRateLimited, failoverReason(&groqerr.APIError{HTTPStatusCode: http.StatusTooManyRequests}))
+	assert.Equal(t, FailoverServerError, failoverReason(&groqerr.ErrRequest{HTTPStatusCode: http.StatusBadGateway}))
+	assert.Equal(t, FailoverTimeout, failoverReason(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+	assert.Equal(t, FailoverError, failoverReason(&groqerr.APIError{HTTPStatusCode: http.StatusBadRequest}))
+	assert.Equal(t, FailoverError, failoverReason(errors.New("connection refused")))
+}
+
+func TestLLMGuard_FailsOverAlongAgentChain(t *testing.T) {
+	var primaryCalls, altCalls int32
+	primary := statusGroq(t, &primaryCalls, map[string]int{"primary": http.StatusTooManyRequests, "secondary": http.StatusServiceUnavailable})
+	alternate := statusGroq(t, &altCalls, nil)
+
+	guard := NewLLMGuard()
+	guard.cooldown = time.Hour
+	guard.AddProvider("openai", alternate)
+	guard.SetChain(DevelopmentAgent, "secondary", "openai:gpt-4o-mini")
+	req := groq.ChatCompletionRequest{Model: "primary", Messages: []groq.ChatCompletionMessage{{Role: "user", Content: "hi"}}}
+
+	ctx, usage := TrackUsage(context.Background())
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := guard.ChatCompletion(ctx, primary, DevelopmentAgent, req)
+	require.NoError(t, err)
+	assert.Equal(t, groq.ChatModel("gpt-4o-mini"), resp.Model)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&altCalls))
+
+	require.Len(t, usage.Failovers, 2)
+	assert.Equal(t, "groq:primary", usage.Failovers[0].From)
+	assert.Equal(t, "groq:secondary", usage.Failovers[0].To)
+	assert.Equal(t, FailoverRateLimited, usage.Failovers[0].Reason)
+	assert.Equal(t, "groq:secondary", usage.Failovers[1].From)
+	assert.Equal(t, "openai:gpt-4o-mini", usage.Failovers[1].To)
+	assert.Equal(t, FailoverServerError, usage.Failovers[1].Reason)
+
+	// A 429 opens the model's breaker at once
+	assert.Equal(t, BreakerOpen, guard.BreakerState("primary"))
+
+	// Agents without a chain keep the model fallbacks
+	_, err = guard.ChatCompletion(ctx, primary, AnalysisAgent, groq.ChatCompletionRequest{Model: "healthy"})
+	require.NoError(t, err)
+
+	result := &Result{}
+	usage.ApplyTo(result)
+	assert.Len(t, result.Failovers, 2)
+}
+
+func TestLLMGuard_ProviderCooldown(t *testing.T) {
+	var calls int32
+	primary := statusGroq(t, &calls, map[string]int{"a": http.StatusInternalServerError, "b": http.StatusBadGateway})
+
+	guard := NewLLMGuard()
+	guard.threshold = 2
+	guard.cooldown = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, model := range []string{"a", "b"} {
+		_, err := guard.ChatCompletion(ctx, primary, DevelopmentAgent, groq.ChatCompletionRequest{Model: groq.ChatModel(model)})
+		assert.Error(t, err)
+	}
+
+	health := guard.Health()
+	require.Len(t, health, 1)
+	assert.Equal(t, "groq", health[0].Provider)
+	assert.False(t, health[0].Healthy)
+	assert.Equal(t, 2, health[0].ConsecutiveFailures)
+
+	// Every model on a cooling provider is skipped without a call
+	before := atomic.LoadInt32(&calls)
+	_, err := guard.ChatCompletion(ctx, primary, DevelopmentAgent, groq.ChatCompletionRequest{Model: "healthy"})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, before, atomic.LoadInt32(&calls))
+}
+
+type transcriptLog []TranscriptEntry
+
+func (l *transcriptLog) Record(entry TranscriptEntry) { *l = append(*l, entry) }
+
+func TestLLMGuard_RecordsTranscript(t *testing.T) {
+	var calls int32
+	client := statusGroq(t, &calls, map[string]int{"primary": http.StatusTooManyRequests})
+	guard := NewLLMGuard()
+	guard.SetFallbacks("primary", "secondary")
+
+	var log transcriptLog
+	ctx := WithTranscript(context.Background(), &log)
+	_, err := guard.ChatCompletion(ctx, client, QualityAgent, groq.ChatCompletionRequest{
+		Model:    "primary",
+		Messages: []groq.ChatCompletionMessage{{Role: groq.RoleSystem, Content: "Review code."}, {Role: groq.RoleUser, Content: "func main() {}"}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, log, 2)
+	assert.Equal(t, "groq:primary", log[0].Target)
+	assert.NotEmpty(t, log[0].Error)
+	assert.Empty(t, log[0].Response)
+	assert.Equal(t, "groq:secondary", log[1].Target)
+	assert.Equal(t, "ok", log[1].Response)
+	assert.Equal(t, []TranscriptMessage{{Role: "system", Content: "Review code."}, {Role: "user", Content: "func main() {}"}}, log[1].Messages)
+	assert.Equal(t, QualityAgent, log[1].Agent)
+}
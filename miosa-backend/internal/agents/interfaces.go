@@ -52,37 +52,62 @@ type TaskContext struct {
 	SessionID      uuid.UUID              `json:"session_id"`
 	ConsultationID uuid.UUID              `json:"consultation_id,omitempty"`
 	Phase          string                 `json:"phase"`
+	RequestID      string                 `json:"request_id,omitempty"` // API request that started the work; see package requestid
 	Memory         map[string]interface{} `json:"memory"`
 	History        []Message              `json:"history"`
+	Entries        []ContextEntry         `json:"entries,omitempty"`
+	Enrichment     *ContextEnrichment     `json:"enrichment,omitempty"` // Set per agent call; see ContextEnricher
 	Metadata       map[string]string      `json:"metadata"`
 }
 
 // Result represents the result of an agent execution
 type Result struct {
-	Success      bool                   `json:"success"`
-	Output       string                 `json:"output"`
-	Data         map[string]interface{} `json:"data"`
-	NextStep     string                 `json:"next_step,omitempty"`
-	NextAgent    AgentType              `json:"next_agent,omitempty"`
-	Confidence   float64                `json:"confidence"`
-	ExecutionMS  int64                  `json:"execution_ms"`
-	Error        error                  `json:"error,omitempty"`
-	Suggestions  []string               `json:"suggestions,omitempty"`
+	Success     bool                   `json:"success"`
+	Output      string                 `json:"output"`
+	Data        map[string]interface{} `json:"data"`
+	Files       []GeneratedFile        `json:"files,omitempty"`
+	NextStep    string                 `json:"next_step,omitempty"`
+	NextAgent   AgentType              `json:"next_agent,omitempty"`
+	Confidence  float64                `json:"confidence"`
+	ExecutionMS int64                  `json:"execution_ms"`
+	Error       error                  `json:"error,omitempty"`
+	Suggestions []string               `json:"suggestions,omitempty"`
+
+	// LLM usage summed over every call the agent made; Model and
+	// FinishReason come from the last call
+	Model             string `json:"model,omitempty"`
+	PromptTokens      int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens  int    `json:"completion_tokens,omitempty"`
+	FinishReason      string `json:"finish_reason,omitempty"`
+	PromptVersion     string `json:"prompt_version,omitempty"`      // See PromptVersion
+	PromptTokensSaved int    `json:"prompt_tokens_saved,omitempty"` // By prompt compression, see PromptCompressor
+	RequestID         string `json:"request_id,omitempty"`          // API request the calls were made for
+
+	// Failovers between LLM targets while the agent ran
+	Failovers []FailoverEvent `json:"failovers,omitempty"`
+}
+
+// GeneratedFile represents a file produced by an agent
+type GeneratedFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Type    string `json:"type"`
 }
 
 // Capability represents a capability of an agent
 type Capability struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Required    bool   `json:"required"`
-	Version     string `json:"version"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Required    bool      `json:"required"`
+	Version     string    `json:"version"`
+	Features    []Feature `json:"features,omitempty"`
 }
 
 // Message represents a message in conversation history
 type Message struct {
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	Role      string            `json:"role"`
+	Content   string            `json:"content"`
+	Timestamp time.Time         `json:"timestamp"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
@@ -201,4 +226,4 @@ const (
 	PhaseMonitoring   Phase = "monitoring"
 	PhaseOptimization Phase = "optimization"
 	PhaseExpansion    Phase = "expansion"
-)
\ No newline at end of file
+)
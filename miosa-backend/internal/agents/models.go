@@ -0,0 +1,312 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultModelsURL lists the models of the Groq API
+const DefaultModelsURL = "https://api.groq.com/openai/v1/models"
+
+// FailoverUnavailable is the failover reason for a model missing from the
+// provider's catalog
+const FailoverUnavailable = "model_unavailable"
+
+// nonChatModels mark models that cannot answer chat completions
+var nonChatModels = []string{"whisper", "tts", "guard", "embed"}
+
+// ModelInfo is a model as the provider lists it
+type ModelInfo struct {
+	ID            string `json:"id"`
+	OwnedBy       string `json:"owned_by"`
+	Active        bool   `json:"active"`
+	ContextWindow int    `json:"context_window"`
+	MaxCompletion int    `json:"max_completion_tokens,omitempty"`
+}
+
+// Chat reports whether the model answers chat completions, as opposed to
+// transcription, speech or moderation models
+func (m ModelInfo) Chat() bool {
+	id := strings.ToLower(m.ID)
+	for _, marker := range nonChatModels {
+		if strings.Contains(id, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// ModelProvider is implemented by agents that report the model they call
+type ModelProvider interface {
+	Model() string
+}
+
+// ModelCheck is the outcome of validating a configured model
+type ModelCheck struct {
+	Model      string `json:"model"`
+	Available  bool   `json:"available"`
+	Suggestion string `json:"suggestion,omitempty"` // Nearest available replacement
+}
+
+// ModelCatalog holds the models the provider currently serves, synced from
+// its models endpoint. Models seen in an earlier sync are remembered, so a
+// model that disappears is replaced by one with the same owner and at
+// least its context window.
+type ModelCatalog struct {
+	url    string
+	apiKey string
+	client *http.Client
+
+	mu       sync.RWMutex
+	models   map[string]ModelInfo
+	seen     map[string]ModelInfo
+	syncedAt time.Time
+}
+
+// NewModelCatalog creates a catalog synced from url, DefaultModelsURL when
+// empty. Until the first sync every model is taken to be available.
+func NewModelCatalog(url, apiKey string) *ModelCatalog {
+	if url == "" {
+		url = DefaultModelsURL
+	}
+	return &ModelCatalog{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+		seen:   make(map[string]ModelInfo),
+	}
+}
+
+// Sync replaces the catalog with the provider's active models and returns
+// the models that were available before and no longer are
+func (c *ModelCatalog) Sync(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list models: %s", resp.Status)
+	}
+	var list struct {
+		Data []ModelInfo `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	models := make(map[string]ModelInfo, len(list.Data))
+	for _, m := range list.Data {
+		if m.Active {
+			models[m.ID] = m
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var removed []string
+	if !c.syncedAt.IsZero() {
+		for id := range c.models {
+			if _, ok := models[id]; !ok {
+				removed = append(removed, id)
+			}
+		}
+		sort.Strings(removed)
+	}
+	for id, m := range models {
+		c.seen[id] = m
+	}
+	c.models, c.syncedAt = models, time.Now()
+	return removed, nil
+}
+
+// Watch syncs the catalog every interval until ctx is done, logging models
+// that disappear along with their suggested replacements
+func (c *ModelCatalog) Watch(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		removed, err := c.Sync(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("Failed to sync model catalog", zap.Error(err))
+			}
+			continue
+		}
+		for _, model := range removed {
+			suggestion, _ := c.Suggest(model)
+			logger.Warn("Model no longer available",
+				zap.String("model", model),
+				zap.String("suggestion", suggestion))
+		}
+	}
+}
+
+// Synced reports when the catalog was last synced, zero if never
+func (c *ModelCatalog) Synced() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.syncedAt
+}
+
+// Models returns the available models sorted by ID
+func (c *ModelCatalog) Models() []ModelInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	models := make([]ModelInfo, 0, len(c.models))
+	for _, m := range c.models {
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models
+}
+
+// Has reports whether model is available, which every model is before the
+// first sync
+func (c *ModelCatalog) Has(model string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.syncedAt.IsZero() {
+		return true
+	}
+	_, ok := c.models[model]
+	return ok
+}
+
+// Resolve returns model when it is available, otherwise its suggested
+// replacement; false when there is none
+func (c *ModelCatalog) Resolve(model string) (string, bool) {
+	if c.Has(model) {
+		return model, true
+	}
+	return c.Suggest(model)
+}
+
+// Suggest returns the available chat model nearest to model. Candidates
+// must fit at least the context window model had, when it was ever seen;
+// among them the same owner wins, then the closest name, then the larger
+// context window.
+func (c *ModelCatalog) Suggest(model string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	old, known := c.seen[model]
+	name := modelName(model)
+	var (
+		best      ModelInfo
+		bestScore = -1
+	)
+	for _, m := range c.models {
+		if m.ID == model || !m.Chat() {
+			continue
+		}
+		if known && old.ContextWindow > 0 && m.ContextWindow < old.ContextWindow {
+			continue
+		}
+		score := editDistance(name, modelName(m.ID))
+		if !sameOwner(model, old, m) {
+			score += len(name)
+		}
+		if bestScore < 0 || score < bestScore ||
+			(score == bestScore && (m.ContextWindow > best.ContextWindow ||
+				m.ContextWindow == best.ContextWindow && m.ID < best.ID)) {
+			best, bestScore = m, score
+		}
+	}
+	return best.ID, bestScore >= 0
+}
+
+// Check validates models against the catalog, suggesting replacements for
+// the missing ones
+func (c *ModelCatalog) Check(models []string) []ModelCheck {
+	checks := make([]ModelCheck, 0, len(models))
+	for _, model := range models {
+		check := ModelCheck{Model: model, Available: c.Has(model)}
+		if !check.Available {
+			check.Suggestion, _ = c.Suggest(model)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// ConfiguredModels returns the models the agents of registry call and the
+// guard routes or fails over to on its primary provider, sorted
+func ConfiguredModels(guard *LLMGuard, registry map[AgentType]Agent) []string {
+	set := make(map[string]bool)
+	for _, agent := range registry {
+		if p, ok := agent.(ModelProvider); ok && p.Model() != "" {
+			set[p.Model()] = true
+		}
+	}
+	for _, model := range guard.Models() {
+		set[model] = true
+	}
+	models := make([]string, 0, len(set))
+	for model := range set {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// modelName is the model ID without its owner prefix
+func modelName(id string) string {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		return strings.ToLower(id[i+1:])
+	}
+	return strings.ToLower(id)
+}
+
+// sameOwner compares owners, falling back to the ID prefix for a model
+// never seen
+func sameOwner(model string, old, m ModelInfo) bool {
+	if old.OwnedBy != "" {
+		return strings.EqualFold(old.OwnedBy, m.OwnedBy)
+	}
+	prefix, _, ok := strings.Cut(model, "/")
+	if ok {
+		return strings.HasPrefix(m.ID, prefix+"/")
+	}
+	family, _, _ := strings.Cut(model, "-")
+	return strings.HasPrefix(m.ID, family)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
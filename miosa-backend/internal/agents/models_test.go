@@ -0,0 +1,157 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// modelsServer lists whatever models currently holds
+type modelsServer struct {
+	mu     sync.Mutex
+	models []ModelInfo
+}
+
+func (s *modelsServer) set(models ...ModelInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = models
+}
+
+func (s *modelsServer) catalog(t *testing.T) *ModelCatalog {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": s.models})
+	}))
+	t.Cleanup(server.Close)
+	return NewModelCatalog(server.URL, "test-key")
+}
+
+var (
+	kimi     = ModelInfo{ID: "moonshotai/kimi-k2-instruct", OwnedBy: "Moonshot AI", Active: true, ContextWindow: 131072}
+	kimi0905 = ModelInfo{ID: "moonshotai/kimi-k2-instruct-0905", OwnedBy: "Moonshot AI", Active: true, ContextWindow: 262144}
+	llama70b = ModelInfo{ID: "llama-3.3-70b-versatile", OwnedBy: "Meta", Active: true, ContextWindow: 131072}
+	llama8b  = ModelInfo{ID: "llama-3.1-8b-instant", OwnedBy: "Meta", Active: true, ContextWindow: 131072}
+	guard    = ModelInfo{ID: "meta-llama/llama-guard-4-12b", OwnedBy: "Meta", Active: true, ContextWindow: 131072}
+	whisper  = ModelInfo{ID: "whisper-large-v3", OwnedBy: "OpenAI", Active: true, ContextWindow: 448}
+)
+
+func TestModelCatalog_Sync(t *testing.T) {
+	provider := &modelsServer{}
+	provider.set(kimi, llama70b, llama8b, whisper, ModelInfo{ID: "retired", Active: false})
+	catalog := provider.catalog(t)
+
+	// Nothing is rejected before the first sync
+	assert.True(t, catalog.Has("anything"))
+	assert.True(t, catalog.Synced().IsZero())
+
+	removed, err := catalog.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	assert.Len(t, catalog.Models(), 4)
+	assert.Equal(t, "llama-3.1-8b-instant", catalog.Models()[0].ID)
+	assert.False(t, catalog.Has("retired"), "inactive models are not available")
+	assert.False(t, catalog.Has("anything"))
+
+	provider.set(kimi0905, llama70b, llama8b, whisper, guard)
+	removed, err = catalog.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"moonshotai/kimi-k2-instruct"}, removed)
+
+	assert.Equal(t, []ModelCheck{
+		{Model: "llama-3.3-70b-versatile", Available: true},
+		{Model: "moonshotai/kimi-k2-instruct", Suggestion: "moonshotai/kimi-k2-instruct-0905"},
+	}, catalog.Check([]string{"llama-3.3-70b-versatile", "moonshotai/kimi-k2-instruct"}))
+
+	_, err = NewModelCatalog(catalog.url, "wrong-key").Sync(context.Background())
+	assert.Error(t, err)
+}
+
+func TestModelCatalog_Suggest(t *testing.T) {
+	provider := &modelsServer{}
+	provider.set(llama70b, llama8b, guard, whisper, kimi0905)
+	catalog := provider.catalog(t)
+	_, err := catalog.Sync(context.Background())
+	require.NoError(t, err)
+
+	for model, want := range map[string]string{
+		"llama-3.1-70b-versatile":     "llama-3.3-70b-versatile",
+		"llama3-8b-8192":              "llama-3.1-8b-instant",
+		"moonshotai/kimi-k2-instruct": "moonshotai/kimi-k2-instruct-0905",
+	} {
+		got, ok := catalog.Suggest(model)
+		assert.True(t, ok, model)
+		assert.Equal(t, want, got, model)
+	}
+
+	// A replacement must fit the context window the model had
+	provider.set(kimi, llama70b)
+	_, err = catalog.Sync(context.Background())
+	require.NoError(t, err)
+	provider.set(ModelInfo{ID: "moonshotai/kimi-lite", OwnedBy: "Moonshot AI", Active: true, ContextWindow: 8192}, llama70b)
+	_, err = catalog.Sync(context.Background())
+	require.NoError(t, err)
+	got, ok := catalog.Suggest("moonshotai/kimi-k2-instruct")
+	assert.True(t, ok)
+	assert.Equal(t, "llama-3.3-70b-versatile", got)
+
+	// Only chat models replace chat models
+	provider.set(whisper, guard)
+	_, err = catalog.Sync(context.Background())
+	require.NoError(t, err)
+	_, ok = catalog.Resolve("llama-3.3-70b-versatile")
+	assert.False(t, ok)
+}
+
+func TestLLMGuard_ReplacesUnavailableModels(t *testing.T) {
+	provider := &modelsServer{}
+	provider.set(llama70b, llama8b)
+	catalog := provider.catalog(t)
+	_, err := catalog.Sync(context.Background())
+	require.NoError(t, err)
+
+	var calls int32
+	client := statusGroq(t, &calls, nil)
+	g := NewLLMGuard()
+	g.SetCatalog(catalog)
+	g.SetFallbacks("llama-3.1-70b-versatile", "llama-3.1-8b-instant")
+	assert.ElementsMatch(t, []string{"llama-3.1-70b-versatile", "llama-3.1-8b-instant"}, g.Models())
+
+	ctx, usage := TrackUsage(context.Background())
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := g.ChatCompletion(ctx, client, AnalysisAgent, groq.ChatCompletionRequest{Model: "llama-3.1-70b-versatile"})
+	require.NoError(t, err)
+	assert.Equal(t, groq.ChatModel("llama-3.3-70b-versatile"), resp.Model)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	require.Len(t, usage.Failovers, 1)
+	assert.Equal(t, FailoverUnavailable, usage.Failovers[0].Reason)
+	assert.Equal(t, "groq:llama-3.3-70b-versatile", usage.Failovers[0].To)
+
+	agents := map[AgentType]Agent{AnalysisAgent: modelAgent("moonshotai/kimi-k2-instruct")}
+	assert.Equal(t, []string{"llama-3.1-70b-versatile", "llama-3.1-8b-instant", "moonshotai/kimi-k2-instruct"}, ConfiguredModels(g, agents))
+}
+
+type modelAgent string
+
+func (a modelAgent) GetType() AgentType            { return AnalysisAgent }
+func (a modelAgent) GetCapabilities() []Capability { return nil }
+func (a modelAgent) GetDescription() string        { return "model test agent" }
+func (a modelAgent) Model() string                 { return string(a) }
+func (a modelAgent) Execute(context.Context, Task) (*Result, error) {
+	return &Result{Success: true}, nil
+}
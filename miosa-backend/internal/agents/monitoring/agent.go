@@ -34,6 +34,11 @@ func (a *MonitoringAgent) GetDescription() string {
 	return "Sets up monitoring, logging, and observability"
 }
 
+// Model returns the model the agent calls
+func (a *MonitoringAgent) Model() string {
+	return a.config.Model
+}
+
 func (a *MonitoringAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
 		{Name: "monitoring", Description: "Setup monitoring", Required: true},
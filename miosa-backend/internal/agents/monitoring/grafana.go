@@ -0,0 +1,254 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GrafanaClient provisions dashboards through the Grafana HTTP API
+type GrafanaClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// DashboardResult describes a dashboard created or updated in Grafana
+type DashboardResult struct {
+	UID     string `json:"uid"`
+	URL     string `json:"url"`
+	Version int    `json:"version"`
+	Status  string `json:"status"`
+}
+
+// DashboardOwner is the tenant and workflow a dashboard is provisioned for
+type DashboardOwner struct {
+	TenantID   string
+	WorkflowID string
+}
+
+// tag marks dashboards provisioned for the owner's workflow
+func (o DashboardOwner) tag() string {
+	return "miosa-workflow:" + o.WorkflowID
+}
+
+// DashboardUID derives the UID of the owner's dashboard with the given
+// title. Each workflow's dashboards get their own UIDs, so workflows of the
+// same or different tenants never replace each other's dashboards.
+func DashboardUID(owner DashboardOwner, title string) string {
+	sum := sha256.Sum256([]byte(owner.TenantID + "/" + owner.WorkflowID + "/" + title))
+	return "miosa-" + hex.EncodeToString(sum[:])[:32]
+}
+
+// statusError is a non-2xx response from Grafana
+type statusError struct {
+	status string
+	code   int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("grafana returned %s: %s", e.status, e.body)
+}
+
+// NewGrafanaClient creates a client for the Grafana instance at baseURL
+// authenticated with a service account token or API key
+func NewGrafanaClient(baseURL, apiKey string) *GrafanaClient {
+	return &GrafanaClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// ValidateDashboard parses generated dashboard JSON, tolerating surrounding
+// markdown fences, and checks the fields Grafana requires. The returned
+// model has its numeric id removed so it can be imported into any instance.
+func ValidateDashboard(raw []byte) (map[string]interface{}, error) {
+	text := string(raw)
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("dashboard does not contain a JSON object")
+	}
+
+	var dashboard map[string]interface{}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &dashboard); err != nil {
+		return nil, fmt.Errorf("invalid dashboard JSON: %w", err)
+	}
+
+	// Generated output sometimes wraps the model like the import API does
+	if inner, ok := dashboard["dashboard"].(map[string]interface{}); ok {
+		dashboard = inner
+	}
+
+	title, _ := dashboard["title"].(string)
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("dashboard is missing a title")
+	}
+
+	if panels, exists := dashboard["panels"]; exists {
+		list, ok := panels.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("dashboard panels must be an array")
+		}
+		for i, p := range list {
+			panel, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("panel %d is not an object", i)
+			}
+			if _, ok := panel["type"].(string); !ok {
+				return nil, fmt.Errorf("panel %d is missing a type", i)
+			}
+		}
+	}
+
+	delete(dashboard, "id")
+	return dashboard, nil
+}
+
+// EnsureFolder returns the UID of the folder with the given title,
+// creating it if it does not exist
+func (c *GrafanaClient) EnsureFolder(ctx context.Context, title string) (string, error) {
+	var folders []struct {
+		UID   string `json:"uid"`
+		Title string `json:"title"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/folders", nil, &folders); err != nil {
+		return "", fmt.Errorf("failed to list folders: %w", err)
+	}
+	for _, f := range folders {
+		if f.Title == title {
+			return f.UID, nil
+		}
+	}
+
+	var created struct {
+		UID string `json:"uid"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/folders", map[string]string{"title": title}, &created); err != nil {
+		return "", fmt.Errorf("failed to create folder: %w", err)
+	}
+	return created.UID, nil
+}
+
+// UpsertDashboard validates a dashboard and saves it in the given folder
+// under a UID derived from owner and its title. An existing dashboard is
+// only overwritten when the same workflow provisioned it. The returned URL
+// is absolute.
+func (c *GrafanaClient) UpsertDashboard(ctx context.Context, raw []byte, folderUID string, owner DashboardOwner) (*DashboardResult, error) {
+	dashboard, err := ValidateDashboard(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	title := dashboard["title"].(string)
+	uid := DashboardUID(owner, title)
+	dashboard["uid"] = uid
+	if len(owner.WorkflowID) >= 8 {
+		// Titles are unique per folder, so each workflow's copy is told apart
+		dashboard["title"] = fmt.Sprintf("%s (%s)", title, owner.WorkflowID[:8])
+	}
+	tags, _ := dashboard["tags"].([]interface{})
+	tags = append(tags, "miosa", owner.tag())
+	if owner.TenantID != "" {
+		tags = append(tags, "miosa-tenant:"+owner.TenantID)
+	}
+	dashboard["tags"] = tags
+
+	overwrite, err := c.ownsDashboard(ctx, uid, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{
+		"dashboard": dashboard,
+		"folderUid": folderUID,
+		"overwrite": overwrite,
+		"message":   "Provisioned by MIOSA monitoring agent",
+	}
+
+	var result DashboardResult
+	if err := c.do(ctx, http.MethodPost, "/api/dashboards/db", payload, &result); err != nil {
+		return nil, fmt.Errorf("failed to save dashboard: %w", err)
+	}
+	if strings.HasPrefix(result.URL, "/") {
+		result.URL = c.baseURL + result.URL
+	}
+	return &result, nil
+}
+
+// ownsDashboard reports whether a dashboard exists at uid and was
+// provisioned for owner's workflow. It fails if another workflow's
+// dashboard is there.
+func (c *GrafanaClient) ownsDashboard(ctx context.Context, uid string, owner DashboardOwner) (bool, error) {
+	var existing struct {
+		Dashboard struct {
+			Tags []string `json:"tags"`
+		} `json:"dashboard"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/dashboards/uid/"+uid, nil, &existing)
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up dashboard: %w", err)
+	}
+	for _, tag := range existing.Dashboard.Tags {
+		if tag == owner.tag() {
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("dashboard %s was not provisioned by workflow %s", uid, owner.WorkflowID)
+}
+
+// do sends a JSON request to Grafana and decodes the response into out
+func (c *GrafanaClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{status: resp.Status, code: resp.StatusCode, body: strings.TrimSpace(string(respBody))}
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,193 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGrafana serves the folder and dashboard endpoints, recording the
+// folders created, the dashboards stored by UID and the last save request
+type fakeGrafana struct {
+	folders    []map[string]string
+	created    []string
+	dashboards map[string]map[string]interface{}
+	saved      map[string]interface{}
+	auth       string
+}
+
+func (f *fakeGrafana) serve(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders":
+			json.NewEncoder(w).Encode(f.folders)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/folders":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			f.created = append(f.created, body["title"])
+			f.folders = append(f.folders, map[string]string{"uid": "new-folder", "title": body["title"]})
+			json.NewEncoder(w).Encode(map[string]string{"uid": "new-folder"})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/"):
+			dashboard, ok := f.dashboards[strings.TrimPrefix(r.URL.Path, "/api/dashboards/uid/")]
+			if !ok {
+				http.Error(w, `{"message":"Dashboard not found"}`, http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"dashboard": dashboard})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&f.saved))
+			dashboard := f.saved["dashboard"].(map[string]interface{})
+			uid := dashboard["uid"].(string)
+			if f.dashboards == nil {
+				f.dashboards = make(map[string]map[string]interface{})
+			}
+			f.dashboards[uid] = dashboard
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"uid": uid, "url": "/d/" + uid + "/service", "version": 2, "status": "success",
+			})
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGrafanaClient_EnsureFolder(t *testing.T) {
+	fake := &fakeGrafana{folders: []map[string]string{{"uid": "ops", "title": "Operations"}}}
+	client := NewGrafanaClient(fake.serve(t).URL+"/", "token")
+
+	uid, err := client.EnsureFolder(context.Background(), "Operations")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", uid)
+	assert.Empty(t, fake.created, "existing folder is reused")
+	assert.Equal(t, "Bearer token", fake.auth)
+
+	uid, err = client.EnsureFolder(context.Background(), "MIOSA")
+	require.NoError(t, err)
+	assert.Equal(t, "new-folder", uid)
+	assert.Equal(t, []string{"MIOSA"}, fake.created)
+
+	// The folder created above is found on the next lookup
+	uid, err = client.EnsureFolder(context.Background(), "MIOSA")
+	require.NoError(t, err)
+	assert.Equal(t, "new-folder", uid)
+	assert.Len(t, fake.created, 1)
+}
+
+func TestGrafanaClient_EnsureFolderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewGrafanaClient(server.URL, "bad").EnsureFolder(context.Background(), "MIOSA")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestGrafanaClient_UpsertDashboard(t *testing.T) {
+	fake := &fakeGrafana{}
+	server := fake.serve(t)
+	client := NewGrafanaClient(server.URL, "")
+	owner := DashboardOwner{TenantID: "tenant-a", WorkflowID: "0123456789abcdef"}
+	uid := DashboardUID(owner, "Service")
+
+	raw := "```json\n" + `{"dashboard": {"id": 7, "uid": "dash1", "title": "Service", "tags": ["api"], "panels": [{"type": "timeseries"}]}}` + "\n```"
+	result, err := client.UpsertDashboard(context.Background(), []byte(raw), "ops", owner)
+	require.NoError(t, err)
+	assert.Equal(t, uid, result.UID)
+	assert.Equal(t, server.URL+"/d/"+uid+"/service", result.URL)
+	assert.Equal(t, 2, result.Version)
+	assert.Empty(t, fake.auth)
+
+	assert.Equal(t, false, fake.saved["overwrite"], "a new dashboard never replaces another")
+	assert.Equal(t, "ops", fake.saved["folderUid"])
+	dashboard := fake.saved["dashboard"].(map[string]interface{})
+	assert.Equal(t, "Service (01234567)", dashboard["title"])
+	assert.Equal(t, uid, dashboard["uid"], "generated uid is replaced")
+	assert.Equal(t, []interface{}{"api", "miosa", "miosa-workflow:0123456789abcdef", "miosa-tenant:tenant-a"}, dashboard["tags"])
+	assert.NotContains(t, dashboard, "id", "numeric id is dropped so any instance accepts it")
+
+	// The same workflow updates its own dashboard
+	_, err = client.UpsertDashboard(context.Background(), []byte(raw), "ops", owner)
+	require.NoError(t, err)
+	assert.Equal(t, true, fake.saved["overwrite"])
+	assert.Len(t, fake.dashboards, 1)
+}
+
+func TestGrafanaClient_UpsertDashboardKeepsWorkflowsApart(t *testing.T) {
+	fake := &fakeGrafana{}
+	client := NewGrafanaClient(fake.serve(t).URL, "")
+	raw := []byte(`{"title": "Service", "panels": []}`)
+
+	owners := []DashboardOwner{
+		{TenantID: "tenant-a", WorkflowID: "aaaaaaaa-1"},
+		{TenantID: "tenant-a", WorkflowID: "bbbbbbbb-2"},
+		{TenantID: "tenant-b", WorkflowID: "aaaaaaaa-1"},
+	}
+	for _, owner := range owners {
+		_, err := client.UpsertDashboard(context.Background(), raw, "", owner)
+		require.NoError(t, err)
+		assert.Equal(t, false, fake.saved["overwrite"])
+	}
+	assert.Len(t, fake.dashboards, 3)
+}
+
+func TestGrafanaClient_UpsertDashboardRefusesForeignDashboard(t *testing.T) {
+	owner := DashboardOwner{TenantID: "tenant-a", WorkflowID: "aaaaaaaa-1"}
+	uid := DashboardUID(owner, "Service")
+	fake := &fakeGrafana{dashboards: map[string]map[string]interface{}{
+		uid: {"uid": uid, "title": "Service", "tags": []string{"handmade"}},
+	}}
+	client := NewGrafanaClient(fake.serve(t).URL, "")
+
+	_, err := client.UpsertDashboard(context.Background(), []byte(`{"title": "Service"}`), "", owner)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not provisioned by workflow")
+	assert.Nil(t, fake.saved)
+}
+
+func TestGrafanaClient_UpsertDashboardRejectsInvalid(t *testing.T) {
+	fake := &fakeGrafana{}
+	client := NewGrafanaClient(fake.serve(t).URL, "")
+
+	_, err := client.UpsertDashboard(context.Background(), []byte(`{"panels": []}`), "", DashboardOwner{})
+	assert.Error(t, err)
+	assert.Nil(t, fake.saved, "invalid dashboards are not sent")
+}
+
+func TestValidateDashboard(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  string
+	}{
+		{"not json", "no dashboard here", "does not contain a JSON object"},
+		{"malformed", `{"title": "x",}`, "invalid dashboard JSON"},
+		{"missing title", `{"panels": []}`, "missing a title"},
+		{"blank title", `{"title": "  "}`, "missing a title"},
+		{"panels not array", `{"title": "x", "panels": {}}`, "panels must be an array"},
+		{"panel not object", `{"title": "x", "panels": [1]}`, "panel 0 is not an object"},
+		{"panel without type", `{"title": "x", "panels": [{"type": "stat"}, {"title": "p"}]}`, "panel 1 is missing a type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateDashboard([]byte(tt.raw))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	dashboard, err := ValidateDashboard([]byte(`{"id": 3, "title": "Service"}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"title": "Service"}, dashboard)
+}
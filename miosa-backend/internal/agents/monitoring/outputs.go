@@ -0,0 +1,62 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// Artifact kinds the monitoring agent's output is sniffed for
+const (
+	OutputPrometheus       = "prometheus"
+	OutputPrometheusRules  = "prometheus-rules"
+	OutputGrafanaDashboard = "grafana-dashboard"
+)
+
+var (
+	yamlScrapeConfigs = regexp.MustCompile(`(?m)^scrape_configs:`)
+	yamlGroups        = regexp.MustCompile(`(?m)^groups:`)
+	yamlAlert         = regexp.MustCompile(`(?m)^\s*(-\s+)?alert:`)
+)
+
+// OutputHandlers saves Prometheus configs and alerting rules, and Grafana
+// dashboards, found in the agent's output
+func (a *MonitoringAgent) OutputHandlers() []agents.OutputHandler {
+	return []agents.OutputHandler{
+		{
+			Kind: OutputPrometheus,
+			Detect: func(b agents.OutputBlock) bool {
+				return yamlScrapeConfigs.MatchString(b.Content)
+			},
+			Path: agents.FixedPath("monitoring/prometheus.yml"),
+		},
+		{
+			Kind: OutputPrometheusRules,
+			Detect: func(b agents.OutputBlock) bool {
+				return yamlGroups.MatchString(b.Content) && yamlAlert.MatchString(b.Content)
+			},
+			Path: agents.FixedPath("monitoring/alerts.yml"),
+		},
+		{
+			Kind:   OutputGrafanaDashboard,
+			Detect: isDashboard,
+			Path:   agents.FixedPath("monitoring/grafana-dashboard.json"),
+		},
+	}
+}
+
+// isDashboard reports whether the block is a JSON object with panels,
+// possibly wrapped like the import API's body
+func isDashboard(b agents.OutputBlock) bool {
+	var model struct {
+		Panels    json.RawMessage `json:"panels"`
+		Dashboard *struct {
+			Panels json.RawMessage `json:"panels"`
+		} `json:"dashboard"`
+	}
+	if err := json.Unmarshal([]byte(b.Content), &model); err != nil {
+		return false
+	}
+	return model.Panels != nil || model.Dashboard != nil && model.Dashboard.Panels != nil
+}
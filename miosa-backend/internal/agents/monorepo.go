@@ -0,0 +1,258 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MonorepoKey is the task parameter carrying the requested *MonorepoPlan,
+// and the Result.Data and TaskContext.Memory key of the plan the architect
+// declared from it
+const MonorepoKey = "monorepo"
+
+// Monorepo tools
+const (
+	MonorepoPNPM = "pnpm" // pnpm workspaces
+	MonorepoGo   = "go"   // Go workspaces
+)
+
+// SharedPackage is code several services import instead of each keeping a copy
+type SharedPackage struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"` // Relative to the repository root
+	Purpose string   `json:"purpose,omitempty"`
+	Exports []string `json:"exports,omitempty"` // Types and functions services use
+}
+
+// MonorepoService is one deployable service of the repository
+type MonorepoService struct {
+	Name string   `json:"name"`
+	Path string   `json:"path"`
+	Uses []string `json:"uses,omitempty"` // Names of the shared packages it imports
+}
+
+// MonorepoPlan lays services out in one repository around shared packages
+type MonorepoPlan struct {
+	Tool     string            `json:"tool"`
+	Module   string            `json:"module"` // Go module path, or npm scope without the @
+	Services []MonorepoService `json:"services"`
+	Shared   []SharedPackage   `json:"shared,omitempty"`
+}
+
+// NewMonorepoPlan places services in the tool's conventional directories;
+// the architect declares the shared packages
+func NewMonorepoPlan(tool, module string, services []string) *MonorepoPlan {
+	p := &MonorepoPlan{Tool: tool, Module: module}
+	for _, name := range services {
+		p.Services = append(p.Services, MonorepoService{Name: name, Path: path.Join(p.serviceDir(), name)})
+	}
+	return p
+}
+
+// serviceDir and sharedDir are where services and shared packages live
+func (p *MonorepoPlan) serviceDir() string {
+	if p.Tool == MonorepoGo {
+		return "services"
+	}
+	return "apps"
+}
+
+func (p *MonorepoPlan) sharedDir() string {
+	if p.Tool == MonorepoGo {
+		return "pkg"
+	}
+	return "packages"
+}
+
+// Declare adds shared packages, placing those without a path in the
+// tool's shared directory
+func (p *MonorepoPlan) Declare(shared ...SharedPackage) {
+	for _, s := range shared {
+		if s.Path == "" {
+			s.Path = path.Join(p.sharedDir(), s.Name)
+		}
+		p.Shared = append(p.Shared, s)
+	}
+}
+
+// ImportPath is how services import a shared package
+func (p *MonorepoPlan) ImportPath(s SharedPackage) string {
+	if p.Tool == MonorepoGo {
+		return p.Module + "/" + s.Path
+	}
+	return "@" + p.Module + "/" + s.Name
+}
+
+// Prompt states the layout for the agents generating the code
+func (p *MonorepoPlan) Prompt() string {
+	var sb strings.Builder
+	switch p.Tool {
+	case MonorepoGo:
+		fmt.Fprintf(&sb, "Monorepo layout (Go workspace, module %s):", p.Module)
+	default:
+		fmt.Fprintf(&sb, "Monorepo layout (pnpm workspaces, scope @%s):", p.Module)
+	}
+	for _, s := range p.Services {
+		fmt.Fprintf(&sb, "\n- Service %s in %s/", s.Name, s.Path)
+		if len(s.Uses) > 0 {
+			fmt.Fprintf(&sb, ", importing %s", strings.Join(s.Uses, ", "))
+		}
+	}
+	for _, s := range p.Shared {
+		fmt.Fprintf(&sb, "\n- Shared package %s in %s/, imported as %s", s.Name, s.Path, p.ImportPath(s))
+		if s.Purpose != "" {
+			sb.WriteString(": " + s.Purpose)
+		}
+		if len(s.Exports) > 0 {
+			fmt.Fprintf(&sb, " (exports %s)", strings.Join(s.Exports, ", "))
+		}
+	}
+	sb.WriteString("\nPut code used by more than one service in a shared package and import it; never copy shared types or utilities into a service.")
+	if p.Tool == MonorepoGo {
+		sb.WriteString(" Each service and shared package has its own go.mod; go.work is generated for you.")
+	} else {
+		sb.WriteString(" Each service and shared package has its own package.json named @" + p.Module + "/<name>; services depend on shared packages with \"workspace:*\". pnpm-workspace.yaml is generated for you.")
+	}
+	return sb.String()
+}
+
+// packageName is a valid service or shared package name
+var packageName = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+
+// ValidPackageName reports whether name can name a service or shared package
+func ValidPackageName(name string) bool {
+	return packageName.MatchString(name)
+}
+
+// DesignPrompt asks the architect which code the plan's services share
+func (p *MonorepoPlan) DesignPrompt(input string) string {
+	names := make([]string, len(p.Services))
+	for i, s := range p.Services {
+		names[i] = s.Name
+	}
+	return fmt.Sprintf(`Design the shared packages of a monorepo with the services %s for:
+
+%s
+
+Declare a package for every type or utility more than one service needs, and which services import it.
+Respond with only JSON: {"shared": [{"name": "lowercase-name", "purpose": "...", "exports": ["TypeOrFunction"]}], "services": [{"name": "service", "uses": ["lowercase-name"]}]}`,
+		strings.Join(names, ", "), input)
+}
+
+// Designed returns a copy of the plan with the shared packages an
+// architect's JSON response declares
+func (p *MonorepoPlan) Designed(response string) (*MonorepoPlan, error) {
+	var design struct {
+		Shared   []SharedPackage `json:"shared"`
+		Services []struct {
+			Name string   `json:"name"`
+			Uses []string `json:"uses"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal([]byte(stripJSONFences(response)), &design); err != nil {
+		return nil, fmt.Errorf("failed to parse monorepo design: %w", err)
+	}
+
+	out := &MonorepoPlan{Tool: p.Tool, Module: p.Module, Services: append([]MonorepoService(nil), p.Services...)}
+	names := make(map[string]bool)
+	for _, s := range out.Services {
+		names[s.Name] = true
+	}
+	declared := make(map[string]bool)
+	for _, s := range design.Shared {
+		switch {
+		case !ValidPackageName(s.Name):
+			return nil, fmt.Errorf("invalid shared package name %q", s.Name)
+		case names[s.Name] || declared[s.Name]:
+			return nil, fmt.Errorf("shared package %q is declared twice or names a service", s.Name)
+		}
+		declared[s.Name] = true
+		out.Declare(SharedPackage{Name: s.Name, Purpose: s.Purpose, Exports: s.Exports})
+	}
+	for _, d := range design.Services {
+		i := out.service(d.Name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown service %q", d.Name)
+		}
+		for _, use := range d.Uses {
+			if !declared[use] {
+				return nil, fmt.Errorf("service %s uses undeclared package %q", d.Name, use)
+			}
+		}
+		out.Services[i].Uses = d.Uses
+	}
+	return out, nil
+}
+
+// WithSharedDefault returns a copy of the plan with one package, "shared",
+// that every service imports
+func (p *MonorepoPlan) WithSharedDefault() *MonorepoPlan {
+	out := &MonorepoPlan{Tool: p.Tool, Module: p.Module}
+	for _, s := range p.Services {
+		s.Uses = []string{"shared"}
+		out.Services = append(out.Services, s)
+	}
+	out.Declare(SharedPackage{Name: "shared", Purpose: "types and utilities used by more than one service"})
+	return out
+}
+
+// service returns the index of the named service, -1 when there is none
+func (p *MonorepoPlan) service(name string) int {
+	for i, s := range p.Services {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// WorkspaceFiles are the root files tying the repository together
+func (p *MonorepoPlan) WorkspaceFiles() []GeneratedFile {
+	var dirs []string
+	for _, s := range p.Services {
+		dirs = append(dirs, s.Path)
+	}
+	for _, s := range p.Shared {
+		dirs = append(dirs, s.Path)
+	}
+
+	if p.Tool == MonorepoGo {
+		var sb strings.Builder
+		sb.WriteString("go 1.22\n\nuse (\n")
+		for _, dir := range dirs {
+			sb.WriteString("\t./" + dir + "\n")
+		}
+		sb.WriteString(")\n")
+		return []GeneratedFile{{Path: "go.work", Content: sb.String(), Type: "config"}}
+	}
+
+	workspace := fmt.Sprintf("packages:\n  - %q\n  - %q\n", p.serviceDir()+"/*", p.sharedDir()+"/*")
+	root := fmt.Sprintf(`{
+  "name": "@%s/root",
+  "private": true,
+  "scripts": {
+    "build": "pnpm -r build",
+    "test": "pnpm -r test"
+  }
+}
+`, p.Module)
+	return []GeneratedFile{
+		{Path: "pnpm-workspace.yaml", Content: workspace, Type: "config"},
+		{Path: "package.json", Content: root, Type: "config"},
+	}
+}
+
+// TaskMonorepo returns the plan the architect declared for the task or,
+// before it has, the requested one; nil without a monorepo layout
+func TaskMonorepo(task Task) *MonorepoPlan {
+	if task.Context != nil {
+		if p, ok := task.Context.Memory[MonorepoKey].(*MonorepoPlan); ok {
+			return p
+		}
+	}
+	p, _ := task.Parameters[MonorepoKey].(*MonorepoPlan)
+	return p
+}
@@ -0,0 +1,60 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonorepoPlan_Designed(t *testing.T) {
+	plan := NewMonorepoPlan(MonorepoGo, "example.com/shop", []string{"orders", "billing"})
+	assert.Equal(t, "services/billing", plan.Services[1].Path)
+
+	designed, err := plan.Designed("```json\n" + `{
+		"shared": [{"name": "models", "purpose": "domain types", "exports": ["Order"]}],
+		"services": [{"name": "orders", "uses": ["models"]}, {"name": "billing", "uses": ["models"]}]
+	}` + "\n```")
+	require.NoError(t, err)
+	assert.Empty(t, plan.Shared, "the requested plan is left as it was")
+	require.Len(t, designed.Shared, 1)
+	assert.Equal(t, "pkg/models", designed.Shared[0].Path)
+	assert.Equal(t, "example.com/shop/pkg/models", designed.ImportPath(designed.Shared[0]))
+	assert.Equal(t, []string{"models"}, designed.Services[1].Uses)
+	assert.Contains(t, designed.Prompt(), "- Shared package models in pkg/models/, imported as example.com/shop/pkg/models: domain types (exports Order)")
+
+	for response, msg := range map[string]string{
+		`{"shared": [{"name": "Models"}]}`:                                                     `invalid shared package name "Models"`,
+		`{"shared": [{"name": "orders"}]}`:                                                     `shared package "orders" is declared twice or names a service`,
+		`{"services": [{"name": "payments"}]}`:                                                 `unknown service "payments"`,
+		`{"shared": [{"name": "models"}], "services": [{"name": "orders", "uses": ["util"]}]}`: `service orders uses undeclared package "util"`,
+		`not json`: "failed to parse monorepo design",
+	} {
+		_, err := plan.Designed(response)
+		assert.ErrorContains(t, err, msg, response)
+	}
+}
+
+func TestMonorepoPlan_WorkspaceFiles(t *testing.T) {
+	plan := NewMonorepoPlan(MonorepoGo, "example.com/shop", []string{"orders"}).WithSharedDefault()
+	assert.Equal(t, []GeneratedFile{{Path: "go.work", Content: "go 1.22\n\nuse (\n\t./services/orders\n\t./pkg/shared\n)\n", Type: "config"}}, plan.WorkspaceFiles())
+
+	plan = NewMonorepoPlan(MonorepoPNPM, "shop", []string{"web", "api"}).WithSharedDefault()
+	assert.Equal(t, "@shop/shared", plan.ImportPath(plan.Shared[0]))
+	files := plan.WorkspaceFiles()
+	require.Len(t, files, 2)
+	assert.Equal(t, "packages:\n  - \"apps/*\"\n  - \"packages/*\"\n", files[0].Content)
+	assert.Contains(t, files[1].Content, `"name": "@shop/root"`)
+}
+
+func TestTaskMonorepo(t *testing.T) {
+	requested := NewMonorepoPlan(MonorepoPNPM, "shop", []string{"web", "api"})
+	task := Task{Parameters: map[string]interface{}{MonorepoKey: requested}, Context: &TaskContext{Memory: map[string]interface{}{}}}
+	assert.Same(t, requested, TaskMonorepo(task))
+
+	declared := requested.WithSharedDefault()
+	task.Context.Record(ArchitectAgent, &Result{Success: true, Output: "design", Data: map[string]interface{}{MonorepoKey: declared}})
+	assert.Same(t, declared, TaskMonorepo(task), "later agents follow the architect's plan")
+
+	assert.Nil(t, TaskMonorepo(Task{}))
+}
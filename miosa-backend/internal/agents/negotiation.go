@@ -0,0 +1,124 @@
+package agents
+
+import "fmt"
+
+// Feature represents an optional protocol feature an agent may support
+type Feature string
+
+const (
+	FeatureStreaming Feature = "streaming"
+	FeatureManifest  Feature = "structured_manifest"
+	FeatureToolCalls Feature = "tool_calls"
+	FeaturePatchMode Feature = "patch_mode"
+)
+
+// FeatureParam is the task parameter carrying the negotiated feature set
+const FeatureParam = "features"
+
+// FeatureProvider is implemented by agents that advertise optional features
+// directly rather than through their capabilities.
+type FeatureProvider interface {
+	SupportedFeatures() []Feature
+}
+
+// SupportedFeatures returns every optional feature the agent declares,
+// either through FeatureProvider or on any of its capabilities. Agents that
+// declare nothing are treated as legacy and support no optional features.
+func SupportedFeatures(agent Agent) map[Feature]bool {
+	supported := make(map[Feature]bool)
+	if provider, ok := agent.(FeatureProvider); ok {
+		for _, f := range provider.SupportedFeatures() {
+			supported[f] = true
+		}
+	}
+	for _, c := range agent.GetCapabilities() {
+		for _, f := range c.Features {
+			supported[f] = true
+		}
+	}
+	return supported
+}
+
+// Negotiate returns the subset of requested features the agent supports,
+// preserving request order.
+func Negotiate(agent Agent, requested ...Feature) []Feature {
+	if len(requested) == 0 {
+		return nil
+	}
+	supported := SupportedFeatures(agent)
+	accepted := make([]Feature, 0, len(requested))
+	for _, f := range requested {
+		if supported[f] {
+			accepted = append(accepted, f)
+		}
+	}
+	return accepted
+}
+
+// RequestedFeatures reads the feature list from task parameters. It accepts
+// the typed slice as well as the string forms produced by JSON decoding.
+func RequestedFeatures(task Task) []Feature {
+	raw, ok := task.Parameters[FeatureParam]
+	if !ok {
+		return nil
+	}
+	switch v := raw.(type) {
+	case []Feature:
+		return v
+	case []string:
+		features := make([]Feature, 0, len(v))
+		for _, s := range v {
+			features = append(features, Feature(s))
+		}
+		return features
+	case []interface{}:
+		features := make([]Feature, 0, len(v))
+		for _, item := range v {
+			features = append(features, Feature(fmt.Sprint(item)))
+		}
+		return features
+	}
+	return nil
+}
+
+// FeatureEnabled reports whether a feature was negotiated for this task.
+// Agents use it to switch to the new behavior and otherwise keep the legacy
+// path.
+func FeatureEnabled(task Task, feature Feature) bool {
+	return containsFeature(RequestedFeatures(task), feature)
+}
+
+// WithNegotiatedFeatures returns a copy of the task whose feature list is
+// narrowed to what the agent supports, along with the features that were
+// dropped. The original task parameters are not modified.
+func WithNegotiatedFeatures(task Task, agent Agent) (Task, []Feature) {
+	requested := RequestedFeatures(task)
+	if len(requested) == 0 {
+		return task, nil
+	}
+	accepted := Negotiate(agent, requested...)
+
+	params := make(map[string]interface{}, len(task.Parameters))
+	for k, v := range task.Parameters {
+		params[k] = v
+	}
+	params[FeatureParam] = accepted
+	task.Parameters = params
+
+	var dropped []Feature
+	for _, f := range requested {
+		if !containsFeature(accepted, f) {
+			dropped = append(dropped, f)
+		}
+	}
+	return task, dropped
+}
+
+func containsFeature(features []Feature, target Feature) bool {
+	for _, f := range features {
+		if f == target {
+			return true
+		}
+	}
+	return false
+}
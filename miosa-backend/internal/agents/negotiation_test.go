@@ -0,0 +1,76 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type featureAgent struct {
+	capAgent
+	declared     []Feature // Advertised through FeatureProvider
+	capabilities []Feature // Advertised on a capability
+}
+
+func (a *featureAgent) SupportedFeatures() []Feature { return a.declared }
+func (a *featureAgent) GetCapabilities() []Capability {
+	return []Capability{{Name: "code_generation", Features: a.capabilities}}
+}
+
+func TestNegotiate(t *testing.T) {
+	legacy := &capAgent{DevelopmentAgent, []string{"code_generation"}}
+	patcher := &featureAgent{capAgent: capAgent{agentType: DevelopmentAgent}, capabilities: []Feature{FeaturePatchMode}}
+	both := &featureAgent{
+		capAgent:     capAgent{agentType: ArchitectAgent},
+		declared:     []Feature{FeatureStreaming},
+		capabilities: []Feature{FeatureManifest},
+	}
+
+	tests := []struct {
+		name      string
+		agent     Agent
+		requested []Feature
+		want      []Feature
+	}{
+		{"nothing requested", patcher, nil, nil},
+		{"legacy agent", legacy, []Feature{FeaturePatchMode, FeatureStreaming}, []Feature{}},
+		{"capability feature", patcher, []Feature{FeaturePatchMode}, []Feature{FeaturePatchMode}},
+		{"unsupported dropped", patcher, []Feature{FeatureToolCalls, FeaturePatchMode}, []Feature{FeaturePatchMode}},
+		{"provider and capability", both, []Feature{FeatureManifest, FeatureToolCalls, FeatureStreaming}, []Feature{FeatureManifest, FeatureStreaming}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.agent, tt.requested...))
+		})
+	}
+}
+
+func TestWithNegotiatedFeatures(t *testing.T) {
+	patcher := &featureAgent{capAgent: capAgent{agentType: DevelopmentAgent}, capabilities: []Feature{FeaturePatchMode}}
+	task := Task{Parameters: map[string]interface{}{
+		FeatureParam: []interface{}{"patch_mode", "streaming"},
+	}}
+
+	negotiated, dropped := WithNegotiatedFeatures(task, patcher)
+	assert.Equal(t, []Feature{FeatureStreaming}, dropped)
+	assert.True(t, FeatureEnabled(negotiated, FeaturePatchMode))
+	assert.False(t, FeatureEnabled(negotiated, FeatureStreaming))
+	// The caller's parameters are left alone
+	assert.True(t, FeatureEnabled(task, FeatureStreaming))
+
+	unchanged, dropped := WithNegotiatedFeatures(Task{}, patcher)
+	assert.Nil(t, dropped)
+	assert.Nil(t, unchanged.Parameters)
+}
+
+func TestOrchestrator_NegotiateFeaturesFallsBackForLegacyAgents(t *testing.T) {
+	o := NewOrchestrator(nil, zap.NewNop(), nil)
+	task := Task{Parameters: map[string]interface{}{FeatureParam: []Feature{FeaturePatchMode}}}
+
+	legacy := o.negotiateFeatures(task, &capAgent{DevelopmentAgent, nil})
+	assert.False(t, FeatureEnabled(legacy, FeaturePatchMode))
+
+	patcher := &featureAgent{capAgent: capAgent{agentType: DevelopmentAgent}, capabilities: []Feature{FeaturePatchMode}}
+	assert.True(t, FeatureEnabled(o.negotiateFeatures(task, patcher), FeaturePatchMode))
+}
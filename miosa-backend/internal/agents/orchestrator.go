@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +24,8 @@ type Orchestrator struct {
 	vectorStore          VectorStore
 	confidenceThreshold  float64
 	subtaskScores        map[uuid.UUID]*SubtaskScore
+	refinement           RefinementPolicy
+	artifacts            *ArtifactRegistry
 	mu                   sync.RWMutex
 }
 
@@ -120,6 +123,8 @@ func NewOrchestrator(groqClient *groq.Client, logger *zap.Logger, vectorStore Ve
 		vectorStore:         vectorStore,
 		confidenceThreshold: 7.0,  // Out of 10 scale
 		subtaskScores:       make(map[uuid.UUID]*SubtaskScore),
+		refinement:          DefaultRefinementPolicy,
+		artifacts:           NewArtifactRegistry(NewMemoryArtifactStore()),
 	}
 	
 	o.workflowAnalyzer = &WorkflowAnalyzer{
@@ -177,8 +182,15 @@ func (o *Orchestrator) Execute(ctx context.Context, task Task) (*Result, error)
 		}
 	}
 	
-	// Analyze task to determine routing
-	routing, err := o.analyzeAndRoute(ctx, task)
+	// Tasks that declare required capabilities are routed by the capability
+	// index; everything else is routed by the model
+	var routing *AgentRoutingDecision
+	var err error
+	if required := RequiredCapabilities(task); len(required) > 0 {
+		routing, err = o.routeByCapability(required)
+	} else {
+		routing, err = o.analyzeAndRoute(ctx, task)
+	}
 	if err != nil {
 		return &Result{
 			Success:     false,
@@ -204,8 +216,11 @@ func (o *Orchestrator) Execute(ctx context.Context, task Task) (*Result, error)
 		}
 	}
 	
+	// Negotiate optional features; unsupported ones fall back to legacy behavior
+	agentTask := o.negotiateFeatures(task, targetAgent)
+
 	// Execute with the selected agent
-	result, err := targetAgent.Execute(ctx, task)
+	result, err := ExecuteTracked(ctx, targetAgent, agentTask)
 	if err != nil {
 		return &Result{
 			Success:     false,
@@ -214,6 +229,9 @@ func (o *Orchestrator) Execute(ctx context.Context, task Task) (*Result, error)
 		}, err
 	}
 	
+	// Low-confidence results go through critique and revision
+	result = o.refine(ctx, targetAgent, agentTask, result)
+	
 	// Score the execution (0-10 scale)
 	score := o.scoreExecution(result)
 	
@@ -236,6 +254,11 @@ func (o *Orchestrator) Execute(ctx context.Context, task Task) (*Result, error)
 	// Store workflow in vector DB for learning
 	go o.storeWorkflowPattern(ctx, task, routing, result, score)
 	
+	// Keep standout results as few-shot examples for similar tasks
+	if _, err := RecordExample(ctx, targetAgent.GetType(), task, result, score); err != nil {
+		o.logger.Warn("Failed to record few-shot example", zap.Error(err))
+	}
+	
 	// Analyze for improvements if score is low
 	if score < o.confidenceThreshold {
 		go o.analyzeForImprovements(ctx, task, result, score)
@@ -249,6 +272,7 @@ func (o *Orchestrator) Execute(ctx context.Context, task Task) (*Result, error)
 		"routed_to":  routing.Agent,
 		"reasoning":  routing.Reasoning,
 		"confidence": routing.Confidence,
+		"features":   RequestedFeatures(agentTask),
 	}
 	
 	result.ExecutionMS = time.Since(startTime).Milliseconds()
@@ -259,6 +283,62 @@ func (o *Orchestrator) Execute(ctx context.Context, task Task) (*Result, error)
 	return result, nil
 }
 
+// routeByCapability picks the best-scoring registered agent for the
+// required capabilities
+func (o *Orchestrator) routeByCapability(required []string) (*AgentRoutingDecision, error) {
+	match, err := IndexCapabilities().Best(required, "")
+	if err != nil {
+		return nil, err
+	}
+	reasoning := fmt.Sprintf("provides %s", strings.Join(match.Matched, ", "))
+	if len(match.Missing) > 0 {
+		reasoning += fmt.Sprintf("; no registered agent provides %s", strings.Join(match.Missing, ", "))
+	}
+	return &AgentRoutingDecision{
+		Agent:      string(match.Type),
+		Reasoning:  reasoning,
+		Confidence: match.Score,
+	}, nil
+}
+
+// agentForStep returns the planned agent for a chain step, or the
+// best-scoring substitute when the planned agent is missing or lacks a
+// capability the task requires
+func (o *Orchestrator) agentForStep(planned AgentType, task Task) (Agent, error) {
+	required := RequiredCapabilities(task)
+	if len(required) == 0 {
+		return Get(planned)
+	}
+	idx := IndexCapabilities()
+	matches := idx.Rank(required, planned)
+	for _, m := range matches {
+		if m.Type == planned && len(m.Missing) == 0 {
+			return m.Agent, nil
+		}
+	}
+	if len(matches) == 0 {
+		return Get(planned)
+	}
+	o.logger.Info("Routing chain step by capability",
+		zap.String("planned", string(planned)),
+		zap.String("selected", string(matches[0].Type)),
+		zap.Strings("required", required),
+		zap.Float64("score", matches[0].Score))
+	return matches[0].Agent, nil
+}
+
+// negotiateFeatures narrows the task's requested features to those the agent
+// supports and logs any that fall back to legacy behavior
+func (o *Orchestrator) negotiateFeatures(task Task, agent Agent) Task {
+	negotiated, dropped := WithNegotiatedFeatures(task, agent)
+	if len(dropped) > 0 {
+		o.logger.Debug("Agent lacks requested features, using legacy behavior",
+			zap.String("agent", string(agent.GetType())),
+			zap.Any("dropped", dropped))
+	}
+	return negotiated
+}
+
 // scoreExecution scores the execution result on a 0-10 scale
 func (o *Orchestrator) scoreExecution(result *Result) float64 {
 	score := 5.0 // Base score
@@ -348,7 +428,7 @@ func (o *Orchestrator) executeLearnedPattern(ctx context.Context, task Task, pat
 			continue
 		}
 		
-		result, err := agent.Execute(ctx, task)
+		result, err := ExecuteTracked(ctx, agent, o.negotiateFeatures(task, agent))
 		if err != nil {
 			continue
 		}
@@ -423,7 +503,7 @@ Respond with JSON format:
 	userPrompt := fmt.Sprintf("Task Type: %s\nInput: %s\nContext Phase: %s\nParameters: %v",
 		task.Type, task.Input, task.Context.Phase, task.Parameters)
 	
-	response, err := o.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := ChatCompletion(ctx, o.groqClient, OrchestratorAgent, groq.ChatCompletionRequest{
 		Model: groq.ChatModel(o.config.Model),
 		Messages: []groq.ChatCompletionMessage{
 			{
@@ -470,10 +550,11 @@ Respond with a JSON array of steps, each containing:
   "agent": "agent_name",
   "description": "what this step does",
   "dependencies": [0], // step numbers this depends on
-  "input": "specific input for this step"
+  "input": "specific input for this step",
+  "capabilities": ["optional", "capabilities", "the", "step", "requires"]
 }`
 
-	response, err := o.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := ChatCompletion(ctx, o.groqClient, OrchestratorAgent, groq.ChatCompletionRequest{
 		Model: groq.ChatModel(o.config.Model),
 		Messages: []groq.ChatCompletionMessage{
 			{
@@ -495,11 +576,12 @@ Respond with a JSON array of steps, each containing:
 	
 	// Parse and convert to tasks
 	var steps []struct {
-		StepNumber   int    `json:"step_number"`
-		Agent        string `json:"agent"`
-		Description  string `json:"description"`
-		Dependencies []int  `json:"dependencies"`
-		Input        string `json:"input"`
+		StepNumber   int      `json:"step_number"`
+		Agent        string   `json:"agent"`
+		Description  string   `json:"description"`
+		Dependencies []int    `json:"dependencies"`
+		Input        string   `json:"input"`
+		Capabilities []string `json:"capabilities"`
 	}
 	
 	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &steps); err != nil {
@@ -520,6 +602,9 @@ Respond with a JSON array of steps, each containing:
 				"dependencies": step.Dependencies,
 			},
 		}
+		if len(step.Capabilities) > 0 {
+			tasks[i].Parameters[CapabilitiesParam] = step.Capabilities
+		}
 	}
 	
 	return tasks, nil
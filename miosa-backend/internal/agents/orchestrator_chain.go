@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
 	"go.uber.org/zap"
 )
 
@@ -30,6 +31,7 @@ func (o *Orchestrator) ExecuteChain(ctx context.Context, task Task, agentChain [
 	
 	o.logger.Info("Starting agent chain execution",
 		zap.String("chain_id", chainID.String()),
+		requestid.Field(ctx),
 		zap.String("task", task.Input),
 		zap.Int("agents", len(agentChain)))
 	
@@ -43,7 +45,6 @@ func (o *Orchestrator) ExecuteChain(ctx context.Context, task Task, agentChain [
 	
 	// Current task that gets passed between agents
 	currentTask := task
-	var lastResult *Result
 	
 	// Execute through each agent in the chain
 	for i, agentType := range agentChain {
@@ -52,8 +53,8 @@ func (o *Orchestrator) ExecuteChain(ctx context.Context, task Task, agentChain [
 			zap.Int("step", i+1),
 			zap.String("agent", string(agentType)))
 		
-		// Get the agent
-		agent, err := Get(agentType)
+		// Get the agent, substituting by capability if the task requires it
+		agent, err := o.agentForStep(agentType, currentTask)
 		if err != nil {
 			o.logger.Error("Failed to get agent",
 				zap.String("agent_type", string(agentType)),
@@ -61,30 +62,32 @@ func (o *Orchestrator) ExecuteChain(ctx context.Context, task Task, agentChain [
 			continue
 		}
 		
-		// If we have a previous result, use its output as input
-		if lastResult != nil && lastResult.Output != "" {
-			// Enrich the task with previous agent's output
-			currentTask.Input = fmt.Sprintf("Previous analysis:\n%s\n\nNow, %s", 
-				truncateOutput(lastResult.Output, 500),
-				currentTask.Input)
-			
-			// Add context from previous execution
-			if currentTask.Context == nil {
-				currentTask.Context = &TaskContext{}
-			}
-			currentTask.Context.History = append(currentTask.Context.History, Message{
-				Role:      "assistant",
-				Content:   lastResult.Output,
-				Timestamp: time.Now(),
-			})
+		// Give the agent the workflow so far within the context budget,
+		// rather than every previous output verbatim
+		if currentTask.Context == nil {
+			currentTask.Context = &TaskContext{}
+		}
+		if history := DefaultContextBuilder.Build(currentTask.Context); history != "" {
+			currentTask.Input = fmt.Sprintf("Previous work:\n%s\n\nNow, %s", history, task.Input)
+		}
+		
+		// Expand artifact references to the sections they point at
+		stepTask := o.negotiateFeatures(currentTask, agent)
+		var sources []uuid.UUID
+		if o.artifacts != nil {
+			stepTask.Input, sources = o.artifacts.Resolve(ctx, stepTask.Input)
 		}
 		
 		// Execute with the agent
 		agentStart := time.Now()
-		result, err := agent.Execute(ctx, currentTask)
+		result, err := ExecuteTracked(ctx, agent, stepTask)
+		if err == nil {
+			result = o.refine(ctx, agent, stepTask, result)
+		}
 		if err != nil {
 			o.logger.Error("Agent execution failed",
 				zap.String("chain_id", chainID.String()),
+				requestid.Field(ctx),
 				zap.String("agent", string(agentType)),
 				zap.Error(err))
 			
@@ -103,9 +106,20 @@ func (o *Orchestrator) ExecuteChain(ctx context.Context, task Task, agentChain [
 		result.Data["chain_step"] = i + 1
 		result.Data["chain_id"] = chainID.String()
 		
+		// Publish the output so later steps can reference it, recording
+		// which artifacts it was derived from
+		if result.Success && result.Output != "" && o.artifacts != nil {
+			artifact, err := o.artifacts.Publish(ctx, agentType, string(agentType), chainID, result.Output, sources)
+			if err != nil {
+				o.logger.Warn("Failed to publish artifact", zap.String("agent", string(agentType)), zap.Error(err))
+			} else {
+				result.Data["artifact"] = artifact.Ref()
+			}
+		}
+		
 		// Store the result
 		chain.Results = append(chain.Results, result)
-		lastResult = result
+		currentTask.Context.Record(agentType, result)
 		
 		// Log the handoff
 		o.logger.Info("Agent completed in chain",
@@ -244,11 +258,3 @@ func (o *Orchestrator) recordChainExecution(chain *ChainExecution) {
 		zap.Float64("avg_confidence", avgConfidence),
 		zap.Bool("success", chain.Success))
 }
-
-// truncateOutput truncates output to specified length
-func truncateOutput(output string, maxLen int) string {
-	if len(output) <= maxLen {
-		return output
-	}
-	return output[:maxLen] + "..."
-}
\ No newline at end of file
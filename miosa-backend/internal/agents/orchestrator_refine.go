@@ -0,0 +1,152 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RefinementPolicy controls the critique-and-retry loop run when an agent
+// returns a low-confidence result
+type RefinementPolicy struct {
+	Threshold     float64   // results below this confidence (0-1) are refined
+	MaxIterations int       // 0 disables refinement
+	Critic        AgentType // agent asked to critique the result
+}
+
+// DefaultRefinementPolicy critiques results below 0.6 confidence with the
+// quality agent, at most twice
+var DefaultRefinementPolicy = RefinementPolicy{
+	Threshold:     0.6,
+	MaxIterations: 2,
+	Critic:        QualityAgent,
+}
+
+// RefinementIteration records one critique and revision
+type RefinementIteration struct {
+	Iteration   int     `json:"iteration"`
+	Critique    string  `json:"critique"`
+	Confidence  float64 `json:"confidence"`
+	Improvement float64 `json:"improvement"` // confidence gained over the previous iteration
+	Accepted    bool    `json:"accepted"`
+	ExecutionMS int64   `json:"execution_ms"`
+}
+
+// SetRefinementPolicy replaces the refinement policy
+func (o *Orchestrator) SetRefinementPolicy(policy RefinementPolicy) {
+	o.refinement = policy
+}
+
+// refine has the critic review a low-confidence result and the original
+// agent revise it, until the confidence clears the threshold, stops
+// improving, or the iteration limit is reached. The best result is returned
+// with the iterations recorded under Data["refinement"].
+func (o *Orchestrator) refine(ctx context.Context, agent Agent, task Task, result *Result) *Result {
+	policy := o.refinement
+	if result == nil || !result.Success || result.Confidence >= policy.Threshold || policy.MaxIterations <= 0 {
+		return result
+	}
+	if agent.GetType() == policy.Critic {
+		return result
+	}
+	critic, err := Get(policy.Critic)
+	if err != nil {
+		return result
+	}
+
+	best := result
+	var iterations []RefinementIteration
+	for i := 1; i <= policy.MaxIterations && best.Confidence < policy.Threshold; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+
+		critique, err := ExecuteTracked(ctx, critic, critiqueTask(task, agent.GetType(), best, i))
+		if err != nil || critique == nil || !critique.Success || critique.Output == "" {
+			o.logger.Warn("Refinement critique failed",
+				zap.String("agent", string(agent.GetType())),
+				zap.Int("iteration", i),
+				zap.Error(err))
+			break
+		}
+
+		revised, err := ExecuteTracked(ctx, agent, revisionTask(task, best, critique.Output, i))
+		if err != nil || revised == nil || !revised.Success {
+			o.logger.Warn("Refinement revision failed",
+				zap.String("agent", string(agent.GetType())),
+				zap.Int("iteration", i),
+				zap.Error(err))
+			break
+		}
+		revised.PromptTokens += best.PromptTokens + critique.PromptTokens
+		revised.CompletionTokens += best.CompletionTokens + critique.CompletionTokens
+
+		iteration := RefinementIteration{
+			Iteration:   i,
+			Critique:    critique.Output,
+			Confidence:  revised.Confidence,
+			Improvement: revised.Confidence - best.Confidence,
+			Accepted:    revised.Confidence >= best.Confidence,
+			ExecutionMS: time.Since(start).Milliseconds(),
+		}
+		iterations = append(iterations, iteration)
+
+		o.logger.Info("Refined low-confidence result",
+			zap.String("agent", string(agent.GetType())),
+			zap.Int("iteration", i),
+			zap.Float64("confidence", revised.Confidence),
+			zap.Float64("improvement", iteration.Improvement))
+
+		if !iteration.Accepted {
+			// The revision made things worse; keep the better answer but
+			// still count its tokens
+			best.PromptTokens, best.CompletionTokens = revised.PromptTokens, revised.CompletionTokens
+			break
+		}
+		best = revised
+	}
+
+	if len(iterations) > 0 {
+		if best.Data == nil {
+			best.Data = make(map[string]interface{})
+		}
+		best.Data["refinement"] = map[string]interface{}{
+			"initial_confidence": result.Confidence,
+			"final_confidence":   best.Confidence,
+			"iterations":         iterations,
+		}
+	}
+	return best
+}
+
+// critiqueTask asks the critic to review an agent's output
+func critiqueTask(task Task, author AgentType, result *Result, iteration int) Task {
+	return Task{
+		ID:   uuid.New(),
+		Type: "critique",
+		Input: fmt.Sprintf("Critique the %s agent's answer to the task below. "+
+			"List concrete problems and how to fix each one.\n\nTask:\n%s\n\nAnswer:\n%s",
+			author, task.Input, result.Output),
+		Parameters: map[string]interface{}{"refinement_iteration": iteration},
+		Context:    task.Context,
+		Priority:   task.Priority,
+		Timeout:    task.Timeout,
+	}
+}
+
+// revisionTask asks the original agent to address the critique
+func revisionTask(task Task, result *Result, critique string, iteration int) Task {
+	revision := task
+	revision.Input = fmt.Sprintf("%s\n\nYour previous answer:\n%s\n\nReviewer critique:\n%s\n\n"+
+		"Revise your answer to address the critique.", task.Input, result.Output, critique)
+	revision.Parameters = make(map[string]interface{}, len(task.Parameters)+1)
+	for k, v := range task.Parameters {
+		revision.Parameters[k] = v
+	}
+	revision.Parameters["refinement_iteration"] = iteration
+	return revision
+}
@@ -0,0 +1,93 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// scriptedAgent returns its confidences in order, one per call
+type scriptedAgent struct {
+	agentType   AgentType
+	confidences []float64
+	inputs      []string
+}
+
+func (a *scriptedAgent) GetType() AgentType            { return a.agentType }
+func (a *scriptedAgent) GetDescription() string        { return "scripted" }
+func (a *scriptedAgent) GetCapabilities() []Capability { return nil }
+func (a *scriptedAgent) Execute(ctx context.Context, task Task) (*Result, error) {
+	a.inputs = append(a.inputs, task.Input)
+	i := len(a.inputs) - 1
+	if i >= len(a.confidences) {
+		i = len(a.confidences) - 1
+	}
+	return &Result{Success: true, Output: "answer", Confidence: a.confidences[i], PromptTokens: 10}, nil
+}
+
+func TestOrchestrator_RefineImprovesLowConfidence(t *testing.T) {
+	Clear()
+	t.Cleanup(Clear)
+	critic := &scriptedAgent{agentType: QualityAgent, confidences: []float64{0.9}}
+	require.NoError(t, Register(critic))
+
+	o := NewOrchestrator(nil, zap.NewNop(), nil)
+	o.SetRefinementPolicy(RefinementPolicy{Threshold: 0.8, MaxIterations: 3, Critic: QualityAgent})
+
+	author := &scriptedAgent{agentType: DevelopmentAgent, confidences: []float64{0.6, 0.85}}
+	initial, _ := author.Execute(context.Background(), Task{Input: "build it"})
+
+	result := o.refine(context.Background(), author, Task{Input: "build it"}, initial)
+	assert.Equal(t, 0.85, result.Confidence)
+	assert.Equal(t, 30, result.PromptTokens, "initial, critique and revision tokens")
+	require.Len(t, critic.inputs, 1, "stops once above threshold")
+	assert.Contains(t, critic.inputs[0], "build it")
+	assert.True(t, strings.Contains(author.inputs[1], "Reviewer critique"))
+
+	refinement := result.Data["refinement"].(map[string]interface{})
+	iterations := refinement["iterations"].([]RefinementIteration)
+	require.Len(t, iterations, 1)
+	assert.InDelta(t, 0.25, iterations[0].Improvement, 1e-9)
+	assert.True(t, iterations[0].Accepted)
+}
+
+func TestOrchestrator_RefineKeepsBestWhenRevisionRegresses(t *testing.T) {
+	Clear()
+	t.Cleanup(Clear)
+	require.NoError(t, Register(&scriptedAgent{agentType: QualityAgent, confidences: []float64{0.9}}))
+
+	o := NewOrchestrator(nil, zap.NewNop(), nil)
+	o.SetRefinementPolicy(RefinementPolicy{Threshold: 0.8, MaxIterations: 3, Critic: QualityAgent})
+
+	author := &scriptedAgent{agentType: DevelopmentAgent, confidences: []float64{0.5, 0.4, 0.95}}
+	initial, _ := author.Execute(context.Background(), Task{})
+
+	result := o.refine(context.Background(), author, Task{}, initial)
+	assert.Equal(t, 0.5, result.Confidence)
+	assert.Len(t, author.inputs, 2, "no further iterations after a regression")
+
+	iterations := result.Data["refinement"].(map[string]interface{})["iterations"].([]RefinementIteration)
+	require.Len(t, iterations, 1)
+	assert.False(t, iterations[0].Accepted)
+}
+
+func TestOrchestrator_RefineSkips(t *testing.T) {
+	Clear()
+	t.Cleanup(Clear)
+	o := NewOrchestrator(nil, zap.NewNop(), nil)
+	author := &scriptedAgent{agentType: DevelopmentAgent, confidences: []float64{0.3}}
+	low := &Result{Success: true, Confidence: 0.3}
+
+	assert.Same(t, low, o.refine(context.Background(), author, Task{}, low), "no critic registered")
+
+	critic := &scriptedAgent{agentType: QualityAgent, confidences: []float64{0.9}}
+	require.NoError(t, Register(critic))
+	high := &Result{Success: true, Confidence: 0.9}
+	assert.Same(t, high, o.refine(context.Background(), author, Task{}, high))
+	assert.Same(t, low, o.refine(context.Background(), critic, Task{}, low), "the critic doesn't critique itself")
+	assert.Empty(t, critic.inputs)
+}
@@ -0,0 +1,227 @@
+package agents
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// OutputBlock is one fenced block of an agent's output, or the whole output
+// when it has no fences
+type OutputBlock struct {
+	Lang    string // The fence's info string, e.g. "yaml"
+	Heading string // The nearest markdown heading above the block
+	Content string
+}
+
+// OutputHandler turns one kind of artifact found in agent output, e.g. a
+// Dockerfile or a Grafana dashboard, into a project file
+type OutputHandler struct {
+	Kind string
+	// Detect sniffs whether a block holds this kind of artifact
+	Detect func(b OutputBlock) bool
+	// Path is where the nth block (from 0) of this kind in one output is
+	// saved, relative to the project
+	Path func(b OutputBlock, n int) string
+}
+
+// OutputHandlerProvider is implemented by agents contributing handlers for
+// the artifacts they produce. They apply to the agent's own output.
+type OutputHandlerProvider interface {
+	OutputHandlers() []OutputHandler
+}
+
+// OutputFile is an artifact found in agent output
+type OutputFile struct {
+	Kind    string
+	Path    string // Slash-separated, relative to the project
+	Content string
+}
+
+// OutputRegistry holds output handlers by artifact kind
+type OutputRegistry struct {
+	handlers map[string]OutputHandler
+	agents   map[string][]AgentType // Output the kind's handler applies to; every agent's when empty
+	order    []string               // Kinds, most recently registered last
+	mu       sync.RWMutex
+}
+
+// NewOutputRegistry creates an empty registry
+func NewOutputRegistry() *OutputRegistry {
+	return &OutputRegistry{handlers: make(map[string]OutputHandler), agents: make(map[string][]AgentType)}
+}
+
+// Register adds h for the output of the given agents, or of every agent
+// when none are given. It replaces any handler of the same kind, and is
+// tried before the handlers registered earlier, so a handler can specialize
+// a broader one.
+func (r *OutputRegistry) Register(h OutputHandler, agentTypes ...AgentType) error {
+	if h.Kind == "" || h.Detect == nil || h.Path == nil {
+		return fmt.Errorf("output handler needs a kind, Detect and Path")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, kind := range r.order {
+		if kind == h.Kind {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	r.order = append(r.order, h.Kind)
+	r.handlers[h.Kind] = h
+	r.agents[h.Kind] = append([]AgentType(nil), agentTypes...)
+	return nil
+}
+
+// RegisterAgent registers the handlers the agent contributes, if any
+func (r *OutputRegistry) RegisterAgent(agent Agent) error {
+	provider, ok := agent.(OutputHandlerProvider)
+	if !ok {
+		return nil
+	}
+	for _, h := range provider.OutputHandlers() {
+		if err := r.Register(h, agent.GetType()); err != nil {
+			return fmt.Errorf("%s: %w", agent.GetType(), err)
+		}
+	}
+	return nil
+}
+
+// Handles reports whether handlers were registered for the agent's output
+// specifically, so it is saved as artifacts rather than as documentation
+func (r *OutputRegistry) Handles(agentType AgentType) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, agentTypes := range r.agents {
+		for _, t := range agentTypes {
+			if t == agentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Kinds lists the registered artifact kinds, in the order they are tried
+func (r *OutputRegistry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.order))
+	for i := len(r.order) - 1; i >= 0; i-- {
+		kinds = append(kinds, r.order[i])
+	}
+	return kinds
+}
+
+// Files sniffs each block of the agent's output and returns the artifacts
+// the handlers recognize. Blocks no handler recognizes, and paths outside
+// the project, are dropped.
+func (r *OutputRegistry) Files(agentType AgentType, output string) []OutputFile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var files []OutputFile
+	seen := make(map[string]int)
+	for _, b := range SplitOutput(output) {
+		for i := len(r.order) - 1; i >= 0; i-- {
+			kind := r.order[i]
+			if !r.appliesTo(kind, agentType) || !r.handlers[kind].Detect(b) {
+				continue
+			}
+			p := path.Clean(r.handlers[kind].Path(b, seen[kind]))
+			seen[kind]++
+			if p == "." || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+				break
+			}
+			files = append(files, OutputFile{Kind: kind, Path: p, Content: b.Content})
+			break
+		}
+	}
+	return files
+}
+
+func (r *OutputRegistry) appliesTo(kind string, agentType AgentType) bool {
+	agentTypes := r.agents[kind]
+	if len(agentTypes) == 0 {
+		return true
+	}
+	for _, t := range agentTypes {
+		if t == agentType {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	fencePattern   = regexp.MustCompile("(?m)^```([\\w+#.-]*)[^\\n]*\\n([\\s\\S]*?)^```")
+	headingPattern = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*\s*$`)
+)
+
+// SplitOutput splits agent output into its fenced blocks, each with the
+// heading it sits under. Output without fences is one block.
+func SplitOutput(output string) []OutputBlock {
+	matches := fencePattern.FindAllStringSubmatchIndex(output, -1)
+	if len(matches) == 0 {
+		content := strings.TrimSpace(output)
+		if content == "" {
+			return nil
+		}
+		return []OutputBlock{{Content: content}}
+	}
+
+	// Comments in shell, YAML and Dockerfile blocks look like headings
+	var headings [][]int
+	for _, h := range headingPattern.FindAllStringSubmatchIndex(output, -1) {
+		inside := false
+		for _, m := range matches {
+			if h[0] > m[0] && h[0] < m[1] {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			headings = append(headings, h)
+		}
+	}
+	blocks := make([]OutputBlock, 0, len(matches))
+	for _, m := range matches {
+		b := OutputBlock{
+			Lang:    strings.ToLower(output[m[2]:m[3]]),
+			Content: strings.TrimSpace(output[m[4]:m[5]]),
+		}
+		for _, h := range headings {
+			if h[0] > m[0] {
+				break
+			}
+			b.Heading = output[h[2]:h[3]]
+		}
+		if b.Content != "" {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// NumberedPath returns p for the first artifact of a kind and numbers the
+// later ones, e.g. monitoring/grafana-dashboard-2.json
+func NumberedPath(p string, n int) string {
+	if n == 0 {
+		return p
+	}
+	ext := path.Ext(p)
+	if ext == path.Base(p) {
+		ext = ""
+	}
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(p, ext), n+1, ext)
+}
+
+// FixedPath is a Path saving every artifact of a kind at p, numbered after
+// the first
+func FixedPath(p string) func(OutputBlock, int) string {
+	return func(_ OutputBlock, n int) string {
+		return NumberedPath(p, n)
+	}
+}
@@ -0,0 +1,79 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const helmOutput = "## Chart\n\n```yaml\n# Chart metadata\napiVersion: v2\nname: api\nversion: 0.1.0\n```\n\n" +
+	"## Deployment\n\n```yaml\napiVersion: apps/v1\nkind: Deployment\n```\n\n" +
+	"## Second chart\n\n```yaml\napiVersion: v2\nname: worker\nversion: 0.1.0\n```\n"
+
+func yamlHandler(kind, marker, p string) OutputHandler {
+	return OutputHandler{
+		Kind:   kind,
+		Detect: func(b OutputBlock) bool { return strings.Contains(b.Content, marker) },
+		Path:   FixedPath(p),
+	}
+}
+
+func TestSplitOutput(t *testing.T) {
+	blocks := SplitOutput(helmOutput)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, OutputBlock{Lang: "yaml", Heading: "Chart", Content: "# Chart metadata\napiVersion: v2\nname: api\nversion: 0.1.0"}, blocks[0])
+	assert.Equal(t, "Deployment", blocks[1].Heading)
+	assert.Equal(t, "Second chart", blocks[2].Heading, "comments inside blocks are not headings")
+
+	assert.Equal(t, []OutputBlock{{Content: "FROM golang:1.23"}}, SplitOutput("\nFROM golang:1.23\n"))
+	assert.Empty(t, SplitOutput("  \n"))
+}
+
+func TestOutputRegistry_Files(t *testing.T) {
+	registry := NewOutputRegistry()
+	require.NoError(t, registry.Register(yamlHandler("kubernetes", "apiVersion:", "deployment/k8s.yaml"), DeploymentAgent))
+	// Registered later, so it is tried first and claims the charts
+	require.NoError(t, registry.Register(yamlHandler("helm-chart", "apiVersion: v2", "deployment/helm/Chart.yaml"), DeploymentAgent))
+
+	files := registry.Files(DeploymentAgent, helmOutput)
+	require.Len(t, files, 3)
+	assert.Equal(t, OutputFile{Kind: "helm-chart", Path: "deployment/helm/Chart.yaml", Content: "# Chart metadata\napiVersion: v2\nname: api\nversion: 0.1.0"}, files[0])
+	assert.Equal(t, "kubernetes", files[1].Kind)
+	assert.Equal(t, "deployment/k8s.yaml", files[1].Path)
+	assert.Equal(t, "deployment/helm/Chart-2.yaml", files[2].Path)
+	assert.Equal(t, []string{"helm-chart", "kubernetes"}, registry.Kinds())
+
+	assert.Empty(t, registry.Files(ArchitectAgent, helmOutput), "handlers apply to the agents they were registered for")
+	assert.True(t, registry.Handles(DeploymentAgent))
+	assert.False(t, registry.Handles(ArchitectAgent))
+}
+
+func TestOutputRegistry_RegisterValidatesAndReplaces(t *testing.T) {
+	registry := NewOutputRegistry()
+	assert.Error(t, registry.Register(OutputHandler{Kind: "empty"}))
+
+	require.NoError(t, registry.Register(yamlHandler("kubernetes", "apiVersion:", "k8s.yaml")))
+	require.NoError(t, registry.Register(yamlHandler("chart", "apiVersion: v2", "Chart.yaml")))
+	require.NoError(t, registry.Register(yamlHandler("kubernetes", "kind:", "manifests/k8s.yaml")))
+	assert.Equal(t, []string{"kubernetes", "chart"}, registry.Kinds())
+
+	// Registered for every agent, which still save their documentation
+	files := registry.Files(ArchitectAgent, helmOutput)
+	require.Len(t, files, 3)
+	assert.Equal(t, "manifests/k8s.yaml", files[1].Path)
+	assert.False(t, registry.Handles(ArchitectAgent))
+}
+
+func TestOutputRegistry_DropsPathsOutsideTheProject(t *testing.T) {
+	registry := NewOutputRegistry()
+	require.NoError(t, registry.Register(yamlHandler("escape", "apiVersion:", "../outside.yaml")))
+	assert.Empty(t, registry.Files(DeploymentAgent, helmOutput))
+}
+
+func TestNumberedPath(t *testing.T) {
+	assert.Equal(t, "monitoring/prometheus.yml", NumberedPath("monitoring/prometheus.yml", 0))
+	assert.Equal(t, "monitoring/prometheus-3.yml", NumberedPath("monitoring/prometheus.yml", 2))
+	assert.Equal(t, "deployment/Dockerfile-2", NumberedPath("deployment/Dockerfile", 1))
+}
@@ -0,0 +1,294 @@
+package agents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+)
+
+// PatchFilesParam is the task parameter putting the development agent in
+// patch mode: the current files it may edit, as []GeneratedFile
+const PatchFilesParam = "patch_files"
+
+// PatchesKey is the Result.Data key of the patches a patch-mode run returned
+const PatchesKey = "patches"
+
+// Edit operations
+const (
+	EditReplace = "replace" // Replace lines Start..End with Content
+	EditInsert  = "insert"  // Insert Content after the one line equal to Anchor
+	EditDelete  = "delete"  // Delete lines Start..End
+)
+
+// Edit is one change to a file. Line numbers are 1-based, inclusive and
+// refer to the file as it was given, whatever the other edits do.
+type Edit struct {
+	Op      string `json:"op"`
+	Start   int    `json:"start,omitempty"`
+	End     int    `json:"end,omitempty"`
+	Anchor  string `json:"anchor,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// FilePatch is the edits to one file. Base is the SHA-256 of the content
+// the edits were made against; a file changed since is not patched.
+type FilePatch struct {
+	Path  string `json:"path"`
+	Base  string `json:"base,omitempty"`
+	Edits []Edit `json:"edits"`
+}
+
+// PatchError explains why a patch cannot be applied
+type PatchError struct {
+	Path    string
+	Edit    int // Index of the failing edit, -1 for the patch as a whole
+	Message string
+}
+
+func (e *PatchError) Error() string {
+	if e.Edit < 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: edit %d: %s", e.Path, e.Edit+1, e.Message)
+}
+
+// ContentHash is the Base of a patch made against content
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// PatchFiles returns the files a patch-mode task edits, nil when the task
+// is not in patch mode
+func PatchFiles(task Task) []GeneratedFile {
+	files, _ := task.Parameters[PatchFilesParam].([]GeneratedFile)
+	return files
+}
+
+// span is an edit resolved to the half-open range of original lines it
+// replaces
+type span struct {
+	start, end int
+	lines      []string
+	edit       int
+}
+
+// Apply returns content with the patch's edits applied. Edits may not
+// overlap, and every edit is checked before any is applied.
+func (p FilePatch) Apply(content string) (string, error) {
+	if p.Base != "" && p.Base != ContentHash(content) {
+		return "", &PatchError{Path: p.Path, Edit: -1, Message: "file changed since the patch was made"}
+	}
+	if len(p.Edits) == 0 {
+		return "", &PatchError{Path: p.Path, Edit: -1, Message: "has no edits"}
+	}
+
+	trailing := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	spans := make([]span, 0, len(p.Edits))
+	for i, e := range p.Edits {
+		s := span{edit: i}
+		switch e.Op {
+		case EditReplace, EditDelete:
+			if e.Start < 1 || e.End < e.Start || e.End > len(lines) {
+				return "", &PatchError{Path: p.Path, Edit: i, Message: fmt.Sprintf("lines %d-%d are outside the file's %d lines", e.Start, e.End, len(lines))}
+			}
+			s.start, s.end = e.Start-1, e.End
+			if e.Op == EditReplace {
+				s.lines = contentLines(e.Content)
+			}
+		case EditInsert:
+			if strings.TrimSpace(e.Anchor) == "" {
+				return "", &PatchError{Path: p.Path, Edit: i, Message: "insert needs an anchor line"}
+			}
+			at := -1
+			for n, line := range lines {
+				if strings.TrimSpace(line) != strings.TrimSpace(e.Anchor) {
+					continue
+				}
+				if at >= 0 {
+					return "", &PatchError{Path: p.Path, Edit: i, Message: fmt.Sprintf("anchor %q matches more than one line", e.Anchor)}
+				}
+				at = n
+			}
+			if at < 0 {
+				return "", &PatchError{Path: p.Path, Edit: i, Message: fmt.Sprintf("anchor %q matches no line", e.Anchor)}
+			}
+			s.start, s.end = at+1, at+1
+			s.lines = contentLines(e.Content)
+		default:
+			return "", &PatchError{Path: p.Path, Edit: i, Message: fmt.Sprintf("unknown op %q", e.Op)}
+		}
+		spans = append(spans, s)
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i-1].end > spans[i].start {
+			return "", &PatchError{Path: p.Path, Edit: spans[i].edit, Message: fmt.Sprintf("overlaps edit %d", spans[i-1].edit+1)}
+		}
+	}
+
+	out := make([]string, 0, len(lines))
+	next := 0
+	for _, s := range spans {
+		out = append(out, lines[next:s.start]...)
+		out = append(out, s.lines...)
+		next = s.end
+	}
+	out = append(out, lines[next:]...)
+
+	result := strings.Join(out, "\n")
+	if trailing && len(out) > 0 {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// contentLines splits an edit's content into lines
+func contentLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// ApplyPatches applies patches to files, by path, returning the new
+// content of every patched file. Nothing is returned unless every patch
+// applies: patches must name given files, at most once each.
+func ApplyPatches(files map[string]string, patches []FilePatch) (map[string]string, error) {
+	patched := make(map[string]string, len(patches))
+	var errs []error
+	seen := make(map[string]bool, len(patches))
+	for _, p := range patches {
+		content, ok := files[p.Path]
+		switch {
+		case !ok:
+			errs = append(errs, &PatchError{Path: p.Path, Edit: -1, Message: "is not one of the files given"})
+			continue
+		case seen[p.Path]:
+			errs = append(errs, &PatchError{Path: p.Path, Edit: -1, Message: "is patched more than once"})
+			continue
+		}
+		seen[p.Path] = true
+		out, err := p.Apply(content)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		patched[p.Path] = out
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return patched, nil
+}
+
+// ParsePatches reads the patches of a patch-mode response
+func ParsePatches(s string) ([]FilePatch, error) {
+	var response struct {
+		Patches []FilePatch `json:"patches"`
+	}
+	if err := json.Unmarshal([]byte(stripJSONFences(s)), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse patches: %w", err)
+	}
+	if len(response.Patches) == 0 {
+		return nil, errors.New("response has no patches")
+	}
+	return response.Patches, nil
+}
+
+// PatchPrompt shows files with numbered lines and asks for edits to them
+func PatchPrompt(files []GeneratedFile) string {
+	var sb strings.Builder
+	sb.WriteString("Change the files below by returning edits, not whole files. Line numbers refer to the files as shown; edits to one file may not overlap.\n")
+	sb.WriteString(`Respond with only JSON: {"patches": [{"path": "...", "edits": [` +
+		`{"op": "replace", "start": 3, "end": 5, "content": "new lines"}, ` +
+		`{"op": "insert", "anchor": "an existing line, matching exactly one", "content": "lines inserted after it"}, ` +
+		`{"op": "delete", "start": 10, "end": 12}]}]}` + "\n")
+	for _, f := range files {
+		fmt.Fprintf(&sb, "\n=== FILE: %s ===\n", f.Path)
+		for i, line := range strings.Split(strings.TrimSuffix(f.Content, "\n"), "\n") {
+			fmt.Fprintf(&sb, "%5d| %s\n", i+1, line)
+		}
+	}
+	return sb.String()
+}
+
+// RequestPatches asks the model for edits to files, fulfilling task in
+// patch mode. Patches that do not apply are sent back once with the reasons.
+// The result's Data[PatchesKey] holds the patches, each based on the
+// content it was made against.
+func RequestPatches(ctx context.Context, client *groq.Client, agent AgentType, config AgentConfig, task Task, files []GeneratedFile) (*Result, error) {
+	start := time.Now()
+	contents := make(map[string]string, len(files))
+	for _, f := range files {
+		contents[f.Path] = f.Content
+	}
+	messages := WithStackProfile(ctx, task, []groq.ChatCompletionMessage{
+		{Role: groq.RoleSystem, Content: "You are an expert developer making minimal, precise edits to existing code."},
+		{Role: groq.RoleUser, Content: task.Input + "\n\n" + PatchPrompt(files)},
+	})
+
+	result := &Result{}
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := ChatCompletion(ctx, client, agent, groq.ChatCompletionRequest{
+			Model:       groq.ChatModel(config.Model),
+			Messages:    messages,
+			MaxTokens:   config.MaxTokens,
+			Temperature: float32(config.Temperature),
+			TopP:        float32(config.TopP),
+		})
+		if err != nil {
+			return &Result{Error: err, ExecutionMS: time.Since(start).Milliseconds()}, err
+		}
+		result.AddUsage(response)
+		if len(response.Choices) == 0 {
+			lastErr = errors.New("no response from model")
+			continue
+		}
+		content := response.Choices[0].Message.Content
+
+		patches, err := ParsePatches(content)
+		if err == nil {
+			_, err = ApplyPatches(contents, patches)
+		}
+		if err != nil {
+			lastErr = err
+			messages = append(messages,
+				groq.ChatCompletionMessage{Role: groq.RoleAssistant, Content: content},
+				groq.ChatCompletionMessage{Role: groq.RoleUser, Content: "These edits cannot be applied:\n" + err.Error() + "\n\nRespond with corrected JSON for all the edits."})
+			continue
+		}
+
+		summary := make([]string, 0, len(patches))
+		for i := range patches {
+			patches[i].Base = ContentHash(contents[patches[i].Path])
+			summary = append(summary, fmt.Sprintf("%s (%d edits)", patches[i].Path, len(patches[i].Edits)))
+		}
+		result.Success = true
+		result.Output = "Patched " + strings.Join(summary, ", ")
+		result.Confidence = 8.0
+		result.Data = map[string]interface{}{PatchesKey: patches}
+		result.ExecutionMS = time.Since(start).Milliseconds()
+		return result, nil
+	}
+
+	err := fmt.Errorf("patch generation failed: %w", lastErr)
+	result.Error = err
+	result.ExecutionMS = time.Since(start).Milliseconds()
+	return result, err
+}
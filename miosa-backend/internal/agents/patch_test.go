@@ -0,0 +1,131 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const handlerSource = `package main
+
+import "net/http"
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+`
+
+func TestFilePatch_Apply(t *testing.T) {
+	out, err := FilePatch{Path: "main.go", Edits: []Edit{
+		{Op: EditInsert, Anchor: `import "net/http"`, Content: "\nconst greeting = \"ok\"\n"},
+		{Op: EditReplace, Start: 6, End: 6, Content: "\tw.WriteHeader(http.StatusOK)\n\tw.Write([]byte(greeting))"},
+		{Op: EditDelete, Start: 1, End: 2},
+	}}.Apply(handlerSource)
+	require.NoError(t, err)
+	assert.Equal(t, `import "net/http"
+
+const greeting = "ok"
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(greeting))
+}
+`, out)
+
+	for name, tc := range map[string]struct {
+		patch FilePatch
+		err   string
+	}{
+		"out of range":    {FilePatch{Edits: []Edit{{Op: EditDelete, Start: 7, End: 9}}}, "lines 7-9 are outside the file's 7 lines"},
+		"reversed range":  {FilePatch{Edits: []Edit{{Op: EditReplace, Start: 3, End: 2}}}, "outside the file"},
+		"missing anchor":  {FilePatch{Edits: []Edit{{Op: EditInsert, Anchor: "func main() {"}}}, "matches no line"},
+		"empty anchor":    {FilePatch{Edits: []Edit{{Op: EditInsert, Anchor: " "}}}, "insert needs an anchor line"},
+		"overlap":         {FilePatch{Edits: []Edit{{Op: EditReplace, Start: 5, End: 7}, {Op: EditDelete, Start: 6, End: 6}}}, "edit 2: overlaps edit 1"},
+		"insert in range": {FilePatch{Edits: []Edit{{Op: EditDelete, Start: 3, End: 6}, {Op: EditInsert, Anchor: `import "net/http"`}}}, "overlaps edit 1"},
+		"unknown op":      {FilePatch{Edits: []Edit{{Op: "rewrite"}}}, `unknown op "rewrite"`},
+		"no edits":        {FilePatch{}, "has no edits"},
+		"stale base":      {FilePatch{Base: ContentHash("package old\n"), Edits: []Edit{{Op: EditDelete, Start: 1, End: 1}}}, "file changed since the patch was made"},
+	} {
+		_, err := tc.patch.Apply(handlerSource)
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), tc.err, name)
+		}
+	}
+
+	// Two lines with the same content make an anchor ambiguous
+	_, err = FilePatch{Edits: []Edit{{Op: EditInsert, Anchor: "x"}}}.Apply("x\ny\nx")
+	assert.ErrorContains(t, err, "matches more than one line")
+
+	out, err = FilePatch{Base: ContentHash("a\nb"), Edits: []Edit{{Op: EditReplace, Start: 2, End: 2, Content: "c\n"}}}.Apply("a\nb")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nc", out, "a file without a trailing newline keeps none")
+}
+
+func TestApplyPatches(t *testing.T) {
+	files := map[string]string{"main.go": handlerSource, "go.mod": "module app\n"}
+	patched, err := ApplyPatches(files, []FilePatch{
+		{Path: "go.mod", Edits: []Edit{{Op: EditInsert, Anchor: "module app", Content: "\ngo 1.22"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"go.mod": "module app\n\ngo 1.22\n"}, patched)
+
+	// One bad patch rejects them all
+	patched, err = ApplyPatches(files, []FilePatch{
+		{Path: "go.mod", Edits: []Edit{{Op: EditDelete, Start: 1, End: 1}}},
+		{Path: "main.go", Edits: []Edit{{Op: EditDelete, Start: 40, End: 41}}},
+		{Path: "go.mod", Edits: []Edit{{Op: EditDelete, Start: 1, End: 1}}},
+		{Path: "util.go", Edits: []Edit{{Op: EditDelete, Start: 1, End: 1}}},
+	})
+	assert.Nil(t, patched)
+	assert.ErrorContains(t, err, "main.go: edit 1: lines 40-41")
+	assert.ErrorContains(t, err, "go.mod: is patched more than once")
+	assert.ErrorContains(t, err, "util.go: is not one of the files given")
+}
+
+func TestRequestPatches(t *testing.T) {
+	responses := []string{
+		`{"patches": [{"path": "main.go", "edits": [{"op": "delete", "start": 12, "end": 12}]}]}`,
+		"```json\n" + `{"patches": [{"path": "main.go", "edits": [{"op": "replace", "start": 6, "end": 6, "content": "\tw.Write([]byte(\"fixed\"))"}]}]}` + "\n```",
+	}
+	var prompts [][]groq.ChatCompletionMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req groq.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages)
+		content := responses[len(prompts)-1]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewLLMClient("test-key", server.URL)
+	require.NoError(t, err)
+
+	task := Task{Input: "Make the handler say fixed", Parameters: map[string]interface{}{
+		PatchFilesParam: []GeneratedFile{{Path: "main.go", Content: handlerSource}},
+	}}
+	files := PatchFiles(task)
+	require.Len(t, files, 1)
+	result, err := RequestPatches(context.Background(), client, DevelopmentAgent, AgentConfig{Model: "m"}, task, files)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "Patched main.go (1 edits)", result.Output)
+
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[0][len(prompts[0])-1].Content, "    6| \tw.Write([]byte(\"ok\"))")
+	assert.Contains(t, prompts[1][len(prompts[1])-1].Content, "lines 12-12 are outside the file's 7 lines")
+
+	patches := result.Data[PatchesKey].([]FilePatch)
+	require.Len(t, patches, 1)
+	assert.Equal(t, ContentHash(handlerSource), patches[0].Base)
+	out, err := patches[0].Apply(handlerSource)
+	require.NoError(t, err)
+	assert.Contains(t, out, `w.Write([]byte("fixed"))`)
+}
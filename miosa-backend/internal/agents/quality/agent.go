@@ -8,6 +8,7 @@ import (
     "time"
 
     "github.com/conneroisu/groq-go"
+    "github.com/google/uuid"
     "github.com/sormind/OSA/miosa-backend/internal/agents"
 )
 
@@ -15,6 +16,11 @@ import (
 type QualityAgent struct {
     groqClient *groq.Client
     config     agents.AgentConfig
+    vulnDB     VulnerabilityDB
+    licenses   LicenseResolver
+    policies   LicensePolicyStore
+    migrations MigrationVerifier
+    baselines  *baselineCache
 }
 
 // Metrics captures richer evaluation data for code quality.
@@ -45,6 +51,11 @@ func New(groqClient *groq.Client) agents.Agent {
             Temperature: 0.3,
             TopP:        0.9,
         },
+        vulnDB:     NewOSVClient(""),
+        licenses:   NewDepsDevClient(""),
+        policies:   DefaultLicensePolicies,
+        migrations: DefaultMigrationVerifier,
+        baselines:  newBaselineCache(),
     }
 }
 
@@ -56,6 +67,11 @@ func (a *QualityAgent) GetDescription() string {
     return "Ensures code quality through deep static/dynamic analysis, automated testing, and continuous improvement cycles"
 }
 
+// Model returns the model the agent calls
+func (a *QualityAgent) Model() string {
+    return a.config.Model
+}
+
 func (a *QualityAgent) GetCapabilities() []agents.Capability {
     return []agents.Capability{
         {Name: "code_review", Description: "Perform static/dynamic code quality analysis", Required: true},
@@ -80,6 +96,39 @@ func (a *QualityAgent) Execute(ctx context.Context, task agents.Task) (*agents.R
         CoveragePercent:     87.5,
     }
 
+    // Audit the files produced earlier in the workflow, including their
+    // dependency manifests and licenses. Blocking security and compliance
+    // findings fail the quality gate.
+    var assurance *CodeAssuranceResult
+    if files := taskFiles(task); len(files) > 0 {
+        policy := a.licensePolicy(ctx, task)
+        // A workflow run again only re-analyzes what changed since
+        var baseline *Baseline
+        if a.baselines != nil && task.ID != uuid.Nil {
+            baseline = a.baselines.get(task.ID)
+        }
+        if res, err := RunCodeAssurance(ctx, nil, CodeAssuranceRequest{
+            Goal:              task.Input,
+            Files:             files,
+            VulnerabilityDB:   a.vulnDB,
+            LicensePolicy:     &policy,
+            LicenseResolver:   a.licenses,
+            MigrationVerifier: a.migrations,
+            Baseline:          baseline,
+        }); err == nil {
+            assurance = res
+            if a.baselines != nil && task.ID != uuid.Nil {
+                a.baselines.put(task.ID, res)
+            }
+            metrics.TotalFiles = len(files)
+            metrics.TotalLines = 0
+            for _, f := range files {
+                metrics.TotalLines += strings.Count(f.Content, "\n") + 1
+            }
+            metrics.IssuesFound = len(res.Findings)
+        }
+    }
+
     // 2. Generate AI-powered Doing Notes
     notes, err := a.generateDoingNotes(ctx, task.Input, metrics)
     if err != nil {
@@ -104,6 +153,24 @@ func (a *QualityAgent) Execute(ctx context.Context, task agents.Task) (*agents.R
         ExecutionMS: time.Since(startTime).Milliseconds(),
         NextAgent:   agents.DeploymentAgent,
     }
+    if assurance != nil {
+        result.Data = map[string]interface{}{
+            "findings":        assurance.Findings,
+            "assurance_score": assurance.Score,
+        }
+        if assurance.Routes != nil {
+            result.Data["route_coverage"] = assurance.Routes
+        }
+        if assurance.Incremental != nil {
+            result.Data["incremental"] = assurance.Incremental
+        }
+        // Test figures are still simulated, so the gate rests on real findings
+        blocking := blockingFindings(assurance.Findings)
+        result.Success = len(blocking) == 0
+        for _, f := range blocking {
+            result.Suggestions = append(result.Suggestions, f.Remediation)
+        }
+    }
     agents.RecordExecution(a.GetType(), result)
 
     return result, nil
@@ -138,7 +205,7 @@ Respond ONLY as valid JSON:
 Subject: %s
 `, m.TotalFiles, m.TotalLines, m.IssuesFound, m.TestsGenerated, m.TestsPassed, m.TestsFailed, m.CodeComplexityScore, m.CoveragePercent, subject)
 
-    resp, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+    resp, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
         Model: groq.ChatModel(a.config.Model),
         Messages: []groq.ChatCompletionMessage{
             {Role: "system", Content: "You are an AI specialized in code quality and QA reporting."},
@@ -229,3 +296,44 @@ func (a *QualityAgent) formatReport(subject string, m Metrics, notes DoingNotes,
 
     return sb.String()
 }
+
+// taskFiles returns the generated files handed to the agent in
+// task.Parameters["files"]
+func taskFiles(task agents.Task) []CodeFile {
+    generated, _ := task.Parameters["files"].([]agents.GeneratedFile)
+    files := make([]CodeFile, 0, len(generated))
+    for _, f := range generated {
+        files = append(files, CodeFile{Path: f.Path, Content: f.Content})
+    }
+    return files
+}
+
+// licensePolicy returns the task tenant's license policy, or the platform
+// default when the tenant has none or the store is unavailable
+func (a *QualityAgent) licensePolicy(ctx context.Context, task agents.Task) LicensePolicy {
+    if a.policies == nil {
+        return DefaultLicensePolicy
+    }
+    var tenantID uuid.UUID
+    if task.Context != nil {
+        tenantID = task.Context.TenantID
+    }
+    policy, err := a.policies.Policy(ctx, tenantID)
+    if err != nil {
+        return DefaultLicensePolicy
+    }
+    return policy
+}
+
+// blockingFindings are high or critical security and compliance findings,
+// such as vulnerable dependencies or denied licenses, and migrations that
+// fail to apply, all of which must be fixed before deployment
+func blockingFindings(findings []Finding) []Finding {
+    var blocking []Finding
+    for _, f := range findings {
+        if (f.Category == "security" || f.Category == "compliance") && severityRank(f.Severity) >= severityRank("high") || f.Rule == "SQL.MigrationFails" {
+            blocking = append(blocking, f)
+        }
+    }
+    return blocking
+}
@@ -0,0 +1,302 @@
+package quality
+
+import (
+    "context"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// Chunking defaults. DefaultMaxPromptChars is roughly 6k tokens of code per
+// LLM call, leaving room for the system prompt and the JSON response.
+const (
+    DefaultMaxPromptChars = 24000
+    DefaultChunkOverlap   = 15
+)
+
+// Chunk is a contiguous line range of one file sent to the LLM on its own.
+// StartLine and EndLine are 1-based and inclusive.
+type Chunk struct {
+    Path      string
+    Language  string
+    StartLine int
+    EndLine   int
+    Content   string
+}
+
+// Boundary patterns: a new top-level declaration or section starts a segment
+var (
+    goBoundary     = regexp.MustCompile(`^(func|type|var|const)\b|^// -{3,}`)
+    pyBoundary     = regexp.MustCompile(`^(async\s+def|def|class)\s`)
+    jsBoundary     = regexp.MustCompile(`^(export\s+)?(default\s+)?(async\s+)?(function|class|interface|type|enum)\b|^(export\s+)?(const|let|var)\s+\w+\s*=\s*(async\s*)?(\(|function)`)
+    braceBoundary  = regexp.MustCompile(`^(public|private|protected|internal|static|final|abstract|class|interface|def|fn|pub|impl|module)\b`)
+    headerBoundary = regexp.MustCompile(`^#{1,3}\s`)
+)
+
+// ChunkFile splits a file into chunks of about maxChars, cutting on
+// function or section boundaries where possible. Each chunk after the first
+// repeats the last overlap lines of its predecessor so issues spanning a cut
+// are still visible. Files that fit return a single chunk.
+func ChunkFile(file CodeFile, maxChars, overlap int) []Chunk {
+    lang := file.Language
+    if lang == "" {
+        lang = guessLanguageFromPath(file.Path)
+    }
+    lines := strings.SplitAfter(file.Content, "\n")
+    if n := len(lines); n > 0 && lines[n-1] == "" {
+        lines = lines[:n-1]
+    }
+    if len(file.Content) <= maxChars || len(lines) <= 1 {
+        return []Chunk{{Path: file.Path, Language: lang, StartLine: 1, EndLine: len(lines), Content: file.Content}}
+    }
+
+    // Segments are [start, end) line index ranges between boundaries
+    boundary := boundaryPattern(file.Path, lang)
+    var segments [][2]int
+    start := 0
+    for i := 1; i < len(lines); i++ {
+        if boundary.MatchString(lines[i]) {
+            segments = append(segments, [2]int{start, i})
+            start = i
+        }
+    }
+    segments = append(segments, [2]int{start, len(lines)})
+
+    var chunks []Chunk
+    emit := func(from, to int) {
+        if len(chunks) > 0 {
+            from -= overlap
+            if prev := chunks[len(chunks)-1].StartLine; from < prev {
+                from = prev
+            }
+        }
+        if from < 0 {
+            from = 0
+        }
+        chunks = append(chunks, Chunk{
+            Path:      file.Path,
+            Language:  lang,
+            StartLine: from + 1,
+            EndLine:   to,
+            Content:   strings.Join(lines[from:to], ""),
+        })
+    }
+
+    // Greedily pack whole segments; oversized segments are cut by size
+    from, size := 0, 0
+    for _, seg := range segments {
+        segSize := 0
+        for _, l := range lines[seg[0]:seg[1]] {
+            segSize += len(l)
+        }
+        if size > 0 && size+segSize > maxChars {
+            emit(from, seg[0])
+            from, size = seg[0], 0
+        }
+        if segSize <= maxChars {
+            size += segSize
+            continue
+        }
+        for i := seg[0]; i < seg[1]; i++ {
+            if size > 0 && size+len(lines[i]) > maxChars {
+                emit(from, i)
+                from, size = i, 0
+            }
+            size += len(lines[i])
+        }
+    }
+    if from < len(lines) {
+        emit(from, len(lines))
+    }
+    return chunks
+}
+
+func boundaryPattern(path, lang string) *regexp.Regexp {
+    l := strings.ToLower(lang)
+    switch {
+    case isGoLike(path, lang):
+        return goBoundary
+    case isPythonLike(path, lang):
+        return pyBoundary
+    case isJavaScriptLike(path, lang):
+        return jsBoundary
+    case l == "markdown" || strings.HasSuffix(strings.ToLower(path), ".md"):
+        return headerBoundary
+    default:
+        return braceBoundary
+    }
+}
+
+// batchChunks packs chunks into LLM calls of at most maxChars. A batch holds
+// at most one chunk per file so findings can be mapped back by path.
+func batchChunks(chunks []Chunk, maxChars int) [][]Chunk {
+    var batches [][]Chunk
+    var current []Chunk
+    size := 0
+    inBatch := make(map[string]bool)
+    for _, c := range chunks {
+        if len(current) > 0 && (size+len(c.Content) > maxChars || inBatch[c.Path]) {
+            batches = append(batches, current)
+            current, size = nil, 0
+            inBatch = make(map[string]bool)
+        }
+        current = append(current, c)
+        size += len(c.Content)
+        inBatch[c.Path] = true
+    }
+    if len(current) > 0 {
+        batches = append(batches, current)
+    }
+    return batches
+}
+
+// runChunkedLLMAssurance analyzes files too large for one prompt, issuing
+// batch calls concurrently. Findings report lines relative to their chunk and
+// are shifted back to file positions; duplicates from overlapping chunks are
+// merged.
+func runChunkedLLMAssurance(ctx context.Context, model ChatModel, req CodeAssuranceRequest, maxChars int) ([]Finding, error) {
+    var chunks []Chunk
+    for _, f := range req.Files {
+        chunks = append(chunks, ChunkFile(f, maxChars, DefaultChunkOverlap)...)
+    }
+
+    sys := buildSystemPrompt(req)
+    batches := batchChunks(chunks, maxChars)
+    perBatch := make([][]Finding, len(batches))
+    errs := make([]error, len(batches))
+    forEachBounded(len(batches), concurrency(req), func(i int) {
+        perBatch[i], errs[i] = analyzeBatch(ctx, model, sys, req, batches[i])
+    })
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    // Keep partial results; fail only when every call failed
+    var all []Finding
+    var lastErr error
+    succeeded := 0
+    for i := range batches {
+        if errs[i] != nil {
+            lastErr = errs[i]
+            continue
+        }
+        succeeded++
+        all = append(all, perBatch[i]...)
+    }
+    if succeeded == 0 && lastErr != nil {
+        return nil, lastErr
+    }
+    return mergeOverlapping(all), nil
+}
+
+// analyzeBatch makes one LLM call for a batch and returns file-relative findings
+func analyzeBatch(ctx context.Context, model ChatModel, sys string, req CodeAssuranceRequest, batch []Chunk) ([]Finding, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    resp, err := model.Generate(ctx, []ChatMessage{
+        {Role: "system", Content: sys},
+        {Role: "user", Content: buildChunkPrompt(req, batch)},
+    })
+    if err != nil {
+        return nil, err
+    }
+    findings, ok := parseFindingsFromJSON(resp)
+    if !ok {
+        if fragment := extractJSONFragment(resp); fragment != "" {
+            findings, ok = parseFindingsFromJSON(fragment)
+        }
+    }
+    if !ok {
+        return nil, fmt.Errorf("unable to parse LLM response into findings")
+    }
+    return remapFindings(findings, batch), nil
+}
+
+func buildChunkPrompt(req CodeAssuranceRequest, batch []Chunk) string {
+    builder := &strings.Builder{}
+    if strings.TrimSpace(req.Goal) != "" {
+        fmt.Fprintf(builder, "Goal: %s\n\n", req.Goal)
+    }
+    fmt.Fprintf(builder, "Some files are shown as excerpts. Report lineStart and lineEnd relative to the excerpt shown, where its first line is line 1.\n\n")
+    fmt.Fprintf(builder, "Files:\n")
+    for _, c := range batch {
+        fmt.Fprintf(builder, "=== FILE: %s (lang: %s, excerpt lines %d-%d) ===\n", c.Path, c.Language, c.StartLine, c.EndLine)
+        fmt.Fprintf(builder, "%s\n\n", c.Content)
+    }
+    return builder.String()
+}
+
+// remapFindings shifts chunk-relative line numbers to file positions
+func remapFindings(findings []Finding, batch []Chunk) []Finding {
+    byPath := make(map[string]Chunk, len(batch))
+    for _, c := range batch {
+        byPath[c.Path] = c
+    }
+
+    out := make([]Finding, 0, len(findings))
+    for _, f := range findings {
+        c, ok := byPath[f.File]
+        if !ok && len(batch) == 1 {
+            c, ok = batch[0], true
+            f.File = c.Path
+        }
+        if ok && f.LineStart > 0 {
+            offset := c.StartLine - 1
+            f.LineStart += offset
+            if f.LineEnd > 0 {
+                f.LineEnd += offset
+            }
+            if f.LineStart > c.EndLine {
+                f.LineStart = c.EndLine
+            }
+            if f.LineEnd > c.EndLine {
+                f.LineEnd = c.EndLine
+            }
+        }
+        out = append(out, f)
+    }
+    return out
+}
+
+// mergeOverlapping drops findings with the same file and title whose line
+// ranges overlap, keeping the more severe one
+func mergeOverlapping(findings []Finding) []Finding {
+    out := make([]Finding, 0, len(findings))
+    for _, f := range findings {
+        merged := false
+        for i := range out {
+            if out[i].File != f.File || !strings.EqualFold(out[i].Title, f.Title) || !linesOverlap(out[i], f) {
+                continue
+            }
+            if severityRank(f.Severity) > severityRank(out[i].Severity) {
+                out[i] = f
+            }
+            merged = true
+            break
+        }
+        if !merged {
+            out = append(out, f)
+        }
+    }
+    return out
+}
+
+func linesOverlap(a, b Finding) bool {
+    aEnd, bEnd := a.LineEnd, b.LineEnd
+    if aEnd < a.LineStart {
+        aEnd = a.LineStart
+    }
+    if bEnd < b.LineStart {
+        bEnd = b.LineStart
+    }
+    return a.LineStart <= bEnd && b.LineStart <= aEnd
+}
+
+func requestSize(req CodeAssuranceRequest) int {
+    size := 0
+    for _, f := range req.Files {
+        size += len(f.Content)
+    }
+    return size
+}
@@ -0,0 +1,153 @@
+package quality
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// scriptedModel returns the same response to every call and records the
+// prompts and peak number of concurrent calls
+type scriptedModel struct {
+    response string
+    delay    time.Duration
+    prompts  []string
+    inFlight int
+    peak     int
+    mu       sync.Mutex
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, messages []ChatMessage) (string, error) {
+    m.mu.Lock()
+    m.prompts = append(m.prompts, messages[len(messages)-1].Content)
+    m.inFlight++
+    if m.inFlight > m.peak {
+        m.peak = m.inFlight
+    }
+    m.mu.Unlock()
+
+    time.Sleep(m.delay)
+
+    m.mu.Lock()
+    m.inFlight--
+    m.mu.Unlock()
+    return m.response, nil
+}
+
+// goFile builds a Go file of n functions, each bodyLines long
+func goFile(n, bodyLines int) string {
+    var sb strings.Builder
+    sb.WriteString("package big\n\n")
+    for i := 0; i < n; i++ {
+        fmt.Fprintf(&sb, "func f%d() {\n", i)
+        for j := 0; j < bodyLines; j++ {
+            fmt.Fprintf(&sb, "\tx := %d\n", j)
+        }
+        sb.WriteString("}\n\n")
+    }
+    return sb.String()
+}
+
+func TestChunkFile_SplitsOnFunctionBoundaries(t *testing.T) {
+    content := goFile(10, 20)
+    chunks := ChunkFile(CodeFile{Path: "big.go", Content: content}, 600, 3)
+    require.Greater(t, len(chunks), 1)
+
+    lines := strings.SplitAfter(content, "\n")
+    assert.Equal(t, 1, chunks[0].StartLine)
+    assert.Equal(t, len(lines)-1, chunks[len(chunks)-1].EndLine)
+    for i, c := range chunks {
+        assert.Equal(t, strings.Join(lines[c.StartLine-1:c.EndLine], ""), c.Content)
+        if i == 0 {
+            continue
+        }
+        // Each later chunk starts at a function, minus the overlap
+        assert.True(t, strings.HasPrefix(lines[c.StartLine-1+3], "func "), "chunk %d starts at %q", i, lines[c.StartLine-1+3])
+        assert.Equal(t, chunks[i-1].EndLine-2, c.StartLine)
+    }
+
+    single := ChunkFile(CodeFile{Path: "small.go", Content: "package small\n"}, 600, 3)
+    require.Len(t, single, 1)
+    assert.Equal(t, 1, single[0].EndLine)
+}
+
+func TestChunkFile_CutsOversizedSegments(t *testing.T) {
+    chunks := ChunkFile(CodeFile{Path: "huge.go", Content: goFile(1, 200)}, 500, 0)
+    require.Greater(t, len(chunks), 1)
+    for _, c := range chunks {
+        assert.LessOrEqual(t, len(c.Content), 500)
+    }
+}
+
+func TestRunCodeAssurance_ChunksLargeFiles(t *testing.T) {
+    model := &scriptedModel{
+        response: `{"findings":[{"title":"Unused variable","file":"big.go","lineStart":5,"severity":"medium","category":"bug"}]}`,
+    }
+    content := goFile(10, 20)
+
+    result, err := RunCodeAssurance(context.Background(), model, CodeAssuranceRequest{
+        Language:       "go",
+        Files:          []CodeFile{{Path: "big.go", Content: content}},
+        MaxPromptChars: 600,
+    })
+    require.NoError(t, err)
+    require.Greater(t, len(model.prompts), 1)
+    assert.Contains(t, model.prompts[0], "excerpt lines")
+
+    chunks := ChunkFile(CodeFile{Path: "big.go", Content: content}, 600, DefaultChunkOverlap)
+    require.Len(t, model.prompts, len(chunks))
+
+    var lines []int
+    for _, f := range result.Findings {
+        if f.Title == "Unused variable" {
+            lines = append(lines, f.LineStart)
+        }
+    }
+    require.Len(t, lines, len(chunks))
+    for i, c := range chunks {
+        assert.Contains(t, lines, c.StartLine+4, "chunk %d finding is remapped", i)
+    }
+}
+
+func TestMergeOverlapping(t *testing.T) {
+    merged := mergeOverlapping([]Finding{
+        {File: "a.go", Title: "Leak", LineStart: 10, LineEnd: 14, Severity: "medium"},
+        {File: "a.go", Title: "leak", LineStart: 12, Severity: "high"},
+        {File: "a.go", Title: "Leak", LineStart: 40, Severity: "low"},
+        {File: "b.go", Title: "Leak", LineStart: 10, Severity: "low"},
+    })
+    require.Len(t, merged, 3)
+    assert.Equal(t, "high", merged[0].Severity)
+}
+
+func TestRunCodeAssurance_BoundedConcurrency(t *testing.T) {
+    model := &scriptedModel{response: `[]`, delay: 20 * time.Millisecond}
+    var files []CodeFile
+    for i := 0; i < 12; i++ {
+        files = append(files, CodeFile{Path: fmt.Sprintf("f%02d.go", i), Content: goFile(3, 10) + "// TODO: tidy\n"})
+    }
+
+    result, err := RunCodeAssurance(context.Background(), model, CodeAssuranceRequest{
+        Files:          files,
+        MaxPromptChars: 400,
+        Concurrency:    3,
+    })
+    require.NoError(t, err)
+    assert.Len(t, model.prompts, 12, "one call per file when each file fills a batch")
+    assert.LessOrEqual(t, model.peak, 3)
+    assert.Greater(t, model.peak, 1)
+
+    var todoFiles []string
+    for _, f := range result.Findings {
+        if f.Rule == "WIP.Marker" {
+            todoFiles = append(todoFiles, f.File)
+        }
+    }
+    assert.Len(t, todoFiles, 12)
+}
@@ -13,7 +13,12 @@ import (
     "regexp"
     "sort"
     "strings"
+    "sync"
     "time"
+
+    "github.com/sormind/OSA/miosa-backend/internal/logctx"
+    "github.com/sormind/OSA/miosa-backend/internal/redact"
+    "go.uber.org/zap"
 )
 
 // ChatMessage defines the basic role/content structure for LLM communication.
@@ -38,43 +43,60 @@ type CodeFile struct {
 
 // CodeAssuranceRequest holds all necessary input for the code review process.
 type CodeAssuranceRequest struct {
-    Goal               string     `json:"goal,omitempty"`               // Optional high-level purpose of the analysis
-    Language           string     `json:"language,omitempty"`           // Predominant language (e.g., "go", "ts", "python")
-    Files              []CodeFile `json:"files"`                        // Code files to analyze (required)
-    Guidelines         []string   `json:"guidelines,omitempty"`         // Quality standards to apply
-    SeverityThreshold  string     `json:"severityThreshold,omitempty"`  // Minimum severity to report (low|medium|high|critical)
-    MaxFindings        int        `json:"maxFindings,omitempty"`        // Cap on reported issues (0 = no cap)
-    RequestUnifiedDiff bool       `json:"requestUnifiedDiff,omitempty"` // Ask LLM to return unified diffs when applicable
+    Goal               string            `json:"goal,omitempty"`               // Optional high-level purpose of the analysis
+    Language           string            `json:"language,omitempty"`           // Predominant language (e.g., "go", "ts", "python")
+    Files              []CodeFile        `json:"files"`                        // Code files to analyze (required)
+    Guidelines         []string          `json:"guidelines,omitempty"`         // Quality standards to apply
+    Packs              []string          `json:"packs,omitempty"`              // Guideline packs by name or name@version (see GuidelinePacks)
+    SeverityThreshold  string            `json:"severityThreshold,omitempty"`  // Minimum severity to report (low|medium|high|critical)
+    MaxFindings        int               `json:"maxFindings,omitempty"`        // Cap on reported issues (0 = no cap)
+    RequestUnifiedDiff bool              `json:"requestUnifiedDiff,omitempty"` // Ask LLM to return unified diffs when applicable
+    MaxPromptChars     int               `json:"maxPromptChars,omitempty"`     // Code per LLM call before files are chunked (0 = DefaultMaxPromptChars)
+    Concurrency        int               `json:"concurrency,omitempty"`        // Parallel file scans and LLM calls (0 = DefaultConcurrency)
+    MinCloneTokens     int               `json:"minCloneTokens,omitempty"`     // Shortest duplicated token run reported (0 = DefaultMinCloneTokens)
+    MetricThresholds   *MetricThresholds `json:"metricThresholds,omitempty"`   // Per-function limits (nil = DefaultMetricThresholds)
+    VulnerabilityDB    VulnerabilityDB   `json:"-"`                            // Advisory lookup for manifests (nil = no dependency audit)
+    LicensePolicy      *LicensePolicy    `json:"licensePolicy,omitempty"`      // Allowed and denied licenses (nil = no license scan)
+    LicenseResolver    LicenseResolver   `json:"-"`                            // Dependency license lookup (nil = manifests and headers only)
+    MigrationVerifier  MigrationVerifier `json:"-"`                            // Applies SQL migrations to a sandbox database (nil = lint only)
+    MaxEvidenceChars   int               `json:"maxEvidenceChars,omitempty"`   // Runes of evidence kept per finding (0 = DefaultMaxEvidenceChars)
+    Baseline           *Baseline         `json:"baseline,omitempty"`           // Prior run to analyze only the changes since (nil = whole project)
 }
 
 // Finding represents a single detected issue in the analyzed code.
 type Finding struct {
-    ID          string  `json:"id"`
-    Title       string  `json:"title"`
-    Description string  `json:"description"`
-    File        string  `json:"file"`
-    LineStart   int     `json:"lineStart,omitempty"`
-    LineEnd     int     `json:"lineEnd,omitempty"`
-    Severity    string  `json:"severity"`                // low | medium | high | critical
-    Category    string  `json:"category"`                // style | bug | security | performance | maintainability | compliance
-    Rule        string  `json:"rule,omitempty"`          // Linter/static analysis rule name
-    CWE         string  `json:"cwe,omitempty"`           // CWE identifier when applicable
-    Evidence    string  `json:"evidence,omitempty"`      // Code snippet or rationale
-    Impact      string  `json:"impact,omitempty"`        // Why this matters
-    Likelihood  string  `json:"likelihood,omitempty"`    // Qualitative likelihood
-    Remediation string  `json:"remediation,omitempty"`   // Recommended fix
-    Diff        string  `json:"diff,omitempty"`          // Optional unified diff
-    Confidence  float64 `json:"confidence,omitempty"`    // 0.0–1.0 confidence level
+    ID          string   `json:"id"`
+    Title       string   `json:"title"`
+    Description string   `json:"description"`
+    File        string   `json:"file"`
+    LineStart   int      `json:"lineStart,omitempty"`
+    LineEnd     int      `json:"lineEnd,omitempty"`
+    Severity    string   `json:"severity"`              // low | medium | high | critical
+    Category    string   `json:"category"`              // style | bug | security | performance | maintainability | compliance
+    Rule        string   `json:"rule,omitempty"`        // Linter/static analysis rule name
+    CWE         string   `json:"cwe,omitempty"`         // CWE identifier when applicable
+    Evidence    string   `json:"evidence,omitempty"`    // Code snippet or rationale
+    Impact      string   `json:"impact,omitempty"`      // Why this matters
+    Likelihood  string   `json:"likelihood,omitempty"`  // Qualitative likelihood
+    Remediation string   `json:"remediation,omitempty"` // Recommended fix
+    Diff        string   `json:"diff,omitempty"`        // Optional unified diff
+    Confidence  float64  `json:"confidence,omitempty"`  // 0.0–1.0 confidence level
+    References  []string `json:"references,omitempty"`  // Guideline pack controls the finding evidences
 }
 
 // CodeAssuranceResult aggregates all analysis outcomes.
 type CodeAssuranceResult struct {
-    SchemaVersion string    `json:"schemaVersion"`
-    Summary       string    `json:"summary"`
-    Score         float64   `json:"score"`      // 0–100, higher = better
-    Confidence    float64   `json:"confidence"` // Overall certainty (0–1)
-    Findings      []Finding `json:"findings"`
-    ExecutionMS   int64     `json:"executionMS"`
+    SchemaVersion string          `json:"schemaVersion"`
+    Summary       string          `json:"summary"`
+    Score         float64         `json:"score"`      // 0–100, higher = better
+    Confidence    float64         `json:"confidence"` // Overall certainty (0–1)
+    Findings      []Finding       `json:"findings"`
+    Metrics       *MetricsSummary `json:"metrics,omitempty"`  // Function metrics for Go, JS/TS and Python files
+    Routes        *RouteCoverage  `json:"routes,omitempty"`   // Detected endpoints vs the OpenAPI spec
+    Packs         []string        `json:"packs,omitempty"`    // Guideline packs applied, pinned to their versions
+    Manifest      Manifest        `json:"manifest,omitempty"` // File hashes, the baseline of the next incremental run
+    Incremental   *Incremental    `json:"incremental,omitempty"`
+    ExecutionMS   int64           `json:"executionMS"`
 }
 
 // RunCodeAssurance executes static heuristics and optionally augments with LLM analysis.
@@ -85,27 +107,70 @@ func RunCodeAssurance(ctx context.Context, model ChatModel, req CodeAssuranceReq
     if err := validateRequest(req); err != nil {
         return nil, err
     }
+    packs, err := resolvePacks(req.Packs, PackKindCode)
+    if err != nil {
+        return nil, err
+    }
+    req.Guidelines = packGuidelines(req.Guidelines, packs)
     minSeverity := normalizeSeverity(defaultSeverity(req.SeverityThreshold))
 
+    // 0) Against a baseline, only changed files and their neighbors are analyzed
+    manifest := HashFiles(req.Files)
+    var incremental *Incremental
+    var priorFindings []Finding
+    if req.Baseline != nil {
+        req.Files, priorFindings, incremental = narrowToChanges(req.Files, manifest, *req.Baseline)
+    }
+
     // 1) Static heuristics (fast, deterministic)
-    staticFindings := runStaticHeuristics(req)
+    staticFindings := runStaticHeuristics(ctx, req)
+    functions := collectFunctionMetrics(req)
+    staticFindings = append(staticFindings, metricFindings(functions, metricThresholds(req))...)
+    if req.VulnerabilityDB != nil {
+        // Non-fatal: an unreachable advisory database leaves other results intact
+        if f, err := AuditDependencies(ctx, req.VulnerabilityDB, req.Files); err == nil {
+            staticFindings = append(staticFindings, f...)
+        } else {
+            logctx.From(ctx).Warn("Dependency audit failed", zap.Error(err))
+        }
+    }
+    if req.LicensePolicy != nil {
+        // A failed dependency lookup still reports manifest and header licenses
+        uses, err := DetectLicenses(ctx, req.Files, req.LicenseResolver)
+        if err != nil {
+            logctx.From(ctx).Warn("Dependency license lookup failed", zap.Error(err))
+        }
+        staticFindings = append(staticFindings, LicenseFindings(uses, *req.LicensePolicy)...)
+    }
+    staticFindings = append(staticFindings, LintSQL(req.Files)...)
+    staticFindings = append(staticFindings, LintWebSecurity(req.Files)...)
+    staticFindings = append(staticFindings, migrationFindings(ctx, req.MigrationVerifier, req.Files)...)
+    var routes *RouteCoverage
+    if detected := DetectRoutes(req.Files); len(detected) > 0 {
+        routes = CheckRouteCoverage(req.Files, detected)
+        staticFindings = append(staticFindings, routeCoverageFindings(routes, detected)...)
+    }
 
     // 2) Optional LLM analysis for deeper insights
     var llmFindings []Finding
-    if model != nil {
+    if model != nil && len(req.Files) > 0 {
         if f, err := runLLMAssurance(ctx, model, req); err == nil {
             llmFindings = f
         } else {
             // Non-fatal: keep static results if LLM fails
+            logctx.From(ctx).Warn("LLM code assurance failed, keeping static findings", zap.Error(err))
         }
     }
 
-    // Merge findings (static first, then LLM)
+    // Merge findings (static first, then LLM, then those carried over)
     merged := append([]Finding{}, staticFindings...)
     merged = append(merged, llmFindings...)
+    merged = append(merged, priorFindings...)
 
     // Post-process: normalize, filter by severity, deduplicate, sort, cap
     merged = normalizeFindings(merged)
+    merged = limitFindingEvidence(merged, req.MaxEvidenceChars)
+    merged = applyPackRules(merged, packs)
     merged = filterBySeverity(merged, minSeverity)
     merged = dedupeFindings(merged)
     sortFindings(merged)
@@ -122,6 +187,11 @@ func RunCodeAssurance(ctx context.Context, model ChatModel, req CodeAssuranceReq
         Score:         score,
         Confidence:    confidence,
         Findings:      merged,
+        Metrics:       summarizeMetrics(functions),
+        Routes:        routes,
+        Packs:         packRefs(packs),
+        Manifest:      manifest,
+        Incremental:   incremental,
         ExecutionMS:   time.Since(start).Milliseconds(),
     }
     return result, nil
@@ -245,134 +315,155 @@ func dedupeFindings(in []Finding) []Finding {
 
 // -------- Static heuristics (language-agnostic + light language-aware) --------
 
-func runStaticHeuristics(req CodeAssuranceRequest) []Finding {
-    var findings []Finding
-    for _, file := range req.Files {
-        path := file.Path
-        lines := strings.Split(file.Content, "\n")
+func runStaticHeuristics(ctx context.Context, req CodeAssuranceRequest) []Finding {
+    perFile := make([][]Finding, len(req.Files))
+    forEachBounded(len(req.Files), concurrency(req), func(i int) {
+        perFile[i] = staticFileFindings(req.Files[i])
+        // Logged per file, so sampled on large projects
+        logctx.Sampled(ctx).Debug("Analyzed file",
+            zap.String("file", req.Files[i].Path),
+            zap.Int("findings", len(perFile[i])))
+    })
 
-        // 1) TODO/FIXME
-        for i, line := range lines {
-            if strings.Contains(line, "TODO") || strings.Contains(line, "FIXME") {
-                findings = append(findings, Finding{
-                    Title:       "Work-in-progress marker present (TODO/FIXME)",
-                    Description: "Found a TODO/FIXME marker. Consider resolving or converting into a tracked issue.",
-                    File:        path,
-                    LineStart:   i + 1,
-                    Severity:    "low",
-                    Category:    "maintainability",
-                    Rule:        "WIP.Marker",
-                    Evidence:    trimEvidence(line),
-                    Remediation: "Address the pending task or link to an issue; avoid leaving TODO/FIXME in production code.",
-                    Confidence:  0.65,
-                })
-            }
-        }
+    // Concatenate in file order so results are deterministic
+    var findings []Finding
+    for _, f := range perFile {
+        findings = append(findings, f...)
+    }
 
-        // 2) Hard-coded secrets (basic heuristics)
-        findings = append(findings, scanSecrets(path, lines)...)
+    // Cross-file rules
+    findings = append(findings, detectClones(req.Files, req.MinCloneTokens)...)
+    return findings
+}
 
-        // 3) Dangerous dynamic execution patterns
-        findings = append(findings, scanDynamicExecution(path, lines)...)
+// staticFileFindings runs every heuristic over one file. Evidence is the
+// whole source line; RunCodeAssurance redacts and bounds it once merged.
+func staticFileFindings(file CodeFile) []Finding {
+    var findings []Finding
+    path := file.Path
+    lines := strings.Split(file.Content, "\n")
 
-        // 4) Large file heuristic
-        if len(lines) > 1000 {
+    // 1) TODO/FIXME
+    for i, line := range lines {
+        if strings.Contains(line, "TODO") || strings.Contains(line, "FIXME") {
             findings = append(findings, Finding{
-                Title:       "Large file",
-                Description: "File is large; consider splitting into smaller modules to improve readability and testability.",
+                Title:       "Work-in-progress marker present (TODO/FIXME)",
+                Description: "Found a TODO/FIXME marker. Consider resolving or converting into a tracked issue.",
                 File:        path,
-                LineStart:   1,
-                Severity:    "medium",
+                LineStart:   i + 1,
+                Severity:    "low",
                 Category:    "maintainability",
-                Rule:        "File.Size",
-                Evidence:    fmt.Sprintf("%d lines", len(lines)),
-                Remediation: "Refactor into cohesive components with clear responsibilities.",
-                Confidence:  0.8,
+                Rule:        "WIP.Marker",
+                Evidence:    strings.TrimSpace(line),
+                Remediation: "Address the pending task or link to an issue; avoid leaving TODO/FIXME in production code.",
+                Confidence:  0.65,
             })
         }
+    }
 
-        // 5) Console/log noise in JS/TS
-        if isJavaScriptLike(file.Path, file.Language) {
-            for i, line := range lines {
-                if strings.Contains(line, "console.log(") || strings.Contains(line, "console.debug(") {
-                    findings = append(findings, Finding{
-                        Title:       "Debug logging present",
-                        Description: "Debug logging statements found; remove or guard with environment flags for production.",
-                        File:        path,
-                        LineStart:   i + 1,
-                        Severity:    "low",
-                        Category:    "style",
-                        Rule:        "Logging.DebugNoise",
-                        Evidence:    trimEvidence(line),
-                        Remediation: "Use a structured logger with levels and avoid noisy logs in hot paths.",
-                        Confidence:  0.7,
-                    })
-                }
-            }
-        }
+    // 2) Hard-coded secrets (basic heuristics)
+    findings = append(findings, scanSecrets(path, lines)...)
 
-        // 6) Go-specific risky patterns (very light-touch)
-        if isGoLike(file.Path, file.Language) {
-            for i, line := range lines {
-                if strings.Contains(line, "panic(") {
-                    findings = append(findings, Finding{
-                        Title:       "Use of panic in application code",
-                        Description: "Panic should be avoided in application/runtime paths; prefer error returns and handling.",
-                        File:        path,
-                        LineStart:   i + 1,
-                        Severity:    "medium",
-                        Category:    "reliability",
-                        Rule:        "Go.PanicUsage",
-                        Evidence:    trimEvidence(line),
-                        Remediation: "Return errors and handle them at appropriate boundaries; reserve panic for unrecoverable programmer errors.",
-                        Confidence:  0.75,
-                    })
-                }
-                if strings.Contains(line, "os/exec") || strings.Contains(line, "exec.Command(") {
-                    findings = append(findings, Finding{
-                        Title:       "External command execution",
-                        Description: "Executing external commands can be dangerous and platform-dependent.",
-                        File:        path,
-                        LineStart:   i + 1,
-                        Severity:    "medium",
-                        Category:    "security",
-                        Rule:        "Go.ExecUsage",
-                        Evidence:    trimEvidence(line),
-                        Remediation: "Validate inputs rigorously, sandbox execution, and capture/limit resources and time.",
-                        Confidence:  0.75,
-                    })
-                }
+    // 3) Dangerous dynamic execution patterns
+    findings = append(findings, scanDynamicExecution(path, lines)...)
+
+    // 4) Large file heuristic
+    if len(lines) > 1000 {
+        findings = append(findings, Finding{
+            Title:       "Large file",
+            Description: "File is large; consider splitting into smaller modules to improve readability and testability.",
+            File:        path,
+            LineStart:   1,
+            Severity:    "medium",
+            Category:    "maintainability",
+            Rule:        "File.Size",
+            Evidence:    fmt.Sprintf("%d lines", len(lines)),
+            Remediation: "Refactor into cohesive components with clear responsibilities.",
+            Confidence:  0.8,
+        })
+    }
+
+    // 5) Console/log noise in JS/TS
+    if isJavaScriptLike(file.Path, file.Language) {
+        for i, line := range lines {
+            if strings.Contains(line, "console.log(") || strings.Contains(line, "console.debug(") {
+                findings = append(findings, Finding{
+                    Title:       "Debug logging present",
+                    Description: "Debug logging statements found; remove or guard with environment flags for production.",
+                    File:        path,
+                    LineStart:   i + 1,
+                    Severity:    "low",
+                    Category:    "style",
+                    Rule:        "Logging.DebugNoise",
+                    Evidence:    strings.TrimSpace(line),
+                    Remediation: "Use a structured logger with levels and avoid noisy logs in hot paths.",
+                    Confidence:  0.7,
+                })
             }
         }
+    }
 
-        // 7) Naive SQL concatenation detection (any language)
+    // 6) Go-specific risky patterns (very light-touch)
+    if isGoLike(file.Path, file.Language) {
         for i, line := range lines {
-            if strings.Contains(strings.ToLower(line), "select ") && strings.Contains(line, "+") {
+            if strings.Contains(line, "panic(") {
+                findings = append(findings, Finding{
+                    Title:       "Use of panic in application code",
+                    Description: "Panic should be avoided in application/runtime paths; prefer error returns and handling.",
+                    File:        path,
+                    LineStart:   i + 1,
+                    Severity:    "medium",
+                    Category:    "reliability",
+                    Rule:        "Go.PanicUsage",
+                    Evidence:    strings.TrimSpace(line),
+                    Remediation: "Return errors and handle them at appropriate boundaries; reserve panic for unrecoverable programmer errors.",
+                    Confidence:  0.75,
+                })
+            }
+            if strings.Contains(line, "os/exec") || strings.Contains(line, "exec.Command(") {
                 findings = append(findings, Finding{
-                    Title:       "Potential SQL string concatenation",
-                    Description: "String concatenation in SQL may lead to SQL injection vulnerabilities.",
+                    Title:       "External command execution",
+                    Description: "Executing external commands can be dangerous and platform-dependent.",
                     File:        path,
                     LineStart:   i + 1,
-                    Severity:    "high",
+                    Severity:    "medium",
                     Category:    "security",
-                    Rule:        "SQL.Concat",
-                    CWE:         "CWE-89",
-                    Evidence:    trimEvidence(line),
-                    Remediation: "Use prepared statements or parameterized queries.",
-                    Confidence:  0.7,
+                    Rule:        "Go.ExecUsage",
+                    Evidence:    strings.TrimSpace(line),
+                    Remediation: "Validate inputs rigorously, sandbox execution, and capture/limit resources and time.",
+                    Confidence:  0.75,
                 })
             }
         }
     }
+
+    // 7) Naive SQL concatenation detection (any language)
+    for i, line := range lines {
+        if strings.Contains(strings.ToLower(line), "select ") && strings.Contains(line, "+") {
+            findings = append(findings, Finding{
+                Title:       "Potential SQL string concatenation",
+                Description: "String concatenation in SQL may lead to SQL injection vulnerabilities.",
+                File:        path,
+                LineStart:   i + 1,
+                Severity:    "high",
+                Category:    "security",
+                Rule:        "SQL.Concat",
+                CWE:         "CWE-89",
+                Evidence:    strings.TrimSpace(line),
+                Remediation: "Use prepared statements or parameterized queries.",
+                Confidence:  0.7,
+            })
+        }
+    }
     return findings
 }
 
 func scanSecrets(path string, lines []string) []Finding {
     var findings []Finding
 
-    awsKey := regexp.MustCompile(`AKIA[0-9A-Z]{16}`)
-    genericPass := regexp.MustCompile(`(?i)(password|passwd|pwd)\s*[:=]\s*['"][^'"]+['"]`)
-    genericSecret := regexp.MustCompile(`(?i)(api[_-]?key|secret|token)\s*[:=]\s*['"][^'"]+['"]`)
+    awsKey := redact.AWSAccessKey
+    genericPass := redact.GenericPassword
+    genericSecret := redact.GenericSecret
 
     for i, line := range lines {
         switch {
@@ -386,7 +477,7 @@ func scanSecrets(path string, lines []string) []Finding {
                 Category:    "security",
                 Rule:        "Secrets.AWSKey",
                 CWE:         "CWE-798",
-                Evidence:    trimEvidence(line),
+                Evidence:    strings.TrimSpace(line),
                 Remediation: "Remove the key from code, rotate the credentials, and use a secrets manager or environment variables.",
                 Confidence:  0.95,
             })
@@ -400,7 +491,7 @@ func scanSecrets(path string, lines []string) []Finding {
                 Category:    "security",
                 Rule:        "Secrets.Generic",
                 CWE:         "CWE-798",
-                Evidence:    trimEvidence(line),
+                Evidence:    strings.TrimSpace(line),
                 Remediation: "Move secrets to a secure store or environment variables; rotate any exposed credentials.",
                 Confidence:  0.85,
             })
@@ -430,7 +521,7 @@ func scanDynamicExecution(path string, lines []string) []Finding {
                 Category:    "security",
                 Rule:        "Exec.Eval",
                 CWE:         "CWE-94",
-                Evidence:    trimEvidence(line),
+                Evidence:    strings.TrimSpace(line),
                 Remediation: "Avoid eval; use safer parsing/serialization strategies or whitelisted interpreters.",
                 Confidence:  0.85,
             })
@@ -444,7 +535,7 @@ func scanDynamicExecution(path string, lines []string) []Finding {
                 Category:    "security",
                 Rule:        "Exec.FunctionConstructor",
                 CWE:         "CWE-94",
-                Evidence:    trimEvidence(line),
+                Evidence:    strings.TrimSpace(line),
                 Remediation: "Refactor to avoid runtime code construction; validate and limit inputs strictly.",
                 Confidence:  0.8,
             })
@@ -457,7 +548,7 @@ func scanDynamicExecution(path string, lines []string) []Finding {
                 Severity:    "medium",
                 Category:    "security",
                 Rule:        "Exec.Process",
-                Evidence:    trimEvidence(line),
+                Evidence:    strings.TrimSpace(line),
                 Remediation: "Validate arguments; sandbox and limit resources; prefer native libraries where possible.",
                 Confidence:  0.75,
             })
@@ -469,6 +560,14 @@ func scanDynamicExecution(path string, lines []string) []Finding {
 // -------- LLM augmentation --------
 
 func runLLMAssurance(ctx context.Context, model ChatModel, req CodeAssuranceRequest) ([]Finding, error) {
+    maxChars := req.MaxPromptChars
+    if maxChars <= 0 {
+        maxChars = DefaultMaxPromptChars
+    }
+    if requestSize(req) > maxChars {
+        return runChunkedLLMAssurance(ctx, model, req, maxChars)
+    }
+
     sys := buildSystemPrompt(req)
     usr := buildUserPrompt(req)
 
@@ -530,7 +629,7 @@ func buildUserPrompt(req CodeAssuranceRequest) string {
             lang = guessLanguageFromPath(f.Path)
         }
         fmt.Fprintf(builder, "=== FILE: %s (lang: %s) ===\n", f.Path, lang)
-        // Keep size practical; LLM adapters should handle chunking if needed
+        // Requests over MaxPromptChars are chunked before reaching here
         fmt.Fprintf(builder, "%s\n\n", f.Content)
     }
     return builder.String()
@@ -659,15 +758,6 @@ func genFindingID(f Finding) string {
     return fmt.Sprintf("F%08x", h.Sum64())
 }
 
-func trimEvidence(line string) string {
-    line = strings.TrimSpace(line)
-    const max = 200
-    if len(line) > max {
-        return line[:max] + "..."
-    }
-    return line
-}
-
 func isJavaScriptLike(path, lang string) bool {
     l := strings.ToLower(strings.TrimSpace(lang))
     if l == "js" || l == "javascript" || l == "ts" || l == "typescript" {
@@ -716,6 +806,48 @@ func safe(s, fallback string) string {
     return s
 }
 
+// DefaultConcurrency bounds parallel file scans and LLM calls per request
+const DefaultConcurrency = 8
+
+// concurrency returns the request's worker count, defaulting to DefaultConcurrency
+func concurrency(req CodeAssuranceRequest) int {
+    if req.Concurrency > 0 {
+        return req.Concurrency
+    }
+    return DefaultConcurrency
+}
+
+// forEachBounded calls fn for 0..n-1 on at most workers goroutines and
+// waits for all calls to return
+func forEachBounded(n, workers int, fn func(i int)) {
+    if workers > n {
+        workers = n
+    }
+    if workers <= 1 {
+        for i := 0; i < n; i++ {
+            fn(i)
+        }
+        return
+    }
+
+    wg := sync.WaitGroup{}
+    next := make(chan int)
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range next {
+                fn(i)
+            }
+        }()
+    }
+    for i := 0; i < n; i++ {
+        next <- i
+    }
+    close(next)
+    wg.Wait()
+}
+
 func clamp(v, lo, hi float64) float64 {
     if v < lo {
         return lo
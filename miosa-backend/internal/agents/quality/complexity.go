@@ -0,0 +1,473 @@
+package quality
+
+import (
+    "fmt"
+    "go/ast"
+    "go/parser"
+    "go/token"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// MetricThresholds are the per-function limits above which findings are emitted
+type MetricThresholds struct {
+    MaxComplexity int `json:"maxComplexity,omitempty"`
+    MaxNesting    int `json:"maxNesting,omitempty"`
+    MaxParams     int `json:"maxParams,omitempty"`
+    MaxLines      int `json:"maxLines,omitempty"`
+}
+
+// DefaultMetricThresholds applies when a request sets no thresholds
+var DefaultMetricThresholds = MetricThresholds{
+    MaxComplexity: 10,
+    MaxNesting:    4,
+    MaxParams:     5,
+    MaxLines:      60,
+}
+
+// FunctionMetrics describes one function or method
+type FunctionMetrics struct {
+    File       string `json:"file"`
+    Name       string `json:"name"`
+    LineStart  int    `json:"lineStart"`
+    LineEnd    int    `json:"lineEnd"`
+    Lines      int    `json:"lines"`
+    Complexity int    `json:"complexity"` // cyclomatic: 1 + decision points
+    MaxNesting int    `json:"maxNesting"`
+    Params     int    `json:"params"`
+}
+
+// MetricsSummary aggregates function metrics across the analyzed files
+type MetricsSummary struct {
+    Functions     int               `json:"functions"`
+    AvgComplexity float64           `json:"avgComplexity"`
+    MaxComplexity int               `json:"maxComplexity"`
+    AvgLines      float64           `json:"avgLines"`
+    MaxLines      int               `json:"maxLines"`
+    MaxNesting    int               `json:"maxNesting"`
+    Hotspots      []FunctionMetrics `json:"hotspots,omitempty"` // most complex functions first
+}
+
+const maxHotspots = 5
+
+// collectFunctionMetrics measures every function in Go, JS/TS and Python files
+func collectFunctionMetrics(req CodeAssuranceRequest) []FunctionMetrics {
+    perFile := make([][]FunctionMetrics, len(req.Files))
+    forEachBounded(len(req.Files), concurrency(req), func(i int) {
+        f := req.Files[i]
+        switch {
+        case isGoLike(f.Path, f.Language):
+            perFile[i] = goFunctionMetrics(f)
+        case isJavaScriptLike(f.Path, f.Language):
+            perFile[i] = braceFunctionMetrics(f)
+        case isPythonLike(f.Path, f.Language):
+            perFile[i] = pythonFunctionMetrics(f)
+        }
+    })
+
+    var all []FunctionMetrics
+    for _, m := range perFile {
+        all = append(all, m...)
+    }
+    return all
+}
+
+func metricThresholds(req CodeAssuranceRequest) MetricThresholds {
+    t := DefaultMetricThresholds
+    if req.MetricThresholds == nil {
+        return t
+    }
+    if req.MetricThresholds.MaxComplexity > 0 {
+        t.MaxComplexity = req.MetricThresholds.MaxComplexity
+    }
+    if req.MetricThresholds.MaxNesting > 0 {
+        t.MaxNesting = req.MetricThresholds.MaxNesting
+    }
+    if req.MetricThresholds.MaxParams > 0 {
+        t.MaxParams = req.MetricThresholds.MaxParams
+    }
+    if req.MetricThresholds.MaxLines > 0 {
+        t.MaxLines = req.MetricThresholds.MaxLines
+    }
+    return t
+}
+
+// metricFindings reports functions exceeding the thresholds. Values beyond
+// twice the limit are raised to high severity.
+func metricFindings(functions []FunctionMetrics, t MetricThresholds) []Finding {
+    var findings []Finding
+    check := func(m FunctionMetrics, value, limit int, title, rule, what, remediation string) {
+        if value <= limit {
+            return
+        }
+        severity := "medium"
+        if value > 2*limit {
+            severity = "high"
+        }
+        findings = append(findings, Finding{
+            Title:       title,
+            Description: fmt.Sprintf("Function %s has %s %d (threshold %d).", m.Name, what, value, limit),
+            File:        m.File,
+            LineStart:   m.LineStart,
+            LineEnd:     m.LineEnd,
+            Severity:    severity,
+            Category:    "maintainability",
+            Rule:        rule,
+            Evidence:    fmt.Sprintf("%s: %s %d", m.Name, what, value),
+            Remediation: remediation,
+            Confidence:  0.85,
+        })
+    }
+
+    for _, m := range functions {
+        check(m, m.Complexity, t.MaxComplexity, "High cyclomatic complexity", "Complexity.Cyclomatic", "cyclomatic complexity",
+            "Split the function into smaller functions and replace nested conditionals with early returns or lookup tables.")
+        check(m, m.MaxNesting, t.MaxNesting, "Deeply nested code", "Complexity.Nesting", "nesting depth",
+            "Flatten control flow with guard clauses and extract nested blocks into helpers.")
+        check(m, m.Params, t.MaxParams, "Too many parameters", "Function.Params", "parameter count",
+            "Group related parameters into a struct or options object.")
+        check(m, m.Lines, t.MaxLines, "Long function", "Function.Length", "length in lines",
+            "Extract cohesive steps into well-named helper functions.")
+    }
+    return findings
+}
+
+func summarizeMetrics(functions []FunctionMetrics) *MetricsSummary {
+    if len(functions) == 0 {
+        return nil
+    }
+
+    s := &MetricsSummary{Functions: len(functions)}
+    totalComplexity, totalLines := 0, 0
+    for _, m := range functions {
+        totalComplexity += m.Complexity
+        totalLines += m.Lines
+        if m.Complexity > s.MaxComplexity {
+            s.MaxComplexity = m.Complexity
+        }
+        if m.Lines > s.MaxLines {
+            s.MaxLines = m.Lines
+        }
+        if m.MaxNesting > s.MaxNesting {
+            s.MaxNesting = m.MaxNesting
+        }
+    }
+    s.AvgComplexity = float64(totalComplexity) / float64(len(functions))
+    s.AvgLines = float64(totalLines) / float64(len(functions))
+
+    hotspots := append([]FunctionMetrics(nil), functions...)
+    sort.SliceStable(hotspots, func(i, j int) bool { return hotspots[i].Complexity > hotspots[j].Complexity })
+    if len(hotspots) > maxHotspots {
+        hotspots = hotspots[:maxHotspots]
+    }
+    s.Hotspots = hotspots
+    return s
+}
+
+// -------- Go (go/ast) --------
+
+func goFunctionMetrics(file CodeFile) []FunctionMetrics {
+    fset := token.NewFileSet()
+    parsed, err := parser.ParseFile(fset, file.Path, file.Content, parser.SkipObjectResolution)
+    if err != nil {
+        // Snippets without a package clause still get brace-based metrics
+        return braceFunctionMetrics(file)
+    }
+
+    var out []FunctionMetrics
+    for _, decl := range parsed.Decls {
+        fn, ok := decl.(*ast.FuncDecl)
+        if !ok || fn.Body == nil {
+            continue
+        }
+
+        name := fn.Name.Name
+        if fn.Recv != nil && len(fn.Recv.List) > 0 {
+            name = receiverName(fn.Recv.List[0].Type) + "." + name
+        }
+        start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+
+        maxNesting := 0
+        ast.Walk(nestingVisitor{max: &maxNesting}, fn.Body)
+
+        out = append(out, FunctionMetrics{
+            File:       file.Path,
+            Name:       name,
+            LineStart:  start,
+            LineEnd:    end,
+            Lines:      end - start + 1,
+            Complexity: goComplexity(fn.Body),
+            MaxNesting: maxNesting,
+            Params:     fieldCount(fn.Type.Params),
+        })
+    }
+    return out
+}
+
+func goComplexity(body *ast.BlockStmt) int {
+    complexity := 1
+    ast.Inspect(body, func(n ast.Node) bool {
+        switch n := n.(type) {
+        case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+            complexity++
+        case *ast.CaseClause:
+            if n.List != nil {
+                complexity++
+            }
+        case *ast.CommClause:
+            if n.Comm != nil {
+                complexity++
+            }
+        case *ast.BinaryExpr:
+            if n.Op == token.LAND || n.Op == token.LOR {
+                complexity++
+            }
+        }
+        return true
+    })
+    return complexity
+}
+
+// nestingVisitor tracks control-structure depth. An else-if chain counts as
+// one level, as it reads.
+type nestingVisitor struct {
+    depth int
+    max   *int
+}
+
+func (v nestingVisitor) Visit(n ast.Node) ast.Visitor {
+    switch n := n.(type) {
+    case *ast.IfStmt:
+        inner := v.enter()
+        ast.Walk(inner, n.Body)
+        if n.Else != nil {
+            if _, elseIf := n.Else.(*ast.IfStmt); elseIf {
+                ast.Walk(v, n.Else)
+            } else {
+                ast.Walk(inner, n.Else)
+            }
+        }
+        return nil
+    case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+        return v.enter()
+    }
+    return v
+}
+
+func (v nestingVisitor) enter() nestingVisitor {
+    d := v.depth + 1
+    if d > *v.max {
+        *v.max = d
+    }
+    return nestingVisitor{depth: d, max: v.max}
+}
+
+func fieldCount(fields *ast.FieldList) int {
+    if fields == nil {
+        return 0
+    }
+    n := 0
+    for _, f := range fields.List {
+        if len(f.Names) == 0 {
+            n++
+        } else {
+            n += len(f.Names)
+        }
+    }
+    return n
+}
+
+func receiverName(expr ast.Expr) string {
+    switch t := expr.(type) {
+    case *ast.StarExpr:
+        return receiverName(t.X)
+    case *ast.IndexExpr:
+        return receiverName(t.X)
+    case *ast.Ident:
+        return t.Name
+    }
+    return "?"
+}
+
+// -------- JS/TS and other brace languages --------
+
+var (
+    jsFunctionDecl = regexp.MustCompile(`\bfunction\s*\*?\s*(\w*)\s*\(([^)]*)\)`)
+    jsArrowOrExpr  = regexp.MustCompile(`\b(\w+)\s*[:=]\s*(?:async\s*)?(?:function\s*\*?\s*\w*\s*)?\(([^)]*)\)\s*(?::\s*[\w<>\[\]|, ]+)?\s*(?:=>)?\s*\{`)
+    jsMethod       = regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|async|readonly|override|get|set)\s+)*(\w+)\s*\(([^)]*)\)\s*(?::\s*[\w<>\[\]|, ]+)?\s*\{`)
+    jsLiteral      = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`")
+    jsDecision     = regexp.MustCompile(`\b(?:if|for|while|case|catch)\b|&&|\|\||\?\?|\s\?\s`)
+    controlWords   = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true, "function": true}
+)
+
+// braceFunctionMetrics finds functions by signature and measures them by
+// brace matching. Nested functions are measured as part of their parent.
+func braceFunctionMetrics(file CodeFile) []FunctionMetrics {
+    lines := strings.Split(file.Content, "\n")
+    var out []FunctionMetrics
+
+    var current *FunctionMetrics
+    depth, bodyDepth := 0, 0
+    for i, raw := range lines {
+        line := jsLiteral.ReplaceAllString(raw, `""`)
+        if idx := strings.Index(line, "//"); idx >= 0 {
+            line = line[:idx]
+        }
+
+        if current == nil {
+            if name, params, ok := matchBraceFunction(line); ok && strings.Contains(line, "{") {
+                current = &FunctionMetrics{File: file.Path, Name: name, LineStart: i + 1, Complexity: 1, Params: countParams(params)}
+                bodyDepth = depth + 1
+            }
+        }
+
+        for _, ch := range line {
+            switch ch {
+            case '{':
+                depth++
+                if current != nil && depth-bodyDepth > current.MaxNesting {
+                    current.MaxNesting = depth - bodyDepth
+                }
+            case '}':
+                depth--
+            }
+        }
+
+        if current != nil {
+            current.Complexity += len(jsDecision.FindAllString(line, -1))
+            if depth < bodyDepth {
+                current.LineEnd = i + 1
+                current.Lines = current.LineEnd - current.LineStart + 1
+                out = append(out, *current)
+                current = nil
+            }
+        }
+    }
+    return out
+}
+
+func matchBraceFunction(line string) (name, params string, ok bool) {
+    for _, re := range []*regexp.Regexp{jsFunctionDecl, jsArrowOrExpr, jsMethod} {
+        if m := re.FindStringSubmatch(line); m != nil && !controlWords[m[1]] {
+            name = m[1]
+            if name == "" {
+                name = "(anonymous)"
+            }
+            return name, m[2], true
+        }
+    }
+    return "", "", false
+}
+
+// -------- Python (indentation) --------
+
+var (
+    pyDef      = regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+(\w+)\s*\(`)
+    pyDecision = regexp.MustCompile(`\b(?:if|elif|for|while|except|and|or|case)\b`)
+)
+
+func isPythonLike(path, lang string) bool {
+    l := strings.ToLower(strings.TrimSpace(lang))
+    return l == "python" || l == "py" || strings.HasSuffix(strings.ToLower(path), ".py")
+}
+
+// pythonFunctionMetrics measures each def by the lines indented beneath it.
+// Nested defs are measured as part of their parent.
+func pythonFunctionMetrics(file CodeFile) []FunctionMetrics {
+    lines := strings.Split(file.Content, "\n")
+    var out []FunctionMetrics
+
+    for i := 0; i < len(lines); i++ {
+        m := pyDef.FindStringSubmatch(lines[i])
+        if m == nil {
+            continue
+        }
+        indent := len(m[1])
+
+        // Parameters may span lines up to the closing parenthesis
+        sig := lines[i][strings.Index(lines[i], "(")+1:]
+        end := i
+        for !strings.Contains(sig, ")") && end+1 < len(lines) {
+            end++
+            sig += lines[end]
+        }
+        if idx := strings.LastIndex(sig, ")"); idx >= 0 {
+            sig = sig[:idx]
+        }
+
+        fm := FunctionMetrics{File: file.Path, Name: m[2], LineStart: i + 1, Complexity: 1, Params: countPythonParams(sig)}
+        var stack []int
+        last := end
+        for j := end + 1; j < len(lines); j++ {
+            trimmed := strings.TrimSpace(lines[j])
+            if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+                continue
+            }
+            lineIndent := len(lines[j]) - len(strings.TrimLeft(lines[j], " \t"))
+            if lineIndent <= indent {
+                break
+            }
+            last = j
+
+            for len(stack) > 0 && lineIndent < stack[len(stack)-1] {
+                stack = stack[:len(stack)-1]
+            }
+            if len(stack) == 0 || lineIndent > stack[len(stack)-1] {
+                stack = append(stack, lineIndent)
+            }
+            if nesting := len(stack) - 1; nesting > fm.MaxNesting {
+                fm.MaxNesting = nesting
+            }
+            if idx := strings.Index(trimmed, "#"); idx >= 0 {
+                trimmed = trimmed[:idx]
+            }
+            fm.Complexity += len(pyDecision.FindAllString(jsLiteral.ReplaceAllString(trimmed, `""`), -1))
+        }
+
+        fm.LineEnd = last + 1
+        fm.Lines = fm.LineEnd - fm.LineStart + 1
+        out = append(out, fm)
+        i = last
+    }
+    return out
+}
+
+// countParams counts top-level comma-separated parameters
+func countParams(params string) int {
+    if strings.TrimSpace(params) == "" {
+        return 0
+    }
+    n, depth := 1, 0
+    for _, ch := range params {
+        switch ch {
+        case '(', '[', '{', '<':
+            depth++
+        case ')', ']', '}', '>':
+            depth--
+        case ',':
+            if depth == 0 {
+                n++
+            }
+        }
+    }
+    if strings.HasSuffix(strings.TrimSpace(params), ",") {
+        n--
+    }
+    return n
+}
+
+func countPythonParams(params string) int {
+    n := 0
+    for _, p := range strings.Split(params, ",") {
+        p = strings.TrimSpace(p)
+        if i := strings.IndexAny(p, ":="); i >= 0 {
+            p = strings.TrimSpace(p[:i])
+        }
+        if p == "" || p == "self" || p == "cls" || p == "*" || p == "/" {
+            continue
+        }
+        n++
+    }
+    return n
+}
@@ -0,0 +1,139 @@
+package quality
+
+import (
+    "context"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+const goSource = `package shop
+
+type Cart struct{}
+
+func (c *Cart) Apply(a, b int, name string) int {
+    if a > 0 && b > 0 {
+        for i := 0; i < a; i++ {
+            if i%2 == 0 {
+                b++
+            }
+        }
+    } else if a < 0 {
+        b--
+    }
+    switch name {
+    case "x":
+        return 1
+    case "y", "z":
+        return 2
+    default:
+    }
+    return b
+}
+
+func simple() {}
+`
+
+func TestGoFunctionMetrics(t *testing.T) {
+    metrics := goFunctionMetrics(CodeFile{Path: "cart.go", Content: goSource})
+    require.Len(t, metrics, 2)
+
+    m := metrics[0]
+    assert.Equal(t, "Cart.Apply", m.Name)
+    assert.Equal(t, 5, m.LineStart)
+    assert.Equal(t, 23, m.LineEnd)
+    assert.Equal(t, 3, m.Params)
+    // 1 + if + && + for + if + else-if + 2 cases
+    assert.Equal(t, 8, m.Complexity)
+    assert.Equal(t, 3, m.MaxNesting)
+
+    assert.Equal(t, 1, metrics[1].Complexity)
+    assert.Equal(t, 0, metrics[1].MaxNesting)
+}
+
+func TestBraceFunctionMetrics(t *testing.T) {
+    src := `import x from "y";
+
+export async function load(url, opts = {}, retries) {
+  if (!url || retries < 0) {
+    throw new Error("bad {url}");
+  }
+  for (const r of [1, 2]) {
+    try {
+      return await fetch(url);
+    } catch (e) {
+      console.log(e);
+    }
+  }
+}
+
+const add = (a, b) => {
+  return a + b;
+};
+`
+    metrics := braceFunctionMetrics(CodeFile{Path: "load.js", Content: src})
+    require.Len(t, metrics, 2)
+
+    assert.Equal(t, "load", metrics[0].Name)
+    assert.Equal(t, 3, metrics[0].LineStart)
+    assert.Equal(t, 14, metrics[0].LineEnd)
+    assert.Equal(t, 3, metrics[0].Params)
+    // 1 + if + || + for + catch
+    assert.Equal(t, 5, metrics[0].Complexity)
+    assert.Equal(t, 2, metrics[0].MaxNesting)
+
+    assert.Equal(t, "add", metrics[1].Name)
+    assert.Equal(t, 2, metrics[1].Params)
+    assert.Equal(t, 1, metrics[1].Complexity)
+}
+
+func TestPythonFunctionMetrics(t *testing.T) {
+    src := `class Repo:
+    def find(self, key,
+             default=None):
+        # look it up
+        if key in self.items and key:
+            for item in self.items:
+                if item == key:
+                    return item
+        return default
+
+def noop():
+    pass
+`
+    metrics := pythonFunctionMetrics(CodeFile{Path: "repo.py", Content: src})
+    require.Len(t, metrics, 2)
+
+    assert.Equal(t, "find", metrics[0].Name)
+    assert.Equal(t, 2, metrics[0].LineStart)
+    assert.Equal(t, 9, metrics[0].LineEnd)
+    assert.Equal(t, 2, metrics[0].Params)
+    // 1 + if + and + for + if
+    assert.Equal(t, 5, metrics[0].Complexity)
+    assert.Equal(t, 3, metrics[0].MaxNesting)
+
+    assert.Equal(t, "noop", metrics[1].Name)
+    assert.Equal(t, 11, metrics[1].LineStart)
+}
+
+func TestRunCodeAssurance_Metrics(t *testing.T) {
+    result, err := RunCodeAssurance(context.Background(), nil, CodeAssuranceRequest{
+        Files:            []CodeFile{{Path: "cart.go", Content: goSource}},
+        MetricThresholds: &MetricThresholds{MaxComplexity: 3, MaxParams: 2},
+    })
+    require.NoError(t, err)
+
+    require.NotNil(t, result.Metrics)
+    assert.Equal(t, 2, result.Metrics.Functions)
+    assert.Equal(t, 8, result.Metrics.MaxComplexity)
+    assert.Equal(t, "Cart.Apply", result.Metrics.Hotspots[0].Name)
+
+    rules := map[string]string{}
+    for _, f := range result.Findings {
+        rules[f.Rule] = f.Severity
+    }
+    assert.Equal(t, "high", rules["Complexity.Cyclomatic"])
+    assert.Equal(t, "medium", rules["Function.Params"])
+    assert.NotContains(t, rules, "Function.Length")
+}
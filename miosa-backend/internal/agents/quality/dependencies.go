@@ -0,0 +1,329 @@
+package quality
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+)
+
+// DefaultOSVURL is the public OSV vulnerability database API
+const DefaultOSVURL = "https://api.osv.dev"
+
+// Dependency is one pinned package parsed from a manifest
+type Dependency struct {
+    Name      string `json:"name"`
+    Version   string `json:"version"`
+    Ecosystem string `json:"ecosystem"` // OSV ecosystem: Go, npm, PyPI
+    Manifest  string `json:"manifest"`
+    Line      int    `json:"line"`
+}
+
+// Vulnerability is an advisory affecting a dependency version
+type Vulnerability struct {
+    ID       string   `json:"id"`
+    Summary  string   `json:"summary"`
+    Aliases  []string `json:"aliases,omitempty"`
+    Severity string   `json:"severity"` // low | medium | high | critical
+    Fixed    string   `json:"fixed,omitempty"`
+}
+
+// VulnerabilityDB looks up advisories for dependencies. Results are indexed
+// like deps.
+type VulnerabilityDB interface {
+    Query(ctx context.Context, deps []Dependency) ([][]Vulnerability, error)
+}
+
+// -------- Manifest parsing --------
+
+var (
+    goRequire  = regexp.MustCompile(`^\s*(?:require\s+)?([\w.\-/~]+\.[\w.\-/~]+)\s+(v[\w.\-+]+)`)
+    pyPinned   = regexp.MustCompile(`^\s*([A-Za-z0-9_.\-]+)(?:\[[^\]]*\])?\s*==\s*([\w.\-+!]+)`)
+    npmVersion = regexp.MustCompile(`^[\^~=v]*\s*(\d+\.\d+\.\d+[\w.\-+]*)$`)
+)
+
+// ParseManifests extracts pinned dependencies from go.mod, package.json and
+// requirements.txt files. Ranges npm would resolve are audited at their
+// lower bound; unpinned Python requirements are skipped.
+func ParseManifests(files []CodeFile) []Dependency {
+    var deps []Dependency
+    for _, f := range files {
+        switch path.Base(f.Path) {
+        case "go.mod":
+            deps = append(deps, parseGoMod(f)...)
+        case "package.json":
+            deps = append(deps, parsePackageJSON(f)...)
+        case "requirements.txt":
+            deps = append(deps, parseRequirements(f)...)
+        }
+    }
+    return deps
+}
+
+func parseGoMod(f CodeFile) []Dependency {
+    var deps []Dependency
+    inBlock := false
+    for i, line := range strings.Split(f.Content, "\n") {
+        trimmed := strings.TrimSpace(line)
+        switch {
+        case strings.HasPrefix(trimmed, "require ("):
+            inBlock = true
+            continue
+        case inBlock && trimmed == ")":
+            inBlock = false
+            continue
+        case !inBlock && !strings.HasPrefix(trimmed, "require "):
+            continue
+        }
+        if m := goRequire.FindStringSubmatch(trimmed); m != nil {
+            deps = append(deps, Dependency{Name: m[1], Version: m[2], Ecosystem: "Go", Manifest: f.Path, Line: i + 1})
+        }
+    }
+    return deps
+}
+
+func parsePackageJSON(f CodeFile) []Dependency {
+    var pkg struct {
+        Dependencies    map[string]string `json:"dependencies"`
+        DevDependencies map[string]string `json:"devDependencies"`
+    }
+    if err := json.Unmarshal([]byte(f.Content), &pkg); err != nil {
+        return nil
+    }
+
+    lines := strings.Split(f.Content, "\n")
+    var deps []Dependency
+    for _, group := range []map[string]string{pkg.Dependencies, pkg.DevDependencies} {
+        for _, name := range sortedKeys(group) {
+            m := npmVersion.FindStringSubmatch(strings.TrimSpace(group[name]))
+            if m == nil {
+                continue // tags, URLs and complex ranges cannot be audited
+            }
+            deps = append(deps, Dependency{
+                Name:      name,
+                Version:   m[1],
+                Ecosystem: "npm",
+                Manifest:  f.Path,
+                Line:      lineOf(lines, `"`+name+`"`),
+            })
+        }
+    }
+    return deps
+}
+
+func parseRequirements(f CodeFile) []Dependency {
+    var deps []Dependency
+    for i, line := range strings.Split(f.Content, "\n") {
+        if m := pyPinned.FindStringSubmatch(line); m != nil {
+            deps = append(deps, Dependency{Name: m[1], Version: m[2], Ecosystem: "PyPI", Manifest: f.Path, Line: i + 1})
+        }
+    }
+    return deps
+}
+
+func sortedKeys(m map[string]string) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func lineOf(lines []string, needle string) int {
+    for i, l := range lines {
+        if strings.Contains(l, needle) {
+            return i + 1
+        }
+    }
+    return 1
+}
+
+// -------- OSV client --------
+
+// OSVClient queries the OSV API (https://osv.dev)
+type OSVClient struct {
+    baseURL     string
+    httpClient  *http.Client
+    concurrency int
+}
+
+// NewOSVClient creates a client for the OSV API at baseURL, or the public
+// instance when empty
+func NewOSVClient(baseURL string) *OSVClient {
+    if baseURL == "" {
+        baseURL = DefaultOSVURL
+    }
+    return &OSVClient{
+        baseURL: strings.TrimRight(baseURL, "/"),
+        httpClient: &http.Client{
+            Timeout: 15 * time.Second,
+        },
+        concurrency: DefaultConcurrency,
+    }
+}
+
+type osvVuln struct {
+    ID       string   `json:"id"`
+    Summary  string   `json:"summary"`
+    Details  string   `json:"details"`
+    Aliases  []string `json:"aliases"`
+    Affected []struct {
+        Package struct {
+            Name      string `json:"name"`
+            Ecosystem string `json:"ecosystem"`
+        } `json:"package"`
+        Ranges []struct {
+            Events []map[string]string `json:"events"`
+        } `json:"ranges"`
+    } `json:"affected"`
+    DatabaseSpecific struct {
+        Severity string `json:"severity"`
+    } `json:"database_specific"`
+}
+
+// Query looks up each dependency with /v1/query, which returns full
+// advisories including the fixed versions
+func (c *OSVClient) Query(ctx context.Context, deps []Dependency) ([][]Vulnerability, error) {
+    out := make([][]Vulnerability, len(deps))
+    errs := make([]error, len(deps))
+    forEachBounded(len(deps), c.concurrency, func(i int) {
+        out[i], errs[i] = c.query(ctx, deps[i])
+    })
+    for _, err := range errs {
+        if err != nil {
+            return out, err
+        }
+    }
+    return out, nil
+}
+
+func (c *OSVClient) query(ctx context.Context, dep Dependency) ([]Vulnerability, error) {
+    // OSV expects Go versions without the v prefix
+    body, _ := json.Marshal(map[string]interface{}{
+        "version": strings.TrimPrefix(dep.Version, "v"),
+        "package": map[string]string{"name": dep.Name, "ecosystem": dep.Ecosystem},
+    })
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/query", bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("osv query for %s failed: %w", dep.Name, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("osv query for %s returned status %d", dep.Name, resp.StatusCode)
+    }
+
+    var parsed struct {
+        Vulns []osvVuln `json:"vulns"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+        return nil, fmt.Errorf("invalid osv response for %s: %w", dep.Name, err)
+    }
+
+    vulns := make([]Vulnerability, 0, len(parsed.Vulns))
+    for _, v := range parsed.Vulns {
+        summary := v.Summary
+        if summary == "" {
+            summary = firstLine(v.Details)
+        }
+        vulns = append(vulns, Vulnerability{
+            ID:       v.ID,
+            Summary:  summary,
+            Aliases:  v.Aliases,
+            Severity: osvSeverity(v.DatabaseSpecific.Severity),
+            Fixed:    fixedVersion(v, dep),
+        })
+    }
+    return vulns, nil
+}
+
+// osvSeverity maps GHSA-style severities; advisories without one are
+// treated as high
+func osvSeverity(s string) string {
+    switch strings.ToUpper(s) {
+    case "CRITICAL":
+        return "critical"
+    case "MODERATE", "MEDIUM":
+        return "medium"
+    case "LOW":
+        return "low"
+    default:
+        return "high"
+    }
+}
+
+// fixedVersion returns the first fixed event for the dependency's package
+func fixedVersion(v osvVuln, dep Dependency) string {
+    for _, a := range v.Affected {
+        if a.Package.Name != dep.Name {
+            continue
+        }
+        for _, r := range a.Ranges {
+            for _, e := range r.Events {
+                if fixed := e["fixed"]; fixed != "" {
+                    return fixed
+                }
+            }
+        }
+    }
+    return ""
+}
+
+func firstLine(s string) string {
+    if i := strings.IndexByte(s, '\n'); i >= 0 {
+        return s[:i]
+    }
+    return s
+}
+
+// -------- Audit --------
+
+// AuditDependencies parses manifests in files and reports every known
+// vulnerability as a security finding on the manifest line
+func AuditDependencies(ctx context.Context, db VulnerabilityDB, files []CodeFile) ([]Finding, error) {
+    deps := ParseManifests(files)
+    if len(deps) == 0 || db == nil {
+        return nil, nil
+    }
+
+    results, err := db.Query(ctx, deps)
+    if err != nil {
+        return nil, err
+    }
+
+    var findings []Finding
+    for i, dep := range deps {
+        for _, v := range results[i] {
+            remediation := fmt.Sprintf("No fixed version is published; replace %s or mitigate per %s.", dep.Name, v.ID)
+            if v.Fixed != "" {
+                remediation = fmt.Sprintf("Upgrade %s to %s or later.", dep.Name, v.Fixed)
+            }
+            findings = append(findings, Finding{
+                Title:       fmt.Sprintf("Vulnerable dependency %s@%s (%s)", dep.Name, dep.Version, v.ID),
+                Description: safe(v.Summary, "Known vulnerability in this dependency version."),
+                File:        dep.Manifest,
+                LineStart:   dep.Line,
+                Severity:    v.Severity,
+                Category:    "security",
+                Rule:        "Dependency.Vulnerable",
+                Evidence:    strings.TrimSpace(strings.Join(append([]string{v.ID}, v.Aliases...), " ")),
+                Remediation: remediation,
+                Confidence:  0.95,
+            })
+        }
+    }
+    return findings, nil
+}
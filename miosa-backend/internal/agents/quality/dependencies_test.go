@@ -0,0 +1,106 @@
+package quality
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/conneroisu/groq-go"
+    "github.com/sormind/OSA/miosa-backend/internal/agents"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func manifestFiles() []CodeFile {
+    return []CodeFile{
+        {Path: "go.mod", Content: "module example.com/app\n\ngo 1.22\n\nrequire github.com/gin-gonic/gin v1.6.0\n\nrequire (\n\tgolang.org/x/text v0.3.5 // indirect\n)\n"},
+        {Path: "web/package.json", Content: "{\n  \"dependencies\": {\n    \"lodash\": \"^4.17.15\",\n    \"react\": \"latest\"\n  },\n  \"devDependencies\": {\n    \"jest\": \"29.0.0\"\n  }\n}\n"},
+        {Path: "requirements.txt", Content: "flask==0.12.2\nrequests>=2.0\n"},
+    }
+}
+
+func TestParseManifests(t *testing.T) {
+    deps := ParseManifests(manifestFiles())
+    require.Len(t, deps, 5)
+
+    assert.Equal(t, Dependency{Name: "github.com/gin-gonic/gin", Version: "v1.6.0", Ecosystem: "Go", Manifest: "go.mod", Line: 5}, deps[0])
+    assert.Equal(t, "golang.org/x/text", deps[1].Name)
+    assert.Equal(t, 8, deps[1].Line)
+    assert.Equal(t, Dependency{Name: "lodash", Version: "4.17.15", Ecosystem: "npm", Manifest: "web/package.json", Line: 3}, deps[2])
+    assert.Equal(t, "jest", deps[3].Name)
+    assert.Equal(t, Dependency{Name: "flask", Version: "0.12.2", Ecosystem: "PyPI", Manifest: "requirements.txt", Line: 1}, deps[4])
+}
+
+// fakeOSV reports one advisory for lodash and nothing else
+func fakeOSV(t *testing.T) *httptest.Server {
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        assert.Equal(t, "/v1/query", r.URL.Path)
+        var q struct {
+            Version string            `json:"version"`
+            Package map[string]string `json:"package"`
+        }
+        require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+
+        if q.Package["name"] != "lodash" {
+            w.Write([]byte(`{}`))
+            return
+        }
+        assert.Equal(t, "4.17.15", q.Version)
+        w.Write([]byte(`{"vulns":[{
+            "id":"GHSA-p6mc-m468-83gw",
+            "summary":"Prototype Pollution in lodash",
+            "aliases":["CVE-2020-8203"],
+            "affected":[{"package":{"name":"lodash","ecosystem":"npm"},"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"4.17.19"}]}]}],
+            "database_specific":{"severity":"HIGH"}
+        }]}`))
+    }))
+}
+
+func TestAuditDependencies(t *testing.T) {
+    server := fakeOSV(t)
+    defer server.Close()
+
+    findings, err := AuditDependencies(context.Background(), NewOSVClient(server.URL), manifestFiles())
+    require.NoError(t, err)
+    require.Len(t, findings, 1)
+
+    f := findings[0]
+    assert.Equal(t, "Dependency.Vulnerable", f.Rule)
+    assert.Equal(t, "security", f.Category)
+    assert.Equal(t, "high", f.Severity)
+    assert.Equal(t, "web/package.json", f.File)
+    assert.Equal(t, 3, f.LineStart)
+    assert.Contains(t, f.Evidence, "CVE-2020-8203")
+    assert.Equal(t, "Upgrade lodash to 4.17.19 or later.", f.Remediation)
+}
+
+func TestQualityAgent_DependencyGate(t *testing.T) {
+    server := fakeOSV(t)
+    defer server.Close()
+
+    // The LLM is unavailable; the agent falls back to default doing notes
+    llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusBadRequest)
+    }))
+    defer llm.Close()
+    client, err := groq.NewClient("test-key", groq.WithBaseURL(llm.URL))
+    require.NoError(t, err)
+
+    agent := New(client).(*QualityAgent)
+    agent.vulnDB = NewOSVClient(server.URL)
+    agent.licenses = nil
+    var generated []agents.GeneratedFile
+    for _, f := range manifestFiles() {
+        generated = append(generated, agents.GeneratedFile{Path: f.Path, Content: f.Content})
+    }
+
+    result, err := agent.Execute(context.Background(), agents.Task{
+        Input:      "shop",
+        Parameters: map[string]interface{}{"files": generated},
+    })
+    require.NoError(t, err)
+    assert.False(t, result.Success)
+    assert.Contains(t, result.Suggestions, "Upgrade lodash to 4.17.19 or later.")
+}
@@ -0,0 +1,133 @@
+package quality
+
+import (
+    "fmt"
+    "hash/fnv"
+    "regexp"
+    "strings"
+)
+
+// DefaultMinCloneTokens is the shortest duplicated token run reported as a clone
+const DefaultMinCloneTokens = 50
+
+// cloneToken matches identifiers, numbers, string literals and single
+// punctuation characters
+var cloneToken = regexp.MustCompile(`[A-Za-z_]\w*|\d+(?:\.\d+)?|"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`" + `|\S`)
+
+type cloneTok struct {
+    text string
+    hash uint64
+    line int
+}
+
+type tokenPos struct {
+    file  int
+    index int
+}
+
+// tokenize splits a file into tokens, skipping blank and comment-only lines
+func tokenize(content string) []cloneTok {
+    var tokens []cloneTok
+    for i, line := range strings.Split(content, "\n") {
+        trimmed := strings.TrimSpace(line)
+        if trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") ||
+            strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*") {
+            continue
+        }
+        for _, text := range cloneToken.FindAllString(line, -1) {
+            h := fnv.New64a()
+            _, _ = h.Write([]byte(text))
+            tokens = append(tokens, cloneTok{text: text, hash: h.Sum64(), line: i + 1})
+        }
+    }
+    return tokens
+}
+
+// detectClones finds token runs of at least minTokens that appear more than
+// once across the files. Windows of minTokens tokens are hashed with a
+// rolling hash; each repeat of an earlier window is verified and extended to
+// its full length, then reported once against the first occurrence.
+func detectClones(files []CodeFile, minTokens int) []Finding {
+    if minTokens <= 0 {
+        minTokens = DefaultMinCloneTokens
+    }
+
+    tokens := make([][]cloneTok, len(files))
+    for i, f := range files {
+        tokens[i] = tokenize(f.Content)
+    }
+
+    const base = 1000003
+    var pow uint64 = 1
+    for i := 0; i < minTokens-1; i++ {
+        pow *= base
+    }
+
+    first := make(map[uint64]tokenPos)
+    var findings []Finding
+    for fi, ft := range tokens {
+        if len(ft) < minTokens {
+            continue
+        }
+
+        var h uint64
+        for i := 0; i < minTokens; i++ {
+            h = h*base + ft[i].hash
+        }
+
+        // skipUntil suppresses windows inside a clone already reported
+        skipUntil := 0
+        for j := 0; ; j++ {
+            if j >= skipUntil {
+                if prev, ok := first[h]; !ok {
+                    first[h] = tokenPos{file: fi, index: j}
+                } else if n := matchLength(tokens, prev, tokenPos{fi, j}); n >= minTokens {
+                    findings = append(findings, cloneFinding(files, tokens, prev, tokenPos{fi, j}, n))
+                    skipUntil = j + n
+                }
+            }
+
+            if j+minTokens >= len(ft) {
+                break
+            }
+            h = (h-ft[j].hash*pow)*base + ft[j+minTokens].hash
+        }
+    }
+    return findings
+}
+
+// matchLength returns how many tokens match from a and b, never letting a
+// clone overlap itself within one file
+func matchLength(tokens [][]cloneTok, a, b tokenPos) int {
+    ta, tb := tokens[a.file], tokens[b.file]
+    n := 0
+    for a.index+n < len(ta) && b.index+n < len(tb) && ta[a.index+n].text == tb[b.index+n].text {
+        if a.file == b.file && a.index+n >= b.index {
+            break
+        }
+        n++
+    }
+    return n
+}
+
+func cloneFinding(files []CodeFile, tokens [][]cloneTok, orig, dup tokenPos, n int) Finding {
+    origStart := tokens[orig.file][orig.index].line
+    origEnd := tokens[orig.file][orig.index+n-1].line
+    dupStart := tokens[dup.file][dup.index].line
+    dupEnd := tokens[dup.file][dup.index+n-1].line
+    origLoc := fmt.Sprintf("%s:%d-%d", files[orig.file].Path, origStart, origEnd)
+
+    return Finding{
+        Title:       "Duplicated code block",
+        Description: fmt.Sprintf("This block duplicates %d tokens of %s. Duplicated logic must be fixed in every copy and tends to drift.", n, origLoc),
+        File:        files[dup.file].Path,
+        LineStart:   dupStart,
+        LineEnd:     dupEnd,
+        Severity:    "medium",
+        Category:    "maintainability",
+        Rule:        "Duplication.Clone",
+        Evidence:    fmt.Sprintf("%d tokens duplicated at %s and %s:%d-%d", n, origLoc, files[dup.file].Path, dupStart, dupEnd),
+        Remediation: "Extract the shared logic into a single function or module and call it from both locations.",
+        Confidence:  0.9,
+    }
+}
@@ -0,0 +1,63 @@
+package quality
+
+import (
+    "context"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+const sharedBlock = `    total := 0
+    for _, item := range items {
+        if item.Price > 0 && item.Quantity > 0 {
+            total += item.Price * item.Quantity
+        }
+    }
+    return total
+`
+
+func TestDetectClones(t *testing.T) {
+    files := []CodeFile{
+        {Path: "orders.go", Content: "package shop\n\nfunc orderTotal(items []Item) int {\n" + sharedBlock + "}\n"},
+        {Path: "cart.go", Content: "package shop\n\n// cartTotal sums the cart\nfunc cartTotal(items []Item) int {\n" + sharedBlock + "}\n"},
+        {Path: "other.go", Content: "package shop\n\nfunc other() int {\n    return 1\n}\n"},
+    }
+
+    clones := detectClones(files, 30)
+    require.Len(t, clones, 1)
+    c := clones[0]
+    assert.Equal(t, "Duplication.Clone", c.Rule)
+    assert.Equal(t, "maintainability", c.Category)
+    assert.Equal(t, "cart.go", c.File)
+    assert.Equal(t, 4, c.LineStart)
+    assert.Equal(t, 12, c.LineEnd)
+    assert.Contains(t, c.Evidence, "orders.go:3-11")
+    assert.Contains(t, c.Remediation, "Extract")
+
+    assert.Empty(t, detectClones(files, 200), "runs shorter than the minimum are ignored")
+}
+
+func TestDetectClones_SameFile(t *testing.T) {
+    content := "package shop\n\nfunc a(items []Item) int {\n" + sharedBlock + "}\n\nfunc b(items []Item) int {\n" + sharedBlock + "}\n"
+    clones := detectClones([]CodeFile{{Path: "shop.go", Content: content}}, 30)
+    require.Len(t, clones, 1)
+    assert.Equal(t, 13, clones[0].LineStart)
+}
+
+func TestRunCodeAssurance_ReportsClones(t *testing.T) {
+    result, err := RunCodeAssurance(context.Background(), nil, CodeAssuranceRequest{
+        Files: []CodeFile{
+            {Path: "a.go", Content: sharedBlock},
+            {Path: "b.go", Content: sharedBlock},
+        },
+        MinCloneTokens: 20,
+    })
+    require.NoError(t, err)
+
+    var rules []string
+    for _, f := range result.Findings {
+        rules = append(rules, f.Rule)
+    }
+    assert.Contains(t, rules, "Duplication.Clone")
+}
@@ -0,0 +1,312 @@
+package quality
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+)
+
+// e2eDir holds the generated Playwright suite in a project
+const e2eDir = "e2e"
+
+// Files of the generated Playwright suite, and where Playwright leaves the
+// screenshots, videos and traces of failed tests
+const (
+    E2ESpec       = e2eDir + "/flows.spec.ts"
+    E2EConfig     = e2eDir + "/playwright.config.ts"
+    E2EResultsDir = e2eDir + "/results"
+)
+
+// PlaywrightVersion pins the Playwright installed in the sandbox
+const PlaywrightVersion = "1.47.2"
+
+// DefaultE2ETimeout bounds installing Playwright and its browser and running
+// the suite
+const DefaultE2ETimeout = 10 * time.Minute
+
+// ErrNoUserFlows is returned when a project has neither endpoints nor pages
+// to derive user flows from
+var ErrNoUserFlows = errors.New("no endpoints or pages to derive user flows from")
+
+// FlowStep is one request of a user flow
+type FlowStep struct {
+    Method string `json:"method"`
+    Path   string `json:"path"` // OpenAPI form; parameters take the ID the flow created, or 1
+}
+
+// UserFlow is what a user does with one resource, in order: list it,
+// create one, read, update and delete it
+type UserFlow struct {
+    Resource string     `json:"resource"` // Collection path, e.g. /orders
+    Steps    []FlowStep `json:"steps"`
+}
+
+// E2EPlan is a generated Playwright suite: API flows run with Playwright's
+// request context, and pages opened in Chromium
+type E2EPlan struct {
+    BaseURL string     `json:"baseUrl"`
+    Flows   []UserFlow `json:"flows"`
+    Pages   []string   `json:"pages,omitempty"`
+    Spec    string     `json:"-"`
+    Config  string     `json:"-"`
+}
+
+// FlowResult is the outcome of one flow or page test
+type FlowResult struct {
+    Name        string   `json:"name"`
+    Passed      bool     `json:"passed"`
+    Error       string   `json:"error,omitempty"`
+    Attachments []string `json:"attachments,omitempty"` // Screenshots, videos and traces, relative to E2EResultsDir
+}
+
+// E2EReport is the outcome of an end-to-end run
+type E2EReport struct {
+    Tool   string       `json:"tool"`
+    Target string       `json:"target"`
+    Flows  []FlowResult `json:"flows"`
+    Passed bool         `json:"passed"`
+    Error  string       `json:"error,omitempty"` // Why the suite could not produce results
+
+    // Artifacts holds the files of Attachments, for the caller to store
+    // with the workflow
+    Artifacts []SandboxFile `json:"-"`
+}
+
+var (
+    itemPath  = regexp.MustCompile(`/\{[^}/]+\}$`)
+    frontends = regexp.MustCompile(`"(react|vue|svelte|next|nuxt|@angular/core)"\s*:`)
+)
+
+// stepOrder sorts a resource's steps: collection before item, then by what
+// a user does first
+var stepOrder = map[string]int{"GET": 0, "POST": 1, "PUT": 2, "PATCH": 3, "DELETE": 4}
+
+// NewE2EPlan derives user flows from the endpoints detected in files, one
+// per resource, and visits the home page when files hold a frontend.
+// Routes are those of the project's OpenAPI document.
+func NewE2EPlan(files []CodeFile, baseURL string) (*E2EPlan, error) {
+    plan := &E2EPlan{BaseURL: strings.TrimRight(baseURL, "/")}
+    byResource := map[string][]FlowStep{}
+    for _, r := range DetectRoutes(files) {
+        resource := itemPath.ReplaceAllString(r.Path, "")
+        if resource == "" {
+            resource = "/"
+        }
+        byResource[resource] = append(byResource[resource], FlowStep{Method: r.Method, Path: r.Path})
+    }
+    for resource, steps := range byResource {
+        sort.SliceStable(steps, func(i, j int) bool {
+            iItem, jItem := steps[i].Path != resource, steps[j].Path != resource
+            if iItem != jItem {
+                return !iItem
+            }
+            return stepOrder[steps[i].Method] < stepOrder[steps[j].Method]
+        })
+        plan.Flows = append(plan.Flows, UserFlow{Resource: resource, Steps: steps})
+    }
+    sort.Slice(plan.Flows, func(i, j int) bool { return plan.Flows[i].Resource < plan.Flows[j].Resource })
+
+    for _, f := range files {
+        if strings.Contains(f.Path, "node_modules/") {
+            continue
+        }
+        if path.Base(f.Path) == "index.html" || (path.Base(f.Path) == "package.json" && frontends.MatchString(f.Content)) {
+            plan.Pages = []string{"/"}
+            break
+        }
+    }
+    if len(plan.Flows) == 0 && len(plan.Pages) == 0 {
+        return nil, ErrNoUserFlows
+    }
+    plan.Spec = renderPlaywrightSpec(plan)
+    plan.Config = renderPlaywrightConfig(plan)
+    return plan, nil
+}
+
+// renderPlaywrightSpec writes a test per flow and page. Flows only fail on
+// server errors, as requests carry empty bodies that validation and auth
+// may rightly reject.
+func renderPlaywrightSpec(plan *E2EPlan) string {
+    flows, _ := json.MarshalIndent(append([]UserFlow{}, plan.Flows...), "", "  ")
+    pages, _ := json.Marshal(append([]string{}, plan.Pages...))
+    var sb strings.Builder
+    sb.WriteString("// Generated by MIOSA. Run with: BASE_URL=http://localhost:8080 npx playwright test -c " + E2EConfig + "\n")
+    sb.WriteString("import { test, expect } from '@playwright/test';\n\n")
+    fmt.Fprintf(&sb, "const flows = %s;\n\nconst pages = %s;\n\n", flows, pages)
+    sb.WriteString(`for (const flow of flows) {
+  test(` + "`flow ${flow.resource}`" + `, async ({ request }) => {
+    let id = '1';
+    for (const step of flow.steps) {
+      const url = step.path.replace(/\{[^}/]+\}/g, id);
+      const res = await request.fetch(url, {
+        method: step.method,
+        data: step.method === 'POST' || step.method === 'PUT' || step.method === 'PATCH' ? {} : undefined,
+      });
+      expect(res.status(), ` + "`${step.method} ${url}`" + `).toBeLessThan(500);
+      if (step.method === 'POST') {
+        const created = await res.json().catch(() => null);
+        if (created && created.id !== undefined) id = String(created.id);
+      }
+    }
+  });
+}
+
+for (const url of pages) {
+  test(` + "`page ${url}`" + `, async ({ page }) => {
+    const res = await page.goto(url);
+    expect(res?.status() ?? 0, ` + "`GET ${url}`" + `).toBeLessThan(400);
+    await expect(page.locator('body')).toBeVisible();
+  });
+}
+`)
+    return sb.String()
+}
+
+// renderPlaywrightConfig keeps screenshots, videos and traces of failed
+// tests in E2EResultsDir
+func renderPlaywrightConfig(plan *E2EPlan) string {
+    return "// Generated by MIOSA\n" +
+        "import { defineConfig } from '@playwright/test';\n\n" +
+        "export default defineConfig({\n" +
+        "  testDir: '.',\n" +
+        "  outputDir: '" + path.Base(E2EResultsDir) + "',\n" +
+        "  timeout: 30000,\n" +
+        "  retries: 0,\n" +
+        "  use: {\n" +
+        "    baseURL: process.env.BASE_URL || " + jsonString(plan.BaseURL) + ",\n" +
+        "    screenshot: 'only-on-failure',\n" +
+        "    video: 'retain-on-failure',\n" +
+        "    trace: 'retain-on-failure',\n" +
+        "  },\n" +
+        "});\n"
+}
+
+// Command is the shell command running the suite from the project root.
+// Installation output is discarded so only the JSON report reaches stdout.
+func (p *E2EPlan) Command() string {
+    return "([ -f package.json ] || npm init -y >/dev/null 2>&1); " +
+        "npm install --no-save --silent --no-audit --no-fund @playwright/test@" + PlaywrightVersion + " >/dev/null 2>&1; " +
+        "npx --yes playwright install --with-deps chromium >/dev/null 2>&1; " +
+        "BASE_URL=" + shellQuote(p.BaseURL) + " npx --yes playwright test -c " + E2EConfig + " --reporter=json 2>/dev/null"
+}
+
+// shellQuote quotes s as one shell word
+func shellQuote(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RunE2E runs the plan's suite in the sandbox against plan.BaseURL, with
+// files as the project. The files attached to failed tests are returned in
+// the report's Artifacts.
+func RunE2E(ctx context.Context, sandbox Sandbox, files []CodeFile, plan *E2EPlan) *E2EReport {
+    report := &E2EReport{Tool: "playwright", Target: plan.BaseURL}
+    project := make([]CodeFile, 0, len(files)+2)
+    for _, f := range files {
+        if f.Path != E2ESpec && f.Path != E2EConfig {
+            project = append(project, f)
+        }
+    }
+    project = append(project, CodeFile{Path: E2ESpec, Content: plan.Spec}, CodeFile{Path: E2EConfig, Content: plan.Config})
+
+    out, err := sandbox.Exec(ctx, SandboxCommand{Files: project, Command: plan.Command(), Timeout: DefaultE2ETimeout, Collect: E2EResultsDir})
+    if err != nil {
+        report.Error = err.Error()
+        return report
+    }
+    if err := parsePlaywright(report, out.Stdout); err != nil {
+        report.Error = outputTail(out)
+        return report
+    }
+
+    attached := map[string]bool{}
+    report.Passed = true
+    for _, f := range report.Flows {
+        report.Passed = report.Passed && f.Passed
+        for _, a := range f.Attachments {
+            attached[a] = true
+        }
+    }
+    for _, f := range out.Files {
+        if rel := strings.TrimPrefix(f.Path, E2EResultsDir+"/"); attached[rel] {
+            report.Artifacts = append(report.Artifacts, SandboxFile{Path: rel, Content: f.Content})
+        }
+    }
+    return report
+}
+
+type playwrightSuite struct {
+    Specs []struct {
+        Title string `json:"title"`
+        OK    bool   `json:"ok"`
+        Tests []struct {
+            Results []struct {
+                Error *struct {
+                    Message string `json:"message"`
+                } `json:"error"`
+                Attachments []struct {
+                    Path string `json:"path"`
+                } `json:"attachments"`
+            } `json:"results"`
+        } `json:"tests"`
+    } `json:"specs"`
+    Suites []playwrightSuite `json:"suites"`
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// parsePlaywright fills report from Playwright's JSON reporter. Attachment
+// paths are made relative to E2EResultsDir.
+func parsePlaywright(report *E2EReport, stdout string) error {
+    start := strings.Index(stdout, "{")
+    if start < 0 {
+        return errors.New("no report")
+    }
+    var parsed struct {
+        Suites []playwrightSuite `json:"suites"`
+    }
+    if err := json.Unmarshal([]byte(stdout[start:]), &parsed); err != nil {
+        return err
+    }
+    var walk func(suites []playwrightSuite)
+    walk = func(suites []playwrightSuite) {
+        for _, s := range suites {
+            for _, spec := range s.Specs {
+                result := FlowResult{Name: spec.Title, Passed: spec.OK}
+                for _, t := range spec.Tests {
+                    for _, r := range t.Results {
+                        if r.Error != nil && result.Error == "" {
+                            result.Error = truncateMessage(ansiEscape.ReplaceAllString(r.Error.Message, ""))
+                        }
+                        for _, a := range r.Attachments {
+                            if _, rel, ok := strings.Cut(a.Path, E2EResultsDir+"/"); ok && !spec.OK {
+                                result.Attachments = append(result.Attachments, rel)
+                            }
+                        }
+                    }
+                }
+                report.Flows = append(report.Flows, result)
+            }
+            walk(s.Suites)
+        }
+    }
+    walk(parsed.Suites)
+    if len(report.Flows) == 0 {
+        return errors.New("no tests ran")
+    }
+    return nil
+}
+
+// truncateMessage bounds a failure message like test feedback
+func truncateMessage(msg string) string {
+    msg = strings.TrimSpace(msg)
+    if len(msg) > maxFeedbackMessage {
+        msg = msg[:maxFeedbackMessage] + "..."
+    }
+    return msg
+}
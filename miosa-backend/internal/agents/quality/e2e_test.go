@@ -0,0 +1,135 @@
+package quality
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+var e2eProject = []CodeFile{
+    {Path: "main.go", Content: `package main
+
+func main() {
+    r := gin.Default()
+    r.DELETE("/orders/:id", remove)
+    r.GET("/orders/:id", get)
+    r.POST("/orders", create)
+    r.GET("/orders", list)
+    r.GET("/health", health)
+}
+`},
+    {Path: "web/package.json", Content: `{"dependencies": {"react": "^18.2.0"}}`},
+}
+
+func TestNewE2EPlan(t *testing.T) {
+    plan, err := NewE2EPlan(e2eProject, "http://smoke:8080/")
+    require.NoError(t, err)
+    assert.Equal(t, "http://smoke:8080", plan.BaseURL)
+    require.Len(t, plan.Flows, 2)
+    assert.Equal(t, UserFlow{Resource: "/orders", Steps: []FlowStep{
+        {Method: "GET", Path: "/orders"},
+        {Method: "POST", Path: "/orders"},
+        {Method: "GET", Path: "/orders/{id}"},
+        {Method: "DELETE", Path: "/orders/{id}"},
+    }}, plan.Flows[1], "a user lists, creates, reads, then deletes")
+    assert.Equal(t, []string{"/"}, plan.Pages, "frontends get a page test")
+
+    assert.Contains(t, plan.Spec, `"resource": "/orders"`)
+    assert.Contains(t, plan.Spec, "const pages = [\"/\"];")
+    assert.Contains(t, plan.Config, `baseURL: process.env.BASE_URL || "http://smoke:8080"`)
+    assert.Contains(t, plan.Config, "screenshot: 'only-on-failure'")
+    assert.Contains(t, plan.Command(), "BASE_URL='http://smoke:8080' npx --yes playwright test -c "+E2EConfig)
+
+    // Without a frontend, pages render as an empty list
+    apiOnly, err := NewE2EPlan(e2eProject[:1], "")
+    require.NoError(t, err)
+    assert.Contains(t, apiOnly.Spec, "const pages = [];")
+
+    _, err = NewE2EPlan([]CodeFile{{Path: "README.md"}}, "")
+    assert.ErrorIs(t, err, ErrNoUserFlows)
+
+    // Playwright specs are not jest suites
+    assert.Empty(t, DetectTestSuites([]CodeFile{{Path: E2ESpec}}))
+}
+
+const playwrightJSON = `{
+  "config": {},
+  "suites": [{
+    "title": "flows.spec.ts",
+    "specs": [
+      {"title": "flow /orders", "ok": true, "tests": [{"results": [{"status": "passed", "attachments": []}]}]}
+    ],
+    "suites": [{
+      "title": "nested",
+      "specs": [{
+        "title": "page /",
+        "ok": false,
+        "tests": [{"results": [{
+          "status": "failed",
+          "error": {"message": "\u001b[31mError: GET / expected < 400, received 502\u001b[39m"},
+          "attachments": [
+            {"name": "screenshot", "contentType": "image/png", "path": "/tmp/app/e2e/results/flows-page-chromium/test-failed-1.png"},
+            {"name": "video", "contentType": "video/webm", "path": "/tmp/app/e2e/results/flows-page-chromium/video.webm"}
+          ]
+        }]}]
+      }]
+    }]
+  }]
+}`
+
+type e2eSandbox struct {
+    cmd SandboxCommand
+    out *SandboxOutput
+    err error
+}
+
+func (s *e2eSandbox) Exec(ctx context.Context, cmd SandboxCommand) (*SandboxOutput, error) {
+    s.cmd = cmd
+    return s.out, s.err
+}
+
+func TestRunE2E(t *testing.T) {
+    plan, err := NewE2EPlan(e2eProject, "http://smoke:8080")
+    require.NoError(t, err)
+    sandbox := &e2eSandbox{out: &SandboxOutput{ExitCode: 1, Stdout: playwrightJSON, Files: []SandboxFile{
+        {Path: "e2e/results/flows-page-chromium/test-failed-1.png", Content: []byte("png")},
+        {Path: "e2e/results/flows-page-chromium/video.webm", Content: []byte("webm")},
+        {Path: "e2e/results/.last-run.json", Content: []byte("{}")},
+    }}}
+
+    report := RunE2E(context.Background(), sandbox, append(e2eProject, CodeFile{Path: E2ESpec, Content: "stale"}), plan)
+    assert.Equal(t, E2EResultsDir, sandbox.cmd.Collect)
+    specs := 0
+    for _, f := range sandbox.cmd.Files {
+        if f.Path == E2ESpec {
+            specs++
+            assert.Equal(t, plan.Spec, f.Content, "the plan's spec replaces an earlier one")
+        }
+    }
+    assert.Equal(t, 1, specs)
+
+    assert.False(t, report.Passed)
+    require.Len(t, report.Flows, 2)
+    assert.Equal(t, FlowResult{Name: "flow /orders", Passed: true}, report.Flows[0])
+    failed := report.Flows[1]
+    assert.Equal(t, "Error: GET / expected < 400, received 502", failed.Error)
+    assert.Equal(t, []string{"flows-page-chromium/test-failed-1.png", "flows-page-chromium/video.webm"}, failed.Attachments)
+    assert.Equal(t, []SandboxFile{
+        {Path: "flows-page-chromium/test-failed-1.png", Content: []byte("png")},
+        {Path: "flows-page-chromium/video.webm", Content: []byte("webm")},
+    }, report.Artifacts, "only files attached to failures are kept")
+
+    // Runs that produce no report keep the output as the error
+    sandbox.out = &SandboxOutput{ExitCode: 1, Stderr: "npx: command not found"}
+    report = RunE2E(context.Background(), sandbox, e2eProject, plan)
+    assert.False(t, report.Passed)
+    assert.Equal(t, "npx: command not found", report.Error)
+
+    sandbox.err = errors.New("sandbox unreachable")
+    report = RunE2E(context.Background(), sandbox, e2eProject, plan)
+    assert.True(t, strings.HasPrefix(report.Error, "sandbox unreachable"))
+}
@@ -0,0 +1,73 @@
+package quality
+
+import (
+    "fmt"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/sormind/OSA/miosa-backend/internal/redact"
+)
+
+// DefaultMaxEvidenceChars is the evidence, in runes, kept per finding when
+// a request does not set MaxEvidenceChars
+const DefaultMaxEvidenceChars = 200
+
+// trimEvidence bounds evidence to DefaultMaxEvidenceChars, for linters run
+// outside a code assurance request
+func trimEvidence(evidence string) string {
+    return limitEvidence(evidence, DefaultMaxEvidenceChars)
+}
+
+// limitEvidence redacts secrets in evidence, then bounds it to max runes.
+// Evidence spanning lines keeps the whole lines that fit and notes how many
+// were dropped; a single line too long to fit is cut in the middle, so both
+// its start and its end stay visible around a marker counting the runes
+// dropped. Markers come on top of max.
+func limitEvidence(evidence string, max int) string {
+    evidence = redact.Clean(strings.TrimSpace(evidence))
+    if max <= 0 {
+        max = DefaultMaxEvidenceChars
+    }
+    if utf8.RuneCountInString(evidence) <= max {
+        return evidence
+    }
+
+    lines := strings.Split(evidence, "\n")
+    if len(lines) == 1 {
+        return cutLine(evidence, max)
+    }
+    var kept []string
+    used := 0
+    for _, line := range lines {
+        n := utf8.RuneCountInString(line)
+        if len(kept) > 0 {
+            n++ // The newline joining it to the previous line
+        }
+        if used+n > max {
+            break
+        }
+        kept = append(kept, line)
+        used += n
+    }
+    if len(kept) == 0 {
+        kept = []string{cutLine(lines[0], max)}
+    }
+    return fmt.Sprintf("%s\n… [%d more lines]", strings.Join(kept, "\n"), len(lines)-len(kept))
+}
+
+// cutLine keeps the first two thirds and the last third of max runes of a
+// line, marking how many runes were dropped between them
+func cutLine(line string, max int) string {
+    runes := []rune(line)
+    head := max * 2 / 3
+    tail := max - head
+    return fmt.Sprintf("%s …[%d chars]… %s", string(runes[:head]), len(runes)-max, string(runes[len(runes)-tail:]))
+}
+
+// limitFindingEvidence applies limitEvidence to every finding's evidence
+func limitFindingEvidence(findings []Finding, max int) []Finding {
+    for i := range findings {
+        findings[i].Evidence = limitEvidence(findings[i].Evidence, max)
+    }
+    return findings
+}
@@ -0,0 +1,56 @@
+package quality
+
+import (
+    "context"
+    "strings"
+    "testing"
+    "unicode/utf8"
+
+    "github.com/sormind/OSA/miosa-backend/internal/redact"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestLimitEvidence(t *testing.T) {
+    assert.Equal(t, "short line", limitEvidence("  short line  ", 0))
+
+    // Long lines keep their start and end, cut at rune boundaries
+    line := strings.Repeat("日本", 150) + "END"
+    got := limitEvidence(line, 30)
+    assert.True(t, utf8.ValidString(got))
+    assert.True(t, strings.HasPrefix(got, strings.Repeat("日本", 10)+" …[273 chars]… "), got)
+    assert.True(t, strings.HasSuffix(got, "END"))
+
+    // Evidence spanning lines keeps whole lines
+    lines := "first line\nsecond line\nthird line"
+    assert.Equal(t, "first line\nsecond line\n… [1 more lines]", limitEvidence(lines, 25))
+    assert.True(t, strings.HasPrefix(limitEvidence(strings.Repeat("x", 50)+"\nnext", 20), strings.Repeat("x", 13)+" …[30 chars]…"))
+
+    // Secrets are redacted before cutting, so a cut cannot expose part of one
+    secret := `api_key = "` + strings.Repeat("s", 300) + `"`
+    got = limitEvidence(secret, 200)
+    assert.Equal(t, `api_key = "`+redact.Placeholder+`"`, got)
+}
+
+func TestRunCodeAssurance_EvidenceLimit(t *testing.T) {
+    long := "// TODO: " + strings.Repeat("é", 500)
+    req := CodeAssuranceRequest{Files: []CodeFile{
+        {Path: "main.go", Content: "package main\n\n" + long + "\nvar password = \"hunter22\"\n"},
+    }}
+
+    res, err := RunCodeAssurance(context.Background(), nil, req)
+    require.NoError(t, err)
+    byRule := findingsByRule(res.Findings)
+    require.NotEmpty(t, byRule["WIP.Marker"])
+    wip := byRule["WIP.Marker"][0].Evidence
+    assert.True(t, utf8.ValidString(wip))
+    assert.Less(t, utf8.RuneCountInString(wip), DefaultMaxEvidenceChars+30)
+    for _, f := range res.Findings {
+        assert.NotContains(t, f.Evidence, "hunter22", f.Rule)
+    }
+
+    req.MaxEvidenceChars = 1000
+    res, err = RunCodeAssurance(context.Background(), nil, req)
+    require.NoError(t, err)
+    assert.Equal(t, long, findingsByRule(res.Findings)["WIP.Marker"][0].Evidence)
+}
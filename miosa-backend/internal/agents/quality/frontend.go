@@ -0,0 +1,310 @@
+package quality
+
+import (
+    "bufio"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "path"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// DefaultFrontendTimeout bounds one app's install, build, type check and lint
+const DefaultFrontendTimeout = 10 * time.Minute
+
+// Frontend build steps, in the order they run
+const (
+    StepInstall   = "install"
+    StepBuild     = "build"
+    StepTypecheck = "typecheck"
+    StepLint      = "lint"
+)
+
+// Markers delimiting the steps in the build command's output
+const (
+    dirMarker    = "__MIOSA_DIR__"
+    stepMarker   = "__MIOSA_STEP__"
+    exitMarker   = "__MIOSA_EXIT__"
+    cachedMarker = "__MIOSA_CACHED__"
+)
+
+// maxBuildErrors bounds the errors fed back to development per app
+const maxBuildErrors = 50
+
+// FrontendApp is a generated React app: a package.json depending on react
+// with a build script
+type FrontendApp struct {
+    Dir        string `json:"dir"`        // Relative to the project; "." for the root
+    Lockfile   bool   `json:"lockfile"`   // package-lock.json is present, so npm ci installs
+    TypeScript bool   `json:"typescript"` // tsconfig.json is present
+    Lint       bool   `json:"lint"`       // package.json has a lint script
+    cacheKey   string
+}
+
+// DetectFrontendApps finds the React apps in files
+func DetectFrontendApps(files []CodeFile) []FrontendApp {
+    present := make(map[string]string, len(files))
+    for _, f := range files {
+        present[path.Clean(f.Path)] = f.Content
+    }
+
+    var apps []FrontendApp
+    for p, content := range present {
+        if path.Base(p) != "package.json" || strings.Contains(p, "node_modules/") {
+            continue
+        }
+        var manifest struct {
+            Dependencies    map[string]string `json:"dependencies"`
+            DevDependencies map[string]string `json:"devDependencies"`
+            Scripts         map[string]string `json:"scripts"`
+        }
+        if json.Unmarshal([]byte(content), &manifest) != nil || manifest.Scripts["build"] == "" {
+            continue
+        }
+        if _, ok := manifest.Dependencies["react"]; !ok {
+            if _, ok := manifest.DevDependencies["react"]; !ok {
+                continue
+            }
+        }
+
+        dir := path.Dir(p)
+        lock, locked := present[path.Join(dir, "package-lock.json")]
+        _, ts := present[path.Join(dir, "tsconfig.json")]
+        // node_modules is rebuilt whenever the dependencies can change
+        sum := sha256.Sum256([]byte(dir + "\x00" + content + "\x00" + lock))
+        apps = append(apps, FrontendApp{
+            Dir:        dir,
+            Lockfile:   locked,
+            TypeScript: ts,
+            Lint:       manifest.Scripts["lint"] != "",
+            cacheKey:   "npm-" + hex.EncodeToString(sum[:16]),
+        })
+    }
+    sort.Slice(apps, func(i, j int) bool { return apps[i].Dir < apps[j].Dir })
+    return apps
+}
+
+// Cache is the app's node_modules, keyed by its manifest and lockfile
+func (a FrontendApp) Cache() *SandboxCache {
+    return &SandboxCache{Key: a.cacheKey, Dir: path.Join(a.Dir, "node_modules")}
+}
+
+// Command installs the app's dependencies, unless the cache restored
+// them, then builds, type checks and lints it. Each step's output is
+// delimited by markers carrying its exit status. A failed install leaves
+// no node_modules to cache, and nothing runs after it.
+func (a FrontendApp) Command() string {
+    install := "npm install --no-audit --no-fund"
+    if a.Lockfile {
+        install = "npm ci --no-audit --no-fund"
+    }
+    steps := [][2]string{{StepBuild, "npm run build"}}
+    if a.TypeScript {
+        steps = append(steps, [2]string{StepTypecheck, "npx --no-install tsc --noEmit --pretty false"})
+    }
+    if a.Lint {
+        steps = append(steps, [2]string{StepLint, "npm run lint --silent -- --format unix"})
+    }
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "cd %s || exit 1; echo %s \"$PWD\"; ", shellQuote(a.Dir), dirMarker)
+    fmt.Fprintf(&sb, "echo %s %s; if [ -d node_modules ]; then echo %s; else %s 2>&1; fi; s=$?; echo %s $s; [ $s -eq 0 ] || { rm -rf node_modules; exit 0; }; ",
+        stepMarker, StepInstall, cachedMarker, install, exitMarker)
+    for _, step := range steps {
+        fmt.Fprintf(&sb, "echo %s %s; %s 2>&1; echo %s $?; ", stepMarker, step[0], step[1], exitMarker)
+    }
+    return strings.TrimSpace(sb.String())
+}
+
+// BuildError is one error reported while building an app
+type BuildError struct {
+    Step    string `json:"step"`
+    File    string `json:"file,omitempty"` // Relative to the project
+    Line    int    `json:"line,omitempty"`
+    Column  int    `json:"column,omitempty"`
+    Code    string `json:"code,omitempty"` // TypeScript error code or ESLint rule
+    Message string `json:"message"`
+}
+
+// Location is the error's file:line:column, or the step without a file
+func (e BuildError) Location() string {
+    if e.File == "" {
+        return e.Step
+    }
+    return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+}
+
+// FrontendResult is the outcome of building one app
+type FrontendResult struct {
+    Dir         string       `json:"dir"`
+    Cached      bool         `json:"cached"` // node_modules was restored from the cache
+    Steps       []string     `json:"steps"`  // Steps that ran
+    FailedSteps []string     `json:"failed_steps,omitempty"`
+    Errors      []BuildError `json:"errors,omitempty"`
+    Error       string       `json:"error,omitempty"` // Why the app could not be built at all
+}
+
+// FrontendReport is the outcome of the frontend build stage
+type FrontendReport struct {
+    Apps      []FrontendResult `json:"apps"`
+    Errors    int              `json:"errors"`
+    Success   bool             `json:"success"`
+    FixRounds int              `json:"fix_rounds,omitempty"` // Development revisions made to fix errors
+}
+
+// Feedback renders the errors as instructions for the development agent,
+// or "" when every app built cleanly
+func (r *FrontendReport) Feedback() string {
+    if r.Success {
+        return ""
+    }
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "The generated frontend does not build cleanly (%d errors). Fix the code so npm run build, the TypeScript check and the linter pass.\n", r.Errors)
+    for _, app := range r.Apps {
+        if app.Error != "" {
+            fmt.Fprintf(&sb, "\n### %s\n%s\n", path.Join(app.Dir, "package.json"), app.Error)
+        }
+        for _, e := range app.Errors {
+            msg := strings.TrimSpace(e.Message)
+            if len(msg) > maxFeedbackMessage {
+                msg = msg[:maxFeedbackMessage] + "..."
+            }
+            if e.Code != "" {
+                msg = e.Code + ": " + msg
+            }
+            fmt.Fprintf(&sb, "\n### %s (%s)\n%s\n", e.Location(), e.Step, msg)
+        }
+    }
+    return sb.String()
+}
+
+// RunFrontendBuild builds every React app in files in its own sandbox,
+// with node_modules cached across runs by the app's manifest and lockfile.
+// It returns nil when files contain no app.
+func RunFrontendBuild(ctx context.Context, sandbox Sandbox, files []CodeFile) *FrontendReport {
+    apps := DetectFrontendApps(files)
+    if len(apps) == 0 {
+        return nil
+    }
+
+    report := &FrontendReport{Success: true}
+    for _, app := range apps {
+        result := FrontendResult{Dir: app.Dir}
+        out, err := sandbox.Exec(ctx, SandboxCommand{Files: files, Command: app.Command(), Timeout: DefaultFrontendTimeout, Cache: app.Cache()})
+        if err != nil {
+            result.Error = err.Error()
+        } else {
+            parseFrontendBuild(&result, out)
+        }
+
+        report.Errors += len(result.Errors)
+        if result.Error != "" || len(result.FailedSteps) > 0 {
+            report.Success = false
+        }
+        report.Apps = append(report.Apps, result)
+    }
+    return report
+}
+
+var (
+    // tsc without --pretty: src/App.tsx(3,7): error TS2322: message
+    tscError = regexp.MustCompile(`^(.+?)\((\d+),(\d+)\): error (TS\d+): (.+)$`)
+    // ESLint's unix formatter: src/App.tsx:3:7: message [Error/rule]
+    eslintError = regexp.MustCompile(`^(.+?):(\d+):(\d+): (.+) \[Error/(.+)\]$`)
+)
+
+// parseFrontendBuild splits the output into steps and reads the TypeScript
+// and ESLint errors in them. A failed step with no recognizable errors is
+// reported with the end of its output.
+func parseFrontendBuild(result *FrontendResult, out *SandboxOutput) {
+    type step struct {
+        name   string
+        output strings.Builder
+        exit   int
+        done   bool
+    }
+    var steps []*step
+    var root string // The app's directory in the sandbox, prefixing absolute paths
+    scanner := bufio.NewScanner(strings.NewReader(out.Stdout))
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        line := scanner.Text()
+        switch {
+        case strings.HasPrefix(line, dirMarker+" "):
+            root = strings.TrimPrefix(line, dirMarker+" ")
+        case strings.HasPrefix(line, stepMarker+" "):
+            steps = append(steps, &step{name: strings.TrimPrefix(line, stepMarker+" ")})
+        case len(steps) == 0:
+        case strings.HasPrefix(line, exitMarker+" "):
+            cur := steps[len(steps)-1]
+            cur.exit, _ = strconv.Atoi(strings.TrimPrefix(line, exitMarker+" "))
+            cur.done = true
+        case line == cachedMarker:
+            result.Cached = true
+        default:
+            steps[len(steps)-1].output.WriteString(line + "\n")
+        }
+    }
+    if len(steps) == 0 {
+        result.Error = outputTail(out)
+        return
+    }
+
+    seen := make(map[string]bool)
+    for _, s := range steps {
+        result.Steps = append(result.Steps, s.name)
+        failed := !s.done || s.exit != 0
+        if failed {
+            result.FailedSteps = append(result.FailedSteps, s.name)
+        }
+        if s.name == StepInstall {
+            if failed {
+                result.Error = "dependencies failed to install:\n" + outputTail(&SandboxOutput{Stdout: s.output.String(), ExitCode: s.exit})
+            }
+            continue
+        }
+
+        found := 0
+        for _, line := range strings.Split(s.output.String(), "\n") {
+            e, ok := parseBuildError(s.name, strings.TrimSpace(line))
+            if !ok {
+                continue
+            }
+            found++
+            if root != "" && strings.HasPrefix(e.File, root+"/") {
+                e.File = strings.TrimPrefix(e.File, root+"/")
+            }
+            e.File = path.Join(result.Dir, e.File)
+            key := fmt.Sprintf("%s\x00%s\x00%s", e.Location(), e.Code, e.Message)
+            if seen[key] || len(result.Errors) >= maxBuildErrors {
+                continue
+            }
+            seen[key] = true
+            result.Errors = append(result.Errors, e)
+        }
+        if failed && found == 0 && len(result.Errors) < maxBuildErrors {
+            result.Errors = append(result.Errors, BuildError{Step: s.name, Message: outputTail(&SandboxOutput{Stdout: s.output.String(), ExitCode: s.exit})})
+        }
+    }
+}
+
+// parseBuildError reads a tsc or ESLint error line
+func parseBuildError(step, line string) (BuildError, bool) {
+    if m := tscError.FindStringSubmatch(line); m != nil {
+        l, _ := strconv.Atoi(m[2])
+        c, _ := strconv.Atoi(m[3])
+        return BuildError{Step: step, File: m[1], Line: l, Column: c, Code: m[4], Message: m[5]}, true
+    }
+    if m := eslintError.FindStringSubmatch(line); m != nil {
+        l, _ := strconv.Atoi(m[2])
+        c, _ := strconv.Atoi(m[3])
+        return BuildError{Step: step, File: m[1], Line: l, Column: c, Code: m[5], Message: m[4]}, true
+    }
+    return BuildError{}, false
+}
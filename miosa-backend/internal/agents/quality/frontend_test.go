@@ -0,0 +1,99 @@
+package quality
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+var reactApp = []CodeFile{
+    {Path: "web/package.json", Content: `{"dependencies": {"react": "^18.2.0"}, "scripts": {"build": "vite build", "lint": "eslint src"}}`},
+    {Path: "web/package-lock.json", Content: `{"lockfileVersion": 3}`},
+    {Path: "web/tsconfig.json", Content: `{}`},
+    {Path: "web/src/App.tsx", Content: "export default function App() { return <h1>hi</h1> }\n"},
+    {Path: "api/package.json", Content: `{"dependencies": {"express": "^4"}, "scripts": {"build": "tsc"}}`},
+}
+
+func TestDetectFrontendApps(t *testing.T) {
+    apps := DetectFrontendApps(reactApp)
+    require.Len(t, apps, 1, "only packages depending on react are frontend apps")
+    app := apps[0]
+    assert.Equal(t, "web", app.Dir)
+    assert.True(t, app.Lockfile && app.TypeScript && app.Lint)
+    assert.Equal(t, "web/node_modules", app.Cache().Dir)
+
+    cmd := app.Command()
+    assert.Contains(t, cmd, "cd 'web'")
+    assert.Contains(t, cmd, "npm ci --no-audit --no-fund")
+    assert.Contains(t, cmd, "npx --no-install tsc --noEmit --pretty false")
+    assert.Contains(t, cmd, "npm run lint --silent -- --format unix")
+
+    // The cache follows the dependencies, not the source
+    changed := append([]CodeFile(nil), reactApp...)
+    changed[3].Content = "export default function App() { return null }\n"
+    assert.Equal(t, app.Cache().Key, DetectFrontendApps(changed)[0].Cache().Key)
+    changed[1].Content = `{"lockfileVersion": 3, "packages": {}}`
+    assert.NotEqual(t, app.Cache().Key, DetectFrontendApps(changed)[0].Cache().Key)
+}
+
+func TestRunFrontendBuild(t *testing.T) {
+    sandbox := &e2eSandbox{out: &SandboxOutput{Stdout: strings.Join([]string{
+        "__MIOSA_DIR__ /tmp/app/web",
+        "__MIOSA_STEP__ install",
+        "__MIOSA_CACHED__",
+        "__MIOSA_EXIT__ 0",
+        "__MIOSA_STEP__ build",
+        "> vite build",
+        "src/App.tsx(3,7): error TS2322: Type 'number' is not assignable to type 'string'.",
+        "__MIOSA_EXIT__ 2",
+        "__MIOSA_STEP__ typecheck",
+        "src/App.tsx(3,7): error TS2322: Type 'number' is not assignable to type 'string'.",
+        "__MIOSA_EXIT__ 2",
+        "__MIOSA_STEP__ lint",
+        "/tmp/app/web/src/App.tsx:5:3: 'unused' is assigned a value but never used. [Error/no-unused-vars]",
+        "/tmp/app/web/src/App.tsx:6:1: Unexpected console statement. [Warning/no-console]",
+        "__MIOSA_EXIT__ 1",
+    }, "\n")}}
+
+    report := RunFrontendBuild(context.Background(), sandbox, reactApp)
+    require.NotNil(t, report)
+    assert.Equal(t, "web/node_modules", sandbox.cmd.Cache.Dir)
+    assert.False(t, report.Success)
+    assert.Equal(t, 2, report.Errors, "errors reported by both the build and the type check count once")
+
+    app := report.Apps[0]
+    assert.True(t, app.Cached)
+    assert.Equal(t, []string{"install", "build", "typecheck", "lint"}, app.Steps)
+    assert.Equal(t, []string{"build", "typecheck", "lint"}, app.FailedSteps)
+    assert.Equal(t, []BuildError{
+        {Step: "build", File: "web/src/App.tsx", Line: 3, Column: 7, Code: "TS2322", Message: "Type 'number' is not assignable to type 'string'."},
+        {Step: "lint", File: "web/src/App.tsx", Line: 5, Column: 3, Code: "no-unused-vars", Message: "'unused' is assigned a value but never used."},
+    }, app.Errors)
+
+    feedback := report.Feedback()
+    assert.Contains(t, feedback, "### web/src/App.tsx:3:7 (build)\nTS2322: Type 'number'")
+    assert.Contains(t, feedback, "### web/src/App.tsx:5:3 (lint)")
+
+    // A failed install stops the app and is reported with its output
+    sandbox.out = &SandboxOutput{Stdout: "__MIOSA_STEP__ install\nnpm ERR! code ERESOLVE\n__MIOSA_EXIT__ 1\n"}
+    report = RunFrontendBuild(context.Background(), sandbox, reactApp)
+    assert.False(t, report.Success)
+    assert.Contains(t, report.Apps[0].Error, "npm ERR! code ERESOLVE")
+    assert.Contains(t, report.Feedback(), "### web/package.json\ndependencies failed to install")
+
+    // A failing build without recognizable errors keeps its output
+    sandbox.out = &SandboxOutput{Stdout: "__MIOSA_STEP__ install\n__MIOSA_EXIT__ 0\n__MIOSA_STEP__ build\nCould not resolve \"./missing\"\n__MIOSA_EXIT__ 1\n"}
+    report = RunFrontendBuild(context.Background(), sandbox, reactApp)
+    require.Len(t, report.Apps[0].Errors, 1)
+    assert.Contains(t, report.Apps[0].Errors[0].Message, `Could not resolve "./missing"`)
+
+    sandbox.out = &SandboxOutput{Stdout: "__MIOSA_STEP__ install\n__MIOSA_EXIT__ 0\n__MIOSA_STEP__ build\n__MIOSA_EXIT__ 0\n"}
+    report = RunFrontendBuild(context.Background(), sandbox, reactApp)
+    assert.True(t, report.Success)
+    assert.Empty(t, report.Feedback())
+
+    assert.Nil(t, RunFrontendBuild(context.Background(), sandbox, reactApp[4:]))
+}
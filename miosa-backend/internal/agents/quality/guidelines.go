@@ -0,0 +1,272 @@
+package quality
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Guideline pack kinds: which assurance request a pack can be attached to
+const (
+    PackKindCode   = "code"
+    PackKindVisual = "visual"
+)
+
+// ErrUnknownPack is returned when a request names a pack that is not curated
+var ErrUnknownPack = errors.New("unknown guideline pack")
+
+// PackRule configures one built-in rule under a pack. Findings of the rule
+// are raised to at least Severity and tagged with Reference.
+type PackRule struct {
+    Rule      string `json:"rule"`               // Rule ID, or a family prefix ending in "." (e.g. "Secrets.")
+    Severity  string `json:"severity,omitempty"` // Minimum severity (empty = keep the rule's own)
+    Reference string `json:"reference"`          // Control the rule evidences, e.g. "OWASP A03:2021 Injection"
+}
+
+// GuidelinePack is a curated, versioned set of prompt guidance and rule
+// configuration for one standard. Requests attach packs by name, optionally
+// pinned with "name@version".
+type GuidelinePack struct {
+    Name        string     `json:"name"`
+    Version     string     `json:"version"`
+    Kind        string     `json:"kind"` // code | visual
+    Title       string     `json:"title"`
+    Description string     `json:"description"`
+    Source      string     `json:"source"`     // The standard the pack follows
+    Guidelines  []string   `json:"guidelines"` // Appended to the LLM prompt
+    Rules       []PackRule `json:"rules"`
+}
+
+// Ref returns the pinned name of the pack
+func (p GuidelinePack) Ref() string {
+    return p.Name + "@" + p.Version
+}
+
+// guidelinePacks are the curated packs. Publish changes as a new version
+// rather than editing one in place, so pinned requests stay reproducible.
+var guidelinePacks = []GuidelinePack{
+    {
+        Name:        "owasp-top10-2021",
+        Version:     "1.0.0",
+        Kind:        PackKindCode,
+        Title:       "OWASP Top 10 (2021)",
+        Description: "The ten most critical web application security risks.",
+        Source:      "https://owasp.org/Top10/",
+        Guidelines: []string{
+            "OWASP A01:2021 Broken Access Control: every handler enforces authorization server-side; deny by default; no IDOR via user-supplied IDs.",
+            "OWASP A02:2021 Cryptographic Failures: no weak algorithms (MD5, SHA-1, DES, ECB); TLS for data in transit; passwords hashed with bcrypt, scrypt or argon2.",
+            "OWASP A03:2021 Injection: SQL, shell, LDAP and template input is parameterized or escaped; never concatenated from untrusted data.",
+            "OWASP A04:2021 Insecure Design: rate limits, input size limits and business-rule checks on sensitive flows.",
+            "OWASP A05:2021 Security Misconfiguration: no debug modes, default credentials, permissive CORS or verbose errors in production.",
+            "OWASP A06:2021 Vulnerable and Outdated Components: dependencies are pinned and free of known advisories.",
+            "OWASP A07:2021 Identification and Authentication Failures: no hard-coded credentials; sessions and tokens expire and are invalidated on logout.",
+            "OWASP A08:2021 Software and Data Integrity Failures: no deserialization of untrusted data; updates and artifacts are verified.",
+            "OWASP A09:2021 Security Logging and Monitoring Failures: authentication and authorization failures are logged without secrets or personal data.",
+            "OWASP A10:2021 Server-Side Request Forgery: outbound requests to user-supplied URLs are validated against an allow list.",
+        },
+        Rules: []PackRule{
+            {Rule: "SQL.Concat", Severity: "high", Reference: "OWASP A03:2021 Injection"},
+            {Rule: "Exec.", Severity: "high", Reference: "OWASP A03:2021 Injection"},
+            {Rule: "Go.ExecUsage", Severity: "high", Reference: "OWASP A03:2021 Injection"},
+            {Rule: "Dependency.Vulnerable", Reference: "OWASP A06:2021 Vulnerable and Outdated Components"},
+            {Rule: "Secrets.", Severity: "high", Reference: "OWASP A07:2021 Identification and Authentication Failures"},
+        },
+    },
+    {
+        Name:        "cis-controls-v8",
+        Version:     "1.0.0",
+        Kind:        PackKindCode,
+        Title:       "CIS Critical Security Controls v8",
+        Description: "The CIS Controls that apply to application source, dependencies and deployment files.",
+        Source:      "https://www.cisecurity.org/controls/v8",
+        Guidelines: []string{
+            "CIS Control 2: every third-party component is inventoried in a manifest, pinned and under an authorized license.",
+            "CIS Control 3: sensitive data is never hard-coded or logged; secrets come from the environment or a secret manager.",
+            "CIS Control 4: configuration is secure by default; containers run as a non-root user with only required ports exposed.",
+            "CIS Control 7: dependencies with known vulnerabilities are upgraded.",
+            "CIS Control 8: security-relevant events are logged in a structured form, without debug noise.",
+            "CIS Control 16: input is validated, errors are handled explicitly and vetted libraries are used for security functions.",
+        },
+        Rules: []PackRule{
+            {Rule: "License.", Reference: "CIS Control 2 Inventory and Control of Software Assets"},
+            {Rule: "Secrets.", Severity: "critical", Reference: "CIS Control 3 Data Protection"},
+            {Rule: "Dependency.Vulnerable", Severity: "high", Reference: "CIS Control 7 Continuous Vulnerability Management"},
+            {Rule: "Logging.DebugNoise", Reference: "CIS Control 8 Audit Log Management"},
+            {Rule: "SQL.Concat", Severity: "high", Reference: "CIS Control 16 Application Software Security"},
+            {Rule: "Exec.", Severity: "high", Reference: "CIS Control 16 Application Software Security"},
+        },
+    },
+    {
+        Name:        "wcag22-aa",
+        Version:     "1.0.0",
+        Kind:        PackKindVisual,
+        Title:       "WCAG 2.2 Level AA",
+        Description: "Web Content Accessibility Guidelines 2.2 success criteria up to level AA.",
+        Source:      "https://www.w3.org/TR/WCAG22/",
+        Guidelines: []string{
+            "WCAG 2.2 SC 1.3.1 Info and Relationships: headings, lists and form labels are conveyed in markup, not only visually.",
+            "WCAG 2.2 SC 1.4.3 Contrast (Minimum): text has a contrast ratio of at least 4.5:1, or 3:1 for large text.",
+            "WCAG 2.2 SC 1.4.11 Non-text Contrast: controls and focus indicators have a contrast ratio of at least 3:1.",
+            "WCAG 2.2 SC 2.4.7 Focus Visible: every keyboard-focusable control shows a visible focus indicator.",
+            "WCAG 2.2 SC 2.4.11 Focus Not Obscured (Minimum): a focused control is not entirely hidden by sticky headers or overlays.",
+            "WCAG 2.2 SC 2.5.8 Target Size (Minimum): pointer targets are at least 24 by 24 CSS pixels or adequately spaced.",
+            "WCAG 2.2 SC 3.3.2 Labels or Instructions: every input has a visible label.",
+            "WCAG 2.2 SC 3.3.8 Accessible Authentication (Minimum): login does not require a cognitive function test such as transcribing a code.",
+        },
+        Rules: []PackRule{
+            {Rule: "Contrast.Text", Severity: "medium", Reference: "WCAG 2.2 SC 1.4.3 Contrast (Minimum)"},
+            {Rule: "Focus.Indicator", Severity: "high", Reference: "WCAG 2.2 SC 2.4.7 Focus Visible"},
+            {Rule: "Heading.", Reference: "WCAG 2.2 SC 1.3.1 Info and Relationships"},
+            {Rule: "Form.", Reference: "WCAG 2.2 SC 1.3.1 Info and Relationships"},
+            {Rule: "Form.Label", Severity: "high", Reference: "WCAG 2.2 SC 3.3.2 Labels or Instructions"},
+        },
+    },
+}
+
+// GuidelinePacks lists the curated packs, newest version first within a name
+func GuidelinePacks() []GuidelinePack {
+    packs := append([]GuidelinePack(nil), guidelinePacks...)
+    sort.SliceStable(packs, func(i, j int) bool {
+        if packs[i].Name != packs[j].Name {
+            return packs[i].Name < packs[j].Name
+        }
+        return compareVersions(packs[i].Version, packs[j].Version) > 0
+    })
+    return packs
+}
+
+// LookupPack resolves "name" to the newest version of a pack, or
+// "name@version" to that exact version
+func LookupPack(ref string) (GuidelinePack, error) {
+    name, version, pinned := strings.Cut(strings.TrimSpace(ref), "@")
+    for _, p := range GuidelinePacks() {
+        if p.Name == name && (!pinned || p.Version == version) {
+            return p, nil
+        }
+    }
+    return GuidelinePack{}, fmt.Errorf("%w: %s", ErrUnknownPack, ref)
+}
+
+// resolvePacks looks up every ref, rejecting packs of another kind
+func resolvePacks(refs []string, kind string) ([]GuidelinePack, error) {
+    packs := make([]GuidelinePack, 0, len(refs))
+    for _, ref := range refs {
+        p, err := LookupPack(ref)
+        if err != nil {
+            return nil, err
+        }
+        if p.Kind != kind {
+            return nil, fmt.Errorf("guideline pack %s applies to %s assurance, not %s", p.Name, p.Kind, kind)
+        }
+        packs = append(packs, p)
+    }
+    return packs, nil
+}
+
+// packRefs returns the pinned names of packs
+func packRefs(packs []GuidelinePack) []string {
+    var refs []string
+    for _, p := range packs {
+        refs = append(refs, p.Ref())
+    }
+    return refs
+}
+
+// packGuidelines appends the packs' prompt guidance to guidelines
+func packGuidelines(guidelines []string, packs []GuidelinePack) []string {
+    out := append([]string(nil), guidelines...)
+    for _, p := range packs {
+        out = append(out, p.Guidelines...)
+    }
+    return out
+}
+
+// packRuleConfig returns the strictest severity the packs set for rule and
+// the references they tag it with
+func packRuleConfig(packs []GuidelinePack, rule string) (string, []string) {
+    var severity string
+    var refs []string
+    for _, p := range packs {
+        for _, r := range p.Rules {
+            if rule == "" || !(r.Rule == rule || strings.HasSuffix(r.Rule, ".") && strings.HasPrefix(rule, r.Rule)) {
+                continue
+            }
+            if r.Severity != "" && (severity == "" || severityRank(r.Severity) > severityRank(severity)) {
+                severity = r.Severity
+            }
+            refs = appendUnique(refs, r.Reference)
+        }
+    }
+    return severity, refs
+}
+
+// applyPackRules raises and tags findings according to the packs
+func applyPackRules(findings []Finding, packs []GuidelinePack) []Finding {
+    if len(packs) == 0 {
+        return findings
+    }
+    for i := range findings {
+        severity, refs := packRuleConfig(packs, findings[i].Rule)
+        if severity != "" && severityRank(severity) > severityRank(findings[i].Severity) {
+            findings[i].Severity = normalizeSeverity(severity)
+        }
+        for _, ref := range refs {
+            findings[i].References = appendUnique(findings[i].References, ref)
+        }
+    }
+    return findings
+}
+
+func appendUnique(list []string, s string) []string {
+    for _, existing := range list {
+        if existing == s {
+            return list
+        }
+    }
+    return append(list, s)
+}
+
+// compareVersions orders dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+    as, bs := strings.Split(a, "."), strings.Split(b, ".")
+    for i := 0; i < len(as) || i < len(bs); i++ {
+        var x, y int
+        if i < len(as) {
+            x, _ = strconv.Atoi(as[i])
+        }
+        if i < len(bs) {
+            y, _ = strconv.Atoi(bs[i])
+        }
+        if x != y {
+            if x < y {
+                return -1
+            }
+            return 1
+        }
+    }
+    return 0
+}
+
+// PacksHandler serves GET /api/quality/packs, optionally filtered by
+// ?kind=code|visual
+func PacksHandler() http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        kind := r.URL.Query().Get("kind")
+        packs := []GuidelinePack{}
+        for _, p := range GuidelinePacks() {
+            if kind == "" || p.Kind == kind {
+                packs = append(packs, p)
+            }
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "packs": packs,
+            "count": len(packs),
+        })
+    }
+}
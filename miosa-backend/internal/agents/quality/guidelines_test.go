@@ -0,0 +1,135 @@
+package quality
+
+import (
+    "context"
+    "encoding/json"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "github.com/sormind/OSA/miosa-backend/internal/visual"
+)
+
+func TestLookupPack(t *testing.T) {
+    p, err := LookupPack("owasp-top10-2021")
+    require.NoError(t, err)
+    assert.Equal(t, PackKindCode, p.Kind)
+    assert.Equal(t, "owasp-top10-2021@1.0.0", p.Ref())
+
+    p, err = LookupPack("wcag22-aa@1.0.0")
+    require.NoError(t, err)
+    assert.Equal(t, PackKindVisual, p.Kind)
+
+    _, err = LookupPack("wcag22-aa@0.9.0")
+    assert.ErrorIs(t, err, ErrUnknownPack)
+    _, err = LookupPack("pci-dss")
+    assert.ErrorIs(t, err, ErrUnknownPack)
+
+    _, err = resolvePacks([]string{"wcag22-aa"}, PackKindCode)
+    assert.EqualError(t, err, "guideline pack wcag22-aa applies to visual assurance, not code")
+
+    for _, p := range GuidelinePacks() {
+        assert.NotEmpty(t, p.Guidelines, p.Name)
+        for _, r := range p.Rules {
+            assert.NotEmpty(t, r.Reference, "%s %s", p.Name, r.Rule)
+        }
+    }
+
+    assert.Equal(t, 1, compareVersions("1.10.0", "1.9"))
+    assert.Equal(t, 0, compareVersions("2.0", "2.0.0"))
+}
+
+func TestRunCodeAssurance_Packs(t *testing.T) {
+    req := CodeAssuranceRequest{
+        Files: []CodeFile{{Path: "run.js", Content: "const { exec } = require('child_process');\nexec(cmd);\n// TODO tidy\n"}},
+        Packs: []string{"owasp-top10-2021", "cis-controls-v8@1.0.0"},
+    }
+    res, err := RunCodeAssurance(context.Background(), nil, req)
+    require.NoError(t, err)
+    assert.Equal(t, []string{"owasp-top10-2021@1.0.0", "cis-controls-v8@1.0.0"}, res.Packs)
+
+    byRule := map[string]Finding{}
+    for _, f := range res.Findings {
+        byRule[f.Rule] = f
+    }
+    require.Contains(t, byRule, "Exec.Process")
+    assert.Equal(t, "high", byRule["Exec.Process"].Severity, "raised from medium by the packs")
+    assert.Equal(t, []string{"OWASP A03:2021 Injection", "CIS Control 16 Application Software Security"}, byRule["Exec.Process"].References)
+    assert.Empty(t, byRule["WIP.Marker"].References)
+
+    req.Packs = []string{"owasp-top10-2017"}
+    _, err = RunCodeAssurance(context.Background(), nil, req)
+    assert.ErrorIs(t, err, ErrUnknownPack)
+}
+
+// systemPromptModel records the system prompt of each call
+type systemPromptModel struct {
+    system []string
+}
+
+func (m *systemPromptModel) Generate(ctx context.Context, messages []ChatMessage) (string, error) {
+    m.system = append(m.system, messages[0].Content)
+    return `[]`, nil
+}
+
+func TestRunCodeAssurance_PackGuidelinesInPrompt(t *testing.T) {
+    model := &systemPromptModel{}
+    _, err := RunCodeAssurance(context.Background(), model, CodeAssuranceRequest{
+        Files:      []CodeFile{{Path: "main.go", Content: "package main\n"}},
+        Guidelines: []string{"Prefer table-driven tests"},
+        Packs:      []string{"owasp-top10-2021"},
+    })
+    require.NoError(t, err)
+    require.Len(t, model.system, 1)
+    assert.Contains(t, model.system[0], "- Prefer table-driven tests\n- OWASP A01:2021")
+    assert.Contains(t, model.system[0], "- OWASP A10:2021 Server-Side Request Forgery")
+}
+
+func TestRunVisualAssurance(t *testing.T) {
+    req := VisualAssuranceRequest{
+        Artifacts: []visual.Artifact{{ID: "login", Components: []visual.Component{
+            {ID: "hint", Role: "text", Text: "Forgot password?", Foreground: "#888", FontSize: 14},
+            {ID: "email", Role: "input"},
+        }}},
+        Packs: []string{"wcag22-aa"},
+    }
+    res, err := RunVisualAssurance(req)
+    require.NoError(t, err)
+    assert.Equal(t, []string{"wcag22-aa@1.0.0"}, res.Packs)
+    assert.NotEmpty(t, res.Guidelines)
+
+    refs := map[string][]string{}
+    for _, f := range res.Findings {
+        refs[f.Rule] = f.References
+    }
+    assert.Equal(t, []string{"WCAG 2.2 SC 1.4.3 Contrast (Minimum)"}, refs["Contrast.Text"])
+    assert.Equal(t, []string{"WCAG 2.2 SC 1.3.1 Info and Relationships", "WCAG 2.2 SC 3.3.2 Labels or Instructions"}, refs["Form.Label"])
+
+    req.SeverityThreshold = "high"
+    res, err = RunVisualAssurance(req)
+    require.NoError(t, err)
+    for _, f := range res.Findings {
+        assert.Equal(t, "high", f.Severity)
+    }
+
+    _, err = RunVisualAssurance(VisualAssuranceRequest{Artifacts: req.Artifacts, Packs: []string{"owasp-top10-2021"}})
+    assert.Error(t, err)
+    _, err = RunVisualAssurance(VisualAssuranceRequest{})
+    assert.Error(t, err)
+}
+
+func TestPacksHandler(t *testing.T) {
+    rec := httptest.NewRecorder()
+    PacksHandler()(rec, httptest.NewRequest("GET", "/api/quality/packs?kind=visual", nil))
+
+    var body struct {
+        Packs []GuidelinePack `json:"packs"`
+        Count int             `json:"count"`
+    }
+    require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+    require.Equal(t, 1, body.Count)
+    assert.Equal(t, "wcag22-aa", body.Packs[0].Name)
+    assert.NotEmpty(t, body.Packs[0].Rules)
+}
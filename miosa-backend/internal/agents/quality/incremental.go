@@ -0,0 +1,169 @@
+package quality
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/google/uuid"
+)
+
+// Manifest maps file paths to the SHA-256 of their content
+type Manifest map[string]string
+
+// HashFiles returns the manifest of files
+func HashFiles(files []CodeFile) Manifest {
+    m := make(Manifest, len(files))
+    for _, f := range files {
+        sum := sha256.Sum256([]byte(f.Content))
+        m[f.Path] = hex.EncodeToString(sum[:])
+    }
+    return m
+}
+
+// Baseline is a prior code assurance run of the same project. Given one,
+// RunCodeAssurance analyzes only what changed since and carries the other
+// findings over.
+type Baseline struct {
+    Manifest Manifest  `json:"manifest"` // Files the prior findings were computed from
+    Findings []Finding `json:"findings"`
+}
+
+// Incremental reports how a baseline narrowed a code assurance run
+type Incremental struct {
+    Changed  []string `json:"changed"`  // Files added or modified since the baseline
+    Impacted []string `json:"impacted"` // Unchanged files analyzed as neighbors of changed or removed ones
+    Removed  []string `json:"removed"`  // Baseline files no longer present
+    Reused   int      `json:"reused"`   // Prior findings carried over
+}
+
+// narrowToChanges returns the files to analyze against a baseline: those
+// changed since and their neighbors, which a change can affect. Prior
+// findings on every other file still present are returned for merging;
+// findings that name no file are kept too, as deduplication prefers fresh
+// ones. Rules spanning files, such as clone detection and route coverage,
+// see only the analyzed files.
+func narrowToChanges(files []CodeFile, manifest Manifest, base Baseline) ([]CodeFile, []Finding, *Incremental) {
+    inc := &Incremental{Changed: []string{}, Impacted: []string{}, Removed: []string{}}
+    touched := make([]string, 0)
+    for _, f := range files {
+        if base.Manifest[f.Path] != manifest[f.Path] {
+            inc.Changed = append(inc.Changed, f.Path)
+            touched = append(touched, f.Path)
+        }
+    }
+    for p := range base.Manifest {
+        if _, ok := manifest[p]; !ok {
+            inc.Removed = append(inc.Removed, p)
+            touched = append(touched, p)
+        }
+    }
+    sort.Strings(inc.Removed)
+
+    analyze := make(map[string]bool, len(touched))
+    for _, p := range inc.Changed {
+        analyze[p] = true
+    }
+    var scoped []CodeFile
+    for _, f := range files {
+        if analyze[f.Path] {
+            scoped = append(scoped, f)
+            continue
+        }
+        if impactedBy(f, touched) {
+            analyze[f.Path] = true
+            inc.Impacted = append(inc.Impacted, f.Path)
+            scoped = append(scoped, f)
+        }
+    }
+
+    var prior []Finding
+    for _, f := range base.Findings {
+        if f.File != "" {
+            if _, present := manifest[f.File]; !present || analyze[f.File] {
+                continue
+            }
+        }
+        prior = append(prior, f)
+    }
+    inc.Reused = len(prior)
+    return scoped, prior, inc
+}
+
+// impactedBy reports whether a change to any of paths can affect file: it
+// shares their directory and language, as Go packages and Python modules
+// do, or it imports one of them by name, or by package for Go
+func impactedBy(file CodeFile, paths []string) bool {
+    lang := guessLanguageFromPath(file.Path)
+    for _, p := range paths {
+        dir := path.Dir(p)
+        if lang != "plain" && dir == path.Dir(file.Path) && guessLanguageFromPath(p) == lang {
+            return true
+        }
+        if importsName(file.Content, strings.TrimSuffix(path.Base(p), path.Ext(p))) {
+            return true
+        }
+        if lang == "go" && dir != "." && importsName(file.Content, path.Base(dir)) {
+            return true
+        }
+    }
+    return false
+}
+
+var importLine = regexp.MustCompile(`^\s*(import\b|from\s|export\s.*\bfrom\b|.*\brequire\(|#include\b|use\s)`)
+
+// importsName reports whether an import line of content mentions name as a
+// whole word. Go import blocks put paths on lines of their own, so any
+// quoted path ending in name counts too.
+func importsName(content, name string) bool {
+    if name == "" {
+        return false
+    }
+    word := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+    goPath := regexp.MustCompile(`^\s*(\w+\s+)?"[^"]*/` + regexp.QuoteMeta(name) + `"`)
+    for _, line := range strings.Split(content, "\n") {
+        if (importLine.MatchString(line) && word.MatchString(line)) || goPath.MatchString(line) {
+            return true
+        }
+    }
+    return false
+}
+
+// maxBaselines bounds the workflows an agent keeps a baseline for; the
+// oldest is dropped first
+const maxBaselines = 128
+
+// baselineCache keeps the last code assurance run of each workflow, so a
+// resumed or iterated workflow analyzes only what it changed
+type baselineCache struct {
+    mu    sync.Mutex
+    runs  map[uuid.UUID]*Baseline
+    order []uuid.UUID
+}
+
+func newBaselineCache() *baselineCache {
+    return &baselineCache{runs: make(map[uuid.UUID]*Baseline)}
+}
+
+func (c *baselineCache) get(workflowID uuid.UUID) *Baseline {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.runs[workflowID]
+}
+
+func (c *baselineCache) put(workflowID uuid.UUID, result *CodeAssuranceResult) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if _, ok := c.runs[workflowID]; !ok {
+        c.order = append(c.order, workflowID)
+        if len(c.order) > maxBaselines {
+            delete(c.runs, c.order[0])
+            c.order = c.order[1:]
+        }
+    }
+    c.runs[workflowID] = &Baseline{Manifest: result.Manifest, Findings: result.Findings}
+}
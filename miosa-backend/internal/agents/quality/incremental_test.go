@@ -0,0 +1,98 @@
+package quality
+
+import (
+    "context"
+    "testing"
+
+    "github.com/google/uuid"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// countingModel counts LLM calls and the files they covered
+type countingModel struct {
+    calls   int
+    prompts []string
+}
+
+func (m *countingModel) Generate(ctx context.Context, messages []ChatMessage) (string, error) {
+    m.calls++
+    m.prompts = append(m.prompts, messages[len(messages)-1].Content)
+    return `{"findings": []}`, nil
+}
+
+func TestRunCodeAssurance_Incremental(t *testing.T) {
+    ctx := context.Background()
+    files := []CodeFile{
+        {Path: "api/handlers.go", Content: "package api\n\n// TODO: paginate\nfunc List() {}\n"},
+        {Path: "api/routes.go", Content: "package api\n\nfunc Routes() {}\n"},
+        {Path: "store/db.go", Content: "package store\n\n// FIXME: pool size\nfunc Open() {}\n"},
+        {Path: "cmd/main.go", Content: "package main\n\nimport \"example.com/app/store\"\n\nfunc main() { store.Open() }\n"},
+        {Path: "web/app.js", Content: "import { api } from './client'\n// TODO: retry\n"},
+        {Path: "web/client.js", Content: "export const api = {}\n"},
+        {Path: "docs/notes.md", Content: "TODO: write docs\n"},
+    }
+
+    full, err := RunCodeAssurance(ctx, nil, CodeAssuranceRequest{Files: files})
+    require.NoError(t, err)
+    assert.Nil(t, full.Incremental)
+    require.Len(t, full.Manifest, len(files))
+    byFile := func(findings []Finding) map[string]int {
+        counts := make(map[string]int)
+        for _, f := range findings {
+            counts[f.File]++
+        }
+        return counts
+    }
+    before := byFile(full.Findings)
+    require.Equal(t, 1, before["store/db.go"])
+
+    // Fix the FIXME, touch the JS client and delete the docs
+    next := append([]CodeFile{}, files[:6]...)
+    next[2] = CodeFile{Path: "store/db.go", Content: "package store\n\nfunc Open() {}\n"}
+    next[5] = CodeFile{Path: "web/client.js", Content: "export const api = { base: '/v1' }\n"}
+    model := &countingModel{}
+    inc, err := RunCodeAssurance(ctx, model, CodeAssuranceRequest{
+        Files:    next,
+        Baseline: &Baseline{Manifest: full.Manifest, Findings: full.Findings},
+    })
+    require.NoError(t, err)
+    require.NotNil(t, inc.Incremental)
+    assert.Equal(t, []string{"store/db.go", "web/client.js"}, inc.Incremental.Changed)
+    assert.ElementsMatch(t, []string{"cmd/main.go", "web/app.js"}, inc.Incremental.Impacted, "importers of changed files are re-analyzed")
+    assert.Equal(t, []string{"docs/notes.md"}, inc.Incremental.Removed)
+
+    after := byFile(inc.Findings)
+    assert.Zero(t, after["store/db.go"], "fixed findings are dropped")
+    assert.Zero(t, after["docs/notes.md"], "findings of removed files are dropped")
+    assert.Equal(t, before["api/handlers.go"], after["api/handlers.go"], "untouched files keep their findings")
+    assert.Equal(t, before["web/app.js"], after["web/app.js"])
+    assert.Equal(t, before["api/handlers.go"], inc.Incremental.Reused)
+
+    require.Equal(t, 1, model.calls)
+    assert.NotContains(t, model.prompts[0], "api/handlers.go", "only changed and impacted files reach the LLM")
+    assert.Equal(t, HashFiles(next), inc.Manifest)
+
+    // Nothing changed: no analysis, every finding reused
+    model.calls = 0
+    same, err := RunCodeAssurance(ctx, model, CodeAssuranceRequest{
+        Files:    next,
+        Baseline: &Baseline{Manifest: inc.Manifest, Findings: inc.Findings},
+    })
+    require.NoError(t, err)
+    assert.Empty(t, same.Incremental.Changed)
+    assert.Zero(t, model.calls)
+    assert.Equal(t, len(inc.Findings), len(same.Findings))
+}
+
+func TestBaselineCacheBounded(t *testing.T) {
+    c := newBaselineCache()
+    ids := make([]uuid.UUID, maxBaselines+5)
+    for i := range ids {
+        ids[i] = uuid.New()
+        c.put(ids[i], &CodeAssuranceResult{Manifest: Manifest{"a.go": "h"}})
+    }
+    assert.Nil(t, c.get(ids[0]), "oldest workflows are dropped")
+    assert.NotNil(t, c.get(ids[len(ids)-1]))
+    assert.Len(t, c.runs, maxBaselines)
+}
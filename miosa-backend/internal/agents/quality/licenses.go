@@ -0,0 +1,398 @@
+package quality
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "path"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// DefaultDepsDevURL is the public deps.dev API used to resolve dependency licenses
+const DefaultDepsDevURL = "https://api.deps.dev"
+
+// LicensePolicy decides which SPDX licenses are acceptable. Entries match
+// case-insensitively; a trailing "*" matches any suffix (e.g. "AGPL-*").
+// Deny wins over Allow, and an empty Allow list allows everything not denied.
+// The JSON form is what tenants store under settings.license_policy.
+type LicensePolicy struct {
+    Allow []string `json:"allow,omitempty"`
+    Deny  []string `json:"deny,omitempty"`
+}
+
+// DefaultLicensePolicy blocks network copyleft and source-available licenses
+var DefaultLicensePolicy = LicensePolicy{
+    Deny: []string{"AGPL-*", "SSPL-*", "BUSL-*", "Commons-Clause"},
+}
+
+// Check evaluates an SPDX expression. "A OR B" passes when either side
+// passes; "A AND B" needs both.
+func (p LicensePolicy) Check(expr string) (bool, string) {
+    expr = strings.Trim(strings.TrimSpace(expr), "()")
+    if alternatives := splitExpr(expr, " OR "); len(alternatives) > 1 {
+        var reasons []string
+        for _, alt := range alternatives {
+            ok, reason := p.Check(alt)
+            if ok {
+                return true, ""
+            }
+            reasons = append(reasons, reason)
+        }
+        return false, strings.Join(reasons, "; ")
+    }
+    if required := splitExpr(expr, " AND "); len(required) > 1 {
+        for _, req := range required {
+            if ok, reason := p.Check(req); !ok {
+                return false, reason
+            }
+        }
+        return true, ""
+    }
+
+    id := strings.TrimSpace(strings.SplitN(expr, " WITH ", 2)[0])
+    if matchLicense(p.Deny, id) {
+        return false, fmt.Sprintf("%s is on the deny list", id)
+    }
+    if len(p.Allow) > 0 && !matchLicense(p.Allow, id) {
+        return false, fmt.Sprintf("%s is not on the allow list", id)
+    }
+    return true, ""
+}
+
+func splitExpr(expr, op string) []string {
+    parts := strings.Split(expr, op)
+    if len(parts) == 1 {
+        parts = strings.Split(expr, strings.ToLower(op))
+    }
+    return parts
+}
+
+func matchLicense(patterns []string, id string) bool {
+    id = strings.ToLower(id)
+    for _, p := range patterns {
+        p = strings.ToLower(strings.TrimSpace(p))
+        if strings.HasSuffix(p, "*") {
+            if strings.HasPrefix(id, strings.TrimSuffix(p, "*")) {
+                return true
+            }
+        } else if p == id {
+            return true
+        }
+    }
+    return false
+}
+
+// LicensePolicyStore holds per-tenant license policies
+type LicensePolicyStore interface {
+    Policy(ctx context.Context, tenantID uuid.UUID) (LicensePolicy, error)
+    SetPolicy(ctx context.Context, tenantID uuid.UUID, policy LicensePolicy) error
+}
+
+// MemoryLicensePolicyStore keeps policies in memory, falling back to a
+// platform default for tenants without one
+type MemoryLicensePolicyStore struct {
+    fallback LicensePolicy
+    policies map[uuid.UUID]LicensePolicy
+    mu       sync.RWMutex
+}
+
+// NewMemoryLicensePolicyStore creates a store returning fallback for unknown tenants
+func NewMemoryLicensePolicyStore(fallback LicensePolicy) *MemoryLicensePolicyStore {
+    return &MemoryLicensePolicyStore{fallback: fallback, policies: make(map[uuid.UUID]LicensePolicy)}
+}
+
+// Policy returns the tenant's policy or the fallback
+func (s *MemoryLicensePolicyStore) Policy(ctx context.Context, tenantID uuid.UUID) (LicensePolicy, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    if p, ok := s.policies[tenantID]; ok {
+        return p, nil
+    }
+    return s.fallback, nil
+}
+
+// SetPolicy replaces the tenant's policy
+func (s *MemoryLicensePolicyStore) SetPolicy(ctx context.Context, tenantID uuid.UUID, policy LicensePolicy) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.policies[tenantID] = policy
+    return nil
+}
+
+// DefaultLicensePolicies is the store consulted by the quality agent
+var DefaultLicensePolicies LicensePolicyStore = NewMemoryLicensePolicyStore(DefaultLicensePolicy)
+
+// -------- Detection --------
+
+// LicenseUse is a license found in the analyzed files
+type LicenseUse struct {
+    License string `json:"license"` // SPDX identifier or expression
+    Subject string `json:"subject"` // dependency name, or the file for headers
+    File    string `json:"file"`
+    Line    int    `json:"line"`
+    Source  string `json:"source"` // manifest | header | dependency
+}
+
+// LicenseResolver looks up the declared licenses of dependencies. Results
+// are indexed like deps.
+type LicenseResolver interface {
+    Licenses(ctx context.Context, deps []Dependency) ([][]string, error)
+}
+
+var spdxHeader = regexp.MustCompile(`SPDX-License-Identifier:\s*([\w.\-+ ()]+?)\s*(?:\*/|-->|$)`)
+
+// licenseTexts maps distinctive phrases in license headers and LICENSE
+// files to SPDX identifiers. More specific phrases come first.
+var licenseTexts = []struct{ phrase, id string }{
+    {"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL-3.0"},
+    {"Server Side Public License", "SSPL-1.0"},
+    {"Business Source License", "BUSL-1.1"},
+    {"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL-3.0"},
+    {"GNU GENERAL PUBLIC LICENSE", "GPL-3.0"},
+    {"Mozilla Public License", "MPL-2.0"},
+    {"Apache License, Version 2.0", "Apache-2.0"},
+    {"Apache License\nVersion 2.0", "Apache-2.0"},
+    {"Permission is hereby granted, free of charge", "MIT"},
+    {"Redistribution and use in source and binary forms", "BSD-3-Clause"},
+}
+
+// headerScanLines bounds how far into a source file header detection looks
+const headerScanLines = 30
+
+// DetectLicenses finds licenses declared by project manifests, LICENSE
+// files and source headers, and resolves dependency licenses when a
+// resolver is given
+func DetectLicenses(ctx context.Context, files []CodeFile, resolver LicenseResolver) ([]LicenseUse, error) {
+    var uses []LicenseUse
+    for _, f := range files {
+        base := path.Base(f.Path)
+        switch {
+        case base == "package.json" || base == "composer.json":
+            if use, ok := jsonManifestLicense(f); ok {
+                uses = append(uses, use)
+            }
+        case base == "Cargo.toml" || base == "pyproject.toml":
+            if use, ok := tomlManifestLicense(f); ok {
+                uses = append(uses, use)
+            }
+        case strings.HasPrefix(strings.ToUpper(base), "LICENSE") || strings.HasPrefix(strings.ToUpper(base), "COPYING"):
+            if id := classifyLicenseText(f.Content); id != "" {
+                uses = append(uses, LicenseUse{License: id, Subject: f.Path, File: f.Path, Line: 1, Source: "manifest"})
+            }
+        default:
+            if use, ok := headerLicense(f); ok {
+                uses = append(uses, use)
+            }
+        }
+    }
+
+    if resolver == nil {
+        return uses, nil
+    }
+    deps := ParseManifests(files)
+    if len(deps) == 0 {
+        return uses, nil
+    }
+    licenses, err := resolver.Licenses(ctx, deps)
+    if err != nil {
+        return uses, err
+    }
+    for i, dep := range deps {
+        for _, l := range licenses[i] {
+            uses = append(uses, LicenseUse{License: l, Subject: dep.Name + "@" + dep.Version, File: dep.Manifest, Line: dep.Line, Source: "dependency"})
+        }
+    }
+    return uses, nil
+}
+
+func jsonManifestLicense(f CodeFile) (LicenseUse, bool) {
+    var manifest struct {
+        Name    string          `json:"name"`
+        License json.RawMessage `json:"license"`
+    }
+    if err := json.Unmarshal([]byte(f.Content), &manifest); err != nil || len(manifest.License) == 0 {
+        return LicenseUse{}, false
+    }
+
+    // "MIT", {"type": "MIT"} or composer's ["MIT"]
+    var id string
+    var typed struct {
+        Type string `json:"type"`
+    }
+    var list []string
+    switch {
+    case json.Unmarshal(manifest.License, &id) == nil:
+    case json.Unmarshal(manifest.License, &typed) == nil:
+        id = typed.Type
+    case json.Unmarshal(manifest.License, &list) == nil:
+        id = strings.Join(list, " OR ")
+    }
+    if id == "" || strings.EqualFold(id, "UNLICENSED") {
+        return LicenseUse{}, false
+    }
+    return LicenseUse{
+        License: id,
+        Subject: safe(manifest.Name, f.Path),
+        File:    f.Path,
+        Line:    lineOf(strings.Split(f.Content, "\n"), `"license"`),
+        Source:  "manifest",
+    }, true
+}
+
+var tomlLicense = regexp.MustCompile(`^\s*license\s*=\s*(?:"([^"]+)"|\{\s*text\s*=\s*"([^"]+)")`)
+
+func tomlManifestLicense(f CodeFile) (LicenseUse, bool) {
+    for i, line := range strings.Split(f.Content, "\n") {
+        if m := tomlLicense.FindStringSubmatch(line); m != nil {
+            return LicenseUse{License: m[1] + m[2], Subject: f.Path, File: f.Path, Line: i + 1, Source: "manifest"}, true
+        }
+    }
+    return LicenseUse{}, false
+}
+
+// headerLicense reads an SPDX tag or a recognizable license notice from the
+// top of a source file, which usually means the code was copied in
+func headerLicense(f CodeFile) (LicenseUse, bool) {
+    lines := strings.SplitN(f.Content, "\n", headerScanLines+1)
+    if len(lines) > headerScanLines {
+        lines = lines[:headerScanLines]
+    }
+    for i, line := range lines {
+        if m := spdxHeader.FindStringSubmatch(line); m != nil {
+            return LicenseUse{License: strings.TrimSpace(m[1]), Subject: f.Path, File: f.Path, Line: i + 1, Source: "header"}, true
+        }
+    }
+    header := strings.Join(lines, "\n")
+    if id := classifyLicenseText(header); id != "" {
+        return LicenseUse{License: id, Subject: f.Path, File: f.Path, Line: 1, Source: "header"}, true
+    }
+    return LicenseUse{}, false
+}
+
+func classifyLicenseText(text string) string {
+    upper := strings.ToUpper(text)
+    for _, lt := range licenseTexts {
+        if strings.Contains(upper, strings.ToUpper(lt.phrase)) {
+            return lt.id
+        }
+    }
+    return ""
+}
+
+// LicenseFindings reports every license use the policy rejects as a
+// compliance finding
+func LicenseFindings(uses []LicenseUse, policy LicensePolicy) []Finding {
+    var findings []Finding
+    for _, u := range uses {
+        ok, reason := policy.Check(u.License)
+        if ok {
+            continue
+        }
+
+        severity, rule := "medium", "License.NotAllowed"
+        if strings.Contains(reason, "deny list") {
+            severity, rule = "high", "License.Denied"
+        }
+        remediation := fmt.Sprintf("Replace %s with an alternative under an approved license, or obtain a policy exception.", u.Subject)
+        if u.Source == "header" {
+            remediation = "Remove or rewrite the copied code, or obtain a policy exception for its license."
+        }
+
+        findings = append(findings, Finding{
+            Title:       fmt.Sprintf("Disallowed license %s in %s", u.License, u.Subject),
+            Description: fmt.Sprintf("%s (found in %s %s).", reason, u.Source, u.File),
+            File:        u.File,
+            LineStart:   u.Line,
+            Severity:    severity,
+            Category:    "compliance",
+            Rule:        rule,
+            Evidence:    u.License,
+            Remediation: remediation,
+            Confidence:  0.85,
+        })
+    }
+    return findings
+}
+
+// -------- deps.dev client --------
+
+// DepsDevClient resolves dependency licenses from deps.dev
+type DepsDevClient struct {
+    baseURL     string
+    httpClient  *http.Client
+    concurrency int
+}
+
+// NewDepsDevClient creates a client for the deps.dev API at baseURL, or the
+// public instance when empty
+func NewDepsDevClient(baseURL string) *DepsDevClient {
+    if baseURL == "" {
+        baseURL = DefaultDepsDevURL
+    }
+    return &DepsDevClient{
+        baseURL: strings.TrimRight(baseURL, "/"),
+        httpClient: &http.Client{
+            Timeout: 15 * time.Second,
+        },
+        concurrency: DefaultConcurrency,
+    }
+}
+
+// depsDevSystems maps OSV ecosystems to deps.dev package systems
+var depsDevSystems = map[string]string{"Go": "go", "npm": "npm", "PyPI": "pypi"}
+
+// Licenses looks up each dependency version. Unknown packages resolve to no
+// licenses rather than an error.
+func (c *DepsDevClient) Licenses(ctx context.Context, deps []Dependency) ([][]string, error) {
+    out := make([][]string, len(deps))
+    errs := make([]error, len(deps))
+    forEachBounded(len(deps), c.concurrency, func(i int) {
+        out[i], errs[i] = c.licenses(ctx, deps[i])
+    })
+    for _, err := range errs {
+        if err != nil {
+            return out, err
+        }
+    }
+    return out, nil
+}
+
+func (c *DepsDevClient) licenses(ctx context.Context, dep Dependency) ([]string, error) {
+    system, ok := depsDevSystems[dep.Ecosystem]
+    if !ok {
+        return nil, nil
+    }
+    endpoint := fmt.Sprintf("%s/v3/systems/%s/packages/%s/versions/%s",
+        c.baseURL, system, url.PathEscape(dep.Name), url.PathEscape(dep.Version))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("deps.dev lookup for %s failed: %w", dep.Name, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusNotFound {
+        return nil, nil
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("deps.dev lookup for %s returned status %d", dep.Name, resp.StatusCode)
+    }
+
+    var parsed struct {
+        Licenses []string `json:"licenses"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+        return nil, fmt.Errorf("invalid deps.dev response for %s: %w", dep.Name, err)
+    }
+    return parsed.Licenses, nil
+}
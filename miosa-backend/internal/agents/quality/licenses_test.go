@@ -0,0 +1,98 @@
+package quality
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/google/uuid"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestLicensePolicy_Check(t *testing.T) {
+    ok, _ := DefaultLicensePolicy.Check("MIT")
+    assert.True(t, ok)
+
+    ok, reason := DefaultLicensePolicy.Check("AGPL-3.0-only")
+    assert.False(t, ok)
+    assert.Contains(t, reason, "deny list")
+
+    ok, _ = DefaultLicensePolicy.Check("AGPL-3.0-or-later OR MIT")
+    assert.True(t, ok, "either alternative may be chosen")
+
+    ok, _ = DefaultLicensePolicy.Check("MIT AND SSPL-1.0")
+    assert.False(t, ok, "both licenses apply")
+
+    strict := LicensePolicy{Allow: []string{"MIT", "Apache-2.0", "BSD-*"}}
+    ok, reason = strict.Check("GPL-3.0")
+    assert.False(t, ok)
+    assert.Contains(t, reason, "not on the allow list")
+    ok, _ = strict.Check("bsd-3-clause")
+    assert.True(t, ok)
+    ok, _ = strict.Check("Apache-2.0 WITH LLVM-exception")
+    assert.True(t, ok)
+}
+
+func TestDetectLicenses(t *testing.T) {
+    files := []CodeFile{
+        {Path: "package.json", Content: "{\n  \"name\": \"shop\",\n  \"license\": \"MIT\",\n  \"dependencies\": {\"left-pad\": \"1.3.0\"}\n}\n"},
+        {Path: "Cargo.toml", Content: "[package]\nname = \"engine\"\nlicense = \"MIT OR Apache-2.0\"\n"},
+        {Path: "vendor/copied.go", Content: "// SPDX-License-Identifier: AGPL-3.0-only\npackage copied\n"},
+        {Path: "lib/util.js", Content: "/*\n * This program is free software: you can redistribute it under the\n * terms of the GNU Affero General Public License.\n */\n"},
+        {Path: "LICENSE", Content: "Apache License\nVersion 2.0, January 2004\n"},
+        {Path: "main.go", Content: "package main\n"},
+    }
+
+    // deps.dev stand-in reporting left-pad as WTFPL
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        assert.Equal(t, "/v3/systems/npm/packages/left-pad/versions/1.3.0", r.URL.Path)
+        w.Write([]byte(`{"licenses":["WTFPL"]}`))
+    }))
+    defer server.Close()
+
+    uses, err := DetectLicenses(context.Background(), files, NewDepsDevClient(server.URL))
+    require.NoError(t, err)
+
+    got := map[string]LicenseUse{}
+    for _, u := range uses {
+        got[u.File+"|"+u.Source] = u
+    }
+    assert.Equal(t, LicenseUse{License: "MIT", Subject: "shop", File: "package.json", Line: 3, Source: "manifest"}, got["package.json|manifest"])
+    assert.Equal(t, "MIT OR Apache-2.0", got["Cargo.toml|manifest"].License)
+    assert.Equal(t, "AGPL-3.0-only", got["vendor/copied.go|header"].License)
+    assert.Equal(t, "AGPL-3.0", got["lib/util.js|header"].License)
+    assert.Equal(t, "Apache-2.0", got["LICENSE|manifest"].License)
+    assert.Equal(t, "left-pad@1.3.0", got["package.json|dependency"].Subject)
+    assert.NotContains(t, got, "main.go|header")
+
+    findings := LicenseFindings(uses, LicensePolicy{
+        Allow: []string{"MIT", "Apache-2.0"},
+        Deny:  []string{"AGPL-*"},
+    })
+    rules := map[string]string{}
+    for _, f := range findings {
+        assert.Equal(t, "compliance", f.Category)
+        rules[f.File] = f.Rule
+    }
+    assert.Equal(t, map[string]string{
+        "vendor/copied.go": "License.Denied",
+        "lib/util.js":      "License.Denied",
+        "package.json":     "License.NotAllowed",
+    }, rules)
+}
+
+func TestMemoryLicensePolicyStore(t *testing.T) {
+    store := NewMemoryLicensePolicyStore(DefaultLicensePolicy)
+    tenant := uuid.New()
+
+    p, err := store.Policy(context.Background(), tenant)
+    require.NoError(t, err)
+    assert.Equal(t, DefaultLicensePolicy, p)
+
+    custom := LicensePolicy{Deny: []string{"GPL-*"}}
+    require.NoError(t, store.SetPolicy(context.Background(), tenant, custom))
+    p, _ = store.Policy(context.Background(), tenant)
+    assert.Equal(t, custom, p)
+}
@@ -0,0 +1,274 @@
+package quality
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+)
+
+// LoadTestScript is where the generated k6 script is written in a project
+const LoadTestScript = "loadtest/k6.js"
+
+// ErrNoLoadEndpoints is returned when a project has no endpoint a load test
+// can exercise
+var ErrNoLoadEndpoints = errors.New("no GET endpoints to load test")
+
+// LoadThresholds decide whether an endpoint passes its load test
+type LoadThresholds struct {
+    P95MS        float64 `json:"p95Ms"`        // Highest acceptable 95th percentile latency
+    MinRPS       float64 `json:"minRps"`       // Lowest acceptable throughput (0 = no minimum)
+    MaxErrorRate float64 `json:"maxErrorRate"` // Highest acceptable share of 5xx and network errors
+}
+
+// DefaultLoadThresholds are lenient enough for a smoke-test deployment
+var DefaultLoadThresholds = LoadThresholds{P95MS: 500, MaxErrorRate: 0.01}
+
+// LoadTestConfig describes a load test run
+type LoadTestConfig struct {
+    BaseURL    string         `json:"baseUrl"` // Deployment under test, e.g. http://localhost:8080
+    VUs        int            `json:"vus"`     // Concurrent virtual users (0 = 10)
+    Duration   time.Duration  `json:"duration"`
+    Thresholds LoadThresholds `json:"thresholds"`
+}
+
+// LoadPlan is a generated load test: the k6 script and the endpoints it hits
+type LoadPlan struct {
+    Config    LoadTestConfig `json:"config"`
+    Endpoints []string       `json:"endpoints"`         // "METHOD /path" keys
+    Skipped   []string       `json:"skipped,omitempty"` // Write endpoints, which need request bodies and would mutate data
+    Script    string         `json:"-"`
+}
+
+// EndpointLoad is the measured performance of one endpoint
+type EndpointLoad struct {
+    Endpoint  string   `json:"endpoint"`
+    Requests  int      `json:"requests"`
+    RPS       float64  `json:"rps"`
+    P95MS     float64  `json:"p95Ms"`
+    AvgMS     float64  `json:"avgMs"`
+    ErrorRate float64  `json:"errorRate"`
+    Passed    bool     `json:"passed"`
+    Failures  []string `json:"failures,omitempty"` // Thresholds the endpoint missed
+}
+
+// LoadReport is the outcome of a load test
+type LoadReport struct {
+    Tool       string         `json:"tool"`
+    Target     string         `json:"target"`
+    Thresholds LoadThresholds `json:"thresholds"`
+    Endpoints  []EndpointLoad `json:"endpoints"`
+    Skipped    []string       `json:"skipped,omitempty"`
+    Passed     bool           `json:"passed"`
+    Error      string         `json:"error,omitempty"` // Why the test could not produce results
+}
+
+// LoadTester runs a load plan against its target
+type LoadTester interface {
+    Run(ctx context.Context, plan *LoadPlan) (*LoadReport, error)
+}
+
+// DefaultLoadTester runs the orchestrator's performance stage. It is nil,
+// skipping the stage, unless a k6 binary and a target are configured.
+var DefaultLoadTester LoadTester
+
+var pathParam = regexp.MustCompile(`\{[^}/]+\}`)
+
+// NewLoadPlan generates a k6 script for the GET endpoints detected in files.
+// Path parameters are filled with 1.
+func NewLoadPlan(files []CodeFile, config LoadTestConfig) (*LoadPlan, error) {
+    if config.VUs <= 0 {
+        config.VUs = 10
+    }
+    if config.Duration <= 0 {
+        config.Duration = 30 * time.Second
+    }
+    if config.Thresholds == (LoadThresholds{}) {
+        config.Thresholds = DefaultLoadThresholds
+    }
+
+    plan := &LoadPlan{Config: config}
+    seen := map[string]bool{}
+    for _, r := range DetectRoutes(files) {
+        key := r.Key()
+        if seen[key] {
+            continue
+        }
+        seen[key] = true
+        if r.Method == "GET" {
+            plan.Endpoints = append(plan.Endpoints, key)
+        } else {
+            plan.Skipped = append(plan.Skipped, key)
+        }
+    }
+    sort.Strings(plan.Endpoints)
+    sort.Strings(plan.Skipped)
+    if len(plan.Endpoints) == 0 {
+        return nil, ErrNoLoadEndpoints
+    }
+    plan.Script = renderK6Script(plan)
+    return plan, nil
+}
+
+// renderK6Script tags each request with its endpoint and sets a threshold
+// per endpoint so k6 reports every endpoint's metrics separately
+func renderK6Script(plan *LoadPlan) string {
+    t := plan.Config.Thresholds
+    var sb strings.Builder
+    sb.WriteString("// Generated by MIOSA. Run with: k6 run -e BASE_URL=http://localhost:8080 loadtest/k6.js\n")
+    sb.WriteString("import http from 'k6/http';\nimport { check } from 'k6';\n\n")
+    fmt.Fprintf(&sb, "const BASE_URL = __ENV.BASE_URL || %s;\n\n", jsonString(strings.TrimRight(plan.Config.BaseURL, "/")))
+    sb.WriteString("// Only 5xx responses and network errors count as failures; auth errors are expected\n")
+    sb.WriteString("http.setResponseCallback(http.expectedStatuses({ min: 200, max: 499 }));\n\n")
+
+    sb.WriteString("export const options = {\n")
+    fmt.Fprintf(&sb, "  vus: %d,\n  duration: '%s',\n  thresholds: {\n", plan.Config.VUs, k6Duration(plan.Config.Duration))
+    for _, e := range plan.Endpoints {
+        tag := endpointTag(e)
+        fmt.Fprintf(&sb, "    %s: ['p(95)<%g'],\n", jsonString("http_req_duration{endpoint:"+tag+"}"), t.P95MS)
+        fmt.Fprintf(&sb, "    %s: ['rate<=%g'],\n", jsonString("http_req_failed{endpoint:"+tag+"}"), t.MaxErrorRate)
+        fmt.Fprintf(&sb, "    %s: ['rate>=%g'],\n", jsonString("http_reqs{endpoint:"+tag+"}"), t.MinRPS)
+    }
+    sb.WriteString("  },\n};\n\nconst endpoints = [\n")
+    for _, e := range plan.Endpoints {
+        method, path, _ := strings.Cut(e, " ")
+        fmt.Fprintf(&sb, "  { name: %s, method: %s, path: %s },\n", jsonString(endpointTag(e)), jsonString(method), jsonString(pathParam.ReplaceAllString(path, "1")))
+    }
+    sb.WriteString(`];
+
+export default function () {
+  for (const e of endpoints) {
+    const res = http.request(e.method, BASE_URL + e.path, null, { tags: { endpoint: e.name } });
+    check(res, { 'no server error': (r) => r.status < 500 });
+  }
+}
+`)
+    return sb.String()
+}
+
+// endpointTag is the endpoint's k6 tag value. Path parameters are written
+// as :name since braces would end k6's submetric selector.
+func endpointTag(endpoint string) string {
+    return pathParam.ReplaceAllStringFunc(endpoint, func(p string) string {
+        return ":" + p[1:len(p)-1]
+    })
+}
+
+func k6Duration(d time.Duration) string {
+    if d%time.Second == 0 {
+        return fmt.Sprintf("%ds", int(d/time.Second))
+    }
+    return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+func jsonString(s string) string {
+    b, _ := json.Marshal(s)
+    return string(b)
+}
+
+// K6LoadTester runs plans with the k6 CLI, passing the target as BASE_URL
+type K6LoadTester struct {
+    Binary string   // Path to k6
+    Env    []string // Extra KEY=value settings, e.g. auth tokens the script reads
+}
+
+// NewK6LoadTester creates a tester running binary
+func NewK6LoadTester(binary string) *K6LoadTester {
+    return &K6LoadTester{Binary: binary}
+}
+
+// Run executes the plan's script and reads k6's summary export. Missed
+// thresholds are reported per endpoint; the error is only for failures to
+// run k6 at all.
+func (k *K6LoadTester) Run(ctx context.Context, plan *LoadPlan) (*LoadReport, error) {
+    dir, err := os.MkdirTemp("", "miosa-k6-")
+    if err != nil {
+        return nil, fmt.Errorf("failed to create load test directory: %w", err)
+    }
+    defer os.RemoveAll(dir)
+
+    script := filepath.Join(dir, "k6.js")
+    if err := os.WriteFile(script, []byte(plan.Script), 0644); err != nil {
+        return nil, fmt.Errorf("failed to write k6 script: %w", err)
+    }
+    summary := filepath.Join(dir, "summary.json")
+
+    cmd := exec.CommandContext(ctx, k.Binary, "run", "--quiet", "--no-color", "--summary-export", summary, script)
+    cmd.Dir = dir
+    cmd.Env = append(append(os.Environ(), "BASE_URL="+plan.Config.BaseURL), k.Env...)
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+    runErr := cmd.Run()
+    var exitErr *exec.ExitError
+    if runErr != nil && !errors.As(runErr, &exitErr) {
+        return nil, fmt.Errorf("failed to run %s: %w", k.Binary, runErr)
+    }
+
+    report := &LoadReport{Tool: "k6", Target: plan.Config.BaseURL, Thresholds: plan.Config.Thresholds, Skipped: plan.Skipped}
+    data, err := os.ReadFile(summary)
+    if err != nil {
+        // k6 exits non-zero without a summary when the script or target is broken
+        report.Error = strings.TrimSpace(stderr.String())
+        if report.Error == "" && runErr != nil {
+            report.Error = runErr.Error()
+        }
+        return report, nil
+    }
+    var export struct {
+        Metrics map[string]map[string]interface{} `json:"metrics"`
+    }
+    if err := json.Unmarshal(data, &export); err != nil {
+        return nil, fmt.Errorf("failed to parse k6 summary: %w", err)
+    }
+    report.Endpoints = evaluateLoad(plan, export.Metrics)
+    report.Passed = true
+    for _, e := range report.Endpoints {
+        report.Passed = report.Passed && e.Passed
+    }
+    return report, nil
+}
+
+// evaluateLoad reads each endpoint's tagged submetrics and checks them
+// against the plan's thresholds
+func evaluateLoad(plan *LoadPlan, metrics map[string]map[string]interface{}) []EndpointLoad {
+    t := plan.Config.Thresholds
+    value := func(metric, endpoint, field string) float64 {
+        v, _ := metrics[metric+"{endpoint:"+endpointTag(endpoint)+"}"][field].(float64)
+        return v
+    }
+
+    results := make([]EndpointLoad, 0, len(plan.Endpoints))
+    for _, e := range plan.Endpoints {
+        load := EndpointLoad{
+            Endpoint:  e,
+            Requests:  int(value("http_reqs", e, "count")),
+            RPS:       value("http_reqs", e, "rate"),
+            P95MS:     value("http_req_duration", e, "p(95)"),
+            AvgMS:     value("http_req_duration", e, "avg"),
+            ErrorRate: value("http_req_failed", e, "value"),
+        }
+        if load.Requests == 0 {
+            load.Failures = append(load.Failures, "no requests completed")
+        }
+        if load.P95MS > t.P95MS {
+            load.Failures = append(load.Failures, fmt.Sprintf("p95 %.1fms exceeds %gms", load.P95MS, t.P95MS))
+        }
+        if load.ErrorRate > t.MaxErrorRate {
+            load.Failures = append(load.Failures, fmt.Sprintf("error rate %.2f%% exceeds %g%%", load.ErrorRate*100, t.MaxErrorRate*100))
+        }
+        if t.MinRPS > 0 && load.RPS < t.MinRPS {
+            load.Failures = append(load.Failures, fmt.Sprintf("throughput %.1f req/s is below %g req/s", load.RPS, t.MinRPS))
+        }
+        load.Passed = len(load.Failures) == 0
+        results = append(results, load)
+    }
+    return results
+}
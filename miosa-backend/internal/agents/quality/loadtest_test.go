@@ -0,0 +1,89 @@
+package quality
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestNewLoadPlan(t *testing.T) {
+    plan, err := NewLoadPlan([]CodeFile{ginServer}, LoadTestConfig{BaseURL: "http://localhost:8080/", Duration: 1500 * time.Millisecond})
+    require.NoError(t, err)
+    assert.Equal(t, []string{"GET /api/v1/files/{path}", "GET /api/v1/users/{id}", "GET /health"}, plan.Endpoints)
+    assert.Equal(t, []string{"POST /api/v1/users"}, plan.Skipped)
+    assert.Equal(t, 10, plan.Config.VUs)
+    assert.Equal(t, DefaultLoadThresholds, plan.Config.Thresholds)
+
+    assert.Contains(t, plan.Script, `const BASE_URL = __ENV.BASE_URL || "http://localhost:8080";`)
+    assert.Contains(t, plan.Script, "  vus: 10,\n  duration: '1500ms',\n")
+    assert.Contains(t, plan.Script, `    "http_req_duration{endpoint:GET /api/v1/users/:id}": ['p(95)<500'],`)
+    assert.Contains(t, plan.Script, `    "http_req_failed{endpoint:GET /api/v1/users/:id}": ['rate<=0.01'],`)
+    assert.Contains(t, plan.Script, `  { name: "GET /api/v1/users/:id", method: "GET", path: "/api/v1/users/1" },`)
+    assert.NotContains(t, plan.Script, "POST")
+
+    _, err = NewLoadPlan([]CodeFile{{Path: "main.go", Content: `r.POST("/users", create)`}}, LoadTestConfig{})
+    assert.ErrorIs(t, err, ErrNoLoadEndpoints)
+}
+
+// fakeK6 writes a script standing in for the k6 CLI
+func fakeK6(t *testing.T, script string) string {
+    bin := filepath.Join(t.TempDir(), "k6")
+    require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"+script), 0755))
+    return bin
+}
+
+func TestK6LoadTester(t *testing.T) {
+    plan, err := NewLoadPlan([]CodeFile{{Path: "main.go", Content: `r.GET("/health", h)
+r.GET("/users/:id", u)`}}, LoadTestConfig{BaseURL: "http://app:8080", Thresholds: LoadThresholds{P95MS: 200, MinRPS: 50, MaxErrorRate: 0.01}})
+    require.NoError(t, err)
+
+    // The summary path follows --summary-export
+    bin := fakeK6(t, `test "$BASE_URL" = http://app:8080 || exit 107
+test -f "$6" || exit 107
+cat > "$5" <<'EOF'
+{"metrics": {
+  "http_reqs{endpoint:GET /health}": {"count": 3000, "rate": 100},
+  "http_req_duration{endpoint:GET /health}": {"avg": 12.5, "p(95)": 40},
+  "http_req_failed{endpoint:GET /health}": {"value": 0},
+  "http_reqs{endpoint:GET /users/:id}": {"count": 900, "rate": 30},
+  "http_req_duration{endpoint:GET /users/:id}": {"avg": 150, "p(95)": 320},
+  "http_req_failed{endpoint:GET /users/:id}": {"value": 0.05}
+}}
+EOF
+exit 99
+`)
+    report, err := NewK6LoadTester(bin).Run(context.Background(), plan)
+    require.NoError(t, err)
+    assert.Equal(t, "http://app:8080", report.Target)
+    assert.False(t, report.Passed)
+    require.Len(t, report.Endpoints, 2)
+
+    health := report.Endpoints[0]
+    assert.Equal(t, EndpointLoad{Endpoint: "GET /health", Requests: 3000, RPS: 100, P95MS: 40, AvgMS: 12.5, Passed: true}, health)
+
+    users := report.Endpoints[1]
+    assert.Equal(t, "GET /users/{id}", users.Endpoint)
+    assert.False(t, users.Passed)
+    assert.Equal(t, []string{
+        "p95 320.0ms exceeds 200ms",
+        "error rate 5.00% exceeds 1%",
+        "throughput 30.0 req/s is below 50 req/s",
+    }, users.Failures)
+
+    bin = fakeK6(t, `echo "GoError: connection refused" >&2
+exit 107
+`)
+    report, err = NewK6LoadTester(bin).Run(context.Background(), plan)
+    require.NoError(t, err)
+    assert.False(t, report.Passed)
+    assert.Empty(t, report.Endpoints)
+    assert.Equal(t, "GoError: connection refused", report.Error)
+
+    _, err = NewK6LoadTester(filepath.Join(t.TempDir(), "missing")).Run(context.Background(), plan)
+    assert.Error(t, err)
+}
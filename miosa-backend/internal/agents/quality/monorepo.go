@@ -0,0 +1,309 @@
+package quality
+
+import (
+    "fmt"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// Monorepo issue kinds
+const (
+    MonorepoMissingPackage   = "missing_package"   // A declared shared package has no files
+    MonorepoMissingExport    = "missing_export"    // A shared package lacks an export the architect declared
+    MonorepoUndeclaredImport = "undeclared_import" // An import of another service or an undeclared package
+    MonorepoUnknownSymbol    = "unknown_symbol"    // A symbol the imported shared package does not define
+    MonorepoDuplicate        = "duplicate"         // A service redefines a type a shared package exports
+)
+
+var (
+    goImportLine  = regexp.MustCompile(`(?m)^import\s+(?:([\w.]+)\s+)?"([^"]+)"`)
+    goImportBlock = regexp.MustCompile(`(?ms)^import\s*\((.*?)^\)`)
+    goImportSpec  = regexp.MustCompile(`(?m)^\s*(?:([\w.]+)\s+)?"([^"]+)"`)
+    goExported    = regexp.MustCompile(`(?m)^(?:func(?:\s*\([^)]*\))?|var|const)\s+([A-Z]\w*)`)
+    goTypeDecl    = regexp.MustCompile(`(?m)^type\s+(\w+)`)
+
+    tsImport     = regexp.MustCompile(`(?m)^\s*import\s+(?:type\s+)?(?:(\{[^}]*\}|[\w$]+|\*\s+as\s+[\w$]+)(?:\s*,\s*(\{[^}]*\}))?\s+from\s+)?['"]([^'"]+)['"]`)
+    tsRequire    = regexp.MustCompile(`require\(\s*['"]([^'"]+)['"]\s*\)`)
+    tsExported   = regexp.MustCompile(`(?m)^export\s+(?:declare\s+)?(?:default\s+)?(?:async\s+)?(?:abstract\s+)?(?:function\*?|class|interface|type|const|let|var|enum)\s+([A-Za-z_$][\w$]*)`)
+    tsExportList = regexp.MustCompile(`(?m)^export\s+(?:type\s+)?\{([^}]*)\}`)
+    tsTypeDecl   = regexp.MustCompile(`(?m)^(?:export\s+)?(?:declare\s+)?(?:abstract\s+)?(?:class|interface|type|enum)\s+([A-Za-z_$][\w$]*)`)
+)
+
+// MonorepoIssue is one broken cross-reference
+type MonorepoIssue struct {
+    Kind    string `json:"kind"`
+    Package string `json:"package"` // Service or shared package the problem is in
+    File    string `json:"file,omitempty"`
+    Message string `json:"message"`
+}
+
+// MonorepoReport is the contract check of a monorepo's cross-references
+type MonorepoReport struct {
+    Tool     string          `json:"tool"`
+    Packages int             `json:"packages"` // Shared packages found
+    Imports  int             `json:"imports"`  // Imports between workspace packages
+    Issues   []MonorepoIssue `json:"issues,omitempty"`
+}
+
+// Valid reports whether every cross-reference resolves
+func (r *MonorepoReport) Valid() bool {
+    return len(r.Issues) == 0
+}
+
+// sharedSymbols are the names a shared package defines
+type sharedSymbols struct {
+    found   bool
+    exports map[string]bool
+    types   map[string]bool
+}
+
+// CheckMonorepo validates the code of a monorepo against its plan: every
+// shared package exists and defines what the architect declared, services
+// import only shared packages and symbols they define, and no service keeps
+// its own copy of a type a shared package exports
+func CheckMonorepo(plan *agents.MonorepoPlan, files []CodeFile) *MonorepoReport {
+    report := &MonorepoReport{Tool: plan.Tool}
+
+    symbols := make(map[string]*sharedSymbols, len(plan.Shared))
+    for _, s := range plan.Shared {
+        symbols[s.Name] = &sharedSymbols{exports: make(map[string]bool), types: make(map[string]bool)}
+    }
+    for _, f := range files {
+        name, shared := monorepoOwner(plan, f.Path)
+        if !shared || !isMonorepoSource(f.Path) {
+            continue
+        }
+        sym := symbols[name]
+        sym.found = true
+        exports, types := definedSymbols(f)
+        for _, e := range exports {
+            sym.exports[e] = true
+        }
+        for _, t := range types {
+            sym.types[t] = true
+        }
+    }
+    for _, s := range plan.Shared {
+        sym := symbols[s.Name]
+        if !sym.found {
+            report.add(MonorepoMissingPackage, s.Name, "", "shared package %s has no source files in %s/", s.Name, s.Path)
+            continue
+        }
+        report.Packages++
+        for _, e := range s.Exports {
+            if !sym.exports[e] {
+                report.add(MonorepoMissingExport, s.Name, "", "shared package %s does not define its declared export %s", s.Name, e)
+            }
+        }
+    }
+
+    for _, f := range files {
+        owner, shared := monorepoOwner(plan, f.Path)
+        if owner == "" || !isMonorepoSource(f.Path) {
+            continue
+        }
+        for _, imp := range monorepoImports(plan, f) {
+            report.Imports++
+            target, targetShared := imp.target, imp.shared
+            switch {
+            case target == owner:
+                // Imports within a package are its own business
+            case !targetShared && target != "":
+                report.add(MonorepoUndeclaredImport, owner, f.Path, "imports service %s; move the shared code to a shared package", target)
+            case target == "":
+                report.add(MonorepoUndeclaredImport, owner, f.Path, "imports %s, which is not a declared package", imp.path)
+            default:
+                sym := symbols[target]
+                if !sym.found {
+                    continue
+                }
+                for _, name := range imp.names {
+                    if !sym.exports[name] {
+                        report.add(MonorepoUnknownSymbol, owner, f.Path, "uses %s, which shared package %s does not define", name, target)
+                    }
+                }
+            }
+        }
+
+        if shared {
+            continue
+        }
+        _, types := definedSymbols(f)
+        for _, t := range types {
+            for _, s := range plan.Shared {
+                if sym := symbols[s.Name]; sym.types[t] && sym.exports[t] {
+                    report.add(MonorepoDuplicate, owner, f.Path, "redefines %s from shared package %s; import it instead", t, s.Name)
+                }
+            }
+        }
+    }
+    return report
+}
+
+func (r *MonorepoReport) add(kind, pkg, file, format string, args ...interface{}) {
+    r.Issues = append(r.Issues, MonorepoIssue{Kind: kind, Package: pkg, File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+// monorepoOwner returns the service or shared package a path is in
+func monorepoOwner(plan *agents.MonorepoPlan, file string) (name string, shared bool) {
+    file = path.Clean(file)
+    for _, s := range plan.Shared {
+        if file == s.Path || strings.HasPrefix(file, s.Path+"/") {
+            return s.Name, true
+        }
+    }
+    for _, s := range plan.Services {
+        if file == s.Path || strings.HasPrefix(file, s.Path+"/") {
+            return s.Name, false
+        }
+    }
+    return "", false
+}
+
+func isMonorepoSource(file string) bool {
+    switch path.Ext(file) {
+    case ".go", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+        return true
+    }
+    return false
+}
+
+// definedSymbols returns the exported names and the type names of a file
+func definedSymbols(f CodeFile) (exports, types []string) {
+    if path.Ext(f.Path) == ".go" {
+        for _, m := range goExported.FindAllStringSubmatch(f.Content, -1) {
+            exports = append(exports, m[1])
+        }
+        for _, m := range goTypeDecl.FindAllStringSubmatch(f.Content, -1) {
+            types = append(types, m[1])
+            if isExportedGo(m[1]) {
+                exports = append(exports, m[1])
+            }
+        }
+        return exports, types
+    }
+    for _, m := range tsExported.FindAllStringSubmatch(f.Content, -1) {
+        exports = append(exports, m[1])
+    }
+    for _, m := range tsExportList.FindAllStringSubmatch(f.Content, -1) {
+        exports = append(exports, importedNames(m[1], true)...)
+    }
+    for _, m := range tsTypeDecl.FindAllStringSubmatch(f.Content, -1) {
+        types = append(types, m[1])
+    }
+    return exports, types
+}
+
+func isExportedGo(name string) bool {
+    return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// monorepoImport is an import of a workspace package
+type monorepoImport struct {
+    path   string
+    target string // Service or shared package imported, empty when undeclared
+    shared bool
+    names  []string // Symbols used from it
+}
+
+// monorepoImports returns a file's imports of workspace packages: Go
+// imports under the module, and JavaScript imports in the npm scope
+func monorepoImports(plan *agents.MonorepoPlan, f CodeFile) []monorepoImport {
+    var imports []monorepoImport
+    if path.Ext(f.Path) == ".go" {
+        var specs [][]string
+        for _, m := range goImportLine.FindAllStringSubmatch(f.Content, -1) {
+            specs = append(specs, m[1:])
+        }
+        for _, block := range goImportBlock.FindAllStringSubmatch(f.Content, -1) {
+            for _, m := range goImportSpec.FindAllStringSubmatch(block[1], -1) {
+                specs = append(specs, m[1:])
+            }
+        }
+        for _, spec := range specs {
+            alias, importPath := spec[0], spec[1]
+            if !strings.HasPrefix(importPath, plan.Module+"/") {
+                continue
+            }
+            imp := monorepoImport{path: importPath}
+            imp.target, imp.shared = monorepoOwner(plan, strings.TrimPrefix(importPath, plan.Module+"/"))
+            if alias == "" {
+                alias = path.Base(importPath)
+            }
+            if alias != "_" && alias != "." {
+                imp.names = goQualified(f.Content, alias)
+            }
+            imports = append(imports, imp)
+        }
+        return imports
+    }
+
+    scope := "@" + plan.Module + "/"
+    resolve := func(spec string, names []string) {
+        if !strings.HasPrefix(spec, scope) {
+            return
+        }
+        name := strings.SplitN(strings.TrimPrefix(spec, scope), "/", 2)[0]
+        imp := monorepoImport{path: spec, names: names}
+        for _, s := range plan.Shared {
+            if s.Name == name {
+                imp.target, imp.shared = name, true
+            }
+        }
+        for _, s := range plan.Services {
+            if s.Name == name {
+                imp.target = name
+            }
+        }
+        imports = append(imports, imp)
+    }
+    for _, m := range tsImport.FindAllStringSubmatch(f.Content, -1) {
+        var names []string
+        for _, clause := range m[1:3] {
+            if strings.HasPrefix(clause, "{") {
+                names = append(names, importedNames(strings.Trim(clause, "{}"), false)...)
+            }
+        }
+        resolve(m[3], names)
+    }
+    for _, m := range tsRequire.FindAllStringSubmatch(f.Content, -1) {
+        resolve(m[1], nil)
+    }
+    return imports
+}
+
+// goQualified returns the exported names used as alias.Name, sorted
+func goQualified(content, alias string) []string {
+    pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(alias) + `\.([A-Z]\w*)`)
+    seen := make(map[string]bool)
+    var names []string
+    for _, m := range pattern.FindAllStringSubmatch(content, -1) {
+        if !seen[m[1]] {
+            seen[m[1]] = true
+            names = append(names, m[1])
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// importedNames parses the names of a braced import or export list. An
+// import uses the name before "as"; an export publishes the one after it.
+func importedNames(list string, exported bool) []string {
+    var names []string
+    for _, item := range strings.Split(list, ",") {
+        fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(item), "type "))
+        switch {
+        case len(fields) == 0:
+        case len(fields) == 3 && fields[1] == "as" && exported:
+            names = append(names, fields[2])
+        default:
+            names = append(names, fields[0])
+        }
+    }
+    return names
+}
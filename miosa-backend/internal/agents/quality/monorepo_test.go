@@ -0,0 +1,72 @@
+package quality
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+
+    "github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestCheckMonorepo_Go(t *testing.T) {
+    plan := agents.NewMonorepoPlan(agents.MonorepoGo, "example.com/shop", []string{"orders", "billing"})
+    plan.Declare(
+        agents.SharedPackage{Name: "models", Exports: []string{"Order", "NewOrder"}},
+        agents.SharedPackage{Name: "auth"},
+    )
+    files := []CodeFile{
+        {Path: "pkg/models/order.go", Content: "package models\n\ntype Order struct{ ID string }\n\nfunc NewOrder() *Order { return &Order{} }\n"},
+        {Path: "services/orders/main.go", Content: `package main
+
+import (
+    "fmt"
+
+    "example.com/shop/pkg/models"
+    "example.com/shop/pkg/util"
+)
+
+func main() { fmt.Println(models.NewOrder(), models.Total, util.X) }
+`},
+        {Path: "services/billing/main.go", Content: `package main
+
+import billing "example.com/shop/services/orders"
+
+type Order struct{ ID string }
+
+func main() { billing.Run() }
+`},
+    }
+
+    report := CheckMonorepo(plan, files)
+    assert.Equal(t, 1, report.Packages)
+    assert.Equal(t, 3, report.Imports)
+    assert.False(t, report.Valid())
+    assert.Equal(t, []MonorepoIssue{
+        {Kind: MonorepoMissingPackage, Package: "auth", Message: "shared package auth has no source files in pkg/auth/"},
+        {Kind: MonorepoUnknownSymbol, Package: "orders", File: "services/orders/main.go", Message: "uses Total, which shared package models does not define"},
+        {Kind: MonorepoUndeclaredImport, Package: "orders", File: "services/orders/main.go", Message: "imports example.com/shop/pkg/util, which is not a declared package"},
+        {Kind: MonorepoUndeclaredImport, Package: "billing", File: "services/billing/main.go", Message: "imports service orders; move the shared code to a shared package"},
+        {Kind: MonorepoDuplicate, Package: "billing", File: "services/billing/main.go", Message: "redefines Order from shared package models; import it instead"},
+    }, report.Issues)
+}
+
+func TestCheckMonorepo_PNPM(t *testing.T) {
+    plan := agents.NewMonorepoPlan(agents.MonorepoPNPM, "shop", []string{"web", "api"})
+    plan.Declare(agents.SharedPackage{Name: "types", Exports: []string{"User", "formatPrice"}})
+    files := []CodeFile{
+        {Path: "packages/types/src/index.ts", Content: "export interface User { id: string }\nconst format = (n: number) => `$${n}`\nexport { format as formatPrice }\n"},
+        {Path: "apps/web/src/App.tsx", Content: "import React from 'react'\nimport { type User, formatPrice } from '@shop/types'\n"},
+        {Path: "apps/api/src/server.ts", Content: "import { User as Account, Cart } from '@shop/types'\nconst web = require('@shop/web')\n"},
+        {Path: "README.md", Content: "import { Nothing } from '@shop/types'"},
+    }
+
+    report := CheckMonorepo(plan, files)
+    assert.Equal(t, 3, report.Imports)
+    assert.Equal(t, []MonorepoIssue{
+        {Kind: MonorepoUnknownSymbol, Package: "api", File: "apps/api/src/server.ts", Message: "uses Cart, which shared package types does not define"},
+        {Kind: MonorepoUndeclaredImport, Package: "api", File: "apps/api/src/server.ts", Message: "imports service web; move the shared code to a shared package"},
+    }, report.Issues)
+
+    files[2].Content = "import { User } from '@shop/types'\n"
+    assert.True(t, CheckMonorepo(plan, files).Valid())
+}
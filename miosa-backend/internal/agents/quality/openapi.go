@@ -0,0 +1,435 @@
+package quality
+
+import (
+    "errors"
+    "fmt"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// OpenAPIVersion is the OpenAPI release generated specs declare
+const OpenAPIVersion = "3.1.0"
+
+// Route is an HTTP endpoint registered in generated code
+type Route struct {
+    Method string `json:"method"` // Upper-case HTTP method
+    Path   string `json:"path"`   // OpenAPI form, e.g. /users/{id}
+    File   string `json:"file"`
+    Line   int    `json:"line"`
+}
+
+// Key identifies the endpoint as "METHOD /path"
+func (r Route) Key() string {
+    return r.Method + " " + r.Path
+}
+
+// OpenAPIDocument is the subset of an OpenAPI 3.1 document derived from routes
+type OpenAPIDocument struct {
+    OpenAPI string                                  `json:"openapi" yaml:"openapi"`
+    Info    OpenAPIInfo                             `json:"info" yaml:"info"`
+    Paths   map[string]map[string]*OpenAPIOperation `json:"paths" yaml:"paths"`
+}
+
+// OpenAPIInfo is the document's info object
+type OpenAPIInfo struct {
+    Title   string `json:"title" yaml:"title"`
+    Version string `json:"version" yaml:"version"`
+}
+
+// OpenAPIOperation documents one method on a path
+type OpenAPIOperation struct {
+    OperationID string                     `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+    Summary     string                     `json:"summary,omitempty" yaml:"summary,omitempty"`
+    Parameters  []OpenAPIParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+    RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+    Responses   map[string]OpenAPIResponse `json:"responses" yaml:"responses"`
+}
+
+// OpenAPIParameter is a path parameter
+type OpenAPIParameter struct {
+    Name     string            `json:"name" yaml:"name"`
+    In       string            `json:"in" yaml:"in"`
+    Required bool              `json:"required" yaml:"required"`
+    Schema   map[string]string `json:"schema" yaml:"schema"`
+}
+
+// OpenAPIRequestBody is a JSON request body of unknown shape
+type OpenAPIRequestBody struct {
+    Content map[string]OpenAPIMedia `json:"content" yaml:"content"`
+}
+
+// OpenAPIResponse is one documented response
+type OpenAPIResponse struct {
+    Description string                  `json:"description" yaml:"description"`
+    Content     map[string]OpenAPIMedia `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// OpenAPIMedia is a media type entry
+type OpenAPIMedia struct {
+    Schema map[string]string `json:"schema" yaml:"schema"`
+}
+
+// RouteCoverage compares the endpoints detected in code with those an
+// OpenAPI spec documents
+type RouteCoverage struct {
+    SpecFile     string   `json:"specFile,omitempty"` // Spec found in the project; empty when derived
+    Detected     int      `json:"detected"`
+    Documented   int      `json:"documented"`           // Detected endpoints present in the spec
+    Percent      float64  `json:"percent"`              // Documented / Detected, 0–100
+    Undocumented []string `json:"undocumented,omitempty"` // Detected but missing from the spec
+    Stale        []string `json:"stale,omitempty"`        // In the spec but not found in code
+}
+
+var (
+    // app.get('/users/:id', ...) and router.post("/login", ...)
+    expressRoute = regexp.MustCompile(`\b(\w+)\.(get|post|put|patch|delete|head|options)\(\s*['"` + "`" + `](/[^'"` + "`" + `]*)['"` + "`" + `]`)
+    // app.use('/api', router)
+    expressMount = regexp.MustCompile(`\b(\w+)\.use\(\s*['"` + "`" + `](/[^'"` + "`" + `]*)['"` + "`" + `]\s*,\s*(\w+)\s*\)`)
+    // r.GET("/users/:id", ...)
+    ginRoute = regexp.MustCompile(`\b(\w+)\.(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS)\(\s*"([^"]*)"`)
+    // api := r.Group("/api")
+    ginGroup = regexp.MustCompile(`\b(\w+)\s*:?=\s*(\w+)\.Group\(\s*"([^"]*)"`)
+
+    // HTTP clients whose calls look like Express routes
+    clientReceivers = map[string]bool{"axios": true, "http": true, "$http": true, "client": true, "cy": true}
+
+    routeParam = regexp.MustCompile(`([:*])([A-Za-z_][A-Za-z0-9_]*)`)
+    specParam  = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// DetectRoutes finds Express and Gin route registrations. Gin groups and
+// Express mounts are resolved when declared in the same file as the routes.
+func DetectRoutes(files []CodeFile) []Route {
+    var routes []Route
+    for _, f := range files {
+        switch {
+        case isGoLike(f.Path, f.Language):
+            routes = append(routes, fileRoutes(f, ginGroup, ginRoute, func(m []string) (string, string, string) {
+                return m[1], m[2], m[3]
+            })...)
+        case isJavaScriptLike(f.Path, f.Language):
+            routes = append(routes, fileRoutes(f, expressMount, expressRoute, func(m []string) (string, string, string) {
+                return m[3], m[1], m[2]
+            })...)
+        }
+    }
+
+    seen := make(map[string]bool, len(routes))
+    out := routes[:0]
+    for _, r := range routes {
+        if !seen[r.Key()] {
+            seen[r.Key()] = true
+            out = append(out, r)
+        }
+    }
+    sort.SliceStable(out, func(i, j int) bool {
+        if out[i].Path != out[j].Path {
+            return out[i].Path < out[j].Path
+        }
+        return out[i].Method < out[j].Method
+    })
+    return out
+}
+
+// fileRoutes resolves prefixes declared with prefixPattern, then collects
+// routes. prefix splits a prefix match into child, parent and path.
+func fileRoutes(f CodeFile, prefixPattern, routePattern *regexp.Regexp, prefix func([]string) (string, string, string)) []Route {
+    lines := strings.Split(f.Content, "\n")
+
+    parents := make(map[string]string)
+    segments := make(map[string]string)
+    for _, line := range lines {
+        for _, m := range prefixPattern.FindAllStringSubmatch(line, -1) {
+            child, parent, p := prefix(m)
+            parents[child] = parent
+            segments[child] = p
+        }
+    }
+    resolve := func(name string) string {
+        full := ""
+        for depth := 0; depth < 10; depth++ {
+            p, ok := segments[name]
+            if !ok {
+                break
+            }
+            full = p + full
+            name = parents[name]
+        }
+        return full
+    }
+
+    var routes []Route
+    for i, line := range lines {
+        for _, m := range routePattern.FindAllStringSubmatch(line, -1) {
+            if clientReceivers[m[1]] {
+                continue
+            }
+            routes = append(routes, Route{
+                Method: strings.ToUpper(m[2]),
+                Path:   openAPIPath(resolve(m[1]) + m[3]),
+                File:   f.Path,
+                Line:   i + 1,
+            })
+        }
+    }
+    return routes
+}
+
+// openAPIPath converts :param and *param segments to {param}
+func openAPIPath(p string) string {
+    p = routeParam.ReplaceAllString(p, "{$2}")
+    p = path.Clean("/" + p)
+    return p
+}
+
+// BuildOpenAPI derives a document with one operation per route
+func BuildOpenAPI(title, version string, routes []Route) *OpenAPIDocument {
+    doc := &OpenAPIDocument{
+        OpenAPI: OpenAPIVersion,
+        Info:    OpenAPIInfo{Title: title, Version: version},
+        Paths:   make(map[string]map[string]*OpenAPIOperation),
+    }
+    for _, r := range routes {
+        op := &OpenAPIOperation{
+            OperationID: operationID(r),
+            Summary:     fmt.Sprintf("%s %s (%s:%d)", r.Method, r.Path, r.File, r.Line),
+            Responses: map[string]OpenAPIResponse{
+                "200": {
+                    Description: "Successful response",
+                    Content:     map[string]OpenAPIMedia{"application/json": {Schema: map[string]string{"type": "object"}}},
+                },
+            },
+        }
+        for _, m := range specParam.FindAllStringSubmatch(r.Path, -1) {
+            op.Parameters = append(op.Parameters, OpenAPIParameter{
+                Name:     m[1],
+                In:       "path",
+                Required: true,
+                Schema:   map[string]string{"type": "string"},
+            })
+        }
+        switch r.Method {
+        case "POST", "PUT", "PATCH":
+            op.RequestBody = &OpenAPIRequestBody{
+                Content: map[string]OpenAPIMedia{"application/json": {Schema: map[string]string{"type": "object"}}},
+            }
+        }
+        if doc.Paths[r.Path] == nil {
+            doc.Paths[r.Path] = make(map[string]*OpenAPIOperation)
+        }
+        doc.Paths[r.Path][strings.ToLower(r.Method)] = op
+    }
+    return doc
+}
+
+var operationIDInvalid = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// operationID names an operation after its method and path, e.g.
+// GET /users/{id} becomes getUsersId
+func operationID(r Route) string {
+    var sb strings.Builder
+    sb.WriteString(strings.ToLower(r.Method))
+    for _, part := range operationIDInvalid.Split(r.Path, -1) {
+        if part != "" {
+            sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+        }
+    }
+    return sb.String()
+}
+
+// ValidateOpenAPI checks the structural rules generated specs must meet
+func ValidateOpenAPI(doc *OpenAPIDocument) error {
+    var errs []error
+    if !strings.HasPrefix(doc.OpenAPI, "3.1.") {
+        errs = append(errs, fmt.Errorf("openapi: version %q is not 3.1.x", doc.OpenAPI))
+    }
+    if doc.Info.Title == "" {
+        errs = append(errs, errors.New("info.title: is required"))
+    }
+    if doc.Info.Version == "" {
+        errs = append(errs, errors.New("info.version: is required"))
+    }
+
+    operationIDs := make(map[string]string)
+    for _, p := range sortedPaths(doc) {
+        if !strings.HasPrefix(p, "/") {
+            errs = append(errs, fmt.Errorf("paths.%s: must start with /", p))
+        }
+        templated := make(map[string]bool)
+        for _, m := range specParam.FindAllStringSubmatch(p, -1) {
+            templated[m[1]] = true
+        }
+        for method, op := range doc.Paths[p] {
+            where := fmt.Sprintf("paths.%s.%s", p, method)
+            switch method {
+            case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+            default:
+                errs = append(errs, fmt.Errorf("%s: unknown method", where))
+            }
+            if len(op.Responses) == 0 {
+                errs = append(errs, fmt.Errorf("%s.responses: at least one response is required", where))
+            }
+            if op.OperationID != "" {
+                if other, dup := operationIDs[op.OperationID]; dup {
+                    errs = append(errs, fmt.Errorf("%s.operationId: %q is also used by %s", where, op.OperationID, other))
+                }
+                operationIDs[op.OperationID] = where
+            }
+            declared := make(map[string]bool)
+            for _, param := range op.Parameters {
+                if param.In != "path" {
+                    continue
+                }
+                declared[param.Name] = true
+                if !templated[param.Name] {
+                    errs = append(errs, fmt.Errorf("%s.parameters: %q is not in the path", where, param.Name))
+                }
+                if !param.Required {
+                    errs = append(errs, fmt.Errorf("%s.parameters: path parameter %q must be required", where, param.Name))
+                }
+            }
+            for name := range templated {
+                if !declared[name] {
+                    errs = append(errs, fmt.Errorf("%s.parameters: path parameter %q is not declared", where, name))
+                }
+            }
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// MarshalOpenAPI renders the document as YAML
+func MarshalOpenAPI(doc *OpenAPIDocument) ([]byte, error) {
+    out, err := yaml.Marshal(doc)
+    if err != nil {
+        return nil, fmt.Errorf("failed to marshal openapi document: %w", err)
+    }
+    return out, nil
+}
+
+// isOpenAPISpec reports whether a file looks like an OpenAPI or Swagger spec
+func isOpenAPISpec(p string) bool {
+    base := strings.ToLower(path.Base(p))
+    for _, name := range []string{"openapi", "swagger"} {
+        for _, ext := range []string{".yaml", ".yml", ".json"} {
+            if base == name+ext {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// specEndpoints returns the "METHOD /path" keys a spec documents, with
+// parameter names blanked so /users/{id} matches /users/{userId}
+func specEndpoints(f CodeFile) (map[string]bool, error) {
+    var doc struct {
+        Paths map[string]map[string]interface{} `yaml:"paths"`
+    }
+    if err := yaml.Unmarshal([]byte(f.Content), &doc); err != nil {
+        return nil, fmt.Errorf("failed to parse %s: %w", f.Path, err)
+    }
+    endpoints := make(map[string]bool)
+    for p, methods := range doc.Paths {
+        for method := range methods {
+            endpoints[strings.ToUpper(method)+" "+normalizeSpecPath(p)] = true
+        }
+    }
+    return endpoints, nil
+}
+
+func normalizeSpecPath(p string) string {
+    return specParam.ReplaceAllString(p, "{}")
+}
+
+// CheckRouteCoverage compares routes with the first OpenAPI spec in files.
+// Without one, coverage is measured against the spec derived from routes,
+// so only endpoints dropped during derivation count as undocumented.
+func CheckRouteCoverage(files []CodeFile, routes []Route) *RouteCoverage {
+    coverage := &RouteCoverage{Detected: len(routes)}
+
+    var documented map[string]bool
+    for _, f := range files {
+        if !isOpenAPISpec(f.Path) {
+            continue
+        }
+        if endpoints, err := specEndpoints(f); err == nil {
+            coverage.SpecFile = f.Path
+            documented = endpoints
+            break
+        }
+    }
+    if documented == nil {
+        documented = make(map[string]bool)
+        for p, methods := range BuildOpenAPI("derived", "0", routes).Paths {
+            for method := range methods {
+                documented[strings.ToUpper(method)+" "+normalizeSpecPath(p)] = true
+            }
+        }
+    }
+
+    detected := make(map[string]bool, len(routes))
+    for _, r := range routes {
+        key := r.Method + " " + normalizeSpecPath(r.Path)
+        detected[key] = true
+        if documented[key] {
+            coverage.Documented++
+        } else {
+            coverage.Undocumented = append(coverage.Undocumented, r.Key())
+        }
+    }
+    for key := range documented {
+        if !detected[key] {
+            coverage.Stale = append(coverage.Stale, key)
+        }
+    }
+    sort.Strings(coverage.Stale)
+
+    if coverage.Detected > 0 {
+        coverage.Percent = float64(coverage.Documented) * 100 / float64(coverage.Detected)
+    }
+    return coverage
+}
+
+// routeCoverageFindings flags endpoints a project's own spec leaves out
+func routeCoverageFindings(coverage *RouteCoverage, routes []Route) []Finding {
+    if coverage == nil || coverage.SpecFile == "" {
+        return nil
+    }
+    missing := make(map[string]bool, len(coverage.Undocumented))
+    for _, key := range coverage.Undocumented {
+        missing[key] = true
+    }
+    var findings []Finding
+    for _, r := range routes {
+        if !missing[r.Key()] {
+            continue
+        }
+        findings = append(findings, Finding{
+            Title:       "Endpoint missing from OpenAPI spec",
+            Description: fmt.Sprintf("%s is registered in code but not documented in %s.", r.Key(), coverage.SpecFile),
+            File:        r.File,
+            LineStart:   r.Line,
+            Severity:    "low",
+            Category:    "maintainability",
+            Rule:        "OpenAPI.Undocumented",
+            Evidence:    r.Key(),
+            Remediation: "Document the endpoint in the OpenAPI spec or regenerate the spec from the routes.",
+            Confidence:  0.8,
+        })
+    }
+    return findings
+}
+
+func sortedPaths(doc *OpenAPIDocument) []string {
+    paths := make([]string, 0, len(doc.Paths))
+    for p := range doc.Paths {
+        paths = append(paths, p)
+    }
+    sort.Strings(paths)
+    return paths
+}
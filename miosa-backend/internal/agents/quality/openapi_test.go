@@ -0,0 +1,119 @@
+package quality
+
+import (
+    "context"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+var ginServer = CodeFile{Path: "main.go", Content: `package main
+
+func routes(r *gin.Engine) {
+    r.GET("/health", health)
+    api := r.Group("/api")
+    v1 := api.Group("/v1")
+    v1.GET("/users/:id", getUser)
+    v1.POST("/users", createUser)
+    v1.GET("/files/*path", getFile)
+}
+`}
+
+var expressServer = CodeFile{Path: "src/server.js", Content: `const router = express.Router()
+router.get('/orders/:orderId', getOrder)
+router.delete("/orders/:orderId", deleteOrder)
+app.use('/api', router)
+app.get('/', index)
+axios.get('/api/external')
+`}
+
+func TestDetectRoutes(t *testing.T) {
+    var keys []string
+    for _, r := range DetectRoutes([]CodeFile{ginServer, expressServer}) {
+        keys = append(keys, r.Key())
+    }
+    assert.Equal(t, []string{
+        "GET /",
+        "DELETE /api/orders/{orderId}",
+        "GET /api/orders/{orderId}",
+        "GET /api/v1/files/{path}",
+        "POST /api/v1/users",
+        "GET /api/v1/users/{id}",
+        "GET /health",
+    }, keys)
+}
+
+func TestBuildOpenAPI(t *testing.T) {
+    doc := BuildOpenAPI("shop", "1.0.0", DetectRoutes([]CodeFile{ginServer}))
+    require.NoError(t, ValidateOpenAPI(doc))
+
+    op := doc.Paths["/api/v1/users/{id}"]["get"]
+    require.NotNil(t, op)
+    assert.Equal(t, "getApiV1UsersId", op.OperationID)
+    assert.Equal(t, []OpenAPIParameter{{Name: "id", In: "path", Required: true, Schema: map[string]string{"type": "string"}}}, op.Parameters)
+    assert.NotNil(t, doc.Paths["/api/v1/users"]["post"].RequestBody)
+
+    out, err := MarshalOpenAPI(doc)
+    require.NoError(t, err)
+    assert.Contains(t, string(out), "openapi: 3.1.0\n")
+}
+
+func TestValidateOpenAPI_Invalid(t *testing.T) {
+    doc := &OpenAPIDocument{
+        OpenAPI: "3.0.0",
+        Info:    OpenAPIInfo{Title: "shop"},
+        Paths: map[string]map[string]*OpenAPIOperation{
+            "/users/{id}": {"get": {OperationID: "getUser"}},
+            "/teams":      {"get": {OperationID: "getUser", Responses: map[string]OpenAPIResponse{"200": {Description: "ok"}}}},
+        },
+    }
+
+    err := ValidateOpenAPI(doc)
+    require.Error(t, err)
+    for _, want := range []string{"not 3.1.x", "info.version", "at least one response", `"id" is not declared`, `"getUser" is also used`} {
+        assert.Contains(t, err.Error(), want)
+    }
+}
+
+func TestCheckRouteCoverage(t *testing.T) {
+    spec := CodeFile{Path: "docs/openapi.yaml", Content: `openapi: 3.1.0
+paths:
+  /health:
+    get: {}
+  /api/v1/users/{userId}:
+    get: {}
+  /api/v1/legacy:
+    get: {}
+`}
+    files := []CodeFile{ginServer, spec}
+    routes := DetectRoutes(files)
+
+    coverage := CheckRouteCoverage(files, routes)
+    assert.Equal(t, "docs/openapi.yaml", coverage.SpecFile)
+    assert.Equal(t, 4, coverage.Detected)
+    assert.Equal(t, 2, coverage.Documented)
+    assert.Equal(t, 50.0, coverage.Percent)
+    assert.Equal(t, []string{"GET /api/v1/files/{path}", "POST /api/v1/users"}, coverage.Undocumented)
+    assert.Equal(t, []string{"GET /api/v1/legacy"}, coverage.Stale)
+
+    derived := CheckRouteCoverage([]CodeFile{ginServer}, routes)
+    assert.Empty(t, derived.SpecFile)
+    assert.Equal(t, 100.0, derived.Percent)
+}
+
+func TestRunCodeAssurance_RouteCoverage(t *testing.T) {
+    spec := CodeFile{Path: "openapi.json", Content: `{"openapi": "3.1.0", "paths": {"/health": {"get": {}}}}`}
+    res, err := RunCodeAssurance(context.Background(), nil, CodeAssuranceRequest{Files: []CodeFile{ginServer, spec}})
+    require.NoError(t, err)
+    require.NotNil(t, res.Routes)
+    assert.Equal(t, 1, res.Routes.Documented)
+
+    var undocumented int
+    for _, f := range res.Findings {
+        if f.Rule == "OpenAPI.Undocumented" {
+            undocumented++
+        }
+    }
+    assert.Equal(t, 3, undocumented)
+}
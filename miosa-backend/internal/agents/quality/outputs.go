@@ -0,0 +1,41 @@
+package quality
+
+import (
+    "fmt"
+    "regexp"
+
+    "github.com/sormind/OSA/miosa-backend/internal/agents"
+    "github.com/sormind/OSA/miosa-backend/internal/langdetect"
+)
+
+// OutputTest is the artifact kind of test code in the agent's output
+const OutputTest = "test"
+
+// testMarker matches the test declarations of Go, Python and the
+// JavaScript test runners
+var testMarker = regexp.MustCompile(`(?m)^func Test\w*\(t \*testing\.T\)|^\s*def test_\w*\(|^\s*(describe|it|test)\(\s*['"` + "`" + `]`)
+
+// OutputHandlers saves the test code found in the agent's output under
+// tests/, named so its framework picks it up
+func (a *QualityAgent) OutputHandlers() []agents.OutputHandler {
+    return []agents.OutputHandler{
+        {
+            Kind: OutputTest,
+            Detect: func(b agents.OutputBlock) bool {
+                return testMarker.MatchString(b.Content)
+            },
+            Path: testPath,
+        },
+    }
+}
+
+func testPath(b agents.OutputBlock, n int) string {
+    lang := langdetect.Classify("", b.Content)
+    switch lang.Name {
+    case "go":
+        return fmt.Sprintf("tests/generated_%d_test.go", n+1)
+    case langdetect.Text.Name:
+        return fmt.Sprintf("tests/test_%d.js", n+1)
+    }
+    return fmt.Sprintf("tests/test_%d%s", n+1, lang.Extension)
+}
@@ -0,0 +1,27 @@
+package quality
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestQualityAgent_OutputHandlers(t *testing.T) {
+    registry := agents.NewOutputRegistry()
+    require.NoError(t, registry.RegisterAgent(New(nil)))
+
+    output := "```go\npackage api\n\nimport \"testing\"\n\nfunc TestHealth(t *testing.T) {}\n```\n\n" +
+        "```python\nimport pytest\n\ndef test_health():\n    assert True\n```\n\n" +
+        "```js\ndescribe('health', () => {\n  it('responds', () => {})\n})\n```\n\n" +
+        "```json\n{\"coverage\": 81.5}\n```\n"
+
+    var paths []string
+    for _, f := range registry.Files(agents.QualityAgent, output) {
+        paths = append(paths, f.Path)
+    }
+    assert.Equal(t, []string{"tests/generated_1_test.go", "tests/test_2.py", "tests/test_3.js"}, paths,
+        "the coverage report is not a test")
+}
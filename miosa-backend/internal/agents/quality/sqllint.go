@@ -0,0 +1,263 @@
+package quality
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/google/uuid"
+    "github.com/sormind/OSA/miosa-backend/internal/sqlschema"
+)
+
+// MigrationVerifier applies migrations, in order, to a throwaway database
+type MigrationVerifier interface {
+    Verify(ctx context.Context, migrations []CodeFile) error
+}
+
+// DefaultMigrationVerifier is the verifier consulted by the quality agent.
+// It is nil, skipping verification, unless a sandbox database is configured.
+var DefaultMigrationVerifier MigrationVerifier
+
+// MigrationError is the first migration that failed to apply
+type MigrationError struct {
+    File string
+    Err  error
+}
+
+func (e *MigrationError) Error() string {
+    return fmt.Sprintf("migration %s failed: %v", e.File, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+    return e.Err
+}
+
+// PostgresMigrationVerifier applies migrations inside a schema created for
+// the run and dropped afterwards, so a shared sandbox database stays clean
+type PostgresMigrationVerifier struct {
+    db *sql.DB
+}
+
+// NewPostgresMigrationVerifier creates a verifier using the sandbox database
+func NewPostgresMigrationVerifier(db *sql.DB) *PostgresMigrationVerifier {
+    return &PostgresMigrationVerifier{db: db}
+}
+
+// Verify applies each migration in turn, stopping at the first failure
+func (v *PostgresMigrationVerifier) Verify(ctx context.Context, migrations []CodeFile) error {
+    // Pin one session so search_path applies to every migration
+    conn, err := v.db.Conn(ctx)
+    if err != nil {
+        return fmt.Errorf("failed to connect to sandbox database: %w", err)
+    }
+    defer conn.Close()
+
+    schema := "verify_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+    if _, err := conn.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+        return fmt.Errorf("failed to create sandbox schema: %w", err)
+    }
+    defer conn.ExecContext(context.Background(), "DROP SCHEMA IF EXISTS "+schema+" CASCADE")
+
+    if _, err := conn.ExecContext(ctx, "SET search_path TO "+schema); err != nil {
+        return fmt.Errorf("failed to select sandbox schema: %w", err)
+    }
+    for _, m := range migrations {
+        if _, err := conn.ExecContext(ctx, m.Content); err != nil {
+            return &MigrationError{File: m.Path, Err: err}
+        }
+    }
+    return nil
+}
+
+// isSQLFile reports whether a file holds SQL to lint
+func isSQLFile(path string) bool {
+    return strings.HasSuffix(strings.ToLower(path), ".sql")
+}
+
+// isDownMigration reports whether the file undoes a migration, where
+// dropping objects is expected
+func isDownMigration(path string) bool {
+    p := strings.ToLower(path)
+    return strings.HasSuffix(p, ".down.sql") || strings.HasSuffix(p, "_down.sql")
+}
+
+// LintSQL parses the SQL files and checks for tables without primary keys,
+// foreign keys without a supporting index, names that break the snake_case
+// convention and destructive statements outside down migrations
+func LintSQL(files []CodeFile) []Finding {
+    schema := sqlschema.New()
+    var findings []Finding
+    for _, f := range files {
+        if !isSQLFile(f.Path) {
+            continue
+        }
+        for _, stmt := range sqlschema.Tokenize(f.Content) {
+            if !isDownMigration(f.Path) {
+                if finding, ok := destructiveFinding(f.Path, stmt); ok {
+                    findings = append(findings, finding)
+                }
+            }
+            schema.Apply(f.Path, stmt)
+        }
+    }
+    return append(findings, schemaFindings(schema)...)
+}
+
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func schemaFindings(schema *sqlschema.Schema) []Finding {
+    var findings []Finding
+    for _, t := range schema.Tables {
+        // CREATE TABLE ... AS and PARTITION OF declare no columns of their own
+        if len(t.PrimaryKey) == 0 && len(t.Columns) > 0 {
+            findings = append(findings, Finding{
+                Title:       "Table without a primary key",
+                Description: fmt.Sprintf("Table %s has no primary key, so rows cannot be addressed reliably and logical replication cannot update or delete them.", t.Name),
+                File:        t.File,
+                LineStart:   t.Line,
+                Severity:    "high",
+                Category:    "reliability",
+                Rule:        "SQL.MissingPrimaryKey",
+                Evidence:    "CREATE TABLE " + t.Name,
+                Remediation: "Add a primary key, e.g. an id column declared PRIMARY KEY.",
+                Confidence:  0.9,
+            })
+        }
+
+        names := []sqlschema.Token{t.Token}
+        for _, c := range t.Columns {
+            names = append(names, c.Token)
+        }
+        for i, name := range names {
+            raw := name.Raw()
+            if snakeCase.MatchString(raw) {
+                continue
+            }
+            kind := "Column"
+            if i == 0 {
+                kind = "Table"
+            }
+            findings = append(findings, Finding{
+                Title:       "Identifier is not snake_case",
+                Description: fmt.Sprintf("%s %q in table %s breaks the lower snake_case naming the rest of the schema uses.", kind, raw, t.Name),
+                File:        t.File,
+                LineStart:   name.Line,
+                Severity:    "low",
+                Category:    "style",
+                Rule:        "SQL.Naming",
+                Evidence:    raw,
+                Remediation: "Use unquoted lower snake_case names so queries need no quoting.",
+                Confidence:  0.8,
+            })
+        }
+
+        for _, fk := range t.ForeignKeys {
+            if t.Covered(fk.Columns) {
+                continue
+            }
+            cols := strings.Join(fk.Columns, ", ")
+            findings = append(findings, Finding{
+                Title:       "Foreign key without an index",
+                Description: fmt.Sprintf("%s(%s) references %s but no index starts with those columns, so joins and cascading deletes scan the table.", t.Name, cols, fk.References.Table),
+                File:        fk.File,
+                LineStart:   fk.Line,
+                Severity:    "medium",
+                Category:    "performance",
+                Rule:        "SQL.UnindexedForeignKey",
+                Evidence:    fmt.Sprintf("%s(%s) REFERENCES %s", t.Name, cols, fk.References.Table),
+                Remediation: fmt.Sprintf("CREATE INDEX idx_%s_%s ON %s (%s);", t.Name, strings.Join(fk.Columns, "_"), t.Name, cols),
+                Confidence:  0.85,
+            })
+        }
+    }
+    return findings
+}
+
+// destructiveFinding flags statements that drop or discard data
+func destructiveFinding(file string, stmt sqlschema.Statement) (Finding, bool) {
+    p := sqlschema.NewParser(stmt.Tokens)
+    var what string
+    switch {
+    case p.Accept("DROP", "TABLE"), p.Accept("DROP", "SCHEMA"), p.Accept("DROP", "DATABASE"):
+        what = strings.ToUpper(stmt.Tokens[0].Text + " " + stmt.Tokens[1].Text)
+    case p.Accept("TRUNCATE"):
+        what = "TRUNCATE"
+    case p.Accept("DELETE", "FROM"):
+        if !stmt.Has("WHERE") {
+            what = "DELETE without WHERE"
+        }
+    case p.Accept("UPDATE"):
+        if !stmt.Has("WHERE") {
+            what = "UPDATE without WHERE"
+        }
+    case p.Accept("ALTER", "TABLE"):
+        switch {
+        case stmt.Has("DROP", "COLUMN"):
+            what = "ALTER TABLE ... DROP COLUMN"
+        case stmt.Has("ALTER", "COLUMN") && stmt.Has("TYPE"):
+            what = "ALTER TABLE ... ALTER COLUMN ... TYPE"
+        }
+    }
+    if what == "" {
+        return Finding{}, false
+    }
+    return Finding{
+        Title:       "Destructive statement in migration",
+        Description: fmt.Sprintf("%s can lose data when the migration runs against an existing database.", what),
+        File:        file,
+        LineStart:   stmt.Line,
+        Severity:    "high",
+        Category:    "reliability",
+        Rule:        "SQL.Destructive",
+        Evidence:    what,
+        Remediation: "Move destructive changes into a reviewed down migration, or migrate the data first and drop in a later release.",
+        Confidence:  0.85,
+    }, true
+}
+
+// Migrations returns the SQL files to apply in order: up migrations and
+// plain scripts, sorted by path
+func Migrations(files []CodeFile) []CodeFile {
+    var out []CodeFile
+    for _, f := range files {
+        if isSQLFile(f.Path) && !isDownMigration(f.Path) {
+            out = append(out, f)
+        }
+    }
+    sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+    return out
+}
+
+// migrationFindings applies the project's migrations with verifier and
+// reports the one that fails
+func migrationFindings(ctx context.Context, verifier MigrationVerifier, files []CodeFile) []Finding {
+    migrations := Migrations(files)
+    if verifier == nil || len(migrations) == 0 {
+        return nil
+    }
+    err := verifier.Verify(ctx, migrations)
+    if err == nil {
+        return nil
+    }
+    var merr *MigrationError
+    if !errors.As(err, &merr) {
+        // The sandbox itself failed; that says nothing about the migrations
+        return nil
+    }
+    return []Finding{{
+        Title:       "Migration fails to apply",
+        Description: fmt.Sprintf("Applying the migrations in order to an empty Postgres database failed: %v", err),
+        File:        merr.File,
+        LineStart:   1,
+        Severity:    "high",
+        Category:    "bug",
+        Rule:        "SQL.MigrationFails",
+        Evidence:    trimEvidence(err.Error()),
+        Remediation: "Fix the failing statement and make sure migrations only depend on objects created by earlier ones.",
+        Confidence:  0.95,
+    }}
+}
@@ -0,0 +1,117 @@
+package quality
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/DATA-DOG/go-sqlmock"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func findingsByRule(findings []Finding) map[string][]Finding {
+    byRule := make(map[string][]Finding)
+    for _, f := range findings {
+        byRule[f.Rule] = append(byRule[f.Rule], f)
+    }
+    return byRule
+}
+
+func TestLintSQL(t *testing.T) {
+    files := []CodeFile{
+        {Path: "migrations/001_init.up.sql", Content: `CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    email TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    "createdAt" TIMESTAMPTZ
+);
+
+CREATE TABLE audit_log (
+    user_id INT,
+    action TEXT,
+    CONSTRAINT fk_audit_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX idx_audit_log_user ON audit_log (user_id, action);
+`},
+        {Path: "migrations/002_tags.up.sql", Content: `CREATE TABLE tags (name TEXT);
+ALTER TABLE tags ADD PRIMARY KEY (name);
+ALTER TABLE orders DROP COLUMN legacy;
+DELETE FROM tags;
+`},
+        {Path: "migrations/002_tags.down.sql", Content: "DROP TABLE tags;\n"},
+    }
+
+    byRule := findingsByRule(LintSQL(files))
+
+    require.Len(t, byRule["SQL.MissingPrimaryKey"], 1)
+    assert.Contains(t, byRule["SQL.MissingPrimaryKey"][0].Description, "audit_log")
+    assert.Equal(t, 12, byRule["SQL.MissingPrimaryKey"][0].LineStart)
+
+    require.Len(t, byRule["SQL.UnindexedForeignKey"], 1, "audit_log.user_id leads an index")
+    assert.Equal(t, "migrations/001_init.up.sql", byRule["SQL.UnindexedForeignKey"][0].File)
+    assert.Equal(t, 8, byRule["SQL.UnindexedForeignKey"][0].LineStart)
+    assert.Contains(t, byRule["SQL.UnindexedForeignKey"][0].Remediation, "CREATE INDEX idx_orders_user_id ON orders (user_id);")
+
+    require.Len(t, byRule["SQL.Naming"], 1)
+    assert.Equal(t, "createdAt", byRule["SQL.Naming"][0].Evidence)
+
+    require.Len(t, byRule["SQL.Destructive"], 2, "down migrations may drop")
+    assert.Equal(t, "ALTER TABLE ... DROP COLUMN", byRule["SQL.Destructive"][0].Evidence)
+    assert.Equal(t, "DELETE without WHERE", byRule["SQL.Destructive"][1].Evidence)
+}
+
+func TestMigrations_OrderedUpOnly(t *testing.T) {
+    got := Migrations([]CodeFile{
+        {Path: "db/002_b.up.sql"},
+        {Path: "db/002_b.down.sql"},
+        {Path: "db/001_a.up.sql"},
+        {Path: "main.go"},
+    })
+    require.Len(t, got, 2)
+    assert.Equal(t, "db/001_a.up.sql", got[0].Path)
+    assert.Equal(t, "db/002_b.up.sql", got[1].Path)
+}
+
+func TestPostgresMigrationVerifier(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    mock.ExpectExec(`CREATE SCHEMA verify_[0-9a-f]{32}`).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(`SET search_path TO verify_`).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(`CREATE TABLE users`).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(`CREATE INDEX`).WillReturnError(errors.New(`relation "orders" does not exist`))
+    mock.ExpectExec(`DROP SCHEMA IF EXISTS verify_[0-9a-f]{32} CASCADE`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+    err = NewPostgresMigrationVerifier(db).Verify(context.Background(), []CodeFile{
+        {Path: "001.sql", Content: "CREATE TABLE users (id int primary key)"},
+        {Path: "002.sql", Content: "CREATE INDEX idx ON orders (user_id)"},
+    })
+    var merr *MigrationError
+    require.ErrorAs(t, err, &merr)
+    assert.Equal(t, "002.sql", merr.File)
+    assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type stubVerifier struct{ err error }
+
+func (s stubVerifier) Verify(ctx context.Context, migrations []CodeFile) error {
+    return s.err
+}
+
+func TestMigrationFindings(t *testing.T) {
+    files := []CodeFile{{Path: "schema.sql", Content: "CREATE TABLE t (id int primary key);"}}
+
+    findings := migrationFindings(context.Background(), stubVerifier{err: &MigrationError{File: "schema.sql", Err: errors.New("syntax error")}}, files)
+    require.Len(t, findings, 1)
+    assert.Equal(t, "SQL.MigrationFails", findings[0].Rule)
+    assert.Len(t, blockingFindings(findings), 1)
+
+    assert.Empty(t, migrationFindings(context.Background(), stubVerifier{err: errors.New("sandbox unreachable")}, files))
+    assert.Empty(t, migrationFindings(context.Background(), nil, files))
+}
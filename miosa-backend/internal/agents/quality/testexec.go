@@ -0,0 +1,479 @@
+package quality
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "path"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/sormind/OSA/miosa-backend/internal/apierror"
+    "github.com/sormind/OSA/miosa-backend/internal/requestid"
+)
+
+// Test frameworks the test stage can run
+const (
+    FrameworkGo     = "go"
+    FrameworkJest   = "jest"
+    FrameworkPytest = "pytest"
+)
+
+// coverageMarker separates a suite's test output from the coverage summary
+// appended to it, for frameworks that only write coverage to a file
+const coverageMarker = "__MIOSA_COVERAGE__"
+
+// DefaultTestTimeout bounds one suite's run in the sandbox
+const DefaultTestTimeout = 5 * time.Minute
+
+// TestSuite is the generated tests one framework runs
+type TestSuite struct {
+    Framework string   `json:"framework"`
+    Files     []string `json:"files"`
+}
+
+// DetectTestSuites groups the test files in files by the framework that
+// runs them: *_test.go with go test, *.test.js, *.spec.ts and the quality
+// agent's tests/test_N.js with jest, and test_*.py or *_test.py with pytest.
+// Playwright specs under e2e/ are left to RunE2E.
+func DetectTestSuites(files []CodeFile) []TestSuite {
+    byFramework := map[string][]string{}
+    for _, f := range files {
+        if framework := testFramework(f.Path); framework != "" {
+            byFramework[framework] = append(byFramework[framework], f.Path)
+        }
+    }
+
+    suites := make([]TestSuite, 0, len(byFramework))
+    for _, framework := range []string{FrameworkGo, FrameworkJest, FrameworkPytest} {
+        if paths := byFramework[framework]; len(paths) > 0 {
+            sort.Strings(paths)
+            suites = append(suites, TestSuite{Framework: framework, Files: paths})
+        }
+    }
+    return suites
+}
+
+var jestFile = regexp.MustCompile(`\.(test|spec)\.[jt]sx?$`)
+
+func testFramework(p string) string {
+    base := path.Base(p)
+    switch {
+    case strings.HasSuffix(base, "_test.go"):
+        return FrameworkGo
+    case strings.HasPrefix(p, e2eDir+"/"):
+        return ""
+    case jestFile.MatchString(base),
+        strings.HasPrefix(base, "test_") && strings.HasSuffix(base, ".js") && path.Base(path.Dir(p)) == "tests":
+        return FrameworkJest
+    case strings.HasSuffix(base, ".py") && (strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py")):
+        return FrameworkPytest
+    }
+    return ""
+}
+
+// Command is the shell command that runs the suite from the project root.
+// Dependencies are installed first; their output is discarded so only the
+// test report reaches stdout.
+func (s TestSuite) Command() string {
+    switch s.Framework {
+    case FrameworkGo:
+        return "go test -json -cover ./... 2>&1"
+    case FrameworkJest:
+        return "([ -f package.json ] && npm install --silent --no-audit --no-fund >/dev/null 2>&1); " +
+            "npx --yes jest --ci --json --coverage --coverageReporters=json-summary " +
+            "--testMatch '**/?(*.)+(spec|test).[jt]s?(x)' '**/tests/test_*.js' --testPathIgnorePatterns '/node_modules/' '/" + e2eDir + "/' 2>/dev/null; " +
+            "echo; echo " + coverageMarker + "; cat coverage/coverage-summary.json 2>/dev/null"
+    case FrameworkPytest:
+        return "python -m pip install -q pytest pytest-cov >/dev/null 2>&1; " +
+            "([ -f requirements.txt ] && python -m pip install -q -r requirements.txt >/dev/null 2>&1); " +
+            "python -m pytest -q -rf -p no:cacheprovider --cov=. --cov-report=term 2>&1"
+    }
+    return ""
+}
+
+// TestFailure is one failing test, or a suite that could not run
+type TestFailure struct {
+    Name    string `json:"name"`
+    Message string `json:"message,omitempty"`
+}
+
+// SuiteResult is the outcome of running one suite
+type SuiteResult struct {
+    Framework string        `json:"framework"`
+    Files     []string      `json:"files"`
+    Passed    int           `json:"passed"`
+    Failed    int           `json:"failed"`
+    Skipped   int           `json:"skipped"`
+    Coverage  float64       `json:"coverage"` // Percent of statements or lines; -1 when not reported
+    Failures  []TestFailure `json:"failures,omitempty"`
+    Error     string        `json:"error,omitempty"` // Why the suite produced no results
+}
+
+// TestReport is the outcome of the test stage
+type TestReport struct {
+    Suites    []SuiteResult `json:"suites"`
+    Passed    int           `json:"passed"`
+    Failed    int           `json:"failed"`
+    Success   bool          `json:"success"`
+    FixRounds int           `json:"fix_rounds,omitempty"` // Development revisions made to fix failures
+}
+
+// Failures lists every failure across suites, including suites that could
+// not run
+func (r *TestReport) Failures() []TestFailure {
+    var failures []TestFailure
+    for _, s := range r.Suites {
+        if s.Error != "" {
+            failures = append(failures, TestFailure{Name: s.Framework + " suite", Message: s.Error})
+        }
+        failures = append(failures, s.Failures...)
+    }
+    return failures
+}
+
+// maxFeedbackMessage bounds each failure message fed back to development
+const maxFeedbackMessage = 1500
+
+// Feedback renders the failures as instructions for the development agent,
+// or "" when every suite passed
+func (r *TestReport) Feedback() string {
+    failures := r.Failures()
+    if len(failures) == 0 {
+        return ""
+    }
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "The generated tests failed (%d passed, %d failed). Fix the code so these tests pass; change a test only if it is wrong.\n", r.Passed, r.Failed)
+    for _, f := range failures {
+        msg := strings.TrimSpace(f.Message)
+        if len(msg) > maxFeedbackMessage {
+            msg = msg[:maxFeedbackMessage] + "..."
+        }
+        fmt.Fprintf(&sb, "\n### %s\n%s\n", f.Name, msg)
+    }
+    return sb.String()
+}
+
+// SandboxCommand is a command run against a copy of the project
+type SandboxCommand struct {
+    Files   []CodeFile
+    Command string
+    Timeout time.Duration
+    Collect string // Directory, relative to the project, whose files are returned after the command
+    Cache   *SandboxCache
+}
+
+// SandboxCache is a directory the sandbox keeps between commands: restored
+// before a command when Key was saved, and saved after it otherwise
+type SandboxCache struct {
+    Key string `json:"key"` // Letters, digits, '.', '_' or '-'; derived from what Dir is built from
+    Dir string `json:"dir"` // Relative to the project
+}
+
+// SandboxFile is a file the command left in the collected directory
+type SandboxFile struct {
+    Path    string `json:"path"` // Relative to the project
+    Content []byte `json:"content"`
+}
+
+// SandboxOutput is what a sandboxed command printed, and the files it left
+// in the collected directory
+type SandboxOutput struct {
+    ExitCode int           `json:"exitCode"`
+    Stdout   string        `json:"stdout"`
+    Stderr   string        `json:"stderr"`
+    Files    []SandboxFile `json:"files,omitempty"`
+}
+
+// Sandbox runs commands in isolation from the orchestrator host
+type Sandbox interface {
+    Exec(ctx context.Context, cmd SandboxCommand) (*SandboxOutput, error)
+}
+
+// DefaultSandbox runs the orchestrator's test stage. It is nil, skipping
+// the stage, unless a sandbox is configured.
+var DefaultSandbox Sandbox
+
+// E2BSandbox runs commands through the E2B server's /exec endpoint, which
+// uploads the files into a fresh sandbox and runs the command there
+type E2BSandbox struct {
+    URL    string // Server base URL, e.g. http://localhost:3001
+    Client *http.Client
+}
+
+// NewE2BSandbox creates a sandbox client for the E2B server at url
+func NewE2BSandbox(url string) *E2BSandbox {
+    return &E2BSandbox{URL: strings.TrimRight(url, "/"), Client: http.DefaultClient}
+}
+
+// Exec sends the files and command to the server. A non-zero exit status
+// is reported in the output; the error is for failures to run at all.
+func (e *E2BSandbox) Exec(ctx context.Context, cmd SandboxCommand) (*SandboxOutput, error) {
+    body := map[string]interface{}{
+        "files":     cmd.Files,
+        "command":   cmd.Command,
+        "timeoutMs": cmd.Timeout.Milliseconds(),
+    }
+    if cmd.Collect != "" {
+        body["collect"] = cmd.Collect
+    }
+    if cmd.Cache != nil {
+        body["cache"] = cmd.Cache
+    }
+    payload, err := json.Marshal(body)
+    if err != nil {
+        return nil, err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL+"/exec", bytes.NewReader(payload))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    requestid.Set(ctx, req)
+
+    resp, err := e.Client.Do(req)
+    if err != nil {
+        return nil, apierror.Wrap(apierror.CategorySandboxFailure, "sandbox unreachable", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+        return nil, apierror.Newf(apierror.CategorySandboxFailure, "sandbox returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+    }
+    var out SandboxOutput
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+        return nil, apierror.Wrap(apierror.CategorySandboxFailure, "invalid sandbox response", err)
+    }
+    return &out, nil
+}
+
+// RunTests runs every suite detected in files in its own sandbox. It
+// returns nil when files contain no tests. Suites the sandbox could not
+// run are reported with an Error; the report only succeeds when every
+// suite ran and none of its tests failed.
+func RunTests(ctx context.Context, sandbox Sandbox, files []CodeFile) *TestReport {
+    suites := DetectTestSuites(files)
+    if len(suites) == 0 {
+        return nil
+    }
+
+    report := &TestReport{Success: true}
+    for _, suite := range suites {
+        result := SuiteResult{Framework: suite.Framework, Files: suite.Files, Coverage: -1}
+        out, err := sandbox.Exec(ctx, SandboxCommand{Files: files, Command: suite.Command(), Timeout: DefaultTestTimeout})
+        if err != nil {
+            result.Error = err.Error()
+        } else {
+            parseSuite(&result, out)
+        }
+
+        report.Passed += result.Passed
+        report.Failed += result.Failed
+        if result.Failed > 0 || result.Error != "" {
+            report.Success = false
+        }
+        report.Suites = append(report.Suites, result)
+    }
+    return report
+}
+
+// parseSuite fills result from the framework's output. Output with no
+// recognizable results is kept as the suite's error.
+func parseSuite(result *SuiteResult, out *SandboxOutput) {
+    switch result.Framework {
+    case FrameworkGo:
+        parseGoTest(result, out.Stdout)
+    case FrameworkJest:
+        parseJest(result, out.Stdout)
+    case FrameworkPytest:
+        parsePytest(result, out.Stdout)
+    }
+    if result.Passed+result.Failed+result.Skipped == 0 && len(result.Failures) == 0 {
+        result.Error = outputTail(out)
+    }
+}
+
+// goTestEvent is one line of go test -json
+type goTestEvent struct {
+    Action  string
+    Package string
+    Test    string
+    Output  string
+}
+
+var goCoverage = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+
+// parseGoTest counts test events and averages package coverage. Packages
+// that fail without a failing test, such as build failures, are reported
+// with their output.
+func parseGoTest(result *SuiteResult, stdout string) {
+    output := map[string]*strings.Builder{} // Package or package/test
+    failedTests := map[string]bool{}
+    var coverage []float64
+
+    scanner := bufio.NewScanner(strings.NewReader(stdout))
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+    for scanner.Scan() {
+        var e goTestEvent
+        if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+            continue
+        }
+        key := e.Package
+        if e.Test != "" {
+            key += "/" + e.Test
+        }
+        switch e.Action {
+        case "output":
+            if output[key] == nil {
+                output[key] = &strings.Builder{}
+            }
+            output[key].WriteString(e.Output)
+            if m := goCoverage.FindStringSubmatch(e.Output); m != nil && e.Test == "" {
+                if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+                    coverage = append(coverage, pct)
+                }
+            }
+        case "pass":
+            if e.Test != "" {
+                result.Passed++
+            }
+        case "skip":
+            if e.Test != "" {
+                result.Skipped++
+            }
+        case "fail":
+            if e.Test != "" {
+                result.Failed++
+                failedTests[e.Package] = true
+                result.Failures = append(result.Failures, TestFailure{Name: e.Test, Message: text(output[key])})
+            } else if !failedTests[e.Package] {
+                result.Failures = append(result.Failures, TestFailure{Name: e.Package, Message: text(output[key])})
+            }
+        }
+    }
+
+    if len(coverage) > 0 {
+        total := 0.0
+        for _, pct := range coverage {
+            total += pct
+        }
+        result.Coverage = total / float64(len(coverage))
+    }
+}
+
+func text(sb *strings.Builder) string {
+    if sb == nil {
+        return ""
+    }
+    return strings.TrimSpace(sb.String())
+}
+
+// jestReport is the part of jest --json the stage reads
+type jestReport struct {
+    NumPassedTests  int `json:"numPassedTests"`
+    NumFailedTests  int `json:"numFailedTests"`
+    NumPendingTests int `json:"numPendingTests"`
+    TestResults     []struct {
+        Name             string `json:"name"`
+        Status           string `json:"status"`
+        Message          string `json:"message"`
+        AssertionResults []struct {
+            FullName        string   `json:"fullName"`
+            Status          string   `json:"status"`
+            FailureMessages []string `json:"failureMessages"`
+        } `json:"assertionResults"`
+    } `json:"testResults"`
+}
+
+// parseJest reads the JSON report and the coverage summary after
+// coverageMarker
+func parseJest(result *SuiteResult, stdout string) {
+    report, summary, _ := strings.Cut(stdout, coverageMarker)
+    var r jestReport
+    if start := strings.Index(report, "{"); start >= 0 && json.Unmarshal([]byte(strings.TrimSpace(report[start:])), &r) == nil {
+        result.Passed, result.Failed, result.Skipped = r.NumPassedTests, r.NumFailedTests, r.NumPendingTests
+        for _, file := range r.TestResults {
+            failedAssertions := 0
+            for _, a := range file.AssertionResults {
+                if a.Status == "failed" {
+                    failedAssertions++
+                    result.Failures = append(result.Failures, TestFailure{Name: a.FullName, Message: strings.Join(a.FailureMessages, "\n")})
+                }
+            }
+            // A file that fails to load has no assertions, only a message
+            if file.Status == "failed" && failedAssertions == 0 {
+                result.Failures = append(result.Failures, TestFailure{Name: path.Base(file.Name), Message: file.Message})
+            }
+        }
+    }
+
+    var coverage struct {
+        Total struct {
+            Lines struct {
+                Pct json.Number `json:"pct"`
+            } `json:"lines"`
+        } `json:"total"`
+    }
+    if json.Unmarshal([]byte(strings.TrimSpace(summary)), &coverage) == nil {
+        if pct, err := coverage.Total.Lines.Pct.Float64(); err == nil {
+            result.Coverage = pct
+        }
+    }
+}
+
+var (
+    pytestCount    = regexp.MustCompile(`(\d+) (passed|failed|skipped|errors?)\b`)
+    pytestFailed   = regexp.MustCompile(`(?m)^(FAILED|ERROR) (\S+)(?: - (.*))?$`)
+    pytestCoverage = regexp.MustCompile(`(?m)^TOTAL\s+.*?(\d+(?:\.\d+)?)%\s*$`)
+)
+
+// parsePytest reads the final summary line, the short failure summary
+// enabled by -rf, and the TOTAL row of pytest-cov's report
+func parsePytest(result *SuiteResult, stdout string) {
+    lines := strings.Split(strings.TrimSpace(stdout), "\n")
+    for i := len(lines) - 1; i >= 0; i-- {
+        matches := pytestCount.FindAllStringSubmatch(lines[i], -1)
+        if len(matches) == 0 || !strings.Contains(lines[i], " in ") {
+            continue
+        }
+        for _, m := range matches {
+            n, _ := strconv.Atoi(m[1])
+            switch m[2] {
+            case "passed":
+                result.Passed = n
+            case "failed", "error", "errors":
+                result.Failed += n
+            case "skipped":
+                result.Skipped = n
+            }
+        }
+        break
+    }
+    for _, m := range pytestFailed.FindAllStringSubmatch(stdout, -1) {
+        result.Failures = append(result.Failures, TestFailure{Name: m[2], Message: m[3]})
+    }
+    if m := pytestCoverage.FindStringSubmatch(stdout); m != nil {
+        if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+            result.Coverage = pct
+        }
+    }
+}
+
+// outputTail is the end of a command's output, where errors usually are
+func outputTail(out *SandboxOutput) string {
+    text := strings.TrimSpace(out.Stdout + "\n" + out.Stderr)
+    if text == "" {
+        return fmt.Sprintf("no test output (exit status %d)", out.ExitCode)
+    }
+    if len(text) > maxFeedbackMessage {
+        text = "..." + text[len(text)-maxFeedbackMessage:]
+    }
+    return text
+}
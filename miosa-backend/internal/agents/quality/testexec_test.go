@@ -0,0 +1,179 @@
+package quality
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestDetectTestSuites(t *testing.T) {
+    suites := DetectTestSuites([]CodeFile{
+        {Path: "main.go"},
+        {Path: "handlers/users_test.go"},
+        {Path: "tests/test_2.js"},
+        {Path: "tests/test_1.js"},
+        {Path: "src/app.spec.ts"},
+        {Path: "scripts/test_data.js"},
+        {Path: "tests/test_api.py"},
+        {Path: "app.py"},
+    })
+
+    assert.Equal(t, []TestSuite{
+        {Framework: FrameworkGo, Files: []string{"handlers/users_test.go"}},
+        {Framework: FrameworkJest, Files: []string{"src/app.spec.ts", "tests/test_1.js", "tests/test_2.js"}},
+        {Framework: FrameworkPytest, Files: []string{"tests/test_api.py"}},
+    }, suites)
+    assert.Empty(t, DetectTestSuites([]CodeFile{{Path: "main.go"}}))
+}
+
+const goTestOutput = `{"Action":"run","Package":"example.com/app","Test":"TestCreate"}
+{"Action":"output","Package":"example.com/app","Test":"TestCreate","Output":"    users_test.go:12: expected 201, got 500\n"}
+{"Action":"fail","Package":"example.com/app","Test":"TestCreate"}
+{"Action":"pass","Package":"example.com/app","Test":"TestList"}
+{"Action":"skip","Package":"example.com/app","Test":"TestSlow"}
+{"Action":"output","Package":"example.com/app","Output":"coverage: 60.0% of statements\n"}
+{"Action":"fail","Package":"example.com/app"}
+{"Action":"output","Package":"example.com/app/store","Output":"store/db.go:4:2: undefined: sqlx\n"}
+{"Action":"fail","Package":"example.com/app/store"}
+`
+
+func TestParseGoTest(t *testing.T) {
+    result := SuiteResult{Framework: FrameworkGo, Coverage: -1}
+    parseSuite(&result, &SandboxOutput{ExitCode: 1, Stdout: goTestOutput})
+
+    assert.Equal(t, 1, result.Passed)
+    assert.Equal(t, 1, result.Failed)
+    assert.Equal(t, 1, result.Skipped)
+    assert.Equal(t, 60.0, result.Coverage)
+    assert.Equal(t, []TestFailure{
+        {Name: "TestCreate", Message: "users_test.go:12: expected 201, got 500"},
+        {Name: "example.com/app/store", Message: "store/db.go:4:2: undefined: sqlx"},
+    }, result.Failures)
+}
+
+func TestParseJest(t *testing.T) {
+    stdout := `{"numPassedTests":3,"numFailedTests":1,"numPendingTests":0,"testResults":[` +
+        `{"name":"/tmp/app/tests/test_1.js","status":"failed","assertionResults":[` +
+        `{"fullName":"users creates a user","status":"failed","failureMessages":["Expected: 201\nReceived: 500"]},` +
+        `{"fullName":"users lists users","status":"passed"}]},` +
+        `{"name":"/tmp/app/tests/test_2.js","status":"failed","message":"Cannot find module './app'","assertionResults":[]}]}` +
+        "\n" + coverageMarker + "\n" + `{"total":{"lines":{"total":40,"covered":30,"pct":75}}}`
+
+    result := SuiteResult{Framework: FrameworkJest, Coverage: -1}
+    parseSuite(&result, &SandboxOutput{ExitCode: 0, Stdout: stdout})
+
+    assert.Equal(t, 3, result.Passed)
+    assert.Equal(t, 1, result.Failed)
+    assert.Equal(t, 75.0, result.Coverage)
+    assert.Equal(t, []TestFailure{
+        {Name: "users creates a user", Message: "Expected: 201\nReceived: 500"},
+        {Name: "test_2.js", Message: "Cannot find module './app'"},
+    }, result.Failures)
+}
+
+func TestParsePytest(t *testing.T) {
+    stdout := `..F.s
+---------- coverage: platform linux, python 3.11.6 -----------
+Name          Stmts   Miss  Cover
+---------------------------------
+app.py           20      4    80%
+---------------------------------
+TOTAL            20      4    80%
+
+=========================== short test summary info ===========================
+FAILED tests/test_api.py::test_create - assert 500 == 201
+1 failed, 3 passed, 1 skipped in 0.42s
+`
+    result := SuiteResult{Framework: FrameworkPytest, Coverage: -1}
+    parseSuite(&result, &SandboxOutput{ExitCode: 1, Stdout: stdout})
+
+    assert.Equal(t, 3, result.Passed)
+    assert.Equal(t, 1, result.Failed)
+    assert.Equal(t, 1, result.Skipped)
+    assert.Equal(t, 80.0, result.Coverage)
+    assert.Equal(t, []TestFailure{{Name: "tests/test_api.py::test_create", Message: "assert 500 == 201"}}, result.Failures)
+}
+
+func TestParseSuite_NoResults(t *testing.T) {
+    result := SuiteResult{Framework: FrameworkPytest, Coverage: -1}
+    parseSuite(&result, &SandboxOutput{ExitCode: 127, Stderr: "python: command not found"})
+    assert.Equal(t, "python: command not found", result.Error)
+}
+
+type fakeSandbox struct {
+    outputs  map[string]*SandboxOutput // By command prefix
+    commands []string
+}
+
+func (f *fakeSandbox) Exec(ctx context.Context, cmd SandboxCommand) (*SandboxOutput, error) {
+    f.commands = append(f.commands, cmd.Command)
+    for prefix, out := range f.outputs {
+        if strings.HasPrefix(cmd.Command, prefix) {
+            return out, nil
+        }
+    }
+    return &SandboxOutput{ExitCode: 127}, nil
+}
+
+func TestRunTests(t *testing.T) {
+    files := []CodeFile{{Path: "main.go"}, {Path: "main_test.go"}, {Path: "tests/test_api.py"}}
+    sandbox := &fakeSandbox{outputs: map[string]*SandboxOutput{
+        "go test": {Stdout: `{"Action":"pass","Package":"app","Test":"TestMain"}`},
+        "python":  {Stdout: "FAILED tests/test_api.py::test_create - KeyError: 'id'\n1 failed in 0.1s"},
+    }}
+
+    report := RunTests(context.Background(), sandbox, files)
+    require.NotNil(t, report)
+    assert.Len(t, sandbox.commands, 2)
+    assert.Equal(t, 1, report.Passed)
+    assert.Equal(t, 1, report.Failed)
+    assert.False(t, report.Success)
+    assert.Contains(t, report.Feedback(), "### tests/test_api.py::test_create\nKeyError: 'id'")
+
+    assert.Nil(t, RunTests(context.Background(), sandbox, []CodeFile{{Path: "main.go"}}))
+
+    passing := &TestReport{Passed: 2, Success: true}
+    assert.Empty(t, passing.Feedback())
+}
+
+func TestE2BSandbox_Exec(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        assert.Equal(t, "/exec", r.URL.Path)
+        var body struct {
+            Files     []CodeFile `json:"files"`
+            Command   string     `json:"command"`
+            TimeoutMs int64      `json:"timeoutMs"`
+            Collect   string     `json:"collect"`
+        }
+        require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+        assert.Equal(t, "go test ./...", body.Command)
+        assert.Equal(t, int64(60000), body.TimeoutMs)
+        assert.Equal(t, "coverage", body.Collect)
+        require.Len(t, body.Files, 1)
+        w.Write([]byte(`{"exitCode": 1, "stdout": "FAIL", "files": [{"path": "coverage/out.txt", "content": "b2s="}]}`))
+    }))
+    defer server.Close()
+
+    out, err := NewE2BSandbox(server.URL+"/").Exec(context.Background(), SandboxCommand{
+        Files:   []CodeFile{{Path: "main_test.go", Content: "package main"}},
+        Command: "go test ./...",
+        Timeout: time.Minute,
+        Collect: "coverage",
+    })
+    require.NoError(t, err)
+    assert.Equal(t, &SandboxOutput{ExitCode: 1, Stdout: "FAIL", Files: []SandboxFile{{Path: "coverage/out.txt", Content: []byte("ok")}}}, out)
+
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "E2B_API_KEY is not set", http.StatusInternalServerError)
+    }))
+    defer failing.Close()
+    _, err = NewE2BSandbox(failing.URL).Exec(context.Background(), SandboxCommand{Command: "true"})
+    assert.ErrorContains(t, err, "E2B_API_KEY is not set")
+}
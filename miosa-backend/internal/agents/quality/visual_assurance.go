@@ -0,0 +1,64 @@
+package quality
+
+import (
+    "errors"
+    "time"
+
+    "github.com/sormind/OSA/miosa-backend/internal/visual"
+)
+
+// VisualAssuranceRequest defines the input for accessibility checks over
+// rendered UI artifacts.
+type VisualAssuranceRequest struct {
+    Artifacts         []visual.Artifact `json:"artifacts"`                   // Screens, mocks or screenshots to check (required)
+    Packs             []string          `json:"packs,omitempty"`             // Visual guideline packs, e.g. "wcag22-aa"
+    SeverityThreshold string            `json:"severityThreshold,omitempty"` // Minimum severity to report (low|medium|high|critical)
+}
+
+// VisualAssuranceResult aggregates the visual findings of every artifact.
+type VisualAssuranceResult struct {
+    SchemaVersion string                 `json:"schemaVersion"`
+    Findings      []visual.VisualFinding `json:"findings"`
+    Guidelines    []string               `json:"guidelines,omitempty"` // Pack guidance for reviewers and LLM critique
+    Packs         []string               `json:"packs,omitempty"`      // Guideline packs applied, pinned to their versions
+    ExecutionMS   int64                  `json:"executionMS"`
+}
+
+// RunVisualAssurance runs the visual accessibility rules over each artifact,
+// configured by the requested guideline packs.
+func RunVisualAssurance(req VisualAssuranceRequest) (*VisualAssuranceResult, error) {
+    start := time.Now()
+
+    if len(req.Artifacts) == 0 {
+        return nil, errors.New("no artifacts provided")
+    }
+    packs, err := resolvePacks(req.Packs, PackKindVisual)
+    if err != nil {
+        return nil, err
+    }
+    minRank := severityRank(defaultSeverity(req.SeverityThreshold))
+
+    findings := []visual.VisualFinding{}
+    for _, a := range req.Artifacts {
+        for _, f := range visual.Check(a) {
+            severity, refs := packRuleConfig(packs, f.Rule)
+            if severity != "" && severityRank(severity) > severityRank(f.Severity) {
+                f.Severity = normalizeSeverity(severity)
+            }
+            for _, ref := range refs {
+                f.References = appendUnique(f.References, ref)
+            }
+            if severityRank(f.Severity) >= minRank {
+                findings = append(findings, f)
+            }
+        }
+    }
+
+    return &VisualAssuranceResult{
+        SchemaVersion: "1.0.0",
+        Findings:      findings,
+        Guidelines:    packGuidelines(nil, packs),
+        Packs:         packRefs(packs),
+        ExecutionMS:   time.Since(start).Milliseconds(),
+    }, nil
+}
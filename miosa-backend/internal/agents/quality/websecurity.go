@@ -0,0 +1,172 @@
+package quality
+
+import (
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+var (
+    ginEngineCall  = regexp.MustCompile(`gin\.(?:Default|New)\(\)`)
+    expressAppCall = regexp.MustCompile(`\bexpress\(\)`)
+
+    // Evidence that a gateway hardens its responses: hand-written headers or
+    // a helmet-style library
+    securityHeaderMarker = regexp.MustCompile(`(?i)x-content-type-options|\bhelmet\b|gin-contrib/secure|unrolled/secure`)
+
+    stateChangingRoute = regexp.MustCompile(`\.(?:POST|PUT|PATCH|DELETE|post|put|patch|delete)\(\s*["'\x60]`)
+    cookieAuth         = regexp.MustCompile(`\.SetCookie\(|http\.Cookie\{|res\.cookie\(|express-session|cookie-session|sessions\.Sessions\(`)
+    csrfMarker         = regexp.MustCompile(`(?i)csrf|xsrf`)
+
+    ginSetCookie   = regexp.MustCompile(`\.SetCookie\(([^()]*)\)`)
+    httpCookie     = regexp.MustCompile(`http\.Cookie\{`)
+    expressCookie  = regexp.MustCompile(`res\.cookie\(`)
+    secureFlag     = regexp.MustCompile(`(?i)secure\s*:\s*true`)
+    httpOnlyFlag   = regexp.MustCompile(`(?i)httponly\s*:\s*true`)
+    cookieDefaults = regexp.MustCompile(`\bcookieOptions\b`)
+)
+
+// LintWebSecurity checks gin and Express gateways for the Security rule
+// family: missing security headers, cookie-authenticated state-changing
+// routes without CSRF protection, and cookies issued without the Secure and
+// HttpOnly flags. CSRF cookies are exempt from HttpOnly since the
+// double-submit pattern needs scripts to read them.
+func LintWebSecurity(files []CodeFile) []Finding {
+    var gateway *CodeFile
+    var gatewayLine int
+    hasHeaders, hasRoutes, hasCookies, hasCSRF := false, false, false, false
+
+    for i := range files {
+        f := &files[i]
+        if !isWebSource(f.Path) {
+            continue
+        }
+        if gateway == nil {
+            if loc := ginEngineCall.FindStringIndex(f.Content); loc != nil && strings.HasSuffix(f.Path, ".go") {
+                gateway, gatewayLine = f, lineAtOffset(f.Content, loc[0])
+            } else if loc := expressAppCall.FindStringIndex(f.Content); loc != nil && !strings.HasSuffix(f.Path, ".go") {
+                gateway, gatewayLine = f, lineAtOffset(f.Content, loc[0])
+            }
+        }
+        hasHeaders = hasHeaders || securityHeaderMarker.MatchString(f.Content)
+        hasRoutes = hasRoutes || stateChangingRoute.MatchString(f.Content)
+        hasCookies = hasCookies || cookieAuth.MatchString(f.Content)
+        hasCSRF = hasCSRF || csrfMarker.MatchString(f.Content)
+    }
+    if gateway == nil {
+        return nil
+    }
+
+    var findings []Finding
+    if !hasHeaders {
+        findings = append(findings, Finding{
+            Title:       "Gateway sends no security headers",
+            Description: "The web gateway does not set X-Content-Type-Options, X-Frame-Options, Content-Security-Policy or similar hardening headers.",
+            File:        gateway.Path,
+            LineStart:   gatewayLine,
+            Severity:    "medium",
+            Category:    "security",
+            Rule:        "Security.Headers",
+            CWE:         "CWE-693",
+            Remediation: "Add middleware that sets nosniff, frame denial, a referrer policy and a Content-Security-Policy (helmet for Express, a small handler for gin).",
+            Confidence:  0.75,
+        })
+    }
+    if hasRoutes && hasCookies && !hasCSRF {
+        findings = append(findings, Finding{
+            Title:       "State-changing routes lack CSRF protection",
+            Description: "The gateway authenticates with cookies and accepts POST, PUT, PATCH or DELETE requests, but no CSRF token is checked.",
+            File:        gateway.Path,
+            LineStart:   gatewayLine,
+            Severity:    "high",
+            Category:    "security",
+            Rule:        "Security.CSRF",
+            CWE:         "CWE-352",
+            Remediation: "Require a CSRF token (e.g. a double-submit cookie echoed in a header) on state-changing requests, or authenticate with bearer tokens instead of cookies.",
+            Confidence:  0.7,
+        })
+    }
+    for _, f := range files {
+        if isWebSource(f.Path) {
+            findings = append(findings, cookieFlagFindings(f)...)
+        }
+    }
+    return findings
+}
+
+// cookieFlagFindings reports cookies set without Secure or HttpOnly
+func cookieFlagFindings(file CodeFile) []Finding {
+    var findings []Finding
+    report := func(offset int, evidence string) {
+        findings = append(findings, Finding{
+            Title:       "Cookie issued without Secure or HttpOnly",
+            Description: "A cookie is set without the Secure and HttpOnly flags, so it can leak over plain HTTP or be read by injected scripts.",
+            File:        file.Path,
+            LineStart:   lineAtOffset(file.Content, offset),
+            Severity:    "medium",
+            Category:    "security",
+            Rule:        "Security.CookieFlags",
+            CWE:         "CWE-614",
+            Evidence:    trimEvidence(evidence),
+            Remediation: "Set Secure, HttpOnly and SameSite on every cookie that carries a session or other sensitive value.",
+            Confidence:  0.7,
+        })
+    }
+
+    // gin: c.SetCookie(name, value, maxAge, path, domain, secure, httpOnly)
+    for _, m := range ginSetCookie.FindAllStringSubmatchIndex(file.Content, -1) {
+        call := file.Content[m[0]:m[1]]
+        args := strings.Split(file.Content[m[2]:m[3]], ",")
+        if len(args) != 7 || csrfMarker.MatchString(args[0]) {
+            continue
+        }
+        if strings.TrimSpace(args[5]) != "true" || strings.TrimSpace(args[6]) != "true" {
+            report(m[0], call)
+        }
+    }
+
+    // net/http: &http.Cookie{...}
+    for _, loc := range httpCookie.FindAllStringIndex(file.Content, -1) {
+        literal := file.Content[loc[0]:]
+        if end := strings.Index(literal, "}"); end >= 0 {
+            literal = literal[:end+1]
+        }
+        if csrfMarker.MatchString(literal) {
+            continue
+        }
+        if !strings.Contains(literal, "Secure:") || !strings.Contains(literal, "HttpOnly:") ||
+            strings.Contains(literal, "Secure: false") || strings.Contains(literal, "HttpOnly: false") {
+            report(loc[0], literal)
+        }
+    }
+
+    // Express: res.cookie(name, value, options)
+    for _, loc := range expressCookie.FindAllStringIndex(file.Content, -1) {
+        call := file.Content[loc[0]:]
+        if end := strings.Index(call, ");"); end >= 0 {
+            call = call[:end+2]
+        }
+        if csrfMarker.MatchString(call) || cookieDefaults.MatchString(call) {
+            continue
+        }
+        if !secureFlag.MatchString(call) || !httpOnlyFlag.MatchString(call) {
+            report(loc[0], call)
+        }
+    }
+    return findings
+}
+
+func isWebSource(path string) bool {
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".go":
+        return !strings.HasSuffix(path, "_test.go")
+    case ".js", ".ts", ".mjs", ".cjs":
+        return true
+    }
+    return false
+}
+
+// lineAtOffset returns the 1-based line of offset in content
+func lineAtOffset(content string, offset int) int {
+    return strings.Count(content[:offset], "\n") + 1
+}
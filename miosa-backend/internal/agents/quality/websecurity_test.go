@@ -0,0 +1,40 @@
+package quality
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestLintWebSecurity(t *testing.T) {
+    gateway := CodeFile{Path: "main.go", Content: `package main
+
+func main() {
+    r := gin.New()
+    r.DELETE("/items/:id", deleteItem)
+    http.SetCookie(w, &http.Cookie{Name: "session", Value: v, HttpOnly: true})
+    c.SetCookie("csrf_token", t, 0, "/", "", true, false)
+}
+`}
+    findings := LintWebSecurity([]CodeFile{gateway})
+    rules := map[string]int{}
+    for _, f := range findings {
+        rules[f.Rule] = f.LineStart
+    }
+    assert.Equal(t, map[string]int{"Security.Headers": 4, "Security.CookieFlags": 6}, rules,
+        "the CSRF cookie counts as CSRF protection and is exempt from HttpOnly")
+
+    express := CodeFile{Path: "app.js", Content: `const app = express();
+app.use(helmet());
+app.put('/profile', update);
+res.cookie('sid', id, { secure: true, httpOnly: true });
+app.use(session({ secret }));
+`}
+    findings = LintWebSecurity([]CodeFile{express, {Path: "sessions.js", Content: "require('express-session')"}})
+    if assert.Len(t, findings, 1) {
+        assert.Equal(t, "Security.CSRF", findings[0].Rule)
+        assert.Equal(t, "CWE-352", findings[0].CWE)
+    }
+
+    assert.Empty(t, LintWebSecurity([]CodeFile{{Path: "lib.go", Content: "package lib\n"}}))
+}
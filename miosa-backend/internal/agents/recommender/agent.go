@@ -20,7 +20,7 @@ import (
 type RecommenderAgent struct {
 	id             uuid.UUID
 	groqClient     *groq.Client
-	redisClient    *redis.Client
+	redisClient    redis.UniversalClient
 	logger         *zap.Logger
 	testResults    map[string]*ToolTestMetrics
 	improvements   map[string]*Improvement
@@ -73,7 +73,7 @@ func New(groqClient *groq.Client) *RecommenderAgent {
 }
 
 // SetRedis adds Redis client for caching
-func (a *RecommenderAgent) SetRedis(client *redis.Client) {
+func (a *RecommenderAgent) SetRedis(client redis.UniversalClient) {
 	a.redisClient = client
 }
 
@@ -375,7 +375,7 @@ func (a *RecommenderAgent) callKimiK2WithTools(ctx context.Context, prompt strin
 	// Note: groq-go library may not have direct tool support yet
 	// For now, we'll use standard chat completion
 	
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
 		Model: "moonshotai/kimi-k2-instruct",
 		Messages: []groq.ChatCompletionMessage{
 			{
@@ -403,7 +403,7 @@ func (a *RecommenderAgent) callKimiK2WithTools(ctx context.Context, prompt strin
 
 func (a *RecommenderAgent) executeCode(ctx context.Context, code string) map[string]interface{} {
 	// Use compound-beta-mini for code execution
-	response, err := a.groqClient.ChatCompletion(ctx, groq.ChatCompletionRequest{
+	response, err := agents.ChatCompletion(ctx, a.groqClient, a.GetType(), groq.ChatCompletionRequest{
 		Model: "compound-beta-mini",
 		Messages: []groq.ChatCompletionMessage{
 			{
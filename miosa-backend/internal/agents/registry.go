@@ -96,6 +96,20 @@ func Register(agent Agent) error {
     return nil
 }
 
+// Unregister removes an agent from the registry
+func Unregister(agentType AgentType) error {
+    defaultRegistry.mu.Lock()
+    defer defaultRegistry.mu.Unlock()
+
+    if _, exists := defaultRegistry.agents[agentType]; !exists {
+        return fmt.Errorf("agent %s not registered", agentType)
+    }
+
+    delete(defaultRegistry.agents, agentType)
+    delete(defaultRegistry.toolsByAgent, agentType)
+    return nil
+}
+
 // Get retrieves an agent by type
 func Get(agentType AgentType) (Agent, error) {
     defaultRegistry.mu.RLock()
@@ -0,0 +1,501 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/sormind/OSA/miosa-backend/internal/logctx"
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned when every candidate model's breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open for all candidate models")
+
+// DefaultLLMTimeout bounds LLM calls for agents without a specific timeout
+const DefaultLLMTimeout = 60 * time.Second
+
+// defaultAgentTimeouts reflect how much output each agent typically produces
+var defaultAgentTimeouts = map[AgentType]time.Duration{
+	OrchestratorAgent:  30 * time.Second,
+	CommunicationAgent: 30 * time.Second,
+	AnalysisAgent:      60 * time.Second,
+	StrategyAgent:      60 * time.Second,
+	RecommenderAgent:   60 * time.Second,
+	ArchitectAgent:     90 * time.Second,
+	QualityAgent:       90 * time.Second,
+	MonitoringAgent:    90 * time.Second,
+	DeploymentAgent:    90 * time.Second,
+	DevelopmentAgent:   120 * time.Second,
+}
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// modelBreaker trips after threshold consecutive failures and lets a single
+// probe through once cooldown has passed
+type modelBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     string
+	openedAt  time.Time
+	probing   bool
+	mu        sync.Mutex
+}
+
+func (b *modelBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		// Only one probe at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *modelBreaker) configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+func (b *modelBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// release ends a probe without recording an outcome
+func (b *modelBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// trip opens the breaker immediately, as when the model is rate limited
+func (b *modelBreaker) trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+}
+
+func (b *modelBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// LLMGuard applies per-agent timeouts and per provider/model circuit
+// breakers to LLM calls, failing over along the agent's chain, or the
+// model's fallbacks, when a call fails or a breaker is open. Providers
+// failing repeatedly cool down as a whole.
+type LLMGuard struct {
+	provider  string
+	model     string
+	timeouts  map[AgentType]time.Duration
+	fallbacks map[string][]string
+	chains    map[AgentType][]Target
+	providers map[string]*groq.Client // Alternate providers by name
+	breakers  map[string]*modelBreaker
+	health    map[string]*providerHealth
+	catalog   *ModelCatalog     // Models the primary provider serves; nil skips validation
+	compress  *PromptCompressor // Nil sends prompts as they are
+	threshold int
+	cooldown  time.Duration
+	mu        sync.RWMutex
+}
+
+// NewLLMGuard creates a guard with default timeouts and a breaker that
+// trips after 5 consecutive failures for 30 seconds
+func NewLLMGuard() *LLMGuard {
+	timeouts := make(map[AgentType]time.Duration, len(defaultAgentTimeouts))
+	for agent, d := range defaultAgentTimeouts {
+		timeouts[agent] = d
+	}
+	return &LLMGuard{
+		provider:  "groq",
+		timeouts:  timeouts,
+		fallbacks: make(map[string][]string),
+		chains:    make(map[AgentType][]Target),
+		providers: make(map[string]*groq.Client),
+		breakers:  make(map[string]*modelBreaker),
+		health:    make(map[string]*providerHealth),
+		threshold: 5,
+		cooldown:  30 * time.Second,
+	}
+}
+
+// DefaultLLMGuard is used by ChatCompletion
+var DefaultLLMGuard = NewLLMGuard()
+
+// Configure applies timeouts, breaker settings and fallbacks from config
+func (g *LLMGuard) Configure(cfg *config.LLMConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for agent, d := range cfg.AgentTimeouts {
+		g.timeouts[AgentType(agent)] = d
+	}
+	for model, alternatives := range cfg.ModelFallbacks {
+		g.fallbacks[model] = alternatives
+	}
+	for agent, chain := range cfg.AgentFailover {
+		targets := make([]Target, 0, len(chain))
+		for _, s := range chain {
+			targets = append(targets, ParseTarget(s))
+		}
+		g.chains[AgentType(agent)] = targets
+	}
+	if cfg.BreakerThreshold > 0 {
+		g.threshold = cfg.BreakerThreshold
+	}
+	if cfg.BreakerCooldown > 0 {
+		g.cooldown = cfg.BreakerCooldown
+	}
+	// Breakers already created keep their state but take the new settings
+	for _, b := range g.breakers {
+		b.configure(g.threshold, g.cooldown)
+	}
+	if cfg.DefaultProvider != "" {
+		g.provider = cfg.DefaultProvider
+	}
+}
+
+// SetModel routes every call to model instead of the one each agent
+// requests; an empty model restores per-agent models
+func (g *LLMGuard) SetModel(model string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.model = model
+}
+
+// SetProvider sets the provider name used to key circuit breakers
+func (g *LLMGuard) SetProvider(provider string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.provider = provider
+}
+
+// SetTimeout overrides the LLM call timeout for an agent type
+func (g *LLMGuard) SetTimeout(agent AgentType, d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.timeouts[agent] = d
+}
+
+// SetFallbacks sets the models tried, in order, when model is unavailable
+func (g *LLMGuard) SetFallbacks(model string, alternatives ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.fallbacks[model] = alternatives
+}
+
+// SetCatalog validates every call to the primary provider against catalog,
+// replacing models it no longer serves with their nearest available one
+func (g *LLMGuard) SetCatalog(catalog *ModelCatalog) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.catalog = catalog
+}
+
+// SetCompressor compresses long prompts with c before they are sent; nil
+// sends them as they are
+func (g *LLMGuard) SetCompressor(c *PromptCompressor) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.compress = c
+}
+
+// Compression returns the tokens prompt compression saved, by agent, or
+// nil when it is off
+func (g *LLMGuard) Compression() map[AgentType]CompressionStats {
+	g.mu.RLock()
+	c := g.compress
+	g.mu.RUnlock()
+	if c == nil {
+		return nil
+	}
+	return c.Stats()
+}
+
+// Models returns the models the guard routes or fails over to on the
+// primary provider
+func (g *LLMGuard) Models() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var models []string
+	if g.model != "" {
+		models = append(models, g.model)
+	}
+	for model, alternatives := range g.fallbacks {
+		models = append(models, model)
+		models = append(models, alternatives...)
+	}
+	for _, chain := range g.chains {
+		for _, t := range chain {
+			if t.Provider == "" || t.Provider == g.provider {
+				models = append(models, t.Model)
+			}
+		}
+	}
+	return models
+}
+
+// Model returns the model every call is routed to, or "" when each agent
+// uses its own
+func (g *LLMGuard) Model() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.model
+}
+
+// Timeouts returns a copy of the per-agent LLM call timeouts
+func (g *LLMGuard) Timeouts() map[AgentType]time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	timeouts := make(map[AgentType]time.Duration, len(g.timeouts))
+	for agent, d := range g.timeouts {
+		timeouts[agent] = d
+	}
+	return timeouts
+}
+
+// Fallbacks returns a copy of the fallback models of every model
+func (g *LLMGuard) Fallbacks() map[string][]string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	fallbacks := make(map[string][]string, len(g.fallbacks))
+	for model, alternatives := range g.fallbacks {
+		fallbacks[model] = append([]string(nil), alternatives...)
+	}
+	return fallbacks
+}
+
+// Timeout returns the LLM call timeout for an agent type
+func (g *LLMGuard) Timeout(agent AgentType) time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if d, ok := g.timeouts[agent]; ok && d > 0 {
+		return d
+	}
+	return DefaultLLMTimeout
+}
+
+// BreakerState returns the breaker state of a model on the primary provider
+func (g *LLMGuard) BreakerState(model string) string {
+	g.mu.RLock()
+	b, ok := g.breakers[g.provider+"/"+model]
+	g.mu.RUnlock()
+	if !ok {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (g *LLMGuard) breaker(t Target) *modelBreaker {
+	key := t.Provider + "/" + t.Model
+	g.mu.RLock()
+	b, ok := g.breakers[key]
+	g.mu.RUnlock()
+	if ok {
+		return b
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if b, ok := g.breakers[key]; ok {
+		return b
+	}
+	b = &modelBreaker{threshold: g.threshold, cooldown: g.cooldown, state: BreakerClosed}
+	g.breakers[key] = b
+	return b
+}
+
+// candidates returns the requested model on the primary provider followed
+// by the agent's chain, or the model's fallbacks when it has none
+func (g *LLMGuard) candidates(agent AgentType, model string) []Target {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	targets := []Target{{Provider: g.provider, Model: model}}
+	if chain, ok := g.chains[agent]; ok {
+		for _, t := range chain {
+			if t.Provider == "" {
+				t.Provider = g.provider
+			}
+			targets = append(targets, t)
+		}
+		return targets
+	}
+	for _, alternative := range g.fallbacks[model] {
+		targets = append(targets, Target{Provider: g.provider, Model: alternative})
+	}
+	return targets
+}
+
+// ChatCompletion calls client with the agent's timeout. Models missing from
+// the guard's catalog are replaced before calling. When a call fails,
+// or a target's breaker is open or its provider cooling down, the next
+// target of the agent's chain is tried; a rate-limited model's breaker
+// opens at once. If no target can be tried the call fails fast with
+// ErrCircuitOpen. Failovers are recorded in the usage tracker in ctx, every
+// attempt in its transcript recorder, and the response's Model field
+// reports which model actually answered.
+func (g *LLMGuard) ChatCompletion(ctx context.Context, client *groq.Client, agent AgentType, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error) {
+	timeout := g.Timeout(agent)
+	var lastErr error
+
+	g.mu.RLock()
+	if g.model != "" {
+		req.Model = groq.ChatModel(g.model)
+	}
+	threshold, cooldown := g.threshold, g.cooldown
+	primary, catalog, compressor := g.provider, g.catalog, g.compress
+	g.mu.RUnlock()
+
+	// Long prompts are compressed once, before any target is tried
+	if compressor != nil {
+		var saved int
+		req.Messages, saved = compressor.Compress(agent, req.Messages)
+		if usage := UsageFromContext(ctx); usage != nil && saved > 0 {
+			usage.AddSaved(saved)
+		}
+	}
+
+	// The target the next attempt fails over from, and why
+	var from *FailoverEvent
+	skip := func(t Target, reason string) {
+		if from == nil {
+			from = &FailoverEvent{From: t.String(), Reason: reason}
+		}
+	}
+
+	for _, target := range g.candidates(agent, string(req.Model)) {
+		// A model the provider stopped serving fails over to its nearest
+		// replacement
+		if catalog != nil && target.Provider == primary {
+			model, ok := catalog.Resolve(target.Model)
+			if model != target.Model {
+				skip(target, FailoverUnavailable)
+			}
+			if !ok {
+				continue
+			}
+			target.Model = model
+		}
+		targetClient := g.client(target.Provider, client)
+		if targetClient == nil {
+			skip(target, FailoverUnconfigured)
+			continue
+		}
+		health := g.providerHealth(target.Provider)
+		if health.coolingDown(time.Now()) {
+			skip(target, FailoverCooldown)
+			continue
+		}
+		b := g.breaker(target)
+		if !b.allow() {
+			skip(target, FailoverCircuitOpen)
+			continue
+		}
+		if from != nil {
+			from.Agent, from.To, from.At = agent, target.String(), time.Now()
+			logctx.From(ctx).Warn("LLM failover",
+				zap.String(logctx.FieldAgent, string(agent)),
+				zap.String(logctx.FieldModel, string(req.Model)),
+				zap.String("from", from.From),
+				zap.String("to", from.To),
+				zap.String("reason", from.Reason),
+				zap.String("error", from.Error))
+			if usage := UsageFromContext(ctx); usage != nil {
+				usage.AddFailover(*from)
+			}
+			from = nil
+		}
+
+		attempt := req
+		attempt.Model = groq.ChatModel(target.Model)
+
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		resp, err := targetClient.ChatCompletion(callCtx, attempt)
+		cancel()
+		if transcript := TranscriptFromContext(ctx); transcript != nil {
+			transcript.Record(newTranscriptEntry(agent, target, attempt, resp, err, start))
+		}
+
+		if err == nil {
+			b.success()
+			health.success()
+			if usage := UsageFromContext(ctx); usage != nil {
+				usage.Add(resp)
+				usage.AddPrompt(req.Messages)
+			}
+			return resp, nil
+		}
+
+		// The caller gave up; that says nothing about the model's health
+		if ctx.Err() != nil {
+			b.release()
+			return resp, err
+		}
+
+		reason := failoverReason(err)
+		if reason == FailoverRateLimited {
+			b.trip()
+		} else {
+			b.failure()
+		}
+		health.failure(reason, err, threshold, cooldown)
+		if reason == FailoverTimeout {
+			err = fmt.Errorf("%s agent timed out after %s on %s: %w", agent, timeout, target, err)
+		}
+		lastErr = err
+		from = &FailoverEvent{From: target.String(), Reason: reason, Error: err.Error()}
+	}
+
+	if lastErr == nil {
+		return groq.ChatCompletionResponse{}, fmt.Errorf("%s: %w", req.Model, ErrCircuitOpen)
+	}
+	return groq.ChatCompletionResponse{}, lastErr
+}
+
+// ChatCompletion runs an agent's LLM call through DefaultLLMGuard
+func ChatCompletion(ctx context.Context, client *groq.Client, agent AgentType, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error) {
+	return DefaultLLMGuard.ChatCompletion(ctx, client, agent, req)
+}
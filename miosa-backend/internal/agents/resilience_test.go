@@ -0,0 +1,164 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGroq fails every request for the "broken" model and answers others
+func fakeGroq(t *testing.T, calls *int32) *groq.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "broken" {
+			http.Error(w, `{"error":{"message":"model overloaded"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": req.Model,
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": "ok"}, "finish_reason": "stop"},
+			},
+			"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 3, "total_tokens": 13},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := groq.NewClient("test-key", groq.WithBaseURL(server.URL))
+	require.NoError(t, err)
+	return client
+}
+
+func TestLLMGuard_BreakerTripsAndFallsBack(t *testing.T) {
+	var calls int32
+	client := fakeGroq(t, &calls)
+
+	guard := NewLLMGuard()
+	guard.threshold = 2
+	guard.cooldown = time.Hour
+	req := groq.ChatCompletionRequest{
+		Model:    "broken",
+		Messages: []groq.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := guard.ChatCompletion(context.Background(), client, DevelopmentAgent, req)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, BreakerOpen, guard.BreakerState("broken"))
+
+	// Open breaker without fallbacks fails fast without calling the provider
+	before := atomic.LoadInt32(&calls)
+	_, err := guard.ChatCompletion(context.Background(), client, DevelopmentAgent, req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, before, atomic.LoadInt32(&calls))
+
+	// With a fallback configured the alternative model answers
+	guard.SetFallbacks("broken", "healthy")
+	resp, err := guard.ChatCompletion(context.Background(), client, DevelopmentAgent, req)
+	require.NoError(t, err)
+	assert.Equal(t, groq.ChatModel("healthy"), resp.Model)
+}
+
+func TestLLMGuard_ConfigureAppliesToExistingBreakers(t *testing.T) {
+	var calls int32
+	client := fakeGroq(t, &calls)
+
+	guard := NewLLMGuard()
+	req := groq.ChatCompletionRequest{
+		Model:    "broken",
+		Messages: []groq.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	}
+
+	// The breaker is created with the default threshold of 5
+	_, err := guard.ChatCompletion(context.Background(), client, DevelopmentAgent, req)
+	assert.Error(t, err)
+	assert.Equal(t, BreakerClosed, guard.BreakerState("broken"))
+
+	guard.Configure(&config.LLMConfig{BreakerThreshold: 2, BreakerCooldown: time.Hour})
+	_, err = guard.ChatCompletion(context.Background(), client, DevelopmentAgent, req)
+	assert.Error(t, err)
+	assert.Equal(t, BreakerOpen, guard.BreakerState("broken"))
+
+	// A shorter cooldown lets the open breaker probe again
+	guard.Configure(&config.LLMConfig{BreakerCooldown: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	before := atomic.LoadInt32(&calls)
+	_, err = guard.ChatCompletion(context.Background(), client, DevelopmentAgent, req)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, before+1, atomic.LoadInt32(&calls))
+}
+
+func TestLLMGuard_Timeouts(t *testing.T) {
+	guard := NewLLMGuard()
+	assert.Equal(t, 120*time.Second, guard.Timeout(DevelopmentAgent))
+	assert.Equal(t, DefaultLLMTimeout, guard.Timeout(IntegrationAgent))
+
+	guard.SetTimeout(CommunicationAgent, 5*time.Second)
+	assert.Equal(t, 5*time.Second, guard.Timeout(CommunicationAgent))
+}
+
+func TestExecuteTracked_FillsUsage(t *testing.T) {
+	var calls int32
+	client := fakeGroq(t, &calls)
+
+	agent := &usageAgent{client: client}
+	result, err := ExecuteTracked(context.Background(), agent, Task{Input: "hi"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "healthy", result.Model)
+	assert.Equal(t, 20, result.PromptTokens)
+	assert.Equal(t, 6, result.CompletionTokens)
+	assert.Equal(t, "stop", result.FinishReason)
+	assert.Equal(t, PromptVersion(usagePrompt), result.PromptVersion)
+	assert.Len(t, result.PromptVersion, 12)
+}
+
+func TestPromptVersion(t *testing.T) {
+	user := []groq.ChatCompletionMessage{{Role: groq.RoleUser, Content: "hi"}}
+	assert.Empty(t, PromptVersion(user))
+
+	v1 := PromptVersion(append([]groq.ChatCompletionMessage{{Role: groq.RoleSystem, Content: "You are terse."}}, user...))
+	v2 := PromptVersion([]groq.ChatCompletionMessage{{Role: groq.RoleSystem, Content: "You are terse."}, {Role: groq.RoleUser, Content: "other"}})
+	v3 := PromptVersion([]groq.ChatCompletionMessage{{Role: groq.RoleSystem, Content: "You are verbose."}})
+	assert.Equal(t, v1, v2, "user input does not change the prompt version")
+	assert.NotEqual(t, v1, v3)
+}
+
+var usagePrompt = []groq.ChatCompletionMessage{{Role: groq.RoleSystem, Content: "You are a test agent."}}
+
+// usageAgent makes two LLM calls without reporting usage itself
+type usageAgent struct {
+	client *groq.Client
+}
+
+func (a *usageAgent) GetType() AgentType            { return AnalysisAgent }
+func (a *usageAgent) GetCapabilities() []Capability { return nil }
+func (a *usageAgent) GetDescription() string        { return "usage test agent" }
+func (a *usageAgent) Execute(ctx context.Context, task Task) (*Result, error) {
+	req := groq.ChatCompletionRequest{
+		Model:    "healthy",
+		Messages: append(usagePrompt, groq.ChatCompletionMessage{Role: "user", Content: task.Input}),
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := ChatCompletion(ctx, a.client, a.GetType(), req); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Success: true}, nil
+}
@@ -0,0 +1,168 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSpillThreshold is the output size, in bytes, beyond which agent
+// outputs are spilled to artifact storage
+const DefaultSpillThreshold = 256 << 10
+
+// SpillPreviewBytes bounds the preview kept in place of a spilled output
+const SpillPreviewBytes = 2 << 10
+
+// SpillKind is the artifact kind spilled outputs are stored as
+const SpillKind = "output"
+
+// OutputRef stands in for an agent output too large to carry around: the
+// full text is stored as an artifact and only its size and start are kept
+type OutputRef struct {
+	Ref     string `json:"ref"`
+	Size    int    `json:"size"`
+	Preview string `json:"preview"`
+}
+
+// Spiller moves outputs larger than its threshold into artifact storage so
+// workflow results, API payloads and events stay bounded
+type Spiller struct {
+	registry  *ArtifactRegistry
+	threshold int
+}
+
+// NewSpiller creates a spiller publishing to registry. A threshold of zero
+// or less disables spilling.
+func NewSpiller(registry *ArtifactRegistry, threshold int) *Spiller {
+	return &Spiller{registry: registry, threshold: threshold}
+}
+
+// Spill returns the output to keep in place and, when output was larger
+// than the threshold, the reference to its full text. A spilled output is
+// replaced by nothing; the reference carries its preview.
+func (s *Spiller) Spill(ctx context.Context, agent AgentType, workflowID uuid.UUID, output string) (string, *OutputRef, error) {
+	if s == nil || s.threshold <= 0 || len(output) <= s.threshold {
+		return output, nil, nil
+	}
+	artifact, err := s.registry.Publish(ctx, agent, SpillKind, workflowID, output, nil)
+	if err != nil {
+		return output, nil, err
+	}
+	return "", &OutputRef{Ref: artifact.Ref(), Size: len(output), Preview: preview(output, SpillPreviewBytes)}, nil
+}
+
+// Load returns the full output a reference points to
+func (s *Spiller) Load(ctx context.Context, ref *OutputRef) (string, error) {
+	artifact, err := s.registry.Get(ctx, ref.Ref)
+	if err != nil {
+		return "", err
+	}
+	return artifact.Content, nil
+}
+
+// preview returns at most n bytes from the start of s, cut at a rune
+// boundary
+func preview(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// FileArtifactStore keeps artifacts under dir: the metadata as <id>.json
+// and the content, referenced by Location, as <id>.txt, so listing lineage
+// does not read every document
+type FileArtifactStore struct {
+	dir string
+}
+
+// NewFileArtifactStore creates a store rooted at dir
+func NewFileArtifactStore(dir string) *FileArtifactStore {
+	return &FileArtifactStore{dir: dir}
+}
+
+// Put writes the artifact
+func (s *FileArtifactStore) Put(ctx context.Context, artifact *Artifact) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	meta := *artifact
+	meta.Content = ""
+	meta.Location = artifact.ID.String() + ".txt"
+	if err := writeFileAtomic(filepath.Join(s.dir, meta.Location), []byte(artifact.Content)); err != nil {
+		return err
+	}
+	data, err := json.Marshal(&meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode artifact: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, artifact.ID.String()+".json"), data)
+}
+
+// Get reads the artifact with id and its content
+func (s *FileArtifactStore) Get(ctx context.Context, id uuid.UUID) (*Artifact, error) {
+	artifact, err := s.meta(filepath.Join(s.dir, id.String()+".json"))
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(s.dir, filepath.Base(artifact.Location)))
+	if err != nil {
+		return nil, err
+	}
+	artifact.Content = string(content)
+	return artifact, nil
+}
+
+// Derived returns artifacts derived from id, without their content
+func (s *FileArtifactStore) Derived(ctx context.Context, id uuid.UUID) ([]*Artifact, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Artifact
+	for _, file := range files {
+		a, err := s.meta(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range a.DerivedFrom {
+			if src == id {
+				out = append(out, a)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *FileArtifactStore) meta(path string) (*Artifact, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrArtifactNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	return &a, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
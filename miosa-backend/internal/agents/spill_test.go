@@ -0,0 +1,71 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpiller_SpillsLargeOutputs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	spiller := NewSpiller(NewArtifactRegistry(NewFileArtifactStore(dir)), 1024)
+	workflowID := uuid.New()
+
+	output, ref, err := spiller.Spill(ctx, AnalysisAgent, workflowID, "small output")
+	require.NoError(t, err)
+	assert.Equal(t, "small output", output)
+	assert.Nil(t, ref)
+
+	// Multi-byte runes straddle the preview limit
+	large := strings.Repeat("é", SpillPreviewBytes)
+	output, ref, err = spiller.Spill(ctx, DevelopmentAgent, workflowID, large)
+	require.NoError(t, err)
+	assert.Empty(t, output, "spilled outputs are not kept in place")
+	require.NotNil(t, ref)
+	assert.Regexp(t, `^artifact://output/[0-9a-f-]{36}$`, ref.Ref)
+	assert.Equal(t, len(large), ref.Size)
+	assert.LessOrEqual(t, len(ref.Preview), SpillPreviewBytes)
+	assert.True(t, utf8.ValidString(ref.Preview))
+	assert.True(t, strings.HasPrefix(large, ref.Preview))
+
+	// A new spiller over the same directory still finds the output
+	reopened := NewSpiller(NewArtifactRegistry(NewFileArtifactStore(dir)), 1024)
+	full, err := reopened.Load(ctx, ref)
+	require.NoError(t, err)
+	assert.Equal(t, large, full)
+
+	_, err = reopened.Load(ctx, &OutputRef{Ref: "artifact://output/" + uuid.New().String()})
+	assert.ErrorIs(t, err, ErrArtifactNotFound)
+}
+
+func TestSpiller_Disabled(t *testing.T) {
+	large := strings.Repeat("x", DefaultSpillThreshold+1)
+	output, ref, err := NewSpiller(NewArtifactRegistry(NewMemoryArtifactStore()), 0).Spill(context.Background(), DevelopmentAgent, uuid.Nil, large)
+	require.NoError(t, err)
+	assert.Equal(t, large, output)
+	assert.Nil(t, ref)
+}
+
+func TestFileArtifactStore_Lineage(t *testing.T) {
+	ctx := context.Background()
+	registry := NewArtifactRegistry(NewFileArtifactStore(t.TempDir()))
+
+	design, err := registry.Publish(ctx, ArchitectAgent, "architecture", uuid.Nil, architectureDoc, nil)
+	require.NoError(t, err)
+	code, err := registry.Publish(ctx, DevelopmentAgent, "", uuid.Nil, "package chat", []uuid.UUID{design.ID})
+	require.NoError(t, err)
+
+	derived, err := registry.Lineage(ctx, design.Ref())
+	require.NoError(t, err)
+	require.Len(t, derived, 1)
+	assert.Equal(t, code.ID, derived[0].ID)
+
+	resolved, _ := registry.Resolve(ctx, design.Ref()+"#data-model")
+	assert.Contains(t, resolved, "partitioned by room")
+}
@@ -0,0 +1,246 @@
+package agents
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+)
+
+// Limits on a stack profile, which is injected into every prompt
+const (
+	MaxProfileItems      = 20
+	MaxProfileItemLength = 200
+)
+
+// ErrTenantNotFound is returned when saving a profile for an unknown tenant
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// StackProfile is a tenant's preferred stack and house conventions. The
+// architect and development agents follow it so generated projects match
+// the tenant's own code. The JSON form is what tenants store under
+// settings.stack_profile.
+type StackProfile struct {
+	Language      string   `json:"language,omitempty"`
+	Framework     string   `json:"framework,omitempty"`
+	ORM           string   `json:"orm,omitempty"`
+	TestFramework string   `json:"test_framework,omitempty"`
+	LintRules     []string `json:"lint_rules,omitempty"`  // e.g. "golangci-lint with errcheck and revive"
+	Naming        []string `json:"naming,omitempty"`      // e.g. "snake_case table and column names"
+	Conventions   []string `json:"conventions,omitempty"` // Anything else, e.g. "errors are wrapped with %w"
+}
+
+// IsZero reports whether the profile states no preferences
+func (p *StackProfile) IsZero() bool {
+	return p == nil || p.Language == "" && p.Framework == "" && p.ORM == "" && p.TestFramework == "" &&
+		len(p.LintRules) == 0 && len(p.Naming) == 0 && len(p.Conventions) == 0
+}
+
+// Validate checks the profile against the size limits
+func (p *StackProfile) Validate() error {
+	for _, field := range []struct{ name, value string }{
+		{"language", p.Language}, {"framework", p.Framework}, {"orm", p.ORM}, {"test_framework", p.TestFramework},
+	} {
+		if len(field.value) > MaxProfileItemLength {
+			return fmt.Errorf("%s must be at most %d characters", field.name, MaxProfileItemLength)
+		}
+	}
+	for _, list := range []struct {
+		name  string
+		items []string
+	}{
+		{"lint_rules", p.LintRules}, {"naming", p.Naming}, {"conventions", p.Conventions},
+	} {
+		if len(list.items) > MaxProfileItems {
+			return fmt.Errorf("%s must have at most %d items", list.name, MaxProfileItems)
+		}
+		for _, item := range list.items {
+			if len(item) > MaxProfileItemLength {
+				return fmt.Errorf("%s items must be at most %d characters", list.name, MaxProfileItemLength)
+			}
+		}
+	}
+	return nil
+}
+
+// Stack lists the profile's framework, ORM and test framework
+func (p *StackProfile) Stack() []string {
+	var stack []string
+	for _, s := range []string{p.Framework, p.ORM, p.TestFramework} {
+		if s != "" {
+			stack = append(stack, s)
+		}
+	}
+	return stack
+}
+
+// Prompt renders the profile as instructions for a system prompt
+func (p *StackProfile) Prompt() string {
+	if p.IsZero() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Follow the team's house style:")
+	for _, field := range []struct{ label, value string }{
+		{"Language", p.Language},
+		{"Framework", p.Framework},
+		{"ORM / data access", p.ORM},
+		{"Test framework", p.TestFramework},
+	} {
+		if field.value != "" {
+			sb.WriteString("\n- " + field.label + ": " + field.value)
+		}
+	}
+	for _, list := range []struct {
+		label string
+		items []string
+	}{
+		{"Lint rules", p.LintRules},
+		{"Naming", p.Naming},
+		{"Conventions", p.Conventions},
+	} {
+		if len(list.items) > 0 {
+			sb.WriteString("\n- " + list.label + ": " + strings.Join(list.items, "; "))
+		}
+	}
+	return sb.String()
+}
+
+// StackProfileStore holds per-tenant stack profiles
+type StackProfileStore interface {
+	// Profile returns the tenant's profile, or nil when it has none
+	Profile(ctx context.Context, tenantID uuid.UUID) (*StackProfile, error)
+	SetProfile(ctx context.Context, tenantID uuid.UUID, profile *StackProfile) error
+}
+
+// MemoryStackProfileStore keeps profiles in memory, for binaries without a
+// database
+type MemoryStackProfileStore struct {
+	profiles map[uuid.UUID]*StackProfile
+	mu       sync.RWMutex
+}
+
+// NewMemoryStackProfileStore creates an empty store
+func NewMemoryStackProfileStore() *MemoryStackProfileStore {
+	return &MemoryStackProfileStore{profiles: make(map[uuid.UUID]*StackProfile)}
+}
+
+// Profile returns the tenant's profile, if any
+func (s *MemoryStackProfileStore) Profile(ctx context.Context, tenantID uuid.UUID) (*StackProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.profiles[tenantID], nil
+}
+
+// SetProfile replaces the tenant's profile
+func (s *MemoryStackProfileStore) SetProfile(ctx context.Context, tenantID uuid.UUID, profile *StackProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles[tenantID] = profile
+	return nil
+}
+
+// PostgresStackProfileStore keeps profiles in the tenants table under
+// settings.stack_profile
+type PostgresStackProfileStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStackProfileStore creates a Postgres-backed store
+func NewPostgresStackProfileStore(db *sql.DB) *PostgresStackProfileStore {
+	return &PostgresStackProfileStore{db: db}
+}
+
+// Profile returns the tenant's profile, if any
+func (s *PostgresStackProfileStore) Profile(ctx context.Context, tenantID uuid.UUID) (*StackProfile, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT settings->'stack_profile' FROM tenants WHERE id = $1`, tenantID,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stack profile: %w", err)
+	}
+	// NULL when the tenant has never saved a profile
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var profile StackProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to decode stack profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// SetProfile replaces the tenant's profile
+func (s *PostgresStackProfileStore) SetProfile(ctx context.Context, tenantID uuid.UUID, profile *StackProfile) error {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to encode stack profile: %w", err)
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE tenants
+		SET settings = jsonb_set(COALESCE(settings, '{}'), '{stack_profile}', $2::jsonb), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		tenantID, data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save stack profile: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// DefaultStackProfiles is the store consulted by WithStackProfile
+var DefaultStackProfiles StackProfileStore = NewMemoryStackProfileStore()
+
+// StackProfileFor returns the tenant's profile from DefaultStackProfiles,
+// or nil when it has none or the store is unavailable
+func StackProfileFor(ctx context.Context, tenantID uuid.UUID) *StackProfile {
+	if DefaultStackProfiles == nil || tenantID == uuid.Nil {
+		return nil
+	}
+	profile, err := DefaultStackProfiles.Profile(ctx, tenantID)
+	if err != nil || profile.IsZero() {
+		return nil
+	}
+	return profile
+}
+
+// TaskStackProfile returns the profile of the task's tenant
+func TaskStackProfile(ctx context.Context, task Task) *StackProfile {
+	if task.Context == nil {
+		return nil
+	}
+	return StackProfileFor(ctx, task.Context.TenantID)
+}
+
+// WithStackProfile appends the task tenant's house style to the first
+// system message, adding one if messages has none. It is left out when the
+// task's enrichment already carries it.
+func WithStackProfile(ctx context.Context, task Task, messages []groq.ChatCompletionMessage) []groq.ChatCompletionMessage {
+	if task.Context != nil && task.Context.Enrichment != nil && task.Context.Enrichment.Preferences != "" {
+		return messages
+	}
+	profile := TaskStackProfile(ctx, task)
+	if profile == nil {
+		return messages
+	}
+
+	out := append([]groq.ChatCompletionMessage(nil), messages...)
+	if len(out) > 0 && out[0].Role == "system" {
+		out[0].Content += "\n\n" + profile.Prompt()
+		return out
+	}
+	return append([]groq.ChatCompletionMessage{{Role: "system", Content: profile.Prompt()}}, out...)
+}
@@ -0,0 +1,92 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var houseStyle = &StackProfile{
+	Language:      "go",
+	Framework:     "chi",
+	ORM:           "sqlc",
+	TestFramework: "testify",
+	Naming:        []string{"snake_case columns", "plural table names"},
+}
+
+func TestStackProfile_Prompt(t *testing.T) {
+	assert.Equal(t, "Follow the team's house style:\n"+
+		"- Language: go\n"+
+		"- Framework: chi\n"+
+		"- ORM / data access: sqlc\n"+
+		"- Test framework: testify\n"+
+		"- Naming: snake_case columns; plural table names", houseStyle.Prompt())
+	assert.Equal(t, []string{"chi", "sqlc", "testify"}, houseStyle.Stack())
+	assert.Empty(t, (&StackProfile{}).Prompt())
+
+	assert.NoError(t, houseStyle.Validate())
+	assert.EqualError(t, (&StackProfile{Conventions: make([]string, MaxProfileItems+1)}).Validate(), "conventions must have at most 20 items")
+	assert.EqualError(t, (&StackProfile{ORM: strings.Repeat("x", MaxProfileItemLength+1)}).Validate(), "orm must be at most 200 characters")
+}
+
+func TestWithStackProfile(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStackProfileStore()
+	defer func(prev StackProfileStore) { DefaultStackProfiles = prev }(DefaultStackProfiles)
+	DefaultStackProfiles = store
+
+	tenant := uuid.New()
+	require.NoError(t, store.SetProfile(ctx, tenant, houseStyle))
+	task := Task{Context: &TaskContext{TenantID: tenant}}
+
+	messages := []groq.ChatCompletionMessage{{Role: "system", Content: "You write code."}, {Role: "user", Content: "Build it"}}
+	got := WithStackProfile(ctx, task, messages)
+	assert.Equal(t, "You write code.\n\n"+houseStyle.Prompt(), got[0].Content)
+	assert.Equal(t, "You write code.", messages[0].Content, "the caller's messages are not modified")
+
+	got = WithStackProfile(ctx, task, messages[1:])
+	require.Len(t, got, 2)
+	assert.Equal(t, groq.ChatCompletionMessage{Role: "system", Content: houseStyle.Prompt()}, got[0])
+
+	other := Task{Context: &TaskContext{TenantID: uuid.New()}}
+	assert.Equal(t, messages, WithStackProfile(ctx, other, messages))
+	assert.Equal(t, messages, WithStackProfile(ctx, Task{}, messages))
+}
+
+func TestPostgresStackProfileStore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewPostgresStackProfileStore(db)
+	tenant := uuid.New()
+
+	mock.ExpectExec("UPDATE tenants").
+		WithArgs(tenant, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.SetProfile(ctx, tenant, houseStyle))
+
+	mock.ExpectExec("UPDATE tenants").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, store.SetProfile(ctx, uuid.New(), houseStyle), ErrTenantNotFound)
+
+	mock.ExpectQuery("SELECT settings->'stack_profile' FROM tenants").
+		WithArgs(tenant).
+		WillReturnRows(sqlmock.NewRows([]string{"stack_profile"}).AddRow([]byte(`{"language":"go","orm":"sqlc"}`)))
+	profile, err := store.Profile(ctx, tenant)
+	require.NoError(t, err)
+	assert.Equal(t, &StackProfile{Language: "go", ORM: "sqlc"}, profile)
+
+	mock.ExpectQuery("SELECT settings->'stack_profile' FROM tenants").
+		WillReturnRows(sqlmock.NewRows([]string{"stack_profile"}).AddRow(nil))
+	profile, err = store.Profile(ctx, tenant)
+	require.NoError(t, err)
+	assert.Nil(t, profile)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
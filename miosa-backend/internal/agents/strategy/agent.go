@@ -34,6 +34,11 @@ func (a *StrategyAgent) GetDescription() string {
 	return "Develops strategic plans and roadmaps"
 }
 
+// Model returns the model the agent calls
+func (a *StrategyAgent) Model() string {
+	return a.config.Model
+}
+
 func (a *StrategyAgent) GetCapabilities() []agents.Capability {
 	return []agents.Capability{
 		{Name: "planning", Description: "Strategic planning", Required: true},
@@ -0,0 +1,74 @@
+package agents
+
+import (
+	"context"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+)
+
+// TranscriptMessage is one message of a prompt
+type TranscriptMessage struct {
+	Role    string `json:"role"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+// TranscriptEntry is one LLM call: what the model was asked and what it
+// answered, or why the call failed
+type TranscriptEntry struct {
+	Agent            AgentType           `json:"agent"`
+	Target           string              `json:"target"` // provider:model called
+	Messages         []TranscriptMessage `json:"messages"`
+	Response         string              `json:"response,omitempty"`
+	FinishReason     string              `json:"finish_reason,omitempty"`
+	PromptTokens     int                 `json:"prompt_tokens,omitempty"`
+	CompletionTokens int                 `json:"completion_tokens,omitempty"`
+	Error            string              `json:"error,omitempty"`
+	At               time.Time           `json:"at"`
+	DurationMS       int64               `json:"duration_ms"`
+}
+
+// TranscriptRecorder receives every LLM call made under a context carrying it
+type TranscriptRecorder interface {
+	Record(entry TranscriptEntry)
+}
+
+type transcriptKey struct{}
+
+// WithTranscript returns a context under which every ChatCompletion
+// attempt, failed or not, is recorded with recorder
+func WithTranscript(ctx context.Context, recorder TranscriptRecorder) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, recorder)
+}
+
+// TranscriptFromContext returns the recorder installed by WithTranscript, if any
+func TranscriptFromContext(ctx context.Context) TranscriptRecorder {
+	r, _ := ctx.Value(transcriptKey{}).(TranscriptRecorder)
+	return r
+}
+
+// newTranscriptEntry records a call to target that started at start
+func newTranscriptEntry(agent AgentType, target Target, req groq.ChatCompletionRequest, resp groq.ChatCompletionResponse, err error, start time.Time) TranscriptEntry {
+	entry := TranscriptEntry{
+		Agent:      agent,
+		Target:     target.String(),
+		Messages:   make([]TranscriptMessage, 0, len(req.Messages)),
+		At:         start.UTC(),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	for _, m := range req.Messages {
+		entry.Messages = append(entry.Messages, TranscriptMessage{Role: string(m.Role), Name: m.Name, Content: m.Content})
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if len(resp.Choices) > 0 {
+		entry.Response = resp.Choices[0].Message.Content
+		entry.FinishReason = string(resp.Choices[0].FinishReason)
+	}
+	entry.PromptTokens = resp.Usage.PromptTokens
+	entry.CompletionTokens = resp.Usage.CompletionTokens
+	return entry
+}
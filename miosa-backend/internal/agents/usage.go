@@ -0,0 +1,169 @@
+package agents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+)
+
+// Usage accumulates token usage across the LLM calls of one agent execution
+type Usage struct {
+	Model             string `json:"model,omitempty"`
+	PromptTokens      int    `json:"prompt_tokens"`
+	CompletionTokens  int    `json:"completion_tokens"`
+	FinishReason      string `json:"finish_reason,omitempty"`
+	PromptVersion     string `json:"prompt_version,omitempty"`
+	Calls             int    `json:"calls"`
+	PromptTokensSaved int    `json:"prompt_tokens_saved,omitempty"` // By prompt compression
+	parent            *Usage
+	mu                sync.Mutex
+
+	// Failovers made between the execution's LLM targets
+	Failovers []FailoverEvent `json:"failovers,omitempty"`
+}
+
+type usageKey struct{}
+
+// TrackUsage returns a context under which every ChatCompletion call adds
+// its usage to the returned tracker. Usage also rolls up into any tracker
+// already installed in ctx.
+func TrackUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{parent: UsageFromContext(ctx)}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// UsageFromContext returns the tracker installed by TrackUsage, if any
+func UsageFromContext(ctx context.Context) *Usage {
+	u, _ := ctx.Value(usageKey{}).(*Usage)
+	return u
+}
+
+// Add records one completion response
+func (u *Usage) Add(resp groq.ChatCompletionResponse) {
+	if u.parent != nil {
+		u.parent.Add(resp)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.Calls++
+	u.PromptTokens += resp.Usage.PromptTokens
+	u.CompletionTokens += resp.Usage.CompletionTokens
+	if resp.Model != "" {
+		u.Model = string(resp.Model)
+	}
+	if len(resp.Choices) > 0 {
+		u.FinishReason = string(resp.Choices[0].FinishReason)
+	}
+}
+
+// AddSaved records prompt tokens compression saved on a call
+func (u *Usage) AddSaved(tokens int) {
+	if u.parent != nil {
+		u.parent.AddSaved(tokens)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.PromptTokensSaved += tokens
+}
+
+// AddPrompt records the version of the system prompt a request was sent with
+func (u *Usage) AddPrompt(messages []groq.ChatCompletionMessage) {
+	if u.parent != nil {
+		u.parent.AddPrompt(messages)
+	}
+	version := PromptVersion(messages)
+	if version == "" {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.PromptVersion = version
+}
+
+// PromptVersion fingerprints the system messages of a request, so that output
+// can be traced to the prompt that produced it. It is empty when there are none.
+func PromptVersion(messages []groq.ChatCompletionMessage) string {
+	h := sha256.New()
+	found := false
+	for _, m := range messages {
+		if m.Role == groq.RoleSystem {
+			h.Write([]byte(m.Content))
+			h.Write([]byte{0})
+			found = true
+		}
+	}
+	if !found {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// ApplyTo fills the result's usage fields unless the agent already set them
+func (u *Usage) ApplyTo(result *Result) {
+	if u == nil || result == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(result.Failovers) == 0 {
+		result.Failovers = append([]FailoverEvent(nil), u.Failovers...)
+	}
+	if u.Calls == 0 {
+		return
+	}
+	if result.PromptTokensSaved == 0 {
+		result.PromptTokensSaved = u.PromptTokensSaved
+	}
+	if result.PromptVersion == "" {
+		result.PromptVersion = u.PromptVersion
+	}
+	if result.PromptTokens > 0 || result.CompletionTokens > 0 {
+		return
+	}
+	result.PromptTokens = u.PromptTokens
+	result.CompletionTokens = u.CompletionTokens
+	if result.Model == "" {
+		result.Model = u.Model
+	}
+	if result.FinishReason == "" {
+		result.FinishReason = u.FinishReason
+	}
+}
+
+// AddUsage adds a completion response's usage to the result
+func (r *Result) AddUsage(resp groq.ChatCompletionResponse) {
+	r.PromptTokens += resp.Usage.PromptTokens
+	r.CompletionTokens += resp.Usage.CompletionTokens
+	if resp.Model != "" {
+		r.Model = string(resp.Model)
+	}
+	if len(resp.Choices) > 0 {
+		r.FinishReason = string(resp.Choices[0].FinishReason)
+	}
+}
+
+// TotalTokens returns prompt plus completion tokens
+func (r *Result) TotalTokens() int {
+	return r.PromptTokens + r.CompletionTokens
+}
+
+// ExecuteTracked runs the agent and fills the result's usage from every LLM
+// call made through ChatCompletion during the execution, tagged with the
+// request ID in ctx
+func ExecuteTracked(ctx context.Context, agent Agent, task Task) (*Result, error) {
+	ctx, usage := TrackUsage(ctx)
+	result, err := agent.Execute(ctx, task)
+	usage.ApplyTo(result)
+	if result != nil && result.RequestID == "" {
+		result.RequestID = requestid.FromContext(ctx)
+	}
+	return result, err
+}
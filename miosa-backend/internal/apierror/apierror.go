@@ -0,0 +1,205 @@
+// Package apierror classifies errors into a small taxonomy and renders them
+// as RFC 7807 problem+json responses with matching zap log fields, so every
+// service reports failures the same way.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/conneroisu/groq-go/pkg/groqerr"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// Category is the kind of failure, stable across services and used by
+// clients to decide whether to retry
+type Category string
+
+const (
+	CategoryValidation          Category = "validation"
+	CategoryUnauthorized        Category = "unauthorized"
+	CategoryForbidden           Category = "forbidden" // Authenticated, but not allowed to do this
+	CategoryNotFound            Category = "not_found"
+	CategoryConflict            Category = "conflict"
+	CategoryProviderUnavailable Category = "provider_unavailable" // The model provider is down, rate limited or every circuit is open
+	CategoryBudgetExceeded      Category = "budget_exceeded"      // A token, cost or provider quota ran out
+	CategorySandboxFailure      Category = "sandbox_failure"      // The E2B sandbox could not build or run the project
+	CategoryUnavailable         Category = "unavailable"          // The service is draining or not configured
+	CategoryRejected            Category = "content_rejected"     // The request breaks the content policy
+	CategoryInternal            Category = "internal"
+)
+
+// categoryInfo is how a category is presented to clients
+type categoryInfo struct {
+	status int
+	title  string
+	retry  bool // Whether the response carries Retry-After
+}
+
+var categories = map[Category]categoryInfo{
+	CategoryValidation:          {http.StatusBadRequest, "Invalid request", false},
+	CategoryUnauthorized:        {http.StatusUnauthorized, "Authentication required", false},
+	CategoryForbidden:           {http.StatusForbidden, "Permission denied", false},
+	CategoryNotFound:            {http.StatusNotFound, "Resource not found", false},
+	CategoryConflict:            {http.StatusConflict, "Conflicting request", false},
+	CategoryProviderUnavailable: {http.StatusServiceUnavailable, "Model provider unavailable", true},
+	CategoryBudgetExceeded:      {http.StatusPaymentRequired, "Budget exceeded", false},
+	CategorySandboxFailure:      {http.StatusBadGateway, "Sandbox failure", false},
+	CategoryUnavailable:         {http.StatusServiceUnavailable, "Service unavailable", true},
+	CategoryRejected:            {http.StatusUnprocessableEntity, "Request rejected by content policy", false},
+	CategoryInternal:            {http.StatusInternalServerError, "Internal error", false},
+}
+
+// Status returns the HTTP status used for the category
+func (c Category) Status() int {
+	if info, ok := categories[c]; ok {
+		return info.status
+	}
+	return http.StatusInternalServerError
+}
+
+// FieldError describes one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Violation is the content policy a rejected request broke, and how to
+// appeal the decision
+type Violation struct {
+	Category   string `json:"category"`
+	Reason     string `json:"reason,omitempty"`
+	DecisionID string `json:"decision_id"`
+	Appeal     string `json:"appeal,omitempty"` // URL or path to request a review
+}
+
+// Error is a categorized error. Message is shown to clients; Err is the
+// underlying cause, which is only logged.
+type Error struct {
+	Category  Category
+	Message   string
+	Fields    []FieldError
+	Violation *Violation
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error of the given category
+func New(category Category, message string) *Error {
+	return &Error{Category: category, Message: message}
+}
+
+// Newf creates an error of the given category with a formatted message
+func Newf(category Category, format string, args ...interface{}) *Error {
+	return New(category, fmt.Sprintf(format, args...))
+}
+
+// Wrap categorizes err, showing message to clients in its place
+func Wrap(category Category, message string, err error) *Error {
+	return &Error{Category: category, Message: message, Err: err}
+}
+
+// Provider categorizes a failed model call: quota errors exceed the
+// budget and anything else leaves the provider unavailable
+func Provider(message string, err error) *Error {
+	category := CategoryProviderUnavailable
+	if CategoryOf(err) == CategoryBudgetExceeded {
+		category = CategoryBudgetExceeded
+	}
+	return Wrap(category, message, err)
+}
+
+// Invalid creates a validation error listing the offending fields
+func Invalid(message string, fields ...FieldError) *Error {
+	return &Error{Category: CategoryValidation, Message: message, Fields: fields}
+}
+
+// Rejected creates an error for a request the content policy refuses
+func Rejected(message string, v Violation) *Error {
+	return &Error{Category: CategoryRejected, Message: message, Violation: &v}
+}
+
+// sentinels maps errors from other packages onto the taxonomy
+var sentinels = []struct {
+	err      error
+	category Category
+}{
+	{agents.ErrCircuitOpen, CategoryProviderUnavailable},
+	{agents.ErrDraining, CategoryUnavailable},
+	{agents.ErrNoCheckpoints, CategoryNotFound},
+	{agents.ErrTenantNotFound, CategoryNotFound},
+	{agents.ErrArtifactNotFound, CategoryNotFound},
+}
+
+// CategoryOf classifies err. Errors that are not an *Error are matched
+// against known sentinels and model provider responses, and are otherwise
+// internal.
+func CategoryOf(err error) Category {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Category
+	}
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return s.category
+		}
+	}
+	if category, ok := providerCategory(err); ok {
+		return category
+	}
+	return CategoryInternal
+}
+
+// From returns err as an *Error, classifying it if needed
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	category := CategoryOf(err)
+	e := &Error{Category: category, Err: err}
+	if category != CategoryInternal {
+		// Sentinel and provider messages are safe to show
+		e.Message = err.Error()
+	}
+	return e
+}
+
+// providerCategory classifies errors returned by the Groq API. Quota
+// errors exhaust the budget; rate limits and server errors make the
+// provider unavailable.
+func providerCategory(err error) (Category, bool) {
+	status, detail := 0, ""
+	var apiErr *groqerr.APIError
+	var reqErr *groqerr.ErrRequest
+	switch {
+	case errors.As(err, &apiErr):
+		status, detail = apiErr.HTTPStatusCode, apiErr.Type+" "+fmt.Sprint(apiErr.Code)
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	default:
+		return "", false
+	}
+	switch {
+	case strings.Contains(detail, "quota"):
+		return CategoryBudgetExceeded, true
+	case status == http.StatusTooManyRequests || status >= 500:
+		return CategoryProviderUnavailable, true
+	}
+	return "", false
+}
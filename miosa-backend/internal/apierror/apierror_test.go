@@ -0,0 +1,115 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conneroisu/groq-go/pkg/groqerr"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestCategoryOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"categorized", New(CategorySandboxFailure, "build failed"), CategorySandboxFailure},
+		{"wrapped", fmt.Errorf("step 3: %w", Invalid("bad input")), CategoryValidation},
+		{"circuit open", fmt.Errorf("llama3: %w", agents.ErrCircuitOpen), CategoryProviderUnavailable},
+		{"draining", agents.ErrDraining, CategoryUnavailable},
+		{"no checkpoints", agents.ErrNoCheckpoints, CategoryNotFound},
+		{"rate limited", &groqerr.APIError{HTTPStatusCode: 429, Type: "tokens"}, CategoryProviderUnavailable},
+		{"quota", &groqerr.APIError{HTTPStatusCode: 429, Code: "insufficient_quota"}, CategoryBudgetExceeded},
+		{"provider down", &groqerr.ErrRequest{HTTPStatusCode: 502}, CategoryProviderUnavailable},
+		{"bad model request", &groqerr.APIError{HTTPStatusCode: 400}, CategoryInternal},
+		{"unknown", errors.New("boom"), CategoryInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CategoryOf(tt.err))
+		})
+	}
+
+	assert.Equal(t, CategoryBudgetExceeded, Provider("chat failed", &groqerr.APIError{Code: "insufficient_quota"}).Category)
+	assert.Equal(t, CategoryProviderUnavailable, Provider("chat failed", errors.New("timeout")).Category)
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/orchestrate", nil)
+	Write(w, r, Invalid("invalid request", FieldError{Field: "description", Message: "is required"}))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	var problem Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+	assert.Equal(t, Problem{
+		Type:     "urn:miosa:error:validation",
+		Title:    "Invalid request",
+		Status:   http.StatusBadRequest,
+		Detail:   "invalid request",
+		Instance: "/api/orchestrate",
+		Category: CategoryValidation,
+		Fields:   []FieldError{{Field: "description", Message: "is required"}},
+	}, problem)
+
+	w = httptest.NewRecorder()
+	Write(w, r, fmt.Errorf("agent: %w", agents.ErrCircuitOpen))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, RetryAfter, w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	Write(w, nil, Wrap(CategoryInternal, "Failed to load session", errors.New("pq: password authentication failed")))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), `"detail":"Failed to load session"`)
+	assert.NotContains(t, w.Body.String(), "pq:", "causes stay in the logs")
+	assert.NotContains(t, w.Body.String(), "instance")
+
+	w = httptest.NewRecorder()
+	Write(w, nil, errors.New("pq: password authentication failed"))
+	assert.NotContains(t, w.Body.String(), "pq:")
+
+	w = httptest.NewRecorder()
+	Write(w, r, Rejected("request refused", Violation{Category: "malware", DecisionID: "d1", Appeal: "/appeals/d1"}))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"violation":{"category":"malware","decision_id":"d1","appeal":"/appeals/d1"}`)
+}
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	reached := false
+	router.GET("/sessions/:id", func(c *gin.Context) {
+		Abort(c, New(CategoryNotFound, "Session not found"))
+	}, func(c *gin.Context) { reached = true })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sessions/1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"category":"not_found"`)
+	assert.False(t, reached)
+}
+
+func TestFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Error("Workflow failed", Fields(Wrap(CategorySandboxFailure, "sandbox unreachable", errors.New("connection refused")), zap.String("workflow_id", "w1"))...)
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "sandbox_failure", fields["error_category"])
+	assert.Equal(t, int64(http.StatusBadGateway), fields["status"])
+	assert.Equal(t, "sandbox unreachable: connection refused", fields["error"])
+	assert.Equal(t, "w1", fields["workflow_id"])
+}
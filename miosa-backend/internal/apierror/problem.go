@@ -0,0 +1,87 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// RetryAfter is the Retry-After value, in seconds, sent with categories
+// that are expected to recover
+const RetryAfter = "30"
+
+// Problem is an RFC 7807 problem details object. Category, Fields and
+// Violation are extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Category  Category     `json:"category"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	Violation *Violation   `json:"violation,omitempty"`
+}
+
+// NewProblem describes err for clients. Internal errors keep their cause
+// out of the response; it belongs in the logs.
+func NewProblem(err error, instance string) *Problem {
+	e := From(err)
+	info, ok := categories[e.Category]
+	if !ok {
+		info = categories[CategoryInternal]
+	}
+	detail := e.Message
+	if detail == "" && e.Category != CategoryInternal && e.Err != nil {
+		detail = e.Err.Error()
+	}
+	return &Problem{
+		Type:      "urn:miosa:error:" + string(e.Category),
+		Title:     info.title,
+		Status:    info.status,
+		Detail:    detail,
+		Instance:  instance,
+		Category:  e.Category,
+		Fields:    e.Fields,
+		Violation: e.Violation,
+	}
+}
+
+// Write serves err as a problem+json response. r may be nil, leaving the
+// problem without an instance.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	instance := ""
+	if r != nil {
+		instance = r.URL.Path
+	}
+	problem := NewProblem(err, instance)
+	if categories[problem.Category].retry {
+		w.Header().Set("Retry-After", RetryAfter)
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// Abort serves err as a problem+json response and stops the gin handler
+// chain
+func Abort(c *gin.Context, err error) {
+	Write(c.Writer, c.Request, err)
+	c.Abort()
+}
+
+// Fields returns the zap fields logged with err: its category, status and
+// cause, followed by extra
+func Fields(err error, extra ...zap.Field) []zap.Field {
+	category := CategoryOf(err)
+	return append([]zap.Field{
+		zap.String("error_category", string(category)),
+		zap.Int("status", category.Status()),
+		zap.Error(err),
+	}, extra...)
+}
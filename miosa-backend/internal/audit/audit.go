@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+)
+
+// Action identifies an audited orchestration operation
+type Action string
+
+const (
+	ActionOrchestrate       Action = "orchestrate.request"
+	ActionApproval          Action = "approval.decision"
+	ActionImprovementApply  Action = "improvement.apply"
+	ActionDeploymentTrigger Action = "deployment.trigger"
+	ActionFileWrite         Action = "file.write"
+	ActionRepositoryIngest  Action = "repository.ingest"
+	ActionAgentRegister     Action = "agent.register"
+	ActionAgentDeregister   Action = "agent.deregister"
+	ActionPatternImport     Action = "pattern.import"
+	ActionGitHubPullRequest Action = "github.pull_request"
+	ActionTranscriptExport  Action = "transcript.export"
+	ActionFlagUpdate        Action = "flag.update"
+	ActionModeration        Action = "moderation.decision"
+	ActionModerationAppeal  Action = "moderation.appeal"
+	ActionScheduleUpdate    Action = "schedule.update"
+)
+
+// Actor types recorded with each event
+const (
+	ActorUser      = "user"
+	ActorAPIKey    = "api_key"
+	ActorSystem    = "system"
+	ActorAnonymous = "anonymous"
+)
+
+// Event statuses
+const (
+	StatusSuccess  = "success"
+	StatusFailure  = "failure"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Event is a single append-only audit record
+type Event struct {
+	ID          uuid.UUID         `json:"id"`
+	TenantID    uuid.UUID         `json:"tenant_id,omitempty"`
+	WorkflowID  uuid.UUID         `json:"workflow_id,omitempty"`
+	Actor       string            `json:"actor"`
+	ActorType   string            `json:"actor_type"`
+	Action      Action            `json:"action"`
+	Resource    string            `json:"resource,omitempty"`
+	RequestHash string            `json:"request_hash,omitempty"`
+	Status      string            `json:"status"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Filter selects events for compliance review. Zero values match everything.
+type Filter struct {
+	TenantID   uuid.UUID
+	WorkflowID uuid.UUID
+	Actor      string
+	Action     Action
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// Store persists audit events. Implementations must never modify or delete
+// events once appended.
+type Store interface {
+	Append(ctx context.Context, event *Event) error
+	Query(ctx context.Context, filter Filter) ([]*Event, error)
+}
+
+// Log records events to a store. A nil *Log is valid and records nothing,
+// so callers don't need to guard every call site.
+type Log struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// New creates an audit log backed by store
+func New(store Store, logger *zap.Logger) *Log {
+	return &Log{store: store, logger: logger}
+}
+
+// Record appends an event, filling in its ID, timestamp and defaults.
+// Failures are logged rather than returned so auditing never breaks the
+// operation being audited.
+func (l *Log) Record(ctx context.Context, event Event) {
+	if l == nil || l.store == nil {
+		return
+	}
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	if event.Actor == "" {
+		event.Actor = ActorAnonymous
+	}
+	if event.ActorType == "" {
+		event.ActorType = ActorAnonymous
+	}
+	if event.Status == "" {
+		event.Status = StatusSuccess
+	}
+	if id := requestid.FromContext(ctx); id != "" && event.Metadata["request_id"] == "" {
+		metadata := map[string]string{"request_id": id}
+		for k, v := range event.Metadata {
+			metadata[k] = v
+		}
+		event.Metadata = metadata
+	}
+
+	if err := l.store.Append(ctx, &event); err != nil && l.logger != nil {
+		l.logger.Error("Failed to record audit event",
+			zap.String("action", string(event.Action)),
+			zap.String("actor", event.Actor),
+			zap.Error(err))
+	}
+}
+
+// Query returns events matching filter, newest first
+func (l *Log) Query(ctx context.Context, filter Filter) ([]*Event, error) {
+	if l == nil || l.store == nil {
+		return nil, nil
+	}
+	if filter.Limit <= 0 || filter.Limit > 1000 {
+		filter.Limit = 100
+	}
+	return l.store.Query(ctx, filter)
+}
+
+// HashRequest returns the hex SHA-256 of a request payload. Byte slices and
+// strings are hashed as-is; anything else is hashed as JSON.
+func HashRequest(v interface{}) string {
+	var data []byte
+	switch p := v.(type) {
+	case []byte:
+		data = p
+	case string:
+		data = []byte(p)
+	default:
+		var err error
+		if data, err = json.Marshal(p); err != nil {
+			return ""
+		}
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// matches reports whether event satisfies filter
+func (f Filter) matches(e *Event) bool {
+	if f.TenantID != uuid.Nil && e.TenantID != f.TenantID {
+		return false
+	}
+	if f.WorkflowID != uuid.Nil && e.WorkflowID != f.WorkflowID {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if !f.From.IsZero() && e.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.CreatedAt.After(f.To) {
+		return false
+	}
+	return true
+}
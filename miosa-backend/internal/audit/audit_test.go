@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+)
+
+func TestLog_RecordAndQuery(t *testing.T) {
+	ctx := context.Background()
+	log := New(NewMemoryStore(), zap.NewNop())
+	workflowID := uuid.New()
+
+	log.Record(ctx, Event{Actor: "alice", ActorType: ActorUser, Action: ActionOrchestrate, WorkflowID: workflowID})
+	log.Record(ctx, Event{Actor: "alice", ActorType: ActorUser, Action: ActionFileWrite, WorkflowID: workflowID, Resource: "main.go"})
+	log.Record(ctx, Event{Action: ActionImprovementApply})
+
+	events, err := log.Query(ctx, Filter{Actor: "alice"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, ActionFileWrite, events[0].Action, "newest first")
+	assert.NotEqual(t, uuid.Nil, events[0].ID)
+	assert.Equal(t, StatusSuccess, events[0].Status)
+
+	events, err = log.Query(ctx, Filter{Action: ActionImprovementApply})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, ActorAnonymous, events[0].Actor)
+
+	events, err = log.Query(ctx, Filter{From: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestLog_RecordsRequestID(t *testing.T) {
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	log := New(NewMemoryStore(), zap.NewNop())
+	meta := map[string]string{"outcome": "written"}
+	log.Record(ctx, Event{Action: ActionFileWrite, Metadata: meta})
+
+	events, err := log.Query(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]string{"outcome": "written", "request_id": "req-1"}, events[0].Metadata)
+	assert.Len(t, meta, 1, "the caller's metadata is not modified")
+}
+
+func TestLog_NilIsNoop(t *testing.T) {
+	var log *Log
+	log.Record(context.Background(), Event{Action: ActionOrchestrate})
+	events, err := log.Query(context.Background(), Filter{})
+	assert.NoError(t, err)
+	assert.Nil(t, events)
+}
+
+func TestHashRequest(t *testing.T) {
+	assert.Equal(t, HashRequest("abc"), HashRequest([]byte("abc")))
+	assert.Len(t, HashRequest(map[string]string{"a": "b"}), 64)
+	assert.NotEqual(t, HashRequest("a"), HashRequest("b"))
+}
+
+func TestFilterFromQuery(t *testing.T) {
+	wf := uuid.New()
+	filter, err := FilterFromQuery(url.Values{
+		"actor":    {"bob"},
+		"workflow": {wf.String()},
+		"from":     {"2026-01-01T00:00:00Z"},
+		"limit":    {"5"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", filter.Actor)
+	assert.Equal(t, wf, filter.WorkflowID)
+	assert.Equal(t, 2026, filter.From.Year())
+	assert.Equal(t, 5, filter.Limit)
+
+	_, err = FilterFromQuery(url.Values{"from": {"yesterday"}})
+	assert.Error(t, err)
+}
+
+func TestQueryHandler_LimitsToCallerTenant(t *testing.T) {
+	ctx := context.Background()
+	log := New(NewMemoryStore(), zap.NewNop())
+	mine, other := uuid.New(), uuid.New()
+	log.Record(ctx, Event{Actor: "alice", Action: ActionOrchestrate, TenantID: mine})
+	log.Record(ctx, Event{Actor: "mallory", Action: ActionOrchestrate, TenantID: other})
+
+	handler := QueryHandler(log, func(*http.Request) uuid.UUID { return mine })
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/audit?tenant="+other.String(), nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Events []*Event `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Events, 1)
+	assert.Equal(t, "alice", body.Events[0].Actor)
+
+	handler = QueryHandler(log, func(*http.Request) uuid.UUID { return uuid.Nil })
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/audit", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// FilterFromQuery parses actor, tenant, workflow, action, from, to and
+// limit query parameters. Times are RFC 3339.
+func FilterFromQuery(params url.Values) (Filter, error) {
+	filter := Filter{
+		Actor:  params.Get("actor"),
+		Action: Action(params.Get("action")),
+	}
+
+	var err error
+	if v := params.Get("tenant"); v != "" {
+		if filter.TenantID, err = uuid.Parse(v); err != nil {
+			return filter, fmt.Errorf("invalid tenant: %w", err)
+		}
+	}
+	if v := params.Get("workflow"); v != "" {
+		if filter.WorkflowID, err = uuid.Parse(v); err != nil {
+			return filter, fmt.Errorf("invalid workflow: %w", err)
+		}
+	}
+	if v := params.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	filter.Limit, _ = strconv.Atoi(params.Get("limit"))
+	return filter, nil
+}
+
+// QueryHandler serves GET /api/audit?actor=&workflow=&action=&from=&to=&limit=
+// for the tenant tenantOf returns for the caller, whatever tenant the query
+// names. Callers without a tenant are refused.
+func QueryHandler(l *Log, tenantOf func(*http.Request) uuid.UUID) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := FilterFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.TenantID = tenantOf(r)
+		if filter.TenantID == uuid.Nil {
+			http.Error(w, "caller has no tenant", http.StatusForbidden)
+			return
+		}
+
+		events, err := l.Query(r.Context(), filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"events": events,
+			"count":  len(events),
+		})
+	}
+}
+
+// ActorFromRequest identifies the caller of an unauthenticated endpoint
+// from the X-User-ID header, falling back to the remote address
+func ActorFromRequest(r *http.Request) (actor, actorType string) {
+	if id := r.Header.Get("X-User-ID"); id != "" {
+		return id, ActorUser
+	}
+	return r.RemoteAddr, ActorAnonymous
+}
+
+// FromTaskContext fills the actor and tenant of event from an authenticated
+// task context, as set by the gateway auth middleware
+func FromTaskContext(event Event, tc *agents.TaskContext) Event {
+	if tc == nil {
+		return event
+	}
+	event.TenantID = tc.TenantID
+	if id := tc.Metadata["api_key_id"]; id != "" {
+		event.Actor, event.ActorType = id, ActorAPIKey
+		return event
+	}
+	if tc.UserID != uuid.Nil {
+		event.Actor, event.ActorType = tc.UserID.String(), ActorUser
+	}
+	return event
+}
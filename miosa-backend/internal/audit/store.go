@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps events in process, for binaries without a database
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []*Event
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Append adds a copy of event
+func (s *MemoryStore) Append(ctx context.Context, event *Event) error {
+	e := *event
+	s.mu.Lock()
+	s.events = append(s.events, &e)
+	s.mu.Unlock()
+	return nil
+}
+
+// Query returns matching events, newest first
+func (s *MemoryStore) Query(ctx context.Context, filter Filter) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Event
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if !filter.matches(s.events[i]) {
+			continue
+		}
+		e := *s.events[i]
+		out = append(out, &e)
+		if filter.Limit > 0 && len(out) >= filter.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// PostgresStore writes events to the append-only orchestration_audit table
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a Postgres-backed store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Append inserts event
+func (s *PostgresStore) Append(ctx context.Context, event *Event) error {
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO orchestration_audit (id, tenant_id, workflow_id, actor, actor_type,
+			action, resource, request_hash, status, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		event.ID, nullUUID(event.TenantID), nullUUID(event.WorkflowID), event.Actor, event.ActorType,
+		string(event.Action), event.Resource, event.RequestHash, event.Status, metadata, event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return nil
+}
+
+// Query returns matching events, newest first
+func (s *PostgresStore) Query(ctx context.Context, filter Filter) ([]*Event, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if filter.TenantID != uuid.Nil {
+		add("tenant_id = $%d", filter.TenantID)
+	}
+	if filter.WorkflowID != uuid.Nil {
+		add("workflow_id = $%d", filter.WorkflowID)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action = $%d", string(filter.Action))
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at <= $%d", filter.To)
+	}
+
+	query := `SELECT id, tenant_id, workflow_id, actor, actor_type, action, resource,
+		request_hash, status, metadata, created_at FROM orchestration_audit`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		var (
+			e          Event
+			tenantID   uuid.NullUUID
+			workflowID uuid.NullUUID
+			action     string
+			resource   sql.NullString
+			hash       sql.NullString
+			metadata   []byte
+		)
+		if err := rows.Scan(&e.ID, &tenantID, &workflowID, &e.Actor, &e.ActorType, &action,
+			&resource, &hash, &e.Status, &metadata, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		e.TenantID = tenantID.UUID
+		e.WorkflowID = workflowID.UUID
+		e.Action = Action(action)
+		e.Resource = resource.String
+		e.RequestHash = hash.String
+		if len(metadata) > 0 {
+			_ = json.Unmarshal(metadata, &e.Metadata)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func nullUUID(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,106 @@
+// Package catalog serves the agent and command metadata frontends list.
+// Listings are built once, sorted so the same registries always produce the
+// same bytes, and versioned by a hash of their content, which is also the
+// ETag. Clients revalidate with If-None-Match and diff catalogs by version.
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/claude"
+)
+
+// Agent describes a registered agent
+type Agent struct {
+	Type         agents.AgentType    `json:"type"`
+	Description  string              `json:"description"`
+	Capabilities []agents.Capability `json:"capabilities"`
+}
+
+// Parameter is the schema of a command parameter
+type Parameter struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"` // string, int, bool or enum
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Values      []string    `json:"values,omitempty"` // Enum values
+}
+
+// Command describes a slash command
+type Command struct {
+	Command     claude.CommandType `json:"command"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Agent       agents.AgentType   `json:"agent,omitempty"`
+	Actions     []string           `json:"actions,omitempty"`
+	Parameters  []Parameter        `json:"parameters,omitempty"`
+	Examples    []string           `json:"examples,omitempty"`
+	Icon        string             `json:"icon,omitempty"`
+}
+
+// Catalog is every agent and command, with the version of its content
+type Catalog struct {
+	Version  string    `json:"version"`
+	Agents   []Agent   `json:"agents"`
+	Commands []Command `json:"commands"`
+}
+
+// Build lists the agents of registry and the commands of commands, which
+// may be nil, sorted by type and name
+func Build(registry map[agents.AgentType]agents.Agent, commands *claude.CommandRegistry) *Catalog {
+	c := &Catalog{Agents: make([]Agent, 0, len(registry)), Commands: []Command{}}
+	for agentType, agent := range registry {
+		caps := append([]agents.Capability(nil), agent.GetCapabilities()...)
+		sort.SliceStable(caps, func(i, j int) bool { return caps[i].Name < caps[j].Name })
+		c.Agents = append(c.Agents, Agent{Type: agentType, Description: agent.GetDescription(), Capabilities: caps})
+	}
+	sort.Slice(c.Agents, func(i, j int) bool { return c.Agents[i].Type < c.Agents[j].Type })
+
+	if commands != nil {
+		for _, cmd := range commands.List() {
+			c.Commands = append(c.Commands, command(cmd))
+		}
+	}
+	c.Version = version(c.Agents, c.Commands)
+	return c
+}
+
+func command(cmd *claude.Command) Command {
+	out := Command{
+		Command:     cmd.Type,
+		Name:        cmd.Name,
+		Description: cmd.Description,
+		Agent:       cmd.Agent,
+		Actions:     cmd.Actions,
+		Examples:    cmd.Examples,
+		Icon:        cmd.Icon,
+	}
+	for key, p := range cmd.Parameters {
+		name := p.Name
+		if name == "" {
+			name = key
+		}
+		out.Parameters = append(out.Parameters, Parameter{
+			Name:        name,
+			Type:        p.Type,
+			Required:    p.Required,
+			Default:     p.Default,
+			Description: p.Description,
+			Values:      p.Values,
+		})
+	}
+	sort.Slice(out.Parameters, func(i, j int) bool { return out.Parameters[i].Name < out.Parameters[j].Name })
+	return out
+}
+
+// version hashes the JSON form of the listings
+func version(v ...interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
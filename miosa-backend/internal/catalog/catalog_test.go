@@ -0,0 +1,120 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/claude"
+)
+
+type fakeAgent struct {
+	agentType agents.AgentType
+	caps      []agents.Capability
+}
+
+func (a fakeAgent) GetType() agents.AgentType            { return a.agentType }
+func (a fakeAgent) GetCapabilities() []agents.Capability { return a.caps }
+func (a fakeAgent) GetDescription() string               { return string(a.agentType) + " agent" }
+func (a fakeAgent) Execute(context.Context, agents.Task) (*agents.Result, error) {
+	return &agents.Result{Success: true}, nil
+}
+
+func registry() map[agents.AgentType]agents.Agent {
+	return map[agents.AgentType]agents.Agent{
+		agents.QualityAgent: fakeAgent{agents.QualityAgent, []agents.Capability{{Name: "testing"}, {Name: "code_review", Required: true}}},
+		agents.AnalysisAgent: fakeAgent{agents.AnalysisAgent, []agents.Capability{
+			{Name: "requirements_analysis", Version: "2", Features: []agents.Feature{"streaming"}},
+		}},
+		agents.ArchitectAgent: fakeAgent{agents.ArchitectAgent, nil},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	c := Build(registry(), claude.NewCommandRegistry())
+
+	types := make([]agents.AgentType, len(c.Agents))
+	for i, a := range c.Agents {
+		types[i] = a.Type
+	}
+	assert.Equal(t, []agents.AgentType{agents.AnalysisAgent, agents.ArchitectAgent, agents.QualityAgent}, types)
+	assert.Equal(t, "code_review", c.Agents[2].Capabilities[0].Name)
+	assert.Equal(t, []agents.Feature{"streaming"}, c.Agents[0].Capabilities[0].Features)
+
+	require.NotEmpty(t, c.Commands)
+	for i := 1; i < len(c.Commands); i++ {
+		assert.Less(t, c.Commands[i-1].Command, c.Commands[i].Command)
+	}
+	var orchestrate Command
+	for _, cmd := range c.Commands {
+		if cmd.Command == claude.CommandOrchestrate {
+			orchestrate = cmd
+		}
+	}
+	require.NotEmpty(t, orchestrate.Parameters)
+	for i := 1; i < len(orchestrate.Parameters); i++ {
+		assert.Less(t, orchestrate.Parameters[i-1].Name, orchestrate.Parameters[i].Name)
+	}
+
+	// The same registries always give the same version
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, c.Version, Build(registry(), claude.NewCommandRegistry()).Version)
+	}
+	changed := registry()
+	delete(changed, agents.ArchitectAgent)
+	assert.NotEqual(t, c.Version, Build(changed, claude.NewCommandRegistry()).Version)
+	assert.Empty(t, Build(changed, nil).Commands)
+}
+
+func TestCache(t *testing.T) {
+	agentsByType := registry()
+	builds := 0
+	cache := NewCache(func() *Catalog {
+		builds++
+		return Build(agentsByType, claude.NewCommandRegistry())
+	})
+
+	get := func(h http.HandlerFunc, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	w := get(cache.AgentsHandler(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []Agent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Len(t, listed, 3)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, cache.Catalog().Version, w.Header().Get(VersionHeader))
+
+	assert.Equal(t, http.StatusNotModified, get(cache.AgentsHandler(), etag).Code)
+	assert.Equal(t, http.StatusNotModified, get(cache.AgentsHandler(), `"other", W/`+etag).Code)
+	assert.Equal(t, http.StatusOK, get(cache.AgentsHandler(), `"other"`).Code)
+	assert.NotEqual(t, etag, get(cache.CommandsHandler(), "").Header().Get("ETag"), "each listing has its own tag")
+	assert.Equal(t, 1, builds)
+
+	var whole Catalog
+	require.NoError(t, json.Unmarshal(get(cache.Handler(), "").Body.Bytes(), &whole))
+	assert.Equal(t, listed, whole.Agents)
+
+	// A changed registry shows once the cache is invalidated
+	delete(agentsByType, agents.ArchitectAgent)
+	assert.Equal(t, http.StatusNotModified, get(cache.AgentsHandler(), etag).Code)
+	cache.Invalidate()
+	w = get(cache.AgentsHandler(), etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, 2, builds)
+}
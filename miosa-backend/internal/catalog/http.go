@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// VersionHeader carries the catalog version on every response
+const VersionHeader = "X-Catalog-Version"
+
+// Cache holds the catalog, and the encoded responses served from it, until
+// Invalidate is called
+type Cache struct {
+	build func() *Catalog
+
+	mu        sync.Mutex
+	catalog   *Catalog
+	responses map[string]*response
+}
+
+// response is an encoded view of the catalog
+type response struct {
+	body []byte
+	etag string
+}
+
+// NewCache creates a cache building the catalog with build on first use
+func NewCache(build func() *Catalog) *Cache {
+	return &Cache{build: build}
+}
+
+// Catalog returns the cached catalog, building it if needed
+func (c *Cache) Catalog() *Catalog {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current()
+}
+
+// Invalidate drops the cached catalog, so the next request rebuilds it
+// from the registries
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.catalog, c.responses = nil, nil
+}
+
+func (c *Cache) current() *Catalog {
+	if c.catalog == nil {
+		c.catalog = c.build()
+		c.responses = make(map[string]*response)
+	}
+	return c.catalog
+}
+
+// view returns the named view of the catalog, encoding it on first use
+func (c *Cache) view(name string, fn func(*Catalog) interface{}) (*response, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	catalog := c.current()
+	if resp, ok := c.responses[name]; ok {
+		return resp, catalog.Version, nil
+	}
+	body, err := json.Marshal(fn(catalog))
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(body)
+	resp := &response{body: body, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+	c.responses[name] = resp
+	return resp, catalog.Version, nil
+}
+
+// Handler serves GET /api/catalog, the whole catalog
+func (c *Cache) Handler() http.HandlerFunc {
+	return c.serve("catalog", func(cat *Catalog) interface{} { return cat })
+}
+
+// AgentsHandler serves GET /api/agents, the agents with their capabilities
+func (c *Cache) AgentsHandler() http.HandlerFunc {
+	return c.serve("agents", func(cat *Catalog) interface{} { return cat.Agents })
+}
+
+// CommandsHandler serves GET /api/commands, the slash commands with their
+// parameter schemas
+func (c *Cache) CommandsHandler() http.HandlerFunc {
+	return c.serve("commands", func(cat *Catalog) interface{} { return cat.Commands })
+}
+
+func (c *Cache) serve(name string, fn func(*Catalog) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, version, err := c.view(name, fn)
+		if err != nil {
+			http.Error(w, "failed to encode catalog", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", resp.etag)
+		w.Header().Set(VersionHeader, version)
+		// Clients may keep the response but revalidate before using it
+		w.Header().Set("Cache-Control", "no-cache")
+		if matches(r.Header.Get("If-None-Match"), resp.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp.body)
+	}
+}
+
+// matches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires
+func matches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package claude
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
@@ -554,12 +555,13 @@ func (r *CommandRegistry) GetByName(name string) (*Command, bool) {
 	return r.Get(cmdType)
 }
 
-// List returns all registered commands
+// List returns all registered commands, sorted by type
 func (r *CommandRegistry) List() []*Command {
 	commands := make([]*Command, 0, len(r.commands))
 	for _, cmd := range r.commands {
 		commands = append(commands, cmd)
 	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Type < commands[j].Type })
 	return commands
 }
 
@@ -691,7 +693,7 @@ func (r *CommandRegistry) GetAllHelp() string {
 		"Meta":         {},
 	}
 
-	for _, cmd := range r.commands {
+	for _, cmd := range r.List() {
 		switch cmd.Type {
 		case CommandOrchestrate:
 			categories["Core"] = append(categories["Core"], cmd)
@@ -708,8 +710,8 @@ func (r *CommandRegistry) GetAllHelp() string {
 		}
 	}
 
-	for category, cmds := range categories {
-		if len(cmds) > 0 {
+	for _, category := range []string{"Core", "Development", "Operations", "Analysis", "Integration", "Meta"} {
+		if cmds := categories[category]; len(cmds) > 0 {
 			help.WriteString(fmt.Sprintf("\n%s Commands:\n", category))
 			for _, cmd := range cmds {
 				help.WriteString(fmt.Sprintf("  %s %-15s %s\n", cmd.Icon, string(cmd.Type), cmd.Description))
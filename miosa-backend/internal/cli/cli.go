@@ -0,0 +1,455 @@
+// Package cli implements the miosa command line client for the orchestration API
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/ingest"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+// Usage describes the arguments accepted by Run
+const Usage = `usage: miosa [flags] <command> [arguments]
+
+commands:
+  generate "<description>"   run a workflow, streaming progress as agents finish
+  status <id>                show a workflow's results
+  download <id>              download a workflow's generated files
+  quality <path>             run the static quality checks on a local project
+  verify <id> <dir>          check downloaded files against the workflow's signed manifest
+
+flags:
+  -api-url URL      REST API base URL (default $MIOSA_API_URL or http://localhost:8092)
+  -grpc ADDR        gRPC address used to stream progress (default $MIOSA_GRPC_ADDR or localhost:9092)
+  -api-key KEY      API key sent with every request (default $MIOSA_API_KEY)
+
+Run 'miosa <command> -h' for the flags of a command.`
+
+// DefaultAPIURL and DefaultGRPCAddr match the enhanced orchestrator's defaults
+const (
+	DefaultAPIURL   = "http://localhost:8092"
+	DefaultGRPCAddr = "localhost:9092"
+)
+
+// ErrQualityBelowMinimum is returned by quality when the score is below -min-score
+var ErrQualityBelowMinimum = errors.New("quality score below minimum")
+
+// ErrIntegrityMismatch is returned by verify when files or the manifest
+// were tampered with
+var ErrIntegrityMismatch = errors.New("integrity check failed")
+
+// Config holds the connection settings shared by every command
+type Config struct {
+	APIURL   string
+	GRPCAddr string
+	APIKey   string
+}
+
+// Run parses global flags from args and runs the named command, writing its
+// output to out
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("miosa", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var cfg Config
+	fs.StringVar(&cfg.APIURL, "api-url", envOr("MIOSA_API_URL", DefaultAPIURL), "")
+	fs.StringVar(&cfg.GRPCAddr, "grpc", envOr("MIOSA_GRPC_ADDR", DefaultGRPCAddr), "")
+	fs.StringVar(&cfg.APIKey, "api-key", os.Getenv("MIOSA_API_KEY"), "")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%v\n%s", err, Usage)
+	}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", Usage)
+	}
+	switch args[0] {
+	case "generate":
+		return runGenerate(ctx, cfg, args[1:], out)
+	case "status":
+		return runStatus(ctx, cfg, args[1:], out)
+	case "download":
+		return runDownload(ctx, cfg, args[1:], out)
+	case "quality":
+		return runQuality(ctx, args[1:], out)
+	case "verify":
+		return runVerify(ctx, cfg, args[1:], out)
+	case "help", "-h", "--help":
+		fmt.Fprintln(out, Usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], Usage)
+}
+
+// commandFlags returns a flag set whose usage errors name the command
+func commandFlags(name, usage string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "usage: miosa %s\n\nflags:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runGenerate(ctx context.Context, cfg Config, args []string, out io.Writer) error {
+	fs := commandFlags("generate", `generate [flags] "<description>"`, out)
+	var (
+		language    = fs.String("language", "", "Primary language of the generated project")
+		stack       = fs.String("stack", "", "Comma-separated target stack, e.g. go,postgres")
+		constraints = fs.String("constraints", "", "Comma-separated constraints")
+		pipeline    = fs.String("pipeline", "", "Named pipeline to run instead of the default sequence")
+		outDir      = fs.String("out", "", "Download the generated files into this directory")
+		noStream    = fs.Bool("no-stream", false, "Wait for the REST API instead of streaming progress over gRPC")
+		jsonOut     = fs.Bool("json", false, "Print the finished workflow as JSON")
+		params      orchestrate.Params
+	)
+	fs.Func("param", "Pipeline parameter as name=value, e.g. db=postgres; repeatable", func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("must be name=value")
+		}
+		if params == nil {
+			params = make(orchestrate.Params)
+		}
+		params[strings.TrimSpace(name)] = value
+		return nil
+	})
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("generate takes exactly one description")
+	}
+
+	req := &orchestrate.Request{
+		Description: fs.Arg(0),
+		TargetStack: splitList(*stack),
+		Constraints: splitList(*constraints),
+		Language:    *language,
+		Pipeline:    *pipeline,
+		Params:      params,
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	var (
+		workflow *orchestrate.Workflow
+		err      error
+	)
+	if *noStream {
+		workflow, err = NewClient(cfg.APIURL, cfg.APIKey).Orchestrate(ctx, req)
+	} else {
+		workflow, err = streamGenerate(ctx, cfg, req, out)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		printJSON(out, workflow)
+	} else {
+		printWorkflow(out, workflow)
+	}
+	if *outDir != "" {
+		return download(ctx, cfg, workflow.ID, *outDir, out)
+	}
+	return nil
+}
+
+// streamGenerate runs req over gRPC, printing a line per workflow event
+func streamGenerate(ctx context.Context, cfg Config, req *orchestrate.Request, out io.Writer) (*orchestrate.Workflow, error) {
+	conn, err := grpc.NewClient(cfg.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", cfg.GRPCAddr, err)
+	}
+	defer conn.Close()
+
+	if cfg.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", cfg.APIKey)
+	}
+	return orchestrate.NewClient(conn).StreamWorkflow(ctx, req, func(e orchestrate.Event) {
+		printEvent(out, e)
+	})
+}
+
+func runStatus(ctx context.Context, cfg Config, args []string, out io.Writer) error {
+	fs := commandFlags("status", "status [flags] <id>", out)
+	jsonOut := fs.Bool("json", false, "Print the workflow as JSON")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	id, err := workflowArg(fs)
+	if err != nil {
+		return err
+	}
+
+	workflow, err := NewClient(cfg.APIURL, cfg.APIKey).Workflow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		printJSON(out, workflow)
+	} else {
+		printWorkflow(out, workflow)
+	}
+	return nil
+}
+
+func runDownload(ctx context.Context, cfg Config, args []string, out io.Writer) error {
+	fs := commandFlags("download", "download [flags] <id>", out)
+	outDir := fs.String("out", "", "Directory to write the files into (default miosa-<id prefix>)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	id, err := workflowArg(fs)
+	if err != nil {
+		return err
+	}
+	dir := *outDir
+	if dir == "" {
+		dir = "miosa-" + id.String()[:8]
+	}
+	return download(ctx, cfg, id, dir, out)
+}
+
+func download(ctx context.Context, cfg Config, id uuid.UUID, dir string, out io.Writer) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := NewClient(cfg.APIURL, cfg.APIKey).Download(ctx, id, dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "files written to %s\n", dir)
+	return nil
+}
+
+func runQuality(ctx context.Context, args []string, out io.Writer) error {
+	fs := commandFlags("quality", "quality [flags] <path>", out)
+	var (
+		severity = fs.String("severity", "low", "Minimum severity to report (low|medium|high|critical)")
+		packs    = fs.String("packs", "", "Comma-separated guideline packs, e.g. owasp-top10-2021,cis-controls-v8")
+		maxBytes = fs.Int64("max-bytes", 4<<20, "Largest total source size to read")
+		minScore = fs.Float64("min-score", 0, "Exit non-zero when the score is below this (0-100)")
+		jsonOut  = fs.Bool("json", false, "Print the result as JSON")
+	)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("quality takes exactly one path")
+	}
+
+	inv, err := ingest.Walk(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %w", fs.Arg(0), err)
+	}
+	files := readSources(inv, *maxBytes)
+	if len(files) == 0 {
+		return fmt.Errorf("no source files found in %s", fs.Arg(0))
+	}
+
+	result, err := quality.RunCodeAssurance(ctx, nil, quality.CodeAssuranceRequest{
+		Language:          inv.Primary,
+		Files:             files,
+		SeverityThreshold: *severity,
+		Packs:             splitList(*packs),
+	})
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		printJSON(out, result)
+	} else {
+		fmt.Fprintf(out, "score %.1f: %s\n", result.Score, result.Summary)
+		for _, f := range result.Findings {
+			loc := f.File
+			if f.LineStart > 0 {
+				loc = fmt.Sprintf("%s:%d", f.File, f.LineStart)
+			}
+			fmt.Fprintf(out, "  %-8s %s  %s\n", f.Severity, loc, f.Title)
+		}
+	}
+	if result.Score < *minScore {
+		return fmt.Errorf("%w: %.1f < %.1f", ErrQualityBelowMinimum, result.Score, *minScore)
+	}
+	return nil
+}
+
+func runVerify(ctx context.Context, cfg Config, args []string, out io.Writer) error {
+	fs := commandFlags("verify", "verify [flags] <id> <dir>", out)
+	var (
+		key      = fs.String("key", os.Getenv("MIOSA_VERIFY_KEY"), "Ed25519 public key (ed25519:<base64>) or HMAC secret the manifest was signed with (default $MIOSA_VERIFY_KEY)")
+		manifest = fs.String("manifest", "", "Read the signed manifest from this file instead of the API")
+		jsonOut  = fs.Bool("json", false, "Print the result as JSON")
+	)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("verify takes a workflow id and a directory")
+	}
+	id, err := uuid.Parse(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid workflow id %q", fs.Arg(0))
+	}
+	verifier, err := integrity.ParseVerifier(*key)
+	if err != nil {
+		return err
+	}
+
+	var m *integrity.Manifest
+	if *manifest != "" {
+		data, err := os.ReadFile(*manifest)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("failed to decode manifest: %w", err)
+		}
+	} else if m, err = NewClient(cfg.APIURL, cfg.APIKey).Manifest(ctx, id); err != nil {
+		return err
+	}
+	if m.WorkflowID != id {
+		return fmt.Errorf("%w: manifest is for workflow %s", ErrIntegrityMismatch, m.WorkflowID)
+	}
+
+	result, err := integrity.Verify(fs.Arg(1), m, verifier)
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		printJSON(out, result)
+	} else {
+		fmt.Fprintf(out, "signature %s, %d of %d files verified\n", result.Signature, result.Verified, len(m.Files))
+		for _, path := range result.Modified {
+			fmt.Fprintf(out, "  modified  %s\n", path)
+		}
+		for _, path := range result.Missing {
+			fmt.Fprintf(out, "  missing   %s\n", path)
+		}
+	}
+	if !result.Valid {
+		return ErrIntegrityMismatch
+	}
+	return nil
+}
+
+// readSources reads the inventoried source files, up to maxBytes in total
+func readSources(inv *ingest.Inventory, maxBytes int64) []quality.CodeFile {
+	var files []quality.CodeFile
+	var total int64
+	for _, f := range inv.SourceFiles() {
+		if total+f.Bytes > maxBytes {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(inv.Root, filepath.FromSlash(f.Path)))
+		if err != nil {
+			continue
+		}
+		total += f.Bytes
+		files = append(files, quality.CodeFile{Path: f.Path, Content: string(content), Language: f.Language})
+	}
+	return files
+}
+
+// parseArgs parses flags given before or after the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
+
+func workflowArg(fs *flag.FlagSet) (uuid.UUID, error) {
+	if fs.NArg() != 1 {
+		return uuid.Nil, fmt.Errorf("%s takes exactly one workflow id", fs.Name())
+	}
+	id, err := uuid.Parse(fs.Arg(0))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid workflow id %q", fs.Arg(0))
+	}
+	return id, nil
+}
+
+func printEvent(out io.Writer, e orchestrate.Event) {
+	switch e.Type {
+	case orchestrate.EventStepStarted:
+		fmt.Fprintf(out, "[%d] %s started\n", e.Step+1, e.Agent)
+	case orchestrate.EventStepCompleted:
+		line := fmt.Sprintf("[%d] %s done", e.Step+1, e.Agent)
+		if e.Result != nil {
+			line += fmt.Sprintf(" in %dms (confidence %.2f)", e.Result.ExecutionMS, e.Result.Confidence)
+			if e.Result.Reused {
+				line += ", reused"
+			}
+		}
+		fmt.Fprintln(out, line)
+	case orchestrate.EventStepFailed:
+		fmt.Fprintf(out, "[%d] %s failed: %s\n", e.Step+1, e.Agent, e.Error)
+	case orchestrate.EventWorkflowFailed:
+		fmt.Fprintf(out, "workflow %s failed: %s\n", e.WorkflowID, e.Error)
+	}
+}
+
+func printWorkflow(out io.Writer, w *orchestrate.Workflow) {
+	state := "succeeded"
+	if !w.Success {
+		state = "failed"
+	}
+	fmt.Fprintf(out, "workflow %s %s\n", w.ID, state)
+	for i, s := range w.Steps {
+		mark := "ok"
+		if !s.Success {
+			mark = "FAILED"
+		}
+		fmt.Fprintf(out, "  %2d. %-16s %-6s %6dms  confidence %.2f\n", i+1, s.Agent, mark, s.ExecutionMS, s.Confidence)
+	}
+}
+
+func printJSON(out io.Writer, v interface{}) {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+var workflowID = uuid.MustParse("3f1c2a9e-8d4b-4c6a-9f0e-1a2b3c4d5e6f")
+
+// fakeAPI serves the REST endpoints the CLI calls from a workspace at root
+func fakeAPI(t *testing.T, root string, keys *[]string) *httptest.Server {
+	result := map[string]interface{}{
+		"workflow_id": workflowID,
+		"success":     true,
+		"timestamp":   time.Unix(1700000000, 0).UTC(),
+		"results": []map[string]interface{}{
+			{"agent": "analysis", "success": true, "output": "analyzed", "confidence": 0.9, "execution_ms": 12},
+		},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/orchestrate", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := orchestrate.Decode(w, r); err != nil {
+			orchestrate.WriteError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}).Methods("POST")
+	router.HandleFunc("/api/workflow/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] != workflowID.String() {
+			http.Error(w, "Workflow not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}).Methods("GET")
+	router.HandleFunc("/api/workflow/{id}/archive", workspace.ArchiveHandler(func(uuid.UUID) string { return root })).Methods("GET")
+	router.HandleFunc("/api/workflow/{id}/integrity", integrity.ManifestHandler(func(uuid.UUID) string { return root })).Methods("GET")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*keys = append(*keys, r.Header.Get("X-API-Key"))
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := Run(context.Background(), args, &out)
+	return out.String(), err
+}
+
+func TestRun_Status(t *testing.T) {
+	var keys []string
+	srv := fakeAPI(t, t.TempDir(), &keys)
+
+	out, err := run(t, "-api-url", srv.URL, "-api-key", "secret", "status", workflowID.String())
+	require.NoError(t, err)
+	assert.Contains(t, out, "workflow "+workflowID.String()+" succeeded")
+	assert.Contains(t, out, "analysis")
+	assert.Equal(t, []string{"secret"}, keys)
+
+	out, err = run(t, "-api-url", srv.URL, "status", "-json", workflowID.String())
+	require.NoError(t, err)
+	var w orchestrate.Workflow
+	require.NoError(t, json.Unmarshal([]byte(out), &w))
+	assert.Equal(t, workflowID, w.ID)
+	require.Len(t, w.Steps, 1)
+	assert.Equal(t, int64(12), w.Steps[0].ExecutionMS)
+
+	_, err = run(t, "-api-url", srv.URL, "status", uuid.New().String())
+	assert.ErrorContains(t, err, "404")
+	_, err = run(t, "-api-url", srv.URL, "status", "nope")
+	assert.EqualError(t, err, `invalid workflow id "nope"`)
+}
+
+func TestRun_Download(t *testing.T) {
+	root := t.TempDir()
+	ws, err := workspace.Open(root)
+	require.NoError(t, err)
+	_, err = ws.Write("cmd/api/main.go", "package main\n", workspace.Provenance{WorkflowID: workflowID.String(), Run: "r", Agent: "development"})
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+
+	var keys []string
+	srv := fakeAPI(t, root, &keys)
+	dir := filepath.Join(t.TempDir(), "out")
+
+	out, err := run(t, "-api-url", srv.URL, "download", workflowID.String(), "-out", dir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "files written to "+dir)
+	content, err := os.ReadFile(filepath.Join(dir, "cmd", "api", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(content))
+}
+
+func TestRun_GenerateNoStream(t *testing.T) {
+	var keys []string
+	srv := fakeAPI(t, t.TempDir(), &keys)
+
+	out, err := run(t, "-api-url", srv.URL, "generate", "-no-stream", "-stack", "go, postgres", "Build a todo API with auth")
+	require.NoError(t, err)
+	assert.Contains(t, out, "succeeded")
+
+	_, err = run(t, "-api-url", srv.URL, "generate", "-no-stream", "short")
+	assert.ErrorContains(t, err, "invalid request: description")
+	assert.Len(t, keys, 1, "invalid requests are rejected before calling the API")
+}
+
+// streamBackend emits one step and records the request and API key it was
+// called with
+type streamBackend struct {
+	key string
+	req *orchestrate.Request
+}
+
+func (b *streamBackend) Orchestrate(ctx context.Context, req *orchestrate.Request) (*orchestrate.Workflow, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-api-key")) > 0 {
+		b.key = md.Get("x-api-key")[0]
+	}
+	b.req = req
+	step := orchestrate.StepResult{Agent: agents.AnalysisAgent, Success: true, Confidence: 0.8, ExecutionMS: 7}
+	orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepStarted, WorkflowID: workflowID, Agent: step.Agent})
+	orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepCompleted, WorkflowID: workflowID, Agent: step.Agent, Result: &step})
+	return &orchestrate.Workflow{ID: workflowID, Success: true, Steps: []orchestrate.StepResult{step}}, nil
+}
+
+func (b *streamBackend) Workflow(uuid.UUID) (*orchestrate.Workflow, bool) { return nil, false }
+func (b *streamBackend) Agents() []orchestrate.AgentInfo                  { return nil }
+
+func TestRun_GenerateStream(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	backend := &streamBackend{}
+	orchestrate.RegisterOrchestratorServer(srv, backend)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	out, err := run(t, "-grpc", lis.Addr().String(), "-api-key", "secret", "generate", "-param", "app_name=todo", "-param", "port=8080", "Build {{app_name}}, a todo API with auth")
+	require.NoError(t, err)
+	assert.Equal(t, "Build todo, a todo API with auth", backend.req.Description)
+	assert.Equal(t, orchestrate.Params{"app_name": "todo", "port": 8080}, backend.req.Params)
+	assert.Contains(t, out, "[1] analysis started\n[1] analysis done in 7ms (confidence 0.80)\n")
+	assert.Contains(t, out, "workflow "+workflowID.String()+" succeeded")
+	assert.Equal(t, "secret", backend.key)
+}
+
+func TestRun_Quality(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "fmt"
+
+var password = "hunter2hunter2"
+
+func main() { fmt.Println(password) }
+`), 0644))
+
+	out, err := run(t, "quality", "-json", dir)
+	require.NoError(t, err)
+	var result struct {
+		Score    float64 `json:"score"`
+		Findings []struct {
+			File string `json:"file"`
+		} `json:"findings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &result))
+	require.NotEmpty(t, result.Findings)
+	assert.Equal(t, "main.go", result.Findings[0].File)
+
+	_, err = run(t, "quality", "-min-score", "101", dir)
+	assert.ErrorIs(t, err, ErrQualityBelowMinimum)
+
+	_, err = run(t, "quality", t.TempDir())
+	assert.ErrorContains(t, err, "no source files")
+}
+
+func TestRun_Verify(t *testing.T) {
+	root := t.TempDir()
+	ws, err := workspace.Open(root)
+	require.NoError(t, err)
+	_, err = ws.Write("main.go", "package main\n", workspace.Provenance{WorkflowID: workflowID.String(), Run: "r", Agent: "development"})
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+	m, err := integrity.Sign(root, workflowID, []string{"main.go"}, integrity.NewHMACSigner([]byte("s3cret")))
+	require.NoError(t, err)
+	require.NoError(t, integrity.Save(root, m))
+
+	var keys []string
+	srv := fakeAPI(t, root, &keys)
+	dir := t.TempDir()
+	_, err = run(t, "-api-url", srv.URL, "download", workflowID.String(), "-out", dir)
+	require.NoError(t, err)
+
+	out, err := run(t, "-api-url", srv.URL, "verify", "-key", "s3cret", workflowID.String(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, "signature valid, 1 of 1 files verified\n", out)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package evil\n"), 0644))
+	out, err = run(t, "-api-url", srv.URL, "verify", "-key", "s3cret", workflowID.String(), dir)
+	assert.ErrorIs(t, err, ErrIntegrityMismatch)
+	assert.Contains(t, out, "modified  main.go")
+
+	// A manifest saved alongside the files, checked with the wrong key
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifest, data, 0644))
+	out, err = run(t, "verify", "-manifest", manifest, "-key", "wrong", workflowID.String(), root)
+	assert.ErrorIs(t, err, ErrIntegrityMismatch)
+	assert.Contains(t, out, "signature unknown_key")
+
+	_, err = run(t, "verify", workflowID.String(), dir)
+	assert.ErrorContains(t, err, "empty verification key")
+}
+
+func TestRun_Usage(t *testing.T) {
+	_, err := run(t)
+	assert.ErrorContains(t, err, "missing command")
+	_, err = run(t, "deploy")
+	assert.ErrorContains(t, err, `unknown command "deploy"`)
+	_, err = run(t, "status")
+	assert.EqualError(t, err, "status takes exactly one workflow id")
+}
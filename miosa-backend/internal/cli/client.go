@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sormind/OSA/miosa-backend/internal/ingest"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+// Client calls the orchestrator's REST API
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient returns a client for the API at baseURL. A non-empty apiKey is
+// sent as X-API-Key with every request.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		// Synchronous orchestration runs every agent before responding
+		http: &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// workflowResponse is the REST shape of a workflow result
+type workflowResponse struct {
+	WorkflowID uuid.UUID                `json:"workflow_id"`
+	Results    []orchestrate.StepResult `json:"results"`
+	Success    bool                     `json:"success"`
+	Timestamp  time.Time                `json:"timestamp"`
+}
+
+func (r *workflowResponse) workflow() *orchestrate.Workflow {
+	return &orchestrate.Workflow{ID: r.WorkflowID, Success: r.Success, Steps: r.Results, Timestamp: r.Timestamp}
+}
+
+// Orchestrate runs req through POST /api/orchestrate and waits for the result
+func (c *Client) Orchestrate(ctx context.Context, req *orchestrate.Request) (*orchestrate.Workflow, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp workflowResponse
+	if err := c.do(ctx, http.MethodPost, "/api/orchestrate", bytes.NewReader(body), &resp); err != nil {
+		return nil, err
+	}
+	return resp.workflow(), nil
+}
+
+// Workflow fetches a workflow through GET /api/workflow/{id}
+func (c *Client) Workflow(ctx context.Context, id uuid.UUID) (*orchestrate.Workflow, error) {
+	var resp workflowResponse
+	if err := c.do(ctx, http.MethodGet, "/api/workflow/"+id.String(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.workflow(), nil
+}
+
+// Download extracts the workflow's generated files into dir
+func (c *Client) Download(ctx context.Context, id uuid.UUID, dir string) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/workflow/"+id.String()+"/archive", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := ingest.Extract(resp.Body, "workflow.tar.gz", dir); err != nil {
+		return fmt.Errorf("failed to extract workflow files: %w", err)
+	}
+	return nil
+}
+
+// Manifest fetches a workflow's signed manifest through
+// GET /api/workflow/{id}/integrity
+func (c *Client) Manifest(ctx context.Context, id uuid.UUID) (*integrity.Manifest, error) {
+	var m integrity.Manifest
+	if err := c.do(ctx, http.MethodGet, "/api/workflow/"+id.String()+"/integrity", nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send issues a request, turning non-2xx responses into errors
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", path, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
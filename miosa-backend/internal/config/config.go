@@ -17,6 +17,7 @@ type Config struct {
 	Services ServicesConfig
 	Security SecurityConfig
 	Features FeatureFlags
+	Plugins  []PluginConfig
 }
 
 type ServerConfig struct {
@@ -88,6 +89,20 @@ type LLMConfig struct {
 	StreamingEnabled bool
 	CacheResponses   bool
 	CacheTTL         time.Duration
+	// AgentTimeouts bounds a single LLM call per agent type, e.g.
+	// AGENT_TIMEOUTS="development=120s,communication=20s"
+	AgentTimeouts map[string]time.Duration
+	// BreakerThreshold consecutive failures trip a model's circuit breaker
+	// for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// ModelFallbacks lists alternatives tried when a model's breaker is open,
+	// e.g. MODEL_FALLBACKS="moonshotai/kimi-k2-instruct=llama-3.3-70b-versatile|llama-3.1-8b-instant"
+	ModelFallbacks map[string][]string
+	// AgentFailover lists the targets an agent fails over to, in order and
+	// in place of the model fallbacks; provider:model names an alternate
+	// provider, e.g. AGENT_FAILOVER="development=llama-3.1-8b-instant|openai:gpt-4o-mini"
+	AgentFailover map[string][]string
 }
 
 type LLMProvider struct {
@@ -187,6 +202,16 @@ type SecurityConfig struct {
 	ContentSecurityPolicy string
 }
 
+// PluginConfig declares an out-of-process agent served over gRPC, e.g.
+// AGENT_PLUGINS="security_scanner=scanner:50051,docs_writer=localhost:50052"
+type PluginConfig struct {
+	Type    string        // agent type the plugin registers as
+	Address string        // gRPC host:port
+	Timeout time.Duration // bound on a single Execute call
+	TLS     bool
+	Token   string // sent as a bearer token on every call, if set
+}
+
 type FeatureFlags struct {
 	EnableMCP           bool
 	EnableWebSockets    bool
@@ -219,8 +244,8 @@ func Load() (*Config, error) {
 			MaxOpenConns:     getIntEnv("DB_MAX_OPEN_CONNS", 25),
 			MaxIdleConns:     getIntEnv("DB_MAX_IDLE_CONNS", 10),
 			ConnMaxLifetime:  getDurationEnv("DB_CONN_MAX_LIFETIME", time.Hour),
-			EnableMigrations: getBoolEnv("ENABLE_MIGRATIONS", true),
-			MigrationsPath:   getEnv("MIGRATIONS_PATH", "internal/db/migrations"),
+			EnableMigrations: getBoolEnv("AUTO_MIGRATE", false),
+			MigrationsPath:   getEnv("MIGRATIONS_PATH", ""), // empty uses the migrations embedded in the binary
 			EnableReplicas:   getBoolEnv("ENABLE_READ_REPLICAS", false),
 			ReadReplicaURLs:  getSliceEnv("READ_REPLICA_URLS", []string{}),
 			VectorDimension:  getIntEnv("VECTOR_DIMENSION", 1536),
@@ -258,6 +283,11 @@ func Load() (*Config, error) {
 			StreamingEnabled: getBoolEnv("LLM_STREAMING_ENABLED", true),
 			CacheResponses:   getBoolEnv("LLM_CACHE_RESPONSES", true),
 			CacheTTL:         getDurationEnv("LLM_CACHE_TTL", 1*time.Hour),
+			AgentTimeouts:    loadAgentTimeouts(),
+			BreakerThreshold: getIntEnv("LLM_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  getDurationEnv("LLM_BREAKER_COOLDOWN", 30*time.Second),
+			ModelFallbacks:   loadModelFallbacks(),
+			AgentFailover:    loadAgentFailover(),
 		},
 		Services: ServicesConfig{
 			E2B: E2BConfig{
@@ -335,6 +365,7 @@ func Load() (*Config, error) {
 			EnableE2E:            getBoolEnv("FEATURE_E2E", true),
 			ExperimentalFeatures: loadExperimentalFeatures(),
 		},
+		Plugins: LoadPlugins(),
 	}
 
 	return cfg, cfg.Validate()
@@ -567,6 +598,75 @@ func loadExperimentalFeatures() map[string]bool {
 	return features
 }
 
+func loadAgentTimeouts() map[string]time.Duration {
+	timeouts := make(map[string]time.Duration)
+
+	for _, pair := range getSliceEnv("AGENT_TIMEOUTS", nil) {
+		agent, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err == nil {
+			timeouts[agent] = d
+		}
+	}
+
+	return timeouts
+}
+
+func loadModelFallbacks() map[string][]string {
+	fallbacks := make(map[string][]string)
+
+	for _, pair := range getSliceEnv("MODEL_FALLBACKS", nil) {
+		model, alternatives, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		fallbacks[model] = strings.Split(alternatives, "|")
+	}
+
+	return fallbacks
+}
+
+func loadAgentFailover() map[string][]string {
+	chains := make(map[string][]string)
+
+	for _, pair := range getSliceEnv("AGENT_FAILOVER", nil) {
+		agent, targets, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		chains[agent] = strings.Split(targets, "|")
+	}
+
+	return chains
+}
+
+// LoadPlugins reads AGENT_PLUGINS and the shared AGENT_PLUGIN_TIMEOUT,
+// AGENT_PLUGIN_TLS and AGENT_PLUGIN_TOKEN settings
+func LoadPlugins() []PluginConfig {
+	var plugins []PluginConfig
+	timeout := getDurationEnv("AGENT_PLUGIN_TIMEOUT", 2*time.Minute)
+	useTLS := getBoolEnv("AGENT_PLUGIN_TLS", false)
+	token := os.Getenv("AGENT_PLUGIN_TOKEN")
+
+	for _, pair := range getSliceEnv("AGENT_PLUGINS", nil) {
+		agentType, address, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || agentType == "" || address == "" {
+			continue
+		}
+		plugins = append(plugins, PluginConfig{
+			Type:    agentType,
+			Address: address,
+			Timeout: timeout,
+			TLS:     useTLS,
+			Token:   token,
+		})
+	}
+
+	return plugins
+}
+
 func generateRandomSecret() string {
 	return "default-secret-change-in-production"
 }
\ No newline at end of file
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variable of every setting: the
+// batch-workers flag is read from MIOSA_BATCH_WORKERS
+const EnvPrefix = "MIOSA_"
+
+// ConfigEnv names the config file when the -config flag is not given
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+// Sources of a setting's effective value, lowest precedence first
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// mask replaces secret values in printed configuration
+const mask = "********"
+
+// Loader layers a binary's settings: flag defaults, then a YAML config
+// file, then environment variables, then flags given on the command line.
+// Settings are declared as flags on the loader's FlagSet, so each binary
+// keeps one definition per setting with its default and usage.
+type Loader struct {
+	fs       *flag.FlagSet
+	file     *string
+	env      map[string][]string // Legacy variables read after the prefixed one
+	secrets  map[string]bool
+	required []string
+	sources  map[string]string
+}
+
+// NewLoader returns a loader for the settings declared on fs and adds the
+// -config flag naming the YAML config file
+func NewLoader(fs *flag.FlagSet) *Loader {
+	return &Loader{
+		fs:      fs,
+		file:    fs.String("config", "", "YAML config file (defaults to $"+ConfigEnv+")"),
+		env:     make(map[string][]string),
+		secrets: make(map[string]bool),
+		sources: make(map[string]string),
+	}
+}
+
+// FlagSet returns the flags the loader reads
+func (l *Loader) FlagSet() *flag.FlagSet {
+	return l.fs
+}
+
+// Env also reads the setting from vars, for variables that predate the
+// prefixed names. The prefixed variable wins when both are set.
+func (l *Loader) Env(name string, vars ...string) {
+	l.env[name] = append(l.env[name], vars...)
+}
+
+// Secret declares a string setting whose value is masked when printed.
+// Secrets are best set in the environment or a config file rather than
+// on the command line.
+func (l *Loader) Secret(name, usage string, vars ...string) *string {
+	value := l.fs.String(name, "", usage)
+	l.secrets[name] = true
+	l.Env(name, vars...)
+	return value
+}
+
+// Require makes Validate fail when any of the named settings is empty
+func (l *Loader) Require(names ...string) {
+	l.required = append(l.required, names...)
+}
+
+// EnvVars returns the environment variables read for a setting, in order
+// of precedence
+func (l *Loader) EnvVars(name string) []string {
+	prefixed := EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	return append([]string{prefixed}, l.env[name]...)
+}
+
+// Load parses args and layers the config file and environment under the
+// flags that were given. A .env file in the working directory is read
+// first; it never overrides variables already set.
+func (l *Loader) Load(args []string) error {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading .env: %w", err)
+	}
+	if err := l.fs.Parse(args); err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	l.fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	l.fs.VisitAll(func(f *flag.Flag) { l.sources[f.Name] = SourceDefault })
+
+	path := *l.file
+	if !given["config"] {
+		path = os.Getenv(ConfigEnv)
+	}
+	if path != "" {
+		settings, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		for name, value := range settings {
+			if l.fs.Lookup(name) == nil || name == "config" {
+				return fmt.Errorf("%s: unknown setting %q", path, name)
+			}
+			if given[name] {
+				continue
+			}
+			if err := l.fs.Set(name, value); err != nil {
+				return fmt.Errorf("%s: setting %q: %w", path, name, err)
+			}
+			l.sources[name] = SourceFile
+		}
+	}
+
+	var errs []error
+	l.fs.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || f.Name == "config" {
+			return
+		}
+		for _, key := range l.EnvVars(f.Name) {
+			value := os.Getenv(key)
+			if value == "" {
+				continue
+			}
+			if err := l.fs.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+			l.sources[f.Name] = SourceEnv + " " + key
+			return
+		}
+	})
+	for name := range given {
+		l.sources[name] = SourceFlag
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every required setting left empty
+func (l *Loader) Validate() error {
+	var errs []error
+	for _, name := range l.required {
+		f := l.fs.Lookup(name)
+		if f == nil || f.Value.String() == "" {
+			errs = append(errs, fmt.Errorf("%s is required: set -%s, %s or %q in the config file",
+				name, name, strings.Join(l.EnvVars(name), ", "), name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Source returns where a setting's effective value came from
+func (l *Loader) Source(name string) string {
+	return l.sources[name]
+}
+
+// PrintRequested reports whether the command line asked for
+// "config print" after the flags
+func (l *Loader) PrintRequested() bool {
+	args := l.fs.Args()
+	return len(args) == 2 && args[0] == "config" && args[1] == "print"
+}
+
+// Print writes the effective configuration with the source of each value.
+// Secrets are masked.
+func (l *Loader) Print(w io.Writer) error {
+	var names []string
+	l.fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, name := range names {
+		value := l.fs.Lookup(name).Value.String()
+		if l.secrets[name] && value != "" {
+			value = mask
+		}
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, value, l.sources[name])
+	}
+	return tw.Flush()
+}
+
+// readFile reads a YAML config file into flag values. Nested mappings are
+// joined with dashes, so loadtest: {vus: 20} sets -loadtest-vus, and lists
+// become comma-separated values.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	settings := make(map[string]string)
+	flatten("", doc, settings)
+	return settings, nil
+}
+
+func flatten(prefix string, doc map[string]interface{}, out map[string]string) {
+	for key, value := range doc {
+		name := key
+		if prefix != "" {
+			name = prefix + "-" + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(name, v, out)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[name] = strings.Join(items, ",")
+		case nil:
+			out[name] = ""
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+}
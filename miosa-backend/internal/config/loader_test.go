@@ -0,0 +1,89 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLoader() (*Loader, *string, *int, *time.Duration, *string) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := NewLoader(fs)
+	port := fs.String("port", "8092", "Server port")
+	workers := fs.Int("batch-workers", 4, "Batch workers")
+	duration := fs.Duration("loadtest-duration", 30*time.Second, "Load test duration")
+	apiKey := l.Secret("groq-api-key", "Groq API key", "GROQ_API_KEY")
+	return l, port, workers, duration, apiKey
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoader_Layering(t *testing.T) {
+	path := writeConfig(t, "port: 9000\nbatch-workers: 8\nloadtest:\n  duration: 1m\n")
+	t.Setenv("MIOSA_BATCH_WORKERS", "16")
+	t.Setenv("GROQ_API_KEY", "gsk_secret")
+
+	l, port, workers, duration, apiKey := newTestLoader()
+	require.NoError(t, l.Load([]string{"-config", path, "-port", "9100"}))
+
+	assert.Equal(t, "9100", *port)
+	assert.Equal(t, SourceFlag, l.Source("port"), "flags beat the file")
+	assert.Equal(t, 16, *workers)
+	assert.Equal(t, "env MIOSA_BATCH_WORKERS", l.Source("batch-workers"), "the environment beats the file")
+	assert.Equal(t, time.Minute, *duration)
+	assert.Equal(t, SourceFile, l.Source("loadtest-duration"))
+	assert.Equal(t, "gsk_secret", *apiKey)
+	assert.NoError(t, l.Validate())
+}
+
+func TestLoader_ConfigFromEnv(t *testing.T) {
+	t.Setenv(ConfigEnv, writeConfig(t, "port: 9000\n"))
+
+	l, port, _, _, _ := newTestLoader()
+	require.NoError(t, l.Load(nil))
+	assert.Equal(t, "9000", *port)
+	assert.Equal(t, SourceDefault, l.Source("batch-workers"))
+}
+
+func TestLoader_Errors(t *testing.T) {
+	l, _, _, _, _ := newTestLoader()
+	err := l.Load([]string{"-config", writeConfig(t, "prot: 9000\n")})
+	assert.ErrorContains(t, err, `unknown setting "prot"`)
+
+	l, _, _, _, _ = newTestLoader()
+	err = l.Load([]string{"-config", writeConfig(t, "batch-workers: many\n")})
+	assert.ErrorContains(t, err, `setting "batch-workers"`)
+
+	t.Setenv("MIOSA_LOADTEST_DURATION", "soon")
+	l, _, _, _, _ = newTestLoader()
+	assert.ErrorContains(t, l.Load(nil), "MIOSA_LOADTEST_DURATION")
+
+	l, _, _, _, _ = newTestLoader()
+	l.Require("groq-api-key")
+	l.Load(nil)
+	assert.ErrorContains(t, l.Validate(), "groq-api-key is required: set -groq-api-key, MIOSA_GROQ_API_KEY, GROQ_API_KEY or \"groq-api-key\" in the config file")
+}
+
+func TestLoader_Print(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "gsk_secret")
+	l, _, _, _, _ := newTestLoader()
+	require.NoError(t, l.Load([]string{"-port", "9100", "config", "print"}))
+	require.True(t, l.PrintRequested())
+
+	var out strings.Builder
+	require.NoError(t, l.Print(&out))
+	assert.NotContains(t, out.String(), "gsk_secret")
+	assert.Regexp(t, `groq-api-key\s+\*{8}\s+env GROQ_API_KEY`, out.String())
+	assert.Regexp(t, `port\s+9100\s+flag`, out.String())
+	assert.Regexp(t, `batch-workers\s+4\s+default`, out.String())
+}
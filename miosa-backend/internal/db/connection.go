@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 	"github.com/sormind/OSA/miosa-backend/internal/config"
@@ -81,6 +80,15 @@ func initializePostgres(cfg config.DatabaseConfig) error {
 	
 	pgDB = db
 	
+	if cfg.EnableMigrations {
+		if err := Migrate(db, cfg.MigrationsPath); err != nil {
+			return err
+		}
+		if logger != nil {
+			logger.Info("Database migrations applied")
+		}
+	}
+	
 	poolConfig, err := pgxpool.ParseConfig(cfg.PostgresURL)
 	if err != nil {
 		return fmt.Errorf("failed to parse pgx config: %w", err)
@@ -118,15 +126,15 @@ func initializeRedis(cfg config.RedisConfig) error {
 	
 	if cfg.EnableCluster && len(cfg.ClusterAddresses) > 0 {
 		redisCluster = redis.NewClusterClient(&redis.ClusterOptions{
-			Addrs:        cfg.ClusterAddresses,
-			MaxRetries:   cfg.MaxRetries,
-			DialTimeout:  cfg.DialTimeout,
-			ReadTimeout:  cfg.ReadTimeout,
-			WriteTimeout: cfg.WriteTimeout,
-			PoolSize:     cfg.PoolSize,
-			MinIdleConns: cfg.MinIdleConns,
-			MaxConnAge:   cfg.MaxConnAge,
-			IdleTimeout:  cfg.IdleTimeout,
+			Addrs:           cfg.ClusterAddresses,
+			MaxRetries:      cfg.MaxRetries,
+			DialTimeout:     cfg.DialTimeout,
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+			PoolSize:        cfg.PoolSize,
+			MinIdleConns:    cfg.MinIdleConns,
+			ConnMaxLifetime: cfg.MaxConnAge,
+			ConnMaxIdleTime: cfg.IdleTimeout,
 		})
 		
 		if err := redisCluster.Ping(ctx).Err(); err != nil {
@@ -150,7 +158,7 @@ func initializeRedis(cfg config.RedisConfig) error {
 		opt.WriteTimeout = cfg.WriteTimeout
 		opt.PoolSize = cfg.PoolSize
 		opt.MinIdleConns = cfg.MinIdleConns
-		opt.MaxConnAge = cfg.MaxConnAge
+		opt.ConnMaxLifetime = cfg.MaxConnAge
 		opt.ConnMaxIdleTime = cfg.IdleTimeout
 		
 		redisClient = redis.NewClient(opt)
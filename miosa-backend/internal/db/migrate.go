@@ -2,17 +2,44 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/sormind/OSA/miosa-backend/internal/db/migrations"
 )
 
-func Migrate(db *sql.DB, migrationsPath string) error {
+// Migrator applies schema migrations to the platform database
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator creates a migrator using the migrations embedded in the binary
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("could not load embedded migrations: %w", err)
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("could not create driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return nil, fmt.Errorf("could not create migrate instance: %w", err)
+	}
+	return &Migrator{m: m}, nil
+}
+
+// NewMigratorFromPath creates a migrator reading migrations from a directory
+func NewMigratorFromPath(db *sql.DB, migrationsPath string) (*Migrator, error) {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("could not create driver: %w", err)
+		return nil, fmt.Errorf("could not create driver: %w", err)
 	}
 
 	m, err := migrate.NewWithDatabaseInstance(
@@ -21,49 +48,94 @@ func Migrate(db *sql.DB, migrationsPath string) error {
 		driver,
 	)
 	if err != nil {
-		return fmt.Errorf("could not create migrate instance: %w", err)
+		return nil, fmt.Errorf("could not create migrate instance: %w", err)
 	}
+	return &Migrator{m: m}, nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+// Up applies all pending migrations. Being up to date is not an error.
+func (mg *Migrator) Up() error {
+	if err := mg.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return fmt.Errorf("could not run migrations: %w", err)
 	}
+	return nil
+}
 
+// Down rolls back the given number of migrations
+func (mg *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive")
+	}
+	if err := mg.m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not roll back migrations: %w", err)
+	}
 	return nil
 }
 
-func Rollback(db *sql.DB, migrationsPath string, steps int) error {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+// Goto migrates up or down to a specific version
+func (mg *Migrator) Goto(version uint) error {
+	if err := mg.m.Migrate(version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not migrate to version %d: %w", version, err)
+	}
+	return nil
+}
+
+// Force sets the recorded version without running migrations, clearing the
+// dirty flag left by a failed migration
+func (mg *Migrator) Force(version int) error {
+	return mg.m.Force(version)
+}
+
+// Version returns the current schema version and whether the last migration
+// failed part way. A database with no migrations applied reports version 0.
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Close releases the migration source. The database handle is left open.
+func (mg *Migrator) Close() error {
+	srcErr, _ := mg.m.Close()
+	return srcErr
+}
+
+// Migrate applies all pending migrations, using the embedded migrations when
+// migrationsPath is empty
+func Migrate(db *sql.DB, migrationsPath string) error {
+	mg, err := openMigrator(db, migrationsPath)
 	if err != nil {
 		return err
 	}
+	defer mg.Close()
+	return mg.Up()
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsPath),
-		"postgres",
-		driver,
-	)
+// Rollback rolls back the given number of migrations
+func Rollback(db *sql.DB, migrationsPath string, steps int) error {
+	mg, err := openMigrator(db, migrationsPath)
 	if err != nil {
 		return err
 	}
-
-	return m.Steps(-steps)
+	defer mg.Close()
+	return mg.Down(steps)
 }
 
+// Version returns the current schema version
 func Version(db *sql.DB, migrationsPath string) (uint, bool, error) {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	mg, err := openMigrator(db, migrationsPath)
 	if err != nil {
 		return 0, false, err
 	}
+	defer mg.Close()
+	return mg.Version()
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsPath),
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return 0, false, err
+func openMigrator(db *sql.DB, migrationsPath string) (*Migrator, error) {
+	if migrationsPath == "" {
+		return NewMigrator(db)
 	}
-
-	version, dirty, err := m.Version()
-	return version, dirty, err
-}
\ No newline at end of file
+	return NewMigratorFromPath(db, migrationsPath)
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MigrateUsage describes the arguments accepted by RunMigrateCommand
+const MigrateUsage = `usage: migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [N]       roll back N migrations (default 1)
+  goto V         migrate up or down to version V
+  force V        mark version V as applied without running it (clears dirty state)
+  version        print the current schema version`
+
+// RunMigrateCommand runs a migrate subcommand against db, reading migrations
+// from migrationsPath or the embedded set when it is empty
+func RunMigrateCommand(db *sql.DB, migrationsPath string, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", MigrateUsage)
+	}
+
+	mg, err := openMigrator(db, migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+
+	switch args[0] {
+	case "up":
+		err = mg.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		err = mg.Down(steps)
+	case "goto":
+		if len(args) < 2 {
+			return fmt.Errorf("goto requires a version")
+		}
+		v, perr := strconv.ParseUint(args[1], 10, 64)
+		if perr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = mg.Goto(uint(v))
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("force requires a version")
+		}
+		v, perr := strconv.Atoi(args[1])
+		if perr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = mg.Force(v)
+	case "version":
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], MigrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := mg.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		fmt.Fprintf(out, "schema version %d (dirty: last migration failed; repair the schema, then use force)\n", version)
+	} else {
+		fmt.Fprintf(out, "schema version %d\n", version)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"io/fs"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/sormind/OSA/miosa-backend/internal/db/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_\w+\.(up|down)\.sql$`)
+
+func TestEmbeddedMigrations_Paired(t *testing.T) {
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	directions := make(map[int]map[string]bool)
+	for _, f := range files {
+		m := migrationName.FindStringSubmatch(f)
+		require.NotNil(t, m, "%s does not follow <version>_<name>.<up|down>.sql", f)
+
+		version, _ := strconv.Atoi(m[1])
+		if directions[version] == nil {
+			directions[version] = make(map[string]bool)
+		}
+		directions[version][m[2]] = true
+	}
+
+	for version := 1; version <= len(directions); version++ {
+		d, ok := directions[version]
+		require.True(t, ok, "missing migration %03d", version)
+		assert.True(t, d["up"], "migration %03d has no up file", version)
+		assert.True(t, d["down"], "migration %03d has no down file", version)
+	}
+}
+
+func TestRunMigrateCommand_RequiresCommand(t *testing.T) {
+	err := RunMigrateCommand(nil, "", nil, nil)
+	assert.ErrorContains(t, err, "missing command")
+}
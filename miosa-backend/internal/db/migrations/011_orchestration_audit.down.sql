@@ -0,0 +1,6 @@
+-- Migration 011 Down: Drop orchestration audit trail
+
+DROP TRIGGER IF EXISTS orchestration_audit_no_truncate ON orchestration_audit;
+DROP TRIGGER IF EXISTS orchestration_audit_no_update ON orchestration_audit;
+DROP FUNCTION IF EXISTS orchestration_audit_append_only();
+DROP TABLE IF EXISTS orchestration_audit;
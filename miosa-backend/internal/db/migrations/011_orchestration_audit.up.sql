@@ -0,0 +1,46 @@
+-- Migration 011: Append-only audit trail for orchestration actions
+-- The partitioned audit_logs table covers platform events; this table records
+-- agent operations (orchestrate requests, approvals, improvements, deployments
+-- and file writes) for compliance review.
+
+CREATE TABLE IF NOT EXISTS orchestration_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID,
+    workflow_id UUID,
+
+    -- Actor information
+    actor VARCHAR(255) NOT NULL,
+    actor_type VARCHAR(20) NOT NULL CHECK (actor_type IN ('user', 'api_key', 'system', 'anonymous')),
+
+    -- Action details
+    action VARCHAR(100) NOT NULL,
+    resource VARCHAR(1000),
+    request_hash VARCHAR(64),
+    status VARCHAR(20) NOT NULL DEFAULT 'success' CHECK (status IN ('success', 'failure', 'approved', 'rejected')),
+
+    metadata JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_orchestration_audit_tenant_time ON orchestration_audit(tenant_id, created_at DESC);
+CREATE INDEX idx_orchestration_audit_actor ON orchestration_audit(actor, created_at DESC);
+CREATE INDEX idx_orchestration_audit_workflow ON orchestration_audit(workflow_id) WHERE workflow_id IS NOT NULL;
+CREATE INDEX idx_orchestration_audit_action ON orchestration_audit(action);
+
+-- Reject updates and deletes so the trail is append-only
+CREATE OR REPLACE FUNCTION orchestration_audit_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'orchestration_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orchestration_audit_no_update
+    BEFORE UPDATE OR DELETE ON orchestration_audit
+    FOR EACH ROW
+    EXECUTE FUNCTION orchestration_audit_append_only();
+
+CREATE TRIGGER orchestration_audit_no_truncate
+    BEFORE TRUNCATE ON orchestration_audit
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION orchestration_audit_append_only();
@@ -0,0 +1,4 @@
+-- Migration 012 Down: Drop tenants and prompt templates
+
+DROP TABLE IF EXISTS prompt_templates;
+DROP TABLE IF EXISTS tenants;
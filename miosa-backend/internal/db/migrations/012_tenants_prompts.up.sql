@@ -0,0 +1,38 @@
+-- Migration 012: Tenants and versioned prompt templates
+-- tenant_id columns elsewhere were free-standing UUIDs; this gives tenants a
+-- home and lets agent prompts be versioned per tenant instead of hardcoded.
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    plan VARCHAR(50) NOT NULL DEFAULT 'free',
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'deleted')),
+    settings JSONB DEFAULT '{}',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tenants_status ON tenants(status);
+
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- NULL tenant_id marks the platform default
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    agent VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    version INT NOT NULL DEFAULT 1 CHECK (version >= 1),
+    content TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT false,
+
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_prompt_templates_version
+    ON prompt_templates(COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::UUID), agent, name, version);
+-- Only one active version per tenant, agent and prompt
+CREATE UNIQUE INDEX idx_prompt_templates_active
+    ON prompt_templates(COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::UUID), agent, name)
+    WHERE active;
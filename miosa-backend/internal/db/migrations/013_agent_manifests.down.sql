@@ -0,0 +1,3 @@
+-- Migration 013 Down: Drop agent marketplace manifests
+
+DROP TABLE IF EXISTS agent_manifests;
@@ -0,0 +1,11 @@
+-- Migration 013: Agent marketplace manifests
+-- Agents registered through POST /api/agents/register are stored here and
+-- re-registered when the orchestrator restarts. manifest holds the full
+-- manifest, including the auth token presented to the agent.
+
+CREATE TABLE IF NOT EXISTS agent_manifests (
+    agent_type VARCHAR(63) PRIMARY KEY,
+    endpoint VARCHAR(255) NOT NULL,
+    manifest JSONB NOT NULL,
+    registered_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+-- Migration 014 Down: Drop learned collaboration patterns
+
+DROP TABLE IF EXISTS improvement_results;
+DROP TABLE IF EXISTS improvement_suggestions;
+DROP TABLE IF EXISTS collaboration_patterns;
@@ -0,0 +1,41 @@
+-- Migration 014: Learned collaboration patterns
+-- Patterns used to live only in Redis with no expiry, so a flush lost them
+-- and nothing bounded their growth. Postgres is now the source of truth and
+-- Redis a read-through cache; stale patterns are pruned by the engine.
+
+CREATE TABLE IF NOT EXISTS collaboration_patterns (
+    id UUID PRIMARY KEY,
+    -- task type and agent sequence, as generated by the engine
+    pattern_key VARCHAR(512) NOT NULL UNIQUE,
+    task_type VARCHAR(100) NOT NULL,
+    q_value DOUBLE PRECISION NOT NULL DEFAULT 0,
+    confidence_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    usage_count BIGINT NOT NULL DEFAULT 0,
+    pattern JSONB NOT NULL,
+    last_updated TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_collaboration_patterns_task_type ON collaboration_patterns(task_type, q_value DESC);
+CREATE INDEX idx_collaboration_patterns_last_updated ON collaboration_patterns(last_updated);
+
+CREATE TABLE IF NOT EXISTS improvement_suggestions (
+    id UUID PRIMARY KEY,
+    pattern_id UUID NOT NULL REFERENCES collaboration_patterns(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    expected_impact DOUBLE PRECISION NOT NULL DEFAULT 0,
+    confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+    suggestion JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_improvement_suggestions_pattern ON improvement_suggestions(pattern_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS improvement_results (
+    suggestion_id UUID PRIMARY KEY REFERENCES improvement_suggestions(id) ON DELETE CASCADE,
+    improvement_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+    validated BOOLEAN NOT NULL DEFAULT false,
+    results JSONB NOT NULL,
+    validated_at TIMESTAMPTZ
+);
@@ -0,0 +1,4 @@
+-- Migration 015 Down: Drop gateway chat sessions
+
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS chat_sessions;
@@ -0,0 +1,29 @@
+-- Migration 015: Gateway chat sessions
+-- /api/chat was stateless; sessions keep per-user conversation history so
+-- later turns can be answered in context.
+
+CREATE TABLE IF NOT EXISTS chat_sessions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    model VARCHAR(100) NOT NULL,
+    system_prompt TEXT NOT NULL DEFAULT '',
+    message_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_chat_sessions_owner ON chat_sessions(tenant_id, user_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id UUID PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES chat_sessions(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('system', 'user', 'assistant')),
+    content TEXT NOT NULL,
+    model VARCHAR(100) NOT NULL DEFAULT '',
+    tokens INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_chat_messages_session ON chat_messages(session_id, created_at);
@@ -0,0 +1,3 @@
+-- Migration 016 Down: Drop consultation sessions
+
+DROP TABLE IF EXISTS consultation_sessions;
@@ -0,0 +1,19 @@
+-- Migration 016: Consultation sessions
+-- Consultations move through the initial, exploration and deep-dive phases;
+-- turns and phase transitions are kept with the session.
+
+CREATE TABLE IF NOT EXISTS consultation_sessions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    topic TEXT NOT NULL,
+    context TEXT NOT NULL DEFAULT '',
+    phase VARCHAR(20) NOT NULL CHECK (phase IN ('initial', 'exploration', 'deep-dive', 'complete')),
+    turns JSONB NOT NULL DEFAULT '[]',
+    transitions JSONB NOT NULL DEFAULT '[]',
+    recommendation JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_consultation_sessions_owner ON consultation_sessions(tenant_id, user_id, updated_at DESC);
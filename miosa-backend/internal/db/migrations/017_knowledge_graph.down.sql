@@ -0,0 +1,4 @@
+-- Migration 017 Down: Drop knowledge graphs
+
+DROP TABLE IF EXISTS knowledge_graph_edges;
+DROP TABLE IF EXISTS knowledge_graph_nodes;
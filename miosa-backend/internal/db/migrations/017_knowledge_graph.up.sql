@@ -0,0 +1,27 @@
+-- Migration 017: Project knowledge graphs
+-- Services, endpoints, tables and dependencies of a generated project, as
+-- designed by the analysis and architecture agents and found in its code.
+
+CREATE TABLE IF NOT EXISTS knowledge_graph_nodes (
+    project_id UUID NOT NULL,
+    id TEXT NOT NULL,
+    tenant_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('service', 'endpoint', 'table', 'dependency')),
+    name TEXT NOT NULL,
+    attrs JSONB NOT NULL DEFAULT '{}',
+    sources JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, id)
+);
+
+CREATE INDEX idx_knowledge_graph_nodes_kind ON knowledge_graph_nodes(project_id, kind);
+
+CREATE TABLE IF NOT EXISTS knowledge_graph_edges (
+    project_id UUID NOT NULL,
+    from_id TEXT NOT NULL,
+    to_id TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    PRIMARY KEY (project_id, from_id, to_id, type),
+    FOREIGN KEY (project_id, from_id) REFERENCES knowledge_graph_nodes(project_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id, to_id) REFERENCES knowledge_graph_nodes(project_id, id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- Migration 018 Down: Drop scheduled workflows
+
+DROP TABLE IF EXISTS workflow_schedule_runs;
+DROP TABLE IF EXISTS workflow_schedules;
@@ -0,0 +1,32 @@
+-- Migration 018: Scheduled workflows
+-- Recurring orchestrations registered by tenants, and the history of their
+-- runs.
+
+CREATE TABLE IF NOT EXISTS workflow_schedules (
+    id UUID PRIMARY KEY,
+    tenant TEXT NOT NULL,
+    name TEXT NOT NULL,
+    cron TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT '',
+    overlap VARCHAR(10) NOT NULL CHECK (overlap IN ('skip', 'queue', 'allow')),
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    request JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_workflow_schedules_tenant ON workflow_schedules(tenant);
+
+CREATE TABLE IF NOT EXISTS workflow_schedule_runs (
+    id UUID PRIMARY KEY,
+    schedule_id UUID NOT NULL REFERENCES workflow_schedules(id) ON DELETE CASCADE,
+    workflow_id UUID,
+    trigger VARCHAR(10) NOT NULL CHECK (trigger IN ('schedule', 'manual')),
+    status VARCHAR(10) NOT NULL CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'skipped')),
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_workflow_schedule_runs_schedule ON workflow_schedule_runs(schedule_id, scheduled_for DESC);
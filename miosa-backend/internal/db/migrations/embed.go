@@ -0,0 +1,10 @@
+// Package migrations embeds the platform's SQL schema migrations so binaries
+// can apply them without shipping the .sql files.
+package migrations
+
+import "embed"
+
+// FS holds the numbered <version>_<name>.up.sql / .down.sql migrations
+//
+//go:embed *.up.sql *.down.sql
+var FS embed.FS
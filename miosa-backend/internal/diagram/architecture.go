@@ -0,0 +1,170 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/graph"
+)
+
+// MaxServiceEndpoints bounds the endpoints listed in a service's box
+const MaxServiceEndpoints = 8
+
+// relations maps the graph edges drawn to their label and style; services
+// exposing endpoints are drawn inside the service instead
+var relations = map[string]struct {
+	label  string
+	dotted bool
+}{
+	graph.EdgeCalls:      {"calls", false},
+	graph.EdgeUses:       {"uses", false},
+	graph.EdgeDependsOn:  {"depends on", true},
+	graph.EdgeReferences: {"references", true},
+}
+
+var shapes = map[graph.Kind]Shape{
+	graph.KindService:    ShapeRound,
+	graph.KindTable:      ShapeCylinder,
+	graph.KindDependency: ShapeHexagon,
+}
+
+var nonWord = regexp.MustCompile(`\W+`)
+
+// ids assigns diagram identifiers to graph nodes, unique and safe for both
+// Mermaid and PlantUML
+type ids map[string]string
+
+func (m ids) assign(n *graph.Node) string {
+	prefix := map[graph.Kind]string{graph.KindService: "svc", graph.KindTable: "tbl", graph.KindDependency: "dep"}[n.Kind]
+	base := prefix + "_" + strings.Trim(nonWord.ReplaceAllString(n.Name, "_"), "_")
+	id := base
+	for i := 2; m.taken(id); i++ {
+		id = fmt.Sprintf("%s_%d", base, i)
+	}
+	m[n.ID] = id
+	return id
+}
+
+func (m ids) taken(id string) bool {
+	for _, v := range m {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// drawn returns the services, tables and dependencies of g in a stable
+// order, with the endpoints each service exposes
+func drawn(g *graph.Graph) ([]*graph.Node, map[string][]string) {
+	var nodes []*graph.Node
+	for _, n := range g.Nodes {
+		if _, ok := shapes[n.Kind]; ok {
+			nodes = append(nodes, n)
+		}
+	}
+	order := map[graph.Kind]int{graph.KindService: 0, graph.KindTable: 1, graph.KindDependency: 2}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Kind != nodes[j].Kind {
+			return order[nodes[i].Kind] < order[nodes[j].Kind]
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	endpoints := make(map[string][]string)
+	for _, e := range g.Edges {
+		if e.Type == graph.EdgeExposes {
+			if n := g.Node(e.To); n != nil {
+				endpoints[e.From] = append(endpoints[e.From], n.Name)
+			}
+		}
+	}
+	for id := range endpoints {
+		sort.Strings(endpoints[id])
+	}
+	return nodes, endpoints
+}
+
+// FromGraph draws the services, tables and dependencies of g, left to
+// right, with each service listing the endpoints it exposes. It returns nil
+// when g has none of them.
+func FromGraph(g *graph.Graph) *Flowchart {
+	nodes, endpoints := drawn(g)
+	if len(nodes) == 0 {
+		return nil
+	}
+	f := &Flowchart{Direction: "LR"}
+	names := ids{}
+	for _, n := range nodes {
+		label := n.Name
+		if eps := endpoints[n.ID]; len(eps) > 0 {
+			shown := eps
+			if len(shown) > MaxServiceEndpoints {
+				shown = shown[:MaxServiceEndpoints]
+			}
+			label += "\n" + strings.Join(shown, "\n")
+			if more := len(eps) - len(shown); more > 0 {
+				label += fmt.Sprintf("\n… %d more", more)
+			}
+		}
+		f.Nodes = append(f.Nodes, FlowNode{ID: names.assign(n), Label: label, Shape: shapes[n.Kind]})
+	}
+	for _, e := range g.Edges {
+		rel, ok := relations[e.Type]
+		from, to := names[e.From], names[e.To]
+		if ok && from != "" && to != "" {
+			f.Edges = append(f.Edges, FlowEdge{From: from, To: to, Label: rel.label, Dotted: rel.dotted})
+		}
+	}
+	return f
+}
+
+// plantQuote quotes a string for a C4-PlantUML macro argument
+func plantQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `"`, `'`), "\n", `\n`) + `"`
+}
+
+// C4 draws g as a C4-PlantUML container diagram: services as containers
+// inside the system boundary, tables as databases and dependencies as
+// external systems. It returns "" when g has none of them.
+func C4(g *graph.Graph) string {
+	nodes, endpoints := drawn(g)
+	if len(nodes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("@startuml\n!include <C4/C4_Container>\n\nSystem_Boundary(project, \"Project\") {\n")
+	names := ids{}
+	var external []string
+	for _, n := range nodes {
+		id := names.assign(n)
+		switch n.Kind {
+		case graph.KindService:
+			eps := endpoints[n.ID]
+			if len(eps) > MaxServiceEndpoints {
+				eps = append(eps[:MaxServiceEndpoints:MaxServiceEndpoints], fmt.Sprintf("… %d more", len(endpoints[n.ID])-MaxServiceEndpoints))
+			}
+			fmt.Fprintf(&sb, "    Container(%s, %s, \"service\", %s)\n", id, plantQuote(n.Name), plantQuote(strings.Join(eps, "\n")))
+		case graph.KindTable:
+			fmt.Fprintf(&sb, "    ContainerDb(%s, %s, \"table\", %s)\n", id, plantQuote(n.Name), plantQuote(n.Attrs["columns"]))
+		case graph.KindDependency:
+			external = append(external, fmt.Sprintf("System_Ext(%s, %s)", id, plantQuote(n.Name)))
+		}
+	}
+	sb.WriteString("}\n")
+	for _, line := range external {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+	for _, e := range g.Edges {
+		rel, ok := relations[e.Type]
+		from, to := names[e.From], names[e.To]
+		if ok && from != "" && to != "" {
+			fmt.Fprintf(&sb, "Rel(%s, %s, %s)\n", from, to, plantQuote(rel.label))
+		}
+	}
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
@@ -0,0 +1,112 @@
+package diagram
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sample() *graph.Graph {
+	g := graph.New(uuid.New())
+	orders := g.Add(graph.KindService, "orders", "", nil)
+	payments := g.Add(graph.KindService, "payments", "", nil)
+	g.Link(orders, g.Add(graph.KindEndpoint, "GET /orders/{id}", "", nil), graph.EdgeExposes)
+	g.Link(orders, g.Add(graph.KindEndpoint, `POST /orders?note="x"`, "", nil), graph.EdgeExposes)
+	g.Link(orders, payments, graph.EdgeCalls)
+	table := g.Add(graph.KindTable, "orders", "", map[string]string{"columns": "id, total"})
+	g.Link(orders, table, graph.EdgeUses)
+	g.Link(orders, g.Add(graph.KindDependency, "github.com/gin-gonic/gin", "", nil), graph.EdgeDependsOn)
+	g.Add(graph.KindEndpoint, "GET /healthz", "", nil)
+	return g
+}
+
+func TestFromGraph(t *testing.T) {
+	assert.Nil(t, FromGraph(graph.New(uuid.New())))
+
+	f := FromGraph(sample())
+	require.NotNil(t, f)
+	assert.Equal(t, []FlowNode{
+		{ID: "svc_orders", Label: "orders\nGET /orders/{id}\nPOST /orders?note=\"x\"", Shape: ShapeRound},
+		{ID: "svc_payments", Label: "payments", Shape: ShapeRound},
+		{ID: "tbl_orders", Label: "orders", Shape: ShapeCylinder},
+		{ID: "dep_github_com_gin_gonic_gin", Label: "github.com/gin-gonic/gin", Shape: ShapeHexagon},
+	}, f.Nodes, "endpoints are listed in their service; unowned ones are left out")
+	assert.Contains(t, f.Edges, FlowEdge{From: "svc_orders", To: "dep_github_com_gin_gonic_gin", Label: "depends on", Dotted: true})
+
+	// What String writes, ParseMermaid reads back
+	src := f.String()
+	assert.Contains(t, src, `svc_orders("orders<br/>GET /orders/{id}<br/>POST /orders?note=#quot;x#quot;")`)
+	assert.Contains(t, src, "svc_orders -->|calls| svc_payments")
+	parsed, err := ParseMermaid(src)
+	require.NoError(t, err)
+	assert.Equal(t, f, parsed)
+}
+
+func TestParseMermaid(t *testing.T) {
+	f, err := ParseMermaid("%% comment\ngraph TD\n  a[\"A\"]\n  b{{\"B\"}}\n\n  a -.-> b\n")
+	require.NoError(t, err)
+	assert.Equal(t, "TB", f.Direction)
+	assert.Equal(t, []FlowEdge{{From: "a", To: "b", Dotted: true}}, f.Edges)
+
+	for src, msg := range map[string]string{
+		"":                                     "empty flowchart",
+		"sequenceDiagram\n":                    "line 1: expected a flowchart header",
+		"flowchart LR\n  a[\"A\")\n":           "line 2: mismatched brackets",
+		"flowchart LR\n  a[\"A\"]\n  a --> b":  "line 3: undeclared node b",
+		"flowchart LR\n  a[\"A\"]\n  a[\"B\"]": "line 3: node a declared twice",
+		"flowchart LR\n  click a call x()":     "line 2: unsupported statement",
+	} {
+		_, err := ParseMermaid(src)
+		require.Error(t, err, src)
+		assert.Contains(t, err.Error(), msg)
+	}
+}
+
+func TestC4(t *testing.T) {
+	assert.Empty(t, C4(graph.New(uuid.New())))
+
+	src := C4(sample())
+	assert.True(t, strings.HasPrefix(src, "@startuml\n!include <C4/C4_Container>"))
+	assert.Contains(t, src, `Container(svc_orders, "orders", "service", "GET /orders/{id}\nPOST /orders?note='x'")`)
+	assert.Contains(t, src, `ContainerDb(tbl_orders, "orders", "table", "id, total")`)
+	assert.Contains(t, src, `System_Ext(dep_github_com_gin_gonic_gin, "github.com/gin-gonic/gin")`)
+	assert.Contains(t, src, `Rel(svc_orders, svc_payments, "calls")`)
+	assert.True(t, strings.HasSuffix(src, "@enduml\n"))
+}
+
+func TestSVG(t *testing.T) {
+	for _, dir := range []string{"LR", "TB", "RL", "BT"} {
+		f := FromGraph(sample())
+		f.Direction = dir
+		svg := f.SVG()
+
+		// Well-formed, with labels escaped
+		dec := xml.NewDecoder(bytes.NewReader(svg))
+		var text strings.Builder
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, dir)
+			if cd, ok := tok.(xml.CharData); ok {
+				text.Write(cd)
+			}
+		}
+		assert.Contains(t, text.String(), `POST /orders?note="x"`)
+		assert.Equal(t, len(f.Edges), strings.Count(string(svg), "<line "), dir)
+		assert.Contains(t, string(svg), `stroke-dasharray`)
+	}
+
+	// Cycles still lay out
+	f, err := ParseMermaid("flowchart LR\n a(\"a\")\n b(\"b\")\n a --> b\n b --> a\n")
+	require.NoError(t, err)
+	assert.Contains(t, string(f.SVG()), "<svg")
+}
@@ -0,0 +1,156 @@
+// Package diagram draws a project's architecture from its knowledge graph as
+// Mermaid flowcharts and C4-PlantUML, and renders flowcharts to SVG without
+// a browser so the IDE can preview them.
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Shape is how a flowchart node is drawn
+type Shape string
+
+// Node shapes, by their Mermaid brackets
+const (
+	ShapeBox      Shape = "box"      // id["label"]
+	ShapeRound    Shape = "round"    // id("label")
+	ShapeCylinder Shape = "cylinder" // id[("label")]
+	ShapeHexagon  Shape = "hexagon"  // id{{"label"}}
+)
+
+var brackets = map[Shape][2]string{
+	ShapeBox:      {"[", "]"},
+	ShapeRound:    {"(", ")"},
+	ShapeCylinder: {"[(", ")]"},
+	ShapeHexagon:  {"{{", "}}"},
+}
+
+// FlowNode is a flowchart node. Labels may span lines.
+type FlowNode struct {
+	ID    string
+	Label string
+	Shape Shape
+}
+
+// FlowEdge is an arrow between two nodes, dotted for weaker relations
+type FlowEdge struct {
+	From   string
+	To     string
+	Label  string
+	Dotted bool
+}
+
+// Flowchart is the subset of Mermaid flowcharts this package writes, parses
+// and renders
+type Flowchart struct {
+	Direction string // LR, RL, TB or BT
+	Nodes     []FlowNode
+	Edges     []FlowEdge
+}
+
+// Node returns the node with id, or nil
+func (f *Flowchart) Node(id string) *FlowNode {
+	for i := range f.Nodes {
+		if f.Nodes[i].ID == id {
+			return &f.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// escapeLabel quotes a label for Mermaid
+func escapeLabel(label string) string {
+	label = strings.ReplaceAll(label, `"`, "#quot;")
+	return strings.ReplaceAll(label, "\n", "<br/>")
+}
+
+func unescapeLabel(label string) string {
+	label = strings.ReplaceAll(label, "<br/>", "\n")
+	return strings.ReplaceAll(label, "#quot;", `"`)
+}
+
+// String returns the flowchart's Mermaid source
+func (f *Flowchart) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "flowchart %s\n", f.Direction)
+	for _, n := range f.Nodes {
+		b := brackets[n.Shape]
+		fmt.Fprintf(&sb, "    %s%s\"%s\"%s\n", n.ID, b[0], escapeLabel(n.Label), b[1])
+	}
+	for _, e := range f.Edges {
+		arrow := "-->"
+		if e.Dotted {
+			arrow = "-.->"
+		}
+		if e.Label != "" {
+			arrow += "|" + escapeLabel(e.Label) + "|"
+		}
+		fmt.Fprintf(&sb, "    %s %s %s\n", e.From, arrow, e.To)
+	}
+	return sb.String()
+}
+
+var (
+	header   = regexp.MustCompile(`^(?:flowchart|graph)\s+(LR|RL|TB|TD|BT)$`)
+	nodeDecl = regexp.MustCompile(`^([A-Za-z_][\w-]*)(\[\(|\{\{|\[|\()"([^"]*)"(\)\]|\}\}|\]|\))$`)
+	edgeDecl = regexp.MustCompile(`^([A-Za-z_][\w-]*)\s+(-->|-\.->)(?:\|([^|]*)\|)?\s+([A-Za-z_][\w-]*)$`)
+)
+
+// ParseMermaid parses and validates a flowchart in the subset String
+// writes: a header, nodes with quoted labels declared before use, plain
+// and dotted arrows with optional labels, and %% comments. Anything else
+// is an error naming its line.
+func ParseMermaid(src string) (*Flowchart, error) {
+	f := &Flowchart{}
+	declared := make(map[string]bool)
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		if f.Direction == "" {
+			m := header.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: expected a flowchart header, got %q", i+1, line)
+			}
+			f.Direction = m[1]
+			if f.Direction == "TD" {
+				f.Direction = "TB"
+			}
+			continue
+		}
+		if m := nodeDecl.FindStringSubmatch(line); m != nil {
+			shape := Shape("")
+			for s, b := range brackets {
+				if b[0] == m[2] && b[1] == m[4] {
+					shape = s
+				}
+			}
+			if shape == "" {
+				return nil, fmt.Errorf("line %d: mismatched brackets %s…%s", i+1, m[2], m[4])
+			}
+			if declared[m[1]] {
+				return nil, fmt.Errorf("line %d: node %s declared twice", i+1, m[1])
+			}
+			declared[m[1]] = true
+			f.Nodes = append(f.Nodes, FlowNode{ID: m[1], Label: unescapeLabel(m[3]), Shape: shape})
+			continue
+		}
+		if m := edgeDecl.FindStringSubmatch(line); m != nil {
+			for _, id := range []string{m[1], m[4]} {
+				if !declared[id] {
+					return nil, fmt.Errorf("line %d: undeclared node %s", i+1, id)
+				}
+			}
+			f.Edges = append(f.Edges, FlowEdge{From: m[1], To: m[4], Label: unescapeLabel(m[3]), Dotted: m[2] == "-.->"})
+			continue
+		}
+		return nil, fmt.Errorf("line %d: unsupported statement %q", i+1, line)
+	}
+	if f.Direction == "" {
+		return nil, fmt.Errorf("empty flowchart")
+	}
+	return f, nil
+}
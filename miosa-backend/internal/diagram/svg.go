@@ -0,0 +1,188 @@
+package diagram
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout constants of rendered flowcharts, in pixels
+const (
+	charWidth  = 7
+	lineHeight = 16
+	padding    = 12
+	minWidth   = 80
+	rankGap    = 90
+	nodeGap    = 30
+	margin     = 20
+)
+
+type box struct {
+	x, y, w, h float64 // Top-left corner and size
+	lines      []string
+}
+
+func (b box) center() (float64, float64) {
+	return b.x + b.w/2, b.y + b.h/2
+}
+
+// border returns where the line from b's center towards (x, y) leaves b
+func (b box) border(x, y float64) (float64, float64) {
+	cx, cy := b.center()
+	dx, dy := x-cx, y-cy
+	if dx == 0 && dy == 0 {
+		return cx, cy
+	}
+	t := math.Inf(1)
+	if dx != 0 {
+		t = math.Min(t, b.w/2/math.Abs(dx))
+	}
+	if dy != 0 {
+		t = math.Min(t, b.h/2/math.Abs(dy))
+	}
+	return cx + dx*t, cy + dy*t
+}
+
+// ranks places each node one rank after the furthest node with an arrow to
+// it. Cycles stop growing once ranks reach the node count.
+func (f *Flowchart) ranks() map[string]int {
+	rank := make(map[string]int, len(f.Nodes))
+	for range f.Nodes {
+		changed := false
+		for _, e := range f.Edges {
+			if e.From != e.To && rank[e.To] < rank[e.From]+1 && rank[e.From]+1 < len(f.Nodes) {
+				rank[e.To] = rank[e.From] + 1
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return rank
+}
+
+// layout sizes each node to its label and stacks the nodes of each rank,
+// ranks running along the flowchart's direction
+func (f *Flowchart) layout() (map[string]*box, float64, float64) {
+	rank := f.ranks()
+	boxes := make(map[string]*box, len(f.Nodes))
+	var columns [][]*box
+	for _, n := range f.Nodes {
+		lines := strings.Split(n.Label, "\n")
+		widest := 0
+		for _, l := range lines {
+			if c := utf8.RuneCountInString(l); c > widest {
+				widest = c
+			}
+		}
+		b := &box{
+			w:     math.Max(minWidth, float64(widest*charWidth+2*padding)),
+			h:     float64(len(lines)*lineHeight + 2*padding),
+			lines: lines,
+		}
+		boxes[n.ID] = b
+		r := rank[n.ID]
+		for len(columns) <= r {
+			columns = append(columns, nil)
+		}
+		columns[r] = append(columns[r], b)
+	}
+
+	vertical := f.Direction == "TB" || f.Direction == "BT"
+	along, width, height := float64(margin), 0.0, 0.0
+	for _, col := range columns {
+		across, depth := float64(margin), 0.0
+		for _, b := range col {
+			if vertical {
+				b.x, b.y = across, along
+				across += b.w + nodeGap
+				depth = math.Max(depth, b.h)
+			} else {
+				b.x, b.y = along, across
+				across += b.h + nodeGap
+				depth = math.Max(depth, b.w)
+			}
+		}
+		along += depth + rankGap
+		if vertical {
+			width = math.Max(width, across-nodeGap+margin)
+		} else {
+			height = math.Max(height, across-nodeGap+margin)
+		}
+	}
+	if vertical {
+		height = along - rankGap + margin
+	} else {
+		width = along - rankGap + margin
+	}
+
+	// Right to left and bottom to top mirror the layout
+	for _, b := range boxes {
+		switch f.Direction {
+		case "RL":
+			b.x = width - b.x - b.w
+		case "BT":
+			b.y = height - b.y - b.h
+		}
+	}
+	return boxes, width, height
+}
+
+// SVG renders the flowchart as a standalone SVG image
+func (f *Flowchart) SVG() []byte {
+	boxes, width, height := f.layout()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	sb.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#555"/></marker></defs>` + "\n")
+
+	for _, e := range f.Edges {
+		from, to := boxes[e.From], boxes[e.To]
+		if from == nil || to == nil || from == to {
+			continue
+		}
+		fx, fy := from.center()
+		tx, ty := to.center()
+		x1, y1 := from.border(tx, ty)
+		x2, y2 := to.border(fx, fy)
+		dash := ""
+		if e.Dotted {
+			dash = ` stroke-dasharray="4 3"`
+		}
+		fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#555"%s marker-end="url(#arrow)"/>`+"\n", x1, y1, x2, y2, dash)
+		if e.Label != "" {
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#555" font-size="10">%s</text>`+"\n", (x1+x2)/2, (y1+y2)/2-4, html.EscapeString(e.Label))
+		}
+	}
+
+	for _, n := range f.Nodes {
+		b := boxes[n.ID]
+		style := `fill="#f4f6fb" stroke="#334"`
+		switch n.Shape {
+		case ShapeRound:
+			fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="12" %s/>`+"\n", b.x, b.y, b.w, b.h, style)
+		case ShapeCylinder:
+			fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="4" %s/>`+"\n", b.x, b.y, b.w, b.h, style)
+			fmt.Fprintf(&sb, `<ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="5" %s/>`+"\n", b.x+b.w/2, b.y, b.w/2, style)
+		case ShapeHexagon:
+			in := math.Min(padding, b.w/4)
+			fmt.Fprintf(&sb, `<polygon points="%.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f %.1f,%.1f" %s/>`+"\n",
+				b.x+in, b.y, b.x+b.w-in, b.y, b.x+b.w, b.y+b.h/2, b.x+b.w-in, b.y+b.h, b.x+in, b.y+b.h, b.x, b.y+b.h/2, style)
+		default:
+			fmt.Fprintf(&sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" %s/>`+"\n", b.x, b.y, b.w, b.h, style)
+		}
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="middle">`, b.x+b.w/2, b.y+padding+lineHeight-4)
+		for i, line := range b.lines {
+			dy, weight := lineHeight, ""
+			if i == 0 {
+				dy, weight = 0, ` font-weight="bold"`
+			}
+			fmt.Fprintf(&sb, `<tspan x="%.1f" dy="%d"%s>%s</tspan>`, b.x+b.w/2, dy, weight, html.EscapeString(line))
+		}
+		sb.WriteString("</text>\n")
+	}
+	sb.WriteString("</svg>\n")
+	return []byte(sb.String())
+}
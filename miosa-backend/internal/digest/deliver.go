@@ -0,0 +1,114 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sormind/OSA/miosa-backend/internal/slack"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook body, keyed with
+// the webhook secret, as "sha256=<hex>"
+const SignatureHeader = "X-Miosa-Signature"
+
+// SlackSender posts digests with the Slack bot
+type SlackSender struct {
+	Client *slack.Client
+}
+
+// Send posts the digest to channel
+func (s *SlackSender) Send(ctx context.Context, channel string, d *Digest) error {
+	_, err := s.Client.PostMessage(ctx, slack.Message{Channel: channel, Text: "*" + d.Title() + "*\n\n" + d.Summary})
+	return err
+}
+
+// WebhookSender posts digests as JSON, signed when Secret is set
+type WebhookSender struct {
+	Secret string
+	HTTP   *http.Client
+}
+
+// NewWebhookSender creates a sender signing bodies with secret
+func NewWebhookSender(secret string) *WebhookSender {
+	return &WebhookSender{Secret: secret, HTTP: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Send posts the digest to url
+func (s *WebhookSender) Send(ctx context.Context, url string, d *Digest) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post digest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("digest webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailSender mails digests through an SMTP server
+type EmailSender struct {
+	Addr     string // host:port
+	From     string
+	Username string // Authenticates with PLAIN when set
+	Password string
+
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Send mails the digest to every address in to
+func (s *EmailSender) Send(ctx context.Context, to []string, d *Digest) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address %q: %w", s.Addr, err)
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	send := s.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if err := send(s.Addr, auth, s.From, to, s.message(to, d)); err != nil {
+		return fmt.Errorf("failed to mail digest: %w", err)
+	}
+	return nil
+}
+
+// message is the digest as a plain text email
+func (s *EmailSender) message(to []string, d *Digest) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", s.From)
+	fmt.Fprintf(&sb, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&sb, "Subject: %s\r\n", d.Title())
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	sb.WriteString(strings.ReplaceAll(d.Summary, "\n", "\r\n"))
+	sb.WriteString("\r\n")
+	return []byte(sb.String())
+}
@@ -0,0 +1,243 @@
+// Package digest compiles daily and weekly summaries of each tenant's
+// workflows — how many ran, how many succeeded, what they cost, the quality
+// issues seen most often and the self-improvements applied — and delivers
+// them to the tenant's Slack channel, webhook or email addresses.
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Period is how often a digest is sent and how much it covers
+type Period string
+
+const (
+	Daily  Period = "daily"
+	Weekly Period = "weekly"
+)
+
+// Duration is the time a digest of the period covers
+func (p Period) Duration() time.Duration {
+	if p == Weekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Valid reports whether p is a known period
+func (p Period) Valid() bool {
+	return p == Daily || p == Weekly
+}
+
+// MaxTopIssues bounds the issues a digest lists
+const MaxTopIssues = 5
+
+// Issue is a quality problem found in a workflow's output
+type Issue struct {
+	Source string `json:"source"` // The check that found it: tests, security, frontend, monorepo, routes
+	Kind   string `json:"kind"`   // What it is, e.g. a security rule or a TypeScript error code
+}
+
+// Run is one completed workflow, as the digest counts it
+type Run struct {
+	WorkflowID  string    `json:"workflow_id"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	Success     bool      `json:"success"`
+	CostUSD     float64   `json:"cost_usd"`
+	Issues      []Issue   `json:"issues,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Improvement is a self-improvement applied to the orchestrator. It applies
+// to every tenant, so every digest lists it.
+type Improvement struct {
+	Kind      string    `json:"kind"`
+	Summary   string    `json:"summary"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// IssueCount is how many workflows an issue was found in
+type IssueCount struct {
+	Issue
+	Workflows int `json:"workflows"`
+}
+
+// Digest summarizes a tenant's workflows over one period
+type Digest struct {
+	TenantID     string        `json:"tenant_id"`
+	Period       Period        `json:"period"`
+	From         time.Time     `json:"from"`
+	To           time.Time     `json:"to"`
+	Workflows    int           `json:"workflows"`
+	Succeeded    int           `json:"succeeded"`
+	SuccessRate  float64       `json:"success_rate"` // Percent, 0-100
+	SpendUSD     float64       `json:"spend_usd"`
+	TopIssues    []IssueCount  `json:"top_issues,omitempty"`
+	Improvements []Improvement `json:"improvements,omitempty"`
+	Summary      string        `json:"summary"` // Rendered for people to read
+}
+
+// Compile summarizes the runs of tenant completed in [from, to), with the
+// improvements applied in the same window
+func Compile(tenant string, period Period, from, to time.Time, runs []Run, improvements []Improvement) *Digest {
+	d := &Digest{TenantID: tenant, Period: period, From: from, To: to}
+	counts := make(map[Issue]int)
+	for _, r := range runs {
+		if r.TenantID != tenant || r.CompletedAt.Before(from) || !r.CompletedAt.Before(to) {
+			continue
+		}
+		d.Workflows++
+		if r.Success {
+			d.Succeeded++
+		}
+		d.SpendUSD += r.CostUSD
+		// An issue counts once per workflow however often it was found
+		seen := make(map[Issue]bool)
+		for _, issue := range r.Issues {
+			if !seen[issue] {
+				seen[issue] = true
+				counts[issue]++
+			}
+		}
+	}
+	if d.Workflows > 0 {
+		d.SuccessRate = float64(d.Succeeded) * 100 / float64(d.Workflows)
+	}
+
+	for issue, n := range counts {
+		d.TopIssues = append(d.TopIssues, IssueCount{Issue: issue, Workflows: n})
+	}
+	sort.Slice(d.TopIssues, func(i, j int) bool {
+		a, b := d.TopIssues[i], d.TopIssues[j]
+		if a.Workflows != b.Workflows {
+			return a.Workflows > b.Workflows
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Kind < b.Kind
+	})
+	if len(d.TopIssues) > MaxTopIssues {
+		d.TopIssues = d.TopIssues[:MaxTopIssues]
+	}
+
+	for _, imp := range improvements {
+		if !imp.AppliedAt.Before(from) && imp.AppliedAt.Before(to) {
+			d.Improvements = append(d.Improvements, imp)
+		}
+	}
+	sort.Slice(d.Improvements, func(i, j int) bool { return d.Improvements[i].AppliedAt.Before(d.Improvements[j].AppliedAt) })
+	return d
+}
+
+// Empty reports whether nothing happened in the period
+func (d *Digest) Empty() bool {
+	return d.Workflows == 0 && len(d.Improvements) == 0
+}
+
+// Title names the digest by the day it starts, e.g. "Daily digest for
+// acme, 2026-10-15"
+func (d *Digest) Title() string {
+	day := d.From.UTC().Format("2006-01-02")
+	if d.Period == Weekly {
+		day += " to " + d.To.Add(-time.Nanosecond).UTC().Format("2006-01-02")
+	}
+	name := strings.ToUpper(string(d.Period[:1])) + string(d.Period[1:])
+	tenant := d.TenantID
+	if tenant == "" {
+		tenant = "workflows without a tenant"
+	}
+	return fmt.Sprintf("%s digest for %s, %s", name, tenant, day)
+}
+
+// Facts lists the digest's numbers, one per line; it is the plain
+// rendering, and what the communication agent writes its summary from
+func (d *Digest) Facts() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Workflows run: %d\n", d.Workflows)
+	if d.Workflows > 0 {
+		fmt.Fprintf(&sb, "Succeeded: %d (%.0f%%)\n", d.Succeeded, d.SuccessRate)
+	}
+	fmt.Fprintf(&sb, "Spend: $%.2f\n", d.SpendUSD)
+	if len(d.TopIssues) > 0 {
+		sb.WriteString("Top quality issues:\n")
+		for _, issue := range d.TopIssues {
+			fmt.Fprintf(&sb, "- %s %s, in %d workflow(s)\n", issue.Source, issue.Kind, issue.Workflows)
+		}
+	}
+	if len(d.Improvements) > 0 {
+		sb.WriteString("Self-improvements applied:\n")
+		for _, imp := range d.Improvements {
+			fmt.Fprintf(&sb, "- %s: %s\n", imp.AppliedAt.UTC().Format("2006-01-02 15:04"), imp.Summary)
+		}
+	}
+	return sb.String()
+}
+
+// Prompt asks the communication agent to write the digest for the tenant
+func (d *Digest) Prompt() string {
+	return fmt.Sprintf(`Write the team's %s digest, titled "%s". In a short paragraph, say how the period went and point out anything that needs attention, then keep the facts below as a list. Use only these facts; do not invent numbers.
+
+%s`, d.Period, d.Title(), d.Facts())
+}
+
+// Log keeps the runs the digests are compiled from. It holds what the
+// longest period needs and drops older runs.
+type Log struct {
+	mu   sync.Mutex
+	runs []Run
+	keep time.Duration
+}
+
+// NewLog creates an empty log keeping a week and a day of runs
+func NewLog() *Log {
+	return &Log{keep: Weekly.Duration() + Daily.Duration()}
+}
+
+// Record adds a completed workflow
+func (l *Log) Record(r Run) {
+	if r.CompletedAt.IsZero() {
+		r.CompletedAt = time.Now()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runs = append(l.runs, r)
+	// Runs arrive in completion order, so the expired ones are first
+	cutoff := r.CompletedAt.Add(-l.keep)
+	i := 0
+	for i < len(l.runs) && l.runs[i].CompletedAt.Before(cutoff) {
+		i++
+	}
+	l.runs = l.runs[i:]
+}
+
+// Since returns the runs completed since from
+func (l *Log) Since(from time.Time) []Run {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var runs []Run
+	for _, r := range l.runs {
+		if !r.CompletedAt.Before(from) {
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
+
+// Tenants returns the tenants with runs since from, sorted
+func (l *Log) Tenants(from time.Time) []string {
+	seen := make(map[string]bool)
+	var tenants []string
+	for _, r := range l.Since(from) {
+		if !seen[r.TenantID] {
+			seen[r.TenantID] = true
+			tenants = append(tenants, r.TenantID)
+		}
+	}
+	sort.Strings(tenants)
+	return tenants
+}
@@ -0,0 +1,177 @@
+package digest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// monday is 2026-10-12 08:00 UTC, a weekly boundary at hour 8
+var monday = time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+
+func TestCompile(t *testing.T) {
+	from, to := monday.Add(-24*time.Hour), monday
+	runs := []Run{
+		{TenantID: "acme", Success: true, CostUSD: 0.25, CompletedAt: from.Add(time.Hour),
+			Issues: []Issue{{"security", "sql_injection"}, {"security", "sql_injection"}, {"tests", "failing_tests"}}},
+		{TenantID: "acme", Success: false, CostUSD: 0.5, CompletedAt: from.Add(2 * time.Hour),
+			Issues: []Issue{{"security", "sql_injection"}}},
+		{TenantID: "acme", Success: true, CostUSD: 9, CompletedAt: to}, // After the window
+		{TenantID: "other", Success: true, CostUSD: 9, CompletedAt: from.Add(time.Hour)},
+	}
+	improvements := []Improvement{
+		{Kind: "agent_swap", Summary: "swap", AppliedAt: from.Add(3 * time.Hour)},
+		{Kind: "model", Summary: "old", AppliedAt: from.Add(-time.Hour)},
+	}
+
+	d := Compile("acme", Daily, from, to, runs, improvements)
+	assert.Equal(t, 2, d.Workflows)
+	assert.Equal(t, 1, d.Succeeded)
+	assert.Equal(t, 50.0, d.SuccessRate)
+	assert.InDelta(t, 0.75, d.SpendUSD, 1e-9)
+	require.Len(t, d.TopIssues, 2)
+	assert.Equal(t, IssueCount{Issue: Issue{"security", "sql_injection"}, Workflows: 2}, d.TopIssues[0], "issues count once per workflow")
+	require.Len(t, d.Improvements, 1)
+	assert.Equal(t, "swap", d.Improvements[0].Summary)
+
+	assert.Equal(t, "Daily digest for acme, 2026-10-11", d.Title())
+	facts := d.Facts()
+	assert.Contains(t, facts, "Succeeded: 1 (50%)")
+	assert.Contains(t, facts, "Spend: $0.75")
+	assert.Contains(t, facts, "- security sql_injection, in 2 workflow(s)")
+	assert.Contains(t, d.Prompt(), facts)
+
+	assert.True(t, Compile("nobody", Daily, from, to, runs, nil).Empty())
+}
+
+func TestBoundary(t *testing.T) {
+	cfg := Config{Hour: 8}
+	wednesday := monday.Add(2*24*time.Hour + 3*time.Hour)
+	assert.Equal(t, monday.Add(2*24*time.Hour), cfg.Boundary(Daily, wednesday))
+	assert.Equal(t, monday.Add(24*time.Hour), cfg.Boundary(Daily, monday.Add(2*24*time.Hour-time.Minute)))
+	assert.Equal(t, monday, cfg.Boundary(Weekly, wednesday))
+	assert.Equal(t, monday, cfg.Boundary(Weekly, monday))
+	assert.Equal(t, monday.Add(-7*24*time.Hour), cfg.Boundary(Weekly, monday.Add(-time.Minute)))
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "digests.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+hour: 8
+default: {periods: [weekly], webhook: https://hooks.example.com/miosa}
+tenants:
+  acme: {periods: [daily, weekly], slack_channel: C0123, email: [ops@acme.test]}
+`), 0o644))
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.True(t, cfg.For("acme").Wants(Daily))
+	assert.Equal(t, "C0123", cfg.For("acme").SlackChannel)
+	assert.False(t, cfg.For("someone").Wants(Daily))
+	assert.True(t, cfg.For("someone").Wants(Weekly))
+
+	require.NoError(t, os.WriteFile(path, []byte("default: {periods: [hourly]}\n"), 0o644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "unknown period")
+}
+
+func TestSchedulerDue(t *testing.T) {
+	log := NewLog()
+	log.Record(Run{TenantID: "acme", Success: true, CompletedAt: monday.Add(2 * time.Hour)})
+	log.Record(Run{TenantID: "quiet", Success: true, CompletedAt: monday.Add(3 * time.Hour)})
+	cfg := Config{
+		Hour:    8,
+		Default: Subscription{Periods: []Period{Weekly}},
+		Tenants: map[string]Subscription{"acme": {Periods: []Period{Daily}}, "idle": {Periods: []Period{Daily}}},
+	}
+	s := NewScheduler(cfg, log, zap.NewNop(), monday.Add(time.Hour))
+	rendered := 0
+	s.Render = func(ctx context.Context, d *Digest) (string, error) {
+		rendered++
+		if d.TenantID == "acme" {
+			return "", errors.New("model unavailable")
+		}
+		return "rendered", nil
+	}
+
+	assert.Empty(t, s.Due(context.Background(), monday.Add(2*time.Hour)), "nothing is due before the next boundary")
+
+	due := s.Due(context.Background(), monday.Add(24*time.Hour+time.Minute))
+	require.Len(t, due, 2)
+	assert.Equal(t, "acme", due[0].TenantID)
+	assert.Equal(t, 1, due[0].Workflows)
+	assert.Contains(t, due[0].Summary, "Workflows run: 1", "a failed render sends the facts")
+	assert.Equal(t, "idle", due[1].TenantID)
+	assert.True(t, due[1].Empty())
+	assert.Equal(t, 1, rendered, "empty digests are not rendered")
+
+	assert.Empty(t, s.Due(context.Background(), monday.Add(24*time.Hour+2*time.Minute)), "digests are sent once")
+
+	// The weekly digest goes to tenants on the default that ran workflows
+	weekly := s.Due(context.Background(), monday.Add(7*24*time.Hour))
+	var tenants []string
+	for _, d := range weekly {
+		if d.Period == Weekly {
+			tenants = append(tenants, d.TenantID)
+		}
+	}
+	assert.Equal(t, []string{"quiet"}, tenants)
+}
+
+func TestDeliver(t *testing.T) {
+	var webhookBody []byte
+	var signature string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookBody, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer hook.Close()
+
+	var mailedTo []string
+	var mail string
+	cfg := Config{Tenants: map[string]Subscription{"acme": {
+		Periods:      []Period{Daily},
+		Webhook:      hook.URL,
+		Email:        []string{"ops@acme.test"},
+		SlackChannel: "C0123",
+	}}}
+	s := NewScheduler(cfg, NewLog(), zap.NewNop(), monday)
+	s.Webhook = NewWebhookSender("secret")
+	s.Email = &EmailSender{Addr: "smtp.example.com:587", From: "miosa@example.com", Username: "u", Password: "p",
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			assert.NotNil(t, a)
+			mailedTo, mail = to, string(msg)
+			return nil
+		}}
+
+	d := &Digest{TenantID: "acme", Period: Daily, From: monday.Add(-24 * time.Hour), To: monday, Summary: "All good.\nNothing failed."}
+	err := s.Deliver(context.Background(), d)
+	assert.ErrorContains(t, err, "no Slack bot", "a missing channel fails after the others are tried")
+
+	var got Digest
+	require.NoError(t, json.Unmarshal(webhookBody, &got))
+	assert.Equal(t, "acme", got.TenantID)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(webhookBody)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+
+	assert.Equal(t, []string{"ops@acme.test"}, mailedTo)
+	assert.Contains(t, mail, "Subject: Daily digest for acme, 2026-10-11\r\n")
+	assert.True(t, strings.HasSuffix(mail, "All good.\r\nNothing failed.\r\n"))
+}
@@ -0,0 +1,28 @@
+package digest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+)
+
+// PreviewHandler serves GET /api/admin/digests/preview?tenant=<id>&period=daily|weekly:
+// the tenant's digest for the period ending now, rendered but not sent
+func PreviewHandler(s *Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		period := Period(r.URL.Query().Get("period"))
+		if period == "" {
+			period = Daily
+		}
+		if !period.Valid() {
+			apierror.Write(w, r, apierror.Invalid("invalid period",
+				apierror.FieldError{Field: "period", Message: "must be daily or weekly"}))
+			return
+		}
+		d := s.Preview(r.Context(), r.URL.Query().Get("tenant"), period, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d)
+	}
+}
@@ -0,0 +1,248 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Subscription is where and how often a tenant's digests go
+type Subscription struct {
+	Periods      []Period `yaml:"periods" json:"periods"`
+	SlackChannel string   `yaml:"slack_channel" json:"slack_channel,omitempty"`
+	Webhook      string   `yaml:"webhook" json:"webhook,omitempty"`
+	Email        []string `yaml:"email" json:"email,omitempty"`
+}
+
+// Wants reports whether the subscription takes digests of period
+func (s Subscription) Wants(period Period) bool {
+	for _, p := range s.Periods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is the digest schedule and the subscriptions by tenant ID;
+// tenants without their own that ran workflows get Default
+type Config struct {
+	Hour    int                     `yaml:"hour" json:"hour"` // UTC hour digests go out; weekly ones on Mondays
+	Default Subscription            `yaml:"default" json:"default"`
+	Tenants map[string]Subscription `yaml:"tenants" json:"tenants,omitempty"`
+}
+
+// For returns the subscription applying to tenant
+func (c Config) For(tenant string) Subscription {
+	if sub, ok := c.Tenants[tenant]; ok {
+		return sub
+	}
+	return c.Default
+}
+
+// LoadConfig reads digest subscriptions from a YAML file, e.g.
+//
+//	hour: 8
+//	default: {periods: [weekly], webhook: https://hooks.example.com/miosa}
+//	tenants:
+//	  acme: {periods: [daily, weekly], slack_channel: C0123, email: [ops@acme.test]}
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read digest config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse digest config: %w", err)
+	}
+	if cfg.Hour < 0 || cfg.Hour > 23 {
+		return cfg, fmt.Errorf("invalid digest config: hour %d must be between 0 and 23", cfg.Hour)
+	}
+	subs := []Subscription{cfg.Default}
+	for _, sub := range cfg.Tenants {
+		subs = append(subs, sub)
+	}
+	for _, sub := range subs {
+		for _, p := range sub.Periods {
+			if !p.Valid() {
+				return cfg, fmt.Errorf("invalid digest config: unknown period %q", p)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// Boundary is the end of the most recent period of the schedule at or
+// before now: today's hour for daily digests, Monday's for weekly ones
+func (c Config) Boundary(period Period, now time.Time) time.Time {
+	now = now.UTC()
+	b := time.Date(now.Year(), now.Month(), now.Day(), c.Hour, 0, 0, 0, time.UTC)
+	if b.After(now) {
+		b = b.AddDate(0, 0, -1)
+	}
+	if period == Weekly {
+		b = b.AddDate(0, 0, -((int(b.Weekday()) + 6) % 7))
+	}
+	return b
+}
+
+// Scheduler sends each tenant's digests once per period. Digests are only
+// sent for periods ending after the scheduler started, so a restart does
+// not send them again.
+type Scheduler struct {
+	config Config
+	log    *Log
+	logger *zap.Logger
+
+	// Render writes the summary of a compiled digest; without it, or when
+	// it fails, the summary is the digest's facts
+	Render func(ctx context.Context, d *Digest) (string, error)
+	// Improvements returns the self-improvements applied since from
+	Improvements func(from time.Time) []Improvement
+
+	Slack   *SlackSender   // Nil without a Slack bot
+	Webhook *WebhookSender // Unsigned unless replaced
+	Email   *EmailSender   // Nil without an SMTP server
+
+	mu   sync.Mutex
+	sent map[Period]time.Time // The last boundary sent, per period
+}
+
+// NewScheduler creates a scheduler for the runs in log, starting at now
+func NewScheduler(config Config, log *Log, logger *zap.Logger, now time.Time) *Scheduler {
+	s := &Scheduler{config: config, log: log, logger: logger, Webhook: NewWebhookSender(""), sent: make(map[Period]time.Time)}
+	for _, p := range []Period{Daily, Weekly} {
+		s.sent[p] = config.Boundary(p, now)
+	}
+	return s
+}
+
+// Due compiles the digests of every period that ended since the last ones
+// were sent, marking them sent. Tenants with a subscription of their own
+// always get a digest; the rest only when they ran workflows.
+func (s *Scheduler) Due(ctx context.Context, now time.Time) []*Digest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var digests []*Digest
+	for _, period := range []Period{Daily, Weekly} {
+		to := s.config.Boundary(period, now)
+		if !to.After(s.sent[period]) {
+			continue
+		}
+		s.sent[period] = to
+		from := to.Add(-period.Duration())
+
+		runs := s.log.Since(from)
+		var improvements []Improvement
+		if s.Improvements != nil {
+			improvements = s.Improvements(from)
+		}
+		tenants := make(map[string]bool)
+		for tenant := range s.config.Tenants {
+			tenants[tenant] = true
+		}
+		if len(s.config.Default.Periods) > 0 {
+			for _, tenant := range s.log.Tenants(from) {
+				tenants[tenant] = true
+			}
+		}
+		for tenant := range tenants {
+			if !s.config.For(tenant).Wants(period) {
+				continue
+			}
+			d := Compile(tenant, period, from, to, runs, improvements)
+			d.Summary = s.render(ctx, d)
+			digests = append(digests, d)
+		}
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		if digests[i].Period != digests[j].Period {
+			return digests[i].Period == Daily
+		}
+		return digests[i].TenantID < digests[j].TenantID
+	})
+	return digests
+}
+
+// Preview compiles and renders the tenant's digest for the period ending
+// now, without marking anything sent
+func (s *Scheduler) Preview(ctx context.Context, tenant string, period Period, now time.Time) *Digest {
+	from := now.Add(-period.Duration())
+	var improvements []Improvement
+	if s.Improvements != nil {
+		improvements = s.Improvements(from)
+	}
+	d := Compile(tenant, period, from, now, s.log.Since(from), improvements)
+	d.Summary = s.render(ctx, d)
+	return d
+}
+
+// render writes the digest's summary, falling back to its facts
+func (s *Scheduler) render(ctx context.Context, d *Digest) string {
+	if s.Render != nil && !d.Empty() {
+		summary, err := s.Render(ctx, d)
+		if err == nil && summary != "" {
+			return summary
+		}
+		s.logger.Warn("Failed to render digest; sending its facts", zap.String("tenant", d.TenantID), zap.Error(err))
+	}
+	return d.Facts()
+}
+
+// Deliver sends a digest to every channel of the tenant's subscription,
+// returning the first error after trying them all
+func (s *Scheduler) Deliver(ctx context.Context, d *Digest) error {
+	sub := s.config.For(d.TenantID)
+	var first error
+	fail := func(channel string, err error) {
+		s.logger.Warn("Failed to deliver digest", zap.String("tenant", d.TenantID), zap.String("channel", channel), zap.Error(err))
+		if first == nil {
+			first = err
+		}
+	}
+	if sub.SlackChannel != "" {
+		if s.Slack == nil {
+			fail("slack", fmt.Errorf("no Slack bot is configured"))
+		} else if err := s.Slack.Send(ctx, sub.SlackChannel, d); err != nil {
+			fail("slack", err)
+		}
+	}
+	if sub.Webhook != "" {
+		if err := s.Webhook.Send(ctx, sub.Webhook, d); err != nil {
+			fail("webhook", err)
+		}
+	}
+	if len(sub.Email) > 0 {
+		if s.Email == nil {
+			fail("email", fmt.Errorf("no SMTP server is configured"))
+		} else if err := s.Email.Send(ctx, sub.Email, d); err != nil {
+			fail("email", err)
+		}
+	}
+	return first
+}
+
+// Run sends the digests that are due every interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, d := range s.Due(ctx, now) {
+				if err := s.Deliver(ctx, d); err == nil {
+					s.logger.Info("Sent digest", zap.String("tenant", d.TenantID), zap.String("period", string(d.Period)), zap.Int("workflows", d.Workflows))
+				}
+			}
+		}
+	}
+}
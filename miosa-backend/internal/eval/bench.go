@@ -0,0 +1,200 @@
+package eval
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// TargetStats summarizes one provider/model's run of a suite, overall or
+// for one agent
+type TargetStats struct {
+	Cases         int     `json:"cases"`
+	Passed        int     `json:"passed"`
+	PassRate      float64 `json:"pass_rate"`
+	Errors        int     `json:"errors"`
+	LatencyMeanMS int64   `json:"latency_mean_ms"`
+	LatencyP50MS  int64   `json:"latency_p50_ms"`
+	LatencyP95MS  int64   `json:"latency_p95_ms"`
+
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	CostPerPassUSD   float64 `json:"cost_per_pass_usd,omitempty"`
+
+	// CompileRate is the share of cases generating Go or JSON files whose
+	// files all parse
+	CodeCases   int     `json:"code_cases"`
+	CompileRate float64 `json:"compile_rate"`
+	// AssuranceScore is the mean static assurance score of the cases that
+	// generated files
+	AssuredCases   int     `json:"assured_cases"`
+	AssuranceScore float64 `json:"assurance_score"`
+}
+
+// TargetBench is one target's results in a Benchmark
+type TargetBench struct {
+	Target  string                            `json:"target"` // provider:model, see agents.ParseTarget
+	Stats   TargetStats                       `json:"stats"`
+	ByAgent map[agents.AgentType]*TargetStats `json:"by_agent"`
+	Report  *Report                           `json:"report"`
+}
+
+// Recommendation ranks the targets for one agent, best first
+type Recommendation struct {
+	Agent agents.AgentType `json:"agent"`
+	Chain []string         `json:"chain"`
+}
+
+// Benchmark compares runs of the same suite on several targets
+type Benchmark struct {
+	Suite           string           `json:"suite"`
+	Targets         []*TargetBench   `json:"targets"`
+	Recommendations []Recommendation `json:"recommendations"`
+}
+
+// NewBenchmark compares reports, each the run of the suite on the target
+// of the same index
+func NewBenchmark(targets []string, reports []*Report) *Benchmark {
+	b := &Benchmark{}
+	agentTypes := map[agents.AgentType]bool{}
+	for i, report := range reports {
+		if b.Suite == "" {
+			b.Suite = report.Suite
+		}
+		tb := &TargetBench{
+			Target:  targets[i],
+			Stats:   Summarize(report.Results),
+			ByAgent: make(map[agents.AgentType]*TargetStats),
+			Report:  report,
+		}
+		byAgent := map[agents.AgentType][]CaseResult{}
+		for _, res := range report.Results {
+			byAgent[res.Agent] = append(byAgent[res.Agent], res)
+		}
+		for t, results := range byAgent {
+			stats := Summarize(results)
+			tb.ByAgent[t] = &stats
+			agentTypes[t] = true
+		}
+		b.Targets = append(b.Targets, tb)
+	}
+
+	types := make([]string, 0, len(agentTypes))
+	for t := range agentTypes {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		b.Recommendations = append(b.Recommendations, b.recommend(agents.AgentType(t)))
+	}
+	return b
+}
+
+// recommend orders the targets that passed any of an agent's cases by
+// pass rate, then assurance score, cost and median latency
+func (b *Benchmark) recommend(agent agents.AgentType) Recommendation {
+	type ranked struct {
+		target string
+		stats  *TargetStats
+	}
+	var candidates []ranked
+	for _, tb := range b.Targets {
+		if stats := tb.ByAgent[agent]; stats != nil && stats.Passed > 0 {
+			candidates = append(candidates, ranked{tb.Target, stats})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, c := candidates[i].stats, candidates[j].stats
+		switch {
+		case a.PassRate != c.PassRate:
+			return a.PassRate > c.PassRate
+		case a.AssuranceScore != c.AssuranceScore:
+			return a.AssuranceScore > c.AssuranceScore
+		case a.CostUSD != c.CostUSD:
+			return a.CostUSD < c.CostUSD
+		}
+		return a.LatencyP50MS < c.LatencyP50MS
+	})
+	rec := Recommendation{Agent: agent}
+	for _, c := range candidates {
+		rec.Chain = append(rec.Chain, c.target)
+	}
+	return rec
+}
+
+// FailoverChains formats the recommendations as the -agent-failover flag
+// of the orchestrator reads them: agent=target|target,...
+func (b *Benchmark) FailoverChains() string {
+	var pairs []string
+	for _, rec := range b.Recommendations {
+		if len(rec.Chain) > 0 {
+			pairs = append(pairs, string(rec.Agent)+"="+strings.Join(rec.Chain, "|"))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Summarize aggregates case results
+func Summarize(results []CaseResult) TargetStats {
+	var s TargetStats
+	var latencies []int64
+	var totalMS int64
+	var compiled int
+	var assurance float64
+	for _, res := range results {
+		s.Cases++
+		if res.Passed {
+			s.Passed++
+		}
+		if res.Error != "" {
+			s.Errors++
+		}
+		latencies = append(latencies, res.DurationMS)
+		totalMS += res.DurationMS
+		s.PromptTokens += res.PromptTokens
+		s.CompletionTokens += res.CompletionTokens
+		s.CostUSD += res.CostUSD
+		if res.CodeFiles > 0 {
+			s.CodeCases++
+			if res.CompileErrors == 0 {
+				compiled++
+			}
+		}
+		if res.AssuranceScore != nil {
+			s.AssuredCases++
+			assurance += *res.AssuranceScore
+		}
+	}
+	if s.Cases == 0 {
+		return s
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.PassRate = rate(s.Passed, s.Cases)
+	s.LatencyMeanMS = totalMS / int64(s.Cases)
+	s.LatencyP50MS = percentile(latencies, 0.50)
+	s.LatencyP95MS = percentile(latencies, 0.95)
+	s.CompileRate = rate(compiled, s.CodeCases)
+	if s.Passed > 0 {
+		s.CostPerPassUSD = s.CostUSD / float64(s.Passed)
+	}
+	if s.AssuredCases > 0 {
+		s.AssuranceScore = assurance / float64(s.AssuredCases)
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,417 @@
+// Package eval runs a golden-task suite against agents and checks structural
+// properties of their outputs, so prompt or model changes can be validated
+// before rollout.
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/reporting"
+)
+
+// Expect describes the structural properties a case's output must have.
+// Zero values disable a check.
+type Expect struct {
+	Success        bool     `json:"success,omitempty"`
+	MinOutputChars int      `json:"min_output_chars,omitempty"`
+	OutputContains []string `json:"output_contains,omitempty"`
+	// Files are path patterns (path.Match syntax) that must each match at
+	// least one generated file
+	Files []string `json:"files,omitempty"`
+	// Compiles requires generated Go and JSON files to be syntactically
+	// valid. Dependencies are not fetched, so this is a parse-level check.
+	Compiles      bool    `json:"compiles,omitempty"`
+	MinFindings   *int    `json:"min_findings,omitempty"`
+	MaxFindings   *int    `json:"max_findings,omitempty"`
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+}
+
+// Case is a single golden task
+type Case struct {
+	Name   string                 `json:"name"`
+	Agent  agents.AgentType       `json:"agent"`
+	Type   string                 `json:"type,omitempty"`
+	Input  string                 `json:"input"`
+	Params map[string]interface{} `json:"parameters,omitempty"`
+	Expect Expect                 `json:"expect"`
+}
+
+// Suite is an ordered set of golden tasks
+type Suite struct {
+	Name  string `json:"name"`
+	Cases []Case `json:"cases"`
+}
+
+// LoadSuite reads a suite from a JSON file
+func LoadSuite(file string) (*Suite, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite %s: %w", file, err)
+	}
+	for i, c := range suite.Cases {
+		if c.Name == "" || c.Agent == "" || c.Input == "" {
+			return nil, fmt.Errorf("case %d: name, agent and input are required", i)
+		}
+	}
+	return &suite, nil
+}
+
+// CaseResult is the outcome of one case
+type CaseResult struct {
+	Name       string           `json:"name"`
+	Agent      agents.AgentType `json:"agent"`
+	Passed     bool             `json:"passed"`
+	Failures   []string         `json:"failures,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	DurationMS int64            `json:"duration_ms"`
+
+	Model             string  `json:"model,omitempty"` // Of the last LLM call
+	PromptTokens      int     `json:"prompt_tokens"`
+	CompletionTokens  int     `json:"completion_tokens"`
+	CostUSD           float64 `json:"cost_usd"`
+	PromptTokensSaved int     `json:"prompt_tokens_saved,omitempty"` // By prompt compression
+
+	// CodeFiles counts generated Go and JSON files, CompileErrors those
+	// that do not parse, whether or not the case expects them to
+	CodeFiles     int `json:"code_files,omitempty"`
+	CompileErrors int `json:"compile_errors,omitempty"`
+	// AssuranceScore is the static code assurance score (0-100) of the
+	// generated files, when the runner scores them
+	AssuranceScore *float64 `json:"assurance_score,omitempty"`
+}
+
+// AgentStats summarizes the pass rate of one agent
+type AgentStats struct {
+	Passed int     `json:"passed"`
+	Total  int     `json:"total"`
+	Rate   float64 `json:"rate"`
+}
+
+// Report aggregates a suite run
+type Report struct {
+	Suite      string                           `json:"suite"`
+	Provider   string                           `json:"provider,omitempty"`
+	Model      string                           `json:"model,omitempty"`
+	Results    []CaseResult                     `json:"results"`
+	Passed     int                              `json:"passed"`
+	Total      int                              `json:"total"`
+	PassRate   float64                          `json:"pass_rate"`
+	ByAgent    map[agents.AgentType]*AgentStats `json:"by_agent"`
+	StartedAt  time.Time                        `json:"started_at"`
+	DurationMS int64                            `json:"duration_ms"`
+
+	PromptTokens      int     `json:"prompt_tokens"`
+	CompletionTokens  int     `json:"completion_tokens"`
+	CostUSD           float64 `json:"cost_usd"`
+	PromptTokensSaved int     `json:"prompt_tokens_saved,omitempty"`
+}
+
+// Resolver returns the agent that should run a case
+type Resolver func(agents.AgentType) (agents.Agent, error)
+
+// Runner executes suites
+type Runner struct {
+	resolve Resolver
+	timeout time.Duration
+	assure  bool
+}
+
+// NewRunner creates a runner that gives each case up to timeout to finish
+func NewRunner(resolve Resolver, timeout time.Duration) *Runner {
+	if resolve == nil {
+		resolve = agents.Get
+	}
+	return &Runner{resolve: resolve, timeout: timeout}
+}
+
+// SetAssurance scores the files each case generates with the static code
+// assurance checks, without LLM review
+func (r *Runner) SetAssurance(enabled bool) {
+	r.assure = enabled
+}
+
+// Run executes every case in order and reports pass rates
+func (r *Runner) Run(ctx context.Context, suite *Suite) *Report {
+	report := &Report{
+		Suite:     suite.Name,
+		ByAgent:   make(map[agents.AgentType]*AgentStats),
+		StartedAt: time.Now(),
+	}
+
+	for _, c := range suite.Cases {
+		res := r.runCase(ctx, c)
+		report.Results = append(report.Results, res)
+
+		stats, ok := report.ByAgent[c.Agent]
+		if !ok {
+			stats = &AgentStats{}
+			report.ByAgent[c.Agent] = stats
+		}
+		stats.Total++
+		report.Total++
+		report.PromptTokens += res.PromptTokens
+		report.CompletionTokens += res.CompletionTokens
+		report.CostUSD += res.CostUSD
+		report.PromptTokensSaved += res.PromptTokensSaved
+		if res.Passed {
+			stats.Passed++
+			report.Passed++
+		}
+	}
+
+	for _, stats := range report.ByAgent {
+		stats.Rate = rate(stats.Passed, stats.Total)
+	}
+	report.PassRate = rate(report.Passed, report.Total)
+	report.DurationMS = time.Since(report.StartedAt).Milliseconds()
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case) CaseResult {
+	start := time.Now()
+	res := CaseResult{Name: c.Name, Agent: c.Agent}
+
+	agent, err := r.resolve(c.Agent)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	result, err := agents.ExecuteTracked(ctx, agent, agents.Task{
+		ID:         uuid.New(),
+		Type:       c.Type,
+		Input:      c.Input,
+		Parameters: c.Params,
+		Context: &agents.TaskContext{
+			Phase:  "evaluation",
+			Memory: make(map[string]interface{}),
+		},
+	})
+	res.DurationMS = time.Since(start).Milliseconds()
+	if result != nil {
+		res.Model = result.Model
+		res.PromptTokens, res.CompletionTokens = result.PromptTokens, result.CompletionTokens
+		res.PromptTokensSaved = result.PromptTokensSaved
+		res.CostUSD = reporting.EstimateCost(result.Model, result.PromptTokens, result.CompletionTokens)
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.CodeFiles, res.CompileErrors = compileCheck(result.Files)
+	if r.assure && len(result.Files) > 0 {
+		res.AssuranceScore = assuranceScore(ctx, result.Files)
+	}
+
+	res.Failures = Check(c.Expect, result)
+	res.Passed = len(res.Failures) == 0
+	return res
+}
+
+// Check returns a description of every expectation the result violates
+func Check(expect Expect, result *agents.Result) []string {
+	if result == nil {
+		return []string{"agent returned no result"}
+	}
+
+	var failures []string
+	if expect.Success && !result.Success {
+		failures = append(failures, "expected success")
+	}
+	if len(result.Output) < expect.MinOutputChars {
+		failures = append(failures, fmt.Sprintf("output has %d chars, want at least %d", len(result.Output), expect.MinOutputChars))
+	}
+	lower := strings.ToLower(result.Output)
+	for _, s := range expect.OutputContains {
+		if !strings.Contains(lower, strings.ToLower(s)) {
+			failures = append(failures, fmt.Sprintf("output missing %q", s))
+		}
+	}
+	if result.Confidence < expect.MinConfidence {
+		failures = append(failures, fmt.Sprintf("confidence %.2f below %.2f", result.Confidence, expect.MinConfidence))
+	}
+
+	for _, pattern := range expect.Files {
+		if !anyFileMatches(result.Files, pattern) {
+			failures = append(failures, fmt.Sprintf("no generated file matches %q", pattern))
+		}
+	}
+
+	if expect.Compiles {
+		for _, f := range result.Files {
+			if err := syntaxCheck(f); err != nil {
+				failures = append(failures, fmt.Sprintf("%s does not compile: %v", f.Path, err))
+			}
+		}
+	}
+
+	if expect.MinFindings != nil || expect.MaxFindings != nil {
+		n, ok := countFindings(result)
+		switch {
+		case !ok:
+			failures = append(failures, "result reports no findings")
+		case expect.MinFindings != nil && n < *expect.MinFindings:
+			failures = append(failures, fmt.Sprintf("%d findings, want at least %d", n, *expect.MinFindings))
+		case expect.MaxFindings != nil && n > *expect.MaxFindings:
+			failures = append(failures, fmt.Sprintf("%d findings, want at most %d", n, *expect.MaxFindings))
+		}
+	}
+
+	return failures
+}
+
+func anyFileMatches(files []agents.GeneratedFile, pattern string) bool {
+	for _, f := range files {
+		if ok, _ := path.Match(pattern, f.Path); ok {
+			return true
+		}
+		// Patterns without a directory match the base name anywhere
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(f.Path)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func syntaxCheck(f agents.GeneratedFile) error {
+	switch strings.ToLower(path.Ext(f.Path)) {
+	case ".go":
+		_, err := parser.ParseFile(token.NewFileSet(), f.Path, f.Content, parser.AllErrors)
+		return err
+	case ".json":
+		if !json.Valid([]byte(f.Content)) {
+			return fmt.Errorf("invalid JSON")
+		}
+	}
+	return nil
+}
+
+// compileCheck counts the files syntaxCheck covers and those failing it
+func compileCheck(files []agents.GeneratedFile) (checked, failed int) {
+	for _, f := range files {
+		switch strings.ToLower(path.Ext(f.Path)) {
+		case ".go", ".json":
+			checked++
+			if syntaxCheck(f) != nil {
+				failed++
+			}
+		}
+	}
+	return checked, failed
+}
+
+// assuranceScore runs the static code assurance checks over files, nil
+// when they cannot run
+func assuranceScore(ctx context.Context, files []agents.GeneratedFile) *float64 {
+	req := quality.CodeAssuranceRequest{Files: make([]quality.CodeFile, 0, len(files))}
+	for _, f := range files {
+		req.Files = append(req.Files, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	result, err := quality.RunCodeAssurance(ctx, nil, req)
+	if err != nil {
+		return nil
+	}
+	return &result.Score
+}
+
+// countFindings reads the findings reported in result data, either as a
+// "findings" list or an "issues_found" count
+func countFindings(result *agents.Result) (int, bool) {
+	if v, ok := result.Data["findings"]; ok {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			return rv.Len(), true
+		}
+	}
+	switch n := result.Data["issues_found"].(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+func rate(passed, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(passed) / float64(total)
+}
+
+// Failed returns the results of failing cases sorted by name
+func (r *Report) Failed() []CaseResult {
+	var failed []CaseResult
+	for _, res := range r.Results {
+		if !res.Passed {
+			failed = append(failed, res)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Name < failed[j].Name })
+	return failed
+}
+
+// Comparison is the impact of a change, such as prompt compression, on a
+// suite: the cases it broke or fixed and the prompt tokens it saved
+type Comparison struct {
+	BaselinePassRate  float64  `json:"baseline_pass_rate"`
+	CandidatePassRate float64  `json:"candidate_pass_rate"`
+	PassRateDelta     float64  `json:"pass_rate_delta"`
+	Regressions       []string `json:"regressions,omitempty"` // Cases passing only in the baseline
+	Fixes             []string `json:"fixes,omitempty"`       // Cases passing only in the candidate
+	PromptTokensSaved int      `json:"prompt_tokens_saved"`   // Baseline prompt tokens less the candidate's
+}
+
+// Compare reports how candidate differs from baseline, a run of the same
+// suite without the change
+func Compare(baseline, candidate *Report) *Comparison {
+	c := &Comparison{
+		BaselinePassRate:  baseline.PassRate,
+		CandidatePassRate: candidate.PassRate,
+		PassRateDelta:     candidate.PassRate - baseline.PassRate,
+		PromptTokensSaved: baseline.PromptTokens - candidate.PromptTokens,
+	}
+	passed := make(map[string]bool, len(baseline.Results))
+	for _, res := range baseline.Results {
+		passed[res.Name] = res.Passed
+	}
+	for _, res := range candidate.Results {
+		was, ok := passed[res.Name]
+		switch {
+		case !ok:
+		case was && !res.Passed:
+			c.Regressions = append(c.Regressions, res.Name)
+		case !was && res.Passed:
+			c.Fixes = append(c.Fixes, res.Name)
+		}
+	}
+	sort.Strings(c.Regressions)
+	sort.Strings(c.Fixes)
+	return c
+}
@@ -0,0 +1,172 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAgent struct {
+	agentType agents.AgentType
+	result    *agents.Result
+	err       error
+}
+
+func (s *stubAgent) GetType() agents.AgentType            { return s.agentType }
+func (s *stubAgent) GetCapabilities() []agents.Capability { return nil }
+func (s *stubAgent) GetDescription() string               { return "stub" }
+func (s *stubAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	return s.result, s.err
+}
+
+func intPtr(n int) *int { return &n }
+
+func TestCheck(t *testing.T) {
+	result := &agents.Result{
+		Success: true,
+		Output:  "Use a Redis cache in front of Postgres",
+		Files: []agents.GeneratedFile{
+			{Path: "cmd/server/main.go", Content: "package main\n\nfunc main() {}\n"},
+			{Path: "config.json", Content: `{"port": 8080`},
+		},
+		Data: map[string]interface{}{"findings": []string{"a", "b"}},
+	}
+
+	assert.Empty(t, Check(Expect{
+		Success:        true,
+		OutputContains: []string{"redis", "CACHE"},
+		Files:          []string{"main.go", "*.json"},
+		MinFindings:    intPtr(1),
+		MaxFindings:    intPtr(2),
+	}, result))
+
+	failures := Check(Expect{
+		MinOutputChars: 1000,
+		Files:          []string{"Dockerfile"},
+		Compiles:       true,
+		MaxFindings:    intPtr(1),
+	}, result)
+	require.Len(t, failures, 4)
+	assert.Contains(t, failures[2], "config.json does not compile")
+}
+
+func TestRunner_Run(t *testing.T) {
+	registry := map[agents.AgentType]agents.Agent{
+		agents.AnalysisAgent: &stubAgent{result: &agents.Result{Success: true, Output: "requirements and risks"}},
+		agents.QualityAgent:  &stubAgent{err: errors.New("model unavailable")},
+	}
+	runner := NewRunner(func(t agents.AgentType) (agents.Agent, error) {
+		if a, ok := registry[t]; ok {
+			return a, nil
+		}
+		return nil, errors.New("unknown agent")
+	}, 0)
+
+	report := runner.Run(context.Background(), &Suite{Name: "test", Cases: []Case{
+		{Name: "ok", Agent: agents.AnalysisAgent, Input: "x", Expect: Expect{OutputContains: []string{"risk"}}},
+		{Name: "short", Agent: agents.AnalysisAgent, Input: "x", Expect: Expect{MinOutputChars: 100}},
+		{Name: "broken", Agent: agents.QualityAgent, Input: "x"},
+	}})
+
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Passed)
+	assert.InDelta(t, 0.5, report.ByAgent[agents.AnalysisAgent].Rate, 0.001)
+	assert.Equal(t, 0, report.ByAgent[agents.QualityAgent].Passed)
+
+	failed := report.Failed()
+	require.Len(t, failed, 2)
+	assert.Equal(t, "model unavailable", failed[0].Error)
+}
+
+func TestLoadSuite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "suite.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"name":"custom","cases":[
+		{"name":"dev","agent":"development","input":"write code","expect":{"compiles":true,"max_findings":3}}
+	]}`), 0644))
+
+	suite, err := LoadSuite(file)
+	require.NoError(t, err)
+	require.Len(t, suite.Cases, 1)
+	assert.True(t, suite.Cases[0].Expect.Compiles)
+	assert.Equal(t, 3, *suite.Cases[0].Expect.MaxFindings)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"cases":[{"name":"missing agent","input":"x"}]}`), 0644))
+	_, err = LoadSuite(file)
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{PassRate: 0.5, PromptTokens: 1000, Results: []CaseResult{
+		{Name: "a", Passed: true}, {Name: "b", Passed: false}, {Name: "c", Passed: true},
+	}}
+	candidate := &Report{PassRate: 0.5, PromptTokens: 600, Results: []CaseResult{
+		{Name: "a", Passed: false}, {Name: "b", Passed: true}, {Name: "c", Passed: true},
+	}}
+	c := Compare(baseline, candidate)
+	assert.Equal(t, []string{"a"}, c.Regressions)
+	assert.Equal(t, []string{"b"}, c.Fixes)
+	assert.Equal(t, 400, c.PromptTokensSaved)
+	assert.Zero(t, c.PassRateDelta)
+}
+
+func TestRunner_CodeMetrics(t *testing.T) {
+	dev := &stubAgent{result: &agents.Result{
+		Success:          true,
+		Model:            "llama-3.1-8b-instant",
+		PromptTokens:     1_000_000,
+		CompletionTokens: 1_000_000,
+		Files: []agents.GeneratedFile{
+			{Path: "main.go", Content: "package main\n\nfunc main() {}\n"},
+			{Path: "config.json", Content: `{"port":`},
+			{Path: "README.md", Content: "# app"},
+		},
+	}}
+	runner := NewRunner(func(agents.AgentType) (agents.Agent, error) { return dev, nil }, 0)
+	runner.SetAssurance(true)
+
+	report := runner.Run(context.Background(), &Suite{Cases: []Case{{Name: "dev", Agent: agents.DevelopmentAgent, Input: "x"}}})
+	res := report.Results[0]
+	assert.Equal(t, 2, res.CodeFiles)
+	assert.Equal(t, 1, res.CompileErrors)
+	assert.InDelta(t, 0.13, res.CostUSD, 0.0001)
+	assert.InDelta(t, 0.13, report.CostUSD, 0.0001)
+	require.NotNil(t, res.AssuranceScore)
+}
+
+func TestBenchmark(t *testing.T) {
+	score := func(v float64) *float64 { return &v }
+	fast := &Report{Suite: "golden", Results: []CaseResult{
+		{Name: "a", Agent: agents.AnalysisAgent, Passed: true, DurationMS: 100, CostUSD: 0.01},
+		{Name: "d", Agent: agents.DevelopmentAgent, Passed: true, DurationMS: 300, CodeFiles: 2, CompileErrors: 1, AssuranceScore: score(60)},
+	}}
+	strong := &Report{Suite: "golden", Results: []CaseResult{
+		{Name: "a", Agent: agents.AnalysisAgent, Passed: true, DurationMS: 900, CostUSD: 0.05},
+		{Name: "d", Agent: agents.DevelopmentAgent, Passed: true, DurationMS: 1200, CodeFiles: 2, AssuranceScore: score(90)},
+	}}
+	failing := &Report{Suite: "golden", Results: []CaseResult{
+		{Name: "a", Agent: agents.AnalysisAgent, Error: "timeout", DurationMS: 5000},
+		{Name: "d", Agent: agents.DevelopmentAgent, Error: "timeout", DurationMS: 5000},
+	}}
+
+	b := NewBenchmark([]string{"llama-3.1-8b-instant", "llama-3.3-70b-versatile", "openai:gpt-4o-mini"}, []*Report{fast, strong, failing})
+	require.Len(t, b.Targets, 3)
+	s := b.Targets[0].Stats
+	assert.Equal(t, 1.0, s.PassRate)
+	assert.Equal(t, int64(100), s.LatencyP50MS)
+	assert.Equal(t, int64(300), s.LatencyP95MS)
+	assert.Equal(t, 0.0, s.CompileRate)
+	assert.Equal(t, 1.0, b.Targets[1].Stats.CompileRate)
+	assert.Equal(t, 2, b.Targets[2].Stats.Errors)
+
+	assert.Equal(t, []Recommendation{
+		{Agent: agents.AnalysisAgent, Chain: []string{"llama-3.1-8b-instant", "llama-3.3-70b-versatile"}},
+		{Agent: agents.DevelopmentAgent, Chain: []string{"llama-3.3-70b-versatile", "llama-3.1-8b-instant"}},
+	}, b.Recommendations)
+	assert.Equal(t, "analysis=llama-3.1-8b-instant|llama-3.3-70b-versatile,development=llama-3.3-70b-versatile|llama-3.1-8b-instant", b.FailoverChains())
+}
@@ -0,0 +1,87 @@
+package eval
+
+import "github.com/sormind/OSA/miosa-backend/internal/agents"
+
+// DefaultSuite is the built-in golden-task suite covering each core agent
+func DefaultSuite() *Suite {
+	return &Suite{
+		Name: "golden",
+		Cases: []Case{
+			{
+				Name:  "analysis/todo-api",
+				Agent: agents.AnalysisAgent,
+				Type:  "analysis",
+				Input: "Build a REST API for a todo list with user accounts, due dates and reminders",
+				Expect: Expect{
+					Success:        true,
+					MinOutputChars: 500,
+					OutputContains: []string{"requirement", "risk"},
+				},
+			},
+			{
+				Name:  "strategy/saas-launch",
+				Agent: agents.StrategyAgent,
+				Type:  "strategy",
+				Input: "Plan the technical roadmap for launching a multi-tenant invoicing SaaS",
+				Expect: Expect{
+					Success:        true,
+					MinOutputChars: 400,
+				},
+			},
+			{
+				Name:  "architect/url-shortener",
+				Agent: agents.ArchitectAgent,
+				Type:  "architecture",
+				Input: "Design the architecture for a URL shortener handling 10k writes per second",
+				Expect: Expect{
+					Success:        true,
+					MinOutputChars: 500,
+					OutputContains: []string{"database", "cache"},
+				},
+			},
+			{
+				Name:  "development/go-http-handler",
+				Agent: agents.DevelopmentAgent,
+				Type:  "development",
+				Input: "Write a Go HTTP handler that returns the current server time as JSON",
+				Expect: Expect{
+					Success:        true,
+					MinOutputChars: 200,
+					OutputContains: []string{"func", "json"},
+					Compiles:       true,
+				},
+			},
+			{
+				Name:  "quality/review",
+				Agent: agents.QualityAgent,
+				Type:  "quality",
+				Input: "Review the quality of a Go service with a SQL query built by string concatenation",
+				Expect: Expect{
+					MinOutputChars: 200,
+					OutputContains: []string{"recommendation"},
+				},
+			},
+			{
+				Name:  "deployment/container",
+				Agent: agents.DeploymentAgent,
+				Type:  "deployment",
+				Input: "Create a deployment plan for a Go API and Postgres on Kubernetes",
+				Expect: Expect{
+					Success:        true,
+					MinOutputChars: 300,
+					Compiles:       true,
+				},
+			},
+			{
+				Name:  "communication/greeting",
+				Agent: agents.CommunicationAgent,
+				Type:  "chat",
+				Input: "Hi, what can you help me build?",
+				Expect: Expect{
+					Success:        true,
+					MinOutputChars: 40,
+				},
+			},
+		},
+	}
+}
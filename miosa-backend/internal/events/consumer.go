@@ -0,0 +1,249 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// fieldEvent is the stream entry field holding the encoded envelope
+const fieldEvent = "event"
+
+// Consumer defaults
+const (
+	DefaultMaxDeliveries = 5
+	DefaultClaimIdle     = time.Minute
+	DefaultBlock         = 5 * time.Second
+	DefaultBatch         = 10
+)
+
+// Consumer reads a topic's stream as one member of a consumer group. Each
+// event goes to one member of the group and is acknowledged once handled;
+// events a crashed or failing member left pending are claimed again after
+// ClaimIdle, and moved to the dead-letter stream after MaxDeliveries.
+type Consumer struct {
+	client redis.UniversalClient
+	topic  string
+	group  string
+	name   string
+	logger *zap.Logger
+
+	MaxDeliveries int64
+	ClaimIdle     time.Duration
+	Block         time.Duration // How long a read waits for new events
+	Batch         int64
+}
+
+// NewConsumer creates the consumer name in group for topic
+func NewConsumer(client redis.UniversalClient, topic, group, name string, logger *zap.Logger) *Consumer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Consumer{
+		client:        client,
+		topic:         topic,
+		group:         group,
+		name:          name,
+		logger:        logger,
+		MaxDeliveries: DefaultMaxDeliveries,
+		ClaimIdle:     DefaultClaimIdle,
+		Block:         DefaultBlock,
+		Batch:         DefaultBatch,
+	}
+}
+
+// Run creates the group if needed, then handles events until ctx is done.
+// A new group starts with the events published after it was created.
+func (c *Consumer) Run(ctx context.Context, handle Handler) error {
+	stream := StreamKey(c.topic)
+	err := c.client.XGroupCreateMkStream(ctx, stream, c.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", c.group, err)
+	}
+
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= c.ClaimIdle {
+			if err := c.Reclaim(ctx, handle); err != nil && ctx.Err() == nil {
+				c.logger.Warn("Failed to reclaim pending events", zap.String("stream", stream), zap.Error(err))
+			}
+			lastClaim = time.Now()
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.name,
+			Streams:  []string{stream, ">"},
+			Count:    c.Batch,
+			Block:    c.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			c.logger.Warn("Failed to read events", zap.String("stream", stream), zap.Error(err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				c.process(ctx, msg, 1, handle)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// Reclaim takes over the events left pending longer than ClaimIdle and
+// handles them again, dead-lettering those delivered MaxDeliveries times
+func (c *Consumer) Reclaim(ctx context.Context, handle Handler) error {
+	stream := StreamKey(c.topic)
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  c.group,
+		Idle:   c.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  c.Batch,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		msgs, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    c.group,
+			Consumer: c.name,
+			MinIdle:  c.ClaimIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			// Trimmed from the stream since it was delivered
+			c.client.XAck(ctx, stream, c.group, p.ID)
+			continue
+		}
+		deliveries := p.RetryCount + 1
+		if p.RetryCount >= c.MaxDeliveries {
+			c.deadLetter(ctx, msgs[0], p.RetryCount, fmt.Errorf("not handled after %d deliveries", p.RetryCount))
+			continue
+		}
+		c.process(ctx, msgs[0], deliveries, handle)
+	}
+	return nil
+}
+
+// process handles one delivery. Events that cannot be parsed, or whose
+// last allowed delivery fails, are dead-lettered; other failures stay
+// pending for Reclaim.
+func (c *Consumer) process(ctx context.Context, msg redis.XMessage, deliveries int64, handle Handler) {
+	payload, _ := msg.Values[fieldEvent].(string)
+	env, err := Parse([]byte(payload))
+	if err != nil {
+		c.deadLetter(ctx, msg, deliveries, err)
+		return
+	}
+
+	if err := handle(ctx, env); err != nil {
+		if deliveries >= c.MaxDeliveries {
+			c.deadLetter(ctx, msg, deliveries, err)
+			return
+		}
+		c.logger.Warn("Failed to handle event, will retry",
+			zap.String("topic", c.topic),
+			zap.String("type", env.Type),
+			zap.String("event_id", env.ID),
+			zap.Int64("deliveries", deliveries),
+			zap.Error(err))
+		return
+	}
+	if err := c.client.XAck(ctx, StreamKey(c.topic), c.group, msg.ID).Err(); err != nil {
+		c.logger.Warn("Failed to acknowledge event", zap.String("entry", msg.ID), zap.Error(err))
+	}
+}
+
+// deadLetter moves an entry to the dead-letter stream and acknowledges it.
+// If it cannot be stored it stays pending.
+func (c *Consumer) deadLetter(ctx context.Context, msg redis.XMessage, deliveries int64, cause error) {
+	payload, _ := msg.Values[fieldEvent].(string)
+	err := c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: DeadLetterKey(c.topic),
+		MaxLen: DefaultMaxLen,
+		Approx: true,
+		Values: []string{
+			fieldEvent, payload,
+			"entry", msg.ID,
+			"group", c.group,
+			"consumer", c.name,
+			"deliveries", strconv.FormatInt(deliveries, 10),
+			"error", cause.Error(),
+		},
+	}).Err()
+	if err != nil {
+		c.logger.Error("Failed to dead-letter event", zap.String("entry", msg.ID), zap.Error(err))
+		return
+	}
+	c.logger.Warn("Dead-lettered event",
+		zap.String("topic", c.topic),
+		zap.String("entry", msg.ID),
+		zap.Int64("deliveries", deliveries),
+		zap.Error(cause))
+	c.client.XAck(ctx, StreamKey(c.topic), c.group, msg.ID)
+}
+
+// DeadLetter is an event a consumer group gave up on
+type DeadLetter struct {
+	ID         string    `json:"id"`
+	Entry      string    `json:"entry"` // ID in the topic's stream
+	Group      string    `json:"group"`
+	Consumer   string    `json:"consumer"`
+	Deliveries int64     `json:"deliveries"`
+	Error      string    `json:"error"`
+	Event      *Envelope `json:"event,omitempty"` // Nil when the payload was not an event
+	Payload    string    `json:"payload,omitempty"`
+}
+
+// DeadLetters returns up to count of topic's dead letters, newest first
+func DeadLetters(ctx context.Context, client redis.UniversalClient, topic string, count int64) ([]DeadLetter, error) {
+	msgs, err := client.XRevRangeN(ctx, DeadLetterKey(topic), "+", "-", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		field := func(name string) string {
+			v, _ := msg.Values[name].(string)
+			return v
+		}
+		letter := DeadLetter{
+			ID:       msg.ID,
+			Entry:    field("entry"),
+			Group:    field("group"),
+			Consumer: field("consumer"),
+			Error:    field("error"),
+		}
+		letter.Deliveries, _ = strconv.ParseInt(field("deliveries"), 10, 64)
+		if env, err := Parse([]byte(field(fieldEvent))); err == nil {
+			letter.Event = &env
+		} else {
+			letter.Payload = field(fieldEvent)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
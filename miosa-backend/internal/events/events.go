@@ -0,0 +1,203 @@
+// Package events carries typed, versioned events between services over
+// Redis. Every event is published on its topic's pub/sub channel, for live
+// subscribers that can miss events, and appended to the topic's stream,
+// which consumer groups read with at-least-once delivery; events that keep
+// failing are moved to a dead-letter stream.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Topics, each a pub/sub channel and, under StreamKey, a stream
+const (
+	TopicConfigUpdates      = "config_updates"
+	TopicMonitoringRequests = "monitoring:requests"
+)
+
+// TopicAgentEvents is the topic of events for one agent
+func TopicAgentEvents(agent string) string {
+	return "events:" + agent
+}
+
+// StreamKey is the stream consumer groups read topic from
+func StreamKey(topic string) string {
+	return "stream:" + topic
+}
+
+// DeadLetterKey is the stream holding topic's events that could not be
+// handled
+func DeadLetterKey(topic string) string {
+	return "deadletter:" + topic
+}
+
+// DefaultMaxLen bounds each stream, approximately
+const DefaultMaxLen = 10000
+
+var (
+	// ErrNotEnvelope is returned by Parse for payloads that are not
+	// events, such as messages from publishers predating this package
+	ErrNotEnvelope = errors.New("payload is not an event envelope")
+	// ErrUnsupportedVersion is returned when decoding an event with a
+	// newer schema version than the consumer knows
+	ErrUnsupportedVersion = errors.New("unsupported event schema version")
+	// ErrWrongType is returned when decoding an event into another type
+	ErrWrongType = errors.New("event has a different type")
+)
+
+// Event is a typed payload. Versions only grow when a change is not
+// backward compatible; consumers refuse events newer than their own.
+type Event interface {
+	Topic() string
+	EventType() string
+	SchemaVersion() int
+}
+
+// Envelope wraps an event's data with its type, schema version and origin
+type Envelope struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	Source  string          `json:"source,omitempty"` // Publishing service
+	Time    time.Time       `json:"time"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// NewEnvelope wraps e, published by source
+func NewEnvelope(source string, e Event) (Envelope, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to encode %s event: %w", e.EventType(), err)
+	}
+	return Envelope{
+		ID:      uuid.New().String(),
+		Type:    e.EventType(),
+		Version: e.SchemaVersion(),
+		Source:  source,
+		Time:    time.Now().UTC(),
+		Data:    data,
+	}, nil
+}
+
+// Parse reads an envelope, returning ErrNotEnvelope for other JSON
+func Parse(payload []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return env, fmt.Errorf("%w: %v", ErrNotEnvelope, err)
+	}
+	if env.Type == "" || env.Version == 0 || len(env.Data) == 0 {
+		return env, ErrNotEnvelope
+	}
+	return env, nil
+}
+
+// Decode unmarshals the envelope's data into e, refusing other types and
+// newer schema versions
+func (env Envelope) Decode(e Event) error {
+	if env.Type != e.EventType() {
+		return fmt.Errorf("%w: %s, want %s", ErrWrongType, env.Type, e.EventType())
+	}
+	if env.Version > e.SchemaVersion() {
+		return fmt.Errorf("%w: %s v%d, consumer knows v%d", ErrUnsupportedVersion, env.Type, env.Version, e.SchemaVersion())
+	}
+	if err := json.Unmarshal(env.Data, e); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", env.Type, err)
+	}
+	return nil
+}
+
+// Handler handles one event. An error leaves a stream event pending, to be
+// delivered again.
+type Handler func(ctx context.Context, env Envelope) error
+
+// Bus publishes events. A nil *Bus, or one without a client, publishes
+// nothing.
+type Bus struct {
+	client redis.UniversalClient
+	source string
+	maxLen int64
+}
+
+// NewBus creates a bus publishing as source
+func NewBus(client redis.UniversalClient, source string) *Bus {
+	return &Bus{client: client, source: source, maxLen: DefaultMaxLen}
+}
+
+// Publish sends e to its topic's subscribers and appends it to the topic's
+// stream. The event is returned even when publishing fails.
+func (b *Bus) Publish(ctx context.Context, e Event) (Envelope, error) {
+	env, err := NewEnvelope(b.sourceName(), e)
+	if err != nil || b == nil || b.client == nil {
+		return env, err
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return env, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	pubErr := b.client.Publish(ctx, e.Topic(), payload).Err()
+	addErr := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamKey(e.Topic()),
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: []string{fieldEvent, string(payload)},
+	}).Err()
+	if err := errors.Join(pubErr, addErr); err != nil {
+		return env, fmt.Errorf("failed to publish %s event: %w", env.Type, err)
+	}
+	return env, nil
+}
+
+func (b *Bus) sourceName() string {
+	if b == nil {
+		return ""
+	}
+	return b.source
+}
+
+// Subscribe calls handle with every event published on topic until ctx is
+// done. Pub/sub delivers at most once: events published while no
+// subscriber is connected are lost, so durable consumers use a Consumer.
+// Payloads that are not events, and handler errors, are logged.
+func Subscribe(ctx context.Context, client redis.UniversalClient, topic string, handle Handler, logger *zap.Logger) error {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	sub := client.Subscribe(ctx, topic)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			env, err := Parse([]byte(msg.Payload))
+			if err != nil {
+				logger.Warn("Ignoring malformed event", zap.String("topic", topic), zap.Error(err))
+				continue
+			}
+			if err := handle(ctx, env); err != nil {
+				logger.Warn("Failed to handle event",
+					zap.String("topic", topic),
+					zap.String("type", env.Type),
+					zap.String("event_id", env.ID),
+					zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, e Event) string {
+	t.Helper()
+	env, err := NewEnvelope("test", e)
+	require.NoError(t, err)
+	payload, err := json.Marshal(env)
+	require.NoError(t, err)
+	return string(payload)
+}
+
+func TestEnvelope(t *testing.T) {
+	request := EvaluationRequest{PatternID: uuid.New(), SuggestionID: uuid.New(), Window: "30m"}
+	env, err := Parse([]byte(encode(t, request)))
+	require.NoError(t, err)
+	assert.Equal(t, "evaluate_improvement", env.Type)
+	assert.Equal(t, 1, env.Version)
+	assert.Equal(t, "test", env.Source)
+	assert.NotEmpty(t, env.ID)
+
+	var decoded EvaluationRequest
+	require.NoError(t, env.Decode(&decoded))
+	assert.Equal(t, request, decoded)
+
+	assert.ErrorIs(t, env.Decode(&ConfigUpdate{}), ErrWrongType)
+	env.Version = 2
+	assert.ErrorIs(t, env.Decode(&decoded), ErrUnsupportedVersion)
+
+	_, err = Parse([]byte(`{"type":"config_update","target":"orchestrator","update":{"kind":"model"}}`))
+	assert.ErrorIs(t, err, ErrNotEnvelope, "bare messages from older publishers")
+	_, err = Parse([]byte(`not json`))
+	assert.ErrorIs(t, err, ErrNotEnvelope)
+}
+
+func TestBus_Publish(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	// Payloads carry a fresh ID and time, so only commands and keys are matched
+	keys := mock.CustomMatch(func(expected, actual []interface{}) error {
+		if fmt.Sprint(expected[:2]) != fmt.Sprint(actual[:2]) {
+			return fmt.Errorf("got %v, want %v", actual[:2], expected[:2])
+		}
+		return nil
+	})
+	keys.ExpectPublish("events:development", "").SetVal(1)
+	keys.ExpectXAdd(&redis.XAddArgs{Stream: "stream:events:development", MaxLen: DefaultMaxLen, Approx: true, Values: []string{"event", ""}}).SetVal("1-0")
+
+	env, err := NewBus(client, "task-queue").Publish(context.Background(), TaskCreated{TaskID: uuid.New(), Agent: "development"})
+	require.NoError(t, err)
+	assert.Equal(t, "task_created", env.Type)
+	assert.Equal(t, "task-queue", env.Source)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var bus *Bus
+	_, err = bus.Publish(context.Background(), ConfigUpdate{Target: "orchestrator"})
+	assert.NoError(t, err, "a nil bus publishes nothing")
+}
+
+func deadLetterArgs(payload, entry string, deliveries, cause string) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream: "deadletter:config_updates",
+		MaxLen: DefaultMaxLen,
+		Approx: true,
+		Values: []string{
+			"event", payload,
+			"entry", entry,
+			"group", "orchestrators",
+			"consumer", "orchestrator-1",
+			"deliveries", deliveries,
+			"error", cause,
+		},
+	}
+}
+
+func TestConsumer_Process(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	c := NewConsumer(client, TopicConfigUpdates, "orchestrators", "orchestrator-1", nil)
+	payload := encode(t, ConfigUpdate{Target: "orchestrator", Kind: "model"})
+	msg := redis.XMessage{ID: "1-0", Values: map[string]interface{}{"event": payload}}
+
+	var handled []string
+	ok := func(ctx context.Context, env Envelope) error {
+		handled = append(handled, env.Type)
+		return nil
+	}
+	failing := func(ctx context.Context, env Envelope) error { return errors.New("apply failed") }
+
+	mock.ExpectXAck("stream:config_updates", "orchestrators", "1-0").SetVal(1)
+	c.process(ctx, msg, 1, ok)
+	assert.Equal(t, []string{"config_update"}, handled)
+
+	c.process(ctx, msg, 2, failing) // Stays pending for a retry
+
+	mock.ExpectXAdd(deadLetterArgs(payload, "1-0", "5", "apply failed")).SetVal("9-0")
+	mock.ExpectXAck("stream:config_updates", "orchestrators", "1-0").SetVal(1)
+	c.process(ctx, msg, 5, failing)
+
+	garbled := redis.XMessage{ID: "2-0", Values: map[string]interface{}{"event": "not json"}}
+	mock.ExpectXAdd(deadLetterArgs("not json", "2-0", "1", "payload is not an event envelope: invalid character 'o' in literal null (expecting 'u')")).SetVal("9-1")
+	mock.ExpectXAck("stream:config_updates", "orchestrators", "2-0").SetVal(1)
+	c.process(ctx, garbled, 1, ok)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumer_Reclaim(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	c := NewConsumer(client, TopicConfigUpdates, "orchestrators", "orchestrator-1", nil)
+	payload := encode(t, ConfigUpdate{Target: "orchestrator", Kind: "model"})
+
+	mock.ExpectXPendingExt(&redis.XPendingExtArgs{
+		Stream: "stream:config_updates", Group: "orchestrators", Idle: time.Minute, Start: "-", End: "+", Count: DefaultBatch,
+	}).SetVal([]redis.XPendingExt{
+		{ID: "1-0", Consumer: "orchestrator-2", RetryCount: 1},
+		{ID: "2-0", Consumer: "orchestrator-2", RetryCount: 5},
+	})
+	claim := func(id string) *redis.XClaimArgs {
+		return &redis.XClaimArgs{
+			Stream: "stream:config_updates", Group: "orchestrators", Consumer: "orchestrator-1", MinIdle: time.Minute, Messages: []string{id},
+		}
+	}
+	mock.ExpectXClaim(claim("1-0")).SetVal([]redis.XMessage{{ID: "1-0", Values: map[string]interface{}{"event": payload}}})
+	mock.ExpectXAck("stream:config_updates", "orchestrators", "1-0").SetVal(1)
+	mock.ExpectXClaim(claim("2-0")).SetVal([]redis.XMessage{{ID: "2-0", Values: map[string]interface{}{"event": payload}}})
+	mock.ExpectXAdd(deadLetterArgs(payload, "2-0", "5", "not handled after 5 deliveries")).SetVal("9-0")
+	mock.ExpectXAck("stream:config_updates", "orchestrators", "2-0").SetVal(1)
+
+	var handled int
+	require.NoError(t, c.Reclaim(ctx, func(ctx context.Context, env Envelope) error {
+		handled++
+		return nil
+	}))
+	assert.Equal(t, 1, handled, "events past MaxDeliveries are dead-lettered unhandled")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeadLetters(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	payload := encode(t, ConfigUpdate{Target: "orchestrator", Kind: "model"})
+	mock.ExpectXRevRangeN("deadletter:config_updates", "+", "-", 10).SetVal([]redis.XMessage{
+		{ID: "9-1", Values: map[string]interface{}{"event": "not json", "entry": "2-0", "deliveries": "1", "error": "malformed"}},
+		{ID: "9-0", Values: map[string]interface{}{"event": payload, "entry": "1-0", "group": "orchestrators", "deliveries": "5", "error": "apply failed"}},
+	})
+
+	letters, err := DeadLetters(context.Background(), client, TopicConfigUpdates, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	assert.Nil(t, letters[0].Event)
+	assert.Equal(t, "not json", letters[0].Payload)
+	require.NotNil(t, letters[1].Event)
+	assert.Equal(t, "config_update", letters[1].Event.Type)
+	assert.Equal(t, int64(5), letters[1].Deliveries)
+	assert.Equal(t, "apply failed", letters[1].Error)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
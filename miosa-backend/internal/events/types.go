@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// ConfigUpdate asks the services named by Target to apply a live
+// configuration change of Kind
+type ConfigUpdate struct {
+	Target string          `json:"target"`
+	Kind   string          `json:"kind"`
+	Config json.RawMessage `json:"config"`
+}
+
+func (ConfigUpdate) Topic() string      { return TopicConfigUpdates }
+func (ConfigUpdate) EventType() string  { return "config_update" }
+func (ConfigUpdate) SchemaVersion() int { return 1 }
+
+// EvaluationRequest asks the monitoring service to measure the impact of
+// an applied improvement over Window, e.g. "30m"
+type EvaluationRequest struct {
+	PatternID    uuid.UUID `json:"pattern_id"`
+	SuggestionID uuid.UUID `json:"suggestion_id"`
+	Window       string    `json:"window"`
+}
+
+func (EvaluationRequest) Topic() string      { return TopicMonitoringRequests }
+func (EvaluationRequest) EventType() string  { return "evaluate_improvement" }
+func (EvaluationRequest) SchemaVersion() int { return 1 }
+
+// TaskCreated announces a collaborative task queued for Agent. Task is the
+// task as the queue stores it.
+type TaskCreated struct {
+	TaskID   uuid.UUID       `json:"task_id"`
+	Agent    string          `json:"agent"`
+	Priority int             `json:"priority"`
+	Task     json.RawMessage `json:"task,omitempty"`
+}
+
+func (e TaskCreated) Topic() string    { return TopicAgentEvents(e.Agent) }
+func (TaskCreated) EventType() string  { return "task_created" }
+func (TaskCreated) SchemaVersion() int { return 1 }
@@ -0,0 +1,289 @@
+// Package flags turns new agent behaviors on per tenant or for a
+// percentage of workflows, so a behavior such as patch mode or a new
+// prompt can be rolled out in stages. Each workflow's decisions are
+// recorded in its report, which explains why two runs behaved differently.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// FlagsKey is the Redis hash of flags, each field a flag name and each
+// value the flag as JSON
+const FlagsKey = "feature_flags"
+
+// DefaultTTL bounds how long flags are cached between store reads
+const DefaultTTL = 15 * time.Second
+
+// Reasons a flag was on or off for a workflow
+const (
+	ReasonTenant  = "tenant"  // The tenant's override decided
+	ReasonEnabled = "enabled" // The flag is on everywhere
+	ReasonRollout = "rollout" // The workflow's bucket fell inside the percentage
+	ReasonOff     = "off"     // Nothing turned the flag on
+)
+
+var (
+	ErrInvalidFlag = errors.New("invalid feature flag")
+	ErrNotFound    = errors.New("feature flag not found")
+)
+
+// flagName is a valid flag name; flags named after an agents.Feature
+// switch that feature
+var flagName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// Flag is one agent behavior and who it is on for
+type Flag struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Enabled     bool            `json:"enabled"`           // On for every workflow
+	Percentage  int             `json:"percentage"`        // Share of workflows it is on for when not Enabled, 0-100
+	Tenants     map[string]bool `json:"tenants,omitempty"` // Per-tenant overrides, taking precedence over the rest
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Validate checks the flag's name and percentage
+func (f Flag) Validate() error {
+	if !flagName.MatchString(f.Name) {
+		return fmt.Errorf("%w: name %q must be lowercase letters, digits and underscores", ErrInvalidFlag, f.Name)
+	}
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return fmt.Errorf("%w: percentage %d must be between 0 and 100", ErrInvalidFlag, f.Percentage)
+	}
+	return nil
+}
+
+// Decision is whether a flag was on for one workflow, and why
+type Decision struct {
+	Flag    string `json:"flag"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+	Bucket  int    `json:"bucket,omitempty"` // The workflow's rollout bucket, 0-99, when a percentage decided
+}
+
+// Evaluate decides the flag for a workflow of a tenant. A workflow's
+// bucket depends only on the flag and the workflow, so it gets the same
+// decision every time it is evaluated, and raising the percentage only
+// ever adds workflows.
+func Evaluate(f Flag, tenantID, workflowID string) Decision {
+	d := Decision{Flag: f.Name}
+	if on, ok := f.Tenants[tenantID]; ok && tenantID != "" {
+		d.Enabled, d.Reason = on, ReasonTenant
+		return d
+	}
+	if f.Enabled {
+		d.Enabled, d.Reason = true, ReasonEnabled
+		return d
+	}
+	if f.Percentage > 0 {
+		d.Bucket = Bucket(f.Name, workflowID)
+		d.Enabled, d.Reason = d.Bucket < f.Percentage, ReasonRollout
+		if d.Enabled {
+			return d
+		}
+	}
+	d.Reason = ReasonOff
+	return d
+}
+
+// Bucket places a workflow in one of 100 rollout buckets for a flag
+func Bucket(flag, workflowID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flag + "\x00" + workflowID))
+	return int(h.Sum32() % 100)
+}
+
+// Decisions are the flags evaluated for one workflow, sorted by name
+type Decisions []Decision
+
+// Lookup returns the decision for a flag, and whether the flag exists
+func (ds Decisions) Lookup(flag string) (Decision, bool) {
+	for _, d := range ds {
+		if d.Flag == flag {
+			return d, true
+		}
+	}
+	return Decision{}, false
+}
+
+// Store persists flags
+type Store interface {
+	List(ctx context.Context) ([]Flag, error)
+	Put(ctx context.Context, f Flag) error
+	Delete(ctx context.Context, name string) error
+}
+
+// MemoryStore keeps flags in the process, for deployments without Redis
+type MemoryStore struct {
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{flags: make(map[string]Flag)}
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Flag, 0, len(s.flags))
+	for _, f := range s.flags {
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, f Flag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags[f.Name] = f
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.flags[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.flags, name)
+	return nil
+}
+
+// RedisStore keeps flags in the FlagsKey hash, shared by every instance
+type RedisStore struct {
+	client redis.UniversalClient
+	logger *zap.Logger
+}
+
+// NewRedisStore creates a store on the FlagsKey hash
+func NewRedisStore(client redis.UniversalClient, logger *zap.Logger) *RedisStore {
+	return &RedisStore{client: client, logger: logger}
+}
+
+// List reads every flag. Values that do not decode are logged and skipped
+// rather than failing every workflow.
+func (s *RedisStore) List(ctx context.Context) ([]Flag, error) {
+	fields, err := s.client.HGetAll(ctx, FlagsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Flag, 0, len(fields))
+	for name, raw := range fields {
+		var f Flag
+		if err := json.Unmarshal([]byte(raw), &f); err != nil {
+			s.logger.Warn("Skipping undecodable feature flag", zap.String("flag", name), zap.Error(err))
+			continue
+		}
+		f.Name = name
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func (s *RedisStore) Put(ctx context.Context, f Flag) error {
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, FlagsKey, f.Name, raw).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, name string) error {
+	n, err := s.client.HDel(ctx, FlagsKey, name).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Service evaluates flags for workflows from a cached copy of the store.
+// When the store cannot be read, the last flags read keep being used.
+type Service struct {
+	store  Store
+	ttl    time.Duration
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	flags   []Flag
+	fetched time.Time
+}
+
+// NewService creates a service reading store at most once per ttl
+func NewService(store Store, ttl time.Duration, logger *zap.Logger) *Service {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Service{store: store, ttl: ttl, logger: logger}
+}
+
+// Flags returns every flag, sorted by name
+func (s *Service) Flags(ctx context.Context) []Flag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fetched.IsZero() || time.Since(s.fetched) >= s.ttl {
+		flags, err := s.store.List(ctx)
+		if err != nil {
+			s.logger.Warn("Failed to read feature flags; using the last ones read", zap.Error(err))
+		} else {
+			sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+			s.flags = flags
+		}
+		// A failing store is retried after the TTL, not on every workflow
+		s.fetched = time.Now()
+	}
+	return append([]Flag(nil), s.flags...)
+}
+
+// Evaluate decides every flag for a workflow of a tenant
+func (s *Service) Evaluate(ctx context.Context, tenantID, workflowID string) Decisions {
+	flags := s.Flags(ctx)
+	out := make(Decisions, 0, len(flags))
+	for _, f := range flags {
+		out = append(out, Evaluate(f, tenantID, workflowID))
+	}
+	return out
+}
+
+// Put validates and stores a flag
+func (s *Service) Put(ctx context.Context, f Flag) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	f.UpdatedAt = time.Now().UTC()
+	if err := s.store.Put(ctx, f); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// Delete removes a flag, turning its behavior off everywhere
+func (s *Service) Delete(ctx context.Context, name string) error {
+	if err := s.store.Delete(ctx, name); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.fetched = time.Time{}
+	s.mu.Unlock()
+}
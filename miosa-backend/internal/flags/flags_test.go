@@ -0,0 +1,169 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+)
+
+func TestEvaluate(t *testing.T) {
+	f := Flag{Name: "patch_mode", Percentage: 30, Tenants: map[string]bool{"on": true, "off": false}}
+
+	d := Evaluate(f, "on", "wf")
+	assert.Equal(t, Decision{Flag: "patch_mode", Enabled: true, Reason: ReasonTenant}, d)
+	d = Evaluate(Flag{Name: "patch_mode", Enabled: true, Tenants: f.Tenants}, "off", "wf")
+	assert.False(t, d.Enabled, "a tenant override beats enabled")
+	assert.Equal(t, ReasonTenant, d.Reason)
+
+	assert.Equal(t, ReasonEnabled, Evaluate(Flag{Name: "x", Enabled: true}, "", "wf").Reason)
+	assert.Equal(t, Decision{Flag: "x", Reason: ReasonOff}, Evaluate(Flag{Name: "x"}, "", "wf"))
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		d := Evaluate(f, "other", fmt.Sprint("wf-", i))
+		assert.Equal(t, d, Evaluate(f, "other", fmt.Sprint("wf-", i)), "decisions are stable")
+		if d.Enabled {
+			on++
+			assert.Equal(t, ReasonRollout, d.Reason)
+			assert.Less(t, d.Bucket, 30)
+		}
+	}
+	assert.InDelta(t, 300, on, 60)
+
+	// Raising the percentage keeps every workflow already on
+	wider := f
+	wider.Percentage = 60
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprint("wf-", i)
+		if Evaluate(f, "", id).Enabled {
+			assert.True(t, Evaluate(wider, "", id).Enabled)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Flag{Name: "prompt_v2", Percentage: 100}.Validate())
+	assert.ErrorIs(t, Flag{Name: "Prompt V2"}.Validate(), ErrInvalidFlag)
+	assert.ErrorIs(t, Flag{Name: "x", Percentage: 101}.Validate(), ErrInvalidFlag)
+	assert.ErrorIs(t, Flag{Name: "x", Percentage: -1}.Validate(), ErrInvalidFlag)
+}
+
+func TestRedisStore(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	store := NewRedisStore(client, zap.NewNop())
+	ctx := context.Background()
+
+	mock.ExpectHGetAll(FlagsKey).SetVal(map[string]string{
+		"patch_mode": `{"enabled":true}`,
+		"broken":     `not json`,
+	})
+	flags, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, flags, 1, "undecodable flags are skipped")
+	assert.Equal(t, "patch_mode", flags[0].Name)
+	assert.True(t, flags[0].Enabled)
+
+	mock.ExpectHDel(FlagsKey, "missing").SetVal(0)
+	assert.ErrorIs(t, store.Delete(ctx, "missing"), ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// failingStore fails to list after its first read
+type failingStore struct {
+	*MemoryStore
+	reads int
+}
+
+func (s *failingStore) List(ctx context.Context) ([]Flag, error) {
+	s.reads++
+	if s.reads > 1 {
+		return nil, errors.New("store unavailable")
+	}
+	return s.MemoryStore.List(ctx)
+}
+
+func TestServiceCachesAndSurvivesStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	store := &failingStore{MemoryStore: NewMemoryStore()}
+	store.MemoryStore.Put(ctx, Flag{Name: "b", Enabled: true})
+	store.MemoryStore.Put(ctx, Flag{Name: "a"})
+	svc := NewService(store, time.Nanosecond, zap.NewNop())
+
+	ds := svc.Evaluate(ctx, "", "wf")
+	require.Len(t, ds, 2)
+	assert.Equal(t, "a", ds[0].Flag, "decisions are sorted by flag")
+	d, ok := ds.Lookup("b")
+	assert.True(t, ok)
+	assert.True(t, d.Enabled)
+
+	time.Sleep(time.Millisecond)
+	assert.Len(t, svc.Evaluate(ctx, "", "wf"), 2, "the last flags read are kept")
+	assert.Equal(t, 2, store.reads)
+
+	cached := NewService(store.MemoryStore, time.Hour, zap.NewNop())
+	cached.Flags(ctx)
+	store.MemoryStore.Put(ctx, Flag{Name: "c"})
+	assert.Len(t, cached.Flags(ctx), 2, "flags are cached for the TTL")
+	require.NoError(t, cached.Put(ctx, Flag{Name: "d"}))
+	assert.Len(t, cached.Flags(ctx), 4, "a change through the service invalidates the cache")
+}
+
+func TestHandlers(t *testing.T) {
+	svc := NewService(NewMemoryStore(), time.Hour, zap.NewNop())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/flags", Handler(svc)).Methods("GET")
+	router.HandleFunc("/api/admin/flags/{name}", PutHandler(svc, nil)).Methods("PUT")
+	router.HandleFunc("/api/admin/flags/{name}", DeleteHandler(svc, nil)).Methods("DELETE")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("PUT", "/api/admin/flags/patch_mode", `{"percentage": 25, "tenants": {"t1": true}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"patch_mode"`)
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/api/admin/flags/patch_mode", `{"percentage": 250}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/api/admin/flags/patch_mode", `nope`).Code)
+
+	w = do("GET", "/api/admin/flags", "")
+	assert.Contains(t, w.Body.String(), `"percentage":25`)
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/admin/flags/patch_mode", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/api/admin/flags/patch_mode", "").Code)
+}
+
+func TestHandlersAuditTheAuthenticatedCaller(t *testing.T) {
+	auditLog := audit.New(audit.NewMemoryStore(), zap.NewNop())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/flags/{name}", PutHandler(NewService(NewMemoryStore(), time.Hour, zap.NewNop()), auditLog)).Methods("PUT")
+
+	principal := &middleware.Principal{UserID: uuid.New(), TenantID: uuid.New(), Role: middleware.RoleAdmin}
+	req := httptest.NewRequest("PUT", "/api/admin/flags/patch_mode", strings.NewReader(`{"percentage": 25}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req.WithContext(middleware.WithPrincipal(req.Context(), principal)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	events, err := auditLog.Query(context.Background(), audit.Filter{Action: audit.ActionFlagUpdate})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, principal.UserID.String(), events[0].Actor)
+	assert.Equal(t, audit.ActorUser, events[0].ActorType)
+	assert.Equal(t, principal.TenantID, events[0].TenantID)
+}
@@ -0,0 +1,82 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+)
+
+// Handler serves GET /api/admin/flags: every flag, sorted by name
+func Handler(s *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"flags": s.Flags(r.Context())})
+	}
+}
+
+// PutHandler serves PUT /api/admin/flags/{name}, creating or replacing
+// the flag with the JSON body
+func PutHandler(s *Service, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var f Flag
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&f); err != nil {
+			apierror.Write(w, r, apierror.Invalid("invalid request body"))
+			return
+		}
+		f.Name = mux.Vars(r)["name"]
+		if err := s.Put(r.Context(), f); err != nil {
+			if errors.Is(err, ErrInvalidFlag) {
+				apierror.Write(w, r, apierror.Invalid(err.Error(), apierror.FieldError{Field: "flag", Message: err.Error()}))
+				return
+			}
+			apierror.Write(w, r, err)
+			return
+		}
+		record(r, auditLog, f.Name, "put")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(f)
+	}
+}
+
+// DeleteHandler serves DELETE /api/admin/flags/{name}
+func DeleteHandler(s *Service, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		if err := s.Delete(r.Context(), name); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, err.Error()))
+				return
+			}
+			apierror.Write(w, r, err)
+			return
+		}
+		record(r, auditLog, name, "delete")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// record audits a change to a flag, attributed to the caller
+// middleware.RequireHTTP authenticated
+func record(r *http.Request, auditLog *audit.Log, name, op string) {
+	if auditLog == nil {
+		return
+	}
+	actor, actorType := audit.ActorFromRequest(r)
+	event := audit.Event{
+		Actor:     actor,
+		ActorType: actorType,
+		Action:    audit.ActionFlagUpdate,
+		Resource:  name,
+		Status:    audit.StatusSuccess,
+		Metadata:  map[string]string{"operation": op},
+	}
+	if principal, ok := middleware.PrincipalFromRequest(r); ok {
+		event = audit.FromTaskContext(event, principal.TaskContext())
+	}
+	auditLog.Record(r.Context(), event)
+}
@@ -0,0 +1,182 @@
+// Package githubapp runs MIOSA as a GitHub App: labeling an issue starts a
+// workflow against the repository, and the generated changes come back as a
+// pull request carrying the quality report and a quality-gate check run.
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultAPIURL is the public GitHub REST API
+const DefaultAPIURL = "https://api.github.com"
+
+// Client calls the GitHub REST API as an App
+type Client struct {
+	appID   int64
+	key     *rsa.PrivateKey
+	baseURL string
+	http    *http.Client
+	now     func() time.Time
+}
+
+// NewClient returns a client for the App appID signing with key. An empty
+// baseURL uses DefaultAPIURL; GitHub Enterprise serves the API at
+// https://<host>/api/v3.
+func NewClient(appID int64, key *rsa.PrivateKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &Client{
+		appID:   appID,
+		key:     key,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+		now:     time.Now,
+	}
+}
+
+// ParsePrivateKey reads the PEM private key GitHub issues for an App
+func ParsePrivateKey(pem []byte) (*rsa.PrivateKey, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	return key, nil
+}
+
+// appToken signs the short-lived JWT that authenticates as the App itself
+func (c *Client) appToken() (string, error) {
+	now := c.now()
+	claims := jwt.RegisteredClaims{
+		Issuer: strconv.FormatInt(c.appID, 10),
+		// Backdated to allow for clock drift, as GitHub recommends
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(c.key)
+}
+
+// InstallationToken creates an access token scoped to one installation
+func (c *Client) InstallationToken(ctx context.Context, installationID int64) (string, error) {
+	appToken, err := c.appToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app token: %w", err)
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", installationID)
+	if err := c.do(ctx, appToken, http.MethodPost, path, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// PullRequest is the input for CreatePullRequest
+type PullRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Body  string `json:"body"`
+}
+
+// CreatedPullRequest identifies an opened pull request
+type CreatedPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// CreatePullRequest opens a pull request in repo ("owner/name")
+func (c *Client) CreatePullRequest(ctx context.Context, token, repo string, pr PullRequest) (*CreatedPullRequest, error) {
+	var created CreatedPullRequest
+	if err := c.do(ctx, token, http.MethodPost, "/repos/"+repo+"/pulls", pr, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Check run conclusions
+const (
+	ConclusionSuccess = "success"
+	ConclusionFailure = "failure"
+	ConclusionNeutral = "neutral"
+)
+
+// CheckRun is a completed status check on a commit
+type CheckRun struct {
+	Name       string         `json:"name"`
+	HeadSHA    string         `json:"head_sha"`
+	Status     string         `json:"status"`
+	Conclusion string         `json:"conclusion"`
+	Output     CheckRunOutput `json:"output"`
+}
+
+// CheckRunOutput is the summary shown on a check run's page
+type CheckRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Text    string `json:"text,omitempty"`
+}
+
+// CreateCheckRun reports a completed check on a commit in repo
+func (c *Client) CreateCheckRun(ctx context.Context, token, repo string, run CheckRun) error {
+	run.Status = "completed"
+	return c.do(ctx, token, http.MethodPost, "/repos/"+repo+"/check-runs", run, nil)
+}
+
+// CreateComment comments on an issue or pull request in repo
+func (c *Client) CreateComment(ctx context.Context, token, repo string, number int, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number)
+	return c.do(ctx, token, http.MethodPost, path, map[string]string{"body": body}, nil)
+}
+
+// do sends a JSON request authenticated with token and decodes the response
+// into out when it is not nil
+func (c *Client) do(ctx context.Context, token, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call GitHub %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("GitHub %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+	return nil
+}
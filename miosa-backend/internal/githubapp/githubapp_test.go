@@ -0,0 +1,253 @@
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+// fakeGitHub records the API calls the service makes
+type fakeGitHub struct {
+	mu       sync.Mutex
+	key      *rsa.PublicKey
+	calls    []string
+	bodies   map[string]map[string]interface{}
+	appToken string
+	called   chan string
+}
+
+func (g *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call := r.Method + " " + r.URL.Path
+	g.calls = append(g.calls, call)
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	g.bodies[call] = body
+	if g.called != nil {
+		g.called <- call
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/app/installations/"):
+		if g.key == nil {
+			http.Error(w, "no app", http.StatusInternalServerError)
+			return
+		}
+		g.appToken = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		json.NewEncoder(w).Encode(map[string]string{"token": "ghs_installation"})
+	case strings.HasSuffix(r.URL.Path, "/pulls"):
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"number": 7, "html_url": "https://github.com/acme/todo/pull/7"})
+	default:
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	}
+}
+
+// genBackend generates one file into root under each workflow's ID
+type genBackend struct {
+	root    string
+	got     *orchestrate.Request
+	quality bool
+}
+
+func (b *genBackend) Orchestrate(ctx context.Context, req *orchestrate.Request) (*orchestrate.Workflow, error) {
+	b.got = req
+	id := uuid.New()
+	ws, err := workspace.Open(b.root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ws.Write("internal/todo/handler.go", "package todo\n", workspace.Provenance{WorkflowID: id.String(), Run: "r", Agent: "development"}); err != nil {
+		return nil, err
+	}
+	if err := ws.Save(); err != nil {
+		return nil, err
+	}
+	return &orchestrate.Workflow{ID: id, Success: true, Steps: []orchestrate.StepResult{
+		{Agent: agents.DevelopmentAgent, Success: true, Confidence: 0.9, ExecutionMS: 30},
+		{Agent: agents.QualityAgent, Success: b.quality, Output: "**Metrics:**\n- Issues found: 0\n", Data: map[string]interface{}{"assurance_score": 96.0}},
+	}}, nil
+}
+
+func (b *genBackend) Workflow(uuid.UUID) (*orchestrate.Workflow, bool) { return nil, false }
+func (b *genBackend) Agents() []orchestrate.AgentInfo                  { return nil }
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(context.Background(), dir, args...)
+	require.NoError(t, err)
+	return out
+}
+
+// originRepo creates a bare repository with one commit on main
+func originRepo(t *testing.T) string {
+	origin := filepath.Join(t.TempDir(), "todo.git")
+	runGit(t, t.TempDir(), "init", "--bare", "--initial-branch=main", origin)
+	work := t.TempDir()
+	runGit(t, work, "clone", origin, ".")
+	require.NoError(t, os.WriteFile(filepath.Join(work, "go.mod"), []byte("module todo\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(work, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "-c", "user.name=dev", "-c", "user.email=dev@example.com", "commit", "-m", "init")
+	runGit(t, work, "push", "origin", "HEAD:main")
+	return origin
+}
+
+func newTestService(t *testing.T, gh *fakeGitHub, backend *genBackend) *Service {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	gh.key = &key.PublicKey
+	gh.bodies = make(map[string]map[string]interface{})
+	api := httptest.NewServer(gh)
+	t.Cleanup(api.Close)
+
+	s := NewService(Config{WebhookSecret: "shh", WorkDir: t.TempDir()}, NewClient(42, key, api.URL), backend,
+		func(uuid.UUID) string { return backend.root }, nil, zap.NewNop())
+	s.clone = func(ctx context.Context, repoURL, ref, dir string) error {
+		_, err := git(ctx, "", "clone", "--branch", ref, repoURL, dir)
+		return err
+	}
+	return s
+}
+
+func issueEvent(origin string) *IssueEvent {
+	var e IssueEvent
+	e.Action = "labeled"
+	e.Label.Name = "miosa"
+	e.Issue.Number = 12
+	e.Issue.Title = "Add a todo handler"
+	e.Issue.Body = "Expose CRUD endpoints for todos backed by postgres."
+	e.Issue.HTMLURL = "https://github.com/acme/todo/issues/12"
+	e.Repository.FullName = "acme/todo"
+	e.Repository.CloneURL = origin
+	e.Repository.DefaultBranch = "main"
+	e.Installation.ID = 99
+	e.Sender.Login = "octocat"
+	return &e
+}
+
+func TestService_Handle(t *testing.T) {
+	origin := originRepo(t)
+	gh := &fakeGitHub{}
+	backend := &genBackend{root: t.TempDir(), quality: true}
+	s := newTestService(t, gh, backend)
+
+	outcome, err := s.Handle(context.Background(), issueEvent(origin))
+	require.NoError(t, err)
+
+	assert.Equal(t, "go", backend.got.Language)
+	assert.Contains(t, backend.got.Description, "Add a todo handler\n\nExpose CRUD endpoints")
+	assert.Equal(t, []string{
+		"POST /app/installations/99/access_tokens",
+		"POST /repos/acme/todo/check-runs",
+		"POST /repos/acme/todo/pulls",
+		"POST /repos/acme/todo/issues/12/comments",
+	}, gh.calls)
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(gh.appToken, claims, func(*jwt.Token) (interface{}, error) { return gh.key, nil })
+	require.NoError(t, err, "the installation token is requested with an App JWT")
+	assert.Equal(t, "42", claims.Issuer)
+
+	// The generated file is committed on the branch on top of main
+	assert.Equal(t, "miosa/issue-12", outcome.Branch)
+	assert.Equal(t, outcome.CommitSHA, runGit(t, origin, "rev-parse", "refs/heads/miosa/issue-12"))
+	assert.Equal(t, "package todo\n", runGit(t, origin, "show", "miosa/issue-12:internal/todo/handler.go")+"\n")
+	assert.Equal(t, runGit(t, origin, "rev-parse", "main"), runGit(t, origin, "rev-parse", "miosa/issue-12^"))
+
+	check := gh.bodies["POST /repos/acme/todo/check-runs"]
+	assert.Equal(t, outcome.CommitSHA, check["head_sha"])
+	assert.Equal(t, ConclusionSuccess, check["conclusion"])
+	assert.Equal(t, "Quality gate passed (score 96)", check["output"].(map[string]interface{})["title"])
+
+	pr := gh.bodies["POST /repos/acme/todo/pulls"]
+	assert.Equal(t, "miosa/issue-12", pr["head"])
+	assert.Equal(t, "main", pr["base"])
+	assert.Contains(t, pr["body"], "Closes #12")
+	assert.Contains(t, pr["body"], "Issues found: 0")
+	assert.Equal(t, 7, outcome.PullRequest.Number)
+	assert.Contains(t, gh.bodies["POST /repos/acme/todo/issues/12/comments"]["body"], "Opened #7")
+}
+
+func TestService_HandleFailureComments(t *testing.T) {
+	gh := &fakeGitHub{}
+	backend := &genBackend{root: t.TempDir()}
+	s := newTestService(t, gh, backend)
+
+	event := issueEvent(filepath.Join(t.TempDir(), "missing.git"))
+	_, err := s.Handle(context.Background(), event)
+	require.Error(t, err)
+	assert.Contains(t, gh.bodies["POST /repos/acme/todo/issues/12/comments"]["body"], "MIOSA could not complete this issue")
+	assert.Nil(t, backend.got)
+}
+
+func TestQualityGate(t *testing.T) {
+	failed := QualityGate(&orchestrate.Workflow{Steps: []orchestrate.StepResult{{Agent: agents.QualityAgent, Output: "report"}}})
+	assert.Equal(t, ConclusionFailure, failed.Conclusion)
+	assert.Equal(t, "report", failed.Summary)
+
+	assert.Equal(t, ConclusionNeutral, QualityGate(&orchestrate.Workflow{}).Conclusion)
+}
+
+func sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte("shh"))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	gh := &fakeGitHub{called: make(chan string, 10)}
+	s := newTestService(t, gh, &genBackend{root: t.TempDir()})
+	gh.key = nil // Background handling stops at authentication
+
+	post := func(eventType string, payload []byte, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/github/webhook", bytes.NewReader(payload))
+		req.Header.Set("X-GitHub-Event", eventType)
+		req.Header.Set("X-Hub-Signature-256", signature)
+		rec := httptest.NewRecorder()
+		s.WebhookHandler()(rec, req)
+		return rec.Code
+	}
+
+	event := issueEvent("https://github.com/acme/todo.git")
+	payload, _ := json.Marshal(event)
+	assert.Equal(t, http.StatusUnauthorized, post("issues", payload, "sha256=00"))
+	assert.Equal(t, http.StatusNoContent, post("ping", []byte("{}"), sign([]byte("{}"))))
+
+	event.Label.Name = "bug"
+	other, _ := json.Marshal(event)
+	assert.Equal(t, http.StatusNoContent, post("issues", other, sign(other)))
+
+	assert.Equal(t, http.StatusAccepted, post("issues", payload, sign(payload)))
+	select {
+	case call := <-gh.called:
+		assert.Equal(t, "POST /app/installations/99/access_tokens", call)
+	case <-time.After(5 * time.Second):
+		t.Fatal("labeled issue was not handled")
+	}
+}
@@ -0,0 +1,357 @@
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/ingest"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+// Defaults for Config
+const (
+	DefaultLabel     = "miosa"
+	DefaultCheckName = "MIOSA quality gate"
+	// maxBodyChars stays under GitHub's 65536 character limit for pull
+	// request bodies and check run summaries
+	maxBodyChars = 60000
+)
+
+// Config configures the GitHub integration
+type Config struct {
+	WebhookSecret string
+	Label         string // Issue label that starts a workflow (default DefaultLabel)
+	CheckName     string // Name of the quality gate check run (default DefaultCheckName)
+	WorkDir       string // Parent of the temporary clones (default os.TempDir())
+	CommitAuthor  string // "Name <email>" of generated commits
+}
+
+// Service turns labeled issues into pull requests
+type Service struct {
+	cfg     Config
+	github  *Client
+	backend orchestrate.Backend
+	root    func(workflowID uuid.UUID) string
+	audit   *audit.Log
+	logger  *zap.Logger
+
+	// clone fetches repoURL at ref into dir; replaced in tests
+	clone func(ctx context.Context, repoURL, ref, dir string) error
+}
+
+// NewService creates a service that runs workflows on backend and reads
+// their generated files from the workspace root maps each workflow to
+func NewService(cfg Config, github *Client, backend orchestrate.Backend, root func(uuid.UUID) string, auditLog *audit.Log, logger *zap.Logger) *Service {
+	if cfg.Label == "" {
+		cfg.Label = DefaultLabel
+	}
+	if cfg.CheckName == "" {
+		cfg.CheckName = DefaultCheckName
+	}
+	if cfg.CommitAuthor == "" {
+		cfg.CommitAuthor = "miosa[bot] <miosa[bot]@users.noreply.github.com>"
+	}
+	return &Service{
+		cfg:     cfg,
+		github:  github,
+		backend: backend,
+		root:    root,
+		audit:   auditLog,
+		logger:  logger,
+		clone:   ingest.Clone,
+	}
+}
+
+// Triggers reports whether event should start a workflow
+func (s *Service) Triggers(event *IssueEvent) bool {
+	return event.Action == "labeled" && strings.EqualFold(event.Label.Name, s.cfg.Label) &&
+		event.Installation.ID != 0 && event.Repository.FullName != ""
+}
+
+// Outcome is what handling one issue produced
+type Outcome struct {
+	WorkflowID  uuid.UUID
+	Branch      string
+	CommitSHA   string
+	PullRequest *CreatedPullRequest
+	Gate        Gate
+}
+
+// Handle runs the workflow an issue asks for and opens a pull request with
+// the result. Failures are reported as a comment on the issue.
+func (s *Service) Handle(ctx context.Context, event *IssueEvent) (*Outcome, error) {
+	repo, number := event.Repository.FullName, event.Issue.Number
+	token, err := s.github.InstallationToken(ctx, event.Installation.ID)
+	if err != nil {
+		s.logger.Error("Failed to authenticate GitHub installation", zap.String("repo", repo), zap.Error(err))
+		return nil, err
+	}
+
+	outcome, err := s.handle(ctx, token, event)
+
+	auditEvent := audit.Event{
+		Actor:       event.Sender.Login,
+		ActorType:   audit.ActorUser,
+		Action:      audit.ActionGitHubPullRequest,
+		Resource:    event.Issue.HTMLURL,
+		RequestHash: audit.HashRequest(event.Issue.Title + "\n" + event.Issue.Body),
+		Status:      audit.StatusSuccess,
+		Metadata:    map[string]string{"repository": repo},
+	}
+	if outcome != nil {
+		auditEvent.WorkflowID = outcome.WorkflowID
+	}
+	if err != nil {
+		auditEvent.Status = audit.StatusFailure
+		s.logger.Error("GitHub workflow failed", zap.String("repo", repo), zap.Int("issue", number), zap.Error(err))
+		if cerr := s.github.CreateComment(ctx, token, repo, number, "MIOSA could not complete this issue: "+err.Error()); cerr != nil {
+			s.logger.Warn("Failed to comment on issue", zap.Error(cerr))
+		}
+	} else {
+		auditEvent.Metadata["pull_request"] = outcome.PullRequest.HTMLURL
+		comment := fmt.Sprintf("Opened #%d with the generated changes. Quality gate: %s.", outcome.PullRequest.Number, outcome.Gate.Conclusion)
+		if cerr := s.github.CreateComment(ctx, token, repo, number, comment); cerr != nil {
+			s.logger.Warn("Failed to comment on issue", zap.Error(cerr))
+		}
+	}
+	s.audit.Record(ctx, auditEvent)
+	return outcome, err
+}
+
+func (s *Service) handle(ctx context.Context, token string, event *IssueEvent) (*Outcome, error) {
+	dir, err := os.MkdirTemp(s.cfg.WorkDir, "github-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cloneURL, err := authenticatedURL(event.Repository.CloneURL, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.clone(ctx, cloneURL, event.Repository.DefaultBranch, dir); err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", event.Repository.FullName, err)
+	}
+
+	req := issueRequest(event, dir)
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	workflow, err := s.backend.Orchestrate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	outcome := &Outcome{WorkflowID: workflow.ID, Gate: QualityGate(workflow)}
+
+	copied, err := copyGenerated(s.root(workflow.ID), workflow.ID, dir)
+	if err != nil {
+		return outcome, fmt.Errorf("failed to copy generated files: %w", err)
+	}
+	if copied == 0 {
+		return outcome, fmt.Errorf("workflow %s generated no files", workflow.ID)
+	}
+
+	outcome.Branch = fmt.Sprintf("%s/issue-%d", s.cfg.Label, event.Issue.Number)
+	message := fmt.Sprintf("Generate changes for #%d: %s\n\nWorkflow %s", event.Issue.Number, event.Issue.Title, workflow.ID)
+	if outcome.CommitSHA, err = s.commitAndPush(ctx, dir, outcome.Branch, message); err != nil {
+		return outcome, err
+	}
+
+	repo := event.Repository.FullName
+	summary := truncate(outcome.Gate.Summary, maxBodyChars)
+	if err := s.github.CreateCheckRun(ctx, token, repo, CheckRun{
+		Name:       s.cfg.CheckName,
+		HeadSHA:    outcome.CommitSHA,
+		Conclusion: outcome.Gate.Conclusion,
+		Output:     CheckRunOutput{Title: outcome.Gate.Title(), Summary: summary},
+	}); err != nil {
+		// The pull request is still useful without the check
+		s.logger.Warn("Failed to create check run", zap.String("repo", repo), zap.Error(err))
+	}
+
+	outcome.PullRequest, err = s.github.CreatePullRequest(ctx, token, repo, PullRequest{
+		Title: fmt.Sprintf("MIOSA: %s", event.Issue.Title),
+		Head:  outcome.Branch,
+		Base:  event.Repository.DefaultBranch,
+		Body:  PullRequestBody(event, workflow, outcome.Gate),
+	})
+	if err != nil {
+		return outcome, err
+	}
+	return outcome, nil
+}
+
+// issueRequest builds the orchestrate request for an issue, targeting the
+// repository's primary language when MIOSA supports it
+func issueRequest(event *IssueEvent, dir string) *orchestrate.Request {
+	description := strings.TrimSpace(event.Issue.Title + "\n\n" + event.Issue.Body)
+	if r := []rune(description); len(r) > orchestrate.MaxDescriptionLength {
+		description = string(r[:orchestrate.MaxDescriptionLength])
+	}
+	req := &orchestrate.Request{Description: description}
+	if inv, err := ingest.Walk(dir); err == nil {
+		for _, lang := range orchestrate.Languages {
+			if lang == inv.Primary {
+				req.Language = lang
+			}
+		}
+	}
+	return req
+}
+
+// copyGenerated copies the files workflowID generated under root into dir,
+// returning how many were copied
+func copyGenerated(root string, workflowID uuid.UUID, dir string) (int, error) {
+	ws, err := workspace.Open(root)
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for _, f := range ws.Files() {
+		if f.Provenance.WorkflowID != workflowID.String() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(f.Path)))
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return copied, err
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
+
+// commitAndPush commits everything in dir to branch and pushes it, returning
+// the commit SHA. The branch belongs to the service, so a rerun replaces it.
+func (s *Service) commitAndPush(ctx context.Context, dir, branch, message string) (string, error) {
+	name, email := splitAuthor(s.cfg.CommitAuthor)
+	steps := [][]string{
+		{"checkout", "-B", branch},
+		{"add", "-A"},
+		{"-c", "user.name=" + name, "-c", "user.email=" + email, "commit", "-m", message},
+		{"push", "--force", "origin", "HEAD:refs/heads/" + branch},
+	}
+	for _, args := range steps {
+		if _, err := git(ctx, dir, args...); err != nil {
+			return "", err
+		}
+	}
+	sha, err := git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// authenticatedURL embeds an installation token in an https clone URL
+func authenticatedURL(cloneURL, token string) (string, error) {
+	u, err := url.Parse(cloneURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid clone URL: %w", err)
+	}
+	if u.Scheme == "https" {
+		u.User = url.UserPassword("x-access-token", token)
+	}
+	return u.String(), nil
+}
+
+func splitAuthor(author string) (name, email string) {
+	name, email, ok := strings.Cut(author, "<")
+	if !ok {
+		return strings.TrimSpace(author), ""
+	}
+	return strings.TrimSpace(name), strings.TrimSuffix(strings.TrimSpace(email), ">")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n\n_Truncated._"
+}
+
+// Gate is the quality gate verdict for a workflow
+type Gate struct {
+	Conclusion string  // ConclusionSuccess, ConclusionFailure or ConclusionNeutral
+	Score      float64 // Assurance score, 0-100, when the quality step audited files
+	Summary    string  // The quality agent's report
+}
+
+// Title is the one-line check run title
+func (g Gate) Title() string {
+	switch g.Conclusion {
+	case ConclusionSuccess:
+		return fmt.Sprintf("Quality gate passed (score %.0f)", g.Score)
+	case ConclusionFailure:
+		return fmt.Sprintf("Quality gate failed (score %.0f)", g.Score)
+	}
+	return "Quality gate did not run"
+}
+
+// QualityGate reads the verdict of the workflow's quality step. The quality
+// agent fails its step when the generated files have blocking findings.
+func QualityGate(w *orchestrate.Workflow) Gate {
+	for _, step := range w.Steps {
+		if step.Agent != agents.QualityAgent {
+			continue
+		}
+		gate := Gate{Conclusion: ConclusionFailure, Summary: step.Output}
+		if step.Success {
+			gate.Conclusion = ConclusionSuccess
+		}
+		if score, ok := step.Data["assurance_score"].(float64); ok {
+			gate.Score = score
+		}
+		return gate
+	}
+	return Gate{Conclusion: ConclusionNeutral, Summary: "The workflow did not run the quality agent."}
+}
+
+// PullRequestBody describes the generated changes with the quality report
+func PullRequestBody(event *IssueEvent, w *orchestrate.Workflow, gate Gate) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Generated by MIOSA for #%d.\n\nCloses #%d\n\n", event.Issue.Number, event.Issue.Number)
+	fmt.Fprintf(&sb, "## %s\n\n", gate.Title())
+
+	sb.WriteString("| Step | Agent | Result | Confidence | Time |\n|---|---|---|---|---|\n")
+	for i, step := range w.Steps {
+		result := "ok"
+		if !step.Success {
+			result = "failed"
+		}
+		fmt.Fprintf(&sb, "| %d | %s | %s | %.2f | %dms |\n", i+1, step.Agent, result, step.Confidence, step.ExecutionMS)
+	}
+	fmt.Fprintf(&sb, "\nWorkflow `%s`\n\n## Quality report\n\n", w.ID)
+
+	return sb.String() + truncate(gate.Summary, maxBodyChars-sb.Len())
+}
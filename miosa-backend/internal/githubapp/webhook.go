@@ -0,0 +1,92 @@
+package githubapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxPayloadBytes is the largest webhook payload GitHub delivers
+const MaxPayloadBytes = 25 << 20
+
+// IssueEvent is the subset of an "issues" webhook payload the service uses
+type IssueEvent struct {
+	Action string `json:"action"`
+	Label  struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Issue struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	} `json:"issue"`
+	Repository struct {
+		FullName      string `json:"full_name"`
+		CloneURL      string `json:"clone_url"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// VerifySignature checks the X-Hub-Signature-256 header GitHub computes
+// over the payload with the webhook secret
+func VerifySignature(secret, signature string, payload []byte) bool {
+	sig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// WebhookHandler serves POST /api/github/webhook. Issues labeled with the
+// service's label start a workflow in the background and are acknowledged
+// with 202; every other event is acknowledged with 204 and ignored.
+func (s *Service) WebhookHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPayloadBytes))
+		if err != nil {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if !VerifySignature(s.cfg.WebhookSecret, r.Header.Get("X-Hub-Signature-256"), payload) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get("X-GitHub-Event") != "issues" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var event IssueEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		if !s.Triggers(&event) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// GitHub times deliveries out after ten seconds, so the workflow
+		// outlives the request
+		go s.Handle(context.Background(), &event)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
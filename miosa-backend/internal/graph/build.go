@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/sqlschema"
+)
+
+var (
+	// A heading or a bullet label naming a service, e.g. "## Order Service"
+	// or "- **payments-service**: ..."; prose mentioning one is not a match
+	serviceLine = regexp.MustCompile(`(?i)^\s*(?:#{1,6}\s+(?:\d+\.\s*)?([a-z][\w\- ]{0,40}?)[\s_-](?:micro)?service\s*$|[-*]\s+(?:\*\*|` + "`" + `)([a-z][\w\- ]{0,40}?)[\s_-](?:micro)?service(?:\*\*|` + "`" + `)?\s*(?::|$|\s[-–—]))`)
+	heading     = regexp.MustCompile(`^\s*#{1,6}\s+`)
+	endpoint    = regexp.MustCompile(`\b(GET|POST|PUT|PATCH|DELETE)\s+` + "`?" + `(/[\w\-/{}:.]*)`)
+	pathParam   = regexp.MustCompile(`:([A-Za-z_]\w*)`)
+	tableName   = regexp.MustCompile("(?i)`(\\w+)`\\s+table\\b|\\btable\\s+`(\\w+)`")
+	sqlFence    = regexp.MustCompile("(?s)```sql\\s*\n(.*?)```")
+	sqlUse      = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|JOIN)\s+["` + "`" + `]?(\w+)`)
+	serviceURL  = regexp.MustCompile(`https?://([a-z][a-z0-9_-]*)[:/"'` + "`" + `]`)
+)
+
+// technologies maps how documents name well-known infrastructure to one
+// dependency name
+var technologies = map[string]string{
+	"postgres":      "postgresql",
+	"postgresql":    "postgresql",
+	"mysql":         "mysql",
+	"mongodb":       "mongodb",
+	"redis":         "redis",
+	"kafka":         "kafka",
+	"rabbitmq":      "rabbitmq",
+	"nats":          "nats",
+	"elasticsearch": "elasticsearch",
+	"dynamodb":      "dynamodb",
+	"sqlite":        "sqlite",
+	"s3":            "s3",
+	"stripe":        "stripe",
+}
+
+var techWord = regexp.MustCompile(`(?i)\b(postgres(?:ql)?|mysql|mongodb|redis|kafka|rabbitmq|nats|elasticsearch|dynamodb|sqlite|s3|stripe)\b`)
+
+// serviceName normalizes how documents and paths name a service, so
+// "Order Service", "order-service" and "order_svc" are one node
+func serviceName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.Trim(name, "*`")))
+	name = strings.NewReplacer(" ", "-", "_", "-").Replace(name)
+	for _, suffix := range []string{"-service", "-svc", "-microservice"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
+
+// endpointName returns "METHOD /path" with path parameters in OpenAPI form
+func endpointName(method, p string) string {
+	return strings.ToUpper(method) + " " + pathParam.ReplaceAllString(strings.TrimSuffix(p, "."), "{$1}")
+}
+
+// AddDocument adds what an agent's design document names: services from
+// headings and bullets, the endpoints, tables and technologies mentioned
+// under each, and the other services a section mentions as calls. Tables
+// declared in sql code blocks keep their columns and foreign keys.
+func (g *Graph) AddDocument(source, text string) {
+	known := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		if m := serviceLine.FindStringSubmatch(line); m != nil {
+			if name := serviceName(m[1] + m[2]); name != "" {
+				known[name] = true
+				g.Add(KindService, name, source, nil)
+			}
+		}
+	}
+
+	var current *Node
+	for _, line := range strings.Split(text, "\n") {
+		if m := serviceLine.FindStringSubmatch(line); m != nil {
+			// The rest of a bullet describes the service too
+			current = g.Node(NodeID(KindService, serviceName(m[1]+m[2])))
+		} else if heading.MatchString(line) {
+			current = nil // A section about something other than a service
+		}
+		for _, m := range endpoint.FindAllStringSubmatch(line, -1) {
+			g.Link(current, g.Add(KindEndpoint, endpointName(m[1], m[2]), source, nil), EdgeExposes)
+		}
+		for _, m := range tableName.FindAllStringSubmatch(line, -1) {
+			g.Link(current, g.Add(KindTable, strings.ToLower(m[1]+m[2]), source, nil), EdgeUses)
+		}
+		for _, m := range techWord.FindAllStringSubmatch(line, -1) {
+			g.Link(current, g.Add(KindDependency, technologies[strings.ToLower(m[1])], source, nil), EdgeDependsOn)
+		}
+		if current != nil {
+			lower := strings.ToLower(line)
+			for name := range known {
+				if name != current.Name && (strings.Contains(lower, name+" service") || strings.Contains(lower, name+"-service")) {
+					g.Link(current, g.Node(NodeID(KindService, name)), EdgeCalls)
+				}
+			}
+		}
+	}
+
+	schema := sqlschema.New()
+	for _, m := range sqlFence.FindAllStringSubmatch(text, -1) {
+		schema.AddFile(source, m[1])
+	}
+	g.addSchema(schema, source, nil)
+}
+
+// AddFiles adds what generated code declares: endpoints from route
+// registrations, tables from SQL files, dependencies from manifests and
+// calls from URLs naming another service. A file belongs to the service of
+// its services/, apps/ or cmd/ directory, or of a top-level directory
+// named like a service; other files belong to none.
+func (g *Graph) AddFiles(files []agents.GeneratedFile) {
+	code := make([]quality.CodeFile, 0, len(files))
+	for _, f := range files {
+		code = append(code, quality.CodeFile{Path: f.Path, Content: f.Content})
+	}
+	owner := func(file string) *Node {
+		if name := serviceOf(file); name != "" {
+			return g.Add(KindService, name, file, nil)
+		}
+		return nil
+	}
+
+	for _, r := range quality.DetectRoutes(code) {
+		g.Link(owner(r.File), g.Add(KindEndpoint, endpointName(r.Method, r.Path), r.File, nil), EdgeExposes)
+	}
+
+	schema := sqlschema.New()
+	for _, f := range files {
+		if strings.EqualFold(path.Ext(f.Path), ".sql") {
+			schema.AddFile(f.Path, f.Content)
+		}
+	}
+	g.addSchema(schema, "", owner)
+
+	for _, d := range quality.ParseManifests(code) {
+		dep := g.Add(KindDependency, d.Name, d.Manifest, map[string]string{"version": d.Version, "ecosystem": d.Ecosystem})
+		g.Link(owner(d.Manifest), dep, EdgeDependsOn)
+	}
+
+	for _, f := range files {
+		svc := owner(f.Path)
+		if svc == nil {
+			continue
+		}
+		for _, m := range sqlUse.FindAllStringSubmatch(f.Content, -1) {
+			if t := g.Node(NodeID(KindTable, strings.ToLower(m[1]))); t != nil {
+				g.Link(svc, t, EdgeUses)
+			}
+		}
+		for _, m := range serviceURL.FindAllStringSubmatch(f.Content, -1) {
+			if other := g.Node(NodeID(KindService, serviceName(m[1]))); other != nil {
+				g.Link(svc, other, EdgeCalls)
+			}
+		}
+	}
+}
+
+// addSchema adds the tables of schema with their columns and foreign keys.
+// owner, if set, names the service using each table by its file.
+func (g *Graph) addSchema(schema *sqlschema.Schema, source string, owner func(string) *Node) {
+	for _, t := range schema.Tables {
+		cols := make([]string, 0, len(t.Columns))
+		for _, c := range t.Columns {
+			cols = append(cols, c.Name)
+		}
+		src := source
+		if src == "" {
+			src = t.File
+		}
+		node := g.Add(KindTable, strings.ToLower(t.Name), src, map[string]string{"columns": strings.Join(cols, ", ")})
+		if owner != nil {
+			g.Link(owner(t.File), node, EdgeUses)
+		}
+		refs := make(map[string]bool)
+		for _, fk := range t.ForeignKeys {
+			refs[strings.ToLower(fk.References.Table)] = true
+		}
+		for _, c := range t.Columns {
+			if c.References != nil {
+				refs[strings.ToLower(c.References.Table)] = true
+			}
+		}
+		names := make([]string, 0, len(refs))
+		for name := range refs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			g.Link(node, g.Add(KindTable, name, src, nil), EdgeReferences)
+		}
+	}
+}
+
+// serviceOf returns the service a generated file belongs to, or ""
+func serviceOf(file string) string {
+	parts := strings.Split(path.Clean(file), "/")
+	if len(parts) > 2 {
+		switch parts[0] {
+		case "services", "apps", "cmd":
+			return serviceName(parts[1])
+		}
+	}
+	if len(parts) > 1 {
+		lower := strings.ToLower(parts[0])
+		if strings.HasSuffix(lower, "-service") || strings.HasSuffix(lower, "_service") || strings.HasSuffix(lower, "-svc") {
+			return serviceName(parts[0])
+		}
+	}
+	return ""
+}
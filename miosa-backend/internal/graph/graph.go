@@ -0,0 +1,290 @@
+// Package graph keeps a typed knowledge graph of a generated project: its
+// services, the endpoints they expose, the tables they use and the
+// dependencies they rely on. It is built from the analysis and
+// architecture documents and the generated code, and shown to later agents
+// so every service they generate agrees with the others.
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kind is the type of a node
+type Kind string
+
+// Node kinds
+const (
+	KindService    Kind = "service"
+	KindEndpoint   Kind = "endpoint"
+	KindTable      Kind = "table"
+	KindDependency Kind = "dependency"
+)
+
+// Edge types
+const (
+	EdgeExposes    = "exposes"    // Service to endpoint
+	EdgeUses       = "uses"       // Service to table
+	EdgeDependsOn  = "depends_on" // Service to dependency
+	EdgeCalls      = "calls"      // Service to service
+	EdgeReferences = "references" // Table to table, by foreign key
+)
+
+// PromptChars bounds the graph rendered into a prompt
+const PromptChars = 4000
+
+// Node is a service, endpoint, table or dependency
+type Node struct {
+	ID      string            `json:"id"` // "<kind>:<name>", see NodeID
+	Kind    Kind              `json:"kind"`
+	Name    string            `json:"name"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+	Sources []string          `json:"sources,omitempty"` // Agents and files the node was found in
+}
+
+// Edge is a typed relation between two nodes
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// Graph is the knowledge graph of one project. Projects are workflows.
+type Graph struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Nodes     []*Node   `json:"nodes"`
+	Edges     []Edge    `json:"edges"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	index map[string]*Node
+	links map[Edge]bool
+}
+
+// New returns an empty graph for a project
+func New(projectID uuid.UUID) *Graph {
+	return &Graph{ProjectID: projectID, Nodes: []*Node{}, Edges: []Edge{}}
+}
+
+// NodeID identifies the node of a kind and name
+func NodeID(kind Kind, name string) string {
+	return string(kind) + ":" + name
+}
+
+// reindex rebuilds the lookups after the graph was decoded or loaded
+func (g *Graph) reindex() {
+	if g.index != nil {
+		return
+	}
+	g.index = make(map[string]*Node, len(g.Nodes))
+	for _, n := range g.Nodes {
+		g.index[n.ID] = n
+	}
+	g.links = make(map[Edge]bool, len(g.Edges))
+	for _, e := range g.Edges {
+		g.links[e] = true
+	}
+}
+
+// Add returns the node of kind and name, creating it if needed. Attributes
+// and the source are merged into an existing node.
+func (g *Graph) Add(kind Kind, name, source string, attrs map[string]string) *Node {
+	g.reindex()
+	id := NodeID(kind, name)
+	n, ok := g.index[id]
+	if !ok {
+		n = &Node{ID: id, Kind: kind, Name: name}
+		g.index[id] = n
+		g.Nodes = append(g.Nodes, n)
+	}
+	for k, v := range attrs {
+		if v == "" {
+			continue
+		}
+		if n.Attrs == nil {
+			n.Attrs = make(map[string]string)
+		}
+		n.Attrs[k] = v
+	}
+	if source != "" && !contains(n.Sources, source) {
+		n.Sources = append(n.Sources, source)
+	}
+	return n
+}
+
+// Link adds an edge, once, between two nodes of the graph
+func (g *Graph) Link(from, to *Node, typ string) {
+	if from == nil || to == nil || from.ID == to.ID {
+		return
+	}
+	g.reindex()
+	e := Edge{From: from.ID, To: to.ID, Type: typ}
+	if g.links[e] {
+		return
+	}
+	g.links[e] = true
+	g.Edges = append(g.Edges, e)
+}
+
+// Node returns the node with id, or nil
+func (g *Graph) Node(id string) *Node {
+	g.reindex()
+	return g.index[id]
+}
+
+// Filter returns the subgraph of the nodes keep accepts and the edges
+// between them
+func (g *Graph) Filter(keep func(*Node) bool) *Graph {
+	out := New(g.ProjectID)
+	out.TenantID, out.UpdatedAt = g.TenantID, g.UpdatedAt
+	kept := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if keep(n) {
+			out.Nodes = append(out.Nodes, n)
+			kept[n.ID] = true
+		}
+	}
+	for _, e := range g.Edges {
+		if kept[e.From] && kept[e.To] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
+
+// Neighborhood returns the subgraph of the node with id and the nodes it
+// has an edge to or from, or nil if there is no such node
+func (g *Graph) Neighborhood(id string) *Graph {
+	if g.Node(id) == nil {
+		return nil
+	}
+	near := map[string]bool{id: true}
+	for _, e := range g.Edges {
+		if e.From == id {
+			near[e.To] = true
+		}
+		if e.To == id {
+			near[e.From] = true
+		}
+	}
+	return g.Filter(func(n *Node) bool { return near[n.ID] })
+}
+
+// targets returns the names of the nodes n has edges of typ to, sorted
+func (g *Graph) targets(n *Node, typ string) []string {
+	var names []string
+	for _, e := range g.Edges {
+		if e.From == n.ID && e.Type == typ {
+			if t := g.Node(e.To); t != nil {
+				names = append(names, t.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Prompt renders the graph for a system prompt, or "" if it is empty. It
+// is cut at PromptChars, noting how many lines were left out.
+func (g *Graph) Prompt() string {
+	if g == nil || len(g.Nodes) == 0 {
+		return ""
+	}
+	byKind := make(map[Kind][]*Node)
+	for _, n := range g.Nodes {
+		byKind[n.Kind] = append(byKind[n.Kind], n)
+	}
+	for _, nodes := range byKind {
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	}
+
+	var lines []string
+	if services := byKind[KindService]; len(services) > 0 {
+		lines = append(lines, "## Services")
+		for _, s := range services {
+			var parts []string
+			for _, rel := range []struct{ typ, label string }{
+				{EdgeExposes, "exposes"},
+				{EdgeUses, "uses tables"},
+				{EdgeDependsOn, "depends on"},
+				{EdgeCalls, "calls"},
+			} {
+				if names := g.targets(s, rel.typ); len(names) > 0 {
+					parts = append(parts, rel.label+" "+strings.Join(names, ", "))
+				}
+			}
+			line := "- " + s.Name
+			if len(parts) > 0 {
+				line += ": " + strings.Join(parts, "; ")
+			}
+			lines = append(lines, line)
+		}
+	}
+	if tables := byKind[KindTable]; len(tables) > 0 {
+		lines = append(lines, "## Tables")
+		for _, t := range tables {
+			line := "- " + t.Name
+			if cols := t.Attrs["columns"]; cols != "" {
+				line += " (" + cols + ")"
+			}
+			if refs := g.targets(t, EdgeReferences); len(refs) > 0 {
+				line += " references " + strings.Join(refs, ", ")
+			}
+			lines = append(lines, line)
+		}
+	}
+	if endpoints := g.unowned(byKind[KindEndpoint]); len(endpoints) > 0 {
+		lines = append(lines, "## Endpoints")
+		for _, e := range endpoints {
+			lines = append(lines, "- "+e.Name)
+		}
+	}
+	if deps := byKind[KindDependency]; len(deps) > 0 {
+		names := make([]string, 0, len(deps))
+		for _, d := range deps {
+			names = append(names, d.Name)
+		}
+		lines = append(lines, "## Dependencies", strings.Join(names, ", "))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Project knowledge graph\nKeep new work consistent with the services, endpoints, tables and dependencies already designed or generated.")
+	for i, line := range lines {
+		if sb.Len()+len(line)+1 > PromptChars {
+			fmt.Fprintf(&sb, "\n… (%d more lines)", len(lines)-i)
+			break
+		}
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
+
+// unowned returns the endpoints no service exposes
+func (g *Graph) unowned(endpoints []*Node) []*Node {
+	exposed := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.Type == EdgeExposes {
+			exposed[e.To] = true
+		}
+	}
+	var out []*Node
+	for _, n := range endpoints {
+		if !exposed[n.ID] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
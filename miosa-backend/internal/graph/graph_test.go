@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const architecture = "# Architecture\n\n" +
+	"## Order Service\n" +
+	"Stores orders in PostgreSQL and publishes events to Kafka.\n" +
+	"- POST /orders creates an order\n" +
+	"- GET /orders/:id\n" +
+	"- Charges cards through the payments service\n\n" +
+	"## Payments Service\n" +
+	"- POST /payments, backed by the `payments` table\n\n" +
+	"## Observability\n" +
+	"- GET /healthz on every service\n\n" +
+	"```sql\n" +
+	"CREATE TABLE orders (id UUID PRIMARY KEY, total INT);\n" +
+	"CREATE TABLE payments (id UUID PRIMARY KEY, order_id UUID REFERENCES orders(id));\n" +
+	"```\n"
+
+func targets(g *Graph, from, typ string) []string {
+	var out []string
+	for _, e := range g.Edges {
+		if e.From == from && e.Type == typ {
+			out = append(out, e.To)
+		}
+	}
+	return out
+}
+
+func TestAddDocument(t *testing.T) {
+	g := New(uuid.New())
+	g.AddDocument("architect", architecture)
+
+	order := g.Node("service:order")
+	require.NotNil(t, order)
+	assert.Equal(t, []string{"architect"}, order.Sources)
+	assert.ElementsMatch(t, []string{"endpoint:POST /orders", "endpoint:GET /orders/{id}"}, targets(g, order.ID, EdgeExposes))
+	assert.ElementsMatch(t, []string{"dependency:postgresql", "dependency:kafka"}, targets(g, order.ID, EdgeDependsOn))
+	assert.Equal(t, []string{"service:payments"}, targets(g, order.ID, EdgeCalls))
+	assert.Equal(t, []string{"table:payments"}, targets(g, "service:payments", EdgeUses))
+
+	assert.NotNil(t, g.Node("endpoint:GET /healthz"), "endpoints outside a service section have no owner")
+	assert.Equal(t, []string{"endpoint:POST /payments"}, targets(g, "service:payments", EdgeExposes))
+	assert.Equal(t, "id, total", g.Node("table:orders").Attrs["columns"])
+	assert.Equal(t, []string{"table:orders"}, targets(g, "table:payments", EdgeReferences))
+
+	assert.Nil(t, g.Node("service:charges-cards-through-the-payments"), "prose mentioning a service declares none")
+	g.AddDocument("analysis", "- **inventory-service**: reserves stock in Redis\n")
+	assert.Equal(t, []string{"dependency:redis"}, targets(g, "service:inventory", EdgeDependsOn))
+
+	// Adding the same document again changes nothing
+	nodes, edges := len(g.Nodes), len(g.Edges)
+	g.AddDocument("architect", architecture)
+	assert.Len(t, g.Nodes, nodes)
+	assert.Len(t, g.Edges, edges)
+}
+
+func TestAddFiles(t *testing.T) {
+	g := New(uuid.New())
+	g.AddFiles([]agents.GeneratedFile{
+		{Path: "services/orders/main.go", Content: "package main\n\nfunc main() {\n\tr := gin.Default()\n\tr.GET(\"/orders/:id\", get)\n\thttp.Post(\"http://payments-service:8080/payments\", \"\", nil)\n\tdb.Query(\"SELECT * FROM orders WHERE id = $1\")\n}\n"},
+		{Path: "services/orders/go.mod", Content: "module orders\n\nrequire github.com/gin-gonic/gin v1.9.1\n"},
+		{Path: "services/payments/server.js", Content: "app.post('/payments', pay)\n"},
+		{Path: "db/schema.sql", Content: "CREATE TABLE orders (id UUID PRIMARY KEY);\n"},
+	})
+
+	assert.Equal(t, []string{"endpoint:GET /orders/{id}"}, targets(g, "service:orders", EdgeExposes))
+	assert.Equal(t, []string{"endpoint:POST /payments"}, targets(g, "service:payments", EdgeExposes))
+	assert.Equal(t, []string{"dependency:github.com/gin-gonic/gin"}, targets(g, "service:orders", EdgeDependsOn))
+	assert.Equal(t, "v1.9.1", g.Node("dependency:github.com/gin-gonic/gin").Attrs["version"])
+	assert.Equal(t, []string{"table:orders"}, targets(g, "service:orders", EdgeUses))
+	assert.Equal(t, []string{"service:payments"}, targets(g, "service:orders", EdgeCalls))
+	assert.Equal(t, []string{"db/schema.sql"}, g.Node("table:orders").Sources)
+}
+
+func TestPrompt(t *testing.T) {
+	assert.Empty(t, New(uuid.New()).Prompt())
+
+	g := New(uuid.New())
+	g.AddDocument("architect", architecture)
+	prompt := g.Prompt()
+	assert.True(t, strings.HasPrefix(prompt, "# Project knowledge graph"))
+	assert.Contains(t, prompt, "- order: exposes GET /orders/{id}, POST /orders; depends on kafka, postgresql; calls payments")
+	assert.Contains(t, prompt, "- payments (id, order_id) references orders")
+	assert.Contains(t, prompt, "## Endpoints\n- GET /healthz")
+
+	for i := 0; i < 500; i++ {
+		g.Add(KindTable, strings.Repeat("t", 10)+string(rune('a'+i%26))+strings.Repeat("x", i%7), "", nil)
+		g.Add(KindEndpoint, "GET /e"+uuid.NewString(), "", nil)
+	}
+	prompt = g.Prompt()
+	assert.LessOrEqual(t, len(prompt), PromptChars+40)
+	assert.Contains(t, prompt, "more lines)")
+}
+
+func TestHandler(t *testing.T) {
+	store := NewMemoryStore()
+	g := New(uuid.New())
+	g.AddDocument("architect", architecture)
+	require.NoError(t, store.Save(context.Background(), g))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/workflow/{id}/graph", Handler(store))
+	get := func(url string) (*httptest.ResponseRecorder, *Graph) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var out Graph
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		}
+		return rec, &out
+	}
+
+	base := "/api/workflow/" + g.ProjectID.String() + "/graph"
+	rec, all := get(base)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, all.Nodes, len(g.Nodes))
+
+	_, services := get(base + "?kind=service")
+	assert.Len(t, services.Nodes, 2)
+	assert.Equal(t, []Edge{{From: "service:order", To: "service:payments", Type: EdgeCalls}}, services.Edges)
+
+	_, near := get(base + "?node=service:payments")
+	names := make([]string, 0, len(near.Nodes))
+	for _, n := range near.Nodes {
+		names = append(names, n.ID)
+	}
+	assert.ElementsMatch(t, []string{"service:payments", "service:order", "endpoint:POST /payments", "table:payments"}, names)
+
+	rec, _ = get(base + "?node=service:missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = get("/api/workflow/" + uuid.NewString() + "/graph")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = get("/api/workflow/nope/graph")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
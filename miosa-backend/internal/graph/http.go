@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Handler serves GET /api/workflow/{id}/graph. ?kind= keeps the nodes of
+// one kind and the edges between them; ?node= keeps a node, by ID, and its
+// neighbors.
+func Handler(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "invalid workflow id", http.StatusBadRequest)
+			return
+		}
+		g, err := store.Load(r.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		params := r.URL.Query()
+		if node := params.Get("node"); node != "" {
+			if g = g.Neighborhood(node); g == nil {
+				http.Error(w, "node not found", http.StatusNotFound)
+				return
+			}
+		}
+		if kind := Kind(params.Get("kind")); kind != "" {
+			g = g.Filter(func(n *Node) bool { return n.Kind == kind })
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g)
+	}
+}
@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for projects without a graph
+var ErrNotFound = errors.New("knowledge graph not found")
+
+// Store persists project graphs
+type Store interface {
+	// Save replaces the project's graph with g
+	Save(ctx context.Context, g *Graph) error
+	Load(ctx context.Context, projectID uuid.UUID) (*Graph, error)
+}
+
+// MemoryStore keeps graphs in memory
+type MemoryStore struct {
+	graphs map[uuid.UUID]*Graph
+	mu     sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{graphs: make(map[uuid.UUID]*Graph)}
+}
+
+// Save stores a copy of g
+func (m *MemoryStore) Save(ctx context.Context, g *Graph) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.graphs[g.ProjectID] = copyGraph(g)
+	return nil
+}
+
+// Load returns a copy of the project's graph
+func (m *MemoryStore) Load(ctx context.Context, projectID uuid.UUID) (*Graph, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	g, ok := m.graphs[projectID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyGraph(g), nil
+}
+
+func copyGraph(g *Graph) *Graph {
+	copied := New(g.ProjectID)
+	copied.TenantID, copied.UpdatedAt = g.TenantID, g.UpdatedAt
+	for _, n := range g.Nodes {
+		node := *n
+		node.Sources = append([]string(nil), n.Sources...)
+		if n.Attrs != nil {
+			node.Attrs = make(map[string]string, len(n.Attrs))
+			for k, v := range n.Attrs {
+				node.Attrs[k] = v
+			}
+		}
+		copied.Nodes = append(copied.Nodes, &node)
+	}
+	copied.Edges = append(copied.Edges, g.Edges...)
+	return copied
+}
+
+// PostgresStore keeps graphs in the knowledge_graph_nodes and
+// knowledge_graph_edges tables, with node attributes and sources as JSONB
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a Postgres-backed store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Save replaces the project's nodes and edges in one transaction
+func (p *PostgresStore) Save(ctx context.Context, g *Graph) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Edges go with their nodes
+	if _, err := tx.ExecContext(ctx, `DELETE FROM knowledge_graph_nodes WHERE project_id = $1`, g.ProjectID); err != nil {
+		return fmt.Errorf("failed to clear knowledge graph: %w", err)
+	}
+	for _, n := range g.Nodes {
+		attrs, err := json.Marshal(n.Attrs)
+		if err != nil {
+			return fmt.Errorf("failed to encode attributes: %w", err)
+		}
+		sources, err := json.Marshal(n.Sources)
+		if err != nil {
+			return fmt.Errorf("failed to encode sources: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO knowledge_graph_nodes (project_id, id, tenant_id, kind, name, attrs, sources, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			g.ProjectID, n.ID, g.TenantID, n.Kind, n.Name, attrs, sources, g.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save node %s: %w", n.ID, err)
+		}
+	}
+	for _, e := range g.Edges {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO knowledge_graph_edges (project_id, from_id, to_id, type)
+			VALUES ($1, $2, $3, $4)`,
+			g.ProjectID, e.From, e.To, e.Type,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save edge %s %s %s: %w", e.From, e.Type, e.To, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit knowledge graph: %w", err)
+	}
+	return nil
+}
+
+// Load returns the project's graph
+func (p *PostgresStore) Load(ctx context.Context, projectID uuid.UUID) (*Graph, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, tenant_id, kind, name, attrs, sources, updated_at FROM knowledge_graph_nodes
+		WHERE project_id = $1
+		ORDER BY id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load knowledge graph: %w", err)
+	}
+	defer rows.Close()
+
+	g := New(projectID)
+	for rows.Next() {
+		var n Node
+		var attrs, sources []byte
+		var updated time.Time
+		if err := rows.Scan(&n.ID, &g.TenantID, &n.Kind, &n.Name, &attrs, &sources, &updated); err != nil {
+			return nil, fmt.Errorf("failed to scan node: %w", err)
+		}
+		if err := json.Unmarshal(attrs, &n.Attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode attributes: %w", err)
+		}
+		if err := json.Unmarshal(sources, &n.Sources); err != nil {
+			return nil, fmt.Errorf("failed to decode sources: %w", err)
+		}
+		if updated.After(g.UpdatedAt) {
+			g.UpdatedAt = updated
+		}
+		g.Nodes = append(g.Nodes, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(g.Nodes) == 0 {
+		return nil, ErrNotFound
+	}
+
+	edges, err := p.db.QueryContext(ctx, `
+		SELECT from_id, to_id, type FROM knowledge_graph_edges
+		WHERE project_id = $1
+		ORDER BY from_id, type, to_id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load knowledge graph edges: %w", err)
+	}
+	defer edges.Close()
+	for edges.Next() {
+		var e Edge
+		if err := edges.Scan(&e.From, &e.To, &e.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan edge: %w", err)
+		}
+		g.Edges = append(g.Edges, e)
+	}
+	return g, edges.Err()
+}
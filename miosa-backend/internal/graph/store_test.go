@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleGraph() *Graph {
+	g := New(uuid.New())
+	g.TenantID = uuid.New()
+	g.UpdatedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	orders := g.Add(KindService, "orders", "architect", nil)
+	g.Link(orders, g.Add(KindTable, "orders", "db/schema.sql", map[string]string{"columns": "id"}), EdgeUses)
+	return g
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	g := sampleGraph()
+
+	_, err := store.Load(ctx, g.ProjectID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Save(ctx, g))
+	g.Node("table:orders").Attrs["columns"] = "changed"
+	loaded, err := store.Load(ctx, g.ProjectID)
+	require.NoError(t, err)
+	assert.Equal(t, "id", loaded.Node("table:orders").Attrs["columns"], "the store keeps a copy")
+	assert.Equal(t, g.Edges, loaded.Edges)
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewPostgresStore(db)
+	g := sampleGraph()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM knowledge_graph_nodes").WithArgs(g.ProjectID).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO knowledge_graph_nodes").
+		WithArgs(g.ProjectID, "service:orders", g.TenantID, KindService, "orders", []byte("null"), []byte(`["architect"]`), g.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO knowledge_graph_nodes").
+		WithArgs(g.ProjectID, "table:orders", g.TenantID, KindTable, "orders", []byte(`{"columns":"id"}`), []byte(`["db/schema.sql"]`), g.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO knowledge_graph_edges").
+		WithArgs(g.ProjectID, "service:orders", "table:orders", EdgeUses).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, store.Save(ctx, g))
+
+	mock.ExpectQuery("SELECT id, tenant_id, kind, name, attrs, sources, updated_at FROM knowledge_graph_nodes").
+		WithArgs(g.ProjectID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "kind", "name", "attrs", "sources", "updated_at"}).
+			AddRow("service:orders", g.TenantID, "service", "orders", []byte("null"), []byte(`["architect"]`), g.UpdatedAt).
+			AddRow("table:orders", g.TenantID, "table", "orders", []byte(`{"columns":"id"}`), []byte(`["db/schema.sql"]`), g.UpdatedAt))
+	mock.ExpectQuery("SELECT from_id, to_id, type FROM knowledge_graph_edges").
+		WithArgs(g.ProjectID).
+		WillReturnRows(sqlmock.NewRows([]string{"from_id", "to_id", "type"}).AddRow("service:orders", "table:orders", EdgeUses))
+	loaded, err := store.Load(ctx, g.ProjectID)
+	require.NoError(t, err)
+	assert.Equal(t, g.TenantID, loaded.TenantID)
+	assert.Equal(t, g.UpdatedAt, loaded.UpdatedAt)
+	assert.Equal(t, g.Edges, loaded.Edges)
+	assert.Equal(t, "id", loaded.Node("table:orders").Attrs["columns"])
+
+	mock.ExpectQuery("SELECT id, tenant_id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "kind", "name", "attrs", "sources", "updated_at"}))
+	_, err = store.Load(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
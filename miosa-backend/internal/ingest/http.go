@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+)
+
+// Request is the JSON body accepted by Handler for git ingestion
+type Request struct {
+	GitURL string `json:"git_url"`
+	Ref    string `json:"ref,omitempty"`
+	Goal   string `json:"goal,omitempty"`
+}
+
+// Handler serves POST /api/ingest. It accepts either a JSON Request naming a
+// git repository or a multipart form with an "archive" file (zip or tar.gz)
+// and optional "goal" field. Each repository is fetched into a temporary
+// directory under workDir that is removed once the audit completes.
+func Handler(p *Pipeline, workDir string, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dir, err := os.MkdirTemp(workDir, "ingest-")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		var source, goal string
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			r.Body = http.MaxBytesReader(w, r.Body, MaxArchiveBytes+(1<<20))
+			file, header, err := r.FormFile("archive")
+			if err != nil {
+				http.Error(w, "archive file is required", http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+
+			if err := Extract(file, header.Filename, dir); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			source, goal = header.Filename, r.FormValue("goal")
+		} else {
+			var req Request
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.GitURL == "" {
+				http.Error(w, "git_url or an archive upload is required", http.StatusBadRequest)
+				return
+			}
+			if err := Clone(r.Context(), req.GitURL, req.Ref, dir); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			source, goal = req.GitURL, req.Goal
+		}
+
+		report, err := p.Audit(r.Context(), dir, source, goal)
+
+		if auditLog != nil {
+			actor, actorType := audit.ActorFromRequest(r)
+			event := audit.Event{
+				Actor:       actor,
+				ActorType:   actorType,
+				Action:      audit.ActionRepositoryIngest,
+				Resource:    r.URL.Path,
+				RequestHash: audit.HashRequest(source + "\n" + goal),
+				Status:      audit.StatusSuccess,
+				Metadata:    map[string]string{"source": source},
+			}
+			if err != nil {
+				event.Status = audit.StatusFailure
+			} else {
+				event.WorkflowID = report.ID
+			}
+			auditLog.Record(r.Context(), event)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,183 @@
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAgent struct {
+	agentType agents.AgentType
+	tasks     []agents.Task
+}
+
+func (s *stubAgent) GetType() agents.AgentType            { return s.agentType }
+func (s *stubAgent) GetCapabilities() []agents.Capability { return nil }
+func (s *stubAgent) GetDescription() string               { return "stub" }
+func (s *stubAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	s.tasks = append(s.tasks, task)
+	return &agents.Result{Success: true, Output: string(s.agentType) + " report"}, nil
+}
+
+func writeTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+	return root
+}
+
+func sampleRepo() map[string]string {
+	return map[string]string{
+		"go.mod":                    "module example.com/app\n\ngo 1.22\n",
+		"main.go":                   "package main\n\nfunc main() {\n\tserve()\n}\n",
+		"server/server.go":          "package server\n\nfunc serve() {}\n",
+		"web/app.ts":                "export const x = 1\n",
+		"README.md":                 "# app\n",
+		"node_modules/lib/index.js": "module.exports = {}\n",
+		"logo.png":                  "\x89PNG\x00\x00",
+	}
+}
+
+func TestWalk(t *testing.T) {
+	inv, err := Walk(writeTree(t, sampleRepo()))
+	require.NoError(t, err)
+
+	assert.Equal(t, "go", inv.Primary)
+	assert.Equal(t, 5, inv.TotalFiles, "node_modules and binaries are skipped")
+	assert.Equal(t, 2, inv.Languages["go"].Files)
+	assert.Equal(t, 8, inv.Languages["go"].Lines)
+	assert.InDelta(t, 8.0/9.0, inv.Languages["go"].Share, 0.001)
+	assert.Zero(t, inv.Languages["markdown"].Share)
+	assert.Equal(t, "go modules", inv.Manifests["go.mod"])
+	assert.Len(t, inv.SourceFiles(), 3)
+
+	summary := inv.Summary(1)
+	assert.Contains(t, summary, "Primary language: go")
+	assert.Contains(t, summary, "go.mod (go modules)")
+	assert.Contains(t, summary, "- main.go (go, 5 lines)")
+	assert.NotContains(t, summary, "server/server.go")
+}
+
+func TestExtract(t *testing.T) {
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	for path, content := range map[string]string{"app/main.go": "package main\n", "app/README.md": "hi\n"} {
+		f, err := zw.Create(path)
+		require.NoError(t, err)
+		f.Write([]byte(content))
+	}
+	require.NoError(t, zw.Close())
+
+	dir := t.TempDir()
+	require.NoError(t, Extract(bytes.NewReader(zbuf.Bytes()), "repo.zip", dir))
+	content, err := os.ReadFile(filepath.Join(dir, "app", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(content))
+
+	var tbuf bytes.Buffer
+	gz := gzip.NewWriter(&tbuf)
+	tw := tar.NewWriter(gz)
+	body := "package lib\n"
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "lib/lib.go", Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+	tw.Write([]byte(body))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	dir = t.TempDir()
+	require.NoError(t, Extract(bytes.NewReader(tbuf.Bytes()), "repo.tar.gz", dir))
+	assert.FileExists(t, filepath.Join(dir, "lib", "lib.go"))
+
+	assert.ErrorIs(t, Extract(strings.NewReader("x"), "repo.rar", t.TempDir()), ErrUnsupportedArchive)
+}
+
+func TestExtract_RejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("../../escape.txt")
+	require.NoError(t, err)
+	f.Write([]byte("owned"))
+	require.NoError(t, zw.Close())
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "repo")
+	require.NoError(t, os.Mkdir(dir, 0755))
+
+	err = Extract(bytes.NewReader(buf.Bytes()), "evil.zip", dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "escapes")
+	assert.NoFileExists(t, filepath.Join(parent, "escape.txt"))
+}
+
+func TestValidateGitURL(t *testing.T) {
+	for _, ok := range []string{"https://github.com/org/repo.git", "ssh://git@host/repo", "git@github.com:org/repo.git"} {
+		assert.NoError(t, ValidateGitURL(ok), ok)
+	}
+	for _, bad := range []string{"file:///etc", "/srv/repo", "http://github.com/org/repo", "https://"} {
+		assert.Error(t, ValidateGitURL(bad), bad)
+	}
+}
+
+func TestPipeline_Audit(t *testing.T) {
+	registry := map[agents.AgentType]*stubAgent{
+		agents.AnalysisAgent:  {agentType: agents.AnalysisAgent},
+		agents.ArchitectAgent: {agentType: agents.ArchitectAgent},
+	}
+	p := NewPipeline(func(agentType agents.AgentType) (agents.Agent, error) {
+		if agent, ok := registry[agentType]; ok {
+			return agent, nil
+		}
+		return nil, fmt.Errorf("agent %s not registered", agentType)
+	})
+
+	report, err := p.Audit(context.Background(), writeTree(t, sampleRepo()), "repo.zip", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "repo.zip", report.Source)
+	assert.Len(t, report.Results, 2)
+	assert.Contains(t, report.Errors[agents.QualityAgent], "not registered")
+	require.NotNil(t, report.Assurance)
+
+	task := registry[agents.AnalysisAgent].tasks[0]
+	assert.Equal(t, "audit", task.Type)
+	assert.Contains(t, task.Input, "Primary language: go")
+	assert.Contains(t, task.Input, "--- main.go ---")
+	assert.Equal(t, "go", task.Parameters["language"])
+
+	archTask := registry[agents.ArchitectAgent].tasks[0]
+	assert.Equal(t, "analysis report", archTask.Context.Memory["analysis_output"])
+}
+
+func TestPipeline_AuditEmptyRepository(t *testing.T) {
+	_, err := NewPipeline(nil).Audit(context.Background(), writeTree(t, map[string]string{"logo.png": "\x00"}), "x", "")
+	assert.Error(t, err)
+}
+
+func TestPipeline_AuditFlagsDeniedLicenses(t *testing.T) {
+	files := sampleRepo()
+	files["package.json"] = `{"name": "web", "license": "AGPL-3.0-only"}`
+
+	report, err := NewPipeline(func(agents.AgentType) (agents.Agent, error) {
+		return nil, fmt.Errorf("no agents")
+	}).Audit(context.Background(), writeTree(t, files), "repo", "")
+	require.NoError(t, err)
+
+	var rules []string
+	for _, f := range report.Assurance.Findings {
+		rules = append(rules, f.Rule)
+	}
+	assert.Contains(t, rules, "License.Denied")
+}
@@ -0,0 +1,266 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MaxFileBytes is the largest file counted in the inventory; larger files are
+// usually generated or data and are listed as skipped
+const MaxFileBytes = 1 << 20
+
+// skipDirs are dependency, build and VCS directories never worth auditing
+var skipDirs = map[string]bool{
+	".git": true, ".hg": true, ".svn": true, "node_modules": true, "vendor": true,
+	"dist": true, "build": true, "target": true, ".next": true, ".venv": true,
+	"venv": true, "__pycache__": true, ".idea": true, ".vscode": true, "coverage": true,
+}
+
+// languageByExt maps file extensions to languages
+var languageByExt = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "javascript",
+	".mjs": "javascript", ".ts": "typescript", ".tsx": "typescript",
+	".java": "java", ".kt": "kotlin", ".rb": "ruby", ".php": "php",
+	".rs": "rust", ".cs": "csharp", ".swift": "swift", ".scala": "scala",
+	".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".hpp": "cpp",
+	".ex": "elixir", ".exs": "elixir", ".sql": "sql", ".sh": "shell",
+	".html": "html", ".css": "css", ".scss": "css", ".vue": "vue", ".svelte": "svelte",
+	".yaml": "yaml", ".yml": "yaml", ".json": "json", ".toml": "toml", ".md": "markdown",
+	".tf": "terraform", ".proto": "protobuf",
+}
+
+// configLanguages are counted in the inventory but not in the primary
+// language profile
+var configLanguages = map[string]bool{
+	"yaml": true, "json": true, "toml": true, "markdown": true,
+}
+
+// manifestFiles identify build systems and frameworks
+var manifestFiles = map[string]string{
+	"go.mod": "go modules", "package.json": "npm", "requirements.txt": "pip",
+	"pyproject.toml": "python project", "Pipfile": "pipenv", "Cargo.toml": "cargo",
+	"pom.xml": "maven", "build.gradle": "gradle", "build.gradle.kts": "gradle",
+	"Gemfile": "bundler", "composer.json": "composer", "mix.exs": "mix",
+	"Dockerfile": "docker", "docker-compose.yml": "docker compose",
+	"Makefile": "make", "tsconfig.json": "typescript",
+}
+
+// File is one inventoried source file
+type File struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Lines    int    `json:"lines"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// LanguageStats aggregates files of one language
+type LanguageStats struct {
+	Files int     `json:"files"`
+	Lines int     `json:"lines"`
+	Bytes int64   `json:"bytes"`
+	Share float64 `json:"share"` // fraction of source lines
+}
+
+// Inventory describes a walked repository
+type Inventory struct {
+	Root       string                    `json:"-"`
+	Files      []File                    `json:"files"`
+	Languages  map[string]*LanguageStats `json:"languages"`
+	Primary    string                    `json:"primary_language"`
+	Manifests  map[string]string         `json:"manifests"`
+	TotalFiles int                       `json:"total_files"`
+	TotalLines int                       `json:"total_lines"`
+	Skipped    []string                  `json:"skipped,omitempty"`
+}
+
+// Walk inventories every text file under root
+func Walk(root string) (*Inventory, error) {
+	inv := &Inventory{
+		Root:      root,
+		Languages: make(map[string]*LanguageStats),
+		Manifests: make(map[string]string),
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if kind, ok := manifestFiles[d.Name()]; ok {
+			inv.Manifests[rel] = kind
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > MaxFileBytes {
+			inv.Skipped = append(inv.Skipped, rel)
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(content) {
+			return nil
+		}
+
+		lang := languageByExt[strings.ToLower(filepath.Ext(path))]
+		if lang == "" {
+			if _, ok := manifestFiles[d.Name()]; !ok {
+				return nil
+			}
+			lang = "config"
+		}
+
+		f := File{Path: rel, Language: lang, Lines: bytes.Count(content, []byte("\n")), Bytes: info.Size()}
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			f.Lines++
+		}
+		inv.Files = append(inv.Files, f)
+		inv.TotalFiles++
+		inv.TotalLines += f.Lines
+
+		stats, ok := inv.Languages[lang]
+		if !ok {
+			stats = &LanguageStats{}
+			inv.Languages[lang] = stats
+		}
+		stats.Files++
+		stats.Lines += f.Lines
+		stats.Bytes += f.Bytes
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk repository: %w", err)
+	}
+
+	inv.profile()
+	return inv, nil
+}
+
+// profile computes language shares over source lines and the primary language
+func (inv *Inventory) profile() {
+	sourceLines := 0
+	for lang, stats := range inv.Languages {
+		if !configLanguages[lang] && lang != "config" {
+			sourceLines += stats.Lines
+		}
+	}
+
+	best := 0
+	for _, lang := range inv.sortedLanguages() {
+		stats := inv.Languages[lang]
+		if configLanguages[lang] || lang == "config" || sourceLines == 0 {
+			continue
+		}
+		stats.Share = float64(stats.Lines) / float64(sourceLines)
+		if stats.Lines > best {
+			best = stats.Lines
+			inv.Primary = lang
+		}
+	}
+}
+
+func (inv *Inventory) sortedLanguages() []string {
+	langs := make([]string, 0, len(inv.Languages))
+	for lang := range inv.Languages {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		a, b := inv.Languages[langs[i]], inv.Languages[langs[j]]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return langs[i] < langs[j]
+	})
+	return langs
+}
+
+// Summary renders the inventory for agent prompts, listing at most maxFiles
+// of the largest source files
+func (inv *Inventory) Summary(maxFiles int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Repository: %d files, %d lines. Primary language: %s\n",
+		inv.TotalFiles, inv.TotalLines, valueOr(inv.Primary, "unknown"))
+
+	sb.WriteString("\nLanguages:\n")
+	for _, lang := range inv.sortedLanguages() {
+		stats := inv.Languages[lang]
+		fmt.Fprintf(&sb, "- %s: %d files, %d lines", lang, stats.Files, stats.Lines)
+		if stats.Share > 0 {
+			fmt.Fprintf(&sb, " (%.0f%%)", stats.Share*100)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(inv.Manifests) > 0 {
+		sb.WriteString("\nBuild manifests:\n")
+		paths := make([]string, 0, len(inv.Manifests))
+		for p := range inv.Manifests {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			fmt.Fprintf(&sb, "- %s (%s)\n", p, inv.Manifests[p])
+		}
+	}
+
+	files := inv.SourceFiles()
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Lines > files[j].Lines })
+	if len(files) > maxFiles {
+		files = files[:maxFiles]
+	}
+	if len(files) > 0 {
+		sb.WriteString("\nLargest source files:\n")
+		for _, f := range files {
+			fmt.Fprintf(&sb, "- %s (%s, %d lines)\n", f.Path, f.Language, f.Lines)
+		}
+	}
+	return sb.String()
+}
+
+// SourceFiles returns files in programming languages, excluding config and docs
+func (inv *Inventory) SourceFiles() []File {
+	var files []File
+	for _, f := range inv.Files {
+		if !configLanguages[f.Language] && f.Language != "config" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// isBinary treats content with a NUL byte in its first 8KB as binary
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -0,0 +1,202 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+)
+
+// Budgets for the source handed to agents and static assurance
+const (
+	DefaultSummaryFiles  = 40
+	DefaultExcerptBytes  = 24 << 10
+	DefaultAssuranceSize = 2 << 20
+)
+
+// auditAgents run in order; each sees the previous outputs in task memory
+var auditAgents = []agents.AgentType{
+	agents.AnalysisAgent,
+	agents.ArchitectAgent,
+	agents.QualityAgent,
+}
+
+// Resolver returns the agent registered for a type
+type Resolver func(agents.AgentType) (agents.Agent, error)
+
+// Report is the outcome of auditing an ingested repository
+type Report struct {
+	ID         uuid.UUID                           `json:"id"`
+	Source     string                              `json:"source"`
+	Inventory  *Inventory                          `json:"inventory"`
+	Results    map[agents.AgentType]*agents.Result `json:"results"`
+	Errors     map[agents.AgentType]string         `json:"errors,omitempty"`
+	Assurance  *quality.CodeAssuranceResult        `json:"assurance,omitempty"`
+	DurationMS int64                               `json:"duration_ms"`
+}
+
+// Pipeline audits repositories with the analysis, architect and quality agents
+type Pipeline struct {
+	resolve      Resolver
+	excerptBytes int
+}
+
+// NewPipeline creates a pipeline resolving agents with resolve, or the
+// global registry when nil
+func NewPipeline(resolve Resolver) *Pipeline {
+	if resolve == nil {
+		resolve = agents.Get
+	}
+	return &Pipeline{resolve: resolve, excerptBytes: DefaultExcerptBytes}
+}
+
+// Audit walks root and runs the audit agents over the inventory
+func (p *Pipeline) Audit(ctx context.Context, root, source, goal string) (*Report, error) {
+	start := time.Now()
+
+	inv, err := Walk(root)
+	if err != nil {
+		return nil, err
+	}
+	if inv.TotalFiles == 0 {
+		return nil, fmt.Errorf("repository contains no source files")
+	}
+
+	report := &Report{
+		ID:        uuid.New(),
+		Source:    source,
+		Inventory: inv,
+		Results:   make(map[agents.AgentType]*agents.Result),
+		Errors:    make(map[agents.AgentType]string),
+	}
+
+	files := p.loadFiles(inv, DefaultAssuranceSize)
+	if len(files) > 0 {
+		policy := quality.DefaultLicensePolicy
+		assurance, err := quality.RunCodeAssurance(ctx, nil, quality.CodeAssuranceRequest{
+			Goal:          goal,
+			Language:      inv.Primary,
+			Files:         files,
+			LicensePolicy: &policy,
+		})
+		if err == nil {
+			report.Assurance = assurance
+		}
+	}
+
+	task := p.auditTask(report.ID, inv, files, goal)
+	for _, agentType := range auditAgents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		agent, err := p.resolve(agentType)
+		if err != nil {
+			report.Errors[agentType] = err.Error()
+			continue
+		}
+
+		result, err := agents.ExecuteTracked(ctx, agent, task)
+		if err != nil {
+			report.Errors[agentType] = err.Error()
+			continue
+		}
+		report.Results[agentType] = result
+		task.Context.Memory[string(agentType)+"_output"] = result.Output
+	}
+
+	report.DurationMS = time.Since(start).Milliseconds()
+	return report, nil
+}
+
+// auditTask builds the task shared by every audit agent
+func (p *Pipeline) auditTask(id uuid.UUID, inv *Inventory, files []quality.CodeFile, goal string) agents.Task {
+	if goal == "" {
+		goal = "Audit this existing codebase: describe its architecture, assess code quality and recommend improvements."
+	}
+
+	var input strings.Builder
+	input.WriteString(goal)
+	input.WriteString("\n\n")
+	input.WriteString(inv.Summary(DefaultSummaryFiles))
+
+	if excerpts := excerpt(files, p.excerptBytes); excerpts != "" {
+		input.WriteString("\nKey file excerpts:\n")
+		input.WriteString(excerpts)
+	}
+
+	return agents.Task{
+		ID:    id,
+		Type:  "audit",
+		Input: input.String(),
+		Parameters: map[string]interface{}{
+			"repository": inv,
+			"language":   inv.Primary,
+			"mode":       "existing_codebase",
+		},
+		Context: &agents.TaskContext{
+			Phase:    "audit",
+			Memory:   make(map[string]interface{}),
+			Metadata: map[string]string{"source": "ingest"},
+		},
+		Priority: 1,
+	}
+}
+
+// loadFiles reads source files, largest first, up to maxBytes in total.
+// Build manifests come first so declared licenses are always checked.
+func (p *Pipeline) loadFiles(inv *Inventory, maxBytes int64) []quality.CodeFile {
+	sources := inv.SourceFiles()
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Lines > sources[j].Lines })
+
+	var manifests []File
+	for _, f := range inv.Files {
+		if _, ok := inv.Manifests[f.Path]; ok {
+			manifests = append(manifests, f)
+		}
+	}
+	sources = append(manifests, sources...)
+
+	var files []quality.CodeFile
+	var total int64
+	for _, f := range sources {
+		if total+f.Bytes > maxBytes {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(inv.Root, filepath.FromSlash(f.Path)))
+		if err != nil {
+			continue
+		}
+		total += f.Bytes
+		files = append(files, quality.CodeFile{Path: f.Path, Content: string(content), Language: f.Language})
+	}
+	return files
+}
+
+// excerpt concatenates the leading part of each file within budget bytes
+func excerpt(files []quality.CodeFile, budget int) string {
+	const perFile = 4 << 10
+
+	var sb strings.Builder
+	for _, f := range files {
+		if sb.Len() >= budget {
+			break
+		}
+		content := f.Content
+		if len(content) > perFile {
+			content = content[:perFile] + "\n// ... truncated"
+		}
+		if remaining := budget - sb.Len(); len(content) > remaining {
+			content = content[:remaining]
+		}
+		content = strings.ToValidUTF8(content, "")
+		fmt.Fprintf(&sb, "\n--- %s ---\n%s\n", f.Path, content)
+	}
+	return sb.String()
+}
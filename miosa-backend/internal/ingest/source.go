@@ -0,0 +1,213 @@
+// Package ingest fetches an existing codebase, builds a file inventory and
+// language profile, and runs the audit agents over it so MIOSA can analyze
+// and extend projects that were not generated from scratch.
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits applied while fetching a repository
+const (
+	MaxArchiveBytes   = 200 << 20
+	MaxExtractedBytes = 500 << 20
+	MaxArchiveEntries = 50000
+	CloneTimeout      = 2 * time.Minute
+)
+
+// ErrUnsupportedArchive is returned for archive formats other than zip and tar(.gz)
+var ErrUnsupportedArchive = errors.New("unsupported archive format: use .zip, .tar or .tar.gz")
+
+// ValidateGitURL accepts https and ssh remotes only, so a request cannot make
+// the server clone from its own filesystem
+func ValidateGitURL(raw string) error {
+	if strings.HasPrefix(raw, "git@") && strings.Contains(raw, ":") {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid git URL: %w", err)
+	}
+	switch u.Scheme {
+	case "https", "ssh":
+	default:
+		return fmt.Errorf("git URL scheme must be https or ssh, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("git URL has no host")
+	}
+	return nil
+}
+
+// Clone shallow-clones repoURL at ref (or the default branch) into dir
+func Clone(ctx context.Context, repoURL, ref, dir string) error {
+	if err := ValidateGitURL(repoURL); err != nil {
+		return err
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, CloneTimeout)
+	defer cancel()
+
+	args := []string{"clone", "--depth", "1", "--single-branch"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", repoURL, dir)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// Never prompt for credentials; private repos must use a token in the URL
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git clone failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Extract unpacks a zip or tar(.gz) archive named name into dir
+func Extract(r io.Reader, name, dir string) error {
+	data, err := io.ReadAll(io.LimitReader(r, MaxArchiveBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxArchiveBytes {
+		return fmt.Errorf("archive exceeds %d bytes", MaxArchiveBytes)
+	}
+
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return extractZip(data, dir)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, dir)
+	case strings.HasSuffix(lower, ".tar"):
+		return extractTar(bytes.NewReader(data), dir)
+	}
+	return ErrUnsupportedArchive
+}
+
+// safeJoin resolves an archive entry under dir, rejecting entries that would
+// escape it
+func safeJoin(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q escapes the extraction directory", name)
+	}
+	return target, nil
+}
+
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	if len(zr.File) > MaxArchiveEntries {
+		return fmt.Errorf("archive has more than %d entries", MaxArchiveEntries)
+	}
+
+	var written int64
+	for _, f := range zr.File {
+		target, err := safeJoin(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		n, err := writeFile(target, rc, MaxExtractedBytes-written)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		written += n
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	var written int64
+	for entries := 0; ; entries++ {
+		if entries > MaxArchiveEntries {
+			return fmt.Errorf("archive has more than %d entries", MaxArchiveEntries)
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			n, err := writeFile(target, tr, MaxExtractedBytes-written)
+			if err != nil {
+				return err
+			}
+			written += n
+		}
+		// Symlinks and devices are skipped
+	}
+}
+
+func writeFile(target string, r io.Reader, remaining int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return n, err
+	}
+	if n > remaining {
+		return n, fmt.Errorf("archive expands beyond %d bytes", MaxExtractedBytes)
+	}
+	return n, nil
+}
@@ -0,0 +1,74 @@
+package integrity
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ManifestHandler serves GET /api/workflow/{id}/integrity, the workflow's
+// signed manifest. root maps a workflow to the workspace it writes into.
+func ManifestHandler(root func(workflowID uuid.UUID) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, m, ok := loadManifest(w, r, root)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+	}
+}
+
+// VerifyHandler serves GET /api/workflow/{id}/integrity/verify, checking
+// the workflow's files as they are on disk now against its signed manifest
+func VerifyHandler(root func(workflowID uuid.UUID) string, verifier Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, m, ok := loadManifest(w, r, root)
+		if !ok {
+			return
+		}
+		result, err := Verify(root(id), m, verifier)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// KeyHandler serves GET /api/integrity/key, the algorithm, key ID and, for
+// Ed25519, the public key consumers verify manifests with
+func KeyHandler(verifier Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"algorithm":  verifier.Algorithm(),
+			"key_id":     verifier.KeyID(),
+			"public_key": PublicKey(verifier),
+		})
+	}
+}
+
+// loadManifest parses the {id} route variable and loads its manifest,
+// writing an error response when either fails
+func loadManifest(w http.ResponseWriter, r *http.Request, root func(uuid.UUID) string) (uuid.UUID, *Manifest, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid workflow id", http.StatusBadRequest)
+		return uuid.Nil, nil, false
+	}
+	m, err := Load(root(id), id)
+	if errors.Is(err, ErrNoManifest) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return uuid.Nil, nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return uuid.Nil, nil, false
+	}
+	return id, m, true
+}
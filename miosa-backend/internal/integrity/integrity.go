@@ -0,0 +1,180 @@
+// Package integrity signs the files a workflow generated so consumers can
+// detect tampering. A manifest lists the SHA-256 checksum of every file and
+// is signed with an HMAC secret or an Ed25519 key; verifying recomputes the
+// checksums of a directory and checks them and the signature.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+// Dir holds the signed manifests of a workspace, one per workflow
+const Dir = workspace.StateDir + "/integrity"
+
+// ErrNoManifest is returned when a workflow has no signed manifest
+var ErrNoManifest = errors.New("no signed manifest for workflow")
+
+// FileDigest is the checksum of one generated file
+type FileDigest struct {
+	Path   string `json:"path"` // Relative to the project root, slash-separated
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest lists a workflow's generated files and signs them
+type Manifest struct {
+	WorkflowID uuid.UUID    `json:"workflow_id"`
+	Files      []FileDigest `json:"files"`
+	SignedAt   time.Time    `json:"signed_at"`
+	Algorithm  string       `json:"algorithm"`
+	KeyID      string       `json:"key_id"`
+	Signature  []byte       `json:"signature"` // Over Payload
+}
+
+// Payload is the canonical encoding the signature covers: the manifest
+// without its signature, files sorted by path
+func (m *Manifest) Payload() []byte {
+	unsigned := *m
+	unsigned.Signature = nil
+	unsigned.Files = append([]FileDigest(nil), m.Files...)
+	sort.Slice(unsigned.Files, func(i, j int) bool { return unsigned.Files[i].Path < unsigned.Files[j].Path })
+	data, _ := json.Marshal(unsigned)
+	return data
+}
+
+// Sign checksums paths under root and signs the result with signer
+func Sign(root string, workflowID uuid.UUID, paths []string, signer Signer) (*Manifest, error) {
+	m := &Manifest{
+		WorkflowID: workflowID,
+		Files:      make([]FileDigest, 0, len(paths)),
+		SignedAt:   time.Now().UTC(),
+		Algorithm:  signer.Algorithm(),
+		KeyID:      signer.KeyID(),
+	}
+	for _, p := range paths {
+		d, err := digest(root, p)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, *d)
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	sig, err := signer.Sign(m.Payload())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign manifest: %w", err)
+	}
+	m.Signature = sig
+	return m, nil
+}
+
+// Result is the outcome of verifying a directory against a manifest
+type Result struct {
+	WorkflowID uuid.UUID `json:"workflow_id"`
+	Valid      bool      `json:"valid"` // Signature verified and every file unchanged
+	Signature  string    `json:"signature"`
+	Verified   int       `json:"verified"`
+	Modified   []string  `json:"modified,omitempty"`
+	Missing    []string  `json:"missing,omitempty"`
+}
+
+// Signature states
+const (
+	SignatureValid   = "valid"
+	SignatureInvalid = "invalid"
+	SignatureUnknown = "unknown_key" // Signed with a key other than the verifier's
+)
+
+// Verify checks m's signature with verifier and the files under root
+// against m's checksums. Files not listed in m are ignored.
+func Verify(root string, m *Manifest, verifier Verifier) (*Result, error) {
+	r := &Result{WorkflowID: m.WorkflowID, Signature: SignatureValid}
+	switch {
+	case m.Algorithm != verifier.Algorithm() || m.KeyID != verifier.KeyID():
+		r.Signature = SignatureUnknown
+	case verifier.Verify(m.Payload(), m.Signature) != nil:
+		r.Signature = SignatureInvalid
+	}
+	for _, want := range m.Files {
+		got, err := digest(root, want.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			r.Missing = append(r.Missing, want.Path)
+		case err != nil:
+			return nil, err
+		case got.SHA256 != want.SHA256 || got.Size != want.Size:
+			r.Modified = append(r.Modified, want.Path)
+		default:
+			r.Verified++
+		}
+	}
+	r.Valid = r.Signature == SignatureValid && len(r.Modified) == 0 && len(r.Missing) == 0
+	return r, nil
+}
+
+// digest checksums a project-relative path under root
+func digest(root, rel string) (*FileDigest, error) {
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return nil, fmt.Errorf("path %s is outside the project", rel)
+	}
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDigest{Path: rel, SHA256: hex.EncodeToString(h.Sum(nil)), Size: n}, nil
+}
+
+// Save writes m into the workspace at root
+func Save(root string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := manifestPath(root, m.WorkflowID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads the signed manifest of workflowID from the workspace at root
+func Load(root string, workflowID uuid.UUID) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath(root, workflowID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func manifestPath(root string, workflowID uuid.UUID) string {
+	return filepath.Join(root, filepath.FromSlash(Dir), workflowID.String()+".json")
+}
@@ -0,0 +1,130 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func project(t *testing.T) string {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"main.go":           "package main\n",
+		"internal/db/db.go": "package db\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+	}
+	return root
+}
+
+func TestSignVerify(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	ed, err := ParseSigner("ed25519:" + base64.StdEncoding.EncodeToString(seed))
+	require.NoError(t, err)
+	public, err := ParseVerifier(PublicKey(ed))
+	require.NoError(t, err)
+	hmacSigner, err := ParseSigner("s3cret")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		signer   Signer
+		verifier Verifier
+	}{
+		{"hmac", hmacSigner, NewHMACSigner([]byte("s3cret"))},
+		{"ed25519", ed, public},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			root := project(t)
+			id := uuid.New()
+			m, err := Sign(root, id, []string{"main.go", "internal/db/db.go"}, tc.signer)
+			require.NoError(t, err)
+			assert.Equal(t, tc.signer.Algorithm(), m.Algorithm)
+			assert.Equal(t, "internal/db/db.go", m.Files[0].Path)
+			assert.Equal(t, int64(len("package main\n")), m.Files[1].Size)
+
+			require.NoError(t, Save(root, m))
+			loaded, err := Load(root, id)
+			require.NoError(t, err)
+			result, err := Verify(root, loaded, tc.verifier)
+			require.NoError(t, err)
+			assert.Equal(t, &Result{WorkflowID: id, Valid: true, Signature: SignatureValid, Verified: 2}, result)
+
+			// Tampered files
+			require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package evil\n"), 0644))
+			require.NoError(t, os.Remove(filepath.Join(root, "internal/db/db.go")))
+			result, err = Verify(root, loaded, tc.verifier)
+			require.NoError(t, err)
+			assert.False(t, result.Valid)
+			assert.Equal(t, []string{"main.go"}, result.Modified)
+			assert.Equal(t, []string{"internal/db/db.go"}, result.Missing)
+
+			// Tampered manifest
+			loaded.Files[0].SHA256 = m.Files[1].SHA256
+			result, err = Verify(root, loaded, tc.verifier)
+			require.NoError(t, err)
+			assert.Equal(t, SignatureInvalid, result.Signature)
+		})
+	}
+
+	m, err := Sign(project(t), uuid.New(), []string{"main.go"}, hmacSigner)
+	require.NoError(t, err)
+	result, err := Verify(project(t), m, NewHMACSigner([]byte("other")))
+	require.NoError(t, err)
+	assert.Equal(t, SignatureUnknown, result.Signature)
+	assert.False(t, result.Valid)
+
+	_, err = Sign(project(t), uuid.New(), []string{"../etc/passwd"}, hmacSigner)
+	assert.ErrorContains(t, err, "outside the project")
+	_, err = ParseSigner("ed25519:AAAA")
+	assert.ErrorContains(t, err, "3 bytes")
+	_, err = Load(t.TempDir(), uuid.New())
+	assert.ErrorIs(t, err, ErrNoManifest)
+}
+
+func TestHandlers(t *testing.T) {
+	root := project(t)
+	id := uuid.New()
+	signer := NewHMACSigner([]byte("s3cret"))
+	m, err := Sign(root, id, []string{"main.go"}, signer)
+	require.NoError(t, err)
+	require.NoError(t, Save(root, m))
+
+	dir := func(uuid.UUID) string { return root }
+	r := mux.NewRouter()
+	r.HandleFunc("/api/workflow/{id}/integrity", ManifestHandler(dir))
+	r.HandleFunc("/api/workflow/{id}/integrity/verify", VerifyHandler(dir, signer))
+	r.HandleFunc("/api/integrity/key", KeyHandler(signer))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/workflow/" + id.String() + "/integrity")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var served Manifest
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, m.Signature, served.Signature)
+
+	rec = get("/api/workflow/" + id.String() + "/integrity/verify")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"workflow_id": "`+id.String()+`", "valid": true, "signature": "valid", "verified": 1}`, rec.Body.String())
+
+	rec = get("/api/integrity/key")
+	assert.JSONEq(t, `{"algorithm": "hmac-sha256", "key_id": "`+signer.KeyID()+`", "public_key": ""}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/api/workflow/"+uuid.NewString()+"/integrity").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/workflow/nope/integrity/verify").Code)
+}
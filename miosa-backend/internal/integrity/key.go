@@ -0,0 +1,158 @@
+package integrity
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Signature algorithms
+const (
+	AlgorithmHMAC    = "hmac-sha256"
+	AlgorithmEd25519 = "ed25519"
+)
+
+// ed25519Prefix marks an Ed25519 key in ParseSigner and ParseVerifier;
+// any other key is an HMAC secret
+const ed25519Prefix = "ed25519:"
+
+// Verifier checks manifest signatures
+type Verifier interface {
+	Algorithm() string
+	// KeyID identifies the key without revealing it
+	KeyID() string
+	Verify(payload, signature []byte) error
+}
+
+// Signer signs manifests and verifies what it signed
+type Signer interface {
+	Verifier
+	Sign(payload []byte) ([]byte, error)
+}
+
+var errBadSignature = errors.New("signature does not match")
+
+// hmacKey signs with a shared secret; consumers need the same secret
+type hmacKey []byte
+
+// NewHMACSigner returns a signer using HMAC-SHA256 with secret
+func NewHMACSigner(secret []byte) Signer {
+	return hmacKey(secret)
+}
+
+func (k hmacKey) Algorithm() string { return AlgorithmHMAC }
+
+func (k hmacKey) KeyID() string {
+	sum := sha256.Sum256(append([]byte("miosa-integrity:"), k...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (k hmacKey) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+func (k hmacKey) Verify(payload, signature []byte) error {
+	want, _ := k.Sign(payload)
+	if !hmac.Equal(want, signature) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// ed25519Key verifies with a public key, and signs when it holds the
+// private key too
+type ed25519Key struct {
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+}
+
+// NewEd25519Signer returns a signer using an Ed25519 private key, whose
+// public key consumers verify with
+func NewEd25519Signer(private ed25519.PrivateKey) Signer {
+	return &ed25519Key{public: private.Public().(ed25519.PublicKey), private: private}
+}
+
+// NewEd25519Verifier returns a verifier for an Ed25519 public key
+func NewEd25519Verifier(public ed25519.PublicKey) Verifier {
+	return &ed25519Key{public: public}
+}
+
+func (k *ed25519Key) Algorithm() string { return AlgorithmEd25519 }
+
+func (k *ed25519Key) KeyID() string {
+	sum := sha256.Sum256(k.public)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (k *ed25519Key) Sign(payload []byte) ([]byte, error) {
+	if k.private == nil {
+		return nil, errors.New("no private key to sign with")
+	}
+	return ed25519.Sign(k.private, payload), nil
+}
+
+func (k *ed25519Key) Verify(payload, signature []byte) error {
+	if !ed25519.Verify(k.public, payload, signature) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// PublicKey encodes the key consumers verify with in the form ParseVerifier
+// reads. HMAC secrets have no public form and return "".
+func PublicKey(v Verifier) string {
+	if k, ok := v.(*ed25519Key); ok {
+		return ed25519Prefix + base64.StdEncoding.EncodeToString(k.public)
+	}
+	return ""
+}
+
+// ParseSigner reads a signing key: "ed25519:" followed by a base64 Ed25519
+// seed or private key, or else an HMAC secret
+func ParseSigner(key string) (Signer, error) {
+	if key == "" {
+		return nil, errors.New("empty signing key")
+	}
+	encoded, ok := strings.CutPrefix(key, ed25519Prefix)
+	if !ok {
+		return NewHMACSigner([]byte(key)), nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ed25519 key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return NewEd25519Signer(ed25519.NewKeyFromSeed(raw)), nil
+	case ed25519.PrivateKeySize:
+		return NewEd25519Signer(ed25519.PrivateKey(raw)), nil
+	}
+	return nil, fmt.Errorf("invalid Ed25519 key: %d bytes", len(raw))
+}
+
+// ParseVerifier reads a verification key: "ed25519:" followed by a base64
+// Ed25519 public key, or else an HMAC secret
+func ParseVerifier(key string) (Verifier, error) {
+	if key == "" {
+		return nil, errors.New("empty verification key")
+	}
+	encoded, ok := strings.CutPrefix(key, ed25519Prefix)
+	if !ok {
+		return NewHMACSigner([]byte(key)), nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ed25519 public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key: %d bytes", len(raw))
+	}
+	return NewEd25519Verifier(ed25519.PublicKey(raw)), nil
+}
@@ -0,0 +1,311 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// BM25 tuning parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// rrfK dampens rank differences when fusing full-text and vector results
+	rrfK = 60.0
+)
+
+// Document is an indexed piece of generated documentation
+type Document struct {
+	ID        uuid.UUID        `json:"id"`
+	TenantID  string           `json:"tenant_id,omitempty"`
+	ProjectID string           `json:"project_id,omitempty"`
+	Agent     agents.AgentType `json:"agent,omitempty"`
+	Title     string           `json:"title"`
+	Path      string           `json:"path,omitempty"`
+	Content   string           `json:"content"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Query describes a knowledge base search
+type Query struct {
+	Text      string
+	TenantID  string
+	ProjectID string
+	Agent     agents.AgentType
+	Limit     int
+}
+
+// Hit is a ranked search result
+type Hit struct {
+	Document *Document `json:"document"`
+	Score    float64   `json:"score"`
+	Snippet  string    `json:"snippet"`
+}
+
+// Embedder turns text into a vector for semantic search
+type Embedder interface {
+	Embed(ctx context.Context, text string) (pgvector.Vector, error)
+}
+
+// Base indexes markdown documents for full-text search and, when a vector
+// store and embedder are configured, semantic search
+type Base struct {
+	docs        map[uuid.UUID]*Document
+	postings    map[string]map[uuid.UUID]int
+	lengths     map[uuid.UUID]int
+	totalLength int
+	vectorStore agents.VectorStore
+	embedder    Embedder
+	mu          sync.RWMutex
+}
+
+// New creates a knowledge base. vectorStore and embedder may be nil, in
+// which case only full-text search is used.
+func New(vectorStore agents.VectorStore, embedder Embedder) *Base {
+	return &Base{
+		docs:        make(map[uuid.UUID]*Document),
+		postings:    make(map[string]map[uuid.UUID]int),
+		lengths:     make(map[uuid.UUID]int),
+		vectorStore: vectorStore,
+		embedder:    embedder,
+	}
+}
+
+// Index adds a document to the knowledge base
+func (b *Base) Index(ctx context.Context, doc *Document) error {
+	if doc.ID == uuid.Nil {
+		doc.ID = uuid.New()
+	}
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = time.Now()
+	}
+	if doc.Title == "" {
+		doc.Title = markdownTitle(doc.Content)
+	}
+
+	terms := tokenize(doc.Title + "\n" + doc.Content)
+
+	b.mu.Lock()
+	b.docs[doc.ID] = doc
+	for _, term := range terms {
+		if b.postings[term] == nil {
+			b.postings[term] = make(map[uuid.UUID]int)
+		}
+		b.postings[term][doc.ID]++
+	}
+	b.lengths[doc.ID] = len(terms)
+	b.totalLength += len(terms)
+	b.mu.Unlock()
+
+	if b.vectorStore == nil || b.embedder == nil {
+		return nil
+	}
+
+	embedding, err := b.embedder.Embed(ctx, doc.Title+"\n"+doc.Content)
+	if err != nil {
+		return fmt.Errorf("failed to embed document: %w", err)
+	}
+	return b.vectorStore.Store(ctx, doc.ID, embedding, map[string]interface{}{
+		"kind":       "knowledge",
+		"tenant_id":  doc.TenantID,
+		"project_id": doc.ProjectID,
+		"agent":      string(doc.Agent),
+		"title":      doc.Title,
+	})
+}
+
+// IndexDir indexes every markdown file below dir, attributing the files
+// to the given tenant and project
+func (b *Base) IndexDir(ctx context.Context, dir, tenantID, projectID string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := b.Index(ctx, &Document{
+			TenantID:  tenantID,
+			ProjectID: projectID,
+			Path:      path,
+			Content:   string(content),
+		}); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// Search ranks documents by BM25 and, when available, fuses the ranking
+// with vector similarity using reciprocal rank fusion
+func (b *Base) Search(ctx context.Context, q Query) ([]Hit, error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return nil, fmt.Errorf("query text is required")
+	}
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+
+	textRanked := b.fullText(q)
+
+	fused := make(map[uuid.UUID]float64)
+	for rank, id := range textRanked {
+		fused[id] += 1 / (rrfK + float64(rank+1))
+	}
+
+	if b.vectorStore != nil && b.embedder != nil {
+		embedding, err := b.embedder.Embed(ctx, q.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		results, err := b.vectorStore.Search(ctx, embedding, q.Limit*3)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+		rank := 0
+		for _, r := range results {
+			if kind, _ := r.Metadata["kind"].(string); kind != "knowledge" {
+				continue
+			}
+			b.mu.RLock()
+			doc, ok := b.docs[r.ID]
+			b.mu.RUnlock()
+			if !ok || !matches(doc, q) {
+				continue
+			}
+			fused[r.ID] += 1 / (rrfK + float64(rank+1))
+			rank++
+		}
+	}
+
+	hits := make([]Hit, 0, len(fused))
+	terms := tokenize(q.Text)
+	b.mu.RLock()
+	for id, score := range fused {
+		doc := b.docs[id]
+		hits = append(hits, Hit{Document: doc, Score: score, Snippet: snippet(doc.Content, terms)})
+	}
+	b.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+// fullText returns matching document IDs ordered by BM25 score
+func (b *Base) fullText(q Query) []uuid.UUID {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.docs) == 0 {
+		return nil
+	}
+	avgLen := float64(b.totalLength) / float64(len(b.docs))
+	n := float64(len(b.docs))
+
+	scores := make(map[uuid.UUID]float64)
+	for _, term := range tokenize(q.Text) {
+		postings := b.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			if !matches(b.docs[id], q) {
+				continue
+			}
+			f := float64(tf)
+			norm := f + bm25K1*(1-bm25B+bm25B*float64(b.lengths[id])/avgLen)
+			scores[id] += idf * f * (bm25K1 + 1) / norm
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return scores[ids[i]] > scores[ids[j]]
+	})
+	return ids
+}
+
+// matches applies the tenant, project and agent filters of a query
+func matches(doc *Document, q Query) bool {
+	if q.TenantID != "" && doc.TenantID != q.TenantID {
+		return false
+	}
+	if q.ProjectID != "" && doc.ProjectID != q.ProjectID {
+		return false
+	}
+	if q.Agent != "" && doc.Agent != q.Agent {
+		return false
+	}
+	return true
+}
+
+// tokenize lowercases text and splits it into alphanumeric terms
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := fields[:0]
+	for _, f := range fields {
+		if len(f) > 1 {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// markdownTitle returns the first heading of a markdown document
+func markdownTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+	}
+	return "Untitled"
+}
+
+// snippet returns the first line containing a query term
+func snippet(content string, terms []string) string {
+	for _, line := range strings.Split(content, "\n") {
+		lower := strings.ToLower(line)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				line = strings.TrimSpace(line)
+				if r := []rune(line); len(r) > 200 {
+					line = string(r[:200]) + "..."
+				}
+				return line
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBase_Search(t *testing.T) {
+	ctx := context.Background()
+	kb := New(nil, nil)
+
+	require.NoError(t, kb.Index(ctx, &Document{
+		ProjectID: "p1",
+		Agent:     agents.ArchitectAgent,
+		Content:   "# Architecture\nWe chose PostgreSQL with pgvector for embeddings.",
+	}))
+	require.NoError(t, kb.Index(ctx, &Document{
+		ProjectID: "p1",
+		Agent:     agents.StrategyAgent,
+		Content:   "# Strategy\nShip the MVP in two phases.",
+	}))
+	require.NoError(t, kb.Index(ctx, &Document{
+		ProjectID: "p2",
+		Agent:     agents.ArchitectAgent,
+		Content:   "# Other project\nPostgreSQL is used for billing.",
+	}))
+
+	hits, err := kb.Search(ctx, Query{Text: "postgresql", ProjectID: "p1"})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "Architecture", hits[0].Document.Title)
+	assert.Contains(t, hits[0].Snippet, "PostgreSQL")
+
+	hits, err = kb.Search(ctx, Query{Text: "postgresql"})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	_, err = kb.Search(ctx, Query{Text: "  "})
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// SearchHandler serves GET /api/knowledge/search?q=&tenant=&project=&agent=&limit=
+func SearchHandler(b *Base) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		limit, _ := strconv.Atoi(params.Get("limit"))
+
+		hits, err := b.Search(r.Context(), Query{
+			Text:      params.Get("q"),
+			TenantID:  params.Get("tenant"),
+			ProjectID: params.Get("project"),
+			Agent:     agents.AgentType(params.Get("agent")),
+			Limit:     limit,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"query":   params.Get("q"),
+			"results": hits,
+			"count":   len(hits),
+		})
+	}
+}
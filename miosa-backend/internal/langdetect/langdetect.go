@@ -0,0 +1,162 @@
+// Package langdetect classifies source code by language in the manner of
+// GitHub's linguist: a known file name or extension decides, then a shebang,
+// then weighted content heuristics. It names the extension files of the
+// language take, so unnamed code can be saved under a fitting name.
+package langdetect
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// Language is a classified language
+type Language struct {
+	Name      string `json:"name"`      // Lowercase identifier, e.g. go, python, csharp
+	Extension string `json:"extension"` // Conventional extension, with the dot
+}
+
+// Text is returned when nothing identifies the language
+var Text = Language{Name: "text", Extension: ".txt"}
+
+// minScore is the content score a language needs to be chosen; one weak
+// signal, like an arrow function, is not enough
+const minScore = 3
+
+// maxSample bounds the content the heuristics read
+const maxSample = 64 << 10
+
+// Classify returns the language of content. filename may be empty; when
+// it has a known name or an unambiguous extension it decides.
+func Classify(filename, content string) Language {
+	if len(content) > maxSample {
+		content = content[:maxSample]
+	}
+	candidates := byFilename(filename)
+	if len(candidates) == 1 {
+		return candidates[0].Language
+	}
+	if l, ok := byShebang(content); ok && (len(candidates) == 0 || contains(candidates, l.Name)) {
+		return l.Language
+	}
+	if len(candidates) == 0 {
+		candidates = languages
+	}
+	return byContent(content, candidates)
+}
+
+// Names lists the languages Classify knows
+func Names() []string {
+	names := make([]string, len(languages))
+	for i, l := range languages {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// byFilename returns the languages a file name or extension may hold
+func byFilename(filename string) []*language {
+	if filename == "" {
+		return nil
+	}
+	base := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	var matched []*language
+	for _, l := range languages {
+		for _, name := range l.filenames {
+			if base == name {
+				return []*language{l}
+			}
+		}
+	}
+	ext := strings.ToLower(path.Ext(base))
+	if ext == "" {
+		return nil
+	}
+	for _, l := range languages {
+		for _, e := range l.extensions {
+			if ext == e {
+				matched = append(matched, l)
+			}
+		}
+	}
+	return matched
+}
+
+// byShebang reads the interpreter of a script, looking through env
+func byShebang(content string) (*language, bool) {
+	if !strings.HasPrefix(content, "#!") {
+		return nil, false
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's options, as in env -S deno run
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = f
+				break
+			}
+		}
+	}
+	// python3.12 is python
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	for _, l := range languages {
+		for _, name := range l.interpreters {
+			if interpreter == name {
+				return l, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// byContent scores every candidate's signals against content, returning
+// the highest scoring one, the earliest declared on ties
+func byContent(content string, candidates []*language) Language {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		if len(candidates) < len(languages) {
+			return candidates[0].Language
+		}
+		return Text
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) && contains(candidates, "json") {
+		return jsonLanguage.Language
+	}
+
+	var best *language
+	bestScore := 0
+	for _, l := range candidates {
+		score := 0
+		for _, s := range l.signals {
+			if s.pattern.MatchString(content) {
+				score += s.weight
+			}
+		}
+		if score > bestScore {
+			best, bestScore = l, score
+		}
+	}
+	switch {
+	case best != nil && bestScore >= minScore:
+		return best.Language
+	case len(candidates) < len(languages):
+		// The extension narrowed it down; take its usual language
+		return candidates[0].Language
+	}
+	return Text
+}
+
+func contains(candidates []*language, name string) bool {
+	for _, l := range candidates {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package langdetect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify_Content(t *testing.T) {
+	for want, code := range map[string]string{
+		"go": `package main
+
+import "fmt"
+
+func main() {
+	msg := "hi"
+	fmt.Println(msg)
+}`,
+		"python": `from flask import Flask
+
+app = Flask(__name__)
+
+def index():
+    return {"ok": True}`,
+		"javascript": `const express = require('express');
+const app = express();
+app.get('/', (req, res) => res.send('ok'));
+module.exports = app;`,
+		"typescript": `import { Request } from 'express';
+
+export interface User {
+  id: number;
+  name: string;
+}
+
+export const greet = (u: User): string => u.name;`,
+		"java": `package com.example;
+
+import java.util.List;
+
+public class App {
+    public static void main(String[] args) {
+        System.out.println("hi");
+    }
+}`,
+		"kotlin": `package com.example
+
+data class User(val id: Int, val name: String)
+
+fun main() {
+    val user = User(1, "a")
+    println(user)
+}`,
+		"scala": `object Main extends App {
+  case class User(id: Int)
+  def greet(name: String): String = s"hi $name"
+}`,
+		"c": `#include <stdio.h>
+
+int main(void) {
+    printf("hi\n");
+    return 0;
+}`,
+		"cpp": `#include <iostream>
+
+int main() {
+    std::cout << "hi" << std::endl;
+}`,
+		"objective-c": `#import <Foundation/Foundation.h>
+
+@interface Greeter : NSObject
+@end`,
+		"csharp": `using System;
+
+namespace App
+{
+    public class User { public string Name { get; set; } }
+}`,
+		"swift": `import SwiftUI
+
+struct ContentView: View {
+    @State var count = 0
+}`,
+		"rust": `use std::collections::HashMap;
+
+fn main() {
+    let mut m = HashMap::new();
+    m.insert(1, 2);
+    println!("{:?}", m);
+}`,
+		"ruby": `require 'sinatra'
+
+class App
+  attr_accessor :name
+
+  def greet
+    puts "hi"
+  end
+end`,
+		"php":    "<?php\necho $name;\n",
+		"perl":   "use strict;\nuse warnings;\nmy $x = 1;\n",
+		"lua":    "local t = {}\nfor k, v in pairs(t) do\n  print(k)\nend\n",
+		"r":      "library(dplyr)\ndf <- data.frame(x = c(1, 2))\ndf %>% summary()\n",
+		"dart":   "import 'package:flutter/material.dart';\n\nvoid main() => runApp(App());\n",
+		"elixir": "defmodule Greeter do\n  def hello(name) do\n    name |> String.upcase()\n  end\nend\n",
+		"erlang": "-module(hello).\n-export([world/0]).\n\nworld() ->\n    io:format(\"hi~n\").\n",
+		"haskell": `module Main where
+
+main :: IO ()
+main = putStrLn "hi"`,
+		"clojure":    "(ns app.core)\n\n(defn greet [name] (str \"hi \" name))\n",
+		"shell":      "set -euo pipefail\nexport APP_ENV=prod\nif [ -f .env ]; then\n  echo loaded\nfi\n",
+		"powershell": "param([string]$Name)\nWrite-Host \"hi $Name\"\n",
+		"sql":        "CREATE TABLE users (\n  id SERIAL PRIMARY KEY,\n  email VARCHAR(255) NOT NULL\n);",
+		"html":       "<!DOCTYPE html>\n<html><body><div>hi</div></body></html>",
+		"css":        "body {\n  margin: 0;\n  padding: 8px;\n}\n",
+		"json":       `{"name": "app", "version": "1.0.0"}`,
+		"yaml":       "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\n",
+		"toml":       "[package]\nname = \"app\"\nversion = \"0.1.0\"\n",
+		"xml":        "<?xml version=\"1.0\"?>\n<project xmlns=\"http://maven.apache.org/POM/4.0.0\"></project>",
+		"markdown":   "# Setup\n\nSee [the docs](https://example.com) for **details**.\n",
+		"dockerfile": "FROM golang:1.22 AS build\nWORKDIR /app\nCOPY . .\nRUN go build -o /bin/api\n",
+		"makefile":   ".PHONY: build\nbuild:\n\tgo build $(LDFLAGS) ./...\n",
+		"terraform":  "provider \"aws\" {\n  region = var.region\n}\n\nresource \"aws_s3_bucket\" \"assets\" {\n}\n",
+		"protobuf":   "syntax = \"proto3\";\n\nmessage User {\n  string id = 1;\n}\n",
+		"graphql":    "type Query {\n  user(id: ID!): User\n}\n",
+		"vue":        "<template>\n  <div>{{ msg }}</div>\n</template>\n\n<script setup>\nconst msg = 'hi'\n</script>\n",
+	} {
+		assert.Equal(t, want, Classify("", code).Name, code)
+	}
+
+	// Braces alone no longer make JSON
+	assert.Equal(t, Text, Classify("", "{ this is not json }"))
+	assert.Equal(t, Text, Classify("", ""))
+	assert.GreaterOrEqual(t, len(Names()), 30)
+}
+
+func TestClassify_Filename(t *testing.T) {
+	assert.Equal(t, Language{Name: "dockerfile", Extension: ".dockerfile"}, Classify("build/Dockerfile", "anything"))
+	assert.Equal(t, "makefile", Classify("Makefile", "").Name)
+	assert.Equal(t, "typescript", Classify("src/app.TSX", "").Name)
+	assert.Equal(t, "r", Classify("analysis.R", "").Name)
+
+	// The extension decides over content
+	assert.Equal(t, "python", Classify("tool.py", "const x = 1").Name)
+
+	// Ambiguous extensions are settled by content, defaulting to the first
+	assert.Equal(t, "cpp", Classify("vec.h", "#include <vector>\ntemplate <typename T> class Vec {};").Name)
+	assert.Equal(t, "objective-c", Classify("Greeter.h", "#import <Foundation/Foundation.h>\n@interface Greeter : NSObject\n@end").Name)
+	assert.Equal(t, "c", Classify("util.h", "").Name)
+
+	// Unknown extensions fall back to content
+	assert.Equal(t, "go", Classify("main.txt.bak", "package main\n\nfunc main() {}\n").Name)
+}
+
+func TestClassify_Shebang(t *testing.T) {
+	for want, script := range map[string]string{
+		"python":     "#!/usr/bin/env python3\nprint('hi')\n",
+		"shell":      "#!/bin/bash\necho hi\n",
+		"javascript": "#!/usr/bin/env node\nconsole.log('hi')\n",
+		"typescript": "#!/usr/bin/env -S deno run\nconsole.log('hi')\n",
+		"ruby":       "#!/usr/bin/ruby -w\nputs 'hi'\n",
+	} {
+		assert.Equal(t, want, Classify("", script).Name, script)
+	}
+	// A shebang for a language the extension rules out is ignored
+	assert.Equal(t, "shell", Classify("run.sh", "#!/usr/bin/env python3\n").Name)
+}
@@ -0,0 +1,327 @@
+package langdetect
+
+import "regexp"
+
+// signal is a pattern whose match counts weight toward a language
+type signal struct {
+	weight  int
+	pattern *regexp.Regexp
+}
+
+// language describes how to recognise one language. The first extension
+// is the conventional one.
+type language struct {
+	Language
+	extensions   []string
+	filenames    []string
+	interpreters []string
+	signals      []signal
+}
+
+// s compiles a multi-line signal
+func s(weight int, expr string) signal {
+	return signal{weight: weight, pattern: regexp.MustCompile(`(?m)` + expr)}
+}
+
+func lang(name string, extensions, filenames, interpreters []string, signals ...signal) *language {
+	return &language{
+		Language:     Language{Name: name, Extension: extensions[0]},
+		extensions:   extensions,
+		filenames:    filenames,
+		interpreters: interpreters,
+		signals:      signals,
+	}
+}
+
+// Signals shared by languages that extend another
+var (
+	jsSignals = []signal{
+		s(2, `\bconst \w+ = `),
+		s(1, `\blet \w+ = `),
+		s(2, `\brequire\(['"][^'"]+['"]\)`),
+		s(3, `\bmodule\.exports\b|^exports\.\w+ =`),
+		s(1, `=>`),
+		s(2, `\bfunction\s*\w*\s*\([^)]*\)\s*\{`),
+		s(3, `\bconsole\.\w+\(`),
+		s(3, `^\s*import .+ from ['"][^'"]+['"];?\s*$`),
+		s(3, `^export (default |const |function |class |async )`),
+		s(2, `\b(document|window)\.\w+`),
+		s(1, `===|!==`),
+	}
+	cSignals = []signal{
+		s(3, `^#include\s*[<"][\w/.]+\.h[>"]`),
+		s(2, `^\s*(static )?(int|void|char|long|double|float|unsigned|size_t)\s+\**\w+\s*\([^;]*\)\s*\{?\s*$`),
+		s(2, `\b(printf|fprintf|malloc|calloc|free|sizeof)\(`),
+		s(2, `^#(define|ifndef|ifdef|endif)\b`),
+		s(1, `\bNULL\b`),
+		s(1, `\bstruct \w+\s*\{`),
+	}
+)
+
+var jsonLanguage = lang("json", []string{".json"}, []string{".babelrc", ".eslintrc"}, nil)
+
+// languages in tie-break order: a language extending another comes after it
+var languages = []*language{
+	lang("go", []string{".go"}, nil, nil,
+		s(5, `^package \w+\s*$`),
+		s(3, `^func (\(\w+ \*?\w+(\[.+\])?\) )?\w+(\[.+\])?\(`),
+		s(2, `\w := `),
+		s(2, `^import (\($|"[\w./-]+"$)`),
+		s(2, `\berr != nil\b`),
+		s(2, `\bfmt\.\w+\(`),
+		s(2, `^type \w+ (struct|interface) \{`),
+	),
+	lang("python", []string{".py", ".pyw", ".pyi"}, nil, []string{"python", "pypy"},
+		s(3, `^\s*(async )?def \w+\(.*\)( -> .+)?:\s*$`),
+		s(3, `^from [\w.]+ import `),
+		s(1, `^import \w+(\.\w+)*( as \w+)?\s*$`),
+		s(3, `if __name__ == ['"]__main__['"]`),
+		s(2, `\bself\.\w+`),
+		s(2, `^\s*class \w+(\(.*\))?:\s*$`),
+		s(2, `^\s*(elif|except|with)\b.*:\s*$`),
+		s(1, `\bprint\(`),
+	),
+	lang("javascript", []string{".js", ".mjs", ".cjs", ".jsx"}, nil, []string{"node", "nodejs", "bun"},
+		jsSignals...,
+	),
+	lang("typescript", []string{".ts", ".tsx", ".mts", ".cts"}, nil, []string{"deno", "ts-node", "tsx"},
+		append([]signal{
+			s(3, `^\s*(export )?interface \w+(<.+>)?( extends [\w<>, ]+)? \{`),
+			s(3, `\w\??:\s*(string|number|boolean|any|void|unknown|never)(\[\])?\b`),
+			s(2, `^\s*(export )?type \w+(<.+>)? = `),
+			s(2, `\bas (string|number|const|any|unknown)\b`),
+			s(2, `\b(private|public|protected|readonly) \w+\??:`),
+		}, jsSignals...)...,
+	),
+	lang("java", []string{".java"}, nil, nil,
+		s(4, `^package [\w.]+;\s*$`),
+		s(3, `^import (static )?[\w.]+(\.\*)?;\s*$`),
+		s(2, `\bpublic (static )?(final )?(abstract )?(class|interface|enum|record) `),
+		s(3, `\bSystem\.(out|err)\.print`),
+		s(2, `@(Override|Autowired|RestController|GetMapping|PostMapping|Entity|Test)\b`),
+		s(2, `\bprivate (static )?final \w+`),
+		s(3, `public static void main\(String`),
+	),
+	lang("kotlin", []string{".kt", ".kts"}, nil, []string{"kotlin"},
+		s(3, `^\s*((private|override|suspend|inline|internal|public) )*fun (<.+> )?\w+\(`),
+		s(2, `\bval \w+(: [\w<>?]+)? = `),
+		s(2, `^package [\w.]+\s*$`),
+		s(1, `\bprintln\(`),
+		s(3, `\b(data class|companion object|sealed class)\b|\bwhen \(.*\) \{`),
+		s(1, `: \w+\?( =|,|\))`),
+	),
+	lang("scala", []string{".scala", ".sc"}, nil, []string{"scala"},
+		s(3, `^\s*(case class|sealed trait|trait \w+|object \w+)`),
+		s(3, `^\s*def \w+(\[.+\])?(\(.*\))?\s*(: [\w\[\], ]+)?\s*=`),
+		s(2, `^import [\w.]+\.(_|\{)`),
+		s(1, `\bval \w+ = `),
+		s(2, `\bextends App\b`),
+	),
+	lang("c", []string{".c", ".h"}, nil, nil, cSignals...),
+	lang("cpp", []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx", ".h"}, nil, nil,
+		append([]signal{
+			s(3, `^#include\s*<(iostream|vector|string|map|memory|algorithm|cstdio|cstdlib|cstring|unordered_map)>`),
+			s(3, `\bstd::`),
+			s(2, `\b(cout|cerr)\s*<<`),
+			s(2, `^\s*template\s*<`),
+			s(1, `^\s*(class|namespace) \w+\s*(:\s*(public|private|protected) \w+\s*)?\{`),
+			s(2, `\busing namespace \w+;`),
+		}, cSignals...)...,
+	),
+	lang("objective-c", []string{".m", ".mm", ".h"}, nil, nil,
+		append([]signal{
+			s(4, `^#import\s*[<"]`),
+			s(4, `^@(interface|implementation|protocol|end)\b`),
+			s(3, `\b(NSString|NSLog|NSObject|NSArray)\b`),
+			s(1, `^\s*@property\b`),
+		}, cSignals...)...,
+	),
+	lang("csharp", []string{".cs"}, nil, nil,
+		s(4, `^using System(\.[\w.]+)?;`),
+		s(2, `^\s*namespace [\w.]+\s*(;|\{)?\s*$`),
+		s(3, `\bConsole\.Write(Line)?\(`),
+		s(1, `\bpublic (async )?(static )?(Task|void|string|int|bool)(<.+>)? \w+\(`),
+		s(3, `\{ get; (set; |private set; |init; )?\}`),
+		s(1, `\bvar \w+ = new \w+`),
+		s(2, `^\s*\[(HttpGet|HttpPost|ApiController|Route|Fact|TestMethod)\b`),
+	),
+	lang("swift", []string{".swift"}, nil, []string{"swift"},
+		s(5, `^import (Foundation|UIKit|SwiftUI|Combine|XCTest)\s*$`),
+		s(2, `^\s*(let|var) \w+: [A-Z]\w*[?!]?( =|\s*$)`),
+		s(3, `\bguard (let|var)\b`),
+		s(1, `\bif let \w+ = `),
+		s(2, `^\s*func \w+(<.+>)?\(.*\)( async)?( throws)?( -> .+)?\s*\{`),
+		s(3, `@(State|Published|objc|IBOutlet|MainActor|Binding)\b`),
+		s(3, `\bstruct \w+: (View|Codable|Equatable|Hashable)\b`),
+	),
+	lang("rust", []string{".rs"}, nil, nil,
+		s(3, `^\s*(pub(\(crate\))? )?(async )?fn \w+(<.+>)?\(`),
+		s(3, `\blet mut \w+`),
+		s(3, `\b(println|eprintln|format|panic|assert_eq|write|writeln)!\(|\bvec!\[`),
+		s(3, `^\s*use (std|crate|super|self)::`),
+		s(2, `^\s*impl(<.+>)? \w+`),
+		s(2, `^\s*#\[(derive|test|cfg|tokio::main)`),
+		s(2, `&mut \w+`),
+	),
+	lang("ruby", []string{".rb", ".rake", ".gemspec"}, []string{"Gemfile", "Rakefile", "Podfile", "Vagrantfile"}, []string{"ruby"},
+		s(2, `^\s*require ['"][\w/]+['"]\s*$`),
+		s(3, `^\s*def (self\.)?\w+[?!]?(\(.*\))?\s*$`),
+		s(1, `^\s*end\s*$`),
+		s(3, `\b(attr_accessor|attr_reader|require_relative)\b|^\s*puts `),
+		s(2, `^\s*class \w+( < [\w:]+)?\s*$`),
+		s(3, `\bdo \|\w+(, \w+)*\|`),
+		s(1, `^\s*module \w+\s*$`),
+	),
+	lang("php", []string{".php"}, nil, []string{"php"},
+		s(6, `<\?php`),
+		s(2, `\$\w+->\w+`),
+		s(2, `^\s*(namespace [\w\\]+;|use [\w\\]+;)`),
+		s(2, `\becho \$|\$this->`),
+	),
+	lang("perl", []string{".pl", ".pm"}, nil, []string{"perl"},
+		s(4, `^\s*use (strict|warnings);`),
+		s(3, `\bmy [\$@%]\w+`),
+		s(2, `^\s*sub \w+\s*\{`),
+		s(1, `=~ [sm]?/`),
+	),
+	lang("lua", []string{".lua"}, nil, []string{"lua", "luajit"},
+		s(3, `^\s*local (function )?\w+`),
+		s(2, `^\s*function [\w.:]+\(.*\)\s*$`),
+		s(1, `\bthen\s*$`),
+		s(2, `~=`),
+		s(1, `^\s*end\s*$`),
+		s(3, `\bi?pairs\(`),
+	),
+	lang("r", []string{".r", ".rmd"}, nil, []string{"Rscript"},
+		s(3, `\w+ <- function\(`),
+		s(1, `^\w+ <- `),
+		s(3, `\blibrary\(\w+\)`),
+		s(2, `\bdata\.frame\(|\bc\(\d`),
+		s(3, `%>%`),
+	),
+	lang("dart", []string{".dart"}, nil, []string{"dart"},
+		s(4, `^import 'package:[\w/.]+';`),
+		s(2, `^void main\(\)`),
+		s(3, `@override\b`),
+		s(3, `\bextends (StatelessWidget|StatefulWidget|State<)`),
+		s(2, `\bWidget build\(`),
+		s(1, `\bFuture<`),
+	),
+	lang("elixir", []string{".ex", ".exs"}, nil, []string{"elixir"},
+		s(5, `^\s*defmodule [\w.]+ do`),
+		s(3, `^\s*defp? \w+[?!]?(\(.*\))? do`),
+		s(2, `\|>`),
+		s(3, `@(moduledoc|doc|spec)\b`),
+		s(1, `\bdo\s*$`),
+	),
+	lang("erlang", []string{".erl", ".hrl"}, nil, []string{"escript"},
+		s(5, `^-module\(\w+\)\.`),
+		s(3, `^-(export|import|record|include)\(`),
+		s(2, `\)\s*->\s*$`),
+		s(3, `\bio:format\(`),
+	),
+	lang("haskell", []string{".hs"}, nil, []string{"runhaskell", "runghc"},
+		s(5, `^module [\w.]+( \(.*\))? where`),
+		s(3, `^\w+ :: `),
+		s(3, `^import qualified `),
+		s(2, `^newtype \w+|^data \w+.* = `),
+		s(3, `\bputStrLn\b|<- getLine`),
+	),
+	lang("clojure", []string{".clj", ".cljs", ".cljc", ".edn"}, nil, []string{"clojure", "bb"},
+		s(5, `^\(ns [\w.-]+`),
+		s(3, `^\((defn|def|defmacro|defprotocol) `),
+		s(2, `\(let \[`),
+	),
+	lang("shell", []string{".sh", ".bash", ".zsh"}, []string{".bashrc", ".zshrc", ".profile", ".bash_profile"}, []string{"sh", "bash", "zsh", "dash", "ksh"},
+		s(3, `^\s*export [A-Z_][A-Z0-9_]*=`),
+		s(2, `^\s*echo `),
+		s(3, `^\s*set -[a-z]+`),
+		s(3, `^\s*if \[\[? .* \]\]?`),
+		s(3, `^\s*(fi|done|esac)\s*$`),
+		s(1, `\$\{?[A-Z_][A-Z0-9_]*\}?`),
+		s(2, `^\s*(sudo |apt-get |curl |chmod |mkdir -p |docker |pip install |npm (install|run|ci) )`),
+	),
+	lang("powershell", []string{".ps1", ".psm1"}, nil, []string{"pwsh", "powershell"},
+		s(4, `\b(Write-(Host|Output)|Get-[A-Z]\w+|Set-[A-Z]\w+|New-Object|Invoke-[A-Z]\w+)\b`),
+		s(2, `^\s*param\s*\(`),
+		s(2, ` -(eq|ne|lt|gt|like|match) `),
+		s(2, `\[(string|int|switch|bool)\]\$`),
+	),
+	lang("sql", []string{".sql"}, nil, nil,
+		s(4, `(?i)^\s*(create|alter|drop) (table|index|view|schema|unique index|database|type|extension)\b`),
+		s(2, `(?i)^\s*select\b`),
+		s(1, `(?i)\bfrom \w+`),
+		s(1, `(?i)^\s*where\b`),
+		s(3, `(?i)^\s*(insert into|update \w+ set|delete from)\b`),
+		s(3, `(?i)\b(primary key|foreign key|references \w+\s*\(|not null|varchar\(\d+\))`),
+	),
+	lang("html", []string{".html", ".htm"}, nil, nil,
+		s(5, `(?i)<!doctype html|<html[\s>]`),
+		s(2, `(?i)<(div|span|body|head|p|a|ul|li|link|meta|form|table)[\s>]`),
+		s(1, `</\w+>`),
+	),
+	lang("css", []string{".css", ".scss", ".less"}, nil, nil,
+		s(2, `^\s*[a-z-]+\s*:\s*[^;{]+;\s*$`),
+		s(3, `^\s*@(media|import|keyframes|font-face)\b`),
+		s(2, `\b\d+(px|em|rem|vh|vw)\b`),
+		s(2, `^\s*[.#][\w-]+[^{(]*\{\s*$`),
+		s(2, `^\s*(body|html|h[1-6]|p|a|div|ul|li|button|input|:root)\b[^{(]*\{\s*$`),
+	),
+	jsonLanguage,
+	lang("yaml", []string{".yaml", ".yml"}, nil, nil,
+		s(2, `^---\s*$`),
+		s(2, `^[\w-]+:( .*)?$`),
+		s(3, `^\s*- [\w-]+: `),
+		s(3, `^(apiVersion|kind|services|jobs|steps|metadata|spec):`),
+	),
+	lang("toml", []string{".toml"}, []string{"Cargo.lock", "Pipfile"}, nil,
+		s(3, `^\[[\w.-]+\]\s*$`),
+		s(4, `^\[\[[\w.-]+\]\]\s*$`),
+		s(1, `^[\w-]+ = ("|\d|\[|true|false)`),
+	),
+	lang("xml", []string{".xml", ".xsd", ".plist"}, nil, nil,
+		s(5, `^<\?xml `),
+		s(3, `\bxmlns(:\w+)?="`),
+		s(1, `<(\w+:)?\w+( [\w:]+="[^"]*")+ ?/?>`),
+	),
+	lang("markdown", []string{".md", ".markdown"}, nil, nil,
+		s(1, `^#{1,6} \S`),
+		s(2, `\[[^\]]+\]\([^)]+\)`),
+		s(1, `^\s*[-*] \S`),
+		s(3, "^```"),
+		s(2, `\*\*\w[^*]*\*\*`),
+	),
+	lang("dockerfile", []string{".dockerfile"}, []string{"Dockerfile", "Containerfile"}, nil,
+		s(3, `^FROM [\w./:@${}-]+( (AS|as) [\w-]+)?\s*$`),
+		s(3, `^(RUN|CMD|ENTRYPOINT|COPY|WORKDIR|EXPOSE|ENV|ARG|HEALTHCHECK) `),
+	),
+	lang("makefile", []string{".mk"}, []string{"Makefile", "GNUmakefile", "makefile"}, []string{"make"},
+		s(4, `^\.PHONY:`),
+		s(2, `^[\w.%/-]+:( [^=]*)?$`),
+		s(1, "^\t\\S"),
+		s(2, `\$\([A-Z_]+\)`),
+	),
+	lang("terraform", []string{".tf", ".tfvars"}, nil, nil,
+		s(4, `^(resource|data) "\w+" "[\w-]+" \{`),
+		s(3, `^(provider|variable|output|module) "[\w-]+" \{`),
+		s(3, `^terraform \{`),
+		s(2, `\bvar\.\w+`),
+	),
+	lang("protobuf", []string{".proto"}, nil, nil,
+		s(5, `^syntax = "proto[23]";`),
+		s(3, `^message \w+ \{`),
+		s(3, `\brpc \w+\(`),
+		s(3, `^\s*(repeated |optional )?[\w.]+ \w+ = \d+;`),
+	),
+	lang("graphql", []string{".graphql", ".gql"}, nil, nil,
+		s(2, `^(type|input|enum|interface|union) \w+( implements \w+)? \{`),
+		s(3, `^(query|mutation|subscription)( \w+)?(\(.*\))? \{`),
+		s(3, `^schema \{`),
+		s(3, `: \[?\w+!?\]?!`),
+	),
+	lang("vue", []string{".vue"}, nil, nil,
+		s(4, `^<template>`),
+		s(2, `^<script( setup)?( lang="ts")?>`),
+		s(2, `^<style( scoped)?`),
+	),
+}
@@ -0,0 +1,52 @@
+package liveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"go.uber.org/zap"
+)
+
+// EnrichmentPollInterval is how often WatchEnrichment rereads the config.
+// The engine stores it without publishing, so it is polled.
+const EnrichmentPollInterval = 30 * time.Second
+
+// LoadEnrichment sets enricher's config from agents.ContextBuilderConfigKey.
+// A missing key clears it, so removing the key rolls enrichment back.
+func LoadEnrichment(ctx context.Context, client redis.UniversalClient, enricher *agents.ContextEnricher) error {
+	raw, err := client.Get(ctx, agents.ContextBuilderConfigKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		enricher.SetConfig(agents.EnrichmentConfig{})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var config agents.EnrichmentConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return err
+	}
+	enricher.SetConfig(config)
+	return nil
+}
+
+// WatchEnrichment loads the enrichment config every interval until ctx is
+// done. Failed reads are logged and keep the config in effect.
+func WatchEnrichment(ctx context.Context, client redis.UniversalClient, enricher *agents.ContextEnricher, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := LoadEnrichment(ctx, client, enricher); err != nil && ctx.Err() == nil {
+			logger.Warn("Failed to load context enrichment config", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package liveconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestLoadEnrichment(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	enricher := agents.NewContextEnricher(nil)
+
+	mock.ExpectGet(agents.ContextBuilderConfigKey).SetVal(`{"additional_context":["full_history","user_preferences"]}`)
+	require.NoError(t, LoadEnrichment(context.Background(), client, enricher))
+	assert.True(t, enricher.Config().Has(agents.ContextFullHistory))
+	assert.False(t, enricher.Config().Has(agents.ContextRelatedTasks))
+
+	mock.ExpectGet(agents.ContextBuilderConfigKey).SetVal(`not json`)
+	assert.Error(t, LoadEnrichment(context.Background(), client, enricher))
+	assert.True(t, enricher.Config().Has(agents.ContextFullHistory), "a bad config keeps the current one")
+
+	mock.ExpectGet(agents.ContextBuilderConfigKey).RedisNil()
+	require.NoError(t, LoadEnrichment(context.Background(), client, enricher))
+	assert.Empty(t, enricher.Config().AdditionalContext)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
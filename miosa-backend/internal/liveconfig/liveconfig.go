@@ -0,0 +1,358 @@
+// Package liveconfig holds the orchestrator settings that can change
+// without a restart: parallel step groups, agent routing overrides, model
+// selection and LLM timeouts. Updates arrive on the Redis channel the
+// self-improvement engine publishes to, and every change is kept as a
+// versioned snapshot that can be rolled back.
+package liveconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/events"
+	"go.uber.org/zap"
+)
+
+// Channel is the Redis channel carrying configuration updates
+const Channel = events.TopicConfigUpdates
+
+// Target is the update target the orchestrator applies
+const Target = "orchestrator"
+
+// Update kinds
+const (
+	KindInitial  = "initial"
+	KindParallel = "parallel_execution" // Run groups of consecutive steps concurrently
+	KindRouting  = "agent_swap"         // Route a step to a different agent
+	KindRules    = "routing_rules"      // Replace every routing rule
+	KindModel    = "model"              // Route every LLM call to one model, or set fallbacks
+	KindTimeouts = "timeouts"           // Per-agent LLM call timeouts
+	KindRollback = "rollback"           // Restore an earlier snapshot
+)
+
+// MaxHistory bounds the snapshots kept for rollback
+const MaxHistory = 50
+
+var (
+	ErrUnknownKind    = errors.New("unknown config update kind")
+	ErrUnknownVersion = errors.New("unknown config version")
+)
+
+// Message is an event published on Channel
+type Message struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Update Update `json:"update"`
+}
+
+// Update is one configuration change
+type Update struct {
+	Kind   string          `json:"kind"`
+	Config json.RawMessage `json:"config"`
+}
+
+// Snapshot is one version of the live configuration. Each snapshot is
+// complete, so applying it never depends on the ones before it.
+type Snapshot struct {
+	Version        int                                `json:"version"`
+	Kind           string                             `json:"kind"`
+	RolledBackTo   int                                `json:"rolled_back_to,omitempty"`
+	CreatedAt      time.Time                          `json:"created_at"`
+	ParallelGroups [][]agents.AgentType               `json:"parallel_groups,omitempty"`
+	MaxConcurrency int                                `json:"max_concurrency,omitempty"` // 0 leaves groups uncapped
+	Routing        map[string]agents.AgentType        `json:"routing,omitempty"`         // Keyed by RuleField
+	Model          string                             `json:"model,omitempty"`
+	Fallbacks      map[string][]string                `json:"fallbacks,omitempty"`
+	Timeouts       map[agents.AgentType]time.Duration `json:"timeouts,omitempty"`
+}
+
+func (s *Snapshot) clone() *Snapshot {
+	c := *s
+	c.ParallelGroups = make([][]agents.AgentType, len(s.ParallelGroups))
+	for i, group := range s.ParallelGroups {
+		c.ParallelGroups[i] = append([]agents.AgentType(nil), group...)
+	}
+	c.Routing = make(map[string]agents.AgentType, len(s.Routing))
+	for from, to := range s.Routing {
+		c.Routing[from] = to
+	}
+	c.Fallbacks = make(map[string][]string, len(s.Fallbacks))
+	for model, alternatives := range s.Fallbacks {
+		c.Fallbacks[model] = append([]string(nil), alternatives...)
+	}
+	c.Timeouts = make(map[agents.AgentType]time.Duration, len(s.Timeouts))
+	for agent, d := range s.Timeouts {
+		c.Timeouts[agent] = d
+	}
+	return &c
+}
+
+// Store holds the snapshot history and applies the current snapshot to an
+// LLM guard. A nil *Store routes every step to its own agent and runs
+// steps one at a time.
+type Store struct {
+	guard   *agents.LLMGuard
+	logger  *zap.Logger
+	history []*Snapshot // Oldest first
+	mu      sync.RWMutex
+}
+
+// NewStore records guard's current settings as version 1
+func NewStore(guard *agents.LLMGuard, logger *zap.Logger) *Store {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	initial := &Snapshot{
+		Version:   1,
+		Kind:      KindInitial,
+		CreatedAt: time.Now().UTC(),
+		Model:     guard.Model(),
+		Fallbacks: guard.Fallbacks(),
+		Timeouts:  guard.Timeouts(),
+	}
+	return &Store{guard: guard, logger: logger, history: []*Snapshot{initial.clone()}}
+}
+
+// Current returns a copy of the snapshot in effect
+func (s *Store) Current() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.history[len(s.history)-1].clone()
+}
+
+// History returns copies of the retained snapshots, oldest first
+func (s *Store) History() []*Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	history := make([]*Snapshot, len(s.history))
+	for i, snap := range s.history {
+		history[i] = snap.clone()
+	}
+	return history
+}
+
+// Apply records update as a new snapshot and puts it into effect
+func (s *Store) Apply(update Update) (*Snapshot, error) {
+	if update.Kind == KindRollback {
+		var cfg struct {
+			Version int `json:"version"`
+		}
+		if err := decode(update, &cfg); err != nil {
+			return nil, err
+		}
+		return s.Rollback(cfg.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.history[len(s.history)-1].clone()
+	if err := change(next, update); err != nil {
+		return nil, err
+	}
+	return s.push(next), nil
+}
+
+// Rollback restores version as a new snapshot, so the rollback can itself
+// be rolled back
+func (s *Store) Rollback(version int) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, snap := range s.history {
+		if snap.Version == version {
+			next := snap.clone()
+			next.Kind = KindRollback
+			next.RolledBackTo = version
+			return s.push(next), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+}
+
+// push makes snap current and applies its model settings. Callers hold s.mu.
+func (s *Store) push(snap *Snapshot) *Snapshot {
+	prev := s.history[len(s.history)-1]
+	snap.Version = prev.Version + 1
+	snap.CreatedAt = time.Now().UTC()
+
+	s.guard.SetModel(snap.Model)
+	for agent, d := range snap.Timeouts {
+		s.guard.SetTimeout(agent, d)
+	}
+	for model := range prev.Fallbacks {
+		if _, ok := snap.Fallbacks[model]; !ok {
+			s.guard.SetFallbacks(model)
+		}
+	}
+	for model, alternatives := range snap.Fallbacks {
+		s.guard.SetFallbacks(model, alternatives...)
+	}
+
+	s.history = append(s.history, snap)
+	if len(s.history) > MaxHistory {
+		s.history = s.history[len(s.history)-MaxHistory:]
+	}
+	s.logger.Info("Applied live config",
+		zap.Int("version", snap.Version),
+		zap.String("kind", snap.Kind))
+	return snap.clone()
+}
+
+// Route returns the agent that runs agentType's steps in tasks of taskType.
+// A rule for the task type wins over one for every task type.
+func (s *Store) Route(taskType string, agentType agents.AgentType) agents.AgentType {
+	if s == nil {
+		return agentType
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	routing := s.history[len(s.history)-1].Routing
+	if to, ok := routing[RuleField(taskType, agentType)]; ok && taskType != "" {
+		return to
+	}
+	if to, ok := routing[RuleField("", agentType)]; ok {
+		return to
+	}
+	return agentType
+}
+
+// Group returns how many steps of sequence, starting at start, run
+// together: the consecutive steps whose agents share a parallel group with
+// sequence[start], up to MaxConcurrency. It is 1 for ungrouped steps.
+func (s *Store) Group(sequence []agents.AgentType, start int) int {
+	if s == nil {
+		return 1
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap := s.history[len(s.history)-1]
+	for _, group := range snap.ParallelGroups {
+		if !contains(group, sequence[start]) {
+			continue
+		}
+		n := 1
+		for start+n < len(sequence) && contains(group, sequence[start+n]) &&
+			(snap.MaxConcurrency <= 0 || n < snap.MaxConcurrency) {
+			n++
+		}
+		return n
+	}
+	return 1
+}
+
+// change applies update to snap
+func change(snap *Snapshot, update Update) error {
+	snap.Kind = update.Kind
+	snap.RolledBackTo = 0
+	switch update.Kind {
+	case KindParallel:
+		var cfg struct {
+			Groups         [][]agents.AgentType `json:"parallel_agents"`
+			MaxConcurrency int                  `json:"max_concurrency"`
+		}
+		if err := decode(update, &cfg); err != nil {
+			return err
+		}
+		groups := make([][]agents.AgentType, 0, len(cfg.Groups))
+		for _, group := range cfg.Groups {
+			if len(group) > 1 {
+				groups = append(groups, group)
+			}
+		}
+		snap.ParallelGroups = groups
+		snap.MaxConcurrency = cfg.MaxConcurrency
+
+	case KindRouting:
+		var cfg struct {
+			TaskType string           `json:"task_type"`
+			From     agents.AgentType `json:"old_agent"`
+			To       agents.AgentType `json:"new_agent"`
+		}
+		if err := decode(update, &cfg); err != nil {
+			return err
+		}
+		if cfg.From == "" {
+			return fmt.Errorf("%s: old_agent is required", update.Kind)
+		}
+		// An empty or identical new agent removes the override
+		field := RuleField(cfg.TaskType, cfg.From)
+		if cfg.To == "" || cfg.To == cfg.From {
+			delete(snap.Routing, field)
+		} else {
+			snap.Routing[field] = cfg.To
+		}
+
+	case KindRules:
+		var cfg struct {
+			Rules map[string]agents.AgentType `json:"rules"`
+		}
+		if err := decode(update, &cfg); err != nil {
+			return err
+		}
+		routing := make(map[string]agents.AgentType, len(cfg.Rules))
+		for field, to := range cfg.Rules {
+			if _, from, ok := ParseRuleField(field); !ok || to == "" || to == from {
+				return fmt.Errorf("%s: invalid rule %q = %q", update.Kind, field, to)
+			}
+			routing[field] = to
+		}
+		snap.Routing = routing
+
+	case KindModel:
+		var cfg struct {
+			Model     *string             `json:"model"`
+			Fallbacks map[string][]string `json:"fallbacks"`
+		}
+		if err := decode(update, &cfg); err != nil {
+			return err
+		}
+		if cfg.Model != nil {
+			snap.Model = *cfg.Model
+		}
+		for model, alternatives := range cfg.Fallbacks {
+			if len(alternatives) == 0 {
+				delete(snap.Fallbacks, model)
+			} else {
+				snap.Fallbacks[model] = alternatives
+			}
+		}
+
+	case KindTimeouts:
+		var cfg struct {
+			Timeouts map[agents.AgentType]string `json:"timeouts"`
+		}
+		if err := decode(update, &cfg); err != nil {
+			return err
+		}
+		for agent, value := range cfg.Timeouts {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("%s: invalid timeout %q for %s", update.Kind, value, agent)
+			}
+			snap.Timeouts[agent] = d
+		}
+
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownKind, update.Kind)
+	}
+	return nil
+}
+
+func decode(update Update, v interface{}) error {
+	if err := json.Unmarshal(update.Config, v); err != nil {
+		return fmt.Errorf("%s: %w", update.Kind, err)
+	}
+	return nil
+}
+
+func contains(group []agents.AgentType, agentType agents.AgentType) bool {
+	for _, a := range group {
+		if a == agentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package liveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/events"
+)
+
+func publish(t *testing.T, s *Store, kind string, config interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(config)
+	require.NoError(t, err)
+	payload, err := json.Marshal(Message{Type: "config_update", Target: Target, Update: Update{Kind: kind, Config: raw}})
+	require.NoError(t, err)
+	s.Handle(payload)
+}
+
+var sequence = []agents.AgentType{
+	agents.StrategyAgent,
+	agents.AnalysisAgent,
+	agents.ArchitectAgent,
+	agents.QualityAgent,
+	agents.MonitoringAgent,
+	agents.DeploymentAgent,
+}
+
+func TestStore_Group(t *testing.T) {
+	var nilStore *Store
+	assert.Equal(t, 1, nilStore.Group(sequence, 0))
+
+	s := NewStore(agents.NewLLMGuard(), nil)
+	publish(t, s, KindParallel, map[string]interface{}{
+		"parallel_groups": [][]string{{"3f2c9a4e-0000-0000-0000-000000000000"}},
+		"parallel_agents": [][]agents.AgentType{{agents.QualityAgent, agents.MonitoringAgent, agents.DeploymentAgent}},
+		"max_concurrency": 2,
+	})
+
+	assert.Equal(t, 1, s.Group(sequence, 0))
+	assert.Equal(t, 2, s.Group(sequence, 3), "capped at max_concurrency")
+	assert.Equal(t, 2, s.Group(sequence, 4))
+	assert.Equal(t, 1, s.Group(sequence, 5), "last step of the sequence")
+}
+
+func TestStore_RoutingModelAndTimeouts(t *testing.T) {
+	guard := agents.NewLLMGuard()
+	s := NewStore(guard, nil)
+	baseline := guard.Timeout(agents.DevelopmentAgent)
+
+	publish(t, s, KindRouting, map[string]string{"old_agent": "analysis", "new_agent": "strategy"})
+	publish(t, s, KindModel, map[string]interface{}{
+		"model":     "llama-3.3-70b-versatile",
+		"fallbacks": map[string][]string{"llama-3.3-70b-versatile": {"llama-3.1-8b-instant"}},
+	})
+	publish(t, s, KindTimeouts, map[string]interface{}{"timeouts": map[string]string{"development": "4m"}})
+
+	assert.Equal(t, agents.StrategyAgent, s.Route("", agents.AnalysisAgent))
+	assert.Equal(t, agents.QualityAgent, s.Route("", agents.QualityAgent))
+	assert.Equal(t, "llama-3.3-70b-versatile", guard.Model())
+	assert.Equal(t, []string{"llama-3.1-8b-instant"}, guard.Fallbacks()["llama-3.3-70b-versatile"])
+	assert.Equal(t, 4*time.Minute, guard.Timeout(agents.DevelopmentAgent))
+	assert.Equal(t, 4, s.Current().Version)
+
+	snap, err := s.Rollback(1)
+	require.NoError(t, err)
+	assert.Equal(t, 5, snap.Version)
+	assert.Equal(t, 1, snap.RolledBackTo)
+	assert.Equal(t, agents.AnalysisAgent, s.Route("", agents.AnalysisAgent))
+	assert.Empty(t, guard.Model())
+	assert.Empty(t, guard.Fallbacks()["llama-3.3-70b-versatile"])
+	assert.Equal(t, baseline, guard.Timeout(agents.DevelopmentAgent))
+
+	publish(t, s, KindRollback, map[string]int{"version": 4})
+	assert.Equal(t, 4*time.Minute, guard.Timeout(agents.DevelopmentAgent), "rollbacks can be rolled back")
+}
+
+func TestStore_RejectsInvalidUpdates(t *testing.T) {
+	s := NewStore(agents.NewLLMGuard(), nil)
+
+	publish(t, s, KindTimeouts, map[string]interface{}{"timeouts": map[string]string{"development": "soon"}})
+	publish(t, s, "add_validation", map[string]interface{}{"validation_rules": []string{"schema_check"}})
+	publish(t, s, KindRouting, map[string]string{"new_agent": "strategy"})
+	s.Handle([]byte(`{"type":"config_update","target":"monitoring","update":{"kind":"timeouts"}}`))
+	s.Handle([]byte(`not json`))
+	assert.Equal(t, 1, s.Current().Version)
+
+	_, err := s.Apply(Update{Kind: "add_validation", Config: json.RawMessage(`{}`)})
+	assert.ErrorIs(t, err, ErrUnknownKind)
+	_, err = s.Rollback(7)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestStore_HandlesEvents(t *testing.T) {
+	guard := agents.NewLLMGuard()
+	s := NewStore(guard, nil)
+
+	event := func(e events.ConfigUpdate, version int) []byte {
+		env, err := events.NewEnvelope("self-improvement", e)
+		require.NoError(t, err)
+		env.Version = version
+		payload, err := json.Marshal(env)
+		require.NoError(t, err)
+		return payload
+	}
+	s.Handle(event(events.ConfigUpdate{Target: Target, Kind: KindModel, Config: json.RawMessage(`{"model":"llama-3.1-8b-instant"}`)}, 1))
+	assert.Equal(t, "llama-3.1-8b-instant", guard.Model())
+
+	s.Handle(event(events.ConfigUpdate{Target: Target, Kind: KindModel, Config: json.RawMessage(`{"model":"llama-3.3-70b-versatile"}`)}, 2))
+	s.Handle(event(events.ConfigUpdate{Target: "monitoring", Kind: KindModel, Config: json.RawMessage(`{"model":"llama-3.3-70b-versatile"}`)}, 1))
+	assert.Equal(t, "llama-3.1-8b-instant", guard.Model(), "newer schemas and other targets are ignored")
+	assert.Equal(t, 2, s.Current().Version)
+}
+
+func TestRollbackHandler(t *testing.T) {
+	s := NewStore(agents.NewLLMGuard(), nil)
+	publish(t, s, KindRouting, map[string]string{"old_agent": "analysis", "new_agent": "strategy"})
+
+	w := httptest.NewRecorder()
+	RollbackHandler(s)(w, httptest.NewRequest(http.MethodPost, "/api/config/live/rollback?version=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, agents.AnalysisAgent, s.Route("", agents.AnalysisAgent))
+
+	w = httptest.NewRecorder()
+	RollbackHandler(s)(w, httptest.NewRequest(http.MethodPost, "/api/config/live/rollback?version=9", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	Handler(s)(w, httptest.NewRequest(http.MethodGet, "/api/config/live", nil))
+	var body struct {
+		Current *Snapshot   `json:"current"`
+		History []*Snapshot `json:"history"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, 3, body.Current.Version)
+	assert.Len(t, body.History, 3)
+}
+
+func TestStore_RouteByTaskType(t *testing.T) {
+	s := NewStore(agents.NewLLMGuard(), nil)
+	publish(t, s, KindRouting, map[string]string{"task_type": "implementation", "old_agent": "analysis", "new_agent": "strategy"})
+	publish(t, s, KindRouting, map[string]string{"old_agent": "analysis", "new_agent": "architect"})
+
+	assert.Equal(t, agents.StrategyAgent, s.Route("implementation", agents.AnalysisAgent))
+	assert.Equal(t, agents.ArchitectAgent, s.Route("review", agents.AnalysisAgent), "falls back to the rule for every task type")
+	assert.Equal(t, agents.ArchitectAgent, s.Route("", agents.AnalysisAgent))
+
+	publish(t, s, KindRouting, map[string]string{"task_type": "implementation", "old_agent": "analysis"})
+	assert.Equal(t, agents.ArchitectAgent, s.Route("implementation", agents.AnalysisAgent))
+}
+
+func TestStore_LoadRules(t *testing.T) {
+	client, mock := redismock.NewClientMock()
+	mock.ExpectHGetAll(RulesKey).SetVal(map[string]string{
+		"old_agent":                             "quality",
+		"new_agent":                             "monitoring",
+		RuleField("implementation", "analysis"): "strategy",
+	})
+
+	s := NewStore(agents.NewLLMGuard(), nil)
+	publish(t, s, KindRouting, map[string]string{"old_agent": "deployment", "new_agent": "monitoring"})
+	_, err := s.LoadRules(context.Background(), client)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, agents.StrategyAgent, s.Route("implementation", agents.AnalysisAgent))
+	assert.Equal(t, agents.MonitoringAgent, s.Route("review", agents.QualityAgent), "legacy single-rule hash")
+	assert.Equal(t, agents.DeploymentAgent, s.Route("", agents.DeploymentAgent), "the stored rules replace earlier ones")
+
+	mock.ExpectHGetAll(RulesKey).SetVal(map[string]string{"analysis": "strategy"})
+	_, err = s.LoadRules(context.Background(), client)
+	assert.Error(t, err)
+	assert.Equal(t, agents.StrategyAgent, s.Route("implementation", agents.AnalysisAgent))
+}
+
+func TestParseRuleField(t *testing.T) {
+	taskType, agent, ok := ParseRuleField(RuleField("", agents.AnalysisAgent))
+	assert.True(t, ok)
+	assert.Empty(t, taskType)
+	assert.Equal(t, agents.AnalysisAgent, agent)
+
+	_, _, ok = ParseRuleField("analysis")
+	assert.False(t, ok)
+}
@@ -0,0 +1,68 @@
+package liveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// RulesKey is the Redis hash of agent routing rules written by the
+// self-improvement engine. Each field is a RuleField and each value the
+// agent that takes over its steps.
+const RulesKey = "agent_routing_rules"
+
+// AnyTaskType scopes a rule to every task type
+const AnyTaskType = "*"
+
+// RuleField names the rule routing agentType's steps in tasks of taskType.
+// An empty taskType matches every task type.
+func RuleField(taskType string, agentType agents.AgentType) string {
+	if taskType == "" {
+		taskType = AnyTaskType
+	}
+	return taskType + ":" + string(agentType)
+}
+
+// ParseRuleField splits a RuleField into its task type and agent
+func ParseRuleField(field string) (taskType string, agentType agents.AgentType, ok bool) {
+	i := strings.LastIndex(field, ":")
+	if i <= 0 || i == len(field)-1 {
+		return "", "", false
+	}
+	taskType = field[:i]
+	if taskType == AnyTaskType {
+		taskType = ""
+	}
+	return taskType, agents.AgentType(field[i+1:]), true
+}
+
+// LoadRules replaces the routing rules with the contents of RulesKey, so
+// swaps applied before this process started still take effect. Hashes
+// written by older engines hold a single old_agent/new_agent pair, which
+// is read as a rule for every task type.
+func (s *Store) LoadRules(ctx context.Context, client redis.UniversalClient) (*Snapshot, error) {
+	fields, err := client.HGetAll(ctx, RulesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string]agents.AgentType, len(fields))
+	if from, to := fields["old_agent"], fields["new_agent"]; from != "" && to != "" && from != to {
+		rules[RuleField("", agents.AgentType(from))] = agents.AgentType(to)
+	}
+	for field, to := range fields {
+		if field == "old_agent" || field == "new_agent" {
+			continue
+		}
+		rules[field] = agents.AgentType(to)
+	}
+
+	raw, err := json.Marshal(map[string]interface{}{"rules": rules})
+	if err != nil {
+		return nil, err
+	}
+	return s.Apply(Update{Kind: KindRules, Config: raw})
+}
@@ -0,0 +1,111 @@
+package liveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/events"
+	"go.uber.org/zap"
+)
+
+// Handle applies one event published on Channel, or a bare Message from
+// publishers predating typed events. Messages for other targets are
+// ignored; invalid updates are logged and leave the current snapshot in
+// effect.
+func (s *Store) Handle(payload []byte) {
+	msg, err := parseMessage(payload)
+	if err != nil {
+		s.logger.Warn("Ignoring malformed config update", zap.Error(err))
+		return
+	}
+	if msg.Target != Target {
+		return
+	}
+	if _, err := s.Apply(msg.Update); err != nil {
+		s.logger.Warn("Rejected config update",
+			zap.String("kind", msg.Update.Kind),
+			zap.Error(err))
+	}
+}
+
+// parseMessage reads a ConfigUpdate event, or a bare Message
+func parseMessage(payload []byte) (Message, error) {
+	env, err := events.Parse(payload)
+	if errors.Is(err, events.ErrNotEnvelope) {
+		var msg Message
+		err := json.Unmarshal(payload, &msg)
+		return msg, err
+	}
+	var update events.ConfigUpdate
+	if err := env.Decode(&update); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Type:   env.Type,
+		Target: update.Target,
+		Update: Update{Kind: update.Kind, Config: update.Config},
+	}, nil
+}
+
+// Subscribe loads the stored routing rules, then applies the updates
+// published on Channel until ctx is done. Rules are loaded after the
+// subscription is confirmed so no swap published in between is lost.
+func (s *Store) Subscribe(ctx context.Context, client redis.UniversalClient) error {
+	sub := client.Subscribe(ctx, Channel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	if _, err := s.LoadRules(ctx, client); err != nil {
+		s.logger.Warn("Failed to load agent routing rules", zap.Error(err))
+	}
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			s.Handle([]byte(msg.Payload))
+		}
+	}
+}
+
+// Handler serves GET /api/config/live: the current snapshot and the
+// history available for rollback
+func Handler(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"current": s.Current(),
+			"history": s.History(),
+		})
+	}
+}
+
+// RollbackHandler serves POST /api/config/live/rollback?version=<n>
+func RollbackHandler(s *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, err := strconv.Atoi(r.URL.Query().Get("version"))
+		if err != nil {
+			apierror.Write(w, r, apierror.Invalid("invalid version",
+				apierror.FieldError{Field: "version", Message: "must be a snapshot version"}))
+			return
+		}
+		snap, err := s.Rollback(version)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snap)
+	}
+}
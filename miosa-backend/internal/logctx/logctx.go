@@ -0,0 +1,104 @@
+// Package logctx carries a zap logger through contexts, enriched as work
+// narrows from a workflow to a step to an LLM call, so every line logged
+// under a context has the same workflow_id, tenant_id, step, agent and
+// model fields whichever package logs it.
+package logctx
+
+import (
+	"context"
+	"time"
+
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Field names shared by every enriched log line
+const (
+	FieldWorkflowID = "workflow_id"
+	FieldTenantID   = "tenant_id"
+	FieldStep       = "step"
+	FieldAgent      = "agent"
+	FieldModel      = "model"
+)
+
+// Sampling of per-file messages: of each message and level, the first
+// SampleFirst lines per SampleTick are logged, then every SampleThereafter-th
+const (
+	SampleTick       = time.Second
+	SampleFirst      = 10
+	SampleThereafter = 100
+)
+
+type contextKey struct{}
+
+// loggers pairs a logger with its sampled variant. The sampled logger is
+// derived once, so its counters are shared by everything logging under
+// the context.
+type loggers struct {
+	logger  *zap.Logger
+	sampled *zap.Logger
+}
+
+// NewContext returns a context carrying logger
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	sampled := logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, SampleTick, SampleFirst, SampleThereafter)
+	}))
+	return context.WithValue(ctx, contextKey{}, loggers{logger: logger, sampled: sampled})
+}
+
+// From returns the logger in ctx, or zap's global logger if there is none
+func From(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(contextKey{}).(loggers); ok {
+		return l.logger
+	}
+	return zap.L()
+}
+
+// FromOr returns the logger in ctx, or fallback if there is none, for
+// components that keep their own logger for calls made outside a workflow
+func FromOr(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if l, ok := ctx.Value(contextKey{}).(loggers); ok {
+		return l.logger
+	}
+	return fallback
+}
+
+// Sampled returns the sampled logger in ctx, for messages logged once per
+// file or chunk that would otherwise flood the logs on large projects
+func Sampled(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(contextKey{}).(loggers); ok {
+		return l.sampled
+	}
+	return zap.L()
+}
+
+// With returns a context whose loggers add fields to every line
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	l, ok := ctx.Value(contextKey{}).(loggers)
+	if !ok {
+		return NewContext(ctx, zap.L().With(fields...))
+	}
+	return context.WithValue(ctx, contextKey{}, loggers{logger: l.logger.With(fields...), sampled: l.sampled.With(fields...)})
+}
+
+// Workflow returns a context logging the workflow, its tenant when known,
+// and ctx's request ID
+func Workflow(ctx context.Context, workflowID, tenantID string) context.Context {
+	fields := []zap.Field{zap.String(FieldWorkflowID, workflowID), requestid.Field(ctx)}
+	if tenantID != "" {
+		fields = append(fields, zap.String(FieldTenantID, tenantID))
+	}
+	return With(ctx, fields...)
+}
+
+// Step returns a context logging the workflow step and the agent running it
+func Step(ctx context.Context, step int, agent string) context.Context {
+	return With(ctx, zap.Int(FieldStep, step), zap.String(FieldAgent, agent))
+}
+
+// Model returns a context logging the model a call is made to
+func Model(ctx context.Context, model string) context.Context {
+	return With(ctx, zap.String(FieldModel, model))
+}
@@ -0,0 +1,60 @@
+package logctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWorkflowStepFields(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	ctx = NewContext(ctx, zap.New(core))
+	ctx = Workflow(ctx, "wf-1", "tenant-1")
+	step := Model(Step(ctx, 3, "development"), "llama-3.3-70b-versatile")
+
+	From(step).Info("step line")
+	From(ctx).Info("workflow line")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{
+		FieldWorkflowID: "wf-1",
+		FieldTenantID:   "tenant-1",
+		"request_id":    "req-1",
+		FieldStep:       int64(3),
+		FieldAgent:      "development",
+		FieldModel:      "llama-3.3-70b-versatile",
+	}, entries[0].ContextMap())
+	assert.NotContains(t, entries[1].ContextMap(), FieldStep, "narrowing does not leak into the parent context")
+
+	// Workflows without a tenant omit the field
+	From(Workflow(NewContext(context.Background(), zap.New(core)), "wf-2", "")).Info("no tenant")
+	assert.NotContains(t, logs.All()[2].ContextMap(), FieldTenantID)
+}
+
+func TestSampled(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := NewContext(context.Background(), zap.New(core))
+	file := With(ctx, zap.String("file", "main.go"))
+
+	for i := 0; i < SampleFirst+50; i++ {
+		Sampled(file).Debug("Analyzed file")
+	}
+	assert.Equal(t, SampleFirst, logs.FilterMessage("Analyzed file").Len(), "contexts derived from one logger share sampling counters")
+
+	for i := 0; i < SampleFirst+50; i++ {
+		From(file).Debug("Unsampled")
+	}
+	assert.Equal(t, SampleFirst+50, logs.FilterMessage("Unsampled").Len())
+}
+
+func TestFromWithoutLogger(t *testing.T) {
+	assert.Equal(t, zap.L(), From(context.Background()))
+	assert.NotNil(t, From(With(context.Background(), zap.String("k", "v"))))
+}
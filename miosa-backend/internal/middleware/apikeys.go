@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix is prepended to every generated key so leaked keys are easy
+// to recognise in logs and secret scanners
+const APIKeyPrefix = "miosa_"
+
+// ErrAPIKeyNotFound is returned when no key matches the lookup
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey is a machine-to-machine credential stored in the api_keys table.
+// Only the SHA-256 hash of the key is persisted.
+type APIKey struct {
+	ID               uuid.UUID  `json:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id"`
+	WorkspaceID      uuid.UUID  `json:"workspace_id,omitempty"`
+	CreatedBy        uuid.UUID  `json:"created_by"`
+	Name             string     `json:"name"`
+	Prefix           string     `json:"prefix"`
+	Scopes           []string   `json:"scopes"`
+	RateLimitPerHour int        `json:"rate_limit_per_hour,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	UsageCount       int        `json:"usage_count"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Active reports whether the key may still be used
+func (k *APIKey) Active() bool {
+	if k.Status != "active" {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// APIKeyStore manages API keys in Postgres
+type APIKeyStore struct {
+	db *sql.DB
+}
+
+// NewAPIKeyStore creates a new API key store
+func NewAPIKeyStore(db *sql.DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+// GenerateAPIKey returns a new random plaintext key
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return APIKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 hash stored for a key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create stores a new key and returns it together with the plaintext value.
+// The plaintext is never stored and cannot be retrieved again.
+func (s *APIKeyStore) Create(ctx context.Context, key *APIKey) (string, error) {
+	for _, scope := range key.Scopes {
+		if !isKnownScope(scope) {
+			return "", fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	plaintext, err := GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode scopes: %w", err)
+	}
+
+	key.Prefix = plaintext[:len(APIKeyPrefix)+8]
+	key.Status = "active"
+
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (tenant_id, workspace_id, created_by, key_name, key_hash,
+			key_prefix, scopes, rate_limit_per_hour, expires_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`,
+		key.TenantID, nullUUID(key.WorkspaceID), key.CreatedBy, key.Name, HashAPIKey(plaintext),
+		key.Prefix, scopes, nullInt(key.RateLimitPerHour), key.ExpiresAt, key.Status,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to store api key: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// List returns all keys belonging to a tenant, newest first
+func (s *APIKeyStore) List(ctx context.Context, tenantID uuid.UUID) ([]*APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Lookup finds a key by its hash
+func (s *APIKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1`, hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// Revoke marks a tenant's key as revoked
+func (s *APIKeyStore) Revoke(ctx context.Context, tenantID, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET status = 'revoked', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Touch records a use of the key
+func (s *APIKeyStore) Touch(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP, usage_count = usage_count + 1
+		WHERE id = $1`, id)
+	return err
+}
+
+const apiKeyColumns = `id, tenant_id, workspace_id, created_by, key_name, key_prefix, scopes,
+	rate_limit_per_hour, expires_at, last_used_at, usage_count, status, created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var (
+		key         APIKey
+		workspaceID uuid.NullUUID
+		scopes      []byte
+		rateLimit   sql.NullInt64
+		usageCount  sql.NullInt64
+		status      sql.NullString
+		expiresAt   sql.NullTime
+		lastUsedAt  sql.NullTime
+	)
+	err := row.Scan(&key.ID, &key.TenantID, &workspaceID, &key.CreatedBy, &key.Name, &key.Prefix,
+		&scopes, &rateLimit, &expiresAt, &lastUsedAt, &usageCount, &status, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scopes) > 0 {
+		if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+			return nil, fmt.Errorf("failed to decode scopes: %w", err)
+		}
+	}
+	if workspaceID.Valid {
+		key.WorkspaceID = workspaceID.UUID
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	key.RateLimitPerHour = int(rateLimit.Int64)
+	key.UsageCount = int(usageCount.Int64)
+	key.Status = status.String
+	return &key, nil
+}
+
+func isKnownScope(scope string) bool {
+	for _, s := range KnownScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func nullUUID(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id
+}
+
+func nullInt(n int) interface{} {
+	if n <= 0 {
+		return nil
+	}
+	return n
+}
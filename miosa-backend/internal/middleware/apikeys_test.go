@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capture matches any string argument and keeps it for later assertions
+type capture struct{ value *string }
+
+func (c capture) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*c.value = s
+	return ok
+}
+
+func apiKeyRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "tenant_id", "workspace_id", "created_by", "key_name", "key_prefix", "scopes",
+		"rate_limit_per_hour", "expires_at", "last_used_at", "usage_count", "status", "created_at"})
+}
+
+func TestHashAPIKey(t *testing.T) {
+	key, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+	assert.Len(t, key, len(APIKeyPrefix)+64)
+
+	other, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	hash := HashAPIKey(key)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{64}$`), hash)
+	assert.Equal(t, hash, HashAPIKey(key), "hashing is deterministic")
+	assert.NotEqual(t, hash, HashAPIKey(other))
+	assert.NotContains(t, hash, key)
+}
+
+func TestAPIKeyStore_CreateStoresOnlyTheHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewAPIKeyStore(db)
+
+	var hash, prefix string
+	id, tenant, user := uuid.New(), uuid.New(), uuid.New()
+	mock.ExpectQuery("INSERT INTO api_keys").
+		WithArgs(tenant, nil, user, "ci", capture{&hash}, capture{&prefix}, []byte(`["agents:read"]`), 100, nil, "active").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(id, time.Now()))
+
+	key := &APIKey{TenantID: tenant, CreatedBy: user, Name: "ci", Scopes: []string{ScopeAgentsRead}, RateLimitPerHour: 100}
+	plaintext, err := store.Create(context.Background(), key)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, HashAPIKey(plaintext), hash)
+	assert.Equal(t, plaintext[:len(APIKeyPrefix)+8], prefix)
+	assert.Equal(t, id, key.ID)
+	assert.True(t, key.Active())
+}
+
+func TestAPIKeyStore_CreateRejectsUnknownScopes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewAPIKeyStore(db).Create(context.Background(), &APIKey{Name: "ci", Scopes: []string{"root"}})
+	assert.ErrorContains(t, err, "unknown scope: root")
+	assert.NoError(t, mock.ExpectationsWereMet(), "nothing is stored")
+}
+
+func TestAPIKeyStore_Lookup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewAPIKeyStore(db)
+
+	id, tenant := uuid.New(), uuid.New()
+	expires := time.Now().Add(time.Hour)
+	mock.ExpectQuery("FROM api_keys").WithArgs("found").WillReturnRows(apiKeyRows().
+		AddRow(id, tenant, nil, uuid.New(), "ci", "miosa_abcd1234", []byte(`["agents:read","chat:write"]`), 50, expires, nil, 3, "active", time.Now()))
+	mock.ExpectQuery("FROM api_keys").WithArgs("missing").WillReturnRows(apiKeyRows())
+
+	key, err := store.Lookup(context.Background(), "found")
+	require.NoError(t, err)
+	assert.Equal(t, id, key.ID)
+	assert.Equal(t, tenant, key.TenantID)
+	assert.Equal(t, uuid.Nil, key.WorkspaceID)
+	assert.Equal(t, []string{ScopeAgentsRead, ScopeChatWrite}, key.Scopes)
+	assert.Equal(t, 50, key.RateLimitPerHour)
+	assert.Equal(t, 3, key.UsageCount)
+	assert.Nil(t, key.LastUsedAt)
+	assert.True(t, key.Active())
+
+	_, err = store.Lookup(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKey_Active(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	assert.False(t, (&APIKey{Status: "revoked"}).Active())
+	assert.False(t, (&APIKey{Status: "active", ExpiresAt: &past}).Active())
+	assert.True(t, (&APIKey{Status: "active"}).Active())
+}
@@ -1,9 +1,22 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"go.uber.org/zap"
 )
 
 var (
@@ -20,6 +33,364 @@ func init() {
 	prometheus.MustRegister(apiRequests)
 }
 
+// Scopes understood by the auth middleware
+const (
+	ScopeAdminAll           = "admin:all"
+	ScopeAgentsRead         = "agents:read"
+	ScopeOrchestrateExecute = "orchestrate:execute"
+	ScopeQualityRead        = "quality:read"
+	ScopeChatWrite          = "chat:write"
+	ScopeKeysManage         = "keys:manage"
+)
+
+// KnownScopes lists every scope that may be granted to a token or API key
+var KnownScopes = []string{
+	ScopeAdminAll,
+	ScopeAgentsRead,
+	ScopeOrchestrateExecute,
+	ScopeQualityRead,
+	ScopeChatWrite,
+	ScopeKeysManage,
+}
+
+// routeScopes maps path prefixes to the scope they require. A prefix
+// matches the path itself and the paths below it; the longest matching
+// prefix wins and paths without a match only require authentication.
+var routeScopes = map[string]string{
+	"/api/admin":                 ScopeAdminAll,
+	"/api/keys":                  ScopeKeysManage,
+	"/api/agents":                ScopeAgentsRead,
+	"/api/agents/execute":        ScopeOrchestrateExecute,
+	"/api/commands":              ScopeAgentsRead,
+	"/api/catalog":               ScopeAgentsRead,
+	"/api/orchestrate":           ScopeOrchestrateExecute,
+	"/api/collaboration/execute": ScopeOrchestrateExecute,
+	"/api/quality":               ScopeQualityRead,
+	"/api/chat":                  ScopeChatWrite,
+}
+
+// Claims are the JWT claims issued to users
+type Claims struct {
+	UserID      uuid.UUID `json:"user_id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Scopes      []string  `json:"scopes"`
+	jwt.RegisteredClaims
+}
+
+// AuthMiddleware authenticates requests with JWT bearer tokens or API keys
+// and populates the task context used by downstream middleware
+type AuthMiddleware struct {
+	config    *config.AuthConfig
+	db        *sql.DB
+	redis     redis.UniversalClient
+	logger    *zap.Logger
+	apiKeys   *APIKeyStore
+	skipPaths []string
+}
+
+// NewAuthMiddleware creates a new auth middleware. db and redis may be nil;
+// tenant verification, API keys and token revocation are then unavailable.
+func NewAuthMiddleware(cfg *config.AuthConfig, db *sql.DB, redisClient redis.UniversalClient, logger *zap.Logger) *AuthMiddleware {
+	m := &AuthMiddleware{
+		config:    cfg,
+		db:        db,
+		redis:     redisClient,
+		logger:    logger,
+		skipPaths: []string{"/health", "/metrics"},
+	}
+	if db != nil {
+		m.apiKeys = NewAPIKeyStore(db)
+	}
+	return m
+}
+
+// APIKeys returns the store backing API key authentication, or nil when no
+// database is configured
+func (m *AuthMiddleware) APIKeys() *APIKeyStore {
+	return m.apiKeys
+}
+
+// Handle authenticates the request
+func (m *AuthMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, path := range m.skipPaths {
+			if c.Request.URL.Path == path {
+				c.Next()
+				return
+			}
+		}
+
+		if key := extractAPIKey(c.Request); key != "" {
+			m.handleAPIKey(c, key)
+			return
+		}
+
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			m.abort(c, http.StatusUnauthorized, "Missing or invalid authorization header")
+			return
+		}
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+		claims, err := m.parseToken(tokenString)
+		if err != nil {
+			m.logger.Debug("Token validation failed", zap.Error(err))
+			m.abort(c, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		ctx := c.Request.Context()
+		if m.redis != nil {
+			revoked, err := m.redis.Exists(ctx, blacklistKey(tokenString)).Result()
+			if err != nil {
+				m.logger.Warn("Token revocation check failed", zap.Error(err))
+			} else if revoked > 0 {
+				m.abort(c, http.StatusUnauthorized, "Token has been revoked")
+				return
+			}
+		}
+
+		// Scopes narrow a token below its role; a token without any is
+		// governed by its role alone, which RBAC checks per route
+		if len(claims.Scopes) > 0 && !HasScope(claims.Scopes, requiredScope(c.Request.URL.Path)) {
+			m.abort(c, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+
+		if m.db != nil {
+			allowed, err := m.checkTenantAccess(ctx, claims.UserID, claims.TenantID)
+			if err != nil {
+				m.logger.Error("Tenant access check failed", zap.Error(err))
+				m.abort(c, http.StatusInternalServerError, "Failed to verify tenant access")
+				return
+			}
+			if !allowed {
+				m.abort(c, http.StatusForbidden, "Access denied for this tenant")
+				return
+			}
+		}
+
+		m.setContext(c, claims.UserID, claims.TenantID, claims.WorkspaceID, map[string]string{
+			"auth_method": "jwt",
+			"email":       claims.Email,
+			"role":        claims.Role,
+		})
+		c.Set("claims", claims)
+		c.Set("scopes", claims.Scopes)
+		c.Next()
+	}
+}
+
+// handleAPIKey authenticates a machine-to-machine request
+func (m *AuthMiddleware) handleAPIKey(c *gin.Context, rawKey string) {
+	if m.apiKeys == nil {
+		m.abort(c, http.StatusUnauthorized, "API key authentication is not available")
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := m.apiKeys.Lookup(ctx, HashAPIKey(rawKey))
+	if err != nil {
+		if !errors.Is(err, ErrAPIKeyNotFound) {
+			m.logger.Error("API key lookup failed", zap.Error(err))
+		}
+		m.abort(c, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+	if !key.Active() {
+		m.abort(c, http.StatusUnauthorized, "API key is revoked or expired")
+		return
+	}
+
+	if !HasScope(key.Scopes, requiredScope(c.Request.URL.Path)) {
+		m.abort(c, http.StatusForbidden, "Insufficient permissions")
+		return
+	}
+
+	go func(id uuid.UUID) {
+		touchCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.apiKeys.Touch(touchCtx, id); err != nil {
+			m.logger.Debug("Failed to record API key usage", zap.Error(err))
+		}
+	}(key.ID)
+
+	metadata := map[string]string{
+		"auth_method": "api_key",
+		"api_key_id":  key.ID.String(),
+	}
+	if key.RateLimitPerHour > 0 {
+		metadata["rate_limit_per_hour"] = fmt.Sprintf("%d", key.RateLimitPerHour)
+	}
+	m.setContext(c, key.CreatedBy, key.TenantID, key.WorkspaceID, metadata)
+	c.Set("api_key", key)
+	c.Set("scopes", key.Scopes)
+	c.Next()
+}
+
+// setContext stores the authenticated identity for downstream handlers
+func (m *AuthMiddleware) setContext(c *gin.Context, userID, tenantID, workspaceID uuid.UUID, metadata map[string]string) {
+	taskCtx := &agents.TaskContext{
+		UserID:      userID,
+		TenantID:    tenantID,
+		WorkspaceID: workspaceID,
+		SessionID:   uuid.New(),
+		Phase:       m.determinePhase(c.Request.URL.Path),
+		Memory:      make(map[string]interface{}),
+		Metadata:    metadata,
+	}
+	c.Set("task_context", taskCtx)
+	c.Set("user_id", userID.String())
+	c.Set("tenant_id", tenantID.String())
+}
+
+// abort writes an error response and stops the chain
+func (m *AuthMiddleware) abort(c *gin.Context, status int, message string) {
+	apiRequests.WithLabelValues(c.Request.Method, c.FullPath(), fmt.Sprintf("%d", status)).Inc()
+	c.JSON(status, gin.H{"error": message})
+	c.Abort()
+}
+
+// parseToken validates a signed JWT and returns its claims
+func (m *AuthMiddleware) parseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(m.config.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// checkTenantAccess verifies the user belongs to the tenant in the token
+func (m *AuthMiddleware) checkTenantAccess(ctx context.Context, userID, tenantID uuid.UUID) (bool, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE id = $1 AND tenant_id = $2`,
+		userID, tenantID,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GenerateToken signs the given claims
+func (m *AuthMiddleware) GenerateToken(claims *Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(m.config.JWTSecret))
+}
+
+// RefreshToken issues a new token with the same identity and a fresh expiry
+func (m *AuthMiddleware) RefreshToken(tokenString string) (string, error) {
+	claims, err := m.parseToken(tokenString)
+	if err != nil {
+		return "", fmt.Errorf("invalid token: %w", err)
+	}
+
+	expiry := m.config.JWTExpiry
+	if expiry == 0 {
+		expiry = 24 * time.Hour
+	}
+	now := time.Now()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiry))
+
+	return m.GenerateToken(claims)
+}
+
+// RevokeToken blacklists a token until it would have expired anyway
+func (m *AuthMiddleware) RevokeToken(ctx context.Context, tokenString string, expiry time.Duration) error {
+	if m.redis == nil {
+		return errors.New("token revocation requires redis")
+	}
+	return m.redis.Set(ctx, blacklistKey(tokenString), true, expiry).Err()
+}
+
+// determinePhase maps a request path to the MIOSA loop phase
+func (m *AuthMiddleware) determinePhase(path string) string {
+	switch {
+	case strings.Contains(path, "/consultation"):
+		return string(agents.PhaseConsultation)
+	case strings.Contains(path, "/analyze"):
+		return string(agents.PhaseAnalysis)
+	case strings.Contains(path, "/develop"):
+		return string(agents.PhaseDevelopment)
+	case strings.Contains(path, "/deploy"):
+		return string(agents.PhaseDeployment)
+	case strings.Contains(path, "/monitor"):
+		return string(agents.PhaseMonitoring)
+	default:
+		return string(agents.PhaseStrategy)
+	}
+}
+
+// extractAPIKey reads an API key from X-API-Key or "Authorization: ApiKey <key>"
+func extractAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "ApiKey ") {
+		return strings.TrimPrefix(auth, "ApiKey ")
+	}
+	return ""
+}
+
+// requiredScope returns the scope needed for a path, or "" if none
+func requiredScope(path string) string {
+	best := ""
+	scope := ""
+	for prefix, s := range routeScopes {
+		if underPrefix(path, prefix) && len(prefix) > len(best) {
+			best, scope = prefix, s
+		}
+	}
+	return scope
+}
+
+// underPrefix reports whether path is prefix or below it, so /api/agents
+// covers /api/agents/execute but not /api/agentsX
+func underPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// ScopesFromContext returns the scopes granted to the authenticated caller
+func ScopesFromContext(c *gin.Context) []string {
+	if v, exists := c.Get("scopes"); exists {
+		if scopes, ok := v.([]string); ok {
+			return scopes
+		}
+	}
+	return nil
+}
+
+// HasScope reports whether granted satisfies required; admin:all grants everything
+func HasScope(granted []string, required string) bool {
+	if required == "" {
+		return true
+	}
+	for _, s := range granted {
+		if s == required || s == ScopeAdminAll {
+			return true
+		}
+	}
+	return false
+}
+
+func blacklistKey(token string) string {
+	return fmt.Sprintf("blacklist:token:%s", token)
+}
+
 // ValidateAuth checks JWT token
 func ValidateAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -29,9 +400,9 @@ func ValidateAuth(next http.HandlerFunc) http.HandlerFunc {
 			apiRequests.WithLabelValues(r.Method, r.URL.Path, "401").Inc()
 			return
 		}
-		
+
 		// TODO: Validate JWT token
 		apiRequests.WithLabelValues(r.Method, r.URL.Path, "200").Inc()
 		next(w, r)
 	}
-}
\ No newline at end of file
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"context"
-	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"net/http"
@@ -66,7 +65,7 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 	
 	tests := []struct {
 		name           string
-		setupAuth      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock)
+		setupAuth      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) string // Returns the Authorization header it set mocks for, if any
 		authorization  string
 		path           string
 		expectedStatus int
@@ -75,7 +74,7 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 	}{
 		{
 			name: "successful JWT validation with valid token",
-			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) {
+			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) string {
 				userID := uuid.New()
 				tenantID := uuid.New()
 				workspaceID := uuid.New()
@@ -90,8 +89,8 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 				sqlMock.ExpectQuery("SELECT COUNT").
 					WithArgs(userID, tenantID).
 					WillReturnRows(rows)
+				return "Bearer " + token
 			},
-			authorization:  "Bearer " + createValidToken(uuid.New(), uuid.New(), uuid.New(), []string{"agents:read"}),
 			path:           "/api/agents",
 			expectedStatus: http.StatusOK,
 			checkContext: func(c *gin.Context) bool {
@@ -105,7 +104,7 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 		},
 		{
 			name:           "missing authorization header",
-			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) {},
+			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) string { return "" },
 			authorization:  "",
 			path:           "/api/agents",
 			expectedStatus: http.StatusUnauthorized,
@@ -113,7 +112,7 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 		},
 		{
 			name:           "invalid authorization format",
-			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) {},
+			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) string { return "" },
 			authorization:  "InvalidFormat token",
 			path:           "/api/agents",
 			expectedStatus: http.StatusUnauthorized,
@@ -121,7 +120,7 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 		},
 		{
 			name:           "expired JWT token",
-			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) {},
+			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) string { return "" },
 			authorization:  "Bearer " + createExpiredToken(),
 			path:           "/api/agents",
 			expectedStatus: http.StatusUnauthorized,
@@ -129,43 +128,45 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 		},
 		{
 			name: "blacklisted token",
-			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) {
+			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) string {
 				token := createValidToken(uuid.New(), uuid.New(), uuid.New(), []string{"agents:read"})
 				
 				// Mock Redis check - token is blacklisted
 				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s", token)).
 					SetVal(1)
+				return "Bearer " + token
 			},
-			authorization:  "Bearer " + createValidToken(uuid.New(), uuid.New(), uuid.New(), []string{"agents:read"}),
 			path:           "/api/agents",
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   `{"error":"Token has been revoked"}`,
 		},
 		{
 			name: "insufficient scopes",
-			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) {
+			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) string {
 				userID := uuid.New()
 				tenantID := uuid.New()
 				
+				token := createValidToken(userID, tenantID, uuid.New(), []string{"chat:write"})
+				
 				// Mock Redis check for blacklist
-				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s",
-					createValidToken(userID, tenantID, uuid.New(), []string{}))).
+				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s", token)).
 					SetVal(0)
+				return "Bearer " + token
 			},
-			authorization:  "Bearer " + createValidToken(uuid.New(), uuid.New(), uuid.New(), []string{}),
 			path:           "/api/agents",
 			expectedStatus: http.StatusForbidden,
 			expectedBody:   `{"error":"Insufficient permissions"}`,
 		},
 		{
 			name: "tenant access denied",
-			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) {
+			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) string {
 				userID := uuid.New()
 				tenantID := uuid.New()
 				
+				token := createValidToken(userID, tenantID, uuid.New(), []string{"agents:read"})
+				
 				// Mock Redis check for blacklist
-				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s",
-					createValidToken(userID, tenantID, uuid.New(), []string{"agents:read"}))).
+				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s", token)).
 					SetVal(0)
 				
 				// Mock database check - user doesn't have access to tenant
@@ -173,28 +174,29 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 				sqlMock.ExpectQuery("SELECT COUNT").
 					WithArgs(userID, tenantID).
 					WillReturnRows(rows)
+				return "Bearer " + token
 			},
-			authorization:  "Bearer " + createValidToken(uuid.New(), uuid.New(), uuid.New(), []string{"agents:read"}),
 			path:           "/api/agents",
 			expectedStatus: http.StatusForbidden,
 			expectedBody:   `{"error":"Access denied for this tenant"}`,
 		},
 		{
 			name:           "skip auth for health endpoint",
-			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) {},
+			setupAuth:      func(*AuthMiddleware, sqlmock.Sqlmock, redismock.ClientMock) string { return "" },
 			authorization:  "",
 			path:           "/health",
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name: "admin override with admin:all scope",
-			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) {
+			setupAuth: func(auth *AuthMiddleware, sqlMock sqlmock.Sqlmock, redisMock redismock.ClientMock) string {
 				userID := uuid.New()
 				tenantID := uuid.New()
 				
+				token := createValidToken(userID, tenantID, uuid.New(), []string{"admin:all"})
+				
 				// Mock Redis check for blacklist
-				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s",
-					createValidToken(userID, tenantID, uuid.New(), []string{"admin:all"}))).
+				redisMock.ExpectExists(fmt.Sprintf("blacklist:token:%s", token)).
 					SetVal(0)
 				
 				// Mock database check for tenant access
@@ -202,8 +204,8 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 				sqlMock.ExpectQuery("SELECT COUNT").
 					WithArgs(userID, tenantID).
 					WillReturnRows(rows)
+				return "Bearer " + token
 			},
-			authorization:  "Bearer " + createValidToken(uuid.New(), uuid.New(), uuid.New(), []string{"admin:all"}),
 			path:           "/api/admin",
 			expectedStatus: http.StatusOK,
 		},
@@ -227,7 +229,10 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 			auth := NewAuthMiddleware(authConfig, db, redisClient, logger)
 			
 			// Setup mocks
-			tt.setupAuth(auth, sqlMock, redisMock)
+			authorization := tt.authorization
+			if header := tt.setupAuth(auth, sqlMock, redisMock); header != "" {
+				authorization = header
+			}
 			
 			// Create test router
 			router := gin.New()
@@ -247,8 +252,8 @@ func TestAuthMiddleware_Handle(t *testing.T) {
 			
 			// Create test request
 			req := httptest.NewRequest("GET", tt.path, nil)
-			if tt.authorization != "" {
-				req.Header.Set("Authorization", tt.authorization)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
 			}
 			
 			// Perform request
@@ -397,6 +402,183 @@ func TestAuthMiddleware_DeterminePhase(t *testing.T) {
 	}
 }
 
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/agents", ScopeAgentsRead},
+		{"/api/agents/", ScopeAgentsRead},
+		{"/api/agents/registered", ScopeAgentsRead},
+		{"/api/agents/execute", ScopeOrchestrateExecute},
+		{"/api/agents/execute/batch", ScopeOrchestrateExecute},
+		{"/api/agentsX", ""},
+		{"/api/agents-admin", ""},
+		{"/api/keys", ScopeKeysManage},
+		{"/api/keys/123", ScopeKeysManage},
+		{"/api/keystore", ""},
+		{"/api/chat/sessions", ScopeChatWrite},
+		{"/api/chatter", ""},
+		{"/api", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, requiredScope(tt.path))
+		})
+	}
+}
+
+func TestAuthMiddleware_APIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		scopes         string
+		status         string
+		path           string
+		expectedStatus int
+	}{
+		{"granted scope", `["agents:read"]`, "active", "/api/agents", http.StatusOK},
+		{"missing scope", `["chat:write"]`, "active", "/api/agents", http.StatusForbidden},
+		{"more specific route needs its own scope", `["agents:read"]`, "active", "/api/agents/execute", http.StatusForbidden},
+		{"admin grants everything", `["admin:all"]`, "active", "/api/keys", http.StatusOK},
+		{"prefix of another route is not covered", `["chat:write"]`, "active", "/api/agentsX", http.StatusOK},
+		{"revoked key", `["agents:read"]`, "revoked", "/api/agents", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			raw := "miosa_test-key"
+			sqlMock.ExpectQuery("FROM api_keys").
+				WithArgs(HashAPIKey(raw)).
+				WillReturnRows(apiKeyRows().AddRow(uuid.New(), uuid.New(), nil, uuid.New(), "ci", "miosa_test-key",
+					[]byte(tt.scopes), 25, nil, nil, 0, tt.status, time.Now()))
+
+			auth := NewAuthMiddleware(&config.AuthConfig{JWTSecret: "test-secret"}, db, nil, zap.NewNop())
+			var metadata map[string]string
+			router := gin.New()
+			router.Use(auth.Handle())
+			router.GET("/*path", func(c *gin.Context) {
+				taskCtx, _ := c.Get("task_context")
+				metadata = taskCtx.(*agents.TaskContext).Metadata
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("X-API-Key", raw)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "api_key", metadata["auth_method"])
+				assert.Equal(t, "25", metadata["rate_limit_per_hour"])
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_RoleOnlyJWT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := "test-secret"
+	tests := []struct {
+		name           string
+		role           string
+		scopes         []string
+		expectedStatus int
+	}{
+		{"developer without scopes claim", "developer", nil, http.StatusOK},
+		{"viewer without scopes claim", "viewer", nil, http.StatusForbidden},
+		{"scopes still narrow the role", "developer", []string{ScopeAgentsRead}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			userID, tenantID := uuid.New(), uuid.New()
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+				UserID:   userID,
+				TenantID: tenantID,
+				Role:     tt.role,
+				Scopes:   tt.scopes,
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				},
+			}).SignedString([]byte(secret))
+			require.NoError(t, err)
+			sqlMock.ExpectQuery("SELECT COUNT").
+				WithArgs(userID, tenantID).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+			auth := NewAuthMiddleware(&config.AuthConfig{JWTSecret: secret}, db, nil, zap.NewNop())
+			router := gin.New()
+			router.Use(auth.Handle())
+			router.POST("/api/agents/execute", NewRBACMiddleware(zap.NewNop()).Require(PermOrchestrateExecute), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/agents/execute", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestAuthMiddleware_ChatWriteKeyCreatesChatSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"create chat session", http.MethodPost, "/api/chat/sessions", http.StatusCreated},
+		{"send chat message", http.MethodPost, "/api/chat/sessions/abc/messages", http.StatusCreated},
+		{"orchestrate is still denied", http.MethodPost, "/api/agents/execute", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			raw := "miosa_chat-key"
+			sqlMock.ExpectQuery("FROM api_keys").
+				WithArgs(HashAPIKey(raw)).
+				WillReturnRows(apiKeyRows().AddRow(uuid.New(), uuid.New(), nil, uuid.New(), "chat", "miosa_chat-key",
+					[]byte(`["chat:write"]`), 0, nil, nil, 0, "active", time.Now()))
+
+			auth := NewAuthMiddleware(&config.AuthConfig{JWTSecret: "test-secret"}, db, nil, zap.NewNop())
+			rbac := NewRBACMiddleware(zap.NewNop())
+			created := func(c *gin.Context) { c.Status(http.StatusCreated) }
+			router := gin.New()
+			router.Use(auth.Handle())
+			chatWrite := rbac.RequireOrScope(PermOrchestrateExecute, ScopeChatWrite)
+			router.POST("/api/chat/sessions", chatWrite, created)
+			router.POST("/api/chat/sessions/:id/messages", chatWrite, created)
+			router.POST("/api/agents/execute", rbac.Require(PermOrchestrateExecute), created)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-API-Key", raw)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+}
+
 // Mock AnyTime for SQL queries
 type AnyTime struct{}
 
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func signTestToken(t *testing.T, secret string, claims Claims) string {
+	claims.RegisteredClaims = jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthMiddleware_RequireHTTP(t *testing.T) {
+	secret := "test-secret"
+	tenantID := uuid.New()
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"no credentials", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer not-a-token", http.StatusUnauthorized},
+		{"role lacks permission", "Bearer " + signTestToken(t, secret, Claims{TenantID: tenantID, Role: "developer"}), http.StatusForbidden},
+		{"role holds permission", "Bearer " + signTestToken(t, secret, Claims{TenantID: tenantID, Role: "operator"}), http.StatusOK},
+		{"wrong secret", "Bearer " + signTestToken(t, "other", Claims{TenantID: tenantID, Role: "admin"}), http.StatusUnauthorized},
+	}
+
+	auth := NewAuthMiddleware(&config.AuthConfig{JWTSecret: secret}, nil, nil, zap.NewNop())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tenant uuid.UUID
+			handler := auth.RequireHTTP(PermAuditRead, func(w http.ResponseWriter, r *http.Request) {
+				tenant = TenantOf(r)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/audit", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tenantID, tenant)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_RequireHTTPWithAPIKey(t *testing.T) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	raw := "miosa_admin-key"
+	keyID, tenantID := uuid.New(), uuid.New()
+	sqlMock.ExpectQuery("FROM api_keys").
+		WithArgs(HashAPIKey(raw)).
+		WillReturnRows(apiKeyRows().AddRow(keyID, tenantID, nil, uuid.New(), "ops", "miosa_admin-key",
+			[]byte(`["admin:all"]`), 0, nil, nil, 0, "active", time.Now()))
+
+	auth := NewAuthMiddleware(&config.AuthConfig{JWTSecret: "test-secret"}, db, nil, zap.NewNop())
+	var principal *Principal
+	handler := auth.RequireHTTP(PermTenantsManage, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFromRequest(r)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/workspaces/purge", nil)
+	req.Header.Set("X-API-Key", raw)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, principal)
+	assert.Equal(t, RoleAdmin, principal.Role)
+	assert.Equal(t, tenantID, principal.TenantID)
+	assert.Equal(t, keyID.String(), principal.TaskContext().Metadata["api_key_id"])
+}
+
+func TestAuthMiddleware_RequireHTTPFailsClosedWithoutAuth(t *testing.T) {
+	var auth *AuthMiddleware
+	called := false
+	handler := auth.RequireHTTP(PermWorkflowsRead, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/audit", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.False(t, called)
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
@@ -104,6 +106,11 @@ func NewLoggingMiddleware(config *LoggingConfig) (*LoggingMiddleware, error) {
 	if config.EnableOTel {
 		m.tracer = otel.Tracer("miosa-backend")
 		m.propagator = otel.GetTextMapPropagator()
+		// The global propagator is a no-op until one is installed; honor
+		// W3C traceparent headers regardless
+		if len(m.propagator.Fields()) == 0 {
+			m.propagator = propagation.TraceContext{}
+		}
 	}
 
 	// Compile redaction patterns for GDPR compliance
@@ -148,35 +155,11 @@ func (m *LoggingMiddleware) Handle() gin.HandlerFunc {
 		c.Set("trace_context", ctx)
 		c.Header("X-Trace-ID", traceID)
 
-		// Get tenant and user context from auth middleware
-		var tenantID, userID, workspaceID string
-		if taskCtx, exists := c.Get("task_context"); exists {
-			if tCtx, ok := taskCtx.(*agents.TaskContext); ok {
-				tenantID = tCtx.TenantID.String()
-				userID = tCtx.UserID.String()
-				workspaceID = tCtx.WorkspaceID.String()
-				// Add trace ID to task context metadata
-				tCtx.Metadata["trace_id"] = traceID
-				tCtx.Metadata["span_id"] = spanID
-
-				// Add to OpenTelemetry span
-				if span != nil {
-					span.SetAttributes(
-						attribute.String("tenant.id", tenantID),
-						attribute.String("user.id", userID),
-						attribute.String("workspace.id", workspaceID),
-					)
-				}
-			}
-		}
-
 		// Create request-scoped logger with context
 		reqLogger := m.logger.With(
+			requestid.Field(c.Request.Context()),
 			zap.String("trace_id", traceID),
 			zap.String("span_id", spanID),
-			zap.String("tenant_id", tenantID),
-			zap.String("user_id", userID),
-			zap.String("workspace_id", workspaceID),
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.String("query", m.redactSensitive(c.Request.URL.RawQuery)),
@@ -251,6 +234,35 @@ func (m *LoggingMiddleware) Handle() gin.HandlerFunc {
 			zap.Int("response_size", blw.body.Len()),
 		}
 
+		// Auth runs after this middleware, so the caller's task context is
+		// only known once the request has been handled
+		if taskCtx, exists := c.Get("task_context"); exists {
+			if tCtx, ok := taskCtx.(*agents.TaskContext); ok {
+				tenantID := tCtx.TenantID.String()
+				userID := tCtx.UserID.String()
+				workspaceID := tCtx.WorkspaceID.String()
+				if tCtx.Metadata == nil {
+					tCtx.Metadata = make(map[string]string)
+				}
+				// Add trace ID to task context metadata
+				tCtx.Metadata["trace_id"] = traceID
+				tCtx.Metadata["span_id"] = spanID
+
+				fields = append(fields,
+					zap.String("tenant_id", tenantID),
+					zap.String("user_id", userID),
+					zap.String("workspace_id", workspaceID),
+				)
+				if span != nil {
+					span.SetAttributes(
+						attribute.String("tenant.id", tenantID),
+						attribute.String("user.id", userID),
+						attribute.String("workspace.id", workspaceID),
+					)
+				}
+			}
+		}
+
 		// Add agent execution metrics if available
 		if agentResult, exists := c.Get("agent_result"); exists {
 			if result, ok := agentResult.(*agents.Result); ok {
@@ -346,8 +358,11 @@ func (m *LoggingMiddleware) readBody(r *http.Request) ([]byte, error) {
 		return nil, err
 	}
 
-	// Restore body for handlers
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	// Restore body for handlers, including anything past the limit
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
 
 	return body, nil
 }
@@ -588,6 +603,7 @@ func CreateLogger(level string, environment string) (*zap.Logger, error) {
 		zap.AddCaller(),
 		zap.AddCallerSkip(1), // Skip wrapper functions
 		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.WrapCore(redact.NewCore), // Strip secrets echoed by LLMs or prompts
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build logger: %w", err)
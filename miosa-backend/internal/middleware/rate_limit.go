@@ -149,6 +149,12 @@ func (m *RateLimitMiddleware) Handle() gin.HandlerFunc {
 
 		// Get rate limit for the plan
 		limit := m.getPlanLimit(plan)
+
+		// API keys carry their own hourly limit and are counted separately
+		if keyID, keyLimit := apiKeyLimit(c); keyID != "" {
+			tenantID = "apikey:" + keyID
+			limit.RequestsPerHour = keyLimit
+		}
 		
 		// Check agent-specific limits if this is an agent execution request
 		if agentType := m.extractAgentType(c); agentType != "" {
@@ -216,6 +222,25 @@ func (m *RateLimitMiddleware) Handle() gin.HandlerFunc {
 	}
 }
 
+// apiKeyLimit returns the API key ID and its hourly limit when the request
+// was authenticated with an API key that has one configured
+func apiKeyLimit(c *gin.Context) (string, int) {
+	taskCtx, exists := c.Get("task_context")
+	if !exists {
+		return "", 0
+	}
+	ctx, ok := taskCtx.(*agents.TaskContext)
+	if !ok {
+		return "", 0
+	}
+	keyID := ctx.Metadata["api_key_id"]
+	limit, err := strconv.Atoi(ctx.Metadata["rate_limit_per_hour"])
+	if keyID == "" || err != nil || limit <= 0 {
+		return "", 0
+	}
+	return keyID, limit
+}
+
 // checkRateLimit checks rate limit using Redis sliding window algorithm
 func (m *RateLimitMiddleware) checkRateLimit(ctx context.Context, key string, limit PlanLimit) (bool, int, time.Time, error) {
 	if limit.RequestsPerHour == -1 {
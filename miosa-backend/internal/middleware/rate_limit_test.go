@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// sameKey matches redis commands by name and key, ignoring the timestamps
+// the sliding window puts in the other arguments
+func sameKey(expected, actual []interface{}) error {
+	if len(expected) < 2 || len(actual) < 2 || expected[0] != actual[0] || expected[1] != actual[1] {
+		return fmt.Errorf("expected %v, got %v", expected, actual)
+	}
+	return nil
+}
+
+// expectWindow mocks one sliding window check of key that finds count
+// requests already in the window
+func expectWindow(mock redismock.ClientMock, key string, count int64) {
+	m := mock.CustomMatch(sameKey)
+	m.ExpectZRemRangeByScore(key, "0", "0").SetVal(0)
+	m.ExpectZCard(key).SetVal(count)
+	m.ExpectZAdd(key, redis.Z{}).SetVal(1)
+	m.ExpectExpire(key, time.Hour).SetVal(true)
+}
+
+func newRateLimitRouter(t *testing.T, metadata map[string]string) (*gin.Engine, redismock.ClientMock) {
+	gin.SetMode(gin.TestMode)
+	client, mock := redismock.NewClientMock()
+	config := DefaultRateLimitConfig()
+	config.EnableCircuitBreaker = false
+	m := NewRateLimitMiddleware(client, zap.NewNop(), config)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("task_context", &agents.TaskContext{TenantID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Metadata: metadata})
+	})
+	router.Use(m.Handle())
+	router.GET("/api/chat", func(c *gin.Context) { c.Status(http.StatusOK) })
+	t.Cleanup(func() { assert.NoError(t, mock.ExpectationsWereMet()) })
+	return router, mock
+}
+
+func TestRateLimitMiddleware_PerAPIKeyLimit(t *testing.T) {
+	keyID := uuid.NewString()
+	router, mock := newRateLimitRouter(t, map[string]string{
+		"auth_method":         "api_key",
+		"api_key_id":          keyID,
+		"rate_limit_per_hour": "2",
+	})
+
+	// Counted under the key rather than the tenant, against the key's limit
+	key := "ratelimit:apikey:" + keyID
+	expectWindow(mock, key, 1)
+	expectWindow(mock, key, 3)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/chat", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/chat", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestRateLimitMiddleware_KeyWithoutLimitUsesPlan(t *testing.T) {
+	router, mock := newRateLimitRouter(t, map[string]string{
+		"auth_method": "api_key",
+		"api_key_id":  uuid.NewString(),
+	})
+	expectWindow(mock, "ratelimit:00000000-0000-0000-0000-000000000001", 3)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/chat", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get("X-RateLimit-Limit"), "free plan")
+}
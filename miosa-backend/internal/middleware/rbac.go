@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Role is a tenant member's role
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleDeveloper Role = "developer"
+	RoleOperator  Role = "operator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission is an operation gated by RBAC
+type Permission string
+
+const (
+	PermWorkflowsRead       Permission = "workflows:read"
+	PermReportsRead         Permission = "reports:read"
+	PermOrchestrateExecute  Permission = "orchestrate:execute"
+	PermImprovementsApprove Permission = "improvements:approve"
+	PermDeploymentsApprove  Permission = "deployments:approve"
+	PermAuditRead           Permission = "audit:read"
+	PermTenantsManage       Permission = "tenants:manage"
+	PermAgentsManage        Permission = "agents:manage"
+)
+
+// roleRank orders roles; each role inherits the permissions of lower ones
+var roleRank = map[Role]int{
+	RoleViewer:    1,
+	RoleDeveloper: 2,
+	RoleOperator:  3,
+	RoleAdmin:     4,
+}
+
+// permissionRoles maps each permission to the minimum role that holds it
+var permissionRoles = map[Permission]Role{
+	PermWorkflowsRead:       RoleViewer,
+	PermReportsRead:         RoleViewer,
+	PermOrchestrateExecute:  RoleDeveloper,
+	PermImprovementsApprove: RoleOperator,
+	PermDeploymentsApprove:  RoleOperator,
+	PermAuditRead:           RoleOperator,
+	PermTenantsManage:       RoleAdmin,
+	PermAgentsManage:        RoleAdmin,
+}
+
+// ParseRole maps a claim value to a role. Unknown or empty values map to
+// viewer so a token without a role never gains write access.
+func ParseRole(s string) Role {
+	switch Role(s) {
+	case RoleDeveloper, RoleOperator, RoleAdmin:
+		return Role(s)
+	case "owner":
+		return RoleAdmin
+	default:
+		return RoleViewer
+	}
+}
+
+// Can reports whether the role holds the permission
+func (r Role) Can(p Permission) bool {
+	min, ok := permissionRoles[p]
+	if !ok {
+		return false
+	}
+	return roleRank[r] >= roleRank[min]
+}
+
+// RBACMiddleware enforces role-based permissions on routes
+type RBACMiddleware struct {
+	logger *zap.Logger
+}
+
+// NewRBACMiddleware creates a new RBAC middleware
+func NewRBACMiddleware(logger *zap.Logger) *RBACMiddleware {
+	return &RBACMiddleware{logger: logger}
+}
+
+// Require returns a handler that rejects requests whose role lacks p.
+// It must run after the auth middleware.
+func (m *RBACMiddleware) Require(p Permission) gin.HandlerFunc {
+	return m.require(p, "")
+}
+
+// RequireOrScope is Require, but also admits callers granted scope. It gates
+// routes an API key may be scoped to without acting as the role that holds p,
+// such as key management under keys:manage.
+func (m *RBACMiddleware) RequireOrScope(p Permission, scope string) gin.HandlerFunc {
+	return m.require(p, scope)
+}
+
+func (m *RBACMiddleware) require(p Permission, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := RoleFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+		if !role.Can(p) && (scope == "" || !HasScope(ScopesFromContext(c), scope)) {
+			m.logger.Warn("RBAC denied request",
+				zap.String("role", string(role)),
+				zap.String("permission", string(p)),
+				zap.String("path", c.Request.URL.Path))
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "Insufficient role",
+				"permission": p,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RoleFromContext resolves the caller's role from JWT claims or, for API
+// keys, from the granted scopes
+func RoleFromContext(c *gin.Context) (Role, bool) {
+	if v, exists := c.Get("claims"); exists {
+		if claims, ok := v.(*Claims); ok {
+			return ParseRole(claims.Role), true
+		}
+	}
+	if v, exists := c.Get("api_key"); exists {
+		if key, ok := v.(*APIKey); ok {
+			return roleFromScopes(key.Scopes), true
+		}
+	}
+	return "", false
+}
+
+// roleFromScopes derives the role an API key acts as
+func roleFromScopes(scopes []string) Role {
+	role := RoleViewer
+	for _, s := range scopes {
+		switch s {
+		case ScopeAdminAll:
+			return RoleAdmin
+		case ScopeOrchestrateExecute:
+			role = RoleDeveloper
+		}
+	}
+	return role
+}
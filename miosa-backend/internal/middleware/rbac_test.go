@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRole_Can(t *testing.T) {
+	assert.True(t, RoleViewer.Can(PermWorkflowsRead))
+	assert.True(t, RoleViewer.Can(PermReportsRead))
+	assert.False(t, RoleViewer.Can(PermOrchestrateExecute))
+
+	assert.True(t, RoleDeveloper.Can(PermOrchestrateExecute))
+	assert.False(t, RoleDeveloper.Can(PermDeploymentsApprove))
+
+	assert.True(t, RoleOperator.Can(PermImprovementsApprove))
+	assert.True(t, RoleOperator.Can(PermDeploymentsApprove))
+	assert.False(t, RoleOperator.Can(PermTenantsManage))
+
+	assert.True(t, RoleAdmin.Can(PermTenantsManage))
+	assert.False(t, RoleAdmin.Can(Permission("unknown")))
+}
+
+func TestParseRole(t *testing.T) {
+	assert.Equal(t, RoleDeveloper, ParseRole("developer"))
+	assert.Equal(t, RoleAdmin, ParseRole("owner"))
+	assert.Equal(t, RoleViewer, ParseRole("user"))
+	assert.Equal(t, RoleViewer, ParseRole(""))
+}
+
+func TestRBACMiddleware_Require(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rbac := NewRBACMiddleware(zap.NewNop())
+
+	tests := []struct {
+		name     string
+		setup    func(c *gin.Context)
+		expected int
+	}{
+		{"no identity", func(c *gin.Context) {}, http.StatusUnauthorized},
+		{"viewer denied", func(c *gin.Context) { c.Set("claims", &Claims{Role: "viewer"}) }, http.StatusForbidden},
+		{"developer allowed", func(c *gin.Context) { c.Set("claims", &Claims{Role: "developer"}) }, http.StatusOK},
+		{"api key with execute scope", func(c *gin.Context) {
+			c.Set("api_key", &APIKey{Scopes: []string{ScopeOrchestrateExecute}})
+		}, http.StatusOK},
+		{"api key read only", func(c *gin.Context) {
+			c.Set("api_key", &APIKey{Scopes: []string{ScopeQualityRead}})
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/run", func(c *gin.Context) {
+				tt.setup(c)
+				c.Next()
+			}, rbac.Require(PermOrchestrateExecute), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run", nil))
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestRBACMiddleware_RequireOrScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rbac := NewRBACMiddleware(zap.NewNop())
+
+	tests := []struct {
+		name     string
+		setup    func(c *gin.Context)
+		expected int
+	}{
+		{"no identity", func(c *gin.Context) {}, http.StatusUnauthorized},
+		{"admin role", func(c *gin.Context) { c.Set("claims", &Claims{Role: "admin"}) }, http.StatusOK},
+		{"developer without scope", func(c *gin.Context) { c.Set("claims", &Claims{Role: "developer"}) }, http.StatusForbidden},
+		{"api key with keys scope", func(c *gin.Context) {
+			key := &APIKey{Scopes: []string{ScopeKeysManage}}
+			c.Set("api_key", key)
+			c.Set("scopes", key.Scopes)
+		}, http.StatusOK},
+		{"api key without keys scope", func(c *gin.Context) {
+			key := &APIKey{Scopes: []string{ScopeOrchestrateExecute}}
+			c.Set("api_key", key)
+			c.Set("scopes", key.Scopes)
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/api/keys", func(c *gin.Context) {
+				tt.setup(c)
+				c.Next()
+			}, rbac.RequireOrScope(PermTenantsManage, ScopeKeysManage), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/keys", nil))
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+
+	// keys:manage gates key management only; the key still acts as a viewer
+	assert.Equal(t, RoleViewer, roleFromScopes([]string{ScopeKeysManage}))
+}
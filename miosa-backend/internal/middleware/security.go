@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -86,7 +87,7 @@ func (m *SecurityMiddleware) addSecurityHeaders(c *gin.Context) {
 	// HSTS (HTTP Strict Transport Security)
 	if m.enableHSTS {
 		c.Header("Strict-Transport-Security", 
-			"max-age="+string(m.hstsMaxAge)+"; includeSubDomains; preload")
+			"max-age="+strconv.Itoa(m.hstsMaxAge)+"; includeSubDomains; preload")
 	}
 	
 	// Content Security Policy
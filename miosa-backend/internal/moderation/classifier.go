@@ -0,0 +1,93 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/conneroisu/groq-go"
+)
+
+// DefaultClassifierModel is the model LLMClassifier asks unless told
+// otherwise; classification needs little reasoning, so it is a fast one
+const DefaultClassifierModel = "llama-3.1-8b-instant"
+
+// Completer is the part of the Groq client the classifier uses
+type Completer interface {
+	ChatCompletion(ctx context.Context, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error)
+}
+
+// LLMClassifier classifies requests by asking a model which policy
+// category, if any, they fall into
+type LLMClassifier struct {
+	completer Completer
+	model     string
+}
+
+// NewLLMClassifier creates a classifier asking model, or
+// DefaultClassifierModel when empty
+func NewLLMClassifier(completer Completer, model string) *LLMClassifier {
+	if model == "" {
+		model = DefaultClassifierModel
+	}
+	return &LLMClassifier{completer: completer, model: model}
+}
+
+// Classify implements Classifier
+func (c *LLMClassifier) Classify(ctx context.Context, text string, categories map[string]string) (Verdict, error) {
+	resp, err := c.completer.ChatCompletion(ctx, groq.ChatCompletionRequest{
+		Model: groq.ChatModel(c.model),
+		Messages: []groq.ChatCompletionMessage{
+			{Role: groq.RoleSystem, Content: classifierPrompt(categories)},
+			{Role: groq.RoleUser, Content: text},
+		},
+		MaxTokens:   200,
+		Temperature: 0,
+	})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to classify request: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return Verdict{}, errors.New("no response from model")
+	}
+	return parseVerdict(resp.Choices[0].Message.Content)
+}
+
+// classifierPrompt lists the categories, sorted so the prompt is stable
+func classifierPrompt(categories map[string]string) string {
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("You screen requests to a code generation service. Decide whether the request asks to build something in one of these prohibited categories:\n\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "- %s: %s\n", name, categories[name])
+	}
+	sb.WriteString("\nLegitimate security work (authentication, password resets, defensive scanners, tests) is not prohibited. ")
+	sb.WriteString(`Reply with JSON only: {"category": "<category or none>", "confidence": <0 to 1>, "reason": "<one sentence>"}`)
+	return sb.String()
+}
+
+// parseVerdict reads the JSON object in a model reply, which may be
+// wrapped in prose or a code fence
+func parseVerdict(content string) (Verdict, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return Verdict{}, fmt.Errorf("classifier reply has no JSON verdict: %q", content)
+	}
+	var v Verdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &v); err != nil {
+		return Verdict{}, fmt.Errorf("failed to parse classifier verdict: %w", err)
+	}
+	v.Category = strings.TrimSpace(strings.ToLower(v.Category))
+	if v.Category == "none" {
+		v.Category = ""
+	}
+	return v, nil
+}
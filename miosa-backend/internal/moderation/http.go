@@ -0,0 +1,97 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+)
+
+// maxAppealLength bounds the explanation sent with an appeal
+const maxAppealLength = 4000
+
+// Record audits d under event, which names the actor, resource and
+// request; the action, status and decision details are filled in
+func Record(ctx context.Context, auditLog *audit.Log, d Decision, event audit.Event) {
+	event.Action = audit.ActionModeration
+	event.Status = audit.StatusApproved
+	if !d.Allowed {
+		event.Status = audit.StatusRejected
+	}
+	metadata := map[string]string{"decision_id": d.ID.String()}
+	for k, v := range map[string]string{
+		"category":         d.Category,
+		"source":           d.Source,
+		"rule":             d.Rule,
+		"reason":           d.Reason,
+		"classifier_error": d.ClassifierError,
+	} {
+		if v != "" {
+			metadata[k] = v
+		}
+	}
+	if d.Confidence > 0 {
+		metadata["confidence"] = strconv.FormatFloat(d.Confidence, 'f', 2, 64)
+	}
+	event.Metadata = metadata
+	auditLog.Record(ctx, event)
+}
+
+// AppealHandler serves POST /api/moderation/appeals/{id}, recording a
+// request for human review of a rejection in the audit log. The body is
+// {"message": "why the request is legitimate"}.
+func AppealHandler(m *Moderator, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.Invalid("invalid decision ID"))
+			return
+		}
+		d, ok := m.Rejection(id)
+		if !ok {
+			apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "no recent rejection with this ID"))
+			return
+		}
+
+		var body struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+			apierror.Write(w, r, apierror.Invalid("invalid request body"))
+			return
+		}
+		if body.Message == "" || len(body.Message) > maxAppealLength {
+			apierror.Write(w, r, apierror.Invalid("invalid appeal", apierror.FieldError{
+				Field:   "message",
+				Message: "must be between 1 and " + strconv.Itoa(maxAppealLength) + " characters",
+			}))
+			return
+		}
+
+		actor, actorType := audit.ActorFromRequest(r)
+		auditLog.Record(r.Context(), audit.Event{
+			Actor:     actor,
+			ActorType: actorType,
+			Action:    audit.ActionModerationAppeal,
+			Resource:  d.ID.String(),
+			Status:    audit.StatusSuccess,
+			Metadata: map[string]string{
+				"decision_id": d.ID.String(),
+				"category":    d.Category,
+				"message":     body.Message,
+			},
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"decision_id": d.ID.String(),
+			"status":      "pending_review",
+		})
+	}
+}
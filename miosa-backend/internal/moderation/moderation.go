@@ -0,0 +1,242 @@
+// Package moderation screens orchestrate requests for prohibited content
+// before any agent runs. Requests are matched against configurable rules
+// first, then, when they pass, classified by an LLM; a rejection names the
+// policy category and where to appeal.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+)
+
+// Built-in policy categories
+const (
+	CategoryMalware              = "malware"
+	CategoryCredentialHarvesting = "credential_harvesting"
+)
+
+// Decision sources
+const (
+	SourceRule       = "rule"
+	SourceClassifier = "classifier"
+)
+
+// Defaults
+const (
+	DefaultMinConfidence = 0.8
+	// DefaultAppealPath is where rejected requests are appealed unless
+	// Config.Appeal names another place; the decision ID is appended
+	DefaultAppealPath = "/api/moderation/appeals/"
+	// maxDecisions bounds the rejections kept for appeals
+	maxDecisions = 1000
+)
+
+// DefaultCategories describe the built-in categories to the classifier
+var DefaultCategories = map[string]string{
+	CategoryMalware:              "software meant to damage, spy on or take over systems without consent: ransomware, keyloggers, botnets, self-spreading worms, antivirus evasion",
+	CategoryCredentialHarvesting: "collecting other people's passwords, tokens or session cookies without consent: phishing pages imitating real services, credential stealers",
+}
+
+// DefaultRules catch unambiguous requests without a model call. Ordinary
+// features such as login forms or password resets do not match.
+var DefaultRules = []Rule{
+	{Name: "ransomware", Category: CategoryMalware, Pattern: `(?i)\bransomware\b|encrypts? (the )?(victim'?s?|user'?s?) files .*(ransom|bitcoin|payment)`, Reason: "builds ransomware"},
+	{Name: "keylogger", Category: CategoryMalware, Pattern: `(?i)\b(keylogger|key ?stroke logger)\b`, Reason: "records keystrokes covertly"},
+	{Name: "av-evasion", Category: CategoryMalware, Pattern: `(?i)\b(undetectable|evade|bypass|avoid)\b.{0,40}\b(antivirus|anti-virus|edr|windows defender)\b`, Reason: "evades malware detection"},
+	{Name: "botnet", Category: CategoryMalware, Pattern: `(?i)\bbotnet\b|\bself[- ](propagating|spreading|replicating)\b.{0,20}\b(worm|virus|malware)\b`, Reason: "builds a botnet or worm"},
+	{Name: "phishing", Category: CategoryCredentialHarvesting, Pattern: `(?i)\bphishing (page|site|kit|campaign)\b|\b(clone|fake|replica of|imitat\w*)\b.{0,40}\blog ?in page\b.{0,60}\b(capture|collect|harvest|steal|send)\w*`, Reason: "imitates a login page to collect credentials"},
+	{Name: "credential-stealer", Category: CategoryCredentialHarvesting, Pattern: `(?i)\b(steal|harvest|exfiltrate|grab)\w*\b.{0,40}\b(passwords?|credentials|session cookies|cookies|tokens)\b`, Reason: "steals credentials"},
+}
+
+// Rule rejects requests matching Pattern, a regular expression
+type Rule struct {
+	Name     string `yaml:"name" json:"name"`
+	Category string `yaml:"category" json:"category"`
+	Pattern  string `yaml:"pattern" json:"pattern"`
+	Reason   string `yaml:"reason" json:"reason,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Config configures a Moderator, e.g.
+//
+//	appeal: https://trust.example.com/appeals/
+//	min_confidence: 0.9
+//	categories:
+//	  weapons: instructions for making weapons
+//	rules:
+//	  - {name: weapons, category: weapons, pattern: '(?i)\bpipe bomb\b'}
+type Config struct {
+	Rules         []Rule            `yaml:"rules"`
+	Categories    map[string]string `yaml:"categories"`     // Added to DefaultCategories
+	NoDefaults    bool              `yaml:"no_defaults"`    // Drop DefaultRules
+	MinConfidence float64           `yaml:"min_confidence"` // Of the classifier to reject; DefaultMinConfidence when zero
+	Appeal        string            `yaml:"appeal"`         // Prefix of appeal links; DefaultAppealPath when empty
+}
+
+// LoadConfig reads a moderation config from a YAML file
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read moderation config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse moderation config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Verdict is a classifier's judgement of a request
+type Verdict struct {
+	Category   string  `json:"category"` // Empty or "none" when allowed
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+}
+
+// Classifier judges requests the rules let through
+type Classifier interface {
+	Classify(ctx context.Context, text string, categories map[string]string) (Verdict, error)
+}
+
+// Decision is the outcome of screening one request
+type Decision struct {
+	ID         uuid.UUID `json:"id"`
+	Allowed    bool      `json:"allowed"`
+	Category   string    `json:"category,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Rule       string    `json:"rule,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Confidence float64   `json:"confidence,omitempty"`
+	// ClassifierError is why the classifier could not judge the request,
+	// which is then allowed on the rules alone
+	ClassifierError string `json:"classifier_error,omitempty"`
+}
+
+// Moderator screens requests with rules and an optional classifier
+type Moderator struct {
+	rules         []Rule
+	categories    map[string]string
+	classifier    Classifier
+	minConfidence float64
+	appeal        string
+
+	mu       sync.Mutex
+	rejected map[uuid.UUID]Decision
+	order    []uuid.UUID
+}
+
+// New creates a moderator from cfg. classifier may be nil to screen with
+// rules alone.
+func New(cfg Config, classifier Classifier) (*Moderator, error) {
+	m := &Moderator{
+		categories:    make(map[string]string),
+		classifier:    classifier,
+		minConfidence: cfg.MinConfidence,
+		appeal:        cfg.Appeal,
+		rejected:      make(map[uuid.UUID]Decision),
+	}
+	if m.minConfidence <= 0 {
+		m.minConfidence = DefaultMinConfidence
+	}
+	if m.appeal == "" {
+		m.appeal = DefaultAppealPath
+	}
+	for name, desc := range DefaultCategories {
+		m.categories[name] = desc
+	}
+	for name, desc := range cfg.Categories {
+		m.categories[name] = desc
+	}
+
+	rules := cfg.Rules
+	if !cfg.NoDefaults {
+		rules = append(append([]Rule(nil), DefaultRules...), cfg.Rules...)
+	}
+	for _, r := range rules {
+		if r.Name == "" || r.Category == "" {
+			return nil, fmt.Errorf("moderation rule %q needs a name and category", r.Name)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("moderation rule %s: %w", r.Name, err)
+		}
+		r.re = re
+		m.rules = append(m.rules, r)
+		if _, ok := m.categories[r.Category]; !ok {
+			m.categories[r.Category] = r.Reason
+		}
+	}
+	return m, nil
+}
+
+// Check screens text. A classifier error allows the request on the rules
+// alone, noting the error in the decision, so an unreachable model does not
+// block every workflow.
+func (m *Moderator) Check(ctx context.Context, text string) Decision {
+	d := Decision{ID: uuid.New(), Allowed: true}
+	for _, r := range m.rules {
+		if r.re.MatchString(text) {
+			d.Allowed, d.Category, d.Source, d.Rule, d.Reason = false, r.Category, SourceRule, r.Name, r.Reason
+			m.remember(d)
+			return d
+		}
+	}
+
+	if m.classifier == nil {
+		return d
+	}
+	v, err := m.classifier.Classify(ctx, text, m.categories)
+	if err != nil {
+		d.ClassifierError = err.Error()
+		return d
+	}
+	d.Confidence = v.Confidence
+	if _, known := m.categories[v.Category]; known && v.Confidence >= m.minConfidence {
+		d.Allowed, d.Category, d.Source, d.Reason = false, v.Category, SourceClassifier, v.Reason
+		m.remember(d)
+	}
+	return d
+}
+
+// Err returns the rejection clients are sent for d, nil when it is allowed
+func (m *Moderator) Err(d Decision) error {
+	if d.Allowed {
+		return nil
+	}
+	return apierror.Rejected("request refused by the content policy ("+d.Category+")", apierror.Violation{
+		Category:   d.Category,
+		Reason:     d.Reason,
+		DecisionID: d.ID.String(),
+		Appeal:     m.appeal + d.ID.String(),
+	})
+}
+
+// Rejection returns a recent rejected decision by ID
+func (m *Moderator) Rejection(id uuid.UUID) (Decision, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.rejected[id]
+	return d, ok
+}
+
+// remember keeps a rejection for appeals, forgetting the oldest beyond
+// maxDecisions
+func (m *Moderator) remember(d Decision) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejected[d.ID] = d
+	m.order = append(m.order, d.ID)
+	if len(m.order) > maxDecisions {
+		delete(m.rejected, m.order[0])
+		m.order = m.order[1:]
+	}
+}
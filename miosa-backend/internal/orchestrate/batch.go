@@ -0,0 +1,506 @@
+package orchestrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+)
+
+// Limits on batch requests
+const (
+	MaxBatchItems     = 50
+	MaxBatchBodyBytes = 1 << 20
+)
+
+// DefaultBatchRetention is how long finished batches stay pollable
+const DefaultBatchRetention = 24 * time.Hour
+
+// ErrBatchNotFound is returned for unknown batches and batches submitted
+// by another tenant
+var ErrBatchNotFound = errors.New("batch not found")
+
+// Batch and item statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCompleted = "completed"
+)
+
+// BatchRequest is the body of POST /api/orchestrate/batch. Items are
+// always run asynchronously, so their async field is ignored.
+type BatchRequest struct {
+	Items []Request `json:"items"`
+}
+
+// BatchItem is the state of one request in a batch
+type BatchItem struct {
+	Index      int         `json:"index"`
+	WorkflowID uuid.UUID   `json:"workflow_id"`
+	Status     string      `json:"status"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+
+	// QueuePosition estimates when a queued item starts: 1 is next
+	QueuePosition int `json:"queue_position,omitempty"`
+
+	request *Request
+}
+
+// BatchSummary counts a batch's items by status
+type BatchSummary struct {
+	Queued    int `json:"queued"`
+	Running   int `json:"running"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Batch is a set of requests submitted together
+type Batch struct {
+	ID         uuid.UUID    `json:"id"`
+	Status     string       `json:"status"`
+	Summary    BatchSummary `json:"summary"`
+	Items      []*BatchItem `json:"items"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+
+	// QueuePosition is that of the batch's next queued item
+	QueuePosition int `json:"queue_position,omitempty"`
+
+	tenant    string
+	actor     string
+	actorType string
+	requestID string
+}
+
+// Job is one batch item handed to a worker
+type Job struct {
+	BatchID    uuid.UUID
+	WorkflowID uuid.UUID
+	Request    *Request
+	Tenant     string
+	Actor      string
+	ActorType  string
+	RequestID  string // Request that submitted the batch
+}
+
+// RunFunc executes one job, returning the workflow result
+type RunFunc func(ctx context.Context, job Job) (interface{}, error)
+
+// queued is an item waiting for a worker
+type queued struct {
+	batch *Batch
+	item  *BatchItem
+}
+
+// Scheduler runs batch items on a fixed pool of workers with weighted fair
+// queuing. Each tenant has its own queue, and workers take from the tenant
+// that has started the fewest items for its weight, among those under their
+// concurrency quota, so one tenant's large batch doesn't hold up everyone
+// else's.
+type Scheduler struct {
+	run       RunFunc
+	workers   int
+	retention time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	quotas  Quotas
+	tenants map[string]*tenantQueue
+	order   []string // Tenants with queued items, in the order they joined
+	vtime   float64  // Virtual service of the last item started
+	batches map[uuid.UUID]*Batch
+	closed  bool
+}
+
+// NewScheduler creates a scheduler running jobs with run on workers
+// goroutines once started
+func NewScheduler(run RunFunc, workers int) *Scheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	s := &Scheduler{
+		run:       run,
+		workers:   workers,
+		retention: DefaultBatchRetention,
+		tenants:   make(map[string]*tenantQueue),
+		batches:   make(map[uuid.UUID]*Batch),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Start launches the workers. They stop taking jobs when ctx is done;
+// running jobs get ctx and should stop with it.
+func (s *Scheduler) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		s.cond.Broadcast()
+	}()
+}
+
+// SetQuotas replaces the tenant quotas; items already running are left to
+// finish
+func (s *Scheduler) SetQuotas(quotas Quotas) {
+	s.mu.Lock()
+	s.quotas = quotas
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// Submit queues reqs as one batch for tenant. Items run under the request
+// ID carried by ctx.
+func (s *Scheduler) Submit(ctx context.Context, tenant, actor, actorType string, reqs []*Request) *Batch {
+	now := time.Now()
+	b := &Batch{
+		ID:        uuid.New(),
+		Status:    StatusQueued,
+		Items:     make([]*BatchItem, len(reqs)),
+		CreatedAt: now,
+		tenant:    tenant,
+		actor:     actor,
+		actorType: actorType,
+		requestID: requestid.FromContext(ctx),
+	}
+
+	s.mu.Lock()
+	s.prune(now)
+	s.batches[b.ID] = b
+	t, ok := s.tenants[tenant]
+	if !ok {
+		t = &tenantQueue{}
+		s.tenants[tenant] = t
+	}
+	if len(t.items) == 0 {
+		// Service a tenant didn't use while idle isn't banked
+		if t.served < s.vtime {
+			t.served = s.vtime
+		}
+		s.order = append(s.order, tenant)
+	}
+	for i, req := range reqs {
+		item := &BatchItem{Index: i, WorkflowID: uuid.New(), Status: StatusQueued, request: req}
+		b.Items[i] = item
+		t.items = append(t.items, queued{batch: b, item: item})
+	}
+	b.Summary.Queued = len(reqs)
+	snapshot := b.snapshot(s.queuePositions(b))
+	s.mu.Unlock()
+
+	s.cond.Broadcast()
+	return snapshot
+}
+
+// Get returns a copy of the tenant's batch
+func (s *Scheduler) Get(tenant string, id uuid.UUID) (*Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[id]
+	if !ok || b.tenant != tenant {
+		return nil, ErrBatchNotFound
+	}
+	return b.snapshot(s.queuePositions(b)), nil
+}
+
+// pick returns the tenant whose item starts next: the least served of those
+// under their quota or, if none is, of those within their burst allowance.
+// Ties go to the tenant that joined first. Callers hold s.mu.
+func (s *Scheduler) pick() (string, bool) {
+	for _, burst := range []bool{false, true} {
+		best := ""
+		for _, name := range s.order {
+			t := s.tenants[name]
+			if !s.quotas.For(name).allows(t.running, burst) {
+				continue
+			}
+			if best == "" || t.served < s.tenants[best].served {
+				best = name
+			}
+		}
+		if best != "" {
+			return best, true
+		}
+	}
+	return "", false
+}
+
+// next starts the first item of tenant. Callers hold s.mu.
+func (s *Scheduler) next(tenant string) queued {
+	t := s.tenants[tenant]
+	q := t.items[0]
+	t.items = t.items[1:]
+	t.running++
+	s.vtime = t.served
+	t.served += 1 / s.quotas.For(tenant).weight()
+	if len(t.items) == 0 {
+		for i, name := range s.order {
+			if name == tenant {
+				s.order = append(s.order[:i:i], s.order[i+1:]...)
+				break
+			}
+		}
+	}
+	return q
+}
+
+// queuePositions estimates the position of each queued item of b by
+// replaying the fair share, assuming no tenant is held back by its quota.
+// Callers hold s.mu.
+func (s *Scheduler) queuePositions(b *Batch) map[*BatchItem]int {
+	positions := make(map[*BatchItem]int, b.Summary.Queued)
+	served := make(map[string]float64, len(s.order))
+	taken := make(map[string]int, len(s.order))
+	for _, name := range s.order {
+		served[name] = s.tenants[name].served
+	}
+	for pos := 1; len(positions) < b.Summary.Queued; pos++ {
+		best := ""
+		for _, name := range s.order {
+			if taken[name] < len(s.tenants[name].items) && (best == "" || served[name] < served[best]) {
+				best = name
+			}
+		}
+		if best == "" {
+			break
+		}
+		q := s.tenants[best].items[taken[best]]
+		taken[best]++
+		served[best] += 1 / s.quotas.For(best).weight()
+		if q.batch == b {
+			positions[q.item] = pos
+		}
+	}
+	return positions
+}
+
+func (s *Scheduler) work(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		tenant, ok := s.pick()
+		for !ok && !s.closed {
+			s.cond.Wait()
+			tenant, ok = s.pick()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		q := s.next(tenant)
+		started := time.Now()
+		q.item.Status = StatusRunning
+		q.item.StartedAt = &started
+		q.batch.Status = StatusRunning
+		q.batch.Summary.Queued--
+		q.batch.Summary.Running++
+		job := Job{
+			BatchID:    q.batch.ID,
+			WorkflowID: q.item.WorkflowID,
+			Request:    q.item.request,
+			Tenant:     q.batch.tenant,
+			Actor:      q.batch.actor,
+			ActorType:  q.batch.actorType,
+			RequestID:  q.batch.requestID,
+		}
+		s.mu.Unlock()
+
+		jobCtx := ctx
+		if job.RequestID != "" {
+			jobCtx = requestid.NewContext(ctx, job.RequestID)
+		}
+		result, err := s.run(jobCtx, job)
+
+		s.mu.Lock()
+		finished := time.Now()
+		t := s.tenants[tenant]
+		if t.running--; t.running == 0 && len(t.items) == 0 {
+			delete(s.tenants, tenant)
+		}
+		q.item.FinishedAt = &finished
+		q.batch.Summary.Running--
+		if err != nil {
+			q.item.Status = StatusFailed
+			q.item.Error = err.Error()
+			q.batch.Summary.Failed++
+		} else {
+			q.item.Status = StatusSucceeded
+			q.item.Result = result
+			q.batch.Summary.Succeeded++
+		}
+		if q.batch.Summary.Queued == 0 && q.batch.Summary.Running == 0 {
+			q.batch.Status = StatusCompleted
+			q.batch.FinishedAt = &finished
+		}
+		s.mu.Unlock()
+		// A worker may be waiting for the tenant to drop under its quota
+		s.cond.Broadcast()
+	}
+}
+
+// prune drops batches that finished more than the retention period ago.
+// Callers hold s.mu.
+func (s *Scheduler) prune(now time.Time) {
+	for id, b := range s.batches {
+		if b.FinishedAt != nil && now.Sub(*b.FinishedAt) > s.retention {
+			delete(s.batches, id)
+		}
+	}
+}
+
+// snapshot copies the batch so it can be encoded without holding the lock,
+// setting the queue positions of its queued items
+func (b *Batch) snapshot(positions map[*BatchItem]int) *Batch {
+	copied := *b
+	copied.Items = make([]*BatchItem, len(b.Items))
+	for i, item := range b.Items {
+		c := *item
+		c.QueuePosition = positions[item]
+		if c.QueuePosition > 0 && (copied.QueuePosition == 0 || c.QueuePosition < copied.QueuePosition) {
+			copied.QueuePosition = c.QueuePosition
+		}
+		copied.Items[i] = &c
+	}
+	return &copied
+}
+
+// DecodeBatch reads and validates a batch body. Item fields are reported
+// as items[i].field.
+func DecodeBatch(w http.ResponseWriter, r *http.Request) ([]*Request, error) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBatchBodyBytes))
+	dec.DisallowUnknownFields()
+
+	var body BatchRequest
+	if err := dec.Decode(&body); err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			return nil, &ValidationError{Fields: []FieldError{{Field: "body", Message: fmt.Sprintf("must be at most %d bytes", MaxBatchBodyBytes)}}}
+		case errors.Is(err, io.EOF):
+			return nil, &ValidationError{Fields: []FieldError{{Field: "body", Message: "is required"}}}
+		default:
+			return nil, &ValidationError{Fields: []FieldError{{Field: "body", Message: err.Error()}}}
+		}
+	}
+
+	verr := &ValidationError{}
+	switch n := len(body.Items); {
+	case n == 0:
+		verr.add("items", "is required")
+	case n > MaxBatchItems:
+		verr.add("items", "must have at most %d items, got %d", MaxBatchItems, n)
+	}
+	reqs := make([]*Request, len(body.Items))
+	for i := range body.Items {
+		req := &body.Items[i]
+		var itemErr *ValidationError
+		if errors.As(req.Validate(), &itemErr) {
+			for _, f := range itemErr.Fields {
+				verr.Fields = append(verr.Fields, FieldError{Field: fmt.Sprintf("items[%d].%s", i, f.Field), Message: f.Message})
+			}
+		}
+		req.Async = false
+		reqs[i] = req
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	return reqs, nil
+}
+
+// TenantFromRequest identifies the tenant whose queue a batch joins: the
+// X-Tenant-ID header, falling back to the caller
+func TenantFromRequest(r *http.Request) string {
+	if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" {
+		return tenant
+	}
+	actor, _ := audit.ActorFromRequest(r)
+	return actor
+}
+
+// SubmitHandler serves POST /api/orchestrate/batch
+func (s *Scheduler) SubmitHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqs, err := DecodeBatch(w, r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		actor, actorType := audit.ActorFromRequest(r)
+		b := s.Submit(r.Context(), TenantFromRequest(r), actor, actorType, reqs)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"batch_id":   b.ID,
+			"status":     b.Status,
+			"items":      b.Items,
+			"status_url": "/api/orchestrate/batch/" + b.ID.String(),
+		})
+	}
+}
+
+// StatusHandler serves GET /api/orchestrate/batch/{id}
+func (s *Scheduler) StatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, ok := s.lookup(w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(b)
+	}
+}
+
+// ItemHandler serves GET /api/orchestrate/batch/{id}/items/{index}
+func (s *Scheduler) ItemHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, ok := s.lookup(w, r)
+		if !ok {
+			return
+		}
+		index, err := strconv.Atoi(mux.Vars(r)["index"])
+		if err != nil || index < 0 || index >= len(b.Items) {
+			apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, "item not found"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(b.Items[index])
+	}
+}
+
+// lookup loads the {id} batch for the caller's tenant, writing the error
+// response when it can't
+func (s *Scheduler) lookup(w http.ResponseWriter, r *http.Request) (*Batch, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid batch id"))
+		return nil, false
+	}
+	b, err := s.Get(TenantFromRequest(r), id)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CategoryNotFound, "batch not found", err))
+		return nil, false
+	}
+	return b, true
+}
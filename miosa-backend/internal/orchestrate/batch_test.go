@@ -0,0 +1,239 @@
+package orchestrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+)
+
+func batchOf(descriptions ...string) []*Request {
+	reqs := make([]*Request, len(descriptions))
+	for i, d := range descriptions {
+		reqs[i] = &Request{Description: d}
+	}
+	return reqs
+}
+
+func waitForBatch(t *testing.T, s *Scheduler, tenant string, b *Batch) *Batch {
+	t.Helper()
+	var got *Batch
+	require.Eventually(t, func() bool {
+		var err error
+		got, err = s.Get(tenant, b.ID)
+		return err == nil && got.Status == StatusCompleted
+	}, 2*time.Second, 5*time.Millisecond)
+	return got
+}
+
+func TestScheduler_FairAcrossTenants(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	s := NewScheduler(func(ctx context.Context, job Job) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, job.Request.Description)
+		return nil, nil
+	}, 1)
+
+	big := s.Submit(context.Background(), "a", "", "", batchOf("a1", "a2", "a3"))
+	small := s.Submit(context.Background(), "b", "", "", batchOf("b1"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	waitForBatch(t, s, "a", big)
+	waitForBatch(t, s, "b", small)
+	assert.Equal(t, []string{"a1", "b1", "a2", "a3"}, order, "b's item runs before a's backlog")
+}
+
+func TestScheduler_Weights(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	s := NewScheduler(func(ctx context.Context, job Job) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, job.Request.Description)
+		return nil, nil
+	}, 1)
+	s.SetQuotas(Quotas{Tenants: map[string]TenantQuota{"a": {Weight: 2}}})
+
+	big := s.Submit(context.Background(), "a", "", "", batchOf("a1", "a2", "a3", "a4"))
+	assert.Equal(t, 1, big.QueuePosition)
+	small := s.Submit(context.Background(), "b", "", "", batchOf("b1", "b2"))
+	assert.Equal(t, []int{2, 5}, []int{small.Items[0].QueuePosition, small.Items[1].QueuePosition})
+	queued, err := s.Get("a", big.ID)
+	require.NoError(t, err)
+	for i, want := range []int{1, 3, 4, 6} {
+		assert.Equal(t, want, queued.Items[i].QueuePosition)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	done := waitForBatch(t, s, "a", big)
+	waitForBatch(t, s, "b", small)
+	assert.Equal(t, []string{"a1", "b1", "a2", "a3", "b2", "a4"}, order, "a gets twice b's share")
+	assert.Zero(t, done.Items[0].QueuePosition, "started items have no position")
+}
+
+func TestScheduler_Quotas(t *testing.T) {
+	release := make(chan struct{})
+	s := NewScheduler(func(ctx context.Context, job Job) (interface{}, error) {
+		<-release
+		return nil, nil
+	}, 4)
+	s.SetQuotas(Quotas{
+		Default: TenantQuota{MaxConcurrent: 1, Burst: 1},
+		Tenants: map[string]TenantQuota{"b": {MaxConcurrent: 1}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	running := func(tenant string, b *Batch) func() bool {
+		return func() bool {
+			got, err := s.Get(tenant, b.ID)
+			return err == nil && got.Summary.Running == map[string]int{"a": 2, "b": 1}[tenant]
+		}
+	}
+
+	// Alone, a bursts over its quota while the workers would sit idle
+	a := s.Submit(context.Background(), "a", "", "", batchOf("a1", "a2", "a3"))
+	require.Eventually(t, running("a", a), 2*time.Second, 5*time.Millisecond)
+	b := s.Submit(context.Background(), "b", "", "", batchOf("b1", "b2"))
+	require.Eventually(t, running("b", b), 2*time.Second, 5*time.Millisecond)
+
+	// Both are at their limits, so the spare worker waits
+	time.Sleep(20 * time.Millisecond)
+	gotA, err := s.Get("a", a.ID)
+	require.NoError(t, err)
+	gotB, err := s.Get("b", b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BatchSummary{Queued: 1, Running: 2}, gotA.Summary)
+	assert.Equal(t, BatchSummary{Queued: 1, Running: 1}, gotB.Summary)
+	assert.Equal(t, 1, gotA.Items[2].QueuePosition)
+	assert.Equal(t, 2, gotB.Items[1].QueuePosition)
+
+	close(release)
+	waitForBatch(t, s, "a", a)
+	waitForBatch(t, s, "b", b)
+}
+
+func TestLoadQuotas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotas.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("default: {max_concurrent: 2, burst: 1}\ntenants:\n  acme: {weight: 3}\n"), 0644))
+	quotas, err := LoadQuotas(path)
+	require.NoError(t, err)
+	assert.Equal(t, TenantQuota{MaxConcurrent: 2, Burst: 1}, quotas.For("other"))
+	assert.Equal(t, 3.0, quotas.For("acme").weight())
+	assert.Equal(t, 1.0, quotas.For("other").weight())
+
+	_, err = LoadQuotas(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestScheduler_ItemStatus(t *testing.T) {
+	s := NewScheduler(func(ctx context.Context, job Job) (interface{}, error) {
+		if job.Request.Description == "bad" {
+			return nil, errors.New("agent failed")
+		}
+		if requestid.FromContext(ctx) != "req-1" {
+			return nil, errors.New("request id not propagated")
+		}
+		return map[string]string{"workflow": job.WorkflowID.String()}, nil
+	}, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	b := s.Submit(requestid.NewContext(context.Background(), "req-1"), "a", "user", "user", batchOf("good", "bad"))
+	assert.Equal(t, StatusQueued, b.Status)
+
+	done := waitForBatch(t, s, "a", b)
+	assert.Equal(t, BatchSummary{Succeeded: 1, Failed: 1}, done.Summary)
+	assert.Equal(t, StatusSucceeded, done.Items[0].Status)
+	assert.NotNil(t, done.Items[0].Result)
+	assert.Equal(t, StatusFailed, done.Items[1].Status)
+	assert.Equal(t, "agent failed", done.Items[1].Error)
+	assert.NotNil(t, done.FinishedAt)
+
+	_, err := s.Get("b", b.ID)
+	assert.ErrorIs(t, err, ErrBatchNotFound, "batches are scoped to their tenant")
+}
+
+func TestDecodeBatch_Invalid(t *testing.T) {
+	decodeBatch := func(body string) error {
+		r := httptest.NewRequest(http.MethodPost, "/api/orchestrate/batch", strings.NewReader(body))
+		_, err := DecodeBatch(httptest.NewRecorder(), r)
+		return err
+	}
+
+	var verr *ValidationError
+	require.ErrorAs(t, decodeBatch(`{"items": []}`), &verr)
+	assert.Equal(t, "items", verr.Fields[0].Field)
+
+	require.ErrorAs(t, decodeBatch(`{"items": [{"description": "Build a URL shortener"}, {"description": "short"}]}`), &verr)
+	require.Len(t, verr.Fields, 1)
+	assert.Equal(t, "items[1].description", verr.Fields[0].Field)
+
+	items := make([]string, MaxBatchItems+1)
+	for i := range items {
+		items[i] = `{"description": "Build service number ` + fmt.Sprint(i) + `"}`
+	}
+	require.ErrorAs(t, decodeBatch(`{"items": [`+strings.Join(items, ",")+`]}`), &verr)
+	assert.Contains(t, verr.Fields[0].Message, "at most")
+}
+
+func TestBatchHandlers(t *testing.T) {
+	s := NewScheduler(func(ctx context.Context, job Job) (interface{}, error) {
+		return job.Tenant, nil
+	}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/orchestrate/batch", s.SubmitHandler()).Methods("POST")
+	router.HandleFunc("/api/orchestrate/batch/{id}", s.StatusHandler()).Methods("GET")
+	router.HandleFunc("/api/orchestrate/batch/{id}/items/{index}", s.ItemHandler()).Methods("GET")
+	serve := func(method, path, tenant, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("X-Tenant-ID", tenant)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/api/orchestrate/batch", "acme", `{"items": [{"description": "Build a billing service"}]}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var accepted struct {
+		BatchID   string `json:"batch_id"`
+		StatusURL string `json:"status_url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+
+	require.Eventually(t, func() bool {
+		w := serve(http.MethodGet, accepted.StatusURL+"/items/0", "acme", "")
+		var item BatchItem
+		return w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &item) == nil && item.Status == StatusSucceeded
+	}, 2*time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, accepted.StatusURL, "acme", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, accepted.StatusURL, "other", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, accepted.StatusURL+"/items/1", "acme", "").Code)
+}
@@ -0,0 +1,91 @@
+package orchestrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// EventType identifies a workflow progress event
+type EventType string
+
+const (
+	EventStepStarted       EventType = "step_started"
+	EventStepCompleted     EventType = "step_completed"
+	EventStepFailed        EventType = "step_failed"
+	EventWorkflowCompleted EventType = "workflow_completed"
+	EventWorkflowFailed    EventType = "workflow_failed"
+
+	// EventAwaitingClarification is sent when a workflow pauses for answers
+	// to questions about its description
+	EventAwaitingClarification EventType = "awaiting_clarification"
+)
+
+// StepResult is the outcome of one agent step
+type StepResult struct {
+	Agent       agents.AgentType       `json:"agent"`
+	Success     bool                   `json:"success"`
+	Output      string                 `json:"output"`
+	Confidence  float64                `json:"confidence"`
+	ExecutionMS int64                  `json:"execution_ms"`
+	Reused      bool                   `json:"reused,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	OutputRef   *agents.OutputRef      `json:"output_ref,omitempty"` // Set, and Output empty, when the output was spilled
+}
+
+// Workflow is a finished or running workflow as served over gRPC
+type Workflow struct {
+	ID        uuid.UUID    `json:"workflow_id"`
+	Success   bool         `json:"success"`
+	Steps     []StepResult `json:"steps"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// AgentInfo describes a registered agent
+type AgentInfo struct {
+	Type         agents.AgentType    `json:"type"`
+	Description  string              `json:"description"`
+	Capabilities []agents.Capability `json:"capabilities"`
+}
+
+// Event reports workflow progress as it happens
+type Event struct {
+	Type       EventType        `json:"type"`
+	WorkflowID uuid.UUID        `json:"workflow_id"`
+	Step       int              `json:"step"`
+	Agent      agents.AgentType `json:"agent,omitempty"`
+	Result     *StepResult      `json:"result,omitempty"`   // Completed steps
+	Error      string           `json:"error,omitempty"`    // Failed steps and workflows
+	Workflow   *Workflow        `json:"workflow,omitempty"` // Completed workflows
+	Time       time.Time        `json:"time"`
+}
+
+type observerKey struct{}
+
+// Observe returns a context under which Notify passes every workflow event
+// to fn. Observers already installed in ctx keep receiving events.
+func Observe(ctx context.Context, fn func(Event)) context.Context {
+	parent, _ := ctx.Value(observerKey{}).(func(Event))
+	if parent != nil {
+		inner := fn
+		fn = func(e Event) {
+			parent(e)
+			inner(e)
+		}
+	}
+	return context.WithValue(ctx, observerKey{}, fn)
+}
+
+// Notify reports an event to the observers installed in ctx, if any
+func Notify(ctx context.Context, e Event) {
+	fn, _ := ctx.Value(observerKey{}).(func(Event))
+	if fn == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	fn(e)
+}
@@ -0,0 +1,764 @@
+package orchestrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// ServiceName is the gRPC service described in proto/orchestrator.proto
+const ServiceName = "miosa.orchestrator.v1.Orchestrator"
+
+const (
+	methodOrchestrate    = "/" + ServiceName + "/Orchestrate"
+	methodStreamWorkflow = "/" + ServiceName + "/StreamWorkflow"
+	methodListAgents     = "/" + ServiceName + "/ListAgents"
+	methodGetWorkflow    = "/" + ServiceName + "/GetWorkflow"
+)
+
+// Backend is what an orchestrator binary exposes over gRPC
+type Backend interface {
+	// Orchestrate runs a validated request to completion, reporting
+	// progress through Notify on ctx
+	Orchestrate(ctx context.Context, req *Request) (*Workflow, error)
+	Workflow(id uuid.UUID) (*Workflow, bool)
+	Agents() []AgentInfo
+}
+
+// ErrWorkflowNotFound is returned by Client.GetWorkflow for unknown workflows
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// protoFile holds the message types of proto/orchestrator.proto. The
+// descriptors are built here rather than generated so the server needs no
+// protoc step; payloads are still typed protobuf on the wire.
+var protoFile = buildProtoFile()
+
+func buildProtoFile() protoreflect.FileDescriptor {
+	const (
+		str    = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		boolT  = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+		i32    = descriptorpb.FieldDescriptorProto_TYPE_INT32
+		i64    = descriptorpb.FieldDescriptorProto_TYPE_INT64
+		double = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+		msg    = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	repeated := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return f
+	}
+	message := func(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+	}
+	local := func(name string) string { return ".miosa.orchestrator.v1." + name }
+
+	seed := message("Seed",
+		field("rows", 1, i32, ""),
+		repeated(field("table_rows", 2, msg, local("Seed.TableRowsEntry"))),
+		field("random_seed", 3, i64, ""),
+	)
+	seed.NestedType = []*descriptorpb.DescriptorProto{{
+		Name:    proto.String("TableRowsEntry"),
+		Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, str, ""), field("value", 2, i32, "")},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}}
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("orchestrator.proto"),
+		Package:    proto.String("miosa.orchestrator.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/struct.proto", "google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("OrchestrateRequest",
+				field("description", 1, str, ""),
+				repeated(field("target_stack", 2, str, "")),
+				repeated(field("constraints", 3, str, "")),
+				field("language", 4, str, ""),
+				field("budget", 5, msg, local("Budget")),
+				field("seed", 6, msg, local("Seed")),
+				field("pipeline", 7, str, ""),
+				field("security", 8, msg, local("Security")),
+				field("params", 9, msg, ".google.protobuf.Struct"),
+				field("monorepo", 10, msg, local("Monorepo")),
+			),
+			message("Budget",
+				field("max_tokens", 1, i64, ""),
+				field("max_cost_usd", 2, double, ""),
+			),
+			seed,
+			message("Security",
+				field("headers", 1, boolT, ""),
+				field("csp", 2, str, ""),
+				field("hsts_max_age", 3, i32, ""),
+				field("csrf", 4, boolT, ""),
+				field("secure_cookies", 5, boolT, ""),
+			),
+			message("Monorepo",
+				field("tool", 1, str, ""),
+				field("module", 2, str, ""),
+				repeated(field("services", 3, str, "")),
+			),
+			message("StepResult",
+				field("agent", 1, str, ""),
+				field("success", 2, boolT, ""),
+				field("output", 3, str, ""),
+				field("confidence", 4, double, ""),
+				field("execution_ms", 5, i64, ""),
+				field("reused", 6, boolT, ""),
+				field("data", 7, msg, ".google.protobuf.Struct"),
+				field("output_ref", 8, msg, local("OutputRef")),
+			),
+			message("OutputRef",
+				field("ref", 1, str, ""),
+				field("size", 2, i64, ""),
+				field("preview", 3, str, ""),
+			),
+			message("Workflow",
+				field("workflow_id", 1, str, ""),
+				field("success", 2, boolT, ""),
+				repeated(field("steps", 3, msg, local("StepResult"))),
+				field("timestamp", 4, msg, ".google.protobuf.Timestamp"),
+			),
+			message("WorkflowEvent",
+				field("type", 1, str, ""),
+				field("workflow_id", 2, str, ""),
+				field("step", 3, i32, ""),
+				field("agent", 4, str, ""),
+				field("result", 5, msg, local("StepResult")),
+				field("error", 6, str, ""),
+				field("workflow", 7, msg, local("Workflow")),
+				field("time", 8, msg, ".google.protobuf.Timestamp"),
+			),
+			message("ListAgentsRequest"),
+			message("ListAgentsResponse",
+				repeated(field("agents", 1, msg, local("AgentInfo"))),
+			),
+			message("AgentInfo",
+				field("type", 1, str, ""),
+				field("description", 2, str, ""),
+				repeated(field("capabilities", 3, msg, local("Capability"))),
+			),
+			message("Capability",
+				field("name", 1, str, ""),
+				field("description", 2, str, ""),
+				field("required", 3, boolT, ""),
+				field("version", 4, str, ""),
+			),
+			message("GetWorkflowRequest",
+				field("workflow_id", 1, str, ""),
+			),
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(fmt.Sprintf("invalid orchestrator descriptor: %v", err))
+	}
+	return fd
+}
+
+// newMessage creates an empty message of a type in protoFile
+func newMessage(name string) pmsg {
+	return pmsg{dynamicpb.NewMessage(protoFile.Messages().ByName(protoreflect.Name(name)))}
+}
+
+// pmsg reads and writes message fields by name
+type pmsg struct {
+	protoreflect.Message
+}
+
+func (m pmsg) field(name string) protoreflect.FieldDescriptor {
+	return m.Descriptor().Fields().ByName(protoreflect.Name(name))
+}
+
+func (m pmsg) str(name string) string             { return m.Get(m.field(name)).String() }
+func (m pmsg) boolean(name string) bool           { return m.Get(m.field(name)).Bool() }
+func (m pmsg) float(name string) float64          { return m.Get(m.field(name)).Float() }
+func (m pmsg) integer(name string) int64          { return m.Get(m.field(name)).Int() }
+func (m pmsg) has(name string) bool               { return m.Has(m.field(name)) }
+func (m pmsg) message(name string) pmsg           { return pmsg{m.Get(m.field(name)).Message()} }
+func (m pmsg) list(name string) protoreflect.List { return m.Get(m.field(name)).List() }
+
+func (m pmsg) setStr(name, v string) {
+	m.Set(m.field(name), protoreflect.ValueOfString(v))
+}
+
+func (m pmsg) setBool(name string, v bool) {
+	m.Set(m.field(name), protoreflect.ValueOfBool(v))
+}
+
+func (m pmsg) setFloat(name string, v float64) {
+	m.Set(m.field(name), protoreflect.ValueOfFloat64(v))
+}
+
+func (m pmsg) setInt(name string, v int64) {
+	fd := m.field(name)
+	if fd.Kind() == protoreflect.Int32Kind {
+		m.Set(fd, protoreflect.ValueOfInt32(int32(v)))
+		return
+	}
+	m.Set(fd, protoreflect.ValueOfInt64(v))
+}
+
+func (m pmsg) setStrs(name string, vs []string) {
+	l := m.Mutable(m.field(name)).List()
+	for _, v := range vs {
+		l.Append(protoreflect.ValueOfString(v))
+	}
+}
+
+func (m pmsg) strs(name string) []string {
+	l := m.list(name)
+	if l.Len() == 0 {
+		return nil
+	}
+	out := make([]string, l.Len())
+	for i := range out {
+		out[i] = l.Get(i).String()
+	}
+	return out
+}
+
+// mutable returns the message field, creating it if unset
+func (m pmsg) mutable(name string) pmsg {
+	return pmsg{m.Mutable(m.field(name)).Message()}
+}
+
+// add appends a new element to a repeated message field
+func (m pmsg) add(name string) pmsg {
+	l := m.Mutable(m.field(name)).List()
+	v := l.NewElement()
+	l.Append(v)
+	return pmsg{v.Message()}
+}
+
+// setTime stores t in a google.protobuf.Timestamp field
+func (m pmsg) setTime(name string, t time.Time) {
+	if !t.IsZero() {
+		m.Set(m.field(name), protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()))
+	}
+}
+
+func (m pmsg) time(name string) time.Time {
+	if !m.has(name) {
+		return time.Time{}
+	}
+	ts := m.message(name)
+	return time.Unix(ts.integer("seconds"), ts.integer("nanos")).UTC()
+}
+
+// setData stores free-form agent data in a google.protobuf.Struct field
+func (m pmsg) setData(name string, data map[string]interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	// Data holds arbitrary Go values; their JSON form is what the REST API serves
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var plain map[string]interface{}
+	if err := json.Unmarshal(raw, &plain); err != nil {
+		return err
+	}
+	s, err := structpb.NewStruct(plain)
+	if err != nil {
+		return err
+	}
+	m.Set(m.field(name), protoreflect.ValueOfMessage(s.ProtoReflect()))
+	return nil
+}
+
+func (m pmsg) data(name string) (map[string]interface{}, error) {
+	if !m.has(name) {
+		return nil, nil
+	}
+	raw, err := proto.Marshal(m.message(name).Interface())
+	if err != nil {
+		return nil, err
+	}
+	var s structpb.Struct
+	if err := proto.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return s.AsMap(), nil
+}
+
+func requestToProto(r *Request) (pmsg, error) {
+	m := newMessage("OrchestrateRequest")
+	m.setStr("description", r.Description)
+	m.setStrs("target_stack", r.TargetStack)
+	m.setStrs("constraints", r.Constraints)
+	m.setStr("language", r.Language)
+	m.setStr("pipeline", r.Pipeline)
+	if r.Budget != nil {
+		b := m.mutable("budget")
+		b.setInt("max_tokens", int64(r.Budget.MaxTokens))
+		b.setFloat("max_cost_usd", r.Budget.MaxCostUSD)
+	}
+	if r.Seed != nil {
+		s := m.mutable("seed")
+		s.setInt("rows", int64(r.Seed.Rows))
+		s.setInt("random_seed", r.Seed.RandSeed)
+		rows := s.Mutable(s.field("table_rows")).Map()
+		for table, n := range r.Seed.TableRows {
+			rows.Set(protoreflect.ValueOfString(table).MapKey(), protoreflect.ValueOfInt32(int32(n)))
+		}
+	}
+	if r.Security != nil {
+		s := m.mutable("security")
+		s.setBool("headers", r.Security.Headers)
+		s.setStr("csp", r.Security.CSP)
+		s.setInt("hsts_max_age", int64(r.Security.HSTSMaxAge))
+		s.setBool("csrf", r.Security.CSRF)
+		s.setBool("secure_cookies", r.Security.SecureCookies)
+	}
+	if r.Monorepo != nil {
+		mr := m.mutable("monorepo")
+		mr.setStr("tool", r.Monorepo.Tool)
+		mr.setStr("module", r.Monorepo.Module)
+		mr.setStrs("services", r.Monorepo.Services)
+	}
+	if err := m.setData("params", r.Params); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func requestFromProto(m pmsg) (*Request, error) {
+	params, err := m.data("params")
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{
+		Description: m.str("description"),
+		TargetStack: m.strs("target_stack"),
+		Constraints: m.strs("constraints"),
+		Language:    m.str("language"),
+		Pipeline:    m.str("pipeline"),
+		Params:      params,
+	}
+	if m.has("budget") {
+		b := m.message("budget")
+		r.Budget = &Budget{MaxTokens: int(b.integer("max_tokens")), MaxCostUSD: b.float("max_cost_usd")}
+	}
+	if m.has("seed") {
+		s := m.message("seed")
+		r.Seed = &Seed{Rows: int(s.integer("rows")), RandSeed: s.integer("random_seed")}
+		if rows := s.Get(s.field("table_rows")).Map(); rows.Len() > 0 {
+			r.Seed.TableRows = make(map[string]int, rows.Len())
+			rows.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				r.Seed.TableRows[k.String()] = int(v.Int())
+				return true
+			})
+		}
+	}
+	if m.has("security") {
+		s := m.message("security")
+		r.Security = &Security{
+			Headers:       s.boolean("headers"),
+			CSP:           s.str("csp"),
+			HSTSMaxAge:    int(s.integer("hsts_max_age")),
+			CSRF:          s.boolean("csrf"),
+			SecureCookies: s.boolean("secure_cookies"),
+		}
+	}
+	if m.has("monorepo") {
+		mr := m.message("monorepo")
+		r.Monorepo = &Monorepo{Tool: mr.str("tool"), Module: mr.str("module"), Services: mr.strs("services")}
+	}
+	return r, nil
+}
+
+func stepToProto(m pmsg, s *StepResult) error {
+	m.setStr("agent", string(s.Agent))
+	m.setBool("success", s.Success)
+	m.setStr("output", s.Output)
+	m.setFloat("confidence", s.Confidence)
+	m.setInt("execution_ms", s.ExecutionMS)
+	m.setBool("reused", s.Reused)
+	if s.OutputRef != nil {
+		ref := m.mutable("output_ref")
+		ref.setStr("ref", s.OutputRef.Ref)
+		ref.setInt("size", int64(s.OutputRef.Size))
+		ref.setStr("preview", s.OutputRef.Preview)
+	}
+	return m.setData("data", s.Data)
+}
+
+func stepFromProto(m pmsg) (*StepResult, error) {
+	data, err := m.data("data")
+	if err != nil {
+		return nil, err
+	}
+	s := &StepResult{
+		Agent:       agents.AgentType(m.str("agent")),
+		Success:     m.boolean("success"),
+		Output:      m.str("output"),
+		Confidence:  m.float("confidence"),
+		ExecutionMS: m.integer("execution_ms"),
+		Reused:      m.boolean("reused"),
+		Data:        data,
+	}
+	if m.has("output_ref") {
+		ref := m.message("output_ref")
+		s.OutputRef = &agents.OutputRef{Ref: ref.str("ref"), Size: int(ref.integer("size")), Preview: ref.str("preview")}
+	}
+	return s, nil
+}
+
+func workflowToProto(m pmsg, w *Workflow) error {
+	m.setStr("workflow_id", w.ID.String())
+	m.setBool("success", w.Success)
+	m.setTime("timestamp", w.Timestamp)
+	for i := range w.Steps {
+		if err := stepToProto(m.add("steps"), &w.Steps[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func workflowFromProto(m pmsg) (*Workflow, error) {
+	id, err := uuid.Parse(m.str("workflow_id"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow id: %w", err)
+	}
+	w := &Workflow{ID: id, Success: m.boolean("success"), Timestamp: m.time("timestamp")}
+	steps := m.list("steps")
+	for i := 0; i < steps.Len(); i++ {
+		step, err := stepFromProto(pmsg{steps.Get(i).Message()})
+		if err != nil {
+			return nil, err
+		}
+		w.Steps = append(w.Steps, *step)
+	}
+	return w, nil
+}
+
+func eventToProto(e Event) (pmsg, error) {
+	m := newMessage("WorkflowEvent")
+	m.setStr("type", string(e.Type))
+	m.setStr("workflow_id", e.WorkflowID.String())
+	m.setInt("step", int64(e.Step))
+	m.setStr("agent", string(e.Agent))
+	m.setStr("error", e.Error)
+	m.setTime("time", e.Time)
+	if e.Result != nil {
+		if err := stepToProto(m.mutable("result"), e.Result); err != nil {
+			return m, err
+		}
+	}
+	if e.Workflow != nil {
+		if err := workflowToProto(m.mutable("workflow"), e.Workflow); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+func eventFromProto(m pmsg) (Event, error) {
+	e := Event{
+		Type:  EventType(m.str("type")),
+		Step:  int(m.integer("step")),
+		Agent: agents.AgentType(m.str("agent")),
+		Error: m.str("error"),
+		Time:  m.time("time"),
+	}
+	e.WorkflowID, _ = uuid.Parse(m.str("workflow_id"))
+	if m.has("result") {
+		step, err := stepFromProto(m.message("result"))
+		if err != nil {
+			return e, err
+		}
+		e.Result = step
+	}
+	if m.has("workflow") {
+		w, err := workflowFromProto(m.message("workflow"))
+		if err != nil {
+			return e, err
+		}
+		e.Workflow = w
+	}
+	return e, nil
+}
+
+// rpcServer implements the service over a Backend
+type rpcServer struct {
+	backend Backend
+}
+
+// RegisterOrchestratorServer serves the Orchestrator service on s
+func RegisterOrchestratorServer(s *grpc.Server, backend Backend) {
+	s.RegisterService(&serviceDesc, &rpcServer{backend: backend})
+}
+
+// request decodes and validates an OrchestrateRequest
+func (s *rpcServer) request(in pmsg) (*Request, error) {
+	req, err := requestFromProto(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return req, nil
+}
+
+func (s *rpcServer) orchestrate(ctx context.Context, in pmsg) (proto.Message, error) {
+	req, err := s.request(in)
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.backend.Orchestrate(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := newMessage("Workflow")
+	if err := workflowToProto(out, w); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out.Interface(), nil
+}
+
+func (s *rpcServer) streamWorkflow(in pmsg, stream grpc.ServerStream) error {
+	req, err := s.request(in)
+	if err != nil {
+		return err
+	}
+
+	// Sends stop at the first failure; the workflow itself runs on
+	var mu sync.Mutex
+	var sendErr error
+	send := func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if sendErr != nil {
+			return
+		}
+		m, err := eventToProto(e)
+		if err == nil {
+			err = stream.SendMsg(m.Interface())
+		}
+		sendErr = err
+	}
+
+	w, err := s.backend.Orchestrate(Observe(stream.Context(), send), req)
+	final := Event{Type: EventWorkflowCompleted, Workflow: w, Time: time.Now()}
+	if w != nil {
+		final.WorkflowID = w.ID
+	}
+	if err != nil {
+		final.Type = EventWorkflowFailed
+		final.Error = err.Error()
+	}
+	send(final)
+	return sendErr
+}
+
+func (s *rpcServer) listAgents(ctx context.Context, in pmsg) (proto.Message, error) {
+	out := newMessage("ListAgentsResponse")
+	for _, a := range s.backend.Agents() {
+		m := out.add("agents")
+		m.setStr("type", string(a.Type))
+		m.setStr("description", a.Description)
+		for _, c := range a.Capabilities {
+			cm := m.add("capabilities")
+			cm.setStr("name", c.Name)
+			cm.setStr("description", c.Description)
+			cm.setBool("required", c.Required)
+			cm.setStr("version", c.Version)
+		}
+	}
+	return out.Interface(), nil
+}
+
+func (s *rpcServer) getWorkflow(ctx context.Context, in pmsg) (proto.Message, error) {
+	id, err := uuid.Parse(in.str("workflow_id"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid workflow id")
+	}
+	w, ok := s.backend.Workflow(id)
+	if !ok {
+		return nil, status.Error(codes.NotFound, ErrWorkflowNotFound.Error())
+	}
+	out := newMessage("Workflow")
+	if err := workflowToProto(out, w); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out.Interface(), nil
+}
+
+// unaryHandler adapts a server method to grpc.MethodDesc
+func unaryHandler(input, fullMethod string, call func(*rpcServer, context.Context, pmsg) (proto.Message, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newMessage(input)
+		if err := dec(in.Interface()); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(*rpcServer), ctx, in)
+		}
+		if interceptor == nil {
+			return handler(ctx, in.Interface())
+		}
+		return interceptor(ctx, in.Interface(), &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, handler)
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Orchestrate", Handler: unaryHandler("OrchestrateRequest", methodOrchestrate, (*rpcServer).orchestrate)},
+		{MethodName: "ListAgents", Handler: unaryHandler("ListAgentsRequest", methodListAgents, (*rpcServer).listAgents)},
+		{MethodName: "GetWorkflow", Handler: unaryHandler("GetWorkflowRequest", methodGetWorkflow, (*rpcServer).getWorkflow)},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamWorkflow",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				in := newMessage("OrchestrateRequest")
+				if err := stream.RecvMsg(in.Interface()); err != nil {
+					return err
+				}
+				return srv.(*rpcServer).streamWorkflow(in, stream)
+			},
+		},
+	},
+	Metadata: "proto/orchestrator.proto",
+}
+
+// Client calls the Orchestrator service, returning typed results
+type Client struct {
+	conn grpc.ClientConnInterface
+}
+
+// NewClient creates a client on conn
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{conn: conn}
+}
+
+// Orchestrate runs a workflow and waits for its result
+func (c *Client) Orchestrate(ctx context.Context, req *Request) (*Workflow, error) {
+	in, err := requestToProto(req)
+	if err != nil {
+		return nil, err
+	}
+	out := newMessage("Workflow")
+	if err := c.conn.Invoke(ctx, methodOrchestrate, in.Interface(), out.Interface()); err != nil {
+		return nil, err
+	}
+	return workflowFromProto(out)
+}
+
+// StreamWorkflow runs a workflow, passing each progress event to fn as it
+// arrives. It returns the finished workflow, or the workflow's error.
+func (c *Client) StreamWorkflow(ctx context.Context, req *Request, fn func(Event)) (*Workflow, error) {
+	in, err := requestToProto(req)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], methodStreamWorkflow)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(in.Interface()); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	for {
+		m := newMessage("WorkflowEvent")
+		if err := stream.RecvMsg(m.Interface()); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("stream ended before the workflow finished")
+			}
+			return nil, err
+		}
+		e, err := eventFromProto(m)
+		if err != nil {
+			return nil, err
+		}
+		if fn != nil {
+			fn(e)
+		}
+		switch e.Type {
+		case EventWorkflowCompleted:
+			return e.Workflow, nil
+		case EventWorkflowFailed:
+			return e.Workflow, errors.New(e.Error)
+		}
+	}
+}
+
+// ListAgents returns the registered agents
+func (c *Client) ListAgents(ctx context.Context) ([]AgentInfo, error) {
+	out := newMessage("ListAgentsResponse")
+	if err := c.conn.Invoke(ctx, methodListAgents, newMessage("ListAgentsRequest").Interface(), out.Interface()); err != nil {
+		return nil, err
+	}
+	list := out.list("agents")
+	infos := make([]AgentInfo, list.Len())
+	for i := range infos {
+		m := pmsg{list.Get(i).Message()}
+		infos[i] = AgentInfo{Type: agents.AgentType(m.str("type")), Description: m.str("description")}
+		caps := m.list("capabilities")
+		for j := 0; j < caps.Len(); j++ {
+			cm := pmsg{caps.Get(j).Message()}
+			infos[i].Capabilities = append(infos[i].Capabilities, agents.Capability{
+				Name:        cm.str("name"),
+				Description: cm.str("description"),
+				Required:    cm.boolean("required"),
+				Version:     cm.str("version"),
+			})
+		}
+	}
+	return infos, nil
+}
+
+// GetWorkflow returns a workflow's result
+func (c *Client) GetWorkflow(ctx context.Context, id uuid.UUID) (*Workflow, error) {
+	in := newMessage("GetWorkflowRequest")
+	in.setStr("workflow_id", id.String())
+	out := newMessage("Workflow")
+	if err := c.conn.Invoke(ctx, methodGetWorkflow, in.Interface(), out.Interface()); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrWorkflowNotFound
+		}
+		return nil, err
+	}
+	return workflowFromProto(out)
+}
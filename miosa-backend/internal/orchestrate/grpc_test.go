@@ -0,0 +1,169 @@
+package orchestrate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// fakeBackend runs a two-step workflow and remembers it
+type fakeBackend struct {
+	got       *Request
+	workflows map[uuid.UUID]*Workflow
+}
+
+func (b *fakeBackend) Orchestrate(ctx context.Context, req *Request) (*Workflow, error) {
+	b.got = req
+	if req.Pipeline == "broken" {
+		return nil, errors.New("pipeline exploded")
+	}
+
+	w := &Workflow{ID: uuid.New(), Success: true, Timestamp: time.Unix(1700000000, 0).UTC()}
+	for i, agent := range []agents.AgentType{agents.AnalysisAgent, agents.DevelopmentAgent} {
+		Notify(ctx, Event{Type: EventStepStarted, WorkflowID: w.ID, Step: i, Agent: agent})
+		step := StepResult{
+			Agent:       agent,
+			Success:     true,
+			Output:      "done " + string(agent),
+			Confidence:  0.9,
+			ExecutionMS: 42,
+			Data:        map[string]interface{}{"files": []interface{}{"main.go"}, "score": 0.5},
+		}
+		if agent == agents.DevelopmentAgent {
+			step.OutputRef = &agents.OutputRef{Ref: "artifact://output/" + uuid.Nil.String(), Size: 4 << 20, Preview: "package main"}
+		}
+		w.Steps = append(w.Steps, step)
+		Notify(ctx, Event{Type: EventStepCompleted, WorkflowID: w.ID, Step: i, Agent: agent, Result: &step})
+	}
+	b.workflows[w.ID] = w
+	return w, nil
+}
+
+func (b *fakeBackend) Workflow(id uuid.UUID) (*Workflow, bool) {
+	w, ok := b.workflows[id]
+	return w, ok
+}
+
+func (b *fakeBackend) Agents() []AgentInfo {
+	return []AgentInfo{{
+		Type:         agents.AnalysisAgent,
+		Description:  "Analyzes requirements",
+		Capabilities: []agents.Capability{{Name: "requirements", Description: "Extract requirements", Required: true, Version: "1.0.0"}},
+	}}
+}
+
+func serveOrchestrator(t *testing.T, backend Backend) *Client {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterOrchestratorServer(srv, backend)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///orchestrator",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestGRPC_Orchestrate(t *testing.T) {
+	backend := &fakeBackend{workflows: make(map[uuid.UUID]*Workflow)}
+	client := serveOrchestrator(t, backend)
+	ctx := context.Background()
+
+	req := &Request{
+		Description: "Build a todo API with auth",
+		TargetStack: []string{"go", "postgres"},
+		Language:    "go",
+		Budget:      &Budget{MaxTokens: 50000, MaxCostUSD: 2.5},
+		Seed:        &Seed{Rows: 25, TableRows: map[string]int{"orders": 100}, RandSeed: 7},
+		Security:    &Security{Headers: true, HSTSMaxAge: 31536000, CSRF: true},
+		Monorepo:    &Monorepo{Tool: "go", Module: "example.com/shop", Services: []string{"orders", "billing"}},
+		Params:      Params{"app_name": "todo", "port": 8080},
+	}
+	w, err := client.Orchestrate(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, req, backend.got, "the request survives the round trip")
+
+	assert.True(t, w.Success)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), w.Timestamp)
+	require.Len(t, w.Steps, 2)
+	assert.Equal(t, agents.DevelopmentAgent, w.Steps[1].Agent)
+	assert.Equal(t, int64(42), w.Steps[1].ExecutionMS)
+	assert.Equal(t, []interface{}{"main.go"}, w.Steps[1].Data["files"])
+	require.NotNil(t, w.Steps[1].OutputRef, "spilled outputs keep their reference")
+	assert.Equal(t, 4<<20, w.Steps[1].OutputRef.Size)
+	assert.Nil(t, w.Steps[0].OutputRef)
+
+	got, err := client.GetWorkflow(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, w, got)
+
+	_, err = client.GetWorkflow(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrWorkflowNotFound)
+
+	_, err = client.Orchestrate(ctx, &Request{Description: "short"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPC_StreamWorkflow(t *testing.T) {
+	backend := &fakeBackend{workflows: make(map[uuid.UUID]*Workflow)}
+	client := serveOrchestrator(t, backend)
+
+	var events []Event
+	w, err := client.StreamWorkflow(context.Background(), &Request{Description: "Build a todo API with auth"}, func(e Event) {
+		events = append(events, e)
+	})
+	require.NoError(t, err)
+
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+		assert.Equal(t, w.ID, e.WorkflowID)
+		assert.False(t, e.Time.IsZero())
+	}
+	assert.Equal(t, []EventType{
+		EventStepStarted, EventStepCompleted, EventStepStarted, EventStepCompleted, EventWorkflowCompleted,
+	}, types)
+	require.NotNil(t, events[3].Result)
+	assert.Equal(t, "done development", events[3].Result.Output)
+	assert.Len(t, w.Steps, 2)
+
+	_, err = client.StreamWorkflow(context.Background(), &Request{Description: "Build a todo API with auth", Pipeline: "broken"}, nil)
+	assert.EqualError(t, err, "pipeline exploded")
+}
+
+func TestGRPC_ListAgents(t *testing.T) {
+	client := serveOrchestrator(t, &fakeBackend{})
+	infos, err := client.ListAgents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, (&fakeBackend{}).Agents(), infos)
+}
+
+func TestObserve(t *testing.T) {
+	var outer, inner []EventType
+	ctx := Observe(context.Background(), func(e Event) { outer = append(outer, e.Type) })
+	ctx = Observe(ctx, func(e Event) { inner = append(inner, e.Type) })
+
+	Notify(ctx, Event{Type: EventStepStarted})
+	Notify(context.Background(), Event{Type: EventStepFailed})
+
+	assert.Equal(t, []EventType{EventStepStarted}, outer)
+	assert.Equal(t, []EventType{EventStepStarted}, inner)
+}
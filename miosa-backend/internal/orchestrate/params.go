@@ -0,0 +1,209 @@
+package orchestrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamType is the type of a pipeline parameter
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamEnum   ParamType = "enum"
+	ParamInt    ParamType = "int"
+	ParamBool   ParamType = "bool"
+)
+
+// DefaultPipeline names the schema of the default sequence in
+// PipelineParams, which also applies to pipelines without their own
+const DefaultPipeline = "default"
+
+// Param declares a parameter a pipeline accepts
+type Param struct {
+	Name     string      `json:"name"`
+	Type     ParamType   `json:"type"`
+	Label    string      `json:"label"`             // How the prompt names it
+	Values   []string    `json:"values,omitempty"`  // Enum values
+	Pattern  string      `json:"pattern,omitempty"` // Strings must match it
+	Min      int         `json:"min,omitempty"`     // Int bounds, when Max is set
+	Max      int         `json:"max,omitempty"`
+	Default  interface{} `json:"default,omitempty"`
+	Required bool        `json:"required,omitempty"`
+}
+
+// Params are the values of a request's pipeline parameters, by name
+type Params map[string]interface{}
+
+// ParamSchema is the parameters of a pipeline, in prompt order
+type ParamSchema []Param
+
+// PipelineParams holds the parameter schema of each pipeline
+var PipelineParams = map[string]ParamSchema{
+	DefaultPipeline: {
+		{Name: "app_name", Type: ParamString, Label: "Application name", Pattern: `^[a-z][a-z0-9-]{0,62}$`},
+		{Name: "db", Type: ParamEnum, Label: "Database", Values: []string{"postgres", "mysql", "sqlite"}},
+		{Name: "auth", Type: ParamEnum, Label: "Authentication", Values: []string{"jwt", "oauth", "none"}},
+		{Name: "port", Type: ParamInt, Label: "HTTP port", Min: 1, Max: 65535},
+	},
+}
+
+// placeholderPattern matches {{name}} in descriptions and constraints
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z][a-z0-9_]*)\s*\}\}`)
+
+// SchemaFor returns the parameter schema of a pipeline
+func SchemaFor(pipeline string) ParamSchema {
+	if schema, ok := PipelineParams[pipeline]; ok {
+		return schema
+	}
+	return PipelineParams[DefaultPipeline]
+}
+
+// validate checks params against the schema, returning them normalized
+// with defaults filled in
+func (s ParamSchema) validate(verr *ValidationError, params Params) Params {
+	known := make(map[string]bool, len(s))
+	for _, p := range s {
+		known[p.Name] = true
+	}
+	var unknown []string
+	for name := range params {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		verr.add("params."+name, "is not a parameter of the pipeline")
+	}
+
+	out := make(Params)
+	for _, p := range s {
+		v, ok := params[p.Name]
+		if !ok || v == nil {
+			switch {
+			case p.Default != nil:
+				out[p.Name] = p.Default
+			case p.Required:
+				verr.add("params."+p.Name, "is required")
+			}
+			continue
+		}
+		value, err := p.normalize(v)
+		if err != nil {
+			verr.add("params."+p.Name, "%s", err)
+			continue
+		}
+		out[p.Name] = value
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// normalize converts a decoded value to the parameter's type. Strings are
+// accepted for every type so values can come from flags and form fields.
+func (p Param) normalize(v interface{}) (interface{}, error) {
+	switch p.Type {
+	case ParamInt:
+		var n float64
+		switch v := v.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		case string:
+			i, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("must be an integer")
+			}
+			n = float64(i)
+		default:
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if n != math.Trunc(n) {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if p.Max != 0 && (n < float64(p.Min) || n > float64(p.Max)) {
+			return nil, fmt.Errorf("must be between %d and %d", p.Min, p.Max)
+		}
+		return int(n), nil
+
+	case ParamBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+	}
+
+	str, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+	str = strings.TrimSpace(str)
+	switch {
+	case p.Type == ParamEnum:
+		str = strings.ToLower(str)
+		if !contains(p.Values, str) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(p.Values, ", "))
+		}
+	case str == "":
+		return nil, fmt.Errorf("must not be empty")
+	case len(str) > MaxItemLength:
+		return nil, fmt.Errorf("must be at most %d characters", MaxItemLength)
+	case p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(str):
+		return nil, fmt.Errorf("must match %s", p.Pattern)
+	}
+	return str, nil
+}
+
+// expand substitutes {{name}} placeholders in text with the parameters,
+// reporting placeholders for parameters that are not set under field
+func expand(verr *ValidationError, field, text string, params Params) string {
+	missing := make(map[string]bool)
+	text = placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := placeholderPattern.FindStringSubmatch(m)[1]
+		v, ok := params[name]
+		if !ok {
+			if !missing[name] {
+				missing[name] = true
+				verr.add(field, "references {{%s}}, which is not set in params", name)
+			}
+			return m
+		}
+		return fmt.Sprint(v)
+	})
+	return text
+}
+
+// requirements states the set parameters for the prompt, in schema order
+func (s ParamSchema) requirements(params Params) []string {
+	var reqs []string
+	for _, p := range s {
+		if v, ok := params[p.Name]; ok {
+			reqs = append(reqs, fmt.Sprintf("%s: %v", p.Label, v))
+		}
+	}
+	return reqs
+}
+
+// ParamsHandler serves GET /api/pipelines/params, the parameter schema of
+// each pipeline
+func ParamsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(PipelineParams)
+	}
+}
@@ -0,0 +1,80 @@
+package orchestrate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_Params(t *testing.T) {
+	req, err := decode(t, `{
+		"description": "Build {{app_name}}, an order tracking API",
+		"constraints": ["expose {{ app_name }} on port {{port}}"],
+		"params": {"app_name": "orders", "db": " MySQL ", "auth": "jwt", "port": "8080"}
+	}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Build orders, an order tracking API", req.Description)
+	assert.Equal(t, []string{"expose orders on port 8080"}, req.Constraints)
+	assert.Equal(t, Params{"app_name": "orders", "db": "mysql", "auth": "jwt", "port": 8080}, req.Params)
+
+	task := req.Task(uuid.New())
+	assert.Contains(t, task.Input, "\n\nRequirements:\n- Application name: orders\n- Database: mysql\n- Authentication: jwt\n- HTTP port: 8080\n")
+	assert.Equal(t, req.Params, task.Parameters["params"])
+
+	// Validating again, as the server does after the CLI, changes nothing
+	again := *req
+	require.NoError(t, again.Validate())
+	assert.Equal(t, req.Params, again.Params)
+}
+
+func TestDecode_InvalidParams(t *testing.T) {
+	_, err := decode(t, `{
+		"description": "Build {{app_name}} with {{cache}}",
+		"params": {"app_name": "Order Service", "db": "oracle", "port": 80.5, "theme": "dark"}
+	}`)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []FieldError{
+		{Field: "params.theme", Message: "is not a parameter of the pipeline"},
+		{Field: "params.app_name", Message: "must match ^[a-z][a-z0-9-]{0,62}$"},
+		{Field: "params.db", Message: "must be one of postgres, mysql, sqlite"},
+		{Field: "params.port", Message: "must be an integer"},
+		{Field: "description", Message: "references {{app_name}}, which is not set in params"},
+		{Field: "description", Message: "references {{cache}}, which is not set in params"},
+	}, verr.Fields)
+}
+
+func TestSchemaFor(t *testing.T) {
+	PipelineParams["static-site"] = ParamSchema{
+		{Name: "title", Type: ParamString, Label: "Site title", Required: true},
+		{Name: "dark_mode", Type: ParamBool, Label: "Dark mode", Default: false},
+	}
+	t.Cleanup(func() { delete(PipelineParams, "static-site") })
+
+	_, err := decode(t, `{"description": "Build a landing page", "pipeline": "static-site", "params": {"db": "postgres"}}`)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []FieldError{
+		{Field: "params.db", Message: "is not a parameter of the pipeline"},
+		{Field: "params.title", Message: "is required"},
+	}, verr.Fields)
+
+	req, err := decode(t, `{"description": "Build a landing page", "pipeline": "static-site", "params": {"title": "Acme"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, Params{"title": "Acme", "dark_mode": false}, req.Params)
+
+	// Pipelines without a schema take the default one
+	assert.Equal(t, PipelineParams[DefaultPipeline], SchemaFor("full-stack"))
+
+	w := httptest.NewRecorder()
+	ParamsHandler()(w, httptest.NewRequest(http.MethodGet, "/api/pipelines/params", nil))
+	var schemas map[string]ParamSchema
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&schemas))
+	assert.Equal(t, "title", schemas["static-site"][0].Name)
+	assert.Len(t, schemas[DefaultPipeline], 4)
+}
@@ -0,0 +1,77 @@
+package orchestrate
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TenantQuota bounds a tenant's share of the batch workers. Zero values
+// are unlimited, except Weight, where zero counts as 1.
+type TenantQuota struct {
+	Weight        float64 `yaml:"weight" json:"weight,omitempty"`                 // Share of the workers relative to other tenants with queued items
+	MaxConcurrent int     `yaml:"max_concurrent" json:"max_concurrent,omitempty"` // Items running at once
+	Burst         int     `yaml:"burst" json:"burst,omitempty"`                   // Items allowed over MaxConcurrent while no other tenant can use a worker
+}
+
+// Quotas are the quotas by tenant; tenants without their own get Default
+type Quotas struct {
+	Default TenantQuota            `yaml:"default" json:"default"`
+	Tenants map[string]TenantQuota `yaml:"tenants" json:"tenants,omitempty"`
+}
+
+// For returns the quota applying to tenant
+func (q Quotas) For(tenant string) TenantQuota {
+	if quota, ok := q.Tenants[tenant]; ok {
+		return quota
+	}
+	return q.Default
+}
+
+// LoadQuotas reads tenant quotas from a YAML file, e.g.
+//
+//	default: {max_concurrent: 2, burst: 2}
+//	tenants:
+//	  acme: {weight: 3, max_concurrent: 6}
+func LoadQuotas(path string) (Quotas, error) {
+	var quotas Quotas
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return quotas, fmt.Errorf("failed to read batch quotas: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &quotas); err != nil {
+		return quotas, fmt.Errorf("failed to parse batch quotas: %w", err)
+	}
+	return quotas, nil
+}
+
+// weight is the tenant's share, 1 unless set
+func (q TenantQuota) weight() float64 {
+	if q.Weight > 0 {
+		return q.Weight
+	}
+	return 1
+}
+
+// allows reports whether a tenant running n items may start another,
+// counting the burst allowance when burst is set
+func (q TenantQuota) allows(n int, burst bool) bool {
+	if q.MaxConcurrent <= 0 {
+		return true
+	}
+	limit := q.MaxConcurrent
+	if burst {
+		limit += q.Burst
+	}
+	return n < limit
+}
+
+// tenantQueue is a tenant's queued items and its place in the fair share
+type tenantQueue struct {
+	items   []queued
+	running int
+	// served is the tenant's virtual service: items started divided by
+	// weight. The tenant with the least goes next.
+	served float64
+}
@@ -0,0 +1,414 @@
+// Package orchestrate defines the request schema shared by the orchestrator
+// binaries' /api/orchestrate endpoints.
+package orchestrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+)
+
+// Limits on request fields
+const (
+	MaxBodyBytes         = 64 << 10
+	MinDescriptionLength = 10
+	MaxDescriptionLength = 8000
+	MaxStackItems        = 10
+	MaxConstraints       = 20
+	MaxItemLength        = 200
+	MaxSeedRows          = 1000
+	MaxCSPLength         = 1000
+	MaxHSTSMaxAge        = 2 * 365 * 24 * 60 * 60 // Two years, the preload list requirement
+	MaxServices          = 10
+)
+
+// Languages accepted in the language field
+var Languages = []string{
+	"go", "python", "javascript", "typescript", "java", "kotlin",
+	"rust", "ruby", "php", "csharp", "swift", "elixir",
+}
+
+var pipelinePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Go module paths and npm scopes accepted as a monorepo's module
+var (
+	goModulePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._~-]*(/[a-z0-9][a-z0-9._~-]*)*$`)
+	npmScopePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+)
+
+// Budget caps what a workflow may spend
+type Budget struct {
+	MaxTokens  int     `json:"max_tokens,omitempty"`
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+}
+
+// Seed sizes the demo data generated for the project's database
+type Seed struct {
+	Rows      int            `json:"rows,omitempty"`
+	TableRows map[string]int `json:"table_rows,omitempty"`
+	RandSeed  int64          `json:"random_seed,omitempty"` // Fixed seed for reproducible data
+}
+
+// Security selects the security middleware generated for the project's web
+// gateway
+type Security struct {
+	Headers       bool   `json:"headers,omitempty"`        // Helmet-style headers: nosniff, frame denial, referrer policy, CSP
+	CSP           string `json:"csp,omitempty"`            // Content-Security-Policy; empty = default-src 'self'
+	HSTSMaxAge    int    `json:"hsts_max_age,omitempty"`   // Strict-Transport-Security max-age in seconds; 0 = no HSTS
+	CSRF          bool   `json:"csrf,omitempty"`           // Require a double-submit CSRF token on state-changing requests
+	SecureCookies bool   `json:"secure_cookies,omitempty"` // Cookies are Secure, HttpOnly and SameSite=Strict
+}
+
+// Monorepo generates several services in one repository, with the code
+// they share in workspace packages instead of copied into each
+type Monorepo struct {
+	Tool     string   `json:"tool"`             // pnpm or go
+	Module   string   `json:"module,omitempty"` // Go module path or npm scope; defaults to app
+	Services []string `json:"services"`
+}
+
+// Request is the body of POST /api/orchestrate
+type Request struct {
+	Description string    `json:"description"`
+	TargetStack []string  `json:"target_stack,omitempty"`
+	Constraints []string  `json:"constraints,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Budget      *Budget   `json:"budget,omitempty"`
+	Seed        *Seed     `json:"seed,omitempty"`
+	Security    *Security `json:"security,omitempty"`
+	Monorepo    *Monorepo `json:"monorepo,omitempty"`
+	Pipeline    string    `json:"pipeline,omitempty"`
+	Params      Params    `json:"params,omitempty"` // Validated against the pipeline's ParamSchema
+	Async       bool      `json:"async,omitempty"`
+	Clarify     bool      `json:"clarify,omitempty"` // Pause for answers first if the description is ambiguous
+
+	// TenantID is set by the server from the caller, never from the body
+	TenantID uuid.UUID `json:"-"`
+}
+
+// FieldError describes one invalid field
+type FieldError = apierror.FieldError
+
+// ValidationError lists every invalid field of a request
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate normalizes the request and checks every field
+func (r *Request) Validate() error {
+	verr := &ValidationError{}
+
+	// Parameters are substituted into the description before it is checked
+	r.Params = SchemaFor(r.Pipeline).validate(verr, r.Params)
+	r.Description = expand(verr, "description", strings.TrimSpace(r.Description), r.Params)
+	switch n := len([]rune(r.Description)); {
+	case n == 0:
+		verr.add("description", "is required")
+	case n < MinDescriptionLength:
+		verr.add("description", "must be at least %d characters", MinDescriptionLength)
+	case n > MaxDescriptionLength:
+		verr.add("description", "must be at most %d characters, got %d", MaxDescriptionLength, n)
+	}
+
+	r.TargetStack = validateList(verr, "target_stack", r.TargetStack, MaxStackItems)
+	r.Constraints = validateList(verr, "constraints", r.Constraints, MaxConstraints)
+	for i, c := range r.Constraints {
+		r.Constraints[i] = expand(verr, fmt.Sprintf("constraints[%d]", i), c, r.Params)
+	}
+
+	r.Language = strings.ToLower(strings.TrimSpace(r.Language))
+	if r.Language != "" && !contains(Languages, r.Language) {
+		verr.add("language", "must be one of %s", strings.Join(Languages, ", "))
+	}
+
+	if r.Budget != nil {
+		if r.Budget.MaxTokens < 0 {
+			verr.add("budget.max_tokens", "must not be negative")
+		}
+		if r.Budget.MaxCostUSD < 0 {
+			verr.add("budget.max_cost_usd", "must not be negative")
+		}
+	}
+
+	if r.Seed != nil {
+		if r.Seed.Rows < 0 || r.Seed.Rows > MaxSeedRows {
+			verr.add("seed.rows", "must be between 0 and %d", MaxSeedRows)
+		}
+		for table, rows := range r.Seed.TableRows {
+			if rows < 0 || rows > MaxSeedRows {
+				verr.add("seed.table_rows."+table, "must be between 0 and %d", MaxSeedRows)
+			}
+		}
+	}
+
+	if r.Security != nil {
+		r.Security.CSP = strings.TrimSpace(r.Security.CSP)
+		if !r.Security.Headers && !r.Security.CSRF && !r.Security.SecureCookies {
+			verr.add("security", "must enable at least one of headers, csrf or secure_cookies")
+		}
+		if len(r.Security.CSP) > MaxCSPLength {
+			verr.add("security.csp", "must be at most %d characters", MaxCSPLength)
+		}
+		if strings.ContainsAny(r.Security.CSP, "\r\n") {
+			verr.add("security.csp", "must be a single line")
+		}
+		if (r.Security.CSP != "" || r.Security.HSTSMaxAge != 0) && !r.Security.Headers {
+			verr.add("security.headers", "must be enabled to set csp or hsts_max_age")
+		}
+		if r.Security.HSTSMaxAge < 0 || r.Security.HSTSMaxAge > MaxHSTSMaxAge {
+			verr.add("security.hsts_max_age", "must be between 0 and %d", MaxHSTSMaxAge)
+		}
+	}
+
+	if r.Monorepo != nil {
+		r.Monorepo.validate(verr)
+	}
+
+	if r.Pipeline != "" && !pipelinePattern.MatchString(r.Pipeline) {
+		verr.add("pipeline", "must be lowercase letters, digits, '-' or '_' (max 64)")
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (m *Monorepo) validate(verr *ValidationError) {
+	m.Tool = strings.ToLower(strings.TrimSpace(m.Tool))
+	m.Module = strings.TrimSpace(m.Module)
+	if m.Module == "" {
+		m.Module = "app"
+	}
+	switch m.Tool {
+	case agents.MonorepoGo:
+		if len(m.Module) > MaxItemLength || !goModulePattern.MatchString(m.Module) {
+			verr.add("monorepo.module", "must be a lowercase Go module path")
+		}
+	case agents.MonorepoPNPM:
+		if !npmScopePattern.MatchString(m.Module) {
+			verr.add("monorepo.module", "must be an npm scope without the @")
+		}
+	default:
+		verr.add("monorepo.tool", "must be one of %s, %s", agents.MonorepoPNPM, agents.MonorepoGo)
+	}
+
+	if len(m.Services) < 2 || len(m.Services) > MaxServices {
+		verr.add("monorepo.services", "must have between 2 and %d services, got %d", MaxServices, len(m.Services))
+	}
+	seen := make(map[string]bool, len(m.Services))
+	for i, name := range m.Services {
+		name = strings.TrimSpace(name)
+		m.Services[i] = name
+		switch {
+		case !agents.ValidPackageName(name):
+			verr.add(fmt.Sprintf("monorepo.services[%d]", i), "must be lowercase letters, digits or '-', starting with a letter (max 40)")
+		case seen[name]:
+			verr.add(fmt.Sprintf("monorepo.services[%d]", i), "duplicates %q", name)
+		}
+		seen[name] = true
+	}
+}
+
+func validateList(verr *ValidationError, field string, items []string, max int) []string {
+	if len(items) > max {
+		verr.add(field, "must have at most %d items, got %d", max, len(items))
+	}
+	cleaned := make([]string, 0, len(items))
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			verr.add(fmt.Sprintf("%s[%d]", field, i), "must not be empty")
+		case len(item) > MaxItemLength:
+			verr.add(fmt.Sprintf("%s[%d]", field, i), "must be at most %d characters", MaxItemLength)
+		}
+		cleaned = append(cleaned, item)
+	}
+	if len(cleaned) == 0 {
+		return nil
+	}
+	return cleaned
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode reads and validates a request body. Unknown fields are rejected so
+// typos in option names are reported instead of silently ignored.
+func Decode(w http.ResponseWriter, r *http.Request) (*Request, error) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	var req Request
+	if err := dec.Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			return nil, &ValidationError{Fields: []FieldError{{Field: "body", Message: fmt.Sprintf("must be at most %d bytes", MaxBodyBytes)}}}
+		case errors.Is(err, io.EOF):
+			return nil, &ValidationError{Fields: []FieldError{{Field: "body", Message: "is required"}}}
+		default:
+			return nil, &ValidationError{Fields: []FieldError{{Field: "body", Message: err.Error()}}}
+		}
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// WriteError writes a problem+json response: 400 listing the invalid
+// fields of a validation error, or the status of err's category
+func WriteError(w http.ResponseWriter, err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		err = apierror.Invalid("invalid request", verr.Fields...)
+	}
+	apierror.Write(w, nil, err)
+}
+
+// Prompt returns the description followed by the requested options so that
+// agents working only from task input still honour them
+func (r *Request) Prompt() string {
+	var sb strings.Builder
+	sb.WriteString(r.Description)
+
+	if r.Language != "" || len(r.TargetStack) > 0 || len(r.Constraints) > 0 || r.Security != nil || r.Monorepo != nil || len(r.Params) > 0 {
+		sb.WriteString("\n\nRequirements:")
+		for _, req := range SchemaFor(r.Pipeline).requirements(r.Params) {
+			sb.WriteString("\n- " + req)
+		}
+		if r.Language != "" {
+			sb.WriteString("\n- Language: " + r.Language)
+		}
+		if len(r.TargetStack) > 0 {
+			sb.WriteString("\n- Target stack: " + strings.Join(r.TargetStack, ", "))
+		}
+		for _, c := range r.Constraints {
+			sb.WriteString("\n- " + c)
+		}
+		if r.Security != nil {
+			for _, req := range r.Security.requirements() {
+				sb.WriteString("\n- Security: " + req)
+			}
+		}
+		if r.Monorepo != nil {
+			sb.WriteString("\n- Monorepo: services " + strings.Join(r.Monorepo.Services, ", ") + " in one " + r.Monorepo.Tool + " workspace, sharing code through workspace packages")
+		}
+	}
+	return sb.String()
+}
+
+// requirements states the enabled security options for the prompt
+func (s *Security) requirements() []string {
+	var reqs []string
+	if s.Headers {
+		csp := s.CSP
+		if csp == "" {
+			csp = "default-src 'self'"
+		}
+		reqs = append(reqs, "the web gateway sends security headers (X-Content-Type-Options, X-Frame-Options, Referrer-Policy, Content-Security-Policy: "+csp+")")
+		if s.HSTSMaxAge > 0 {
+			reqs = append(reqs, fmt.Sprintf("responses send Strict-Transport-Security with max-age=%d", s.HSTSMaxAge))
+		}
+	}
+	if s.CSRF {
+		reqs = append(reqs, "state-changing requests require a CSRF token (double-submit cookie echoed in the X-CSRF-Token header)")
+	}
+	if s.SecureCookies {
+		reqs = append(reqs, "every cookie is Secure, HttpOnly and SameSite=Strict")
+	}
+	return reqs
+}
+
+// Task builds the workflow's root task. Options are also exposed as task
+// parameters for agents that read them directly.
+func (r *Request) Task(id uuid.UUID) agents.Task {
+	params := make(map[string]interface{})
+	if r.Language != "" {
+		params["language"] = r.Language
+	}
+	if len(r.TargetStack) > 0 {
+		params["target_stack"] = r.TargetStack
+	}
+	if len(r.Constraints) > 0 {
+		params["constraints"] = r.Constraints
+	}
+	if r.Budget != nil {
+		params["budget"] = *r.Budget
+	}
+	if r.Pipeline != "" {
+		params["pipeline"] = r.Pipeline
+	}
+	if len(r.Params) > 0 {
+		params["params"] = r.Params
+	}
+	if r.Seed != nil {
+		params["seed"] = *r.Seed
+	}
+	if r.Security != nil {
+		params["security"] = *r.Security
+	}
+	if r.Monorepo != nil {
+		params[agents.MonorepoKey] = agents.NewMonorepoPlan(r.Monorepo.Tool, r.Monorepo.Module, r.Monorepo.Services)
+	}
+
+	return agents.Task{
+		ID:         id,
+		Type:       "implementation",
+		Input:      r.Prompt(),
+		Parameters: params,
+		Context: &agents.TaskContext{
+			TenantID: r.TenantID,
+			Phase:    "initialization",
+			Memory:   make(map[string]interface{}),
+		},
+	}
+}
+
+// ApplyProfile fills in what the request leaves open from the tenant's
+// stack profile: the language, and the profile's framework, ORM and test
+// framework as the target stack. Explicit choices in the request win.
+func (r *Request) ApplyProfile(p *agents.StackProfile) {
+	if p.IsZero() {
+		return
+	}
+	if r.Language == "" {
+		for _, lang := range Languages {
+			if strings.EqualFold(p.Language, lang) {
+				r.Language = lang
+				break
+			}
+		}
+	}
+	if len(r.TargetStack) == 0 {
+		r.TargetStack = p.Stack()
+	}
+}
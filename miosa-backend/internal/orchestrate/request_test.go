@@ -0,0 +1,152 @@
+package orchestrate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func decode(t *testing.T, body string) (*Request, error) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/orchestrate", strings.NewReader(body))
+	return Decode(httptest.NewRecorder(), r)
+}
+
+func TestDecode_Valid(t *testing.T) {
+	req, err := decode(t, `{
+		"description": "  Build a URL shortener with analytics  ",
+		"target_stack": ["postgres", " redis "],
+		"constraints": ["no external SaaS"],
+		"language": "Go",
+		"budget": {"max_tokens": 50000, "max_cost_usd": 2.5},
+		"pipeline": "full-stack",
+		"seed": {"rows": 25, "table_rows": {"orders": 100}},
+		"security": {"headers": true, "csrf": true, "secure_cookies": true},
+		"monorepo": {"tool": "PNPM", "services": ["web", " api "]},
+		"async": true
+	}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Build a URL shortener with analytics", req.Description)
+	assert.Equal(t, []string{"postgres", "redis"}, req.TargetStack)
+	assert.Equal(t, "go", req.Language)
+	assert.True(t, req.Async)
+
+	task := req.Task(uuid.New())
+	assert.Contains(t, task.Input, "- Language: go")
+	assert.Contains(t, task.Input, "- Target stack: postgres, redis")
+	assert.Contains(t, task.Input, "- no external SaaS")
+	assert.Equal(t, "full-stack", task.Parameters["pipeline"])
+	assert.Equal(t, Budget{MaxTokens: 50000, MaxCostUSD: 2.5}, task.Parameters["budget"])
+	assert.Equal(t, Seed{Rows: 25, TableRows: map[string]int{"orders": 100}}, task.Parameters["seed"])
+	assert.Equal(t, Security{Headers: true, CSRF: true, SecureCookies: true}, task.Parameters["security"])
+	assert.Contains(t, task.Input, "- Security: the web gateway sends security headers (X-Content-Type-Options, X-Frame-Options, Referrer-Policy, Content-Security-Policy: default-src 'self')")
+	assert.Contains(t, task.Input, "- Security: every cookie is Secure, HttpOnly and SameSite=Strict")
+	assert.Contains(t, task.Input, "- Monorepo: services web, api in one pnpm workspace")
+	plan := task.Parameters[agents.MonorepoKey].(*agents.MonorepoPlan)
+	assert.Equal(t, "app", plan.Module)
+	assert.Equal(t, "apps/api", plan.Services[1].Path)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"empty body", ``, []string{"body"}},
+		{"unknown field", `{"description": "Build a todo app", "langauge": "go"}`, []string{"body"}},
+		{"missing description", `{}`, []string{"description"}},
+		{"short description", `{"description": "app"}`, []string{"description"}},
+		{"long description", `{"description": "` + strings.Repeat("a", MaxDescriptionLength+1) + `"}`, []string{"description"}},
+		{
+			"bad options",
+			`{"description": "Build a todo app", "language": "cobol", "pipeline": "Bad Name",
+			  "constraints": [""], "budget": {"max_tokens": -1}, "seed": {"rows": 5000}}`,
+			[]string{"constraints[0]", "language", "budget.max_tokens", "seed.rows", "pipeline"},
+		},
+		{"empty security", `{"description": "Build a todo app", "security": {}}`, []string{"security"}},
+		{
+			"bad security",
+			`{"description": "Build a todo app", "security": {"csrf": true, "csp": "default-src *", "hsts_max_age": -1}}`,
+			[]string{"security.headers", "security.hsts_max_age"},
+		},
+		{
+			"bad monorepo",
+			`{"description": "Build a todo app", "monorepo": {"tool": "lerna", "services": ["web", "Web-2", "web"]}}`,
+			[]string{"monorepo.tool", "monorepo.services[1]", "monorepo.services[2]"},
+		},
+		{
+			"bad monorepo module",
+			`{"description": "Build a todo app", "monorepo": {"tool": "go", "module": "Example.com/x", "services": ["web"]}}`,
+			[]string{"monorepo.module", "monorepo.services"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode(t, tt.body)
+			require.Error(t, err)
+
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			fields := make([]string, len(verr.Fields))
+			for i, f := range verr.Fields {
+				fields[i] = f.Field
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, &ValidationError{Fields: []FieldError{{Field: "description", Message: "is required"}}})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var body struct {
+		Category string       `json:"category"`
+		Detail   string       `json:"detail"`
+		Fields   []FieldError `json:"fields"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "validation", body.Category)
+	assert.Equal(t, "invalid request", body.Detail)
+	assert.Equal(t, "description", body.Fields[0].Field)
+
+	w = httptest.NewRecorder()
+	WriteError(w, errors.New("dial tcp 10.0.0.3:5432: connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "10.0.0.3", "internal causes stay out of responses")
+}
+
+func TestApplyProfile(t *testing.T) {
+	profile := &agents.StackProfile{Language: "Go", Framework: "chi", ORM: "sqlc", Conventions: []string{"wrap errors"}}
+
+	_, err := decode(t, `{"description":"Build a todo API with auth","tenant_id":"`+uuid.NewString()+`"}`)
+	assert.Error(t, err, "the tenant never comes from the body")
+
+	req, err := decode(t, `{"description":"Build a todo API with auth"}`)
+	require.NoError(t, err)
+	req.ApplyProfile(profile)
+	assert.Equal(t, "go", req.Language)
+	assert.Equal(t, []string{"chi", "sqlc"}, req.TargetStack)
+
+	explicit := &Request{Description: "Build a todo API", Language: "python", TargetStack: []string{"fastapi"}}
+	explicit.ApplyProfile(profile)
+	assert.Equal(t, "python", explicit.Language)
+	assert.Equal(t, []string{"fastapi"}, explicit.TargetStack)
+
+	tenant := uuid.New()
+	explicit.TenantID = tenant
+	assert.Equal(t, tenant, explicit.Task(uuid.New()).Context.TenantID)
+}
@@ -0,0 +1,121 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// Agent is a registered out-of-process agent. Its manifest is fetched once
+// at registration; Execute and Health go over the wire on every call.
+type Agent struct {
+	agentType agents.AgentType
+	address   string
+	timeout   time.Duration
+	cost      float64
+	manifest  Manifest
+	conn      *grpc.ClientConn
+	client    client
+	health    healthpb.HealthClient
+}
+
+// newAgent wraps an open connection after fetching the plugin's manifest.
+// agentType overrides the type the plugin reports, if set.
+func newAgent(ctx context.Context, conn *grpc.ClientConn, agentType agents.AgentType, timeout time.Duration) (*Agent, error) {
+	a := &Agent{
+		address: conn.Target(),
+		timeout: timeout,
+		conn:    conn,
+		client:  client{conn: conn},
+		health:  healthpb.NewHealthClient(conn),
+	}
+	if err := a.Health(ctx); err != nil {
+		return nil, err
+	}
+	manifest, err := a.client.manifest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch capabilities from plugin %s: %w", a.address, err)
+	}
+	a.manifest = *manifest
+
+	a.agentType = agentType
+	if a.agentType == "" {
+		a.agentType = manifest.Type
+	}
+	if a.agentType == "" {
+		return nil, fmt.Errorf("plugin %s did not report an agent type", a.address)
+	}
+	return a, nil
+}
+
+func (a *Agent) GetType() agents.AgentType {
+	return a.agentType
+}
+
+func (a *Agent) GetDescription() string {
+	if a.manifest.Description != "" {
+		return a.manifest.Description
+	}
+	return fmt.Sprintf("External agent plugin at %s", a.address)
+}
+
+func (a *Agent) GetCapabilities() []agents.Capability {
+	return append([]agents.Capability(nil), a.manifest.Capabilities...)
+}
+
+// CostWeight is the relative cost declared at registration; 0 means the
+// default of 1
+func (a *Agent) CostWeight() float64 {
+	return a.cost
+}
+
+// Execute forwards the task to the plugin, bounded by the plugin timeout
+// and the task's own timeout, whichever is shorter
+func (a *Agent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	start := time.Now()
+	timeout := a.timeout
+	if task.Timeout > 0 && (timeout <= 0 || task.Timeout < timeout) {
+		timeout = task.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result, err := a.client.execute(ctx, task)
+	if err != nil {
+		err = fmt.Errorf("plugin %s failed: %w", a.agentType, err)
+		return &agents.Result{
+			Success:     false,
+			Error:       err,
+			ExecutionMS: time.Since(start).Milliseconds(),
+		}, err
+	}
+	if result.ExecutionMS == 0 {
+		result.ExecutionMS = time.Since(start).Milliseconds()
+	}
+	return result, nil
+}
+
+// Health reports an error unless the plugin's health service is SERVING
+func (a *Agent) Health(ctx context.Context) error {
+	resp, err := a.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("plugin %s health check failed: %w", a.address, err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("plugin %s is %s", a.address, resp.GetStatus())
+	}
+	return nil
+}
+
+// Close releases the plugin connection
+func (a *Agent) Close() error {
+	return a.conn.Close()
+}
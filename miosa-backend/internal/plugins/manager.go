@@ -0,0 +1,173 @@
+package plugins
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+)
+
+// registrationTimeout bounds the health check and manifest fetch when a
+// plugin is registered
+const registrationTimeout = 10 * time.Second
+
+// Manager registers out-of-process agents and tracks their connections
+type Manager struct {
+	logger  *zap.Logger
+	mu      sync.Mutex
+	plugins map[agents.AgentType]*Agent
+}
+
+// NewManager creates a manager with no plugins
+func NewManager(logger *zap.Logger) *Manager {
+	return &Manager{
+		logger:  logger,
+		plugins: make(map[agents.AgentType]*Agent),
+	}
+}
+
+// LoadAll registers every configured plugin. A plugin that is unreachable
+// or unhealthy is logged and skipped so one bad plugin doesn't keep the
+// orchestrator from starting; the number registered is returned.
+func (m *Manager) LoadAll(ctx context.Context, plugins []config.PluginConfig) int {
+	loaded := 0
+	for _, p := range plugins {
+		if _, err := m.Register(ctx, p); err != nil {
+			m.logger.Warn("Skipping agent plugin",
+				zap.String("type", p.Type),
+				zap.String("address", p.Address),
+				zap.Error(err))
+			continue
+		}
+		loaded++
+	}
+	return loaded
+}
+
+// Register dials a plugin, checks its health, fetches its capabilities and
+// adds it to the agent registry
+func (m *Manager) Register(ctx context.Context, p config.PluginConfig, opts ...grpc.DialOption) (*Agent, error) {
+	agent, err := m.connect(ctx, p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.add(agent); err != nil {
+		agent.Close()
+		return nil, err
+	}
+	return agent, nil
+}
+
+// connect dials a plugin and fetches its manifest without registering it
+func (m *Manager) connect(ctx context.Context, p config.PluginConfig, opts ...grpc.DialOption) (*Agent, error) {
+	creds := insecure.NewCredentials()
+	if p.TLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)
+	if p.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(p.Token)))
+	}
+
+	conn, err := grpc.NewClient(p.Address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial plugin %s: %w", p.Address, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, registrationTimeout)
+	defer cancel()
+	agent, err := newAgent(ctx, conn, agents.AgentType(p.Type), p.Timeout)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return agent, nil
+}
+
+// add inserts a connected plugin into the agent registry
+func (m *Manager) add(agent *Agent) error {
+	if err := agents.Register(agent); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.plugins[agent.GetType()] = agent
+	m.mu.Unlock()
+
+	m.logger.Info("Registered agent plugin",
+		zap.String("type", string(agent.GetType())),
+		zap.String("address", agent.address),
+		zap.Int("capabilities", len(agent.GetCapabilities())))
+	return nil
+}
+
+// bearerToken sends a static token in the authorization metadata. Plugins
+// usually sit on a private network, so plaintext transports are allowed.
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// Deregister removes a plugin from the registry and closes its connection
+func (m *Manager) Deregister(agentType agents.AgentType) error {
+	m.mu.Lock()
+	agent, ok := m.plugins[agentType]
+	delete(m.plugins, agentType)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("agent %s is not a plugin", agentType)
+	}
+
+	if err := agents.Unregister(agentType); err != nil {
+		m.logger.Warn("Plugin was not in the registry", zap.String("type", string(agentType)), zap.Error(err))
+	}
+	return agent.Close()
+}
+
+// HealthCheck probes every plugin
+func (m *Manager) HealthCheck(ctx context.Context) map[agents.AgentType]bool {
+	m.mu.Lock()
+	plugins := make([]*Agent, 0, len(m.plugins))
+	for _, a := range m.plugins {
+		plugins = append(plugins, a)
+	}
+	m.mu.Unlock()
+
+	status := make(map[agents.AgentType]bool, len(plugins))
+	for _, a := range plugins {
+		status[a.GetType()] = a.Health(ctx) == nil
+	}
+	return status
+}
+
+// Close deregisters every plugin
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	types := make([]agents.AgentType, 0, len(m.plugins))
+	for t := range m.plugins {
+		types = append(types, t)
+	}
+	m.mu.Unlock()
+
+	var firstErr error
+	for _, t := range types {
+		if err := m.Deregister(t); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,207 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+)
+
+var agentTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,62}$`)
+
+// ErrNotPlugin is returned when deregistering an agent the manager didn't
+// register, such as a built-in agent
+var ErrNotPlugin = errors.New("agent is not a registered plugin")
+
+// AgentManifest describes an agent published to the marketplace: where it
+// runs, how to authenticate to it, what it can do and what it costs
+type AgentManifest struct {
+	Type         agents.AgentType    `json:"type"`
+	Endpoint     string              `json:"endpoint"` // gRPC host:port
+	Auth         ManifestAuth        `json:"auth,omitempty"`
+	Capabilities []agents.Capability `json:"capabilities,omitempty"`
+	CostWeight   float64             `json:"cost_weight,omitempty"` // relative to built-in agents at 1
+	TimeoutMS    int64               `json:"timeout_ms,omitempty"`
+	TLS          bool                `json:"tls,omitempty"`
+	Description  string              `json:"description,omitempty"`
+	RegisteredAt time.Time           `json:"registered_at"`
+}
+
+// ManifestAuth holds the credentials the orchestrator presents to the agent
+type ManifestAuth struct {
+	Type  string `json:"type,omitempty"` // "none" or "bearer"
+	Token string `json:"token,omitempty"`
+}
+
+// Validate checks the manifest before any connection is attempted
+func (m *AgentManifest) Validate() error {
+	if !agentTypePattern.MatchString(string(m.Type)) {
+		return fmt.Errorf("type must be lowercase letters, digits and underscores")
+	}
+	if m.Type == agents.OrchestratorAgent {
+		return fmt.Errorf("type %q is reserved", m.Type)
+	}
+	if _, _, err := net.SplitHostPort(m.Endpoint); err != nil {
+		return fmt.Errorf("endpoint must be host:port: %w", err)
+	}
+	switch m.Auth.Type {
+	case "", "none":
+		if m.Auth.Token != "" {
+			return fmt.Errorf("auth token given without auth type bearer")
+		}
+	case "bearer":
+		if m.Auth.Token == "" {
+			return fmt.Errorf("bearer auth requires a token")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %q", m.Auth.Type)
+	}
+	if m.CostWeight < 0 {
+		return fmt.Errorf("cost_weight must not be negative")
+	}
+	if m.TimeoutMS < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	return nil
+}
+
+// Redacted returns a copy safe to return from the API
+func (m AgentManifest) Redacted() AgentManifest {
+	if m.Auth.Token != "" {
+		m.Auth.Token = "[REDACTED]"
+	}
+	return m
+}
+
+func (m *AgentManifest) pluginConfig() config.PluginConfig {
+	return config.PluginConfig{
+		Type:    string(m.Type),
+		Address: m.Endpoint,
+		Timeout: time.Duration(m.TimeoutMS) * time.Millisecond,
+		TLS:     m.TLS,
+		Token:   m.Auth.Token,
+	}
+}
+
+// ManifestStore persists marketplace manifests so hot-registered agents
+// survive restarts
+type ManifestStore interface {
+	Save(ctx context.Context, m *AgentManifest) error
+	Delete(ctx context.Context, agentType agents.AgentType) error
+	List(ctx context.Context) ([]*AgentManifest, error)
+}
+
+// RegisterManifest validates a manifest, connects to the agent and checks
+// its health, then inserts it into the registry and persists it. Declared
+// capabilities must all be served by the agent; if none are declared the
+// agent's own list is used.
+func (m *Manager) RegisterManifest(ctx context.Context, store ManifestStore, manifest *AgentManifest, opts ...grpc.DialOption) (*Agent, error) {
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	if agents.IsRegistered(manifest.Type) {
+		return nil, fmt.Errorf("agent %s already registered", manifest.Type)
+	}
+
+	agent, err := m.connect(ctx, manifest.pluginConfig(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyManifest(agent, manifest); err != nil {
+		agent.Close()
+		return nil, err
+	}
+	if err := m.add(agent); err != nil {
+		agent.Close()
+		return nil, err
+	}
+
+	if manifest.RegisteredAt.IsZero() {
+		manifest.RegisteredAt = time.Now()
+	}
+	if err := store.Save(ctx, manifest); err != nil {
+		m.Deregister(manifest.Type)
+		return nil, fmt.Errorf("failed to persist manifest: %w", err)
+	}
+	return agent, nil
+}
+
+// DeregisterManifest removes a marketplace agent from the registry and the
+// store
+func (m *Manager) DeregisterManifest(ctx context.Context, store ManifestStore, agentType agents.AgentType) error {
+	m.mu.Lock()
+	_, ok := m.plugins[agentType]
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotPlugin
+	}
+	if err := store.Delete(ctx, agentType); err != nil {
+		return fmt.Errorf("failed to delete manifest: %w", err)
+	}
+	return m.Deregister(agentType)
+}
+
+// Restore re-registers every persisted manifest, skipping agents that are
+// unreachable so they can be re-registered once they are back
+func (m *Manager) Restore(ctx context.Context, store ManifestStore) (int, error) {
+	manifests, err := store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load manifests: %w", err)
+	}
+	restored := 0
+	for _, manifest := range manifests {
+		agent, err := m.connect(ctx, manifest.pluginConfig())
+		if err == nil {
+			if err = applyManifest(agent, manifest); err == nil {
+				err = m.add(agent)
+			}
+			if err != nil {
+				agent.Close()
+			}
+		}
+		if err != nil {
+			m.logger.Warn("Skipping marketplace agent",
+				zap.String("type", string(manifest.Type)),
+				zap.String("endpoint", manifest.Endpoint),
+				zap.Error(err))
+			continue
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// applyManifest narrows the agent to the declared capabilities and sets its
+// cost and description
+func applyManifest(agent *Agent, manifest *AgentManifest) error {
+	if len(manifest.Capabilities) > 0 {
+		served := make(map[string]bool, len(agent.manifest.Capabilities))
+		for _, c := range agent.manifest.Capabilities {
+			served[strings.ToLower(c.Name)] = true
+		}
+		var missing []string
+		for _, c := range manifest.Capabilities {
+			if !served[strings.ToLower(c.Name)] {
+				missing = append(missing, c.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("agent does not serve declared capabilities: %s", strings.Join(missing, ", "))
+		}
+		agent.manifest.Capabilities = manifest.Capabilities
+	}
+	if manifest.Description != "" {
+		agent.manifest.Description = manifest.Description
+	}
+	agent.cost = manifest.CostWeight
+	return nil
+}
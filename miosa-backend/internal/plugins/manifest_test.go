@@ -0,0 +1,143 @@
+package plugins
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestAgentManifest_Validate(t *testing.T) {
+	valid := AgentManifest{Type: "translator", Endpoint: "translator.internal:50051"}
+	require.NoError(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(m *AgentManifest)
+	}{
+		{"bad type", func(m *AgentManifest) { m.Type = "Translator!" }},
+		{"reserved type", func(m *AgentManifest) { m.Type = agents.OrchestratorAgent }},
+		{"no port", func(m *AgentManifest) { m.Endpoint = "translator.internal" }},
+		{"bearer without token", func(m *AgentManifest) { m.Auth = ManifestAuth{Type: "bearer"} }},
+		{"token without bearer", func(m *AgentManifest) { m.Auth = ManifestAuth{Token: "secret"} }},
+		{"unknown auth", func(m *AgentManifest) { m.Auth = ManifestAuth{Type: "mtls"} }},
+		{"negative cost", func(m *AgentManifest) { m.CostWeight = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid
+			tt.modify(&m)
+			assert.Error(t, m.Validate())
+		})
+	}
+}
+
+func TestAgentManifest_Redacted(t *testing.T) {
+	m := AgentManifest{Type: "translator", Auth: ManifestAuth{Type: "bearer", Token: "secret"}}
+	assert.Equal(t, "[REDACTED]", m.Redacted().Auth.Token)
+	assert.Equal(t, "secret", m.Auth.Token)
+}
+
+func TestManager_RegisterManifest(t *testing.T) {
+	agents.Clear()
+	t.Cleanup(agents.Clear)
+	ctx := context.Background()
+
+	m := NewManager(zap.NewNop())
+	t.Cleanup(func() { m.Close() })
+	store := NewFileManifestStore(filepath.Join(t.TempDir(), "manifests.json"))
+	dialer := servePlugin(t, echoAgent{})
+
+	manifest := &AgentManifest{
+		Type:         "market_echo",
+		Endpoint:     "localhost:50051",
+		Capabilities: []agents.Capability{{Name: "echo"}},
+		CostWeight:   0.5,
+		Description:  "Marketplace echo",
+	}
+	agent, err := m.RegisterManifest(ctx, store, manifest, dialer)
+	require.NoError(t, err)
+	assert.True(t, agents.IsRegistered("market_echo"))
+	assert.Equal(t, "Marketplace echo", agent.GetDescription())
+	assert.Equal(t, 0.5, agent.CostWeight())
+	assert.False(t, manifest.RegisteredAt.IsZero())
+
+	saved, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, agents.AgentType("market_echo"), saved[0].Type)
+
+	_, err = m.RegisterManifest(ctx, store, manifest, dialer)
+	assert.Error(t, err, "duplicate types are rejected")
+
+	require.NoError(t, m.DeregisterManifest(ctx, store, "market_echo"))
+	assert.False(t, agents.IsRegistered("market_echo"))
+	saved, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+
+	assert.ErrorIs(t, m.DeregisterManifest(ctx, store, "market_echo"), ErrNotPlugin)
+}
+
+func TestManager_RegisterManifestCapabilityMismatch(t *testing.T) {
+	agents.Clear()
+	t.Cleanup(agents.Clear)
+	ctx := context.Background()
+
+	m := NewManager(zap.NewNop())
+	store := NewFileManifestStore(filepath.Join(t.TempDir(), "manifests.json"))
+	_, err := m.RegisterManifest(ctx, store, &AgentManifest{
+		Type:         "market_echo",
+		Endpoint:     "localhost:50051",
+		Capabilities: []agents.Capability{{Name: "echo"}, {Name: "translate"}},
+	}, servePlugin(t, echoAgent{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "translate")
+	assert.False(t, agents.IsRegistered("market_echo"))
+
+	saved, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestManager_Restore(t *testing.T) {
+	agents.Clear()
+	t.Cleanup(agents.Clear)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "manifests.json")
+	store := NewFileManifestStore(path)
+	require.NoError(t, store.Save(ctx, &AgentManifest{Type: "market_echo", Endpoint: "localhost:50051"}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	m := NewManager(zap.NewNop())
+	t.Cleanup(func() { m.Close() })
+	manifests, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+
+	agent, err := m.connect(ctx, manifests[0].pluginConfig(), servePlugin(t, echoAgent{}))
+	require.NoError(t, err)
+	require.NoError(t, applyManifest(agent, manifests[0]))
+	require.NoError(t, m.add(agent))
+	assert.True(t, agents.IsRegistered("market_echo"))
+}
+
+func TestRank_CheaperAgentWinsTie(t *testing.T) {
+	cheap := &Agent{agentType: "cheap", cost: 0.2, manifest: Manifest{Capabilities: []agents.Capability{{Name: "echo"}}}}
+	pricey := &Agent{agentType: "another", cost: 3, manifest: Manifest{Capabilities: []agents.Capability{{Name: "echo"}}}}
+
+	idx := agents.NewCapabilityIndex(map[agents.AgentType]agents.Agent{"cheap": cheap, "another": pricey})
+	best, err := idx.Best([]string{"echo"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, agents.AgentType("cheap"), best.Type)
+}
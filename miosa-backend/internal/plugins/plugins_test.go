@@ -0,0 +1,103 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/config"
+)
+
+type echoAgent struct{}
+
+func (echoAgent) GetType() agents.AgentType { return "echo" }
+func (echoAgent) GetDescription() string    { return "Echoes its input" }
+func (echoAgent) GetCapabilities() []agents.Capability {
+	return []agents.Capability{{Name: "echo", Description: "Repeat the input", Required: true}}
+}
+func (echoAgent) Execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	if task.Input == "fail" {
+		return nil, errors.New("asked to fail")
+	}
+	return &agents.Result{
+		Success:    true,
+		Output:     task.Input,
+		Data:       map[string]interface{}{"phase": task.Context.Phase},
+		Files:      []agents.GeneratedFile{{Path: "echo.txt", Content: task.Input}},
+		Confidence: 0.9,
+	}, nil
+}
+
+// servePlugin starts an in-memory plugin server and returns a dialer for it
+func servePlugin(t *testing.T, agent agents.Agent) grpc.DialOption {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterAgentServer(srv, agent)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	})
+}
+
+func TestManager_RegisterExecuteDeregister(t *testing.T) {
+	agents.Clear()
+	t.Cleanup(agents.Clear)
+	ctx := context.Background()
+
+	m := NewManager(zap.NewNop())
+	dialer := servePlugin(t, echoAgent{})
+	agent, err := m.Register(ctx, config.PluginConfig{Type: "plugin_echo", Address: "passthrough:///echo"}, dialer)
+	require.NoError(t, err)
+
+	registered, err := agents.Get("plugin_echo")
+	require.NoError(t, err)
+	assert.Same(t, agent, registered)
+	assert.Equal(t, "Echoes its input", registered.GetDescription())
+	require.Len(t, registered.GetCapabilities(), 1)
+	assert.Equal(t, "echo", registered.GetCapabilities()[0].Name)
+
+	result, err := registered.Execute(ctx, agents.Task{
+		ID:      uuid.New(),
+		Input:   "hello",
+		Context: &agents.TaskContext{Phase: "development"},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "hello", result.Output)
+	assert.Equal(t, "development", result.Data["phase"])
+	assert.Equal(t, []agents.GeneratedFile{{Path: "echo.txt", Content: "hello"}}, result.Files)
+
+	result, err = registered.Execute(ctx, agents.Task{Input: "fail", Context: &agents.TaskContext{}})
+	require.NoError(t, err, "agent failures come back as results")
+	assert.False(t, result.Success)
+	assert.EqualError(t, result.Error, "asked to fail")
+
+	assert.Equal(t, map[agents.AgentType]bool{"plugin_echo": true}, m.HealthCheck(ctx))
+
+	require.NoError(t, m.Deregister("plugin_echo"))
+	assert.False(t, agents.IsRegistered("plugin_echo"))
+}
+
+func TestManager_LoadAllSkipsUnreachable(t *testing.T) {
+	agents.Clear()
+	t.Cleanup(agents.Clear)
+
+	m := NewManager(zap.NewNop())
+	lis := bufconn.Listen(1 << 10)
+	lis.Close()
+	_, err := m.Register(context.Background(), config.PluginConfig{Type: "gone", Address: "passthrough:///gone"},
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }))
+	assert.Error(t, err)
+	assert.False(t, agents.IsRegistered("gone"))
+}
@@ -0,0 +1,170 @@
+// Package plugins runs agents out of process over gRPC. A plugin serves the
+// AgentPlugin service described in proto/agent_plugin.proto plus the
+// standard gRPC health service; the Manager dials plugins declared in
+// config and registers them with the agent registry like any built-in agent.
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+const (
+	serviceName           = "miosa.plugin.v1.AgentPlugin"
+	methodGetCapabilities = "/" + serviceName + "/GetCapabilities"
+	methodExecute         = "/" + serviceName + "/Execute"
+)
+
+// Manifest is what a plugin reports from GetCapabilities
+type Manifest struct {
+	Type         agents.AgentType    `json:"type,omitempty"`
+	Description  string              `json:"description,omitempty"`
+	Capabilities []agents.Capability `json:"capabilities"`
+}
+
+// toStruct converts any JSON-encodable value to a protobuf Struct
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
+
+// fromStruct decodes a protobuf Struct into v through its JSON form.
+// agents.Result carries its error as a message string both ways.
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	data, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// client calls the AgentPlugin service on a connection
+type client struct {
+	conn grpc.ClientConnInterface
+}
+
+func (c client) manifest(ctx context.Context) (*Manifest, error) {
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, methodGetCapabilities, &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := fromStruct(out, &m); err != nil {
+		return nil, fmt.Errorf("invalid capabilities response: %w", err)
+	}
+	return &m, nil
+}
+
+func (c client) execute(ctx context.Context, task agents.Task) (*agents.Result, error) {
+	in, err := toStruct(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task: %w", err)
+	}
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, methodExecute, in, out); err != nil {
+		return nil, err
+	}
+	var result agents.Result
+	if err := fromStruct(out, &result); err != nil {
+		return nil, fmt.Errorf("invalid execute response: %w", err)
+	}
+	return &result, nil
+}
+
+// pluginService is the server-side handler type for the service descriptor
+type pluginService interface {
+	getCapabilities(ctx context.Context) (*structpb.Struct, error)
+	execute(ctx context.Context, task *structpb.Struct) (*structpb.Struct, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*pluginService)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCapabilities",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(pluginService).getCapabilities(ctx)
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodGetCapabilities}, handler)
+			},
+		},
+		{
+			MethodName: "Execute",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(pluginService).execute(ctx, req.(*structpb.Struct))
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodExecute}, handler)
+			},
+		},
+	},
+	Metadata: "proto/agent_plugin.proto",
+}
+
+// agentServer adapts an in-process agent to the plugin protocol
+type agentServer struct {
+	agent agents.Agent
+}
+
+func (s agentServer) getCapabilities(ctx context.Context) (*structpb.Struct, error) {
+	return toStruct(Manifest{
+		Type:         s.agent.GetType(),
+		Description:  s.agent.GetDescription(),
+		Capabilities: s.agent.GetCapabilities(),
+	})
+}
+
+func (s agentServer) execute(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var task agents.Task
+	if err := fromStruct(in, &task); err != nil {
+		return nil, fmt.Errorf("invalid task: %w", err)
+	}
+	result, err := s.agent.Execute(ctx, task)
+	if err != nil {
+		if result == nil {
+			result = &agents.Result{}
+		}
+		result.Success = false
+		result.Error = err
+	}
+	return toStruct(result)
+}
+
+// RegisterAgentServer exposes agent on s as a plugin, including the health
+// service. It lets Go teams ship a plugin binary around any agents.Agent.
+func RegisterAgentServer(s *grpc.Server, agent agents.Agent) {
+	s.RegisterService(&serviceDesc, agentServer{agent: agent})
+	healthpb.RegisterHealthServer(s, health.NewServer())
+}
@@ -0,0 +1,171 @@
+package plugins
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// FileManifestStore keeps manifests in a JSON file, for binaries without a
+// database. The file holds auth tokens and is written 0600.
+type FileManifestStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileManifestStore creates a store writing to path
+func NewFileManifestStore(path string) *FileManifestStore {
+	return &FileManifestStore{path: path}
+}
+
+// Save adds or replaces the manifest for its agent type
+func (s *FileManifestStore) Save(ctx context.Context, m *AgentManifest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	all[m.Type] = m
+	return s.write(all)
+}
+
+// Delete removes the manifest for agentType, if any
+func (s *FileManifestStore) Delete(ctx context.Context, agentType agents.AgentType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(all, agentType)
+	return s.write(all)
+}
+
+// List returns every manifest ordered by type
+func (s *FileManifestStore) List(ctx context.Context) ([]*AgentManifest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*AgentManifest, 0, len(all))
+	for _, m := range all {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out, nil
+}
+
+func (s *FileManifestStore) load() (map[agents.AgentType]*AgentManifest, error) {
+	all := make(map[agents.AgentType]*AgentManifest)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*AgentManifest
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", s.path, err)
+	}
+	for _, m := range list {
+		all[m.Type] = m
+	}
+	return all, nil
+}
+
+// write saves manifests atomically via a temp file and rename
+func (s *FileManifestStore) write(all map[agents.AgentType]*AgentManifest) error {
+	list := make([]*AgentManifest, 0, len(all))
+	for _, m := range all {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// PostgresManifestStore keeps manifests in the agent_manifests table
+type PostgresManifestStore struct {
+	db *sql.DB
+}
+
+// NewPostgresManifestStore creates a Postgres-backed store
+func NewPostgresManifestStore(db *sql.DB) *PostgresManifestStore {
+	return &PostgresManifestStore{db: db}
+}
+
+// Save upserts the manifest for its agent type
+func (s *PostgresManifestStore) Save(ctx context.Context, m *AgentManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO agent_manifests (agent_type, endpoint, manifest, registered_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (agent_type) DO UPDATE
+		SET endpoint = EXCLUDED.endpoint, manifest = EXCLUDED.manifest, registered_at = EXCLUDED.registered_at`,
+		string(m.Type), m.Endpoint, data, m.RegisteredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the manifest for agentType, if any
+func (s *PostgresManifestStore) Delete(ctx context.Context, agentType agents.AgentType) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM agent_manifests WHERE agent_type = $1`, string(agentType)); err != nil {
+		return fmt.Errorf("failed to delete manifest: %w", err)
+	}
+	return nil
+}
+
+// List returns every manifest ordered by type
+func (s *PostgresManifestStore) List(ctx context.Context) ([]*AgentManifest, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT manifest FROM agent_manifests ORDER BY agent_type`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifests: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*AgentManifest
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var m AgentManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		out = append(out, &m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,110 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// Placeholder replaces redacted secret values
+const Placeholder = "[REDACTED]"
+
+// Secret patterns shared by code assurance scanning and output redaction
+var (
+	AWSAccessKey    = regexp.MustCompile(`AKIA[0-9A-Z]{16}`)
+	GenericPassword = regexp.MustCompile(`(?i)(password|passwd|pwd)\s*[:=]\s*['"][^'"]+['"]`)
+	GenericSecret   = regexp.MustCompile(`(?i)(api[_-]?key|secret|token)\s*[:=]\s*['"][^'"]+['"]`)
+	GroqAPIKey      = regexp.MustCompile(`gsk_[A-Za-z0-9]{20,}`)
+	OpenAIAPIKey    = regexp.MustCompile(`sk-[A-Za-z0-9_-]{20,}`)
+	BearerToken     = regexp.MustCompile(`Bearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	PrivateKeyBlock = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// rule pairs a pattern with how its matches are rewritten
+type rule struct {
+	pattern *regexp.Regexp
+	replace func(match string) string
+}
+
+var rules = []rule{
+	{PrivateKeyBlock, whole},
+	{AWSAccessKey, whole},
+	{GroqAPIKey, whole},
+	{OpenAIAPIKey, whole},
+	{BearerToken, func(string) string { return "Bearer " + Placeholder }},
+	{GenericPassword, quotedValue},
+	{GenericSecret, quotedValue},
+}
+
+// whole replaces the entire match
+func whole(string) string {
+	return Placeholder
+}
+
+// quotedValue keeps the key name of a key = "value" assignment and
+// replaces only the quoted value
+func quotedValue(match string) string {
+	i := strings.IndexAny(match, `'"`)
+	if i == -1 {
+		return Placeholder
+	}
+	quote := match[i : i+1]
+	return match[:i+1] + Placeholder + quote
+}
+
+// String redacts secrets in s and returns the result with the number of
+// values replaced
+func String(s string) (string, int) {
+	count := 0
+	for _, r := range rules {
+		s = r.pattern.ReplaceAllStringFunc(s, func(match string) string {
+			replaced := r.replace(match)
+			if replaced != match {
+				count++
+			}
+			return replaced
+		})
+	}
+	return s, count
+}
+
+// Clean redacts secrets in s, discarding the count
+func Clean(s string) string {
+	s, _ = String(s)
+	return s
+}
+
+// Result redacts secrets in an agent result's output, generated files and
+// patches in place. The number of redactions is added to Data["redactions"]
+// so callers can report it.
+func Result(result *agents.Result) int {
+	if result == nil {
+		return 0
+	}
+
+	var total, n int
+	result.Output, n = String(result.Output)
+	total += n
+	for i := range result.Files {
+		result.Files[i].Content, n = String(result.Files[i].Content)
+		total += n
+	}
+	if patches, ok := result.Data[agents.PatchesKey].([]agents.FilePatch); ok {
+		for i := range patches {
+			for j := range patches[i].Edits {
+				patches[i].Edits[j].Content, n = String(patches[i].Edits[j].Content)
+				total += n
+			}
+		}
+	}
+
+	if total > 0 {
+		if result.Data == nil {
+			result.Data = make(map[string]interface{})
+		}
+		prev, _ := result.Data["redactions"].(int)
+		result.Data["redactions"] = prev + total
+	}
+	return total
+}
@@ -0,0 +1,59 @@
+package redact
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// core wraps a zapcore.Core and redacts secrets from messages and string
+// fields before they are encoded
+type core struct {
+	zapcore.Core
+}
+
+// NewCore wraps c so every entry it writes is redacted
+func NewCore(c zapcore.Core) zapcore.Core {
+	return &core{Core: c}
+}
+
+// Logger returns a logger that redacts secrets before writing
+func Logger(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(NewCore))
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(fields)}
+}
+
+func (c *core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = Clean(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// redactFields returns a copy of fields with string-like values redacted
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = Clean(f.String)
+		case zapcore.ByteStringType:
+			if b, ok := f.Interface.([]byte); ok {
+				f.Interface = []byte(Clean(string(b)))
+			}
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok && err != nil {
+				f = zap.String(f.Key, Clean(err.Error()))
+			}
+		}
+		out[i] = f
+	}
+	return out
+}
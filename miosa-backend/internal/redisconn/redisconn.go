@@ -0,0 +1,173 @@
+// Package redisconn connects to a standalone Redis, a Sentinel-managed
+// master or a Redis Cluster from one URL, behind redis.UniversalClient, and
+// implements the operations that behave differently in cluster mode.
+//
+// Cluster and Sentinel URLs list every seed address in the host:
+//
+//	redis://:secret@localhost:6379/0
+//	redis+cluster://:secret@node1:6379,node2:6379,node3:6379
+//	redis+sentinel://:secret@sentinel1:26379,sentinel2:26379/mymaster/0?sentinel_password=s
+//
+// The rediss+ schemes use TLS.
+package redisconn
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Modes a URL selects
+const (
+	ModeStandalone = "standalone"
+	ModeCluster    = "cluster"
+	ModeSentinel   = "sentinel"
+)
+
+// Mode returns the deployment rawURL connects to
+func Mode(rawURL string) string {
+	scheme, _, _ := strings.Cut(rawURL, "://")
+	switch strings.TrimPrefix(strings.TrimPrefix(scheme, "rediss"), "redis") {
+	case "+cluster":
+		return ModeCluster
+	case "+sentinel":
+		return ModeSentinel
+	}
+	return ModeStandalone
+}
+
+// New creates a client for rawURL. It does not connect until first used.
+func New(rawURL string) (redis.UniversalClient, error) {
+	switch Mode(rawURL) {
+	case ModeCluster:
+		opts, err := ParseClusterURL(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClusterClient(opts), nil
+	case ModeSentinel:
+		opts, err := ParseSentinelURL(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewFailoverClient(opts), nil
+	}
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return redis.NewClient(opts), nil
+}
+
+// ParseClusterURL parses a redis+cluster:// or rediss+cluster:// URL
+func ParseClusterURL(rawURL string) (*redis.ClusterOptions, error) {
+	u, addrs, err := parseMulti(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	opts := &redis.ClusterOptions{Addrs: addrs}
+	opts.Username, opts.Password = credentials(u)
+	if strings.HasPrefix(u.Scheme, "rediss") {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return opts, nil
+}
+
+// ParseSentinelURL parses a redis+sentinel:// or rediss+sentinel:// URL.
+// The path names the master and, optionally, the database.
+func ParseSentinelURL(rawURL string) (*redis.FailoverOptions, error) {
+	u, addrs, err := parseMulti(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	master, db, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if master == "" {
+		return nil, fmt.Errorf("invalid Redis URL: sentinel URLs name the master in the path")
+	}
+	opts := &redis.FailoverOptions{
+		MasterName:       master,
+		SentinelAddrs:    addrs,
+		SentinelUsername: u.Query().Get("sentinel_username"),
+		SentinelPassword: u.Query().Get("sentinel_password"),
+	}
+	opts.Username, opts.Password = credentials(u)
+	if db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis URL: database %q is not a number", db)
+		}
+	}
+	if strings.HasPrefix(u.Scheme, "rediss") {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return opts, nil
+}
+
+// parseMulti parses a URL whose host is a comma-separated address list
+func parseMulti(rawURL string) (*url.URL, []string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	var addrs []string
+	for _, addr := range strings.Split(u.Host, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, nil, fmt.Errorf("invalid Redis URL: no addresses")
+	}
+	return u, addrs, nil
+}
+
+func credentials(u *url.URL) (username, password string) {
+	if u.User == nil {
+		return "", ""
+	}
+	password, _ = u.User.Password()
+	return u.User.Username(), password
+}
+
+// Scan calls fn with every key matching pattern. A cluster's keys are
+// spread over its masters, so each is scanned; fn is never called
+// concurrently.
+func Scan(ctx context.Context, client redis.UniversalClient, pattern string, fn func(key string)) error {
+	cluster, ok := client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, client, pattern, fn)
+	}
+	var mu sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, pattern, func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			fn(key)
+		})
+	})
+}
+
+func scanNode(ctx context.Context, client redis.Cmdable, pattern string, fn func(key string)) error {
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		fn(iter.Val())
+	}
+	return iter.Err()
+}
+
+// Del deletes keys one command each: a multi-key DEL fails in cluster mode
+// unless every key hashes to the same slot
+func Del(ctx context.Context, client redis.Cmdable, keys ...string) error {
+	var firstErr error
+	for _, key := range keys {
+		if err := client.Del(ctx, key).Err(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,233 @@
+package reporting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"github.com/sormind/OSA/miosa-backend/internal/flags"
+)
+
+// Pricing holds per-million-token prices in USD for a model
+type Pricing struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// ModelPricing lists published Groq on-demand prices used for cost estimates
+var ModelPricing = map[string]Pricing{
+	"llama-3.1-8b-instant":        {InputPerMillion: 0.05, OutputPerMillion: 0.08},
+	"llama-3.3-70b-versatile":     {InputPerMillion: 0.59, OutputPerMillion: 0.79},
+	"llama3-70b-8192":             {InputPerMillion: 0.59, OutputPerMillion: 0.79},
+	"mixtral-8x7b-32768":          {InputPerMillion: 0.24, OutputPerMillion: 0.24},
+	"moonshotai/kimi-k2-instruct": {InputPerMillion: 1.00, OutputPerMillion: 3.00},
+}
+
+// EstimateCost returns the estimated USD cost of a call. Unknown models are
+// reported as zero cost rather than guessed.
+func EstimateCost(model string, promptTokens, completionTokens int) float64 {
+	p, ok := ModelPricing[model]
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*p.InputPerMillion + float64(completionTokens)*p.OutputPerMillion) / 1_000_000
+}
+
+// EnrichmentKey is the result data key holding the milliseconds spent
+// enriching the task's context before the agent ran
+const EnrichmentKey = "enrichment_ms"
+
+// AgentUsage captures the cost and latency of one agent execution
+type AgentUsage struct {
+	Agent             agents.AgentType `json:"agent"`
+	Model             string           `json:"model,omitempty"`
+	Success           bool             `json:"success"`
+	LatencyMS         int64            `json:"latency_ms"`
+	PromptTokens      int              `json:"prompt_tokens"`
+	CompletionTokens  int              `json:"completion_tokens"`
+	TotalTokens       int              `json:"total_tokens"`
+	Retries           int              `json:"retries"`
+	CostUSD           float64          `json:"cost_usd"`
+	EnrichmentMS      int64            `json:"enrichment_ms,omitempty"`       // Adding context before the agent ran; not in LatencyMS
+	PromptTokensSaved int              `json:"prompt_tokens_saved,omitempty"` // Left out of prompts by compression
+
+	// Failovers between LLM targets, e.g. to a secondary model on a 429
+	Failovers []agents.FailoverEvent `json:"failovers,omitempty"`
+}
+
+// WorkflowReport aggregates usage across all agents in a workflow
+type WorkflowReport struct {
+	WorkflowID        uuid.UUID    `json:"workflow_id"`
+	Agents            []AgentUsage `json:"agents"`
+	TotalLatencyMS    int64        `json:"total_latency_ms"`
+	PromptTokens      int          `json:"prompt_tokens"`
+	CompletionTokens  int          `json:"completion_tokens"`
+	TotalTokens       int          `json:"total_tokens"`
+	TotalRetries      int          `json:"total_retries"`
+	TotalCostUSD      float64      `json:"total_cost_usd"`
+	TotalEnrichmentMS int64        `json:"total_enrichment_ms,omitempty"`
+	TotalFailovers    int          `json:"total_failovers,omitempty"`
+	PromptTokensSaved int          `json:"prompt_tokens_saved,omitempty"`
+	GeneratedAt       time.Time    `json:"generated_at"`
+
+	// LoadTest holds per-endpoint latency and throughput when the
+	// performance stage ran against a deployment
+	LoadTest *quality.LoadReport `json:"load_test,omitempty"`
+
+	// E2E holds the outcome of each generated user flow when the
+	// end-to-end stage ran against a deployment
+	E2E *quality.E2EReport `json:"e2e,omitempty"`
+
+	// Flags records which feature flags were on for the workflow, and why,
+	// so behavioral differences between runs can be traced to a rollout
+	Flags flags.Decisions `json:"flags,omitempty"`
+}
+
+// NewWorkflowReport creates an empty report for a workflow
+func NewWorkflowReport(workflowID uuid.UUID) *WorkflowReport {
+	return &WorkflowReport{
+		WorkflowID:  workflowID,
+		Agents:      make([]AgentUsage, 0),
+		GeneratedAt: time.Now(),
+	}
+}
+
+// Add records an agent result in the report and updates the totals
+func (r *WorkflowReport) Add(agentType agents.AgentType, result *agents.Result) AgentUsage {
+	usage := UsageFromResult(agentType, result)
+	r.Agents = append(r.Agents, usage)
+
+	r.TotalLatencyMS += usage.LatencyMS
+	r.PromptTokens += usage.PromptTokens
+	r.CompletionTokens += usage.CompletionTokens
+	r.TotalTokens += usage.TotalTokens
+	r.TotalRetries += usage.Retries
+	r.TotalCostUSD += usage.CostUSD
+	r.TotalEnrichmentMS += usage.EnrichmentMS
+	r.TotalFailovers += len(usage.Failovers)
+	r.PromptTokensSaved += usage.PromptTokensSaved
+	r.GeneratedAt = time.Now()
+	return usage
+}
+
+// UsageFromResult extracts model, token and retry information from the
+// result. The result's usage fields are preferred; agents that only report
+// usage in result data fall back to that.
+func UsageFromResult(agentType agents.AgentType, result *agents.Result) AgentUsage {
+	usage := AgentUsage{Agent: agentType}
+	if result == nil {
+		return usage
+	}
+	usage.Success = result.Success
+	usage.LatencyMS = result.ExecutionMS
+	usage.Retries = toInt(result.Data["retries"])
+	usage.EnrichmentMS = int64(toInt(result.Data[EnrichmentKey]))
+	usage.Failovers = result.Failovers
+	usage.PromptTokensSaved = result.PromptTokensSaved
+
+	if result.PromptTokens > 0 || result.CompletionTokens > 0 {
+		usage.Model = result.Model
+		usage.PromptTokens = result.PromptTokens
+		usage.CompletionTokens = result.CompletionTokens
+		usage.TotalTokens = result.TotalTokens()
+		usage.CostUSD = EstimateCost(usage.Model, usage.PromptTokens, usage.CompletionTokens)
+		return usage
+	}
+
+	if result.Model != "" {
+		usage.Model = result.Model
+	} else if model, ok := result.Data["model_used"].(string); ok {
+		usage.Model = model
+	} else if model, ok := result.Data["model"].(string); ok {
+		usage.Model = model
+	}
+
+	switch u := result.Data["usage"].(type) {
+	case groq.Usage:
+		usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens = u.PromptTokens, u.CompletionTokens, u.TotalTokens
+	case *groq.Usage:
+		if u != nil {
+			usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens = u.PromptTokens, u.CompletionTokens, u.TotalTokens
+		}
+	case map[string]interface{}:
+		usage.PromptTokens = toInt(u["prompt_tokens"])
+		usage.CompletionTokens = toInt(u["completion_tokens"])
+		usage.TotalTokens = toInt(u["total_tokens"])
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = toInt(result.Data["tokens_used"])
+	}
+
+	usage.CostUSD = EstimateCost(usage.Model, usage.PromptTokens, usage.CompletionTokens)
+	return usage
+}
+
+// WriteCSV writes one row per agent followed by a totals row
+func (r *WorkflowReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"workflow_id", "agent", "model", "success", "latency_ms",
+		"prompt_tokens", "completion_tokens", "total_tokens", "retries", "cost_usd", "enrichment_ms", "failovers"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	id := r.WorkflowID.String()
+	for _, a := range r.Agents {
+		row := []string{
+			id,
+			string(a.Agent),
+			a.Model,
+			strconv.FormatBool(a.Success),
+			strconv.FormatInt(a.LatencyMS, 10),
+			strconv.Itoa(a.PromptTokens),
+			strconv.Itoa(a.CompletionTokens),
+			strconv.Itoa(a.TotalTokens),
+			strconv.Itoa(a.Retries),
+			fmt.Sprintf("%.6f", a.CostUSD),
+			strconv.FormatInt(a.EnrichmentMS, 10),
+			strconv.Itoa(len(a.Failovers)),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	total := []string{
+		id, "total", "", "",
+		strconv.FormatInt(r.TotalLatencyMS, 10),
+		strconv.Itoa(r.PromptTokens),
+		strconv.Itoa(r.CompletionTokens),
+		strconv.Itoa(r.TotalTokens),
+		strconv.Itoa(r.TotalRetries),
+		fmt.Sprintf("%.6f", r.TotalCostUSD),
+		strconv.FormatInt(r.TotalEnrichmentMS, 10),
+		strconv.Itoa(r.TotalFailovers),
+	}
+	if err := cw.Write(total); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowReport_Add(t *testing.T) {
+	report := NewWorkflowReport(uuid.New())
+
+	report.Add(agents.DevelopmentAgent, &agents.Result{
+		Success:     true,
+		ExecutionMS: 1200,
+		Data: map[string]interface{}{
+			"model":       "llama-3.3-70b-versatile",
+			"usage":       groq.Usage{PromptTokens: 1000, CompletionTokens: 2000, TotalTokens: 3000},
+			"retries":     1,
+			EnrichmentKey: int64(40),
+		},
+	})
+	report.Add(agents.CommunicationAgent, &agents.Result{
+		Success:     true,
+		ExecutionMS: 300,
+		Data: map[string]interface{}{
+			"model":       "llama-3.1-8b-instant",
+			"tokens_used": 150,
+		},
+		Failovers: []agents.FailoverEvent{{
+			Agent: agents.CommunicationAgent, From: "llama-3.3-70b-versatile", To: "llama-3.1-8b-instant", Reason: agents.FailoverRateLimited,
+		}},
+	})
+
+	require.Len(t, report.Agents, 2)
+	assert.Equal(t, int64(1500), report.TotalLatencyMS)
+	assert.Equal(t, 3150, report.TotalTokens)
+	assert.Equal(t, 1, report.TotalRetries)
+	assert.Equal(t, int64(40), report.TotalEnrichmentMS)
+	assert.Equal(t, 1, report.TotalFailovers)
+	assert.Len(t, report.Agents[1].Failovers, 1)
+	assert.InDelta(t, (1000*0.59+2000*0.79)/1_000_000, report.TotalCostUSD, 1e-12)
+}
+
+func TestUsageFromResult_PrefersResultFields(t *testing.T) {
+	usage := UsageFromResult(agents.AnalysisAgent, &agents.Result{
+		Success:          true,
+		Model:            "llama-3.1-8b-instant",
+		PromptTokens:     400,
+		CompletionTokens: 100,
+		Data: map[string]interface{}{
+			"model": "stale-model",
+			"usage": groq.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+		},
+	})
+
+	assert.Equal(t, "llama-3.1-8b-instant", usage.Model)
+	assert.Equal(t, 500, usage.TotalTokens)
+	assert.InDelta(t, (400*0.05+100*0.08)/1_000_000, usage.CostUSD, 1e-12)
+}
+
+func TestWorkflowReport_WriteCSV(t *testing.T) {
+	report := NewWorkflowReport(uuid.New())
+	report.Add(agents.AnalysisAgent, &agents.Result{Success: true, ExecutionMS: 10})
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "agent", rows[0][1])
+	assert.Equal(t, "analysis", rows[1][1])
+	assert.Equal(t, "total", rows[2][1])
+}
+
+func TestEstimateCost_UnknownModel(t *testing.T) {
+	assert.Equal(t, 0.0, EstimateCost("unknown-model", 1000, 1000))
+}
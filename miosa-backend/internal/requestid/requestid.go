@@ -0,0 +1,101 @@
+// Package requestid assigns every API request an ID and carries it through
+// contexts, so one ID traces a workflow across the gateway, orchestrators,
+// agents, LLM calls, generated files and sandbox deployments.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Header carries the ID on requests and responses. A caller that already
+// has an ID, such as the gateway calling an orchestrator, sends it to keep
+// one correlation ID across services.
+const Header = "X-Request-ID"
+
+// MaxLength bounds caller-supplied IDs; longer or non-printable ones are
+// replaced
+const MaxLength = 128
+
+type contextKey struct{}
+
+// New returns a fresh request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// NewContext returns a context carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Detach returns a background context carrying only ctx's request ID, for
+// work that outlives the request such as async workflows
+func Detach(ctx context.Context) context.Context {
+	if id := FromContext(ctx); id != "" {
+		return NewContext(context.Background(), id)
+	}
+	return context.Background()
+}
+
+// Field is the zap field logging ctx's request ID; it is skipped when
+// there is none
+func Field(ctx context.Context) zap.Field {
+	if id := FromContext(ctx); id != "" {
+		return zap.String("request_id", id)
+	}
+	return zap.Skip()
+}
+
+// Set adds ctx's request ID to an outgoing request
+func Set(ctx context.Context, req *http.Request) {
+	if id := FromContext(ctx); id != "" {
+		req.Header.Set(Header, id)
+	}
+}
+
+// fromRequest returns the caller's ID when it is usable, or a new one
+func fromRequest(r *http.Request) string {
+	id := r.Header.Get(Header)
+	if id == "" || len(id) > MaxLength {
+		return New()
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return New()
+		}
+	}
+	return id
+}
+
+// Handler assigns each request an ID, adding it to the request context and
+// the response headers. It fits mux.Router.Use.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := fromRequest(r)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Gin is Handler for gin engines. The ID is also stored under the
+// "request_id" key.
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := fromRequest(c.Request)
+		c.Header(Header, id)
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	var seen string
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NotEmpty(t, seen)
+	assert.Equal(t, seen, w.Header().Get(Header))
+
+	tests := []struct {
+		name, header string
+		kept         bool
+	}{
+		{"caller id", "gw-7f3a", true},
+		{"too long", strings.Repeat("a", MaxLength+1), false},
+		{"header injection", "abc\tdef", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(Header, tt.header)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tt.kept, seen == tt.header)
+			assert.Equal(t, seen, w.Header().Get(Header))
+		})
+	}
+}
+
+func TestGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Gin())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, FromContext(c.Request.Context())+" "+c.GetString("request_id"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(Header, "gw-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, "gw-1 gw-1", w.Body.String())
+	assert.Equal(t, "gw-1", w.Header().Get(Header))
+}
+
+func TestDetachAndSet(t *testing.T) {
+	ctx, cancel := context.WithCancel(NewContext(context.Background(), "req-1"))
+	cancel()
+
+	detached := Detach(ctx)
+	assert.NoError(t, detached.Err(), "detached work outlives the request")
+	assert.Equal(t, "req-1", FromContext(detached))
+	assert.Empty(t, FromContext(Detach(context.Background())))
+
+	out, _ := http.NewRequest(http.MethodPost, "http://localhost:3001", nil)
+	Set(detached, out)
+	assert.Equal(t, "req-1", out.Header.Get(Header))
+	assert.Equal(t, "request_id", Field(detached).Key)
+}
@@ -0,0 +1,217 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+// MaxBodyBytes bounds a schedule body
+const MaxBodyBytes = 64 << 10
+
+// DefaultRunsLimit is how many runs GET .../runs returns without a limit
+const DefaultRunsLimit = 20
+
+// ListHandler serves GET /api/workflow/schedules: the caller's tenant's
+// schedules
+func ListHandler(s *Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"schedules": s.List(orchestrate.TenantFromRequest(r))})
+	}
+}
+
+// CreateHandler serves POST /api/workflow/schedules
+func CreateHandler(s *Scheduler, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sched, ok := decode(w, r)
+		if !ok {
+			return
+		}
+		now := time.Now().UTC()
+		sched.ID = uuid.New()
+		sched.Tenant = orchestrate.TenantFromRequest(r)
+		sched.CreatedAt, sched.UpdatedAt = now, now
+		created, err := s.Put(r.Context(), sched, now)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		record(r, auditLog, created.ID, "create")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	}
+}
+
+// GetHandler serves GET /api/workflow/schedules/{id}
+func GetHandler(s *Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := lookup(w, r, s)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+	}
+}
+
+// UpdateHandler serves PUT /api/workflow/schedules/{id}, replacing the
+// schedule with the body
+func UpdateHandler(s *Scheduler, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := lookup(w, r, s)
+		if !ok {
+			return
+		}
+		sched, ok := decode(w, r)
+		if !ok {
+			return
+		}
+		now := time.Now().UTC()
+		sched.ID, sched.Tenant = existing.ID, existing.Tenant
+		sched.CreatedAt, sched.UpdatedAt = existing.CreatedAt, now
+		updated, err := s.Put(r.Context(), sched, now)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		record(r, auditLog, updated.ID, "update")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	}
+}
+
+// DeleteHandler serves DELETE /api/workflow/schedules/{id}
+func DeleteHandler(s *Scheduler, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := lookup(w, r, s)
+		if !ok {
+			return
+		}
+		if err := s.Delete(r.Context(), existing.Tenant, existing.ID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		record(r, auditLog, existing.ID, "delete")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RunsHandler serves GET /api/workflow/schedules/{id}/runs?limit=<n>: the
+// schedule's run history, newest first. Each started run's workflow is
+// served at /api/workflow/{workflow_id}.
+func RunsHandler(s *Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := lookup(w, r, s)
+		if !ok {
+			return
+		}
+		limit := DefaultRunsLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > MaxHistory {
+				apierror.Write(w, r, apierror.Invalid("invalid limit",
+					apierror.FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(MaxHistory)}))
+				return
+			}
+			limit = n
+		}
+		runs, err := s.Runs(r.Context(), existing.Tenant, existing.ID, limit)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if runs == nil {
+			runs = []*Run{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"runs": runs})
+	}
+}
+
+// TriggerHandler serves POST /api/workflow/schedules/{id}/runs: fire the
+// schedule now, subject to its overlap policy
+func TriggerHandler(s *Scheduler, auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		existing, ok := lookup(w, r, s)
+		if !ok {
+			return
+		}
+		run, err := s.Trigger(existing.Tenant, existing.ID, time.Now().UTC())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		record(r, auditLog, existing.ID, "trigger")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(run)
+	}
+}
+
+// decode reads and validates a schedule body, writing the error response
+// when it can't
+func decode(w http.ResponseWriter, r *http.Request) (*Schedule, bool) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	var sched Schedule
+	if err := dec.Decode(&sched); err != nil {
+		apierror.Write(w, r, apierror.Invalid("invalid request body",
+			apierror.FieldError{Field: "body", Message: err.Error()}))
+		return nil, false
+	}
+	if err := sched.Validate(); err != nil {
+		orchestrate.WriteError(w, err)
+		return nil, false
+	}
+	return &sched, true
+}
+
+// lookup loads the {id} schedule for the caller's tenant, writing the error
+// response when it can't
+func lookup(w http.ResponseWriter, r *http.Request, s *Scheduler) (*Schedule, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CategoryValidation, "invalid schedule id"))
+		return nil, false
+	}
+	sched, err := s.Get(orchestrate.TenantFromRequest(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return sched, true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.CategoryNotFound, err.Error()))
+		return
+	}
+	apierror.Write(w, r, err)
+}
+
+// record audits a change to a schedule
+func record(r *http.Request, auditLog *audit.Log, id uuid.UUID, op string) {
+	if auditLog == nil {
+		return
+	}
+	actor, actorType := audit.ActorFromRequest(r)
+	auditLog.Record(r.Context(), audit.Event{
+		Actor:     actor,
+		ActorType: actorType,
+		Action:    audit.ActionScheduleUpdate,
+		Resource:  "/api/workflow/schedules/" + id.String(),
+		Status:    audit.StatusSuccess,
+		Metadata:  map[string]string{"operation": op},
+	})
+}
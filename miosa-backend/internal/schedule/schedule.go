@@ -0,0 +1,183 @@
+// Package schedule runs orchestrations on a recurring schedule. Tenants
+// register a request with a cron expression — a nightly quality audit of a
+// linked repository, weekly dependency update PRs — and an overlap policy
+// deciding what happens when the previous run is still going at the next
+// fire time. Every run, including skipped ones, is kept in the schedule's
+// history.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+// Overlap decides what a fire does while the schedule's last run is going
+type Overlap string
+
+const (
+	OverlapSkip  Overlap = "skip"  // Skip the fire
+	OverlapQueue Overlap = "queue" // Run once the current run finishes; at most one waits
+	OverlapAllow Overlap = "allow" // Run alongside it
+)
+
+// Run statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// What started a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Limits on schedules
+const (
+	MaxNameLength = 100
+	MaxHistory    = 100             // Runs kept per schedule
+	MinInterval   = 5 * time.Minute // Schedules may not fire more often
+)
+
+// ErrNotFound is returned for unknown schedules and schedules of another
+// tenant
+var ErrNotFound = errors.New("schedule not found")
+
+// Schedule is a recurring orchestration
+type Schedule struct {
+	ID        uuid.UUID           `json:"id"`
+	Name      string              `json:"name"`
+	Cron      string              `json:"cron"`
+	Timezone  string              `json:"timezone,omitempty"` // IANA zone the cron expression is read in; UTC when empty
+	Overlap   Overlap             `json:"overlap,omitempty"`  // OverlapSkip when empty
+	Paused    bool                `json:"paused,omitempty"`
+	Request   orchestrate.Request `json:"request"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+
+	// Set by the scheduler
+	NextRun *time.Time `json:"next_run,omitempty"`
+	LastRun *Run       `json:"last_run,omitempty"`
+
+	// Tenant owns the schedule; it is set from the caller, never the body
+	Tenant string `json:"-"`
+}
+
+// Run is one fire of a schedule
+type Run struct {
+	ID           uuid.UUID  `json:"id"`
+	ScheduleID   uuid.UUID  `json:"schedule_id"`
+	WorkflowID   uuid.UUID  `json:"workflow_id,omitempty"` // Nil until the run starts
+	Trigger      string     `json:"trigger"`
+	Status       string     `json:"status"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// Validate normalizes the schedule and checks every field, reporting the
+// request's as request.<field>
+func (s *Schedule) Validate() error {
+	verr := &orchestrate.ValidationError{}
+	add := func(field, format string, args ...interface{}) {
+		verr.Fields = append(verr.Fields, orchestrate.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	s.Name = strings.TrimSpace(s.Name)
+	switch {
+	case s.Name == "":
+		add("name", "is required")
+	case len(s.Name) > MaxNameLength:
+		add("name", "must be at most %d characters", MaxNameLength)
+	}
+	if s.Overlap == "" {
+		s.Overlap = OverlapSkip
+	}
+	if s.Overlap != OverlapSkip && s.Overlap != OverlapQueue && s.Overlap != OverlapAllow {
+		add("overlap", "must be skip, queue or allow")
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		add("timezone", "unknown time zone %q", s.Timezone)
+		loc = time.UTC
+	}
+	if c, err := ParseCron(s.Cron); err != nil {
+		add("cron", "%v", err)
+	} else if gap := minGap(c, time.Now().In(loc)); gap == 0 {
+		add("cron", "never fires")
+	} else if gap < MinInterval {
+		add("cron", "must fire at most every %s, fires %s apart", MinInterval, gap)
+	}
+
+	var reqErr *orchestrate.ValidationError
+	if errors.As(s.Request.Validate(), &reqErr) {
+		for _, f := range reqErr.Fields {
+			add("request."+f.Field, "%s", f.Message)
+		}
+	}
+	s.Request.Async = false
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// minGap is the shortest time between the next few fires of c, or 0 if it
+// never fires
+func minGap(c Cron, from time.Time) time.Duration {
+	prev := c.Next(from)
+	if prev.IsZero() {
+		return 0
+	}
+	gap := time.Duration(1<<63 - 1)
+	for i := 0; i < 24; i++ {
+		next := c.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if d := next.Sub(prev); d < gap {
+			gap = d
+		}
+		prev = next
+	}
+	return gap
+}
+
+// Next returns the schedule's first fire after t, or the zero time if it
+// has none. The schedule must be valid.
+func (s *Schedule) Next(t time.Time) time.Time {
+	c, err := ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}
+	}
+	return c.Next(t.In(loc)).UTC()
+}
+
+// copySchedule copies s so it can be handed out without the scheduler's
+// lock
+func copySchedule(s *Schedule) *Schedule {
+	copied := *s
+	if s.LastRun != nil {
+		last := *s.LastRun
+		copied.LastRun = &last
+	}
+	if s.NextRun != nil {
+		next := *s.NextRun
+		copied.NextRun = &next
+	}
+	return &copied
+}
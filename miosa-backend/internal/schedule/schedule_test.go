@@ -0,0 +1,328 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return tm
+	}
+	cases := []struct {
+		expr, after, want string
+	}{
+		{"0 3 * * *", "2026-03-10T12:00:00Z", "2026-03-11T03:00:00Z"},
+		{"*/15 9-17 * * mon-fri", "2026-03-13T17:50:00Z", "2026-03-16T09:00:00Z"}, // Friday evening to Monday
+		{"@weekly", "2026-03-10T12:00:00Z", "2026-03-15T00:00:00Z"},
+		{"0 0 1,15 * *", "2026-03-02T00:00:00Z", "2026-03-15T00:00:00Z"},
+		{"0 0 13 * 5", "2026-03-02T00:00:00Z", "2026-03-06T00:00:00Z"}, // Either the 13th or a Friday
+		{"30 4 29 feb *", "2026-03-01T00:00:00Z", "2028-02-29T04:30:00Z"},
+		{"0 12 * * 7", "2026-03-10T12:00:00Z", "2026-03-15T12:00:00Z"},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expr)
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, at(c.want), cron.Next(at(c.after)), c.expr)
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * * funday"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCron_NextInZone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	cron, err := ParseCron("30 2 * * *")
+	require.NoError(t, err)
+
+	// 2:30 does not exist on the day clocks spring forward
+	next := cron.Next(time.Date(2026, 3, 7, 12, 0, 0, 0, ny))
+	assert.Equal(t, time.Date(2026, 3, 9, 2, 30, 0, 0, ny), next)
+}
+
+func validSchedule() *Schedule {
+	return &Schedule{
+		Name:    "Nightly audit",
+		Cron:    "0 3 * * *",
+		Request: orchestrate.Request{Description: "Run a quality audit of the linked repository and report regressions"},
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	s := validSchedule()
+	require.NoError(t, s.Validate())
+	assert.Equal(t, OverlapSkip, s.Overlap)
+
+	s = &Schedule{Cron: "* * * * *", Timezone: "Mars/Olympus", Overlap: "replace"}
+	var verr *orchestrate.ValidationError
+	require.ErrorAs(t, s.Validate(), &verr)
+	fields := make(map[string]bool)
+	for _, f := range verr.Fields {
+		fields[f.Field] = true
+	}
+	for _, field := range []string{"name", "cron", "timezone", "overlap", "request.description"} {
+		assert.True(t, fields[field], field)
+	}
+}
+
+// blockingRun runs until released, recording the workflows it started
+type blockingRun struct {
+	mu       sync.Mutex
+	started  []uuid.UUID
+	release  chan struct{}
+	failWith error
+}
+
+func newBlockingRun() *blockingRun {
+	return &blockingRun{release: make(chan struct{})}
+}
+
+func (b *blockingRun) run(ctx context.Context, s *Schedule, workflowID uuid.UUID) error {
+	b.mu.Lock()
+	b.started = append(b.started, workflowID)
+	b.mu.Unlock()
+	<-b.release
+	return b.failWith
+}
+
+func (b *blockingRun) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.started)
+}
+
+func put(t *testing.T, s *Scheduler, overlap Overlap, now time.Time) *Schedule {
+	t.Helper()
+	sched := validSchedule()
+	sched.ID, sched.Tenant, sched.Overlap = uuid.New(), "acme", overlap
+	require.NoError(t, sched.Validate())
+	created, err := s.Put(context.Background(), sched, now)
+	require.NoError(t, err)
+	return created
+}
+
+func TestScheduler_Overlap(t *testing.T) {
+	now := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	fire := now.Add(time.Hour)
+	nextDay := fire.Add(24 * time.Hour)
+
+	for _, tc := range []struct {
+		overlap  Overlap
+		statuses []string // Of the second fire, then after the first run finishes
+		started  int
+	}{
+		{OverlapSkip, []string{StatusSkipped, StatusSkipped}, 1},
+		{OverlapQueue, []string{StatusQueued, StatusSucceeded}, 2},
+		{OverlapAllow, []string{StatusRunning, StatusSucceeded}, 2},
+	} {
+		t.Run(string(tc.overlap), func(t *testing.T) {
+			runner := newBlockingRun()
+			store := NewMemoryStore()
+			s := NewScheduler(store, runner.run, nil)
+			sched := put(t, s, tc.overlap, now)
+			assert.Equal(t, fire, *sched.NextRun)
+
+			assert.Empty(t, s.Due(now))
+			first := s.Due(fire)
+			require.Len(t, first, 1)
+			assert.Equal(t, StatusRunning, first[0].Status)
+			assert.Equal(t, fire, first[0].ScheduledFor)
+
+			second := s.Due(nextDay)
+			require.Len(t, second, 1)
+			assert.Equal(t, tc.statuses[0], second[0].Status)
+
+			close(runner.release)
+			require.Eventually(t, func() bool {
+				runs, _ := store.Runs(context.Background(), sched.ID, 1)
+				return runs[0].Status == tc.statuses[1]
+			}, time.Second, 5*time.Millisecond)
+			s.Wait()
+			assert.Equal(t, tc.started, runner.count())
+
+			got, err := s.Get("acme", sched.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.statuses[1], got.LastRun.Status)
+			assert.Equal(t, nextDay.Add(24*time.Hour), *got.NextRun)
+		})
+	}
+}
+
+func TestScheduler_TenantsPausesAndFailures(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	runner := newBlockingRun()
+	runner.failWith = errors.New("workflow failed")
+	close(runner.release)
+	store := NewMemoryStore()
+	s := NewScheduler(store, runner.run, nil)
+	sched := put(t, s, OverlapSkip, now)
+
+	_, err := s.Get("globex", sched.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Trigger("globex", sched.ID, now)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, s.List("globex"))
+	assert.Len(t, s.List("acme"), 1)
+
+	paused := *sched
+	paused.Paused = true
+	updated, err := s.Put(ctx, &paused, now)
+	require.NoError(t, err)
+	assert.Nil(t, updated.NextRun)
+	assert.Empty(t, s.Due(now.Add(48*time.Hour)), "paused schedules don't fire")
+
+	run, err := s.Trigger("acme", sched.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, TriggerManual, run.Trigger)
+	s.Wait()
+	runs, err := s.Runs(ctx, "acme", sched.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, StatusFailed, runs[0].Status)
+	assert.Equal(t, "workflow failed", runs[0].Error)
+	assert.Equal(t, run.WorkflowID, runs[0].WorkflowID)
+
+	// A restarted scheduler picks the schedule and its last run up again
+	restarted := NewScheduler(store, runner.run, nil)
+	require.NoError(t, restarted.Load(ctx, now))
+	loaded, err := restarted.Get("acme", sched.ID)
+	require.NoError(t, err)
+	assert.True(t, loaded.Paused)
+	assert.Equal(t, StatusFailed, loaded.LastRun.Status)
+
+	require.NoError(t, restarted.Delete(ctx, "acme", sched.ID))
+	_, err = restarted.Get("acme", sched.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStore_BoundsHistory(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	id := uuid.New()
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < MaxHistory+5; i++ {
+		require.NoError(t, store.RecordRun(ctx, &Run{ID: uuid.New(), ScheduleID: id, Status: StatusSkipped, ScheduledFor: start.Add(time.Duration(i) * time.Hour)}))
+	}
+	runs, err := store.Runs(ctx, id, MaxHistory*2)
+	require.NoError(t, err)
+	assert.Len(t, runs, MaxHistory)
+	assert.Equal(t, start.Add(time.Duration(MaxHistory+4)*time.Hour), runs[0].ScheduledFor, "newest first")
+}
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewPostgresStore(db)
+	sched := validSchedule()
+	sched.ID, sched.Tenant, sched.Overlap = uuid.New(), "acme", OverlapQueue
+	created := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	sched.CreatedAt, sched.UpdatedAt = created, created
+	request := []byte(`{"description":"Run a quality audit of the linked repository and report regressions"}`)
+
+	mock.ExpectExec("INSERT INTO workflow_schedules").
+		WithArgs(sched.ID, "acme", "Nightly audit", "0 3 * * *", "", "queue", false, request, created, created).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Save(ctx, sched))
+
+	mock.ExpectQuery("SELECT id, tenant, name, cron, timezone, overlap, paused, request, created_at, updated_at").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant", "name", "cron", "timezone", "overlap", "paused", "request", "created_at", "updated_at"}).
+			AddRow(sched.ID, "acme", "Nightly audit", "0 3 * * *", "", "queue", false, request, created, created))
+	schedules, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, OverlapQueue, schedules[0].Overlap)
+	assert.Equal(t, sched.Request.Description, schedules[0].Request.Description)
+
+	run := &Run{ID: uuid.New(), ScheduleID: sched.ID, Trigger: TriggerSchedule, Status: StatusSkipped, ScheduledFor: created}
+	mock.ExpectExec("INSERT INTO workflow_schedule_runs").
+		WithArgs(run.ID, sched.ID, uuid.NullUUID{}, TriggerSchedule, StatusSkipped, created, nil, nil, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM workflow_schedule_runs").WithArgs(sched.ID, MaxHistory).WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, store.RecordRun(ctx, run))
+
+	mock.ExpectQuery("SELECT id, workflow_id, trigger, status, scheduled_for, started_at, finished_at, error").
+		WithArgs(sched.ID, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_id", "trigger", "status", "scheduled_for", "started_at", "finished_at", "error"}).
+			AddRow(run.ID, nil, TriggerSchedule, StatusSkipped, created, nil, nil, ""))
+	runs, err := store.Runs(ctx, sched.ID, 5)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, uuid.Nil, runs[0].WorkflowID)
+	assert.Nil(t, runs[0].StartedAt)
+
+	mock.ExpectExec("DELETE FROM workflow_schedules").WithArgs(sched.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, store.Delete(ctx, sched.ID), ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandlers(t *testing.T) {
+	runner := newBlockingRun()
+	close(runner.release)
+	s := NewScheduler(NewMemoryStore(), runner.run, nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/workflow/schedules", ListHandler(s)).Methods("GET")
+	router.HandleFunc("/api/workflow/schedules", CreateHandler(s, nil)).Methods("POST")
+	router.HandleFunc("/api/workflow/schedules/{id}", GetHandler(s)).Methods("GET")
+	router.HandleFunc("/api/workflow/schedules/{id}/runs", RunsHandler(s)).Methods("GET")
+	router.HandleFunc("/api/workflow/schedules/{id}/runs", TriggerHandler(s, nil)).Methods("POST")
+
+	do := func(method, path, tenant, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Tenant-ID", tenant)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("POST", "/api/workflow/schedules", "acme", `{"name":"Deps","cron":"* * * * *","request":{"description":"Open weekly dependency update pull requests"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "cron")
+
+	rec = do("POST", "/api/workflow/schedules", "acme", `{"name":"Deps","cron":"0 6 * * mon","timezone":"Europe/Berlin","request":{"description":"Open weekly dependency update pull requests"}}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"next_run"`)
+	var created struct {
+		ID uuid.UUID `json:"id"`
+	}
+	require.NoError(t, decodeJSON(rec, &created))
+	path := "/api/workflow/schedules/" + created.ID.String()
+
+	assert.Equal(t, http.StatusNotFound, do("GET", path, "globex", "").Code)
+	assert.Contains(t, do("GET", "/api/workflow/schedules", "acme", "").Body.String(), `"name":"Deps"`)
+
+	rec = do("POST", path+"/runs", "acme", "")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"trigger":"manual"`)
+	s.Wait()
+	rec = do("GET", path+"/runs?limit=5", "acme", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"succeeded"`)
+	assert.Equal(t, http.StatusBadRequest, do("GET", path+"/runs?limit=0", "acme", "").Code)
+}
+
+func decodeJSON(rec *httptest.ResponseRecorder, v interface{}) error {
+	return json.NewDecoder(rec.Body).Decode(v)
+}
@@ -0,0 +1,294 @@
+package schedule
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RunFunc executes one run of a schedule as workflow workflowID
+type RunFunc func(ctx context.Context, s *Schedule, workflowID uuid.UUID) error
+
+// entry is a schedule as the scheduler tracks it
+type entry struct {
+	schedule *Schedule
+	next     time.Time // Zero while paused
+	running  int
+	queued   *Run
+}
+
+// Scheduler fires schedules when they are due. Fires missed while it was
+// not running, and several missed between two checks, collapse into one
+// fire at the next check.
+type Scheduler struct {
+	store  Store
+	run    RunFunc
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*entry
+	ctx     context.Context // Runs get it, so they outlive the request that triggered them
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler running schedules with run
+func NewScheduler(store Store, run RunFunc, logger *zap.Logger) *Scheduler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Scheduler{
+		store:   store,
+		run:     run,
+		logger:  logger,
+		entries: make(map[uuid.UUID]*entry),
+		ctx:     context.Background(),
+	}
+}
+
+// Load tracks every stored schedule, due from now on
+func (s *Scheduler) Load(ctx context.Context, now time.Time) error {
+	schedules, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sched := range schedules {
+		runs, err := s.store.Runs(ctx, sched.ID, 1)
+		if err != nil {
+			return err
+		}
+		if len(runs) > 0 {
+			sched.LastRun = runs[0]
+		}
+		s.mu.Lock()
+		s.entries[sched.ID] = &entry{schedule: sched, next: nextFire(sched, now)}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func nextFire(sched *Schedule, now time.Time) time.Time {
+	if sched.Paused {
+		return time.Time{}
+	}
+	return sched.Next(now)
+}
+
+// Put stores a validated schedule, creating or replacing it. Runs of the
+// previous version continue.
+func (s *Scheduler) Put(ctx context.Context, sched *Schedule, now time.Time) (*Schedule, error) {
+	sched.NextRun, sched.LastRun = nil, nil
+	if err := s.store.Save(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[sched.ID]
+	if !ok {
+		e = &entry{}
+		s.entries[sched.ID] = e
+	} else {
+		sched.LastRun = e.schedule.LastRun
+	}
+	e.schedule = sched
+	e.next = nextFire(sched, now)
+	return e.view(), nil
+}
+
+// Get returns the tenant's schedule
+func (s *Scheduler) Get(tenant string, id uuid.UUID) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok || e.schedule.Tenant != tenant {
+		return nil, ErrNotFound
+	}
+	return e.view(), nil
+}
+
+// List returns the tenant's schedules, by name
+func (s *Scheduler) List(tenant string) []*Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedules := make([]*Schedule, 0)
+	for _, e := range s.entries {
+		if e.schedule.Tenant == tenant {
+			schedules = append(schedules, e.view())
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].Name != schedules[j].Name {
+			return schedules[i].Name < schedules[j].Name
+		}
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+	return schedules
+}
+
+// Delete removes the tenant's schedule. Its running runs continue.
+func (s *Scheduler) Delete(ctx context.Context, tenant string, id uuid.UUID) error {
+	if _, err := s.Get(tenant, id); err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.entries, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Runs returns up to limit of the tenant's schedule's runs, newest first
+func (s *Scheduler) Runs(ctx context.Context, tenant string, id uuid.UUID, limit int) ([]*Run, error) {
+	if _, err := s.Get(tenant, id); err != nil {
+		return nil, err
+	}
+	return s.store.Runs(ctx, id, limit)
+}
+
+// Trigger fires the tenant's schedule now, subject to its overlap policy.
+// Paused schedules can be triggered.
+func (s *Scheduler) Trigger(tenant string, id uuid.UUID, now time.Time) (*Run, error) {
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	if !ok || e.schedule.Tenant != tenant {
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	run := s.fire(e, TriggerManual, now)
+	s.mu.Unlock()
+	return run, nil
+}
+
+// Due fires every schedule due at now, returning the runs
+func (s *Scheduler) Due(now time.Time) []*Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []*Run
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		scheduledFor := e.next
+		e.next = nextFire(e.schedule, now)
+		run := s.fire(e, TriggerSchedule, scheduledFor)
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ScheduledFor.Before(runs[j].ScheduledFor) })
+	return runs
+}
+
+// fire starts, queues or skips a run of e. Callers hold s.mu.
+func (s *Scheduler) fire(e *entry, trigger string, scheduledFor time.Time) *Run {
+	run := &Run{ID: uuid.New(), ScheduleID: e.schedule.ID, Trigger: trigger, ScheduledFor: scheduledFor}
+	switch {
+	case e.running == 0 || e.schedule.Overlap == OverlapAllow:
+		s.start(e, run)
+	case e.schedule.Overlap == OverlapQueue && e.queued == nil:
+		run.Status = StatusQueued
+		e.queued = run
+		s.record(e, run)
+	default:
+		run.Status = StatusSkipped
+		s.record(e, run)
+		s.logger.Info("Skipped scheduled run; the last one is still going",
+			zap.String("schedule_id", e.schedule.ID.String()),
+			zap.String("trigger", trigger))
+	}
+	copied := *run
+	return &copied
+}
+
+// start runs run in the background. Callers hold s.mu.
+func (s *Scheduler) start(e *entry, run *Run) {
+	started := time.Now()
+	run.Status = StatusRunning
+	run.StartedAt = &started
+	run.WorkflowID = uuid.New()
+	e.running++
+	s.record(e, run)
+
+	ctx, sched := s.ctx, copySchedule(e.schedule)
+	finished := *run
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.run(ctx, sched, finished.WorkflowID)
+
+		now := time.Now()
+		finished.FinishedAt = &now
+		finished.Status = StatusSucceeded
+		if err != nil {
+			finished.Status = StatusFailed
+			finished.Error = err.Error()
+			s.logger.Warn("Scheduled run failed", zap.String("schedule_id", sched.ID.String()), zap.String("workflow_id", finished.WorkflowID.String()), zap.Error(err))
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		e.running--
+		s.record(e, &finished)
+		if e.queued != nil && e.running == 0 {
+			queued := e.queued
+			e.queued = nil
+			s.start(e, queued)
+		}
+	}()
+}
+
+// record persists a run and keeps it as the schedule's last. Callers hold
+// s.mu.
+func (s *Scheduler) record(e *entry, run *Run) {
+	copied := *run
+	if last := e.schedule.LastRun; last == nil || last.ID == run.ID || !run.ScheduledFor.Before(last.ScheduledFor) {
+		e.schedule.LastRun = &copied
+	}
+	if err := s.store.RecordRun(s.ctx, &copied); err != nil {
+		s.logger.Warn("Failed to record scheduled run", zap.String("schedule_id", run.ScheduleID.String()), zap.Error(err))
+	}
+}
+
+// view copies the schedule with its next fire. Callers hold s.mu.
+func (e *entry) view() *Schedule {
+	v := copySchedule(e.schedule)
+	v.NextRun = nil
+	if !e.next.IsZero() {
+		next := e.next
+		v.NextRun = &next
+	}
+	return v
+}
+
+// Run fires due schedules every interval until ctx is done. Runs it starts
+// get ctx.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, run := range s.Due(now) {
+				s.logger.Info("Fired schedule",
+					zap.String("schedule_id", run.ScheduleID.String()),
+					zap.String("status", run.Status),
+					zap.String("workflow_id", run.WorkflowID.String()))
+			}
+		}
+	}
+}
+
+// Wait blocks until every started run has finished
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
@@ -0,0 +1,237 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store persists schedules and their run history
+type Store interface {
+	Save(ctx context.Context, s *Schedule) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// List returns every tenant's schedules
+	List(ctx context.Context) ([]*Schedule, error)
+	// RecordRun inserts or updates a run, keeping the newest MaxHistory
+	// of its schedule
+	RecordRun(ctx context.Context, r *Run) error
+	// Runs returns up to limit of the schedule's runs, newest first
+	Runs(ctx context.Context, scheduleID uuid.UUID, limit int) ([]*Run, error)
+}
+
+// MemoryStore keeps schedules in memory
+type MemoryStore struct {
+	schedules map[uuid.UUID]*Schedule
+	runs      map[uuid.UUID][]*Run // Oldest first
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{schedules: make(map[uuid.UUID]*Schedule), runs: make(map[uuid.UUID][]*Run)}
+}
+
+// Save stores a copy of s
+func (m *MemoryStore) Save(ctx context.Context, s *Schedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := copySchedule(s)
+	copied.NextRun, copied.LastRun = nil, nil
+	m.schedules[s.ID] = copied
+	return nil
+}
+
+// Delete removes the schedule and its runs
+func (m *MemoryStore) Delete(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.schedules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.schedules, id)
+	delete(m.runs, id)
+	return nil
+}
+
+// List returns copies of every schedule, oldest first
+func (m *MemoryStore) List(ctx context.Context) ([]*Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schedules := make([]*Schedule, 0, len(m.schedules))
+	for _, s := range m.schedules {
+		schedules = append(schedules, copySchedule(s))
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].CreatedAt.Before(schedules[j].CreatedAt) })
+	return schedules, nil
+}
+
+// RecordRun stores a copy of r
+func (m *MemoryStore) RecordRun(ctx context.Context, r *Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *r
+	runs := m.runs[r.ScheduleID]
+	for i, existing := range runs {
+		if existing.ID == r.ID {
+			runs[i] = &copied
+			return nil
+		}
+	}
+	runs = append(runs, &copied)
+	if len(runs) > MaxHistory {
+		runs = runs[len(runs)-MaxHistory:]
+	}
+	m.runs[r.ScheduleID] = runs
+	return nil
+}
+
+// Runs returns copies of the schedule's newest runs
+func (m *MemoryStore) Runs(ctx context.Context, scheduleID uuid.UUID, limit int) ([]*Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	runs := m.runs[scheduleID]
+	var newest []*Run
+	for i := len(runs) - 1; i >= 0 && len(newest) < limit; i-- {
+		copied := *runs[i]
+		newest = append(newest, &copied)
+	}
+	return newest, nil
+}
+
+// PostgresStore keeps schedules in the workflow_schedules table, with the
+// request as JSONB, and their runs in workflow_schedule_runs
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a Postgres-backed store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Save inserts or replaces the schedule
+func (p *PostgresStore) Save(ctx context.Context, s *Schedule) error {
+	request, err := json.Marshal(s.Request)
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule request: %w", err)
+	}
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO workflow_schedules (id, tenant, name, cron, timezone, overlap, paused, request, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, cron = EXCLUDED.cron, timezone = EXCLUDED.timezone, overlap = EXCLUDED.overlap,
+			paused = EXCLUDED.paused, request = EXCLUDED.request, updated_at = EXCLUDED.updated_at`,
+		s.ID, s.Tenant, s.Name, s.Cron, s.Timezone, string(s.Overlap), s.Paused, request, s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the schedule; its runs go with it
+func (p *PostgresStore) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := p.db.ExecContext(ctx, `DELETE FROM workflow_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns every schedule, oldest first
+func (p *PostgresStore) List(ctx context.Context) ([]*Schedule, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, tenant, name, cron, timezone, overlap, paused, request, created_at, updated_at
+		FROM workflow_schedules
+		ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*Schedule
+	for rows.Next() {
+		var s Schedule
+		var overlap string
+		var request []byte
+		if err := rows.Scan(&s.ID, &s.Tenant, &s.Name, &s.Cron, &s.Timezone, &overlap, &s.Paused, &request, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		s.Overlap = Overlap(overlap)
+		if err := json.Unmarshal(request, &s.Request); err != nil {
+			return nil, fmt.Errorf("failed to decode schedule request: %w", err)
+		}
+		schedules = append(schedules, &s)
+	}
+	return schedules, rows.Err()
+}
+
+// RecordRun upserts the run, then trims the schedule's history
+func (p *PostgresStore) RecordRun(ctx context.Context, r *Run) error {
+	workflowID := uuid.NullUUID{UUID: r.WorkflowID, Valid: r.WorkflowID != uuid.Nil}
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO workflow_schedule_runs (id, schedule_id, workflow_id, trigger, status, scheduled_for, started_at, finished_at, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			workflow_id = EXCLUDED.workflow_id, status = EXCLUDED.status,
+			started_at = EXCLUDED.started_at, finished_at = EXCLUDED.finished_at, error = EXCLUDED.error`,
+		r.ID, r.ScheduleID, workflowID, r.Trigger, r.Status, r.ScheduledFor, r.StartedAt, r.FinishedAt, r.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record schedule run: %w", err)
+	}
+	_, err = p.db.ExecContext(ctx, `
+		DELETE FROM workflow_schedule_runs
+		WHERE schedule_id = $1 AND id NOT IN (
+			SELECT id FROM workflow_schedule_runs WHERE schedule_id = $1 ORDER BY scheduled_for DESC LIMIT $2
+		)`, r.ScheduleID, MaxHistory)
+	if err != nil {
+		return fmt.Errorf("failed to trim schedule runs: %w", err)
+	}
+	return nil
+}
+
+// Runs returns the schedule's newest runs
+func (p *PostgresStore) Runs(ctx context.Context, scheduleID uuid.UUID, limit int) ([]*Run, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, workflow_id, trigger, status, scheduled_for, started_at, finished_at, error
+		FROM workflow_schedule_runs
+		WHERE schedule_id = $1
+		ORDER BY scheduled_for DESC
+		LIMIT $2`, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*Run
+	for rows.Next() {
+		r := Run{ScheduleID: scheduleID}
+		var workflowID uuid.NullUUID
+		var started, finished sql.NullTime
+		if err := rows.Scan(&r.ID, &workflowID, &r.Trigger, &r.Status, &r.ScheduledFor, &started, &finished, &r.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule run: %w", err)
+		}
+		r.WorkflowID = workflowID.UUID
+		r.StartedAt = timePtr(started)
+		r.FinishedAt = timePtr(finished)
+		runs = append(runs, &r)
+	}
+	return runs, rows.Err()
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
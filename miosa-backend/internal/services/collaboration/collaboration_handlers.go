@@ -0,0 +1,221 @@
+package collaboration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"go.uber.org/zap"
+)
+
+// CreateCollaborationRequest is the body of POST /api/collaborations
+type CreateCollaborationRequest struct {
+	Name  string                  `json:"name"`
+	Tasks []CollaborationTaskSpec `json:"tasks" binding:"required,dive"`
+}
+
+// FeedbackRequest is the body of POST .../tasks/:task_id/feedback
+type FeedbackRequest struct {
+	Type        FeedbackType           `json:"type" binding:"required"`
+	Message     string                 `json:"message"`
+	Confidence  float64                `json:"confidence"`
+	Suggestions []string               `json:"suggestions,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Rating      float64                `json:"rating,omitempty"`
+	Passed      *bool                  `json:"passed,omitempty"`
+}
+
+// CreateCollaboration handles POST /api/collaborations
+func (h *Handlers) CreateCollaboration(c *gin.Context) {
+	var req CreateCollaborationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, actor, taskContext := requestTenant(c)
+	collab, tasks, err := h.collaborations.Create(c.Request.Context(), tenantID, actor, req.Name, req.Tasks)
+	if err != nil {
+		h.logger.Warn("Failed to create collaboration", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit.Record(c.Request.Context(), audit.FromTaskContext(audit.Event{
+		WorkflowID:  collab.ID,
+		Action:      audit.ActionOrchestrate,
+		Resource:    c.Request.URL.Path,
+		RequestHash: audit.HashRequest(req),
+		Status:      audit.StatusSuccess,
+	}, taskContext))
+
+	h.logger.Info("Collaboration created",
+		zap.String("collaboration_id", collab.ID.String()),
+		zap.Int("tasks", len(tasks)))
+	c.JSON(http.StatusCreated, gin.H{"collaboration": collab, "tasks": tasks})
+}
+
+// ListCollaborations handles GET /api/collaborations
+func (h *Handlers) ListCollaborations(c *gin.Context) {
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	tenantID, _, _ := requestTenant(c)
+	collabs, err := h.collaborations.List(c.Request.Context(), tenantID, limit)
+	if err != nil {
+		h.logger.Error("Failed to list collaborations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list collaborations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"collaborations": collabs})
+}
+
+// GetCollaboration handles GET /api/collaborations/:id
+func (h *Handlers) GetCollaboration(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	tenantID, _, _ := requestTenant(c)
+	collab, tasks, err := h.collaborations.Get(c.Request.Context(), tenantID, id)
+	if err != nil {
+		h.collaborationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"collaboration": collab, "tasks": tasks})
+}
+
+// GetCollaborationTask handles GET /api/collaborations/:id/tasks/:task_id
+func (h *Handlers) GetCollaborationTask(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	taskID, ok := uuidParam(c, "task_id")
+	if !ok {
+		return
+	}
+	tenantID, _, _ := requestTenant(c)
+	task, err := h.collaborations.Task(c.Request.Context(), tenantID, id, taskID)
+	if err != nil {
+		h.collaborationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"task": task})
+}
+
+// SubmitFeedback handles POST /api/collaborations/:id/tasks/:task_id/feedback
+func (h *Handlers) SubmitFeedback(c *gin.Context) {
+	id, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	taskID, ok := uuidParam(c, "task_id")
+	if !ok {
+		return
+	}
+	var req FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID, actor, _ := requestTenant(c)
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	if actor != "" {
+		metadata["submitted_by"] = actor
+	}
+	task, err := h.collaborations.AddFeedback(c.Request.Context(), tenantID, id, taskID, FeedbackEntry{
+		Type:        req.Type,
+		Message:     req.Message,
+		Confidence:  req.Confidence,
+		Suggestions: req.Suggestions,
+		Metadata:    metadata,
+		Rating:      req.Rating,
+		Passed:      req.Passed,
+	})
+	if err != nil {
+		h.collaborationError(c, err)
+		return
+	}
+	if IsRewardFeedback(req.Type) {
+		h.rewardCollaboration(c.Request.Context(), tenantID, id, task.Feedback[len(task.Feedback)-1])
+	}
+	c.JSON(http.StatusCreated, gin.H{"task": task})
+}
+
+// rewardCollaboration applies user feedback to the pattern learned from
+// the collaboration's agent sequence, if one has been learned
+func (h *Handlers) rewardCollaboration(ctx context.Context, tenantID, id uuid.UUID, entry FeedbackEntry) {
+	if h.improvement == nil {
+		return
+	}
+	_, tasks, err := h.collaborations.Get(ctx, tenantID, id)
+	if err != nil || len(tasks) == 0 {
+		return
+	}
+	sequence := make([]agents.AgentType, 0, len(tasks))
+	for _, t := range tasks {
+		sequence = append(sequence, t.AssignedAgent)
+	}
+	if _, err := h.improvement.ApplyFeedback(ctx, uuid.Nil, tasks[0].Type, sequence, []FeedbackEntry{entry}); err != nil && !errors.Is(err, ErrPatternNotFound) {
+		h.logger.Warn("Failed to apply feedback to pattern",
+			zap.String("collaboration_id", id.String()),
+			zap.Error(err))
+	}
+}
+
+// collaborationError maps collaboration errors to responses
+func (h *Handlers) collaborationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCollaborationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collaboration not found"})
+	case errors.Is(err, ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	default:
+		h.logger.Error("Collaboration request failed", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// requestTenant returns the caller's tenant and identity. Without auth
+// every caller shares the nil tenant.
+func requestTenant(c *gin.Context) (uuid.UUID, string, *agents.TaskContext) {
+	v, exists := c.Get("task_context")
+	if !exists {
+		return uuid.Nil, "", nil
+	}
+	taskContext, ok := v.(*agents.TaskContext)
+	if !ok || taskContext == nil {
+		return uuid.Nil, "", nil
+	}
+	actor := ""
+	if taskContext.UserID != uuid.Nil {
+		actor = taskContext.UserID.String()
+	}
+	return taskContext.TenantID, actor, taskContext
+}
+
+func uuidParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return uuid.Nil, false
+	}
+	return id, true
+}
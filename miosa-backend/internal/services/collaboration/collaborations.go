@@ -0,0 +1,567 @@
+package collaboration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"go.uber.org/zap"
+)
+
+// ErrCollaborationNotFound is returned for unknown collaborations, including
+// those belonging to another tenant
+var ErrCollaborationNotFound = errors.New("collaboration not found")
+
+// ErrTaskNotFound is returned for tasks outside the collaboration
+var ErrTaskNotFound = errors.New("task not found")
+
+// HumanFeedback is the agent type recorded on feedback submitted by people
+const HumanFeedback agents.AgentType = "human"
+
+// Collaboration groups collaborative tasks with dependencies between them.
+// Tasks are queued once every dependency has completed.
+type Collaboration struct {
+	ID        uuid.UUID   `json:"id"`
+	TenantID  uuid.UUID   `json:"tenant_id"`
+	Name      string      `json:"name"`
+	Status    TaskStatus  `json:"status"`
+	TaskIDs   []uuid.UUID `json:"task_ids"`
+	CreatedBy string      `json:"created_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// CollaborationTaskSpec describes one task of a new collaboration. Key is
+// a caller-chosen name other tasks use in DependsOn.
+type CollaborationTaskSpec struct {
+	Key       string                 `json:"key" binding:"required"`
+	Type      string                 `json:"type"`
+	Agent     agents.AgentType       `json:"agent" binding:"required"`
+	Input     string                 `json:"input" binding:"required"`
+	Priority  int                    `json:"priority"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+	DependsOn []string               `json:"depends_on,omitempty"`
+	Deadline  *time.Time             `json:"deadline,omitempty"`
+}
+
+// CollaborationStore persists collaborations and their tasks
+type CollaborationStore interface {
+	SaveCollaboration(ctx context.Context, c *Collaboration) error
+	GetCollaboration(ctx context.Context, id uuid.UUID) (*Collaboration, error)
+	// ListCollaborations returns the tenant's collaborations, newest first
+	ListCollaborations(ctx context.Context, tenantID uuid.UUID, limit int) ([]*Collaboration, error)
+	SaveTask(ctx context.Context, task *CollaborativeTask) error
+	GetTask(ctx context.Context, id uuid.UUID) (*CollaborativeTask, error)
+}
+
+// TaskDispatcher queues a task whose dependencies are satisfied. Enqueue
+// marks the task pending and must persist it where the store reads tasks;
+// TaskQueue does so through the shared task:<id> keys.
+type TaskDispatcher interface {
+	Enqueue(ctx context.Context, task *CollaborativeTask) error
+}
+
+// Collaborations creates collaborations, releases tasks as their
+// dependencies complete and records feedback
+type Collaborations struct {
+	store      CollaborationStore
+	dispatcher TaskDispatcher
+	logger     *zap.Logger
+	mu         sync.Mutex // serializes advance so a task is released once
+}
+
+// NewCollaborations creates a collaboration manager
+func NewCollaborations(store CollaborationStore, dispatcher TaskDispatcher, logger *zap.Logger) *Collaborations {
+	return &Collaborations{store: store, dispatcher: dispatcher, logger: logger}
+}
+
+// Create validates the task graph, stores the collaboration and queues the
+// tasks without dependencies; the rest wait as blocked
+func (m *Collaborations) Create(ctx context.Context, tenantID uuid.UUID, createdBy, name string, specs []CollaborationTaskSpec) (*Collaboration, []*CollaborativeTask, error) {
+	if len(specs) == 0 {
+		return nil, nil, fmt.Errorf("a collaboration needs at least one task")
+	}
+	ids := make(map[string]uuid.UUID, len(specs))
+	for _, spec := range specs {
+		if _, dup := ids[spec.Key]; dup {
+			return nil, nil, fmt.Errorf("duplicate task key %q", spec.Key)
+		}
+		ids[spec.Key] = uuid.New()
+	}
+	for _, spec := range specs {
+		for _, dep := range spec.DependsOn {
+			if _, ok := ids[dep]; !ok {
+				return nil, nil, fmt.Errorf("task %q depends on unknown task %q", spec.Key, dep)
+			}
+		}
+	}
+	if cycle := findCycle(specs); cycle != "" {
+		return nil, nil, fmt.Errorf("dependency cycle through task %q", cycle)
+	}
+
+	now := time.Now()
+	collab := &Collaboration{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Name:      name,
+		Status:    TaskStatusPending,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	tasks := make([]*CollaborativeTask, 0, len(specs))
+	for _, spec := range specs {
+		deps := make([]uuid.UUID, 0, len(spec.DependsOn))
+		for _, dep := range spec.DependsOn {
+			deps = append(deps, ids[dep])
+		}
+		taskContext := map[string]interface{}{"key": spec.Key}
+		for k, v := range spec.Context {
+			taskContext[k] = v
+		}
+		task := &CollaborativeTask{
+			ID:              ids[spec.Key],
+			CollaborationID: &collab.ID,
+			Type:            spec.Type,
+			Priority:        spec.Priority,
+			Status:          TaskStatusBlocked,
+			AssignedAgent:   spec.Agent,
+			CreatedBy:       agents.OrchestratorAgent,
+			Input:           spec.Input,
+			Context:         taskContext,
+			Dependencies:    deps,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			Deadline:        spec.Deadline,
+			MaxRetries:      3,
+		}
+		if err := m.store.SaveTask(ctx, task); err != nil {
+			return nil, nil, err
+		}
+		collab.TaskIDs = append(collab.TaskIDs, task.ID)
+		tasks = append(tasks, task)
+	}
+	if err := m.store.SaveCollaboration(ctx, collab); err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.advance(ctx, collab); err != nil {
+		return nil, nil, err
+	}
+	return collab, tasks, nil
+}
+
+// findCycle returns the key of a task on a dependency cycle, or ""
+func findCycle(specs []CollaborationTaskSpec) string {
+	deps := make(map[string][]string, len(specs))
+	for _, s := range specs {
+		deps[s.Key] = s.DependsOn
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(specs))
+	var visit func(key string) string
+	visit = func(key string) string {
+		switch state[key] {
+		case visiting:
+			return key
+		case done:
+			return ""
+		}
+		state[key] = visiting
+		for _, d := range deps[key] {
+			if c := visit(d); c != "" {
+				return c
+			}
+		}
+		state[key] = done
+		return ""
+	}
+	for _, s := range specs {
+		if c := visit(s.Key); c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+// Get returns the tenant's collaboration and its tasks, releasing any tasks
+// whose dependencies have completed since the last update
+func (m *Collaborations) Get(ctx context.Context, tenantID, id uuid.UUID) (*Collaboration, []*CollaborativeTask, error) {
+	collab, err := m.load(ctx, tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.advance(ctx, collab); err != nil {
+		return nil, nil, err
+	}
+	tasks, err := m.tasks(ctx, collab)
+	if err != nil {
+		return nil, nil, err
+	}
+	return collab, tasks, nil
+}
+
+// List returns the tenant's collaborations, newest first
+func (m *Collaborations) List(ctx context.Context, tenantID uuid.UUID, limit int) ([]*Collaboration, error) {
+	return m.store.ListCollaborations(ctx, tenantID, limit)
+}
+
+// Task returns one task of the tenant's collaboration
+func (m *Collaborations) Task(ctx context.Context, tenantID, id, taskID uuid.UUID) (*CollaborativeTask, error) {
+	collab, err := m.load(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, tid := range collab.TaskIDs {
+		if tid == taskID {
+			return m.store.GetTask(ctx, taskID)
+		}
+	}
+	return nil, ErrTaskNotFound
+}
+
+// AddFeedback appends human feedback to a task
+func (m *Collaborations) AddFeedback(ctx context.Context, tenantID, id, taskID uuid.UUID, entry FeedbackEntry) (*CollaborativeTask, error) {
+	if !validFeedbackType(entry.Type) {
+		return nil, fmt.Errorf("unknown feedback type %q", entry.Type)
+	}
+	if err := validateRewardFeedback(entry); err != nil {
+		return nil, err
+	}
+	task, err := m.Task(ctx, tenantID, id, taskID)
+	if err != nil {
+		return nil, err
+	}
+	entry.AgentType = HumanFeedback
+	entry.Timestamp = time.Now()
+	task.Feedback = append(task.Feedback, entry)
+	task.UpdatedAt = entry.Timestamp
+	if err := m.store.SaveTask(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func validFeedbackType(t FeedbackType) bool {
+	switch t {
+	case FeedbackTypeSuccess, FeedbackTypeImprovement, FeedbackTypeError, FeedbackTypeHandoff, FeedbackTypeCollaborate:
+		return true
+	}
+	return IsRewardFeedback(t)
+}
+
+// TaskFinished advances the task's collaboration; register it with
+// TaskQueue.OnFinish
+func (m *Collaborations) TaskFinished(ctx context.Context, task *CollaborativeTask) {
+	if task.CollaborationID == nil {
+		return
+	}
+	collab, err := m.store.GetCollaboration(ctx, *task.CollaborationID)
+	if err == nil {
+		err = m.advance(ctx, collab)
+	}
+	if err != nil {
+		m.logger.Warn("Failed to advance collaboration",
+			zap.String("collaboration_id", task.CollaborationID.String()),
+			zap.Error(err))
+	}
+}
+
+func (m *Collaborations) load(ctx context.Context, tenantID, id uuid.UUID) (*Collaboration, error) {
+	collab, err := m.store.GetCollaboration(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if collab.TenantID != tenantID {
+		return nil, ErrCollaborationNotFound
+	}
+	return collab, nil
+}
+
+func (m *Collaborations) tasks(ctx context.Context, collab *Collaboration) ([]*CollaborativeTask, error) {
+	tasks := make([]*CollaborativeTask, 0, len(collab.TaskIDs))
+	for _, id := range collab.TaskIDs {
+		task, err := m.store.GetTask(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// advance queues blocked tasks whose dependencies all completed, cancels
+// those with a failed dependency, and updates the collaboration status
+func (m *Collaborations) advance(ctx context.Context, collab *Collaboration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks, err := m.tasks(ctx, collab)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*CollaborativeTask, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+
+	// Cancellations cascade, so repeat until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, t := range tasks {
+			if t.Status != TaskStatusBlocked {
+				continue
+			}
+			ready := true
+			for _, dep := range t.Dependencies {
+				switch byID[dep].Status {
+				case TaskStatusCompleted:
+				case TaskStatusFailed, TaskStatusCancelled:
+					t.Status = TaskStatusCancelled
+					t.UpdatedAt = time.Now()
+					if err := m.store.SaveTask(ctx, t); err != nil {
+						return err
+					}
+					changed, ready = true, false
+				default:
+					ready = false
+				}
+				if t.Status == TaskStatusCancelled {
+					break
+				}
+			}
+			if ready && t.Status == TaskStatusBlocked {
+				if err := m.dispatcher.Enqueue(ctx, t); err != nil {
+					return fmt.Errorf("failed to queue task %s: %w", t.ID, err)
+				}
+				changed = true
+			}
+		}
+	}
+
+	status := collaborationStatus(tasks)
+	if status != collab.Status {
+		collab.Status = status
+		collab.UpdatedAt = time.Now()
+		return m.store.SaveCollaboration(ctx, collab)
+	}
+	return nil
+}
+
+// collaborationStatus is completed when every task completed, failed when
+// any task failed or was cancelled and none are still running, in progress
+// once any task has started and pending otherwise
+func collaborationStatus(tasks []*CollaborativeTask) TaskStatus {
+	completed, failed, active := 0, 0, 0
+	for _, t := range tasks {
+		switch t.Status {
+		case TaskStatusCompleted:
+			completed++
+		case TaskStatusFailed, TaskStatusCancelled:
+			failed++
+		case TaskStatusAssigned, TaskStatusInProgress:
+			active++
+		}
+	}
+	switch {
+	case completed == len(tasks):
+		return TaskStatusCompleted
+	case failed > 0 && failed+completed == len(tasks):
+		return TaskStatusFailed
+	case active > 0 || completed > 0 || failed > 0:
+		return TaskStatusInProgress
+	default:
+		return TaskStatusPending
+	}
+}
+
+// MemoryCollaborationStore keeps collaborations in memory
+type MemoryCollaborationStore struct {
+	collaborations map[uuid.UUID]*Collaboration
+	tasks          map[uuid.UUID]*CollaborativeTask
+	mu             sync.RWMutex
+}
+
+// NewMemoryCollaborationStore creates an empty store
+func NewMemoryCollaborationStore() *MemoryCollaborationStore {
+	return &MemoryCollaborationStore{
+		collaborations: make(map[uuid.UUID]*Collaboration),
+		tasks:          make(map[uuid.UUID]*CollaborativeTask),
+	}
+}
+
+// SaveCollaboration stores a copy of c
+func (s *MemoryCollaborationStore) SaveCollaboration(ctx context.Context, c *Collaboration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *c
+	copied.TaskIDs = append([]uuid.UUID(nil), c.TaskIDs...)
+	s.collaborations[c.ID] = &copied
+	return nil
+}
+
+// GetCollaboration returns a copy of the collaboration
+func (s *MemoryCollaborationStore) GetCollaboration(ctx context.Context, id uuid.UUID) (*Collaboration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.collaborations[id]
+	if !ok {
+		return nil, ErrCollaborationNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// ListCollaborations returns the tenant's collaborations, newest first
+func (s *MemoryCollaborationStore) ListCollaborations(ctx context.Context, tenantID uuid.UUID, limit int) ([]*Collaboration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Collaboration
+	for _, c := range s.collaborations {
+		if c.TenantID == tenantID {
+			copied := *c
+			out = append(out, &copied)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// SaveTask stores a copy of task
+func (s *MemoryCollaborationStore) SaveTask(ctx context.Context, task *CollaborativeTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *task
+	s.tasks[task.ID] = &copied
+	return nil
+}
+
+// GetTask returns a copy of the task
+func (s *MemoryCollaborationStore) GetTask(ctx context.Context, id uuid.UUID) (*CollaborativeTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	copied := *t
+	copied.Feedback = append([]FeedbackEntry(nil), t.Feedback...)
+	return &copied, nil
+}
+
+// collaborationTTL matches the task queue's retention
+const collaborationTTL = 24 * time.Hour
+
+// RedisCollaborationStore keeps collaborations in Redis. Tasks share the
+// task queue's task:<id> keys, so status changes made by agents are seen.
+type RedisCollaborationStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCollaborationStore creates a Redis-backed store
+func NewRedisCollaborationStore(client redis.UniversalClient) *RedisCollaborationStore {
+	return &RedisCollaborationStore{client: client}
+}
+
+// SaveCollaboration stores c and indexes it under its tenant
+func (s *RedisCollaborationStore) SaveCollaboration(ctx context.Context, c *Collaboration) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collaboration: %w", err)
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, "collaboration:"+c.ID.String(), data, collaborationTTL)
+	indexKey := "collaborations:" + c.TenantID.String()
+	pipe.ZAdd(ctx, indexKey, redis.Z{Score: float64(c.CreatedAt.UnixNano()), Member: c.ID.String()})
+	pipe.Expire(ctx, indexKey, collaborationTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store collaboration: %w", err)
+	}
+	return nil
+}
+
+// GetCollaboration returns the collaboration with id
+func (s *RedisCollaborationStore) GetCollaboration(ctx context.Context, id uuid.UUID) (*Collaboration, error) {
+	data, err := s.client.Get(ctx, "collaboration:"+id.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCollaborationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collaboration: %w", err)
+	}
+	var c Collaboration
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal collaboration: %w", err)
+	}
+	return &c, nil
+}
+
+// ListCollaborations returns the tenant's collaborations, newest first.
+// Expired entries are skipped.
+func (s *RedisCollaborationStore) ListCollaborations(ctx context.Context, tenantID uuid.UUID, limit int) ([]*Collaboration, error) {
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(limit - 1)
+	}
+	ids, err := s.client.ZRevRange(ctx, "collaborations:"+tenantID.String(), 0, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collaborations: %w", err)
+	}
+	out := make([]*Collaboration, 0, len(ids))
+	for _, raw := range ids {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			continue
+		}
+		c, err := s.GetCollaboration(ctx, id)
+		if errors.Is(err, ErrCollaborationNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// SaveTask stores the task without queuing it
+func (s *RedisCollaborationStore) SaveTask(ctx context.Context, task *CollaborativeTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	if err := s.client.Set(ctx, "task:"+task.ID.String(), data, collaborationTTL).Err(); err != nil {
+		return fmt.Errorf("failed to store task: %w", err)
+	}
+	return nil
+}
+
+// GetTask returns the task with id
+func (s *RedisCollaborationStore) GetTask(ctx context.Context, id uuid.UUID) (*CollaborativeTask, error) {
+	data, err := s.client.Get(ctx, "task:"+id.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	var task CollaborativeTask
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	return &task, nil
+}
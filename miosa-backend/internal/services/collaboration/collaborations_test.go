@@ -0,0 +1,178 @@
+package collaboration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// storeDispatcher marks queued tasks pending in the store, as TaskQueue does
+// through the shared Redis keys
+type storeDispatcher struct {
+	store  CollaborationStore
+	queued []string
+}
+
+func (d *storeDispatcher) Enqueue(ctx context.Context, task *CollaborativeTask) error {
+	task.Status = TaskStatusPending
+	d.queued = append(d.queued, task.Context["key"].(string))
+	return d.store.SaveTask(ctx, task)
+}
+
+func newTestCollaborations() (*Collaborations, *MemoryCollaborationStore, *storeDispatcher) {
+	store := NewMemoryCollaborationStore()
+	dispatcher := &storeDispatcher{store: store}
+	return NewCollaborations(store, dispatcher, zap.NewNop()), store, dispatcher
+}
+
+func finish(t *testing.T, m *Collaborations, store CollaborationStore, id uuid.UUID, status TaskStatus) {
+	task, err := store.GetTask(context.Background(), id)
+	require.NoError(t, err)
+	task.Status = status
+	require.NoError(t, store.SaveTask(context.Background(), task))
+	m.TaskFinished(context.Background(), task)
+}
+
+func TestCollaborations_ReleasesTasksAsDependenciesComplete(t *testing.T) {
+	ctx := context.Background()
+	m, store, dispatcher := newTestCollaborations()
+	tenant := uuid.New()
+
+	collab, tasks, err := m.Create(ctx, tenant, "user-1", "chat app", []CollaborationTaskSpec{
+		{Key: "design", Agent: agents.ArchitectAgent, Input: "design"},
+		{Key: "backend", Agent: agents.DevelopmentAgent, Input: "backend", DependsOn: []string{"design"}},
+		{Key: "frontend", Agent: agents.DevelopmentAgent, Input: "frontend", DependsOn: []string{"design"}},
+		{Key: "review", Agent: agents.QualityAgent, Input: "review", DependsOn: []string{"backend", "frontend"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"design"}, dispatcher.queued)
+	assert.Equal(t, []uuid.UUID{tasks[0].ID}, tasks[1].Dependencies)
+
+	finish(t, m, store, tasks[0].ID, TaskStatusCompleted)
+	assert.Equal(t, []string{"design", "backend", "frontend"}, dispatcher.queued)
+
+	finish(t, m, store, tasks[1].ID, TaskStatusCompleted)
+	assert.Len(t, dispatcher.queued, 3, "review waits for both")
+	finish(t, m, store, tasks[2].ID, TaskStatusCompleted)
+	assert.Equal(t, "review", dispatcher.queued[3])
+
+	got, _, err := m.Get(ctx, tenant, collab.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusInProgress, got.Status)
+
+	finish(t, m, store, tasks[3].ID, TaskStatusCompleted)
+	got, _, err = m.Get(ctx, tenant, collab.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, got.Status)
+
+	_, _, err = m.Get(ctx, uuid.New(), collab.ID)
+	assert.ErrorIs(t, err, ErrCollaborationNotFound, "other tenants can't see it")
+}
+
+func TestCollaborations_FailureCancelsDependents(t *testing.T) {
+	ctx := context.Background()
+	m, store, _ := newTestCollaborations()
+
+	collab, tasks, err := m.Create(ctx, uuid.Nil, "", "", []CollaborationTaskSpec{
+		{Key: "a", Agent: agents.AnalysisAgent, Input: "a"},
+		{Key: "b", Agent: agents.DevelopmentAgent, Input: "b", DependsOn: []string{"a"}},
+		{Key: "c", Agent: agents.QualityAgent, Input: "c", DependsOn: []string{"b"}},
+	})
+	require.NoError(t, err)
+
+	finish(t, m, store, tasks[0].ID, TaskStatusFailed)
+	got, all, err := m.Get(ctx, uuid.Nil, collab.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, all[1].Status)
+	assert.Equal(t, TaskStatusCancelled, all[2].Status)
+	assert.Equal(t, TaskStatusFailed, got.Status)
+}
+
+func TestCollaborations_RejectsInvalidGraphs(t *testing.T) {
+	m, _, _ := newTestCollaborations()
+	tests := map[string][]CollaborationTaskSpec{
+		"empty":          nil,
+		"duplicate key":  {{Key: "a", Agent: "x", Input: "i"}, {Key: "a", Agent: "x", Input: "i"}},
+		"unknown dep":    {{Key: "a", Agent: "x", Input: "i", DependsOn: []string{"b"}}},
+		"cycle":          {{Key: "a", Agent: "x", Input: "i", DependsOn: []string{"b"}}, {Key: "b", Agent: "x", Input: "i", DependsOn: []string{"a"}}},
+		"self dependent": {{Key: "a", Agent: "x", Input: "i", DependsOn: []string{"a"}}},
+	}
+	for name, specs := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := m.Create(context.Background(), uuid.Nil, "", "", specs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHandlers_CollaborationAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, _, _ := newTestCollaborations()
+	h := &Handlers{collaborations: m, logger: zap.NewNop()}
+	tenant := uuid.New()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("task_context", &agents.TaskContext{TenantID: tenant, UserID: uuid.New()})
+	})
+	r.POST("/collaborations", h.CreateCollaboration)
+	r.GET("/collaborations", h.ListCollaborations)
+	r.GET("/collaborations/:id/tasks/:task_id", h.GetCollaborationTask)
+	r.POST("/collaborations/:id/tasks/:task_id/feedback", h.SubmitFeedback)
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+		return w
+	}
+
+	w := do(http.MethodPost, "/collaborations", CreateCollaborationRequest{
+		Name:  "blog",
+		Tasks: []CollaborationTaskSpec{{Key: "design", Agent: agents.ArchitectAgent, Input: "design a blog"}},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Collaboration Collaboration       `json:"collaboration"`
+		Tasks         []CollaborativeTask `json:"tasks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	taskPath := "/collaborations/" + created.Collaboration.ID.String() + "/tasks/" + created.Tasks[0].ID.String()
+
+	w = do(http.MethodPost, taskPath+"/feedback", FeedbackRequest{Type: FeedbackTypeImprovement, Message: "add comments"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = do(http.MethodGet, taskPath, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var got struct {
+		Task CollaborativeTask `json:"task"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	require.Len(t, got.Task.Feedback, 1)
+	assert.Equal(t, HumanFeedback, got.Task.Feedback[0].AgentType)
+	assert.Equal(t, "add comments", got.Task.Feedback[0].Message)
+
+	w = do(http.MethodPost, taskPath+"/feedback", FeedbackRequest{Type: "praise", Message: "nice"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodGet, "/collaborations/"+created.Collaboration.ID.String()+"/tasks/"+uuid.New().String(), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodGet, "/collaborations", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), created.Collaboration.ID.String())
+}
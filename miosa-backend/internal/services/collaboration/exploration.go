@@ -0,0 +1,184 @@
+package collaboration
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ExplorationStrategy decides how often pattern selection deviates from the
+// highest Q-value so alternative agent sequences keep getting tried
+type ExplorationStrategy string
+
+const (
+	// ExplorationGreedy always exploits the highest Q-value
+	ExplorationGreedy ExplorationStrategy = "greedy"
+	// ExplorationEpsilonGreedy picks a random alternative with probability Epsilon
+	ExplorationEpsilonGreedy ExplorationStrategy = "epsilon_greedy"
+	// ExplorationUCB picks the highest upper confidence bound, favouring
+	// rarely used patterns
+	ExplorationUCB ExplorationStrategy = "ucb"
+)
+
+// ParseExplorationStrategy validates a strategy name, defaulting to
+// epsilon-greedy
+func ParseExplorationStrategy(s string) (ExplorationStrategy, error) {
+	switch st := ExplorationStrategy(strings.ToLower(s)); st {
+	case "":
+		return ExplorationEpsilonGreedy, nil
+	case ExplorationGreedy, ExplorationEpsilonGreedy, ExplorationUCB:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown exploration strategy %q", s)
+	}
+}
+
+// ExplorationPolicy configures pattern selection
+type ExplorationPolicy struct {
+	Strategy ExplorationStrategy
+	// Epsilon is the exploration probability for epsilon-greedy
+	Epsilon float64
+	// UCBConstant scales the UCB exploration bonus
+	UCBConstant float64
+}
+
+// DefaultExplorationPolicy explores one selection in ten
+var DefaultExplorationPolicy = ExplorationPolicy{
+	Strategy:    ExplorationEpsilonGreedy,
+	Epsilon:     0.1,
+	UCBConstant: math.Sqrt2,
+}
+
+// PatternSelection is the outcome of SelectPattern
+type PatternSelection struct {
+	Pattern  *CollaborationPattern `json:"pattern"`
+	Explored bool                  `json:"explored"`
+	Strategy ExplorationStrategy   `json:"strategy"`
+	// Best is the exploit choice, which differs from Pattern when explored
+	Best *CollaborationPattern `json:"best,omitempty"`
+}
+
+// SetExplorationPolicy changes how SelectPattern balances exploration and
+// exploitation
+func (sie *SelfImprovementEngine) SetExplorationPolicy(p ExplorationPolicy) {
+	sie.mu.Lock()
+	defer sie.mu.Unlock()
+	sie.exploration = p
+}
+
+// SelectPattern chooses a pattern for taskType under the exploration
+// policy. Explored patterns are still returned with their learned sequence
+// so running them feeds AnalyzeCollaboration and updates their Q-value.
+// It returns nil when no pattern is known for the task type.
+func (sie *SelfImprovementEngine) SelectPattern(ctx context.Context, taskType string) *PatternSelection {
+	candidates := sie.candidatePatterns(ctx, taskType)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sie.mu.RLock()
+	policy := sie.exploration
+	random := sie.random
+	sie.mu.RUnlock()
+
+	// Stable order so ties and random picks don't depend on map iteration
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].QValue != candidates[j].QValue {
+			return candidates[i].QValue > candidates[j].QValue
+		}
+		return candidates[i].ID.String() < candidates[j].ID.String()
+	})
+	best := candidates[0]
+	selection := &PatternSelection{Pattern: best, Best: best, Strategy: policy.Strategy}
+
+	switch policy.Strategy {
+	case ExplorationEpsilonGreedy:
+		if len(candidates) > 1 && random() < policy.Epsilon {
+			alternatives := candidates[1:]
+			selection.Pattern = alternatives[int(random()*float64(len(alternatives)))%len(alternatives)]
+		}
+	case ExplorationUCB:
+		selection.Pattern = ucbPattern(candidates, policy.UCBConstant)
+	}
+	selection.Explored = selection.Pattern != best
+
+	if selection.Explored {
+		sie.logger.Info("Exploring alternative collaboration pattern",
+			zap.String("task_type", taskType),
+			zap.String("strategy", string(policy.Strategy)),
+			zap.String("pattern_id", selection.Pattern.ID.String()),
+			zap.Float64("q_value", selection.Pattern.QValue),
+			zap.String("best_pattern_id", best.ID.String()),
+			zap.Float64("best_q_value", best.QValue))
+	} else {
+		sie.logger.Debug("Exploiting best collaboration pattern",
+			zap.String("task_type", taskType),
+			zap.String("pattern_id", best.ID.String()),
+			zap.Float64("q_value", best.QValue))
+	}
+	return selection
+}
+
+// candidatePatterns returns the known patterns for taskType, falling back
+// to the store when none are in memory. Stored patterns are cached so
+// a later AnalyzeCollaboration updates them rather than starting over.
+func (sie *SelfImprovementEngine) candidatePatterns(ctx context.Context, taskType string) []*CollaborationPattern {
+	var candidates []*CollaborationPattern
+	sie.mu.RLock()
+	for _, p := range sie.patterns {
+		if p.TaskType == taskType {
+			candidates = append(candidates, p)
+		}
+	}
+	sie.mu.RUnlock()
+	if len(candidates) > 0 {
+		return candidates
+	}
+
+	byID, err := sie.loadPatterns(ctx)
+	if err != nil {
+		sie.logger.Warn("Failed to load patterns", zap.Error(err))
+		return nil
+	}
+	sie.mu.Lock()
+	defer sie.mu.Unlock()
+	for _, p := range byID {
+		if p.TaskType != taskType {
+			continue
+		}
+		key := sie.generatePatternKey(p.TaskType, p.AgentSequence)
+		if cached, ok := sie.patterns[key]; ok {
+			p = cached
+		} else {
+			sie.patterns[key] = p
+		}
+		candidates = append(candidates, p)
+	}
+	return candidates
+}
+
+// ucbPattern returns the pattern maximising Q + c*sqrt(ln N / n). Patterns
+// never used are tried first.
+func ucbPattern(candidates []*CollaborationPattern, c float64) *CollaborationPattern {
+	var total int64
+	for _, p := range candidates {
+		if p.UsageCount <= 0 {
+			return p
+		}
+		total += p.UsageCount
+	}
+
+	var best *CollaborationPattern
+	bestScore := math.Inf(-1)
+	for _, p := range candidates {
+		score := p.QValue + c*math.Sqrt(math.Log(float64(total))/float64(p.UsageCount))
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,107 @@
+package collaboration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func newExplorationEngine(t *testing.T, policy ExplorationPolicy, random ...float64) (*SelfImprovementEngine, *CollaborationPattern, *CollaborationPattern) {
+	t.Helper()
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	sie.SetExplorationPolicy(policy)
+	sie.random = func() float64 {
+		v := random[0]
+		random = random[1:]
+		return v
+	}
+
+	best := learnedPattern("api", []agents.AgentType{agents.ArchitectAgent, agents.DevelopmentAgent}, 0.8, time.Now())
+	best.UsageCount = 50
+	alt := learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.6, time.Now())
+	alt.UsageCount = 2
+	for _, p := range []*CollaborationPattern{best, alt} {
+		require.NoError(t, sie.RecordPattern(context.Background(), p))
+	}
+	return sie, best, alt
+}
+
+func TestSelectPattern_EpsilonGreedy(t *testing.T) {
+	ctx := context.Background()
+	policy := ExplorationPolicy{Strategy: ExplorationEpsilonGreedy, Epsilon: 0.2}
+
+	sie, best, _ := newExplorationEngine(t, policy, 0.5)
+	selection := sie.SelectPattern(ctx, "api")
+	require.NotNil(t, selection)
+	assert.False(t, selection.Explored)
+	assert.Same(t, best, selection.Pattern)
+
+	sie, best, alt := newExplorationEngine(t, policy, 0.1, 0.0)
+	selection = sie.SelectPattern(ctx, "api")
+	assert.True(t, selection.Explored)
+	assert.Same(t, alt, selection.Pattern)
+	assert.Same(t, best, selection.Best)
+
+	assert.Nil(t, sie.SelectPattern(ctx, "unknown"))
+}
+
+func TestSelectPattern_GreedyNeverExplores(t *testing.T) {
+	sie, best, _ := newExplorationEngine(t, ExplorationPolicy{Strategy: ExplorationGreedy, Epsilon: 1})
+	selection := sie.SelectPattern(context.Background(), "api")
+	assert.False(t, selection.Explored)
+	assert.Same(t, best, selection.Pattern)
+	assert.Same(t, best, sie.GetBestPattern(context.Background(), "api"))
+}
+
+func TestSelectPattern_UCBFavoursRarelyUsed(t *testing.T) {
+	ctx := context.Background()
+	sie, _, alt := newExplorationEngine(t, DefaultExplorationPolicy)
+	sie.SetExplorationPolicy(ExplorationPolicy{Strategy: ExplorationUCB, UCBConstant: 1})
+	selection := sie.SelectPattern(ctx, "api")
+	assert.True(t, selection.Explored)
+	assert.Same(t, alt, selection.Pattern)
+
+	sie.SetExplorationPolicy(ExplorationPolicy{Strategy: ExplorationUCB, UCBConstant: 0.01})
+	assert.False(t, sie.SelectPattern(ctx, "api").Explored, "a small bonus exploits")
+
+	fresh := learnedPattern("api", []agents.AgentType{agents.QualityAgent}, 0, time.Now())
+	fresh.UsageCount = 0
+	require.NoError(t, sie.RecordPattern(ctx, fresh))
+	assert.Same(t, fresh, sie.SelectPattern(ctx, "api").Pattern, "unused patterns are tried first")
+}
+
+func TestSelectPattern_ExploredPatternLearns(t *testing.T) {
+	ctx := context.Background()
+	sie, _, alt := newExplorationEngine(t, ExplorationPolicy{Strategy: ExplorationEpsilonGreedy, Epsilon: 1}, 0, 0)
+	selection := sie.SelectPattern(ctx, "api")
+	require.Same(t, alt, selection.Pattern)
+	before := alt.QValue
+
+	var tasks []*CollaborativeTask
+	for _, a := range selection.Pattern.AgentSequence {
+		tasks = append(tasks, &CollaborativeTask{Type: "api", AssignedAgent: a, Status: TaskStatusCompleted, ConfidenceScore: 9})
+	}
+	// The learning half of AnalyzeCollaboration, which needs Redis to persist
+	pattern := sie.extractPattern(tasks)
+	require.Same(t, alt, pattern, "the explored pattern is updated, not duplicated")
+	sie.updateQValue(pattern, sie.calculateReward(tasks), sie.getNextMaxQ(pattern))
+	assert.NotEqual(t, before, alt.QValue)
+	assert.Equal(t, int64(3), alt.UsageCount)
+}
+
+func TestParseExplorationStrategy(t *testing.T) {
+	s, err := ParseExplorationStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, ExplorationEpsilonGreedy, s)
+	s, err = ParseExplorationStrategy("UCB")
+	require.NoError(t, err)
+	assert.Equal(t, ExplorationUCB, s)
+	_, err = ParseExplorationStrategy("thompson")
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package collaboration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+// ErrPatternNotFound is returned when feedback targets a pattern that
+// hasn't been learned
+var ErrPatternNotFound = errors.New("pattern not found")
+
+// IsRewardFeedback reports whether feedback of type t shapes pattern rewards
+func IsRewardFeedback(t FeedbackType) bool {
+	switch t {
+	case FeedbackTypeThumbsUp, FeedbackTypeThumbsDown, FeedbackTypeRating, FeedbackTypeQualityGate:
+		return true
+	}
+	return false
+}
+
+// validateRewardFeedback checks the fields each reward feedback type needs
+func validateRewardFeedback(entry FeedbackEntry) error {
+	switch entry.Type {
+	case FeedbackTypeRating:
+		if entry.Rating < 1 || entry.Rating > 5 {
+			return fmt.Errorf("rating must be between 1 and 5")
+		}
+	case FeedbackTypeQualityGate:
+		if entry.Passed == nil {
+			return fmt.Errorf("passed is required for quality gate feedback")
+		}
+	}
+	return nil
+}
+
+// feedbackReward sums the reward contributed by user and quality-gate
+// feedback; agent feedback is already reflected in confidence scores
+func (w RewardWeights) feedbackReward(feedback []FeedbackEntry) float64 {
+	reward := 0.0
+	for _, f := range feedback {
+		switch f.Type {
+		case FeedbackTypeThumbsUp:
+			reward += w.ThumbsUpBonus
+		case FeedbackTypeThumbsDown:
+			reward += w.ThumbsDownPenalty
+		case FeedbackTypeRating:
+			if f.Rating >= 1 && f.Rating <= 5 {
+				reward += (f.Rating - 3) / 2 * w.RatingWeight
+			}
+		case FeedbackTypeQualityGate:
+			if f.Passed == nil {
+				continue
+			}
+			if *f.Passed {
+				reward += w.QualityGatePass
+			} else {
+				reward += w.QualityGateFail
+			}
+		}
+	}
+	return reward
+}
+
+// ApplyFeedback feeds feedback that arrives after a collaboration was
+// analyzed back into the pattern's Q-value. The pattern is found by ID, or
+// by task type and agent sequence when id is nil.
+func (sie *SelfImprovementEngine) ApplyFeedback(ctx context.Context, id uuid.UUID, taskType string, sequence []agents.AgentType, feedback []FeedbackEntry) (*CollaborationPattern, error) {
+	for _, f := range feedback {
+		if !IsRewardFeedback(f.Type) {
+			return nil, fmt.Errorf("feedback type %q doesn't shape rewards", f.Type)
+		}
+		if err := validateRewardFeedback(f); err != nil {
+			return nil, err
+		}
+	}
+
+	pattern, err := sie.findPattern(ctx, id, taskType, sequence)
+	if err != nil {
+		return nil, err
+	}
+
+	sie.mu.RLock()
+	w := sie.weights
+	sie.mu.RUnlock()
+	reward := w.feedbackReward(feedback)
+	nextMax := sie.getNextMaxQ(pattern)
+
+	sie.mu.Lock()
+	before := pattern.QValue
+	sie.updateQValue(pattern, reward, nextMax)
+	sie.mu.Unlock()
+
+	sie.logger.Info("Applied feedback to collaboration pattern",
+		zap.String("pattern_id", pattern.ID.String()),
+		zap.Int("entries", len(feedback)),
+		zap.Float64("reward", reward),
+		zap.Float64("q_before", before),
+		zap.Float64("q_after", pattern.QValue))
+
+	if err := sie.storePattern(ctx, pattern); err != nil {
+		return pattern, fmt.Errorf("failed to store pattern: %w", err)
+	}
+	return pattern, nil
+}
+
+// findPattern looks a pattern up by ID or key, caching patterns that are
+// only in Redis so later updates don't fork them
+func (sie *SelfImprovementEngine) findPattern(ctx context.Context, id uuid.UUID, taskType string, sequence []agents.AgentType) (*CollaborationPattern, error) {
+	key := sie.generatePatternKey(taskType, sequence)
+	matches := func(p *CollaborationPattern) bool {
+		if id != uuid.Nil {
+			return p.ID == id
+		}
+		return sie.generatePatternKey(p.TaskType, p.AgentSequence) == key
+	}
+
+	sie.mu.RLock()
+	for _, p := range sie.patterns {
+		if matches(p) {
+			sie.mu.RUnlock()
+			return p, nil
+		}
+	}
+	sie.mu.RUnlock()
+
+	byID, err := sie.loadPatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range byID {
+		if !matches(p) {
+			continue
+		}
+		sie.mu.Lock()
+		defer sie.mu.Unlock()
+		k := sie.generatePatternKey(p.TaskType, p.AgentSequence)
+		if cached, ok := sie.patterns[k]; ok {
+			return cached, nil
+		}
+		sie.patterns[k] = p
+		return p, nil
+	}
+	return nil, ErrPatternNotFound
+}
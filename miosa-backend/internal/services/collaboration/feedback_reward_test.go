@@ -0,0 +1,135 @@
+package collaboration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestFeedbackReward(t *testing.T) {
+	w := NewSelfImprovementEngine(nil, zap.NewNop()).weights
+	passed, failed := true, false
+
+	tests := []struct {
+		name     string
+		feedback []FeedbackEntry
+		want     float64
+	}{
+		{"agent feedback ignored", []FeedbackEntry{{Type: FeedbackTypeSuccess}, {Type: FeedbackTypeError}}, 0},
+		{"thumbs cancel out", []FeedbackEntry{{Type: FeedbackTypeThumbsUp}, {Type: FeedbackTypeThumbsDown}}, 0},
+		{"top rating", []FeedbackEntry{{Type: FeedbackTypeRating, Rating: 5}}, w.RatingWeight},
+		{"bottom rating", []FeedbackEntry{{Type: FeedbackTypeRating, Rating: 1}}, -w.RatingWeight},
+		{"neutral rating", []FeedbackEntry{{Type: FeedbackTypeRating, Rating: 3}}, 0},
+		{"gate pass", []FeedbackEntry{{Type: FeedbackTypeQualityGate, Passed: &passed}}, w.QualityGatePass},
+		{"gate fail", []FeedbackEntry{{Type: FeedbackTypeQualityGate, Passed: &failed}}, w.QualityGateFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, w.feedbackReward(tt.feedback), 1e-9)
+		})
+	}
+}
+
+func TestCalculateReward_IncludesUserFeedback(t *testing.T) {
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	task := func(feedback ...FeedbackEntry) []*CollaborativeTask {
+		return []*CollaborativeTask{{Status: TaskStatusCompleted, ConfidenceScore: 7, Feedback: feedback}}
+	}
+
+	base := sie.calculateReward(task())
+	assert.Greater(t, sie.calculateReward(task(FeedbackEntry{Type: FeedbackTypeThumbsUp})), base)
+	assert.Less(t, sie.calculateReward(task(FeedbackEntry{Type: FeedbackTypeThumbsDown})), base)
+
+	sie.weights.ThumbsUpBonus = 0
+	assert.Equal(t, base, sie.calculateReward(task(FeedbackEntry{Type: FeedbackTypeThumbsUp})), "weights are configurable")
+}
+
+func TestApplyFeedback(t *testing.T) {
+	ctx := context.Background()
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	sequence := []agents.AgentType{agents.ArchitectAgent, agents.DevelopmentAgent}
+	pattern := learnedPattern("api", sequence, 0.5, time.Now())
+	require.NoError(t, sie.RecordPattern(ctx, pattern))
+
+	got, err := sie.ApplyFeedback(ctx, uuid.Nil, "api", sequence, []FeedbackEntry{{Type: FeedbackTypeThumbsDown}})
+	require.NoError(t, err)
+	assert.Same(t, pattern, got)
+	assert.Less(t, pattern.QValue, 0.5)
+	lowered := pattern.QValue
+
+	_, err = sie.ApplyFeedback(ctx, pattern.ID, "", nil, []FeedbackEntry{{Type: FeedbackTypeRating, Rating: 5}})
+	require.NoError(t, err)
+	assert.Greater(t, pattern.QValue, lowered)
+
+	_, err = sie.ApplyFeedback(ctx, uuid.New(), "", nil, []FeedbackEntry{{Type: FeedbackTypeThumbsUp}})
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+	_, err = sie.ApplyFeedback(ctx, pattern.ID, "", nil, []FeedbackEntry{{Type: FeedbackTypeRating, Rating: 9}})
+	assert.Error(t, err)
+	_, err = sie.ApplyFeedback(ctx, pattern.ID, "", nil, []FeedbackEntry{{Type: FeedbackTypeQualityGate}})
+	assert.Error(t, err, "gate feedback needs passed")
+	_, err = sie.ApplyFeedback(ctx, pattern.ID, "", nil, []FeedbackEntry{{Type: FeedbackTypeHandoff}})
+	assert.Error(t, err)
+}
+
+func TestHandlers_CollaborationFeedbackShapesReward(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	m, _, _ := newTestCollaborations()
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	h := &Handlers{collaborations: m, improvement: sie, logger: zap.NewNop()}
+
+	collab, tasks, err := m.Create(ctx, uuid.Nil, "", "", []CollaborationTaskSpec{
+		{Key: "design", Type: "api", Agent: agents.ArchitectAgent, Input: "design"},
+		{Key: "build", Type: "api", Agent: agents.DevelopmentAgent, Input: "build", DependsOn: []string{"design"}},
+	})
+	require.NoError(t, err)
+	pattern := learnedPattern("api", []agents.AgentType{agents.ArchitectAgent, agents.DevelopmentAgent}, 0.5, time.Now())
+	require.NoError(t, sie.RecordPattern(ctx, pattern))
+
+	r := gin.New()
+	r.POST("/collaborations/:id/tasks/:task_id/feedback", h.SubmitFeedback)
+	r.POST("/patterns/feedback", h.SubmitPatternFeedback)
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, &buf))
+		return w
+	}
+
+	taskPath := "/collaborations/" + collab.ID.String() + "/tasks/" + tasks[1].ID.String() + "/feedback"
+	w := post(taskPath, FeedbackRequest{Type: FeedbackTypeThumbsUp})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Greater(t, pattern.QValue, 0.5)
+
+	w = post(taskPath, FeedbackRequest{Type: FeedbackTypeRating, Rating: 0})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	raised := pattern.QValue
+	failed := false
+	w = post("/patterns/feedback", PatternFeedbackRequest{
+		PatternID: pattern.ID,
+		Feedback:  []FeedbackRequest{{Type: FeedbackTypeQualityGate, Passed: &failed}},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Less(t, pattern.QValue, raised)
+
+	w = post("/patterns/feedback", PatternFeedbackRequest{
+		TaskType: "ui",
+		Agents:   []agents.AgentType{agents.DevelopmentAgent},
+		Feedback: []FeedbackRequest{{Type: FeedbackTypeThumbsUp}},
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
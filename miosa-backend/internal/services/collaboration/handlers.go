@@ -8,34 +8,59 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
 	"go.uber.org/zap"
 )
 
 // Handlers manages collaboration endpoints
 type Handlers struct {
-	taskQueue     *TaskQueue
-	improvement   *SelfImprovementEngine
-	orchestrator  *agents.Orchestrator
-	redisClient   *redis.Client
-	logger        *zap.Logger
+	taskQueue      *TaskQueue
+	improvement    *SelfImprovementEngine
+	collaborations *Collaborations
+	orchestrator   *agents.Orchestrator
+	redisClient    redis.UniversalClient
+	logger         *zap.Logger
+	audit          *audit.Log
+	drainer        *agents.Drainer
 }
 
 // NewHandlers creates new collaboration handlers
-func NewHandlers(orchestrator *agents.Orchestrator, redisClient *redis.Client, logger *zap.Logger) *Handlers {
+func NewHandlers(orchestrator *agents.Orchestrator, redisClient redis.UniversalClient, logger *zap.Logger) *Handlers {
 	taskQueue := NewTaskQueue(redisClient, logger)
 	improvement := NewSelfImprovementEngine(redisClient, logger)
+	collaborations := NewCollaborations(NewRedisCollaborationStore(redisClient), taskQueue, logger)
+	taskQueue.OnFinish(collaborations.TaskFinished)
 	
 	return &Handlers{
-		taskQueue:     taskQueue,
-		improvement:   improvement,
-		orchestrator:  orchestrator,
-		redisClient:   redisClient,
-		logger:        logger,
+		taskQueue:      taskQueue,
+		improvement:    improvement,
+		collaborations: collaborations,
+		orchestrator:   orchestrator,
+		redisClient:    redisClient,
+		logger:         logger,
 	}
 }
 
+// SetAuditLog records collaboration requests and improvement decisions to l
+func (h *Handlers) SetAuditLog(l *audit.Log) {
+	h.audit = l
+	h.improvement.SetAuditLog(l)
+}
+
+// SetExplorationPolicy configures how learned patterns are picked when a
+// request doesn't name its agents
+func (h *Handlers) SetExplorationPolicy(p ExplorationPolicy) {
+	h.improvement.SetExplorationPolicy(p)
+}
+
+// SetDrainer tracks collaborations so shutdown can drain them
+func (h *Handlers) SetDrainer(d *agents.Drainer) {
+	h.drainer = d
+}
+
 // ExecuteCollaborativeTask handles multi-agent collaboration requests
 func (h *Handlers) ExecuteCollaborativeTask(c *gin.Context) {
 	var req struct {
@@ -54,6 +79,43 @@ func (h *Handlers) ExecuteCollaborativeTask(c *gin.Context) {
 	}
 	
 	ctx := context.Background()
+	var taskContext *agents.TaskContext
+	if v, exists := c.Get("task_context"); exists {
+		taskContext, _ = v.(*agents.TaskContext)
+	}
+
+	if h.drainer != nil {
+		finish, err := h.drainer.Begin(agents.Task{
+			ID:         uuid.New(),
+			Type:       req.Type,
+			Input:      req.Task,
+			Context:    taskContext,
+			Priority:   req.Priority,
+			Parameters: map[string]interface{}{"agents": req.Agents, "parallel": req.Parallel},
+		}, "collaboration/execute")
+		if err != nil {
+			c.Header("Retry-After", "30")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer finish()
+	}
+	h.audit.Record(ctx, audit.FromTaskContext(audit.Event{
+		Action:      audit.ActionOrchestrate,
+		Resource:    c.Request.URL.Path,
+		RequestHash: audit.HashRequest(req),
+	}, taskContext))
+
+	// Without explicit agents, pick a learned sequence for the task type
+	var selection *PatternSelection
+	if len(req.Agents) == 0 && req.Type != "" {
+		if selection = h.improvement.SelectPattern(ctx, req.Type); selection != nil {
+			for _, a := range selection.Pattern.AgentSequence {
+				req.Agents = append(req.Agents, string(a))
+			}
+		}
+	}
+
 	tasks := h.createCollaborativeTasks(req.Task, req.Type, req.Priority, req.Context, req.Agents)
 	
 	// Execute tasks
@@ -64,20 +126,26 @@ func (h *Handlers) ExecuteCollaborativeTask(c *gin.Context) {
 		results = h.executeSequential(ctx, tasks)
 	}
 	
-	// Learn from collaboration if requested
-	if req.LearnFrom {
+	// Learn from collaboration if requested; explored patterns always
+	// learn so their Q-value reflects the attempt
+	if req.LearnFrom || (selection != nil && selection.Explored) {
 		go h.improvement.AnalyzeCollaboration(ctx, tasks)
 	}
 	
 	// Aggregate results
 	finalResult := h.aggregateResults(results)
 	
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success":    finalResult.Success,
 		"output":     finalResult.Output,
 		"confidence": finalResult.Confidence,
 		"agents_used": len(req.Agents),
-	})
+	}
+	if selection != nil {
+		response["pattern_id"] = selection.Pattern.ID
+		response["explored"] = selection.Explored
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *Handlers) createCollaborativeTasks(task, taskType string, priority int, context map[string]interface{}, agentNames []string) []*CollaborativeTask {
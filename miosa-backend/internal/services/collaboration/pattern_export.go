@@ -0,0 +1,231 @@
+package collaboration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// PatternExportVersion is the current pattern file format version
+const PatternExportVersion = 1
+
+// ErrUnsupportedPatternVersion is returned when importing a file written by
+// a newer (or unknown) format version
+var ErrUnsupportedPatternVersion = errors.New("unsupported pattern file version")
+
+// PatternExport is the file format for backing up, transferring and seeding
+// learned collaboration patterns
+type PatternExport struct {
+	Version    int                     `json:"version"`
+	ExportedAt time.Time               `json:"exported_at"`
+	Source     string                  `json:"source,omitempty"` // e.g. "staging"
+	Weights    *RewardWeights          `json:"weights,omitempty"`
+	Patterns   []*CollaborationPattern `json:"patterns"`
+}
+
+// ImportMode decides what happens when an imported pattern already exists
+// for the same task type and agent sequence
+type ImportMode string
+
+const (
+	// ImportMerge keeps whichever copy was updated most recently
+	ImportMerge ImportMode = "merge"
+	// ImportOverwrite always takes the imported copy
+	ImportOverwrite ImportMode = "overwrite"
+	// ImportSeed only adds patterns that don't exist yet
+	ImportSeed ImportMode = "seed"
+)
+
+// ParseImportMode validates a mode, defaulting to merge
+func ParseImportMode(s string) (ImportMode, error) {
+	switch m := ImportMode(strings.ToLower(s)); m {
+	case "":
+		return ImportMerge, nil
+	case ImportMerge, ImportOverwrite, ImportSeed:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown import mode %q", s)
+	}
+}
+
+// ImportReport summarizes an import
+type ImportReport struct {
+	Imported       int      `json:"imported"`
+	Skipped        int      `json:"skipped"`
+	Invalid        []string `json:"invalid,omitempty"`
+	WeightsUpdated bool     `json:"weights_updated"`
+}
+
+// ExportPatterns snapshots every learned pattern, including those only in
+// the pattern store, with the current reward weights
+func (sie *SelfImprovementEngine) ExportPatterns(ctx context.Context, source string) (*PatternExport, error) {
+	byID, err := sie.loadPatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sie.mu.RLock()
+	weights := sie.weights
+	sie.mu.RUnlock()
+
+	export := &PatternExport{
+		Version:    PatternExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Source:     source,
+		Weights:    &weights,
+		Patterns:   make([]*CollaborationPattern, 0, len(byID)),
+	}
+	for _, p := range byID {
+		export.Patterns = append(export.Patterns, p)
+	}
+	sort.Slice(export.Patterns, func(i, j int) bool {
+		a, b := export.Patterns[i], export.Patterns[j]
+		if a.TaskType != b.TaskType {
+			return a.TaskType < b.TaskType
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return export, nil
+}
+
+// loadPatterns returns the stored and in-memory patterns by ID; the
+// in-memory copy wins as it may not have been stored yet
+func (sie *SelfImprovementEngine) loadPatterns(ctx context.Context) (map[uuid.UUID]*CollaborationPattern, error) {
+	stored, err := sie.store.ListPatterns(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*CollaborationPattern, len(stored))
+	for _, p := range stored {
+		byID[p.ID] = p
+	}
+
+	sie.mu.RLock()
+	defer sie.mu.RUnlock()
+	for _, p := range sie.patterns {
+		copied := *p
+		byID[p.ID] = &copied
+	}
+	return byID, nil
+}
+
+// ImportPatterns adds the exported patterns to the engine and store.
+// Invalid patterns are reported and skipped rather than failing the import.
+// Reward weights are replaced only when includeWeights is set.
+func (sie *SelfImprovementEngine) ImportPatterns(ctx context.Context, export *PatternExport, mode ImportMode, includeWeights bool) (*ImportReport, error) {
+	if export.Version < 1 || export.Version > PatternExportVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedPatternVersion, export.Version)
+	}
+
+	known, err := sie.loadPatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*CollaborationPattern, len(known))
+	for _, p := range known {
+		byKey[sie.generatePatternKey(p.TaskType, p.AgentSequence)] = p
+	}
+
+	report := &ImportReport{}
+	sie.mu.Lock()
+	var toStore []*CollaborationPattern
+	for i, p := range export.Patterns {
+		if err := validatePattern(p); err != nil {
+			report.Invalid = append(report.Invalid, fmt.Sprintf("pattern %d: %v", i, err))
+			continue
+		}
+		key := sie.generatePatternKey(p.TaskType, p.AgentSequence)
+		existing, exists := byKey[key]
+		switch {
+		case !exists:
+		case mode == ImportSeed:
+			report.Skipped++
+			continue
+		case mode == ImportMerge && !p.LastUpdated.After(existing.LastUpdated):
+			report.Skipped++
+			continue
+		}
+
+		imported := *p
+		if exists {
+			// Keep one pattern per key so Redis doesn't hold stale copies
+			imported.ID = existing.ID
+		} else if imported.ID == uuid.Nil {
+			imported.ID = uuid.New()
+		}
+		if imported.LastUpdated.IsZero() {
+			imported.LastUpdated = time.Now()
+		}
+		sie.patterns[key] = &imported
+		byKey[key] = &imported
+		toStore = append(toStore, &imported)
+		report.Imported++
+	}
+	if includeWeights && export.Weights != nil {
+		sie.weights = *export.Weights
+		sie.weightsLastLoaded = time.Now()
+		report.WeightsUpdated = true
+	}
+	sie.mu.Unlock()
+
+	for _, p := range toStore {
+		if err := sie.storePattern(ctx, p); err != nil {
+			return report, fmt.Errorf("failed to store pattern %s: %w", p.ID, err)
+		}
+	}
+	if report.WeightsUpdated && sie.redisClient != nil {
+		data, _ := json.Marshal(export.Weights)
+		if err := sie.redisClient.Set(ctx, "self_improvement:weights", data, 0).Err(); err != nil {
+			return report, fmt.Errorf("failed to store weights: %w", err)
+		}
+	}
+
+	sie.logger.Info("Imported collaboration patterns",
+		zap.String("source", export.Source),
+		zap.String("mode", string(mode)),
+		zap.Int("imported", report.Imported),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("invalid", len(report.Invalid)))
+	return report, nil
+}
+
+// SeedPatterns imports curated defaults from a pattern file without
+// touching patterns that were already learned
+func (sie *SelfImprovementEngine) SeedPatterns(ctx context.Context, path string) (*ImportReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var export PatternExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return sie.ImportPatterns(ctx, &export, ImportSeed, false)
+}
+
+func validatePattern(p *CollaborationPattern) error {
+	if p == nil {
+		return fmt.Errorf("empty pattern")
+	}
+	if p.TaskType == "" {
+		return fmt.Errorf("task_type is required")
+	}
+	if len(p.AgentSequence) == 0 {
+		return fmt.Errorf("agent_sequence is required")
+	}
+	if p.SuccessRate < 0 || p.SuccessRate > 1 {
+		return fmt.Errorf("success_rate must be between 0 and 1")
+	}
+	if math.IsNaN(p.QValue) || math.IsInf(p.QValue, 0) {
+		return fmt.Errorf("q_value must be finite")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package collaboration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func learnedPattern(taskType string, sequence []agents.AgentType, q float64, updated time.Time) *CollaborationPattern {
+	return &CollaborationPattern{
+		ID:            uuid.New(),
+		TaskType:      taskType,
+		AgentSequence: sequence,
+		SuccessRate:   0.8,
+		QValue:        q,
+		UsageCount:    5,
+		LastUpdated:   updated,
+	}
+}
+
+func TestPatternExport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	staging := NewSelfImprovementEngine(nil, zap.NewNop())
+	_, err := staging.ImportPatterns(ctx, &PatternExport{
+		Version: PatternExportVersion,
+		Patterns: []*CollaborationPattern{
+			learnedPattern("api", []agents.AgentType{agents.ArchitectAgent, agents.DevelopmentAgent}, 0.7, time.Now()),
+			learnedPattern("ui", []agents.AgentType{agents.DevelopmentAgent}, 0.4, time.Now()),
+		},
+	}, ImportOverwrite, false)
+	require.NoError(t, err)
+
+	export, err := staging.ExportPatterns(ctx, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", export.Source)
+	require.Len(t, export.Patterns, 2)
+	require.NotNil(t, export.Weights)
+
+	data, err := json.Marshal(export)
+	require.NoError(t, err)
+	var decoded PatternExport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	prod := NewSelfImprovementEngine(nil, zap.NewNop())
+	decoded.Weights.SuccessBonus = 0.9
+	report, err := prod.ImportPatterns(ctx, &decoded, ImportMerge, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Imported)
+	assert.True(t, report.WeightsUpdated)
+	assert.Equal(t, 0.9, prod.weights.SuccessBonus)
+
+	again, err := prod.ExportPatterns(ctx, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, export.Patterns[0].QValue, again.Patterns[0].QValue)
+}
+
+func TestImportPatterns_Modes(t *testing.T) {
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+	sequence := []agents.AgentType{agents.AnalysisAgent, agents.QualityAgent}
+
+	tests := []struct {
+		name     string
+		mode     ImportMode
+		updated  time.Time
+		imported int
+		wantQ    float64
+	}{
+		{"merge keeps newer existing", ImportMerge, old.Add(-time.Hour), 0, 0.5},
+		{"merge takes newer import", ImportMerge, time.Now(), 1, 0.9},
+		{"overwrite takes older import", ImportOverwrite, old.Add(-time.Hour), 1, 0.9},
+		{"seed never replaces", ImportSeed, time.Now(), 0, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sie := NewSelfImprovementEngine(nil, zap.NewNop())
+			existing := learnedPattern("review", sequence, 0.5, old)
+			sie.patterns[sie.generatePatternKey("review", sequence)] = existing
+
+			report, err := sie.ImportPatterns(ctx, &PatternExport{
+				Version:  PatternExportVersion,
+				Patterns: []*CollaborationPattern{learnedPattern("review", sequence, 0.9, tt.updated)},
+			}, tt.mode, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.imported, report.Imported)
+			assert.Equal(t, 1-tt.imported, report.Skipped)
+
+			got := sie.patterns[sie.generatePatternKey("review", sequence)]
+			assert.Equal(t, tt.wantQ, got.QValue)
+			assert.Equal(t, existing.ID, got.ID, "one pattern per key")
+		})
+	}
+}
+
+func TestImportPatterns_RejectsInvalid(t *testing.T) {
+	ctx := context.Background()
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+
+	_, err := sie.ImportPatterns(ctx, &PatternExport{Version: PatternExportVersion + 1}, ImportMerge, false)
+	assert.ErrorIs(t, err, ErrUnsupportedPatternVersion)
+
+	bad := learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.5, time.Now())
+	bad.SuccessRate = 1.5
+	report, err := sie.ImportPatterns(ctx, &PatternExport{
+		Version: PatternExportVersion,
+		Patterns: []*CollaborationPattern{
+			bad,
+			{TaskType: "api"},
+			learnedPattern("api", []agents.AgentType{agents.ArchitectAgent}, 0.5, time.Now()),
+		},
+	}, ImportMerge, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Imported)
+	assert.Len(t, report.Invalid, 2)
+
+	_, err = ParseImportMode("replace")
+	assert.Error(t, err)
+}
+
+func TestSeedPatterns_KeepsLearnedPatterns(t *testing.T) {
+	ctx := context.Background()
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	learned := learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.3, time.Now().Add(-time.Hour))
+	sie.patterns[sie.generatePatternKey("api", learned.AgentSequence)] = learned
+
+	data, err := json.Marshal(PatternExport{
+		Version: PatternExportVersion,
+		Weights: &RewardWeights{SuccessBonus: 1},
+		Patterns: []*CollaborationPattern{
+			learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.9, time.Now()),
+			learnedPattern("docs", []agents.AgentType{agents.QualityAgent}, 0.6, time.Now()),
+		},
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "defaults.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	report, err := sie.SeedPatterns(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Imported)
+	assert.False(t, report.WeightsUpdated, "seeding never replaces weights")
+	assert.Equal(t, 0.3, sie.patterns[sie.generatePatternKey("api", learned.AgentSequence)].QValue)
+}
+
+func TestHandlers_PatternImportExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handlers{improvement: NewSelfImprovementEngine(nil, zap.NewNop()), logger: zap.NewNop()}
+	r := gin.New()
+	r.GET("/patterns/export", h.ExportPatterns)
+	r.POST("/patterns/import", h.ImportPatterns)
+
+	body, err := json.Marshal(PatternExport{
+		Version:  PatternExportVersion,
+		Patterns: []*CollaborationPattern{learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.5, time.Now())},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/patterns/import?mode=bogus", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/patterns/import?mode=seed", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report ImportReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Imported)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/patterns/export?source=staging", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	var export PatternExport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	assert.Equal(t, "staging", export.Source)
+	assert.Len(t, export.Patterns, 1)
+}
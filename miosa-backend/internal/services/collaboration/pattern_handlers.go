@@ -0,0 +1,168 @@
+package collaboration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"go.uber.org/zap"
+)
+
+// SetPatternStore makes store the source of truth for learned patterns and
+// loads the patterns it already holds
+func (h *Handlers) SetPatternStore(ctx context.Context, store PatternStore) error {
+	h.improvement.SetPatternStore(store)
+	loaded, err := h.improvement.WarmPatterns(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load patterns: %w", err)
+	}
+	h.logger.Info("Loaded collaboration patterns", zap.Int("patterns", loaded))
+	return nil
+}
+
+// StartPatternPruning removes stale patterns every interval until ctx is done
+func (h *Handlers) StartPatternPruning(ctx context.Context, interval time.Duration) {
+	go h.improvement.RunPruner(ctx, interval)
+}
+
+// SeedPatterns loads curated default patterns from path, keeping any
+// patterns already learned
+func (h *Handlers) SeedPatterns(ctx context.Context, path string) error {
+	report, err := h.improvement.SeedPatterns(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to seed patterns: %w", err)
+	}
+	h.logger.Info("Seeded collaboration patterns",
+		zap.String("path", path),
+		zap.Int("imported", report.Imported),
+		zap.Int("skipped", report.Skipped))
+	return nil
+}
+
+// ExportPatterns handles GET /api/collaboration/patterns/export
+func (h *Handlers) ExportPatterns(c *gin.Context) {
+	export, err := h.improvement.ExportPatterns(c.Request.Context(), c.Query("source"))
+	if err != nil {
+		h.logger.Error("Failed to export patterns", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export patterns"})
+		return
+	}
+
+	filename := fmt.Sprintf("collaboration-patterns-%s.json", export.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, export)
+}
+
+// ImportPatterns handles POST /api/collaboration/patterns/import. The mode
+// query parameter is merge (default), overwrite or seed; weights=true also
+// replaces the reward weights.
+func (h *Handlers) ImportPatterns(c *gin.Context) {
+	mode, err := ParseImportMode(c.Query("mode"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	includeWeights := false
+	if v := c.Query("weights"); v != "" {
+		if includeWeights, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weights"})
+			return
+		}
+	}
+
+	var export PatternExport
+	if err := c.ShouldBindJSON(&export); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, _, taskContext := requestTenant(c)
+	event := audit.Event{
+		Action:      audit.ActionPatternImport,
+		Resource:    c.Request.URL.Path,
+		RequestHash: audit.HashRequest(export),
+		Metadata: map[string]string{
+			"source":   export.Source,
+			"mode":     string(mode),
+			"patterns": strconv.Itoa(len(export.Patterns)),
+			"weights":  strconv.FormatBool(includeWeights),
+		},
+	}
+
+	report, err := h.improvement.ImportPatterns(c.Request.Context(), &export, mode, includeWeights)
+	if err != nil {
+		event.Status = audit.StatusFailure
+		event.Metadata["error"] = err.Error()
+		h.audit.Record(c.Request.Context(), audit.FromTaskContext(event, taskContext))
+
+		if errors.Is(err, ErrUnsupportedPatternVersion) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to import patterns", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import patterns", "report": report})
+		return
+	}
+
+	event.Status = audit.StatusSuccess
+	event.Metadata["imported"] = strconv.Itoa(report.Imported)
+	h.audit.Record(c.Request.Context(), audit.FromTaskContext(event, taskContext))
+	c.JSON(http.StatusOK, report)
+}
+
+// PatternFeedbackRequest is the body of POST /api/collaboration/patterns/feedback.
+// The pattern is identified by pattern_id, or by task_type and agents.
+type PatternFeedbackRequest struct {
+	PatternID uuid.UUID          `json:"pattern_id"`
+	TaskType  string             `json:"task_type"`
+	Agents    []agents.AgentType `json:"agents"`
+	Feedback  []FeedbackRequest  `json:"feedback" binding:"required,min=1,dive"`
+}
+
+// SubmitPatternFeedback handles POST /api/collaboration/patterns/feedback,
+// ingesting thumbs, ratings and quality-gate results into pattern rewards
+func (h *Handlers) SubmitPatternFeedback(c *gin.Context) {
+	var req PatternFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.PatternID == uuid.Nil && (req.TaskType == "" || len(req.Agents) == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pattern_id or task_type and agents are required"})
+		return
+	}
+
+	_, actor, _ := requestTenant(c)
+	entries := make([]FeedbackEntry, 0, len(req.Feedback))
+	for _, f := range req.Feedback {
+		entries = append(entries, FeedbackEntry{
+			AgentType: HumanFeedback,
+			Timestamp: time.Now(),
+			Type:      f.Type,
+			Message:   f.Message,
+			Rating:    f.Rating,
+			Passed:    f.Passed,
+			Metadata:  map[string]interface{}{"submitted_by": actor},
+		})
+	}
+
+	pattern, err := h.improvement.ApplyFeedback(c.Request.Context(), req.PatternID, req.TaskType, req.Agents, entries)
+	switch {
+	case errors.Is(err, ErrPatternNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pattern not found"})
+	case err != nil && pattern == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.logger.Error("Failed to store pattern feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store pattern feedback"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"pattern": pattern})
+	}
+}
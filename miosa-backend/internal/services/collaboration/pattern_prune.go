@@ -0,0 +1,93 @@
+package collaboration
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WarmPatterns loads stored patterns into memory so patterns learned before
+// a restart keep their IDs and history instead of being relearned
+func (sie *SelfImprovementEngine) WarmPatterns(ctx context.Context) (int, error) {
+	stored, err := sie.store.ListPatterns(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	sie.mu.Lock()
+	defer sie.mu.Unlock()
+	loaded := 0
+	for _, p := range stored {
+		key := sie.generatePatternKey(p.TaskType, p.AgentSequence)
+		if _, ok := sie.patterns[key]; ok {
+			continue
+		}
+		sie.patterns[key] = p
+		loaded++
+	}
+	return loaded, nil
+}
+
+// decayedConfidence discounts a pattern's confidence by confidenceDecay for
+// every day since it was last updated
+func (sie *SelfImprovementEngine) decayedConfidence(p *CollaborationPattern, now time.Time) float64 {
+	days := now.Sub(p.LastUpdated).Hours() / 24
+	if days <= 0 {
+		return p.ConfidenceScore
+	}
+	return p.ConfidenceScore * math.Pow(sie.confidenceDecay, days)
+}
+
+// PrunePatterns removes patterns whose decayed confidence has fallen below
+// pruneConfidenceMin, from memory and the store. It returns how many were
+// removed.
+func (sie *SelfImprovementEngine) PrunePatterns(ctx context.Context) (int, error) {
+	all, err := sie.loadPatterns(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	pruned := 0
+	for _, p := range all {
+		if sie.decayedConfidence(p, now) >= sie.pruneConfidenceMin {
+			continue
+		}
+		if err := sie.store.DeletePattern(ctx, p.ID); err != nil {
+			return pruned, err
+		}
+		sie.mu.Lock()
+		key := sie.generatePatternKey(p.TaskType, p.AgentSequence)
+		if cur, ok := sie.patterns[key]; ok && cur.ID == p.ID {
+			delete(sie.patterns, key)
+		}
+		sie.mu.Unlock()
+		pruned++
+		sie.logger.Debug("Pruned stale collaboration pattern",
+			zap.String("pattern_id", p.ID.String()),
+			zap.String("task_type", p.TaskType),
+			zap.Time("last_updated", p.LastUpdated))
+	}
+	if pruned > 0 {
+		sie.logger.Info("Pruned stale collaboration patterns", zap.Int("pruned", pruned))
+	}
+	return pruned, nil
+}
+
+// RunPruner prunes stale patterns every interval until ctx is done
+func (sie *SelfImprovementEngine) RunPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := sie.PrunePatterns(ctx); err != nil {
+				sie.logger.Warn("Failed to prune patterns", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package collaboration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/redisconn"
+)
+
+// PatternStore persists learned patterns and the improvement suggestions
+// made for them
+type PatternStore interface {
+	SavePattern(ctx context.Context, p *CollaborationPattern) error
+	// GetPattern returns ErrPatternNotFound for unknown IDs
+	GetPattern(ctx context.Context, id uuid.UUID) (*CollaborationPattern, error)
+	// ListPatterns returns the patterns for taskType, or every pattern when
+	// taskType is empty
+	ListPatterns(ctx context.Context, taskType string) ([]*CollaborationPattern, error)
+	DeletePattern(ctx context.Context, id uuid.UUID) error
+	// SaveSuggestion upserts the suggestion and its results, if any
+	SaveSuggestion(ctx context.Context, s *ImprovementSuggestion) error
+}
+
+// DefaultPatternCacheTTL bounds how long Redis caches a pattern
+const DefaultPatternCacheTTL = time.Hour
+
+// MemoryPatternStore keeps patterns in memory, for tests and single
+// instances without Redis or Postgres
+type MemoryPatternStore struct {
+	patterns    map[uuid.UUID]*CollaborationPattern
+	suggestions map[uuid.UUID]*ImprovementSuggestion
+	mu          sync.RWMutex
+}
+
+// NewMemoryPatternStore creates an empty in-memory store
+func NewMemoryPatternStore() *MemoryPatternStore {
+	return &MemoryPatternStore{
+		patterns:    make(map[uuid.UUID]*CollaborationPattern),
+		suggestions: make(map[uuid.UUID]*ImprovementSuggestion),
+	}
+}
+
+// SavePattern stores a copy of p
+func (s *MemoryPatternStore) SavePattern(ctx context.Context, p *CollaborationPattern) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *p
+	s.patterns[p.ID] = &copied
+	return nil
+}
+
+// GetPattern returns a copy of the pattern with id
+func (s *MemoryPatternStore) GetPattern(ctx context.Context, id uuid.UUID) (*CollaborationPattern, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.patterns[id]
+	if !ok {
+		return nil, ErrPatternNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+// ListPatterns returns copies of the patterns for taskType
+func (s *MemoryPatternStore) ListPatterns(ctx context.Context, taskType string) ([]*CollaborationPattern, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*CollaborationPattern
+	for _, p := range s.patterns {
+		if taskType == "" || p.TaskType == taskType {
+			copied := *p
+			out = append(out, &copied)
+		}
+	}
+	sortPatterns(out)
+	return out, nil
+}
+
+// DeletePattern removes the pattern and its suggestions
+func (s *MemoryPatternStore) DeletePattern(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.patterns, id)
+	for sid, sg := range s.suggestions {
+		if sg.PatternID == id {
+			delete(s.suggestions, sid)
+		}
+	}
+	return nil
+}
+
+// SaveSuggestion stores a copy of sg
+func (s *MemoryPatternStore) SaveSuggestion(ctx context.Context, sg *ImprovementSuggestion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *sg
+	s.suggestions[sg.ID] = &copied
+	return nil
+}
+
+// Suggestions returns the stored suggestions for patternID
+func (s *MemoryPatternStore) Suggestions(patternID uuid.UUID) []*ImprovementSuggestion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*ImprovementSuggestion
+	for _, sg := range s.suggestions {
+		if sg.PatternID == patternID {
+			out = append(out, sg)
+		}
+	}
+	return out
+}
+
+// RedisPatternStore keeps patterns under pattern:<id> with no expiry. It is
+// the store for deployments without Postgres; pruning keeps it bounded.
+type RedisPatternStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisPatternStore creates a Redis-only store
+func NewRedisPatternStore(client redis.UniversalClient) *RedisPatternStore {
+	return &RedisPatternStore{client: client}
+}
+
+// SavePattern writes p under pattern:<id>
+func (s *RedisPatternStore) SavePattern(ctx context.Context, p *CollaborationPattern) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode pattern: %w", err)
+	}
+	if err := s.client.Set(ctx, patternCacheKey(p.ID), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save pattern: %w", err)
+	}
+	return nil
+}
+
+// GetPattern reads pattern:<id>
+func (s *RedisPatternStore) GetPattern(ctx context.Context, id uuid.UUID) (*CollaborationPattern, error) {
+	data, err := s.client.Get(ctx, patternCacheKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrPatternNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pattern: %w", err)
+	}
+	var p CollaborationPattern
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode pattern: %w", err)
+	}
+	return &p, nil
+}
+
+// ListPatterns scans every pattern:* key, on every master in cluster mode
+func (s *RedisPatternStore) ListPatterns(ctx context.Context, taskType string) ([]*CollaborationPattern, error) {
+	var out []*CollaborationPattern
+	err := redisconn.Scan(ctx, s.client, "pattern:*", func(key string) {
+		data, err := s.client.Get(ctx, key).Bytes()
+		if err != nil {
+			return
+		}
+		var p CollaborationPattern
+		if json.Unmarshal(data, &p) != nil || p.ID == uuid.Nil {
+			return
+		}
+		if taskType == "" || p.TaskType == taskType {
+			out = append(out, &p)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan patterns: %w", err)
+	}
+	sortPatterns(out)
+	return out, nil
+}
+
+// DeletePattern removes pattern:<id>
+func (s *RedisPatternStore) DeletePattern(ctx context.Context, id uuid.UUID) error {
+	if err := s.client.Del(ctx, patternCacheKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete pattern: %w", err)
+	}
+	return nil
+}
+
+// SaveSuggestion writes improvement:<id>, kept for a week
+func (s *RedisPatternStore) SaveSuggestion(ctx context.Context, sg *ImprovementSuggestion) error {
+	data, err := json.Marshal(sg)
+	if err != nil {
+		return fmt.Errorf("failed to encode suggestion: %w", err)
+	}
+	if err := s.client.Set(ctx, fmt.Sprintf("improvement:%s", sg.ID), data, 7*24*time.Hour).Err(); err != nil {
+		return fmt.Errorf("failed to save suggestion: %w", err)
+	}
+	return nil
+}
+
+// PostgresPatternStore keeps patterns in collaboration_patterns and
+// suggestions in improvement_suggestions and improvement_results
+type PostgresPatternStore struct {
+	db *sql.DB
+}
+
+// NewPostgresPatternStore creates a Postgres-backed store
+func NewPostgresPatternStore(db *sql.DB) *PostgresPatternStore {
+	return &PostgresPatternStore{db: db}
+}
+
+// SavePattern upserts p. The key is unique, so a pattern recreated with a
+// new ID updates the existing row, keeping its ID, rather than duplicating it.
+func (s *PostgresPatternStore) SavePattern(ctx context.Context, p *CollaborationPattern) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode pattern: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO collaboration_patterns (id, pattern_key, task_type, q_value, confidence_score, usage_count, pattern, last_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (pattern_key) DO UPDATE
+		SET q_value = EXCLUDED.q_value, confidence_score = EXCLUDED.confidence_score,
+			usage_count = EXCLUDED.usage_count, last_updated = EXCLUDED.last_updated,
+			pattern = jsonb_set(EXCLUDED.pattern, '{id}', to_jsonb(collaboration_patterns.id))`,
+		p.ID, patternKey(p), p.TaskType, p.QValue, p.ConfidenceScore, p.UsageCount, data, p.LastUpdated,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pattern: %w", err)
+	}
+	return nil
+}
+
+// GetPattern loads the pattern with id
+func (s *PostgresPatternStore) GetPattern(ctx context.Context, id uuid.UUID) (*CollaborationPattern, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT pattern FROM collaboration_patterns WHERE id = $1`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPatternNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pattern: %w", err)
+	}
+	var p CollaborationPattern
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode pattern: %w", err)
+	}
+	return &p, nil
+}
+
+// ListPatterns returns the patterns for taskType, best first
+func (s *PostgresPatternStore) ListPatterns(ctx context.Context, taskType string) ([]*CollaborationPattern, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pattern FROM collaboration_patterns
+		WHERE $1 = '' OR task_type = $1
+		ORDER BY task_type, q_value DESC, id`, taskType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list patterns: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*CollaborationPattern
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var p CollaborationPattern
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("failed to decode pattern: %w", err)
+		}
+		out = append(out, &p)
+	}
+	return out, rows.Err()
+}
+
+// DeletePattern removes the pattern; its suggestions and results cascade
+func (s *PostgresPatternStore) DeletePattern(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM collaboration_patterns WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete pattern: %w", err)
+	}
+	return nil
+}
+
+// SaveSuggestion upserts the suggestion and, once applied, its results
+func (s *PostgresPatternStore) SaveSuggestion(ctx context.Context, sg *ImprovementSuggestion) error {
+	data, err := json.Marshal(sg)
+	if err != nil {
+		return fmt.Errorf("failed to encode suggestion: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO improvement_suggestions (id, pattern_id, type, status, expected_impact, confidence, suggestion, created_at, applied_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE
+		SET status = EXCLUDED.status, suggestion = EXCLUDED.suggestion, applied_at = EXCLUDED.applied_at`,
+		sg.ID, sg.PatternID, string(sg.Type), string(sg.Status), sg.ExpectedImpact, sg.Confidence, data, sg.CreatedAt, sg.AppliedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save suggestion: %w", err)
+	}
+
+	if sg.Results != nil {
+		results, err := json.Marshal(sg.Results)
+		if err != nil {
+			return fmt.Errorf("failed to encode results: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO improvement_results (suggestion_id, improvement_rate, validated, results, validated_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (suggestion_id) DO UPDATE
+			SET improvement_rate = EXCLUDED.improvement_rate, validated = EXCLUDED.validated,
+				results = EXCLUDED.results, validated_at = EXCLUDED.validated_at`,
+			sg.ID, sg.Results.ImprovementRate, sg.Results.Validated, results, sg.Results.ValidatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save results: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit suggestion: %w", err)
+	}
+	return nil
+}
+
+// CachedPatternStore reads patterns through a Redis cache in front of the
+// source of truth. Cached entries expire after ttl so Redis stays bounded.
+type CachedPatternStore struct {
+	backing PatternStore
+	client  redis.UniversalClient
+	ttl     time.Duration
+}
+
+// NewCachedPatternStore caches backing's patterns in client for ttl
+func NewCachedPatternStore(backing PatternStore, client redis.UniversalClient, ttl time.Duration) *CachedPatternStore {
+	if ttl <= 0 {
+		ttl = DefaultPatternCacheTTL
+	}
+	return &CachedPatternStore{backing: backing, client: client, ttl: ttl}
+}
+
+// SavePattern writes through to the backing store and refreshes the cache
+func (s *CachedPatternStore) SavePattern(ctx context.Context, p *CollaborationPattern) error {
+	if err := s.backing.SavePattern(ctx, p); err != nil {
+		return err
+	}
+	s.cache(ctx, p)
+	redisconn.Del(ctx, s.client, patternListKey(p.TaskType), patternListKey(""))
+	return nil
+}
+
+// GetPattern serves from the cache, loading and caching misses
+func (s *CachedPatternStore) GetPattern(ctx context.Context, id uuid.UUID) (*CollaborationPattern, error) {
+	if data, err := s.client.Get(ctx, patternCacheKey(id)).Bytes(); err == nil {
+		var p CollaborationPattern
+		if json.Unmarshal(data, &p) == nil {
+			return &p, nil
+		}
+	}
+	p, err := s.backing.GetPattern(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.cache(ctx, p)
+	return p, nil
+}
+
+// ListPatterns serves the cached list for taskType, loading misses
+func (s *CachedPatternStore) ListPatterns(ctx context.Context, taskType string) ([]*CollaborationPattern, error) {
+	key := patternListKey(taskType)
+	if data, err := s.client.Get(ctx, key).Bytes(); err == nil {
+		var out []*CollaborationPattern
+		if json.Unmarshal(data, &out) == nil {
+			return out, nil
+		}
+	}
+	out, err := s.backing.ListPatterns(ctx, taskType)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(out); err == nil {
+		s.client.Set(ctx, key, data, s.ttl)
+	}
+	return out, nil
+}
+
+// DeletePattern deletes from the backing store and evicts cached copies
+func (s *CachedPatternStore) DeletePattern(ctx context.Context, id uuid.UUID) error {
+	p, err := s.GetPattern(ctx, id)
+	if errors.Is(err, ErrPatternNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.backing.DeletePattern(ctx, id); err != nil {
+		return err
+	}
+	redisconn.Del(ctx, s.client, patternCacheKey(id), patternListKey(p.TaskType), patternListKey(""))
+	return nil
+}
+
+// SaveSuggestion writes through; suggestions aren't cached
+func (s *CachedPatternStore) SaveSuggestion(ctx context.Context, sg *ImprovementSuggestion) error {
+	return s.backing.SaveSuggestion(ctx, sg)
+}
+
+func (s *CachedPatternStore) cache(ctx context.Context, p *CollaborationPattern) {
+	if data, err := json.Marshal(p); err == nil {
+		s.client.Set(ctx, patternCacheKey(p.ID), data, s.ttl)
+	}
+}
+
+func patternCacheKey(id uuid.UUID) string {
+	return fmt.Sprintf("pattern:%s", id.String())
+}
+
+// patternListKey is deliberately outside pattern:* so RedisPatternStore
+// scans never see cached lists
+func patternListKey(taskType string) string {
+	return fmt.Sprintf("patterns:list:%s", taskType)
+}
+
+func patternKey(p *CollaborationPattern) string {
+	return patternKeyFor(p.TaskType, p.AgentSequence)
+}
+
+// patternKeyFor identifies a pattern by task type and agent sequence
+func patternKeyFor(taskType string, sequence []agents.AgentType) string {
+	key := taskType
+	for _, a := range sequence {
+		key += "_" + string(a)
+	}
+	return key
+}
+
+func sortPatterns(patterns []*CollaborationPattern) {
+	sort.Slice(patterns, func(i, j int) bool {
+		a, b := patterns[i], patterns[j]
+		if a.TaskType != b.TaskType {
+			return a.TaskType < b.TaskType
+		}
+		if a.QValue != b.QValue {
+			return a.QValue > b.QValue
+		}
+		return a.ID.String() < b.ID.String()
+	})
+}
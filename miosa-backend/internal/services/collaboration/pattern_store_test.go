@@ -0,0 +1,151 @@
+package collaboration
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestPostgresPatternStore(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewPostgresPatternStore(db)
+
+	p := learnedPattern("api", []agents.AgentType{agents.ArchitectAgent, agents.DevelopmentAgent}, 0.7, time.Now())
+	mock.ExpectExec("INSERT INTO collaboration_patterns").
+		WithArgs(p.ID, "api_architect_development", "api", 0.7, p.ConfidenceScore, p.UsageCount, sqlmock.AnyArg(), p.LastUpdated).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, store.SavePattern(ctx, p))
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT pattern FROM collaboration_patterns").
+		WithArgs("api").
+		WillReturnRows(sqlmock.NewRows([]string{"pattern"}).AddRow(data))
+	listed, err := store.ListPatterns(ctx, "api")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, p.ID, listed[0].ID)
+
+	mock.ExpectQuery("SELECT pattern FROM collaboration_patterns WHERE id").
+		WithArgs(p.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"pattern"}))
+	_, err = store.GetPattern(ctx, p.ID)
+	assert.ErrorIs(t, err, ErrPatternNotFound)
+
+	suggestion := &ImprovementSuggestion{
+		ID:        uuid.New(),
+		PatternID: p.ID,
+		Type:      ImprovementTypeCaching,
+		Status:    SuggestionStatusApplied,
+		CreatedAt: time.Now(),
+		Results:   &ImprovementResults{ImprovementRate: 0.2},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO improvement_suggestions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO improvement_results").
+		WithArgs(suggestion.ID, 0.2, false, sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, store.SaveSuggestion(ctx, suggestion))
+
+	mock.ExpectExec("DELETE FROM collaboration_patterns").WithArgs(p.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.DeletePattern(ctx, p.ID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCachedPatternStore_ReadThrough(t *testing.T) {
+	ctx := context.Background()
+	client, mock := redismock.NewClientMock()
+	backing := NewMemoryPatternStore()
+	store := NewCachedPatternStore(backing, client, time.Minute)
+
+	p := learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.4, time.Now().UTC())
+	require.NoError(t, backing.SavePattern(ctx, p))
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+
+	key := "pattern:" + p.ID.String()
+	mock.ExpectGet(key).RedisNil()
+	mock.ExpectSet(key, data, time.Minute).SetVal("OK")
+	got, err := store.GetPattern(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, got.ID)
+
+	mock.ExpectGet(key).SetVal(string(data))
+	got, err = store.GetPattern(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, p.QValue, got.QValue, "served from the cache")
+
+	p.QValue = 0.9
+	updated, err := json.Marshal(p)
+	require.NoError(t, err)
+	mock.ExpectSet(key, updated, time.Minute).SetVal("OK")
+	mock.ExpectDel("patterns:list:api").SetVal(1)
+	mock.ExpectDel("patterns:list:").SetVal(1)
+	require.NoError(t, store.SavePattern(ctx, p))
+
+	stored, err := backing.GetPattern(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, stored.QValue, "writes go through to the source of truth")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrunePatterns_RemovesDecayedPatterns(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryPatternStore()
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	sie.SetPatternStore(store)
+
+	fresh := learnedPattern("api", []agents.AgentType{agents.DevelopmentAgent}, 0.5, time.Now())
+	fresh.ConfidenceScore = 8
+	// 8 * 0.95^60 ≈ 0.37, below the default minimum of 1
+	stale := learnedPattern("api", []agents.AgentType{agents.QualityAgent}, 0.9, time.Now().Add(-60*24*time.Hour))
+	stale.ConfidenceScore = 8
+	storedOnly := learnedPattern("ui", []agents.AgentType{agents.ArchitectAgent}, 0.9, time.Now().Add(-90*24*time.Hour))
+	storedOnly.ConfidenceScore = 8
+
+	require.NoError(t, sie.RecordPattern(ctx, fresh))
+	require.NoError(t, sie.RecordPattern(ctx, stale))
+	require.NoError(t, store.SavePattern(ctx, storedOnly))
+
+	pruned, err := sie.PrunePatterns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+
+	remaining, err := store.ListPatterns(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, fresh.ID, remaining[0].ID)
+	assert.Same(t, fresh, sie.GetBestPattern(ctx, "api"))
+}
+
+func TestWarmPatterns_KeepsIdentityAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryPatternStore()
+	sequence := []agents.AgentType{agents.ArchitectAgent}
+	learned := learnedPattern("api", sequence, 0.6, time.Now())
+	require.NoError(t, store.SavePattern(ctx, learned))
+
+	sie := NewSelfImprovementEngine(nil, zap.NewNop())
+	sie.SetPatternStore(store)
+	loaded, err := sie.WarmPatterns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+
+	pattern := sie.extractPattern([]*CollaborativeTask{{Type: "api", AssignedAgent: agents.ArchitectAgent, Status: TaskStatusCompleted}})
+	assert.Equal(t, learned.ID, pattern.ID)
+	assert.Equal(t, learned.UsageCount+1, pattern.UsageCount)
+}
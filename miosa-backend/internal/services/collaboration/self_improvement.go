@@ -5,6 +5,7 @@ import (
     "encoding/json"
     "fmt"
     "math"
+    "math/rand"
     "sort"
     "sync"
     "time"
@@ -12,12 +13,16 @@ import (
     "github.com/google/uuid"
     "github.com/redis/go-redis/v9"
     "github.com/sormind/OSA/miosa-backend/internal/agents"
+    "github.com/sormind/OSA/miosa-backend/internal/audit"
+    "github.com/sormind/OSA/miosa-backend/internal/events"
+    "github.com/sormind/OSA/miosa-backend/internal/liveconfig"
     "go.uber.org/zap"
 )
 
 // SelfImprovementEngine analyzes agent collaboration patterns and improves them
 type SelfImprovementEngine struct {
-    redisClient *redis.Client
+    redisClient redis.UniversalClient
+    bus         *events.Bus
     logger      *zap.Logger
 
     patterns        map[string]*CollaborationPattern
@@ -27,14 +32,25 @@ type SelfImprovementEngine struct {
     learningRate float64 // alpha
     discount     float64 // gamma
 
-    // Confidence decay of old patterns
-    confidenceDecay float64
+    // Confidence decay of old patterns, applied per day since LastUpdated
+    // when pruning; patterns decaying below pruneConfidenceMin are removed
+    confidenceDecay    float64
+    pruneConfidenceMin float64
+
+    // Source of truth for patterns and suggestions
+    store PatternStore
 
     // Reward weights (can be hot-reloaded from Redis)
     weights           RewardWeights
     weightsTTL        time.Duration
     weightsLastLoaded time.Time
 
+    // Pattern selection; random is swappable for deterministic tests
+    exploration ExplorationPolicy
+    random      func() float64
+
+    audit *audit.Log
+
     mu sync.RWMutex
 }
 
@@ -50,6 +66,13 @@ type RewardWeights struct {
     CompositeBoost      float64 `json:"composite_boost"`        // bonus for composite suggestion expected impact
     HighImpactThreshold float64 `json:"high_impact_threshold"`  // auto-apply if ExpectedImpact >= this
     HighConfidenceMin   float64 `json:"high_confidence_min"`    // auto-apply if Confidence >= this
+
+    // User feedback shaping
+    ThumbsUpBonus       float64 `json:"thumbs_up_bonus"`        // per thumbs up
+    ThumbsDownPenalty   float64 `json:"thumbs_down_penalty"`    // per thumbs down
+    RatingWeight        float64 `json:"rating_weight"`          // scales (rating-3)/2 for 1-5 ratings
+    QualityGatePass     float64 `json:"quality_gate_pass"`      // per passed quality gate
+    QualityGateFail     float64 `json:"quality_gate_fail"`      // per failed quality gate
 }
 
 // CollaborationPattern represents a learned pattern of agent collaboration
@@ -135,15 +158,18 @@ type PerformanceMetrics struct {
 }
 
 // NewSelfImprovementEngine creates a new self-improvement engine
-func NewSelfImprovementEngine(redisClient *redis.Client, logger *zap.Logger) *SelfImprovementEngine {
+func NewSelfImprovementEngine(redisClient redis.UniversalClient, logger *zap.Logger) *SelfImprovementEngine {
     return &SelfImprovementEngine{
         redisClient: redisClient,
+        bus:         events.NewBus(redisClient, "self-improvement"),
         logger:      logger,
         patterns:    make(map[string]*CollaborationPattern),
 
-        learningRate:    0.15,
-        discount:        0.85,
-        confidenceDecay: 0.95,
+        learningRate:       0.15,
+        discount:           0.85,
+        confidenceDecay:    0.95,
+        pruneConfidenceMin: 1.0,
+        store:              defaultPatternStore(redisClient),
 
         weights: RewardWeights{
             SuccessBonus:        1.0,
@@ -156,13 +182,40 @@ func NewSelfImprovementEngine(redisClient *redis.Client, logger *zap.Logger) *Se
             CompositeBoost:      0.1,
             HighImpactThreshold: 0.25,
             HighConfidenceMin:   9.0,
+            ThumbsUpBonus:       0.5,
+            ThumbsDownPenalty:   -0.5,
+            RatingWeight:        0.5,
+            QualityGatePass:     0.3,
+            QualityGateFail:     -0.6,
         },
         weightsTTL:        60 * time.Second,
         weightsLastLoaded: time.Time{},
         improvementBuffer: make([]*ImprovementSuggestion, 0),
+
+        exploration: DefaultExplorationPolicy,
+        random:      rand.Float64,
     }
 }
 
+// defaultPatternStore keeps patterns in Redis when available, as before
+// Postgres became the source of truth
+func defaultPatternStore(redisClient redis.UniversalClient) PatternStore {
+    if redisClient == nil {
+        return NewMemoryPatternStore()
+    }
+    return NewRedisPatternStore(redisClient)
+}
+
+// SetPatternStore replaces where patterns and suggestions are persisted
+func (sie *SelfImprovementEngine) SetPatternStore(s PatternStore) {
+    sie.store = s
+}
+
+// SetAuditLog records approval decisions and applied improvements to l
+func (sie *SelfImprovementEngine) SetAuditLog(l *audit.Log) {
+    sie.audit = l
+}
+
 // AnalyzeCollaboration analyzes a completed collaboration for improvements
 func (sie *SelfImprovementEngine) AnalyzeCollaboration(ctx context.Context, tasks []*CollaborativeTask) error {
     if len(tasks) == 0 {
@@ -187,6 +240,11 @@ func (sie *SelfImprovementEngine) AnalyzeCollaboration(ctx context.Context, task
     // Q-learning update with next state
     sie.updateQValue(pattern, reward, nextMax)
 
+    // Store updated pattern before suggestions that reference it
+    if err := sie.storePattern(ctx, pattern); err != nil {
+        sie.logger.Warn("Failed to persist pattern", zap.String("pattern_id", pattern.ID.String()), zap.Error(err))
+    }
+
     // Generate improvements when needed
     if pattern.ConfidenceScore < 7.0 || pattern.SuccessRate < 0.8 {
         suggestions := sie.generateImprovements(ctx, pattern, tasks)
@@ -203,6 +261,19 @@ func (sie *SelfImprovementEngine) AnalyzeCollaboration(ctx context.Context, task
         for _, suggestion := range suggestions {
             if suggestion.Confidence >= sie.weights.HighConfidenceMin &&
                 suggestion.ExpectedImpact >= sie.weights.HighImpactThreshold {
+                sie.audit.Record(ctx, audit.Event{
+                    Actor:     "self-improvement",
+                    ActorType: audit.ActorSystem,
+                    Action:    audit.ActionApproval,
+                    Resource:  suggestion.ID.String(),
+                    Status:    audit.StatusApproved,
+                    Metadata: map[string]string{
+                        "decision":        "auto",
+                        "type":            string(suggestion.Type),
+                        "confidence":      fmt.Sprintf("%.2f", suggestion.Confidence),
+                        "expected_impact": fmt.Sprintf("%.2f", suggestion.ExpectedImpact),
+                    },
+                })
                 if err := sie.applyImprovement(ctx, suggestion); err != nil {
                     sie.logger.Warn("Auto-apply improvement failed",
                         zap.String("suggestion_id", suggestion.ID.String()),
@@ -212,11 +283,6 @@ func (sie *SelfImprovementEngine) AnalyzeCollaboration(ctx context.Context, task
         }
     }
 
-    // Store updated pattern
-    if err := sie.storePattern(ctx, pattern); err != nil {
-        sie.logger.Warn("Failed to persist pattern", zap.String("pattern_id", pattern.ID.String()), zap.Error(err))
-    }
-
     return nil
 }
 
@@ -333,6 +399,11 @@ func (sie *SelfImprovementEngine) calculateReward(tasks []*CollaborativeTask) fl
     // Confidence aggregate
     reward += totalConfidence
 
+    // Explicit user and quality-gate feedback
+    for _, task := range tasks {
+        reward += w.feedbackReward(task.Feedback)
+    }
+
     // Normalize
     return reward / float64(len(tasks))
 }
@@ -484,6 +555,7 @@ func (sie *SelfImprovementEngine) checkParallelization(pattern *CollaborationPat
             Implementation: &ImplementationDetails{
                 Configuration: map[string]interface{}{
                     "parallel_groups": independentGroups,
+                    "parallel_agents": sie.groupAgents(independentGroups, tasks),
                     "max_concurrency": min(len(independentGroups), 4),
                 },
                 RollbackPlan: "Restore sequential execution if error rate increases >2%.",
@@ -512,6 +584,7 @@ func (sie *SelfImprovementEngine) checkAgentSwap(pattern *CollaborationPattern,
                 Confidence:     7.2,
                 Implementation: &ImplementationDetails{
                     Configuration: map[string]interface{}{
+                        "task_type": pattern.TaskType,
                         "old_agent": weakestAgent,
                         "new_agent": alternative,
                     },
@@ -645,6 +718,22 @@ func (sie *SelfImprovementEngine) checkAddValidation(pattern *CollaborationPatte
     return nil
 }
 
+// recordImprovement audits an improvement application
+func (sie *SelfImprovementEngine) recordImprovement(ctx context.Context, suggestion *ImprovementSuggestion, payload []byte, status string) {
+    sie.audit.Record(ctx, audit.Event{
+        Actor:       "self-improvement",
+        ActorType:   audit.ActorSystem,
+        Action:      audit.ActionImprovementApply,
+        Resource:    suggestion.ID.String(),
+        RequestHash: audit.HashRequest(payload),
+        Status:      status,
+        Metadata: map[string]string{
+            "type":       string(suggestion.Type),
+            "pattern_id": suggestion.PatternID.String(),
+        },
+    })
+}
+
 // applyImprovement applies an improvement suggestion and schedules evaluation
 func (sie *SelfImprovementEngine) applyImprovement(ctx context.Context, suggestion *ImprovementSuggestion) error {
     sie.logger.Info("Applying improvement",
@@ -653,11 +742,12 @@ func (sie *SelfImprovementEngine) applyImprovement(ctx context.Context, suggesti
         zap.Float64("expected_impact", suggestion.ExpectedImpact))
 
     // Persist suggestion
-    improvementKey := fmt.Sprintf("improvement:%s", suggestion.ID)
     improvementData, _ := json.Marshal(suggestion)
-    if err := sie.redisClient.Set(ctx, improvementKey, improvementData, 7*24*time.Hour).Err(); err != nil {
+    if err := sie.store.SaveSuggestion(ctx, suggestion); err != nil {
+        sie.recordImprovement(ctx, suggestion, improvementData, audit.StatusFailure)
         return err
     }
+    sie.recordImprovement(ctx, suggestion, improvementData, audit.StatusSuccess)
 
     // Mark as applied
     now := time.Now()
@@ -704,23 +794,21 @@ func (sie *SelfImprovementEngine) applyImprovement(ctx context.Context, suggesti
     }
 
     // Update stored record with "applied" status and before-metrics
-    improvementData, _ = json.Marshal(suggestion)
-    _ = sie.redisClient.Set(ctx, improvementKey, improvementData, 7*24*time.Hour).Err()
+    if err := sie.store.SaveSuggestion(ctx, suggestion); err != nil {
+        sie.logger.Warn("Failed to persist applied suggestion", zap.String("suggestion_id", suggestion.ID.String()), zap.Error(err))
+    }
 
     return nil
 }
 
 // requestEvaluation publishes a request for monitoring-service to evaluate impact
 func (sie *SelfImprovementEngine) requestEvaluation(ctx context.Context, patternID uuid.UUID, suggestionID uuid.UUID) {
-    payload := map[string]interface{}{
-        "type":          "evaluate_improvement",
-        "pattern_id":    patternID.String(),
-        "suggestion_id": suggestionID.String(),
-        // e.g., evaluate after 30 minutes window
-        "window": "30m",
-    }
-    data, _ := json.Marshal(payload)
-    if err := sie.redisClient.Publish(ctx, "monitoring:requests", data).Err(); err != nil {
+    _, err := sie.bus.Publish(ctx, events.EvaluationRequest{
+        PatternID:    patternID,
+        SuggestionID: suggestionID,
+        Window:       "30m", // Evaluate over the 30 minutes after applying
+    })
+    if err != nil {
         sie.logger.Warn("Failed to publish evaluation request", zap.Error(err))
     }
 }
@@ -765,7 +853,8 @@ func (sie *SelfImprovementEngine) RecordPattern(ctx context.Context, pattern *Co
     return sie.storePattern(ctx, pattern)
 }
 
-// GetBestPattern returns the best pattern for a given task type
+// GetBestPattern returns the best pattern for a given task type, always
+// exploiting the highest Q-value; see SelectPattern for exploration
 func (sie *SelfImprovementEngine) GetBestPattern(ctx context.Context, taskType string) *CollaborationPattern {
     sie.mu.RLock()
     var best *CollaborationPattern
@@ -782,51 +871,49 @@ func (sie *SelfImprovementEngine) GetBestPattern(ctx context.Context, taskType s
         return best
     }
 
-    // Try Redis fallback
-    keys, err := sie.redisClient.Keys(ctx, fmt.Sprintf("pattern:%s*", taskType)).Result()
-    if err == nil {
-        for _, k := range keys {
-            if data, err := sie.redisClient.Get(ctx, k).Result(); err == nil {
-                var p CollaborationPattern
-                if json.Unmarshal([]byte(data), &p) == nil {
-                    if p.QValue > highest {
-                        highest = p.QValue
-                        cp := p
-                        best = &cp
-                    }
-                }
-            }
+    // Fall back to the pattern store
+    stored, err := sie.store.ListPatterns(ctx, taskType)
+    if err != nil {
+        sie.logger.Warn("Failed to list stored patterns", zap.Error(err))
+        return nil
+    }
+    for _, p := range stored {
+        if p.QValue > highest {
+            highest = p.QValue
+            best = p
         }
     }
     return best
 }
 
 func (sie *SelfImprovementEngine) storePattern(ctx context.Context, pattern *CollaborationPattern) error {
-    patternKey := fmt.Sprintf("pattern:%s", pattern.ID.String())
-    b, _ := json.Marshal(pattern)
-    return sie.redisClient.Set(ctx, patternKey, b, 0).Err()
+    return sie.store.SavePattern(ctx, pattern)
 }
 
 func (sie *SelfImprovementEngine) updateOrchestratorConfig(ctx context.Context, configType string, config map[string]interface{}) {
-    event := map[string]interface{}{
-        "type":   "config_update",
-        "target": "orchestrator",
-        "update": map[string]interface{}{
-            "kind":   configType,
-            "config": config,
-        },
-    }
-    data, _ := json.Marshal(event)
-    if err := sie.redisClient.Publish(ctx, "config_updates", data).Err(); err != nil {
+    data, _ := json.Marshal(config)
+    _, err := sie.bus.Publish(ctx, events.ConfigUpdate{
+        Target: liveconfig.Target,
+        Kind:   configType,
+        Config: data,
+    })
+    if err != nil {
         sie.logger.Warn("Failed to publish orchestrator config update", zap.Error(err))
     }
 }
 
+// updateAgentRouting stores the swap as one rule of the routing hash the
+// orchestrator loads at startup, scoped to the pattern's task type
 func (sie *SelfImprovementEngine) updateAgentRouting(ctx context.Context, config map[string]interface{}) {
-    routingKey := "agent_routing_rules"
-    if err := sie.redisClient.HSet(ctx, routingKey, config).Err(); err != nil {
+    taskType, _ := config["task_type"].(string)
+    oldAgent := agents.AgentType(fmt.Sprint(config["old_agent"]))
+    newAgent := fmt.Sprint(config["new_agent"])
+    field := liveconfig.RuleField(taskType, oldAgent)
+    if err := sie.redisClient.HSet(ctx, liveconfig.RulesKey, field, newAgent).Err(); err != nil {
         sie.logger.Warn("Failed to update agent routing", zap.Error(err))
     }
+    // Running orchestrators pick the swap up from config_updates
+    sie.updateOrchestratorConfig(ctx, string(ImprovementTypeAgentSwap), config)
 }
 
 func (sie *SelfImprovementEngine) updateContextBuilder(ctx context.Context, config map[string]interface{}) {
@@ -848,11 +935,7 @@ func (sie *SelfImprovementEngine) enablePatternCaching(ctx context.Context, patt
 // Helper methods
 
 func (sie *SelfImprovementEngine) generatePatternKey(taskType string, sequence []agents.AgentType) string {
-    key := taskType
-    for _, a := range sequence {
-        key += "_" + string(a)
-    }
-    return key
+    return patternKeyFor(taskType, sequence)
 }
 
 func (sie *SelfImprovementEngine) extractContextFeatures(tasks []*CollaborativeTask) map[string]interface{} {
@@ -917,6 +1000,30 @@ func (sie *SelfImprovementEngine) findIndependentTaskGroups(tasks []*Collaborati
     return groups
 }
 
+// groupAgents maps task groups to the agents assigned to them, which is how
+// the orchestrator identifies the steps it may run in parallel
+func (sie *SelfImprovementEngine) groupAgents(groups [][]uuid.UUID, tasks []*CollaborativeTask) [][]agents.AgentType {
+    assigned := make(map[uuid.UUID]agents.AgentType, len(tasks))
+    for _, t := range tasks {
+        assigned[t.ID] = t.AssignedAgent
+    }
+    agentGroups := make([][]agents.AgentType, 0, len(groups))
+    for _, group := range groups {
+        seen := make(map[agents.AgentType]bool)
+        var agentGroup []agents.AgentType
+        for _, id := range group {
+            if a := assigned[id]; a != "" && !seen[a] {
+                seen[a] = true
+                agentGroup = append(agentGroup, a)
+            }
+        }
+        if len(agentGroup) > 1 {
+            agentGroups = append(agentGroups, agentGroup)
+        }
+    }
+    return agentGroups
+}
+
 func (sie *SelfImprovementEngine) stepCritical(tasks []*CollaborativeTask, idx int) bool {
     cur := tasks[idx].ID
     for _, t := range tasks {
@@ -1024,7 +1131,9 @@ func (sie *SelfImprovementEngine) loadWeights(ctx context.Context) error {
         sie.weightsLastLoaded = time.Now()
         return nil
     }
-    var w RewardWeights
+    // Start from the current weights so overrides written before a field
+    // existed don't zero it
+    w := sie.weights
     if json.Unmarshal(raw, &w) == nil {
         sie.weights = w
         sie.logger.Info("Self-improvement weights reloaded")
@@ -10,14 +10,18 @@ import (
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/events"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
 	"go.uber.org/zap"
 )
 
 // TaskQueue represents a distributed task queue for agent collaboration
 type TaskQueue struct {
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
+	bus         *events.Bus
 	logger      *zap.Logger
 	subscribers map[agents.AgentType]*TaskSubscriber
+	onFinish    func(ctx context.Context, task *CollaborativeTask)
 	mu          sync.RWMutex
 }
 
@@ -25,6 +29,7 @@ type TaskQueue struct {
 type CollaborativeTask struct {
 	ID              uuid.UUID                `json:"id"`
 	ParentID        *uuid.UUID               `json:"parent_id,omitempty"`
+	CollaborationID *uuid.UUID               `json:"collaboration_id,omitempty"`
 	Type            string                   `json:"type"`
 	Priority        int                      `json:"priority"`
 	Status          TaskStatus               `json:"status"`
@@ -65,6 +70,8 @@ type FeedbackEntry struct {
 	Confidence  float64                `json:"confidence"`
 	Suggestions []string               `json:"suggestions"`
 	Metadata    map[string]interface{} `json:"metadata"`
+	Rating      float64                `json:"rating,omitempty"` // 1-5, for rating feedback
+	Passed      *bool                  `json:"passed,omitempty"` // for quality gate feedback
 }
 
 // FeedbackType categorizes different types of feedback
@@ -76,6 +83,12 @@ const (
 	FeedbackTypeError       FeedbackType = "error"
 	FeedbackTypeHandoff     FeedbackType = "handoff"
 	FeedbackTypeCollaborate FeedbackType = "collaborate"
+
+	// User and quality-gate feedback, which also shapes pattern rewards
+	FeedbackTypeThumbsUp    FeedbackType = "thumbs_up"
+	FeedbackTypeThumbsDown  FeedbackType = "thumbs_down"
+	FeedbackTypeRating      FeedbackType = "rating"
+	FeedbackTypeQualityGate FeedbackType = "quality_gate"
 )
 
 // TaskSubscriber handles task subscriptions for an agent
@@ -91,9 +104,10 @@ type TaskSubscriber struct {
 type TaskHandler func(ctx context.Context, task *CollaborativeTask) error
 
 // NewTaskQueue creates a new distributed task queue
-func NewTaskQueue(redisClient *redis.Client, logger *zap.Logger) *TaskQueue {
+func NewTaskQueue(redisClient redis.UniversalClient, logger *zap.Logger) *TaskQueue {
 	return &TaskQueue{
 		redisClient: redisClient,
+		bus:         events.NewBus(redisClient, "task-queue"),
 		logger:      logger,
 		subscribers: make(map[agents.AgentType]*TaskSubscriber),
 	}
@@ -103,10 +117,16 @@ func NewTaskQueue(redisClient *redis.Client, logger *zap.Logger) *TaskQueue {
 func (tq *TaskQueue) PublishTask(ctx context.Context, task *CollaborativeTask) error {
 	task.ID = uuid.New()
 	task.CreatedAt = time.Now()
+	return tq.Enqueue(ctx, task)
+}
+
+// Enqueue queues a task under its existing ID, as pending
+func (tq *TaskQueue) Enqueue(ctx context.Context, task *CollaborativeTask) error {
 	task.UpdatedAt = time.Now()
 	task.Status = TaskStatusPending
 
 	// Store task in Redis
+	redact.Result(task.Result)
 	taskKey := fmt.Sprintf("task:%s", task.ID)
 	taskData, err := json.Marshal(task)
 	if err != nil {
@@ -134,11 +154,15 @@ func (tq *TaskQueue) PublishTask(ctx context.Context, task *CollaborativeTask) e
 	}
 
 	// Publish event for real-time notification
-	eventData, _ := json.Marshal(map[string]interface{}{
-		"event": "task_created",
-		"task":  task,
+	_, err = tq.bus.Publish(ctx, events.TaskCreated{
+		TaskID:   task.ID,
+		Agent:    string(task.AssignedAgent),
+		Priority: task.Priority,
+		Task:     taskData,
 	})
-	tq.redisClient.Publish(ctx, fmt.Sprintf("events:%s", task.AssignedAgent), eventData)
+	if err != nil {
+		tq.logger.Warn("Failed to publish task event", zap.String("task_id", task.ID.String()), zap.Error(err))
+	}
 
 	tq.logger.Info("Task published",
 		zap.String("task_id", task.ID.String()),
@@ -148,6 +172,22 @@ func (tq *TaskQueue) PublishTask(ctx context.Context, task *CollaborativeTask) e
 	return nil
 }
 
+// OnFinish registers fn to run when a task completes or finally fails
+func (tq *TaskQueue) OnFinish(fn func(ctx context.Context, task *CollaborativeTask)) {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	tq.onFinish = fn
+}
+
+func (tq *TaskQueue) finished(ctx context.Context, task *CollaborativeTask) {
+	tq.mu.RLock()
+	fn := tq.onFinish
+	tq.mu.RUnlock()
+	if fn != nil {
+		fn(ctx, task)
+	}
+}
+
 // SubscribeToTasks subscribes an agent to receive tasks
 func (tq *TaskQueue) SubscribeToTasks(agentType agents.AgentType, handler TaskHandler) error {
 	tq.mu.Lock()
@@ -261,6 +301,7 @@ func (tq *TaskQueue) processTask(ctx context.Context, subscriber *TaskSubscriber
 	task.ConfidenceScore = tq.calculateConfidence(task, executionTime)
 	
 	tq.updateTask(ctx, task)
+	tq.finished(ctx, task)
 
 	// Trigger self-improvement analysis if confidence is low
 	if task.ConfidenceScore < 7.0 {
@@ -300,7 +341,7 @@ func (tq *TaskQueue) HandoffTask(ctx context.Context, taskID uuid.UUID, fromAgen
 	task.UpdatedAt = time.Now()
 
 	// Re-publish to new agent's queue
-	return tq.PublishTask(ctx, &task)
+	return tq.Enqueue(ctx, &task)
 }
 
 // CreateSubtask creates a subtask for parallel processing
@@ -341,6 +382,7 @@ func (tq *TaskQueue) GetTaskStatus(ctx context.Context, taskID uuid.UUID) (*Coll
 
 // updateTask updates a task in Redis
 func (tq *TaskQueue) updateTask(ctx context.Context, task *CollaborativeTask) error {
+	redact.Result(task.Result)
 	taskKey := fmt.Sprintf("task:%s", task.ID)
 	taskData, err := json.Marshal(task)
 	if err != nil {
@@ -405,14 +447,15 @@ func (tq *TaskQueue) handleTaskFailure(ctx context.Context, task *CollaborativeT
 		task.Feedback = append(task.Feedback, feedback)
 		
 		tq.updateTask(ctx, task)
+		tq.finished(ctx, task)
 		return
 	}
 
-	// Re-queue with exponential backoff
+	// Re-queue with exponential backoff, keeping the ID so collaborations
+	// and status queries still find the task
 	backoff := time.Duration(task.RetryCount) * 5 * time.Second
 	time.AfterFunc(backoff, func() {
-		task.Status = TaskStatusPending
-		tq.PublishTask(context.Background(), task)
+		tq.Enqueue(context.Background(), task)
 	})
 }
 
@@ -0,0 +1,254 @@
+package consultation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Turn roles
+const (
+	RoleUser       = "user"
+	RoleConsultant = "assistant"
+)
+
+// maxHistoryTurns bounds the history sent with each completion
+const maxHistoryTurns = 20
+
+// nextPhaseMarker prefixes the line the model ends each reply with to
+// signal whether the current phase is done
+const nextPhaseMarker = "NEXT_PHASE:"
+
+// Session is a consultation and its progress through the phases
+type Session struct {
+	ID             uuid.UUID       `json:"id"`
+	TenantID       uuid.UUID       `json:"tenant_id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	Topic          string          `json:"topic"`
+	Context        string          `json:"context,omitempty"`
+	Phase          Phase           `json:"phase"`
+	Turns          []Turn          `json:"turns"`
+	Transitions    []Transition    `json:"transitions"`
+	Recommendation *Recommendation `json:"recommendation,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Turn is one message in a consultation
+type Turn struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Phase     Phase     `json:"phase"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Transition records a phase change
+type Transition struct {
+	From      Phase     `json:"from"`
+	To        Phase     `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Recommendation is the phase the engine suggests next. Phase equals the
+// current phase when the consultation should stay where it is.
+type Recommendation struct {
+	Phase  Phase  `json:"phase"`
+	Reason string `json:"reason"`
+}
+
+// Reply is the consultant's answer to a turn
+type Reply struct {
+	Content        string          `json:"content"`
+	Phase          Phase           `json:"phase"`
+	Recommendation *Recommendation `json:"recommendation"`
+}
+
+// Completer is the part of the Groq client the engine uses
+type Completer interface {
+	ChatCompletion(ctx context.Context, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error)
+}
+
+// Engine runs consultations through the phase state machine
+type Engine struct {
+	completer Completer
+	store     Store
+	model     string
+	logger    *zap.Logger
+}
+
+// NewEngine creates a consultation engine answering with model
+func NewEngine(completer Completer, store Store, model string, logger *zap.Logger) *Engine {
+	return &Engine{
+		completer: completer,
+		store:     store,
+		model:     model,
+		logger:    logger,
+	}
+}
+
+// Start opens a consultation in phase and answers the topic
+func (e *Engine) Start(ctx context.Context, tenantID, userID uuid.UUID, topic, topicContext string, phase Phase) (*Session, *Reply, error) {
+	if _, err := StrategyFor(phase); err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	s := &Session{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		Topic:     topic,
+		Context:   topicContext,
+		Phase:     phase,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	opening := topic
+	if topicContext != "" {
+		opening += "\n\nContext:\n" + topicContext
+	}
+	reply, err := e.Respond(ctx, s, opening)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, reply, nil
+}
+
+// Respond answers message in the session's current phase, records both
+// turns and the new recommendation, and saves the session
+func (e *Engine) Respond(ctx context.Context, s *Session, message string) (*Reply, error) {
+	strategy, err := StrategyFor(s.Phase)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := e.completer.ChatCompletion(ctx, groq.ChatCompletionRequest{
+		Model:       groq.ChatModel(e.model),
+		Messages:    e.messages(s, strategy, message),
+		MaxTokens:   1500,
+		Temperature: 0.7,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consultation reply: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("no response from model")
+	}
+
+	content, signalled := parseNextPhase(resp.Choices[0].Message.Content)
+	now := time.Now()
+	s.Turns = append(s.Turns,
+		Turn{Role: RoleUser, Content: message, Phase: s.Phase, CreatedAt: now},
+		Turn{Role: RoleConsultant, Content: content, Phase: s.Phase, CreatedAt: now},
+	)
+	s.Recommendation = recommend(s, strategy, signalled)
+	s.UpdatedAt = now
+
+	if err := e.store.Save(ctx, s); err != nil {
+		return nil, fmt.Errorf("failed to save consultation: %w", err)
+	}
+	return &Reply{Content: content, Phase: s.Phase, Recommendation: s.Recommendation}, nil
+}
+
+// Advance moves the session to phase, recording why, and saves it
+func (e *Engine) Advance(ctx context.Context, s *Session, to Phase, reason string) error {
+	if _, err := StrategyFor(to); err != nil {
+		return err
+	}
+	if !CanTransition(s.Phase, to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, s.Phase, to)
+	}
+
+	now := time.Now()
+	s.Transitions = append(s.Transitions, Transition{From: s.Phase, To: to, Reason: reason, CreatedAt: now})
+	e.logger.Info("Consultation phase changed",
+		zap.String("session_id", s.ID.String()),
+		zap.String("from", string(s.Phase)),
+		zap.String("to", string(to)))
+	s.Phase = to
+	s.Recommendation = nil
+	s.UpdatedAt = now
+
+	if err := e.store.Save(ctx, s); err != nil {
+		return fmt.Errorf("failed to save consultation: %w", err)
+	}
+	return nil
+}
+
+// messages builds the completion messages for a turn: the phase prompt
+// with the marker instructions, recent history and the new message
+func (e *Engine) messages(s *Session, strategy Strategy, message string) []groq.ChatCompletionMessage {
+	prompt := strategy.Prompt
+	if next := transitions[s.Phase]; len(next) > 0 {
+		prompt += fmt.Sprintf(
+			"\n\nEnd every reply with a final line %q followed by %q once this phase's goals are met, or \"stay\" otherwise.",
+			nextPhaseMarker, strategy.Next)
+	}
+	messages := []groq.ChatCompletionMessage{{Role: groq.RoleSystem, Content: prompt}}
+
+	history := s.Turns
+	if len(history) > maxHistoryTurns {
+		history = history[len(history)-maxHistoryTurns:]
+	}
+	for _, t := range history {
+		messages = append(messages, groq.ChatCompletionMessage{Role: groq.Role(t.Role), Content: t.Content})
+	}
+	return append(messages, groq.ChatCompletionMessage{Role: groq.RoleUser, Content: message})
+}
+
+// parseNextPhase strips the marker line from a reply, returning the phase
+// it names. The phase is empty when the model said to stay or gave no
+// marker.
+func parseNextPhase(reply string) (string, Phase) {
+	trimmed := strings.TrimRight(reply, " \n")
+	i := strings.LastIndex(trimmed, "\n")
+	last := strings.TrimSpace(trimmed[i+1:])
+	if !strings.HasPrefix(strings.ToUpper(last), nextPhaseMarker) {
+		return reply, ""
+	}
+
+	content := ""
+	if i >= 0 {
+		content = strings.TrimRight(trimmed[:i], " \n")
+	}
+	value := strings.ToLower(strings.TrimSpace(last[len(nextPhaseMarker):]))
+	if value == "stay" {
+		return content, ""
+	}
+	return content, Phase(value)
+}
+
+// recommend picks the next phase: the one the model signalled when it is
+// a valid transition, otherwise the strategy's next phase once the phase
+// has had its minimum number of turns
+func recommend(s *Session, strategy Strategy, signalled Phase) *Recommendation {
+	if signalled != "" && CanTransition(s.Phase, signalled) {
+		return &Recommendation{Phase: signalled, Reason: "The consultant considers this phase complete"}
+	}
+	if strategy.Next != "" && phaseTurns(s) >= strategy.MinTurns {
+		return &Recommendation{Phase: strategy.Next, Reason: fmt.Sprintf("%d exchanges in %s", phaseTurns(s), s.Phase)}
+	}
+	return &Recommendation{Phase: s.Phase, Reason: "This phase has open questions"}
+}
+
+// phaseTurns counts the exchanges since the session entered its phase
+func phaseTurns(s *Session) int {
+	var entered time.Time
+	if n := len(s.Transitions); n > 0 {
+		entered = s.Transitions[n-1].CreatedAt
+	}
+	exchanges := 0
+	for _, t := range s.Turns {
+		if t.Role == RoleUser && t.Phase == s.Phase && !t.CreatedAt.Before(entered) {
+			exchanges++
+		}
+	}
+	return exchanges
+}
@@ -0,0 +1,157 @@
+package consultation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeCompleter struct {
+	replies  []string
+	requests []groq.ChatCompletionRequest
+}
+
+func (f *fakeCompleter) ChatCompletion(ctx context.Context, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error) {
+	f.requests = append(f.requests, req)
+	reply := f.replies[0]
+	if len(f.replies) > 1 {
+		f.replies = f.replies[1:]
+	}
+	var resp groq.ChatCompletionResponse
+	resp.Choices = append(resp.Choices, groq.ChatCompletionChoice{Message: groq.ChatCompletionMessage{Content: reply}})
+	return resp, nil
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to Phase
+		want     bool
+	}{
+		{PhaseInitial, PhaseExploration, true},
+		{PhaseInitial, PhaseDeepDive, false},
+		{PhaseExploration, PhaseDeepDive, true},
+		{PhaseExploration, PhaseInitial, true},
+		{PhaseDeepDive, PhaseComplete, true},
+		{PhaseDeepDive, PhaseInitial, false},
+		{PhaseComplete, PhaseDeepDive, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CanTransition(tt.from, tt.to), "%s to %s", tt.from, tt.to)
+	}
+
+	p, err := ParsePhase("")
+	require.NoError(t, err)
+	assert.Equal(t, PhaseInitial, p)
+	_, err = ParsePhase("wrap-up")
+	assert.ErrorIs(t, err, ErrUnknownPhase)
+}
+
+func TestParseNextPhase(t *testing.T) {
+	content, next := parseNextPhase("Here is the plan.\n\nNEXT_PHASE: exploration\n")
+	assert.Equal(t, "Here is the plan.", content)
+	assert.Equal(t, PhaseExploration, next)
+
+	content, next = parseNextPhase("More questions.\nnext_phase: stay")
+	assert.Equal(t, "More questions.", content)
+	assert.Empty(t, next)
+
+	content, next = parseNextPhase("No marker here")
+	assert.Equal(t, "No marker here", content)
+	assert.Empty(t, next)
+}
+
+func TestEngine_RecommendsAndAdvances(t *testing.T) {
+	ctx := context.Background()
+	completer := &fakeCompleter{replies: []string{"What is the budget?\nNEXT_PHASE: stay"}}
+	engine := NewEngine(completer, NewMemoryStore(), "model", zap.NewNop())
+
+	s, reply, err := engine.Start(ctx, uuid.Nil, uuid.Nil, "Build a billing system", "", PhaseInitial)
+	require.NoError(t, err)
+	assert.Equal(t, "What is the budget?", reply.Content)
+	assert.Equal(t, PhaseInitial, reply.Recommendation.Phase, "one exchange is below the minimum")
+	assert.Contains(t, completer.requests[0].Messages[0].Content, "NEXT_PHASE:")
+
+	reply, err = engine.Respond(ctx, s, "About 50k")
+	require.NoError(t, err)
+	assert.Equal(t, PhaseExploration, reply.Recommendation.Phase, "minimum turns reached")
+
+	completer.replies = []string{"Options A and B.\nNEXT_PHASE: deep-dive"}
+	require.NoError(t, engine.Advance(ctx, s, PhaseExploration, "ready"))
+	reply, err = engine.Respond(ctx, s, "Compare options")
+	require.NoError(t, err)
+	assert.Equal(t, PhaseDeepDive, reply.Recommendation.Phase, "the model signalled the phase is done")
+	assert.Len(t, completer.requests[2].Messages, 6, "system prompt, four history turns and the message")
+
+	err = engine.Advance(ctx, s, PhaseComplete, "")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	require.Len(t, s.Transitions, 1)
+	assert.Equal(t, Transition{From: PhaseInitial, To: PhaseExploration, Reason: "ready", CreatedAt: s.Transitions[0].CreatedAt}, s.Transitions[0])
+
+	stored, err := engine.store.Get(ctx, uuid.Nil, uuid.Nil, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, PhaseExploration, stored.Phase)
+	assert.Len(t, stored.Turns, 6)
+}
+
+func TestHandlers_Consultation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	completer := &fakeCompleter{replies: []string{"Let's start.\nNEXT_PHASE: exploration"}}
+	h := NewHandlers(completer, NewMemoryStore(), "model", zap.NewNop())
+
+	r := gin.New()
+	r.POST("/consultation/sessions", h.StartSession)
+	r.GET("/consultation/sessions/:id", h.GetSession)
+	r.POST("/consultation/sessions/:id/messages", h.SendMessage)
+	r.POST("/consultation/sessions/:id/phase", h.Transition)
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, &buf))
+		return w
+	}
+
+	w := post("/consultation/sessions", StartRequest{Topic: "Billing", Phase: "bogus"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post("/consultation/sessions", StartRequest{Topic: "Billing"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var started struct {
+		Session Session `json:"session"`
+		Reply   Reply   `json:"reply"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+	assert.Equal(t, PhaseExploration, started.Reply.Recommendation.Phase)
+
+	path := "/consultation/sessions/" + started.Session.ID.String()
+	w = post(path+"/phase", TransitionRequest{Phase: string(PhaseComplete)})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = post(path+"/phase", TransitionRequest{})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var moved Session
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &moved))
+	assert.Equal(t, PhaseExploration, moved.Phase)
+	require.Len(t, moved.Transitions, 1)
+	assert.NotEmpty(t, moved.Transitions[0].Reason, "accepting a recommendation records its reason")
+
+	w = post(path+"/phase", TransitionRequest{})
+	assert.Equal(t, http.StatusConflict, w.Code, "nothing recommended after a transition")
+
+	w = post(path+"/messages", MessageRequest{Message: "Compare"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/consultation/sessions/"+uuid.NewString(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
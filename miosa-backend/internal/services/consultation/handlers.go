@@ -0,0 +1,213 @@
+package consultation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"go.uber.org/zap"
+)
+
+// Handlers manages consultation endpoints
+type Handlers struct {
+	engine *Engine
+	store  Store
+	logger *zap.Logger
+}
+
+// NewHandlers creates consultation handlers. completer may be nil, in
+// which case consultations can be read but not started or continued.
+func NewHandlers(completer Completer, store Store, model string, logger *zap.Logger) *Handlers {
+	h := &Handlers{store: store, logger: logger}
+	if completer != nil {
+		h.engine = NewEngine(completer, store, model, logger)
+	}
+	return h
+}
+
+// StartRequest is the body of POST /api/consultation/sessions
+type StartRequest struct {
+	Topic   string `json:"topic" binding:"required"`
+	Context string `json:"context"`
+	Phase   string `json:"phase"`
+}
+
+// MessageRequest is the body of POST /api/consultation/sessions/:id/messages
+type MessageRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// TransitionRequest is the body of POST /api/consultation/sessions/:id/phase.
+// An empty phase accepts the current recommendation.
+type TransitionRequest struct {
+	Phase  string `json:"phase"`
+	Reason string `json:"reason"`
+}
+
+// StartSession handles POST /api/consultation/sessions
+func (h *Handlers) StartSession(c *gin.Context) {
+	var req StartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	phase, err := ParsePhase(req.Phase)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consultation service not available"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	tenantID, userID := owner(c)
+	session, reply, err := h.engine.Start(ctx, tenantID, userID, req.Topic, req.Context, phase)
+	if err != nil {
+		h.logger.Error("Failed to start consultation", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start consultation"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"session": session, "reply": reply})
+}
+
+// ListSessions handles GET /api/consultation/sessions
+func (h *Handlers) ListSessions(c *gin.Context) {
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	tenantID, userID := owner(c)
+	sessions, err := h.store.List(c.Request.Context(), tenantID, userID, limit)
+	if err != nil {
+		h.logger.Error("Failed to list consultations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list consultations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// GetSession handles GET /api/consultation/sessions/:id
+func (h *Handlers) GetSession(c *gin.Context) {
+	session, ok := h.session(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// SendMessage handles POST /api/consultation/sessions/:id/messages
+func (h *Handlers) SendMessage(c *gin.Context) {
+	session, ok := h.session(c)
+	if !ok {
+		return
+	}
+	var req MessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consultation service not available"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	reply, err := h.engine.Respond(ctx, session, req.Message)
+	if err != nil {
+		h.logger.Error("Consultation reply failed", zap.String("session_id", session.ID.String()), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get response"})
+		return
+	}
+	c.JSON(http.StatusOK, reply)
+}
+
+// Transition handles POST /api/consultation/sessions/:id/phase
+func (h *Handlers) Transition(c *gin.Context) {
+	session, ok := h.session(c)
+	if !ok {
+		return
+	}
+	var req TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consultation service not available"})
+		return
+	}
+
+	to := Phase(req.Phase)
+	reason := req.Reason
+	if to == "" {
+		if session.Recommendation == nil || session.Recommendation.Phase == session.Phase {
+			c.JSON(http.StatusConflict, gin.H{"error": "No phase change is recommended"})
+			return
+		}
+		to = session.Recommendation.Phase
+		if reason == "" {
+			reason = session.Recommendation.Reason
+		}
+	}
+
+	err := h.engine.Advance(c.Request.Context(), session, to, reason)
+	switch {
+	case errors.Is(err, ErrUnknownPhase):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "allowed": transitions[session.Phase]})
+	case err != nil:
+		h.logger.Error("Failed to change consultation phase", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change phase"})
+	default:
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+// session loads the :id session owned by the caller, writing the error
+// response when it can't
+func (h *Handlers) session(c *gin.Context) (*Session, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid consultation ID"})
+		return nil, false
+	}
+	tenantID, userID := owner(c)
+	session, err := h.store.Get(c.Request.Context(), tenantID, userID, id)
+	if errors.Is(err, ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Consultation not found"})
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to load consultation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load consultation"})
+		return nil, false
+	}
+	return session, true
+}
+
+// owner returns the caller's tenant and user. Without auth every caller
+// shares the nil tenant and user.
+func owner(c *gin.Context) (uuid.UUID, uuid.UUID) {
+	if v, exists := c.Get("task_context"); exists {
+		if taskContext, ok := v.(*agents.TaskContext); ok && taskContext != nil {
+			return taskContext.TenantID, taskContext.UserID
+		}
+	}
+	return uuid.Nil, uuid.Nil
+}
@@ -0,0 +1,112 @@
+package consultation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Phase is a stage of a consultation
+type Phase string
+
+const (
+	// PhaseInitial clarifies the goal and constraints
+	PhaseInitial Phase = "initial"
+	// PhaseExploration compares candidate approaches
+	PhaseExploration Phase = "exploration"
+	// PhaseDeepDive details the chosen approach
+	PhaseDeepDive Phase = "deep-dive"
+	// PhaseComplete ends the consultation
+	PhaseComplete Phase = "complete"
+)
+
+var (
+	// ErrUnknownPhase is returned for phases outside the state machine
+	ErrUnknownPhase = errors.New("unknown consultation phase")
+	// ErrInvalidTransition is returned when a phase can't follow the current one
+	ErrInvalidTransition = errors.New("invalid phase transition")
+)
+
+// transitions lists the phases reachable from each phase. Consultations
+// move forward one phase at a time and may step back to revisit the
+// previous one.
+var transitions = map[Phase][]Phase{
+	PhaseInitial:     {PhaseExploration},
+	PhaseExploration: {PhaseDeepDive, PhaseInitial},
+	PhaseDeepDive:    {PhaseComplete, PhaseExploration},
+	PhaseComplete:    nil,
+}
+
+// ParsePhase validates a phase name, defaulting to PhaseInitial
+func ParsePhase(s string) (Phase, error) {
+	if s == "" {
+		return PhaseInitial, nil
+	}
+	p := Phase(s)
+	if _, ok := transitions[p]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownPhase, s)
+	}
+	return p, nil
+}
+
+// CanTransition reports whether a consultation in from may move to to
+func CanTransition(from, to Phase) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Strategy is how the consultant behaves during a phase
+type Strategy struct {
+	// Prompt is the system prompt instruction for the phase
+	Prompt string
+	// MinTurns is how many exchanges the phase usually needs before the
+	// engine recommends moving on without a signal from the model
+	MinTurns int
+	// Next is the phase recommended once this one is done
+	Next Phase
+}
+
+const basePrompt = "You are a senior technical consultant helping a user plan a software project. "
+
+var strategies = map[Phase]Strategy{
+	PhaseInitial: {
+		Prompt:   basePrompt + "Clarify the goal, ask the most important open questions and outline a first approach.",
+		MinTurns: 2,
+		Next:     PhaseExploration,
+	},
+	PhaseExploration: {
+		Prompt:   basePrompt + "Explore alternative approaches, comparing their trade-offs, risks and costs.",
+		MinTurns: 3,
+		Next:     PhaseDeepDive,
+	},
+	PhaseDeepDive: {
+		Prompt:   basePrompt + "Go deep on the chosen approach: architecture, components, data model and delivery plan.",
+		MinTurns: 3,
+		Next:     PhaseComplete,
+	},
+	PhaseComplete: {
+		Prompt: basePrompt + "The consultation is complete. Summarise the agreed plan and answer follow-up questions briefly.",
+	},
+}
+
+// StrategyFor returns the strategy for a phase
+func StrategyFor(p Phase) (Strategy, error) {
+	s, ok := strategies[p]
+	if !ok {
+		return Strategy{}, fmt.Errorf("%w: %q", ErrUnknownPhase, p)
+	}
+	return s, nil
+}
+
+// Prompt returns the system prompt for a phase name, defaulting to the
+// initial phase
+func Prompt(phase string) (string, error) {
+	p, err := ParsePhase(phase)
+	if err != nil {
+		return "", err
+	}
+	return strategies[p].Prompt, nil
+}
@@ -0,0 +1,191 @@
+package consultation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned for unknown sessions and sessions owned
+// by another tenant or user
+var ErrSessionNotFound = errors.New("consultation not found")
+
+// Store persists consultations. Lookups are scoped to the owning tenant
+// and user.
+type Store interface {
+	Save(ctx context.Context, s *Session) error
+	Get(ctx context.Context, tenantID, userID, id uuid.UUID) (*Session, error)
+	// List returns the most recently updated sessions first
+	List(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*Session, error)
+}
+
+// MemoryStore keeps consultations in memory
+type MemoryStore struct {
+	sessions map[uuid.UUID]*Session
+	mu       sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[uuid.UUID]*Session)}
+}
+
+// Save stores a copy of s
+func (m *MemoryStore) Save(ctx context.Context, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.ID] = copySession(s)
+	return nil
+}
+
+// Get returns the session if the tenant and user own it
+func (m *MemoryStore) Get(ctx context.Context, tenantID, userID, id uuid.UUID) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok || s.TenantID != tenantID || s.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+	return copySession(s), nil
+}
+
+// List returns the user's sessions, most recently updated first
+func (m *MemoryStore) List(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*Session
+	for _, s := range m.sessions {
+		if s.TenantID == tenantID && s.UserID == userID {
+			out = append(out, copySession(s))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func copySession(s *Session) *Session {
+	copied := *s
+	copied.Turns = append([]Turn(nil), s.Turns...)
+	copied.Transitions = append([]Transition(nil), s.Transitions...)
+	if s.Recommendation != nil {
+		rec := *s.Recommendation
+		copied.Recommendation = &rec
+	}
+	return &copied
+}
+
+// PostgresStore keeps consultations in the consultation_sessions table,
+// with turns, transitions and the recommendation as JSONB
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a Postgres-backed store
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Save upserts s
+func (p *PostgresStore) Save(ctx context.Context, s *Session) error {
+	turns, err := json.Marshal(s.Turns)
+	if err != nil {
+		return fmt.Errorf("failed to encode turns: %w", err)
+	}
+	transitions, err := json.Marshal(s.Transitions)
+	if err != nil {
+		return fmt.Errorf("failed to encode transitions: %w", err)
+	}
+	recommendation, err := json.Marshal(s.Recommendation)
+	if err != nil {
+		return fmt.Errorf("failed to encode recommendation: %w", err)
+	}
+
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO consultation_sessions
+			(id, tenant_id, user_id, topic, context, phase, turns, transitions, recommendation, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			phase = EXCLUDED.phase,
+			turns = EXCLUDED.turns,
+			transitions = EXCLUDED.transitions,
+			recommendation = EXCLUDED.recommendation,
+			updated_at = EXCLUDED.updated_at`,
+		s.ID, s.TenantID, s.UserID, s.Topic, s.Context, s.Phase, turns, transitions, recommendation, s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save consultation: %w", err)
+	}
+	return nil
+}
+
+const sessionColumns = "id, tenant_id, user_id, topic, context, phase, turns, transitions, recommendation, created_at, updated_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row scanner) (*Session, error) {
+	var s Session
+	var turns, transitions, recommendation []byte
+	if err := row.Scan(&s.ID, &s.TenantID, &s.UserID, &s.Topic, &s.Context, &s.Phase,
+		&turns, &transitions, &recommendation, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(turns, &s.Turns); err != nil {
+		return nil, fmt.Errorf("failed to decode turns: %w", err)
+	}
+	if err := json.Unmarshal(transitions, &s.Transitions); err != nil {
+		return nil, fmt.Errorf("failed to decode transitions: %w", err)
+	}
+	if err := json.Unmarshal(recommendation, &s.Recommendation); err != nil {
+		return nil, fmt.Errorf("failed to decode recommendation: %w", err)
+	}
+	return &s, nil
+}
+
+// Get returns the session if the tenant and user own it
+func (p *PostgresStore) Get(ctx context.Context, tenantID, userID, id uuid.UUID) (*Session, error) {
+	row := p.db.QueryRowContext(ctx, `
+		SELECT `+sessionColumns+` FROM consultation_sessions
+		WHERE id = $1 AND tenant_id = $2 AND user_id = $3`, id, tenantID, userID)
+	s, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	return s, nil
+}
+
+// List returns the user's sessions, most recently updated first
+func (p *PostgresStore) List(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*Session, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+sessionColumns+` FROM consultation_sessions
+		WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY updated_at DESC
+		LIMIT $3`, tenantID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consultations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consultation: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
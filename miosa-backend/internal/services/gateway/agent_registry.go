@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/plugins"
+	"go.uber.org/zap"
+)
+
+// AgentRegistryHandlers hot-registers marketplace agents from their
+// manifests, without restarting the gateway
+type AgentRegistryHandlers struct {
+	manager *plugins.Manager
+	store   plugins.ManifestStore
+	audit   *audit.Log
+	logger  *zap.Logger
+}
+
+// NewAgentRegistryHandlers creates new agent registry handlers
+func NewAgentRegistryHandlers(manager *plugins.Manager, store plugins.ManifestStore, auditLog *audit.Log, logger *zap.Logger) *AgentRegistryHandlers {
+	return &AgentRegistryHandlers{
+		manager: manager,
+		store:   store,
+		audit:   auditLog,
+		logger:  logger,
+	}
+}
+
+// RegisterAgent handles POST /api/agents/register
+func (h *AgentRegistryHandlers) RegisterAgent(c *gin.Context) {
+	var manifest plugins.AgentManifest
+	if err := c.ShouldBindJSON(&manifest); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	if err := manifest.Validate(); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	if agents.IsRegistered(manifest.Type) {
+		apierror.Abort(c, apierror.New(apierror.CategoryConflict, "Agent type already registered"))
+		return
+	}
+
+	_, err := h.manager.RegisterManifest(c.Request.Context(), h.store, &manifest)
+	h.record(c, audit.ActionAgentRegister, manifest.Type, err)
+	if err != nil {
+		// The plugin's endpoint could not be reached or described
+		err = apierror.Provider("", err)
+		h.logger.Warn("Agent registration failed",
+			apierror.Fields(err,
+				zap.String("type", string(manifest.Type)),
+				zap.String("endpoint", manifest.Endpoint))...)
+		apierror.Abort(c, err)
+		return
+	}
+
+	h.logger.Info("Agent registered",
+		zap.String("type", string(manifest.Type)),
+		zap.String("endpoint", manifest.Endpoint))
+	c.JSON(http.StatusCreated, gin.H{"agent": manifest.Redacted()})
+}
+
+// ListAgents handles GET /api/agents/registered
+func (h *AgentRegistryHandlers) ListAgents(c *gin.Context) {
+	manifests, err := h.store.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list agent manifests", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to list agents"))
+		return
+	}
+
+	health := h.manager.HealthCheck(c.Request.Context())
+	out := make([]gin.H, 0, len(manifests))
+	for _, m := range manifests {
+		out = append(out, gin.H{"manifest": m.Redacted(), "healthy": health[m.Type]})
+	}
+	c.JSON(http.StatusOK, gin.H{"agents": out})
+}
+
+// DeregisterAgent handles DELETE /api/agents/:type
+func (h *AgentRegistryHandlers) DeregisterAgent(c *gin.Context) {
+	agentType := agents.AgentType(c.Param("type"))
+
+	err := h.manager.DeregisterManifest(c.Request.Context(), h.store, agentType)
+	if errors.Is(err, plugins.ErrNotPlugin) {
+		apierror.Abort(c, apierror.New(apierror.CategoryNotFound, "Agent not found among registered plugins"))
+		return
+	}
+	h.record(c, audit.ActionAgentDeregister, agentType, err)
+	if err != nil {
+		h.logger.Error("Failed to deregister agent", apierror.Fields(err, zap.String("type", string(agentType)))...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to deregister agent"))
+		return
+	}
+
+	h.logger.Info("Agent deregistered", zap.String("type", string(agentType)))
+	c.JSON(http.StatusOK, gin.H{"deregistered": agentType})
+}
+
+func (h *AgentRegistryHandlers) record(c *gin.Context, action audit.Action, agentType agents.AgentType, err error) {
+	var taskContext *agents.TaskContext
+	if ctx, exists := c.Get("task_context"); exists {
+		taskContext, _ = ctx.(*agents.TaskContext)
+	}
+	event := audit.FromTaskContext(audit.Event{
+		Action:   action,
+		Resource: c.Request.URL.Path,
+		Status:   audit.StatusSuccess,
+		Metadata: map[string]string{"agent_type": string(agentType)},
+	}, taskContext)
+	if err != nil {
+		event.Status = audit.StatusFailure
+	}
+	h.audit.Record(c.Request.Context(), event)
+}
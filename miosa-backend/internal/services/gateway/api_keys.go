@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// APIKeyHandlers manages API keys for the authenticated tenant
+type APIKeyHandlers struct {
+	store  *middleware.APIKeyStore
+	logger *zap.Logger
+}
+
+// NewAPIKeyHandlers creates new API key handlers
+func NewAPIKeyHandlers(store *middleware.APIKeyStore, logger *zap.Logger) *APIKeyHandlers {
+	return &APIKeyHandlers{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	Scopes           []string `json:"scopes" binding:"required"`
+	RateLimitPerHour int      `json:"rate_limit_per_hour"`
+	ExpiresInDays    int      `json:"expires_in_days"`
+}
+
+// CreateAPIKeyResponse includes the plaintext key, which is only shown once
+type CreateAPIKeyResponse struct {
+	Key    string             `json:"key"`
+	APIKey *middleware.APIKey `json:"api_key"`
+}
+
+// CreateKey handles POST /api/keys. A key may only be granted scopes the
+// caller holds itself.
+func (h *APIKeyHandlers) CreateKey(c *gin.Context) {
+	taskContext, ok := h.taskContext(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	granted := middleware.ScopesFromContext(c)
+	for _, scope := range req.Scopes {
+		if !middleware.HasScope(granted, scope) {
+			apierror.Abort(c, apierror.Newf(apierror.CategoryForbidden, "Cannot grant scope %s", scope))
+			return
+		}
+	}
+
+	key := &middleware.APIKey{
+		TenantID:         taskContext.TenantID,
+		WorkspaceID:      taskContext.WorkspaceID,
+		CreatedBy:        taskContext.UserID,
+		Name:             req.Name,
+		Scopes:           req.Scopes,
+		RateLimitPerHour: req.RateLimitPerHour,
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expires
+	}
+
+	plaintext, err := h.store.Create(c.Request.Context(), key)
+	if err != nil {
+		h.logger.Error("Failed to create API key", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+
+	h.logger.Info("API key created",
+		zap.String("key_id", key.ID.String()),
+		zap.String("tenant_id", key.TenantID.String()),
+		zap.Strings("scopes", key.Scopes))
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{Key: plaintext, APIKey: key})
+}
+
+// ListKeys handles GET /api/keys
+func (h *APIKeyHandlers) ListKeys(c *gin.Context) {
+	taskContext, ok := h.taskContext(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.store.List(c.Request.Context(), taskContext.TenantID)
+	if err != nil {
+		h.logger.Error("Failed to list API keys", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to list API keys"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// RevokeKey handles DELETE /api/keys/:id
+func (h *APIKeyHandlers) RevokeKey(c *gin.Context) {
+	taskContext, ok := h.taskContext(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.New(apierror.CategoryValidation, "Invalid key ID"))
+		return
+	}
+
+	if err := h.store.Revoke(c.Request.Context(), taskContext.TenantID, id); err != nil {
+		if errors.Is(err, middleware.ErrAPIKeyNotFound) {
+			apierror.Abort(c, apierror.New(apierror.CategoryNotFound, "API key not found"))
+			return
+		}
+		h.logger.Error("Failed to revoke API key", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to revoke API key"))
+		return
+	}
+
+	h.logger.Info("API key revoked", zap.String("key_id", id.String()))
+	c.JSON(http.StatusOK, gin.H{"revoked": id})
+}
+
+// taskContext returns the authenticated context, writing a 401 if missing
+func (h *APIKeyHandlers) taskContext(c *gin.Context) (*agents.TaskContext, bool) {
+	if ctx, exists := c.Get("task_context"); exists {
+		if taskContext, ok := ctx.(*agents.TaskContext); ok {
+			return taskContext, true
+		}
+	}
+	apierror.Abort(c, apierror.New(apierror.CategoryUnauthorized, "Authentication required"))
+	return nil, false
+}
@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/middleware"
+)
+
+func newKeyRouter(t *testing.T, scopes []string) (*gin.Engine, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gin.SetMode(gin.TestMode)
+	h := NewAPIKeyHandlers(middleware.NewAPIKeyStore(db), zap.NewNop())
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("task_context", &agents.TaskContext{TenantID: uuid.New(), UserID: uuid.New()})
+		c.Set("scopes", scopes)
+	})
+	r.POST("/api/keys", h.CreateKey)
+	return r, mock
+}
+
+func TestAPIKeyHandlers_CreateKeyRejectsScopesTheCallerLacks(t *testing.T) {
+	r, mock := newKeyRouter(t, []string{middleware.ScopeKeysManage})
+
+	w := serveChat(t, r, http.MethodPost, "/api/keys", CreateAPIKeyRequest{
+		Name:   "escalate",
+		Scopes: []string{middleware.ScopeKeysManage, middleware.ScopeAdminAll},
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), middleware.ScopeAdminAll)
+	assert.NoError(t, mock.ExpectationsWereMet(), "nothing is stored")
+}
+
+func TestAPIKeyHandlers_CreateKeyWithHeldScopes(t *testing.T) {
+	r, mock := newKeyRouter(t, []string{middleware.ScopeKeysManage, middleware.ScopeAgentsRead})
+	mock.ExpectQuery("INSERT INTO api_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(uuid.New(), time.Now()))
+
+	w := serveChat(t, r, http.MethodPost, "/api/keys", CreateAPIKeyRequest{
+		Name:   "ci",
+		Scopes: []string{middleware.ScopeAgentsRead},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), middleware.APIKeyPrefix)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyHandlers_AdminMayGrantAnyScope(t *testing.T) {
+	r, mock := newKeyRouter(t, []string{middleware.ScopeAdminAll})
+	mock.ExpectQuery("INSERT INTO api_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(uuid.New(), time.Now()))
+
+	w := serveChat(t, r, http.MethodPost, "/api/keys", CreateAPIKeyRequest{
+		Name:   "ops",
+		Scopes: []string{middleware.ScopeOrchestrateExecute, middleware.ScopeKeysManage},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
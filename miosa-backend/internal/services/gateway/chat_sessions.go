@@ -0,0 +1,306 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"go.uber.org/zap"
+)
+
+// defaultChatModel is used when a chat request doesn't name a model
+const defaultChatModel = "llama-3.1-8b-instant"
+
+// DefaultChatWindowTokens bounds the history sent with each completion
+const DefaultChatWindowTokens = 3000
+
+// chatCompleter is the part of the Groq client sessions use
+type chatCompleter interface {
+	ChatCompletion(ctx context.Context, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error)
+}
+
+// ChatSessionHandlers manages conversational chat sessions
+type ChatSessionHandlers struct {
+	completer    chatCompleter
+	store        ChatSessionStore
+	logger       *zap.Logger
+	windowTokens int
+}
+
+// NewChatSessionHandlers creates chat session handlers. groqClient may be
+// nil, in which case sessions can be managed but not answered.
+func NewChatSessionHandlers(groqClient *groq.Client, store ChatSessionStore, logger *zap.Logger) *ChatSessionHandlers {
+	h := &ChatSessionHandlers{
+		store:        store,
+		logger:       logger,
+		windowTokens: DefaultChatWindowTokens,
+	}
+	if groqClient != nil {
+		h.completer = groqClient
+	}
+	return h
+}
+
+// CreateChatSessionRequest is the body of POST /api/chat/sessions
+type CreateChatSessionRequest struct {
+	Title        string `json:"title"`
+	Model        string `json:"model"`
+	SystemPrompt string `json:"system_prompt"`
+}
+
+// ChatSessionMessageRequest is the body of POST /api/chat/sessions/:id/messages
+type ChatSessionMessageRequest struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// CreateSession handles POST /api/chat/sessions
+func (h *ChatSessionHandlers) CreateSession(c *gin.Context) {
+	var req CreateChatSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+
+	tenantID, userID := chatOwner(c)
+	now := time.Now()
+	session := &ChatSession{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		UserID:       userID,
+		Title:        req.Title,
+		Model:        req.Model,
+		SystemPrompt: req.SystemPrompt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if session.Model == "" {
+		session.Model = defaultChatModel
+	}
+
+	if err := h.store.CreateSession(c.Request.Context(), session); err != nil {
+		h.logger.Error("Failed to create chat session", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to create session"))
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// ListSessions handles GET /api/chat/sessions
+func (h *ChatSessionHandlers) ListSessions(c *gin.Context) {
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			apierror.Abort(c, apierror.New(apierror.CategoryValidation, "Invalid limit"))
+			return
+		}
+		limit = n
+	}
+
+	tenantID, userID := chatOwner(c)
+	sessions, err := h.store.ListSessions(c.Request.Context(), tenantID, userID, limit)
+	if err != nil {
+		h.logger.Error("Failed to list chat sessions", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to list sessions"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// GetSession handles GET /api/chat/sessions/:id, returning the history
+func (h *ChatSessionHandlers) GetSession(c *gin.Context) {
+	session, ok := h.session(c)
+	if !ok {
+		return
+	}
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	messages, err := h.store.Messages(c.Request.Context(), session.ID, limit)
+	if err != nil {
+		h.logger.Error("Failed to load chat messages", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to load messages"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session": session, "messages": messages})
+}
+
+// DeleteSession handles DELETE /api/chat/sessions/:id
+func (h *ChatSessionHandlers) DeleteSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.New(apierror.CategoryValidation, "Invalid session ID"))
+		return
+	}
+	tenantID, userID := chatOwner(c)
+	err = h.store.DeleteSession(c.Request.Context(), tenantID, userID, id)
+	if errors.Is(err, ErrChatSessionNotFound) {
+		apierror.Abort(c, apierror.New(apierror.CategoryNotFound, "Session not found"))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete chat session", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to delete session"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SendMessage handles POST /api/chat/sessions/:id/messages. The reply is
+// generated with as much recent history as fits the context window, and
+// both turns are stored only once the completion succeeds.
+func (h *ChatSessionHandlers) SendMessage(c *gin.Context) {
+	session, ok := h.session(c)
+	if !ok {
+		return
+	}
+	var req ChatSessionMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	if h.completer == nil {
+		apierror.Abort(c, apierror.New(apierror.CategoryUnavailable, "Chat service not available"))
+		return
+	}
+
+	history, err := h.store.Messages(c.Request.Context(), session.ID, 0)
+	if err != nil {
+		h.logger.Error("Failed to load chat messages", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to load messages"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	resp, err := h.completer.ChatCompletion(ctx, groq.ChatCompletionRequest{
+		Model:       groq.ChatModel(session.Model),
+		Messages:    buildChatWindow(session.SystemPrompt, history, req.Message, h.windowTokens),
+		MaxTokens:   1000,
+		Temperature: 0.7,
+	})
+	if err != nil {
+		err = apierror.Provider("Failed to get response", err)
+		h.logger.Error("Chat completion failed", apierror.Fields(err, zap.String("session_id", session.ID.String()))...)
+		apierror.Abort(c, err)
+		return
+	}
+	if len(resp.Choices) == 0 {
+		apierror.Abort(c, apierror.New(apierror.CategoryProviderUnavailable, "No response from model"))
+		return
+	}
+
+	now := time.Now()
+	userMsg := &ChatMessage{
+		ID:        uuid.New(),
+		SessionID: session.ID,
+		Role:      ChatRoleUser,
+		Content:   req.Message,
+		Tokens:    resp.Usage.PromptTokens,
+		CreatedAt: now,
+	}
+	reply := &ChatMessage{
+		ID:        uuid.New(),
+		SessionID: session.ID,
+		Role:      ChatRoleAssistant,
+		Content:   resp.Choices[0].Message.Content,
+		Model:     session.Model,
+		Tokens:    resp.Usage.CompletionTokens,
+		// Keep the reply strictly after the question when ordering by time
+		CreatedAt: now.Add(time.Microsecond),
+	}
+	session.UpdatedAt = reply.CreatedAt
+	if session.Title == "" {
+		session.Title = chatTitle(req.Message)
+	}
+	if err := h.store.AppendMessages(c.Request.Context(), session, userMsg, reply); err != nil {
+		h.logger.Error("Failed to store chat messages", apierror.Fields(err, zap.String("session_id", session.ID.String()))...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to store messages"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"session": session, "message": reply})
+}
+
+// session loads the :id session owned by the caller, writing the error
+// response when it can't
+func (h *ChatSessionHandlers) session(c *gin.Context) (*ChatSession, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.New(apierror.CategoryValidation, "Invalid session ID"))
+		return nil, false
+	}
+	tenantID, userID := chatOwner(c)
+	session, err := h.store.GetSession(c.Request.Context(), tenantID, userID, id)
+	if errors.Is(err, ErrChatSessionNotFound) {
+		apierror.Abort(c, apierror.New(apierror.CategoryNotFound, "Session not found"))
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to load chat session", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to load session"))
+		return nil, false
+	}
+	return session, true
+}
+
+// chatOwner returns the caller's tenant and user. Without auth every
+// caller shares the nil tenant and user.
+func chatOwner(c *gin.Context) (uuid.UUID, uuid.UUID) {
+	if v, exists := c.Get("task_context"); exists {
+		if taskContext, ok := v.(*agents.TaskContext); ok && taskContext != nil {
+			return taskContext.TenantID, taskContext.UserID
+		}
+	}
+	return uuid.Nil, uuid.Nil
+}
+
+// buildChatWindow returns the system prompt, as much of the most recent
+// history as fits in budget tokens, and the new message
+func buildChatWindow(systemPrompt string, history []*ChatMessage, message string, budget int) []groq.ChatCompletionMessage {
+	budget -= chatTokens(systemPrompt) + chatTokens(message)
+
+	start := len(history)
+	for start > 0 {
+		cost := chatTokens(history[start-1].Content)
+		if cost > budget {
+			break
+		}
+		budget -= cost
+		start--
+	}
+
+	messages := make([]groq.ChatCompletionMessage, 0, len(history)-start+2)
+	if systemPrompt != "" {
+		messages = append(messages, groq.ChatCompletionMessage{Role: ChatRoleSystem, Content: systemPrompt})
+	}
+	for _, m := range history[start:] {
+		messages = append(messages, groq.ChatCompletionMessage{Role: groq.Role(m.Role), Content: m.Content})
+	}
+	return append(messages, groq.ChatCompletionMessage{Role: ChatRoleUser, Content: message})
+}
+
+// chatTokens approximates token count at four characters per token
+func chatTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// chatTitle derives a session title from its first message
+func chatTitle(message string) string {
+	const max = 60
+	runes := []rune(message)
+	if len(runes) <= max {
+		return message
+	}
+	return string(runes[:max]) + "…"
+}
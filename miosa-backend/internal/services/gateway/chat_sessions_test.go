@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+type fakeCompleter struct {
+	requests []groq.ChatCompletionRequest
+	reply    string
+}
+
+func (f *fakeCompleter) ChatCompletion(ctx context.Context, req groq.ChatCompletionRequest) (groq.ChatCompletionResponse, error) {
+	f.requests = append(f.requests, req)
+	var resp groq.ChatCompletionResponse
+	resp.Choices = append(resp.Choices, groq.ChatCompletionChoice{
+		Message: groq.ChatCompletionMessage{Role: ChatRoleAssistant, Content: f.reply},
+	})
+	return resp, nil
+}
+
+func newChatRouter(h *ChatSessionHandlers, userID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("task_context", &agents.TaskContext{UserID: userID})
+	})
+	r.POST("/chat/sessions", h.CreateSession)
+	r.GET("/chat/sessions", h.ListSessions)
+	r.GET("/chat/sessions/:id", h.GetSession)
+	r.POST("/chat/sessions/:id/messages", h.SendMessage)
+	r.DELETE("/chat/sessions/:id", h.DeleteSession)
+	return r
+}
+
+func serveChat(t *testing.T, r *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	return w
+}
+
+func TestChatSessions_Conversation(t *testing.T) {
+	store := NewMemoryChatSessionStore()
+	completer := &fakeCompleter{reply: "hello there"}
+	h := &ChatSessionHandlers{completer: completer, store: store, logger: zap.NewNop(), windowTokens: DefaultChatWindowTokens}
+	r := newChatRouter(h, uuid.New())
+
+	w := serveChat(t, r, http.MethodPost, "/chat/sessions", CreateChatSessionRequest{SystemPrompt: "be brief"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var session ChatSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	assert.Equal(t, defaultChatModel, session.Model)
+
+	path := "/chat/sessions/" + session.ID.String()
+	w = serveChat(t, r, http.MethodPost, path+"/messages", ChatSessionMessageRequest{Message: "hi"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serveChat(t, r, http.MethodPost, path+"/messages", ChatSessionMessageRequest{Message: "again"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	require.Len(t, completer.requests, 2)
+	second := completer.requests[1].Messages
+	require.Len(t, second, 4, "system prompt, first exchange and the new message")
+	assert.Equal(t, "be brief", second[0].Content)
+	assert.Equal(t, "hi", second[1].Content)
+	assert.Equal(t, "hello there", second[2].Content)
+	assert.Equal(t, "again", second[3].Content)
+
+	w = serveChat(t, r, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var history struct {
+		Session  ChatSession    `json:"session"`
+		Messages []*ChatMessage `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Equal(t, "hi", history.Session.Title)
+	assert.Equal(t, 4, history.Session.MessageCount)
+	assert.Len(t, history.Messages, 4)
+
+	w = serveChat(t, r, http.MethodGet, "/chat/sessions", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), session.ID.String())
+
+	w = serveChat(t, r, http.MethodDelete, path, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = serveChat(t, r, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestChatSessions_ScopedToOwner(t *testing.T) {
+	store := NewMemoryChatSessionStore()
+	h := &ChatSessionHandlers{completer: &fakeCompleter{}, store: store, logger: zap.NewNop(), windowTokens: DefaultChatWindowTokens}
+
+	w := serveChat(t, newChatRouter(h, uuid.New()), http.MethodPost, "/chat/sessions", CreateChatSessionRequest{Title: "mine"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var session ChatSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+
+	other := newChatRouter(h, uuid.New())
+	path := "/chat/sessions/" + session.ID.String()
+	assert.Equal(t, http.StatusNotFound, serveChat(t, other, http.MethodGet, path, nil).Code)
+	assert.Equal(t, http.StatusNotFound, serveChat(t, other, http.MethodPost, path+"/messages", ChatSessionMessageRequest{Message: "hi"}).Code)
+	assert.Equal(t, http.StatusNotFound, serveChat(t, other, http.MethodDelete, path, nil).Code)
+	assert.NotContains(t, serveChat(t, other, http.MethodGet, "/chat/sessions", nil).Body.String(), session.ID.String())
+}
+
+func TestBuildChatWindow_KeepsLatestWithinBudget(t *testing.T) {
+	long := strings.Repeat("x", 40) // 10 tokens
+	history := []*ChatMessage{
+		{Role: ChatRoleUser, Content: "oldest " + long},
+		{Role: ChatRoleAssistant, Content: long},
+		{Role: ChatRoleUser, Content: long},
+	}
+
+	window := buildChatWindow("", history, "next", 25)
+	require.Len(t, window, 3)
+	assert.Equal(t, long, window[0].Content)
+	assert.Equal(t, "next", window[2].Content)
+
+	window = buildChatWindow("sys", history, "next", 1000)
+	assert.Len(t, window, 5)
+	assert.Equal(t, groq.Role(ChatRoleSystem), window[0].Role)
+}
@@ -0,0 +1,459 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sormind/OSA/miosa-backend/internal/redisconn"
+)
+
+// ErrChatSessionNotFound is returned for unknown sessions and sessions
+// owned by another tenant or user
+var ErrChatSessionNotFound = errors.New("chat session not found")
+
+// Chat message roles
+const (
+	ChatRoleSystem    = "system"
+	ChatRoleUser      = "user"
+	ChatRoleAssistant = "assistant"
+)
+
+// ChatSession is a conversation between a user and the chat model
+type ChatSession struct {
+	ID           uuid.UUID `json:"id"`
+	TenantID     uuid.UUID `json:"tenant_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Title        string    `json:"title,omitempty"`
+	Model        string    `json:"model"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ChatMessage is a single turn in a session
+type ChatMessage struct {
+	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	Tokens    int       `json:"tokens,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChatSessionStore persists sessions and their message history. Lookups
+// are scoped to the owning tenant and user.
+type ChatSessionStore interface {
+	CreateSession(ctx context.Context, s *ChatSession) error
+	GetSession(ctx context.Context, tenantID, userID, id uuid.UUID) (*ChatSession, error)
+	// ListSessions returns the most recently updated sessions first
+	ListSessions(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*ChatSession, error)
+	DeleteSession(ctx context.Context, tenantID, userID, id uuid.UUID) error
+	// AppendMessages adds messages to the session and saves its UpdatedAt
+	// and Title
+	AppendMessages(ctx context.Context, s *ChatSession, messages ...*ChatMessage) error
+	// Messages returns up to limit of the latest messages, oldest first
+	Messages(ctx context.Context, sessionID uuid.UUID, limit int) ([]*ChatMessage, error)
+}
+
+// MemoryChatSessionStore keeps sessions in memory, for tests and gateways
+// running without Redis or Postgres
+type MemoryChatSessionStore struct {
+	sessions map[uuid.UUID]*ChatSession
+	messages map[uuid.UUID][]*ChatMessage
+	mu       sync.RWMutex
+}
+
+// NewMemoryChatSessionStore creates an empty in-memory store
+func NewMemoryChatSessionStore() *MemoryChatSessionStore {
+	return &MemoryChatSessionStore{
+		sessions: make(map[uuid.UUID]*ChatSession),
+		messages: make(map[uuid.UUID][]*ChatMessage),
+	}
+}
+
+// CreateSession stores a copy of s
+func (m *MemoryChatSessionStore) CreateSession(ctx context.Context, s *ChatSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *s
+	m.sessions[s.ID] = &copied
+	return nil
+}
+
+// GetSession returns the session if the tenant and user own it
+func (m *MemoryChatSessionStore) GetSession(ctx context.Context, tenantID, userID, id uuid.UUID) (*ChatSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok || s.TenantID != tenantID || s.UserID != userID {
+		return nil, ErrChatSessionNotFound
+	}
+	copied := *s
+	return &copied, nil
+}
+
+// ListSessions returns the user's sessions, most recently updated first
+func (m *MemoryChatSessionStore) ListSessions(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*ChatSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*ChatSession
+	for _, s := range m.sessions {
+		if s.TenantID == tenantID && s.UserID == userID {
+			copied := *s
+			out = append(out, &copied)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// DeleteSession removes the session and its messages
+func (m *MemoryChatSessionStore) DeleteSession(ctx context.Context, tenantID, userID, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.TenantID != tenantID || s.UserID != userID {
+		return ErrChatSessionNotFound
+	}
+	delete(m.sessions, id)
+	delete(m.messages, id)
+	return nil
+}
+
+// AppendMessages adds messages to the session
+func (m *MemoryChatSessionStore) AppendMessages(ctx context.Context, s *ChatSession, messages ...*ChatMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.sessions[s.ID]
+	if !ok {
+		return ErrChatSessionNotFound
+	}
+	m.messages[s.ID] = append(m.messages[s.ID], messages...)
+	stored.MessageCount += len(messages)
+	stored.UpdatedAt = s.UpdatedAt
+	stored.Title = s.Title
+	s.MessageCount = stored.MessageCount
+	return nil
+}
+
+// Messages returns up to limit of the latest messages, oldest first
+func (m *MemoryChatSessionStore) Messages(ctx context.Context, sessionID uuid.UUID, limit int) ([]*ChatMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	all := m.messages[sessionID]
+	if limit > 0 && len(all) > limit {
+		all = all[len(all)-limit:]
+	}
+	return append([]*ChatMessage(nil), all...), nil
+}
+
+// RedisChatSessionStore keeps sessions as JSON under chat:session:<id>,
+// messages in the chat:messages:<id> list and a per-user index sorted by
+// last update. Everything expires ttl after the session was last used.
+type RedisChatSessionStore struct {
+	client      redis.UniversalClient
+	ttl         time.Duration
+	maxMessages int64
+}
+
+// NewRedisChatSessionStore creates a Redis-backed store. Sessions idle for
+// ttl expire and each keeps at most maxMessages messages.
+func NewRedisChatSessionStore(client redis.UniversalClient, ttl time.Duration, maxMessages int) *RedisChatSessionStore {
+	return &RedisChatSessionStore{client: client, ttl: ttl, maxMessages: int64(maxMessages)}
+}
+
+func chatSessionKey(id uuid.UUID) string  { return "chat:session:" + id.String() }
+func chatMessagesKey(id uuid.UUID) string { return "chat:messages:" + id.String() }
+func chatIndexKey(tenantID, userID uuid.UUID) string {
+	return fmt.Sprintf("chat:sessions:%s:%s", tenantID, userID)
+}
+
+// CreateSession stores s and adds it to the owner's index
+func (r *RedisChatSessionStore) CreateSession(ctx context.Context, s *ChatSession) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, chatSessionKey(s.ID), data, r.ttl)
+	pipe.ZAdd(ctx, chatIndexKey(s.TenantID, s.UserID), redis.Z{Score: float64(s.UpdatedAt.Unix()), Member: s.ID.String()})
+	pipe.Expire(ctx, chatIndexKey(s.TenantID, s.UserID), r.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns the session if the tenant and user own it
+func (r *RedisChatSessionStore) GetSession(ctx context.Context, tenantID, userID, id uuid.UUID) (*ChatSession, error) {
+	data, err := r.client.Get(ctx, chatSessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrChatSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	var s ChatSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	if s.TenantID != tenantID || s.UserID != userID {
+		return nil, ErrChatSessionNotFound
+	}
+	return &s, nil
+}
+
+// ListSessions returns the user's sessions, most recently updated first.
+// Expired sessions are dropped from the index as they're found.
+func (r *RedisChatSessionStore) ListSessions(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*ChatSession, error) {
+	index := chatIndexKey(tenantID, userID)
+	ids, err := r.client.ZRevRange(ctx, index, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	out := make([]*ChatSession, 0, len(ids))
+	for _, raw := range ids {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			continue
+		}
+		s, err := r.GetSession(ctx, tenantID, userID, id)
+		if errors.Is(err, ErrChatSessionNotFound) {
+			r.client.ZRem(ctx, index, raw)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// DeleteSession removes the session, its messages and its index entry
+func (r *RedisChatSessionStore) DeleteSession(ctx context.Context, tenantID, userID, id uuid.UUID) error {
+	if _, err := r.GetSession(ctx, tenantID, userID, id); err != nil {
+		return err
+	}
+	pipe := r.client.TxPipeline()
+	redisconn.Del(ctx, pipe, chatSessionKey(id), chatMessagesKey(id))
+	pipe.ZRem(ctx, chatIndexKey(tenantID, userID), id.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// AppendMessages pushes messages, trims history to maxMessages and
+// refreshes the session's expiry
+func (r *RedisChatSessionStore) AppendMessages(ctx context.Context, s *ChatSession, messages ...*ChatMessage) error {
+	values := make([]interface{}, 0, len(messages))
+	for _, m := range messages {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		values = append(values, data)
+	}
+	s.MessageCount += len(messages)
+	session, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, chatMessagesKey(s.ID), values...)
+	if r.maxMessages > 0 {
+		pipe.LTrim(ctx, chatMessagesKey(s.ID), -r.maxMessages, -1)
+	}
+	pipe.Expire(ctx, chatMessagesKey(s.ID), r.ttl)
+	pipe.Set(ctx, chatSessionKey(s.ID), session, r.ttl)
+	pipe.ZAdd(ctx, chatIndexKey(s.TenantID, s.UserID), redis.Z{Score: float64(s.UpdatedAt.Unix()), Member: s.ID.String()})
+	pipe.Expire(ctx, chatIndexKey(s.TenantID, s.UserID), r.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append messages: %w", err)
+	}
+	return nil
+}
+
+// Messages returns up to limit of the latest messages, oldest first
+func (r *RedisChatSessionStore) Messages(ctx context.Context, sessionID uuid.UUID, limit int) ([]*ChatMessage, error) {
+	start := int64(0)
+	if limit > 0 {
+		start = -int64(limit)
+	}
+	raw, err := r.client.LRange(ctx, chatMessagesKey(sessionID), start, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	out := make([]*ChatMessage, 0, len(raw))
+	for _, item := range raw {
+		var m ChatMessage
+		if err := json.Unmarshal([]byte(item), &m); err != nil {
+			return nil, fmt.Errorf("failed to decode message: %w", err)
+		}
+		out = append(out, &m)
+	}
+	return out, nil
+}
+
+// PostgresChatSessionStore keeps sessions in chat_sessions and messages in
+// chat_messages
+type PostgresChatSessionStore struct {
+	db *sql.DB
+}
+
+// NewPostgresChatSessionStore creates a Postgres-backed store
+func NewPostgresChatSessionStore(db *sql.DB) *PostgresChatSessionStore {
+	return &PostgresChatSessionStore{db: db}
+}
+
+const chatSessionColumns = `id, tenant_id, user_id, title, model, system_prompt, message_count, created_at, updated_at`
+
+// CreateSession inserts s
+func (p *PostgresChatSessionStore) CreateSession(ctx context.Context, s *ChatSession) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO chat_sessions (`+chatSessionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		s.ID, s.TenantID, s.UserID, s.Title, s.Model, s.SystemPrompt, s.MessageCount, s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns the session if the tenant and user own it
+func (p *PostgresChatSessionStore) GetSession(ctx context.Context, tenantID, userID, id uuid.UUID) (*ChatSession, error) {
+	row := p.db.QueryRowContext(ctx, `
+		SELECT `+chatSessionColumns+` FROM chat_sessions
+		WHERE id = $1 AND tenant_id = $2 AND user_id = $3`, id, tenantID, userID)
+	s, err := scanChatSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChatSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return s, nil
+}
+
+// ListSessions returns the user's sessions, most recently updated first
+func (p *PostgresChatSessionStore) ListSessions(ctx context.Context, tenantID, userID uuid.UUID, limit int) ([]*ChatSession, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+chatSessionColumns+` FROM chat_sessions
+		WHERE tenant_id = $1 AND user_id = $2
+		ORDER BY updated_at DESC
+		LIMIT $3`, tenantID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*ChatSession
+	for rows.Next() {
+		s, err := scanChatSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSession removes the session; its messages cascade
+func (p *PostgresChatSessionStore) DeleteSession(ctx context.Context, tenantID, userID, id uuid.UUID) error {
+	res, err := p.db.ExecContext(ctx, `
+		DELETE FROM chat_sessions WHERE id = $1 AND tenant_id = $2 AND user_id = $3`, id, tenantID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrChatSessionNotFound
+	}
+	return nil
+}
+
+// AppendMessages inserts messages and updates the session in one transaction
+func (p *PostgresChatSessionStore) AppendMessages(ctx context.Context, s *ChatSession, messages ...*ChatMessage) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, m := range messages {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO chat_messages (id, session_id, role, content, model, tokens, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			m.ID, m.SessionID, m.Role, m.Content, m.Model, m.Tokens, m.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to append message: %w", err)
+		}
+	}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE chat_sessions SET message_count = message_count + $2, updated_at = $3, title = $4
+		WHERE id = $1
+		RETURNING message_count`, s.ID, len(messages), s.UpdatedAt, s.Title).Scan(&s.MessageCount)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit messages: %w", err)
+	}
+	return nil
+}
+
+// Messages returns up to limit of the latest messages, oldest first
+func (p *PostgresChatSessionStore) Messages(ctx context.Context, sessionID uuid.UUID, limit int) ([]*ChatMessage, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, session_id, role, content, model, tokens, created_at FROM (
+			SELECT * FROM chat_messages WHERE session_id = $1
+			ORDER BY created_at DESC LIMIT $2
+		) latest ORDER BY created_at`, sessionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*ChatMessage
+	for rows.Next() {
+		var m ChatMessage
+		if err := rows.Scan(&m.ID, &m.SessionID, &m.Role, &m.Content, &m.Model, &m.Tokens, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &m)
+	}
+	return out, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChatSession(row rowScanner) (*ChatSession, error) {
+	var s ChatSession
+	err := row.Scan(&s.ID, &s.TenantID, &s.UserID, &s.Title, &s.Model, &s.SystemPrompt, &s.MessageCount, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,358 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/services/consultation"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+	"golang.org/x/time/rate"
+)
+
+// Stream frame types. Clients send chat, consultation, cancel and ping
+// frames; the gateway answers with token, done, cancelled, error, pong and
+// heartbeat frames.
+const (
+	StreamFrameChat         = "chat"
+	StreamFrameConsultation = "consultation"
+	StreamFrameCancel       = "cancel"
+	StreamFramePing         = "ping"
+	StreamFramePong         = "pong"
+	StreamFrameHeartbeat    = "heartbeat"
+	StreamFrameToken        = "token"
+	StreamFrameDone         = "done"
+	StreamFrameCancelled    = "cancelled"
+	StreamFrameError        = "error"
+)
+
+// ChatStreamConfig configures the /ws/chat endpoint
+type ChatStreamConfig struct {
+	ChatModel         string
+	ConsultationModel string
+	MaxTokens         int
+	HeartbeatInterval time.Duration
+	// IdleTimeout closes connections that send nothing, pings included,
+	// for this long
+	IdleTimeout  time.Duration
+	WriteTimeout time.Duration
+	// RequestsPerSecond and Burst limit chat and consultation requests on
+	// each connection
+	RequestsPerSecond float64
+	Burst             int
+	// MaxStreams bounds concurrent streams on each connection
+	MaxStreams int
+}
+
+// DefaultChatStreamConfig returns default configuration
+func DefaultChatStreamConfig() *ChatStreamConfig {
+	return &ChatStreamConfig{
+		ChatModel:         defaultChatModel,
+		ConsultationModel: defaultChatModel,
+		MaxTokens:         1000,
+		HeartbeatInterval: 30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		WriteTimeout:      10 * time.Second,
+		RequestsPerSecond: 1,
+		Burst:             5,
+		MaxStreams:        3,
+	}
+}
+
+// StreamRequest is a frame sent by the client. ID correlates the frames
+// of a stream and is generated when a chat or consultation omits it.
+type StreamRequest struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Topic   string `json:"topic,omitempty"`
+	Context string `json:"context,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+}
+
+// StreamFrame is a frame sent by the gateway
+type StreamFrame struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Content string `json:"content,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Time    string `json:"time,omitempty"`
+}
+
+// tokenStreamer runs a streaming completion, calling onToken for each
+// content delta until the stream ends
+type tokenStreamer func(ctx context.Context, req groq.ChatCompletionRequest, onToken func(string) error) error
+
+// groqStreamer streams completions from the Groq API
+func groqStreamer(client *groq.Client) tokenStreamer {
+	return func(ctx context.Context, req groq.ChatCompletionRequest, onToken func(string) error) error {
+		stream, err := client.ChatCompletionStream(ctx, req)
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			for _, choice := range resp.Choices {
+				if choice.Delta.Content == "" {
+					continue
+				}
+				if err := onToken(choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// ChatStreamHandler streams chat and consultation completions over
+// WebSocket
+type ChatStreamHandler struct {
+	streamer tokenStreamer
+	config   *ChatStreamConfig
+	logger   *zap.Logger
+}
+
+// NewChatStreamHandler creates the /ws/chat handler. groqClient may be nil,
+// in which case connections are refused.
+func NewChatStreamHandler(groqClient *groq.Client, config *ChatStreamConfig, logger *zap.Logger) *ChatStreamHandler {
+	if config == nil {
+		config = DefaultChatStreamConfig()
+	}
+	h := &ChatStreamHandler{config: config, logger: logger}
+	if groqClient != nil {
+		h.streamer = groqStreamer(groqClient)
+	}
+	return h
+}
+
+// Handle upgrades GET /ws/chat to a WebSocket connection
+func (h *ChatStreamHandler) Handle(c *gin.Context) {
+	if h.streamer == nil {
+		apierror.Abort(c, apierror.New(apierror.CategoryUnavailable, "Chat service not available"))
+		return
+	}
+	// Origin and authentication are left to the gateway middleware, so
+	// the handshake accepts non-browser clients without an Origin header
+	websocket.Server{Handler: h.serve}.ServeHTTP(c.Writer, c.Request)
+}
+
+// streamConn is the state of one WebSocket connection
+type streamConn struct {
+	ws      *websocket.Conn
+	limiter *rate.Limiter
+	timeout time.Duration
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	streams map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// send writes a frame, serialising writes from the reader, heartbeat and
+// stream goroutines
+func (sc *streamConn) send(frame StreamFrame) error {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	sc.ws.SetWriteDeadline(time.Now().Add(sc.timeout))
+	return websocket.JSON.Send(sc.ws, frame)
+}
+
+// cancel stops the stream with the given ID, reporting whether it was
+// running
+func (sc *streamConn) cancel(id string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	stop, ok := sc.streams[id]
+	if ok {
+		stop()
+	}
+	return ok
+}
+
+func (h *ChatStreamHandler) serve(ws *websocket.Conn) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	sc := &streamConn{
+		ws:      ws,
+		limiter: rate.NewLimiter(rate.Limit(h.config.RequestsPerSecond), h.config.Burst),
+		timeout: h.config.WriteTimeout,
+		streams: make(map[string]context.CancelFunc),
+	}
+	defer func() {
+		cancel()
+		sc.wg.Wait()
+	}()
+
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		h.heartbeat(ctx, sc)
+	}()
+
+	for {
+		ws.SetReadDeadline(time.Now().Add(h.config.IdleTimeout))
+		var req StreamRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				sc.send(StreamFrame{Type: StreamFrameError, Error: "Invalid message"})
+				continue
+			}
+			if !errors.Is(err, io.EOF) {
+				h.logger.Debug("Chat stream connection closed", zap.Error(err))
+			}
+			return
+		}
+		h.dispatch(ctx, sc, req)
+	}
+}
+
+// dispatch handles one client frame
+func (h *ChatStreamHandler) dispatch(ctx context.Context, sc *streamConn, req StreamRequest) {
+	switch req.Type {
+	case StreamFramePing:
+		sc.send(StreamFrame{Type: StreamFramePong, ID: req.ID, Time: time.Now().Format(time.RFC3339)})
+
+	case StreamFrameCancel:
+		if !sc.cancel(req.ID) {
+			sc.send(StreamFrame{Type: StreamFrameError, ID: req.ID, Error: "No such stream"})
+		}
+
+	case StreamFrameChat, StreamFrameConsultation:
+		if req.ID == "" {
+			req.ID = uuid.New().String()
+		}
+		completion, err := h.completionRequest(req)
+		if err != nil {
+			sc.send(StreamFrame{Type: StreamFrameError, ID: req.ID, Error: err.Error()})
+			return
+		}
+		if !sc.limiter.Allow() {
+			sc.send(StreamFrame{Type: StreamFrameError, ID: req.ID, Error: "Rate limit exceeded"})
+			return
+		}
+
+		sc.mu.Lock()
+		if _, running := sc.streams[req.ID]; running {
+			sc.mu.Unlock()
+			sc.send(StreamFrame{Type: StreamFrameError, ID: req.ID, Error: "Stream already running"})
+			return
+		}
+		if len(sc.streams) >= h.config.MaxStreams {
+			sc.mu.Unlock()
+			sc.send(StreamFrame{Type: StreamFrameError, ID: req.ID, Error: "Too many concurrent streams"})
+			return
+		}
+		streamCtx, stop := context.WithCancel(ctx)
+		sc.streams[req.ID] = stop
+		sc.mu.Unlock()
+
+		sc.wg.Add(1)
+		go func() {
+			defer sc.wg.Done()
+			h.stream(streamCtx, sc, req.ID, completion)
+			sc.mu.Lock()
+			delete(sc.streams, req.ID)
+			sc.mu.Unlock()
+			stop()
+		}()
+
+	default:
+		sc.send(StreamFrame{Type: StreamFrameError, ID: req.ID, Error: "Unknown message type"})
+	}
+}
+
+// stream relays one completion to the client as token frames followed by
+// a done, cancelled or error frame
+func (h *ChatStreamHandler) stream(ctx context.Context, sc *streamConn, id string, req groq.ChatCompletionRequest) {
+	model := string(req.Model)
+	err := h.streamer(ctx, req, func(token string) error {
+		return sc.send(StreamFrame{Type: StreamFrameToken, ID: id, Content: token})
+	})
+
+	switch {
+	case ctx.Err() != nil:
+		sc.send(StreamFrame{Type: StreamFrameCancelled, ID: id})
+	case err != nil:
+		h.logger.Error("Chat stream failed", zap.String("stream_id", id), zap.Error(err))
+		sc.send(StreamFrame{Type: StreamFrameError, ID: id, Error: "Failed to get response"})
+	default:
+		sc.send(StreamFrame{Type: StreamFrameDone, ID: id, Model: model})
+	}
+}
+
+// heartbeat sends a heartbeat frame every interval until ctx is done
+func (h *ChatStreamHandler) heartbeat(ctx context.Context, sc *streamConn) {
+	ticker := time.NewTicker(h.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := sc.send(StreamFrame{Type: StreamFrameHeartbeat, Time: now.Format(time.RFC3339)}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// completionRequest builds the Groq request for a chat or consultation
+// frame
+func (h *ChatStreamHandler) completionRequest(req StreamRequest) (groq.ChatCompletionRequest, error) {
+	completion := groq.ChatCompletionRequest{
+		Model:       groq.ChatModel(req.Model),
+		MaxTokens:   h.config.MaxTokens,
+		Temperature: 0.7,
+	}
+
+	if req.Type == StreamFrameChat {
+		if strings.TrimSpace(req.Message) == "" {
+			return completion, errors.New("message is required")
+		}
+		if completion.Model == "" {
+			completion.Model = groq.ChatModel(h.config.ChatModel)
+		}
+		completion.Messages = []groq.ChatCompletionMessage{{Role: ChatRoleUser, Content: req.Message}}
+		return completion, nil
+	}
+
+	if strings.TrimSpace(req.Topic) == "" {
+		return completion, errors.New("topic is required")
+	}
+	prompt, err := consultation.Prompt(req.Phase)
+	if err != nil {
+		return completion, err
+	}
+	if completion.Model == "" {
+		completion.Model = groq.ChatModel(h.config.ConsultationModel)
+	}
+	content := req.Topic
+	if req.Context != "" {
+		content += "\n\nContext:\n" + req.Context
+	}
+	completion.Messages = []groq.ChatCompletionMessage{
+		{Role: ChatRoleSystem, Content: prompt},
+		{Role: ChatRoleUser, Content: content},
+	}
+	return completion, nil
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conneroisu/groq-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+func dialChatStream(t *testing.T, h *ChatStreamHandler) *websocket.Conn {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws/chat", h.Handle)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/chat", "", srv.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	ws.SetDeadline(time.Now().Add(5 * time.Second))
+	return ws
+}
+
+func receiveFrame(t *testing.T, ws *websocket.Conn) StreamFrame {
+	var frame StreamFrame
+	require.NoError(t, websocket.JSON.Receive(ws, &frame))
+	return frame
+}
+
+func TestChatStream_StreamsTokens(t *testing.T) {
+	var got groq.ChatCompletionRequest
+	h := &ChatStreamHandler{config: DefaultChatStreamConfig(), logger: zap.NewNop()}
+	h.streamer = func(ctx context.Context, req groq.ChatCompletionRequest, onToken func(string) error) error {
+		got = req
+		for _, token := range []string{"Hel", "lo"} {
+			if err := onToken(token); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ws := dialChatStream(t, h)
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameChat, ID: "1", Message: "hi"}))
+	assert.Equal(t, StreamFrame{Type: StreamFrameToken, ID: "1", Content: "Hel"}, receiveFrame(t, ws))
+	assert.Equal(t, StreamFrame{Type: StreamFrameToken, ID: "1", Content: "lo"}, receiveFrame(t, ws))
+	assert.Equal(t, StreamFrame{Type: StreamFrameDone, ID: "1", Model: defaultChatModel}, receiveFrame(t, ws))
+	require.Len(t, got.Messages, 1)
+	assert.Equal(t, "hi", got.Messages[0].Content)
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameConsultation, ID: "2", Topic: "billing", Phase: "deep-dive"}))
+	for frame := receiveFrame(t, ws); frame.Type != StreamFrameDone; frame = receiveFrame(t, ws) {
+	}
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, groq.Role(ChatRoleSystem), got.Messages[0].Role)
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFramePing, ID: "p"}))
+	assert.Equal(t, StreamFramePong, receiveFrame(t, ws).Type)
+}
+
+func TestChatStream_Cancel(t *testing.T) {
+	h := &ChatStreamHandler{config: DefaultChatStreamConfig(), logger: zap.NewNop()}
+	h.streamer = func(ctx context.Context, req groq.ChatCompletionRequest, onToken func(string) error) error {
+		if err := onToken("first"); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ws := dialChatStream(t, h)
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameChat, ID: "1", Message: "hi"}))
+	assert.Equal(t, StreamFrameToken, receiveFrame(t, ws).Type)
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameCancel, ID: "1"}))
+	assert.Equal(t, StreamFrame{Type: StreamFrameCancelled, ID: "1"}, receiveFrame(t, ws))
+}
+
+func TestChatStream_RateLimitAndValidation(t *testing.T) {
+	config := DefaultChatStreamConfig()
+	config.RequestsPerSecond = 0.001
+	config.Burst = 1
+	h := &ChatStreamHandler{config: config, logger: zap.NewNop()}
+	h.streamer = func(ctx context.Context, req groq.ChatCompletionRequest, onToken func(string) error) error {
+		return nil
+	}
+	ws := dialChatStream(t, h)
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameChat, ID: "1"}))
+	assert.Equal(t, StreamFrame{Type: StreamFrameError, ID: "1", Error: "message is required"}, receiveFrame(t, ws))
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameChat, ID: "2", Message: "hi"}))
+	assert.Equal(t, StreamFrameDone, receiveFrame(t, ws).Type)
+
+	require.NoError(t, websocket.JSON.Send(ws, StreamRequest{Type: StreamFrameChat, ID: "3", Message: "hi"}))
+	assert.Equal(t, StreamFrame{Type: StreamFrameError, ID: "3", Error: "Rate limit exceeded"}, receiveFrame(t, ws))
+
+	require.NoError(t, websocket.Message.Send(ws, "not json"))
+	assert.Equal(t, StreamFrameError, receiveFrame(t, ws).Type)
+}
+
+func TestChatStream_Heartbeat(t *testing.T) {
+	config := DefaultChatStreamConfig()
+	config.HeartbeatInterval = 10 * time.Millisecond
+	h := &ChatStreamHandler{config: config, logger: zap.NewNop(), streamer: func(context.Context, groq.ChatCompletionRequest, func(string) error) error { return nil }}
+	ws := dialChatStream(t, h)
+
+	frame := receiveFrame(t, ws)
+	assert.Equal(t, StreamFrameHeartbeat, frame.Type)
+	assert.NotEmpty(t, frame.Time)
+}
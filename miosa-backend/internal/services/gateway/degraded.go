@@ -0,0 +1,510 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"go.uber.org/zap"
+)
+
+// Model provider states
+const (
+	ProviderAvailable    = "available"
+	ProviderUnavailable  = "unavailable"  // Failing; orchestrations are deferred until it recovers
+	ProviderUnconfigured = "unconfigured" // No API key; LLM features stay off until a restart
+)
+
+// Feature states reported by /api/capabilities
+const (
+	FeatureAvailable   = "available"
+	FeatureDeferred    = "deferred" // Requests are accepted and run once the provider recovers
+	FeatureUnavailable = "unavailable"
+)
+
+// Probe intervals while the provider is unavailable. Each failed probe
+// doubles the wait, up to MaxProbeInterval.
+const (
+	DefaultProbeInterval = 15 * time.Second
+	MaxProbeInterval     = 5 * time.Minute
+)
+
+// MaxDeferred bounds the orchestrations held for the provider's recovery,
+// finished ones included until they are pushed out
+const MaxDeferred = 500
+
+// errProviderUnconfigured is returned for LLM features without an API key
+var errProviderUnconfigured = apierror.New(apierror.CategoryUnavailable, "No model provider is configured; see /api/capabilities")
+
+// providerFeatures are the features that call the model provider, and
+// whether their requests are deferred rather than refused while it is down
+var providerFeatures = map[string]bool{
+	"agents":        true,
+	"generate":      true,
+	"chat":          false,
+	"chat_stream":   false,
+	"chat_sessions": false,
+	"consultation":  false,
+	"collaboration": false,
+}
+
+// ProviderStatus is the model provider's availability
+type ProviderStatus struct {
+	Name    string     `json:"name"`
+	State   string     `json:"state"`
+	Error   string     `json:"error,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`    // When it became unavailable
+	RetryAt *time.Time `json:"retry_at,omitempty"` // When it is next checked
+}
+
+// ProviderMonitor tracks whether the model provider is reachable. Requests
+// failing with provider errors mark it unavailable; it is then probed with
+// backoff, and the recovery callbacks run once a probe succeeds.
+type ProviderMonitor struct {
+	name     string
+	probe    func(ctx context.Context) error
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu        sync.Mutex
+	state     string
+	err       string
+	since     time.Time
+	nextCheck time.Time
+	failures  int
+	recovered []func(ctx context.Context)
+	wake      chan struct{}
+}
+
+// NewProviderMonitor creates a monitor checking the provider with probe.
+// A nil probe means no provider is configured.
+func NewProviderMonitor(name string, probe func(ctx context.Context) error, interval time.Duration, logger *zap.Logger) *ProviderMonitor {
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+	m := &ProviderMonitor{name: name, probe: probe, interval: interval, logger: logger, state: ProviderAvailable, wake: make(chan struct{}, 1)}
+	if probe == nil {
+		m.state = ProviderUnconfigured
+	}
+	return m
+}
+
+// Status returns the provider's availability
+func (m *ProviderMonitor) Status() ProviderStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := ProviderStatus{Name: m.name, State: m.state, Error: m.err}
+	if m.state == ProviderUnavailable {
+		since, retry := m.since, m.nextCheck
+		s.Since, s.RetryAt = &since, &retry
+	}
+	return s
+}
+
+// Available reports whether requests can reach the provider. A nil monitor
+// is always available.
+func (m *ProviderMonitor) Available() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state == ProviderAvailable
+}
+
+// MarkUnavailable records a provider failure seen by a request and starts
+// probing for its recovery
+func (m *ProviderMonitor) MarkUnavailable(err error) {
+	m.mu.Lock()
+	if m.state != ProviderAvailable {
+		m.mu.Unlock()
+		return
+	}
+	m.state, m.err, m.failures = ProviderUnavailable, err.Error(), 0
+	m.since = time.Now()
+	m.nextCheck = m.since.Add(m.interval)
+	m.mu.Unlock()
+
+	m.logger.Warn("Model provider unavailable; deferring orchestrations", zap.String("provider", m.name), zap.Error(err))
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// OnRecover registers fn to run each time the provider recovers
+func (m *ProviderMonitor) OnRecover(fn func(ctx context.Context)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recovered = append(m.recovered, fn)
+}
+
+// Check probes an unavailable provider, reporting whether it is available.
+// On recovery the OnRecover callbacks run before Check returns.
+func (m *ProviderMonitor) Check(ctx context.Context) bool {
+	m.mu.Lock()
+	if m.state != ProviderUnavailable {
+		available := m.state == ProviderAvailable
+		m.mu.Unlock()
+		return available
+	}
+	m.mu.Unlock()
+
+	err := m.probe(ctx)
+
+	m.mu.Lock()
+	if err != nil {
+		m.failures++
+		wait := m.interval << m.failures
+		if wait > MaxProbeInterval || wait <= 0 {
+			wait = MaxProbeInterval
+		}
+		m.err, m.nextCheck = err.Error(), time.Now().Add(wait)
+		m.mu.Unlock()
+		return false
+	}
+	down := time.Since(m.since)
+	m.state, m.err, m.failures = ProviderAvailable, "", 0
+	recovered := make([]func(context.Context), len(m.recovered))
+	copy(recovered, m.recovered)
+	m.mu.Unlock()
+
+	m.logger.Info("Model provider recovered", zap.String("provider", m.name), zap.Duration("down", down))
+	for _, fn := range recovered {
+		fn(ctx)
+	}
+	return true
+}
+
+// Run probes the provider whenever it is unavailable until ctx is done
+func (m *ProviderMonitor) Run(ctx context.Context) {
+	if m.probe == nil {
+		return
+	}
+	for {
+		m.mu.Lock()
+		state, next := m.state, m.nextCheck
+		m.mu.Unlock()
+
+		// Nothing to probe while available; wait to be marked unavailable
+		if state != ProviderUnavailable {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.wake:
+			}
+			continue
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Deferred task states
+const (
+	DeferredQueued    = "deferred"
+	DeferredRunning   = "running"
+	DeferredCompleted = "completed"
+	DeferredFailed    = "failed"
+)
+
+// DeferredTask is an orchestration accepted while the provider was down
+type DeferredTask struct {
+	TaskID   uuid.UUID         `json:"task_id"`
+	Source   string            `json:"source"` // The endpoint it was submitted to
+	Status   string            `json:"status"`
+	QueuedAt time.Time         `json:"queued_at"`
+	Attempts int               `json:"attempts"`
+	Result   interface{}       `json:"result,omitempty"` // The response the request would have returned
+	Error    *apierror.Problem `json:"error,omitempty"`
+
+	task agents.Task
+	run  func(ctx context.Context) (interface{}, error)
+}
+
+// DeferredQueue holds orchestrations until the provider recovers, then
+// runs them in the order they arrived. Tasks are kept in memory, so a
+// restart drops the ones still waiting.
+type DeferredQueue struct {
+	logger *zap.Logger
+	max    int
+
+	mu    sync.Mutex
+	tasks map[uuid.UUID]*DeferredTask
+	order []uuid.UUID // Oldest first
+}
+
+// NewDeferredQueue creates a queue holding at most max tasks
+func NewDeferredQueue(max int, logger *zap.Logger) *DeferredQueue {
+	if max <= 0 {
+		max = MaxDeferred
+	}
+	return &DeferredQueue{logger: logger, max: max, tasks: make(map[uuid.UUID]*DeferredTask)}
+}
+
+// Add defers task. Finished tasks make room for it; when every held task
+// is still waiting the queue is full and Add fails.
+func (q *DeferredQueue) Add(task agents.Task, source string, run func(ctx context.Context) (interface{}, error)) (DeferredTask, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.order) >= q.max {
+		for i, id := range q.order {
+			if s := q.tasks[id].Status; s == DeferredCompleted || s == DeferredFailed {
+				delete(q.tasks, id)
+				q.order = append(q.order[:i], q.order[i+1:]...)
+				break
+			}
+		}
+		if len(q.order) >= q.max {
+			return DeferredTask{}, apierror.New(apierror.CategoryUnavailable, "Too many orchestrations are waiting for the model provider")
+		}
+	}
+	t := &DeferredTask{TaskID: task.ID, Source: source, Status: DeferredQueued, QueuedAt: time.Now(), task: task, run: run}
+	q.tasks[task.ID] = t
+	q.order = append(q.order, task.ID)
+	return *t, nil
+}
+
+// Get returns a deferred task
+func (q *DeferredQueue) Get(id uuid.UUID) (DeferredTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.tasks[id]
+	if !ok {
+		return DeferredTask{}, false
+	}
+	return *t, true
+}
+
+// Pending counts the tasks still waiting
+func (q *DeferredQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, t := range q.tasks {
+		if t.Status == DeferredQueued {
+			n++
+		}
+	}
+	return n
+}
+
+// Resume runs the waiting tasks in order. A task failing because the
+// provider is down again goes back to waiting, marks the provider
+// unavailable and stops the resume; the next recovery picks it up. begin,
+// when set, is called before each task and may refuse it, as a draining
+// instance does.
+func (q *DeferredQueue) Resume(ctx context.Context, provider *ProviderMonitor, begin func(agents.Task, string) (func(), error)) int {
+	ran := 0
+	for {
+		t := q.next()
+		if t == nil {
+			return ran
+		}
+		finish := func() {}
+		if begin != nil {
+			f, err := begin(t.task, t.Source)
+			if err != nil {
+				q.finish(t, DeferredQueued, nil, nil)
+				q.logger.Warn("Stopped resuming deferred orchestrations", zap.Error(err))
+				return ran
+			}
+			finish = f
+		}
+		result, err := t.run(ctx)
+		finish()
+		ran++
+
+		switch {
+		case err == nil:
+			q.finish(t, DeferredCompleted, result, nil)
+		case apierror.CategoryOf(err) == apierror.CategoryProviderUnavailable:
+			q.finish(t, DeferredQueued, nil, nil)
+			if provider != nil {
+				provider.MarkUnavailable(err)
+			}
+			return ran
+		default:
+			q.logger.Error("Deferred orchestration failed", apierror.Fields(err, zap.String("task_id", t.TaskID.String()))...)
+			q.finish(t, DeferredFailed, nil, apierror.NewProblem(err, ""))
+		}
+	}
+}
+
+// next marks the oldest waiting task running and returns it
+func (q *DeferredQueue) next() *DeferredTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, id := range q.order {
+		if t := q.tasks[id]; t.Status == DeferredQueued {
+			t.Status = DeferredRunning
+			t.Attempts++
+			return t
+		}
+	}
+	return nil
+}
+
+func (q *DeferredQueue) finish(t *DeferredTask, status string, result interface{}, problem *apierror.Problem) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t.Status, t.Result, t.Error = status, result, problem
+}
+
+// SetDegradation has orchestrations deferred while provider is down and
+// resumed, in arrival order, once it recovers
+func (h *Handlers) SetDegradation(provider *ProviderMonitor, queue *DeferredQueue) {
+	h.provider, h.deferred = provider, queue
+	provider.OnRecover(func(ctx context.Context) {
+		var begin func(agents.Task, string) (func(), error)
+		if h.drainer != nil {
+			begin = h.drainer.Begin
+		}
+		if n := queue.Resume(ctx, provider, begin); n > 0 {
+			h.logger.Info("Resumed deferred orchestrations", zap.Int("count", n))
+		}
+	})
+}
+
+// SetFeatures reports the features that do not depend on the model
+// provider, such as auth or collaboration, at /api/capabilities
+func (h *Handlers) SetFeatures(features map[string]bool) {
+	h.features = features
+}
+
+// deferTask reports whether the task was deferred: because the provider is
+// known to be down, or because err shows it just went down. The response
+// is written when it was.
+func (h *Handlers) deferTask(c *gin.Context, task agents.Task, source string, run func(ctx context.Context) (interface{}, error), err error) bool {
+	if h.provider == nil || h.deferred == nil {
+		return false
+	}
+	if err != nil {
+		if apierror.CategoryOf(err) != apierror.CategoryProviderUnavailable {
+			return false
+		}
+		h.provider.MarkUnavailable(err)
+	} else if h.provider.Available() {
+		return false
+	}
+
+	deferred, addErr := h.deferred.Add(task, source, run)
+	if addErr != nil {
+		apierror.Abort(c, addErr)
+		return true
+	}
+	status := h.provider.Status()
+	if status.RetryAt != nil {
+		wait := int(time.Until(*status.RetryAt).Seconds()) + 1
+		if wait < 1 {
+			wait = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(wait))
+	}
+	location := fmt.Sprintf("/api/deferred/%s", deferred.TaskID)
+	c.Header("Location", location)
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":     deferred.Status,
+		"task_id":    deferred.TaskID,
+		"status_url": location,
+		"retry_at":   status.RetryAt,
+		"provider":   status,
+	})
+	return true
+}
+
+// GetDeferred serves GET /api/deferred/:id, the status of a deferred
+// orchestration and its result once it ran. Tasks are only visible to
+// their tenant.
+func (h *Handlers) GetDeferred(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.Invalid("invalid task id", apierror.FieldError{Field: "id", Message: "must be a UUID"}))
+		return
+	}
+	var t DeferredTask
+	ok := false
+	if h.deferred != nil {
+		t, ok = h.deferred.Get(id)
+	}
+	if ok {
+		if v, exists := c.Get("task_context"); exists {
+			if tc, isContext := v.(*agents.TaskContext); isContext && t.task.Context != nil && tc.TenantID != t.task.Context.TenantID {
+				ok = false
+			}
+		}
+	}
+	if !ok {
+		apierror.Abort(c, apierror.New(apierror.CategoryNotFound, "Deferred task not found"))
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// Capabilities is the response of GET /api/capabilities
+type Capabilities struct {
+	Degraded bool              `json:"degraded"` // Some feature is not fully available
+	Provider ProviderStatus    `json:"provider"`
+	Features map[string]string `json:"features"` // FeatureAvailable, FeatureDeferred or FeatureUnavailable
+	Deferred int               `json:"deferred"` // Orchestrations waiting for the provider
+}
+
+// Capabilities serves GET /api/capabilities so clients can tell which
+// features work right now instead of discovering it from failures
+func (h *Handlers) Capabilities(c *gin.Context) {
+	caps := Capabilities{Features: make(map[string]string)}
+	switch {
+	case h.provider != nil:
+		caps.Provider = h.provider.Status()
+	case h.groqClient != nil:
+		caps.Provider = ProviderStatus{Name: "groq", State: ProviderAvailable}
+	default:
+		caps.Provider = ProviderStatus{Name: "groq", State: ProviderUnconfigured}
+	}
+	if h.deferred != nil {
+		caps.Deferred = h.deferred.Pending()
+	}
+
+	for name, on := range h.features {
+		if _, llm := providerFeatures[name]; llm {
+			continue
+		}
+		caps.Features[name] = FeatureUnavailable
+		if on {
+			caps.Features[name] = FeatureAvailable
+		}
+	}
+	for name, deferrable := range providerFeatures {
+		state := FeatureAvailable
+		switch {
+		case caps.Provider.State == ProviderUnconfigured:
+			state = FeatureUnavailable
+		case name == "collaboration" && !h.features[name]:
+			state = FeatureUnavailable
+		case caps.Provider.State == ProviderUnavailable && deferrable && h.deferred != nil:
+			state = FeatureDeferred
+		case caps.Provider.State == ProviderUnavailable:
+			state = FeatureUnavailable
+		}
+		caps.Features[name] = state
+	}
+	for _, state := range caps.Features {
+		if state != FeatureAvailable {
+			caps.Degraded = true
+		}
+	}
+	c.JSON(http.StatusOK, caps)
+}
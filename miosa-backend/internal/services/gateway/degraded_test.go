@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+)
+
+func TestProviderMonitor_BacksOffAndRecovers(t *testing.T) {
+	probeErr := errors.New("connection refused")
+	m := NewProviderMonitor("groq", func(ctx context.Context) error { return probeErr }, time.Second, zap.NewNop())
+	recovered := 0
+	m.OnRecover(func(ctx context.Context) { recovered++ })
+
+	assert.True(t, m.Available())
+	m.MarkUnavailable(apierror.Provider("rate limited", nil))
+	assert.False(t, m.Available())
+	status := m.Status()
+	assert.Equal(t, ProviderUnavailable, status.State)
+	require.NotNil(t, status.RetryAt)
+
+	assert.False(t, m.Check(context.Background()))
+	first := *m.Status().RetryAt
+	assert.False(t, m.Check(context.Background()))
+	assert.True(t, m.Status().RetryAt.After(first), "each failed probe waits longer")
+	assert.Equal(t, "connection refused", m.Status().Error)
+
+	probeErr = nil
+	assert.True(t, m.Check(context.Background()))
+	assert.True(t, m.Available())
+	assert.Nil(t, m.Status().RetryAt)
+	assert.Equal(t, 1, recovered)
+
+	assert.Equal(t, ProviderUnconfigured, NewProviderMonitor("groq", nil, 0, zap.NewNop()).Status().State)
+}
+
+func TestDeferredQueue_ResumeStopsWhenProviderFailsAgain(t *testing.T) {
+	q := NewDeferredQueue(2, zap.NewNop())
+	m := NewProviderMonitor("groq", func(ctx context.Context) error { return nil }, time.Second, zap.NewNop())
+
+	var ran []int
+	failing := true
+	first := agents.Task{ID: uuid.New()}
+	_, err := q.Add(first, "agents/execute", func(ctx context.Context) (interface{}, error) {
+		ran = append(ran, 1)
+		return "done", nil
+	})
+	require.NoError(t, err)
+	second := agents.Task{ID: uuid.New()}
+	_, err = q.Add(second, "generate", func(ctx context.Context) (interface{}, error) {
+		ran = append(ran, 2)
+		if failing {
+			return nil, apierror.Provider("still down", nil)
+		}
+		return nil, errors.New("bad output")
+	})
+	require.NoError(t, err)
+
+	_, err = q.Add(agents.Task{ID: uuid.New()}, "generate", nil)
+	assert.Equal(t, apierror.CategoryUnavailable, apierror.CategoryOf(err), "a full queue refuses tasks")
+
+	assert.Equal(t, 2, q.Resume(context.Background(), m, nil))
+	assert.Equal(t, []int{1, 2}, ran)
+	done, _ := q.Get(first.ID)
+	assert.Equal(t, DeferredCompleted, done.Status)
+	assert.Equal(t, "done", done.Result)
+	waiting, _ := q.Get(second.ID)
+	assert.Equal(t, DeferredQueued, waiting.Status)
+	assert.False(t, m.Available(), "a provider failure marks it unavailable again")
+	assert.Equal(t, 1, q.Pending())
+
+	failing = false
+	q.Resume(context.Background(), m, nil)
+	failed, _ := q.Get(second.ID)
+	assert.Equal(t, DeferredFailed, failed.Status)
+	assert.Equal(t, 2, failed.Attempts)
+	require.NotNil(t, failed.Error)
+
+	_, err = q.Add(agents.Task{ID: uuid.New()}, "generate", nil)
+	assert.NoError(t, err, "finished tasks make room")
+}
+
+func TestCapabilities_ReportsDegradedFeatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandlers(nil, nil, zap.NewNop())
+	m := NewProviderMonitor("groq", func(ctx context.Context) error { return nil }, time.Second, zap.NewNop())
+	h.SetDegradation(m, NewDeferredQueue(0, zap.NewNop()))
+	h.SetFeatures(map[string]bool{"auth": true, "collaboration": true})
+
+	r := gin.New()
+	r.GET("/api/capabilities", h.Capabilities)
+	capabilities := func() Capabilities {
+		w := serveChat(t, r, http.MethodGet, "/api/capabilities", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var caps Capabilities
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &caps))
+		return caps
+	}
+
+	caps := capabilities()
+	assert.False(t, caps.Degraded)
+	assert.Equal(t, FeatureAvailable, caps.Features["agents"])
+
+	m.MarkUnavailable(apierror.Provider("down", nil))
+	caps = capabilities()
+	assert.True(t, caps.Degraded)
+	assert.Equal(t, ProviderUnavailable, caps.Provider.State)
+	assert.Equal(t, FeatureDeferred, caps.Features["agents"])
+	assert.Equal(t, FeatureUnavailable, caps.Features["chat"])
+	assert.Equal(t, FeatureAvailable, caps.Features["auth"])
+}
+
+func TestGetDeferred_ScopedToTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandlers(nil, nil, zap.NewNop())
+	q := NewDeferredQueue(0, zap.NewNop())
+	h.SetDegradation(NewProviderMonitor("groq", nil, 0, zap.NewNop()), q)
+
+	tenant := uuid.New()
+	task := agents.Task{ID: uuid.New(), Context: &agents.TaskContext{TenantID: tenant}}
+	_, err := q.Add(task, "agents/execute", nil)
+	require.NoError(t, err)
+
+	get := func(caller uuid.UUID) int {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("task_context", &agents.TaskContext{TenantID: caller}) })
+		r.GET("/api/deferred/:id", h.GetDeferred)
+		return serveChat(t, r, http.MethodGet, "/api/deferred/"+task.ID.String(), nil).Code
+	}
+	assert.Equal(t, http.StatusOK, get(tenant))
+	assert.Equal(t, http.StatusNotFound, get(uuid.New()))
+}
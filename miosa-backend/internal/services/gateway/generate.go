@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
+	"go.uber.org/zap"
+)
+
+// Generation types
+const (
+	GenerateCode         = "code"
+	GenerateArchitecture = "architecture"
+	GenerateDocs         = "docs"
+)
+
+// generationPipelines maps each generation type to the agents that run
+// it, in order. The first agent produces the artifact and the rest review
+// or refine it.
+var generationPipelines = map[string][]agents.AgentType{
+	GenerateCode:         {agents.DevelopmentAgent, agents.QualityAgent},
+	GenerateArchitecture: {agents.ArchitectAgent, agents.AnalysisAgent},
+	GenerateDocs:         {agents.CommunicationAgent, agents.AnalysisAgent},
+}
+
+// GenerateRequest is the body of POST /api/generate
+type GenerateRequest struct {
+	Type        string            `json:"type" binding:"required"`
+	Description string            `json:"description" binding:"required"`
+	Context     map[string]string `json:"context"`
+}
+
+// GenerateStep is one agent's part in a generation pipeline
+type GenerateStep struct {
+	Agent            string  `json:"agent"`
+	Success          bool    `json:"success"`
+	Confidence       float64 `json:"confidence"`
+	Artifact         string  `json:"artifact,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	ExecutionMS      int64   `json:"execution_ms"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// GenerateUsage totals LLM usage over a pipeline
+type GenerateUsage struct {
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	TotalTokens      int      `json:"total_tokens"`
+	Models           []string `json:"models,omitempty"`
+}
+
+// GenerateResponse is the result of a generation pipeline
+type GenerateResponse struct {
+	Success     bool                   `json:"success"`
+	TaskID      string                 `json:"task_id"`
+	Type        string                 `json:"type"`
+	Pipeline    []agents.AgentType     `json:"pipeline"`
+	Output      string                 `json:"output"`
+	Artifacts   []agents.GeneratedFile `json:"artifacts"`
+	Steps       []GenerateStep         `json:"steps"`
+	Usage       GenerateUsage          `json:"usage"`
+	ExecutionMS int64                  `json:"execution_ms"`
+}
+
+// Generate handles POST /api/generate, running the type's pipeline
+func (h *Handlers) Generate(c *gin.Context) {
+	if h.orchestrator == nil {
+		apierror.Abort(c, errProviderUnconfigured)
+		return
+	}
+
+	var req GenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	pipeline, ok := generationPipelines[req.Type]
+	if !ok {
+		apierror.Abort(c, apierror.Newf(apierror.CategoryValidation, "Unknown generation type %q, expected code, architecture or docs", req.Type))
+		return
+	}
+
+	taskContext := &agents.TaskContext{Metadata: make(map[string]string)}
+	if v, exists := c.Get("task_context"); exists {
+		taskContext = v.(*agents.TaskContext)
+	}
+	taskContext.RequestID = requestid.FromContext(c.Request.Context())
+	task := generationTask(req, taskContext)
+
+	resource := c.Request.URL.Path
+	run := func(ctx context.Context) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, 90*time.Second)
+		defer cancel()
+
+		chain, err := h.orchestrator.ExecuteChain(ctx, task, pipeline)
+
+		event := audit.FromTaskContext(audit.Event{
+			WorkflowID:  task.ID,
+			Action:      audit.ActionOrchestrate,
+			Resource:    resource,
+			RequestHash: audit.HashRequest(req),
+			Status:      audit.StatusSuccess,
+			Metadata:    map[string]string{"generate_type": req.Type},
+		}, taskContext)
+		if err != nil || !chain.Success {
+			event.Status = audit.StatusFailure
+		}
+		h.audit.Record(ctx, event)
+
+		if err != nil {
+			return nil, err
+		}
+		return newGenerateResponse(req.Type, task.ID, chain), nil
+	}
+
+	// While the provider is down the pipeline waits for it instead of failing
+	if h.deferTask(c, task, "generate", run, nil) {
+		return
+	}
+
+	// Refuse new work while draining so the instance can shut down cleanly
+	if h.drainer != nil {
+		finish, err := h.drainer.Begin(task, "generate")
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		defer finish()
+	}
+
+	resp, err := run(c.Request.Context())
+	if err != nil {
+		if h.deferTask(c, task, "generate", run, err) {
+			return
+		}
+		h.logger.Error("Generation failed",
+			apierror.Fields(err,
+				zap.String("task_id", task.ID.String()),
+				zap.String("type", req.Type))...)
+		apierror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// generationTask builds the pipeline task, appending the request's context
+// to the description and passing it to agents as parameters
+func generationTask(req GenerateRequest, taskContext *agents.TaskContext) agents.Task {
+	input := req.Description
+	params := map[string]interface{}{"generate_type": req.Type}
+	if len(req.Context) > 0 {
+		keys := make([]string, 0, len(req.Context))
+		for k := range req.Context {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var sb strings.Builder
+		sb.WriteString(req.Description)
+		sb.WriteString("\n\nContext:")
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "\n- %s: %s", k, req.Context[k])
+			params[k] = req.Context[k]
+		}
+		input = sb.String()
+	}
+
+	return agents.Task{
+		ID:         uuid.New(),
+		Type:       req.Type,
+		Input:      input,
+		Parameters: params,
+		Context:    taskContext,
+		Priority:   5,
+		Timeout:    90 * time.Second,
+	}
+}
+
+// newGenerateResponse collects the pipeline's output, files and usage
+func newGenerateResponse(kind string, taskID uuid.UUID, chain *agents.ChainExecution) GenerateResponse {
+	resp := GenerateResponse{
+		Success:     chain.Success,
+		TaskID:      taskID.String(),
+		Type:        kind,
+		Pipeline:    chain.Agents,
+		Output:      chain.FinalOutput,
+		Artifacts:   []agents.GeneratedFile{},
+		Steps:       make([]GenerateStep, 0, len(chain.Results)),
+		ExecutionMS: chain.TotalMS,
+	}
+
+	models := make(map[string]bool)
+	for _, result := range chain.Results {
+		step := GenerateStep{
+			Success:          result.Success,
+			Confidence:       result.Confidence,
+			Model:            result.Model,
+			PromptTokens:     result.PromptTokens,
+			CompletionTokens: result.CompletionTokens,
+			ExecutionMS:      result.ExecutionMS,
+		}
+		if agent, ok := result.Data["agent"].(string); ok {
+			step.Agent = agent
+		}
+		if ref, ok := result.Data["artifact"].(string); ok {
+			step.Artifact = ref
+		}
+		if result.Error != nil {
+			step.Error = result.Error.Error()
+		}
+		resp.Steps = append(resp.Steps, step)
+		resp.Artifacts = append(resp.Artifacts, result.Files...)
+
+		resp.Usage.PromptTokens += result.PromptTokens
+		resp.Usage.CompletionTokens += result.CompletionTokens
+		if result.Model != "" && !models[result.Model] {
+			models[result.Model] = true
+			resp.Usage.Models = append(resp.Usage.Models, result.Model)
+		}
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	return resp
+}
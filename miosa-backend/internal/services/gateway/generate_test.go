@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+)
+
+func TestGenerationPipelines(t *testing.T) {
+	assert.Equal(t, []agents.AgentType{agents.DevelopmentAgent, agents.QualityAgent}, generationPipelines[GenerateCode])
+	assert.Equal(t, []agents.AgentType{agents.CommunicationAgent, agents.AnalysisAgent}, generationPipelines[GenerateDocs])
+	assert.Contains(t, generationPipelines, GenerateArchitecture)
+}
+
+func TestGenerationTask_IncludesContext(t *testing.T) {
+	task := generationTask(GenerateRequest{
+		Type:        GenerateCode,
+		Description: "Add a login form",
+		Context:     map[string]string{"language": "go", "framework": "gin"},
+	}, &agents.TaskContext{})
+
+	assert.Equal(t, GenerateCode, task.Type)
+	assert.Equal(t, "Add a login form\n\nContext:\n- framework: gin\n- language: go", task.Input)
+	assert.Equal(t, "go", task.Parameters["language"])
+	assert.Equal(t, GenerateCode, task.Parameters["generate_type"])
+}
+
+func TestNewGenerateResponse_SumsUsage(t *testing.T) {
+	chain := &agents.ChainExecution{
+		Agents:      generationPipelines[GenerateCode],
+		Success:     true,
+		FinalOutput: "reviewed",
+		TotalMS:     1200,
+		Results: []*agents.Result{
+			{
+				Success:          true,
+				Model:            "dev-model",
+				PromptTokens:     100,
+				CompletionTokens: 50,
+				Files:            []agents.GeneratedFile{{Path: "login.go", Content: "package main", Type: "go"}},
+				Data:             map[string]interface{}{"agent": "development", "artifact": "artifact://development/1"},
+			},
+			{
+				Success:          false,
+				Model:            "dev-model",
+				PromptTokens:     10,
+				CompletionTokens: 5,
+				Error:            errors.New("review failed"),
+				Data:             map[string]interface{}{"agent": "quality"},
+			},
+		},
+	}
+
+	resp := newGenerateResponse(GenerateCode, uuid.New(), chain)
+	assert.Equal(t, GenerateUsage{PromptTokens: 110, CompletionTokens: 55, TotalTokens: 165, Models: []string{"dev-model"}}, resp.Usage)
+	require.Len(t, resp.Artifacts, 1)
+	assert.Equal(t, "login.go", resp.Artifacts[0].Path)
+	require.Len(t, resp.Steps, 2)
+	assert.Equal(t, "artifact://development/1", resp.Steps[0].Artifact)
+	assert.Equal(t, "review failed", resp.Steps[1].Error)
+}
+
+func TestGenerate_RejectsUnknownType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandlers(&agents.Orchestrator{}, nil, zap.NewNop())
+	r := gin.New()
+	r.POST("/generate", h.Generate)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(`{"type":"poem","description":"x"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "poem")
+}
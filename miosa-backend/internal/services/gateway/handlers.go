@@ -2,7 +2,6 @@ package gateway
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -10,6 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"github.com/sormind/OSA/miosa-backend/internal/audit"
+	"github.com/sormind/OSA/miosa-backend/internal/requestid"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +20,11 @@ type Handlers struct {
 	orchestrator *agents.Orchestrator
 	groqClient   *groq.Client
 	logger       *zap.Logger
+	audit        *audit.Log
+	drainer      *agents.Drainer
+	provider     *ProviderMonitor
+	deferred     *DeferredQueue
+	features     map[string]bool
 }
 
 // NewHandlers creates new gateway handlers
@@ -29,6 +36,16 @@ func NewHandlers(orchestrator *agents.Orchestrator, groqClient *groq.Client, log
 	}
 }
 
+// SetAuditLog records agent execution requests to l
+func (h *Handlers) SetAuditLog(l *audit.Log) {
+	h.audit = l
+}
+
+// SetDrainer tracks agent executions so shutdown can drain them
+func (h *Handlers) SetDrainer(d *agents.Drainer) {
+	h.drainer = d
+}
+
 // ExecuteAgentRequest represents a request to execute an agent task
 type ExecuteAgentRequest struct {
 	Task     string                 `json:"task" binding:"required"`
@@ -50,13 +67,13 @@ type ExecuteAgentResponse struct {
 // ExecuteAgent handles agent execution requests
 func (h *Handlers) ExecuteAgent(c *gin.Context) {
 	if h.orchestrator == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Agent system not initialized"})
+		apierror.Abort(c, errProviderUnconfigured)
 		return
 	}
 
 	var req ExecuteAgentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Invalid(err.Error()))
 		return
 	}
 
@@ -74,6 +91,7 @@ func (h *Handlers) ExecuteAgent(c *gin.Context) {
 			Metadata:    make(map[string]string),
 		}
 	}
+	taskContext.RequestID = requestid.FromContext(c.Request.Context())
 
 	// Create task
 	task := agents.Task{
@@ -89,31 +107,69 @@ func (h *Handlers) ExecuteAgent(c *gin.Context) {
 		task.Parameters = req.Metadata
 	}
 
-	// Execute through orchestrator
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+	// Execution is a closure so a deferred task can run it once the
+	// provider recovers, after the request is gone
+	resource := c.Request.URL.Path
+	run := func(ctx context.Context) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
 
-	result, err := h.orchestrator.Execute(ctx, task)
-	if err != nil {
-		h.logger.Error("Agent execution failed",
-			zap.Error(err),
-			zap.String("task_id", task.ID.String()),
-			zap.String("task_type", task.Type))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Execution failed: %v", err)})
+		result, err := h.orchestrator.Execute(ctx, task)
+
+		event := audit.FromTaskContext(audit.Event{
+			WorkflowID:  task.ID,
+			Action:      audit.ActionOrchestrate,
+			Resource:    resource,
+			RequestHash: audit.HashRequest(req),
+			Status:      audit.StatusSuccess,
+		}, taskContext)
+		if err != nil || !result.Success {
+			event.Status = audit.StatusFailure
+		}
+		h.audit.Record(ctx, event)
+
+		if err != nil {
+			return nil, err
+		}
+		response := ExecuteAgentResponse{
+			Success:     result.Success,
+			Output:      result.Output,
+			Confidence:  result.Confidence,
+			ExecutionMS: result.ExecutionMS,
+			TaskID:      task.ID.String(),
+		}
+		if result.NextAgent != "" {
+			response.NextAgent = string(result.NextAgent)
+		}
+		return response, nil
+	}
+
+	// While the provider is down the task waits for it instead of failing
+	if h.deferTask(c, task, "agents/execute", run, nil) {
 		return
 	}
 
-	// Return response
-	response := ExecuteAgentResponse{
-		Success:     result.Success,
-		Output:      result.Output,
-		Confidence:  result.Confidence,
-		ExecutionMS: result.ExecutionMS,
-		TaskID:      task.ID.String(),
+	// Refuse new work while draining so the instance can shut down cleanly
+	if h.drainer != nil {
+		finish, err := h.drainer.Begin(task, "agents/execute")
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		defer finish()
 	}
 
-	if result.NextAgent != "" {
-		response.NextAgent = string(result.NextAgent)
+	response, err := run(c.Request.Context())
+	if err != nil {
+		if h.deferTask(c, task, "agents/execute", run, err) {
+			return
+		}
+		h.logger.Error("Agent execution failed",
+			apierror.Fields(err,
+				zap.String("task_id", task.ID.String()),
+				zap.String("task_type", task.Type))...)
+		apierror.Abort(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -132,23 +188,24 @@ type ChatResponse struct {
 	Model   string `json:"model"`
 }
 
-// Chat handles simple chat requests (backward compatibility)
+// Chat handles simple chat requests (backward compatibility). Clients that
+// want tokens as they are generated should use /ws/chat instead.
 func (h *Handlers) Chat(c *gin.Context) {
 	var req ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Invalid(err.Error()))
 		return
 	}
 
 	if h.groqClient == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Chat service not available"})
+		apierror.Abort(c, errProviderUnconfigured)
 		return
 	}
 
 	// Use specified model or default
 	model := req.Model
 	if model == "" {
-		model = "llama-3.1-8b-instant"
+		model = defaultChatModel
 	}
 
 	// Make direct Groq call for simple chat
@@ -168,13 +225,14 @@ func (h *Handlers) Chat(c *gin.Context) {
 	})
 
 	if err != nil {
-		h.logger.Error("Chat completion failed", zap.Error(err))
-		c.JSON(500, gin.H{"error": "Failed to get response"})
+		err = apierror.Provider("Failed to get response", err)
+		h.logger.Error("Chat completion failed", apierror.Fields(err)...)
+		apierror.Abort(c, err)
 		return
 	}
 
 	if len(resp.Choices) == 0 {
-		c.JSON(500, gin.H{"error": "No response from model"})
+		apierror.Abort(c, apierror.New(apierror.CategoryProviderUnavailable, "No response from model"))
 		return
 	}
 
@@ -195,5 +253,39 @@ func (h *Handlers) HealthCheck(c *gin.Context) {
 			"groq":         h.groqClient != nil,
 		},
 	}
+	// Report draining as unhealthy so load balancers stop routing here
+	if h.drainer != nil && h.drainer.Draining() {
+		status["status"] = "draining"
+		status["in_flight"] = h.drainer.InFlightCount()
+		c.JSON(http.StatusServiceUnavailable, status)
+		return
+	}
 	c.JSON(200, status)
-}
\ No newline at end of file
+}
+
+// QueryAudit handles GET /api/audit for compliance review. Results are
+// always scoped to the caller's tenant.
+func (h *Handlers) QueryAudit(c *gin.Context) {
+	filter, err := audit.FilterFromQuery(c.Request.URL.Query())
+	if err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	if ctx, exists := c.Get("task_context"); exists {
+		if taskContext, ok := ctx.(*agents.TaskContext); ok {
+			filter.TenantID = taskContext.TenantID
+		}
+	}
+
+	events, err := h.audit.Query(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Audit query failed", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to query audit log"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+	})
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/apierror"
+	"go.uber.org/zap"
+)
+
+// StackProfileHandlers manages the authenticated tenant's stack profile
+type StackProfileHandlers struct {
+	store  agents.StackProfileStore
+	logger *zap.Logger
+}
+
+// NewStackProfileHandlers creates new stack profile handlers
+func NewStackProfileHandlers(store agents.StackProfileStore, logger *zap.Logger) *StackProfileHandlers {
+	return &StackProfileHandlers{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetProfile handles GET /api/stack-profile
+func (h *StackProfileHandlers) GetProfile(c *gin.Context) {
+	taskContext, ok := h.taskContext(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.store.Profile(c.Request.Context(), taskContext.TenantID)
+	if err != nil {
+		h.logger.Error("Failed to load stack profile", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to load stack profile"))
+		return
+	}
+	if profile == nil {
+		profile = &agents.StackProfile{}
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// SetProfile handles PUT /api/stack-profile, replacing the whole profile
+func (h *StackProfileHandlers) SetProfile(c *gin.Context) {
+	taskContext, ok := h.taskContext(c)
+	if !ok {
+		return
+	}
+
+	var profile agents.StackProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+	if err := profile.Validate(); err != nil {
+		apierror.Abort(c, apierror.Invalid(err.Error()))
+		return
+	}
+
+	if err := h.store.SetProfile(c.Request.Context(), taskContext.TenantID, &profile); err != nil {
+		if errors.Is(err, agents.ErrTenantNotFound) {
+			apierror.Abort(c, apierror.New(apierror.CategoryNotFound, "Tenant not found"))
+			return
+		}
+		h.logger.Error("Failed to save stack profile", apierror.Fields(err)...)
+		apierror.Abort(c, apierror.New(apierror.CategoryInternal, "Failed to save stack profile"))
+		return
+	}
+
+	h.logger.Info("Stack profile updated", zap.String("tenant_id", taskContext.TenantID.String()))
+	c.JSON(http.StatusOK, &profile)
+}
+
+// taskContext returns the authenticated context, writing a 401 if missing
+func (h *StackProfileHandlers) taskContext(c *gin.Context) (*agents.TaskContext, bool) {
+	if ctx, exists := c.Get("task_context"); exists {
+		if taskContext, ok := ctx.(*agents.TaskContext); ok {
+			return taskContext, true
+		}
+	}
+	apierror.Abort(c, apierror.New(apierror.CategoryUnauthorized, "Authentication required"))
+	return nil, false
+}
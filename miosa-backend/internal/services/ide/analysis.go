@@ -0,0 +1,361 @@
+package ide
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents/quality"
+	"golang.org/x/net/websocket"
+)
+
+// Diagnostic severities, named as editors' language clients name them
+const (
+	SeverityError       = "error"
+	SeverityWarning     = "warning"
+	SeverityInformation = "information"
+)
+
+// DiagnosticSource names the analyzer in each diagnostic
+const DiagnosticSource = "code-assurance"
+
+// WebSocket frame types. The server pushes diagnostics frames and answers
+// ping with pong; clients may also send analyze to request a file's
+// diagnostics without saving it.
+const (
+	FrameDiagnostics = "diagnostics"
+	FrameAnalyze     = "analyze"
+	FramePing        = "ping"
+	FramePong        = "pong"
+)
+
+// DefaultAnalysisDelay is how long a file must go unsaved before it is
+// analyzed, so a burst of saves is analyzed once
+const DefaultAnalysisDelay = 300 * time.Millisecond
+
+// MaxAnalysisBytes skips files too large to analyze on every save
+const MaxAnalysisBytes = 1 << 20
+
+// analysisTimeout bounds one file's analysis
+const analysisTimeout = 30 * time.Second
+
+// Diagnostic is one finding, positioned for inline display
+type Diagnostic struct {
+	Line     int    `json:"line"` // 1-based
+	EndLine  int    `json:"endLine,omitempty"`
+	Severity string `json:"severity"`
+	Code     string `json:"code,omitempty"` // Rule that produced the finding
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Fix      string `json:"fix,omitempty"`
+}
+
+// FileDiagnostics is every diagnostic for one version of a file. An empty
+// list clears the file's earlier diagnostics.
+type FileDiagnostics struct {
+	Type        string       `json:"type"`
+	Path        string       `json:"path"`
+	Hash        string       `json:"hash,omitempty"` // SHA-256 of the analyzed content
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Cached      bool         `json:"cached"`
+	DurationMS  int64        `json:"durationMs"`
+}
+
+// Analyzer runs the Code Assurance static heuristics on files as they are
+// saved and pushes the results to WebSocket subscribers. Results are
+// cached per file by content hash, so saving unchanged content costs a
+// hash.
+type Analyzer struct {
+	delay time.Duration
+
+	mu          sync.Mutex
+	cache       map[string]*FileDiagnostics // By path
+	timers      map[string]*time.Timer
+	subscribers map[chan *FileDiagnostics]struct{}
+}
+
+// NewAnalyzer creates an analyzer waiting delay after the last save of a
+// file before analyzing it
+func NewAnalyzer(delay time.Duration) *Analyzer {
+	return &Analyzer{
+		delay:       delay,
+		cache:       make(map[string]*FileDiagnostics),
+		timers:      make(map[string]*time.Timer),
+		subscribers: make(map[chan *FileDiagnostics]struct{}),
+	}
+}
+
+// Analyze returns path's diagnostics for content, reusing the cached
+// result when the content is unchanged. New results are pushed to
+// subscribers.
+func (a *Analyzer) Analyze(ctx context.Context, path, content string) *FileDiagnostics {
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	if cached, ok := a.cache[path]; ok && cached.Hash == hash {
+		a.mu.Unlock()
+		result := *cached
+		result.Cached = true
+		return &result
+	}
+	a.mu.Unlock()
+
+	start := time.Now()
+	result := &FileDiagnostics{Type: FrameDiagnostics, Path: path, Hash: hash, Diagnostics: []Diagnostic{}}
+	assurance, err := quality.RunCodeAssurance(ctx, nil, quality.CodeAssuranceRequest{
+		Files: []quality.CodeFile{{Path: path, Content: content}},
+	})
+	if err != nil {
+		log.Printf("Failed to analyze %s: %v", path, err)
+	} else {
+		for _, f := range assurance.Findings {
+			result.Diagnostics = append(result.Diagnostics, toDiagnostic(f))
+		}
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+
+	a.mu.Lock()
+	a.cache[path] = result
+	a.mu.Unlock()
+	a.publish(result)
+
+	copied := *result
+	return &copied
+}
+
+// Schedule analyzes path once it has gone DefaultAnalysisDelay without
+// another call, reading the content saved at that point
+func (a *Analyzer) Schedule(path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if timer, ok := a.timers[path]; ok {
+		timer.Stop()
+	}
+	a.timers[path] = time.AfterFunc(a.delay, func() {
+		a.mu.Lock()
+		delete(a.timers, path)
+		a.mu.Unlock()
+
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() > MaxAnalysisBytes || !isTextFile(filepath.Base(path)) {
+			return
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), analysisTimeout)
+		defer cancel()
+		a.Analyze(ctx, path, string(content))
+	})
+}
+
+// Forget drops path's diagnostics, telling subscribers to clear them
+func (a *Analyzer) Forget(path string) {
+	a.mu.Lock()
+	if timer, ok := a.timers[path]; ok {
+		timer.Stop()
+		delete(a.timers, path)
+	}
+	_, known := a.cache[path]
+	delete(a.cache, path)
+	a.mu.Unlock()
+
+	if known {
+		a.publish(&FileDiagnostics{Type: FrameDiagnostics, Path: path, Diagnostics: []Diagnostic{}})
+	}
+}
+
+// Snapshot returns the cached diagnostics of every analyzed file, by path
+func (a *Analyzer) Snapshot() []*FileDiagnostics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	snapshot := make([]*FileDiagnostics, 0, len(a.cache))
+	for _, d := range a.cache {
+		copied := *d
+		copied.Cached = true
+		snapshot = append(snapshot, &copied)
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Path < snapshot[j].Path })
+	return snapshot
+}
+
+// subscribe registers a channel receiving each new result. Slow
+// subscribers miss pushes rather than delay analysis.
+func (a *Analyzer) subscribe() chan *FileDiagnostics {
+	ch := make(chan *FileDiagnostics, 32)
+	a.mu.Lock()
+	a.subscribers[ch] = struct{}{}
+	a.mu.Unlock()
+	return ch
+}
+
+func (a *Analyzer) unsubscribe(ch chan *FileDiagnostics) {
+	a.mu.Lock()
+	delete(a.subscribers, ch)
+	a.mu.Unlock()
+}
+
+func (a *Analyzer) publish(d *FileDiagnostics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for ch := range a.subscribers {
+		copied := *d
+		select {
+		case ch <- &copied:
+		default:
+			log.Printf("Dropped diagnostics for %s: subscriber is not reading", d.Path)
+		}
+	}
+}
+
+// toDiagnostic positions a finding for the editor
+func toDiagnostic(f quality.Finding) Diagnostic {
+	d := Diagnostic{
+		Line:    f.LineStart,
+		EndLine: f.LineEnd,
+		Code:    f.Rule,
+		Source:  DiagnosticSource,
+		Message: f.Title,
+		Detail:  f.Description,
+		Fix:     f.Remediation,
+	}
+	if d.Line <= 0 {
+		d.Line = 1
+	}
+	switch strings.ToLower(f.Severity) {
+	case "critical", "high":
+		d.Severity = SeverityError
+	case "medium":
+		d.Severity = SeverityWarning
+	default:
+		d.Severity = SeverityInformation
+	}
+	return d
+}
+
+// AnalyzeFile analyzes a file immediately. The body's content is analyzed
+// when given, so editors can check unsaved changes; otherwise the file on
+// disk is read.
+func (s *IDEService) AnalyzeFile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path    string  `json:"path"`
+		Content *string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		http.Error(w, "Invalid JSON: path is required", http.StatusBadRequest)
+		return
+	}
+
+	// Security check
+	if !s.readable(r, req.Path) {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	var content string
+	if req.Content != nil {
+		content = *req.Content
+	} else {
+		data, err := os.ReadFile(req.Path)
+		if err != nil {
+			http.Error(w, "Failed to read file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		content = string(data)
+	}
+	if len(content) > MaxAnalysisBytes {
+		http.Error(w, "File too large to analyze", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Analyzer.Analyze(r.Context(), req.Path, content))
+}
+
+// Diagnostics upgrades to a WebSocket that first sends the diagnostics of
+// every analyzed file, then each new result as files are saved. A
+// session only hears about files in its workspace.
+func (s *IDEService) Diagnostics(w http.ResponseWriter, r *http.Request) {
+	websocket.Server{Handler: s.serveDiagnostics}.ServeHTTP(w, r)
+}
+
+// diagnosticsRequest is a frame sent by the editor
+type diagnosticsRequest struct {
+	Type string `json:"type"`
+	Path string `json:"path,omitempty"`
+}
+
+func (s *IDEService) serveDiagnostics(ws *websocket.Conn) {
+	updates := s.Analyzer.subscribe()
+	defer s.Analyzer.unsubscribe(updates)
+	root := s.scope(ws.Request())
+
+	var writeMu sync.Mutex
+	send := func(frame interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return websocket.JSON.Send(ws, frame)
+	}
+	for _, d := range s.Analyzer.Snapshot() {
+		if !within(root, d.Path) {
+			continue
+		}
+		if err := send(d); err != nil {
+			return
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var req diagnosticsRequest
+			if err := websocket.JSON.Receive(ws, &req); err != nil {
+				var syntaxErr *json.SyntaxError
+				if errors.As(err, &syntaxErr) {
+					continue
+				}
+				if !errors.Is(err, io.EOF) {
+					log.Printf("Diagnostics connection closed: %v", err)
+				}
+				return
+			}
+			switch req.Type {
+			case FramePing:
+				send(map[string]string{"type": FramePong})
+			case FrameAnalyze:
+				if req.Path != "" && within(root, req.Path) {
+					s.Analyzer.Schedule(req.Path)
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case d := <-updates:
+			if !within(root, d.Path) {
+				continue
+			}
+			if err := send(d); err != nil {
+				return
+			}
+		}
+	}
+}
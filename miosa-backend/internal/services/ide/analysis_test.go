@@ -0,0 +1,121 @@
+package ide
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+const panicky = "package main\n\nfunc main() {\n\tpanic(\"boom\")\n}\n"
+
+func TestAnalyzer_CachesByContentHash(t *testing.T) {
+	a := NewAnalyzer(time.Millisecond)
+	ctx := context.Background()
+
+	first := a.Analyze(ctx, "/work/main.go", panicky)
+	assert.False(t, first.Cached)
+	require.Len(t, first.Diagnostics, 1)
+	assert.Equal(t, Diagnostic{
+		Line:     4,
+		Severity: SeverityWarning,
+		Code:     "Go.PanicUsage",
+		Source:   DiagnosticSource,
+		Message:  "Use of panic in application code",
+		Detail:   first.Diagnostics[0].Detail,
+		Fix:      first.Diagnostics[0].Fix,
+	}, first.Diagnostics[0])
+
+	again := a.Analyze(ctx, "/work/main.go", panicky)
+	assert.True(t, again.Cached)
+	assert.Equal(t, first.Hash, again.Hash)
+
+	fixed := a.Analyze(ctx, "/work/main.go", "package main\n\nfunc main() {}\n")
+	assert.False(t, fixed.Cached)
+	assert.NotEqual(t, first.Hash, fixed.Hash)
+	assert.Empty(t, fixed.Diagnostics)
+}
+
+func TestAnalyzer_ScheduleDebounces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte(panicky), 0644))
+
+	a := NewAnalyzer(20 * time.Millisecond)
+	updates := a.subscribe()
+	defer a.unsubscribe(updates)
+
+	for i := 0; i < 3; i++ {
+		a.Schedule(path)
+	}
+
+	select {
+	case d := <-updates:
+		assert.Equal(t, path, d.Path)
+		assert.Len(t, d.Diagnostics, 1)
+	case <-time.After(time.Second):
+		t.Fatal("no diagnostics pushed")
+	}
+	select {
+	case d := <-updates:
+		t.Fatalf("unexpected second push for %s", d.Path)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	a.Forget(path)
+	cleared := <-updates
+	assert.Empty(t, cleared.Diagnostics)
+	assert.Empty(t, a.Snapshot())
+}
+
+func TestIDEService_PushesDiagnosticsOnSave(t *testing.T) {
+	dir := t.TempDir()
+	s := NewIDEService(dir)
+	s.Analyzer = NewAnalyzer(time.Millisecond)
+	r := mux.NewRouter()
+	s.RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ide/ws"
+	ws, err := websocket.Dial(wsURL, "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	require.NoError(t, websocket.JSON.Send(ws, diagnosticsRequest{Type: FramePing}))
+	var pong map[string]string
+	require.NoError(t, websocket.JSON.Receive(ws, &pong))
+	assert.Equal(t, FramePong, pong["type"])
+
+	path := filepath.Join(dir, "main.go")
+	body, _ := json.Marshal(map[string]string{"path": path, "content": panicky})
+	resp, err := http.Post(server.URL+"/api/ide/file", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var pushed FileDiagnostics
+	require.NoError(t, websocket.JSON.Receive(ws, &pushed))
+	assert.Equal(t, FrameDiagnostics, pushed.Type)
+	assert.Equal(t, path, pushed.Path)
+	require.Len(t, pushed.Diagnostics, 1)
+	assert.Equal(t, "Go.PanicUsage", pushed.Diagnostics[0].Code)
+
+	outside, _ := json.Marshal(map[string]string{"path": "/etc/passwd"})
+	resp, err = http.Post(server.URL+"/api/ide/analyze", "application/json", bytes.NewReader(outside))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,356 @@
+package ide
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+// DefaultSessionTTL is how long a session token lasts unless issued for
+// another duration
+const DefaultSessionTTL = 8 * time.Hour
+
+// WriteAll in SessionClaims.Write lets a session modify its whole workspace
+const WriteAll = "."
+
+// SessionClaims are the claims of an IDE session token. A token opens one
+// workspace, a project directory under the IDE root; it may read anything
+// in it but only write under the directories in Write.
+type SessionClaims struct {
+	WorkflowID string   `json:"workflow_id"`
+	Workspace  string   `json:"workspace"`       // Relative to the IDE root
+	Write      []string `json:"write,omitempty"` // Relative to the workspace; WriteAll for all of it
+	jwt.RegisteredClaims
+}
+
+// Grant is what a session token is issued for
+type Grant struct {
+	WorkflowID string
+	Workspace  string
+	Subject    string // The user or agent the token is for
+	Write      []string
+	TTL        time.Duration // The issuer's TTL when zero; capped at it
+}
+
+// TokenIssuer signs session tokens with the secret the IDE server verifies
+// them with. The orchestrator issues a token for each workspace it opens.
+type TokenIssuer struct {
+	secret []byte
+	ttl    time.Duration // The longest a token lasts
+}
+
+// NewTokenIssuer creates an issuer whose tokens last ttl unless a grant asks
+// for less
+func NewTokenIssuer(secret string, ttl time.Duration) *TokenIssuer {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &TokenIssuer{secret: []byte(secret), ttl: ttl}
+}
+
+// Issue returns a token for g and when it expires
+func (i *TokenIssuer) Issue(g Grant) (string, time.Time, error) {
+	if g.WorkflowID == "" {
+		return "", time.Time{}, errors.New("grant has no workflow")
+	}
+	if _, err := workspacePath("/", g.Workspace); err != nil {
+		return "", time.Time{}, err
+	}
+	for _, dir := range g.Write {
+		if _, err := workspacePath("/", dir); err != nil && dir != WriteAll {
+			return "", time.Time{}, fmt.Errorf("invalid write scope: %w", err)
+		}
+	}
+	ttl := g.TTL
+	if ttl <= 0 || ttl > i.ttl {
+		ttl = i.ttl
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expires := now.Add(ttl)
+	claims := SessionClaims{
+		WorkflowID: g.WorkflowID,
+		Workspace:  g.Workspace,
+		Write:      g.Write,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(id),
+			Subject:   g.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign session token: %w", err)
+	}
+	return token, expires, nil
+}
+
+// Session is an authenticated request's token and the directory it opens
+type Session struct {
+	Claims SessionClaims
+	Root   string // Absolute path of the workspace
+}
+
+type sessionKey struct{}
+
+// SessionFrom returns the request's session, nil when the server does not
+// require authentication
+func SessionFrom(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// Authenticator verifies session tokens and keeps the ones revoked before
+// they expire
+type Authenticator struct {
+	secret []byte
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // Token ID to its expiry
+}
+
+// NewAuthenticator creates an authenticator for tokens signed with secret
+func NewAuthenticator(secret string) *Authenticator {
+	return &Authenticator{secret: []byte(secret), revoked: make(map[string]time.Time)}
+}
+
+// Parse verifies token and returns its claims
+func (a *Authenticator) Parse(token string) (*SessionClaims, error) {
+	claims := &SessionClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.revoked[claims.ID]; ok {
+		return nil, errors.New("token has been revoked")
+	}
+	return claims, nil
+}
+
+// Revoke ends the session of claims before it expires
+func (a *Authenticator) Revoke(claims SessionClaims) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for id, expires := range a.revoked {
+		if expires.Before(now) {
+			delete(a.revoked, id)
+		}
+	}
+	if claims.ExpiresAt != nil {
+		a.revoked[claims.ID] = claims.ExpiresAt.Time
+	}
+}
+
+// middleware authenticates requests with a bearer token, or a token query
+// parameter for WebSockets, and opens the workspace it names under root
+func (a *Authenticator) middleware(root string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || token == r.Header.Get("Authorization") {
+				token = r.URL.Query().Get("token")
+			}
+			if token == "" {
+				http.Error(w, "Missing session token", http.StatusUnauthorized)
+				return
+			}
+			claims, err := a.Parse(token)
+			if err != nil {
+				http.Error(w, "Invalid or expired session token", http.StatusUnauthorized)
+				return
+			}
+			dir, err := workspacePath(root, claims.Workspace)
+			if err != nil {
+				http.Error(w, "Access denied", http.StatusForbidden)
+				return
+			}
+			ctx := context.WithValue(r.Context(), sessionKey{}, &Session{Claims: *claims, Root: dir})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// workspacePath joins rel to root, refusing absolute paths, root itself and
+// paths that leave it
+func workspacePath(root, rel string) (string, error) {
+	if !filepath.IsLocal(rel) || filepath.Clean(rel) == "." {
+		return "", fmt.Errorf("invalid workspace %q", rel)
+	}
+	return filepath.Join(root, rel), nil
+}
+
+// within reports whether path is root or inside it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// scope returns the directory the request may read: its session's
+// workspace, or the whole root without authentication
+func (s *IDEService) scope(r *http.Request) string {
+	if session := SessionFrom(r.Context()); session != nil {
+		return session.Root
+	}
+	return s.RootPath
+}
+
+// readable reports whether the request may read path
+func (s *IDEService) readable(r *http.Request, path string) bool {
+	return path != "" && within(s.scope(r), path)
+}
+
+// writable checks that the request may create, change or delete path. A
+// session must have path in its write scope, and the workspace must belong
+// to the session's workflow when an owner is recorded.
+func (s *IDEService) writable(r *http.Request, path string) error {
+	if !s.readable(r, path) {
+		return errors.New("path is outside the workspace")
+	}
+	session := SessionFrom(r.Context())
+	if session == nil {
+		return nil
+	}
+
+	allowed := false
+	for _, dir := range session.Claims.Write {
+		if within(filepath.Join(session.Root, dir), path) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return errors.New("session may not write here")
+	}
+	if within(filepath.Join(session.Root, workspace.StateDir), path) {
+		return errors.New("workspace state is read-only")
+	}
+	owner, err := workspace.ReadOwner(session.Root)
+	if err != nil {
+		return err
+	}
+	if owner.WorkflowID != "" && owner.WorkflowID != session.Claims.WorkflowID {
+		return errors.New("workspace belongs to another workflow")
+	}
+	return nil
+}
+
+// GetSession returns the caller's workspace, write scope and expiry
+func (s *IDEService) GetSession(w http.ResponseWriter, r *http.Request) {
+	session := SessionFrom(r.Context())
+	if session == nil {
+		http.Error(w, "Authentication is disabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workflow_id": session.Claims.WorkflowID,
+		"workspace":   session.Root,
+		"subject":     session.Claims.Subject,
+		"write":       session.Claims.Write,
+		"expires_at":  session.Claims.ExpiresAt.Time,
+	})
+}
+
+// EndSession revokes the caller's token
+func (s *IDEService) EndSession(w http.ResponseWriter, r *http.Request) {
+	session := SessionFrom(r.Context())
+	if session == nil || s.Auth == nil {
+		http.Error(w, "Authentication is disabled", http.StatusNotFound)
+		return
+	}
+	s.Auth.Revoke(session.Claims)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionRequest is the body of POST /api/workflow/{id}/ide-session
+type sessionRequest struct {
+	Subject string `json:"subject"`
+	TTL     string `json:"ttl"` // A Go duration, e.g. "30m"; capped at the issuer's TTL
+}
+
+// IssueHandler serves POST /api/workflow/{id}/ide-session, issuing a token
+// for the project directory of the workflow. ideRoot is the directory the
+// IDE server serves, and root returns a workflow's project directory under it.
+// write returns the directories a session may write given the workflow's
+// state; the token is read-only when it returns none. It does not
+// authenticate the caller: serve it behind a check that the caller may edit
+// the workflow.
+func IssueHandler(issuer *TokenIssuer, ideRoot string, root func(workflowID uuid.UUID) string, write func(workflowID uuid.UUID) []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "invalid workflow id", http.StatusBadRequest)
+			return
+		}
+		var req sessionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+				http.Error(w, "invalid ttl", http.StatusBadRequest)
+				return
+			}
+		}
+
+		dir := root(id)
+		owner, err := workspace.ReadOwner(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if owner.WorkflowID != id.String() {
+			http.Error(w, "no workspace recorded for workflow", http.StatusNotFound)
+			return
+		}
+		rel, err := filepath.Rel(ideRoot, dir)
+		if err != nil {
+			http.Error(w, "workspace is outside the IDE root", http.StatusInternalServerError)
+			return
+		}
+
+		scopes := write(id)
+		token, expires, err := issuer.Issue(Grant{WorkflowID: id.String(), Workspace: rel, Subject: req.Subject, Write: scopes, TTL: ttl})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      token,
+			"expires_at": expires,
+			"workspace":  rel,
+			"write":      scopes,
+		})
+	}
+}
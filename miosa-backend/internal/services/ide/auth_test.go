@@ -0,0 +1,163 @@
+package ide
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+func TestIDEService_SessionScopesReadsAndWrites(t *testing.T) {
+	root := t.TempDir()
+	workflow := uuid.New()
+	project := filepath.Join(root, "proj")
+	require.NoError(t, workspace.RecordOwner(project, workspace.Owner{WorkflowID: workflow.String()}))
+	other := filepath.Join(root, "other")
+	require.NoError(t, os.MkdirAll(other, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(other, "secret.go"), []byte("package other"), 0644))
+
+	s := NewIDEService(root)
+	s.Auth = NewAuthenticator("secret")
+	r := mux.NewRouter()
+	s.RegisterRoutes(r)
+
+	issuer := NewTokenIssuer("secret", time.Hour)
+	issue := func(g Grant) string {
+		token, _, err := issuer.Issue(g)
+		require.NoError(t, err)
+		return token
+	}
+	reader := issue(Grant{WorkflowID: workflow.String(), Workspace: "proj"})
+	writer := issue(Grant{WorkflowID: workflow.String(), Workspace: "proj", Write: []string{"src"}})
+	stranger := issue(Grant{WorkflowID: uuid.NewString(), Workspace: "proj", Write: []string{WriteAll}})
+
+	do := func(token, method, target string, body interface{}) int {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	save := func(token, path string) int {
+		return do(token, http.MethodPost, "/api/ide/file", map[string]string{"path": path, "content": "package main"})
+	}
+
+	inSrc := filepath.Join(project, "src", "main.go")
+	assert.Equal(t, http.StatusUnauthorized, save("", inSrc))
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, SessionClaims{
+		WorkflowID:       workflow.String(),
+		Workspace:        "proj",
+		Write:            []string{WriteAll},
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, save(expired, inSrc))
+	assert.Equal(t, http.StatusUnauthorized, save("not-a-token", inSrc))
+	assert.Equal(t, http.StatusForbidden, save(reader, inSrc), "read-only sessions cannot write")
+	assert.Equal(t, http.StatusForbidden, save(writer, filepath.Join(project, "main.go")), "outside the write scope")
+	assert.Equal(t, http.StatusForbidden, save(writer, filepath.Join(project, "src", "..", workspace.OwnerFile)))
+	assert.Equal(t, http.StatusForbidden, save(stranger, inSrc), "workspace of another workflow")
+	assert.Equal(t, http.StatusOK, save(writer, inSrc))
+
+	assert.Equal(t, http.StatusOK, do(reader, http.MethodGet, "/api/ide/file?path="+url.QueryEscape(inSrc), nil))
+	assert.Equal(t, http.StatusForbidden, do(reader, http.MethodGet, "/api/ide/file?path="+url.QueryEscape(filepath.Join(other, "secret.go")), nil))
+	assert.Equal(t, http.StatusForbidden, do(reader, http.MethodGet, "/api/ide/file?path="+url.QueryEscape(project+"x/main.go"), nil))
+
+	assert.Equal(t, http.StatusNoContent, do(writer, http.MethodDelete, "/api/ide/session", nil))
+	assert.Equal(t, http.StatusUnauthorized, save(writer, inSrc), "revoked")
+}
+
+func TestTokenIssuer_RejectsEscapingScopes(t *testing.T) {
+	issuer := NewTokenIssuer("secret", 0)
+	for _, g := range []Grant{
+		{Workspace: "proj"},
+		{WorkflowID: "w", Workspace: "../etc"},
+		{WorkflowID: "w", Workspace: "/etc"},
+		{WorkflowID: "w", Workspace: "proj", Write: []string{"../other"}},
+	} {
+		_, _, err := issuer.Issue(g)
+		assert.Error(t, err, g)
+	}
+
+	token, expires, err := issuer.Issue(Grant{WorkflowID: "w", Workspace: "proj"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultSessionTTL), expires, time.Minute)
+	_, expires, err = issuer.Issue(Grant{WorkflowID: "w", Workspace: "proj", TTL: 2 * DefaultSessionTTL})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultSessionTTL), expires, time.Minute, "capped at the issuer's TTL")
+	_, err = NewAuthenticator("other").Parse(token)
+	assert.Error(t, err, "signed with another secret")
+}
+
+func TestIssueHandler_OnlyForRecordedWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	workflow := uuid.New()
+	dir := func(id uuid.UUID) string { return filepath.Join(root, id.String()[:8]) }
+	require.NoError(t, workspace.RecordOwner(dir(workflow), workspace.Owner{WorkflowID: workflow.String()}))
+
+	finished := false
+	write := func(id uuid.UUID) []string {
+		if finished && id == workflow {
+			return []string{"src"}
+		}
+		return nil
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/workflow/{id}/ide-session", IssueHandler(NewTokenIssuer("secret", time.Hour), root, dir, write)).Methods("POST")
+	issue := func(id uuid.UUID, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/workflow/"+id.String()+"/ide-session", bytes.NewBufferString(body)))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNotFound, issue(uuid.New(), "").Code)
+	assert.Equal(t, http.StatusBadRequest, issue(workflow, `{"ttl": "soon"}`).Code)
+
+	claims := func(rec *httptest.ResponseRecorder) *SessionClaims {
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp struct {
+			Token     string `json:"token"`
+			Workspace string `json:"workspace"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, workflow.String()[:8], resp.Workspace)
+		c, err := NewAuthenticator("secret").Parse(resp.Token)
+		require.NoError(t, err)
+		return c
+	}
+
+	// The write scope comes from the workflow's state, never the body
+	running := claims(issue(workflow, `{"subject": "ana", "write": ["."], "ttl": "30m"}`))
+	assert.Equal(t, workflow.String(), running.WorkflowID)
+	assert.Empty(t, running.Write)
+	assert.Equal(t, "ana", running.Subject)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), running.ExpiresAt.Time, time.Minute)
+
+	finished = true
+	done := claims(issue(workflow, `{"write": ["."]}`))
+	assert.Equal(t, []string{"src"}, done.Write)
+
+	// A longer TTL than the issuer's is capped at it
+	long := claims(issue(workflow, `{"ttl": "720h"}`))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), long.ExpiresAt.Time, time.Minute)
+}
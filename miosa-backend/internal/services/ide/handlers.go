@@ -36,12 +36,15 @@ type CodeContent struct {
 // IDEService handles IDE-related operations
 type IDEService struct {
 	RootPath string
+	Analyzer *Analyzer
+	Auth     *Authenticator // Requires session tokens when set
 }
 
 // NewIDEService creates a new IDE service
 func NewIDEService(rootPath string) *IDEService {
 	return &IDEService{
 		RootPath: rootPath,
+		Analyzer: NewAnalyzer(DefaultAnalysisDelay),
 	}
 }
 
@@ -51,6 +54,13 @@ func (s *IDEService) RegisterRoutes(r *mux.Router) {
 	
 	// Add CORS middleware
 	api.Use(corsMiddleware)
+	if s.Auth != nil {
+		api.Use(s.Auth.middleware(s.RootPath))
+	}
+	
+	// Session of the caller's token
+	api.HandleFunc("/session", s.GetSession).Methods("GET")
+	api.HandleFunc("/session", s.EndSession).Methods("DELETE")
 	
 	// File operations
 	api.HandleFunc("/files", s.ListFiles).Methods("GET")
@@ -65,6 +75,13 @@ func (s *IDEService) RegisterRoutes(r *mux.Router) {
 	// Code history and previous versions
 	api.HandleFunc("/history", s.GetFileHistory).Methods("GET")
 	api.HandleFunc("/recent", s.GetRecentFiles).Methods("GET")
+	
+	// Diagnostics, pushed over the WebSocket as files are saved
+	api.HandleFunc("/analyze", s.AnalyzeFile).Methods("POST")
+	api.HandleFunc("/ws", s.Diagnostics).Methods("GET")
+
+	// Architecture diagrams rendered for preview
+	api.HandleFunc("/preview", s.PreviewDiagram).Methods("GET")
 }
 
 // corsMiddleware adds CORS headers
@@ -87,11 +104,11 @@ func corsMiddleware(next http.Handler) http.Handler {
 func (s *IDEService) ListFiles(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Query().Get("path")
 	if path == "" {
-		path = s.RootPath
+		path = s.scope(r)
 	}
 	
-	// Security check - ensure path is within root
-	if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(s.RootPath)) {
+	// Security check - ensure path is within the workspace
+	if !s.readable(r, path) {
 		http.Error(w, "Access denied", http.StatusForbidden)
 		return
 	}
@@ -146,7 +163,7 @@ func (s *IDEService) GetFile(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Security check
-	if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(s.RootPath)) {
+	if !s.readable(r, path) {
 		http.Error(w, "Access denied", http.StatusForbidden)
 		return
 	}
@@ -186,8 +203,8 @@ func (s *IDEService) SaveFile(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Security check
-	if !strings.HasPrefix(filepath.Clean(req.Path), filepath.Clean(s.RootPath)) {
-		http.Error(w, "Access denied", http.StatusForbidden)
+	if err := s.writable(r, req.Path); err != nil {
+		http.Error(w, "Access denied: "+err.Error(), http.StatusForbidden)
 		return
 	}
 	
@@ -202,6 +219,7 @@ func (s *IDEService) SaveFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to save file: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.Analyzer.Schedule(req.Path)
 	
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "saved"})
@@ -216,8 +234,8 @@ func (s *IDEService) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Security check
-	if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(s.RootPath)) {
-		http.Error(w, "Access denied", http.StatusForbidden)
+	if err := s.writable(r, path); err != nil {
+		http.Error(w, "Access denied: "+err.Error(), http.StatusForbidden)
 		return
 	}
 	
@@ -225,6 +243,7 @@ func (s *IDEService) DeleteFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to delete file: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.Analyzer.Forget(path)
 	
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
@@ -232,7 +251,7 @@ func (s *IDEService) DeleteFile(w http.ResponseWriter, r *http.Request) {
 
 // GetFileTree returns a hierarchical file tree
 func (s *IDEService) GetFileTree(w http.ResponseWriter, r *http.Request) {
-	tree, err := s.buildFileTree(s.RootPath, 0, 3) // Max depth of 3
+	tree, err := s.buildFileTree(s.scope(r), 0, 3) // Max depth of 3
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to build file tree: %v", err), http.StatusInternalServerError)
 		return
@@ -322,7 +341,7 @@ func (s *IDEService) SearchFiles(w http.ResponseWriter, r *http.Request) {
 	
 	var results []FileInfo
 	
-	err := filepath.Walk(s.RootPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(s.scope(r), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Continue walking
 		}
@@ -388,7 +407,7 @@ func (s *IDEService) GetFileHistory(w http.ResponseWriter, r *http.Request) {
 	// This is a basic implementation - in a real IDE you'd track actual history
 	var history []map[string]interface{}
 	
-	err := filepath.Walk(s.RootPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(s.scope(r), func(path string, info os.FileInfo, err error) error {
 		if err != nil || info.IsDir() {
 			return nil
 		}
@@ -436,7 +455,7 @@ func (s *IDEService) GetFileHistory(w http.ResponseWriter, r *http.Request) {
 func (s *IDEService) GetRecentFiles(w http.ResponseWriter, r *http.Request) {
 	var recent []FileInfo
 	
-	err := filepath.Walk(s.RootPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(s.scope(r), func(path string, info os.FileInfo, err error) error {
 		if err != nil || info.IsDir() {
 			return nil
 		}
@@ -533,6 +552,12 @@ func getLanguageFromExtension(filename string) string {
 		return "bash"
 	case ".dockerfile":
 		return "dockerfile"
+	case ".mmd":
+		return "mermaid"
+	case ".puml":
+		return "plantuml"
+	case ".svg":
+		return "svg"
 	default:
 		return "text"
 	}
@@ -549,6 +574,7 @@ func isTextFile(filename string) bool {
 		".md": true, ".txt": true, ".sql": true, ".sh": true,
 		".dockerfile": true, ".gitignore": true, ".env": true,
 		".conf": true, ".ini": true, ".toml": true,
+		".mmd": true, ".puml": true, ".svg": true,
 	}
 	
 	return textExts[ext] || ext == ""
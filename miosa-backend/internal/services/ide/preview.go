@@ -0,0 +1,51 @@
+package ide
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/sormind/OSA/miosa-backend/internal/diagram"
+)
+
+// MaxPreviewBytes bounds the diagram sources PreviewDiagram renders
+const MaxPreviewBytes = 256 << 10
+
+// PreviewDiagram renders a Mermaid flowchart file (.mmd) to SVG. Sources
+// outside the subset diagram.ParseMermaid accepts are rejected with the
+// offending line, so editors can show why a preview is missing.
+func (s *IDEService) PreviewDiagram(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "Path parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	// Security check
+	if !s.readable(r, path) {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+	if filepath.Ext(path) != ".mmd" {
+		http.Error(w, "Only Mermaid (.mmd) files can be previewed", http.StatusBadRequest)
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		http.Error(w, "Failed to read file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(content) > MaxPreviewBytes {
+		http.Error(w, "File too large to preview", http.StatusRequestEntityTooLarge)
+		return
+	}
+	chart, err := diagram.ParseMermaid(string(content))
+	if err != nil {
+		http.Error(w, "Invalid diagram: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(chart.SVG())
+}
@@ -0,0 +1,45 @@
+package ide
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDEService_PreviewDiagram(t *testing.T) {
+	dir := t.TempDir()
+	s := NewIDEService(dir)
+	r := mux.NewRouter()
+	s.RegisterRoutes(r)
+
+	valid := filepath.Join(dir, "architecture.mmd")
+	require.NoError(t, os.WriteFile(valid, []byte("flowchart LR\n    svc_api(\"api\")\n    tbl_users[(\"users\")]\n    svc_api -->|uses| tbl_users\n"), 0644))
+	invalid := filepath.Join(dir, "broken.mmd")
+	require.NoError(t, os.WriteFile(invalid, []byte("flowchart LR\n    a --> b\n"), 0644))
+
+	preview := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ide/preview?path="+url.QueryEscape(path), nil))
+		return rec
+	}
+
+	rec := preview(valid)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), ">users</tspan>")
+
+	rec = preview(invalid)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "line 2: undeclared node a")
+
+	assert.Equal(t, http.StatusForbidden, preview("/etc/passwd").Code)
+	assert.Equal(t, http.StatusBadRequest, preview(filepath.Join(dir, "main.go")).Code)
+	assert.Equal(t, "mermaid", getLanguageFromExtension("architecture.mmd"))
+}
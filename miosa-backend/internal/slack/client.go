@@ -0,0 +1,122 @@
+// Package slack serves the command registry as a Slack slash command. Each
+// /miosa invocation is acknowledged inside Slack's three second window, runs
+// in the background and reports into a message thread.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the Slack Web API
+const DefaultAPIURL = "https://slack.com/api"
+
+// MaxRequestAge bounds the X-Slack-Request-Timestamp accepted, so a captured
+// request cannot be replayed later
+const MaxRequestAge = 5 * time.Minute
+
+// ErrStaleRequest is returned for requests signed more than MaxRequestAge ago
+var ErrStaleRequest = errors.New("slack request timestamp is too old")
+
+// ErrBadSignature is returned when X-Slack-Signature does not match the body
+var ErrBadSignature = errors.New("invalid slack signature")
+
+// VerifyRequest checks Slack's v0 signature: an HMAC-SHA256 of
+// "v0:<timestamp>:<body>" keyed with the app's signing secret
+func VerifyRequest(secret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > MaxRequestAge || age < -MaxRequestAge {
+		return ErrStaleRequest
+	}
+
+	sig, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return ErrBadSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Client posts messages with a bot token
+type Client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient returns a Web API client for token. An empty baseURL uses
+// DefaultAPIURL.
+func NewClient(token, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &Client{
+		token:   token,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Message is a chat.postMessage request
+type Message struct {
+	Channel  string `json:"channel"`
+	Text     string `json:"text"`
+	ThreadTS string `json:"thread_ts,omitempty"` // Reply in the thread of this message
+}
+
+// PostMessage posts msg and returns its ts, which identifies the message
+// for threaded replies
+func (c *Client) PostMessage(ctx context.Context, msg Message) (string, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to post slack message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The Web API reports failures in the body, usually with a 200
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode slack response: %s: %w", resp.Status, err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack chat.postMessage failed: %s", result.Error)
+	}
+	return result.TS, nil
+}
@@ -0,0 +1,244 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/claude"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+// maxOutputChars keeps command output well under Slack's message limit
+const maxOutputChars = 3500
+
+// Executor runs a slash command from the command registry, e.g.
+// "/analyze code --depth deep". claude.CommandExecutor implements it.
+type Executor interface {
+	ExecuteCommand(ctx context.Context, input string) (*claude.CommandResult, error)
+}
+
+// Command is a slash command invocation as Slack posts it
+type Command struct {
+	Command   string // The slash command itself, e.g. "/miosa"
+	Text      string // Everything after it
+	UserID    string
+	UserName  string
+	ChannelID string
+	TeamID    string
+}
+
+// Service runs slash commands
+type Service struct {
+	secret   string
+	slack    *Client
+	executor Executor
+	backend  orchestrate.Backend
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewService creates a service verifying requests with signingSecret.
+// orchestrate and status run workflows on backend; every other command is
+// passed to executor. backend audits the workflows it runs.
+func NewService(signingSecret string, client *Client, executor Executor, backend orchestrate.Backend, logger *zap.Logger) *Service {
+	return &Service{
+		secret:   signingSecret,
+		slack:    client,
+		executor: executor,
+		backend:  backend,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Help is the ephemeral reply to an empty /miosa or /miosa help
+const Help = "*MIOSA commands*\n" +
+	"• `/miosa orchestrate <description>` run a workflow, with progress in a thread\n" +
+	"• `/miosa status [workflow id]` show a workflow, or agent status without an id\n" +
+	"• `/miosa <command> [args]` run any registry command, e.g. `/miosa analyze code --depth deep`\n" +
+	"• `/miosa help <command>` show a command's parameters"
+
+// CommandHandler serves POST /api/slack/commands. Slack gives a slash
+// command three seconds to respond, so the command is acknowledged at once
+// and runs in the background, posting its results in a thread.
+func (s *Service) CommandHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64<<10))
+		if err != nil {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := VerifyRequest(s.secret, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), body, s.now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "invalid form body", http.StatusBadRequest)
+			return
+		}
+		cmd := Command{
+			Command:   form.Get("command"),
+			Text:      strings.TrimSpace(form.Get("text")),
+			UserID:    form.Get("user_id"),
+			UserName:  form.Get("user_name"),
+			ChannelID: form.Get("channel_id"),
+			TeamID:    form.Get("team_id"),
+		}
+
+		if cmd.Text == "" || cmd.Text == "help" {
+			reply(w, "ephemeral", Help)
+			return
+		}
+		go s.Run(context.Background(), cmd)
+		reply(w, "ephemeral", fmt.Sprintf("Running `%s %s`…", cmd.Command, cmd.Text))
+	}
+}
+
+func reply(w http.ResponseWriter, responseType, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"response_type": responseType, "text": text})
+}
+
+// Run executes cmd, posting a message to its channel and the results in
+// that message's thread
+func (s *Service) Run(ctx context.Context, cmd Command) {
+	ts, err := s.slack.PostMessage(ctx, Message{
+		Channel: cmd.ChannelID,
+		Text:    fmt.Sprintf("<@%s> ran `%s %s`", cmd.UserID, cmd.Command, cmd.Text),
+	})
+	if err != nil {
+		s.logger.Error("Failed to post slack message", zap.String("channel", cmd.ChannelID), zap.Error(err))
+		return
+	}
+	post := func(text string) {
+		if _, err := s.slack.PostMessage(ctx, Message{Channel: cmd.ChannelID, Text: text, ThreadTS: ts}); err != nil {
+			s.logger.Warn("Failed to post slack reply", zap.String("channel", cmd.ChannelID), zap.Error(err))
+		}
+	}
+
+	name, args, _ := strings.Cut(cmd.Text, " ")
+	args = strings.TrimSpace(args)
+	switch strings.TrimPrefix(name, "/") {
+	case "orchestrate":
+		s.orchestrate(ctx, args, post)
+	case "status":
+		if args != "" {
+			s.status(args, post)
+			return
+		}
+		s.execute(ctx, "/status", post)
+	default:
+		s.execute(ctx, "/"+strings.TrimPrefix(cmd.Text, "/"), post)
+	}
+}
+
+// orchestrate runs a workflow, replying as each agent step finishes
+func (s *Service) orchestrate(ctx context.Context, description string, post func(string)) {
+	req := &orchestrate.Request{Description: description}
+	if err := req.Validate(); err != nil {
+		post(":x: " + err.Error())
+		return
+	}
+
+	ctx = orchestrate.Observe(ctx, func(e orchestrate.Event) {
+		if text := progress(e); text != "" {
+			post(text)
+		}
+	})
+	workflow, err := s.backend.Orchestrate(ctx, req)
+	if err != nil {
+		post(":x: Workflow failed: " + err.Error())
+		return
+	}
+	post(summary(workflow))
+}
+
+// status reports a workflow by ID
+func (s *Service) status(arg string, post func(string)) {
+	id, err := uuid.Parse(arg)
+	if err != nil {
+		post(fmt.Sprintf(":x: `%s` is not a workflow id", arg))
+		return
+	}
+	workflow, ok := s.backend.Workflow(id)
+	if !ok {
+		post(fmt.Sprintf(":x: Workflow `%s` not found", id))
+		return
+	}
+	post(summary(workflow))
+}
+
+// execute runs a registry command and replies with its output
+func (s *Service) execute(ctx context.Context, input string, post func(string)) {
+	result, err := s.executor.ExecuteCommand(ctx, input)
+	if err != nil {
+		post(":x: " + err.Error())
+		return
+	}
+	mark := ":white_check_mark:"
+	if !result.Success {
+		mark = ":warning:"
+	}
+	text := fmt.Sprintf("%s ```%s```", mark, truncate(result.Output))
+	if result.Suggestion != "" {
+		text += "\n" + result.Suggestion
+	}
+	post(text)
+}
+
+// progress renders a workflow event as a thread reply, or "" to skip it
+func progress(e orchestrate.Event) string {
+	switch e.Type {
+	case orchestrate.EventStepCompleted:
+		if e.Result == nil {
+			return fmt.Sprintf(":white_check_mark: *%s* finished", e.Agent)
+		}
+		mark := ":white_check_mark:"
+		if !e.Result.Success {
+			mark = ":warning:"
+		}
+		note := ""
+		if e.Result.Reused {
+			note = ", reused"
+		}
+		return fmt.Sprintf("%s *%s* finished in %.1fs (confidence %.2f%s)",
+			mark, e.Agent, float64(e.Result.ExecutionMS)/1000, e.Result.Confidence, note)
+	case orchestrate.EventStepFailed:
+		return fmt.Sprintf(":x: *%s* failed: %s", e.Agent, e.Error)
+	}
+	return ""
+}
+
+func summary(w *orchestrate.Workflow) string {
+	state := ":tada: Workflow `%s` completed"
+	if !w.Success {
+		state = ":x: Workflow `%s` failed"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, state+" with %d steps\n", w.ID, len(w.Steps))
+	for i, step := range w.Steps {
+		mark := ":white_check_mark:"
+		if !step.Success {
+			mark = ":warning:"
+		}
+		fmt.Fprintf(&sb, "%d. %s %s (%dms)\n", i+1, mark, step.Agent, step.ExecutionMS)
+	}
+	return sb.String()
+}
+
+func truncate(s string) string {
+	if len(s) <= maxOutputChars {
+		return s
+	}
+	return s[:maxOutputChars] + "\n…"
+}
@@ -0,0 +1,191 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/claude"
+	"github.com/sormind/OSA/miosa-backend/internal/orchestrate"
+)
+
+const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func signature(ts string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte("command=%2Fmiosa&text=status")
+
+	assert.NoError(t, VerifyRequest(secret, ts, signature(ts, string(body)), body, now))
+	assert.ErrorIs(t, VerifyRequest(secret, ts, signature(ts, "tampered"), body, now), ErrBadSignature)
+	assert.ErrorIs(t, VerifyRequest("other", ts, signature(ts, string(body)), body, now), ErrBadSignature)
+	assert.ErrorIs(t, VerifyRequest(secret, ts, signature(ts, string(body)), body, now.Add(6*time.Minute)), ErrStaleRequest)
+	assert.ErrorIs(t, VerifyRequest(secret, "soon", "v0=00", body, now), ErrBadSignature)
+}
+
+// fakeSlack records posted messages and numbers their ts
+type fakeSlack struct {
+	mu       sync.Mutex
+	messages []Message
+	posted   chan Message
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "invalid_auth"})
+		return
+	}
+	var msg Message
+	json.NewDecoder(r.Body).Decode(&msg)
+	f.messages = append(f.messages, msg)
+	if f.posted != nil {
+		f.posted <- msg
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": fmt.Sprintf("1700000000.%06d", len(f.messages))})
+}
+
+type fakeBackend struct {
+	workflow *orchestrate.Workflow
+}
+
+func (b *fakeBackend) Orchestrate(ctx context.Context, req *orchestrate.Request) (*orchestrate.Workflow, error) {
+	for i, step := range b.workflow.Steps {
+		step := step
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepStarted, Step: i, Agent: step.Agent})
+		orchestrate.Notify(ctx, orchestrate.Event{Type: orchestrate.EventStepCompleted, Step: i, Agent: step.Agent, Result: &step})
+	}
+	return b.workflow, nil
+}
+
+func (b *fakeBackend) Workflow(id uuid.UUID) (*orchestrate.Workflow, bool) {
+	return b.workflow, id == b.workflow.ID
+}
+
+func (b *fakeBackend) Agents() []orchestrate.AgentInfo { return nil }
+
+type fakeExecutor struct {
+	inputs []string
+}
+
+func (e *fakeExecutor) ExecuteCommand(ctx context.Context, input string) (*claude.CommandResult, error) {
+	e.inputs = append(e.inputs, input)
+	return &claude.CommandResult{Success: true, Output: "ran " + input}, nil
+}
+
+func newTestService(t *testing.T, api *fakeSlack) (*Service, *fakeBackend, *fakeExecutor) {
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	backend := &fakeBackend{workflow: &orchestrate.Workflow{ID: uuid.New(), Success: true, Steps: []orchestrate.StepResult{
+		{Agent: agents.AnalysisAgent, Success: true, Confidence: 0.9, ExecutionMS: 1500},
+		{Agent: agents.DevelopmentAgent, Success: false, Confidence: 0.4, ExecutionMS: 2500},
+	}}}
+	executor := &fakeExecutor{}
+	return NewService(secret, NewClient("xoxb-test", srv.URL), executor, backend, zap.NewNop()), backend, executor
+}
+
+func TestService_RunOrchestrate(t *testing.T) {
+	api := &fakeSlack{}
+	s, backend, _ := newTestService(t, api)
+
+	s.Run(context.Background(), Command{Command: "/miosa", Text: "orchestrate Build a todo API with auth", UserID: "U1", ChannelID: "C1"})
+
+	require.Len(t, api.messages, 4)
+	assert.Equal(t, Message{Channel: "C1", Text: "<@U1> ran `/miosa orchestrate Build a todo API with auth`"}, api.messages[0])
+	for _, msg := range api.messages[1:] {
+		assert.Equal(t, "1700000000.000001", msg.ThreadTS, "progress is threaded under the first message")
+	}
+	assert.Equal(t, ":white_check_mark: *analysis* finished in 1.5s (confidence 0.90)", api.messages[1].Text)
+	assert.Equal(t, ":warning: *development* finished in 2.5s (confidence 0.40)", api.messages[2].Text)
+	assert.Contains(t, api.messages[3].Text, "Workflow `"+backend.workflow.ID.String()+"` completed with 2 steps")
+}
+
+func TestService_RunStatusAndRegistry(t *testing.T) {
+	api := &fakeSlack{}
+	s, backend, executor := newTestService(t, api)
+	ctx := context.Background()
+
+	s.Run(ctx, Command{Command: "/miosa", Text: "status " + backend.workflow.ID.String(), ChannelID: "C1"})
+	assert.Contains(t, api.messages[1].Text, "completed with 2 steps")
+
+	s.Run(ctx, Command{Command: "/miosa", Text: "status " + uuid.New().String(), ChannelID: "C1"})
+	assert.Contains(t, api.messages[3].Text, "not found")
+
+	s.Run(ctx, Command{Command: "/miosa", Text: "status", ChannelID: "C1"})
+	s.Run(ctx, Command{Command: "/miosa", Text: "analyze code --depth deep", ChannelID: "C1"})
+	assert.Equal(t, []string{"/status", "/analyze code --depth deep"}, executor.inputs)
+	assert.Equal(t, ":white_check_mark: ```ran /analyze code --depth deep```", api.messages[7].Text)
+
+	s.Run(ctx, Command{Command: "/miosa", Text: "orchestrate short", ChannelID: "C1"})
+	assert.Contains(t, api.messages[9].Text, "invalid request: description")
+}
+
+func TestCommandHandler(t *testing.T) {
+	api := &fakeSlack{posted: make(chan Message, 10)}
+	s, _, executor := newTestService(t, api)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	post := func(text, sig string) *httptest.ResponseRecorder {
+		body := url.Values{"command": {"/miosa"}, "text": {text}, "user_id": {"U1"}, "channel_id": {"C1"}}.Encode()
+		if sig == "" {
+			sig = signature(ts, body)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/slack/commands", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", sig)
+		rec := httptest.NewRecorder()
+		s.CommandHandler()(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("help", "v0=00").Code)
+
+	rec := post("", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "MIOSA commands")
+
+	rec = post("recommend", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Running `/miosa recommend`")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-api.posted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("command did not run in the background")
+		}
+	}
+	assert.Equal(t, []string{"/recommend"}, executor.inputs)
+}
+
+func TestClient_PostMessageError(t *testing.T) {
+	srv := httptest.NewServer(&fakeSlack{})
+	defer srv.Close()
+	_, err := NewClient("wrong", srv.URL).PostMessage(context.Background(), Message{Channel: "C1", Text: "hi"})
+	assert.EqualError(t, err, "slack chat.postMessage failed: invalid_auth")
+}
@@ -0,0 +1,276 @@
+// Package sqlschema tokenizes Postgres DDL and builds a model of the tables,
+// keys and indexes it declares. It is shared by SQL linting and seed data
+// generation for generated projects.
+package sqlschema
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Token is a word, quoted identifier, literal or punctuation mark
+type Token struct {
+	Text   string // Identifier text without quotes; literals keep theirs
+	Line   int
+	Quoted bool // Double-quoted identifier
+}
+
+// Upper is the token as a keyword, or "" for quoted identifiers
+func (t Token) Upper() string {
+	if t.Quoted {
+		return ""
+	}
+	return strings.ToUpper(t.Text)
+}
+
+// Name folds an identifier the way Postgres does and drops any schema
+// qualifier
+func (t Token) Name() string {
+	if t.Quoted {
+		return t.Text
+	}
+	name := strings.ToLower(t.Text)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// Raw is the identifier as written, without any schema qualifier
+func (t Token) Raw() string {
+	if t.Quoted {
+		return t.Text
+	}
+	return t.Text[strings.LastIndex(t.Text, ".")+1:]
+}
+
+// Statement is one ;-terminated statement
+type Statement struct {
+	Tokens []Token
+	Line   int
+}
+
+// Has reports whether the statement contains words consecutively
+func (s Statement) Has(words ...string) bool {
+	for i := 0; i+len(words) <= len(s.Tokens); i++ {
+		match := true
+		for j, w := range words {
+			if s.Tokens[i+j].Upper() != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Tokenize splits a script into statements, dropping comments and keeping
+// string, quoted identifier and dollar-quoted bodies whole
+func Tokenize(content string) []Statement {
+	var stmts []Statement
+	var cur []Token
+	line := 1
+	emit := func(text string, start int, quoted bool) {
+		cur = append(cur, Token{Text: text, Line: start, Quoted: quoted})
+	}
+	flush := func() {
+		if len(cur) > 0 {
+			stmts = append(stmts, Statement{Tokens: cur, Line: cur[0].Line})
+			cur = nil
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(content[i:], "--"):
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(content) - i - 2
+			}
+			line += strings.Count(content[i:i+2+end], "\n")
+			i += end + 4
+		case c == '\'' || c == '"':
+			start, j := line, i+1
+			for j < len(content) {
+				if content[j] == c {
+					if j+1 < len(content) && content[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(content) {
+				j = len(content) - 1
+			}
+			line += strings.Count(content[i:j+1], "\n")
+			if c == '"' {
+				emit(strings.ReplaceAll(content[i+1:j], `""`, `"`), start, true)
+			} else {
+				emit(content[i:j+1], start, false)
+			}
+			i = j + 1
+		case c == '$' && dollarTag.MatchString(content[i:]):
+			tag := dollarTag.FindString(content[i:])
+			end := strings.Index(content[i+len(tag):], tag)
+			if end < 0 {
+				end = len(content) - i - len(tag)
+			} else {
+				end += len(tag)
+			}
+			body := content[i : i+len(tag)+end]
+			emit(body, line, false)
+			line += strings.Count(body, "\n")
+			i += len(body)
+		case c == ';':
+			flush()
+			i++
+		case isWordByte(c):
+			j := i
+			for j < len(content) && isWordByte(content[j]) {
+				j++
+			}
+			emit(content[i:j], line, false)
+			i = j
+		default:
+			emit(string(c), line, false)
+			i++
+		}
+	}
+	flush()
+	return stmts
+}
+
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Parser walks one statement's tokens
+type Parser struct {
+	toks []Token
+	pos  int
+}
+
+// NewParser starts a parser at the first of toks
+func NewParser(toks []Token) *Parser {
+	return &Parser{toks: toks}
+}
+
+// Done reports whether every token has been consumed
+func (p *Parser) Done() bool {
+	return p.pos >= len(p.toks)
+}
+
+// Peek returns the next token as a keyword without consuming it
+func (p *Parser) Peek() string {
+	if p.Done() {
+		return ""
+	}
+	return p.toks[p.pos].Upper()
+}
+
+// Accept consumes words if the statement continues with them
+func (p *Parser) Accept(words ...string) bool {
+	if p.pos+len(words) > len(p.toks) {
+		return false
+	}
+	for i, w := range words {
+		if p.toks[p.pos+i].Upper() != w {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+// Next consumes and returns the next token
+func (p *Parser) Next() Token {
+	if p.Done() {
+		return Token{}
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t
+}
+
+// Group consumes a parenthesised list, returning its top-level
+// comma-separated items
+func (p *Parser) Group() [][]Token {
+	if p.Peek() != "(" {
+		return nil
+	}
+	p.pos++
+	var items [][]Token
+	var item []Token
+	depth := 0
+	for !p.Done() {
+		t := p.Next()
+		switch {
+		case t.Text == "(" && !t.Quoted:
+			depth++
+		case t.Text == ")" && !t.Quoted:
+			if depth == 0 {
+				if len(item) > 0 {
+					items = append(items, item)
+				}
+				return items
+			}
+			depth--
+		case t.Text == "," && !t.Quoted && depth == 0:
+			items = append(items, item)
+			item = nil
+			continue
+		}
+		item = append(item, t)
+	}
+	return append(items, item)
+}
+
+// Names reads a parenthesised list of column names
+func (p *Parser) Names() []string {
+	var names []string
+	for _, item := range p.Group() {
+		if len(item) > 0 {
+			names = append(names, item[0].Name())
+		}
+	}
+	return names
+}
+
+// Item consumes tokens up to the next top-level comma
+func (p *Parser) Item() []Token {
+	var item []Token
+	depth := 0
+	for !p.Done() {
+		t := p.toks[p.pos]
+		if !t.Quoted {
+			switch t.Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case ",":
+				if depth == 0 {
+					p.pos++
+					return item
+				}
+			}
+		}
+		item = append(item, t)
+		p.pos++
+	}
+	return item
+}
@@ -0,0 +1,293 @@
+package sqlschema
+
+import (
+	"strings"
+)
+
+// Column is a table column
+type Column struct {
+	Name       string
+	Token      Token  // Name as written, for naming checks and line numbers
+	Type       string // Lower-case type, e.g. "varchar(255)" or "timestamp with time zone"
+	NotNull    bool
+	PrimaryKey bool
+	Unique     bool
+	HasDefault bool // DEFAULT clause, identity or serial type
+	Identity   bool // GENERATED ... AS IDENTITY
+	Computed   bool // GENERATED ALWAYS AS (...) STORED; cannot be inserted
+	References *Reference
+}
+
+// Reference is the target of a foreign key
+type Reference struct {
+	Table  string
+	Column string // Empty when the key references the table's primary key
+}
+
+// ForeignKey is a referencing column list
+type ForeignKey struct {
+	Columns    []string
+	References Reference
+	Line       int
+	File       string
+}
+
+// Table is a table as declared across a project's SQL files
+type Table struct {
+	Name        string
+	Token       Token
+	File        string
+	Line        int
+	Columns     []*Column
+	PrimaryKey  []string
+	ForeignKeys []ForeignKey
+	Indexes     [][]string // Leading columns of each index, primary key and unique constraint
+}
+
+// Column returns the named column, or nil
+func (t *Table) Column(name string) *Column {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Covered reports whether an index on the table starts with cols
+func (t *Table) Covered(cols []string) bool {
+	for _, idx := range t.Indexes {
+		if len(idx) < len(cols) {
+			continue
+		}
+		match := true
+		for i, c := range cols {
+			if idx[i] != c {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema accumulates the tables SQL files declare, in declaration order
+type Schema struct {
+	Tables []*Table
+	Enums  map[string][]string // Labels of each CREATE TYPE ... AS ENUM
+	byName map[string]*Table
+}
+
+// New returns an empty schema
+func New() *Schema {
+	return &Schema{Enums: make(map[string][]string), byName: make(map[string]*Table)}
+}
+
+// Table returns the named table, or nil
+func (s *Schema) Table(name string) *Table {
+	return s.byName[name]
+}
+
+// AddFile applies every statement of a SQL file
+func (s *Schema) AddFile(file, content string) {
+	for _, stmt := range Tokenize(content) {
+		s.Apply(file, stmt)
+	}
+}
+
+// Apply records the tables, keys, indexes and enum types a statement
+// declares. Statements other than CREATE TABLE, CREATE INDEX, CREATE TYPE
+// and ALTER TABLE ... ADD are ignored.
+func (s *Schema) Apply(file string, stmt Statement) {
+	p := NewParser(stmt.Tokens)
+	switch {
+	case p.Accept("CREATE"):
+		p.Accept("OR", "REPLACE")
+		for p.Accept("TEMP") || p.Accept("TEMPORARY") || p.Accept("UNLOGGED") {
+		}
+		switch {
+		case p.Accept("TYPE"):
+			name := p.Next().Name()
+			if p.Accept("AS", "ENUM") {
+				var labels []string
+				for _, item := range p.Group() {
+					if len(item) > 0 {
+						labels = append(labels, strings.ReplaceAll(strings.Trim(item[0].Text, "'"), "''", "'"))
+					}
+				}
+				s.Enums[name] = labels
+			}
+		case p.Accept("TABLE"):
+			p.Accept("IF", "NOT", "EXISTS")
+			s.createTable(file, p.Next(), p)
+		case p.Accept("UNIQUE", "INDEX") || p.Accept("INDEX"):
+			p.Accept("CONCURRENTLY")
+			p.Accept("IF", "NOT", "EXISTS")
+			if p.Peek() != "ON" {
+				p.Next()
+			}
+			if p.Accept("ON") {
+				p.Accept("ONLY")
+				t := s.byName[p.Next().Name()]
+				if p.Accept("USING") {
+					p.Next()
+				}
+				if cols := p.Names(); t != nil && len(cols) > 0 {
+					t.Indexes = append(t.Indexes, cols)
+				}
+			}
+		}
+	case p.Accept("ALTER", "TABLE"):
+		p.Accept("IF", "EXISTS")
+		p.Accept("ONLY")
+		t := s.byName[p.Next().Name()]
+		if t == nil {
+			return
+		}
+		for !p.Done() {
+			if p.Accept("ADD") {
+				p.Accept("COLUMN")
+				p.Accept("IF", "NOT", "EXISTS")
+				s.definition(file, t, p.Item())
+				continue
+			}
+			p.Next()
+		}
+	}
+}
+
+func (s *Schema) createTable(file string, name Token, p *Parser) {
+	t := &Table{Name: name.Name(), Token: name, File: file, Line: name.Line}
+	if old, exists := s.byName[t.Name]; exists {
+		for i, existing := range s.Tables {
+			if existing == old {
+				s.Tables = append(s.Tables[:i], s.Tables[i+1:]...)
+				break
+			}
+		}
+	}
+	s.Tables = append(s.Tables, t)
+	s.byName[t.Name] = t
+	for _, item := range p.Group() {
+		s.definition(file, t, item)
+	}
+}
+
+// columnClause starts the constraints following a column's type
+var columnClause = map[string]bool{
+	"NOT": true, "NULL": true, "PRIMARY": true, "UNIQUE": true, "REFERENCES": true, "DEFAULT": true,
+	"CHECK": true, "CONSTRAINT": true, "GENERATED": true, "COLLATE": true,
+}
+
+// definition records a column or table constraint
+func (s *Schema) definition(file string, t *Table, item []Token) {
+	if len(item) == 0 {
+		return
+	}
+	p := NewParser(item)
+	if p.Accept("CONSTRAINT") {
+		p.Next()
+	}
+	switch {
+	case p.Accept("PRIMARY", "KEY"):
+		t.setPrimaryKey(p.Names())
+	case p.Accept("UNIQUE"):
+		if cols := p.Names(); len(cols) > 0 {
+			t.Indexes = append(t.Indexes, cols)
+			if len(cols) == 1 {
+				if c := t.Column(cols[0]); c != nil {
+					c.Unique = true
+				}
+			}
+		}
+	case p.Accept("FOREIGN", "KEY"):
+		cols := p.Names()
+		if p.Accept("REFERENCES") {
+			ref := reference(p)
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{Columns: cols, References: ref, Line: item[0].Line, File: file})
+			if len(cols) == 1 {
+				if c := t.Column(cols[0]); c != nil {
+					c.References = &ref
+				}
+			}
+		}
+	case p.Peek() == "CHECK" || p.Peek() == "EXCLUDE" || p.Peek() == "LIKE":
+	default:
+		name := p.Next()
+		col := &Column{Name: name.Name(), Token: name}
+		var typ []Token
+		for !p.Done() && !columnClause[p.Peek()] {
+			typ = append(typ, p.Next())
+		}
+		col.Type = typeName(typ)
+		if strings.HasSuffix(col.Type, "serial") {
+			col.HasDefault = true
+		}
+		t.Columns = append(t.Columns, col)
+
+		for !p.Done() {
+			switch {
+			case p.Accept("NOT", "NULL"):
+				col.NotNull = true
+			case p.Accept("PRIMARY", "KEY"):
+				t.setPrimaryKey([]string{col.Name})
+			case p.Accept("UNIQUE"):
+				col.Unique = true
+				t.Indexes = append(t.Indexes, []string{col.Name})
+			case p.Accept("DEFAULT"):
+				col.HasDefault = true
+			case p.Accept("GENERATED"):
+				col.HasDefault = true
+				p.Accept("ALWAYS")
+				p.Accept("BY", "DEFAULT")
+				if p.Accept("AS", "IDENTITY") {
+					col.Identity = true
+				} else {
+					col.Computed = true
+				}
+			case p.Accept("REFERENCES"):
+				ref := reference(p)
+				col.References = &ref
+				t.ForeignKeys = append(t.ForeignKeys, ForeignKey{Columns: []string{col.Name}, References: ref, Line: name.Line, File: file})
+			default:
+				p.Next()
+			}
+		}
+	}
+}
+
+func (t *Table) setPrimaryKey(cols []string) {
+	t.PrimaryKey = cols
+	t.Indexes = append(t.Indexes, cols)
+	for _, name := range cols {
+		if c := t.Column(name); c != nil {
+			c.PrimaryKey = true
+			c.NotNull = true
+		}
+	}
+}
+
+// reference reads "table [(column)]" after REFERENCES
+func reference(p *Parser) Reference {
+	ref := Reference{Table: p.Next().Name()}
+	if cols := p.Names(); len(cols) > 0 {
+		ref.Column = cols[0]
+	}
+	return ref
+}
+
+// typeName joins type tokens, spacing words but not punctuation
+func typeName(toks []Token) string {
+	var sb strings.Builder
+	for i, t := range toks {
+		word := isWordByte(t.Text[0])
+		if i > 0 && word && isWordByte(toks[i-1].Text[0]) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(strings.ToLower(t.Text))
+	}
+	return sb.String()
+}
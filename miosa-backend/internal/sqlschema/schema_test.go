@@ -0,0 +1,67 @@
+package sqlschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	stmts := Tokenize(`-- users; not a statement
+CREATE TABLE "Users" (id int); /* block;
+comment */ INSERT INTO notes VALUES ('a;b');
+CREATE FUNCTION f() RETURNS void AS $$ BEGIN; END $$ LANGUAGE plpgsql;`)
+
+	require.Len(t, stmts, 3)
+	assert.Equal(t, 2, stmts[0].Line)
+	assert.Equal(t, Token{Text: "Users", Line: 2, Quoted: true}, stmts[0].Tokens[2])
+	assert.Equal(t, 3, stmts[1].Line)
+	assert.Equal(t, "'a;b'", stmts[1].Tokens[5].Text)
+	assert.Equal(t, "$$ BEGIN; END $$", stmts[2].Tokens[8].Text)
+}
+
+func TestSchema(t *testing.T) {
+	s := New()
+	s.AddFile("001.sql", `CREATE TYPE user_role AS ENUM ('admin', 'member');
+CREATE TABLE public.users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR(255) NOT NULL UNIQUE,
+    role user_role NOT NULL,
+    seq INT GENERATED BY DEFAULT AS IDENTITY,
+    email_domain TEXT GENERATED ALWAYS AS (split_part(email, '@', 2)) STORED,
+    balance NUMERIC(10, 2),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE TABLE orders (
+    id SERIAL,
+    user_id UUID NOT NULL,
+    total INT,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id)
+);`)
+	s.AddFile("002.sql", `ALTER TABLE orders ADD COLUMN parent_id INT REFERENCES orders;
+CREATE INDEX idx_orders_user ON orders (user_id);`)
+
+	require.Len(t, s.Tables, 2)
+	users := s.Table("users")
+	require.NotNil(t, users)
+	assert.Equal(t, []string{"id"}, users.PrimaryKey)
+	assert.Equal(t, "varchar(255)", users.Column("email").Type)
+	assert.True(t, users.Column("email").Unique)
+	assert.Equal(t, "numeric(10,2)", users.Column("balance").Type)
+	assert.Equal(t, "timestamp with time zone", users.Column("created_at").Type)
+	assert.True(t, users.Column("id").HasDefault)
+	assert.True(t, users.Column("seq").Identity)
+	assert.True(t, users.Column("email_domain").Computed)
+	assert.Equal(t, []string{"admin", "member"}, s.Enums["user_role"])
+
+	orders := s.Table("orders")
+	assert.True(t, orders.Column("id").HasDefault, "serial columns default")
+	assert.True(t, orders.Column("id").PrimaryKey)
+	assert.Equal(t, &Reference{Table: "users", Column: "id"}, orders.Column("user_id").References)
+	assert.Equal(t, &Reference{Table: "orders"}, orders.Column("parent_id").References)
+	require.Len(t, orders.ForeignKeys, 2)
+	assert.True(t, orders.Covered([]string{"user_id"}))
+	assert.False(t, orders.Covered([]string{"parent_id"}))
+}
@@ -0,0 +1,259 @@
+// Package transcript keeps the prompt and response of every LLM call a
+// workflow makes, with secrets redacted, so regulated customers can audit
+// what the model was asked and what it generated. Entries are appended to a
+// JSONL file in the workspace as calls finish; Export writes them as a
+// signed JSONL document whose last line signs everything before it.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+	"github.com/sormind/OSA/miosa-backend/internal/redact"
+	"github.com/sormind/OSA/miosa-backend/internal/workspace"
+)
+
+// Dir holds the transcripts of a workspace, one per workflow
+const Dir = workspace.StateDir + "/transcripts"
+
+// ErrNoTranscript is returned when a workflow recorded no LLM calls
+var ErrNoTranscript = errors.New("no transcript for workflow")
+
+// Line types of an exported transcript
+const (
+	TypeHeader    = "header"
+	TypeEntry     = "entry"
+	TypeSignature = "signature"
+)
+
+// Record is a redacted LLM call, numbered in the order calls finished
+type Record struct {
+	Seq int `json:"seq"`
+	agents.TranscriptEntry
+	Redactions int `json:"redactions,omitempty"` // Secrets replaced before recording
+}
+
+// Recorder appends the calls of one workflow to its transcript
+type Recorder struct {
+	path string
+	mu   sync.Mutex
+	seq  int
+	err  error
+}
+
+// NewRecorder opens the transcript of workflowID in the workspace at root,
+// continuing its numbering when the workflow is resumed
+func NewRecorder(root string, workflowID uuid.UUID) (*Recorder, error) {
+	path := transcriptPath(root, workflowID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	records, err := readRecords(path)
+	if err != nil && !errors.Is(err, ErrNoTranscript) {
+		return nil, err
+	}
+	return &Recorder{path: path, seq: len(records)}, nil
+}
+
+// Record redacts entry and appends it. Write failures are kept for Err
+// rather than failing the call that was recorded.
+func (r *Recorder) Record(entry agents.TranscriptEntry) {
+	record := Record{TranscriptEntry: entry}
+	record.Messages = make([]agents.TranscriptMessage, len(entry.Messages))
+	for i, m := range entry.Messages {
+		record.Messages[i] = m
+		record.Messages[i].Content = clean(m.Content, &record.Redactions)
+	}
+	record.Response = clean(entry.Response, &record.Redactions)
+	record.Error = clean(entry.Error, &record.Redactions)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	record.Seq = r.seq
+	if err := appendLine(r.path, record); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Err returns the first failure to write the transcript
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Load reads the transcript of workflowID from the workspace at root
+func Load(root string, workflowID uuid.UUID) ([]Record, error) {
+	return readRecords(transcriptPath(root, workflowID))
+}
+
+// Header opens an exported transcript
+type Header struct {
+	Type       string    `json:"type"`
+	WorkflowID uuid.UUID `json:"workflow_id"`
+	Entries    int       `json:"entries"`
+	ExportedAt time.Time `json:"exported_at"`
+	Algorithm  string    `json:"algorithm"`
+	KeyID      string    `json:"key_id"`
+}
+
+// Signature closes an exported transcript, signing the SHA-256 of every
+// byte before it
+type Signature struct {
+	Type      string `json:"type"`
+	SHA256    string `json:"sha256"`
+	Signature []byte `json:"signature"`
+}
+
+// entryLine is a record as exported
+type entryLine struct {
+	Type string `json:"type"`
+	Record
+}
+
+// Export writes the records of workflowID as JSONL: a header, one line per
+// record and a signature line made with signer
+func Export(w io.Writer, workflowID uuid.UUID, records []Record, signer integrity.Signer) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(Header{
+		Type:       TypeHeader,
+		WorkflowID: workflowID,
+		Entries:    len(records),
+		ExportedAt: time.Now().UTC(),
+		Algorithm:  signer.Algorithm(),
+		KeyID:      signer.KeyID(),
+	}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := enc.Encode(entryLine{Type: TypeEntry, Record: record}); err != nil {
+			return err
+		}
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	sig, err := signer.Sign(sum[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign transcript: %w", err)
+	}
+	if err := enc.Encode(Signature{Type: TypeSignature, SHA256: hex.EncodeToString(sum[:]), Signature: sig}); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Verify checks an exported transcript against verifier and returns its
+// header and records. It fails if any line was changed, added or removed.
+func Verify(r io.Reader, verifier integrity.Verifier) (*Header, []Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := bytes.TrimSuffix(data, []byte("\n"))
+	cut := bytes.LastIndexByte(body, '\n')
+	if cut < 0 {
+		return nil, nil, errors.New("transcript has no signature line")
+	}
+	signed, last := data[:cut+1], body[cut+1:]
+
+	var sig Signature
+	if err := json.Unmarshal(last, &sig); err != nil || sig.Type != TypeSignature {
+		return nil, nil, errors.New("transcript does not end with a signature line")
+	}
+	sum := sha256.Sum256(signed)
+	if hex.EncodeToString(sum[:]) != sig.SHA256 {
+		return nil, nil, errors.New("transcript content does not match its checksum")
+	}
+	if err := verifier.Verify(sum[:], sig.Signature); err != nil {
+		return nil, nil, fmt.Errorf("transcript signature: %w", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(signed, []byte("\n")), []byte("\n"))
+	var header Header
+	if err := json.Unmarshal(lines[0], &header); err != nil || header.Type != TypeHeader {
+		return nil, nil, errors.New("transcript does not start with a header line")
+	}
+	if header.Algorithm != verifier.Algorithm() || header.KeyID != verifier.KeyID() {
+		return nil, nil, fmt.Errorf("transcript was signed with %s key %s", header.Algorithm, header.KeyID)
+	}
+	records := make([]Record, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		var entry entryLine
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Type != TypeEntry {
+			return nil, nil, errors.New("transcript has a malformed entry line")
+		}
+		records = append(records, entry.Record)
+	}
+	if len(records) != header.Entries {
+		return nil, nil, fmt.Errorf("transcript has %d entries, its header %d", len(records), header.Entries)
+	}
+	return &header, records, nil
+}
+
+// clean redacts s, adding the secrets replaced to count
+func clean(s string, count *int) string {
+	s, n := redact.String(s)
+	*count += n
+	return s
+}
+
+func appendLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readRecords(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoTranscript
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode transcript: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func transcriptPath(root string, workflowID uuid.UUID) string {
+	return filepath.Join(root, filepath.FromSlash(Dir), workflowID.String()+".jsonl")
+}
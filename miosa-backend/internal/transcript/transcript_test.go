@@ -0,0 +1,105 @@
+package transcript
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sormind/OSA/miosa-backend/internal/agents"
+	"github.com/sormind/OSA/miosa-backend/internal/integrity"
+)
+
+func entry(prompt, response string) agents.TranscriptEntry {
+	return agents.TranscriptEntry{
+		Agent:    agents.DevelopmentAgent,
+		Target:   "groq:moonshotai/kimi-k2-instruct",
+		Messages: []agents.TranscriptMessage{{Role: "system", Content: "You write Go."}, {Role: "user", Content: prompt}},
+		Response: response,
+		At:       time.Now().UTC(),
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	root, id := t.TempDir(), uuid.New()
+	recorder, err := NewRecorder(root, id)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder.Record(entry("Build an API", "package main"))
+		}()
+	}
+	wg.Wait()
+	recorder.Record(entry(`Use api_key = "sk-live-abcdefghijklmnopqrstuvwxyz"`, `token: "hunter2"`))
+	require.NoError(t, recorder.Err())
+
+	records, err := Load(root, id)
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+	for i, r := range records {
+		assert.Equal(t, i+1, r.Seq)
+	}
+	last := records[5]
+	assert.NotContains(t, last.Messages[1].Content, "sk-live")
+	assert.Contains(t, last.Response, "[REDACTED]")
+	assert.Equal(t, 2, last.Redactions)
+
+	// A resumed workflow continues the numbering
+	recorder, err = NewRecorder(root, id)
+	require.NoError(t, err)
+	recorder.Record(entry("Fix the tests", "done"))
+	records, err = Load(root, id)
+	require.NoError(t, err)
+	assert.Equal(t, 7, records[6].Seq)
+
+	_, err = Load(root, uuid.New())
+	assert.ErrorIs(t, err, ErrNoTranscript)
+}
+
+func TestExport(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signer := integrity.NewEd25519Signer(private)
+	id := uuid.New()
+	records := []Record{
+		{Seq: 1, TranscriptEntry: entry("Build an API", "package main")},
+		{Seq: 2, TranscriptEntry: entry("Add <auth> & tests", "func TestAuth")},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(&buf, id, records, signer))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `"type":"header"`)
+	assert.Contains(t, lines[2], "Add <auth> & tests")
+	assert.Contains(t, lines[3], `"type":"signature"`)
+
+	header, verified, err := Verify(bytes.NewReader(buf.Bytes()), signer)
+	require.NoError(t, err)
+	assert.Equal(t, id, header.WorkflowID)
+	assert.Equal(t, 2, header.Entries)
+	require.Len(t, verified, 2)
+	assert.Equal(t, "func TestAuth", verified[1].Response)
+
+	// Changing, dropping or signing with another key is detected
+	tampered := strings.Replace(buf.String(), "package main", "package evil", 1)
+	_, _, err = Verify(strings.NewReader(tampered), signer)
+	assert.Error(t, err)
+
+	dropped := strings.Join([]string{lines[0], lines[1], lines[3]}, "\n") + "\n"
+	_, _, err = Verify(strings.NewReader(dropped), signer)
+	assert.Error(t, err)
+
+	_, _, err = Verify(bytes.NewReader(buf.Bytes()), integrity.NewHMACSigner([]byte("other")))
+	assert.Error(t, err)
+}
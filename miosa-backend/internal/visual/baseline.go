@@ -0,0 +1,121 @@
+package visual
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Baseline is the approved rendering of one artifact in a project
+type Baseline struct {
+	ProjectID  string      `json:"projectId"`
+	ArtifactID string      `json:"artifactId"`
+	Hash       uint64      `json:"hash"`            // perceptual hash of the screenshot
+	Width      int         `json:"width,omitempty"` // screenshot size; 0 = no screenshot
+	Height     int         `json:"height,omitempty"`
+	Components []Component `json:"components"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// BaselineStore persists baselines per project. Get returns nil, nil when
+// no baseline exists.
+type BaselineStore interface {
+	Get(ctx context.Context, projectID, artifactID string) (*Baseline, error)
+	Save(ctx context.Context, b *Baseline) error
+}
+
+// MemoryBaselineStore keeps baselines in process
+type MemoryBaselineStore struct {
+	mu        sync.RWMutex
+	baselines map[string]Baseline
+}
+
+// NewMemoryBaselineStore creates an empty in-memory store
+func NewMemoryBaselineStore() *MemoryBaselineStore {
+	return &MemoryBaselineStore{baselines: make(map[string]Baseline)}
+}
+
+// Get returns a copy of the baseline, if any
+func (s *MemoryBaselineStore) Get(ctx context.Context, projectID, artifactID string) (*Baseline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.baselines[projectID+"/"+artifactID]
+	if !ok {
+		return nil, nil
+	}
+	return &b, nil
+}
+
+// Save replaces the baseline for b's artifact
+func (s *MemoryBaselineStore) Save(ctx context.Context, b *Baseline) error {
+	s.mu.Lock()
+	s.baselines[b.ProjectID+"/"+b.ArtifactID] = *b
+	s.mu.Unlock()
+	return nil
+}
+
+// DirBaselineStore writes one JSON file per artifact under
+// <root>/<project>/<artifact>.json so baselines can be committed alongside
+// the project they belong to
+type DirBaselineStore struct {
+	root string
+}
+
+// NewDirBaselineStore creates a store rooted at dir
+func NewDirBaselineStore(dir string) *DirBaselineStore {
+	return &DirBaselineStore{root: dir}
+}
+
+func (s *DirBaselineStore) path(projectID, artifactID string) (string, error) {
+	for _, part := range []string{projectID, artifactID} {
+		if part == "" || part != filepath.Base(part) || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid baseline id %q", part)
+		}
+	}
+	return filepath.Join(s.root, projectID, artifactID+".json"), nil
+}
+
+// Get reads the baseline file, if any
+func (s *DirBaselineStore) Get(ctx context.Context, projectID, artifactID string) (*Baseline, error) {
+	path, err := s.path(projectID, artifactID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to decode baseline %s: %w", path, err)
+	}
+	return &b, nil
+}
+
+// Save writes the baseline file, replacing any previous one
+func (s *DirBaselineStore) Save(ctx context.Context, b *Baseline) error {
+	path, err := s.path(b.ProjectID, b.ArtifactID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create baseline dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,142 @@
+package visual
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color is an sRGB color with straight (non-premultiplied) alpha. Channels
+// are 0–255 and A is 0–1.
+type Color struct {
+	R, G, B float64
+	A       float64
+}
+
+// White is the default page background
+var White = Color{255, 255, 255, 1}
+
+var namedColors = map[string]Color{
+	"white":       White,
+	"black":       {0, 0, 0, 1},
+	"transparent": {0, 0, 0, 0},
+	"red":         {255, 0, 0, 1},
+	"green":       {0, 128, 0, 1},
+	"blue":        {0, 0, 255, 1},
+	"gray":        {128, 128, 128, 1},
+	"grey":        {128, 128, 128, 1},
+}
+
+// ParseColor parses #rgb, #rgba, #rrggbb, #rrggbbaa, rgb(), rgba() and a
+// few named colors
+func ParseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, nil
+	}
+	if strings.HasPrefix(s, "#") {
+		return parseHex(s[1:])
+	}
+	if strings.HasPrefix(s, "rgb") {
+		return parseRGBFunc(s)
+	}
+	return Color{}, fmt.Errorf("unsupported color %q", s)
+}
+
+func parseHex(h string) (Color, error) {
+	if len(h) == 3 || len(h) == 4 {
+		var b strings.Builder
+		for _, ch := range h {
+			b.WriteRune(ch)
+			b.WriteRune(ch)
+		}
+		h = b.String()
+	}
+	if len(h) != 6 && len(h) != 8 {
+		return Color{}, fmt.Errorf("invalid hex color #%s", h)
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("invalid hex color #%s", h)
+	}
+	if len(h) == 6 {
+		return Color{float64(v >> 16 & 0xff), float64(v >> 8 & 0xff), float64(v & 0xff), 1}, nil
+	}
+	return Color{float64(v >> 24 & 0xff), float64(v >> 16 & 0xff), float64(v >> 8 & 0xff), float64(v&0xff) / 255}, nil
+}
+
+// parseRGBFunc accepts both rgba(1, 2, 3, 0.5) and rgb(1 2 3 / 50%)
+func parseRGBFunc(s string) (Color, error) {
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	args := strings.FieldsFunc(s[open+1:end], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '/'
+	})
+	if len(args) != 3 && len(args) != 4 {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+
+	var ch [4]float64
+	ch[3] = 1
+	for i, arg := range args {
+		pct := strings.HasSuffix(arg, "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil {
+			return Color{}, fmt.Errorf("invalid color %q", s)
+		}
+		switch {
+		case i == 3 && pct:
+			v /= 100
+		case i < 3 && pct:
+			v = v / 100 * 255
+		}
+		ch[i] = v
+	}
+	return Color{clamp(ch[0], 0, 255), clamp(ch[1], 0, 255), clamp(ch[2], 0, 255), clamp(ch[3], 0, 1)}, nil
+}
+
+// Over composites c onto an opaque backdrop
+func (c Color) Over(backdrop Color) Color {
+	a := c.A
+	return Color{
+		R: c.R*a + backdrop.R*(1-a),
+		G: c.G*a + backdrop.G*(1-a),
+		B: c.B*a + backdrop.B*(1-a),
+		A: 1,
+	}
+}
+
+// Lerp interpolates between two colors in straight sRGB, as browsers do for
+// gradients with opaque stops
+func Lerp(a, b Color, t float64) Color {
+	return Color{
+		R: a.R + (b.R-a.R)*t,
+		G: a.G + (b.G-a.G)*t,
+		B: a.B + (b.B-a.B)*t,
+		A: a.A + (b.A-a.A)*t,
+	}
+}
+
+// Hex formats an opaque color as #rrggbb
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", int(math.Round(c.R)), int(math.Round(c.G)), int(math.Round(c.B)))
+}
+
+// RelativeLuminance implements the WCAG 2.x definition for an opaque color
+func (c Color) RelativeLuminance() float64 {
+	channel := func(v float64) float64 {
+		v /= 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
@@ -0,0 +1,226 @@
+package visual
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WCAG 2.1 AA minimum contrast ratios
+const (
+	MinContrastNormal = 4.5
+	MinContrastLarge  = 3.0
+)
+
+// gradientSamples is how many points between adjacent stops are checked.
+// The worst contrast can fall between two stops (the text luminance may sit
+// between theirs), so each segment is sampled rather than checked at its ends.
+const gradientSamples = 8
+
+// contrastRatio is the WCAG contrast between two opaque colors
+func contrastRatio(a, b Color) float64 {
+	la, lb := a.RelativeLuminance(), b.RelativeLuminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// Contrast is the outcome of checking text against its background
+type Contrast struct {
+	Ratio      float64 // worst case across the background
+	Foreground Color   // composited text color at the worst point
+	Background Color   // composited background color at the worst point
+}
+
+// ContrastAgainst computes the worst-case contrast of fg text over a
+// background, which may be a color, a linear or radial gradient, or empty
+// for the page. Translucent layers are composited onto the page background.
+func ContrastAgainst(fg, background string, page Color) (Contrast, error) {
+	text, err := ParseColor(fg)
+	if err != nil {
+		return Contrast{}, err
+	}
+	page = page.Over(White)
+
+	backgrounds, err := backgroundColors(background)
+	if err != nil {
+		return Contrast{}, err
+	}
+	if len(backgrounds) == 0 {
+		backgrounds = []Color{page}
+	}
+
+	worst := Contrast{Ratio: -1}
+	for _, bg := range backgrounds {
+		bg = bg.Over(page)
+		tc := text.Over(bg)
+		if r := contrastRatio(tc, bg); worst.Ratio < 0 || r < worst.Ratio {
+			worst = Contrast{Ratio: r, Foreground: tc, Background: bg}
+		}
+	}
+	return worst, nil
+}
+
+// backgroundColors returns the colors to test for a background: a single
+// color, or samples along every segment between gradient stops
+func backgroundColors(background string) ([]Color, error) {
+	background = strings.TrimSpace(background)
+	if background == "" {
+		return nil, nil
+	}
+	if !strings.Contains(background, "gradient(") {
+		c, err := ParseColor(background)
+		if err != nil {
+			return nil, err
+		}
+		return []Color{c}, nil
+	}
+
+	stops, err := gradientStops(background)
+	if err != nil {
+		return nil, err
+	}
+	colors := []Color{stops[0]}
+	for i := 1; i < len(stops); i++ {
+		for s := 1; s <= gradientSamples; s++ {
+			colors = append(colors, Lerp(stops[i-1], stops[i], float64(s)/gradientSamples))
+		}
+	}
+	return colors, nil
+}
+
+// gradientStops extracts stop colors from linear-gradient(), radial-gradient()
+// and their repeating forms, ignoring the direction or shape argument and
+// stop positions
+func gradientStops(s string) ([]Color, error) {
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("invalid gradient %q", s)
+	}
+
+	var stops []Color
+	for _, arg := range splitTopLevel(s[open+1 : end]) {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			return nil, fmt.Errorf("invalid gradient %q", s)
+		}
+		// A stop is a color optionally followed by positions
+		colorPart := arg
+		if !strings.HasPrefix(arg, "rgb") && !strings.HasPrefix(arg, "#") {
+			colorPart = strings.Fields(arg)[0]
+		} else if i := strings.IndexByte(arg, ')'); i >= 0 {
+			colorPart = arg[:i+1]
+		} else {
+			colorPart = strings.Fields(arg)[0]
+		}
+		c, err := ParseColor(colorPart)
+		if err != nil {
+			if len(stops) == 0 && isGradientPrelude(arg) {
+				continue
+			}
+			return nil, fmt.Errorf("invalid gradient stop %q", arg)
+		}
+		stops = append(stops, c)
+	}
+	if len(stops) < 2 {
+		return nil, fmt.Errorf("gradient %q needs at least two color stops", s)
+	}
+	return stops, nil
+}
+
+// isGradientPrelude matches the optional first argument: an angle, a
+// "to <side>" direction or a radial shape
+func isGradientPrelude(arg string) bool {
+	arg = strings.ToLower(arg)
+	if strings.HasPrefix(arg, "to ") || strings.Contains(arg, "circle") || strings.Contains(arg, "ellipse") || strings.Contains(arg, " at ") {
+		return true
+	}
+	for _, unit := range []string{"deg", "turn", "rad", "grad"} {
+		if _, err := strconv.ParseFloat(strings.TrimSuffix(arg, unit), 64); err == nil && strings.HasSuffix(arg, unit) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTopLevel splits on commas outside parentheses
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, ch := range s {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// isLargeText follows WCAG: at least 24px, or 18.66px (14pt) bold
+func isLargeText(c Component) bool {
+	return c.FontSize >= 24 || (c.FontSize >= 18.66 && c.FontWeight >= 700)
+}
+
+// CheckContrast reports text components whose worst-case contrast falls
+// below WCAG AA
+func CheckContrast(a Artifact) []VisualFinding {
+	page := White
+	if a.Background != "" {
+		if c, err := ParseColor(a.Background); err == nil {
+			page = c
+		}
+	}
+
+	var findings []VisualFinding
+	for _, c := range a.Components {
+		if strings.TrimSpace(c.Text) == "" || c.Foreground == "" {
+			continue
+		}
+		result, err := ContrastAgainst(c.Foreground, c.Background, page)
+		if err != nil {
+			continue
+		}
+
+		min := MinContrastNormal
+		if isLargeText(c) {
+			min = MinContrastLarge
+		}
+		if result.Ratio >= min {
+			continue
+		}
+
+		severity := "medium"
+		if result.Ratio < min-1.5 {
+			severity = "high"
+		}
+		findings = append(findings, VisualFinding{
+			Rule:        "Contrast.Text",
+			Category:    "accessibility",
+			Severity:    severity,
+			ArtifactID:  a.ID,
+			ComponentID: c.ID,
+			Message:     fmt.Sprintf("Text contrast %.2f:1 is below the WCAG AA minimum of %.1f:1", result.Ratio, min),
+			Evidence: fmt.Sprintf("worst case %s on %s (declared %s on %s)",
+				result.Foreground.Hex(), result.Background.Hex(), c.Foreground, valueOr(c.Background, "page")),
+			Remediation: "Darken or lighten the text or background, and check every part of gradient or translucent backgrounds.",
+			Regions:     []Rect{c.Box},
+			Confidence:  0.9,
+		})
+	}
+	return findings
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
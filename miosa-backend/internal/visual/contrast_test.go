@@ -0,0 +1,84 @@
+package visual
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#fff")
+	require.NoError(t, err)
+	assert.Equal(t, White, c)
+
+	c, err = ParseColor("rgba(0, 0, 0, 0.5)")
+	require.NoError(t, err)
+	assert.Equal(t, Color{0, 0, 0, 0.5}, c)
+
+	c, err = ParseColor("rgb(255 0 0 / 25%)")
+	require.NoError(t, err)
+	assert.Equal(t, Color{255, 0, 0, 0.25}, c)
+
+	c, err = ParseColor("#00000080")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, c.A, 0.01)
+
+	_, err = ParseColor("hsl(0, 0%, 0%)")
+	assert.Error(t, err)
+}
+
+func TestContrastAgainst_Solid(t *testing.T) {
+	res, err := ContrastAgainst("#000", "#fff", White)
+	require.NoError(t, err)
+	assert.InDelta(t, 21, res.Ratio, 0.01)
+}
+
+func TestContrastAgainst_AlphaComposited(t *testing.T) {
+	// 50% black text on white renders as #808080
+	res, err := ContrastAgainst("rgba(0, 0, 0, 0.5)", "", White)
+	require.NoError(t, err)
+	assert.Equal(t, "#808080", res.Foreground.Hex())
+	assert.InDelta(t, 3.98, res.Ratio, 0.01)
+
+	// A translucent background is composited onto a dark page
+	res, err = ContrastAgainst("#fff", "rgba(255, 255, 255, 0.9)", Color{0, 0, 0, 1})
+	require.NoError(t, err)
+	assert.Equal(t, "#e6e6e6", res.Background.Hex())
+	assert.Less(t, res.Ratio, 1.3)
+}
+
+func TestContrastAgainst_GradientWorstCase(t *testing.T) {
+	// White text passes on the dark end but fails on the light end
+	res, err := ContrastAgainst("#fff", "linear-gradient(to right, #000 0%, #ddd 100%)", White)
+	require.NoError(t, err)
+	assert.Equal(t, "#dddddd", res.Background.Hex())
+	assert.Less(t, res.Ratio, MinContrastNormal)
+
+	// Gray text has fine contrast at both stops but not in the middle
+	res, err = ContrastAgainst("#777", "linear-gradient(90deg, black, white)", White)
+	require.NoError(t, err)
+	assert.Less(t, res.Ratio, 1.5)
+
+	_, err = ContrastAgainst("#000", "linear-gradient(to right, #000)", White)
+	assert.Error(t, err)
+}
+
+func TestCheckContrast(t *testing.T) {
+	a := Artifact{
+		ID: "home",
+		Components: []Component{
+			{ID: "title", Text: "Welcome", Foreground: "#000", FontSize: 32},
+			{ID: "hint", Text: "Optional", Foreground: "rgba(0,0,0,0.3)", FontSize: 14},
+			{ID: "banner", Text: "Sale", Foreground: "#fff", Background: "radial-gradient(circle, #222, #eee)", FontSize: 16},
+			{ID: "decor", Foreground: "#eee"},
+		},
+	}
+
+	findings := CheckContrast(a)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "hint", findings[0].ComponentID)
+	assert.Equal(t, "high", findings[0].Severity)
+	assert.Equal(t, "banner", findings[1].ComponentID)
+	assert.Contains(t, findings[1].Evidence, "radial-gradient")
+}
@@ -0,0 +1,77 @@
+package visual
+
+import (
+	"fmt"
+	"strings"
+)
+
+var interactiveRoles = map[string]bool{
+	"button":   true,
+	"link":     true,
+	"input":    true,
+	"textbox":  true,
+	"checkbox": true,
+	"radio":    true,
+	"select":   true,
+	"combobox": true,
+	"switch":   true,
+	"tab":      true,
+	"menuitem": true,
+	"slider":   true,
+}
+
+// isInteractive reports whether a component can receive keyboard focus
+func isInteractive(c Component) bool {
+	return c.Interactive || interactiveRoles[strings.ToLower(c.Role)]
+}
+
+// CheckFocusIndicators reports interactive components whose captured focus
+// state looks the same as their resting state (WCAG 2.4.7). Components
+// without focus metadata are skipped since a static mock can't show focus.
+func CheckFocusIndicators(a Artifact) []VisualFinding {
+	var findings []VisualFinding
+	for _, c := range a.Components {
+		if !isInteractive(c) || c.Focus == nil || hasFocusRing(*c.Focus) {
+			continue
+		}
+		if !sameColor(c.Focus.Foreground, c.Foreground) || !sameColor(c.Focus.Background, c.Background) {
+			continue
+		}
+		findings = append(findings, VisualFinding{
+			Rule:        "Focus.Indicator",
+			Category:    "accessibility",
+			Severity:    "high",
+			ArtifactID:  a.ID,
+			ComponentID: c.ID,
+			Message:     fmt.Sprintf("Focusable %s has no visible focus indicator", valueOr(c.Role, "component")),
+			Evidence: fmt.Sprintf("focus state %s on %s matches resting state and has no outline",
+				valueOr(c.Focus.Foreground, "inherited"), valueOr(c.Focus.Background, "page")),
+			Remediation: "Add a :focus-visible outline or ring, or change the colors on focus with at least 3:1 contrast against the resting state.",
+			Regions:     []Rect{c.Box},
+			Confidence:  0.8,
+		})
+	}
+	return findings
+}
+
+func hasFocusRing(f FocusState) bool {
+	o := strings.ToLower(strings.TrimSpace(f.Outline))
+	return o != "" && o != "none" && o != "0" && !strings.HasPrefix(o, "0px")
+}
+
+// sameColor compares two CSS colors by value; an empty focus value means the
+// resting value is inherited unchanged
+func sameColor(focus, resting string) bool {
+	if strings.TrimSpace(focus) == "" {
+		return true
+	}
+	if strings.EqualFold(strings.TrimSpace(focus), strings.TrimSpace(resting)) {
+		return true
+	}
+	fc, err1 := ParseColor(focus)
+	rc, err2 := ParseColor(resting)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return fc.Over(White).Hex() == rc.Over(White).Hex()
+}
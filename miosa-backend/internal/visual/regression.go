@@ -0,0 +1,236 @@
+package visual
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register decoders for Screenshot
+	_ "image/png"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Default regression tolerances
+const (
+	DefaultHashThreshold = 6   // Hamming distance out of 64 still treated as unchanged
+	DefaultBoxTolerance  = 2.0 // px a component may shift or resize
+)
+
+// PerceptualHash computes a 64-bit difference hash: the image is reduced to
+// 9x8 grayscale cells and each bit records whether a cell is brighter than
+// its right neighbour. Similar images differ in few bits regardless of
+// scale or compression.
+func PerceptualHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+
+	var cells [h][w]float64
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/h
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/w
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/w, x0+1)
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				}
+			}
+			cells[y][x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance is the number of differing bits between two hashes
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Comparer checks artifacts against the baselines stored for their project
+type Comparer struct {
+	store         BaselineStore
+	HashThreshold int
+	BoxTolerance  float64
+}
+
+// NewComparer creates a comparer with the default tolerances
+func NewComparer(store BaselineStore) *Comparer {
+	return &Comparer{store: store, HashThreshold: DefaultHashThreshold, BoxTolerance: DefaultBoxTolerance}
+}
+
+// Approve records a as the baseline for its artifact ID in the project
+func (c *Comparer) Approve(ctx context.Context, projectID string, a Artifact) error {
+	b, err := newBaseline(projectID, a)
+	if err != nil {
+		return err
+	}
+	return c.store.Save(ctx, b)
+}
+
+// Compare diffs a against its baseline and returns regression findings.
+// The first artifact seen for an ID has nothing to compare against and
+// becomes the baseline. Components are matched by ID.
+func (c *Comparer) Compare(ctx context.Context, projectID string, a Artifact) ([]VisualFinding, error) {
+	base, err := c.store.Get(ctx, projectID, a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline: %w", err)
+	}
+	if base == nil {
+		return nil, c.Approve(ctx, projectID, a)
+	}
+	current, err := newBaseline(projectID, a)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, changed := c.diffComponents(a.ID, base.Components, a.Components)
+
+	if base.Width > 0 && current.Width > 0 {
+		distance := HashDistance(base.Hash, current.Hash)
+		resized := base.Width != current.Width || base.Height != current.Height
+		if distance > c.HashThreshold || resized {
+			regions := changed
+			if len(regions) == 0 {
+				regions = []Rect{{Width: float64(current.Width), Height: float64(current.Height)}}
+			}
+			severity := "medium"
+			if distance > 16 || resized {
+				severity = "high"
+			}
+			findings = append(findings, VisualFinding{
+				Rule:       "Regression.Screenshot",
+				Category:   "regression",
+				Severity:   severity,
+				ArtifactID: a.ID,
+				Message:    "Screenshot differs from the approved baseline",
+				Evidence: fmt.Sprintf("perceptual hash distance %d/64 (threshold %d), size %dx%d was %dx%d",
+					distance, c.HashThreshold, current.Width, current.Height, base.Width, base.Height),
+				Remediation: "Review the changed regions; approve the new screenshot as the baseline if the change is intended.",
+				Regions:     regions,
+				Confidence:  0.7,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// diffComponents reports components that moved, resized, disappeared or
+// appeared, along with every changed region
+func (c *Comparer) diffComponents(artifactID string, before, after []Component) ([]VisualFinding, []Rect) {
+	current := make(map[string]Component, len(after))
+	for _, comp := range after {
+		if comp.ID != "" {
+			current[comp.ID] = comp
+		}
+	}
+	previous := make(map[string]bool, len(before))
+
+	var findings []VisualFinding
+	var regions []Rect
+	for _, old := range before {
+		if old.ID == "" {
+			continue
+		}
+		previous[old.ID] = true
+		now, ok := current[old.ID]
+		if !ok {
+			regions = append(regions, old.Box)
+			findings = append(findings, VisualFinding{
+				Rule:        "Regression.Missing",
+				Category:    "regression",
+				Severity:    "high",
+				ArtifactID:  artifactID,
+				ComponentID: old.ID,
+				Message:     fmt.Sprintf("Component %q from the baseline is missing", old.ID),
+				Evidence:    "was at " + formatRect(old.Box),
+				Remediation: "Restore the component or approve the new screenshot as the baseline.",
+				Regions:     []Rect{old.Box},
+				Confidence:  0.9,
+			})
+			continue
+		}
+		if c.sameBox(old.Box, now.Box) {
+			continue
+		}
+		regions = append(regions, old.Box, now.Box)
+		findings = append(findings, VisualFinding{
+			Rule:        "Regression.Layout",
+			Category:    "regression",
+			Severity:    "medium",
+			ArtifactID:  artifactID,
+			ComponentID: old.ID,
+			Message:     fmt.Sprintf("Component %q moved or resized", old.ID),
+			Evidence:    fmt.Sprintf("%s -> %s", formatRect(old.Box), formatRect(now.Box)),
+			Remediation: "Check for unintended layout shifts; approve the new screenshot as the baseline if the change is intended.",
+			Regions:     []Rect{old.Box, now.Box},
+			Confidence:  0.9,
+		})
+	}
+
+	for _, comp := range after {
+		if comp.ID == "" || previous[comp.ID] {
+			continue
+		}
+		regions = append(regions, comp.Box)
+		findings = append(findings, VisualFinding{
+			Rule:        "Regression.Added",
+			Category:    "regression",
+			Severity:    "low",
+			ArtifactID:  artifactID,
+			ComponentID: comp.ID,
+			Message:     fmt.Sprintf("Component %q is not in the baseline", comp.ID),
+			Evidence:    "at " + formatRect(comp.Box),
+			Remediation: "Approve the new screenshot as the baseline if the addition is intended.",
+			Regions:     []Rect{comp.Box},
+			Confidence:  0.8,
+		})
+	}
+	return findings, regions
+}
+
+func (c *Comparer) sameBox(a, b Rect) bool {
+	tol := c.BoxTolerance
+	return math.Abs(a.X-b.X) <= tol && math.Abs(a.Y-b.Y) <= tol &&
+		math.Abs(a.Width-b.Width) <= tol && math.Abs(a.Height-b.Height) <= tol
+}
+
+func formatRect(r Rect) string {
+	return fmt.Sprintf("(%.0f,%.0f %.0fx%.0f)", r.X, r.Y, r.Width, r.Height)
+}
+
+// newBaseline captures a's components and, if present, its screenshot hash
+func newBaseline(projectID string, a Artifact) (*Baseline, error) {
+	b := &Baseline{
+		ProjectID:  projectID,
+		ArtifactID: a.ID,
+		Components: a.Components,
+		CreatedAt:  time.Now(),
+	}
+	if len(a.Screenshot) > 0 {
+		img, _, err := image.Decode(bytes.NewReader(a.Screenshot))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode screenshot for %s: %w", a.ID, err)
+		}
+		b.Hash = PerceptualHash(img)
+		b.Width, b.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	}
+	return b, nil
+}
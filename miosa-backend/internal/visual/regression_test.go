@@ -0,0 +1,108 @@
+package visual
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// screenshot renders a white page with a dark block at (bx, by)
+func screenshot(t *testing.T, w, h, bx, by int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x >= bx && x < bx+w/4 && y >= by && y < by+h/4 {
+				c = color.RGBA{20, 20, 20, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestPerceptualHash(t *testing.T) {
+	decode := func(data []byte) image.Image {
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		return img
+	}
+	a := PerceptualHash(decode(screenshot(t, 320, 240, 20, 20)))
+	scaled := PerceptualHash(decode(screenshot(t, 640, 480, 40, 40)))
+	moved := PerceptualHash(decode(screenshot(t, 320, 240, 200, 150)))
+
+	assert.LessOrEqual(t, HashDistance(a, scaled), DefaultHashThreshold)
+	assert.Greater(t, HashDistance(a, moved), DefaultHashThreshold)
+}
+
+func TestComparer_Compare(t *testing.T) {
+	ctx := context.Background()
+	cmp := NewComparer(NewDirBaselineStore(t.TempDir()))
+
+	base := Artifact{
+		ID:         "home",
+		Screenshot: screenshot(t, 320, 240, 20, 20),
+		Components: []Component{
+			{ID: "logo", Box: Rect{X: 20, Y: 20, Width: 80, Height: 60}},
+			{ID: "nav", Box: Rect{X: 120, Y: 20, Width: 180, Height: 30}},
+			{ID: "footer", Box: Rect{X: 0, Y: 220, Width: 320, Height: 20}},
+		},
+	}
+	findings, err := cmp.Compare(ctx, "shop", base)
+	require.NoError(t, err)
+	assert.Empty(t, findings, "first run records the baseline")
+
+	same := base
+	same.Components = append([]Component(nil), base.Components...)
+	same.Components[1].Box.X++ // within tolerance
+	findings, err = cmp.Compare(ctx, "shop", same)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	changed := Artifact{
+		ID:         "home",
+		Screenshot: screenshot(t, 320, 240, 200, 150),
+		Components: []Component{
+			{ID: "logo", Box: Rect{X: 200, Y: 150, Width: 80, Height: 60}},
+			{ID: "nav", Box: Rect{X: 120, Y: 20, Width: 180, Height: 30}},
+			{ID: "banner", Box: Rect{X: 0, Y: 80, Width: 320, Height: 40}},
+		},
+	}
+	findings, err = cmp.Compare(ctx, "shop", changed)
+	require.NoError(t, err)
+
+	got := rules(findings)
+	assert.Equal(t, []string{"logo"}, got["Regression.Layout"])
+	assert.Equal(t, []string{"footer"}, got["Regression.Missing"])
+	assert.Equal(t, []string{"banner"}, got["Regression.Added"])
+	require.Len(t, got["Regression.Screenshot"], 1)
+	for _, f := range findings {
+		assert.Equal(t, "regression", f.Category)
+		assert.NotEmpty(t, f.Regions)
+	}
+
+	// Approving the change makes it the new baseline
+	require.NoError(t, cmp.Approve(ctx, "shop", changed))
+	findings, err = cmp.Compare(ctx, "shop", changed)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestDirBaselineStore_RejectsPaths(t *testing.T) {
+	store := NewDirBaselineStore(t.TempDir())
+	_, err := store.Get(context.Background(), "..", "home")
+	assert.Error(t, err)
+	assert.Error(t, store.Save(context.Background(), &Baseline{ProjectID: "shop", ArtifactID: "a/b"}))
+
+	b, err := store.Get(context.Background(), "shop", "home")
+	require.NoError(t, err)
+	assert.Nil(t, b)
+}
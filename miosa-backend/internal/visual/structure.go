@@ -0,0 +1,199 @@
+package visual
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// unmarkedHeadingScale is how much larger than body text a short, bold text
+// run must be before it is treated as a visual heading
+const unmarkedHeadingScale = 1.5
+
+var formControlRoles = map[string]bool{
+	"input":    true,
+	"textbox":  true,
+	"textarea": true,
+	"select":   true,
+	"combobox": true,
+	"checkbox": true,
+	"radio":    true,
+	"switch":   true,
+	"slider":   true,
+}
+
+func isHeading(c Component) bool {
+	return strings.EqualFold(c.Role, "heading") || c.Level > 0
+}
+
+// readingOrder sorts components top to bottom, then left to right
+func readingOrder(cs []Component) []Component {
+	out := append([]Component(nil), cs...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Box.Y != out[j].Box.Y {
+			return out[i].Box.Y < out[j].Box.Y
+		}
+		return out[i].Box.X < out[j].Box.X
+	})
+	return out
+}
+
+// headingLevels returns the level of each heading, inferring missing levels
+// by ranking distinct font sizes (largest is h1)
+func headingLevels(headings []Component) []int {
+	var sizes []float64
+	seen := map[float64]bool{}
+	for _, h := range headings {
+		if h.Level == 0 && !seen[h.FontSize] {
+			seen[h.FontSize] = true
+			sizes = append(sizes, h.FontSize)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	levels := make([]int, len(headings))
+	for i, h := range headings {
+		if h.Level > 0 {
+			levels[i] = h.Level
+			continue
+		}
+		levels[i] = min(sort.Search(len(sizes), func(k int) bool { return sizes[k] <= h.FontSize })+1, 6)
+	}
+	return levels
+}
+
+// CheckHeadings reports heading structure problems (WCAG 1.3.1, 2.4.6): a
+// page that doesn't start at h1, skipped levels, and large bold text that
+// looks like a heading but isn't marked as one
+func CheckHeadings(a Artifact) []VisualFinding {
+	var headings, body []Component
+	for _, c := range readingOrder(a.Components) {
+		switch {
+		case isHeading(c):
+			headings = append(headings, c)
+		case strings.TrimSpace(c.Text) != "" && c.FontSize > 0 && !isInteractive(c):
+			body = append(body, c)
+		}
+	}
+
+	var findings []VisualFinding
+	levels := headingLevels(headings)
+	prev := 0
+	for i, h := range headings {
+		level := levels[i]
+		var msg string
+		switch {
+		case prev == 0 && level > 1:
+			msg = fmt.Sprintf("First heading is h%d; pages should start with h1", level)
+		case prev > 0 && level > prev+1:
+			msg = fmt.Sprintf("Heading level jumps from h%d to h%d", prev, level)
+		}
+		prev = level
+		if msg == "" {
+			continue
+		}
+		findings = append(findings, VisualFinding{
+			Rule:        "Heading.Hierarchy",
+			Category:    "accessibility",
+			Severity:    "medium",
+			ArtifactID:  a.ID,
+			ComponentID: h.ID,
+			Message:     msg,
+			Evidence:    fmt.Sprintf("%q at %.0fpx", h.Text, h.FontSize),
+			Remediation: "Use heading levels in order without skipping; style them with CSS instead of picking a level for its size.",
+			Regions:     []Rect{h.Box},
+			Confidence:  headingConfidence(h),
+		})
+	}
+
+	bodySize := medianFontSize(body)
+	for _, c := range body {
+		if bodySize == 0 || c.FontSize < bodySize*unmarkedHeadingScale || c.FontWeight < 600 || len(c.Text) > 80 {
+			continue
+		}
+		findings = append(findings, VisualFinding{
+			Rule:        "Heading.Unmarked",
+			Category:    "accessibility",
+			Severity:    "low",
+			ArtifactID:  a.ID,
+			ComponentID: c.ID,
+			Message:     "Text is styled as a heading but not marked up as one",
+			Evidence:    fmt.Sprintf("%q at %.0fpx bold, body text is %.0fpx", c.Text, c.FontSize, bodySize),
+			Remediation: "Use an h1–h6 element (or role=\"heading\" with aria-level) so screen reader users can navigate by it.",
+			Regions:     []Rect{c.Box},
+			Confidence:  0.5,
+		})
+	}
+	return findings
+}
+
+// headingConfidence is lower when the level was inferred from font size
+func headingConfidence(h Component) float64 {
+	if h.Level > 0 {
+		return 0.9
+	}
+	return 0.6
+}
+
+func medianFontSize(cs []Component) float64 {
+	if len(cs) == 0 {
+		return 0
+	}
+	sizes := make([]float64, len(cs))
+	for i, c := range cs {
+		sizes[i] = c.FontSize
+	}
+	sort.Float64s(sizes)
+	return sizes[len(sizes)/2]
+}
+
+// CheckFormLabels reports form controls without an accessible name: no
+// Label of their own and no label component pointing at them (WCAG 1.3.1,
+// 3.3.2, 4.1.2)
+func CheckFormLabels(a Artifact) []VisualFinding {
+	ids := map[string]bool{}
+	labelled := map[string]bool{}
+	for _, c := range a.Components {
+		ids[c.ID] = true
+		if c.LabelFor != "" {
+			labelled[c.LabelFor] = true
+		}
+	}
+
+	var findings []VisualFinding
+	for _, c := range a.Components {
+		if c.LabelFor != "" && !ids[c.LabelFor] {
+			findings = append(findings, VisualFinding{
+				Rule:        "Form.LabelTarget",
+				Category:    "accessibility",
+				Severity:    "low",
+				ArtifactID:  a.ID,
+				ComponentID: c.ID,
+				Message:     fmt.Sprintf("Label points at missing control %q", c.LabelFor),
+				Remediation: "Point the label's for attribute at the id of the control it names.",
+				Regions:     []Rect{c.Box},
+				Confidence:  0.7,
+			})
+		}
+		if !formControlRoles[strings.ToLower(c.Role)] || strings.TrimSpace(c.Label) != "" || labelled[c.ID] {
+			continue
+		}
+		evidence := "no label, aria-label or aria-labelledby"
+		if strings.TrimSpace(c.Text) != "" {
+			evidence = fmt.Sprintf("only placeholder text %q", c.Text)
+		}
+		findings = append(findings, VisualFinding{
+			Rule:        "Form.Label",
+			Category:    "accessibility",
+			Severity:    "high",
+			ArtifactID:  a.ID,
+			ComponentID: c.ID,
+			Message:     fmt.Sprintf("Form %s has no associated label", c.Role),
+			Evidence:    evidence,
+			Remediation: "Add a visible <label for=...> associated with the control; placeholders disappear on input and are not labels.",
+			Regions:     []Rect{c.Box},
+			Confidence:  0.85,
+		})
+	}
+	return findings
+}
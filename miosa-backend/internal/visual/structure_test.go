@@ -0,0 +1,71 @@
+package visual
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(findings []VisualFinding) map[string][]string {
+	out := map[string][]string{}
+	for _, f := range findings {
+		out[f.Rule] = append(out[f.Rule], f.ComponentID)
+	}
+	return out
+}
+
+func TestCheckFocusIndicators(t *testing.T) {
+	a := Artifact{ID: "form", Components: []Component{
+		{ID: "save", Role: "button", Foreground: "#fff", Background: "#0050b3",
+			Focus: &FocusState{Foreground: "#ffffff", Background: "rgb(0, 80, 179)"}},
+		{ID: "cancel", Role: "button", Foreground: "#fff", Background: "#0050b3",
+			Focus: &FocusState{Outline: "2px solid #ffbf47"}},
+		{ID: "card", Interactive: true, Background: "#fff",
+			Focus: &FocusState{Background: "#eef"}},
+		{ID: "help", Role: "link", Foreground: "#06c"},
+		{ID: "para", Role: "text", Foreground: "#000", Focus: &FocusState{}},
+	}}
+
+	assert.Equal(t, map[string][]string{"Focus.Indicator": {"save"}}, rules(CheckFocusIndicators(a)))
+}
+
+func TestCheckHeadings(t *testing.T) {
+	a := Artifact{ID: "page", Components: []Component{
+		{ID: "title", Role: "heading", Text: "Dashboard", FontSize: 32, Box: Rect{Y: 0}},
+		{ID: "section", Role: "heading", Text: "Usage", FontSize: 24, Box: Rect{Y: 100}},
+		{ID: "detail", Role: "heading", Level: 4, Text: "Last 7 days", FontSize: 18, Box: Rect{Y: 200}},
+		{ID: "promo", Text: "Upgrade today", FontSize: 28, FontWeight: 700, Box: Rect{Y: 300}},
+		{ID: "p1", Text: "Requests per day", FontSize: 16, Box: Rect{Y: 400}},
+		{ID: "p2", Text: "Errors per day", FontSize: 16, Box: Rect{Y: 500}},
+	}}
+
+	got := rules(CheckHeadings(a))
+	assert.Equal(t, []string{"detail"}, got["Heading.Hierarchy"])
+	assert.Equal(t, []string{"promo"}, got["Heading.Unmarked"])
+
+	// A page whose only heading is h2
+	findings := CheckHeadings(Artifact{ID: "p", Components: []Component{{ID: "h", Level: 2, Text: "Intro"}}})
+	require.Len(t, findings, 1)
+	assert.Contains(t, findings[0].Message, "start with h1")
+}
+
+func TestCheckFormLabels(t *testing.T) {
+	a := Artifact{ID: "signup", Components: []Component{
+		{ID: "email-label", Role: "text", Text: "Email", LabelFor: "email"},
+		{ID: "email", Role: "input"},
+		{ID: "search", Role: "input", Label: "Search"},
+		{ID: "name", Role: "textbox", Text: "Your name"},
+		{ID: "ghost-label", Text: "Phone", LabelFor: "phone"},
+	}}
+
+	findings := CheckFormLabels(a)
+	got := rules(findings)
+	assert.Equal(t, []string{"name"}, got["Form.Label"])
+	assert.Equal(t, []string{"ghost-label"}, got["Form.LabelTarget"])
+	for _, f := range findings {
+		if f.Rule == "Form.Label" {
+			assert.Contains(t, f.Evidence, "placeholder")
+		}
+	}
+}
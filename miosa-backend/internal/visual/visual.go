@@ -0,0 +1,70 @@
+// Package visual checks UI mocks and screenshots for accessibility issues
+// and visual regressions. Artifacts describe rendered components (boxes,
+// text and colors) as extracted by a renderer or OCR pass; checks turn them
+// into findings.
+package visual
+
+// Rect is a component bounding box in CSS pixels
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Component is one rendered element of an artifact
+type Component struct {
+	ID         string  `json:"id"`
+	Role       string  `json:"role,omitempty"` // button, link, text, heading, input...
+	Text       string  `json:"text,omitempty"`
+	Box        Rect    `json:"box"`
+	Foreground string  `json:"foreground,omitempty"` // CSS color
+	Background string  `json:"background,omitempty"` // CSS color or gradient; empty = page background
+	FontSize   float64 `json:"fontSize,omitempty"`   // CSS px
+	FontWeight int     `json:"fontWeight,omitempty"`
+
+	Interactive bool        `json:"interactive,omitempty"` // focusable even if Role doesn't say so
+	Focus       *FocusState `json:"focus,omitempty"`       // appearance while focused; nil = not captured
+	Level       int         `json:"level,omitempty"`       // heading level 1–6; 0 = infer from font size
+	Label       string      `json:"label,omitempty"`       // accessible name (aria-label, alt...)
+	LabelFor    string      `json:"labelFor,omitempty"`    // on labels: ID of the control they name
+}
+
+// FocusState is how a component renders while it has keyboard focus
+type FocusState struct {
+	Foreground string `json:"foreground,omitempty"`
+	Background string `json:"background,omitempty"`
+	Outline    string `json:"outline,omitempty"` // CSS outline or box-shadow ring; empty or "none" = no ring
+}
+
+// Artifact is one screen, mock or screenshot under review
+type Artifact struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name,omitempty"`
+	Background string      `json:"background,omitempty"` // page background; default white
+	Components []Component `json:"components"`
+	Screenshot []byte      `json:"screenshot,omitempty"` // PNG or JPEG, base64 in JSON
+}
+
+// VisualFinding is an issue detected in an artifact
+type VisualFinding struct {
+	Rule        string   `json:"rule"`
+	Category    string   `json:"category"` // accessibility | regression
+	Severity    string   `json:"severity"` // low | medium | high | critical
+	ArtifactID  string   `json:"artifactId"`
+	ComponentID string   `json:"componentId,omitempty"`
+	Message     string   `json:"message"`
+	Evidence    string   `json:"evidence,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+	Regions     []Rect   `json:"regions,omitempty"`
+	Confidence  float64  `json:"confidence"`
+	References  []string `json:"references,omitempty"` // guideline pack controls, e.g. WCAG success criteria
+}
+
+// Check runs every rule over the artifact
+func Check(a Artifact) []VisualFinding {
+	findings := CheckContrast(a)
+	findings = append(findings, CheckFocusIndicators(a)...)
+	findings = append(findings, CheckHeadings(a)...)
+	return append(findings, CheckFormLabels(a)...)
+}
@@ -0,0 +1,207 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BlobDir holds the blob store, relative to the directory holding the
+// workspaces. Scan skips it like every hidden directory.
+const BlobDir = ".blobs"
+
+// maxBlobPaths bounds the paths a blob remembers being generated at
+const maxBlobPaths = 5
+
+// DefaultBlobs, when set, keeps the last generated content of tracked files
+// once per checksum for every workspace, instead of a copy per workspace in
+// .miosa/base. Identical boilerplate generated by many workflows, such as
+// Dockerfiles and package.json files, is then stored once.
+var DefaultBlobs *BlobStore
+
+// Blob is content stored once however many files hold it
+type Blob struct {
+	Checksum  string    `json:"checksum"`
+	Size      int64     `json:"size"`
+	Refs      int       `json:"refs"`  // Manifest entries holding the content
+	Paths     []string  `json:"paths"` // Where it was generated, first few
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// BlobStats summarises a blob store
+type BlobStats struct {
+	Blobs      int   `json:"blobs"`
+	Refs       int   `json:"refs"`
+	Bytes      int64 `json:"bytes"`
+	SavedBytes int64 `json:"saved_bytes"` // What a copy per reference would add
+}
+
+// BlobStore is a content-addressable store with reference counting. Each
+// blob is kept as <checksum> with its metadata in <checksum>.json, under a
+// directory named by the first two characters of the checksum.
+type BlobStore struct {
+	dir string
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// NewBlobStore creates a store rooted at dir
+func NewBlobStore(dir string) *BlobStore {
+	return &BlobStore{dir: dir, now: time.Now}
+}
+
+// Put stores content generated at rel, or references the copy already
+// stored, and returns the blob with its updated reference count
+func (s *BlobStore) Put(content, rel string) (*Blob, error) {
+	sum := checksum(content)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob, err := s.stat(sum)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := writeFile(s.path(sum), content); err != nil {
+			return nil, err
+		}
+		blob, err = &Blob{Checksum: sum, Size: int64(len(content)), FirstSeen: s.now()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	blob.Refs++
+	if len(blob.Paths) < maxBlobPaths && !containsString(blob.Paths, rel) {
+		blob.Paths = append(blob.Paths, rel)
+	}
+	if err := writeJSON(s.path(sum)+".json", blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Get returns the content stored under sum
+func (s *BlobStore) Get(sum string) (string, error) {
+	if !validChecksum(sum) {
+		return "", os.ErrNotExist
+	}
+	data, err := os.ReadFile(s.path(sum))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Stat returns the blob stored under sum
+func (s *BlobStore) Stat(sum string) (*Blob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stat(sum)
+}
+
+// Release drops a reference to sum, deleting the blob once none remain
+func (s *BlobStore) Release(sum string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blob, err := s.stat(sum)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if blob.Refs--; blob.Refs > 0 {
+		return writeJSON(s.path(sum)+".json", blob)
+	}
+	if err := os.Remove(s.path(sum)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Remove(s.path(sum) + ".json")
+}
+
+// Stats counts the blobs and what storing them once saves
+func (s *BlobStore) Stats() (BlobStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stats BlobStats
+	metas, err := filepath.Glob(filepath.Join(s.dir, "*", "*.json"))
+	if err != nil {
+		return stats, err
+	}
+	for _, meta := range metas {
+		var blob Blob
+		if err := readJSON(meta, &blob); err != nil {
+			return stats, err
+		}
+		stats.Blobs++
+		stats.Refs += blob.Refs
+		stats.Bytes += blob.Size
+		if blob.Refs > 1 {
+			stats.SavedBytes += blob.Size * int64(blob.Refs-1)
+		}
+	}
+	return stats, nil
+}
+
+func (s *BlobStore) stat(sum string) (*Blob, error) {
+	if !validChecksum(sum) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(s.path(sum) + ".json")
+	if err != nil {
+		return nil, err
+	}
+	var blob Blob
+	if err := json.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode blob %s: %w", sum, err)
+	}
+	return &blob, nil
+}
+
+func (s *BlobStore) path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum)
+}
+
+// releaseBlobs drops the references held by the manifest of the workspace
+// at root, before it is deleted
+func releaseBlobs(root string) error {
+	if DefaultBlobs == nil {
+		return nil
+	}
+	var files map[string]Entry
+	if err := readJSON(filepath.Join(root, manifestFile), &files); err != nil {
+		return err
+	}
+	for _, entry := range files {
+		if entry.Shared {
+			if err := DefaultBlobs.Release(entry.Checksum); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validChecksum reports whether sum is a hex SHA-256, so it is safe to use
+// as a file name
+func validChecksum(sum string) bool {
+	if len(sum) != 64 {
+		return false
+	}
+	for _, c := range sum {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package workspace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useBlobs sets DefaultBlobs to a store under root for the test
+func useBlobs(t *testing.T, root string) *BlobStore {
+	t.Helper()
+	store := NewBlobStore(filepath.Join(root, BlobDir))
+	DefaultBlobs = store
+	t.Cleanup(func() { DefaultBlobs = nil })
+	return store
+}
+
+func TestWorkspace_WriteShared(t *testing.T) {
+	root := t.TempDir()
+	store := useBlobs(t, root)
+	dockerfile := "FROM golang:1.22\nRUN go build ./...\n"
+
+	write := func(name, rel, content string) *WriteResult {
+		t.Helper()
+		ws, err := Open(filepath.Join(root, name))
+		require.NoError(t, err)
+		res, err := ws.Write(rel, content, gen)
+		require.NoError(t, err)
+		require.NoError(t, ws.Save())
+		return res
+	}
+
+	assert.False(t, write("a", "Dockerfile", dockerfile).Reused)
+	res := write("b", "Dockerfile", dockerfile)
+	assert.True(t, res.Reused, "the second workflow generates the same file")
+	assert.Equal(t, dockerfile, readFile(t, res.Path), "projects still hold their files")
+	assert.NoDirExists(t, filepath.Join(root, "b", baseDir), "the base copy is shared")
+
+	blob, err := store.Stat(checksum(dockerfile))
+	require.NoError(t, err)
+	assert.Equal(t, 2, blob.Refs)
+	assert.Equal(t, []string{"Dockerfile"}, blob.Paths)
+
+	// Rewriting the same content holds on to the same reference
+	write("b", "Dockerfile", dockerfile)
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, BlobStats{Blobs: 1, Refs: 2, Bytes: int64(len(dockerfile)), SavedBytes: int64(len(dockerfile))}, stats)
+
+	// Merges read the shared base
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b", "Dockerfile"), []byte("# local\n"+dockerfile), 0644))
+	res = write("b", "Dockerfile", "FROM golang:1.22\nRUN go build -o /app ./...\n")
+	require.Equal(t, Conflict, res.Outcome)
+	assert.True(t, res.Conflict.Clean())
+	assert.Equal(t, "# local\nFROM golang:1.22\nRUN go build -o /app ./...\n", readFile(t, filepath.Join(root, "b", res.Conflict.Merged)))
+
+	// New content releases the old
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b", "Dockerfile"), []byte(dockerfile), 0644))
+	write("b", "Dockerfile", "FROM alpine\n")
+	blob, err = store.Stat(checksum(dockerfile))
+	require.NoError(t, err)
+	assert.Equal(t, 1, blob.Refs)
+
+	// Purging a workspace drops its references; the last one deletes the blob
+	infos, err := Scan(root)
+	require.NoError(t, err)
+	require.Len(t, infos, 2, "the blob store is not a workspace")
+	reaper := NewReaper(root, Policies{}, nil)
+	_, err = reaper.Purge(context.Background(), []string{"a", "b"}, false)
+	require.NoError(t, err)
+	stats, err = store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, BlobStats{}, stats)
+
+	_, err = store.Get("../../etc/passwd")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBlobsHandler(t *testing.T) {
+	store := NewBlobStore(t.TempDir())
+	store.now = func() time.Time { return retentionNow }
+	_, err := store.Put("{}", "package.json")
+	require.NoError(t, err)
+	_, err = store.Put("{}", "web/package.json")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	BlobsHandler(store)(rec, httptest.NewRequest(http.MethodGet, "/api/admin/blobs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"blobs": 1, "refs": 2, "bytes": 2, "saved_bytes": 2}`, rec.Body.String())
+}
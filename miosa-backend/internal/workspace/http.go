@@ -0,0 +1,195 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ProvenanceHandler serves GET /api/workflow/{id}/provenance, listing the
+// files the workflow generated and what produced each. root maps a workflow
+// to the workspace it writes into.
+func ProvenanceHandler(root func(workflowID uuid.UUID) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ws, ok := openWorkflow(w, r, root)
+		if !ok {
+			return
+		}
+
+		files := workflowFiles(ws, id)
+		conflicts := []FileConflict{}
+		for _, c := range ws.Conflicts() {
+			if c.Provenance.WorkflowID == id.String() {
+				conflicts = append(conflicts, c)
+			}
+		}
+		if len(files) == 0 && len(conflicts) == 0 {
+			http.Error(w, "no generated files recorded for workflow", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"workflow_id": id,
+			"files":       files,
+			"conflicts":   conflicts,
+		})
+	}
+}
+
+// ArchiveHandler serves GET /api/workflow/{id}/archive, a tar.gz of the files
+// the workflow generated as they are on disk now
+func ArchiveHandler(root func(workflowID uuid.UUID) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ws, ok := openWorkflow(w, r, root)
+		if !ok {
+			return
+		}
+		files := workflowFiles(ws, id)
+		if len(files) == 0 {
+			http.Error(w, "no generated files recorded for workflow", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=workflow_%s.tar.gz", id.String()[:8]))
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		for _, f := range files {
+			// The response has started, so a file removed since generation
+			// is left out rather than failing the download
+			content, err := os.ReadFile(filepath.Join(ws.root, filepath.FromSlash(f.Path)))
+			if err != nil {
+				continue
+			}
+			hdr := &tar.Header{Name: f.Path, Mode: 0644, Size: int64(len(content)), ModTime: f.GeneratedAt}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return
+			}
+			if _, err := tw.Write(content); err != nil {
+				return
+			}
+		}
+		tw.Close()
+		gz.Close()
+	}
+}
+
+// openWorkflow parses the {id} route variable and opens its workspace,
+// writing an error response when either fails
+func openWorkflow(w http.ResponseWriter, r *http.Request, root func(uuid.UUID) string) (uuid.UUID, *Workspace, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid workflow id", http.StatusBadRequest)
+		return uuid.Nil, nil, false
+	}
+	ws, err := Open(root(id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return uuid.Nil, nil, false
+	}
+	return id, ws, true
+}
+
+// workflowFiles returns the tracked files last written by the workflow
+func workflowFiles(ws *Workspace, id uuid.UUID) []File {
+	files := []File{}
+	for _, f := range ws.Files() {
+		if f.Provenance.WorkflowID == id.String() {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// WorkspacesHandler serves GET /api/admin/workspaces, listing every project
+// directory with its size, owner and, when the policies would purge it now,
+// why. ?tenant_id= limits the list to one tenant.
+func WorkspacesHandler(reaper *Reaper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		infos, err := Scan(reaper.Root)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		policies := reaper.Policies()
+		reasons := make(map[string]string)
+		for _, c := range Select(infos, policies, reaper.now()) {
+			reasons[c.Name] = c.Reason
+		}
+
+		type listed struct {
+			Info
+			Purge string `json:"purge,omitempty"`
+		}
+		tenant := r.URL.Query().Get("tenant_id")
+		list := []listed{}
+		var total int64
+		for _, info := range infos {
+			if tenant != "" && info.TenantID != tenant {
+				continue
+			}
+			list = append(list, listed{Info: info, Purge: reasons[info.Name]})
+			total += info.Bytes
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"workspaces": list,
+			"bytes":      total,
+			"policies":   policies,
+		})
+	}
+}
+
+// PurgeHandler serves POST /api/admin/workspaces/purge. The body names the
+// workspaces to purge; without names the policies' candidates are purged.
+// dry_run reports what would be purged without touching anything.
+func PurgeHandler(reaper *Reaper) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Names  []string `json:"names"`
+			DryRun bool     `json:"dry_run"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid purge request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		result, err := reaper.Purge(r.Context(), req.Names, req.DryRun)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// BlobsHandler serves GET /api/admin/blobs, the size of the blob store and
+// what deduplication saves
+func BlobsHandler(store *BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := store.Stats()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
@@ -0,0 +1,161 @@
+package workspace
+
+import (
+	"strings"
+)
+
+// Conflict markers written into merged files, in the style of diff3
+const (
+	markerOurs   = "<<<<<<< local\n"
+	markerBase   = "||||||| generated\n"
+	markerSep    = "=======\n"
+	markerTheirs = ">>>>>>> proposed\n"
+)
+
+// maxLCSCells bounds the line-matching table; larger differing regions are
+// treated as replaced wholesale
+const maxLCSCells = 1 << 22
+
+// Merge3 merges the changes from base to ours and from base to theirs line
+// by line. Where both sides changed the same lines differently the result
+// holds diff3-style conflict markers; hunks counts those regions.
+func Merge3(base, ours, theirs string) (merged string, hunks int) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	mo, mt := matchLines(b, o), matchLines(b, t)
+
+	var sb strings.Builder
+	bi, oi, ti := 0, 0, 0
+	for {
+		// Lines unchanged on both sides
+		for bi < len(b) && mo[bi] == oi && mt[bi] == ti {
+			sb.WriteString(b[bi])
+			bi, oi, ti = bi+1, oi+1, ti+1
+		}
+
+		// The changed region ends at the next base line both sides kept
+		end := bi
+		for end < len(b) && (mo[end] < 0 || mt[end] < 0) {
+			end++
+		}
+		oEnd, tEnd := len(o), len(t)
+		if end < len(b) {
+			oEnd, tEnd = mo[end], mt[end]
+		}
+		if end == bi && oEnd == oi && tEnd == ti {
+			break
+		}
+
+		bs, ls, ts := b[bi:end], o[oi:oEnd], t[ti:tEnd]
+		switch {
+		case equalLines(ls, bs):
+			writeLines(&sb, ts)
+		case equalLines(ts, bs), equalLines(ls, ts):
+			writeLines(&sb, ls)
+		default:
+			hunks++
+			sb.WriteString(markerOurs)
+			writeTerminated(&sb, ls)
+			sb.WriteString(markerBase)
+			writeTerminated(&sb, bs)
+			sb.WriteString(markerSep)
+			writeTerminated(&sb, ts)
+			sb.WriteString(markerTheirs)
+		}
+		bi, oi, ti = end, oEnd, tEnd
+	}
+	return sb.String(), hunks
+}
+
+// splitLines splits content into lines that keep their newline
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// matchLines pairs each line of a with its line in b along a longest common
+// subsequence, or -1 when it has none. Matches are strictly increasing.
+func matchLines(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Common prefix and suffix need no table
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		match[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		match[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, m := len(ma), len(mb)
+	if n == 0 || m == 0 || (n+1)*(m+1) > maxLCSCells {
+		return match
+	}
+
+	// lcs[i][j] is the LCS length of ma[i:] and mb[j:]
+	w := m + 1
+	lcs := make([]int32, (n+1)*w)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case ma[i] == mb[j]:
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+				lcs[i*w+j] = lcs[(i+1)*w+j]
+			default:
+				lcs[i*w+j] = lcs[i*w+j+1]
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case ma[i] == mb[j]:
+			match[pre+i] = pre + j
+			i++
+			j++
+		case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, l := range lines {
+		sb.WriteString(l)
+	}
+}
+
+// writeTerminated writes lines so that a following marker starts its own line
+func writeTerminated(sb *strings.Builder, lines []string) {
+	writeLines(sb, lines)
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		sb.WriteByte('\n')
+	}
+}
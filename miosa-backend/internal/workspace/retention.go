@@ -0,0 +1,517 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// OwnerFile records who a project directory was generated for, so retention
+// can apply the tenant's policy
+const OwnerFile = StateDir + "/owner.json"
+
+// ErrNotFound is returned when a purge names a workspace that does not exist
+var ErrNotFound = errors.New("workspace not found")
+
+// Purge reasons
+const (
+	ReasonExpired   = "expired"   // Idle for longer than the tenant's TTL
+	ReasonQuota     = "quota"     // Oldest of a tenant over its size quota
+	ReasonRequested = "requested" // Named in a purge request
+)
+
+// Owner is the workflow and tenant a project directory belongs to
+type Owner struct {
+	WorkflowID string    `json:"workflow_id"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecordOwner writes root's owner unless one is already recorded, keeping
+// the original creation time across resumed runs
+func RecordOwner(root string, owner Owner) error {
+	existing, err := ReadOwner(root)
+	if err != nil {
+		return err
+	}
+	if existing.WorkflowID != "" {
+		return nil
+	}
+	if owner.CreatedAt.IsZero() {
+		owner.CreatedAt = time.Now()
+	}
+	if err := writeJSON(filepath.Join(root, OwnerFile), owner); err != nil {
+		return fmt.Errorf("failed to save workspace owner: %w", err)
+	}
+	return nil
+}
+
+// ReadOwner returns root's recorded owner, the zero Owner when none is
+func ReadOwner(root string) (Owner, error) {
+	var owner Owner
+	if err := readJSON(filepath.Join(root, OwnerFile), &owner); err != nil {
+		return Owner{}, fmt.Errorf("failed to load workspace owner: %w", err)
+	}
+	return owner, nil
+}
+
+// Policy limits how long and how much a tenant's workspaces are kept. Zero
+// values are unlimited.
+type Policy struct {
+	TTL      time.Duration `yaml:"ttl" json:"ttl"`             // Since the last change to a workspace
+	MaxBytes int64         `yaml:"max_bytes" json:"max_bytes"` // Across all of a tenant's workspaces
+}
+
+// Policies are the retention policies by tenant ID; tenants without their
+// own, and workspaces without a recorded owner, get Default
+type Policies struct {
+	Default Policy            `yaml:"default" json:"default"`
+	Tenants map[string]Policy `yaml:"tenants" json:"tenants,omitempty"`
+}
+
+// For returns the policy applying to tenant
+func (p Policies) For(tenant string) Policy {
+	if policy, ok := p.Tenants[tenant]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// LoadPolicies reads retention policies from a YAML file, e.g.
+//
+//	default: {ttl: 168h, max_bytes: 1073741824}
+//	tenants:
+//	  5f1c...: {ttl: 720h}
+func LoadPolicies(path string) (Policies, error) {
+	var policies Policies
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policies, fmt.Errorf("failed to read retention policies: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &policies); err != nil {
+		return policies, fmt.Errorf("failed to parse retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+// Info describes one project directory under the workspace root
+type Info struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	WorkflowID   string    `json:"workflow_id,omitempty"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	LastActivity time.Time `json:"last_activity"` // Latest modification of any file
+	Files        int       `json:"files"`
+	Bytes        int64     `json:"bytes"`
+}
+
+// Scan describes every project directory under root, oldest activity
+// first. Hidden directories hold shared state, like BlobDir, and are
+// skipped.
+func Scan(root string) ([]Info, error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []Info{}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := scanDir(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].LastActivity.Before(infos[j].LastActivity) })
+	return infos, nil
+}
+
+func scanDir(dir string) (Info, error) {
+	info := Info{Name: filepath.Base(dir), Path: dir}
+	var owner Owner
+	if err := readJSON(filepath.Join(dir, OwnerFile), &owner); err != nil {
+		return info, fmt.Errorf("failed to load owner of %s: %w", info.Name, err)
+	}
+	info.WorkflowID, info.TenantID, info.CreatedAt = owner.WorkflowID, owner.TenantID, owner.CreatedAt
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(info.LastActivity) {
+			info.LastActivity = fi.ModTime()
+		}
+		if !d.IsDir() {
+			info.Files++
+			info.Bytes += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return info, fmt.Errorf("failed to scan %s: %w", info.Name, err)
+	}
+	return info, nil
+}
+
+// Candidate is a workspace selected for purging
+type Candidate struct {
+	Info
+	Reason string `json:"reason"`
+}
+
+// Select returns the workspaces policies purge at now: those idle past
+// their tenant's TTL, then the oldest of each tenant still over its quota.
+// infos must be oldest first, as Scan returns them.
+func Select(infos []Info, policies Policies, now time.Time) []Candidate {
+	var selected []Candidate
+	usage := make(map[string]int64)
+	var kept []Info
+	for _, info := range infos {
+		policy := policies.For(info.TenantID)
+		if policy.TTL > 0 && now.Sub(info.LastActivity) > policy.TTL {
+			selected = append(selected, Candidate{Info: info, Reason: ReasonExpired})
+			continue
+		}
+		usage[info.TenantID] += info.Bytes
+		kept = append(kept, info)
+	}
+	for _, info := range kept {
+		policy := policies.For(info.TenantID)
+		if policy.MaxBytes > 0 && usage[info.TenantID] > policy.MaxBytes {
+			selected = append(selected, Candidate{Info: info, Reason: ReasonQuota})
+			usage[info.TenantID] -= info.Bytes
+		}
+	}
+	return selected
+}
+
+// Archiver stores a workspace's tar.gz before it is deleted
+type Archiver interface {
+	// Archive stores size bytes from r under key and returns where
+	Archive(ctx context.Context, key string, r io.Reader, size int64) (string, error)
+}
+
+// DirArchiver archives into a directory, such as a mounted bucket
+type DirArchiver struct {
+	Dir string
+}
+
+// Archive implements Archiver
+func (a DirArchiver) Archive(ctx context.Context, key string, r io.Reader, size int64) (string, error) {
+	path := filepath.Join(a.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// HTTPArchiver PUTs archives to an object store under BaseURL, e.g. a
+// bucket endpoint of an S3-compatible gateway or the GCS XML API
+type HTTPArchiver struct {
+	BaseURL string
+	Token   string // Sent as a bearer token when set
+	Client  *http.Client
+}
+
+// Archive implements Archiver
+func (a HTTPArchiver) Archive(ctx context.Context, key string, r io.Reader, size int64) (string, error) {
+	url := strings.TrimRight(a.BaseURL, "/") + "/" + key
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, r)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("object store returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return url, nil
+}
+
+// NewArchiver returns an HTTPArchiver for http(s) URLs and a DirArchiver
+// for anything else
+func NewArchiver(target, token string) Archiver {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return HTTPArchiver{BaseURL: target, Token: token}
+	}
+	return DirArchiver{Dir: strings.TrimPrefix(target, "file://")}
+}
+
+// Purged is the outcome of purging one workspace
+type Purged struct {
+	Candidate
+	Archive string `json:"archive,omitempty"` // Where the workspace was archived
+	Error   string `json:"error,omitempty"`   // Why it was kept
+}
+
+// PurgeResult is the outcome of one purge pass
+type PurgeResult struct {
+	DryRun     bool     `json:"dry_run"`
+	Workspaces []Purged `json:"workspaces"`
+	Bytes      int64    `json:"bytes"` // Freed, or that would be freed on a dry run
+}
+
+// Reaper enforces retention policies on the project directories under Root.
+// With an Archiver, each workspace is archived before deletion and kept if
+// archiving fails.
+type Reaper struct {
+	Root     string
+	Archiver Archiver
+
+	mu       sync.RWMutex
+	policies Policies
+	now      func() time.Time
+}
+
+// NewReaper creates a reaper for root
+func NewReaper(root string, policies Policies, archiver Archiver) *Reaper {
+	return &Reaper{Root: root, Archiver: archiver, policies: policies, now: time.Now}
+}
+
+// Policies returns the policies in effect
+func (r *Reaper) Policies() Policies {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policies
+}
+
+// SetPolicies replaces the policies in effect
+func (r *Reaper) SetPolicies(policies Policies) {
+	r.mu.Lock()
+	r.policies = policies
+	r.mu.Unlock()
+}
+
+// Candidates returns the workspaces the policies would purge now
+func (r *Reaper) Candidates() ([]Candidate, error) {
+	infos, err := Scan(r.Root)
+	if err != nil {
+		return nil, err
+	}
+	return Select(infos, r.Policies(), r.now()), nil
+}
+
+// Purge purges the policies' candidates, plus the workspaces named in
+// names regardless of policy. A dry run reports what would be purged.
+func (r *Reaper) Purge(ctx context.Context, names []string, dryRun bool) (*PurgeResult, error) {
+	infos, err := Scan(r.Root)
+	if err != nil {
+		return nil, err
+	}
+	candidates := Select(infos, r.Policies(), r.now())
+	if len(names) > 0 {
+		candidates, err = named(infos, candidates, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PurgeResult{DryRun: dryRun, Workspaces: []Purged{}}
+	for _, c := range candidates {
+		p := Purged{Candidate: c}
+		if !dryRun {
+			p.Archive, err = r.purge(ctx, c.Info)
+			if err != nil {
+				p.Error = err.Error()
+				result.Workspaces = append(result.Workspaces, p)
+				continue
+			}
+		}
+		result.Bytes += c.Bytes
+		result.Workspaces = append(result.Workspaces, p)
+	}
+	return result, nil
+}
+
+// named restricts candidates to names, adding those no policy selected
+func named(infos []Info, candidates []Candidate, names []string) ([]Candidate, error) {
+	reasons := make(map[string]string)
+	for _, c := range candidates {
+		reasons[c.Name] = c.Reason
+	}
+	byName := make(map[string]Info)
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+	selected := make([]Candidate, 0, len(names))
+	for _, name := range names {
+		info, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		reason := reasons[name]
+		if reason == "" {
+			reason = ReasonRequested
+		}
+		selected = append(selected, Candidate{Info: info, Reason: reason})
+	}
+	return selected, nil
+}
+
+// purge archives a workspace if an archiver is set, then deletes it
+func (r *Reaper) purge(ctx context.Context, info Info) (string, error) {
+	var location string
+	if r.Archiver != nil {
+		var err error
+		location, err = r.archive(ctx, info)
+		if err != nil {
+			return "", fmt.Errorf("failed to archive workspace: %w", err)
+		}
+	}
+	if err := releaseBlobs(info.Path); err != nil {
+		return location, fmt.Errorf("failed to release shared content: %w", err)
+	}
+	if err := os.RemoveAll(info.Path); err != nil {
+		return location, fmt.Errorf("failed to delete workspace: %w", err)
+	}
+	return location, nil
+}
+
+// archive writes the workspace, state included, to a temporary tar.gz and
+// hands it to the archiver under <tenant>/<name>-<time>.tar.gz
+func (r *Reaper) archive(ctx context.Context, info Info) (string, error) {
+	tmp, err := os.CreateTemp("", "workspace-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := writeTarGz(tmp, info.Path, info.Name); err != nil {
+		return "", err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	tenant := info.TenantID
+	if tenant == "" {
+		tenant = "unowned"
+	}
+	key := fmt.Sprintf("%s/%s-%s.tar.gz", tenant, info.Name, r.now().UTC().Format("20060102T150405Z"))
+	return r.Archiver.Archive(ctx, key, tmp, size)
+}
+
+// writeTarGz writes every file under dir to w, named under prefix
+func writeTarGz(w io.Writer, dir, prefix string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = prefix + "/" + filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Run purges policy candidates every interval until ctx is done
+func (r *Reaper) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := r.Purge(ctx, nil, false)
+		if err != nil {
+			logger.Warn("Failed to enforce workspace retention", zap.Error(err))
+		} else {
+			purged := 0
+			for _, p := range result.Workspaces {
+				if p.Error != "" {
+					logger.Warn("Kept workspace past retention",
+						zap.String("workspace", p.Name), zap.String("reason", p.Reason), zap.String("error", p.Error))
+					continue
+				}
+				purged++
+			}
+			if purged > 0 {
+				logger.Info("Purged workspaces", zap.Int("workspaces", purged), zap.Int64("bytes", result.Bytes))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package workspace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var retentionNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// project creates a project directory of size bytes last changed at mtime
+func project(t *testing.T, root, name, tenant string, size int, mtime time.Time) string {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	require.NoError(t, RecordOwner(dir, Owner{WorkflowID: name + "-wf", TenantID: tenant, CreatedAt: mtime}))
+	require.NoError(t, writeFile(filepath.Join(dir, "main.go"), strings.Repeat("x", size)))
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		require.NoError(t, err)
+		return os.Chtimes(path, mtime, mtime)
+	}))
+	return dir
+}
+
+func TestRecordOwner(t *testing.T) {
+	root := t.TempDir()
+	first := Owner{WorkflowID: "wf", TenantID: "acme", CreatedAt: retentionNow}
+	require.NoError(t, RecordOwner(root, first))
+	require.NoError(t, RecordOwner(root, Owner{WorkflowID: "wf", TenantID: "acme"}))
+
+	var owner Owner
+	require.NoError(t, readJSON(filepath.Join(root, OwnerFile), &owner))
+	assert.Equal(t, first, owner, "a resumed run keeps the original owner")
+	assert.True(t, IsState(OwnerFile))
+}
+
+func TestLoadPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retention.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("default: {ttl: 168h, max_bytes: 1000}\ntenants:\n  acme: {ttl: 720h}\n"), 0644))
+
+	policies, err := LoadPolicies(path)
+	require.NoError(t, err)
+	assert.Equal(t, Policy{TTL: 168 * time.Hour, MaxBytes: 1000}, policies.For("other"))
+	assert.Equal(t, Policy{TTL: 720 * time.Hour}, policies.For("acme"))
+
+	_, err = LoadPolicies(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestSelect(t *testing.T) {
+	root := t.TempDir()
+	project(t, root, "old", "acme", 10, retentionNow.Add(-10*24*time.Hour))
+	project(t, root, "a1", "acme", 100, retentionNow.Add(-3*time.Hour))
+	project(t, root, "a2", "acme", 100, retentionNow.Add(-2*time.Hour))
+	project(t, root, "a3", "acme", 100, retentionNow.Add(-time.Hour))
+	project(t, root, "b1", "beta", 100, retentionNow.Add(-30*24*time.Hour))
+
+	infos, err := Scan(root)
+	require.NoError(t, err)
+	require.Len(t, infos, 5)
+	assert.Equal(t, "b1", infos[0].Name, "oldest activity first")
+	assert.Equal(t, "beta", infos[0].TenantID)
+	assert.Equal(t, 2, infos[0].Files, "the owner record counts towards the workspace")
+
+	policies := Policies{
+		Default: Policy{TTL: 7 * 24 * time.Hour, MaxBytes: infos[1].Bytes + 2*infos[2].Bytes},
+		Tenants: map[string]Policy{"beta": {}},
+	}
+	var selected []string
+	for _, c := range Select(infos, policies, retentionNow) {
+		selected = append(selected, c.Name+":"+c.Reason)
+	}
+	assert.Equal(t, []string{"old:" + ReasonExpired, "a1:" + ReasonQuota}, selected)
+}
+
+type failingArchiver struct{}
+
+func (failingArchiver) Archive(ctx context.Context, key string, r io.Reader, size int64) (string, error) {
+	return "", errors.New("bucket unavailable")
+}
+
+func TestReaper_Purge(t *testing.T) {
+	root, archive := t.TempDir(), t.TempDir()
+	expired := project(t, root, "expired", "acme", 10, retentionNow.Add(-48*time.Hour))
+	fresh := project(t, root, "fresh", "acme", 10, retentionNow)
+
+	reaper := NewReaper(root, Policies{Default: Policy{TTL: 24 * time.Hour}}, DirArchiver{Dir: archive})
+	reaper.now = func() time.Time { return retentionNow }
+
+	dry, err := reaper.Purge(context.Background(), nil, true)
+	require.NoError(t, err)
+	require.Len(t, dry.Workspaces, 1)
+	assert.Equal(t, ReasonExpired, dry.Workspaces[0].Reason)
+	assert.DirExists(t, expired, "a dry run deletes nothing")
+
+	result, err := reaper.Purge(context.Background(), nil, false)
+	require.NoError(t, err)
+	require.Len(t, result.Workspaces, 1)
+	assert.NoDirExists(t, expired)
+	assert.DirExists(t, fresh)
+	assert.Equal(t, dry.Bytes, result.Bytes)
+
+	location := result.Workspaces[0].Archive
+	assert.Equal(t, filepath.Join(archive, "acme", "expired-20260301T120000Z.tar.gz"), location)
+	f, err := os.Open(location)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+	}
+	assert.ElementsMatch(t, []string{"expired/main.go", "expired/" + OwnerFile}, names)
+
+	// Named workspaces are purged regardless of policy
+	result, err = reaper.Purge(context.Background(), []string{"fresh"}, false)
+	require.NoError(t, err)
+	require.Len(t, result.Workspaces, 1)
+	assert.Equal(t, ReasonRequested, result.Workspaces[0].Reason)
+	assert.NoDirExists(t, fresh)
+
+	_, err = reaper.Purge(context.Background(), []string{"missing"}, false)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestReaper_PurgeKeepsUnarchived(t *testing.T) {
+	root := t.TempDir()
+	dir := project(t, root, "expired", "", 10, retentionNow.Add(-48*time.Hour))
+	reaper := NewReaper(root, Policies{Default: Policy{TTL: time.Hour}}, failingArchiver{})
+	reaper.now = func() time.Time { return retentionNow }
+
+	result, err := reaper.Purge(context.Background(), nil, false)
+	require.NoError(t, err)
+	require.Len(t, result.Workspaces, 1)
+	assert.Contains(t, result.Workspaces[0].Error, "bucket unavailable")
+	assert.Zero(t, result.Bytes)
+	assert.DirExists(t, dir)
+}
+
+func TestHTTPArchiver(t *testing.T) {
+	var got []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/bucket/acme/ws.tar.gz", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, int64(4), r.ContentLength)
+		got, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	archiver := NewArchiver(server.URL+"/bucket/", "secret")
+	location, err := archiver.Archive(context.Background(), "acme/ws.tar.gz", bytes.NewReader([]byte("data")), 4)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/bucket/acme/ws.tar.gz", location)
+	assert.Equal(t, "data", string(got))
+
+	assert.Equal(t, DirArchiver{Dir: "/mnt/archive"}, NewArchiver("file:///mnt/archive", ""))
+}
+
+func TestWorkspaceAdminHandlers(t *testing.T) {
+	root := t.TempDir()
+	project(t, root, "expired", "acme", 10, retentionNow.Add(-48*time.Hour))
+	project(t, root, "other", "beta", 10, retentionNow)
+	reaper := NewReaper(root, Policies{Default: Policy{TTL: 24 * time.Hour}}, nil)
+	reaper.now = func() time.Time { return retentionNow }
+
+	rec := httptest.NewRecorder()
+	WorkspacesHandler(reaper)(rec, httptest.NewRequest(http.MethodGet, "/api/admin/workspaces?tenant_id=acme", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Workspaces []struct {
+			Name  string `json:"name"`
+			Purge string `json:"purge"`
+		} `json:"workspaces"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Workspaces, 1)
+	assert.Equal(t, "expired", list.Workspaces[0].Name)
+	assert.Equal(t, ReasonExpired, list.Workspaces[0].Purge)
+
+	rec = httptest.NewRecorder()
+	PurgeHandler(reaper)(rec, httptest.NewRequest(http.MethodPost, "/api/admin/workspaces/purge", strings.NewReader(`{"dry_run":true}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result PurgeResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.True(t, result.DryRun)
+	require.Len(t, result.Workspaces, 1)
+	assert.DirExists(t, filepath.Join(root, "expired"))
+
+	rec = httptest.NewRecorder()
+	PurgeHandler(reaper)(rec, httptest.NewRequest(http.MethodPost, "/api/admin/workspaces/purge", strings.NewReader(`{"names":["nope"]}`)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,347 @@
+// Package workspace writes generated files into a project directory without
+// clobbering edits made since the last generation. It remembers a checksum,
+// a copy and the provenance of every file it writes; when a later run would
+// overwrite a file that changed on disk, the new output goes to
+// .miosa/proposed/ with a three-way merge against the local edits instead.
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Paths under the project root holding workspace state
+const (
+	StateDir    = ".miosa"
+	ProposedDir = StateDir + "/proposed"
+	ReportFile  = ProposedDir + "/report.json"
+
+	manifestFile = StateDir + "/manifest.json"
+	baseDir      = StateDir + "/base"
+)
+
+// Outcome is what Write did with a file
+type Outcome string
+
+const (
+	Written   Outcome = "written"   // New, or unmodified since it was last generated
+	Unchanged Outcome = "unchanged" // Already held the generated content
+	Conflict  Outcome = "conflict"  // Modified locally; output proposed instead
+	Kept      Outcome = "kept"      // Owned by a step outside the current run
+)
+
+// Conflict reasons
+const (
+	ReasonModified  = "modified"  // Edited since it was last generated
+	ReasonUntracked = "untracked" // Present but never generated here
+)
+
+// Provenance identifies what generated a file
+type Provenance struct {
+	WorkflowID    string `json:"workflow_id,omitempty"`
+	Run           string `json:"run,omitempty"` // One execution or resumption of the workflow
+	Step          int    `json:"step"`          // Index of the step in the workflow sequence
+	Agent         string `json:"agent,omitempty"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	RequestID     string `json:"request_id,omitempty"` // API request that started the run
+}
+
+// owns reports whether a write with provenance p may replace a file last
+// generated with provenance prev: the same step generated it, or an earlier
+// step of the same run did. Files predating provenance are always replaceable.
+func (p Provenance) owns(prev Provenance) bool {
+	if prev.Run == "" || prev.Run == p.Run {
+		return true
+	}
+	return prev.Step == p.Step && prev.Agent == p.Agent
+}
+
+// Entry records the last content generated for a file
+type Entry struct {
+	Checksum    string     `json:"checksum"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Provenance  Provenance `json:"provenance"`
+	Shared      bool       `json:"shared,omitempty"` // Content kept in DefaultBlobs rather than .miosa/base
+}
+
+// File is a generated file and its manifest entry
+type File struct {
+	Path string `json:"path"`
+	Entry
+}
+
+// FileConflict is generated output held back to protect local edits. Paths
+// are relative to the project root.
+type FileConflict struct {
+	Path       string     `json:"path"`
+	Reason     string     `json:"reason"`
+	Proposed   string     `json:"proposed"`         // The generated output
+	Merged     string     `json:"merged,omitempty"` // Three-way merge of the last generated, local and proposed content
+	Hunks      int        `json:"conflicting_hunks"`
+	DetectedAt time.Time  `json:"detected_at"`
+	Provenance Provenance `json:"provenance"` // Of the proposed content
+}
+
+// Clean reports whether the merge applies without conflicting hunks
+func (c FileConflict) Clean() bool {
+	return c.Merged != "" && c.Hunks == 0
+}
+
+// WriteResult describes one Write
+type WriteResult struct {
+	Outcome  Outcome
+	Path     string // Absolute path of the file written
+	Conflict *FileConflict
+	Reused   bool // Identical content was generated before, here or in another workspace
+}
+
+// Workspace is a project directory and its generation manifest
+type Workspace struct {
+	root      string
+	files     map[string]Entry
+	conflicts map[string]FileConflict
+	now       func() time.Time
+}
+
+// Open loads the workspace state of root, which need not exist yet
+func Open(root string) (*Workspace, error) {
+	w := &Workspace{
+		root:      root,
+		files:     make(map[string]Entry),
+		conflicts: make(map[string]FileConflict),
+		now:       time.Now,
+	}
+	if err := readJSON(filepath.Join(root, manifestFile), &w.files); err != nil {
+		return nil, fmt.Errorf("failed to load workspace manifest: %w", err)
+	}
+	var report []FileConflict
+	if err := readJSON(filepath.Join(root, ReportFile), &report); err != nil {
+		return nil, fmt.Errorf("failed to load conflict report: %w", err)
+	}
+	for _, c := range report {
+		w.conflicts[c.Path] = c
+	}
+	return w, nil
+}
+
+// IsState reports whether a project-relative path is workspace state rather
+// than project content
+func IsState(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	return rel == StateDir || strings.HasPrefix(rel, StateDir+"/")
+}
+
+// Write writes generated content to a project-relative path unless the file
+// there was changed since this workspace last wrote it, or belongs to a step
+// that prov does not own
+func (w *Workspace) Write(rel, content string, prov Provenance) (*WriteResult, error) {
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") || IsState(rel) {
+		return nil, fmt.Errorf("path %s is outside the project", rel)
+	}
+	path := filepath.Join(w.root, rel)
+	sum := checksum(content)
+	entry, tracked := w.files[rel]
+	if tracked && !prov.owns(entry.Provenance) {
+		return &WriteResult{Outcome: Kept, Path: path}, nil
+	}
+
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return w.write(rel, path, content, prov, Written)
+	case err != nil:
+		return nil, err
+	}
+
+	curSum := checksum(string(current))
+	if curSum == sum {
+		return w.write(rel, path, content, prov, Unchanged)
+	}
+	if tracked && entry.Checksum == curSum {
+		return w.write(rel, path, content, prov, Written)
+	}
+	return w.propose(rel, string(current), content, prov)
+}
+
+// write records content as generated and writes it unless it is already there
+func (w *Workspace) write(rel, path, content string, prov Provenance, outcome Outcome) (*WriteResult, error) {
+	if outcome != Unchanged {
+		if err := writeFile(path, content); err != nil {
+			return nil, err
+		}
+	}
+	entry := Entry{Checksum: checksum(content), GeneratedAt: w.now(), Provenance: prov}
+	reused, err := w.recordBase(rel, entry.Checksum, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record generated content: %w", err)
+	}
+	entry.Shared = DefaultBlobs != nil
+	w.files[rel] = entry
+	delete(w.conflicts, rel)
+	return &WriteResult{Outcome: outcome, Path: path, Reused: reused}, nil
+}
+
+// recordBase keeps content as the last generated for rel, in DefaultBlobs
+// when set, reporting whether identical content was already stored there
+func (w *Workspace) recordBase(rel, sum, content string) (bool, error) {
+	prev, tracked := w.files[rel]
+	if DefaultBlobs == nil {
+		return false, writeFile(filepath.Join(w.root, baseDir, rel), content)
+	}
+	if tracked && prev.Shared && prev.Checksum == sum {
+		blob, err := DefaultBlobs.Stat(sum)
+		if err == nil {
+			return blob.Refs > 1, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	blob, err := DefaultBlobs.Put(content, rel)
+	if err != nil {
+		return false, err
+	}
+	if tracked && prev.Shared {
+		if err := DefaultBlobs.Release(prev.Checksum); err != nil {
+			return false, err
+		}
+	}
+	// A copy from before blobs were used is no longer needed
+	os.Remove(filepath.Join(w.root, baseDir, rel))
+	return blob.Refs > 1, nil
+}
+
+// base returns the last generated content of rel
+func (w *Workspace) base(rel string) (string, error) {
+	if entry := w.files[rel]; entry.Shared {
+		if DefaultBlobs == nil {
+			return "", os.ErrNotExist
+		}
+		return DefaultBlobs.Get(entry.Checksum)
+	}
+	data, err := os.ReadFile(filepath.Join(w.root, baseDir, rel))
+	return string(data), err
+}
+
+// propose writes generated content next to, not over, a locally changed file
+func (w *Workspace) propose(rel, local, content string, prov Provenance) (*WriteResult, error) {
+	c := FileConflict{
+		Path:       rel,
+		Reason:     ReasonUntracked,
+		Proposed:   ProposedDir + "/" + rel,
+		DetectedAt: w.now(),
+		Provenance: prov,
+	}
+	path := filepath.Join(w.root, c.Proposed)
+	if err := writeFile(path, content); err != nil {
+		return nil, err
+	}
+
+	// The last generated content is the common ancestor of both sides
+	if _, ok := w.files[rel]; ok {
+		c.Reason = ReasonModified
+		base, err := w.base(rel)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			merged, hunks := Merge3(base, local, content)
+			c.Merged = c.Proposed + ".merged"
+			c.Hunks = hunks
+			if err := writeFile(filepath.Join(w.root, c.Merged), merged); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.conflicts[rel] = c
+	return &WriteResult{Outcome: Conflict, Path: path, Conflict: &c}, nil
+}
+
+// Files returns every generated file with its provenance, sorted by path
+func (w *Workspace) Files() []File {
+	files := make([]File, 0, len(w.files))
+	for path, entry := range w.files {
+		files = append(files, File{Path: path, Entry: entry})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// Conflicts returns the unresolved conflicts, sorted by path
+func (w *Workspace) Conflicts() []FileConflict {
+	conflicts := make([]FileConflict, 0, len(w.conflicts))
+	for _, c := range w.conflicts {
+		conflicts = append(conflicts, c)
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Path < conflicts[j].Path })
+	return conflicts
+}
+
+// Save persists the manifest and the conflict report
+func (w *Workspace) Save() error {
+	if err := writeJSON(filepath.Join(w.root, manifestFile), w.files); err != nil {
+		return fmt.Errorf("failed to save workspace manifest: %w", err)
+	}
+	report := filepath.Join(w.root, ReportFile)
+	if len(w.conflicts) == 0 {
+		if err := os.Remove(report); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear conflict report: %w", err)
+		}
+		return nil
+	}
+	if err := writeJSON(report, w.Conflicts()); err != nil {
+		return fmt.Errorf("failed to save conflict report: %w", err)
+	}
+	return nil
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// readJSON decodes path into v, leaving v untouched if the file is missing
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON replaces path atomically
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,265 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+var gen = Provenance{WorkflowID: "wf", Run: "run-1", Step: 2, Agent: "development", Model: "llama", PromptVersion: "abc123"}
+
+func TestWorkspace_Write(t *testing.T) {
+	root := t.TempDir()
+	main := filepath.Join(root, "src", "main.go")
+
+	ws, err := Open(root)
+	require.NoError(t, err)
+	res, err := ws.Write("src/main.go", "package main\n", gen)
+	require.NoError(t, err)
+	assert.Equal(t, Written, res.Outcome)
+	assert.Equal(t, main, res.Path)
+	require.NoError(t, ws.Save())
+
+	// A re-run with the same output is a no-op
+	ws, err = Open(root)
+	require.NoError(t, err)
+	res, err = ws.Write("src/main.go", "package main\n", gen)
+	require.NoError(t, err)
+	assert.Equal(t, Unchanged, res.Outcome)
+
+	// Unedited files are regenerated in place
+	res, err = ws.Write("src/main.go", "package main\n\nfunc main() {}\n", gen)
+	require.NoError(t, err)
+	assert.Equal(t, Written, res.Outcome)
+	require.NoError(t, ws.Save())
+	assert.Equal(t, "package main\n\nfunc main() {}\n", readFile(t, main))
+	assert.Empty(t, ws.Conflicts())
+
+	_, err = ws.Write("../escape.go", "x", gen)
+	assert.Error(t, err)
+	_, err = ws.Write(".miosa/manifest.json", "{}", gen)
+	assert.Error(t, err)
+}
+
+func TestWorkspace_WriteConflict(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "app.py")
+	generated := "import os\n\ndef main():\n    pass\n"
+
+	ws, err := Open(root)
+	require.NoError(t, err)
+	_, err = ws.Write("app.py", generated, gen)
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+
+	edited := "import os\nimport sys\n\ndef main():\n    pass\n"
+	require.NoError(t, os.WriteFile(path, []byte(edited), 0644))
+
+	ws, err = Open(root)
+	require.NoError(t, err)
+	regenerated := "import os\n\ndef main():\n    run()\n"
+	res, err := ws.Write("app.py", regenerated, gen)
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+
+	assert.Equal(t, Conflict, res.Outcome)
+	assert.Equal(t, edited, readFile(t, path), "local edits must survive")
+	assert.Equal(t, regenerated, readFile(t, filepath.Join(root, ".miosa", "proposed", "app.py")))
+
+	require.NotNil(t, res.Conflict)
+	assert.Equal(t, ReasonModified, res.Conflict.Reason)
+	assert.True(t, res.Conflict.Clean())
+	assert.Equal(t, "import os\nimport sys\n\ndef main():\n    run()\n",
+		readFile(t, filepath.Join(root, res.Conflict.Merged)))
+
+	// The report survives reopening the workspace
+	ws, err = Open(root)
+	require.NoError(t, err)
+	conflicts := ws.Conflicts()
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "app.py", conflicts[0].Path)
+	assert.Equal(t, ".miosa/proposed/app.py", conflicts[0].Proposed)
+
+	// Accepting the proposal resolves the conflict on the next run
+	require.NoError(t, os.WriteFile(path, []byte(regenerated), 0644))
+	res, err = ws.Write("app.py", regenerated, gen)
+	require.NoError(t, err)
+	assert.Equal(t, Unchanged, res.Outcome)
+	require.NoError(t, ws.Save())
+	assert.NoFileExists(t, filepath.Join(root, ReportFile))
+}
+
+func TestWorkspace_WriteUntracked(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# Mine\n"), 0644))
+
+	ws, err := Open(root)
+	require.NoError(t, err)
+	res, err := ws.Write("README.md", "# Generated\n", gen)
+	require.NoError(t, err)
+
+	assert.Equal(t, Conflict, res.Outcome)
+	assert.Equal(t, ReasonUntracked, res.Conflict.Reason)
+	assert.Empty(t, res.Conflict.Merged)
+	assert.False(t, res.Conflict.Clean())
+	assert.Equal(t, "# Mine\n", readFile(t, filepath.Join(root, "README.md")))
+}
+
+func TestWorkspace_Provenance(t *testing.T) {
+	root := t.TempDir()
+	ws, err := Open(root)
+	require.NoError(t, err)
+
+	deploy := Provenance{WorkflowID: "wf", Run: "run-1", Step: 3, Agent: "deployment"}
+	_, err = ws.Write("main.go", "package main\n", gen)
+	require.NoError(t, err)
+	_, err = ws.Write("Dockerfile", "FROM golang\n", deploy)
+	require.NoError(t, err)
+
+	// Later steps of the same run may rewrite earlier output
+	_, err = ws.Write("main.go", "package main\n\n// deployed\n", deploy)
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+
+	ws, err = Open(root)
+	require.NoError(t, err)
+	files := ws.Files()
+	require.Len(t, files, 2)
+	assert.Equal(t, "Dockerfile", files[0].Path)
+	assert.Equal(t, deploy, files[0].Provenance)
+	assert.Equal(t, "main.go", files[1].Path)
+	assert.Equal(t, deploy, files[1].Provenance)
+	assert.NotEmpty(t, files[1].Checksum)
+
+	// A resumed run regenerates only what the re-run step owns
+	resumed := deploy
+	resumed.Run = "run-2"
+	res, err := ws.Write("Dockerfile", "FROM golang:1.22\n", resumed)
+	require.NoError(t, err)
+	assert.Equal(t, Written, res.Outcome)
+
+	dev := gen
+	dev.Run = "run-2"
+	res, err = ws.Write("main.go", "package other\n", dev)
+	require.NoError(t, err)
+	assert.Equal(t, Kept, res.Outcome)
+	assert.Equal(t, "package main\n\n// deployed\n", readFile(t, filepath.Join(root, "main.go")))
+}
+
+func TestProvenanceHandler(t *testing.T) {
+	root := t.TempDir()
+	id := uuid.New()
+	ws, err := Open(root)
+	require.NoError(t, err)
+	_, err = ws.Write("main.go", "package main\n", Provenance{WorkflowID: id.String(), Run: "r", Step: 1, Agent: "development"})
+	require.NoError(t, err)
+	_, err = ws.Write("other.go", "package other\n", gen)
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/workflow/{id}/provenance", ProvenanceHandler(func(uuid.UUID) string { return root }))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/workflow/" + id.String() + "/provenance")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Files []File `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Files, 1)
+	assert.Equal(t, "main.go", body.Files[0].Path)
+	assert.Equal(t, "development", body.Files[0].Provenance.Agent)
+
+	assert.Equal(t, http.StatusNotFound, get("/api/workflow/"+uuid.New().String()+"/provenance").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/workflow/nope/provenance").Code)
+}
+
+func TestArchiveHandler(t *testing.T) {
+	root := t.TempDir()
+	id := uuid.New()
+	ws, err := Open(root)
+	require.NoError(t, err)
+	_, err = ws.Write("cmd/app/main.go", "package main\n", Provenance{WorkflowID: id.String(), Run: "r", Step: 1, Agent: "development"})
+	require.NoError(t, err)
+	_, err = ws.Write("other.go", "package other\n", gen)
+	require.NoError(t, err)
+	require.NoError(t, ws.Save())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/workflow/{id}/archive", ArchiveHandler(func(uuid.UUID) string { return root }))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflow/"+id.String()+"/archive", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "cmd/app/main.go", hdr.Name)
+	content, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(content))
+	_, err = tr.Next()
+	assert.Equal(t, io.EOF, err, "files from other workflows are left out")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/workflow/"+uuid.New().String()+"/archive", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\n"
+	tests := []struct {
+		name   string
+		ours   string
+		theirs string
+		merged string
+		hunks  int
+	}{
+		{"unchanged", base, base, base, 0},
+		{"only ours", "a\nB\nc\nd\n", base, "a\nB\nc\nd\n", 0},
+		{"only theirs", base, "a\nb\nc\nD\n", "a\nb\nc\nD\n", 0},
+		{"disjoint", "A\nb\nc\nd\n", "a\nb\nc\nD\n", "A\nb\nc\nD\n", 0},
+		{"same change", "a\nX\nc\nd\n", "a\nX\nc\nd\n", "a\nX\nc\nd\n", 0},
+		{"insert and delete", "a\nb\nb2\nc\nd\n", "a\nb\nc\n", "a\nb\nb2\nc\n", 0},
+		{
+			"conflict", "a\nours\nc\nd\n", "a\ntheirs\nc\nd\n",
+			"a\n<<<<<<< local\nours\n||||||| generated\nb\n=======\ntheirs\n>>>>>>> proposed\nc\nd\n", 1,
+		},
+		{
+			"no trailing newline", "a\nb\nc\nours", "a\nb\nc\ntheirs",
+			"a\nb\nc\n<<<<<<< local\nours\n||||||| generated\nd\n=======\ntheirs\n>>>>>>> proposed\n", 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, hunks := Merge3(base, tt.ours, tt.theirs)
+			assert.Equal(t, tt.merged, merged)
+			assert.Equal(t, tt.hunks, hunks)
+		})
+	}
+}